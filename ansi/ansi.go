@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // ColorizeFunc is a function that takes a string and returns a string with
@@ -15,6 +16,10 @@ type ColorizeFunc func(string) string
 // colorRx matches single- or double-bracketed color codes, like [r] or [c123].
 var colorRx = regexp.MustCompile(`(?m)\[(\[?-?[a-zA-Z0-9~]{1,4}?\]?)\]`)
 
+// escapeRx matches raw ANSI escape sequences that have already been applied to
+// a string.
+var escapeRx = regexp.MustCompile("\033\\[[0-9;]*m")
+
 var (
 	colorMap = map[string]string{
 		"l": "0;22",
@@ -194,6 +199,36 @@ func Purge(text string) string {
 	return final
 }
 
+// EscapeCodes escapes any color codes in the text so that they're displayed
+// as written instead of being converted to colors, "[r]" becomes "[[r]]".
+func EscapeCodes(text string) string {
+	return colorRx.ReplaceAllStringFunc(text, func(s string) string {
+		match := colorRx.FindStringSubmatch(s)
+		code := match[1]
+		if strings.HasPrefix(code, "[") || strings.HasSuffix(code, "]") {
+			return s
+		}
+		if _, ok := colorToANSI[code]; !ok {
+			return s
+		}
+
+		return "[" + s + "]"
+	})
+}
+
+// Strip removes both unprocessed color codes and any ANSI escape sequences
+// from the given string, leaving only the visible text.
+func Strip(text string) string {
+	return escapeRx.ReplaceAllString(Purge(text), "")
+}
+
+// VisibleLength returns the number of characters that will be displayed when
+// the text is printed, ignoring color codes and ANSI escape sequences. This
+// should be used over len when aligning colored text.
+func VisibleLength(text string) int {
+	return utf8.RuneCountInString(Strip(text))
+}
+
 // Escape will replace all ANSI escape codes with text equivalents so strings
 // can be printed with color codes.
 func Escape(text string) string {
@@ -136,4 +136,28 @@ var _ = Describe("Color", func() {
 			Ω(Colorize(sStr)).Should(Equal(sResult))
 		})
 	})
+
+	Describe("Strip", func() {
+		It("removes color codes and ANSI sequences", func() {
+			Ω(Strip("[r]red[x] and " + Colorize("[b]blue[x]"))).Should(Equal("red and blue"))
+		})
+
+		It("keeps escaped color codes", func() {
+			Ω(Strip("[[r]]red")).Should(Equal("[r]red"))
+		})
+	})
+
+	Describe("EscapeCodes", func() {
+		It("escapes color codes so they are displayed", func() {
+			Ω(EscapeCodes("[r]red [notcode]")).Should(Equal("[[r]]red [notcode]"))
+			Ω(Colorize(EscapeCodes("[r]red"))).Should(Equal("[r]red"))
+		})
+	})
+
+	Describe("VisibleLength", func() {
+		It("counts only displayed characters", func() {
+			Ω(VisibleLength("[R]danger[x]")).Should(Equal(6))
+			Ω(VisibleLength(Colorize("[c123]é[x]"))).Should(Equal(1))
+		})
+	})
 })
@@ -4,7 +4,10 @@
 // assets/raw/DragonInfo.toml
 // assets/raw/Dragonfile.toml
 // assets/raw/init.lua
+// assets/raw/modules/check.lua
 // assets/raw/modules/fn.lua
+// assets/raw/modules/mathx.lua
+// assets/raw/modules/tbl.lua
 // assets/raw/test.toml
 // DO NOT EDIT!
 
@@ -104,6 +107,24 @@ func initLua() (*asset, error) {
 	return a, err
 }
 
+// modulesCheckLua reads file data from disk. It returns an error on failure.
+func modulesCheckLua() (*asset, error) {
+	path := "/Users/brandonbuck/Dev/go/src/github.com/bbuck/dragon-mud/assets/raw/modules/check.lua"
+	name := "modules/check.lua"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
 // modulesFnLua reads file data from disk. It returns an error on failure.
 func modulesFnLua() (*asset, error) {
 	path := "/Users/brandonbuck/Dev/go/src/github.com/bbuck/dragon-mud/assets/raw/modules/fn.lua"
@@ -122,6 +143,42 @@ func modulesFnLua() (*asset, error) {
 	return a, err
 }
 
+// modulesMathxLua reads file data from disk. It returns an error on failure.
+func modulesMathxLua() (*asset, error) {
+	path := "/Users/brandonbuck/Dev/go/src/github.com/bbuck/dragon-mud/assets/raw/modules/mathx.lua"
+	name := "modules/mathx.lua"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
+// modulesTblLua reads file data from disk. It returns an error on failure.
+func modulesTblLua() (*asset, error) {
+	path := "/Users/brandonbuck/Dev/go/src/github.com/bbuck/dragon-mud/assets/raw/modules/tbl.lua"
+	name := "modules/tbl.lua"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
 // testToml reads file data from disk. It returns an error on failure.
 func testToml() (*asset, error) {
 	path := "/Users/brandonbuck/Dev/go/src/github.com/bbuck/dragon-mud/assets/raw/test.toml"
@@ -192,23 +249,28 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	".gitignore": Gitignore,
-	"DragonInfo.toml": dragoninfoToml,
-	"Dragonfile.toml": dragonfileToml,
-	"init.lua": initLua,
-	"modules/fn.lua": modulesFnLua,
-	"test.toml": testToml,
+	".gitignore":        Gitignore,
+	"DragonInfo.toml":   dragoninfoToml,
+	"Dragonfile.toml":   dragonfileToml,
+	"init.lua":          initLua,
+	"modules/check.lua": modulesCheckLua,
+	"modules/fn.lua":    modulesFnLua,
+	"modules/mathx.lua": modulesMathxLua,
+	"modules/tbl.lua":   modulesTblLua,
+	"test.toml":         testToml,
 }
 
 // AssetDir returns the file names below a certain
 // directory embedded in the file by go-bindata.
 // For example if you run go-bindata on data/... and data contains the
 // following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
 // then AssetDir("data") would return []string{"foo.txt", "img"}
 // AssetDir("data/img") would return []string{"a.png", "b.png"}
 // AssetDir("foo.txt") and AssetDir("notexist") would return an error
@@ -239,13 +301,17 @@ type bintree struct {
 	Func     func() (*asset, error)
 	Children map[string]*bintree
 }
+
 var _bintree = &bintree{nil, map[string]*bintree{
-	".gitignore": &bintree{Gitignore, map[string]*bintree{}},
+	".gitignore":      &bintree{Gitignore, map[string]*bintree{}},
 	"DragonInfo.toml": &bintree{dragoninfoToml, map[string]*bintree{}},
 	"Dragonfile.toml": &bintree{dragonfileToml, map[string]*bintree{}},
-	"init.lua": &bintree{initLua, map[string]*bintree{}},
+	"init.lua":        &bintree{initLua, map[string]*bintree{}},
 	"modules": &bintree{nil, map[string]*bintree{
-		"fn.lua": &bintree{modulesFnLua, map[string]*bintree{}},
+		"check.lua": &bintree{modulesCheckLua, map[string]*bintree{}},
+		"fn.lua":    &bintree{modulesFnLua, map[string]*bintree{}},
+		"mathx.lua": &bintree{modulesMathxLua, map[string]*bintree{}},
+		"tbl.lua":   &bintree{modulesTblLua, map[string]*bintree{}},
 	}},
 	"test.toml": &bintree{testToml, map[string]*bintree{}},
 }}
@@ -296,4 +362,3 @@ func _filePath(dir, name string) string {
 	cannonicalName := strings.Replace(name, "\\", "/", -1)
 	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
 }
-
@@ -0,0 +1,322 @@
+-- error levels, gopher-lua counts error itself as a level so these are one
+-- higher than they would be in the reference Lua implementation. CALLER points
+-- at the code that called check and BLAME at the code that called the function
+-- being checked.
+local CALLER = 3
+local BLAME = 4
+
+-- types that can be checked in addition to those returned by type()
+local special_types = {
+  any = function(value)
+    return value ~= nil
+  end,
+  integer = function(value)
+    return type(value) == "number" and value % 1 == 0
+  end,
+  list = function(value)
+    return type(value) == "table" and (next(value) == nil or value[1] ~= nil)
+  end,
+  callable = function(value)
+    if type(value) == "function" then
+      return true
+    end
+
+    local mt = getmetatable(value)
+
+    return type(mt) == "table" and type(mt.__call) == "function"
+  end,
+}
+
+local lua_types = {
+  ["nil"] = true,
+  boolean = true,
+  number = true,
+  string = true,
+  table = true,
+  ["function"] = true,
+  userdata = true,
+  thread = true,
+}
+
+-- parse a type spec like "string", "?table" or "number|string" into a list of
+-- type names, specs starting with ? also allow nil. Parsed specs are cached
+-- since the same spec is usually checked on every call. Invalid specs are a
+-- bug in the code calling check, so the error points there.
+--
+-- params:
+--   spec = the type spec to parse
+--
+-- returns:
+--   the list of allowed type names
+local parsed_specs = {}
+local function parse_spec(spec)
+  if parsed_specs[spec] then
+    return parsed_specs[spec]
+  end
+
+  if type(spec) ~= "string" or spec == "" then
+    error("check: type spec must be a non-empty string", BLAME)
+  end
+
+  local types = {}
+  local rest = spec
+  if rest:sub(1, 1) == "?" then
+    types[1] = "nil"
+    rest = rest:sub(2)
+  end
+
+  for name in rest:gmatch("[^|]+") do
+    name = name:match("^%s*(.-)%s*$")
+    if not lua_types[name] and not special_types[name] then
+      error(string.format("check: unknown type %q in %q", name, spec), BLAME)
+    end
+    types[#types + 1] = name
+  end
+  parsed_specs[spec] = types
+
+  return types
+end
+
+-- determine if the value matches one of the given type names
+local function matches(value, types)
+  for _, name in ipairs(types) do
+    local special = special_types[name]
+    if special then
+      if special(value) then
+        return true
+      end
+    elseif type(value) == name then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- describe the allowed types for error messages, "?string" is described as
+-- "string or nil"
+local function describe_types(types)
+  local names = {}
+  local allows_nil = false
+  for _, name in ipairs(types) do
+    if name == "nil" then
+      allows_nil = true
+    else
+      names[#names + 1] = name
+    end
+  end
+  if allows_nil then
+    names[#names + 1] = "nil"
+  end
+
+  if #names == 1 then
+    return names[1]
+  end
+
+  return table.concat(names, ", ", 1, #names - 1) .. " or " .. names[#names]
+end
+
+-- describe a value for error messages, strings are quoted and tables and
+-- functions are described by their type
+local function describe_value(value)
+  if type(value) == "string" then
+    return string.format("%q", value)
+  elseif type(value) == "number" or type(value) == "boolean" or value == nil then
+    return tostring(value)
+  end
+
+  return type(value)
+end
+
+-- build the start of an argument error, such as "bad argument #2 'target'"
+local function arg_prefix(n, name)
+  if name then
+    return string.format("bad argument #%d '%s'", n, name)
+  end
+
+  return string.format("bad argument #%d", n)
+end
+
+-- check the fields of tbl against spec, returning an error message for the
+-- first field that doesn't match or nil if all of them do
+local function check_fields(tbl, spec, path)
+  -- sort the field names so the same field is always reported first
+  local keys = {}
+  for key in pairs(spec) do
+    keys[#keys + 1] = key
+  end
+  table.sort(keys, function(a, b)
+    return tostring(a) < tostring(b)
+  end)
+
+  for _, key in ipairs(keys) do
+    local field_spec = spec[key]
+    local value = tbl[key]
+    local field = path .. tostring(key)
+    if type(field_spec) == "table" then
+      if type(value) ~= "table" then
+        return string.format("field %q expected table, got %s", field, type(value))
+      end
+
+      local err = check_fields(value, field_spec, field .. ".")
+      if err then
+        return err
+      end
+    else
+      local types = parse_spec(field_spec)
+      if not matches(value, types) then
+        return string.format("field %q expected %s, got %s", field, describe_types(types), type(value))
+      end
+    end
+  end
+
+  return nil
+end
+
+-- Contract checks for plugin functions. Failed checks raise errors that point
+-- at the code that called the checked function, so builders see where the bad
+-- value came from instead of where it was noticed.
+--
+-- Type specs are type names as returned by type(), or "integer", "list",
+-- "callable" or "any" (anything but nil). Several types can be allowed with |
+-- ("number|string") and starting a spec with ? also allows nil ("?table").
+local check = {
+  -- arg verifies the type of an argument.
+  --
+  -- params:
+  --   n = the position of the argument
+  --   value = the argument's value
+  --   spec = the type spec the value must match
+  --   name = optional name of the argument, included in errors
+  --
+  -- returns:
+  --   the value, so it can be checked as it's assigned
+  arg = function(n, value, spec, name)
+    local types = parse_spec(spec)
+    if not matches(value, types) then
+      error(string.format("%s (expected %s, got %s)", arg_prefix(n, name), describe_types(types), type(value)), BLAME)
+    end
+
+    return value
+  end,
+
+  -- fields verifies the fields of a table, such as an options argument.
+  -- Fields not in the spec are ignored.
+  --
+  -- params:
+  --   tbl = the table to check
+  --   spec = table of field names to type specs, nested tables check the
+  --     fields of nested tables
+  --   n = optional argument position of tbl, included in errors
+  --
+  -- returns:
+  --   the table
+  fields = function(tbl, spec, n)
+    if type(spec) ~= "table" then
+      error("check: fields spec must be a table", CALLER)
+    end
+
+    local err
+    if type(tbl) ~= "table" then
+      err = string.format("expected table, got %s", type(tbl))
+    else
+      err = check_fields(tbl, spec, "")
+    end
+
+    if err then
+      if n then
+        err = string.format("%s (%s)", arg_prefix(n), err)
+      else
+        err = "invalid table, " .. err
+      end
+      error(err, BLAME)
+    end
+
+    return tbl
+  end,
+
+  -- one_of verifies an argument is one of a list of values.
+  --
+  -- params:
+  --   n = the position of the argument
+  --   value = the argument's value
+  --   choices = list of allowed values
+  --   name = optional name of the argument, included in errors
+  --
+  -- returns:
+  --   the value
+  one_of = function(n, value, choices, name)
+    local described = {}
+    for i, choice in ipairs(choices) do
+      if value == choice then
+        return value
+      end
+      described[i] = describe_value(choice)
+    end
+
+    error(string.format("%s (expected one of %s, got %s)", arg_prefix(n, name), table.concat(described, ", "), describe_value(value)), BLAME)
+  end,
+
+  -- range verifies a numeric argument is between min and max, inclusive.
+  -- Either bound can be nil to leave that side unbounded.
+  --
+  -- params:
+  --   n = the position of the argument
+  --   value = the argument's value
+  --   min = the lowest allowed value
+  --   max = the highest allowed value
+  --   name = optional name of the argument, included in errors
+  --
+  -- returns:
+  --   the value
+  range = function(n, value, min, max, name)
+    if type(value) == "number" and (min == nil or value >= min) and (max == nil or value <= max) then
+      return value
+    end
+
+    local expected
+    if min ~= nil and max ~= nil then
+      expected = string.format("a number from %s to %s", tostring(min), tostring(max))
+    elseif min ~= nil then
+      expected = string.format("a number of at least %s", tostring(min))
+    else
+      expected = string.format("a number of at most %s", tostring(max))
+    end
+
+    error(string.format("%s (expected %s, got %s)", arg_prefix(n, name), expected, describe_value(value)), BLAME)
+  end,
+
+  -- that raises an error if the condition is false, the message is formatted
+  -- with string.format using any extra arguments. Unlike the other checks the
+  -- error points at the line calling that.
+  --
+  -- params:
+  --   condition = the value that must be truthy
+  --   message = the error message, or format
+  --   ... = values to format into the message
+  --
+  -- returns:
+  --   the condition
+  that = function(condition, message, ...)
+    if not condition then
+      error(string.format(message or "check failed", ...), CALLER)
+    end
+
+    return condition
+  end,
+
+  -- is determines if the value matches the type spec without raising an
+  -- error.
+  --
+  -- params:
+  --   value = the value to check
+  --   spec = the type spec the value must match
+  --
+  -- returns:
+  --   true if the value matches
+  is = function(value, spec)
+    return matches(value, parse_spec(spec))
+  end,
+}
+
+return check
@@ -0,0 +1,285 @@
+-- floor without depending on the math library, the modulo operator in Lua
+-- always returns a value with the sign of the divisor so this works for
+-- negative numbers as well.
+local function floor(x)
+  return x - x % 1
+end
+
+-- round to the nearest whole number, halves round away from zero
+local function round(x)
+  if x < 0 then
+    return -floor(-x + 0.5)
+  end
+
+  return floor(x + 0.5)
+end
+
+local vec2, vec3
+
+-- vectors share all their arithmetic, the only difference is the number of
+-- components. Each vector type is described by its metatable and list of
+-- component names.
+local function vector_type(name, components)
+  local mt = {}
+  mt.__index = mt
+
+  local function new(...)
+    local args = {...}
+    local v = {}
+    for i, c in ipairs(components) do
+      v[c] = args[i] or 0
+    end
+
+    return setmetatable(v, mt)
+  end
+
+  -- apply fn to each component of a and b, where b can be a vector or number
+  local function combine(a, b, fn)
+    local v = {}
+    for _, c in ipairs(components) do
+      if type(a) == "number" then
+        v[c] = fn(a, b[c])
+      elseif type(b) == "number" then
+        v[c] = fn(a[c], b)
+      else
+        v[c] = fn(a[c], b[c])
+      end
+    end
+
+    return setmetatable(v, mt)
+  end
+
+  mt.__add = function(a, b)
+    return combine(a, b, function(x, y) return x + y end)
+  end
+
+  mt.__sub = function(a, b)
+    return combine(a, b, function(x, y) return x - y end)
+  end
+
+  mt.__mul = function(a, b)
+    return combine(a, b, function(x, y) return x * y end)
+  end
+
+  mt.__div = function(a, b)
+    return combine(a, b, function(x, y) return x / y end)
+  end
+
+  mt.__unm = function(a)
+    return combine(a, -1, function(x, y) return x * y end)
+  end
+
+  mt.__eq = function(a, b)
+    for _, c in ipairs(components) do
+      if a[c] ~= b[c] then
+        return false
+      end
+    end
+
+    return true
+  end
+
+  mt.__tostring = function(a)
+    local parts = {}
+    for _, c in ipairs(components) do
+      table.insert(parts, tostring(a[c]))
+    end
+
+    return name .. "(" .. table.concat(parts, ", ") .. ")"
+  end
+
+  -- dot product of this vector and another
+  function mt:dot(other)
+    local sum = 0
+    for _, c in ipairs(components) do
+      sum = sum + self[c] * other[c]
+    end
+
+    return sum
+  end
+
+  -- length (magnitude) of the vector
+  function mt:length()
+    return self:dot(self) ^ 0.5
+  end
+
+  -- new vector in the same direction with a length of 1, zero vectors are
+  -- returned unchanged
+  function mt:normalize()
+    local len = self:length()
+    if len == 0 then
+      return new()
+    end
+
+    return self / len
+  end
+
+  -- distance between the points this vector and another represent
+  function mt:distance(other)
+    return (self - other):length()
+  end
+
+  -- linear interpolation between this vector and another
+  function mt:lerp(other, t)
+    return self + (other - self) * t
+  end
+
+  -- copy of the vector with every component rounded to the nearest whole
+  -- number
+  function mt:round()
+    return combine(self, 0, function(x) return round(x) end)
+  end
+
+  -- the components of the vector as multiple return values
+  function mt:unpack()
+    local values = {}
+    for i, c in ipairs(components) do
+      values[i] = self[c]
+    end
+
+    return unpack(values)
+  end
+
+  return new, mt
+end
+
+local vec2_mt, vec3_mt
+vec2, vec2_mt = vector_type("vec2", {"x", "y"})
+vec3, vec3_mt = vector_type("vec3", {"x", "y", "z"})
+
+-- cross product, only defined for three dimensional vectors
+function vec3_mt:cross(other)
+  return vec3(
+    self.y * other.z - self.z * other.y,
+    self.z * other.x - self.x * other.z,
+    self.x * other.y - self.y * other.x
+  )
+end
+
+local mathx = {
+  -- clamp restricts a value to the given range.
+  --
+  -- params:
+  --   value = the number to clamp
+  --   min = the lowest value allowed
+  --   max = the highest value allowed
+  --
+  -- returns:
+  --   min if value is less than min, max if it's greater than max otherwise
+  --   value
+  clamp = function(value, min, max)
+    if value < min then
+      return min
+    elseif value > max then
+      return max
+    end
+
+    return value
+  end,
+
+  -- lerp linearly interpolates between two numbers.
+  --
+  -- params:
+  --   a = the value when t is 0
+  --   b = the value when t is 1
+  --   t = how far between a and b to go, not restricted to 0 through 1
+  --
+  -- returns:
+  --   the interpolated value
+  lerp = function(a, b, t)
+    return a + (b - a) * t
+  end,
+
+  -- inverse_lerp determines how far between two numbers a value is, the
+  -- opposite of lerp.
+  --
+  -- params:
+  --   a = the start of the range
+  --   b = the end of the range
+  --   value = the value within the range
+  --
+  -- returns:
+  --   0 when value is a, 1 when it's b (or 0 if a and b are equal)
+  inverse_lerp = function(a, b, value)
+    if a == b then
+      return 0
+    end
+
+    return (value - a) / (b - a)
+  end,
+
+  -- round rounds to the nearest whole number, halves round away from zero.
+  round = round,
+
+  -- round_to rounds a value to the nearest multiple of the increment.
+  --
+  -- params:
+  --   value = the number to round
+  --   increment = the multiple to round to, like 5 or 0.01
+  --
+  -- returns:
+  --   the nearest multiple of increment
+  round_to = function(value, increment)
+    if increment == 0 then
+      return value
+    end
+
+    return round(value / increment) * increment
+  end,
+
+  -- percent determines what percentage part is of whole.
+  --
+  -- params:
+  --   part = the portion of the whole
+  --   whole = the total amount
+  --
+  -- returns:
+  --   part as a percentage of whole (0 through 100 for parts within the
+  --   whole), or 0 if whole is 0
+  percent = function(part, whole)
+    if whole == 0 then
+      return 0
+    end
+
+    return part / whole * 100
+  end,
+
+  -- percent_of returns the given percentage of a value, percent_of(25, 80)
+  -- is 20.
+  percent_of = function(percent, whole)
+    return whole * percent / 100
+  end,
+
+  -- sign returns -1 for negative numbers, 1 for positive numbers and 0 for 0.
+  sign = function(value)
+    if value < 0 then
+      return -1
+    elseif value > 0 then
+      return 1
+    end
+
+    return 0
+  end,
+
+  -- vec2 creates a two dimensional vector, missing components default to 0.
+  -- Vectors support +, -, * and / with other vectors (component wise) or
+  -- numbers, unary -, == and tostring, and have dot, length, normalize,
+  -- distance, lerp, round and unpack methods.
+  vec2 = vec2,
+
+  -- vec3 creates a three dimensional vector, it supports everything vec2
+  -- does as well as a cross method.
+  vec3 = vec3,
+
+  -- is_vec2 determines if the value is a vec2.
+  is_vec2 = function(value)
+    return getmetatable(value) == vec2_mt
+  end,
+
+  -- is_vec3 determines if the value is a vec3.
+  is_vec3 = function(value)
+    return getmetatable(value) == vec3_mt
+  end,
+}
+
+return mathx
@@ -0,0 +1,403 @@
+-- determine if a table is a list (has a length) or not
+--
+-- params:
+--   t = table to check if is a list
+--
+-- returns:
+--   true if t has length > 0
+local function is_list(t)
+  return #t > 0
+end
+
+-- build a function that fetches the sort key from a value, keys can be given
+-- as a field name or as a function that returns the key.
+--
+-- params:
+--   key = string field name or function(value): any
+--
+-- returns:
+--   a function that returns the key for a given value
+local function key_getter(key)
+  if type(key) == "function" then
+    return key
+  end
+
+  return function(value)
+    return value[key]
+  end
+end
+
+-- deep copy implementation tracking tables already copied so that cyclic
+-- tables copy correctly.
+local function copy_deep(value, seen)
+  if type(value) ~= "table" then
+    return value
+  end
+
+  if seen[value] then
+    return seen[value]
+  end
+
+  local new_table = {}
+  seen[value] = new_table
+  for k, v in pairs(value) do
+    new_table[copy_deep(k, seen)] = copy_deep(v, seen)
+  end
+
+  return setmetatable(new_table, getmetatable(value))
+end
+
+-- merge source into target, recursing into tables that exist in both
+local function merge_deep(target, source)
+  for k, v in pairs(source) do
+    if type(v) == "table" and type(target[k]) == "table" and not is_list(v) then
+      merge_deep(target[k], v)
+    elseif type(v) == "table" then
+      target[k] = copy_deep(v, {})
+    else
+      target[k] = v
+    end
+  end
+
+  return target
+end
+
+-- stable merge sort of list between lo and hi (inclusive) using less
+local function merge_sort(list, lo, hi, less, scratch)
+  if lo >= hi then
+    return
+  end
+
+  local mid = (lo + hi - (lo + hi) % 2) / 2
+  merge_sort(list, lo, mid, less, scratch)
+  merge_sort(list, mid + 1, hi, less, scratch)
+
+  local i, j, k = lo, mid + 1, lo
+  while i <= mid and j <= hi do
+    -- only take from the right half if strictly less, keeping equal values in
+    -- their original order
+    if less(list[j], list[i]) then
+      scratch[k] = list[j]
+      j = j + 1
+    else
+      scratch[k] = list[i]
+      i = i + 1
+    end
+    k = k + 1
+  end
+  while i <= mid do
+    scratch[k] = list[i]
+    i, k = i + 1, k + 1
+  end
+  while j <= hi do
+    scratch[k] = list[j]
+    j, k = j + 1, k + 1
+  end
+  for n = lo, hi do
+    list[n] = scratch[n]
+  end
+end
+
+-- build a lookup set from the values of a list
+local function to_set(list)
+  local set = {}
+  for _, v in ipairs(list) do
+    set[v] = true
+  end
+
+  return set
+end
+
+-- for the sake of the documentation in this module, 'list' refers to tables
+-- being used strictly as lists of data, like {1, 2, 3} while 'map' refers to
+-- using tables as key/value storage, like {one = 1, two = 2}. Unless stated
+-- otherwise functions never modify the tables given to them.
+local tbl = {
+  -- copy creates a shallow copy of the table, nested tables are shared with
+  -- the original table.
+  --
+  -- params:
+  --   t = the table to copy
+  --
+  -- returns:
+  --   a new table with the same keys, values and metatable as t
+  copy = function(t)
+    local new_table = {}
+    for k, v in pairs(t) do
+      new_table[k] = v
+    end
+
+    return setmetatable(new_table, getmetatable(t))
+  end,
+
+  -- deep_copy creates a copy of the table and every table nested within it,
+  -- cycles are preserved in the copy and metatables are shared.
+  --
+  -- params:
+  --   t = the table to copy
+  --
+  -- returns:
+  --   a completely independent copy of t
+  deep_copy = function(t)
+    return copy_deep(t, {})
+  end,
+
+  -- merge combines any number of maps into a new map, values in later maps
+  -- replace values from earlier maps.
+  --
+  -- params:
+  --   ... = the maps to merge
+  --
+  -- returns:
+  --   a new map containing all keys from all given maps
+  merge = function(...)
+    local new_table = {}
+    for _, t in ipairs({...}) do
+      for k, v in pairs(t) do
+        new_table[k] = v
+      end
+    end
+
+    return new_table
+  end,
+
+  -- deep_merge combines any number of maps into a new map, when both maps
+  -- contain a map for the same key those maps are merged as well. Lists are
+  -- treated as values and replaced rather than merged.
+  --
+  -- params:
+  --   ... = the maps to merge
+  --
+  -- returns:
+  --   a new map containing all keys from all given maps
+  deep_merge = function(...)
+    local new_table = {}
+    for _, t in ipairs({...}) do
+      merge_deep(new_table, t)
+    end
+
+    return new_table
+  end,
+
+  -- map builds a new table by applying mapper to every value.
+  --
+  -- for lists, the function is expected to have the signature:
+  --   mapper(value: any, index: number): any
+  -- for maps, the function is expected to have the signature:
+  --   mapper(value: any, key: any): any
+  --
+  -- params:
+  --   t = the table to map
+  --   mapper = the function used to transform each value
+  --
+  -- returns:
+  --   a new table with the same keys and transformed values
+  map = function(t, mapper)
+    local new_table = {}
+    if is_list(t) then
+      for i, v in ipairs(t) do
+        new_table[i] = mapper(v, i)
+      end
+    else
+      for k, v in pairs(t) do
+        new_table[k] = mapper(v, k)
+      end
+    end
+
+    return new_table
+  end,
+
+  -- filter builds a new table of the values that pass the test, lists stay
+  -- lists (without holes) and maps keep their keys.
+  --
+  -- params:
+  --   t = the table to filter
+  --   test = function(value: any, key: any): boolean
+  --
+  -- returns:
+  --   a new table containing only the values test returned true for
+  filter = function(t, test)
+    local new_table = {}
+    if is_list(t) then
+      for i, v in ipairs(t) do
+        if test(v, i) then
+          table.insert(new_table, v)
+        end
+      end
+    else
+      for k, v in pairs(t) do
+        if test(v, k) then
+          new_table[k] = v
+        end
+      end
+    end
+
+    return new_table
+  end,
+
+  -- reduce combines the values of the table into a single value, lists are
+  -- reduced in order.
+  --
+  -- params:
+  --   t = the table to reduce
+  --   initial = the starting value passed to the first reducer call
+  --   reducer = function(acc: any, value: any, key: any): any
+  --
+  -- returns:
+  --   the final value returned from reducer
+  reduce = function(t, initial, reducer)
+    local acc = initial
+    if is_list(t) then
+      for i, v in ipairs(t) do
+        acc = reducer(acc, v, i)
+      end
+    else
+      for k, v in pairs(t) do
+        acc = reducer(acc, v, k)
+      end
+    end
+
+    return acc
+  end,
+
+  -- keys returns a list of all the keys in the table, in no specific order.
+  keys = function(t)
+    local list = {}
+    for k in pairs(t) do
+      table.insert(list, k)
+    end
+
+    return list
+  end,
+
+  -- values returns a list of all the values in the table, lists keep their
+  -- order while maps have no specific order.
+  values = function(t)
+    local list = {}
+    if is_list(t) then
+      for _, v in ipairs(t) do
+        table.insert(list, v)
+      end
+    else
+      for _, v in pairs(t) do
+        table.insert(list, v)
+      end
+    end
+
+    return list
+  end,
+
+  -- count returns the number of keys in the table, unlike # this works for
+  -- maps as well as lists.
+  count = function(t)
+    local n = 0
+    for _ in pairs(t) do
+      n = n + 1
+    end
+
+    return n
+  end,
+
+  -- contains determines if the value is in the list.
+  contains = function(list, value)
+    for _, v in ipairs(list) do
+      if v == value then
+        return true
+      end
+    end
+
+    return false
+  end,
+
+  -- unique returns a list of the values in the list with duplicates removed,
+  -- keeping the first occurrence of each value.
+  unique = function(list)
+    local seen, new_list = {}, {}
+    for _, v in ipairs(list) do
+      if not seen[v] then
+        seen[v] = true
+        table.insert(new_list, v)
+      end
+    end
+
+    return new_list
+  end,
+
+  -- union returns a list of the unique values found in either list, in the
+  -- order they first appear.
+  union = function(a, b)
+    local seen, new_list = {}, {}
+    for _, list in ipairs({a, b}) do
+      for _, v in ipairs(list) do
+        if not seen[v] then
+          seen[v] = true
+          table.insert(new_list, v)
+        end
+      end
+    end
+
+    return new_list
+  end,
+
+  -- intersection returns a list of the unique values found in both lists, in
+  -- the order they appear in a.
+  intersection = function(a, b)
+    local in_b, seen, new_list = to_set(b), {}, {}
+    for _, v in ipairs(a) do
+      if in_b[v] and not seen[v] then
+        seen[v] = true
+        table.insert(new_list, v)
+      end
+    end
+
+    return new_list
+  end,
+
+  -- difference returns a list of the unique values in a that are not in b,
+  -- in the order they appear in a.
+  difference = function(a, b)
+    local in_b, seen, new_list = to_set(b), {}, {}
+    for _, v in ipairs(a) do
+      if not in_b[v] and not seen[v] then
+        seen[v] = true
+        table.insert(new_list, v)
+      end
+    end
+
+    return new_list
+  end,
+
+  -- sort_by returns a new list sorted by the given key. The sort is stable,
+  -- values with equal keys keep their original order, so sorting by multiple
+  -- keys can be done by sorting by the least significant key first.
+  --
+  -- params:
+  --   list = the list to sort
+  --   key = the field name to sort by or function(value): any returning the
+  --         value to sort by
+  --   descending = true to sort from largest to smallest (default false)
+  --
+  -- returns:
+  --   a new sorted list
+  sort_by = function(list, key, descending)
+    local get = key_getter(key)
+    local less = function(a, b)
+      return get(a) < get(b)
+    end
+    if descending then
+      less = function(a, b)
+        return get(b) < get(a)
+      end
+    end
+
+    local sorted = {}
+    for i, v in ipairs(list) do
+      sorted[i] = v
+    end
+    merge_sort(sorted, 1, #sorted, less, {})
+
+    return sorted
+  end,
+}
+
+return tbl
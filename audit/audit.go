@@ -0,0 +1,285 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package audit records actions that need to be accounted for, such as admin
+// commands, permission changes, economy transactions and logins. Unlike the
+// application log, the audit log is never rotated, sampled or filtered by
+// level. Each record includes the hash of the one before it, so editing or
+// removing a record breaks the chain and is found by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/viper"
+)
+
+// Categories of audited actions, others may be used but these are the ones
+// the server records itself.
+const (
+	Admin      = "admin"
+	Permission = "permission"
+	Economy    = "economy"
+	Login      = "login"
+)
+
+// the longest record that can be read back, records are a single line
+const maxRecordSize = 1024 * 1024
+
+// Record is a single audited action.
+type Record struct {
+	// Seq is the position of the record in the log, starting from 1.
+	Seq uint64 `json:"seq"`
+	// Time the record was added, in UTC.
+	Time time.Time `json:"time"`
+	// Category is the kind of action, such as Admin or Economy.
+	Category string `json:"category"`
+	// Actor is who performed the action.
+	Actor string `json:"actor"`
+	// Action is what was done, like "ban" or "transfer".
+	Action string `json:"action"`
+	// Target is who or what the action was done to, if anything.
+	Target string `json:"target,omitempty"`
+	// Data holds any other details, it must be encodable as JSON.
+	Data map[string]interface{} `json:"data,omitempty"`
+	// PrevHash is the hash of the previous record, empty for the first.
+	PrevHash string `json:"prev_hash"`
+	// Hash covers every other field of the record.
+	Hash string `json:"hash"`
+}
+
+// hash computes the hash of the record, everything but the hash field is
+// included
+func (r Record) hash() (string, error) {
+	r.Hash = ""
+	contents, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(contents)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainError describes where the hash chain of a log is broken.
+type ChainError struct {
+	// Seq is the sequence of the first record that doesn't follow the chain,
+	// or the position of the line for lines that couldn't be read.
+	Seq    uint64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit log broken at record %d: %s", e.Seq, e.Reason)
+}
+
+// Log is an append-only audit log stored as a file of JSON records, one per
+// line.
+type Log struct {
+	path     string
+	file     *os.File
+	lastSeq  uint64
+	lastHash string
+	mutex    *sync.Mutex
+	now      func() time.Time
+}
+
+var (
+	globalLog  *Log
+	globalOnce sync.Once
+)
+
+// Global returns the audit log at the path given by the audit.path setting.
+// The chain is verified as it's opened and any break is logged as an error,
+// records are still added after the last one.
+func Global() *Log {
+	globalOnce.Do(func() {
+		log := logger.NewWithSource("audit")
+
+		var err error
+		globalLog, err = Open(viper.GetString("audit.path"))
+		if err != nil {
+			log.WithError(err).Fatal("Failed to open the audit log.")
+		}
+		if err := globalLog.Verify(); err != nil {
+			log.WithError(err).Error("The audit log has been tampered with.")
+		}
+	})
+
+	return globalLog
+}
+
+// Open opens the audit log at path for appending, creating it if it doesn't
+// exist.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		path:  path,
+		mutex: new(sync.Mutex),
+		now:   time.Now,
+	}
+
+	// new records follow the last one read, whether or not the chain before
+	// it is intact
+	err := l.each(func(r *Record) error {
+		l.lastSeq = r.Seq
+		l.lastHash = r.Hash
+
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*ChainError); !ok {
+			return nil, err
+		}
+	}
+
+	l.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (l *Log) SetClock(now func() time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.now = now
+}
+
+// Append adds the record to the log, filling in its sequence, time and
+// hashes. The record is synced to disk before Append returns.
+func (l *Log) Append(r Record) (*Record, error) {
+	if r.Category == "" || r.Action == "" {
+		return nil, fmt.Errorf("audit records require a category and an action")
+	}
+
+	// normalize the data to what it will be when read back, so the hash
+	// matches when it's verified
+	if len(r.Data) > 0 {
+		contents, err := json.Marshal(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("audit record data can't be saved: %s", err)
+		}
+		r.Data = nil
+		if err := json.Unmarshal(contents, &r.Data); err != nil {
+			return nil, err
+		}
+	} else {
+		r.Data = nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	r.Seq = l.lastSeq + 1
+	r.Time = l.now().UTC()
+	r.PrevHash = l.lastHash
+
+	var err error
+	if r.Hash, err = r.hash(); err != nil {
+		return nil, err
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	if err := l.file.Sync(); err != nil {
+		return nil, err
+	}
+
+	l.lastSeq = r.Seq
+	l.lastHash = r.Hash
+
+	return &r, nil
+}
+
+// Verify reads every record, checking that each follows the one before it.
+// A *ChainError is returned for the first record that doesn't.
+func (l *Log) Verify() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var (
+		seq  uint64
+		prev string
+	)
+
+	return l.each(func(r *Record) error {
+		seq++
+		if r.Seq != seq {
+			return &ChainError{Seq: r.Seq, Reason: fmt.Sprintf("expected record %d", seq)}
+		}
+		if r.PrevHash != prev {
+			return &ChainError{Seq: r.Seq, Reason: "previous hash doesn't match"}
+		}
+
+		hash, err := r.hash()
+		if err != nil {
+			return err
+		}
+		if hash != r.Hash {
+			return &ChainError{Seq: r.Seq, Reason: "contents don't match the hash"}
+		}
+		prev = r.Hash
+
+		return nil
+	})
+}
+
+// Close closes the file the log is written to.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.file.Close()
+}
+
+// each calls fn with every record in order, stopping at the first error.
+// Lines that can't be decoded are reported as a *ChainError. The mutex must be
+// held once the log is open so partially appended records aren't read.
+func (l *Log) each(fn func(*Record) error) error {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	var line uint64
+	for scanner.Scan() {
+		line++
+		r := new(Record)
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			return &ChainError{Seq: line, Reason: fmt.Sprintf("unreadable record: %s", err)}
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
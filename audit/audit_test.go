@@ -0,0 +1,156 @@
+package audit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/audit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit", func() {
+	var (
+		dir  string
+		path string
+		log  *audit.Log
+		now  time.Time
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "audit")
+		path = filepath.Join(dir, "audit.log")
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		var err error
+		log, err = audit.Open(path)
+		Ω(err).Should(BeNil())
+		log.SetClock(func() time.Time { return now })
+	})
+
+	AfterEach(func() {
+		log.Close()
+		os.RemoveAll(dir)
+	})
+
+	record := func(category, actor, action, target string) *audit.Record {
+		r, err := log.Append(audit.Record{
+			Category: category,
+			Actor:    actor,
+			Action:   action,
+			Target:   target,
+			Data:     map[string]interface{}{"reason": "testing", "amount": 10},
+		})
+		Ω(err).Should(BeNil())
+		now = now.Add(time.Minute)
+
+		return r
+	}
+
+	It("chains records together", func() {
+		first := record(audit.Admin, "alice", "ban", "mallory")
+		second := record(audit.Economy, "bob", "transfer", "carol")
+
+		Ω(first.Seq).Should(Equal(uint64(1)))
+		Ω(first.PrevHash).Should(BeEmpty())
+		Ω(second.Seq).Should(Equal(uint64(2)))
+		Ω(second.PrevHash).Should(Equal(first.Hash))
+		Ω(log.Verify()).Should(Succeed())
+	})
+
+	It("continues the chain when reopened", func() {
+		first := record(audit.Login, "alice", "login", "")
+		log.Close()
+
+		var err error
+		log, err = audit.Open(path)
+		Ω(err).Should(BeNil())
+		second := record(audit.Login, "alice", "logout", "")
+
+		Ω(second.Seq).Should(Equal(uint64(2)))
+		Ω(second.PrevHash).Should(Equal(first.Hash))
+		Ω(log.Verify()).Should(Succeed())
+	})
+
+	It("requires a category and action", func() {
+		_, err := log.Append(audit.Record{Actor: "alice"})
+
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	Describe("tampering", func() {
+		BeforeEach(func() {
+			record(audit.Admin, "alice", "ban", "mallory")
+			record(audit.Permission, "alice", "grant", "bob")
+			record(audit.Economy, "bob", "transfer", "carol")
+		})
+
+		rewrite := func(change func([]string) []string) {
+			contents, _ := ioutil.ReadFile(path)
+			lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+			lines = change(lines)
+			ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0640)
+		}
+
+		It("detects edited records", func() {
+			rewrite(func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"bob"`, `"mallory"`, 1)
+
+				return lines
+			})
+
+			err := log.Verify()
+			Ω(err).Should(BeAssignableToTypeOf(&audit.ChainError{}))
+			Ω(err.(*audit.ChainError).Seq).Should(Equal(uint64(2)))
+		})
+
+		It("detects removed records", func() {
+			rewrite(func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			})
+
+			err := log.Verify()
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.(*audit.ChainError).Seq).Should(Equal(uint64(3)))
+		})
+	})
+
+	Describe("queries", func() {
+		BeforeEach(func() {
+			record(audit.Admin, "alice", "ban", "mallory")
+			record(audit.Economy, "bob", "transfer", "carol")
+			record(audit.Economy, "alice", "transfer", "bob")
+			record(audit.Login, "carol", "login", "")
+		})
+
+		It("filters records", func() {
+			records, err := log.Query(audit.Filter{Category: audit.Economy, Actor: "alice"})
+
+			Ω(err).Should(BeNil())
+			Ω(records).Should(HaveLen(1))
+			Ω(records[0].Target).Should(Equal("bob"))
+			Ω(records[0].Data).Should(HaveKeyWithValue("amount", float64(10)))
+		})
+
+		It("limits records to a time range", func() {
+			start := time.Date(2017, time.January, 1, 0, 1, 0, 0, time.UTC)
+			records, err := log.Query(audit.Filter{Since: start, Until: start.Add(time.Minute)})
+
+			Ω(err).Should(BeNil())
+			Ω(records).Should(HaveLen(2))
+			Ω(records[0].Actor).Should(Equal("bob"))
+		})
+
+		It("keeps the most recent records within the limit", func() {
+			records, err := log.Query(audit.Filter{Limit: 2})
+
+			Ω(err).Should(BeNil())
+			Ω(records).Should(HaveLen(2))
+			Ω(records[1].Category).Should(Equal(audit.Login))
+		})
+	})
+})
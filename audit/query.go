@@ -0,0 +1,63 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package audit
+
+import "time"
+
+// Filter selects records from the log, empty fields match every record.
+type Filter struct {
+	Category string
+	Actor    string
+	Action   string
+	Target   string
+	// Since and Until limit records to those added in the range, inclusive.
+	Since time.Time
+	Until time.Time
+	// Limit keeps only the most recent matching records.
+	Limit int
+}
+
+// Matches determines if the record is selected by the filter.
+func (f Filter) Matches(r *Record) bool {
+	switch {
+	case f.Category != "" && r.Category != f.Category:
+		return false
+	case f.Actor != "" && r.Actor != f.Actor:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.Target != "" && r.Target != f.Target:
+		return false
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && r.Time.After(f.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// Query returns the records matching the filter, oldest first.
+func (l *Log) Query(f Filter) ([]*Record, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var records []*Record
+	err := l.each(func(r *Record) error {
+		if !f.Matches(r) {
+			return nil
+		}
+
+		records = append(records, r)
+		if f.Limit > 0 && len(records) > f.Limit {
+			records = records[1:]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
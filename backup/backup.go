@@ -0,0 +1,477 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package backup archives the game's data, like its database and area files,
+// into timestamped gzipped tar files and restores them. Every archive carries
+// a manifest with the size and SHA-256 of each file, which restoring checks
+// before anything is written, so a damaged archive never replaces good data.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/bbuck/dragon-mud/info"
+	"github.com/spf13/viper"
+)
+
+// ManifestName is the name of the manifest in archives.
+const ManifestName = "MANIFEST.json"
+
+// the layout of the time in the names of archives, which sort by time
+const stampLayout = "20060102-150405"
+
+// archiveName matches the names of archives in the backup directory
+var archiveName = regexp.MustCompile(`^dragon-\d{8}-\d{6}\.tar\.gz$`)
+
+// File describes a file in an archive.
+type File struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files of an archive.
+type Manifest struct {
+	Created time.Time `json:"created"`
+	// Version of the server that made the archive.
+	Version string `json:"version"`
+	// Files by their slash separated path, relative to the game.
+	Files map[string]File `json:"files"`
+}
+
+// Snapshot writes a consistent copy of a database to the path, like
+// data.Snapshotter does.
+type Snapshot func(path string) error
+
+// Source is what's archived.
+type Source struct {
+	// Paths are the files and directories archived as they are, relative to
+	// the game. Missing paths are skipped.
+	Paths []string
+	// Databases are database files archived from a copy their snapshot makes,
+	// rather than read while they may be written. They're skipped if they're
+	// also under Paths.
+	Databases map[string]Snapshot
+}
+
+// VerifyError is returned when an archive doesn't match its manifest.
+type VerifyError struct {
+	Archive  string
+	Problems []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s is damaged: %s", e.Archive, strings.Join(e.Problems, "; "))
+}
+
+// entryName returns the slash separated name the path is archived under,
+// paths must be relative and stay inside the game
+func entryName(p string) (string, error) {
+	name := filepath.ToSlash(filepath.Clean(p))
+	if filepath.IsAbs(p) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%s isn't inside the game, backups only take relative paths", p)
+	}
+
+	return name, nil
+}
+
+// Create archives the source to a new file in the directory named with the
+// time, like dragon-20170102-150405.tar.gz, returning its path. The archive
+// is written to a temporary file first, so a failed backup leaves nothing
+// behind.
+func Create(dir string, src Source) (string, *Manifest, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	out := filepath.Join(dir, "dragon-"+now.Format(stampLayout)+".tar.gz")
+
+	tmp, err := ioutil.TempFile(dir, ".backup")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	m := &Manifest{Created: now, Version: info.Version.String(), Files: make(map[string]File)}
+	if err := write(tmp, src, m, filepath.Clean(dir)); err != nil {
+		return "", nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(tmp.Name(), out); err != nil {
+		return "", nil, err
+	}
+
+	return out, m, nil
+}
+
+// write archives the source to w, filling in the manifest, the directory
+// archives are kept in is skipped
+func write(w io.Writer, src Source, m *Manifest, skip string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	databases := make(map[string]bool)
+	for p := range src.Databases {
+		name, err := entryName(p)
+		if err != nil {
+			return err
+		}
+		databases[name] = true
+	}
+
+	for _, root := range src.Paths {
+		if _, err := entryName(root); err != nil {
+			return err
+		}
+		err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if os.IsNotExist(err) && p == root {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() && filepath.Clean(p) == skip {
+				return filepath.SkipDir
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			name, err := entryName(p)
+			if err != nil {
+				return err
+			}
+			if isDatabase(databases, name) {
+				return nil
+			}
+			if _, ok := m.Files[name]; ok {
+				return nil
+			}
+
+			return addFile(tw, name, p, m)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := addDatabases(tw, src.Databases, m); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: ManifestName, Mode: 0600, Size: int64(len(contents)), ModTime: m.Created}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(contents); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// isDatabase returns true if the file is one of the databases or one of
+// their sqlite journals, which are snapshotted rather than read
+func isDatabase(databases map[string]bool, name string) bool {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if strings.HasSuffix(name, suffix) && databases[strings.TrimSuffix(name, suffix)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addDatabases archives a snapshot of each database under its name
+func addDatabases(tw *tar.Writer, databases map[string]Snapshot, m *Manifest) error {
+	if len(databases) == 0 {
+		return nil
+	}
+	dir, err := ioutil.TempDir("", "dragon-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	paths := make([]string, 0, len(databases))
+	for p := range databases {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for i, p := range paths {
+		snap := filepath.Join(dir, fmt.Sprintf("%d.db", i))
+		if err := databases[p](snap); err != nil {
+			return fmt.Errorf("failed to snapshot %s: %s", p, err)
+		}
+		name, _ := entryName(p)
+		if err := addFile(tw, name, snap, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addFile archives the file at the path under the name, recording it in the
+// manifest
+func addFile(tw *tar.Writer, name, p string, m *Manifest) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: int64(fi.Mode().Perm()), Size: fi.Size(), ModTime: fi.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	sum := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, sum), f)
+	if err != nil {
+		return err
+	}
+	m.Files[name] = File{Size: n, SHA256: hex.EncodeToString(sum.Sum(nil))}
+
+	return nil
+}
+
+// each calls fn with the header and contents of every file in the archive
+func each(archive string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return &VerifyError{Archive: archive, Problems: []string{err.Error()}}
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &VerifyError{Archive: archive, Problems: []string{err.Error()}}
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreName returns the slash separated name a file of an archive is
+// restored to, or false if it's outside the game
+func restoreName(name string) (string, bool) {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// Verify checks every file of the archive matches its manifest, returning
+// the manifest or a VerifyError listing the problems.
+func Verify(archive string) (*Manifest, error) {
+	var (
+		m    *Manifest
+		sums = make(map[string]File)
+		verr = &VerifyError{Archive: archive}
+	)
+	err := each(archive, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == ManifestName {
+			m = new(Manifest)
+			if err := json.NewDecoder(r).Decode(m); err != nil {
+				verr.Problems = append(verr.Problems, "the manifest can't be read: "+err.Error())
+			}
+
+			return nil
+		}
+		name, ok := restoreName(hdr.Name)
+		if !ok {
+			verr.Problems = append(verr.Problems, hdr.Name+" is outside the game")
+
+			return nil
+		}
+		sum := sha256.New()
+		n, err := io.Copy(sum, r)
+		if err != nil {
+			return &VerifyError{Archive: archive, Problems: []string{fmt.Sprintf("%s: %s", hdr.Name, err)}}
+		}
+		sums[name] = File{Size: n, SHA256: hex.EncodeToString(sum.Sum(nil))}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		verr.Problems = append(verr.Problems, "it has no manifest")
+
+		return nil, verr
+	}
+
+	for name, want := range m.Files {
+		got, ok := sums[name]
+		switch {
+		case !ok:
+			verr.Problems = append(verr.Problems, name+" is missing")
+		case got != want:
+			verr.Problems = append(verr.Problems, name+" doesn't match its checksum")
+		}
+		delete(sums, name)
+	}
+	for name := range sums {
+		verr.Problems = append(verr.Problems, name+" isn't in the manifest")
+	}
+	if len(verr.Problems) > 0 {
+		sort.Strings(verr.Problems)
+
+		return nil, verr
+	}
+
+	return m, nil
+}
+
+// Restore verifies the archive and then writes its files under the
+// directory, replacing those that are there. Nothing is written if the
+// archive doesn't verify. Files that aren't in the archive are left alone.
+func Restore(archive, dir string) (*Manifest, error) {
+	m, err := Verify(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	err = each(archive, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == ManifestName {
+			return nil
+		}
+		name, _ := restoreName(hdr.Name)
+		contents, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		if mode == 0 {
+			mode = 0600
+		}
+
+		return fs.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), contents, mode)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Configured returns the source the settings give, the paths of the
+// backup.paths setting and the world directory, along with the game's
+// storage when it's kept in a database file.
+func Configured() Source {
+	src := Source{
+		Paths: append(viper.GetStringSlice("backup.paths"), viper.GetString("world.dir")),
+	}
+	switch viper.GetString("storage.driver") {
+	case data.SQLite, data.Bolt:
+		if s, ok := data.Storage().(data.Snapshotter); ok {
+			src.Databases = map[string]Snapshot{viper.GetString("storage.source"): s.Snapshot}
+		}
+	}
+
+	return src
+}
+
+// Run backs up the configured source to the directory given by the
+// backup.dir setting, then removes the oldest archives beyond the number the
+// backup.keep setting keeps. The path of the new archive is returned along
+// with those removed.
+func Run() (string, []string, error) {
+	dir := viper.GetString("backup.dir")
+	archive, _, err := Create(dir, Configured())
+	if err != nil {
+		return "", nil, err
+	}
+	removed, err := Prune(dir, viper.GetInt("backup.keep"))
+
+	return archive, removed, err
+}
+
+// List returns the paths of the archives in the directory, newest first.
+func List(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []string
+	for _, fi := range files {
+		if !fi.IsDir() && archiveName.MatchString(fi.Name()) {
+			archives = append(archives, filepath.Join(dir, fi.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+
+	return archives, nil
+}
+
+// Prune removes all but the newest archives in the directory, keeping the
+// number given, and returns the paths of those removed. Archives are all
+// kept if keep is zero or less.
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	archives, err := List(dir)
+	if err != nil || len(archives) <= keep {
+		return nil, err
+	}
+
+	var removed []string
+	for _, archive := range archives[keep:] {
+		if err := os.Remove(archive); err != nil {
+			return removed, err
+		}
+		removed = append(removed, archive)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,149 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/backup"
+	"github.com/bbuck/dragon-mud/data"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeFiles writes the files, by path, under the directory
+func writeFiles(dir string, files map[string]string) {
+	for name, contents := range files {
+		p := filepath.Join(dir, name)
+		Ω(os.MkdirAll(filepath.Dir(p), 0700)).Should(Succeed())
+		Ω(ioutil.WriteFile(p, []byte(contents), 0600)).Should(Succeed())
+	}
+}
+
+var _ = Describe("Backup", func() {
+	var (
+		root, wd string
+		store    data.Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		wd, err = os.Getwd()
+		Ω(err).ShouldNot(HaveOccurred())
+		root, err = ioutil.TempDir("", "backup")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.Chdir(root)).Should(Succeed())
+
+		writeFiles(root, map[string]string{
+			"data/players/ann.json": `{"name": "Ann"}`,
+			"data/dragon.db-wal":    "journal",
+			"areas/town.yml":        "zone: town",
+		})
+		store, err = data.OpenBolt("data/dragon.db")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(store.Put("players", "ann", []byte("saved"))).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		store.Close()
+		os.Chdir(wd)
+		os.RemoveAll(root)
+	})
+
+	create := func() (string, *Manifest) {
+		archive, m, err := Create("data/backups", Source{
+			Paths:     []string{"data", "areas", "missing"},
+			Databases: map[string]Snapshot{"data/dragon.db": store.(data.Snapshotter).Snapshot},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return archive, m
+	}
+
+	It("archives the files and a snapshot of the databases", func() {
+		archive, m := create()
+		Ω(filepath.Base(archive)).Should(MatchRegexp(`^dragon-\d{8}-\d{6}\.tar\.gz$`))
+		Ω(m.Files).Should(HaveLen(3))
+		Ω(m.Files).Should(HaveKey("data/players/ann.json"))
+		Ω(m.Files).Should(HaveKey("data/dragon.db"))
+		Ω(m.Files).ShouldNot(HaveKey("data/dragon.db-wal"))
+
+		verified, err := Verify(archive)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(verified.Files).Should(Equal(m.Files))
+		_, _, err = Create("backups", Source{Paths: []string{"../elsewhere"}})
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("restores archives", func() {
+		archive, _ := create()
+		out, err := ioutil.TempDir("", "restore")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(out)
+
+		_, err = Restore(archive, out)
+		Ω(err).ShouldNot(HaveOccurred())
+		contents, err := ioutil.ReadFile(filepath.Join(out, "areas", "town.yml"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(contents)).Should(Equal("zone: town"))
+
+		restored, err := data.OpenBolt(filepath.Join(out, "data", "dragon.db"))
+		Ω(err).ShouldNot(HaveOccurred())
+		defer restored.Close()
+		Ω(restored.Get("players", "ann")).Should(Equal([]byte("saved")))
+	})
+
+	It("refuses to restore damaged archives", func() {
+		_, m := create()
+		damaged := filepath.Join(root, "damaged.tar.gz")
+		f, err := os.Create(damaged)
+		Ω(err).ShouldNot(HaveOccurred())
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		files := map[string][]byte{"areas/town.yml": []byte("zone: ruins")}
+		files[ManifestName], err = json.Marshal(m)
+		Ω(err).ShouldNot(HaveOccurred())
+		for name, contents := range files {
+			Ω(tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents))})).Should(Succeed())
+			tw.Write(contents)
+		}
+		tw.Close()
+		gz.Close()
+		f.Close()
+
+		_, err = Restore(damaged, root)
+		Ω(err).Should(BeAssignableToTypeOf(&VerifyError{}))
+		Ω(err.(*VerifyError).Problems).Should(Equal([]string{
+			"areas/town.yml doesn't match its checksum",
+			"data/dragon.db is missing",
+			"data/players/ann.json is missing",
+		}))
+		contents, err := ioutil.ReadFile(filepath.Join(root, "areas", "town.yml"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(contents)).Should(Equal("zone: town"))
+	})
+
+	It("keeps only the newest archives", func() {
+		dir := filepath.Join(root, "backups")
+		writeFiles(dir, map[string]string{
+			"dragon-20170101-040000.tar.gz": "",
+			"dragon-20170102-040000.tar.gz": "",
+			"dragon-20170103-040000.tar.gz": "",
+			"notes.txt":                     "",
+		})
+
+		removed, err := Prune(dir, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(removed).Should(Equal([]string{filepath.Join(dir, "dragon-20170101-040000.tar.gz")}))
+		archives, err := List(dir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(archives).Should(Equal([]string{
+			filepath.Join(dir, "dragon-20170103-040000.tar.gz"),
+			filepath.Join(dir, "dragon-20170102-040000.tar.gz"),
+		}))
+	})
+})
@@ -0,0 +1,208 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package cache provides a bounded, expiring key/value store that is safe to
+// use from any number of goroutines.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// an entry in the cache, stored in the recency list
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Stats reports how the cache has been used.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// Cache is a least recently used cache where entries may also expire. When
+// the cache is full the least recently used entry is evicted to make room.
+type Cache struct {
+	MaxEntries int
+	DefaultTTL time.Duration
+
+	entries   map[string]*list.Element
+	recency   *list.List
+	mutex     *sync.Mutex
+	now       func() time.Time
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// New creates a cache holding at most maxEntries (0 is unbounded) where
+// entries expire after defaultTTL unless given their own TTL (0 never
+// expires).
+func New(maxEntries int, defaultTTL time.Duration) *Cache {
+	return &Cache{
+		MaxEntries: maxEntries,
+		DefaultTTL: defaultTTL,
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
+		mutex:      new(sync.Mutex),
+		now:        time.Now,
+	}
+}
+
+var (
+	globalCache *Cache
+	globalOnce  sync.Once
+)
+
+// Global returns the cache shared by the game, configured from
+// "cache.max_entries" and "cache.default_ttl".
+func Global() *Cache {
+	globalOnce.Do(func() {
+		globalCache = New(viper.GetInt("cache.max_entries"), viper.GetDuration("cache.default_ttl"))
+	})
+
+	return globalCache
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (c *Cache) SetClock(now func() time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = now
+}
+
+// Set stores the value under the key using the default TTL.
+func (c *Cache) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.DefaultTTL)
+}
+
+// SetWithTTL stores the value under the key, expiring after ttl (0 never
+// expires).
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		c.recency.MoveToFront(el)
+
+		return
+	}
+
+	c.entries[key] = c.recency.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.MaxEntries > 0 && c.recency.Len() > c.MaxEntries {
+		c.removeElement(c.recency.Back())
+		c.evictions++
+	}
+}
+
+// Get fetches the value for the key, the second return is false if the key
+// is missing or has expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if c.expired(e) {
+		c.removeElement(el)
+		c.misses++
+
+		return nil, false
+	}
+
+	c.recency.MoveToFront(el)
+	c.hits++
+
+	return e.value, true
+}
+
+// Delete removes the key from the cache.
+func (c *Cache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Clear removes every entry from the cache.
+func (c *Cache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.recency.Init()
+}
+
+// Prune removes all expired entries and returns how many were removed.
+func (c *Cache) Prune() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for el := c.recency.Front(); el != nil; {
+		next := el.Next()
+		if c.expired(el.Value.(*entry)) {
+			c.removeElement(el)
+			removed++
+		}
+		el = next
+	}
+
+	return removed
+}
+
+// Len returns the number of entries in the cache, including any that have
+// expired but not yet been removed.
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.recency.Len()
+}
+
+// Stats returns the usage statistics of the cache.
+func (c *Cache) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return Stats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Size:      c.recency.Len(),
+	}
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return !e.expires.IsZero() && !c.now().Before(e.expires)
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	c.recency.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
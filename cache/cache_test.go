@@ -0,0 +1,77 @@
+package cache_test
+
+import (
+	"time"
+
+	. "github.com/bbuck/dragon-mud/cache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		c   *Cache
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		c = New(2, time.Minute)
+		c.SetClock(func() time.Time {
+			return now
+		})
+	})
+
+	It("stores and fetches values", func() {
+		c.Set("a", 1)
+		val, ok := c.Get("a")
+
+		Ω(ok).Should(BeTrue())
+		Ω(val).Should(Equal(1))
+	})
+
+	It("expires values", func() {
+		c.Set("a", 1)
+		c.SetWithTTL("b", 2, 0)
+		now = now.Add(time.Minute)
+
+		_, ok := c.Get("a")
+		Ω(ok).Should(BeFalse())
+		_, ok = c.Get("b")
+		Ω(ok).Should(BeTrue())
+	})
+
+	It("evicts the least recently used entry", func() {
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Get("a")
+		c.Set("c", 3)
+
+		_, ok := c.Get("b")
+		Ω(ok).Should(BeFalse())
+		_, ok = c.Get("a")
+		Ω(ok).Should(BeTrue())
+		Ω(c.Stats().Evictions).Should(Equal(uint64(1)))
+	})
+
+	It("prunes expired entries", func() {
+		c.SetWithTTL("a", 1, time.Second)
+		c.SetWithTTL("b", 2, time.Hour)
+		now = now.Add(time.Minute)
+
+		Ω(c.Prune()).Should(Equal(1))
+		Ω(c.Len()).Should(Equal(1))
+	})
+
+	It("tracks hits and misses", func() {
+		c.Set("a", 1)
+		c.Get("a")
+		c.Get("b")
+
+		stats := c.Stats()
+		Ω(stats.Hits).Should(Equal(uint64(1)))
+		Ω(stats.Misses).Should(Equal(uint64(1)))
+		Ω(stats.Size).Should(Equal(1))
+	})
+})
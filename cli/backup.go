@@ -0,0 +1,116 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/backup"
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	restoreDir string
+	backupCmd  = &cobra.Command{
+		Use:   "backup",
+		Short: "Archive the storage and area files of the game.",
+		Long: `Write a timestamped archive of the game's data, the storage, the area files
+and the paths the backup settings name, to the backup directory. The oldest
+archives beyond the number the settings keep are removed. SQLite databases are
+copied consistently while the server uses them, but a bolt database can only
+be opened by one process so the server has to be stopped first. While it runs
+the server backs up on its own schedule (backup.schedule).`,
+		Run: func(*cobra.Command, []string) {
+			log := logger.NewWithSource("cmd(backup)")
+
+			// only one process can open a bolt database, it fails here
+			// rather than wherever the storage is first used
+			if viper.GetString("storage.driver") == data.Bolt {
+				store, err := data.Open(data.Bolt, viper.GetString("storage.source"))
+				if err != nil {
+					log.WithError(err).Fatal("Failed to open the bolt database, stop the server before backing it up.")
+				}
+				data.SetStorage(store)
+				defer store.Close()
+			}
+
+			archive, removed, err := backup.Run()
+			if err != nil {
+				log.WithError(err).Fatal("Failed to back up the game.")
+			}
+			log.WithField("archive", archive).Info("Backed up the game.")
+			for _, old := range removed {
+				log.WithField("archive", old).Info("Removed an old backup.")
+			}
+		},
+	}
+	backupListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the archives in the backup directory, newest first.",
+		Run: func(*cobra.Command, []string) {
+			log := logger.NewWithSource("cmd(backup)")
+
+			archives, err := backup.List(viper.GetString("backup.dir"))
+			if err != nil {
+				log.WithError(err).Fatal("Failed to list the backups.")
+			}
+			for _, archive := range archives {
+				output.Stdout().PlainPrintln(filepath.Base(archive))
+			}
+		},
+	}
+	backupVerifyCmd = &cobra.Command{
+		Use:   "verify [archives...]",
+		Short: "Check archives match the checksums of their manifests.",
+		Run: func(_ *cobra.Command, args []string) {
+			log := logger.NewWithSource("cmd(backup)")
+
+			for _, archive := range args {
+				m, err := backup.Verify(archive)
+				if err != nil {
+					log.WithError(err).Error("The backup is damaged.")
+
+					continue
+				}
+				log.WithFields(logger.Fields{
+					"archive": archive,
+					"files":   len(m.Files),
+					"created": m.Created,
+				}).Info("The backup is intact.")
+			}
+		},
+	}
+	restoreCmd = &cobra.Command{
+		Use:   "restore archive",
+		Short: "Restore the game's data from a backup.",
+		Long: `Check every file of the archive against its manifest and then write them
+back, replacing the files that are there. Nothing is written if the archive is
+damaged. Stop the server before restoring, it would otherwise save over what's
+restored.`,
+		Run: func(_ *cobra.Command, args []string) {
+			if len(args) != 1 {
+				logger.NewWithSource("cmd(restore)").Fatal("Give the archive to restore.")
+			}
+			log := logger.NewWithSource("cmd(restore)").WithField("archive", args[0])
+
+			m, err := backup.Restore(args[0], restoreDir)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to restore the backup.")
+			}
+			log.WithFields(logger.Fields{
+				"files":   len(m.Files),
+				"created": m.Created,
+			}).Info("Restored the backup.")
+		},
+	}
+)
+
+func init() {
+	restoreCmd.Flags().StringVarP(&restoreDir, "dir", "d", ".", "Restore the files under this directory instead of the game's")
+	backupCmd.AddCommand(backupListCmd, backupVerifyCmd)
+	RootCmd.AddCommand(backupCmd, restoreCmd)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/game/world/diku"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	importPrefix string
+	importDir    string
+	importCmd    = &cobra.Command{
+		Use:   "import [files...]",
+		Short: "Convert ROM, Merc and Diku .are area files into DragonMUD areas.",
+		Long: `Convert the area files of Diku derived MUDs into DragonMUD's YAML area
+files, written to the world directory. Rooms, mobiles, objects and resets are
+converted and anything that can't be is listed so it can be ported by hand.
+Areas that link to each other should be imported together with the same
+prefix.`,
+		Run: func(_ *cobra.Command, args []string) {
+			log := logger.NewWithSource("cmd(import)")
+
+			dir := importDir
+			if dir == "" {
+				dir = viper.GetString("world.dir")
+			}
+			for _, path := range args {
+				flog := log.WithField("file", path)
+				a, warnings, err := diku.ImportFile(path, diku.Options{Prefix: importPrefix})
+				for _, warning := range warnings {
+					flog.Warn(warning)
+				}
+				if err != nil {
+					flog.WithError(err).Error("Failed to import the area.")
+
+					continue
+				}
+
+				out := filepath.Join(dir, a.Zone.ID+".yml")
+				if err := world.WriteArea(out, a); err != nil {
+					flog.WithError(err).Error("Failed to write the area.")
+
+					continue
+				}
+				flog.WithFields(logger.Fields{
+					"area":  out,
+					"rooms": len(a.Rooms),
+					"npcs":  len(a.NPCs),
+					"items": len(a.Items),
+				}).Info("Imported the area.")
+			}
+		},
+	}
+)
+
+func init() {
+	importCmd.Flags().StringVarP(&importPrefix, "prefix", "p", "", "Put before vnums to make the ids of rooms, NPCs and items")
+	importCmd.Flags().StringVarP(&importDir, "dir", "d", "", "Write the areas to this directory instead of the world directory")
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package cli
+
+import (
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/data/migrate"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/output"
+	"github.com/bbuck/dragon-mud/plugins"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateNamespace string
+	migrateSteps     int
+	migrateCmd       = &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply the pending migrations to the storage.",
+		Long: `Bring the storage up to date by applying the migrations of the game and its
+plugins that haven't been applied yet. Migrations are SQL files in the
+migrations directory of the game and of each plugin, each plugin's in a
+namespace named after it, and are applied from the lowest version to the
+highest.`,
+		Run: func(*cobra.Command, []string) {
+			log := migrateLog()
+
+			done, err := migrate.Global().Up(data.Storage(), migrateNamespace)
+			for _, mig := range done {
+				log.WithField("migration", mig.String()).Info("Applied the migration.")
+			}
+			if err != nil {
+				log.WithError(err).Fatal("Failed to apply the migrations.")
+			}
+			if len(done) == 0 {
+				log.Info("The storage is up to date.")
+			}
+		},
+	}
+	migrateDownCmd = &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recent migrations of a namespace.",
+		Long: `Undo the migrations of the namespace applied most recently, from the highest
+version to the lowest. Migrations without a down file can't be undone.`,
+		Run: func(*cobra.Command, []string) {
+			log := migrateLog()
+			if migrateNamespace == "" {
+				log.Fatal("Give the namespace of the migrations to revert with --namespace.")
+			}
+
+			done, err := migrate.Global().Down(data.Storage(), migrateNamespace, migrateSteps)
+			for _, mig := range done {
+				log.WithField("migration", mig.String()).Info("Reverted the migration.")
+			}
+			if err != nil {
+				log.WithError(err).Fatal("Failed to revert the migrations.")
+			}
+		},
+	}
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they've been applied.",
+		Run: func(*cobra.Command, []string) {
+			log := migrateLog()
+
+			list, err := migrate.Global().Status(data.Storage())
+			if err != nil {
+				log.WithError(err).Fatal("Failed to read the migrations.")
+			}
+			for _, st := range list {
+				if migrateNamespace != "" && st.Namespace != migrateNamespace {
+					continue
+				}
+				switch {
+				case st.Unknown:
+					output.Stdout().Printf("[R]unknown[x]  %s, applied %s\n", st, st.Applied.Format("2006-01-02 15:04"))
+				case st.Pending():
+					output.Stdout().Printf("[Y]pending[x]  %s\n", st)
+				default:
+					output.Stdout().Printf("[G]applied[x]  %s, %s\n", st, st.Applied.Format("2006-01-02 15:04"))
+				}
+			}
+		},
+	}
+)
+
+// migrateLog loads the migrations of the game and its plugins, returning the
+// log of the migrate commands
+func migrateLog() logger.Log {
+	log := logger.NewWithSource("cmd(migrate)")
+	if err := plugins.LoadMigrations(); err != nil {
+		log.WithError(err).Fatal("Failed to load the migrations.")
+	}
+
+	return log
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVarP(&migrateNamespace, "namespace", "n", "", "Only migrate this namespace, like \"game\" or the name of a plugin")
+	migrateDownCmd.Flags().IntVarP(&migrateSteps, "steps", "s", 1, "The number of migrations to revert")
+	migrateCmd.AddCommand(migrateDownCmd, migrateStatusCmd)
+	RootCmd.AddCommand(migrateCmd)
+}
@@ -23,7 +23,9 @@ func main() {
 	cmdEngine := getCommandEngine()
 	defer cmdEngine.Close()
 
-	if err := cli.RootCmd.Execute(); err != nil {
+	err := cli.RootCmd.Execute()
+	logger.Close()
+	if err != nil {
 		os.Exit(errs.ErrGeneral)
 	}
 }
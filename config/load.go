@@ -44,6 +44,240 @@ func RegisterDefaults() {
 
 	viper.SetDefault("env", "development")
 
+	// telnet defaults, MCCP compression, GMCP and MSDP are offered to every
+	// client
+	viper.SetDefault("telnet.mccp", true)
+	viper.SetDefault("telnet.gmcp", true)
+	viper.SetDefault("telnet.msdp", true)
+	viper.SetDefault("telnet.charset", "UTF-8")
+
+	// telnet TLS defaults, TLS is disabled unless a port is given
+	viper.SetDefault("telnet.tls.port", "")
+	viper.SetDefault("telnet.tls.autocert.cache_dir", "data/autocert")
+
+	// websocket defaults, an empty address doesn't accept WebSocket clients
+	viper.SetDefault("websocket.address", "")
+	viper.SetDefault("websocket.path", "/ws")
+
+	// session defaults
+	viper.SetDefault("session.idle_timeout", "30m")
+	viper.SetDefault("session.link_dead_timeout", "5m")
+	viper.SetDefault("session.output_delay", "10ms")
+	viper.SetDefault("session.page_length", 24)
+
+	// input defaults
+	viper.SetDefault("input.pulse", "250ms")
+	viper.SetDefault("input.queue_limit", 20)
+
+	// account defaults
+	viper.SetDefault("account.store", "files")
+	viper.SetDefault("account.dir", "data/accounts")
+	viper.SetDefault("account.min_password_length", 8)
+	viper.SetDefault("account.max_login_attempts", 3)
+	viper.SetDefault("account.hash.time", 1)
+	viper.SetDefault("account.hash.memory", 64*1024)
+	viper.SetDefault("account.hash.threads", 2)
+
+	// character defaults
+	viper.SetDefault("character.store", "files")
+	viper.SetDefault("character.dir", "data/characters")
+	viper.SetDefault("character.stats", []string{"str", "int", "wis", "dex", "con", "cha"})
+	viper.SetDefault("character.roll", "4d6")
+	viper.SetDefault("character.roll_keep", 3)
+	viper.SetDefault("character.reserved_names", []string{"all", "self", "someone", "admin", "immortal", "god"})
+	viper.SetDefault("character.banned_words", []string{})
+
+	// player defaults
+	viper.SetDefault("player.store", "files")
+	viper.SetDefault("player.dir", "data/players")
+	viper.SetDefault("player.carry_weight", 100)
+
+	// world defaults
+	viper.SetDefault("world.dir", "areas")
+	viper.SetDefault("world.start_room", "")
+
+	// equipment defaults
+	viper.SetDefault("equipment.dir", "bodies")
+
+	// mob defaults
+	viper.SetDefault("mob.reset", "@every 15m")
+
+	// ai defaults
+	viper.SetDefault("ai.dir", "behaviors")
+	viper.SetDefault("ai.pulse", "2s")
+
+	// combat defaults
+	viper.SetDefault("combat.pulse", "3s")
+	viper.SetDefault("combat.unarmed", "1d4")
+
+	// pvp defaults
+	viper.SetDefault("pvp.dir", "pvp")
+	viper.SetDefault("pvp.default", "consent")
+	viper.SetDefault("pvp.cooldown", "5m")
+
+	// skill defaults
+	viper.SetDefault("skill.dir", "skills")
+	viper.SetDefault("skill.adept", 75)
+
+	// effect defaults
+	viper.SetDefault("effect.pulse", "6s")
+
+	// quest defaults
+	viper.SetDefault("quest.dir", "quests")
+
+	// dialogue defaults
+	viper.SetDefault("dialogue.dir", "dialogues")
+
+	// shop defaults
+	viper.SetDefault("shop.dir", "shops")
+	viper.SetDefault("shop.stat", "coins")
+	viper.SetDefault("shop.denominations", []string{"gold:100", "silver:10", "copper:1"})
+
+	// crafting defaults
+	viper.SetDefault("craft.dir", "recipes")
+	viper.SetDefault("craft.pulse", "3s")
+
+	// loot defaults
+	viper.SetDefault("loot.dir", "loot")
+	viper.SetDefault("loot.rule", "round_robin")
+
+	// channel defaults
+	viper.SetDefault("channels.dir", "channels")
+
+	// social defaults
+	viper.SetDefault("social.dir", "socials")
+	viper.SetDefault("social.file", "data/socials.yml")
+
+	// mail defaults
+	viper.SetDefault("mail.dir", "data/mail")
+
+	// board defaults
+	viper.SetDefault("board.dir", "boards")
+	viper.SetDefault("board.posts", "data/boards")
+
+	// presence defaults
+	viper.SetDefault("presence.dir", "data/logins")
+	viper.SetDefault("presence.history", 20)
+
+	// help defaults
+	viper.SetDefault("help.dir", "help")
+	viper.SetDefault("help.file", "data/help.yml")
+
+	// perm defaults
+	viper.SetDefault("perm.file", "data/staff.yml")
+
+	// ban defaults
+	viper.SetDefault("ban.file", "data/bans.json")
+
+	// clan defaults
+	viper.SetDefault("clan.file", "data/clans.yml")
+
+	// instance defaults
+	viper.SetDefault("instance.dir", "instances")
+	viper.SetDefault("instance.pulse", "10s")
+
+	// trigger defaults
+	viper.SetDefault("trigger.dir", "triggers")
+	viper.SetDefault("trigger.pulse", "5s")
+
+	// procgen defaults
+	viper.SetDefault("procgen.dir", "dungeons")
+
+	// group defaults
+	viper.SetDefault("group.max_size", 8)
+	viper.SetDefault("group.experience", "xp")
+
+	// advance defaults
+	viper.SetDefault("advance.file", "advancement.yml")
+
+	// vision defaults
+	viper.SetDefault("vision.day", 0)
+	viper.SetDefault("vision.night", -2)
+
+	// language defaults
+	viper.SetDefault("language.dir", "languages")
+	viper.SetDefault("language.default", "common")
+	viper.SetDefault("language.gain", 10)
+
+	// map defaults
+	viper.SetDefault("map.radius", 3)
+	viper.SetDefault("map.terrain", []string{"water:~", "water_noswim:~", "forest:*", "mountain:^", "road:="})
+
+	// death defaults
+	viper.SetDefault("death.policy", "recall")
+	viper.SetDefault("death.recall_room", "")
+	viper.SetDefault("death.corpse_decay", "5m")
+	viper.SetDefault("death.player_corpse_decay", "30m")
+	viper.SetDefault("death.xp_loss", 10)
+	viper.SetDefault("death.respawn_hp", 10)
+	viper.SetDefault("death.pulse", "10s")
+
+	// prompt defaults
+	viper.SetDefault("prompt.default", "%h/%H hp %m/%M mana> ")
+
+	// game clock defaults
+	viper.SetDefault("clock.epoch", "2017-01-01T00:00:00Z")
+	viper.SetDefault("clock.hour_length", "2m")
+	viper.SetDefault("clock.ratio", 0)
+	viper.SetDefault("clock.calendar", "")
+	viper.SetDefault("clock.pulse", "1s")
+
+	// weather defaults
+	viper.SetDefault("weather.dir", "weather")
+	viper.SetDefault("weather.climate", "temperate")
+
+	// localization defaults
+	viper.SetDefault("i18n.default_locale", "en")
+
+	// cache defaults
+	viper.SetDefault("cache.max_entries", 10000)
+	viper.SetDefault("cache.default_ttl", "5m")
+
+	// log defaults
+	viper.SetDefault("log.ring_size", 200)
+	viper.SetDefault("log.error_events", false)
+	viper.SetDefault("log.buffer_size", 1024)
+	viper.SetDefault("log.overflow", "drop")
+	viper.SetDefault("log.sample_window", "10s")
+	viper.SetDefault("log.sample_every", 100)
+
+	// audit defaults
+	viper.SetDefault("audit.path", "data/audit.log")
+
+	// metrics defaults, an empty address doesn't serve metrics
+	viper.SetDefault("metrics.address", "")
+	viper.SetDefault("metrics.admin_token", "")
+
+	// queue defaults
+	viper.SetDefault("queue.dir", "data/queue")
+	viper.SetDefault("queue.retry_delay", "10s")
+
+	// scripting defaults
+	viper.SetDefault("scripting.fs.root", "data")
+	viper.SetDefault("scripting.fs.quota", 10*1024*1024)
+
+	// storage defaults
+	viper.SetDefault("storage.driver", "sqlite")
+	viper.SetDefault("storage.source", "data/dragon.db")
+
+	// graph defaults
+	viper.SetDefault("graph.driver", "memory")
+
+	// migrate defaults
+	viper.SetDefault("migrate.check", true)
+
+	// save defaults
+	viper.SetDefault("save.interval", "5m")
+
+	// backup defaults
+	viper.SetDefault("backup.dir", "backups")
+	viper.SetDefault("backup.paths", []string{"data"})
+	viper.SetDefault("backup.keep", 7)
+	viper.SetDefault("backup.schedule", "")
+
+	// checkpoint defaults
+	viper.SetDefault("checkpoint.dir", "data/checkpoints")
+
 	// database defaults
 	viper.SetDefault("database.development.host", "localhost")
 	viper.SetDefault("database.development.username", "neo4j")
@@ -0,0 +1,114 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore keeps values in a BoltDB file, each bucket of the store is a
+// bucket of the file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens a store in the BoltDB file at the path, creating it and
+// the directory it's in if they don't exist.
+func OpenBolt(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("bolt storage needs the path of a database file")
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Get reads the value from its bucket.
+func (s *BoltStore) Get(bucket, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrNotFound
+		}
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		// values are only valid during the transaction
+		value = append([]byte(nil), v...)
+
+		return nil
+	})
+
+	return value, err
+}
+
+// Put writes the value to its bucket, creating the bucket if it's new.
+func (s *BoltStore) Put(bucket, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), value)
+	})
+}
+
+// Delete removes the value from its bucket.
+func (s *BoltStore) Delete(bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(key))
+	})
+}
+
+// Keys lists the keys of the bucket, which bolt keeps sorted.
+func (s *BoltStore) Keys(bucket string) ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+// Snapshot copies the database to the file at the path in a read
+// transaction, so the copy is consistent while the store is in use.
+func (s *BoltStore) Snapshot(path string) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	})
+}
+
+// Close closes the file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
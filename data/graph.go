@@ -0,0 +1,291 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/viper"
+)
+
+// The drivers graphs are opened with, besides Memory which keeps the graph
+// in memory and is the default.
+const (
+	// Neo4j keeps the graph in the Neo4j database the database settings of
+	// the environment connect to.
+	Neo4j = "neo4j"
+)
+
+// The labels of nodes and kinds of relationships the game puts in the
+// graph, scripts are free to add their own.
+const (
+	// RoomLabel is the label of rooms, joined by ExitKind relationships with
+	// the direction of the exit.
+	RoomLabel = "Room"
+	ExitKind  = "EXIT"
+	// QuestLabel is the label of quests, joined to the quests they require by
+	// RequiresKind relationships.
+	QuestLabel   = "Quest"
+	RequiresKind = "REQUIRES"
+)
+
+// Node is something in the graph, like a room or a quest, by its label and
+// its id.
+type Node struct {
+	Label string
+	ID    string
+}
+
+// String returns the label and id of the node.
+func (n Node) String() string {
+	return n.Label + ":" + n.ID
+}
+
+// Edge is a relationship of a kind from one node to another, like the exit
+// from one room to another or a faction's standing with a player.
+type Edge struct {
+	From  Node
+	Kind  string
+	To    Node
+	Props map[string]interface{}
+}
+
+// Graph keeps the nodes of the game whose value is in how they're related.
+// Nodes exist as long as they have a relationship, there's at most one
+// relationship of each kind from one node to another.
+type Graph interface {
+	// Link creates the edge, replacing the properties of any there was.
+	Link(e Edge) error
+	// Unlink removes the relationship of the kind from one node to the
+	// other, missing relationships are ignored.
+	Unlink(from Node, kind string, to Node) error
+	// Edges returns the relationships of the kind from the node, or of every
+	// kind if it's empty.
+	Edges(from Node, kind string) ([]Edge, error)
+	// Inbound returns the relationships of the kind to the node, or of every
+	// kind if it's empty.
+	Inbound(to Node, kind string) ([]Edge, error)
+	// Path returns the nodes along the shortest path from one node to the
+	// other following relationships of the kind, including both ends, or
+	// ErrNotFound if there's none.
+	Path(from, to Node, kind string) ([]Node, error)
+	// Clear removes the nodes with the label and their relationships.
+	Clear(label string) error
+	// Close releases the database, the graph can't be used after.
+	Close() error
+}
+
+// identifier matches the labels, kinds and property names the graph allows,
+// which may be written into queries
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validate checks the labels and kind of the edge can be used in queries
+func (e Edge) validate() error {
+	for _, name := range []string{e.From.Label, e.Kind, e.To.Label} {
+		if err := validName(name); err != nil {
+			return err
+		}
+	}
+	for name := range e.Props {
+		if err := validName(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validName checks the label, kind or property name is an identifier
+func validName(name string) error {
+	if !identifier.MatchString(name) {
+		return fmt.Errorf("%q isn't a name the graph allows, names are letters, digits and underscores", name)
+	}
+
+	return nil
+}
+
+var (
+	graph     Graph
+	graphOnce sync.Once
+)
+
+// GlobalGraph returns the graph the game uses, opened with the driver
+// given by the graph.driver setting. It's kept in memory if the graph can't
+// be opened.
+func GlobalGraph() Graph {
+	graphOnce.Do(func() {
+		driver := viper.GetString("graph.driver")
+		if driver != Neo4j {
+			graph = NewMemoryGraph()
+
+			return
+		}
+
+		var err error
+		graph, err = OpenNeo4j(DB())
+		if err != nil {
+			logger.NewWithSource("graph").WithError(err).Error("Failed to open the graph, keeping it in memory.")
+			graph = NewMemoryGraph()
+		}
+	})
+
+	return graph
+}
+
+// SetGraph replaces the graph the game uses, like with a memory graph for
+// tests.
+func SetGraph(g Graph) {
+	graphOnce.Do(func() {})
+	graph = g
+}
+
+// edgeKey identifies a relationship of a MemoryGraph
+type edgeKey struct {
+	from, to Node
+	kind     string
+}
+
+// MemoryGraph keeps a graph in memory, it's lost when the server stops.
+type MemoryGraph struct {
+	edges map[edgeKey]Edge
+	mutex *sync.RWMutex
+}
+
+// NewMemoryGraph creates an empty in memory graph.
+func NewMemoryGraph() *MemoryGraph {
+	return &MemoryGraph{
+		edges: make(map[edgeKey]Edge),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Link stores a copy of the edge.
+func (g *MemoryGraph) Link(e Edge) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	props := make(map[string]interface{}, len(e.Props))
+	for k, v := range e.Props {
+		props[k] = v
+	}
+	e.Props = props
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.edges[edgeKey{from: e.From, to: e.To, kind: e.Kind}] = e
+
+	return nil
+}
+
+// Unlink removes the edge.
+func (g *MemoryGraph) Unlink(from Node, kind string, to Node) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.edges, edgeKey{from: from, to: to, kind: kind})
+
+	return nil
+}
+
+// Edges returns the edges from the node, sorted by kind and where they go.
+func (g *MemoryGraph) Edges(from Node, kind string) ([]Edge, error) {
+	return g.find(func(e Edge) bool {
+		return e.From == from && (kind == "" || e.Kind == kind)
+	}), nil
+}
+
+// Inbound returns the edges to the node, sorted by kind and where they go.
+func (g *MemoryGraph) Inbound(to Node, kind string) ([]Edge, error) {
+	return g.find(func(e Edge) bool {
+		return e.To == to && (kind == "" || e.Kind == kind)
+	}), nil
+}
+
+// find returns the edges match is true for, sorted
+func (g *MemoryGraph) find(match func(Edge) bool) []Edge {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	var edges []Edge
+	for _, e := range g.edges {
+		if match(e) {
+			edges = append(edges, e)
+		}
+	}
+	sortEdges(edges)
+
+	return edges
+}
+
+// sortEdges sorts the edges by kind, then where they're from and where they
+// go
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.From != b.From {
+			return a.From.String() < b.From.String()
+		}
+
+		return a.To.String() < b.To.String()
+	})
+}
+
+// Path searches breadth first from one node for the other.
+func (g *MemoryGraph) Path(from, to Node, kind string) ([]Node, error) {
+	if from == to {
+		return []Node{from}, nil
+	}
+	previous := map[Node]Node{from: from}
+	queue := []Node{from}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		edges, _ := g.Edges(n, kind)
+		for _, e := range edges {
+			if _, seen := previous[e.To]; seen {
+				continue
+			}
+			previous[e.To] = n
+			if e.To != to {
+				queue = append(queue, e.To)
+
+				continue
+			}
+			path := []Node{to}
+			for at := n; at != from; at = previous[at] {
+				path = append([]Node{at}, path...)
+			}
+
+			return append([]Node{from}, path...), nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// Clear removes the edges from or to nodes with the label.
+func (g *MemoryGraph) Clear(label string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for key := range g.edges {
+		if key.from.Label == label || key.to.Label == label {
+			delete(g.edges, key)
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing, there's nothing to release.
+func (g *MemoryGraph) Close() error {
+	return nil
+}
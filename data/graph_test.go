@@ -0,0 +1,63 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data_test
+
+import (
+	. "github.com/bbuck/dragon-mud/data"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryGraph", func() {
+	var (
+		g                  *MemoryGraph
+		orcs, ann, hall, x Node
+	)
+
+	BeforeEach(func() {
+		g = NewMemoryGraph()
+		orcs = Node{Label: "Faction", ID: "orcs"}
+		ann = Node{Label: "Player", ID: "ann"}
+		hall = Node{Label: RoomLabel, ID: "hall"}
+		x = Node{Label: RoomLabel, ID: "x"}
+	})
+
+	It("links, lists and unlinks relationships", func() {
+		Ω(g.Link(Edge{From: orcs, Kind: "STANDING", To: ann, Props: map[string]interface{}{"value": -10}})).Should(Succeed())
+		Ω(g.Link(Edge{From: orcs, Kind: "STANDING", To: ann, Props: map[string]interface{}{"value": 5}})).Should(Succeed())
+		Ω(g.Link(Edge{From: ann, Kind: "IN", To: hall})).Should(Succeed())
+
+		edges, err := g.Edges(orcs, "STANDING")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(edges).Should(HaveLen(1))
+		Ω(edges[0].Props["value"]).Should(Equal(5))
+		Ω(g.Inbound(ann, "")).Should(HaveLen(1))
+		Ω(g.Edges(ann, "STANDING")).Should(BeEmpty())
+
+		Ω(g.Unlink(orcs, "STANDING", ann)).Should(Succeed())
+		Ω(g.Edges(orcs, "")).Should(BeEmpty())
+	})
+
+	It("finds the shortest path along relationships of a kind", func() {
+		g.Link(Edge{From: hall, Kind: ExitKind, To: x})
+		g.Link(Edge{From: x, Kind: ExitKind, To: ann})
+		g.Link(Edge{From: hall, Kind: "SECRET", To: ann})
+
+		Ω(g.Path(hall, ann, ExitKind)).Should(Equal([]Node{hall, x, ann}))
+		Ω(g.Path(hall, ann, "")).Should(Equal([]Node{hall, ann}))
+		Ω(g.Path(hall, hall, ExitKind)).Should(Equal([]Node{hall}))
+		_, err := g.Path(ann, hall, ExitKind)
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+
+	It("clears nodes by label and refuses names it can't query", func() {
+		g.Link(Edge{From: hall, Kind: ExitKind, To: x})
+		g.Link(Edge{From: orcs, Kind: "STANDING", To: ann})
+
+		Ω(g.Clear(RoomLabel)).Should(Succeed())
+		Ω(g.Edges(hall, "")).Should(BeEmpty())
+		Ω(g.Edges(orcs, "")).Should(HaveLen(1))
+		Ω(g.Link(Edge{From: hall, Kind: "EXIT) DELETE n //", To: x})).ShouldNot(Succeed())
+	})
+})
@@ -0,0 +1,465 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package migrate keeps the game's storage in step with the code that reads
+// it. Migrations are numbered changes to the storage, written in Go or as SQL
+// files, each with an up that makes the change and an optional down that
+// undoes it. The migrations applied are recorded in the storage itself, so
+// the server can refuse to start when the storage and the code disagree.
+//
+// Migrations belong to a namespace, the server's own are in Core, the
+// game's in Game and each plugin's in a namespace named after the plugin,
+// so versions only need to be unique within a namespace.
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+)
+
+// The namespaces of migrations that don't belong to a plugin.
+const (
+	// Core holds the migrations of the server itself.
+	Core = "core"
+	// Game holds the migrations in the migrations directory of the game.
+	Game = "game"
+)
+
+// Bucket is the bucket of the storage applied migrations are recorded in.
+const Bucket = "migrations"
+
+// ErrIrreversible is returned when reverting a migration without a down.
+var ErrIrreversible = errors.New("the migration can't be undone")
+
+// Func changes the storage for a migration.
+type Func func(s data.Store) error
+
+// Migration is a numbered change to the storage.
+type Migration struct {
+	Namespace string
+	// Version orders the migrations of the namespace, they're applied from
+	// the lowest to the highest.
+	Version int64
+	Name    string
+	Up      Func
+	// Down undoes Up, migrations without one can't be reverted.
+	Down Func
+}
+
+// String returns the namespace, version and name of the migration.
+func (m Migration) String() string {
+	return fmt.Sprintf("%s %d (%s)", m.Namespace, m.Version, m.Name)
+}
+
+// Record is what's saved in the storage when a migration is applied.
+type Record struct {
+	Namespace string    `json:"namespace"`
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	Applied   time.Time `json:"applied"`
+}
+
+// key is where the record of the migration is kept in the bucket
+func key(namespace string, version int64) string {
+	return fmt.Sprintf("%s:%d", namespace, version)
+}
+
+// Status describes a migration that's known or was applied.
+type Status struct {
+	Namespace string
+	Version   int64
+	Name      string
+	// Applied is when the migration was applied, zero if it's pending.
+	Applied time.Time
+	// Unknown is true for migrations that were applied but aren't
+	// registered, like those of newer code or of a removed plugin.
+	Unknown bool
+}
+
+// Pending returns true if the migration hasn't been applied.
+func (s Status) Pending() bool {
+	return s.Applied.IsZero()
+}
+
+// String returns the namespace, version and name of the migration.
+func (s Status) String() string {
+	return fmt.Sprintf("%s %d (%s)", s.Namespace, s.Version, s.Name)
+}
+
+// DriftError is returned by Check when the storage doesn't match the
+// migrations.
+type DriftError struct {
+	Pending []Status
+	Unknown []Status
+}
+
+func (e *DriftError) Error() string {
+	var parts []string
+	if len(e.Pending) > 0 {
+		parts = append(parts, fmt.Sprintf("%d migrations are pending: %s", len(e.Pending), joinStatus(e.Pending)))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("%d applied migrations are unknown: %s", len(e.Unknown), joinStatus(e.Unknown)))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// joinStatus lists the migrations, separated by commas
+func joinStatus(list []Status) string {
+	names := make([]string, len(list))
+	for i, s := range list {
+		names[i] = s.String()
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// namespaceName matches the names of namespaces, which are part of the keys
+// of records
+var namespaceName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Migrator knows the migrations of each namespace and applies them to
+// stores.
+type Migrator struct {
+	migrations map[string]map[int64]Migration
+	mutex      *sync.RWMutex
+}
+
+var (
+	global     *Migrator
+	globalOnce sync.Once
+)
+
+// Global returns the migrator the server uses.
+func Global() *Migrator {
+	globalOnce.Do(func() {
+		global = NewMigrator()
+	})
+
+	return global
+}
+
+// NewMigrator creates a migrator without any migrations.
+func NewMigrator() *Migrator {
+	return &Migrator{
+		migrations: make(map[string]map[int64]Migration),
+		mutex:      new(sync.RWMutex),
+	}
+}
+
+// Register adds the migration, versions can't be registered twice in a
+// namespace.
+func (m *Migrator) Register(mig Migration) error {
+	if !namespaceName.MatchString(mig.Namespace) {
+		return fmt.Errorf("%q isn't a namespace, namespaces are letters, digits, dots, dashes and underscores", mig.Namespace)
+	}
+	if mig.Version <= 0 {
+		return fmt.Errorf("migration %s must have a version above zero", mig)
+	}
+	if mig.Up == nil {
+		return fmt.Errorf("migration %s has nothing to apply", mig)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.migrations[mig.Namespace] == nil {
+		m.migrations[mig.Namespace] = make(map[int64]Migration)
+	}
+	if have, ok := m.migrations[mig.Namespace][mig.Version]; ok {
+		return fmt.Errorf("migration %s has the version of %s", mig, have)
+	}
+	m.migrations[mig.Namespace][mig.Version] = mig
+
+	return nil
+}
+
+// sqlFile matches the names of SQL migration files, like
+// "3_add_mail.up.sql"
+var sqlFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadDir registers the SQL migrations in the directory in the namespace.
+// Each migration is a file named with its version and name, ending in
+// .up.sql, and optionally another ending in .down.sql that undoes it. A
+// missing directory has no migrations.
+func (m *Migrator) LoadDir(namespace, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	found := make(map[int64]*Migration)
+	var versions []int64
+	for _, fi := range files {
+		match := sqlFile.FindStringSubmatch(fi.Name())
+		if fi.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		mig, ok := found[version]
+		if !ok {
+			mig = &Migration{Namespace: namespace, Version: version, Name: match[2]}
+			found[version] = mig
+			versions = append(versions, version)
+		}
+		if mig.Name != match[2] {
+			return fmt.Errorf("%s: version %d is also named %q", fi.Name(), version, mig.Name)
+		}
+		if match[3] == "up" {
+			mig.Up = SQL(string(contents))
+		} else {
+			mig.Down = SQL(string(contents))
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+	for _, version := range versions {
+		if err := m.Register(*found[version]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SQL returns a migration func that executes the statements in a
+// transaction, it fails for stores that aren't SQL databases.
+func SQL(statements string) Func {
+	return func(s data.Store) error {
+		store, ok := s.(interface {
+			DB() *sql.DB
+		})
+		if !ok {
+			return errors.New("SQL migrations need the storage to be a SQL database")
+		}
+
+		tx, err := store.DB().Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(statements); err != nil {
+			tx.Rollback()
+
+			return err
+		}
+
+		return tx.Commit()
+	}
+}
+
+// Namespaces returns the namespaces with migrations, sorted.
+func (m *Migrator) Namespaces() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	names := make([]string, 0, len(m.migrations))
+	for name := range m.migrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Migrations returns the migrations of the namespace, from the lowest
+// version to the highest.
+func (m *Migrator) Migrations(namespace string) []Migration {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	list := make([]Migration, 0, len(m.migrations[namespace]))
+	for _, mig := range m.migrations[namespace] {
+		list = append(list, mig)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list
+}
+
+// applied reads the records of the migrations applied to the store
+func applied(s data.Store) (map[string]Record, error) {
+	b := data.NewBucket(s, Bucket)
+	keys, err := b.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]Record, len(keys))
+	for _, k := range keys {
+		var r Record
+		if err := b.Load(k, &r); err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+		records[k] = r
+	}
+
+	return records, nil
+}
+
+// Status lists the migrations that are known or were applied to the store,
+// sorted by namespace and version.
+func (m *Migrator) Status(s data.Store) ([]Status, error) {
+	records, err := applied(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Status
+	for _, namespace := range m.Namespaces() {
+		for _, mig := range m.Migrations(namespace) {
+			k := key(mig.Namespace, mig.Version)
+			list = append(list, Status{
+				Namespace: mig.Namespace,
+				Version:   mig.Version,
+				Name:      mig.Name,
+				Applied:   records[k].Applied,
+			})
+			delete(records, k)
+		}
+	}
+	for _, r := range records {
+		list = append(list, Status{
+			Namespace: r.Namespace,
+			Version:   r.Version,
+			Name:      r.Name,
+			Applied:   r.Applied,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
+
+// Check returns a DriftError if migrations are pending or migrations were
+// applied that aren't known.
+func (m *Migrator) Check(s data.Store) error {
+	list, err := m.Status(s)
+	if err != nil {
+		return err
+	}
+
+	drift := new(DriftError)
+	for _, st := range list {
+		switch {
+		case st.Unknown:
+			drift.Unknown = append(drift.Unknown, st)
+		case st.Pending():
+			drift.Pending = append(drift.Pending, st)
+		}
+	}
+	if len(drift.Pending) > 0 || len(drift.Unknown) > 0 {
+		return drift
+	}
+
+	return nil
+}
+
+// Up applies the pending migrations of the namespace, or of every namespace
+// if it's empty, from the lowest version to the highest. It stops at the
+// first that fails, returning those that were applied.
+func (m *Migrator) Up(s data.Store, namespace string) ([]Migration, error) {
+	records, err := applied(s)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := []string{namespace}
+	if namespace == "" {
+		namespaces = m.Namespaces()
+	}
+
+	b := data.NewBucket(s, Bucket)
+	var done []Migration
+	for _, ns := range namespaces {
+		for _, mig := range m.Migrations(ns) {
+			k := key(mig.Namespace, mig.Version)
+			if _, ok := records[k]; ok {
+				continue
+			}
+			if err := mig.Up(s); err != nil {
+				return done, fmt.Errorf("migration %s failed: %s", mig, err)
+			}
+			r := Record{Namespace: mig.Namespace, Version: mig.Version, Name: mig.Name, Applied: time.Now().UTC()}
+			if err := b.Save(k, r); err != nil {
+				return done, fmt.Errorf("migration %s was applied but couldn't be recorded: %s", mig, err)
+			}
+			done = append(done, mig)
+		}
+	}
+
+	return done, nil
+}
+
+// Down reverts the given number of the most recent migrations applied in
+// the namespace, from the highest version to the lowest. It stops at the
+// first that fails, returning those that were reverted.
+func (m *Migrator) Down(s data.Store, namespace string, steps int) ([]Migration, error) {
+	list, err := m.Status(s)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration)
+	for _, mig := range m.Migrations(namespace) {
+		known[mig.Version] = mig
+	}
+
+	b := data.NewBucket(s, Bucket)
+	var done []Migration
+	for i := len(list) - 1; i >= 0 && len(done) < steps; i-- {
+		st := list[i]
+		if st.Namespace != namespace || st.Pending() {
+			continue
+		}
+		mig, ok := known[st.Version]
+		if !ok {
+			return done, fmt.Errorf("migration %s isn't known, it can't be undone", st)
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("migration %s failed: %s", mig, ErrIrreversible)
+		}
+		if err := mig.Down(s); err != nil {
+			return done, fmt.Errorf("migration %s failed: %s", mig, err)
+		}
+		if err := b.Delete(key(mig.Namespace, mig.Version)); err != nil {
+			return done, fmt.Errorf("migration %s was undone but is still recorded: %s", mig, err)
+		}
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package migrate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/data"
+	. "github.com/bbuck/dragon-mud/data/migrate"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// setting returns a migration that sets the key of the settings bucket
+func setting(namespace string, version int64, key string) Migration {
+	return Migration{
+		Namespace: namespace,
+		Version:   version,
+		Name:      "set " + key,
+		Up: func(s data.Store) error {
+			return s.Put("settings", key, []byte("on"))
+		},
+		Down: func(s data.Store) error {
+			return s.Delete("settings", key)
+		},
+	}
+}
+
+var _ = Describe("Migrator", func() {
+	var (
+		m     *Migrator
+		store *data.MemoryStore
+	)
+
+	BeforeEach(func() {
+		m = NewMigrator()
+		store = data.NewMemoryStore()
+		Ω(m.Register(setting(Game, 2, "titles"))).Should(Succeed())
+		Ω(m.Register(setting(Game, 1, "mail"))).Should(Succeed())
+		Ω(m.Register(setting("castles", 1, "sieges"))).Should(Succeed())
+	})
+
+	It("refuses migrations it can't apply", func() {
+		Ω(m.Register(setting(Game, 1, "again"))).ShouldNot(Succeed())
+		Ω(m.Register(setting(Game, 0, "zero"))).ShouldNot(Succeed())
+		Ω(m.Register(setting("the:game", 3, "colon"))).ShouldNot(Succeed())
+		Ω(m.Register(Migration{Namespace: Game, Version: 3})).ShouldNot(Succeed())
+	})
+
+	It("applies pending migrations in order and records them", func() {
+		Ω(m.Check(store)).Should(BeAssignableToTypeOf(&DriftError{}))
+
+		done, err := m.Up(store, Game)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(done).Should(HaveLen(2))
+		Ω(done[0].Name).Should(Equal("set mail"))
+		Ω(store.Get("settings", "titles")).Should(Equal([]byte("on")))
+
+		drift := m.Check(store).(*DriftError)
+		Ω(drift.Pending).Should(HaveLen(1))
+		Ω(drift.Pending[0].Namespace).Should(Equal("castles"))
+
+		done, err = m.Up(store, "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(done).Should(HaveLen(1))
+		Ω(m.Check(store)).Should(Succeed())
+	})
+
+	It("reverts the latest migrations of a namespace", func() {
+		m.Up(store, "")
+
+		done, err := m.Down(store, Game, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(done).Should(HaveLen(1))
+		Ω(done[0].Version).Should(BeEquivalentTo(2))
+		_, err = store.Get("settings", "titles")
+		Ω(err).Should(Equal(data.ErrNotFound))
+		Ω(store.Get("settings", "sieges")).Should(Equal([]byte("on")))
+
+		list, err := m.Status(store)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(list).Should(HaveLen(3))
+		Ω(list[2].Pending()).Should(BeTrue())
+	})
+
+	It("won't revert migrations without a down", func() {
+		Ω(m.Register(Migration{Namespace: Game, Version: 3, Name: "forever", Up: func(data.Store) error {
+			return nil
+		}})).Should(Succeed())
+		m.Up(store, Game)
+
+		done, err := m.Down(store, Game, 2)
+		Ω(err).Should(MatchError(ContainSubstring(ErrIrreversible.Error())))
+		Ω(done).Should(BeEmpty())
+	})
+
+	It("finds migrations that were applied but aren't known", func() {
+		m.Up(store, "")
+
+		other := NewMigrator()
+		Ω(other.Register(setting(Game, 1, "mail"))).Should(Succeed())
+		drift := other.Check(store).(*DriftError)
+		Ω(drift.Pending).Should(BeEmpty())
+		Ω(drift.Unknown).Should(HaveLen(2))
+		Ω(drift.Error()).Should(ContainSubstring("castles 1 (set sieges)"))
+	})
+
+	Describe("SQL migrations", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "migrate")
+			Ω(err).ShouldNot(HaveOccurred())
+			files := map[string]string{
+				"1_create_titles.up.sql":   "CREATE TABLE titles (name VARCHAR(64) PRIMARY KEY);",
+				"1_create_titles.down.sql": "DROP TABLE titles;",
+				"2_add_knight.up.sql":      "INSERT INTO titles (name) VALUES ('knight');",
+				"notes.txt":                "not a migration",
+			}
+			for name, contents := range files {
+				Ω(ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)).Should(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("loads and applies the files of a directory", func() {
+			sql := NewMigrator()
+			Ω(sql.LoadDir("heraldry", dir)).Should(Succeed())
+			Ω(sql.LoadDir("missing", filepath.Join(dir, "missing"))).Should(Succeed())
+			Ω(sql.Namespaces()).Should(Equal([]string{"heraldry"}))
+			Ω(sql.Migrations("heraldry")).Should(HaveLen(2))
+
+			s, err := data.OpenSQLite(filepath.Join(dir, "dragon.db"))
+			Ω(err).ShouldNot(HaveOccurred())
+			defer s.Close()
+
+			done, err := sql.Up(s, "")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(done).Should(HaveLen(2))
+			var count int
+			db := s.(*data.SQLStore).DB()
+			Ω(db.QueryRow("SELECT COUNT(*) FROM titles").Scan(&count)).Should(Succeed())
+			Ω(count).Should(Equal(1))
+
+			_, err = sql.Up(data.NewMemoryStore(), "")
+			Ω(err).Should(MatchError(ContainSubstring("SQL database")))
+		})
+	})
+})
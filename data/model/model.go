@@ -0,0 +1,924 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package model saves typed records in the game's storage without writing
+// queries. A model is defined once with its fields, their validations and
+// which of them are indexed, and then records are created, found, updated,
+// deleted and queried through it from Go or from the "model" Lua module.
+//
+// Records of a model are kept as JSON in a bucket of the storage, and each
+// indexed field has a bucket of its own listing the records with each value,
+// so looking records up by an indexed field doesn't read every record.
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/data"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The types of fields.
+const (
+	String = "string"
+	Number = "number"
+	Bool   = "bool"
+	// Table fields hold lists and maps of any of the other types.
+	Table = "table"
+)
+
+// ID is the field every record has, the key it's saved under.
+const ID = "id"
+
+// Bucket is the bucket of the storage the indexes of each model are
+// recorded in, so indexes are rebuilt when they change.
+const Bucket = "models"
+
+// ErrUnknown is returned for models that haven't been defined.
+var ErrUnknown = errors.New("there's no such model")
+
+// Record is a record of a model, field names to their values. Numbers are
+// float64 and tables are []interface{} or map[string]interface{}, as they
+// are when decoded from JSON.
+type Record map[string]interface{}
+
+// ID returns the id of the record, empty if it has none.
+func (r Record) ID() string {
+	id, _ := r[ID].(string)
+
+	return id
+}
+
+// Field describes a field of a model and how its values are validated.
+type Field struct {
+	Name string
+	// Type is one of String, Number, Bool or Table.
+	Type string
+	// Required fields must have a value, after the default is applied.
+	Required bool
+	// Default is used when the field has no value.
+	Default interface{}
+	// Min and Max bound numbers, or the length of strings, when they're set.
+	Min, Max *float64
+	// Pattern is a regular expression strings must match.
+	Pattern string
+	// Choices lists the values the field may have, any if it's empty.
+	Choices []interface{}
+	// Index keeps an index of the field, so records can be looked up by its
+	// value without reading them all.
+	Index bool
+	// Unique fields are indexed and no two records may share a value.
+	Unique bool
+}
+
+// Def defines a model.
+type Def struct {
+	// Name of the model, letters, digits and underscores.
+	Name   string
+	Fields []Field
+	// Validate checks the record after its fields are, it's optional.
+	Validate func(Record) error
+}
+
+// FieldError is why the value of a field isn't valid.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// ValidationError lists why the fields of a record aren't valid.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	reasons := make([]string, len(e))
+	for i, fe := range e {
+		reasons[i] = fe.Error()
+	}
+
+	return strings.Join(reasons, ", ")
+}
+
+// identifier matches names of models and fields
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Registry keeps the models of the game, saving their records in a store.
+type Registry struct {
+	models map[string]*Model
+	store  data.Store
+	mutex  *sync.RWMutex
+
+	// the lock of each model by name, kept by the registry so the models a
+	// definition replaces share it with those replacing them
+	locks map[string]*sync.RWMutex
+}
+
+var (
+	global     *Registry
+	globalOnce sync.Once
+)
+
+// Global returns the registry of the game, which saves records in the game's
+// storage.
+func Global() *Registry {
+	globalOnce.Do(func() {
+		global = NewRegistry()
+	})
+
+	return global
+}
+
+// NewRegistry creates a registry without any models, saving records in the
+// game's storage until it's given a store.
+func NewRegistry() *Registry {
+	return &Registry{
+		models: make(map[string]*Model),
+		mutex:  new(sync.RWMutex),
+		locks:  make(map[string]*sync.RWMutex),
+	}
+}
+
+// SetStore sets the store records are saved in.
+func (r *Registry) SetStore(s data.Store) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.store = s
+}
+
+// Store returns the store records are saved in.
+func (r *Registry) Store() data.Store {
+	r.mutex.RLock()
+	s := r.store
+	r.mutex.RUnlock()
+
+	if s == nil {
+		return data.Storage()
+	}
+
+	return s
+}
+
+// Define adds the model, replacing any with the same name. The indexes of
+// the model are rebuilt if its indexed fields changed since it was last
+// defined.
+func (r *Registry) Define(def Def) (*Model, error) {
+	m, err := newModel(r, def)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkIndexes(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.models[def.Name] = m
+
+	return m, nil
+}
+
+// lock returns the lock of the model with the name. Plugins define their
+// models in each engine, so models replaced by a definition may still be
+// used and records have to be changed under the same lock.
+func (r *Registry) lock(name string) *sync.RWMutex {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	l, ok := r.locks[name]
+	if !ok {
+		l = new(sync.RWMutex)
+		r.locks[name] = l
+	}
+
+	return l
+}
+
+// Get returns the model with the name, or ErrUnknown.
+func (r *Registry) Get(name string) (*Model, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	m, ok := r.models[name]
+	if !ok {
+		return nil, ErrUnknown
+	}
+
+	return m, nil
+}
+
+// Names returns the names of the models, sorted.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.models))
+	for name := range r.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Model creates, finds, updates and deletes records of a model.
+type Model struct {
+	def      Def
+	fields   map[string]Field
+	patterns map[string]*regexp.Regexp
+	registry *Registry
+	mutex    *sync.RWMutex
+}
+
+// newModel checks the definition, compiling the patterns of its fields
+func newModel(r *Registry, def Def) (*Model, error) {
+	if !identifier.MatchString(def.Name) {
+		return nil, fmt.Errorf("%q isn't a model name, names are letters, digits and underscores", def.Name)
+	}
+
+	m := &Model{
+		def:      def,
+		fields:   make(map[string]Field),
+		patterns: make(map[string]*regexp.Regexp),
+		registry: r,
+		mutex:    r.lock(def.Name),
+	}
+	for _, f := range def.Fields {
+		if !identifier.MatchString(f.Name) || f.Name == ID {
+			return nil, fmt.Errorf("%q isn't a field name of %s", f.Name, def.Name)
+		}
+		if _, ok := m.fields[f.Name]; ok {
+			return nil, fmt.Errorf("%s has two %s fields", def.Name, f.Name)
+		}
+		switch f.Type {
+		case String, Number, Bool, Table:
+		default:
+			return nil, fmt.Errorf("the type of %s.%s is one of string, number, bool or table, not %q", def.Name, f.Name, f.Type)
+		}
+		if f.Unique {
+			f.Index = true
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("the pattern of %s.%s: %s", def.Name, f.Name, err)
+			}
+			m.patterns[f.Name] = re
+		}
+		m.fields[f.Name] = f
+	}
+
+	return m, nil
+}
+
+// Name returns the name of the model.
+func (m *Model) Name() string {
+	return m.def.Name
+}
+
+// Fields returns the fields of the model.
+func (m *Model) Fields() []Field {
+	return append([]Field(nil), m.def.Fields...)
+}
+
+// bucket is where the records of the model are saved
+func (m *Model) bucket() *data.Bucket {
+	return data.NewBucket(m.registry.Store(), "model:"+m.def.Name)
+}
+
+// indexBucket is where the index of the field is saved
+func (m *Model) indexBucket(field string) *data.Bucket {
+	return data.NewBucket(m.registry.Store(), "model:"+m.def.Name+":"+field)
+}
+
+// indexed returns the names of the indexed fields, sorted
+func (m *Model) indexed() []string {
+	var names []string
+	for _, f := range m.def.Fields {
+		if f.Index || f.Unique {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Validate checks the record, returning it with its values converted to
+// their field's types and defaults applied.
+func (m *Model) Validate(r Record) (Record, error) {
+	clean := Record{}
+	var errs ValidationError
+	for name := range r {
+		if _, ok := m.fields[name]; !ok && name != ID {
+			errs = append(errs, FieldError{Field: name, Reason: "isn't a field of " + m.def.Name})
+		}
+	}
+	for _, f := range m.def.Fields {
+		v, reason := m.validField(f, r[f.Name])
+		if reason != "" {
+			errs = append(errs, FieldError{Field: f.Name, Reason: reason})
+
+			continue
+		}
+		if v != nil {
+			clean[f.Name] = v
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Field < errs[j].Field
+		})
+
+		return nil, errs
+	}
+	if id := r.ID(); id != "" {
+		clean[ID] = id
+	}
+	if m.def.Validate != nil {
+		if err := m.def.Validate(clean); err != nil {
+			return nil, err
+		}
+	}
+
+	return clean, nil
+}
+
+// validField converts the value to the field's type and checks it, returning
+// why it isn't valid
+func (m *Model) validField(f Field, v interface{}) (interface{}, string) {
+	if v == nil {
+		v = f.Default
+	}
+	if v == nil {
+		if f.Required {
+			return nil, "is required"
+		}
+
+		return nil, ""
+	}
+
+	v, ok := convert(f.Type, v)
+	if !ok {
+		return nil, "must be a " + f.Type
+	}
+
+	var size float64
+	switch t := v.(type) {
+	case float64:
+		size = t
+	case string:
+		size = float64(len(t))
+		if re, ok := m.patterns[f.Name]; ok && !re.MatchString(t) {
+			return nil, "doesn't match " + f.Pattern
+		}
+	}
+	if f.Min != nil && size < *f.Min {
+		if f.Type == String {
+			return nil, fmt.Sprintf("must be at least %v long", *f.Min)
+		}
+
+		return nil, fmt.Sprintf("must be at least %v", *f.Min)
+	}
+	if f.Max != nil && size > *f.Max {
+		if f.Type == String {
+			return nil, fmt.Sprintf("must be at most %v long", *f.Max)
+		}
+
+		return nil, fmt.Sprintf("must be at most %v", *f.Max)
+	}
+	if len(f.Choices) > 0 {
+		key := indexKey(v)
+		for _, choice := range f.Choices {
+			if c, ok := convert(f.Type, choice); ok && indexKey(c) == key {
+				return v, ""
+			}
+		}
+
+		return nil, fmt.Sprintf("must be one of %v", f.Choices)
+	}
+
+	return v, ""
+}
+
+// convert returns the value as the type, numbers are converted to float64
+// and tables are converted to what they'd be decoded from JSON as
+func convert(typ string, v interface{}) (interface{}, bool) {
+	switch typ {
+	case String:
+		s, ok := v.(string)
+
+		return s, ok
+	case Bool:
+		b, ok := v.(bool)
+
+		return b, ok
+	case Number:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), true
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), true
+		}
+	case Table:
+		kind := reflect.ValueOf(v).Kind()
+		if kind != reflect.Map && kind != reflect.Slice && kind != reflect.Array {
+			return nil, false
+		}
+		contents, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var t interface{}
+		if err := json.Unmarshal(contents, &t); err != nil {
+			return nil, false
+		}
+
+		return t, true
+	}
+
+	return nil, false
+}
+
+// indexKey is the key of the value in an index
+func indexKey(v interface{}) string {
+	contents, _ := json.Marshal(v)
+
+	return string(contents)
+}
+
+// Create validates and saves a new record, giving it an id if it doesn't
+// have one.
+func (m *Model) Create(r Record) (Record, error) {
+	clean, err := m.Validate(r)
+	if err != nil {
+		return nil, err
+	}
+	if clean.ID() == "" {
+		clean[ID] = uuid.NewV1().String()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var existing Record
+	if err := m.bucket().Load(clean.ID(), &existing); err != data.ErrNotFound {
+		if err == nil {
+			return nil, fmt.Errorf("%s %s already exists", m.def.Name, clean.ID())
+		}
+
+		return nil, err
+	}
+	if err := m.checkUnique(clean); err != nil {
+		return nil, err
+	}
+	if err := m.bucket().Save(clean.ID(), clean); err != nil {
+		return nil, err
+	}
+
+	return clean, m.index(nil, clean)
+}
+
+// Find returns the record with the id, or data.ErrNotFound.
+func (m *Model) Find(id string) (Record, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.load(id)
+}
+
+// load reads the record with the id
+func (m *Model) load(id string) (Record, error) {
+	var r Record
+	if err := m.bucket().Load(id, &r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Update changes the fields of the record with the id, fields with nil
+// values are removed. The record is validated again and saved.
+func (m *Model) Update(id string, changes Record) (Record, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+	updated := Record{}
+	for k, v := range old {
+		updated[k] = v
+	}
+	for k, v := range changes {
+		if v == nil {
+			delete(updated, k)
+		} else {
+			updated[k] = v
+		}
+	}
+	updated[ID] = id
+
+	clean, err := m.Validate(updated)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkUnique(clean); err != nil {
+		return nil, err
+	}
+	if err := m.bucket().Save(id, clean); err != nil {
+		return nil, err
+	}
+
+	return clean, m.index(old, clean)
+}
+
+// Delete removes the record with the id, missing records are ignored.
+func (m *Model) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old, err := m.load(id)
+	if err == data.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.bucket().Delete(id); err != nil {
+		return err
+	}
+
+	return m.index(old, nil)
+}
+
+// checkUnique returns a validation error if another record has the value of
+// a unique field of the record
+func (m *Model) checkUnique(r Record) error {
+	var errs ValidationError
+	for _, f := range m.def.Fields {
+		v, ok := r[f.Name]
+		if !f.Unique || !ok {
+			continue
+		}
+		ids, err := m.lookup(f.Name, v)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if id != r.ID() {
+				errs = append(errs, FieldError{Field: f.Name, Reason: "is already taken"})
+
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// lookup returns the ids of the records with the value in the index of the
+// field
+func (m *Model) lookup(field string, v interface{}) ([]string, error) {
+	var ids []string
+	err := m.indexBucket(field).Load(indexKey(v), &ids)
+	if err == data.ErrNotFound {
+		return nil, nil
+	}
+
+	return ids, err
+}
+
+// index moves the record from the entries of its old values in the indexes
+// to those of its new values, either may be nil
+func (m *Model) index(old, updated Record) error {
+	id := old.ID()
+	if id == "" {
+		id = updated.ID()
+	}
+	for _, field := range m.indexed() {
+		before, had := old[field]
+		after, has := updated[field]
+		if had && has && indexKey(before) == indexKey(after) {
+			continue
+		}
+		if had {
+			if err := m.unindex(field, before, id); err != nil {
+				return err
+			}
+		}
+		if has {
+			ids, err := m.lookup(field, after)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+			sort.Strings(ids)
+			if err := m.indexBucket(field).Save(indexKey(after), ids); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// unindex removes the id from the entry of the value in the index of the
+// field
+func (m *Model) unindex(field string, v interface{}, id string) error {
+	ids, err := m.lookup(field, v)
+	if err != nil {
+		return err
+	}
+	kept := ids[:0]
+	for _, have := range ids {
+		if have != id {
+			kept = append(kept, have)
+		}
+	}
+	if len(kept) == 0 {
+		return m.indexBucket(field).Delete(indexKey(v))
+	}
+
+	return m.indexBucket(field).Save(indexKey(v), kept)
+}
+
+// checkIndexes rebuilds the indexes if the indexed fields aren't those
+// recorded when the model was last defined
+func (m *Model) checkIndexes() error {
+	b := data.NewBucket(m.registry.Store(), Bucket)
+	var recorded []string
+	if err := b.Load(m.def.Name, &recorded); err != nil && err != data.ErrNotFound {
+		return err
+	}
+	indexed := m.indexed()
+	if indexKey(recorded) == indexKey(indexed) {
+		return nil
+	}
+	if err := m.Reindex(); err != nil {
+		return err
+	}
+
+	return b.Save(m.def.Name, indexed)
+}
+
+// Reindex rebuilds the indexes of the model from its records.
+func (m *Model) Reindex() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, field := range m.indexed() {
+		b := m.indexBucket(field)
+		keys, err := b.Keys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+
+	records, err := m.all()
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := m.index(nil, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// all reads every record of the model
+func (m *Model) all() ([]Record, error) {
+	b := m.bucket()
+	keys, err := b.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(keys))
+	for _, k := range keys {
+		var r Record
+		if err := b.Load(k, &r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// Query selects records of a model.
+type Query struct {
+	// Where is the values fields must have, every record matches if it's
+	// empty.
+	Where map[string]interface{}
+	// OrderBy is the field records are sorted by, their id if it's empty.
+	// Records without the field come last.
+	OrderBy string
+	// Desc sorts records from the highest value to the lowest.
+	Desc bool
+	// Limit is the most records returned, all of them if it's zero.
+	Limit int
+}
+
+// Query returns the records matching the query. Records are looked up by an
+// indexed field of Where if there is one, otherwise every record is read.
+func (m *Model) Query(q Query) ([]Record, error) {
+	where := make(map[string]string, len(q.Where))
+	var byIndex string
+	for name, v := range q.Where {
+		f, ok := m.fields[name]
+		if !ok && name != ID {
+			return nil, fmt.Errorf("%s isn't a field of %s", name, m.def.Name)
+		}
+		if name != ID {
+			if v, ok = convert(f.Type, v); !ok {
+				return nil, fmt.Errorf("%s must be a %s", name, f.Type)
+			}
+		}
+		where[name] = indexKey(v)
+		if (f.Index || f.Unique) && (byIndex == "" || name < byIndex) {
+			byIndex = name
+		}
+	}
+	if q.OrderBy != "" && q.OrderBy != ID {
+		if _, ok := m.fields[q.OrderBy]; !ok {
+			return nil, fmt.Errorf("%s isn't a field of %s", q.OrderBy, m.def.Name)
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var (
+		records []Record
+		err     error
+	)
+	id, byID := q.Where[ID].(string)
+	switch {
+	case byID:
+		var r Record
+		if r, err = m.load(id); err == nil {
+			records = []Record{r}
+		} else if err == data.ErrNotFound {
+			err = nil
+		}
+	case byIndex != "":
+		records, err = m.byIndex(byIndex, q.Where[byIndex])
+	default:
+		records, err = m.all()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matched := records[:0]
+	for _, r := range records {
+		if matches(r, where) {
+			matched = append(matched, r)
+		}
+	}
+	sortRecords(matched, q.OrderBy, q.Desc)
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+
+	return matched, nil
+}
+
+// byIndex reads the records with the value in the index of the field
+func (m *Model) byIndex(field string, v interface{}) ([]Record, error) {
+	v, _ = convert(m.fields[field].Type, v)
+	ids, err := m.lookup(field, v)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(ids))
+	for _, id := range ids {
+		r, err := m.load(id)
+		if err == data.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// matches returns true if the record has each of the values, given as index
+// keys
+func matches(r Record, where map[string]string) bool {
+	for name, key := range where {
+		v, ok := r[name]
+		if !ok || indexKey(v) != key {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortRecords sorts the records by the field, then by id
+func sortRecords(records []Record, field string, desc bool) {
+	if field == "" {
+		field = ID
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, hasA := records[i][field]
+		b, hasB := records[j][field]
+		if hasA != hasB {
+			return hasA
+		}
+		if c := compare(a, b); c != 0 {
+			return (c < 0) != desc
+		}
+
+		return records[i].ID() < records[j].ID()
+	})
+}
+
+// compare orders numbers, strings and bools, other values are equal
+func compare(a, b interface{}) int {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok && x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if !x {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Where returns the records with each of the values, sorted by id.
+func (m *Model) Where(where map[string]interface{}) ([]Record, error) {
+	return m.Query(Query{Where: where})
+}
+
+// First returns the first record with each of the values by id, or
+// data.ErrNotFound.
+func (m *Model) First(where map[string]interface{}) (Record, error) {
+	records, err := m.Query(Query{Where: where, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, data.ErrNotFound
+	}
+
+	return records[0], nil
+}
+
+// All returns every record of the model, sorted by id.
+func (m *Model) All() ([]Record, error) {
+	return m.Query(Query{})
+}
+
+// Count returns the number of records with each of the values.
+func (m *Model) Count(where map[string]interface{}) (int, error) {
+	records, err := m.Query(Query{Where: where})
+
+	return len(records), err
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package model_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+	. "github.com/bbuck/dragon-mud/data/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// slowStore takes a moment to write, so records created at the same time
+// overlap
+type slowStore struct {
+	*data.MemoryStore
+}
+
+func (s slowStore) Put(bucket, key string, value []byte) error {
+	time.Sleep(time.Millisecond)
+
+	return s.MemoryStore.Put(bucket, key, value)
+}
+
+var _ = Describe("Model", func() {
+	var (
+		registry *Registry
+		store    *data.MemoryStore
+		bounties *Model
+		zero     = float64(0)
+		bounty   = Def{
+			Name: "bounty",
+			Fields: []Field{
+				{Name: "target", Type: String, Required: true, Index: true},
+				{Name: "reward", Type: Number, Required: true, Min: &zero},
+				{Name: "status", Type: String, Default: "open", Choices: []interface{}{"open", "claimed"}},
+				{Name: "code", Type: String, Unique: true, Pattern: `^[A-Z]+$`},
+			},
+		}
+	)
+
+	BeforeEach(func() {
+		store = data.NewMemoryStore()
+		registry = NewRegistry()
+		registry.SetStore(store)
+
+		var err error
+		bounties, err = registry.Define(bounty)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("refuses definitions it can't use", func() {
+		_, err := registry.Define(Def{Name: "bad name"})
+		Ω(err).Should(HaveOccurred())
+		_, err = registry.Define(Def{Name: "bad", Fields: []Field{{Name: "id", Type: String}}})
+		Ω(err).Should(HaveOccurred())
+		_, err = registry.Define(Def{Name: "bad", Fields: []Field{{Name: "when", Type: "time"}}})
+		Ω(err).Should(HaveOccurred())
+		_, err = registry.Get("bad")
+		Ω(err).Should(Equal(ErrUnknown))
+		Ω(registry.Names()).Should(Equal([]string{"bounty"}))
+	})
+
+	It("validates records", func() {
+		_, err := bounties.Create(Record{"reward": -5, "status": "paid", "wanted": true})
+		Ω(err).Should(BeAssignableToTypeOf(ValidationError{}))
+		Ω(err.Error()).Should(Equal("reward must be at least 0, status must be one of [open claimed], target is required, wanted isn't a field of bounty"))
+
+		r, err := bounties.Create(Record{"target": "grimble", "reward": 50})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.ID()).ShouldNot(BeEmpty())
+		Ω(r["reward"]).Should(Equal(float64(50)))
+		Ω(r["status"]).Should(Equal("open"))
+	})
+
+	It("finds, updates and deletes records", func() {
+		r, _ := bounties.Create(Record{"id": "b1", "target": "grimble", "reward": 50})
+		_, err := bounties.Create(Record{"id": "b1", "target": "grimble", "reward": 50})
+		Ω(err).Should(MatchError("bounty b1 already exists"))
+
+		found, err := bounties.Find(r.ID())
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(found).Should(Equal(r))
+
+		updated, err := bounties.Update("b1", Record{"target": "snaggle", "status": "claimed"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(updated["reward"]).Should(Equal(float64(50)))
+		Ω(bounties.Where(map[string]interface{}{"target": "grimble"})).Should(BeEmpty())
+		Ω(bounties.Where(map[string]interface{}{"target": "snaggle"})).Should(HaveLen(1))
+
+		Ω(bounties.Delete("b1")).Should(Succeed())
+		_, err = bounties.Find("b1")
+		Ω(err).Should(Equal(data.ErrNotFound))
+		Ω(bounties.Where(map[string]interface{}{"target": "snaggle"})).Should(BeEmpty())
+	})
+
+	It("keeps unique fields unique", func() {
+		_, err := bounties.Create(Record{"id": "b1", "target": "grimble", "reward": 5, "code": "RED"})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = bounties.Create(Record{"target": "snaggle", "reward": 5, "code": "RED"})
+		Ω(err).Should(MatchError("code is already taken"))
+		_, err = bounties.Update("b1", Record{"code": "RED", "reward": 10})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("keeps unique fields unique through models defined again", func() {
+		registry.SetStore(slowStore{store})
+		again, err := registry.Define(bounty)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var (
+			wg      sync.WaitGroup
+			created int32
+		)
+		for i := 0; i < 20; i++ {
+			m := bounties
+			if i%2 == 1 {
+				m = again
+			}
+			wg.Add(1)
+			go func(m *Model) {
+				defer wg.Done()
+				if _, err := m.Create(Record{"target": "grimble", "reward": 5, "code": "RED"}); err == nil {
+					atomic.AddInt32(&created, 1)
+				}
+			}(m)
+		}
+		wg.Wait()
+		Ω(created).Should(Equal(int32(1)))
+	})
+
+	It("queries records", func() {
+		bounties.Create(Record{"id": "b1", "target": "grimble", "reward": 50})
+		bounties.Create(Record{"id": "b2", "target": "grimble", "reward": 80})
+		bounties.Create(Record{"id": "b3", "target": "snaggle", "reward": 20, "status": "claimed"})
+
+		records, err := bounties.Query(Query{OrderBy: "reward", Desc: true, Limit: 2})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(records).Should(HaveLen(2))
+		Ω(records[0].ID()).Should(Equal("b2"))
+		Ω(records[1].ID()).Should(Equal("b1"))
+
+		Ω(bounties.Count(map[string]interface{}{"target": "grimble", "reward": 50})).Should(Equal(1))
+		Ω(bounties.Count(map[string]interface{}{"status": "open"})).Should(Equal(2))
+		first, err := bounties.First(map[string]interface{}{"id": "b3"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(first["target"]).Should(Equal("snaggle"))
+		_, err = bounties.Where(map[string]interface{}{"bounty": 1})
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("rebuilds indexes when the indexed fields change", func() {
+		bounties.Create(Record{"id": "b1", "target": "grimble", "reward": 50, "status": "claimed"})
+
+		def := bounty
+		def.Fields = append([]Field(nil), bounty.Fields...)
+		def.Fields[2].Index = true
+		redefined, err := registry.Define(def)
+		Ω(err).ShouldNot(HaveOccurred())
+		keys, err := store.Keys("model:bounty:status")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(keys).Should(Equal([]string{`"claimed"`}))
+		Ω(redefined.Count(map[string]interface{}{"status": "claimed"})).Should(Equal(1))
+	})
+})
@@ -0,0 +1,211 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/talon"
+)
+
+// Neo4jGraph keeps a graph in Neo4j, nodes are matched by their label and
+// their id property.
+type Neo4jGraph struct {
+	db *talon.DB
+}
+
+// OpenNeo4j opens a graph in the Neo4j database.
+func OpenNeo4j(db *talon.DB) (Graph, error) {
+	if db == nil {
+		return nil, fmt.Errorf("the neo4j graph needs a database connection")
+	}
+
+	return &Neo4jGraph{db: db}, nil
+}
+
+// Link merges the nodes and the relationship, setting its properties.
+func (g *Neo4jGraph) Link(e Edge) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	params := talon.Properties{"from": e.From.ID, "to": e.To.ID}
+	var props []string
+	for _, k := range talon.Properties(e.Props).Keys() {
+		params["p_"+k] = e.Props[k]
+		props = append(props, fmt.Sprintf("%s: {p_%s}", k, k))
+	}
+	cypher := fmt.Sprintf(
+		"MERGE (a:%s {id: {from}}) MERGE (b:%s {id: {to}}) MERGE (a)-[r:%s]->(b) SET r = {%s}",
+		e.From.Label, e.To.Label, e.Kind, strings.Join(props, ", "),
+	)
+
+	return g.exec(cypher, params)
+}
+
+// Unlink deletes the relationship.
+func (g *Neo4jGraph) Unlink(from Node, kind string, to Node) error {
+	if err := (Edge{From: from, Kind: kind, To: to}).validate(); err != nil {
+		return err
+	}
+	cypher := fmt.Sprintf(
+		"MATCH (:%s {id: {from}})-[r:%s]->(:%s {id: {to}}) DELETE r",
+		from.Label, kind, to.Label,
+	)
+
+	return g.exec(cypher, talon.Properties{"from": from.ID, "to": to.ID})
+}
+
+// Edges matches the relationships from the node.
+func (g *Neo4jGraph) Edges(from Node, kind string) ([]Edge, error) {
+	pattern, err := relationship(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := validName(from.Label); err != nil {
+		return nil, err
+	}
+	cypher := fmt.Sprintf(
+		"MATCH (a:%s {id: {id}})-%s->(b) RETURN labels(b)[0], b.id, type(r), properties(r)",
+		from.Label, pattern,
+	)
+
+	return g.edges(cypher, talon.Properties{"id": from.ID}, func(other Node, kind string, props map[string]interface{}) Edge {
+		return Edge{From: from, Kind: kind, To: other, Props: props}
+	})
+}
+
+// Inbound matches the relationships to the node.
+func (g *Neo4jGraph) Inbound(to Node, kind string) ([]Edge, error) {
+	pattern, err := relationship(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := validName(to.Label); err != nil {
+		return nil, err
+	}
+	cypher := fmt.Sprintf(
+		"MATCH (a:%s {id: {id}})<-%s-(b) RETURN labels(b)[0], b.id, type(r), properties(r)",
+		to.Label, pattern,
+	)
+
+	return g.edges(cypher, talon.Properties{"id": to.ID}, func(other Node, kind string, props map[string]interface{}) Edge {
+		return Edge{From: other, Kind: kind, To: to, Props: props}
+	})
+}
+
+// Path asks Neo4j for the shortest path.
+func (g *Neo4jGraph) Path(from, to Node, kind string) ([]Node, error) {
+	if from == to {
+		return []Node{from}, nil
+	}
+	if err := (Edge{From: from, Kind: "PATH", To: to}).validate(); err != nil {
+		return nil, err
+	}
+	steps := "[*]"
+	if kind != "" {
+		if err := validName(kind); err != nil {
+			return nil, err
+		}
+		steps = "[:" + kind + "*]"
+	}
+	cypher := fmt.Sprintf(
+		"MATCH (a:%s {id: {from}}), (b:%s {id: {to}}), p = shortestPath((a)-%s->(b)) UNWIND nodes(p) AS n RETURN labels(n)[0], n.id",
+		from.Label, to.Label, steps,
+	)
+	rows, err := g.rows(cypher, talon.Properties{"from": from.ID, "to": to.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	path := make([]Node, len(rows))
+	for i, row := range rows {
+		path[i] = node(row)
+	}
+
+	return path, nil
+}
+
+// Clear detaches and deletes the nodes with the label.
+func (g *Neo4jGraph) Clear(label string) error {
+	if err := validName(label); err != nil {
+		return err
+	}
+
+	return g.exec(fmt.Sprintf("MATCH (n:%s) DETACH DELETE n", label), nil)
+}
+
+// Close does nothing, the connection is shared with the db module.
+func (g *Neo4jGraph) Close() error {
+	return nil
+}
+
+// edges runs the query, building an edge from each row of the other node's
+// label and id and the relationship's type and properties
+func (g *Neo4jGraph) edges(cypher string, params talon.Properties, build func(Node, string, map[string]interface{}) Edge) ([]Edge, error) {
+	rows, err := g.rows(cypher, params)
+	if err != nil {
+		return nil, err
+	}
+	edges := make([]Edge, 0, len(rows))
+	for _, row := range rows {
+		kind, _ := row.GetIndex(2)
+		props, _ := row.GetIndex(3)
+		m, _ := props.(map[string]interface{})
+		edges = append(edges, build(node(row), fmt.Sprint(kind), m))
+	}
+	sortEdges(edges)
+
+	return edges, nil
+}
+
+// rows runs the query, returning every row
+func (g *Neo4jGraph) rows(cypher string, params talon.Properties) ([]*talon.Row, error) {
+	q, err := g.db.CypherP(cypher, params)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.All()
+}
+
+// exec runs the query, which returns no rows
+func (g *Neo4jGraph) exec(cypher string, params talon.Properties) error {
+	q := g.db.Cypher(cypher)
+	if len(params) > 0 {
+		var err error
+		if q, err = g.db.CypherP(cypher, params); err != nil {
+			return err
+		}
+	}
+	_, err := q.Exec()
+
+	return err
+}
+
+// relationship returns the pattern matching relationships of the kind, or
+// any kind if it's empty, as r
+func relationship(kind string) (string, error) {
+	if kind == "" {
+		return "[r]", nil
+	}
+	if err := validName(kind); err != nil {
+		return "", err
+	}
+
+	return "[r:" + kind + "]", nil
+}
+
+// node reads the node of the row's label and id, its first two columns
+func node(row *talon.Row) Node {
+	label, _ := row.GetIndex(0)
+	id, _ := row.GetIndex(1)
+
+	return Node{Label: fmt.Sprint(label), ID: fmt.Sprint(id)}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound is returned when there's nothing stored under a key.
+var ErrNotFound = errors.New("not found")
+
+// The drivers stores are opened with.
+const (
+	// SQLite stores everything in a file, it's the default.
+	SQLite = "sqlite"
+	// Postgres stores everything in a PostgreSQL database.
+	Postgres = "postgres"
+	// Bolt stores everything in a BoltDB file.
+	Bolt = "bolt"
+	// Memory keeps everything in memory, it's lost when the server stops.
+	Memory = "memory"
+)
+
+// The settings of what's saved, like account.store, name where it's saved.
+const (
+	// FileStore saves each thing as a file in a directory, it's the default.
+	FileStore = "files"
+	// DatabaseStore saves things in the game's storage.
+	DatabaseStore = "database"
+)
+
+// Store persists values in buckets, each value under a key of its bucket.
+// Players, characters and the data of plugins each have their own bucket,
+// so they can share one database without knowing which it is.
+type Store interface {
+	// Get returns the value under the key, or ErrNotFound.
+	Get(bucket, key string) ([]byte, error)
+	// Put creates or replaces the value under the key.
+	Put(bucket, key string, value []byte) error
+	// Delete removes the value under the key, missing keys are ignored.
+	Delete(bucket, key string) error
+	// Keys returns the keys of the bucket, sorted.
+	Keys(bucket string) ([]string, error)
+	// Close releases the database, the store can't be used after.
+	Close() error
+}
+
+// Snapshotter is a store that can copy its database to a file while it's in
+// use, for backups.
+type Snapshotter interface {
+	Snapshot(path string) error
+}
+
+// Driver opens a store with the data source, like a path or a connection
+// string.
+type Driver func(source string) (Store, error)
+
+var (
+	drivers = map[string]Driver{
+		SQLite:   OpenSQLite,
+		Postgres: OpenPostgres,
+		Bolt:     OpenBolt,
+		Memory: func(string) (Store, error) {
+			return NewMemoryStore(), nil
+		},
+	}
+	driversMutex = new(sync.RWMutex)
+)
+
+// Register adds the driver with the name, replacing any there was.
+func Register(name string, driver Driver) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the drivers stores can be opened with,
+// sorted.
+func Drivers() []string {
+	driversMutex.RLock()
+	defer driversMutex.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Open opens a store with the named driver and the data source.
+func Open(driver, source string) (Store, error) {
+	driversMutex.RLock()
+	open, ok := drivers[driver]
+	driversMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("the storage driver is one of %v, not %q", Drivers(), driver)
+	}
+
+	return open(source)
+}
+
+var (
+	storage     Store
+	storageOnce sync.Once
+)
+
+// Storage returns the store the game uses, opened with the driver and
+// source given by the storage.driver and storage.source settings. It stops
+// the server if the store can't be opened.
+func Storage() Store {
+	storageOnce.Do(func() {
+		driver := viper.GetString("storage.driver")
+		l := logger.NewWithSource("storage").WithField("driver", driver)
+		l.Debug("Opening the storage.")
+
+		var err error
+		storage, err = Open(driver, viper.GetString("storage.source"))
+		if err != nil {
+			l.WithError(err).Fatal("Failed to open the storage.")
+		}
+	})
+
+	return storage
+}
+
+// SetStorage replaces the store the game uses, like with a memory store for
+// tests.
+func SetStorage(s Store) {
+	storageOnce.Do(func() {})
+	storage = s
+}
+
+// Bucket saves and loads values of a bucket of a store as JSON.
+type Bucket struct {
+	Store Store
+	Name  string
+}
+
+// NewBucket creates a bucket with the name in the store.
+func NewBucket(s Store, name string) *Bucket {
+	return &Bucket{Store: s, Name: name}
+}
+
+// Save stores the value under the key as JSON.
+func (b *Bucket) Save(key string, v interface{}) error {
+	contents, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.Store.Put(b.Name, key, contents)
+}
+
+// Load reads the value under the key into v, or returns ErrNotFound.
+func (b *Bucket) Load(key string, v interface{}) error {
+	contents, err := b.Store.Get(b.Name, key)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(contents, v)
+}
+
+// Delete removes the value under the key.
+func (b *Bucket) Delete(key string) error {
+	return b.Store.Delete(b.Name, key)
+}
+
+// Keys returns the keys of the bucket, sorted.
+func (b *Bucket) Keys() ([]string, error) {
+	return b.Store.Keys(b.Name)
+}
+
+// MemoryStore keeps values in memory, they're lost when the server stops.
+type MemoryStore struct {
+	buckets map[string]map[string][]byte
+	mutex   *sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]map[string][]byte),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+// Get returns a copy of the value.
+func (s *MemoryStore) Get(bucket, key string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+// Put stores a copy of the value.
+func (s *MemoryStore) Put(bucket, key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string][]byte)
+	}
+	s.buckets[bucket][key] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// Delete removes the value.
+func (s *MemoryStore) Delete(bucket, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.buckets[bucket], key)
+
+	return nil
+}
+
+// Keys returns the keys of the bucket.
+func (s *MemoryStore) Keys(bucket string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Close does nothing, there's nothing to release.
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package data_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/data"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Set DATA_POSTGRES_SOURCE to a connection string, like
+// "postgres://postgres@localhost/dragon_test?sslmode=disable", to test the
+// postgres driver against a live database.
+var _ = Describe("Store", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "dragon-data")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	sources := map[string]func() string{
+		Memory: func() string { return "" },
+		SQLite: func() string { return filepath.Join(dir, "db", "dragon.db") },
+		Bolt:   func() string { return filepath.Join(dir, "db", "dragon.bolt") },
+	}
+	if source := os.Getenv("DATA_POSTGRES_SOURCE"); source != "" {
+		sources[Postgres] = func() string { return source }
+	}
+
+	for driver, source := range sources {
+		driver, source := driver, source
+
+		It("saves, lists and deletes values with the "+driver+" driver", func() {
+			s, err := Open(driver, source())
+			Ω(err).ShouldNot(HaveOccurred())
+			defer s.Close()
+
+			_, err = s.Get("players", "ann")
+			Ω(err).Should(Equal(ErrNotFound))
+			Ω(s.Put("players", "bob", []byte("1"))).Should(Succeed())
+			Ω(s.Put("players", "ann", []byte("2"))).Should(Succeed())
+			Ω(s.Put("players", "ann", []byte("3"))).Should(Succeed())
+			Ω(s.Put("accounts", "ann", []byte("4"))).Should(Succeed())
+
+			value, err := s.Get("players", "ann")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(value)).Should(Equal("3"))
+			Ω(s.Keys("players")).Should(Equal([]string{"ann", "bob"}))
+			Ω(s.Keys("nothing")).Should(BeEmpty())
+
+			Ω(s.Delete("players", "ann")).Should(Succeed())
+			Ω(s.Delete("players", "nobody")).Should(Succeed())
+			Ω(s.Delete("nothing", "ann")).Should(Succeed())
+			Ω(s.Keys("players")).Should(Equal([]string{"bob"}))
+			value, err = s.Get("accounts", "ann")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(value)).Should(Equal("4"))
+
+			Ω(s.Delete("players", "bob")).Should(Succeed())
+			Ω(s.Delete("accounts", "ann")).Should(Succeed())
+		})
+	}
+
+	It("keeps what's saved in files after they're closed", func() {
+		for _, driver := range []string{SQLite, Bolt} {
+			path := filepath.Join(dir, driver+".db")
+			s, err := Open(driver, path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s.Put("players", "ann", []byte("1"))).Should(Succeed())
+			Ω(s.Close()).Should(Succeed())
+
+			s, err = Open(driver, path)
+			Ω(err).ShouldNot(HaveOccurred())
+			value, err := s.Get("players", "ann")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(value)).Should(Equal("1"))
+			Ω(s.Close()).Should(Succeed())
+		}
+	})
+
+	It("snapshots file databases while they're open", func() {
+		for _, driver := range []string{SQLite, Bolt} {
+			s, err := Open(driver, filepath.Join(dir, driver+".db"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s.Put("players", "ann", []byte("1"))).Should(Succeed())
+			snap := filepath.Join(dir, driver+"-snapshot.db")
+			Ω(s.(Snapshotter).Snapshot(snap)).Should(Succeed())
+			Ω(s.Close()).Should(Succeed())
+
+			s, err = Open(driver, snap)
+			Ω(err).ShouldNot(HaveOccurred())
+			value, err := s.Get("players", "ann")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(value)).Should(Equal("1"))
+			Ω(s.Close()).Should(Succeed())
+		}
+	})
+
+	It("refuses unknown drivers and stores without a file", func() {
+		_, err := Open("mongo", "")
+		Ω(err).Should(MatchError(`the storage driver is one of [bolt memory postgres sqlite], not "mongo"`))
+		_, err = Open(SQLite, "")
+		Ω(err).Should(HaveOccurred())
+		_, err = Open(Bolt, "")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("saves values as JSON in buckets", func() {
+		b := NewBucket(NewMemoryStore(), "players")
+		Ω(b.Save("ann", map[string]int{"level": 3})).Should(Succeed())
+
+		var v map[string]int
+		Ω(b.Load("ann", &v)).Should(Succeed())
+		Ω(v).Should(Equal(map[string]int{"level": 3}))
+		Ω(b.Load("bob", &v)).Should(Equal(ErrNotFound))
+		Ω(b.Keys()).Should(Equal([]string{"ann"}))
+		Ω(b.Delete("ann")).Should(Succeed())
+		Ω(b.Keys()).Should(BeEmpty())
+	})
+})
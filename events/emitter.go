@@ -39,6 +39,8 @@ type emittedEvent struct {
 	event string
 	data  Data
 	done  chan struct{}
+	// checked events already ran their before handlers through Check
+	checked bool
 }
 
 // Emitter represents a type capable of handling a list of callable actions to
@@ -153,6 +155,46 @@ func (e *Emitter) off(evt string) {
 	}
 }
 
+// Check runs the before:<event> handlers right away, returning the first error
+// one returns. It lets handlers cancel an action before it happens, by
+// returning ErrHalt or an error saying why, where Emit would only tell them
+// about it. Once the action is done Confirm emits the rest of the event.
+func (e *Emitter) Check(evt string, d Data) error {
+	if d == nil {
+		d = NewData()
+	} else {
+		d = d.Clone()
+	}
+
+	return e.emit("before:"+evt, d)
+}
+
+// Confirm emits the event and after:<event> like Emit, without running the
+// before:<event> handlers Check already ran.
+func (e *Emitter) Confirm(evt string, d Data) Done {
+	return e.push(evt, d, true)
+}
+
+// EmitSync calls the handlers of the event, with its before and after
+// handlers, right away and returns the first error one returns.
+func (e *Emitter) EmitSync(evt string, d Data) error {
+	if d == nil {
+		d = NewData()
+	} else {
+		d = d.Clone()
+	}
+
+	err := e.emit("before:"+evt, d)
+	if err == nil {
+		err = e.emit(evt, d)
+	}
+	if err == nil {
+		err = e.emit("after:"+evt, d)
+	}
+
+	return err
+}
+
 // Emit will call all handlers and once handlers assigned to listen to the event
 // as well as emitting a before:<event> and after:<event> before and after.
 // This method is asyncronous and returns no values directly, failures get
@@ -168,6 +210,11 @@ func (e *Emitter) Emit(evt string, d Data) Done {
 		}
 	}
 
+	return e.push(evt, d, false)
+}
+
+// push queues the event to be handled
+func (e *Emitter) push(evt string, d Data, checked bool) Done {
 	if d == nil {
 		d = NewData()
 	} else {
@@ -176,9 +223,10 @@ func (e *Emitter) Emit(evt string, d Data) Done {
 
 	done := make(Done)
 	ee := &emittedEvent{
-		event: evt,
-		data:  d,
-		done:  done,
+		event:   evt,
+		data:    d,
+		done:    done,
+		checked: checked,
 	}
 	// we don't want to hold up calls to Emit, even if buffer limits are
 	// reached.
@@ -192,7 +240,10 @@ func (e *Emitter) Emit(evt string, d Data) Done {
 func (e *Emitter) handleEmissions() {
 	for evt := range e.incomingEvents {
 		go func(event *emittedEvent) {
-			err := e.emit("before:"+event.event, event.data)
+			var err error
+			if !event.checked {
+				err = e.emit("before:"+event.event, event.data)
+			}
 			if err == nil {
 				err = e.emit(event.event, event.data)
 			}
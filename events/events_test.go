@@ -212,5 +212,54 @@ var _ = Describe("Events", func() {
 			close(c)
 			close(done)
 		})
+
+		It("checks before handlers right away", func() {
+			em.On("before:test9", events.HandlerFunc(func(d events.Data) error {
+				if d["blocked"] == true {
+					return events.ErrHalt
+				}
+
+				return nil
+			}))
+
+			Ω(em.Check("test9", nil)).Should(Succeed())
+			Ω(em.Check("test9", events.Data{"blocked": true})).Should(Equal(events.ErrHalt))
+		})
+
+		It("confirms checked events without running before handlers again", func(done Done) {
+			c := make(chan interface{}, 3)
+			em.On("before:test10", events.HandlerFunc(func(events.Data) error {
+				c <- 1
+
+				return nil
+			}))
+			em.On("test10", events.HandlerFunc(func(events.Data) error {
+				c <- 2
+
+				return nil
+			}))
+			em.On("after:test10", events.HandlerFunc(func(events.Data) error {
+				c <- 3
+
+				return nil
+			}))
+
+			Ω(em.Check("test10", nil)).Should(Succeed())
+			Ω(<-c).Should(Equal(1))
+			<-em.Confirm("test10", nil)
+			Ω(<-c).Should(Equal(2))
+			Ω(<-c).Should(Equal(3))
+			Ω(c).Should(BeEmpty())
+			close(done)
+		})
+
+		It("returns the first error emitting right away", func() {
+			em.On("test11", events.HandlerFunc(func(events.Data) error {
+				return events.ErrHalt
+			}))
+
+			Ω(em.EmitSync("test11", nil)).Should(Equal(events.ErrHalt))
+			Ω(em.EmitSync("test12", nil)).Should(Succeed())
+		})
 	})
 })
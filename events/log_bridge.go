@@ -0,0 +1,61 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package events
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// LogErrorEvent is emitted by a bridged emitter for every error logged.
+const LogErrorEvent = "log:error"
+
+// logBridge is a log sink emitting error entries as events
+type logBridge struct {
+	emitter *Emitter
+}
+
+// BridgeLogErrors emits LogErrorEvent on the emitter whenever an error (or
+// more severe) entry is logged, so handlers can alert someone about failures.
+// The event data contains the "message", "level", "source", "time" (RFC 3339)
+// and the entry's "fields". Failures of the event's own handlers aren't
+// emitted again, but a handler that logs errors itself will trigger another
+// event. The returned function stops the bridge.
+func BridgeLogErrors(em *Emitter) (stop func()) {
+	return logger.AttachSink(&logBridge{emitter: em})
+}
+
+func (b *logBridge) Write(e *logger.Entry) error {
+	if e.Level > logger.ErrorLevel {
+		return nil
+	}
+
+	// the emitter logs failed handlers, which would emit the event again
+	if evt, _ := e.Fields["event"].(string); evt == LogErrorEvent {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(e.Fields))
+	for k, v := range e.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+	source, _ := e.Fields["prefix"].(string)
+
+	b.emitter.Emit(LogErrorEvent, Data{
+		"message": e.Message,
+		"level":   e.Level.String(),
+		"source":  source,
+		"time":    e.Time.Format(time.RFC3339),
+		"fields":  fields,
+	})
+
+	return nil
+}
+
+func (b *logBridge) Close() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package events_test
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BridgeLogErrors", func() {
+	var (
+		em     *events.Emitter
+		stop   func()
+		alerts chan events.Data
+	)
+
+	BeforeEach(func() {
+		logger.TestLog()
+		em = events.NewEmitter(nil)
+		stop = events.BridgeLogErrors(em)
+		alerts = make(chan events.Data, 10)
+		em.On(events.LogErrorEvent, events.HandlerFunc(func(d events.Data) error {
+			alerts <- d
+
+			return nil
+		}))
+	})
+
+	AfterEach(func() {
+		stop()
+		em.Stop()
+	})
+
+	It("emits errors with their fields", func(done Done) {
+		logger.NewWithSource("combat").WithError(errors.New("no target")).Error("Swing failed.")
+
+		d := <-alerts
+		Ω(d["message"]).Should(Equal("Swing failed."))
+		Ω(d["level"]).Should(Equal("error"))
+		Ω(d["source"]).Should(Equal("combat"))
+		Ω(d["fields"]).Should(HaveKeyWithValue("error", "no target"))
+		close(done)
+	})
+
+	It("ignores less severe entries", func() {
+		logger.NewWithSource("combat").Warn("Swing missed.")
+
+		Consistently(alerts).ShouldNot(Receive())
+	})
+
+	It("stops emitting once stopped", func() {
+		stop()
+		logger.NewWithSource("combat").Error("Swing failed.")
+
+		Consistently(alerts).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,214 @@
+// Copyright 2016-2017 Brandon Buck
+
+package fs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrOutsideSandbox is returned when a path would resolve to a location
+	// outside of the sandbox root.
+	ErrOutsideSandbox = errors.New("path is outside of the sandbox")
+
+	// ErrQuotaExceeded is returned when a write would grow the sandbox beyond
+	// it's configured quota.
+	ErrQuotaExceeded = errors.New("write would exceed the sandbox size quota")
+)
+
+// Sandbox restricts file operations to a single root directory, with an
+// optional limit on the total number of bytes stored in it. Paths given to the
+// sandbox are always relative to it's root.
+type Sandbox struct {
+	Root  string
+	Quota int64
+}
+
+// NewSandbox creates a sandbox rooted at the given directory. A quota of 0 or
+// less means the sandbox size is not limited. The root directory is created
+// on first write, not when the sandbox is created.
+func NewSandbox(root string, quota int64) *Sandbox {
+	return &Sandbox{
+		Root:  filepath.Clean(root),
+		Quota: quota,
+	}
+}
+
+// Read returns the contents of the file with the given name.
+func (s *Sandbox) Read(name string) ([]byte, error) {
+	fpath, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(fpath)
+}
+
+// Write replaces the contents of the named file, creating it and any parent
+// directories as necessary.
+func (s *Sandbox) Write(name string, contents []byte) error {
+	fpath, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if s.Quota > 0 {
+		used, err := s.Size()
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(fpath); err == nil {
+			used -= fi.Size()
+		}
+		if used+int64(len(contents)) > s.Quota {
+			return ErrQuotaExceeded
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fpath, contents, 0644)
+}
+
+// Exists returns whether a file or directory exists with the given name.
+func (s *Sandbox) Exists(name string) bool {
+	fpath, err := s.resolve(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fpath)
+
+	return err == nil
+}
+
+// List returns the sorted names of the entries within the given directory,
+// directory names are suffixed with a "/". A directory that does not exist is
+// treated as empty.
+func (s *Sandbox) List(dir string) ([]string, error) {
+	dpath, err := s.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := ioutil.ReadDir(dpath)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(infos))
+	for i, fi := range infos {
+		names[i] = fi.Name()
+		if fi.IsDir() {
+			names[i] += "/"
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Remove deletes the named file or empty directory.
+func (s *Sandbox) Remove(name string) error {
+	fpath, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	if fpath == s.Root {
+		return ErrOutsideSandbox
+	}
+
+	return os.Remove(fpath)
+}
+
+// Size returns the total number of bytes stored in the sandbox.
+func (s *Sandbox) Size() (int64, error) {
+	var total int64
+	err := filepath.Walk(s.Root, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			total += fi.Size()
+		}
+
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	return total, err
+}
+
+// convert a sandbox relative name into a full path, ensuring it does not
+// escape the sandbox root.
+func (s *Sandbox) resolve(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", ErrOutsideSandbox
+	}
+
+	fpath := filepath.Join(s.Root, name)
+	if !within(s.Root, fpath) {
+		return "", ErrOutsideSandbox
+	}
+
+	// a link inside of the sandbox could still point outside of it, so the
+	// check is made again with the links followed
+	root, err := evalExisting(s.Root)
+	if err != nil {
+		return "", err
+	}
+	real, err := evalExisting(fpath)
+	if err != nil {
+		return "", err
+	}
+	if !within(root, real) {
+		return "", ErrOutsideSandbox
+	}
+
+	return fpath, nil
+}
+
+// determine if the path is the root or inside of it
+func within(root, fpath string) bool {
+	return fpath == root || strings.HasPrefix(fpath, root+string(filepath.Separator))
+}
+
+// follow the links in the part of the path that exists, what's left doesn't
+// exist yet so it can't be a link. Links that lead nowhere are refused, the
+// file would be created wherever they point.
+func evalExisting(fpath string) (string, error) {
+	rest := ""
+	for {
+		if _, err := os.Lstat(fpath); err == nil {
+			real, err := filepath.EvalSymlinks(fpath)
+			if os.IsNotExist(err) {
+				return "", ErrOutsideSandbox
+			}
+			if err != nil {
+				return "", err
+			}
+
+			return filepath.Join(real, rest), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(fpath)
+		if parent == fpath {
+			return filepath.Join(fpath, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(fpath), rest)
+		fpath = parent
+	}
+}
@@ -0,0 +1,83 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/fs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sandbox", func() {
+	var (
+		root string
+		box  *Sandbox
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "sandbox")
+		Ω(err).Should(BeNil())
+		box = NewSandbox(root, 16)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("writes and reads files", func() {
+		Ω(box.Write("notes/one.txt", []byte("hello"))).Should(Succeed())
+		contents, err := box.Read("notes/one.txt")
+		Ω(err).Should(BeNil())
+		Ω(string(contents)).Should(Equal("hello"))
+		Ω(box.Exists("notes/one.txt")).Should(BeTrue())
+	})
+
+	It("lists directory contents", func() {
+		box.Write("b.txt", []byte("b"))
+		box.Write("a/c.txt", []byte("c"))
+		names, err := box.List(".")
+		Ω(err).Should(BeNil())
+		Ω(names).Should(Equal([]string{"a/", "b.txt"}))
+	})
+
+	It("rejects paths outside of the root", func() {
+		_, err := box.Read("../secret")
+		Ω(err).Should(Equal(ErrOutsideSandbox))
+		Ω(box.Write("/etc/passwd", []byte("x"))).Should(Equal(ErrOutsideSandbox))
+		Ω(box.Exists("../")).Should(BeFalse())
+	})
+
+	It("rejects links that lead outside of the root", func() {
+		outside, err := ioutil.TempDir("", "outside")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(outside)
+		Ω(ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("s"), 0644)).Should(Succeed())
+		Ω(os.Symlink(outside, filepath.Join(root, "escape"))).Should(Succeed())
+		Ω(os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling"))).Should(Succeed())
+
+		_, err = box.Read("escape/secret")
+		Ω(err).Should(Equal(ErrOutsideSandbox))
+		Ω(box.Write("escape/new.txt", []byte("x"))).Should(Equal(ErrOutsideSandbox))
+		Ω(box.Write("dangling", []byte("x"))).Should(Equal(ErrOutsideSandbox))
+		Ω(filepath.Join(outside, "new.txt")).ShouldNot(BeAnExistingFile())
+		Ω(filepath.Join(outside, "missing")).ShouldNot(BeAnExistingFile())
+	})
+
+	It("follows links that stay inside of the root", func() {
+		Ω(box.Write("real/one.txt", []byte("hello"))).Should(Succeed())
+		Ω(os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "alias"))).Should(Succeed())
+		contents, err := box.Read("alias/one.txt")
+		Ω(err).Should(BeNil())
+		Ω(string(contents)).Should(Equal("hello"))
+	})
+
+	It("enforces the quota", func() {
+		Ω(box.Write("one.txt", []byte("0123456789"))).Should(Succeed())
+		Ω(box.Write("two.txt", []byte("0123456789"))).Should(Equal(ErrQuotaExceeded))
+		Ω(box.Write("one.txt", []byte("0123456789abcdef"))).Should(Succeed())
+	})
+})
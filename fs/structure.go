@@ -24,7 +24,10 @@ var ProjectStructure = Dir{
 	"client": Dir{
 		"init.lua": File{},
 	},
-	"views": Dir{},
+	"views":      Dir{},
+	"locales":    Dir{},
+	"names":      Dir{},
+	"migrations": Dir{},
 }
 
 // PluginStructure represents what a plugin is intended to look like.
@@ -39,7 +42,10 @@ var PluginStructure = Dir{
 	"client": Dir{
 		"init.lua": File{},
 	},
-	"views": Dir{},
+	"views":      Dir{},
+	"locales":    Dir{},
+	"names":      Dir{},
+	"migrations": Dir{},
 }
 
 // CreateStructureParams makes it easier and more meaningful to call
@@ -0,0 +1,64 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces the file at the path with the contents so that a crash
+// at any point leaves either the old file or the new one, never a partial
+// write. The contents are written and synced to a temporary file in the same
+// directory, which is then renamed over the path and the directory synced so
+// the rename itself survives a power loss. The directory is created if it
+// doesn't exist.
+func WriteFile(path string, contents []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// a no-op once the rename succeeds
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir flushes the entries of the directory. Not every platform can sync
+// a directory, failing to is ignored since the file is in place either way
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
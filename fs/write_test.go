@@ -0,0 +1,42 @@
+package fs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/fs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteFile", func() {
+	var root string
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "write")
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("replaces files without leaving temporary files behind", func() {
+		path := filepath.Join(root, "players", "ann.json")
+		Ω(WriteFile(path, []byte("first"), 0600)).Should(Succeed())
+		Ω(WriteFile(path, []byte("second"), 0600)).Should(Succeed())
+
+		contents, err := ioutil.ReadFile(path)
+		Ω(err).Should(BeNil())
+		Ω(string(contents)).Should(Equal("second"))
+		fi, err := os.Stat(path)
+		Ω(err).Should(BeNil())
+		Ω(fi.Mode().Perm()).Should(Equal(os.FileMode(0600)))
+		files, err := ioutil.ReadDir(filepath.Dir(path))
+		Ω(err).Should(BeNil())
+		Ω(files).Should(HaveLen(1))
+	})
+})
@@ -0,0 +1,331 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package account manages the accounts players log in with. An account has a
+// password, hashed with argon2id, an optional email address and any number of
+// characters. Players log in through a Login, which walks them through
+// choosing an account and a character to play.
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/spf13/viper"
+)
+
+// Errors returned by a Manager.
+var (
+	ErrNotFound      = errors.New("no such account")
+	ErrTaken         = errors.New("that name is taken")
+	ErrWrongPassword = errors.New("wrong password")
+)
+
+// Limits on the names of accounts and characters.
+const (
+	MinNameLength = 3
+	MaxNameLength = 16
+)
+
+// Account is a player's login to the game.
+type Account struct {
+	// Name is the account's name as it was first typed, names are unique
+	// without regard to case.
+	Name string `json:"name"`
+	// Email is the address of the player, if they gave one.
+	Email string `json:"email,omitempty"`
+	// PasswordHash is the argon2id hash of the password.
+	PasswordHash string `json:"password_hash"`
+	// Characters are the names of the account's characters.
+	Characters []string `json:"characters"`
+	// Created is when the account was made.
+	Created time.Time `json:"created"`
+	// LastLogin is when the player last logged in.
+	LastLogin time.Time `json:"last_login,omitempty"`
+}
+
+// SetPassword hashes the password and stores it.
+func (a *Account) SetPassword(password string) error {
+	hash, err := HashPassword(password, DefaultHashParams())
+	if err != nil {
+		return err
+	}
+	a.PasswordHash = hash
+
+	return nil
+}
+
+// CheckPassword is true if the password is the account's.
+func (a *Account) CheckPassword(password string) bool {
+	ok, _ := CheckPassword(password, a.PasswordHash)
+
+	return ok
+}
+
+// HasCharacter is true if the character, in any case, belongs to the account.
+func (a *Account) HasCharacter(name string) bool {
+	return a.character(name) != ""
+}
+
+// character returns the character's name as it's stored
+func (a *Account) character(name string) string {
+	for _, c := range a.Characters {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+
+	return ""
+}
+
+// key is how names are compared and stored
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+// ValidateName checks that the name can be used for an account or a
+// character, names are letters only.
+func ValidateName(name string) error {
+	if len(name) < MinNameLength || len(name) > MaxNameLength {
+		return fmt.Errorf("names must be %d to %d letters long", MinNameLength, MaxNameLength)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) || r > unicode.MaxASCII {
+			return errors.New("names can only contain letters")
+		}
+	}
+
+	return nil
+}
+
+// ValidatePassword checks that the password is at least as long as the
+// account.min_password_length setting.
+func ValidatePassword(password string) error {
+	if min := viper.GetInt("account.min_password_length"); len(password) < min {
+		return fmt.Errorf("passwords must be at least %d characters long", min)
+	}
+
+	return nil
+}
+
+// ValidateEmail does a simple check that the address looks like one, an
+// empty address is allowed.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 || strings.ContainsAny(email, " \t") {
+		return errors.New("that doesn't look like an email address")
+	}
+
+	return nil
+}
+
+// Manager creates, finds and saves accounts.
+type Manager struct {
+	store  Store
+	owners map[string]string
+	now    func() time.Time
+	mutex  *sync.Mutex
+}
+
+// NewManager creates a manager keeping accounts in the store.
+func NewManager(s Store) *Manager {
+	return &Manager{
+		store: s,
+		now:   time.Now,
+		mutex: new(sync.Mutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager of the game's accounts, saved in the directory
+// given by the account.dir setting, or the game's database if the
+// account.store setting is "database".
+func Global() *Manager {
+	globalOnce.Do(func() {
+		if viper.GetString("account.store") == data.DatabaseStore {
+			globalManager = NewManager(NewDataStore(data.Storage()))
+		} else {
+			globalManager = NewManager(NewDirStore(viper.GetString("account.dir")))
+		}
+	})
+
+	return globalManager
+}
+
+// SetClock replaces the function used to get the current time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Create makes and saves a new account, failing with ErrTaken if the name is
+// used by another account.
+func (m *Manager) Create(name, password, email string) (*Account, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	if err := ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, err := m.store.Load(key(name)); err == nil {
+		return nil, ErrTaken
+	} else if err != ErrNotFound {
+		return nil, err
+	}
+
+	a := &Account{
+		Name:    name,
+		Email:   email,
+		Created: m.now().UTC(),
+	}
+	if err := a.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := m.store.Save(key(name), a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Get returns the account with the name, or ErrNotFound.
+func (m *Manager) Get(name string) (*Account, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.store.Load(key(name))
+}
+
+// Exists is true if there's an account with the name.
+func (m *Manager) Exists(name string) bool {
+	_, err := m.Get(name)
+
+	return err == nil
+}
+
+// Authenticate returns the account if the password is right, recording the
+// time of the login. ErrNotFound or ErrWrongPassword is returned if it isn't.
+func (m *Manager) Authenticate(name, password string) (*Account, error) {
+	a, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !a.CheckPassword(password) {
+		return nil, ErrWrongPassword
+	}
+
+	m.mutex.Lock()
+	a.LastLogin = m.now().UTC()
+	m.mutex.Unlock()
+
+	return a, m.Save(a)
+}
+
+// Save stores changes to the account.
+func (m *Manager) Save(a *Account) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.store.Save(key(a.Name), a)
+}
+
+// Delete removes the account, its characters become free to use.
+func (m *Manager) Delete(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.owners != nil {
+		for c, owner := range m.owners {
+			if owner == key(name) {
+				delete(m.owners, c)
+			}
+		}
+	}
+
+	return m.store.Delete(key(name))
+}
+
+// AddCharacter gives the account a new character, failing with ErrTaken if
+// any account has a character with the name.
+func (m *Manager) AddCharacter(a *Account, character string) error {
+	if err := ValidateName(character); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.loadOwners(); err != nil {
+		return err
+	}
+	if _, ok := m.owners[key(character)]; ok {
+		return ErrTaken
+	}
+
+	a.Characters = append(a.Characters, character)
+	if err := m.store.Save(key(a.Name), a); err != nil {
+		a.Characters = a.Characters[:len(a.Characters)-1]
+
+		return err
+	}
+	m.owners[key(character)] = key(a.Name)
+
+	return nil
+}
+
+// Owner returns the account the character belongs to, or ErrNotFound.
+func (m *Manager) Owner(character string) (*Account, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.loadOwners(); err != nil {
+		return nil, err
+	}
+	owner, ok := m.owners[key(character)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return m.store.Load(owner)
+}
+
+// loadOwners indexes the owner of every character the first time it's needed,
+// the mutex must be held
+func (m *Manager) loadOwners() error {
+	if m.owners != nil {
+		return nil
+	}
+
+	accounts, err := m.store.All()
+	if err != nil {
+		return err
+	}
+	m.owners = make(map[string]string)
+	for _, a := range accounts {
+		for _, c := range a.Characters {
+			m.owners[key(c)] = key(a.Name)
+		}
+	}
+
+	return nil
+}
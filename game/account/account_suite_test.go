@@ -0,0 +1,23 @@
+package account_test
+
+import (
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAccount(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Account Suite")
+}
+
+var _ = BeforeSuite(func() {
+	viper.Set("account.hash.time", 1)
+	viper.Set("account.hash.memory", 1024)
+	viper.Set("account.hash.threads", 1)
+	viper.Set("account.min_password_length", 8)
+	viper.Set("account.max_login_attempts", 3)
+})
@@ -0,0 +1,168 @@
+package account_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/bbuck/dragon-mud/game/account"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Passwords", func() {
+	params := HashParams{Time: 1, Memory: 1024, Threads: 1}
+
+	It("checks passwords against their hash", func() {
+		hash, err := HashPassword("hunter22", params)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(hash).Should(HavePrefix("$argon2id$v=19$m=1024,t=1,p=1$"))
+		Ω(CheckPassword("hunter22", hash)).Should(BeTrue())
+		Ω(CheckPassword("hunter23", hash)).Should(BeFalse())
+	})
+
+	It("salts each hash", func() {
+		first, _ := HashPassword("hunter22", params)
+		second, _ := HashPassword("hunter22", params)
+
+		Ω(first).ShouldNot(Equal(second))
+	})
+
+	It("rejects hashes it can't read", func() {
+		_, err := CheckPassword("hunter22", "$2a$10$notargon")
+
+		Ω(err).Should(Equal(ErrInvalidHash))
+	})
+})
+
+var _ = Describe("Validation", func() {
+	It("checks names", func() {
+		Ω(ValidateName("Thorin")).Should(Succeed())
+		Ω(ValidateName("Al")).ShouldNot(Succeed())
+		Ω(ValidateName("Th0rin")).ShouldNot(Succeed())
+		Ω(ValidateName(strings.Repeat("a", MaxNameLength+1))).ShouldNot(Succeed())
+	})
+
+	It("checks email addresses", func() {
+		Ω(ValidateEmail("")).Should(Succeed())
+		Ω(ValidateEmail("thorin@example.com")).Should(Succeed())
+		Ω(ValidateEmail("thorin")).ShouldNot(Succeed())
+		Ω(ValidateEmail("thorin@")).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		m   *Manager
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		m = NewManager(NewMemoryStore())
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time {
+			return now
+		})
+	})
+
+	It("creates accounts with unique names", func() {
+		a, err := m.Create("Thorin", "oakenshield", "thorin@example.com")
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.Created).Should(Equal(now))
+		Ω(a.PasswordHash).ShouldNot(ContainSubstring("oakenshield"))
+		Ω(m.Exists("thorin")).Should(BeTrue())
+
+		_, err = m.Create("THORIN", "something", "")
+		Ω(err).Should(Equal(ErrTaken))
+	})
+
+	It("checks what it creates", func() {
+		_, err := m.Create("Thorin", "short", "")
+		Ω(err).Should(HaveOccurred())
+
+		_, err = m.Create("Thorin", "oakenshield", "nope")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("authenticates with the password", func() {
+		m.Create("Thorin", "oakenshield", "")
+		now = now.Add(time.Hour)
+
+		a, err := m.Authenticate("thorin", "oakenshield")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.LastLogin).Should(Equal(now))
+
+		_, err = m.Authenticate("thorin", "arkenstone")
+		Ω(err).Should(Equal(ErrWrongPassword))
+		_, err = m.Authenticate("bilbo", "oakenshield")
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+
+	It("keeps character names unique across accounts", func() {
+		thorin, _ := m.Create("Thorin", "oakenshield", "")
+		bilbo, _ := m.Create("Bilbo", "baggins1", "")
+
+		Ω(m.AddCharacter(thorin, "Fili")).Should(Succeed())
+		Ω(m.AddCharacter(thorin, "Kili")).Should(Succeed())
+		Ω(m.AddCharacter(bilbo, "fili")).Should(Equal(ErrTaken))
+
+		owner, err := m.Owner("KILI")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(owner.Name).Should(Equal("Thorin"))
+		Ω(owner.Characters).Should(Equal([]string{"Fili", "Kili"}))
+		Ω(owner.HasCharacter("fili")).Should(BeTrue())
+	})
+
+	It("frees the characters of deleted accounts", func() {
+		thorin, _ := m.Create("Thorin", "oakenshield", "")
+		m.AddCharacter(thorin, "Fili")
+
+		Ω(m.Delete("thorin")).Should(Succeed())
+		_, err := m.Owner("Fili")
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+})
+
+var _ = Describe("DirStore", func() {
+	var (
+		dir   string
+		store *DirStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "accounts")
+		Ω(err).ShouldNot(HaveOccurred())
+		store = NewDirStore(dir + "/accounts")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("saves and loads accounts", func() {
+		a := &Account{Name: "Thorin", Characters: []string{"Fili"}}
+
+		Ω(store.Save("thorin", a)).Should(Succeed())
+		loaded, err := store.Load("thorin")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(loaded.Characters).Should(Equal([]string{"Fili"}))
+
+		all, err := store.All()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(all).Should(HaveLen(1))
+	})
+
+	It("reports missing accounts", func() {
+		_, err := store.Load("thorin")
+		Ω(err).Should(Equal(ErrNotFound))
+
+		_, err = store.Load("../thorin")
+		Ω(err).Should(Equal(ErrNotFound))
+		Ω(store.Delete("thorin")).Should(Succeed())
+	})
+})
@@ -0,0 +1,511 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package account
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/spf13/viper"
+)
+
+// State is the step of logging in a player is on.
+type State int
+
+// Login states
+const (
+	// AskName asks for the name of the player's account.
+	AskName State = iota
+	// ConfirmName asks whether to create an account with a name that isn't
+	// taken.
+	ConfirmName
+	// AskPassword asks for the password of an existing account.
+	AskPassword
+	// NewPassword asks for the password of a new account.
+	NewPassword
+	// ConfirmPassword asks for the new password again.
+	ConfirmPassword
+	// AskEmail asks for the email address of a new account.
+	AskEmail
+	// ChooseCharacter asks which character to play.
+	ChooseCharacter
+	// CreateCharacter players are making a new character with the login's
+	// Creator.
+	CreateCharacter
+	// LoggedIn players have chosen a character and are in the game.
+	LoggedIn
+)
+
+// String returns the name of the state, used in its event.
+func (s State) String() string {
+	switch s {
+	case AskName:
+		return "name"
+	case ConfirmName:
+		return "confirm_name"
+	case AskPassword:
+		return "password"
+	case NewPassword:
+		return "new_password"
+	case ConfirmPassword:
+		return "confirm_password"
+	case AskEmail:
+		return "email"
+	case ChooseCharacter:
+		return "character"
+	case CreateCharacter:
+		return "create"
+	case LoggedIn:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// Events emitted while logging in. Each carries the caller's id and the name
+// of the account, once it's known.
+const (
+	// StateEventPrefix begins the event emitted as the player reaches each
+	// step, followed by the name of the state, like "login:password".
+	StateEventPrefix = "login:"
+	// CreatedEvent is emitted when a new account is made.
+	CreatedEvent = "account:created"
+	// LoginEvent is emitted when a player gives the right password.
+	LoginEvent = "account:login"
+	// FailedEvent is emitted when a player gives the wrong password, it
+	// includes the number of failures.
+	FailedEvent = "account:login_failed"
+)
+
+// Creator makes new characters during login. It's given the name the player
+// chose and takes over their input until the character is made.
+type Creator interface {
+	// ValidateName checks that a new character can have the name.
+	ValidateName(name string) error
+	// Create starts making the character for the account, returning the mode
+	// the player's input goes to until it's finished. done is called with the
+	// character's name once it's added to the account, or an empty name if
+	// the player gives up.
+	Create(caller command.Caller, a *Account, name string, done func(character string)) command.Mode
+}
+
+// TooManyFailuresMessage is shown before players giving the wrong password
+// too many times are disconnected.
+const TooManyFailuresMessage = "Too many failed attempts, goodbye."
+
+// Login walks a player through logging in, it's a command.Mode taking their
+// input until they're playing a character. Existing players give their
+// account's password, new players create an account by choosing a password
+// and optionally giving an email address. Either way they finish by choosing
+// a character to play.
+type Login struct {
+	accounts *Manager
+	emitter  *events.Emitter
+	state    State
+	name     string
+	password string
+	account  *Account
+	failures int
+	creator  Creator
+	creating command.Mode
+	created  string
+	check    func(account, character string) error
+	onDone   func(a *Account, character string)
+	onQuit   func()
+	mutex    *sync.Mutex
+}
+
+// NewLogin creates a login for accounts in the manager, emitting events to
+// em, which may be nil. The player logs in when done is called with their
+// account and character, quit is called if they give up or fail too often.
+func NewLogin(m *Manager, em *events.Emitter, done func(a *Account, character string), quit func()) *Login {
+	return &Login{
+		accounts: m,
+		emitter:  em,
+		onDone:   done,
+		onQuit:   quit,
+		mutex:    new(sync.Mutex),
+	}
+}
+
+// SetCreator sets how new characters are made, without one they're added to
+// the account as soon as they're named.
+func (l *Login) SetCreator(c Creator) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.creator = c
+}
+
+// SetCheck sets what's checked as players get into their account and choose
+// a character, like bans. It's given the account's name and the character's,
+// empty when checking the account, an error keeps them out and its message
+// is shown to them.
+func (l *Login) SetCheck(check func(account, character string) error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.check = check
+}
+
+// Start asks the caller for their account's name.
+func (l *Login) Start(caller command.Caller) {
+	l.enter(caller, AskName)
+}
+
+// State returns the step the player is on.
+func (l *Login) State() State {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.state
+}
+
+// Account returns the player's account once they've logged into it.
+func (l *Login) Account() *Account {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.account
+}
+
+// Input handles the caller's answer to the current step, returning true once
+// they're playing or have quit.
+func (l *Login) Input(caller command.Caller, line string) bool {
+	line = strings.TrimSpace(line)
+
+	switch l.State() {
+	case AskName:
+		return l.askName(caller, line)
+	case ConfirmName:
+		switch strings.ToLower(line) {
+		case "y", "yes":
+			l.enter(caller, NewPassword)
+		case "n", "no":
+			l.enter(caller, AskName)
+		default:
+			caller.Send("Please answer yes or no.")
+		}
+	case AskPassword:
+		return l.askPassword(caller, line)
+	case NewPassword:
+		if err := ValidatePassword(line); err != nil {
+			caller.Send(sentence(err))
+
+			break
+		}
+		l.mutex.Lock()
+		l.password = line
+		l.mutex.Unlock()
+		l.enter(caller, ConfirmPassword)
+	case ConfirmPassword:
+		l.mutex.Lock()
+		match := l.password == line
+		l.mutex.Unlock()
+		if !match {
+			caller.Send("The passwords don't match.")
+			l.enter(caller, NewPassword)
+
+			break
+		}
+		l.enter(caller, AskEmail)
+	case AskEmail:
+		return l.askEmail(caller, line)
+	case ChooseCharacter:
+		return l.chooseCharacter(caller, line)
+	case CreateCharacter:
+		return l.createCharacter(caller, line)
+	}
+
+	return false
+}
+
+func (l *Login) askName(caller command.Caller, line string) bool {
+	if strings.EqualFold(line, "quit") {
+		l.quit()
+
+		return true
+	}
+	if err := ValidateName(line); err != nil {
+		caller.Send(sentence(err))
+
+		return false
+	}
+
+	a, err := l.accounts.Get(line)
+	l.mutex.Lock()
+	l.name = line
+	if err == nil {
+		l.name = a.Name
+	}
+	l.mutex.Unlock()
+
+	switch err {
+	case nil:
+		l.enter(caller, AskPassword)
+	case ErrNotFound:
+		l.enter(caller, ConfirmName)
+	default:
+		caller.Send("Accounts can't be loaded right now, please try again later.")
+		l.quit()
+
+		return true
+	}
+
+	return false
+}
+
+func (l *Login) askPassword(caller command.Caller, line string) bool {
+	l.mutex.Lock()
+	name := l.name
+	l.mutex.Unlock()
+
+	a, err := l.accounts.Authenticate(name, line)
+	if err != nil {
+		l.mutex.Lock()
+		l.failures++
+		failures := l.failures
+		l.mutex.Unlock()
+		data := l.data(caller)
+		data["failures"] = failures
+		l.emit(FailedEvent, data)
+
+		if max := viper.GetInt("account.max_login_attempts"); max > 0 && failures >= max {
+			caller.Send(TooManyFailuresMessage)
+			l.quit()
+
+			return true
+		}
+		caller.Send("Wrong password.")
+		l.enter(caller, AskPassword)
+
+		return false
+	}
+
+	if err := l.allowed(a.Name, ""); err != nil {
+		caller.Send(err.Error())
+		l.quit()
+
+		return true
+	}
+
+	l.mutex.Lock()
+	l.account = a
+	l.mutex.Unlock()
+	l.emit(LoginEvent, l.data(caller))
+	l.enter(caller, ChooseCharacter)
+
+	return false
+}
+
+func (l *Login) askEmail(caller command.Caller, line string) bool {
+	if err := ValidateEmail(line); err != nil {
+		caller.Send(sentence(err))
+
+		return false
+	}
+
+	l.mutex.Lock()
+	name, password := l.name, l.password
+	l.password = ""
+	l.mutex.Unlock()
+
+	a, err := l.accounts.Create(name, password, line)
+	if err != nil {
+		caller.Send(sentence(err))
+		l.enter(caller, AskName)
+
+		return false
+	}
+
+	l.mutex.Lock()
+	l.account = a
+	l.mutex.Unlock()
+	l.emit(CreatedEvent, l.data(caller))
+	l.enter(caller, ChooseCharacter)
+
+	return false
+}
+
+func (l *Login) chooseCharacter(caller command.Caller, line string) bool {
+	a := l.Account()
+	if character := a.character(line); character != "" {
+		if err := l.allowed(a.Name, character); err != nil {
+			caller.Send(err.Error())
+
+			return false
+		}
+
+		return l.finish(a, caller, character)
+	}
+
+	if err := ValidateName(line); err != nil {
+		caller.Send(sentence(err))
+
+		return false
+	}
+	if _, err := l.accounts.Owner(line); err == nil {
+		caller.Send(sentence(ErrTaken))
+
+		return false
+	}
+	if err := l.allowed(a.Name, line); err != nil {
+		caller.Send(err.Error())
+
+		return false
+	}
+
+	l.mutex.Lock()
+	creator := l.creator
+	l.mutex.Unlock()
+	if creator == nil {
+		if err := l.accounts.AddCharacter(a, line); err != nil {
+			caller.Send(sentence(err))
+
+			return false
+		}
+
+		return l.finish(a, caller, line)
+	}
+
+	if err := creator.ValidateName(line); err != nil {
+		caller.Send(sentence(err))
+
+		return false
+	}
+	l.mutex.Lock()
+	l.created = ""
+	l.mutex.Unlock()
+	l.enter(caller, CreateCharacter)
+	mode := creator.Create(caller, a, line, func(character string) {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		l.created = character
+	})
+	l.mutex.Lock()
+	l.creating = mode
+	l.mutex.Unlock()
+
+	return false
+}
+
+// createCharacter passes input to the creator until it's finished
+func (l *Login) createCharacter(caller command.Caller, line string) bool {
+	l.mutex.Lock()
+	mode := l.creating
+	l.mutex.Unlock()
+
+	if !mode.Input(caller, line) {
+		return false
+	}
+
+	l.mutex.Lock()
+	character := l.created
+	l.creating = nil
+	l.mutex.Unlock()
+	if character == "" {
+		l.enter(caller, ChooseCharacter)
+
+		return false
+	}
+
+	return l.finish(l.Account(), caller, character)
+}
+
+// finish logs the player in as the character
+func (l *Login) finish(a *Account, caller command.Caller, character string) bool {
+	l.enter(caller, LoggedIn)
+	if l.onDone != nil {
+		l.onDone(a, character)
+	}
+
+	return true
+}
+
+// enter moves to the state, asking its question
+func (l *Login) enter(caller command.Caller, s State) {
+	l.mutex.Lock()
+	l.state = s
+	name := l.name
+	a := l.account
+	l.mutex.Unlock()
+
+	switch s {
+	case AskName:
+		caller.Send("By what name do you wish to be known?")
+	case ConfirmName:
+		caller.Send(fmt.Sprintf("There's no account named %s, create it? (y/n)", name))
+	case AskPassword:
+		caller.Send("Password:")
+	case NewPassword:
+		caller.Send(fmt.Sprintf("Choose a password at least %d characters long:", viper.GetInt("account.min_password_length")))
+	case ConfirmPassword:
+		caller.Send("Type the password again:")
+	case AskEmail:
+		caller.Send("Enter your email address, or leave it blank:")
+	case ChooseCharacter:
+		if len(a.Characters) == 0 {
+			caller.Send("Type a name for your first character:")
+
+			break
+		}
+		caller.Send(fmt.Sprintf(
+			"Your characters: %s\nType the name of a character to play, or a new name to create one:",
+			strings.Join(a.Characters, ", "),
+		))
+	}
+
+	data := l.data(caller)
+	data["state"] = s.String()
+	l.emit(StateEventPrefix+s.String(), data)
+}
+
+// allowed returns why the player can't have the account or character, if
+// the check keeps them out
+func (l *Login) allowed(account, character string) error {
+	l.mutex.Lock()
+	check := l.check
+	l.mutex.Unlock()
+
+	if check == nil {
+		return nil
+	}
+
+	return check(account, character)
+}
+
+func (l *Login) quit() {
+	if l.onQuit != nil {
+		l.onQuit()
+	}
+}
+
+func (l *Login) data(caller command.Caller) events.Data {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return events.Data{
+		"caller":  caller.ID(),
+		"account": l.name,
+	}
+}
+
+func (l *Login) emit(evt string, data events.Data) {
+	if l.emitter != nil {
+		l.emitter.Emit(evt, data)
+	}
+}
+
+// sentence turns an error into a sentence to show the player
+func sentence(err error) string {
+	msg := err.Error()
+	if msg == "" {
+		return msg
+	}
+
+	return strings.ToUpper(msg[:1]) + msg[1:] + "."
+}
@@ -0,0 +1,186 @@
+package account_test
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/account"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// visitor records what they're asked while logging in
+type visitor struct {
+	sent []string
+}
+
+func (v *visitor) ID() string {
+	return "visitor-1"
+}
+
+func (v *visitor) Level() command.Level {
+	return command.Player
+}
+
+func (v *visitor) Send(text string) error {
+	v.sent = append(v.sent, text)
+
+	return nil
+}
+
+func (v *visitor) last() string {
+	return v.sent[len(v.sent)-1]
+}
+
+var _ = Describe("Login", func() {
+	var (
+		m         *Manager
+		em        *events.Emitter
+		v         *visitor
+		l         *Login
+		character string
+		quit      bool
+		received  chan string
+		input     func(lines ...string) bool
+	)
+
+	BeforeEach(func() {
+		m = NewManager(NewMemoryStore())
+		em = events.NewEmitter(nil)
+		v = new(visitor)
+		character, quit = "", false
+		received = make(chan string, 50)
+		l = NewLogin(m, em, func(_ *Account, c string) {
+			character = c
+		}, func() {
+			quit = true
+		})
+		input = func(lines ...string) bool {
+			done := false
+			for _, line := range lines {
+				done = l.Input(v, line)
+			}
+
+			return done
+		}
+	})
+
+	listen := func(evts ...string) {
+		for _, evt := range evts {
+			evt := evt
+			em.On(evt, events.HandlerFunc(func(d events.Data) error {
+				received <- evt + " " + d["account"].(string)
+
+				return nil
+			}))
+		}
+	}
+
+	It("creates accounts for new players", func() {
+		listen(CreatedEvent, StateEventPrefix+"email")
+		l.Start(v)
+
+		Ω(input("Thorin", "yes", "oakenshield", "oakenshield")).Should(BeFalse())
+		Ω(l.State()).Should(Equal(AskEmail))
+		Ω(input("thorin@example.com")).Should(BeFalse())
+		Ω(l.State()).Should(Equal(ChooseCharacter))
+		Ω(v.last()).Should(Equal("Type a name for your first character:"))
+		Ω(input("Fili")).Should(BeTrue())
+
+		Ω(character).Should(Equal("Fili"))
+		Ω(l.State()).Should(Equal(LoggedIn))
+		a, err := m.Get("thorin")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.Email).Should(Equal("thorin@example.com"))
+		Ω(a.Characters).Should(Equal([]string{"Fili"}))
+		Eventually(received).Should(Receive(Equal("login:email Thorin")))
+		Eventually(received).Should(Receive(Equal("account:created Thorin")))
+	})
+
+	It("asks for new passwords again when they don't match", func() {
+		l.Start(v)
+		input("Thorin", "y", "oakenshield", "oakenshielf")
+
+		Ω(l.State()).Should(Equal(NewPassword))
+		Ω(v.sent).Should(ContainElement("The passwords don't match."))
+	})
+
+	It("lets players pick another name", func() {
+		l.Start(v)
+		input("Thorin", "n")
+
+		Ω(l.State()).Should(Equal(AskName))
+	})
+
+	It("logs existing players into their characters", func() {
+		a, _ := m.Create("Thorin", "oakenshield", "")
+		m.AddCharacter(a, "Kili")
+		listen(LoginEvent)
+		l.Start(v)
+
+		Ω(input("thorin", "oakenshield")).Should(BeFalse())
+		Ω(v.last()).Should(ContainSubstring("Your characters: Kili"))
+		Ω(input("kili")).Should(BeTrue())
+		Ω(character).Should(Equal("Kili"))
+		Ω(l.Account().Name).Should(Equal("Thorin"))
+		Eventually(received).Should(Receive(Equal("account:login Thorin")))
+	})
+
+	It("disconnects players who fail too often", func() {
+		m.Create("Thorin", "oakenshield", "")
+		listen(FailedEvent)
+		l.Start(v)
+
+		Ω(input("thorin", "one", "two")).Should(BeFalse())
+		Ω(quit).Should(BeFalse())
+		Ω(input("three")).Should(BeTrue())
+		Ω(quit).Should(BeTrue())
+		Ω(v.last()).Should(Equal(TooManyFailuresMessage))
+		Eventually(received).Should(Receive(Equal("account:login_failed Thorin")))
+	})
+
+	It("refuses characters owned by others", func() {
+		a, _ := m.Create("Bilbo", "baggins12", "")
+		m.AddCharacter(a, "Fili")
+		m.Create("Thorin", "oakenshield", "")
+		l.Start(v)
+
+		Ω(input("thorin", "oakenshield", "fili")).Should(BeFalse())
+		Ω(v.last()).Should(Equal("That name is taken."))
+	})
+
+	It("keeps out accounts and characters the check refuses", func() {
+		a, _ := m.Create("Thorin", "oakenshield", "")
+		m.AddCharacter(a, "Kili")
+		m.Create("Smaug", "goldhoard", "")
+		l.SetCheck(func(account, character string) error {
+			if account == "Smaug" || character == "Kili" || character == "Azog" {
+				return errors.New("You are banned.")
+			}
+
+			return nil
+		})
+		l.Start(v)
+
+		Ω(input("thorin", "oakenshield", "kili")).Should(BeFalse())
+		Ω(v.last()).Should(Equal("You are banned."))
+		Ω(input("Azog")).Should(BeFalse())
+		Ω(v.last()).Should(Equal("You are banned."))
+		Ω(input("Fili")).Should(BeTrue())
+		Ω(character).Should(Equal("Fili"))
+
+		l.Start(v)
+		Ω(input("smaug", "goldhoard")).Should(BeTrue())
+		Ω(quit).Should(BeTrue())
+		Ω(v.last()).Should(Equal("You are banned."))
+	})
+
+	It("lets players quit", func() {
+		l.Start(v)
+
+		Ω(input("quit")).Should(BeTrue())
+		Ω(quit).Should(BeTrue())
+	})
+})
@@ -0,0 +1,105 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package account
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrInvalidHash is returned when a stored password hash can't be read.
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// HashParams tune the cost of hashing passwords with argon2id, higher costs
+// are slower to guess but also slower to log in with.
+type HashParams struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+	// Memory is the memory used in KiB.
+	Memory uint32
+	// Threads is the number of threads used.
+	Threads uint8
+}
+
+// the lengths of the random salt and of the hash, in bytes
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// DefaultHashParams returns the parameters from the account.hash settings.
+func DefaultHashParams() HashParams {
+	return HashParams{
+		Time:    uint32(viper.GetInt("account.hash.time")),
+		Memory:  uint32(viper.GetInt("account.hash.memory")),
+		Threads: uint8(viper.GetInt("account.hash.threads")),
+	}
+}
+
+// HashPassword hashes the password with a random salt, the result holds the
+// parameters and salt used so they can change without breaking older hashes.
+// It looks like "$argon2id$v=19$m=65536,t=1,p=2$<salt>$<hash>".
+func HashPassword(password string, p HashParams) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory,
+		p.Time,
+		p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword is true if the password matches the hash.
+func CheckPassword(password, hash string) (bool, error) {
+	p, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// decodeHash reads the parameters, salt and key out of a hash
+func decodeHash(hash string) (HashParams, []byte, []byte, error) {
+	var p HashParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package account
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/fs"
+)
+
+// Store persists accounts, keyed by their lower case name.
+type Store interface {
+	// Save creates or replaces the account.
+	Save(key string, a *Account) error
+	// Load returns the account, or ErrNotFound.
+	Load(key string) (*Account, error)
+	// Delete removes the account.
+	Delete(key string) error
+	// All returns every account.
+	All() ([]*Account, error)
+}
+
+// DirStore saves each account as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving accounts to the directory, the directory
+// is created when the first account is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Save writes the account to <key>.json, through a temporary file so a crash
+// never leaves a partially written account behind.
+func (s *DirStore) Save(key string, a *Account) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, key+".json")
+	return fs.WriteFile(path, contents, 0600)
+}
+
+// Load reads the account's file.
+func (s *DirStore) Load(key string) (*Account, error) {
+	if strings.ContainsAny(key, `/\.`) {
+		return nil, ErrNotFound
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a := new(Account)
+	if err := json.Unmarshal(contents, a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Delete removes the account's file, missing files are ignored.
+func (s *DirStore) Delete(key string) error {
+	err := os.Remove(filepath.Join(s.Dir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// All reads every account file in the directory.
+func (s *DirStore) All() ([]*Account, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []*Account
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+
+		a, err := s.Load(strings.TrimSuffix(fi.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, nil
+}
+
+// MemoryStore keeps accounts in memory, they're lost when the server stops.
+type MemoryStore struct {
+	accounts map[string]Account
+	mutex    *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts: make(map[string]Account),
+		mutex:    new(sync.Mutex),
+	}
+}
+
+// Save stores a copy of the account.
+func (s *MemoryStore) Save(key string, a *Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := *a
+	stored.Characters = append([]string(nil), a.Characters...)
+	s.accounts[key] = stored
+
+	return nil
+}
+
+// Load returns a copy of the account.
+func (s *MemoryStore) Load(key string) (*Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.accounts[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	a.Characters = append([]string(nil), a.Characters...)
+
+	return &a, nil
+}
+
+// Delete removes the account.
+func (s *MemoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.accounts, key)
+
+	return nil
+}
+
+// All returns copies of every account.
+func (s *MemoryStore) All() ([]*Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	accounts := make([]*Account, 0, len(s.accounts))
+	for _, a := range s.accounts {
+		stored := a
+		stored.Characters = append([]string(nil), a.Characters...)
+		accounts = append(accounts, &stored)
+	}
+
+	return accounts, nil
+}
+
+// DataStore saves accounts in the "accounts" bucket of a data store, like
+// the game's database.
+type DataStore struct {
+	bucket *data.Bucket
+}
+
+// NewDataStore creates a store saving accounts in the data store.
+func NewDataStore(s data.Store) *DataStore {
+	return &DataStore{bucket: data.NewBucket(s, "accounts")}
+}
+
+// Save stores the account as JSON.
+func (s *DataStore) Save(key string, a *Account) error {
+	return s.bucket.Save(key, a)
+}
+
+// Load reads the account.
+func (s *DataStore) Load(key string) (*Account, error) {
+	a := new(Account)
+	err := s.bucket.Load(key, a)
+	if err == data.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Delete removes the account.
+func (s *DataStore) Delete(key string) error {
+	return s.bucket.Delete(key)
+}
+
+// All reads every account in the bucket.
+func (s *DataStore) All() ([]*Account, error) {
+	keys, err := s.bucket.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []*Account
+	for _, key := range keys {
+		a, err := s.Load(key)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,343 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package admin carries out what the game's staff ask of it: going anywhere,
+// bringing players to them, making players type commands, stopping,
+// restarting or upgrading the game in place, and resetting zones. The
+// commands need both a level and a capability, see the perm package, so
+// staff can be trusted with some of them and not others.
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// Events emitted by staff. Handlers of before:admin:force and
+// before:admin:shutdown can stop them by returning events.ErrHalt, or an
+// error whose message is told to the staff member. Forcing is given the
+// name of the staff member, the player forced and the command, shutdowns
+// are given the name, the reason, whether the game will reboot and whether
+// it's a copyover.
+const (
+	ForceEvent    = "admin:force"
+	ShutdownEvent = "admin:shutdown"
+)
+
+// Messages told to staff who can't do something.
+const (
+	CancelMessage  = "You can't do that right now."
+	NoStopMessage  = "The game can't be stopped from here."
+	OutrankMessage = "You can't force someone of your rank or above."
+)
+
+// Staffer is a staff member acting on the game, like a player.
+type Staffer interface {
+	movement.Mover
+	Level() command.Level
+}
+
+// Player is a player in the game that staff act on, as both a mover and
+// the caller typing their commands.
+type Player interface {
+	movement.Mover
+	command.Caller
+}
+
+// Stop is why the game is stopping, and whether it starts again. A copyover
+// reboots keeping players connected, it's also a reboot.
+type Stop struct {
+	By       string
+	Reason   string
+	Reboot   bool
+	Copyover bool
+}
+
+// Manager carries out what staff ask.
+type Manager struct {
+	world    *world.World
+	movement *movement.Movement
+	players  func() []Player
+	force    func(p Player, line string)
+	stop     func(Stop)
+	reset    func(zone string) error
+	emitter  *events.Emitter
+	mutex    *sync.RWMutex
+}
+
+// NewManager creates a manager moving players around the world with the
+// Movement. The emitter may be nil.
+func NewManager(w *world.World, mv *movement.Movement, em *events.Emitter) *Manager {
+	return &Manager{
+		world:    w,
+		movement: mv,
+		emitter:  em,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's admin manager, for the game's world.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), movement.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetPlayers sets how to find the players in the game. Without it staff
+// can't find anyone.
+func (m *Manager) SetPlayers(fn func() []Player) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.players = fn
+}
+
+// SetForce sets how a player is made to type a line, like queueing it with
+// their own input.
+func (m *Manager) SetForce(fn func(p Player, line string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.force = fn
+}
+
+// SetStop sets what stops the game, it's called once players have been told
+// why.
+func (m *Manager) SetStop(fn func(Stop)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stop = fn
+}
+
+// SetReset sets what resets a zone, running its resets now.
+func (m *Manager) SetReset(fn func(zone string) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reset = fn
+}
+
+// Player returns the player in the game with the name, in any case.
+func (m *Manager) Player(name string) (Player, bool) {
+	for _, p := range m.online() {
+		if strings.EqualFold(p.Name(), name) {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// Goto takes the staff member to the room with the id, or to the player
+// with the name if there's no such room.
+func (m *Manager) Goto(s Staffer, where string) error {
+	room := where
+	if _, ok := m.world.Room(where); !ok {
+		p, ok := m.Player(where)
+		if !ok {
+			return refuse(fmt.Sprintf("There's no room or player %q.", where))
+		}
+		room = p.Location()
+	}
+
+	return m.movement.Teleport(s, room)
+}
+
+// Transfer brings the player with the name to the room, the staff member's
+// own room if it's empty.
+func (m *Manager) Transfer(s Staffer, name, room string) (Player, error) {
+	p, ok := m.Player(name)
+	if !ok {
+		return nil, refuse(fmt.Sprintf("There's nobody called %q in the game.", name))
+	}
+	if room == "" {
+		room = s.Location()
+	}
+	if err := m.movement.Teleport(p, room); err != nil {
+		return nil, err
+	}
+	p.Send(fmt.Sprintf("%s has brought you here.", s.Name()))
+
+	return p, nil
+}
+
+// Force makes the player with the name type the line as though they had.
+// Only admins force those at their own level or above.
+func (m *Manager) Force(s Staffer, name, line string) error {
+	p, ok := m.Player(name)
+	if !ok {
+		return refuse(fmt.Sprintf("There's nobody called %q in the game.", name))
+	}
+	if p.Level() >= s.Level() && s.Level() < command.Admin {
+		return refuse(OutrankMessage)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return refuse("What should they do?")
+	}
+
+	m.mutex.RLock()
+	force := m.force
+	m.mutex.RUnlock()
+
+	if force == nil {
+		return refuse("Nobody can be forced to do anything.")
+	}
+	data := events.Data{
+		"staff":   s.Name(),
+		"player":  p.Name(),
+		"command": line,
+	}
+	if err := m.check(ForceEvent, data); err != nil {
+		return err
+	}
+	force(p, line)
+	m.confirm(ForceEvent, data)
+
+	return nil
+}
+
+// Shutdown tells every player the game is stopping and why, then stops it,
+// starting it again if it's a reboot.
+func (m *Manager) Shutdown(s Staffer, reason string, reboot bool) error {
+	text := "The game is shutting down"
+	if reboot {
+		text = "The game is rebooting, come back in a moment"
+	}
+
+	return m.halt(s, Stop{By: s.Name(), Reason: reason, Reboot: reboot}, text)
+}
+
+// Copyover tells every player the game is being upgraded and why, then
+// starts it again in place without disconnecting them.
+func (m *Manager) Copyover(s Staffer, reason string) error {
+	text := "The world shimmers as it's rebuilt around you, hold on a moment"
+
+	return m.halt(s, Stop{By: s.Name(), Reason: reason, Reboot: true, Copyover: true}, text)
+}
+
+// halt tells every player the text along with the reason, then stops the
+// game
+func (m *Manager) halt(s Staffer, st Stop, text string) error {
+	m.mutex.RLock()
+	stop := m.stop
+	m.mutex.RUnlock()
+
+	if stop == nil {
+		return refuse(NoStopMessage)
+	}
+	st.Reason = strings.TrimSpace(st.Reason)
+	data := events.Data{
+		"staff":    st.By,
+		"reason":   st.Reason,
+		"reboot":   st.Reboot,
+		"copyover": st.Copyover,
+	}
+	if err := m.check(ShutdownEvent, data); err != nil {
+		return err
+	}
+
+	if st.Reason != "" {
+		text += ": " + st.Reason
+	}
+	for _, p := range m.online() {
+		p.Send(text + ".")
+	}
+	m.confirm(ShutdownEvent, data)
+	stop(st)
+
+	return nil
+}
+
+// Reset runs the resets of the zone with the id now, the zone of the staff
+// member's room if it's empty. The zone is returned even if some of its
+// resets failed.
+func (m *Manager) Reset(s Staffer, zone string) (string, error) {
+	m.mutex.RLock()
+	reset := m.reset
+	m.mutex.RUnlock()
+
+	if reset == nil {
+		return "", refuse("Zones can't be reset from here.")
+	}
+	if zone == "" {
+		r, ok := m.world.Room(s.Location())
+		if !ok {
+			return "", refuse("You aren't in a zone.")
+		}
+		zone = r.Zone
+	}
+	if _, ok := m.world.Zone(zone); !ok {
+		return "", refuse(fmt.Sprintf("There's no zone %q.", zone))
+	}
+
+	return zone, reset(zone)
+}
+
+// online returns the players in the game
+func (m *Manager) online() []Player {
+	m.mutex.RLock()
+	players := m.players
+	m.mutex.RUnlock()
+
+	if players == nil {
+		return nil
+	}
+
+	return players()
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
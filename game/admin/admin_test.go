@@ -0,0 +1,184 @@
+package admin_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/admin"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// player is someone in the game who remembers what they're told and typed
+type player struct {
+	name     string
+	location string
+	level    command.Level
+	sent     []string
+	typed    []string
+}
+
+func (p *player) ID() string {
+	return strings.ToLower(p.name)
+}
+
+func (p *player) Name() string {
+	return p.name
+}
+
+func (p *player) Location() string {
+	return p.location
+}
+
+func (p *player) SetLocation(room string) {
+	p.location = room
+}
+
+func (p *player) Level() command.Level {
+	return p.level
+}
+
+func (p *player) Send(text string) error {
+	p.sent = append(p.sent, text)
+
+	return nil
+}
+
+func (p *player) told(text string) bool {
+	return strings.Contains(strings.Join(p.sent, "\n"), text)
+}
+
+var _ = Describe("Admin", func() {
+	var (
+		em              *events.Emitter
+		m               *Manager
+		ann, bob, carla *player
+		stopped         []Stop
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "square", Zone: "town", Name: "Town Square"},
+			{ID: "vault", Zone: "town", Name: "The Vault"},
+			{ID: "jail", Zone: "town", Name: "The Jail"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+
+		em = events.NewEmitter(nil)
+		m = NewManager(w, movement.New(w, nil), em)
+		ann = &player{name: "Ann", location: "square", level: command.Staff}
+		bob = &player{name: "Bob", location: "vault"}
+		carla = &player{name: "Carla", location: "jail", level: command.Staff}
+		m.SetPlayers(func() []Player {
+			return []Player{ann, bob, carla}
+		})
+		m.SetForce(func(p Player, line string) {
+			p.(*player).typed = append(p.(*player).typed, line)
+		})
+		stopped = nil
+		m.SetStop(func(s Stop) {
+			stopped = append(stopped, s)
+		})
+	})
+
+	It("goes to rooms and players", func() {
+		Ω(m.Goto(ann, "jail")).Should(Succeed())
+		Ω(ann.location).Should(Equal("jail"))
+		Ω(m.Goto(ann, "bob")).Should(Succeed())
+		Ω(ann.location).Should(Equal("vault"))
+		Ω(ann.told("The Vault")).Should(BeTrue())
+		Ω(m.Goto(ann, "moon")).Should(MatchError("There's no room or player \"moon\"."))
+	})
+
+	It("transfers players to the staff member or a room", func() {
+		_, err := m.Transfer(ann, "BOB", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(bob.location).Should(Equal("square"))
+		Ω(bob.told("Ann has brought you here.")).Should(BeTrue())
+
+		_, err = m.Transfer(ann, "bob", "jail")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(bob.location).Should(Equal("jail"))
+		_, err = m.Transfer(ann, "dave", "")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("forces players below the staff member", func() {
+		Ω(m.Force(ann, "bob", "say hello")).Should(Succeed())
+		Ω(bob.typed).Should(Equal([]string{"say hello"}))
+		Ω(m.Force(ann, "carla", "quit")).Should(MatchError(OutrankMessage))
+
+		ann.level = command.Admin
+		em.On("before:"+ForceEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["command"] == "quit" {
+				return errors.New("Not that.")
+			}
+
+			return nil
+		}))
+		Ω(m.Force(ann, "carla", "quit")).Should(MatchError("Not that."))
+		Ω(m.Force(ann, "carla", "look")).Should(Succeed())
+		Ω(carla.typed).Should(Equal([]string{"look"}))
+	})
+
+	It("tells everyone why the game stops before stopping it", func() {
+		Ω(m.Shutdown(ann, "new areas", true)).Should(Succeed())
+		Ω(bob.told("The game is rebooting, come back in a moment: new areas.")).Should(BeTrue())
+		Ω(stopped).Should(Equal([]Stop{{By: "Ann", Reason: "new areas", Reboot: true}}))
+
+		Ω(m.Copyover(ann, "")).Should(Succeed())
+		Ω(bob.told("The world shimmers as it's rebuilt around you, hold on a moment.")).Should(BeTrue())
+		Ω(stopped[1]).Should(Equal(Stop{By: "Ann", Reboot: true, Copyover: true}))
+
+		m.SetStop(nil)
+		Ω(m.Shutdown(ann, "", false)).Should(MatchError(NoStopMessage))
+	})
+
+	It("resets zones", func() {
+		_, err := m.Reset(ann, "")
+		Ω(err).Should(MatchError("Zones can't be reset from here."))
+		var reset []string
+		m.SetReset(func(zone string) error {
+			reset = append(reset, zone)
+			if len(reset) > 1 {
+				return errors.New("zone town reset 1: no such NPC")
+			}
+
+			return nil
+		})
+
+		Ω(m.Reset(ann, "")).Should(Equal("town"))
+		_, err = m.Reset(ann, "moon")
+		Ω(err).Should(MatchError("There's no zone \"moon\"."))
+		_, err = m.Reset(ann, "town")
+		Ω(err).Should(MatchError("zone town reset 1: no such NPC"))
+		Ω(reset).Should(Equal([]string{"town", "town"}))
+	})
+
+	It("runs the commands for staff", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Staffer { return c.(*player) }) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		Ω(d.Dispatch(bob, "goto jail")).Should(Equal(command.ErrUnknown))
+		d.Dispatch(ann, "goto nowhere")
+		d.Dispatch(ann, "transfer bob")
+		d.Dispatch(ann, "force bob smile")
+		d.Dispatch(ann, "zreset")
+		Ω(d.Dispatch(ann, "shutdown")).Should(Equal(command.ErrUnknown))
+		Ω(ann.sent).Should(ContainElement("There's no room or player \"nowhere\"."))
+		Ω(ann.sent).Should(ContainElement("Bob is transferred."))
+		Ω(ann.sent).Should(ContainElement("Zones can't be reset from here."))
+		Ω(bob.typed).Should(Equal([]string{"smile"}))
+	})
+})
@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package admin
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/perm"
+)
+
+// NewCommands creates the goto and transfer commands staff move themselves
+// and players with, force to make a player type a command, zreset to reset
+// a zone now and the shutdown and reboot commands admins stop the game with.
+func NewCommands(m *Manager, resolve command.Resolver[Staffer]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:       "goto",
+			Args:       []command.Arg{{Name: "where", Kind: command.Word}},
+			Level:      command.Staff,
+			Capability: perm.Goto,
+			Help:       "Takes you to the room with the id given, or to the player with the name.",
+			Source:     "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+				return sendRefused(ctx, m.Goto(s, ctx.String("where")))
+			}),
+		},
+		{
+			Name: "transfer",
+			Args: []command.Arg{
+				{Name: "player", Kind: command.Word},
+				{Name: "room", Kind: command.Word, Optional: true},
+			},
+			Level:      command.Staff,
+			Capability: perm.Transfer,
+			Help:       "Brings a player to you, or to the room with the id given.",
+			Source:     "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+				p, err := m.Transfer(s, ctx.String("player"), ctx.String("room"))
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(fmt.Sprintf("%s is transferred.", p.Name()))
+			}),
+		},
+		{
+			Name: "force",
+			Args: []command.Arg{
+				{Name: "player", Kind: command.Word},
+				{Name: "command", Kind: command.Text},
+			},
+			Level:      command.Staff,
+			Capability: perm.Force,
+			Help:       "Makes a player type the command given, as though they had.",
+			Source:     "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+				if err := m.Force(s, ctx.String("player"), ctx.String("command")); err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send("Done.")
+			}),
+		},
+		{
+			Name:       "zreset",
+			Args:       []command.Arg{{Name: "zone", Kind: command.Word, Optional: true}},
+			Level:      command.Staff,
+			Capability: perm.Reset,
+			Help:       "Resets the zone with the id given now, or the zone you're in.",
+			Source:     "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+				zone, err := m.Reset(s, ctx.String("zone"))
+				if _, ok := err.(*item.Refused); ok {
+					return sendRefused(ctx, err)
+				}
+				if err != nil {
+					return ctx.Send(fmt.Sprintf("%s is reset, but %s.", zone, err))
+				}
+
+				return ctx.Send(fmt.Sprintf("%s is reset.", zone))
+			}),
+		},
+		stopCommand(m, resolve, "shutdown", perm.Shutdown, false),
+		stopCommand(m, resolve, "reboot", perm.Reboot, true),
+		{
+			Name:       "copyover",
+			Args:       []command.Arg{{Name: "reason", Kind: command.Text, Optional: true}},
+			Level:      command.Admin,
+			Capability: perm.Copyover,
+			Help:       "Saves everyone and restarts the game in place, keeping players connected.",
+			Source:     "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+				return sendRefused(ctx, m.Copyover(s, ctx.String("reason")))
+			}),
+		},
+	}
+}
+
+// stopCommand creates the command stopping the game, and starting it again
+// if it reboots
+func stopCommand(m *Manager, resolve command.Resolver[Staffer], name, capability string, reboot bool) *command.Command {
+	help := "Saves everyone and shuts the game down, telling players the reason given."
+	if reboot {
+		help = "Saves everyone and restarts the game, telling players the reason given."
+	}
+
+	return &command.Command{
+		Name:       name,
+		Args:       []command.Arg{{Name: "reason", Kind: command.Text, Optional: true}},
+		Level:      command.Admin,
+		Capability: capability,
+		Help:       help,
+		Source:     "game",
+		Handler: handler(resolve, func(ctx *command.Context, s Staffer) error {
+			return sendRefused(ctx, m.Shutdown(s, ctx.String("reason"), reboot))
+		}),
+	}
+}
+
+// sendRefused tells the staff member why they were refused, other errors are
+// returned
+func sendRefused(ctx *command.Context, err error) error {
+	switch e := err.(type) {
+	case *item.Refused:
+		return ctx.Send(e.Message)
+	case *movement.Blocked:
+		return ctx.Send(e.Message)
+	}
+
+	return err
+}
+
+// handler resolves the caller's staff member for fn
+func handler(resolve command.Resolver[Staffer], fn func(*command.Context, Staffer) error) command.Handler {
+	return func(ctx *command.Context) error {
+		s := resolve(ctx.Caller)
+		if s == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		return fn(ctx, s)
+	}
+}
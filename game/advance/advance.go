@@ -0,0 +1,264 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package advance levels players up as they earn experience. How much
+// experience each level takes follows a curve, and what players gain as they
+// reach a level is defined by gains in the advancement file, for every
+// player or those of a class or race, and by hooks written in Go or Lua.
+// Players at a high enough level can remort, starting over from the first
+// level with bonuses for each time they have.
+package advance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The kinds of curve.
+const (
+	// Linear curves take Base more experience for each level.
+	Linear = "linear"
+	// Exponential curves take Base for the second level and Factor times
+	// what the last level took for each after.
+	Exponential = "exponential"
+	// Table curves list the experience each level takes.
+	Table = "table"
+)
+
+// Kinds lists the kinds of curve.
+var Kinds = []string{Linear, Exponential, Table}
+
+// Curve decides how much experience each level takes.
+type Curve struct {
+	Kind   string  `yaml:"kind"`
+	Base   int     `yaml:"base,omitempty"`
+	Factor float64 `yaml:"factor,omitempty"`
+	// Levels are the experience totals needed for the second level onward,
+	// for table curves.
+	Levels []int `yaml:"levels,omitempty"`
+	// Max is the highest level, zero is no limit.
+	Max int `yaml:"max,omitempty"`
+}
+
+// DefaultCurve is used without an advancement file.
+var DefaultCurve = Curve{Kind: Exponential, Base: 1000, Factor: 1.5, Max: 50}
+
+// validate fills in defaults and checks the curve makes sense
+func (c *Curve) validate() error {
+	c.Kind = strings.ToLower(c.Kind)
+	if c.Kind == Exponential && c.Factor == 0 {
+		c.Factor = 1.5
+	}
+	switch {
+	case c.Kind != Linear && c.Kind != Exponential && c.Kind != Table:
+		return fmt.Errorf("curve: the kind is one of %v, not %q", Kinds, c.Kind)
+	case c.Kind != Table && c.Base < 1:
+		return fmt.Errorf("curve: %s curves need a base of at least 1", c.Kind)
+	case c.Kind == Exponential && c.Factor < 1:
+		return fmt.Errorf("curve: the factor can't be less than 1")
+	case c.Kind == Table && len(c.Levels) == 0:
+		return fmt.Errorf("curve: table curves need levels")
+	case c.Max < 0:
+		return fmt.Errorf("curve: max can't be negative")
+	}
+	for i := 1; i < len(c.Levels); i++ {
+		if c.Levels[i] <= c.Levels[i-1] {
+			return fmt.Errorf("curve: levels must keep rising, level %d takes no more than level %d", i+2, i+1)
+		}
+	}
+
+	return nil
+}
+
+// Needed returns the experience it takes to reach the level, or -1 if it
+// can't be reached.
+func (c Curve) Needed(level int) int {
+	switch {
+	case level <= 1:
+		return 0
+	case c.Max > 0 && level > c.Max:
+		return -1
+	}
+
+	switch c.Kind {
+	case Linear:
+		return c.Base * (level - 1)
+	case Exponential:
+		total, step := 0.0, float64(c.Base)
+		for i := 1; i < level; i++ {
+			total += step
+			step *= c.Factor
+		}
+		if total > math.MaxInt32 {
+			return -1
+		}
+
+		return int(total + 0.5)
+	case Table:
+		if level-2 < len(c.Levels) {
+			return c.Levels[level-2]
+		}
+	}
+
+	return -1
+}
+
+// Level returns the level the experience reaches.
+func (c Curve) Level(xp int) int {
+	level := 1
+	for {
+		next := c.Needed(level + 1)
+		if next < 0 || xp < next {
+			return level
+		}
+		level++
+	}
+}
+
+// Gain is what players gain as they reach a level.
+type Gain struct {
+	// Class and Race limit the gain to players of them, it's for everyone
+	// when they're empty.
+	Class string `yaml:"class,omitempty"`
+	Race  string `yaml:"race,omitempty"`
+	// MinLevel and MaxLevel limit the levels the gain is for, zero is no
+	// limit.
+	MinLevel int `yaml:"min_level,omitempty"`
+	MaxLevel int `yaml:"max_level,omitempty"`
+	// Every makes the gain only for levels divisible by it, every level by
+	// default.
+	Every int `yaml:"every,omitempty"`
+	// Stats are added to the player's stats.
+	Stats map[string]int `yaml:"stats,omitempty"`
+	// Skills raise how well the player knows each skill, by percent, those
+	// they don't know are learned.
+	Skills map[string]int `yaml:"skills,omitempty"`
+}
+
+// validate fills in defaults and checks the gain makes sense
+func (g *Gain) validate() error {
+	switch {
+	case g.MinLevel < 0 || g.MaxLevel < 0 || g.Every < 0:
+		return fmt.Errorf("gains can't have negative levels")
+	case g.MaxLevel > 0 && g.MaxLevel < g.MinLevel:
+		return fmt.Errorf("gains can't have a max_level below their min_level")
+	}
+	if g.Every == 0 {
+		g.Every = 1
+	}
+
+	return nil
+}
+
+// For is true if the gain is for players of the class and race reaching the
+// level.
+func (g Gain) For(class, race string, level int) bool {
+	switch {
+	case g.Class != "" && !strings.EqualFold(g.Class, class):
+		return false
+	case g.Race != "" && !strings.EqualFold(g.Race, race):
+		return false
+	case g.MinLevel > 0 && level < g.MinLevel:
+		return false
+	case g.MaxLevel > 0 && level > g.MaxLevel:
+		return false
+	}
+
+	return level%g.Every == 0
+}
+
+// Remort describes starting over from the first level.
+type Remort struct {
+	// Level is the level players can remort at, zero turns remorting off.
+	Level int `yaml:"level,omitempty"`
+	// Max is how many times players can remort, zero is no limit.
+	Max int `yaml:"max,omitempty"`
+	// Stats are added to the player's stats each time they remort.
+	Stats map[string]int `yaml:"stats,omitempty"`
+	// Reset are stats set back to zero, experience and level always are.
+	Reset []string `yaml:"reset,omitempty"`
+}
+
+// Config is the layout of the advancement file.
+type Config struct {
+	Curve  Curve  `yaml:"curve"`
+	Gains  []Gain `yaml:"gains,omitempty"`
+	Remort Remort `yaml:"remort,omitempty"`
+}
+
+// validate fills in defaults and checks the config makes sense
+func (c *Config) validate() error {
+	if err := c.Curve.validate(); err != nil {
+		return err
+	}
+	gains := make([]Gain, len(c.Gains))
+	for i, g := range c.Gains {
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("gains[%d]: %s", i, err)
+		}
+		gains[i] = g
+	}
+	c.Gains = gains
+	if c.Remort.Level < 0 || c.Remort.Max < 0 {
+		return fmt.Errorf("remort: the level and max can't be negative")
+	}
+
+	return nil
+}
+
+// ParseConfig reads the advancement file, like:
+//   curve:
+//     kind: exponential
+//     base: 1000
+//     factor: 1.5
+//     max: 50
+//   gains:
+//     - stats:
+//         max_hp: 5
+//         practices: 1
+//     - class: warrior
+//       stats:
+//         max_hp: 10
+//     - class: mage
+//       min_level: 10
+//       every: 5
+//       skills:
+//         fireball: 5
+//   remort:
+//     level: 50
+//     max: 3
+//     stats:
+//       max_hp: 50
+func ParseConfig(contents []byte) (Config, error) {
+	var c Config
+	if err := yaml.UnmarshalStrict(contents, &c); err != nil {
+		return Config{}, err
+	}
+	if err := c.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return c, nil
+}
+
+// LoadConfig reads the advancement file at the path, the default config is
+// returned if there isn't one.
+func LoadConfig(path string) (Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{Curve: DefaultCurve}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := ParseConfig(contents)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,13 @@
+package advance_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAdvance(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Advance Suite")
+}
@@ -0,0 +1,193 @@
+package advance_test
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/advance"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// adventurer levels up and remembers what they're told
+type adventurer struct {
+	class  string
+	stats  map[string]int
+	skills map[string]int
+	sent   []string
+}
+
+func (a *adventurer) Name() string {
+	return "Ann"
+}
+
+func (a *adventurer) Class() string {
+	return a.class
+}
+
+func (a *adventurer) Race() string {
+	return "elf"
+}
+
+func (a *adventurer) Stat(name string) int {
+	return a.stats[name]
+}
+
+func (a *adventurer) SetStat(name string, value int) {
+	a.stats[name] = value
+}
+
+func (a *adventurer) AddStat(name string, delta int) int {
+	a.stats[name] += delta
+
+	return a.stats[name]
+}
+
+func (a *adventurer) Skill(id string) int {
+	return a.skills[id]
+}
+
+func (a *adventurer) SetSkill(id string, percent int) {
+	a.skills[id] = percent
+}
+
+func (a *adventurer) Send(text string) error {
+	a.sent = append(a.sent, text)
+
+	return nil
+}
+
+const config = `
+curve:
+  kind: linear
+  base: 100
+  max: 10
+gains:
+  - stats:
+      max_hp: 5
+  - class: warrior
+    stats:
+      max_hp: 10
+  - class: mage
+    min_level: 3
+    every: 2
+    skills:
+      fireball: 60
+remort:
+  level: 10
+  max: 1
+  stats:
+    max_hp: 50
+  reset: [max_hp]
+`
+
+var _ = Describe("Curve", func() {
+	It("takes more experience for each level", func() {
+		linear := Curve{Kind: Linear, Base: 100}
+		Ω(linear.Needed(1)).Should(Equal(0))
+		Ω(linear.Needed(3)).Should(Equal(200))
+		Ω(linear.Level(250)).Should(Equal(3))
+
+		exp := Curve{Kind: Exponential, Base: 100, Factor: 2, Max: 4}
+		Ω(exp.Needed(2)).Should(Equal(100))
+		Ω(exp.Needed(4)).Should(Equal(700))
+		Ω(exp.Needed(5)).Should(Equal(-1))
+		Ω(exp.Level(1000000)).Should(Equal(4))
+
+		table := Curve{Kind: Table, Levels: []int{50, 500}}
+		Ω(table.Level(499)).Should(Equal(2))
+		Ω(table.Level(500)).Should(Equal(3))
+		Ω(table.Needed(4)).Should(Equal(-1))
+	})
+
+	It("rejects curves that don't make sense", func() {
+		_, err := ParseConfig([]byte("curve:\n  kind: spiral\n"))
+		Ω(err).Should(HaveOccurred())
+		_, err = ParseConfig([]byte("curve:\n  kind: table\n  levels: [100, 50]\n"))
+		Ω(err).Should(HaveOccurred())
+		_, err = ParseConfig([]byte("curve:\n  kind: linear\n"))
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		m   *Manager
+		em  *events.Emitter
+		ann *adventurer
+	)
+
+	BeforeEach(func() {
+		c, err := ParseConfig([]byte(config))
+		Ω(err).ShouldNot(HaveOccurred())
+		em = events.NewEmitter(nil)
+		m = NewManager(em)
+		Ω(m.SetConfig(c)).Should(Succeed())
+		ann = &adventurer{class: "warrior", stats: map[string]int{}, skills: map[string]int{}}
+	})
+
+	It("levels advancers up with the gains for their class", func() {
+		leveled := make(chan events.Data, 2)
+		em.On(LevelEvent, events.HandlerFunc(func(d events.Data) error {
+			leveled <- d
+
+			return nil
+		}))
+
+		Ω(m.Award(ann, 250)).Should(Equal(2))
+		Ω(ann.stats[LevelStat]).Should(Equal(3))
+		Ω(ann.stats["max_hp"]).Should(Equal(30))
+		Ω(ann.sent).Should(ContainElement("You are now level 3!"))
+		Ω(ann.sent).Should(ContainElement("You gain 15 max_hp."))
+		var d events.Data
+		Eventually(leveled).Should(Receive(&d))
+		Ω(d["player"]).Should(Equal("Ann"))
+		Ω(d["class"]).Should(Equal("warrior"))
+
+		Ω(m.Award(ann, 10)).Should(Equal(0))
+	})
+
+	It("raises skills and runs hooks every few levels", func() {
+		ann.class = "mage"
+		var hooked []int
+		m.Hook("arcana", func(a Advancer, level int) {
+			hooked = append(hooked, level)
+			a.AddStat("mana", 10)
+		})
+
+		m.Award(ann, 500)
+		Ω(ann.skills["fireball"]).Should(Equal(100))
+		Ω(hooked).Should(Equal([]int{2, 3, 4, 5, 6}))
+		Ω(ann.stats["mana"]).Should(Equal(50))
+
+		m.Hook("arcana", nil)
+		m.Award(ann, 100)
+		Ω(hooked).Should(HaveLen(5))
+	})
+
+	It("lets advancers remort at a high enough level", func() {
+		Ω(m.Remort(ann)).Should(MatchError("You must reach level 10 to remort."))
+
+		m.Award(ann, 5000)
+		Ω(ann.stats[LevelStat]).Should(Equal(10))
+		Ω(m.Remort(ann)).Should(Succeed())
+		Ω(ann.stats[LevelStat]).Should(Equal(1))
+		Ω(ann.stats[DefaultStat]).Should(Equal(0))
+		Ω(ann.stats["max_hp"]).Should(Equal(50))
+		Ω(ann.stats[RemortStat]).Should(Equal(1))
+
+		m.Award(ann, 5000)
+		Ω(m.Remort(ann)).Should(MatchError(MaxRemortMessage))
+	})
+
+	It("lets handlers stop remorts", func() {
+		em.On("before:"+RemortEvent, events.HandlerFunc(func(events.Data) error {
+			return errors.New("The gods refuse you.")
+		}))
+		m.Award(ann, 5000)
+
+		Ω(m.Remort(ann)).Should(MatchError("The gods refuse you."))
+		Ω(ann.stats[LevelStat]).Should(Equal(10))
+	})
+})
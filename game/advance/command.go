@@ -0,0 +1,77 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package advance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the experience command, which shows the caller's
+// level and how far they are from the next, and the remort command.
+func NewCommands(m *Manager, resolve command.Resolver[Advancer]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:    "experience",
+			Aliases: []string{"xp", "level"},
+			Help:    "Shows your level and the experience you need for the next.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, a Advancer) error {
+				return ctx.Send(progress(m, a))
+			}),
+		},
+		{
+			Name:   "remort",
+			Args:   []command.Arg{{Name: "confirm", Kind: command.Word, Optional: true}},
+			Help:   "Starts you over from the first level, with bonuses for having done it. Type \"remort confirm\" to do it.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, a Advancer) error {
+				if !strings.EqualFold(ctx.String("confirm"), "confirm") {
+					return ctx.Send("Remorting starts you over from the first level, type \"remort confirm\" if you're sure.")
+				}
+
+				return m.Remort(a)
+			}),
+		},
+	}
+}
+
+// progress describes the advancer's level and experience
+func progress(m *Manager, a Advancer) string {
+	level, xp := a.Stat(LevelStat), a.Stat(m.Stat())
+	if level < 1 {
+		level = 1
+	}
+	lines := []string{fmt.Sprintf("You are level %d with %d experience.", level, xp)}
+	if next := m.Needed(level + 1); next < 0 {
+		lines = append(lines, "You can't go any higher.")
+	} else {
+		lines = append(lines, fmt.Sprintf("You need %d more to reach level %d.", next-xp, level+1))
+	}
+	if n := a.Stat(RemortStat); n > 0 {
+		lines = append(lines, fmt.Sprintf("You have remorted %d time(s).", n))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's advancer for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Advancer], fn func(*command.Context, Advancer) error) command.Handler {
+	return func(ctx *command.Context) error {
+		a := resolve(ctx.Caller)
+		if a == nil {
+			return ctx.Send("You can't advance.")
+		}
+
+		err := fn(ctx, a)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
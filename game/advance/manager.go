@@ -0,0 +1,358 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package advance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// The stats advancement keeps, along with the experience stat.
+const (
+	LevelStat  = "level"
+	RemortStat = "remorts"
+)
+
+// DefaultStat is the stat experience is kept in unless it's changed.
+const DefaultStat = "xp"
+
+// The events of advancement. LevelEvent is given the name, class and race
+// of the player, the level they reached and the stats they gained.
+// Handlers of before:player:remort can stop players remorting by returning
+// events.ErrHalt, or an error whose message is told to them, it's given
+// their name, the level they were and how many times they've remorted.
+const (
+	LevelEvent  = "player:leveled"
+	RemortEvent = "player:remort"
+)
+
+// Messages told to players as they advance.
+const (
+	LevelMessage     = "You are now level %d!"
+	GainMessage      = "You gain %s."
+	RemortMessage    = "You are reborn, ready to begin again."
+	NoRemortMessage  = "There's no remorting here."
+	NotReadyMessage  = "You must reach level %d to remort."
+	MaxRemortMessage = "You can't remort any more."
+	CancelMessage    = "You can't do that right now."
+)
+
+// Advancer is a character who levels up, like a player.
+type Advancer interface {
+	Name() string
+	Class() string
+	Race() string
+	Stat(name string) int
+	SetStat(name string, value int)
+	AddStat(name string, delta int) int
+	Skill(id string) int
+	SetSkill(id string, percent int)
+}
+
+// Sender is an advancer told how they advance.
+type Sender interface {
+	Send(text string) error
+}
+
+// HookFunc gives an advancer what they gain for reaching the level, beyond
+// what's in the advancement file.
+type HookFunc func(a Advancer, level int)
+
+// Manager levels advancers up as they earn experience.
+type Manager struct {
+	config  Config
+	stat    string
+	hooks   map[string]HookFunc
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager with the default curve and no gains. The
+// emitter may be nil.
+func NewManager(em *events.Emitter) *Manager {
+	return &Manager{
+		config:  Config{Curve: DefaultCurve},
+		stat:    DefaultStat,
+		hooks:   make(map[string]HookFunc),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's advancement manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetStat changes the stat experience is kept in.
+func (m *Manager) SetStat(stat string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if stat != "" {
+		m.stat = stat
+	}
+}
+
+// Stat returns the stat experience is kept in.
+func (m *Manager) Stat() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.stat
+}
+
+// SetConfig replaces the curve, gains and remorting.
+func (m *Manager) SetConfig(c Config) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.config = c
+
+	return nil
+}
+
+// Config returns the curve, gains and remorting.
+func (m *Manager) Config() Config {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.config
+}
+
+// AddGain adds a gain to those in the config.
+func (m *Manager) AddGain(g Gain) error {
+	if err := g.validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.config.Gains = append(append([]Gain(nil), m.config.Gains...), g)
+
+	return nil
+}
+
+// Hook sets the hook with the name, replacing any there was, or removing it
+// if fn is nil. Hooks run in order of their names after the gains.
+func (m *Manager) Hook(name string, fn HookFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if fn == nil {
+		delete(m.hooks, name)
+
+		return
+	}
+	m.hooks[name] = fn
+}
+
+// Needed returns the experience it takes to reach the level, or -1 if it
+// can't be reached.
+func (m *Manager) Needed(level int) int {
+	return m.Config().Curve.Needed(level)
+}
+
+// Award gives the advancer experience, returning how many levels they gained.
+func (m *Manager) Award(a Advancer, xp int) int {
+	a.AddStat(m.Stat(), xp)
+
+	return m.Check(a)
+}
+
+// Check levels the advancer up to the level their experience reaches,
+// returning how many levels they gained. Advancers never lose levels.
+func (m *Manager) Check(a Advancer) int {
+	m.mutex.RLock()
+	config, stat := m.config, m.stat
+	m.mutex.RUnlock()
+
+	level := a.Stat(LevelStat)
+	if level < 1 {
+		level = 1
+		a.SetStat(LevelStat, level)
+	}
+	target := config.Curve.Level(a.Stat(stat))
+	gained := 0
+	for level < target {
+		level++
+		gained++
+		a.SetStat(LevelStat, level)
+		m.levelUp(a, config, level)
+	}
+
+	return gained
+}
+
+// levelUp gives the advancer what they gain for reaching the level
+func (m *Manager) levelUp(a Advancer, config Config, level int) {
+	stats := make(map[string]int)
+	for _, g := range config.Gains {
+		if !g.For(a.Class(), a.Race(), level) {
+			continue
+		}
+		for stat, n := range g.Stats {
+			a.AddStat(stat, n)
+			stats[stat] += n
+		}
+		for skill, n := range g.Skills {
+			percent := a.Skill(skill) + n
+			if percent > 100 {
+				percent = 100
+			}
+			a.SetSkill(skill, percent)
+		}
+	}
+
+	m.mutex.RLock()
+	names := make([]string, 0, len(m.hooks))
+	for name := range m.hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hooks := make([]HookFunc, len(names))
+	for i, name := range names {
+		hooks[i] = m.hooks[name]
+	}
+	m.mutex.RUnlock()
+
+	for _, fn := range hooks {
+		fn(a, level)
+	}
+
+	tell(a, fmt.Sprintf(LevelMessage, level))
+	if len(stats) > 0 {
+		gains := make([]string, 0, len(stats))
+		for _, stat := range sortedKeys(stats) {
+			gains = append(gains, fmt.Sprintf("%d %s", stats[stat], stat))
+		}
+		tell(a, fmt.Sprintf(GainMessage, strings.Join(gains, ", ")))
+	}
+	m.emit(LevelEvent, events.Data{
+		"player": a.Name(),
+		"class":  a.Class(),
+		"race":   a.Race(),
+		"level":  level,
+		"stats":  stats,
+	})
+}
+
+// Remort starts the advancer over from the first level, with the remort
+// bonuses, if they're at a high enough level.
+func (m *Manager) Remort(a Advancer) error {
+	m.mutex.RLock()
+	r, stat := m.config.Remort, m.stat
+	m.mutex.RUnlock()
+
+	level, remorts := a.Stat(LevelStat), a.Stat(RemortStat)
+	switch {
+	case r.Level == 0:
+		return &item.Refused{Message: NoRemortMessage}
+	case level < r.Level:
+		return &item.Refused{Message: fmt.Sprintf(NotReadyMessage, r.Level)}
+	case r.Max > 0 && remorts >= r.Max:
+		return &item.Refused{Message: MaxRemortMessage}
+	}
+	data := events.Data{"player": a.Name(), "level": level, "remorts": remorts}
+	if err := m.check(RemortEvent, data); err != nil {
+		return err
+	}
+
+	a.SetStat(LevelStat, 1)
+	a.SetStat(stat, 0)
+	for _, s := range r.Reset {
+		a.SetStat(s, 0)
+	}
+	for _, s := range sortedKeys(r.Stats) {
+		a.AddStat(s, r.Stats[s])
+	}
+	data["remorts"] = a.AddStat(RemortStat, 1)
+	tell(a, RemortMessage)
+	m.confirm(RemortEvent, data)
+
+	return nil
+}
+
+// tell sends the text to the advancer if they can be told things
+func tell(a Advancer, text string) {
+	if s, ok := a.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
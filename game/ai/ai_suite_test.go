@@ -0,0 +1,13 @@
+package ai_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AI Suite")
+}
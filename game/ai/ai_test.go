@@ -0,0 +1,233 @@
+package ai_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/game/ai"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const guardTree = `
+name: guard
+root:
+  selector:
+    - sequence:
+        - condition: flag
+          args: {flag: hurt}
+        - action: log
+          args: {say: flee}
+    - cooldown: 1h
+      do:
+        action: log
+        args: {say: patrol}
+    - invert:
+        action: log
+        args: {say: rest}
+`
+
+var _ = Describe("Brain", func() {
+	var (
+		brain  *ai.Brain
+		mobs   *mob.Manager
+		guard  *mob.Mob
+		said   []string
+		status ai.Status
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town"})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "gate", Zone: "town", Name: "The Gate"})).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "guard", Zone: "town", Name: "a guard", Props: map[string]interface{}{ai.BehaviorProp: "guard"}})).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "rat", Zone: "town", Name: "a rat"})).Should(Succeed())
+		mobs = mob.NewManager(w, item.NewFloor(), nil)
+		brain = ai.NewBrain(mobs, ai.NewTrees(), ai.NewLeaves())
+		Ω(brain.Trees().LoadYAML([]byte(guardTree))).Should(Succeed())
+
+		said = nil
+		status = ai.Success
+		brain.Leaves().Action("log", func(ctx *ai.Context) ai.Status {
+			said = append(said, ctx.Args["say"].(string))
+
+			return status
+		})
+		guard, _ = mobs.Spawn("guard", "gate")
+	})
+
+	It("ticks the tree a mob's NPC names", func() {
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Success))
+		Ω(said).Should(Equal([]string{"patrol"}))
+
+		guard.SetFlag("hurt", true)
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Success))
+		Ω(said).Should(Equal([]string{"patrol", "flee"}))
+	})
+
+	It("cools down and inverts", func() {
+		brain.TickMob(guard)
+		status = ai.Failure
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Success))
+		Ω(said).Should(Equal([]string{"patrol", "rest"}))
+
+		status = ai.Running
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Running))
+	})
+
+	It("fails mobs without a tree and leaves that don't exist", func() {
+		rat, _ := mobs.Spawn("rat", "gate")
+		Ω(brain.TickMob(rat)).Should(Equal(ai.Failure))
+
+		Ω(brain.Trees().Add("lost", ai.Spec{Action: "nothing"})).Should(Succeed())
+		rat.SetProp(ai.BehaviorProp, "lost")
+		Ω(brain.TickMob(rat)).Should(Equal(ai.Failure))
+		Ω(brain.Leaves().Remove("log")).Should(BeTrue())
+		Ω(brain.Leaves().Remove("log")).Should(BeFalse())
+	})
+
+	It("keeps a blackboard for each mob while it's alive", func() {
+		Ω(brain.Trees().Add("remember", ai.Spec{Sequence: []ai.Spec{
+			{Condition: "is_set", Args: map[string]interface{}{"key": "seen"}},
+			{Action: "clear", Args: map[string]interface{}{"key": "seen"}},
+		}})).Should(Succeed())
+		guard.SetProp(ai.BehaviorProp, "remember")
+
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Failure))
+		brain.Board(guard.ID()).Set("seen", "Alice")
+		Ω(brain.TickMob(guard)).Should(Equal(ai.Success))
+		Ω(brain.Board(guard.ID()).Has("seen")).Should(BeFalse())
+
+		brain.Board(guard.ID()).Set("seen", "Bob")
+		mobs.Kill(guard.ID(), "")
+		brain.Tick()
+		Ω(brain.Board(guard.ID()).Has("seen")).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Walker", func() {
+	var (
+		brain *ai.Brain
+		mobs  *mob.Manager
+		alice string
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town"})).Should(Succeed())
+		Ω(w.AddZone(world.Zone{ID: "wild", Name: "The Wild"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "gate", Zone: "town"},
+			{ID: "square", Zone: "town"},
+			{ID: "market", Zone: "town"},
+			{ID: "cellar", Zone: "town", Flags: map[string]bool{"no_mob": true}},
+			{ID: "forest", Zone: "wild"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("gate", world.South, "square")).Should(Succeed())
+		Ω(w.Link("square", world.East, "market")).Should(Succeed())
+		Ω(w.Link("square", world.Down, "cellar")).Should(Succeed())
+		Ω(w.Link("market", world.East, "forest")).Should(Succeed())
+		for _, def := range []world.NPCDef{
+			{ID: "rat", Zone: "town", Name: "a rat", Props: map[string]interface{}{ai.MovementProp: ai.Wander}},
+			{ID: "guard", Zone: "town", Name: "a guard", Props: map[string]interface{}{ai.MovementProp: ai.Patrol, ai.RouteProp: []interface{}{"gate", "market"}}},
+			{ID: "hound", Zone: "town", Name: "a hound", Props: map[string]interface{}{ai.MovementProp: ai.Wander, ai.BehaviorProp: "hunt"}},
+			{ID: "rabbit", Zone: "town", Name: "a rabbit", Props: map[string]interface{}{ai.MovementProp: ai.Flee}},
+		} {
+			Ω(w.SetNPC(def)).Should(Succeed())
+		}
+
+		mobs = mob.NewManager(w, item.NewFloor(), nil)
+		brain = ai.NewBrain(mobs, ai.NewTrees(), ai.NewLeaves())
+		brain.SetWalker(ai.NewWalker(mobs, movement.New(w, nil), w))
+		alice = "market"
+		brain.Walker().SetLocate(func(target string) (string, bool) {
+			return alice, target == "Alice"
+		})
+	})
+
+	It("wanders its zone, keeping out of rooms mobs can't enter", func() {
+		rat, _ := mobs.Spawn("rat", "square")
+		for i := 0; i < 30; i++ {
+			brain.Tick()
+			Ω([]string{"gate", "square", "market"}).Should(ContainElement(rat.Location()))
+		}
+
+		rat.SetFlag(ai.SentinelFlag, true)
+		Ω(brain.TickMob(rat)).Should(Equal(ai.Failure))
+	})
+
+	It("patrols its route", func() {
+		guard, _ := mobs.Spawn("guard", "gate")
+		var rooms []string
+		for i := 0; i < 4; i++ {
+			Ω(brain.TickMob(guard)).Should(Equal(ai.Success))
+			rooms = append(rooms, guard.Location())
+		}
+
+		Ω(rooms).Should(Equal([]string{"square", "market", "square", "gate"}))
+	})
+
+	It("pursues targets in trees, overriding its movement", func() {
+		Ω(brain.Trees().Add("hunt", ai.Spec{Action: ai.Pursue, Args: map[string]interface{}{"target": "Alice"}})).Should(Succeed())
+		hound, _ := mobs.Spawn("hound", "gate")
+
+		Ω(brain.TickMob(hound)).Should(Equal(ai.Running))
+		Ω(hound.Location()).Should(Equal("square"))
+		Ω(brain.TickMob(hound)).Should(Equal(ai.Success))
+		Ω(hound.Location()).Should(Equal("market"))
+	})
+
+	It("flees from targets in its room", func() {
+		rabbit, _ := mobs.Spawn("rabbit", "market")
+		Ω(brain.TickMob(rabbit)).Should(Equal(ai.Failure))
+
+		brain.Board(rabbit.ID()).Set(ai.TargetKey, "Alice")
+		Ω(brain.TickMob(rabbit)).Should(Equal(ai.Success))
+		Ω([]string{"square", "forest"}).Should(ContainElement(rabbit.Location()))
+		Ω(brain.TickMob(rabbit)).Should(Equal(ai.Failure))
+	})
+})
+
+var _ = Describe("Trees", func() {
+	It("builds parallel and chance nodes", func() {
+		node, err := ai.Build(ai.Spec{Parallel: []ai.Spec{
+			{Succeed: &ai.Spec{Condition: "nothing"}},
+			{Chance: 100, Do: &ai.Spec{Action: "set", Args: map[string]interface{}{"key": "k", "value": 1}}},
+		}})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(node).Should(BeAssignableToTypeOf(ai.Parallel{}))
+	})
+
+	It("explains bad specs", func() {
+		_, err := ai.Build(ai.Spec{Sequence: []ai.Spec{{Action: "a", Condition: "b"}}})
+		Ω(err).Should(MatchError("root.sequence[0]: a node needs exactly one of selector, sequence, parallel, invert, succeed, chance, cooldown, action or condition"))
+		_, err = ai.Build(ai.Spec{Cooldown: "soon", Do: &ai.Spec{Action: "a"}})
+		Ω(err).Should(MatchError(`root: cooldown must be a duration, like "30s"`))
+		_, err = ai.Build(ai.Spec{Selector: []ai.Spec{}})
+		Ω(err).Should(MatchError("root.selector: needs at least one child"))
+		_, err = ai.Build(ai.Spec{Chance: 50})
+		Ω(err).Should(MatchError("root: chance needs a node to do"))
+	})
+
+	It("loads the trees in a directory", func() {
+		dir, err := ioutil.TempDir("", "trees")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Ω(ioutil.WriteFile(filepath.Join(dir, "guard.yml"), []byte(guardTree), 0644)).Should(Succeed())
+
+		trees := ai.NewTrees()
+		Ω(trees.LoadDir(dir)).Should(Succeed())
+		Ω(trees.Names()).Should(Equal([]string{"guard"}))
+
+		Ω(ioutil.WriteFile(filepath.Join(dir, "bad.yml"), []byte("name: bad\nroot: {action: a, colour: red}\n"), 0644)).Should(Succeed())
+		Ω(trees.LoadDir(dir)).ShouldNot(Succeed())
+	})
+})
@@ -0,0 +1,180 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ai
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/cooldown"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// BehaviorProp is the prop of a mob, or of the NPC it's made from, naming
+// the tree it runs. Mobs with a tree only move the way it says, those
+// without one move the way their movement prop names.
+const BehaviorProp = "behavior"
+
+// Brain ticks the trees of the mobs in the game.
+type Brain struct {
+	mobs      *mob.Manager
+	trees     *Trees
+	leaves    *Leaves
+	walker    *Walker
+	boards    map[string]*Blackboard
+	cooldowns *cooldown.Tracker
+	stop      chan struct{}
+	mutex     *sync.Mutex
+}
+
+// NewBrain creates a brain running the trees for the managed mobs.
+func NewBrain(mobs *mob.Manager, trees *Trees, leaves *Leaves) *Brain {
+	return &Brain{
+		mobs:      mobs,
+		trees:     trees,
+		leaves:    leaves,
+		boards:    make(map[string]*Blackboard),
+		cooldowns: cooldown.New(),
+		mutex:     new(sync.Mutex),
+	}
+}
+
+var (
+	globalBrain *Brain
+	globalOnce  sync.Once
+)
+
+// Global returns the game's brain, with the built in leaves and those moving
+// mobs through the game's world.
+func Global() *Brain {
+	globalOnce.Do(func() {
+		globalBrain = NewBrain(mob.Global(), NewTrees(), NewLeaves())
+		globalBrain.SetWalker(NewWalker(mob.Global(), movement.Global(), world.Global()))
+	})
+
+	return globalBrain
+}
+
+// Trees returns the trees mobs can run.
+func (b *Brain) Trees() *Trees {
+	return b.trees
+}
+
+// Leaves returns the actions and conditions trees can use.
+func (b *Brain) Leaves() *Leaves {
+	return b.leaves
+}
+
+// SetWalker sets what moves mobs on their own, adding its movement actions
+// to the leaves.
+func (b *Brain) SetWalker(w *Walker) {
+	w.AddLeaves(b.leaves)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.walker = w
+}
+
+// Walker returns what moves mobs on their own, nil if nothing does.
+func (b *Brain) Walker() *Walker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.walker
+}
+
+// Board returns the blackboard of the mob with the id.
+func (b *Brain) Board(id string) *Blackboard {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	board, ok := b.boards[id]
+	if !ok {
+		board = NewBlackboard()
+		b.boards[id] = board
+	}
+
+	return board
+}
+
+// TickMob ticks the mob's tree, or the action its movement prop names if it
+// doesn't have one, failing if it has neither.
+func (b *Brain) TickMob(m *mob.Mob) Status {
+	ctx := &Context{
+		Mob:       m,
+		Board:     b.Board(m.ID()),
+		leaves:    b.leaves,
+		cooldowns: b.cooldowns,
+	}
+	name, _ := m.Prop(BehaviorProp).(string)
+	if root, ok := b.trees.Get(name); ok {
+		return root.Tick(ctx)
+	}
+	if mode, ok := m.Prop(MovementProp).(string); ok {
+		return Leaf{Name: mode, Args: movementArgs(m)}.Tick(ctx)
+	}
+
+	return Failure
+}
+
+// Tick ticks the tree or movement of every mob with one, forgetting the
+// blackboards of mobs no longer in the game.
+func (b *Brain) Tick() {
+	mobs := b.mobs.All()
+	alive := make(map[string]bool, len(mobs))
+	for _, m := range mobs {
+		alive[m.ID()] = true
+		_, tree := m.Prop(BehaviorProp).(string)
+		_, moves := m.Prop(MovementProp).(string)
+		if tree || moves {
+			b.TickMob(m)
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for id := range b.boards {
+		if !alive[id] {
+			delete(b.boards, id)
+		}
+	}
+}
+
+// Start ticks every interval in the background until stopped.
+func (b *Brain) Start(interval time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.stop != nil || interval <= 0 {
+		return
+	}
+	b.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}(b.stop)
+}
+
+// Stop halts ticking.
+func (b *Brain) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LeafFunc is an action or condition run for a mob.
+type LeafFunc func(*Context) Status
+
+// Leaves holds the actions and conditions trees can use by name.
+type Leaves struct {
+	leaves map[string]LeafFunc
+	mutex  *sync.RWMutex
+}
+
+// NewLeaves creates a set of leaves with the built in ones:
+//   flag (condition): the mob has the flag given as "flag"
+//   is_set (condition): the blackboard has a value for "key"
+//   set (action): sets "key" on the blackboard to "value"
+//   clear (action): removes "key" from the blackboard
+func NewLeaves() *Leaves {
+	l := &Leaves{
+		leaves: make(map[string]LeafFunc),
+		mutex:  new(sync.RWMutex),
+	}
+	l.Condition("flag", func(ctx *Context) bool {
+		return ctx.Mob.Flag(arg(ctx, "flag"))
+	})
+	l.Condition("is_set", func(ctx *Context) bool {
+		return ctx.Board.Has(arg(ctx, "key"))
+	})
+	l.Action("set", func(ctx *Context) Status {
+		ctx.Board.Set(arg(ctx, "key"), ctx.Args["value"])
+
+		return Success
+	})
+	l.Action("clear", func(ctx *Context) Status {
+		ctx.Board.Set(arg(ctx, "key"), nil)
+
+		return Success
+	})
+
+	return l
+}
+
+// Action adds the action, replacing any leaf with the name.
+func (l *Leaves) Action(name string, fn LeafFunc) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.leaves[strings.ToLower(name)] = fn
+}
+
+// Condition adds the condition, which succeeds when fn is true, replacing any
+// leaf with the name.
+func (l *Leaves) Condition(name string, fn func(*Context) bool) {
+	l.Action(name, func(ctx *Context) Status {
+		if fn(ctx) {
+			return Success
+		}
+
+		return Failure
+	})
+}
+
+// Remove removes the leaf, returning false if there wasn't one.
+func (l *Leaves) Remove(name string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, ok := l.leaves[strings.ToLower(name)]
+	delete(l.leaves, strings.ToLower(name))
+
+	return ok
+}
+
+// Get returns the leaf with the name.
+func (l *Leaves) Get(name string) (LeafFunc, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	fn, ok := l.leaves[strings.ToLower(name)]
+
+	return fn, ok
+}
+
+// arg returns the leaf argument as a string
+func arg(ctx *Context, name string) string {
+	if v, ok := ctx.Args[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+
+	return ""
+}
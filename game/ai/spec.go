@@ -0,0 +1,240 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ai
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Spec describes a node of a tree as it's written in a tree file. Each spec
+// is exactly one of:
+//   - a selector, sequence or parallel of the child specs
+//   - invert or succeed, decorating the child spec
+//   - chance, the percent of the time Do is ticked
+//   - cooldown, the duration after Do succeeds it isn't ticked again
+//   - an action or condition, with Args given to it
+type Spec struct {
+	Selector  []Spec                 `yaml:"selector,omitempty"`
+	Sequence  []Spec                 `yaml:"sequence,omitempty"`
+	Parallel  []Spec                 `yaml:"parallel,omitempty"`
+	Invert    *Spec                  `yaml:"invert,omitempty"`
+	Succeed   *Spec                  `yaml:"succeed,omitempty"`
+	Chance    int                    `yaml:"chance,omitempty"`
+	Cooldown  string                 `yaml:"cooldown,omitempty"`
+	Do        *Spec                  `yaml:"do,omitempty"`
+	Action    string                 `yaml:"action,omitempty"`
+	Condition string                 `yaml:"condition,omitempty"`
+	Args      map[string]interface{} `yaml:"args,omitempty"`
+}
+
+// TreeFile is the layout of a tree file, like:
+//   name: guard
+//   root:
+//     selector:
+//       - sequence:
+//           - condition: flag
+//             args: {flag: hurt}
+//           - action: flee
+//       - chance: 10
+//         do: {action: wander}
+type TreeFile struct {
+	Name string `yaml:"name"`
+	Root Spec   `yaml:"root"`
+}
+
+// Build creates the node the spec describes.
+func Build(s Spec) (Node, error) {
+	return build(s, "root")
+}
+
+func build(s Spec, path string) (Node, error) {
+	kinds := 0
+	for _, set := range []bool{
+		s.Selector != nil, s.Sequence != nil, s.Parallel != nil, s.Invert != nil,
+		s.Succeed != nil, s.Chance != 0, s.Cooldown != "", s.Action != "", s.Condition != "",
+	} {
+		if set {
+			kinds++
+		}
+	}
+	fail := func(format string, args ...interface{}) (Node, error) {
+		return nil, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case kinds != 1:
+		return fail("a node needs exactly one of selector, sequence, parallel, invert, succeed, chance, cooldown, action or condition")
+	case s.Do != nil && s.Chance == 0 && s.Cooldown == "":
+		return fail("only chance and cooldown nodes have do")
+	case s.Args != nil && s.Action == "" && s.Condition == "":
+		return fail("only actions and conditions have args")
+	}
+
+	children := func(kind string, specs []Spec) ([]Node, error) {
+		if len(specs) == 0 {
+			return nil, fmt.Errorf("%s.%s: needs at least one child", path, kind)
+		}
+		nodes := make([]Node, len(specs))
+		for i, child := range specs {
+			n, err := build(child, fmt.Sprintf("%s.%s[%d]", path, kind, i))
+			if err != nil {
+				return nil, err
+			}
+			nodes[i] = n
+		}
+
+		return nodes, nil
+	}
+
+	switch {
+	case s.Selector != nil:
+		nodes, err := children("selector", s.Selector)
+
+		return Selector(nodes), err
+	case s.Sequence != nil:
+		nodes, err := children("sequence", s.Sequence)
+
+		return Sequence(nodes), err
+	case s.Parallel != nil:
+		nodes, err := children("parallel", s.Parallel)
+
+		return Parallel(nodes), err
+	case s.Invert != nil:
+		child, err := build(*s.Invert, path+".invert")
+
+		return Inverter{Child: child}, err
+	case s.Succeed != nil:
+		child, err := build(*s.Succeed, path+".succeed")
+
+		return Succeeder{Child: child}, err
+	case s.Chance != 0:
+		if s.Chance < 0 || s.Chance > 100 {
+			return fail("chance must be a percent from 1 to 100")
+		}
+		if s.Do == nil {
+			return fail("chance needs a node to do")
+		}
+		child, err := build(*s.Do, path+".do")
+
+		return Chance{Percent: s.Chance, Child: child}, err
+	case s.Cooldown != "":
+		d, err := time.ParseDuration(s.Cooldown)
+		if err != nil || d <= 0 {
+			return fail("cooldown must be a duration, like \"30s\"")
+		}
+		if s.Do == nil {
+			return fail("cooldown needs a node to do")
+		}
+		child, err := build(*s.Do, path+".do")
+
+		return Cooldown{Key: path, Duration: d, Child: child}, err
+	case s.Action != "":
+		return Leaf{Name: s.Action, Args: s.Args}, nil
+	}
+
+	return Leaf{Name: s.Condition, Args: s.Args}, nil
+}
+
+// Trees holds the behavior trees by name.
+type Trees struct {
+	trees map[string]Node
+	mutex *sync.RWMutex
+}
+
+// NewTrees creates an empty set of trees.
+func NewTrees() *Trees {
+	return &Trees{
+		trees: make(map[string]Node),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add builds the tree from its spec, replacing any tree with the name.
+func (t *Trees) Add(name string, root Spec) error {
+	if name == "" {
+		return fmt.Errorf("trees need a name")
+	}
+	node, err := Build(root)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.trees[strings.ToLower(name)] = node
+
+	return nil
+}
+
+// Get returns the root of the tree with the name.
+func (t *Trees) Get(name string) (Node, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	node, ok := t.trees[strings.ToLower(name)]
+
+	return node, ok
+}
+
+// Names returns the names of the trees, sorted.
+func (t *Trees) Names() []string {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	names := make([]string, 0, len(t.trees))
+	for name := range t.trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// LoadDir adds the trees in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (t *Trees) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := t.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the tree described by the YAML document.
+func (t *Trees) LoadYAML(contents []byte) error {
+	var f TreeFile
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+
+	return t.Add(f.Name, f.Root)
+}
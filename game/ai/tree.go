@@ -0,0 +1,240 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package ai gives mobs behavior trees. A tree is ticked for each mob naming
+// it in its "behavior" prop every AI pulse, from the root down: selectors try
+// their children until one doesn't fail, sequences run theirs until one
+// doesn't succeed and decorators change how their child runs. The leaves are
+// the actions and conditions registered by name, from Go or from Lua, and
+// share what they know about their mob on its blackboard.
+package ai
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/cooldown"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// Status is the result of ticking a node.
+type Status int
+
+// A node fails, succeeds or is still running and should be ticked again.
+const (
+	Failure Status = iota
+	Success
+	Running
+)
+
+// String returns the name of the status, like "success".
+func (s Status) String() string {
+	switch s {
+	case Success:
+		return "success"
+	case Running:
+		return "running"
+	}
+
+	return "failure"
+}
+
+// Context is what nodes are ticked with, the mob the tree is run for and its
+// blackboard. Args holds the arguments of the leaf being ticked.
+type Context struct {
+	Mob   *mob.Mob
+	Board *Blackboard
+	Args  map[string]interface{}
+
+	leaves    *Leaves
+	cooldowns *cooldown.Tracker
+}
+
+// Node is part of a behavior tree.
+type Node interface {
+	Tick(*Context) Status
+}
+
+// Selector ticks its children in order until one succeeds or is running,
+// failing if they all fail.
+type Selector []Node
+
+// Tick runs the selector.
+func (s Selector) Tick(ctx *Context) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Failure {
+			return status
+		}
+	}
+
+	return Failure
+}
+
+// Sequence ticks its children in order until one fails or is running,
+// succeeding if they all succeed.
+type Sequence []Node
+
+// Tick runs the sequence.
+func (s Sequence) Tick(ctx *Context) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Success {
+			return status
+		}
+	}
+
+	return Success
+}
+
+// Parallel ticks all of its children, failing if any fail and running while
+// any are running.
+type Parallel []Node
+
+// Tick runs every child.
+func (p Parallel) Tick(ctx *Context) Status {
+	result := Success
+	for _, child := range p {
+		switch child.Tick(ctx) {
+		case Failure:
+			result = Failure
+		case Running:
+			if result == Success {
+				result = Running
+			}
+		}
+	}
+
+	return result
+}
+
+// Inverter succeeds when its child fails and fails when it succeeds.
+type Inverter struct {
+	Child Node
+}
+
+// Tick runs the child and inverts its result.
+func (i Inverter) Tick(ctx *Context) Status {
+	switch i.Child.Tick(ctx) {
+	case Success:
+		return Failure
+	case Failure:
+		return Success
+	}
+
+	return Running
+}
+
+// Succeeder succeeds however its child finishes.
+type Succeeder struct {
+	Child Node
+}
+
+// Tick runs the child.
+func (s Succeeder) Tick(ctx *Context) Status {
+	if s.Child.Tick(ctx) == Running {
+		return Running
+	}
+
+	return Success
+}
+
+// Chance ticks its child Percent percent of the time, failing otherwise.
+type Chance struct {
+	Percent int
+	Child   Node
+}
+
+// Tick may run the child.
+func (c Chance) Tick(ctx *Context) Status {
+	if random.Intn(100) >= c.Percent {
+		return Failure
+	}
+
+	return c.Child.Tick(ctx)
+}
+
+// Cooldown fails without ticking its child for Duration after the child
+// succeeds. Each mob has its own cooldown, Key tells the tree's cooldowns
+// apart.
+type Cooldown struct {
+	Key      string
+	Duration time.Duration
+	Child    Node
+}
+
+// Tick runs the child unless it's cooling down.
+func (c Cooldown) Tick(ctx *Context) Status {
+	key := fmt.Sprintf("ai:%s:%s", ctx.Mob.ID(), c.Key)
+	if !ctx.cooldowns.Ready(key) {
+		return Failure
+	}
+	status := c.Child.Tick(ctx)
+	if status == Success {
+		ctx.cooldowns.Set(key, c.Duration)
+	}
+
+	return status
+}
+
+// Leaf runs the action or condition registered with the name, failing if
+// there isn't one.
+type Leaf struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// Tick runs the leaf's function with its args.
+func (l Leaf) Tick(ctx *Context) Status {
+	fn, ok := ctx.leaves.Get(l.Name)
+	if !ok {
+		return Failure
+	}
+	ctx.Args = l.Args
+
+	return fn(ctx)
+}
+
+// Blackboard is what a mob's tree remembers between ticks, like who it's
+// chasing.
+type Blackboard struct {
+	values map[string]interface{}
+	mutex  *sync.RWMutex
+}
+
+// NewBlackboard creates an empty blackboard.
+func NewBlackboard() *Blackboard {
+	return &Blackboard{
+		values: make(map[string]interface{}),
+		mutex:  new(sync.RWMutex),
+	}
+}
+
+// Get returns the value of the key, nil if it isn't set.
+func (b *Blackboard) Get(key string) interface{} {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.values[key]
+}
+
+// Has is true if the key is set.
+func (b *Blackboard) Has(key string) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	_, ok := b.values[key]
+
+	return ok
+}
+
+// Set sets the key, nil removes it.
+func (b *Blackboard) Set(key string, value interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if value == nil {
+		delete(b.values, key)
+	} else {
+		b.values[key] = value
+	}
+}
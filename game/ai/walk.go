@@ -0,0 +1,292 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/path"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The ways mobs move on their own. Each is an action trees can use, and
+// mobs without a tree move the way their movement prop names.
+//   wander: through a random exit to another room of the same zone, "chance"
+//     is the percent of the time it moves
+//   patrol: a step along the "route" of rooms, back to the start after the
+//     last
+//   flee: through a random exit when the "target" is in the room
+//   pursue: a step along the shortest path to the "target", succeeding once
+//     they're in the same room
+// Targets are the name of a player or the id of a mob, given as an arg or
+// set on the mob's blackboard as "target".
+const (
+	Wander = "wander"
+	Patrol = "patrol"
+	Flee   = "flee"
+	Pursue = "pursue"
+)
+
+// Props of mobs, or the NPCs they're made from, for moving on their own.
+const (
+	// MovementProp names the way the mob moves when it has no tree.
+	MovementProp = "movement"
+	// RouteProp lists the rooms the mob patrols, in order.
+	RouteProp = "route"
+	// MoveChanceProp is the percent of the time a wandering mob moves.
+	MoveChanceProp = "move_chance"
+)
+
+// TargetKey is the key of the blackboard holding who the mob pursues or
+// flees from.
+const TargetKey = "target"
+
+// Flags that keep mobs from moving on their own.
+const (
+	// SentinelFlag keeps the mob from wandering away from where it's put.
+	SentinelFlag = "sentinel"
+	// NoMobFlag keeps mobs from wandering or fleeing into the room.
+	NoMobFlag = "no_mob"
+)
+
+// patrolKey is the key of the blackboard holding the waypoint the mob is
+// walking to
+const patrolKey = "patrol"
+
+// Walker moves mobs through the world for the movement actions.
+type Walker struct {
+	mobs     *mob.Manager
+	movement *movement.Movement
+	world    *world.World
+	locate   func(target string) (string, bool)
+	mutex    *sync.RWMutex
+}
+
+// NewWalker creates a walker moving the managed mobs through the world.
+func NewWalker(mobs *mob.Manager, mv *movement.Movement, w *world.World) *Walker {
+	return &Walker{
+		mobs:     mobs,
+		movement: mv,
+		world:    w,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// SetLocate sets how targets that aren't mobs, like players, are found,
+// returning the room they're in. Without it only mobs can be targets.
+func (w *Walker) SetLocate(fn func(target string) (string, bool)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.locate = fn
+}
+
+// Locate returns the room the target is in.
+func (w *Walker) Locate(target string) (string, bool) {
+	if m := w.mobs.Get(target); m != nil {
+		return m.Location(), true
+	}
+
+	w.mutex.RLock()
+	locate := w.locate
+	w.mutex.RUnlock()
+
+	if locate == nil || target == "" {
+		return "", false
+	}
+
+	return locate(target)
+}
+
+// Wander moves the mob through a random exit into another room of its
+// zone, returning false if it didn't move.
+func (w *Walker) Wander(m *mob.Mob) bool {
+	if m.Flag(SentinelFlag) {
+		return false
+	}
+	r, ok := w.world.Room(m.Location())
+	if !ok {
+		return false
+	}
+
+	return w.moveRandomly(m, func(to world.Room) bool {
+		return to.Zone == r.Zone
+	})
+}
+
+// StepToward moves the mob one step along the shortest path to the room,
+// returning false if it didn't move.
+func (w *Walker) StepToward(m *mob.Mob, room string) bool {
+	if room == "" || room == m.Location() {
+		return false
+	}
+	p, err := path.Find(m.Location(), room, path.Options{
+		Exits:        w.world.PathExits,
+		IgnoreClosed: true,
+	})
+	if err != nil || len(p.Steps) == 0 {
+		return false
+	}
+
+	return w.movement.Move(m, world.Direction(p.Steps[0].Exit)) == nil
+}
+
+// Flee moves the mob through a random exit if the target is in its room,
+// returning false if it didn't move.
+func (w *Walker) Flee(m *mob.Mob, target string) bool {
+	room, ok := w.Locate(target)
+	if !ok || room != m.Location() {
+		return false
+	}
+
+	return w.moveRandomly(m, func(world.Room) bool {
+		return true
+	})
+}
+
+// Pursue moves the mob a step toward the target, returning whether they're
+// in the same room and whether it moved.
+func (w *Walker) Pursue(m *mob.Mob, target string) (caught, moved bool) {
+	room, ok := w.Locate(target)
+	if !ok {
+		return false, false
+	}
+	if room == m.Location() {
+		return true, false
+	}
+	moved = w.StepToward(m, room)
+
+	return room == m.Location(), moved
+}
+
+// moveRandomly moves the mob through a random open exit into a room mobs
+// may enter that allowed is true for
+func (w *Walker) moveRandomly(m *mob.Mob, allowed func(world.Room) bool) bool {
+	r, ok := w.world.Room(m.Location())
+	if !ok {
+		return false
+	}
+	var exits []world.Exit
+	for _, e := range r.SortedExits(false) {
+		to, ok := w.world.Room(e.To)
+		if !ok || e.Closed || to.Flag(NoMobFlag) || !allowed(to) {
+			continue
+		}
+		exits = append(exits, e)
+	}
+	if len(exits) == 0 {
+		return false
+	}
+
+	return w.movement.Move(m, exits[random.Intn(len(exits))].Direction) == nil
+}
+
+// AddLeaves adds the movement actions to the leaves, replacing any with
+// their names.
+func (w *Walker) AddLeaves(l *Leaves) {
+	l.Action(Wander, func(ctx *Context) Status {
+		if chance, ok := number(ctx.Args["chance"]); ok && random.Intn(100) >= chance {
+			return Failure
+		}
+
+		return status(w.Wander(ctx.Mob))
+	})
+	l.Action(Patrol, func(ctx *Context) Status {
+		route := stringList(ctx.Args["route"])
+		if len(route) == 0 {
+			return Failure
+		}
+		next, _ := number(ctx.Board.Get(patrolKey))
+		next %= len(route)
+		if ctx.Mob.Location() == route[next] {
+			next = (next + 1) % len(route)
+			ctx.Board.Set(patrolKey, next)
+		}
+
+		return status(w.StepToward(ctx.Mob, route[next]))
+	})
+	l.Action(Flee, func(ctx *Context) Status {
+		return status(w.Flee(ctx.Mob, targetOf(ctx)))
+	})
+	l.Action(Pursue, func(ctx *Context) Status {
+		caught, moved := w.Pursue(ctx.Mob, targetOf(ctx))
+		switch {
+		case caught:
+			return Success
+		case moved:
+			return Running
+		}
+
+		return Failure
+	})
+}
+
+// movementArgs returns the args of the mob's movement action, from its
+// props
+func movementArgs(m *mob.Mob) map[string]interface{} {
+	return map[string]interface{}{
+		"chance": m.Prop(MoveChanceProp),
+		"route":  m.Prop(RouteProp),
+	}
+}
+
+// targetOf returns who the action is given, or who's on the blackboard
+func targetOf(ctx *Context) string {
+	if t := arg(ctx, "target"); t != "" {
+		return t
+	}
+	if t := ctx.Board.Get(TargetKey); t != nil {
+		return fmt.Sprint(t)
+	}
+
+	return ""
+}
+
+// status is success if ok is true, failure if it isn't
+func status(ok bool) Status {
+	if ok {
+		return Success
+	}
+
+	return Failure
+}
+
+// number reads a whole number from a prop, arg or blackboard value
+func number(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// stringList reads a list of strings from a prop or arg
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		s := make([]string, len(list))
+		for i, item := range list {
+			s[i] = fmt.Sprint(item)
+		}
+
+		return s
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package ban keeps people out of the game. Bans are on an address or a
+// range of them written as a CIDR, an account or a single character, last
+// for a while or forever and say why they were made. Addresses are checked
+// as clients connect, accounts and characters as players log in.
+package ban
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of bans.
+const (
+	Address   = "ip"
+	Account   = "account"
+	Character = "character"
+)
+
+// Kinds are the kinds of bans, in the order they're checked.
+var Kinds = []string{Address, Account, Character}
+
+// Ban keeps whoever it matches out of the game.
+type Ban struct {
+	// Kind is Address, Account or Character.
+	Kind string `json:"kind"`
+	// Target is the address, CIDR range or name banned.
+	Target string `json:"target"`
+	Reason string `json:"reason,omitempty"`
+	// By is who made the ban.
+	By      string    `json:"by,omitempty"`
+	Created time.Time `json:"created"`
+	// Expires is when the ban ends, zero if it never does.
+	Expires time.Time `json:"expires"`
+}
+
+// Permanent is true if the ban never ends.
+func (b Ban) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+// Expired is true if the ban has ended by the time given.
+func (b Ban) Expired(now time.Time) bool {
+	return !b.Permanent() && !now.Before(b.Expires)
+}
+
+// Message is what those the ban keeps out are told.
+func (b Ban) Message() string {
+	var text string
+	switch b.Kind {
+	case Address:
+		text = "Connections from your address are banned"
+	case Account:
+		text = "Your account is banned"
+	default:
+		text = fmt.Sprintf("%s is banned", b.Target)
+	}
+	if !b.Permanent() {
+		text += " until " + b.Expires.UTC().Format("2006-01-02 15:04 MST")
+	}
+	if b.Reason != "" {
+		text += ": " + b.Reason
+	}
+
+	return text + "."
+}
+
+// key is how the ban is found, its kind and target without regard to case
+func (b Ban) key() string {
+	return b.Kind + ":" + strings.ToLower(b.Target)
+}
+
+// matches is true if the ban is on the value, an address for address bans
+// or a name otherwise
+func (b Ban) matches(value string) bool {
+	if b.Kind != Address {
+		return strings.EqualFold(b.Target, value)
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	if _, network, err := net.ParseCIDR(b.Target); err == nil {
+		return network.Contains(ip)
+	}
+
+	return ip.Equal(net.ParseIP(b.Target))
+}
+
+func (b Ban) validate() error {
+	switch b.Kind {
+	case Address:
+		if _, _, err := net.ParseCIDR(b.Target); err == nil {
+			return nil
+		}
+		if net.ParseIP(b.Target) == nil {
+			return fmt.Errorf("%q isn't an address or a CIDR range", b.Target)
+		}
+	case Account, Character:
+		if strings.TrimSpace(b.Target) == "" || strings.ContainsAny(b.Target, " \t") {
+			return fmt.Errorf("%s bans need a single name", b.Kind)
+		}
+	default:
+		return fmt.Errorf("bans must be on an ip, account or character, not %q", b.Kind)
+	}
+
+	return nil
+}
+
+// ParseDuration reads how long a ban lasts, a Go duration like "12h" or a
+// number of days or weeks like "3d" or "2w". "forever" and "permanent" are
+// zero, bans that don't end.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "forever", "permanent", "perm":
+		return 0, nil
+	case "":
+		return 0, errors.New("bans need a length of time")
+	}
+
+	if unit := s[len(s)-1]; unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%q isn't a length of time", s)
+		}
+		d := time.Duration(n) * 24 * time.Hour
+		if unit == 'w' {
+			d *= 7
+		}
+
+		return d, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q isn't a length of time", s)
+	}
+
+	return d, nil
+}
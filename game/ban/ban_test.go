@@ -0,0 +1,264 @@
+package ban_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/ban"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// ledger remembers the records written to it, failing when told to
+type ledger struct {
+	records []audit.Record
+	fail    bool
+}
+
+func (l *ledger) Append(r audit.Record) (*audit.Record, error) {
+	if l.fail {
+		return nil, errors.New("disk full")
+	}
+	l.records = append(l.records, r)
+
+	return &r, nil
+}
+
+// staff is a caller making bans who remembers what they're told
+type staff struct {
+	sent []string
+}
+
+func (s *staff) ID() string {
+	return "session-1"
+}
+
+func (s *staff) Level() command.Level {
+	return command.Admin
+}
+
+func (s *staff) Send(text string) error {
+	s.sent = append(s.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Ban", func() {
+	var (
+		m   *Manager
+		l   *ledger
+		now time.Time
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "ban")
+		Ω(err).ShouldNot(HaveOccurred())
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		l = new(ledger)
+		m = NewManager()
+		m.SetClock(func() time.Time { return now })
+		m.SetLedger(l)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("reads durations in days, weeks and Go's own units", func() {
+		for in, d := range map[string]time.Duration{
+			"3d":      72 * time.Hour,
+			"2w":      14 * 24 * time.Hour,
+			"90m":     90 * time.Minute,
+			"forever": 0,
+		} {
+			Ω(ParseDuration(in)).Should(Equal(d), in)
+		}
+		for _, in := range []string{"", "soon", "0d", "-1h"} {
+			_, err := ParseDuration(in)
+			Ω(err).Should(HaveOccurred(), in)
+		}
+	})
+
+	It("bans addresses, ranges, accounts and characters without regard to case", func() {
+		_, err := m.Add("Ann", "ip", "10.1.0.0/16", "spam", 0)
+		Ω(err).ShouldNot(HaveOccurred())
+		m.Add("Ann", "ip", "192.168.1.7", "", 0)
+		m.Add("Ann", "account", "Smaug", "", 0)
+		m.Add("Ann", "character", "Azog", "", 0)
+
+		b, ok := m.Find(Address, "10.1.200.3")
+		Ω(ok).Should(BeTrue())
+		Ω(b.Reason).Should(Equal("spam"))
+		_, ok = m.Find(Address, "10.2.0.1")
+		Ω(ok).Should(BeFalse())
+		_, ok = m.Check("192.168.1.7", "", "")
+		Ω(ok).Should(BeTrue())
+		_, ok = m.Check("127.0.0.1", "smaug", "")
+		Ω(ok).Should(BeTrue())
+		_, ok = m.Check("127.0.0.1", "Thorin", "AZOG")
+		Ω(ok).Should(BeTrue())
+		_, ok = m.Check("127.0.0.1", "Thorin", "Kili")
+		Ω(ok).Should(BeFalse())
+
+		_, err = m.Add("Ann", "ip", "not-an-address", "", 0)
+		Ω(err).Should(MatchError("\"not-an-address\" isn't an address or a CIDR range."))
+		_, err = m.Add("Ann", "planet", "Earth", "", 0)
+		Ω(err).ShouldNot(Succeed())
+	})
+
+	It("lets bans expire and lifts them", func() {
+		b, _ := m.Add("Ann", "character", "Azog", "griefing", 48*time.Hour)
+		Ω(b.Message()).Should(Equal("Azog is banned until 2017-03-03 12:00 UTC: griefing."))
+
+		now = now.Add(47 * time.Hour)
+		Ω(m.Bans()).Should(HaveLen(1))
+		now = now.Add(time.Hour)
+		Ω(m.Bans()).Should(BeEmpty())
+		_, ok := m.Check("", "", "Azog")
+		Ω(ok).Should(BeFalse())
+
+		m.Add("Ann", "account", "Smaug", "", 0)
+		Ω(m.Remove("Bob", "account", "smaug")).Should(Succeed())
+		Ω(m.Bans()).Should(BeEmpty())
+		Ω(m.Remove("Bob", "account", "smaug")).Should(MatchError("There's no account ban on \"smaug\"."))
+	})
+
+	It("audits bans and refuses those that can't be audited", func() {
+		m.Add("Ann", "ip", "10.0.0.0/8", "spam", time.Hour)
+		m.Remove("Bob", "ip", "10.0.0.0/8")
+		Ω(l.records).Should(HaveLen(2))
+		Ω(l.records[0].Category).Should(Equal(audit.Admin))
+		Ω(l.records[0].Actor).Should(Equal("Ann"))
+		Ω(l.records[0].Action).Should(Equal("ban"))
+		Ω(l.records[0].Target).Should(Equal("ip:10.0.0.0/8"))
+		Ω(l.records[0].Data).Should(HaveKeyWithValue("expires", "2017-03-01T13:00:00Z"))
+		Ω(l.records[1].Action).Should(Equal("unban"))
+
+		l.fail = true
+		_, err := m.Add("Ann", "account", "Smaug", "", 0)
+		Ω(err).Should(MatchError(LedgerMessage))
+		Ω(m.Bans()).Should(BeEmpty())
+	})
+
+	It("emits bans as they're made", func() {
+		em := events.NewEmitter(nil)
+		received := make(chan events.Data, 1)
+		em.On(AddEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+		m.SetEmitter(em)
+		m.Add("Ann", "account", "Smaug", "hoarding", 0)
+
+		Eventually(received).Should(Receive(Equal(events.Data{
+			"kind":    Account,
+			"target":  "Smaug",
+			"by":      "Ann",
+			"reason":  "hoarding",
+			"expires": "",
+		})))
+	})
+
+	It("saves the bans to the file", func() {
+		path := filepath.Join(dir, "bans.json")
+		Ω(m.SetFile(path)).Should(Succeed())
+		m.Add("Ann", "ip", "10.0.0.0/8", "spam", 24*time.Hour)
+		m.Add("Ann", "character", "Azog", "", 0)
+
+		loaded := NewManager()
+		loaded.SetClock(func() time.Time { return now })
+		Ω(loaded.SetFile(path)).Should(Succeed())
+		Ω(loaded.Bans()).Should(Equal(m.Bans()))
+	})
+
+	It("lets staff ban, list and lift bans", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(command.Caller) string { return "Ann" }) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+		s := new(staff)
+
+		d.Dispatch(s, "ban ip 10.0.0.0/8 3d too much spam")
+		d.Dispatch(s, "ban character Azog forever")
+		d.Dispatch(s, "ban account Smaug someday")
+		d.Dispatch(s, "bans")
+		d.Dispatch(s, "unban character azog")
+		d.Dispatch(s, "unban character azog")
+		Ω(s.sent).Should(Equal([]string{
+			"Banned ip 10.0.0.0/8 until 2017-03-04 12:00 UTC.",
+			"Banned character Azog forever.",
+			"\"someday\" isn't a length of time.",
+			"ip 10.0.0.0/8 until 2017-03-04 12:00 UTC, by Ann: too much spam\ncharacter Azog forever, by Ann",
+			"The ban on azog is lifted.",
+			"There's no character ban on \"azog\".",
+		}))
+	})
+
+	Describe("handler", func() {
+		var handler http.Handler
+
+		serve := func(method string, form url.Values) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "/admin/bans?"+form.Encode(), nil)
+			if method == "POST" {
+				req = httptest.NewRequest(method, "/admin/bans", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			return rec
+		}
+
+		bans := func(rec *httptest.ResponseRecorder) []Ban {
+			var bans []Ban
+			Ω(json.Unmarshal(rec.Body.Bytes(), &bans)).Should(Succeed())
+
+			return bans
+		}
+
+		BeforeEach(func() {
+			handler = Handler(m)
+		})
+
+		It("adds bans in the name of the api", func() {
+			rec := serve("POST", url.Values{"kind": {"account"}, "target": {"Smaug"}, "duration": {"1w"}})
+
+			Ω(rec.Code).Should(Equal(http.StatusOK))
+			Ω(bans(rec)).Should(HaveLen(1))
+			Ω(bans(rec)[0].By).Should(Equal(APIActor))
+			Ω(l.records[0].Actor).Should(Equal(APIActor))
+		})
+
+		It("rejects bans that don't make sense", func() {
+			Ω(serve("POST", url.Values{"kind": {"ip"}, "target": {"nowhere"}, "duration": {"1d"}}).Code).Should(Equal(http.StatusBadRequest))
+			Ω(serve("POST", url.Values{"kind": {"ip"}, "target": {"10.0.0.1"}}).Code).Should(Equal(http.StatusBadRequest))
+			Ω(serve("PATCH", nil).Code).Should(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("lists and lifts bans", func() {
+			m.Add("Ann", "character", "Azog", "", 0)
+			Ω(bans(serve("GET", nil))).Should(HaveLen(1))
+
+			rec := serve("DELETE", url.Values{"kind": {"character"}, "target": {"azog"}, "by": {"Bob"}})
+			Ω(rec.Code).Should(Equal(http.StatusOK))
+			Ω(bans(rec)).Should(BeEmpty())
+			Ω(l.records[1].Actor).Should(Equal("Bob"))
+		})
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ban
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/perm"
+)
+
+// Namer returns the name of who a command caller is playing, bans are made
+// and lifted in their name.
+type Namer func(command.Caller) string
+
+// NewCommands creates the bans command listing the bans, and the ban and
+// unban commands staff make and lift them with.
+func NewCommands(m *Manager, name Namer) []*command.Command {
+	return []*command.Command{
+		{
+			Name:       "bans",
+			Level:      command.Staff,
+			Capability: perm.Ban,
+			Help:       "Lists the bans, who made them, why and when they end.",
+			Source:     "game",
+			Handler: func(ctx *command.Context) error {
+				return ctx.Send(listing(m.Bans()))
+			},
+		},
+		{
+			Name: "ban",
+			Args: []command.Arg{
+				{Name: "kind", Kind: command.Word},
+				{Name: "target", Kind: command.Word},
+				{Name: "duration", Kind: command.Word},
+				{Name: "reason", Kind: command.Text, Optional: true},
+			},
+			Level:      command.Staff,
+			Capability: perm.Ban,
+			Help: "Bans an ip address or CIDR range, an account or a character, like " +
+				"\"ban ip 10.0.0.0/8 3d spamming\". The duration is like 12h, 3d or 2w, " +
+				"or forever.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				d, err := ParseDuration(ctx.String("duration"))
+				if err != nil {
+					return ctx.Send(sentence(err))
+				}
+				b, err := m.Add(name(ctx.Caller), ctx.String("kind"), ctx.String("target"), ctx.String("reason"), d)
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(fmt.Sprintf("Banned %s.", describe(b)))
+			},
+		},
+		{
+			Name: "unban",
+			Args: []command.Arg{
+				{Name: "kind", Kind: command.Word},
+				{Name: "target", Kind: command.Word},
+			},
+			Level:      command.Staff,
+			Capability: perm.Ban,
+			Help:       "Lifts the ban on an ip address or CIDR range, an account or a character.",
+			Source:     "game",
+			Handler: func(ctx *command.Context) error {
+				if err := m.Remove(name(ctx.Caller), ctx.String("kind"), ctx.String("target")); err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(fmt.Sprintf("The ban on %s is lifted.", ctx.String("target")))
+			},
+		},
+	}
+}
+
+// describe says what the ban is on and how long for
+func describe(b Ban) string {
+	text := fmt.Sprintf("%s %s", b.Kind, b.Target)
+	if b.Permanent() {
+		return text + " forever"
+	}
+
+	return text + " until " + b.Expires.Format("2006-01-02 15:04 MST")
+}
+
+// listing describes each ban, who made it and why
+func listing(bans []Ban) string {
+	if len(bans) == 0 {
+		return "Nobody is banned."
+	}
+
+	lines := make([]string, 0, len(bans))
+	for _, b := range bans {
+		line := describe(b)
+		if b.By != "" {
+			line += ", by " + b.By
+		}
+		if b.Reason != "" {
+			line += ": " + b.Reason
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sendRefused tells the staff member why they were refused, other errors are
+// returned
+func sendRefused(ctx *command.Context, err error) error {
+	if refused, ok := err.(*item.Refused); ok {
+		return ctx.Send(refused.Message)
+	}
+
+	return err
+}
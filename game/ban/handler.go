@@ -0,0 +1,65 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ban
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// APIActor is who bans made and lifted through the handler are recorded as
+// being by, when the request doesn't give a "by" parameter.
+const APIActor = "api"
+
+// Handler serves the bans so they can be managed without logging in. GET
+// returns the bans. POST bans the "target" parameter, with a "kind" of ip,
+// account or character, for the "duration" parameter, like "3d" or
+// "forever", giving the "reason". DELETE lifts the ban of the "kind" on the
+// "target". Every request responds with the bans after the change.
+func Handler(m *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		by := r.FormValue("by")
+		if by == "" {
+			by = APIActor
+		}
+
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			d, perr := ParseDuration(r.FormValue("duration"))
+			if perr != nil {
+				http.Error(w, perr.Error(), http.StatusBadRequest)
+
+				return
+			}
+			_, err = m.Add(by, r.FormValue("kind"), r.FormValue("target"), r.FormValue("reason"), d)
+		case http.MethodDelete:
+			err = m.Remove(by, r.FormValue("kind"), r.FormValue("target"))
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+
+			return
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			if _, ok := err.(*item.Refused); ok {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+
+		bans := m.Bans()
+		if bans == nil {
+			bans = []Ban{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bans)
+	})
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package ban
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// Events emitted as bans are made and lifted, given the kind and target of
+// the ban, who made or lifted it and, for new bans, the reason and when it
+// expires, empty if it doesn't.
+const (
+	AddEvent    = "ban:add"
+	RemoveEvent = "ban:remove"
+)
+
+// LedgerMessage is told to staff whose ban can't be written to the ledger,
+// bans aren't made or lifted without it.
+const LedgerMessage = "The ban can't be recorded right now."
+
+// Ledger is where bans being made and lifted are written, like the audit
+// log.
+type Ledger interface {
+	Append(r audit.Record) (*audit.Record, error)
+}
+
+// Manager keeps the bans.
+type Manager struct {
+	bans    map[string]Ban
+	file    string
+	ledger  Ledger
+	emitter *events.Emitter
+	now     func() time.Time
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager without any bans.
+func NewManager() *Manager {
+	return &Manager{
+		bans:  make(map[string]Ban),
+		now:   time.Now,
+		mutex: new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's bans.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager()
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetLedger sets where bans are written as they're made and lifted, they
+// aren't without one.
+func (m *Manager) SetLedger(l Ledger) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ledger = l
+}
+
+// SetClock changes how the manager tells the time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// SetFile sets where bans are saved, loading those saved there. Missing
+// files are ignored.
+func (m *Manager) SetFile(path string) error {
+	m.mutex.Lock()
+	m.file = path
+	m.mutex.Unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var bans []Ban
+	if err := json.Unmarshal(contents, &bans); err != nil {
+		return err
+	}
+	for _, b := range bans {
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, b := range bans {
+		m.bans[b.key()] = b
+	}
+
+	return nil
+}
+
+// Add bans the target for the duration, forever if it's zero, replacing any
+// ban already on it. Bans are saved as they're made.
+func (m *Manager) Add(by, kind, target, reason string, d time.Duration) (Ban, error) {
+	b := Ban{
+		Kind:   strings.ToLower(kind),
+		Target: strings.TrimSpace(target),
+		Reason: strings.TrimSpace(reason),
+		By:     by,
+	}
+	if err := b.validate(); err != nil {
+		return Ban{}, refuse(sentence(err))
+	}
+
+	m.mutex.RLock()
+	b.Created = m.now().UTC()
+	m.mutex.RUnlock()
+	if d > 0 {
+		b.Expires = b.Created.Add(d)
+	}
+
+	data := map[string]interface{}{
+		"reason":  b.Reason,
+		"expires": expires(b),
+	}
+	if err := m.record(by, "ban", b, data); err != nil {
+		return Ban{}, err
+	}
+
+	m.mutex.Lock()
+	m.bans[b.key()] = b
+	m.mutex.Unlock()
+
+	m.emit(AddEvent, b, by)
+
+	return b, m.save()
+}
+
+// Remove lifts the ban on the target.
+func (m *Manager) Remove(by, kind, target string) error {
+	key := Ban{Kind: strings.ToLower(kind), Target: strings.TrimSpace(target)}.key()
+
+	m.mutex.RLock()
+	b, ok := m.bans[key]
+	m.mutex.RUnlock()
+
+	if !ok {
+		return refuse(fmt.Sprintf("There's no %s ban on %q.", strings.ToLower(kind), target))
+	}
+	if err := m.record(by, "unban", b, nil); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.bans, key)
+	m.mutex.Unlock()
+
+	m.emit(RemoveEvent, b, by)
+
+	return m.save()
+}
+
+// Bans returns the bans that haven't expired, by kind and then target.
+func (m *Manager) Bans() []Ban {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := m.now()
+	var bans []Ban
+	for _, b := range m.bans {
+		if !b.Expired(now) {
+			bans = append(bans, b)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].Kind != bans[j].Kind {
+			return kindOrder(bans[i].Kind) < kindOrder(bans[j].Kind)
+		}
+
+		return strings.ToLower(bans[i].Target) < strings.ToLower(bans[j].Target)
+	})
+
+	return bans
+}
+
+// Find returns the ban of the kind on the value, an address for address
+// bans or a name for the others, if it hasn't expired.
+func (m *Manager) Find(kind, value string) (Ban, bool) {
+	if value == "" {
+		return Ban{}, false
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := m.now()
+	for _, b := range m.bans {
+		if b.Kind == kind && !b.Expired(now) && b.matches(value) {
+			return b, true
+		}
+	}
+
+	return Ban{}, false
+}
+
+// Check returns the ban keeping out a player connecting from the address,
+// with the account and character, if any of them are banned. Empty values
+// aren't checked.
+func (m *Manager) Check(address, account, character string) (Ban, bool) {
+	for _, kind := range Kinds {
+		value := character
+		switch kind {
+		case Address:
+			value = address
+		case Account:
+			value = account
+		}
+		if b, ok := m.Find(kind, value); ok {
+			return b, true
+		}
+	}
+
+	return Ban{}, false
+}
+
+// record writes the ban being made or lifted to the ledger, if there is one
+func (m *Manager) record(by, action string, b Ban, data map[string]interface{}) error {
+	m.mutex.RLock()
+	ledger := m.ledger
+	m.mutex.RUnlock()
+
+	if ledger == nil {
+		return nil
+	}
+	_, err := ledger.Append(audit.Record{
+		Category: audit.Admin,
+		Actor:    by,
+		Action:   action,
+		Target:   b.Kind + ":" + b.Target,
+		Data:     data,
+	})
+	if err != nil {
+		return refuse(LedgerMessage)
+	}
+
+	return nil
+}
+
+func (m *Manager) emit(evt string, b Ban, by string) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return
+	}
+	data := events.Data{
+		"kind":   b.Kind,
+		"target": b.Target,
+		"by":     by,
+	}
+	if evt == AddEvent {
+		data["reason"] = b.Reason
+		data["expires"] = expires(b)
+	}
+	emitter.Emit(evt, data)
+}
+
+// save writes the bans that haven't expired to the file, if there is one
+func (m *Manager) save() error {
+	m.mutex.RLock()
+	path := m.file
+	m.mutex.RUnlock()
+
+	if path == "" {
+		return nil
+	}
+	contents, err := json.MarshalIndent(m.Bans(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// expires is when the ban ends as written in records and events, empty if
+// it doesn't
+func expires(b Ban) string {
+	if b.Permanent() {
+		return ""
+	}
+
+	return b.Expires.Format(time.RFC3339)
+}
+
+// kindOrder is the position of the kind in Kinds
+func kindOrder(kind string) int {
+	for i, k := range Kinds {
+		if k == kind {
+			return i
+		}
+	}
+
+	return len(Kinds)
+}
+
+// sentence turns an error into a sentence to show staff
+func sentence(err error) string {
+	msg := err.Error()
+
+	return strings.ToUpper(msg[:1]) + msg[1:] + "."
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package board puts bulletin boards in rooms for players to leave notes on.
+// Boards are defined in YAML files with the room they hang in, and their
+// posts are saved so they outlast the server. Access hooks can keep players
+// from reading, posting on or clearing a board, like a guild board only its
+// members see.
+package board
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Def defines a board.
+type Def struct {
+	// ID is how the board is known to scripts and what its posts are saved
+	// under.
+	ID string `yaml:"id"`
+	// Name is what players call the board, like "the town notice board".
+	Name string `yaml:"name"`
+	// Room is the id of the room the board hangs in.
+	Room string `yaml:"room"`
+	// Max is the most posts the board holds, the oldest are taken down to
+	// make room for new ones. Zero is no limit.
+	Max int `yaml:"max,omitempty"`
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	d.ID = strings.ToLower(d.ID)
+	if d.ID == "" || strings.ContainsAny(d.ID, `/\. `) {
+		return fmt.Errorf("boards need an id of one word")
+	}
+	if d.Room == "" {
+		return fmt.Errorf("board %s: needs a room", d.ID)
+	}
+	if d.Max < 0 {
+		return fmt.Errorf("board %s: can't hold fewer than no posts", d.ID)
+	}
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+
+	return nil
+}
+
+// Post is a note left on a board.
+type Post struct {
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body,omitempty"`
+	Posted  time.Time `json:"posted"`
+}
+
+// File is the layout of a board file, a list of boards.
+type File struct {
+	Boards []Def `yaml:"boards"`
+}
+
+// Defs holds the board definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[strings.ToLower(id)]
+
+	return def, ok
+}
+
+// In returns the board hanging in the room, the first by id if there are
+// several.
+func (d *Defs) In(room string) (Def, bool) {
+	for _, def := range d.All() {
+		if def.Room == room {
+			return def, true
+		}
+	}
+
+	return Def{}, false
+}
+
+// All returns every definition, sorted by id.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// LoadDir adds the boards in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the boards listed in the YAML document, like:
+//   boards:
+//     - id: town
+//       name: the town notice board
+//       room: town-square
+//       max: 50
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Boards {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Store persists the posts on each board, by the board's id.
+type Store interface {
+	// Load returns the posts on the board, oldest first.
+	Load(id string) ([]Post, error)
+	// Save replaces the posts on the board.
+	Save(id string, posts []Post) error
+}
+
+// DirStore saves the posts on each board as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving posts to the directory, the directory
+// is created when the first board is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Load reads the board's file, boards never posted on have no posts.
+func (s *DirStore) Load(id string) ([]Post, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var posts []Post
+	err = json.Unmarshal(contents, &posts)
+
+	return posts, err
+}
+
+// Save writes the posts to <id>.json through a temporary file, so a crash
+// while saving leaves the last posts in place.
+func (s *DirStore) Save(id string, posts []Post) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(posts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, id+".json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// MemoryStore keeps posts in memory, for tests and boards that are cleared
+// by a restart.
+type MemoryStore struct {
+	boards map[string][]Post
+	mutex  *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		boards: make(map[string][]Post),
+		mutex:  new(sync.Mutex),
+	}
+}
+
+// Load returns a copy of the posts on the board.
+func (s *MemoryStore) Load(id string) ([]Post, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Post(nil), s.boards[id]...), nil
+}
+
+// Save stores a copy of the posts.
+func (s *MemoryStore) Save(id string, posts []Post) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.boards[id] = append([]Post(nil), posts...)
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package board_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// patron uses boards and remembers what they're told
+type patron struct {
+	name  string
+	room  string
+	level command.Level
+	sent  []string
+}
+
+func (p *patron) ID() string {
+	return strings.ToLower(p.name)
+}
+
+func (p *patron) Name() string {
+	return p.name
+}
+
+func (p *patron) Location() string {
+	return p.room
+}
+
+func (p *patron) Level() command.Level {
+	return p.level
+}
+
+func (p *patron) Send(text string) error {
+	p.sent = append(p.sent, text)
+
+	return nil
+}
+
+func (p *patron) told(text string) bool {
+	for _, s := range p.sent {
+		if s == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ = Describe("Boards", func() {
+	var (
+		em       *events.Emitter
+		m        *Manager
+		ann, bob *patron
+	)
+
+	BeforeEach(func() {
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(`
+boards:
+  - id: Town
+    name: the town notice board
+    room: square
+    max: 2
+  - id: guild
+    room: guildhall
+`))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, NewMemoryStore(), em)
+		ann = &patron{name: "Ann", room: "square"}
+		bob = &patron{name: "Bob", room: "square"}
+	})
+
+	It("validates boards", func() {
+		defs := NewDefs()
+		Ω(defs.Add(Def{ID: "notices"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "two words", Room: "square"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "notices", Room: "square", Max: -1})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "notices", Room: "square"})).Should(Succeed())
+		def, ok := defs.In("square")
+		Ω(ok).Should(BeTrue())
+		Ω(def.Name).Should(Equal("notices"))
+	})
+
+	It("posts, reads and removes notes on the board in the room", func() {
+		_, err := m.Post(ann, "Lost cat", "Answers to Whiskers.")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Post(bob, "Selling swords", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		p, err := m.Read(bob, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Author).Should(Equal("Ann"))
+
+		_, err = m.Remove(bob, 1)
+		Ω(err).Should(MatchError(DeniedMessage))
+		bob.level = command.Builder
+		_, err = m.Remove(bob, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		posts, err := m.Posts("town")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(posts).Should(HaveLen(1))
+
+		_, err = m.Read(&patron{name: "Cat", room: "kitchen"}, 1)
+		Ω(err).Should(MatchError(NoBoardMessage))
+		_, err = m.Read(ann, 5)
+		Ω(err).Should(MatchError(NoPostMessage))
+	})
+
+	It("takes down the oldest posts of full boards", func() {
+		for _, subject := range []string{"one", "two", "three"} {
+			_, err := m.Add("town", Post{Author: "Crier", Subject: subject})
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		posts, err := m.Posts("town")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(posts).Should(HaveLen(2))
+		Ω(posts[0].Subject).Should(Equal("two"))
+	})
+
+	It("lets access hooks refuse readers", func() {
+		m.SetAccess("guild", func(req Request) error {
+			if req.Board.ID == "guild" && req.Reader.Name() != "Ann" {
+				return errors.New("Only members may read the guild board.")
+			}
+
+			return nil
+		})
+		m.SetAccess("quiet", func(req Request) error {
+			if req.Action == PostAction {
+				return errors.New("The board is full of nails.")
+			}
+
+			return nil
+		})
+		ann.room, bob.room = "guildhall", "guildhall"
+
+		_, err := m.Here(ann)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Here(bob)
+		Ω(err).Should(MatchError("Only members may read the guild board."))
+		_, err = m.Post(ann, "Hello", "Hi.")
+		Ω(err).Should(MatchError("The board is full of nails."))
+
+		m.SetAccess("quiet", nil)
+		_, err = m.Post(ann, "Hello", "Hi.")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("lets before handlers stop changes and tells of those made", func(done Done) {
+		removed := make(chan events.Data, 1)
+		em.On("before:"+PostEvent, events.HandlerFunc(func(d events.Data) error {
+			if strings.Contains(d["subject"].(string), "gold") {
+				return events.ErrHalt
+			}
+
+			return nil
+		}))
+		em.On(RemoveEvent, events.HandlerFunc(func(d events.Data) error {
+			removed <- d
+
+			return nil
+		}))
+
+		_, err := m.Post(ann, "Cheap gold", "")
+		Ω(err).Should(MatchError(CancelMessage))
+		_, err = m.Post(ann, "Hello", "Hi.")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Delete("town", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		d := <-removed
+		Ω(d["subject"]).Should(Equal("Hello"))
+		close(done)
+	})
+
+	It("saves posts to a directory", func() {
+		dir, err := ioutil.TempDir("", "boards")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		_, err = NewManager(m.Defs(), NewDirStore(dir), nil).Add("guild", Post{Author: "Ann", Subject: "Dues"})
+		Ω(err).ShouldNot(HaveOccurred())
+		posts, err := NewManager(m.Defs(), NewDirStore(dir), nil).Posts("guild")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(posts).Should(HaveLen(1))
+		Ω(posts[0].Subject).Should(Equal("Dues"))
+	})
+
+	It("uses the board with the board command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Reader {
+			return c.(*patron)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+		dispatch := func(p *patron, line string) {
+			Ω(dispatcher.Dispatch(p, line)).Should(Succeed())
+		}
+
+		dispatch(ann, "board")
+		Ω(ann.told("Nothing is posted on the town notice board.")).Should(BeTrue())
+		dispatch(ann, "board post Lost cat")
+		dispatch(ann, "Answers to Whiskers.")
+		dispatch(ann, ".s")
+		Ω(ann.told("You post \"Lost cat\" on the town notice board.")).Should(BeTrue())
+
+		dispatch(bob, "board")
+		Ω(bob.told("Posted on the town notice board:\n  1. Ann          Lost cat")).Should(BeTrue())
+		dispatch(bob, "board remove 1")
+		Ω(bob.told(DeniedMessage)).Should(BeTrue())
+		dispatch(ann, "board remove 1")
+		Ω(ann.told("You take down \"Lost cat\".")).Should(BeTrue())
+	})
+})
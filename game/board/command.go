@@ -0,0 +1,144 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// NewCommands creates the board command for the board in the caller's room.
+// Alone it lists the posts, "board read <n>" reads one, "board post
+// <subject>" writes a note in the editor and "board remove <n>" takes one
+// down.
+func NewCommands(m *Manager, resolve command.Resolver[Reader]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "board",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "rest", Kind: command.Text, Optional: true},
+			},
+			Help: "Lists the posts on the board here, \"board read <n>\" reads one, " +
+				"\"board post <subject>\" writes one and \"board remove <n>\" takes one down.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, r Reader) error {
+				rest := strings.TrimSpace(ctx.String("rest"))
+				switch action := strings.ToLower(ctx.String("action")); action {
+				case "", "list":
+					def, err := m.Here(r)
+					if err != nil {
+						return err
+					}
+					posts, err := m.Posts(def.ID)
+					if err != nil {
+						return err
+					}
+
+					return ctx.Send(listing(def, posts))
+				case "read", "remove":
+					n, err := strconv.Atoi(rest)
+					if err != nil {
+						return ctx.Send(fmt.Sprintf("Which post do you want to %s?", action))
+					}
+					if action == "remove" {
+						p, err := m.Remove(r, n)
+						if err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("You take down \"%s\".", p.Subject))
+					}
+					p, err := m.Read(r, n)
+					if err != nil {
+						return err
+					}
+
+					return ctx.Send(show(p))
+				case "post", "write":
+					if rest == "" {
+						return ctx.Send("What's the subject of your post?")
+					}
+					def, err := m.Here(r)
+					if err != nil {
+						return err
+					}
+					if err := m.allowed(Request{Board: def, Action: PostAction, Reader: r}); err != nil {
+						return err
+					}
+					e := editor.New("", func(body string) {
+						_, err := m.Post(r, rest, body)
+						if ref, ok := err.(*item.Refused); ok {
+							ctx.Send(ref.Message)
+
+							return
+						}
+						if err != nil {
+							logger.NewWithSource("board").WithError(err).WithField("board", def.ID).Error("Failed to post on a board.")
+							ctx.Send("Your post couldn't be put up.")
+
+							return
+						}
+						ctx.Send(fmt.Sprintf("You post \"%s\" on %s.", rest, def.Name))
+					})
+					editor.Open(ctx.Dispatcher, ctx.Caller, e)
+
+					return nil
+				}
+
+				return ctx.Send(ctx.Command.Help)
+			}),
+		},
+	}
+}
+
+// listing lists the posts on the board
+func listing(def Def, posts []Post) string {
+	if len(posts) == 0 {
+		return fmt.Sprintf("Nothing is posted on %s.", def.Name)
+	}
+	lines := []string{fmt.Sprintf("Posted on %s:", def.Name)}
+	for i, p := range posts {
+		lines = append(lines, fmt.Sprintf("%3d. %-12s %s", i+1, p.Author, p.Subject))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// show shows the whole post
+func show(p Post) string {
+	lines := []string{
+		"Subject: " + p.Subject,
+		"By: " + p.Author,
+		"Posted: " + p.Posted.Format("Jan 2 2006 15:04"),
+	}
+	if p.Body != "" {
+		lines = append(lines, "", strings.TrimRight(p.Body, "\n"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's reader for fn, telling the caller when
+// something is refused
+func handler(resolve command.Resolver[Reader], fn func(*command.Context, Reader) error) command.Handler {
+	return func(ctx *command.Context) error {
+		r := resolve(ctx.Caller)
+		if r == nil {
+			return ctx.Send("You can't use boards.")
+		}
+
+		err := fn(ctx, r)
+		if ref, ok := err.(*item.Refused); ok {
+			return ctx.Send(ref.Message)
+		}
+
+		return err
+	}
+}
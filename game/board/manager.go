@@ -0,0 +1,380 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package board
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/spf13/viper"
+)
+
+// Events emitted for changes to boards. Handlers of their before: events can
+// stop them by returning events.ErrHalt, or an error whose message is told to
+// the player. Both are given the board, author and subject, a removal also
+// who removed it.
+const (
+	PostEvent   = "board:post"
+	RemoveEvent = "board:remove"
+)
+
+// Actions checked by access hooks.
+const (
+	ReadAction   = "read"
+	PostAction   = "post"
+	RemoveAction = "remove"
+)
+
+// Messages told to players who can't do something with a board.
+const (
+	NoBoardMessage = "There's no board here."
+	NoPostMessage  = "There's no such post."
+	EmptyMessage   = "You can't post an empty note."
+	DeniedMessage  = "You aren't allowed to do that."
+	CancelMessage  = "You can't do that right now."
+)
+
+// Reader is someone using a board, like a player.
+type Reader interface {
+	ID() string
+	Name() string
+	Location() string
+	Level() command.Level
+}
+
+// Request is something a reader wants to do with a board, for access hooks
+// to allow or refuse.
+type Request struct {
+	Board  Def
+	Action string
+	Reader Reader
+	// Post is the post being removed, for the remove action.
+	Post Post
+}
+
+// Access allows a request by returning nil, or refuses it with an error
+// whose message is told to the reader.
+type Access func(Request) error
+
+type namedAccess struct {
+	name string
+	fn   Access
+}
+
+// Manager keeps the posts on the game's boards.
+type Manager struct {
+	defs    *Defs
+	store   Store
+	access  []namedAccess
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager for the defined boards, keeping their posts
+// in the store. The emitter may be nil.
+func NewManager(defs *Defs, s Store, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:    defs,
+		store:   s,
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's board manager, saving posts in the directory
+// given by the board.posts setting.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), NewDirStore(viper.GetString("board.posts")), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetStore changes where posts are kept, like to keep them in memory.
+func (m *Manager) SetStore(s Store) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.store = s
+}
+
+// Defs returns the board definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// SetAccess sets the access hook with the name, replacing any there was, or
+// removes it if fn is nil. Every hook must allow a request, they're asked in
+// the order their names were first set.
+func (m *Manager) SetAccess(name string, fn Access) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var access []namedAccess
+	found := false
+	for _, na := range m.access {
+		if na.name == name {
+			found = true
+			if fn == nil {
+				continue
+			}
+			na.fn = fn
+		}
+		access = append(access, na)
+	}
+	if !found && fn != nil {
+		access = append(access, namedAccess{name, fn})
+	}
+	m.access = access
+}
+
+// Here returns the board in the reader's room, if they may read it.
+func (m *Manager) Here(r Reader) (Def, error) {
+	def, ok := m.defs.In(r.Location())
+	if !ok {
+		return def, &item.Refused{Message: NoBoardMessage}
+	}
+
+	return def, m.allowed(Request{Board: def, Action: ReadAction, Reader: r})
+}
+
+// Posts returns the posts on the board, oldest first.
+func (m *Manager) Posts(id string) ([]Post, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return nil, &item.Refused{Message: NoBoardMessage}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.store.Load(def.ID)
+}
+
+// Read returns post n on the board in the reader's room, counting from 1.
+func (m *Manager) Read(r Reader, n int) (Post, error) {
+	def, err := m.Here(r)
+	if err != nil {
+		return Post{}, err
+	}
+	posts, err := m.Posts(def.ID)
+	if err != nil {
+		return Post{}, err
+	}
+	if n < 1 || n > len(posts) {
+		return Post{}, &item.Refused{Message: NoPostMessage}
+	}
+
+	return posts[n-1], nil
+}
+
+// Post puts the reader's note on the board in their room.
+func (m *Manager) Post(r Reader, subject, body string) (Post, error) {
+	def, err := m.Here(r)
+	if err != nil {
+		return Post{}, err
+	}
+	if err := m.allowed(Request{Board: def, Action: PostAction, Reader: r}); err != nil {
+		return Post{}, err
+	}
+
+	return m.Add(def.ID, Post{Author: r.Name(), Subject: subject, Body: body})
+}
+
+// Add puts the post on the board with the id, like a notice posted by a
+// script. Access hooks aren't asked.
+func (m *Manager) Add(id string, p Post) (Post, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return p, &item.Refused{Message: NoBoardMessage}
+	}
+	if strings.TrimSpace(p.Body) == "" && strings.TrimSpace(p.Subject) == "" {
+		return p, &item.Refused{Message: EmptyMessage}
+	}
+	if strings.TrimSpace(p.Subject) == "" {
+		p.Subject = "(no subject)"
+	}
+	p.Posted = time.Now()
+	data := events.Data{
+		"board":   def.ID,
+		"author":  p.Author,
+		"subject": p.Subject,
+	}
+	if err := m.check(PostEvent, data); err != nil {
+		return p, err
+	}
+
+	err := m.update(def.ID, func(posts []Post) ([]Post, error) {
+		posts = append(posts, p)
+		if def.Max > 0 && len(posts) > def.Max {
+			posts = posts[len(posts)-def.Max:]
+		}
+
+		return posts, nil
+	})
+	if err != nil {
+		return p, err
+	}
+	m.confirm(PostEvent, data)
+
+	return p, nil
+}
+
+// Remove takes post n, counting from 1, off the board in the reader's
+// room. Readers remove their own posts, builders any of them.
+func (m *Manager) Remove(r Reader, n int) (Post, error) {
+	def, err := m.Here(r)
+	if err != nil {
+		return Post{}, err
+	}
+	posts, err := m.Posts(def.ID)
+	if err != nil {
+		return Post{}, err
+	}
+	if n < 1 || n > len(posts) {
+		return Post{}, &item.Refused{Message: NoPostMessage}
+	}
+	p := posts[n-1]
+	if !strings.EqualFold(p.Author, r.Name()) && r.Level() < command.Builder {
+		return p, &item.Refused{Message: DeniedMessage}
+	}
+	if err := m.allowed(Request{Board: def, Action: RemoveAction, Reader: r, Post: p}); err != nil {
+		return p, err
+	}
+
+	return m.remove(def.ID, n, r.Name())
+}
+
+// Delete takes post n, counting from 1, off the board with the id, like a
+// script clearing out old notices. Access hooks aren't asked.
+func (m *Manager) Delete(id string, n int) (Post, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return Post{}, &item.Refused{Message: NoBoardMessage}
+	}
+
+	return m.remove(def.ID, n, "")
+}
+
+// remove takes the post off the board if before handlers allow it
+func (m *Manager) remove(id string, n int, by string) (Post, error) {
+	var p Post
+	posts, err := m.Posts(id)
+	if err != nil {
+		return p, err
+	}
+	if n < 1 || n > len(posts) {
+		return p, &item.Refused{Message: NoPostMessage}
+	}
+	p = posts[n-1]
+	data := events.Data{
+		"board":   id,
+		"author":  p.Author,
+		"subject": p.Subject,
+		"by":      by,
+	}
+	if err := m.check(RemoveEvent, data); err != nil {
+		return p, err
+	}
+
+	err = m.update(id, func(posts []Post) ([]Post, error) {
+		// the board may have changed while handlers ran, find the post again
+		for i, other := range posts {
+			if other.Posted.Equal(p.Posted) && other.Author == p.Author {
+				return append(posts[:i], posts[i+1:]...), nil
+			}
+		}
+
+		return nil, &item.Refused{Message: NoPostMessage}
+	})
+	if err != nil {
+		return p, err
+	}
+	m.confirm(RemoveEvent, data)
+
+	return p, nil
+}
+
+// allowed asks every access hook about the request
+func (m *Manager) allowed(req Request) error {
+	m.mutex.RLock()
+	access := m.access
+	m.mutex.RUnlock()
+
+	for _, na := range access {
+		if err := na.fn(req); err != nil {
+			if _, ok := err.(*item.Refused); ok {
+				return err
+			}
+
+			return &item.Refused{Message: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// update changes the posts on the board with fn and saves them, nothing
+// changes if fn fails
+func (m *Manager) update(id string, fn func([]Post) ([]Post, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	posts, err := m.store.Load(id)
+	if err != nil {
+		return err
+	}
+	posts, err = fn(posts)
+	if err != nil {
+		return err
+	}
+
+	return m.store.Save(id, posts)
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package channels carries what players say to everyone listening on a
+// channel, like gossip or newbie, wherever they are in the game. Channels
+// are defined in YAML files or by scripts with how their lines are shown,
+// how many are kept for those who missed them and who may use them. Players
+// join and leave channels and can be muted on them, which is saved with
+// them.
+package channels
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/ansi"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The formats lines are shown in unless a channel gives its own.
+const (
+	DefaultFormat     = "[{channel}] {speaker}: {message}"
+	DefaultSelfFormat = "[{channel}] You: {message}"
+)
+
+// DefaultHistory is how many lines a channel keeps unless it says otherwise.
+const DefaultHistory = 20
+
+// Def defines a channel.
+type Def struct {
+	// ID is how the channel is known, it's also the command players speak on
+	// it with.
+	ID string `yaml:"id"`
+	// Name is what the channel is shown as, like "Gossip".
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases,omitempty"`
+	// Format is how lines are shown to listeners, {channel}, {speaker} and
+	// {message} are replaced with the channel's name, who spoke and what
+	// they said. It may contain color codes.
+	Format string `yaml:"format,omitempty"`
+	// Self is how lines are shown to the speaker, like "You gossip,
+	// '{message}'".
+	Self string `yaml:"self,omitempty"`
+	// History is how many lines are kept, -1 keeps none.
+	History int `yaml:"history,omitempty"`
+	// Quiet channels aren't listened to until players join them.
+	Quiet bool `yaml:"quiet,omitempty"`
+	// Flag limits the channel to players with the flag set, like "newbie".
+	Flag string `yaml:"flag,omitempty"`
+	// Scope is the name of the scope hook splitting the channel into groups,
+	// like "clan". Lines only reach those in the speaker's group.
+	Scope string `yaml:"scope,omitempty"`
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	d.ID = strings.ToLower(strings.TrimSpace(d.ID))
+	if d.ID == "" || strings.ContainsAny(d.ID, " \t") {
+		return fmt.Errorf("channels need an id that's a single word")
+	}
+	if d.Name == "" {
+		d.Name = strings.Title(d.ID)
+	}
+	if d.Format == "" {
+		d.Format = DefaultFormat
+	}
+	if d.Self == "" {
+		d.Self = DefaultSelfFormat
+	}
+	if d.History < -1 {
+		return fmt.Errorf("channel %s: history can't be below -1", d.ID)
+	}
+	if d.History == 0 {
+		d.History = DefaultHistory
+	}
+
+	return nil
+}
+
+// format shows the line in the layout given, color codes in the message are
+// shown as typed
+func (d Def) format(layout, speaker, message string) string {
+	return strings.NewReplacer(
+		"{channel}", d.Name,
+		"{speaker}", speaker,
+		"{message}", ansi.EscapeCodes(message),
+	).Replace(layout)
+}
+
+// Show shows the line as listeners of the channel see it.
+func (d Def) Show(l Line) string {
+	return d.format(d.Format, l.Speaker, l.Message)
+}
+
+// Defaults returns the channels the game has unless they're replaced: gossip
+// for everyone, newbie for players with the newbie flag, clan for those in
+// the same clan and gtell for those in the same group.
+func Defaults() []Def {
+	return []Def{
+		{
+			ID:     "gossip",
+			Format: "[m]{speaker} gossips, '{message}'[x]",
+			Self:   "[m]You gossip, '{message}'[x]",
+		},
+		{
+			ID:     "newbie",
+			Format: "[g][Newbie] {speaker}: {message}[x]",
+			Self:   "[g][Newbie] You: {message}[x]",
+			Flag:   "newbie",
+		},
+		{
+			ID:     "clan",
+			Format: "[c][Clan] {speaker}: {message}[x]",
+			Self:   "[c][Clan] You: {message}[x]",
+			Scope:  "clan",
+		},
+		{
+			ID:     "gtell",
+			Format: "[y][Group] {speaker}: {message}[x]",
+			Self:   "[y][Group] You: {message}[x]",
+			Scope:  "group",
+		},
+	}
+}
+
+// File is the layout of a channel file, a list of channels.
+type File struct {
+	Channels []Def `yaml:"channels"`
+}
+
+// Defs holds the channel definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[strings.ToLower(id)]
+
+	return def, ok
+}
+
+// All returns every definition, sorted by id.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// LoadDir adds the channels in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the channels listed in the YAML document, like:
+//   channels:
+//     - id: ooc
+//       name: OOC
+//       aliases: [chat]
+//       format: "[y]({channel}) {speaker}: {message}[x]"
+//       self: "[y]({channel}) You: {message}[x]"
+//       history: 50
+//       quiet: true
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Channels {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
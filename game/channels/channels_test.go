@@ -0,0 +1,249 @@
+package channels_test
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// listener uses channels and remembers what they're told
+type listener struct {
+	name    string
+	level   command.Level
+	flags   map[string]bool
+	clan    string
+	members map[string]Membership
+	sent    []string
+}
+
+func newListener(name string) *listener {
+	return &listener{
+		name:    name,
+		flags:   make(map[string]bool),
+		members: make(map[string]Membership),
+	}
+}
+
+func (l *listener) ID() string {
+	return strings.ToLower(l.name)
+}
+
+func (l *listener) Name() string {
+	return l.name
+}
+
+func (l *listener) Level() command.Level {
+	return l.level
+}
+
+func (l *listener) Send(text string) error {
+	l.sent = append(l.sent, text)
+
+	return nil
+}
+
+func (l *listener) Flag(name string) bool {
+	return l.flags[name]
+}
+
+func (l *listener) Membership(channel string) (Membership, bool) {
+	ms, ok := l.members[channel]
+
+	return ms, ok
+}
+
+func (l *listener) SetMembership(channel string, ms Membership) {
+	l.members[channel] = ms
+}
+
+func (l *listener) told(text string) bool {
+	for _, sent := range l.sent {
+		if sent == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+const channels = `
+channels:
+  - id: ooc
+    name: OOC
+    aliases: [chat]
+    history: 2
+    quiet: true
+`
+
+var _ = Describe("Channels", func() {
+	var (
+		em             *events.Emitter
+		m              *Manager
+		ann, bob, cleo *listener
+	)
+
+	BeforeEach(func() {
+		defs := NewDefs()
+		for _, def := range Defaults() {
+			Ω(defs.Add(def)).Should(Succeed())
+		}
+		Ω(defs.LoadYAML([]byte(channels))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, em)
+		ann, bob, cleo = newListener("Ann"), newListener("Bob"), newListener("Cleo")
+		m.SetMembers(func() []Member {
+			return []Member{ann, bob, cleo}
+		})
+		m.Scope("clan", func(mem Member) string {
+			return mem.(*listener).clan
+		})
+	})
+
+	It("validates channels", func() {
+		defs := NewDefs()
+		Ω(defs.Add(Def{})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "two words"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "Shout"})).Should(Succeed())
+
+		def, ok := defs.Get("shout")
+		Ω(ok).Should(BeTrue())
+		Ω(def.Name).Should(Equal("Shout"))
+		Ω(def.Format).Should(Equal(DefaultFormat))
+		Ω(def.History).Should(Equal(DefaultHistory))
+	})
+
+	It("carries lines to everyone listening", func() {
+		Ω(m.Send(ann, "gossip", "hello [r]there")).Should(Succeed())
+		Ω(ann.told("[m]You gossip, 'hello [[r]]there'[x]")).Should(BeTrue())
+		Ω(bob.told("[m]Ann gossips, 'hello [[r]]there'[x]")).Should(BeTrue())
+		Ω(ann.sent).Should(HaveLen(1))
+
+		Ω(m.Leave(cleo, "gossip")).Should(Succeed())
+		Ω(m.Send(bob, "gossip", "anyone?")).Should(Succeed())
+		Ω(cleo.sent).Should(HaveLen(1))
+		Ω(m.Send(cleo, "gossip", "hi")).Should(MatchError(NotListeningMessage))
+		Ω(m.Send(cleo, "gossip", "  ")).Should(MatchError(EmptyMessage))
+		Ω(m.Send(cleo, "shout", "hi")).Should(MatchError(UnknownMessage))
+	})
+
+	It("keeps quiet channels to those who join them", func() {
+		Ω(m.Listening(ann, "ooc")).Should(BeFalse())
+		Ω(m.Join(ann, "ooc")).Should(Succeed())
+		Ω(m.Join(bob, "ooc")).Should(Succeed())
+		Ω(m.Send(ann, "ooc", "one")).Should(Succeed())
+		Ω(bob.told("[OOC] Ann: one")).Should(BeTrue())
+		Ω(cleo.sent).Should(BeEmpty())
+	})
+
+	It("limits channels to flagged and scoped members", func() {
+		Ω(m.Send(ann, "newbie", "help")).Should(MatchError(DeniedMessage))
+		ann.flags["newbie"], bob.flags["newbie"] = true, true
+		Ω(m.Send(ann, "newbie", "help")).Should(Succeed())
+		Ω(bob.told("[g][Newbie] Ann: help[x]")).Should(BeTrue())
+		Ω(cleo.sent).Should(BeEmpty())
+
+		ann.clan, bob.clan, cleo.clan = "wolves", "bears", "wolves"
+		Ω(m.Send(ann, "clan", "to arms")).Should(Succeed())
+		Ω(cleo.told("[c][Clan] Ann: to arms[x]")).Should(BeTrue())
+		Ω(bob.told("[c][Clan] Ann: to arms[x]")).Should(BeFalse())
+		Ω(m.Lines("clan", "wolves")).Should(HaveLen(1))
+		Ω(m.Lines("clan", "bears")).Should(BeEmpty())
+		bob.clan = ""
+		Ω(m.Send(bob, "clan", "hi")).Should(MatchError(DeniedMessage))
+	})
+
+	It("keeps a limited history", func() {
+		Ω(m.Join(ann, "ooc")).Should(Succeed())
+		for _, said := range []string{"one", "two", "three"} {
+			Ω(m.Send(ann, "ooc", said)).Should(Succeed())
+		}
+		lines, err := m.History(ann, "ooc")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(lines).Should(HaveLen(2))
+		Ω(lines[0].Message).Should(Equal("two"))
+		def, _ := m.Defs().Get("ooc")
+		Ω(def.Show(lines[1])).Should(Equal("[OOC] Ann: three"))
+	})
+
+	It("mutes members until the mute wears off", func() {
+		Ω(m.Mute(ann, "gossip", time.Time{})).Should(Succeed())
+		Ω(m.Muted(ann, "gossip")).Should(BeTrue())
+		Ω(m.Send(ann, "gossip", "hi")).Should(MatchError(MutedMessage))
+		Ω(m.Unmute(ann, "gossip")).Should(Succeed())
+		Ω(m.Send(ann, "gossip", "hi")).Should(Succeed())
+
+		Ω(m.Mute(ann, "gossip", time.Now().Add(-time.Minute))).Should(Succeed())
+		Ω(m.Muted(ann, "gossip")).Should(BeFalse())
+		Ω(m.Listening(ann, "gossip")).Should(BeTrue())
+	})
+
+	It("lets before handlers stop lines and tells of what's said", func(done Done) {
+		said := make(chan events.Data, 1)
+		em.On("before:"+SendEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["message"] == "spam" {
+				return errors.New("No spamming.")
+			}
+
+			return nil
+		}))
+		em.On(SendEvent, events.HandlerFunc(func(d events.Data) error {
+			said <- d
+
+			return nil
+		}))
+
+		Ω(m.Send(ann, "gossip", "spam")).Should(MatchError("No spamming."))
+		Ω(m.Announce("gossip", "The Crier", "Hear ye", "")).Should(Succeed())
+		Ω(bob.told("[m]The Crier gossips, 'Hear ye'[x]")).Should(BeTrue())
+
+		d := <-said
+		Ω(d["speaker"]).Should(Equal(""))
+		Ω(d["name"]).Should(Equal("The Crier"))
+		close(done)
+	})
+
+	It("speaks on channels with commands", func() {
+		registry := command.NewRegistry()
+		resolve := func(c command.Caller) Member {
+			return c.(*listener)
+		}
+		for _, c := range NewCommands(m, resolve) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		Ω(m.SetRegistry(registry, resolve)).Should(Succeed())
+		Ω(m.Create(Def{ID: "trade", Quiet: true})).Should(Succeed())
+		dispatch := func(l *listener, line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(l, line)).Should(Succeed())
+		}
+
+		dispatch(ann, "gossip")
+		Ω(ann.told("Nothing has been said on the Gossip channel.")).Should(BeTrue())
+		dispatch(ann, "gossip hi all")
+		Ω(bob.told("[m]Ann gossips, 'hi all'[x]")).Should(BeTrue())
+		dispatch(bob, "gossip")
+		Ω(bob.sent).Should(Equal([]string{"[m]Ann gossips, 'hi all'[x]", "[m]Ann gossips, 'hi all'[x]"}))
+		dispatch(ann, "channels on shout")
+		Ω(ann.told(UnknownMessage)).Should(BeTrue())
+		dispatch(ann, "channels on trade")
+		Ω(ann.told("You join the Trade channel.")).Should(BeTrue())
+		dispatch(ann, "channels off gossip")
+		dispatch(ann, "channels")
+		Ω(ann.told("Channels:\n  gossip (off)\n  ooc (off)\n  trade (on)")).Should(BeTrue())
+
+		ann.level = command.Admin
+		dispatch(ann, "mute bob trade 5")
+		Ω(ann.told("Bob is muted on the Trade channel.")).Should(BeTrue())
+		Ω(m.Muted(bob, "trade")).Should(BeTrue())
+		dispatch(ann, "unmute bob trade")
+		Ω(bob.told("You can speak on the Trade channel again.")).Should(BeTrue())
+		dispatch(ann, "mute dan trade")
+		Ω(ann.told("There's no one called dan.")).Should(BeTrue())
+	})
+})
@@ -0,0 +1,195 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// Source is the source of the commands channels are spoken on.
+const Source = "channels"
+
+// NewCommands creates the channels command, which lists the channels and
+// joins and leaves them, and the mute and unmute commands for admins. Each
+// channel's own command is registered by Manager.SetRegistry.
+func NewCommands(m *Manager, resolve command.Resolver[Member]) []*command.Command {
+	target := []command.Arg{
+		{Name: "player", Kind: command.Word},
+		{Name: "channel", Kind: command.Word},
+	}
+
+	return []*command.Command{
+		{
+			Name: "channels",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "channel", Kind: command.Word, Optional: true},
+			},
+			Help:   "Lists the channels, \"channels on <channel>\" and \"channels off <channel>\" join and leave them.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, mem Member) error {
+				id := ctx.String("channel")
+				switch strings.ToLower(ctx.String("action")) {
+				case "":
+					return ctx.Send(listing(m, mem))
+				case "on", "join":
+					if err := m.Join(mem, id); err != nil {
+						return err
+					}
+					def, _ := m.defs.Get(id)
+
+					return ctx.Send(fmt.Sprintf("You join the %s channel.", def.Name))
+				case "off", "leave":
+					if err := m.Leave(mem, id); err != nil {
+						return err
+					}
+					def, _ := m.defs.Get(id)
+
+					return ctx.Send(fmt.Sprintf("You leave the %s channel.", def.Name))
+				}
+
+				return ctx.Send("Usage: " + ctx.Command.Usage())
+			}),
+		},
+		{
+			Name:   "mute",
+			Args:   append(target, command.Arg{Name: "minutes", Kind: command.Number, Optional: true}),
+			Level:  command.Admin,
+			Help:   "Stops a player speaking on a channel, for some minutes or until they're unmuted.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, _ Member) error {
+				target, def, err := m.target(ctx.String("player"), ctx.String("channel"))
+				if err != nil {
+					return err
+				}
+				var until time.Time
+				if minutes := ctx.Int("minutes"); minutes > 0 {
+					until = time.Now().Add(time.Duration(minutes) * time.Minute)
+				}
+				if err := m.Mute(target, def.ID, until); err != nil {
+					return err
+				}
+				target.Send(fmt.Sprintf("You've been muted on the %s channel.", def.Name))
+
+				return ctx.Send(fmt.Sprintf("%s is muted on the %s channel.", target.Name(), def.Name))
+			}),
+		},
+		{
+			Name:   "unmute",
+			Args:   target,
+			Level:  command.Admin,
+			Help:   "Lets a muted player speak on a channel again.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, _ Member) error {
+				target, def, err := m.target(ctx.String("player"), ctx.String("channel"))
+				if err != nil {
+					return err
+				}
+				if err := m.Unmute(target, def.ID); err != nil {
+					return err
+				}
+				target.Send(fmt.Sprintf("You can speak on the %s channel again.", def.Name))
+
+				return ctx.Send(fmt.Sprintf("%s can speak on the %s channel again.", target.Name(), def.Name))
+			}),
+		},
+	}
+}
+
+// channelCommand creates the command the channel is spoken on, given
+// nothing to say it shows what was said
+func channelCommand(m *Manager, def Def, resolve command.Resolver[Member]) *command.Command {
+	return &command.Command{
+		Name:    def.ID,
+		Aliases: def.Aliases,
+		Args:    []command.Arg{{Name: "message", Kind: command.Text, Optional: true}},
+		Help:    fmt.Sprintf("Says something on the %s channel, or shows what was said.", def.Name),
+		Source:  Source,
+		Handler: handler(resolve, func(ctx *command.Context, mem Member) error {
+			message := ctx.String("message")
+			if strings.TrimSpace(message) != "" {
+				return m.Send(mem, def.ID, message)
+			}
+			lines, err := m.History(mem, def.ID)
+			if err != nil {
+				return err
+			}
+			current, _ := m.defs.Get(def.ID)
+			if len(lines) == 0 {
+				return ctx.Send(fmt.Sprintf("Nothing has been said on the %s channel.", current.Name))
+			}
+			shown := make([]string, 0, len(lines))
+			for _, l := range lines {
+				shown = append(shown, current.Show(l))
+			}
+
+			return ctx.Send(strings.Join(shown, "\n"))
+		}),
+	}
+}
+
+// listing lists the channels the member can use and whether they listen
+func listing(m *Manager, mem Member) string {
+	defs := m.Usable(mem)
+	if len(defs) == 0 {
+		return "There are no channels."
+	}
+	lines := []string{"Channels:"}
+	for _, def := range defs {
+		status := "off"
+		if listening(def, mem) {
+			status = "on"
+		}
+		if m.Muted(mem, def.ID) {
+			status += ", muted"
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%s)", def.ID, status))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// target finds the member with the name and the channel with the id
+func (m *Manager) target(name, id string) (Member, Def, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return nil, Def{}, &item.Refused{Message: UnknownMessage}
+	}
+
+	m.mutex.RLock()
+	members := m.members
+	m.mutex.RUnlock()
+
+	if members != nil {
+		for _, mem := range members() {
+			if strings.EqualFold(mem.Name(), name) {
+				return mem, def, nil
+			}
+		}
+	}
+
+	return nil, def, &item.Refused{Message: fmt.Sprintf("There's no one called %s.", name)}
+}
+
+// handler resolves the caller's member for fn, telling the caller when
+// using a channel is refused
+func handler(resolve command.Resolver[Member], fn func(*command.Context, Member) error) command.Handler {
+	return func(ctx *command.Context) error {
+		mem := resolve(ctx.Caller)
+		if mem == nil {
+			return ctx.Send("You can't use channels.")
+		}
+
+		err := fn(ctx, mem)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
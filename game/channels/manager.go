@@ -0,0 +1,441 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package channels
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// SendEvent is emitted when something is said on a channel. Handlers of
+// before:channel:send can stop it by returning events.ErrHalt, or an error
+// whose message is told to the speaker. It's given the id of the channel,
+// the id and name of the speaker (the id is empty for announcements), the
+// message and the scope it was said in.
+const SendEvent = "channel:send"
+
+// Messages told to players who can't use a channel.
+const (
+	UnknownMessage      = "There's no such channel."
+	DeniedMessage       = "You can't use that channel."
+	NotListeningMessage = "You aren't listening to that channel."
+	MutedMessage        = "You've been muted on that channel."
+	EmptyMessage        = "Say what?"
+	CancelMessage       = "You can't do that right now."
+)
+
+// Membership is a player's standing on a channel, saved with them.
+type Membership struct {
+	// Joined is true if they listen to the channel.
+	Joined bool `json:"joined"`
+	// Muted players can't speak on the channel until Until, or until they're
+	// unmuted if it's zero.
+	Muted bool      `json:"muted,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+}
+
+// silenced is true if the mute hasn't worn off
+func (ms Membership) silenced(now time.Time) bool {
+	return ms.Muted && (ms.Until.IsZero() || now.Before(ms.Until))
+}
+
+// Member is someone who uses channels, like a player.
+type Member interface {
+	ID() string
+	Name() string
+	Send(text string) error
+	Flag(name string) bool
+	// Membership returns their standing on the channel with the id, false
+	// if it was never changed.
+	Membership(channel string) (Membership, bool)
+	SetMembership(channel string, ms Membership)
+}
+
+// ScopeFunc returns the group a member talks in on channels with the scope,
+// like the id of their clan, empty if they can't use them.
+type ScopeFunc func(m Member) string
+
+// Line is something said on a channel.
+type Line struct {
+	Time    time.Time
+	Speaker string
+	Message string
+}
+
+// Manager carries lines to the members listening on each channel and keeps
+// their history.
+type Manager struct {
+	defs     *Defs
+	scopes   map[string]ScopeFunc
+	history  map[string][]Line
+	members  func() []Member
+	registry *command.Registry
+	resolve  command.Resolver[Member]
+	emitter  *events.Emitter
+	mutex    *sync.RWMutex
+}
+
+// NewManager creates a manager for the channels. The emitter may be nil.
+func NewManager(defs *Defs, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:    defs,
+		scopes:  make(map[string]ScopeFunc),
+		history: make(map[string][]Line),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's channel manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetMembers sets how the members who could be listening are found, like
+// the players in the game.
+func (m *Manager) SetMembers(fn func() []Member) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.members = fn
+}
+
+// SetRegistry registers the command of each channel with the registry, and
+// of every channel created after.
+func (m *Manager) SetRegistry(r *command.Registry, resolve command.Resolver[Member]) error {
+	m.mutex.Lock()
+	m.registry, m.resolve = r, resolve
+	m.mutex.Unlock()
+
+	for _, def := range m.defs.All() {
+		if err := m.register(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Defs returns the channel definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// Create adds the channel, replacing any with its id, and registers its
+// command if there's a registry.
+func (m *Manager) Create(def Def) error {
+	if err := m.defs.Add(def); err != nil {
+		return err
+	}
+	def, _ = m.defs.Get(def.ID)
+
+	return m.register(def)
+}
+
+// Scope sets the scope hook with the name, replacing any there was.
+func (m *Manager) Scope(name string, fn ScopeFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.scopes[name] = fn
+}
+
+// Usable returns the channels the member may use, sorted by id.
+func (m *Manager) Usable(mem Member) []Def {
+	var defs []Def
+	for _, def := range m.defs.All() {
+		if _, ok := m.scope(def, mem); ok {
+			defs = append(defs, def)
+		}
+	}
+
+	return defs
+}
+
+// Listening is true if the member hears the channel with the id.
+func (m *Manager) Listening(mem Member, id string) bool {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return false
+	}
+
+	return listening(def, mem)
+}
+
+// Join starts the member listening to the channel with the id.
+func (m *Manager) Join(mem Member, id string) error {
+	return m.update(mem, id, func(ms *Membership) {
+		ms.Joined = true
+	})
+}
+
+// Leave stops the member listening to the channel with the id.
+func (m *Manager) Leave(mem Member, id string) error {
+	return m.update(mem, id, func(ms *Membership) {
+		ms.Joined = false
+	})
+}
+
+// Mute stops the member speaking on the channel with the id until the time
+// given, or until they're unmuted if it's zero.
+func (m *Manager) Mute(mem Member, id string, until time.Time) error {
+	return m.update(mem, id, func(ms *Membership) {
+		ms.Muted, ms.Until = true, until
+	})
+}
+
+// Unmute lets the member speak on the channel with the id again.
+func (m *Manager) Unmute(mem Member, id string) error {
+	return m.update(mem, id, func(ms *Membership) {
+		ms.Muted, ms.Until = false, time.Time{}
+	})
+}
+
+// Muted is true if the member can't speak on the channel with the id.
+func (m *Manager) Muted(mem Member, id string) bool {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return false
+	}
+	ms, _ := mem.Membership(def.ID)
+
+	return ms.silenced(time.Now())
+}
+
+// Send says the message on the channel with the id, it's heard by every
+// member listening in the speaker's scope.
+func (m *Manager) Send(speaker Member, id, message string) error {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return &item.Refused{Message: UnknownMessage}
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return &item.Refused{Message: EmptyMessage}
+	}
+	scope, ok := m.scope(def, speaker)
+	if !ok {
+		return &item.Refused{Message: DeniedMessage}
+	}
+	if !listening(def, speaker) {
+		return &item.Refused{Message: NotListeningMessage}
+	}
+	if ms, _ := speaker.Membership(def.ID); ms.silenced(time.Now()) {
+		return &item.Refused{Message: MutedMessage}
+	}
+	data := sendData(def, speaker.ID(), speaker.Name(), message, scope)
+	if err := m.check(SendEvent, data); err != nil {
+		return err
+	}
+
+	speaker.Send(def.format(def.Self, speaker.Name(), message))
+	m.deliver(def, speaker.ID(), speaker.Name(), message, scope)
+	m.confirm(SendEvent, data)
+
+	return nil
+}
+
+// Announce says the message on the channel with the id as the speaker
+// named, like a script's town crier. It's heard by every member listening
+// in the scope, which is ignored by channels without one.
+func (m *Manager) Announce(id, speaker, message, scope string) error {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return &item.Refused{Message: UnknownMessage}
+	}
+	if def.Scope == "" {
+		scope = ""
+	}
+
+	m.deliver(def, "", speaker, message, scope)
+	m.confirm(SendEvent, sendData(def, "", speaker, message, scope))
+
+	return nil
+}
+
+// History returns what was said on the channel with the id in the member's
+// scope, oldest first.
+func (m *Manager) History(mem Member, id string) ([]Line, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return nil, &item.Refused{Message: UnknownMessage}
+	}
+	scope, ok := m.scope(def, mem)
+	if !ok {
+		return nil, &item.Refused{Message: DeniedMessage}
+	}
+
+	return m.Lines(def.ID, scope), nil
+}
+
+// Lines returns what was said on the channel with the id in the scope,
+// oldest first.
+func (m *Manager) Lines(id, scope string) []Line {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	lines := m.history[historyKey(strings.ToLower(id), scope)]
+
+	return append([]Line(nil), lines...)
+}
+
+// deliver keeps the line and tells it to everyone listening in the scope
+// but the speaker
+func (m *Manager) deliver(def Def, from, speaker, message, scope string) {
+	m.mutex.Lock()
+	if def.History > 0 {
+		key := historyKey(def.ID, scope)
+		lines := append(m.history[key], Line{Time: time.Now(), Speaker: speaker, Message: message})
+		if len(lines) > def.History {
+			lines = lines[len(lines)-def.History:]
+		}
+		m.history[key] = lines
+	}
+	members := m.members
+	m.mutex.Unlock()
+
+	if members == nil {
+		return
+	}
+	text := def.format(def.Format, speaker, message)
+	for _, mem := range members() {
+		if from != "" && mem.ID() == from {
+			continue
+		}
+		if s, ok := m.scope(def, mem); !ok || s != scope || !listening(def, mem) {
+			continue
+		}
+		mem.Send(text)
+	}
+}
+
+// update changes the member's standing on the channel with the id
+func (m *Manager) update(mem Member, id string, fn func(*Membership)) error {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return &item.Refused{Message: UnknownMessage}
+	}
+	if _, ok := m.scope(def, mem); !ok {
+		return &item.Refused{Message: DeniedMessage}
+	}
+	ms, _ := mem.Membership(def.ID)
+	ms.Joined = listening(def, mem)
+	fn(&ms)
+	mem.SetMembership(def.ID, ms)
+
+	return nil
+}
+
+// scope returns the group the member uses the channel in, false if they
+// can't use it
+func (m *Manager) scope(def Def, mem Member) (string, bool) {
+	if def.Flag != "" && !mem.Flag(def.Flag) {
+		return "", false
+	}
+	if def.Scope == "" {
+		return "", true
+	}
+
+	m.mutex.RLock()
+	fn := m.scopes[def.Scope]
+	m.mutex.RUnlock()
+
+	if fn == nil {
+		return "", false
+	}
+	scope := fn(mem)
+
+	return scope, scope != ""
+}
+
+// register adds the channel's command to the registry, replacing the one it
+// had before
+func (m *Manager) register(def Def) error {
+	m.mutex.RLock()
+	r, resolve := m.registry, m.resolve
+	m.mutex.RUnlock()
+
+	if r == nil {
+		return nil
+	}
+	if c, ok := r.Get(def.ID); ok && c.Source == Source {
+		r.Unregister(c.Name)
+	}
+
+	return r.Register(channelCommand(m, def, resolve))
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+// listening is true if the member hears the channel, members who never
+// joined or left it hear channels that aren't quiet
+func listening(def Def, mem Member) bool {
+	if ms, ok := mem.Membership(def.ID); ok {
+		return ms.Joined
+	}
+
+	return !def.Quiet
+}
+
+func historyKey(id, scope string) string {
+	return id + "\x00" + scope
+}
+
+func sendData(def Def, id, name, message, scope string) events.Data {
+	return events.Data{
+		"channel": def.ID,
+		"speaker": id,
+		"name":    name,
+		"message": message,
+		"scope":   scope,
+	}
+}
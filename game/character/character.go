@@ -0,0 +1,225 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package character makes and stores players' characters. The races and
+// classes players choose from are registered by plugins, usually as Lua
+// tables, so a game decides what its characters can be without changing the
+// server.
+package character
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/spf13/viper"
+)
+
+// Character is a player's character as it's saved.
+type Character struct {
+	// Name is the character's name as the player chose it.
+	Name string `json:"name"`
+	// Account is the name of the account the character belongs to.
+	Account string `json:"account"`
+	// Race is the name of the character's race, if the game has races.
+	Race string `json:"race,omitempty"`
+	// Class is the name of the character's class, if the game has classes.
+	Class string `json:"class,omitempty"`
+	// Stats are the character's rolled stats, including the bonuses of their
+	// race and class.
+	Stats map[string]int `json:"stats"`
+	// Created is when the character was made.
+	Created time.Time `json:"created"`
+}
+
+// Key is how the character is stored, its name in lower case.
+func (c *Character) Key() string {
+	return strings.ToLower(c.Name)
+}
+
+// Store persists characters, keyed by their lower case name.
+type Store interface {
+	// Save creates or replaces the character.
+	Save(c *Character) error
+	// Load returns the character, or ErrNotFound.
+	Load(name string) (*Character, error)
+	// Delete removes the character.
+	Delete(name string) error
+}
+
+var (
+	globalStore     Store
+	globalStoreOnce sync.Once
+)
+
+// GlobalStore returns the store of the game's characters, saved in the
+// directory given by the character.dir setting, or the game's database if
+// the character.store setting is "database".
+func GlobalStore() Store {
+	globalStoreOnce.Do(func() {
+		if viper.GetString("character.store") == data.DatabaseStore {
+			globalStore = NewDataStore(data.Storage())
+		} else {
+			globalStore = NewDirStore(viper.GetString("character.dir"))
+		}
+	})
+
+	return globalStore
+}
+
+// DirStore saves each character as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving characters to the directory, the
+// directory is created when the first character is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Save writes the character to <name>.json, through a temporary file so a
+// crash never leaves a partially written character behind.
+func (s *DirStore) Save(c *Character) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, c.Key()+".json")
+	return fs.WriteFile(path, contents, 0600)
+}
+
+// Load reads the character's file.
+func (s *DirStore) Load(name string) (*Character, error) {
+	if strings.ContainsAny(name, `/\.`) {
+		return nil, ErrNotFound
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, strings.ToLower(name)+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(Character)
+	if err := json.Unmarshal(contents, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Delete removes the character's file, missing files are ignored.
+func (s *DirStore) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.Dir, strings.ToLower(name)+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// MemoryStore keeps characters in memory, they're lost when the server stops.
+type MemoryStore struct {
+	characters map[string]Character
+	mutex      *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		characters: make(map[string]Character),
+		mutex:      new(sync.Mutex),
+	}
+}
+
+// Save stores a copy of the character.
+func (s *MemoryStore) Save(c *Character) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.characters[c.Key()] = copyCharacter(*c)
+
+	return nil
+}
+
+// Load returns a copy of the character.
+func (s *MemoryStore) Load(name string) (*Character, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.characters[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c = copyCharacter(c)
+
+	return &c, nil
+}
+
+// Delete removes the character.
+func (s *MemoryStore) Delete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.characters, strings.ToLower(name))
+
+	return nil
+}
+
+// DataStore saves characters in the "characters" bucket of a data store,
+// like the game's database.
+type DataStore struct {
+	bucket *data.Bucket
+}
+
+// NewDataStore creates a store saving characters in the data store.
+func NewDataStore(s data.Store) *DataStore {
+	return &DataStore{bucket: data.NewBucket(s, "characters")}
+}
+
+// Save stores the character as JSON.
+func (s *DataStore) Save(c *Character) error {
+	return s.bucket.Save(c.Key(), c)
+}
+
+// Load reads the character.
+func (s *DataStore) Load(name string) (*Character, error) {
+	c := new(Character)
+	err := s.bucket.Load(strings.ToLower(name), c)
+	if err == data.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Delete removes the character.
+func (s *DataStore) Delete(name string) error {
+	return s.bucket.Delete(strings.ToLower(name))
+}
+
+func copyCharacter(c Character) Character {
+	stats := make(map[string]int, len(c.Stats))
+	for k, v := range c.Stats {
+		stats[k] = v
+	}
+	c.Stats = stats
+
+	return c
+}
@@ -0,0 +1,25 @@
+package character_test
+
+import (
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCharacter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Character Suite")
+}
+
+var _ = BeforeSuite(func() {
+	viper.Set("account.hash.time", 1)
+	viper.Set("account.hash.memory", 1024)
+	viper.Set("account.hash.threads", 1)
+	viper.Set("account.min_password_length", 8)
+	viper.Set("character.stats", []string{"str", "dex"})
+	viper.Set("character.roll", "4d6")
+	viper.Set("character.roll_keep", 3)
+})
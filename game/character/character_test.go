@@ -0,0 +1,239 @@
+package character_test
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/account"
+	. "github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newcomer records what they're shown while making a character
+type newcomer struct {
+	sent []string
+}
+
+func (n *newcomer) ID() string {
+	return "newcomer-1"
+}
+
+func (n *newcomer) Level() command.Level {
+	return command.Player
+}
+
+func (n *newcomer) Send(text string) error {
+	n.sent = append(n.sent, text)
+
+	return nil
+}
+
+func (n *newcomer) last() string {
+	return n.sent[len(n.sent)-1]
+}
+
+var _ = Describe("Registry", func() {
+	var r *Registry
+
+	BeforeEach(func() {
+		r = NewRegistry()
+		r.AddRace(Race{Name: "Dwarf"})
+		r.AddRace(Race{Name: "Elf"})
+		r.AddClass(Class{Name: "Warrior"})
+		r.AddClass(Class{Name: "Ranger", Races: []string{"elf"}})
+	})
+
+	It("keeps races and classes in order, replacing them by name", func() {
+		r.AddRace(Race{Name: "dwarf", Description: "Stout."})
+
+		races := r.Races()
+		Ω(races).Should(HaveLen(2))
+		Ω(races[0].Description).Should(Equal("Stout."))
+		Ω(races[1].Name).Should(Equal("Elf"))
+	})
+
+	It("limits classes to the races they allow", func() {
+		Ω(r.Classes("Dwarf")).Should(HaveLen(1))
+		Ω(r.Classes("Elf")).Should(HaveLen(2))
+		Ω(r.Classes("")).Should(HaveLen(2))
+	})
+
+	It("validates names", func() {
+		r.Reserve("Smaug")
+		r.Ban("darn")
+
+		Ω(r.ValidateName("Bilbo")).Should(Succeed())
+		Ω(r.ValidateName("smaug")).Should(Equal(ErrReservedName))
+		Ω(r.ValidateName("Xdarnx")).Should(Equal(ErrBannedName))
+		Ω(r.ValidateName("B1lbo")).ShouldNot(Succeed())
+	})
+
+	It("rolls stats keeping the highest dice", func() {
+		for i := 0; i < 20; i++ {
+			stats := RollStats()
+			Ω(stats).Should(HaveLen(2))
+			Ω(stats["str"]).Should(BeNumerically(">=", 3))
+			Ω(stats["str"]).Should(BeNumerically("<=", 18))
+		}
+	})
+})
+
+var _ = Describe("Creation", func() {
+	var (
+		r         *Registry
+		accounts  *account.Manager
+		store     *MemoryStore
+		em        *events.Emitter
+		creator   *Creator
+		n         *newcomer
+		a         *account.Account
+		created   []string
+		received  chan events.Data
+		createdAt time.Time
+		mode      command.Mode
+		input     func(lines ...string) bool
+	)
+
+	BeforeEach(func() {
+		r = NewRegistry()
+		r.AddRace(Race{Name: "Dwarf", Description: "Stout and stubborn.", Stats: map[string]int{"str": 2, "dex": -1}})
+		r.AddRace(Race{Name: "Elf"})
+		r.AddClass(Class{Name: "Warrior", Stats: map[string]int{"str": 1}})
+		r.AddClass(Class{Name: "Ranger", Races: []string{"elf"}})
+		accounts = account.NewManager(account.NewMemoryStore())
+		store = NewMemoryStore()
+		em = events.NewEmitter(nil)
+		received = make(chan events.Data, 5)
+		em.On(CreatedEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+		createdAt = time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+		creator = NewCreator(r, accounts, store, em)
+		creator.SetClock(func() time.Time {
+			return createdAt
+		})
+		n = new(newcomer)
+		a, _ = accounts.Create("Thorin", "oakenshield", "")
+		created = nil
+		mode = creator.Create(n, a, "Fili", func(c string) {
+			created = append(created, c)
+		})
+		input = func(lines ...string) bool {
+			done := false
+			for _, line := range lines {
+				done = mode.Input(n, line)
+			}
+
+			return done
+		}
+	})
+
+	It("shows the races to choose from", func() {
+		Ω(n.last()).Should(Equal("Choose a race for Fili:\n  1) Dwarf - Stout and stubborn.\n  2) Elf"))
+	})
+
+	It("makes and saves the character", func() {
+		Ω(input("1")).Should(BeFalse())
+		Ω(n.last()).Should(Equal("Choose a class:\n  1) Warrior"))
+		Ω(input("warrior")).Should(BeFalse())
+		Ω(n.last()).Should(HavePrefix("Your stats are:\n  str "))
+		Ω(input("roll", "keep")).Should(BeTrue())
+
+		Ω(created).Should(Equal([]string{"Fili"}))
+		Ω(a.Characters).Should(Equal([]string{"Fili"}))
+		c, err := store.Load("fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c.Account).Should(Equal("Thorin"))
+		Ω(c.Race).Should(Equal("Dwarf"))
+		Ω(c.Class).Should(Equal("Warrior"))
+		Ω(c.Created).Should(Equal(createdAt))
+		Ω(c.Stats["str"]).Should(BeNumerically(">=", 6))
+		Ω(c.Stats["dex"]).Should(BeNumerically("<=", 17))
+
+		var d events.Data
+		Eventually(received).Should(Receive(&d))
+		Ω(d["character"]).Should(Equal("Fili"))
+		Ω(d["race"]).Should(Equal("Dwarf"))
+	})
+
+	It("rejects choices that aren't offered", func() {
+		input("Hobbit")
+		Ω(n.last()).Should(Equal("That isn't one of the races."))
+
+		input("dwarf", "ranger")
+		Ω(n.last()).Should(Equal("That isn't one of the classes."))
+		Ω(mode.(*Creation).Step()).Should(Equal(ChooseClass))
+	})
+
+	It("can be cancelled", func() {
+		Ω(input("elf", "cancel")).Should(BeTrue())
+
+		Ω(created).Should(Equal([]string{""}))
+		Ω(a.Characters).Should(BeEmpty())
+	})
+
+	It("skips choices that aren't registered", func() {
+		creator = NewCreator(NewRegistry(), accounts, store, nil)
+		mode = creator.Create(n, a, "Kili", func(c string) {
+			created = append(created, c)
+		})
+
+		Ω(n.last()).Should(HavePrefix("Your stats are:"))
+		Ω(input("k")).Should(BeTrue())
+		Ω(created).Should(Equal([]string{"Kili"}))
+	})
+
+	It("finishes logging in the player", func() {
+		var playing string
+		login := account.NewLogin(accounts, nil, func(_ *account.Account, c string) {
+			playing = c
+		}, nil)
+		login.SetCreator(creator)
+		login.Start(n)
+
+		for _, line := range []string{"thorin", "oakenshield", "Kili", "elf", "ranger"} {
+			Ω(login.Input(n, line)).Should(BeFalse())
+		}
+		Ω(login.State()).Should(Equal(account.CreateCharacter))
+		Ω(login.Input(n, "keep")).Should(BeTrue())
+		Ω(playing).Should(Equal("Kili"))
+	})
+
+	It("checks names when logging in", func() {
+		r.Reserve("Smaug")
+		login := account.NewLogin(accounts, nil, nil, nil)
+		login.SetCreator(creator)
+		login.Start(n)
+		login.Input(n, "thorin")
+		login.Input(n, "oakenshield")
+		login.Input(n, "smaug")
+
+		Ω(n.last()).Should(Equal("That name is reserved."))
+		Ω(login.State()).Should(Equal(account.ChooseCharacter))
+	})
+})
+
+var _ = Describe("DirStore", func() {
+	It("saves and loads characters", func() {
+		dir, err := ioutil.TempDir("", "characters")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		store := NewDirStore(dir)
+
+		Ω(store.Save(&Character{Name: "Fili", Stats: map[string]int{"str": 12}})).Should(Succeed())
+		c, err := store.Load("FILI")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c.Stats).Should(Equal(map[string]int{"str": 12}))
+
+		Ω(store.Delete("fili")).Should(Succeed())
+		_, err = store.Load("fili")
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+})
@@ -0,0 +1,344 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package character
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/account"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/spf13/viper"
+)
+
+// CreatedEvent is emitted when a character is made. It carries the caller's
+// id, the account, the character's name, race and class and their stats.
+const CreatedEvent = "character:created"
+
+// Step is where a player is in making a character.
+type Step int
+
+// Creation steps, races and classes are skipped if none are registered
+const (
+	ChooseRace Step = iota
+	ChooseClass
+	ChooseStats
+	Finished
+)
+
+// String returns the name of the step.
+func (s Step) String() string {
+	switch s {
+	case ChooseRace:
+		return "race"
+	case ChooseClass:
+		return "class"
+	case ChooseStats:
+		return "stats"
+	case Finished:
+		return "finished"
+	default:
+		return "unknown"
+	}
+}
+
+// Creator makes characters for players logging in, it's an account.Creator.
+type Creator struct {
+	registry *Registry
+	accounts *account.Manager
+	store    Store
+	emitter  *events.Emitter
+	now      func() time.Time
+}
+
+// NewCreator creates a creator offering the races and classes of the
+// registry, adding characters to their accounts in the manager and saving
+// them to the store. Events are emitted to em, which may be nil.
+func NewCreator(r *Registry, accounts *account.Manager, store Store, em *events.Emitter) *Creator {
+	return &Creator{
+		registry: r,
+		accounts: accounts,
+		store:    store,
+		emitter:  em,
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the function used to get the current time, for tests.
+func (c *Creator) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// ValidateName checks the name against the registry.
+func (c *Creator) ValidateName(name string) error {
+	return c.registry.ValidateName(name)
+}
+
+// Create starts making the character, showing the caller the first choice.
+func (c *Creator) Create(caller command.Caller, a *account.Account, name string, done func(character string)) command.Mode {
+	cr := &Creation{
+		creator: c,
+		account: a,
+		name:    name,
+		done:    done,
+		mutex:   new(sync.Mutex),
+	}
+	cr.enter(caller, ChooseRace)
+
+	return cr
+}
+
+// Creation is a character being made, it's a command.Mode taking the player's
+// choices. Typing cancel at any step abandons the character.
+type Creation struct {
+	creator *Creator
+	account *account.Account
+	name    string
+	step    Step
+	race    string
+	class   string
+	stats   map[string]int
+	done    func(character string)
+	mutex   *sync.Mutex
+}
+
+// Step returns the step the player is on.
+func (cr *Creation) Step() Step {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	return cr.step
+}
+
+// Input handles the player's choice for the current step, returning true once
+// the character is made or abandoned.
+func (cr *Creation) Input(caller command.Caller, line string) bool {
+	line = strings.TrimSpace(line)
+	if strings.EqualFold(line, "cancel") {
+		caller.Send("You stop making " + cr.name + ".")
+		cr.finish("")
+
+		return true
+	}
+
+	switch cr.Step() {
+	case ChooseRace:
+		races := cr.creator.registry.Races()
+		names := make([]string, len(races))
+		for i, race := range races {
+			names[i] = race.Name
+		}
+		race, ok := choose(names, line)
+		if !ok {
+			caller.Send("That isn't one of the races.")
+
+			break
+		}
+		cr.mutex.Lock()
+		cr.race = race
+		cr.mutex.Unlock()
+		cr.enter(caller, ChooseClass)
+	case ChooseClass:
+		cr.mutex.Lock()
+		race := cr.race
+		cr.mutex.Unlock()
+		classes := cr.creator.registry.Classes(race)
+		names := make([]string, len(classes))
+		for i, class := range classes {
+			names[i] = class.Name
+		}
+		class, ok := choose(names, line)
+		if !ok {
+			caller.Send("That isn't one of the classes.")
+
+			break
+		}
+		cr.mutex.Lock()
+		cr.class = class
+		cr.mutex.Unlock()
+		cr.enter(caller, ChooseStats)
+	case ChooseStats:
+		switch strings.ToLower(line) {
+		case "keep", "k":
+			return cr.save(caller)
+		case "roll", "r":
+			cr.enter(caller, ChooseStats)
+		default:
+			caller.Send("Type keep to accept these stats or roll to roll again.")
+		}
+	}
+
+	return false
+}
+
+// enter moves to the step and shows its screen, steps without anything to
+// choose from are skipped
+func (cr *Creation) enter(caller command.Caller, step Step) {
+	r := cr.creator.registry
+
+	cr.mutex.Lock()
+	cr.step = step
+	race := cr.race
+	cr.mutex.Unlock()
+
+	switch step {
+	case ChooseRace:
+		races := r.Races()
+		if len(races) == 0 {
+			cr.enter(caller, ChooseClass)
+
+			return
+		}
+		lines := []string{"Choose a race for " + cr.name + ":"}
+		for i, race := range races {
+			lines = append(lines, option(i, race.Name, race.Description))
+		}
+		caller.Send(strings.Join(lines, "\n"))
+	case ChooseClass:
+		classes := r.Classes(race)
+		if len(classes) == 0 {
+			cr.enter(caller, ChooseStats)
+
+			return
+		}
+		lines := []string{"Choose a class:"}
+		for i, class := range classes {
+			lines = append(lines, option(i, class.Name, class.Description))
+		}
+		caller.Send(strings.Join(lines, "\n"))
+	case ChooseStats:
+		stats := cr.roll()
+		caller.Send("Your stats are:\n" + formatStats(stats) + "\nType keep to accept them or roll to roll again.")
+	}
+}
+
+// roll rolls new stats and adds the bonuses of the race and class
+func (cr *Creation) roll() map[string]int {
+	r := cr.creator.registry
+	stats := RollStats()
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	if race, ok := r.Race(cr.race); ok {
+		for stat, bonus := range race.Stats {
+			stats[stat] += bonus
+		}
+	}
+	if class, ok := r.Class(cr.class); ok {
+		for stat, bonus := range class.Stats {
+			stats[stat] += bonus
+		}
+	}
+	cr.stats = stats
+
+	return stats
+}
+
+// save adds the character to the account and saves it
+func (cr *Creation) save(caller command.Caller) bool {
+	c := cr.creator
+
+	cr.mutex.Lock()
+	ch := &Character{
+		Name:    cr.name,
+		Account: cr.account.Name,
+		Race:    cr.race,
+		Class:   cr.class,
+		Stats:   cr.stats,
+		Created: c.now().UTC(),
+	}
+	cr.step = Finished
+	cr.mutex.Unlock()
+
+	if err := c.accounts.AddCharacter(cr.account, ch.Name); err != nil {
+		caller.Send(fmt.Sprintf("%s couldn't be made, %s.", ch.Name, err))
+		cr.finish("")
+
+		return true
+	}
+	if err := c.store.Save(ch); err != nil {
+		caller.Send("Your character couldn't be saved, please try again later.")
+		cr.finish("")
+
+		return true
+	}
+
+	if c.emitter != nil {
+		c.emitter.Emit(CreatedEvent, events.Data{
+			"caller":    caller.ID(),
+			"account":   ch.Account,
+			"character": ch.Name,
+			"race":      ch.Race,
+			"class":     ch.Class,
+			"stats":     ch.Stats,
+		})
+	}
+	cr.finish(ch.Name)
+
+	return true
+}
+
+func (cr *Creation) finish(character string) {
+	if cr.done != nil {
+		cr.done(character)
+	}
+}
+
+// choose finds the option picked by number or name
+func choose(options []string, input string) (string, bool) {
+	if n, err := strconv.Atoi(input); err == nil {
+		if n >= 1 && n <= len(options) {
+			return options[n-1], true
+		}
+
+		return "", false
+	}
+	for _, opt := range options {
+		if strings.EqualFold(opt, input) {
+			return opt, true
+		}
+	}
+
+	return "", false
+}
+
+func option(i int, name, description string) string {
+	if description == "" {
+		return fmt.Sprintf("  %d) %s", i+1, name)
+	}
+
+	return fmt.Sprintf("  %d) %s - %s", i+1, name, description)
+}
+
+// formatStats lists the stats in a row, in the order of the character.stats
+// setting followed by any others
+func formatStats(stats map[string]int) string {
+	var names, others []string
+	listed := make(map[string]bool)
+	for _, name := range viper.GetStringSlice("character.stats") {
+		if _, ok := stats[name]; ok && !listed[name] {
+			names = append(names, name)
+			listed[name] = true
+		}
+	}
+	for name := range stats {
+		if !listed[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	names = append(names, others...)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, stats[name])
+	}
+
+	return "  " + strings.Join(parts, "  ")
+}
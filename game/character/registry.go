@@ -0,0 +1,245 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package character
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/account"
+	"github.com/bbuck/dragon-mud/random"
+	"github.com/spf13/viper"
+)
+
+// Errors about character names, and ErrNotFound for characters that were
+// never saved.
+var (
+	ErrNotFound     = errors.New("no such character")
+	ErrReservedName = errors.New("that name is reserved")
+	ErrBannedName   = errors.New("that name isn't allowed")
+)
+
+// Race is a race players can choose for their characters.
+type Race struct {
+	Name        string
+	Description string
+	// Stats are added to the rolled stats of characters of the race, they
+	// can be negative.
+	Stats map[string]int
+}
+
+// Class is a class players can choose for their characters.
+type Class struct {
+	Name        string
+	Description string
+	// Stats are added to the rolled stats of characters of the class.
+	Stats map[string]int
+	// Races are the names of the races that can be the class, any race can
+	// if it's empty.
+	Races []string
+}
+
+// Allows is true if characters of the race can be the class.
+func (c *Class) Allows(race string) bool {
+	if len(c.Races) == 0 {
+		return true
+	}
+	for _, r := range c.Races {
+		if strings.EqualFold(r, race) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Registry holds what characters can be made of, the races and classes to
+// choose from and the names that can't be used.
+type Registry struct {
+	races    []*Race
+	classes  []*Class
+	reserved map[string]bool
+	banned   []string
+	mutex    *sync.RWMutex
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		reserved: make(map[string]bool),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalRegistry *Registry
+	globalOnce     sync.Once
+)
+
+// Global returns the game's registry, it starts with the names in the
+// character.reserved_names setting reserved and the words in the
+// character.banned_words setting banned.
+func Global() *Registry {
+	globalOnce.Do(func() {
+		globalRegistry = NewRegistry()
+		globalRegistry.Reserve(viper.GetStringSlice("character.reserved_names")...)
+		globalRegistry.Ban(viper.GetStringSlice("character.banned_words")...)
+	})
+
+	return globalRegistry
+}
+
+// AddRace adds the race, replacing one with the same name. Races are offered
+// in the order they're first added.
+func (r *Registry) AddRace(race Race) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.races {
+		if strings.EqualFold(existing.Name, race.Name) {
+			r.races[i] = &race
+
+			return
+		}
+	}
+	r.races = append(r.races, &race)
+}
+
+// AddClass adds the class, replacing one with the same name. Classes are
+// offered in the order they're first added.
+func (r *Registry) AddClass(class Class) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.classes {
+		if strings.EqualFold(existing.Name, class.Name) {
+			r.classes[i] = &class
+
+			return
+		}
+	}
+	r.classes = append(r.classes, &class)
+}
+
+// Races returns every race.
+func (r *Registry) Races() []Race {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	races := make([]Race, len(r.races))
+	for i, race := range r.races {
+		races[i] = *race
+	}
+
+	return races
+}
+
+// Classes returns the classes characters of the race can be, every class if
+// race is empty.
+func (r *Registry) Classes(race string) []Class {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var classes []Class
+	for _, class := range r.classes {
+		if race == "" || class.Allows(race) {
+			classes = append(classes, *class)
+		}
+	}
+
+	return classes
+}
+
+// Race returns the race with the name.
+func (r *Registry) Race(name string) (Race, bool) {
+	for _, race := range r.Races() {
+		if strings.EqualFold(race.Name, name) {
+			return race, true
+		}
+	}
+
+	return Race{}, false
+}
+
+// Class returns the class with the name.
+func (r *Registry) Class(name string) (Class, bool) {
+	for _, class := range r.Classes("") {
+		if strings.EqualFold(class.Name, name) {
+			return class, true
+		}
+	}
+
+	return Class{}, false
+}
+
+// Reserve stops characters from being named any of the names, like the names
+// of important NPCs.
+func (r *Registry) Reserve(names ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, name := range names {
+		r.reserved[strings.ToLower(name)] = true
+	}
+}
+
+// Ban stops characters from having names containing any of the words.
+func (r *Registry) Ban(words ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, word := range words {
+		if word != "" {
+			r.banned = append(r.banned, strings.ToLower(word))
+		}
+	}
+}
+
+// ValidateName checks that a new character can have the name, it must be a
+// valid account name that isn't reserved and doesn't contain a banned word.
+func (r *Registry) ValidateName(name string) error {
+	if err := account.ValidateName(name); err != nil {
+		return err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	lower := strings.ToLower(name)
+	if r.reserved[lower] {
+		return ErrReservedName
+	}
+	for _, word := range r.banned {
+		if strings.Contains(lower, word) {
+			return ErrBannedName
+		}
+	}
+
+	return nil
+}
+
+// RollStats rolls each stat named in the character.stats setting with the
+// dice of the character.roll setting, like "4d6", adding up the highest
+// character.roll_keep of them. Zero keeps every die.
+func RollStats() map[string]int {
+	dice := viper.GetString("character.roll")
+	keep := viper.GetInt("character.roll_keep")
+
+	stats := make(map[string]int)
+	for _, stat := range viper.GetStringSlice("character.stats") {
+		rolls := random.RollDie(dice)
+		sort.Sort(sort.Reverse(sort.IntSlice(rolls)))
+		if keep > 0 && keep < len(rolls) {
+			rolls = rolls[:keep]
+		}
+		total := 0
+		for _, roll := range rolls {
+			total += roll
+		}
+		stats[stat] = total
+	}
+
+	return stats
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package checkpoint snapshots the state of the game, like the players, what
+// lies in the rooms and the mobs roaming them, to named checkpoints the game
+// can be rolled back to. Rolling back undoes the damage of a duping bug or a
+// script gone wrong on a live game. Each part of the game's state registers
+// how it's captured and restored, checkpoints are saved as JSON files.
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/spf13/viper"
+)
+
+// Events emitted for checkpoints, each is given the name of the checkpoint
+// and who made or rolled back to it. Handlers of before:checkpoint:create
+// and before:checkpoint:rollback can stop them by returning events.ErrHalt,
+// or an error whose message is told to the staff member. Rollbacks are also
+// given when the checkpoint was made, as a unix time.
+const (
+	CreateEvent   = "checkpoint:create"
+	RollbackEvent = "checkpoint:rollback"
+)
+
+// Messages told to staff who can't make or roll back to a checkpoint.
+const (
+	CancelMessage  = "You can't do that right now."
+	NameMessage    = "Checkpoints are named with letters, digits, dashes and underscores."
+	ExistsMessage  = "There's already a checkpoint named %q."
+	UnknownMessage = "There's no checkpoint named %q."
+)
+
+// the names checkpoints can have, they name their files
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Part captures and restores a part of the game's state.
+type Part struct {
+	// Capture returns the state as it is now, it must be encodable as JSON.
+	Capture func() (interface{}, error)
+	// Restore puts the state back as a capture had it, given the JSON the
+	// capture was encoded as.
+	Restore func(state json.RawMessage) error
+}
+
+// Checkpoint is the state of the game at a moment.
+type Checkpoint struct {
+	Name    string    `json:"name"`
+	By      string    `json:"by,omitempty"`
+	Created time.Time `json:"created"`
+	// Parts are the state of each part of the game, by name.
+	Parts map[string]json.RawMessage `json:"parts"`
+}
+
+// Manager makes checkpoints of the registered parts and rolls back to them.
+type Manager struct {
+	dir     string
+	parts   map[string]Part
+	emitter *events.Emitter
+	now     func() time.Time
+	// held while capturing or restoring so neither sees the other half done
+	busy  *sync.Mutex
+	mutex *sync.RWMutex
+}
+
+// NewManager creates a manager saving checkpoints to the directory, without
+// any parts. The emitter may be nil.
+func NewManager(dir string, em *events.Emitter) *Manager {
+	return &Manager{
+		dir:     dir,
+		parts:   make(map[string]Part),
+		emitter: em,
+		now:     time.Now,
+		busy:    new(sync.Mutex),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's checkpoints, saved to the directory the
+// checkpoint.dir setting names.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(viper.GetString("checkpoint.dir"), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetClock replaces the function used to get the current time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Register adds the part with the name, replacing any there was.
+func (m *Manager) Register(name string, p Part) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.parts[name] = p
+}
+
+// Unregister removes the part with the name.
+func (m *Manager) Unregister(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.parts, name)
+}
+
+// Names returns the names of the parts, sorted.
+func (m *Manager) Names() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	names := make([]string, 0, len(m.parts))
+	for name := range m.parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Create captures every part to a new checkpoint with the name, made by who
+// is named. Names can't be reused, delete the old checkpoint first.
+func (m *Manager) Create(name, by string) (Checkpoint, error) {
+	if !validName.MatchString(name) {
+		return Checkpoint{}, refuse(NameMessage)
+	}
+	path := m.path(name)
+	if _, err := os.Stat(path); err == nil {
+		return Checkpoint{}, refuse(fmt.Sprintf(ExistsMessage, name))
+	}
+	data := events.Data{"name": name, "by": by}
+	if err := m.check(CreateEvent, data); err != nil {
+		return Checkpoint{}, err
+	}
+
+	m.busy.Lock()
+	m.mutex.RLock()
+	c := Checkpoint{
+		Name:    name,
+		By:      by,
+		Created: m.now().UTC(),
+		Parts:   make(map[string]json.RawMessage, len(m.parts)),
+	}
+	parts := make(map[string]Part, len(m.parts))
+	for n, p := range m.parts {
+		parts[n] = p
+	}
+	m.mutex.RUnlock()
+
+	for n, p := range parts {
+		state, err := p.Capture()
+		if err == nil {
+			c.Parts[n], err = json.Marshal(state)
+		}
+		if err != nil {
+			m.busy.Unlock()
+
+			return Checkpoint{}, fmt.Errorf("capturing %s: %s", n, err)
+		}
+	}
+	m.busy.Unlock()
+
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if err := fs.WriteFile(path, encoded, 0600); err != nil {
+		return Checkpoint{}, err
+	}
+	m.confirm(CreateEvent, data)
+
+	return c, nil
+}
+
+// Load reads the checkpoint with the name.
+func (m *Manager) Load(name string) (Checkpoint, error) {
+	var c Checkpoint
+	if !validName.MatchString(name) {
+		return c, refuse(NameMessage)
+	}
+	encoded, err := ioutil.ReadFile(m.path(name))
+	if os.IsNotExist(err) {
+		return c, refuse(fmt.Sprintf(UnknownMessage, name))
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(encoded, &c)
+
+	return c, err
+}
+
+// List returns every checkpoint without its parts, newest first.
+func (m *Manager) List() ([]Checkpoint, error) {
+	files, err := ioutil.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		c, err := m.Load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		c.Parts = nil
+		checkpoints = append(checkpoints, c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Created.After(checkpoints[j].Created)
+	})
+
+	return checkpoints, nil
+}
+
+// Delete removes the checkpoint with the name.
+func (m *Manager) Delete(name string) error {
+	if !validName.MatchString(name) {
+		return refuse(NameMessage)
+	}
+	err := os.Remove(m.path(name))
+	if os.IsNotExist(err) {
+		return refuse(fmt.Sprintf(UnknownMessage, name))
+	}
+
+	return err
+}
+
+// Rollback puts every part back as the checkpoint with the name has it,
+// rolled back by who is named. Parts the checkpoint doesn't have are left
+// alone. Every part is restored even if some fail, the first error is
+// returned along with the checkpoint.
+func (m *Manager) Rollback(name, by string) (Checkpoint, error) {
+	c, err := m.Load(name)
+	if err != nil {
+		return c, err
+	}
+	data := events.Data{
+		"name":    c.Name,
+		"by":      by,
+		"created": c.Created.Unix(),
+	}
+	if err := m.check(RollbackEvent, data); err != nil {
+		return c, err
+	}
+
+	m.busy.Lock()
+	var firstErr error
+	for _, n := range m.Names() {
+		state, ok := c.Parts[n]
+		if !ok {
+			continue
+		}
+		m.mutex.RLock()
+		p, ok := m.parts[n]
+		m.mutex.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := p.Restore(state); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("restoring %s: %s", n, err)
+		}
+	}
+	m.busy.Unlock()
+	m.confirm(RollbackEvent, data)
+
+	return c, firstErr
+}
+
+// path returns the file of the checkpoint with the name
+func (m *Manager) path(name string) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return filepath.Join(m.dir, name+".json")
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
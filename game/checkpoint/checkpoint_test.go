@@ -0,0 +1,188 @@
+package checkpoint_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/checkpoint"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// admin is a caller making checkpoints who remembers what they're told
+type admin struct {
+	sent []string
+}
+
+func (a *admin) ID() string {
+	return "session-1"
+}
+
+func (a *admin) Level() command.Level {
+	return command.Admin
+}
+
+func (a *admin) Send(text string) error {
+	a.sent = append(a.sent, text)
+
+	return nil
+}
+
+// counter is a part of the game's state for checkpoints
+type counter struct {
+	value int
+	fail  bool
+}
+
+func (c *counter) part() Part {
+	return Part{
+		Capture: func() (interface{}, error) {
+			return c.value, nil
+		},
+		Restore: func(state json.RawMessage) error {
+			if c.fail {
+				return errors.New("out of memory")
+			}
+
+			return json.Unmarshal(state, &c.value)
+		},
+	}
+}
+
+var _ = Describe("Checkpoint", func() {
+	var (
+		m     *Manager
+		dir   string
+		now   time.Time
+		gold  *counter
+		souls *counter
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "dragon-checkpoint")
+		Ω(err).ShouldNot(HaveOccurred())
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		m = NewManager(dir, nil)
+		m.SetClock(func() time.Time { return now })
+		gold = &counter{value: 100}
+		souls = &counter{value: 3}
+		m.Register("gold", gold.part())
+		m.Register("souls", souls.part())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("rolls every part back to the checkpoint", func() {
+		c, err := m.Create("before-raid", "Ann")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c.Created).Should(Equal(now))
+		Ω(m.Names()).Should(Equal([]string{"gold", "souls"}))
+
+		gold.value = 1000000
+		souls.value = 0
+		_, err = m.Rollback("before-raid", "Bob")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(gold.value).Should(Equal(100))
+		Ω(souls.value).Should(Equal(3))
+	})
+
+	It("restores every part it can, returning the first failure", func() {
+		m.Create("safe", "Ann")
+		gold.value, souls.value = 5, 5
+		gold.fail = true
+
+		_, err := m.Rollback("safe", "Ann")
+		Ω(err).Should(MatchError("restoring gold: out of memory"))
+		Ω(gold.value).Should(Equal(5))
+		Ω(souls.value).Should(Equal(3))
+	})
+
+	It("leaves parts the checkpoint doesn't have alone", func() {
+		m.Create("old", "Ann")
+		hp := &counter{value: 20}
+		m.Register("hp", hp.part())
+		hp.value = 1
+
+		_, err := m.Rollback("old", "Ann")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(hp.value).Should(Equal(1))
+	})
+
+	It("refuses bad, taken and unknown names", func() {
+		_, err := m.Create("../etc", "Ann")
+		Ω(err).Should(MatchError(NameMessage))
+		m.Create("one", "Ann")
+		_, err = m.Create("one", "Ann")
+		Ω(err).Should(MatchError(`There's already a checkpoint named "one".`))
+		_, err = m.Rollback("two", "Ann")
+		Ω(err).Should(MatchError(`There's no checkpoint named "two".`))
+		Ω(m.Delete("one")).Should(Succeed())
+		Ω(m.Delete("one")).Should(MatchError(`There's no checkpoint named "one".`))
+	})
+
+	It("lists checkpoints newest first", func() {
+		m.Create("first", "Ann")
+		now = now.Add(time.Hour)
+		m.Create("second", "")
+
+		checkpoints, err := m.List()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(checkpoints).Should(Equal([]Checkpoint{
+			{Name: "second", Created: now},
+			{Name: "first", By: "Ann", Created: now.Add(-time.Hour)},
+		}))
+	})
+
+	It("lets scripts stop rollbacks", func() {
+		em := events.NewEmitter(nil)
+		em.On("before:"+RollbackEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["by"] == "Bob" {
+				return errors.New("Bob can't roll back the game.")
+			}
+
+			return events.ErrHalt
+		}))
+		m.SetEmitter(em)
+		m.Create("safe", "Ann")
+		gold.value = 0
+
+		_, err := m.Rollback("safe", "Bob")
+		Ω(err).Should(MatchError("Bob can't roll back the game."))
+		_, err = m.Rollback("safe", "Ann")
+		Ω(err).Should(MatchError(CancelMessage))
+		Ω(gold.value).Should(Equal(0))
+	})
+
+	It("lets admins make, list and roll back to checkpoints", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(command.Caller) string { return "Ann" }) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+		a := new(admin)
+
+		d.Dispatch(a, "checkpoints")
+		d.Dispatch(a, "checkpoint")
+		d.Dispatch(a, "checkpoint before-raid")
+		gold.value = 0
+		d.Dispatch(a, "rollback before-raid")
+		d.Dispatch(a, "rollback after-raid")
+		Ω(gold.value).Should(Equal(100))
+		Ω(a.sent).Should(Equal([]string{
+			"There are no checkpoints.",
+			"Checkpoint 20170301-120000 is saved.",
+			"Checkpoint before-raid is saved.",
+			"The game is rolled back to before-raid.",
+			"There's no checkpoint named \"after-raid\".",
+		}))
+	})
+})
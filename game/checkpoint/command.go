@@ -0,0 +1,109 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package checkpoint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/perm"
+)
+
+// Namer returns the name of who a command caller is playing, checkpoints are
+// made and rolled back to in their name.
+type Namer func(command.Caller) string
+
+// NewCommands creates the checkpoint command admins make checkpoints with,
+// checkpoints listing them and rollback to roll the game back to one.
+func NewCommands(m *Manager, name Namer) []*command.Command {
+	return []*command.Command{
+		{
+			Name:       "checkpoint",
+			Args:       []command.Arg{{Name: "name", Kind: command.Word, Optional: true}},
+			Level:      command.Admin,
+			Capability: perm.Checkpoint,
+			Help: "Saves the players, the items lying in rooms, the mobs and the doors " +
+				"to a checkpoint with the name given, or one named after the time.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				n := ctx.String("name")
+				if n == "" {
+					m.mutex.RLock()
+					n = m.now().UTC().Format("20060102-150405")
+					m.mutex.RUnlock()
+				}
+				c, err := m.Create(n, name(ctx.Caller))
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(fmt.Sprintf("Checkpoint %s is saved.", c.Name))
+			},
+		},
+		{
+			Name:       "checkpoints",
+			Level:      command.Admin,
+			Capability: perm.Checkpoint,
+			Help:       "Lists the checkpoints, newest first, with who made them and when.",
+			Source:     "game",
+			Handler: func(ctx *command.Context) error {
+				checkpoints, err := m.List()
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(listing(checkpoints))
+			},
+		},
+		{
+			Name:       "rollback",
+			Args:       []command.Arg{{Name: "name", Kind: command.Word}},
+			Level:      command.Admin,
+			Capability: perm.Rollback,
+			Help: "Rolls the game back to the checkpoint with the name given, undoing " +
+				"everything players, items and mobs went through since.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				c, err := m.Rollback(ctx.String("name"), name(ctx.Caller))
+				if _, ok := err.(*item.Refused); ok {
+					return sendRefused(ctx, err)
+				}
+				if err != nil {
+					return ctx.Send(fmt.Sprintf("The game is rolled back to %s, but %s.", c.Name, err))
+				}
+
+				return ctx.Send(fmt.Sprintf("The game is rolled back to %s.", c.Name))
+			},
+		},
+	}
+}
+
+// listing describes each checkpoint, who made it and when
+func listing(checkpoints []Checkpoint) string {
+	if len(checkpoints) == 0 {
+		return "There are no checkpoints."
+	}
+
+	lines := make([]string, 0, len(checkpoints))
+	for _, c := range checkpoints {
+		line := c.Name + ", " + c.Created.Format("2006-01-02 15:04 MST")
+		if c.By != "" {
+			line += ", by " + c.By
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sendRefused tells the admin why they were refused, other errors are
+// returned
+func sendRefused(ctx *command.Context, err error) error {
+	if refused, ok := err.(*item.Refused); ok {
+		return ctx.Send(refused.Message)
+	}
+
+	return err
+}
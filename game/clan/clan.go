@@ -0,0 +1,245 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package clan keeps the organizations players found and join. Clans rank
+// their members, each rank granting permissions, keep a treasury members
+// pay into, talk on the clan channel and own rooms only their members may
+// enter. Scripts add permissions of their own and keep data on clans for
+// features the game doesn't have.
+package clan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The events of clans changing, each is given the id and name of the clan.
+// Handlers of before:clan:create and before:clan:join can stop them by
+// returning events.ErrHalt, or an error whose message is told to the
+// player. Invites, joins and leaves are given the member, leaves also say
+// whether they were kicked, rank changes the member and their rank,
+// deposits and withdrawals the member, amount and treasury and claims the
+// room and whether it was released.
+const (
+	CreateEvent   = "clan:create"
+	InviteEvent   = "clan:invite"
+	JoinEvent     = "clan:join"
+	LeaveEvent    = "clan:leave"
+	RankEvent     = "clan:rank"
+	DepositEvent  = "clan:deposit"
+	WithdrawEvent = "clan:withdraw"
+	ClaimEvent    = "clan:claim"
+	DisbandEvent  = "clan:disband"
+)
+
+// The permissions ranks grant.
+const (
+	// Invite lets members invite players to join.
+	Invite = "invite"
+	// Kick lets members throw out those of lower rank.
+	Kick = "kick"
+	// Promote lets members change the rank of those of lower rank, up to
+	// just below their own.
+	Promote = "promote"
+	// Withdraw lets members take money out of the treasury.
+	Withdraw = "withdraw"
+	// Claim lets members claim and release rooms.
+	Claim = "claim"
+	// All grants every permission.
+	All = "*"
+)
+
+// Permissions are the permissions the game knows of, scripts add others.
+var Permissions = []string{Invite, Kick, Promote, Withdraw, Claim}
+
+// Messages told to members who can't do something.
+const (
+	NotMemberMessage  = "You aren't in a clan."
+	NotAllowedMessage = "Your rank doesn't allow that."
+	PoorMessage       = "You don't have that much money."
+	NoMoneyMessage    = "Clans don't keep money here."
+	CancelMessage     = "You can't do that right now."
+)
+
+// ClaimFlag is the room flag marking rooms clans can claim.
+const ClaimFlag = "clanhall"
+
+// MaxNameLength is the longest a clan's name can be.
+const MaxNameLength = 30
+
+// Member is someone who can be in a clan, like a player.
+type Member interface {
+	Name() string
+	Location() string
+	Send(text string) error
+	Stat(name string) int
+	AddStat(name string, delta int) int
+}
+
+// Rank is a standing in a clan and what it allows.
+type Rank struct {
+	Name        string   `yaml:"name"`
+	Permissions []string `yaml:"permissions,omitempty"`
+}
+
+// Can is true if the rank grants the permission.
+func (r Rank) Can(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == All || strings.EqualFold(p, permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultRanks are the ranks clans are founded with, highest first.
+var DefaultRanks = []Rank{
+	{Name: "leader", Permissions: []string{All}},
+	{Name: "officer", Permissions: []string{Invite, Kick, Promote}},
+	{Name: "member"},
+}
+
+// Clan is an organization of players.
+type Clan struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// Ranks are from the highest to the lowest. Those of the highest rank
+	// lead the clan and can always do everything.
+	Ranks []Rank `yaml:"ranks"`
+	// Members holds the rank of each member, by name.
+	Members  map[string]string `yaml:"members"`
+	Treasury int               `yaml:"treasury,omitempty"`
+	Rooms    []string          `yaml:"rooms,omitempty"`
+	// Data is kept for scripts, by key.
+	Data map[string]string `yaml:"data,omitempty"`
+}
+
+// Has is true if the player with the name is a member.
+func (c Clan) Has(name string) bool {
+	_, ok := c.member(name)
+
+	return ok
+}
+
+// RankOf returns the rank of the member with the name.
+func (c Clan) RankOf(name string) (Rank, bool) {
+	i := c.standing(name)
+	if i < 0 {
+		return Rank{}, false
+	}
+
+	return c.Ranks[i], true
+}
+
+// Can is true if the member with the name has a rank that grants the
+// permission.
+func (c Clan) Can(name, permission string) bool {
+	i := c.standing(name)
+
+	return i == 0 || (i > 0 && c.Ranks[i].Can(permission))
+}
+
+// Leaders returns the names of those of the highest rank.
+func (c Clan) Leaders() []string {
+	var leaders []string
+	for name := range c.Members {
+		if c.standing(name) == 0 {
+			leaders = append(leaders, name)
+		}
+	}
+
+	return leaders
+}
+
+// Owns is true if the clan owns the room.
+func (c Clan) Owns(room string) bool {
+	for _, r := range c.Rooms {
+		if r == room {
+			return true
+		}
+	}
+
+	return false
+}
+
+// member returns the name the member is kept under, in any case
+func (c Clan) member(name string) (string, bool) {
+	for m := range c.Members {
+		if strings.EqualFold(m, name) {
+			return m, true
+		}
+	}
+
+	return "", false
+}
+
+// standing returns the position of the member's rank, 0 being the highest,
+// -1 if they aren't a member
+func (c Clan) standing(name string) int {
+	m, ok := c.member(name)
+	if !ok {
+		return -1
+	}
+
+	return c.rank(c.Members[m])
+}
+
+// rank returns the position of the rank with the name, -1 if there isn't
+// one
+func (c Clan) rank(name string) int {
+	for i, r := range c.Ranks {
+		if strings.EqualFold(r.Name, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// copy returns the clan with its own ranks, members, rooms and data
+func (c *Clan) copy() Clan {
+	d := *c
+	d.Ranks = make([]Rank, len(c.Ranks))
+	for i, r := range c.Ranks {
+		d.Ranks[i] = Rank{Name: r.Name, Permissions: append([]string(nil), r.Permissions...)}
+	}
+	d.Members = make(map[string]string, len(c.Members))
+	for name, rank := range c.Members {
+		d.Members[name] = rank
+	}
+	d.Rooms = append([]string(nil), c.Rooms...)
+	d.Data = make(map[string]string, len(c.Data))
+	for k, v := range c.Data {
+		d.Data[k] = v
+	}
+
+	return d
+}
+
+// validate checks the clan loaded from a file makes sense
+func (c Clan) validate() error {
+	if c.ID == "" || c.Name == "" {
+		return fmt.Errorf("clans need an id and a name")
+	}
+	if len(c.Ranks) == 0 {
+		return fmt.Errorf("clan %q has no ranks", c.ID)
+	}
+	for name, rank := range c.Members {
+		if c.rank(rank) < 0 {
+			return fmt.Errorf("%s has the rank %q in clan %q, which it doesn't have", name, rank, c.ID)
+		}
+	}
+
+	return nil
+}
+
+// File is the layout of the clan file.
+type File struct {
+	Clans []Clan `yaml:"clans"`
+}
+
+// idFor returns the id a clan with the name is known by, its name in lower
+// case with dashes for spaces
+func idFor(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
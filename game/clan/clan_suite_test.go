@@ -0,0 +1,13 @@
+package clan_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestClan(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Clan Suite")
+}
@@ -0,0 +1,252 @@
+package clan_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// member is a player who remembers their stats and what they're told
+type member struct {
+	name     string
+	location string
+	stats    map[string]int
+	sent     []string
+}
+
+func newMember(name string) *member {
+	return &member{name: name, location: "hall", stats: map[string]int{"coins": 100}}
+}
+
+func (m *member) ID() string {
+	return strings.ToLower(m.name)
+}
+
+func (m *member) Name() string {
+	return m.name
+}
+
+func (m *member) Location() string {
+	return m.location
+}
+
+func (m *member) Level() command.Level {
+	return command.Player
+}
+
+func (m *member) Stat(name string) int {
+	return m.stats[name]
+}
+
+func (m *member) AddStat(name string, delta int) int {
+	m.stats[name] += delta
+
+	return m.stats[name]
+}
+
+func (m *member) Send(text string) error {
+	m.sent = append(m.sent, text)
+
+	return nil
+}
+
+func (m *member) told(text string) bool {
+	return strings.Contains(strings.Join(m.sent, "\n"), text)
+}
+
+var _ = Describe("Clan", func() {
+	var (
+		em              *events.Emitter
+		m               *Manager
+		ann, bob, carla *member
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "hall", Zone: "town", Flags: map[string]bool{ClaimFlag: true}})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "square", Zone: "town"})).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(w, em)
+		m.SetMoney("coins", shop.DefaultCurrency)
+		ann, bob, carla = newMember("Ann"), newMember("Bob"), newMember("Carla")
+		m.SetLookup(func(name string) Member {
+			for _, mem := range []*member{ann, bob, carla} {
+				if strings.EqualFold(mem.name, name) {
+					return mem
+				}
+			}
+
+			return nil
+		})
+	})
+
+	found := func(leader *member, members ...*member) {
+		_, err := m.Create(leader, "Silver Wolves")
+		Ω(err).ShouldNot(HaveOccurred())
+		for _, mem := range members {
+			Ω(m.Invite(leader, mem)).Should(Succeed())
+			Ω(m.Join(mem)).Should(Succeed())
+		}
+	}
+
+	It("founds clans and lets those invited join at the lowest rank", func() {
+		found(ann)
+		_, err := m.Create(bob, "silver  wolves")
+		Ω(err).Should(MatchError("There's already a clan called silver wolves."))
+		Ω(m.Join(bob)).Should(MatchError("Nobody has invited you to a clan."))
+		Ω(m.Invite(ann, bob)).Should(Succeed())
+		Ω(bob.told("Ann invites you to join Silver Wolves")).Should(BeTrue())
+		Ω(m.Join(bob)).Should(Succeed())
+
+		c, ok := m.Of("BOB")
+		Ω(ok).Should(BeTrue())
+		Ω(c.ID).Should(Equal("silver-wolves"))
+		Ω(c.Members).Should(Equal(map[string]string{"Ann": "leader", "Bob": "member"}))
+		Ω(ann.told("Bob joins the clan.")).Should(BeTrue())
+		Ω(m.Invite(bob, carla)).Should(MatchError(NotAllowedMessage))
+		Ω(m.Clans()).Should(HaveLen(1))
+	})
+
+	It("lets handlers stop players joining", func() {
+		em.On("before:"+JoinEvent, events.HandlerFunc(func(d events.Data) error {
+			return errors.New("The wolves don't want you.")
+		}))
+		found(ann)
+		Ω(m.Invite(ann, bob)).Should(Succeed())
+
+		Ω(m.Join(bob)).Should(MatchError("The wolves don't want you."))
+		Ω(m.Can("Bob", Invite)).Should(BeFalse())
+	})
+
+	It("ranks members and keeps them to their rank's permissions", func() {
+		found(ann, bob, carla)
+
+		Ω(m.Promote(bob, "Carla", "officer")).Should(MatchError(NotAllowedMessage))
+		Ω(m.Promote(ann, "Bob", "officer")).Should(Succeed())
+		Ω(carla.told("Bob is now an officer.")).Should(BeTrue())
+		Ω(m.Can("bob", Invite)).Should(BeTrue())
+		Ω(m.Can("bob", Withdraw)).Should(BeFalse())
+		Ω(m.Promote(bob, "Carla", "officer")).Should(MatchError("You can only give ranks below your own."))
+		Ω(m.Kick(bob, "Ann")).Should(MatchError("You don't outrank Ann."))
+
+		Ω(m.SetRank(bob, "banker", nil)).Should(MatchError("Only the clan's leaders can change its ranks."))
+		Ω(m.SetRank(ann, "banker", []string{"teleport"})).Should(MatchError("There's no permission \"teleport\"."))
+		Ω(m.AddPermission("teleport")).Should(Succeed())
+		Ω(m.SetRank(ann, "banker", []string{Withdraw, "teleport"})).Should(Succeed())
+		Ω(m.Promote(ann, "Carla", "banker")).Should(Succeed())
+		Ω(m.Can("Carla", "teleport")).Should(BeTrue())
+		c, _ := m.Of("Ann")
+		Ω(c.Ranks[2]).Should(Equal(Rank{Name: "banker", Permissions: []string{Withdraw, "teleport"}}))
+
+		Ω(m.Kick(bob, "Carla")).Should(Succeed())
+		Ω(carla.told("Bob throws you out of Silver Wolves.")).Should(BeTrue())
+		_, ok := m.Of("Carla")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("keeps the last leader until the clan is handed over or disbanded", func() {
+		found(ann, bob)
+
+		Ω(m.Leave("Ann")).Should(MatchError("Make someone else a leader or disband the clan first."))
+		Ω(m.Leave("Bob")).Should(Succeed())
+		Ω(m.Leave("Ann")).Should(Succeed())
+		Ω(m.Clans()).Should(BeEmpty())
+	})
+
+	It("keeps a treasury", func() {
+		found(ann, bob)
+
+		Ω(m.Deposit(bob, 500)).Should(MatchError(PoorMessage))
+		Ω(m.Deposit(bob, 60)).Should(Succeed())
+		Ω(bob.stats["coins"]).Should(Equal(40))
+		Ω(m.Withdraw(bob, 10)).Should(MatchError(NotAllowedMessage))
+		Ω(m.Withdraw(ann, 70)).Should(MatchError("The treasury doesn't have that much money."))
+		Ω(m.Withdraw(ann, 20)).Should(Succeed())
+		Ω(ann.stats["coins"]).Should(Equal(120))
+
+		Ω(m.Disband(bob)).Should(MatchError("Only the clan's leaders can disband it."))
+		Ω(m.Disband(ann)).Should(Succeed())
+		Ω(ann.stats["coins"]).Should(Equal(160))
+		Ω(bob.told("Ann disbands Silver Wolves.")).Should(BeTrue())
+		_, ok := m.Of("Bob")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("claims rooms flagged for clans, keeping others out", func() {
+		found(ann)
+		ann.location = "square"
+		Ω(m.Claim(ann)).Should(MatchError("Clans can't claim this room."))
+		ann.location = "hall"
+		Ω(m.Claim(ann)).Should(Succeed())
+
+		c, ok := m.Owner("hall")
+		Ω(ok).Should(BeTrue())
+		Ω(c.Name).Should(Equal("Silver Wolves"))
+		Ω(m.Allowed("ann", "hall")).Should(BeTrue())
+		Ω(m.Allowed("Bob", "hall")).Should(BeFalse())
+		Ω(m.Allowed("Bob", "square")).Should(BeTrue())
+
+		Ω(m.Release(ann)).Should(Succeed())
+		Ω(m.Allowed("Bob", "hall")).Should(BeTrue())
+	})
+
+	It("saves clans and the data scripts keep on them", func() {
+		dir, err := ioutil.TempDir("", "clan")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "clans.yml")
+		Ω(m.SetFile(path)).Should(Succeed())
+		found(ann, bob)
+		m.Deposit(bob, 25)
+		Ω(m.SetData("Silver Wolves", "motto", "Run together")).Should(Succeed())
+
+		loaded := NewManager(world.New(), nil)
+		Ω(loaded.SetFile(path)).Should(Succeed())
+		c, ok := loaded.Of("bob")
+		Ω(ok).Should(BeTrue())
+		Ω(c.Treasury).Should(Equal(25))
+		Ω(c.Data).Should(Equal(map[string]string{"motto": "Run together"}))
+		Ω(loaded.Clans()).Should(Equal(m.Clans()))
+	})
+
+	It("runs the clan command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Member {
+			return c.(*member)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "clan")
+		d.Dispatch(ann, "clan create Silver Wolves")
+		d.Dispatch(ann, "clan invite bob")
+		d.Dispatch(bob, "clan join")
+		d.Dispatch(bob, "clan deposit 2 silver")
+		d.Dispatch(ann, "clan")
+		d.Dispatch(ann, "clan ranks")
+		d.Dispatch(ann, "clan promote bob")
+		Ω(ann.sent).Should(Equal([]string{
+			NotMemberMessage,
+			"You found Silver Wolves.",
+			"You invite Bob to join the clan.",
+			"Bob joins the clan.",
+			"Silver Wolves, where you're a leader.\nTreasury: 2 silver\n  Ann (leader)\n  Bob (member)",
+			"leader: everything\nofficer: invite, kick, promote\nmember: nothing",
+			"Usage: clan promote <player> <rank>",
+		}))
+		Ω(bob.sent).Should(ContainElement("You put 2 silver in the treasury."))
+	})
+})
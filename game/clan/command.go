@@ -0,0 +1,231 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package clan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the clan command, which shows the caller's clan and
+// lists, founds, joins, leaves and runs clans. Members talk on the clan
+// channel, see the channels package.
+func NewCommands(m *Manager, resolve command.Resolver[Member]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "clan",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "rest", Kind: command.Text, Optional: true},
+			},
+			Help: "Shows your clan. \"clan list\" lists the clans, \"clan create <name>\" " +
+				"founds one, \"clan join\" accepts an invite and \"clan leave\" leaves. " +
+				"Ranks permitting, \"clan invite <player>\", \"clan kick <player>\", " +
+				"\"clan promote <player> <rank>\", \"clan deposit <amount>\", " +
+				"\"clan withdraw <amount>\", \"clan claim\" and \"clan release\" the room " +
+				"you're in. Leaders see \"clan ranks\", change them with " +
+				"\"clan rank <rank> [permissions]\" and \"clan disband\".",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, mem Member) error {
+				rest := ctx.String("rest")
+				needs := func(fn func() error) error {
+					if rest == "" {
+						return ctx.Send("Usage: " + ctx.Command.Usage())
+					}
+
+					return fn()
+				}
+
+				switch strings.ToLower(ctx.String("action")) {
+				case "":
+					return ctx.Send(info(m, mem))
+				case "list":
+					return ctx.Send(list(m.Clans()))
+				case "create", "found":
+					return needs(func() error {
+						c, err := m.Create(mem, rest)
+						if err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("You found %s.", c.Name))
+					})
+				case "invite":
+					return needs(func() error {
+						invitee := m.find(rest)
+						if invitee == nil {
+							return ctx.Send(fmt.Sprintf("There's nobody called %q in the game.", rest))
+						}
+						if err := m.Invite(mem, invitee); err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("You invite %s to join the clan.", invitee.Name()))
+					})
+				case "join", "accept":
+					return m.Join(mem)
+				case "leave", "quit":
+					return m.Leave(mem.Name())
+				case "kick":
+					return needs(func() error {
+						return m.Kick(mem, rest)
+					})
+				case "promote", "demote":
+					fields := strings.Fields(rest)
+					if len(fields) != 2 {
+						return ctx.Send("Usage: clan promote <player> <rank>")
+					}
+
+					return m.Promote(mem, fields[0], fields[1])
+				case "ranks":
+					c, ok := m.Of(mem.Name())
+					if !ok {
+						return ctx.Send(NotMemberMessage)
+					}
+
+					return ctx.Send(ranks(c))
+				case "rank":
+					return needs(func() error {
+						fields := strings.Fields(rest)
+						if err := m.SetRank(mem, fields[0], fields[1:]); err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("The %s rank is set.", strings.ToLower(fields[0])))
+					})
+				case "deposit", "withdraw":
+					return needs(func() error {
+						amount, err := m.Currency().Parse(rest)
+						if err != nil {
+							return ctx.Send(err.Error())
+						}
+						if strings.ToLower(ctx.String("action")) == "deposit" {
+							if err := m.Deposit(mem, amount); err != nil {
+								return err
+							}
+
+							return ctx.Send(fmt.Sprintf("You put %s in the treasury.", m.Currency().Format(amount)))
+						}
+						if err := m.Withdraw(mem, amount); err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("You take %s from the treasury.", m.Currency().Format(amount)))
+					})
+				case "claim":
+					if err := m.Claim(mem); err != nil {
+						return err
+					}
+
+					return ctx.Send("This room now belongs to your clan.")
+				case "release":
+					if err := m.Release(mem); err != nil {
+						return err
+					}
+
+					return ctx.Send("Your clan gives up this room.")
+				case "disband":
+					return m.Disband(mem)
+				}
+
+				return ctx.Send("Usage: " + ctx.Command.Usage())
+			}),
+		},
+	}
+}
+
+// info describes the member's clan, its members and their ranks
+func info(m *Manager, mem Member) string {
+	c, ok := m.Of(mem.Name())
+	if !ok {
+		return NotMemberMessage
+	}
+
+	rank, _ := c.RankOf(mem.Name())
+	lines := []string{
+		fmt.Sprintf("%s, where you're %s %s.", c.Name, article(rank.Name), rank.Name),
+		"Treasury: " + m.Currency().Format(c.Treasury),
+	}
+	if len(c.Rooms) > 0 {
+		lines = append(lines, "Rooms: "+strings.Join(c.Rooms, ", "))
+	}
+	names := make([]string, 0, len(c.Members))
+	for name := range c.Members {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.standing(names[i]), c.standing(names[j])
+		if a != b {
+			return a < b
+		}
+
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		line := fmt.Sprintf("  %s (%s)", name, c.Members[name])
+		if m.find(name) == nil {
+			line += ", not in the game"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// list names each clan and how many members it has
+func list(clans []Clan) string {
+	if len(clans) == 0 {
+		return "There are no clans."
+	}
+
+	lines := make([]string, 0, len(clans))
+	for _, c := range clans {
+		n := len(c.Members)
+		noun := "members"
+		if n == 1 {
+			noun = "member"
+		}
+		lines = append(lines, fmt.Sprintf("%s, %d %s", c.Name, n, noun))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ranks lists the clan's ranks and what they allow, highest first
+func ranks(c Clan) string {
+	lines := make([]string, 0, len(c.Ranks))
+	for i, r := range c.Ranks {
+		allows := strings.Join(r.Permissions, ", ")
+		switch {
+		case i == 0:
+			allows = "everything"
+		case allows == "":
+			allows = "nothing"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", r.Name, allows))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's member for fn, telling them why they were
+// refused
+func handler(resolve command.Resolver[Member], fn func(*command.Context, Member) error) command.Handler {
+	return func(ctx *command.Context) error {
+		mem := resolve(ctx.Caller)
+		if mem == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		err := fn(ctx, mem)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
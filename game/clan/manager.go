@@ -0,0 +1,908 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package clan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/world"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Manager keeps the clans, who's in them and who's been invited.
+type Manager struct {
+	world  *world.World
+	lookup func(name string) Member
+	clans  map[string]*Clan
+	// of holds the id of each member's clan, by lower case name
+	of map[string]string
+	// invites holds the id of the clan each player was invited to, by lower
+	// case name
+	invites     map[string]string
+	permissions map[string]bool
+	stat        string
+	currency    shop.Currency
+	file        string
+	emitter     *events.Emitter
+	mutex       *sync.RWMutex
+}
+
+// NewManager creates a manager with no clans whose rooms are in the world.
+// The emitter may be nil.
+func NewManager(w *world.World, em *events.Emitter) *Manager {
+	m := &Manager{
+		world:       w,
+		clans:       make(map[string]*Clan),
+		of:          make(map[string]string),
+		invites:     make(map[string]string),
+		permissions: make(map[string]bool),
+		currency:    shop.DefaultCurrency,
+		emitter:     em,
+		mutex:       new(sync.RWMutex),
+	}
+	for _, p := range Permissions {
+		m.permissions[p] = true
+	}
+
+	return m
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's clans.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetLookup sets how members in the game are found by name. Without it
+// nobody is told what happens to their clan.
+func (m *Manager) SetLookup(fn func(name string) Member) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lookup = fn
+}
+
+// SetMoney sets the stat money is paid into treasuries from and the
+// currency it's counted in. Without a stat clans keep no money.
+func (m *Manager) SetMoney(stat string, c shop.Currency) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stat, m.currency = stat, c
+}
+
+// Currency returns the currency money is counted in.
+func (m *Manager) Currency() shop.Currency {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.currency
+}
+
+// SetFile sets where clans are saved, loading those saved there. Missing
+// files are ignored.
+func (m *Manager) SetFile(path string) error {
+	m.mutex.Lock()
+	m.file = path
+	m.mutex.Unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, c := range f.Clans {
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := range f.Clans {
+		c := f.Clans[i].copy()
+		m.clans[c.ID] = &c
+		for name := range c.Members {
+			m.of[key(name)] = c.ID
+		}
+	}
+
+	return nil
+}
+
+// AddPermission lets ranks grant the permission, so scripts can keep clan
+// features of their own to some ranks.
+func (m *Manager) AddPermission(permission string) error {
+	permission = strings.ToLower(strings.TrimSpace(permission))
+	if permission == "" || permission == All || strings.ContainsAny(permission, " \t") {
+		return refuse(fmt.Sprintf("%q can't be a permission.", permission))
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.permissions[permission] = true
+
+	return nil
+}
+
+// Permissions returns the permissions ranks can grant, sorted.
+func (m *Manager) Permissions() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	permissions := make([]string, 0, len(m.permissions))
+	for p := range m.permissions {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+
+	return permissions
+}
+
+// Clan returns the clan with the id or name.
+func (m *Manager) Clan(name string) (Clan, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	c, ok := m.clans[idFor(name)]
+	if !ok {
+		return Clan{}, false
+	}
+
+	return c.copy(), true
+}
+
+// Clans returns every clan, by name.
+func (m *Manager) Clans() []Clan {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	clans := make([]Clan, 0, len(m.clans))
+	for _, c := range m.clans {
+		clans = append(clans, c.copy())
+	}
+	sort.Slice(clans, func(i, j int) bool {
+		return clans[i].Name < clans[j].Name
+	})
+
+	return clans
+}
+
+// Of returns the clan the player with the name is in.
+func (m *Manager) Of(name string) (Clan, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	c, ok := m.clan(name)
+	if !ok {
+		return Clan{}, false
+	}
+
+	return c.copy(), true
+}
+
+// Can is true if the player with the name is in a clan and their rank
+// grants the permission.
+func (m *Manager) Can(name, permission string) bool {
+	c, ok := m.Of(name)
+
+	return ok && c.Can(name, permission)
+}
+
+// Owner returns the clan owning the room.
+func (m *Manager) Owner(room string) (Clan, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, c := range m.clans {
+		if c.Owns(room) {
+			return c.copy(), true
+		}
+	}
+
+	return Clan{}, false
+}
+
+// Allowed is true if the player with the name may enter the room, which is
+// any room no clan owns and those their clan does.
+func (m *Manager) Allowed(name, room string) bool {
+	c, ok := m.Owner(room)
+
+	return !ok || c.Has(name)
+}
+
+// Create founds a clan with the name, led by the founder.
+func (m *Manager) Create(founder Member, name string) (Clan, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case name == "":
+		return Clan{}, refuse("What should the clan be called?")
+	case len(name) > MaxNameLength:
+		return Clan{}, refuse(fmt.Sprintf("Clan names can't be longer than %d letters.", MaxNameLength))
+	}
+	data := events.Data{
+		"clan":   idFor(name),
+		"name":   name,
+		"member": founder.Name(),
+	}
+	if err := m.check(CreateEvent, data); err != nil {
+		return Clan{}, err
+	}
+
+	m.mutex.Lock()
+	if _, ok := m.of[key(founder.Name())]; ok {
+		m.mutex.Unlock()
+
+		return Clan{}, refuse("You're already in a clan.")
+	}
+	if _, ok := m.clans[idFor(name)]; ok {
+		m.mutex.Unlock()
+
+		return Clan{}, refuse(fmt.Sprintf("There's already a clan called %s.", name))
+	}
+	c := (&Clan{ID: idFor(name), Name: name, Ranks: DefaultRanks}).copy()
+	c.Members[founder.Name()] = c.Ranks[0].Name
+	m.clans[c.ID] = &c
+	m.of[key(founder.Name())] = c.ID
+	created := c.copy()
+	m.mutex.Unlock()
+
+	m.confirm(CreateEvent, data)
+
+	return created, m.save()
+}
+
+// Invite asks the invitee to join the clan of the member, whose rank must
+// let them invite.
+func (m *Manager) Invite(by, invitee Member) error {
+	m.mutex.Lock()
+	c, err := m.allowed(by.Name(), Invite)
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	if _, ok := m.of[key(invitee.Name())]; ok {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s is already in a clan.", invitee.Name()))
+	}
+	m.invites[key(invitee.Name())] = c.ID
+	id, name := c.ID, c.Name
+	m.mutex.Unlock()
+
+	invitee.Send(fmt.Sprintf("%s invites you to join %s, type \"clan join\" to accept.", by.Name(), name))
+	m.emit(InviteEvent, events.Data{
+		"clan":   id,
+		"name":   name,
+		"by":     by.Name(),
+		"member": invitee.Name(),
+	})
+
+	return nil
+}
+
+// Join accepts the member's invite, giving them the clan's lowest rank.
+func (m *Manager) Join(member Member) error {
+	m.mutex.RLock()
+	id, invited := m.invites[key(member.Name())]
+	c, exists := m.clans[id]
+	var name string
+	if exists {
+		name = c.Name
+	}
+	m.mutex.RUnlock()
+
+	if !invited {
+		return refuse("Nobody has invited you to a clan.")
+	}
+	if !exists {
+		m.mutex.Lock()
+		delete(m.invites, key(member.Name()))
+		m.mutex.Unlock()
+
+		return refuse("That clan doesn't exist anymore.")
+	}
+	data := events.Data{
+		"clan":   id,
+		"name":   name,
+		"member": member.Name(),
+	}
+	if err := m.check(JoinEvent, data); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.invites, key(member.Name()))
+	if _, ok := m.of[key(member.Name())]; ok {
+		m.mutex.Unlock()
+
+		return refuse("You're already in a clan.")
+	}
+	c, exists = m.clans[id]
+	if !exists {
+		m.mutex.Unlock()
+
+		return refuse("That clan doesn't exist anymore.")
+	}
+	c.Members[member.Name()] = c.Ranks[len(c.Ranks)-1].Name
+	m.of[key(member.Name())] = id
+	joined := c.copy()
+	m.mutex.Unlock()
+
+	member.Send(fmt.Sprintf("You join %s.", joined.Name))
+	m.tell(joined, fmt.Sprintf("%s joins the clan.", member.Name()), member.Name())
+	m.confirm(JoinEvent, data)
+
+	return m.save()
+}
+
+// Leave takes the player with the name out of their clan. The last leader
+// can't leave while there are others, they hand the clan over or disband
+// it. Clans left empty are disbanded.
+func (m *Manager) Leave(name string) error {
+	m.mutex.Lock()
+	c, ok := m.clan(name)
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(NotMemberMessage)
+	}
+	if c.standing(name) == 0 && len(c.Leaders()) == 1 && len(c.Members) > 1 {
+		m.mutex.Unlock()
+
+		return refuse("Make someone else a leader or disband the clan first.")
+	}
+	m.mutex.Unlock()
+
+	return m.remove(name, "")
+}
+
+// Kick has the member throw the player with the name, who must be of lower
+// rank, out of the clan.
+func (m *Manager) Kick(by Member, name string) error {
+	m.mutex.RLock()
+	c, err := m.allowed(by.Name(), Kick)
+	if err == nil {
+		err = outranks(*c, by.Name(), name)
+	}
+	m.mutex.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return m.remove(name, by.Name())
+}
+
+// Promote has the member give the player with the name, who must be of
+// lower rank, the rank. Leaders give any rank, others those below their
+// own.
+func (m *Manager) Promote(by Member, name, rank string) error {
+	m.mutex.Lock()
+	c, err := m.allowed(by.Name(), Promote)
+	if err == nil {
+		err = outranks(*c, by.Name(), name)
+	}
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	i := c.rank(rank)
+	if i < 0 {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s has no rank %q.", c.Name, rank))
+	}
+	if mine := c.standing(by.Name()); mine != 0 && i <= mine {
+		m.mutex.Unlock()
+
+		return refuse("You can only give ranks below your own.")
+	}
+	member, _ := c.member(name)
+	c.Members[member] = c.Ranks[i].Name
+	changed := c.copy()
+	m.mutex.Unlock()
+
+	m.tell(changed, fmt.Sprintf("%s is now %s %s.", member, article(changed.Ranks[i].Name), changed.Ranks[i].Name), "")
+	m.emit(RankEvent, events.Data{
+		"clan":   changed.ID,
+		"name":   changed.Name,
+		"member": member,
+		"rank":   changed.Ranks[i].Name,
+	})
+
+	return m.save()
+}
+
+// SetRank has a leader of the clan give the rank the permissions, adding it
+// just above the lowest rank if the clan doesn't have it. The highest rank
+// always has every permission.
+func (m *Manager) SetRank(by Member, rank string, permissions []string) error {
+	rank = strings.ToLower(strings.TrimSpace(rank))
+	if rank == "" || strings.ContainsAny(rank, " \t") {
+		return refuse("Ranks are named with a single word.")
+	}
+
+	m.mutex.Lock()
+	c, ok := m.clan(by.Name())
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(NotMemberMessage)
+	}
+	if c.standing(by.Name()) != 0 {
+		m.mutex.Unlock()
+
+		return refuse("Only the clan's leaders can change its ranks.")
+	}
+	var granted []string
+	for _, p := range permissions {
+		p = strings.ToLower(p)
+		if !m.permissions[p] {
+			m.mutex.Unlock()
+
+			return refuse(fmt.Sprintf("There's no permission %q.", p))
+		}
+		granted = append(granted, p)
+	}
+	switch i := c.rank(rank); {
+	case i == 0:
+		m.mutex.Unlock()
+
+		return refuse("The highest rank can always do everything.")
+	case i > 0:
+		c.Ranks[i].Permissions = granted
+	default:
+		last := len(c.Ranks) - 1
+		c.Ranks = append(c.Ranks[:last:last], Rank{Name: rank, Permissions: granted}, c.Ranks[last])
+	}
+	m.mutex.Unlock()
+
+	return m.save()
+}
+
+// Deposit moves the amount of the member's money into their clan's
+// treasury.
+func (m *Manager) Deposit(member Member, amount int) error {
+	m.mutex.Lock()
+	c, ok := m.clan(member.Name())
+	switch {
+	case !ok:
+		m.mutex.Unlock()
+
+		return refuse(NotMemberMessage)
+	case m.stat == "":
+		m.mutex.Unlock()
+
+		return refuse(NoMoneyMessage)
+	case amount <= 0 || member.Stat(m.stat) < amount:
+		m.mutex.Unlock()
+
+		return refuse(PoorMessage)
+	}
+	member.AddStat(m.stat, -amount)
+	c.Treasury += amount
+	data := events.Data{
+		"clan":     c.ID,
+		"name":     c.Name,
+		"member":   member.Name(),
+		"amount":   amount,
+		"treasury": c.Treasury,
+	}
+	m.mutex.Unlock()
+
+	m.emit(DepositEvent, data)
+
+	return m.save()
+}
+
+// Withdraw moves the amount from the treasury of the member's clan to them,
+// their rank must let them withdraw.
+func (m *Manager) Withdraw(member Member, amount int) error {
+	m.mutex.Lock()
+	c, err := m.allowed(member.Name(), Withdraw)
+	switch {
+	case err != nil:
+		m.mutex.Unlock()
+
+		return err
+	case m.stat == "":
+		m.mutex.Unlock()
+
+		return refuse(NoMoneyMessage)
+	case amount <= 0 || c.Treasury < amount:
+		m.mutex.Unlock()
+
+		return refuse("The treasury doesn't have that much money.")
+	}
+	c.Treasury -= amount
+	member.AddStat(m.stat, amount)
+	data := events.Data{
+		"clan":     c.ID,
+		"name":     c.Name,
+		"member":   member.Name(),
+		"amount":   amount,
+		"treasury": c.Treasury,
+	}
+	m.mutex.Unlock()
+
+	m.emit(WithdrawEvent, data)
+
+	return m.save()
+}
+
+// Claim gives the room the member is in, which must be flagged for clans,
+// to their clan. Their rank must let them claim.
+func (m *Manager) Claim(member Member) error {
+	room, ok := m.world.Room(member.Location())
+	if !ok || !room.Flag(ClaimFlag) {
+		return refuse("Clans can't claim this room.")
+	}
+
+	m.mutex.Lock()
+	c, err := m.allowed(member.Name(), Claim)
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	for _, other := range m.clans {
+		if other.Owns(room.ID) {
+			m.mutex.Unlock()
+
+			return refuse(fmt.Sprintf("This room belongs to %s.", other.Name))
+		}
+	}
+	c.Rooms = append(c.Rooms, room.ID)
+	data := events.Data{
+		"clan":     c.ID,
+		"name":     c.Name,
+		"member":   member.Name(),
+		"room":     room.ID,
+		"released": false,
+	}
+	m.mutex.Unlock()
+
+	m.emit(ClaimEvent, data)
+
+	return m.save()
+}
+
+// Release gives up the clan's claim to the room the member is in. Their
+// rank must let them claim.
+func (m *Manager) Release(member Member) error {
+	room := member.Location()
+
+	m.mutex.Lock()
+	c, err := m.allowed(member.Name(), Claim)
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	if !c.Owns(room) {
+		m.mutex.Unlock()
+
+		return refuse("Your clan doesn't own this room.")
+	}
+	var rooms []string
+	for _, r := range c.Rooms {
+		if r != room {
+			rooms = append(rooms, r)
+		}
+	}
+	c.Rooms = rooms
+	data := events.Data{
+		"clan":     c.ID,
+		"name":     c.Name,
+		"member":   member.Name(),
+		"room":     room,
+		"released": true,
+	}
+	m.mutex.Unlock()
+
+	m.emit(ClaimEvent, data)
+
+	return m.save()
+}
+
+// Disband has a leader break up their clan, they're given what was in its
+// treasury.
+func (m *Manager) Disband(by Member) error {
+	m.mutex.Lock()
+	c, ok := m.clan(by.Name())
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(NotMemberMessage)
+	}
+	if c.standing(by.Name()) != 0 {
+		m.mutex.Unlock()
+
+		return refuse("Only the clan's leaders can disband it.")
+	}
+	if c.Treasury > 0 && m.stat != "" {
+		by.AddStat(m.stat, c.Treasury)
+	}
+	disbanded := m.disband(c)
+	m.mutex.Unlock()
+
+	m.tell(disbanded, fmt.Sprintf("%s disbands %s.", by.Name(), disbanded.Name), "")
+	m.emit(DisbandEvent, events.Data{
+		"clan":   disbanded.ID,
+		"name":   disbanded.Name,
+		"member": by.Name(),
+	})
+
+	return m.save()
+}
+
+// SetData keeps the value under the key on the clan with the id, for
+// scripts. Empty values remove the key.
+func (m *Manager) SetData(id, k, value string) error {
+	m.mutex.Lock()
+	c, ok := m.clans[idFor(id)]
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("There's no clan %q.", id))
+	}
+	if c.Data == nil {
+		c.Data = make(map[string]string)
+	}
+	if value == "" {
+		delete(c.Data, k)
+	} else {
+		c.Data[k] = value
+	}
+	m.mutex.Unlock()
+
+	return m.save()
+}
+
+// remove takes the player out of their clan, kicked by the one named if
+// they're given, disbanding it if it's left empty
+func (m *Manager) remove(name, by string) error {
+	m.mutex.Lock()
+	c, ok := m.clan(name)
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(NotMemberMessage)
+	}
+	member, _ := c.member(name)
+	delete(c.Members, member)
+	delete(m.of, key(member))
+	empty := len(c.Members) == 0
+	var left Clan
+	if empty {
+		left = m.disband(c)
+	} else {
+		left = c.copy()
+	}
+	m.mutex.Unlock()
+
+	text, others := fmt.Sprintf("You leave %s.", left.Name), fmt.Sprintf("%s leaves the clan.", member)
+	if by != "" {
+		text, others = fmt.Sprintf("%s throws you out of %s.", by, left.Name), fmt.Sprintf("%s throws %s out of the clan.", by, member)
+	}
+	if mem := m.find(member); mem != nil {
+		mem.Send(text)
+	}
+	m.tell(left, others, "")
+	m.emit(LeaveEvent, events.Data{
+		"clan":   left.ID,
+		"name":   left.Name,
+		"member": member,
+		"kicked": by != "",
+	})
+	if empty {
+		m.emit(DisbandEvent, events.Data{
+			"clan":   left.ID,
+			"name":   left.Name,
+			"member": member,
+		})
+	}
+
+	return m.save()
+}
+
+// disband forgets the clan, its members and invites to it, returning what
+// it was. The mutex must be locked.
+func (m *Manager) disband(c *Clan) Clan {
+	delete(m.clans, c.ID)
+	for name := range c.Members {
+		delete(m.of, key(name))
+	}
+	for name, id := range m.invites {
+		if id == c.ID {
+			delete(m.invites, name)
+		}
+	}
+
+	return c.copy()
+}
+
+// allowed returns the clan the player is in if their rank grants the
+// permission, the mutex must be locked
+func (m *Manager) allowed(name, permission string) (*Clan, error) {
+	c, ok := m.clan(name)
+	if !ok {
+		return nil, refuse(NotMemberMessage)
+	}
+	if !c.Can(name, permission) {
+		return nil, refuse(NotAllowedMessage)
+	}
+
+	return c, nil
+}
+
+// clan returns the clan the player is in, the mutex must be locked
+func (m *Manager) clan(name string) (*Clan, bool) {
+	id, ok := m.of[key(name)]
+	if !ok {
+		return nil, false
+	}
+	c, ok := m.clans[id]
+
+	return c, ok
+}
+
+// outranks refuses unless the one named by is of higher rank than the
+// member with the name
+func outranks(c Clan, by, name string) error {
+	theirs := c.standing(name)
+	if theirs < 0 {
+		return refuse(fmt.Sprintf("%s isn't in %s.", name, c.Name))
+	}
+	if key(by) == key(name) || theirs <= c.standing(by) {
+		return refuse(fmt.Sprintf("You don't outrank %s.", name))
+	}
+
+	return nil
+}
+
+// find returns the member in the game with the name, or nil
+func (m *Manager) find(name string) Member {
+	m.mutex.RLock()
+	lookup := m.lookup
+	m.mutex.RUnlock()
+
+	if lookup == nil {
+		return nil
+	}
+
+	return lookup(name)
+}
+
+// tell sends the text to the members of the clan in the game, except the
+// one named
+func (m *Manager) tell(c Clan, text, except string) {
+	for name := range c.Members {
+		if key(name) == key(except) {
+			continue
+		}
+		if mem := m.find(name); mem != nil {
+			mem.Send(text)
+		}
+	}
+}
+
+// save writes the clans to the file, if there is one
+func (m *Manager) save() error {
+	m.mutex.RLock()
+	path := m.file
+	f := File{Clans: make([]Clan, 0, len(m.clans))}
+	for _, c := range m.clans {
+		f.Clans = append(f.Clans, c.copy())
+	}
+	m.mutex.RUnlock()
+
+	if path == "" {
+		return nil
+	}
+	sort.Slice(f.Clans, func(i, j int) bool {
+		return f.Clans[i].ID < f.Clans[j].ID
+	})
+	contents, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0644)
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+// article returns "an" for words starting with a vowel, "a" otherwise
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(strings.ToLower(word)[0])) {
+		return "an"
+	}
+
+	return "a"
+}
+
+// key is how players are known, their name in lower case
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package clock
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Season describes a part of the year and when the sun rises and sets during
+// it. Sunrise and Sunset are hours of the game day.
+type Season struct {
+	Name    string `yaml:"name"`
+	Sunrise int    `yaml:"sunrise"`
+	Sunset  int    `yaml:"sunset"`
+}
+
+// Month is a named month of the game year, each month belongs to a single
+// season.
+type Month struct {
+	Name   string `yaml:"name"`
+	Season string `yaml:"season"`
+}
+
+// Calendar defines the structure of the game year, how many hours are in a
+// day, how many days in a month and what months make up the year.
+type Calendar struct {
+	HoursPerDay  int               `yaml:"hours_per_day"`
+	DaysPerMonth int               `yaml:"days_per_month"`
+	Months       []Month           `yaml:"months"`
+	Seasons      map[string]Season `yaml:"seasons"`
+}
+
+// DefaultCalendar is a twelve month, thirty day calendar with four seasons,
+// used when no calendar has been configured.
+var DefaultCalendar = &Calendar{
+	HoursPerDay:  24,
+	DaysPerMonth: 30,
+	Months: []Month{
+		{Name: "Deepwinter", Season: "winter"},
+		{Name: "Thawing", Season: "winter"},
+		{Name: "Seedtime", Season: "spring"},
+		{Name: "Rains", Season: "spring"},
+		{Name: "Blossom", Season: "spring"},
+		{Name: "Highsun", Season: "summer"},
+		{Name: "Embers", Season: "summer"},
+		{Name: "Harvest", Season: "summer"},
+		{Name: "Fallow", Season: "autumn"},
+		{Name: "Leaffall", Season: "autumn"},
+		{Name: "Frost", Season: "autumn"},
+		{Name: "Longnight", Season: "winter"},
+	},
+	Seasons: map[string]Season{
+		"winter": {Name: "winter", Sunrise: 8, Sunset: 17},
+		"spring": {Name: "spring", Sunrise: 6, Sunset: 19},
+		"summer": {Name: "summer", Sunrise: 5, Sunset: 21},
+		"autumn": {Name: "autumn", Sunrise: 7, Sunset: 18},
+	},
+}
+
+// HoursPerYear returns the number of game hours in a full game year.
+func (c *Calendar) HoursPerYear() int64 {
+	return int64(c.HoursPerDay) * int64(c.DaysPerMonth) * int64(len(c.Months))
+}
+
+// SeasonFor returns the season the given month (1 based) falls in.
+func (c *Calendar) SeasonFor(month int) Season {
+	if month < 1 || month > len(c.Months) {
+		return Season{}
+	}
+
+	return c.Seasons[c.Months[month-1].Season]
+}
+
+// LoadCalendar reads a calendar from the YAML document, like:
+//   hours_per_day: 20
+//   days_per_month: 28
+//   months:
+//     - {name: Frostmoot, season: cold}
+//     - {name: Greening, season: warm}
+//   seasons:
+//     cold: {sunrise: 8, sunset: 15}
+//     warm: {sunrise: 5, sunset: 17}
+// Seasons are named by their key.
+func LoadCalendar(contents []byte) (*Calendar, error) {
+	var c Calendar
+	if err := yaml.UnmarshalStrict(contents, &c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// validate checks the calendar makes sense, naming its seasons by their key
+func (c *Calendar) validate() error {
+	if c.HoursPerDay <= 0 || c.DaysPerMonth <= 0 {
+		return fmt.Errorf("calendars need hours in a day and days in a month")
+	}
+	if len(c.Months) == 0 {
+		return fmt.Errorf("calendars need months")
+	}
+	for name, s := range c.Seasons {
+		if s.Sunrise < 0 || s.Sunrise >= s.Sunset || s.Sunset > c.HoursPerDay {
+			return fmt.Errorf("the sun must rise before it sets within a day in %s", name)
+		}
+		s.Name = name
+		c.Seasons[name] = s
+	}
+	for _, m := range c.Months {
+		if _, ok := c.Seasons[m.Season]; !ok {
+			return fmt.Errorf("month %s is in the season %q, which the calendar doesn't have", m.Name, m.Season)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+)
+
+// The events of game time passing while the clock is running, each is given
+// the game time like the time module's game(). Every hour is emitted, and
+// the hours the sun rises and sets in the season are emitted again as
+// sunrise and sunset.
+const (
+	HourEvent    = "time:hour"
+	SunriseEvent = "time:sunrise"
+	SunsetEvent  = "time:sunset"
+)
+
+// MaxCatchup is the most game hours emitted at once when the clock falls
+// behind, like when the server was busy. Older hours are skipped.
+const MaxCatchup = 24
+
+// Clock converts real world time into game time. Game time advances at a fixed
+// rate from the epoch, with one game hour passing every HourLength of real
+// time, so the game time never needs to be stored and is the same across
+// restarts.
+type Clock struct {
+	Epoch      time.Time
+	HourLength time.Duration
+	Calendar   *Calendar
+
+	now     func() time.Time
+	emitter *events.Emitter
+	// last is the number of game hours since the epoch when the clock last
+	// ticked, ticked is false until it has
+	last   int64
+	ticked bool
+	stop   chan struct{}
+	mutex  *sync.RWMutex
+}
+
+// New creates a game clock starting at epoch where each game hour lasts the
+// given real duration. If no calendar is given the DefaultCalendar is used.
+func New(epoch time.Time, hourLength time.Duration, cal *Calendar) *Clock {
+	if cal == nil {
+		cal = DefaultCalendar
+	}
+	if hourLength <= 0 {
+		hourLength = 2 * time.Minute
+	}
+
+	return &Clock{
+		Epoch:      epoch,
+		HourLength: hourLength,
+		Calendar:   cal,
+		now:        time.Now,
+		mutex:      new(sync.RWMutex),
+	}
+}
+
+// SetEmitter changes the emitter the passing of game time is emitted with.
+func (c *Clock) SetEmitter(em *events.Emitter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.emitter = em
+}
+
+// SetClock changes how the clock tells the real time, for tests.
+func (c *Clock) SetClock(now func() time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = now
+}
+
+// Now returns the current game time.
+func (c *Clock) Now() GameTime {
+	c.mutex.RLock()
+	now := c.now
+	c.mutex.RUnlock()
+
+	return c.At(now())
+}
+
+// Start checks for game hours passing every interval, emitting them. It
+// does nothing if the clock is already running.
+func (c *Clock) Start(interval time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stop != nil || interval <= 0 {
+		return
+	}
+	c.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		c.Tick()
+		for {
+			select {
+			case <-ticker.C:
+				c.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}(c.stop)
+}
+
+// Stop halts the clock's checks, game time still passes.
+func (c *Clock) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// Tick emits each game hour that has begun since the clock last ticked,
+// at most MaxCatchup of them. The first tick only notes the time.
+func (c *Clock) Tick() {
+	c.mutex.Lock()
+	hours := c.hours(c.now())
+	last, ticked := c.last, c.ticked
+	c.last, c.ticked = hours, true
+	emitter := c.emitter
+	c.mutex.Unlock()
+
+	if !ticked || emitter == nil || hours <= last {
+		return
+	}
+	from := last + 1
+	if hours-last > MaxCatchup {
+		from = hours - MaxCatchup + 1
+	}
+	for h := from; h <= hours; h++ {
+		gt := c.At(c.Epoch.Add(time.Duration(h) * c.HourLength))
+		emitter.Emit(HourEvent, gt.Data())
+		switch season := gt.Season(); gt.Hour {
+		case season.Sunrise:
+			emitter.Emit(SunriseEvent, gt.Data())
+		case season.Sunset:
+			emitter.Emit(SunsetEvent, gt.Data())
+		}
+	}
+}
+
+// hours returns how many whole game hours have passed since the epoch at
+// the real time
+func (c *Clock) hours(t time.Time) int64 {
+	elapsed := t.Sub(c.Epoch)
+	if elapsed < 0 {
+		return 0
+	}
+
+	return int64(elapsed / c.HourLength)
+}
+
+// At returns the game time at the given real world time.
+func (c *Clock) At(t time.Time) GameTime {
+	elapsed := t.Sub(c.Epoch)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	hours := int64(elapsed / c.HourLength)
+	minute := int((elapsed % c.HourLength) * 60 / c.HourLength)
+
+	cal := c.Calendar
+	hoursPerMonth := int64(cal.HoursPerDay) * int64(cal.DaysPerMonth)
+	year := hours / cal.HoursPerYear()
+	hours %= cal.HoursPerYear()
+	month := int(hours/hoursPerMonth) + 1
+	hours %= hoursPerMonth
+	day := int(hours/int64(cal.HoursPerDay)) + 1
+	hour := int(hours % int64(cal.HoursPerDay))
+
+	return GameTime{
+		Year:     int(year) + 1,
+		Month:    month,
+		Day:      day,
+		Hour:     hour,
+		Minute:   minute,
+		calendar: cal,
+	}
+}
+
+// GameTime is a single moment on the game calendar. Years, months and days
+// are 1 based while hours and minutes start at 0.
+type GameTime struct {
+	Year   int
+	Month  int
+	Day    int
+	Hour   int
+	Minute int
+
+	calendar *Calendar
+}
+
+// MonthName returns the calendar name for the month.
+func (g GameTime) MonthName() string {
+	return g.calendar.Months[g.Month-1].Name
+}
+
+// Season returns the season the game time falls within.
+func (g GameTime) Season() Season {
+	return g.calendar.SeasonFor(g.Month)
+}
+
+// IsDay returns true if the sun is up, between sunrise and sunset for the
+// current season.
+func (g GameTime) IsDay() bool {
+	s := g.Season()
+
+	return g.Hour >= s.Sunrise && g.Hour < s.Sunset
+}
+
+// IsNight is the opposite of IsDay.
+func (g GameTime) IsNight() bool {
+	return !g.IsDay()
+}
+
+// Data returns the game time as event data, with the same keys as the time
+// module's game().
+func (g GameTime) Data() events.Data {
+	season := g.Season()
+
+	return events.Data{
+		"year":       g.Year,
+		"month":      g.Month,
+		"month_name": g.MonthName(),
+		"day":        g.Day,
+		"hour":       g.Hour,
+		"minute":     g.Minute,
+		"season":     season.Name,
+		"sunrise":    season.Sunrise,
+		"sunset":     season.Sunset,
+		"is_day":     g.IsDay(),
+	}
+}
+
+// String formats the game time like "14:05, day 3 of Highsun, year 12".
+func (g GameTime) String() string {
+	return fmt.Sprintf("%02d:%02d, day %d of %s, year %d", g.Hour, g.Minute, g.Day, g.MonthName(), g.Year)
+}
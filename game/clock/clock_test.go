@@ -0,0 +1,155 @@
+package clock_test
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/clock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock", func() {
+	var (
+		epoch = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		c     = New(epoch, time.Minute, nil)
+	)
+
+	It("starts at the beginning of the calendar", func() {
+		gt := c.At(epoch)
+		Ω(gt.Year).Should(Equal(1))
+		Ω(gt.Month).Should(Equal(1))
+		Ω(gt.Day).Should(Equal(1))
+		Ω(gt.Hour).Should(Equal(0))
+		Ω(gt.MonthName()).Should(Equal("Deepwinter"))
+	})
+
+	It("advances one game hour per hour length", func() {
+		gt := c.At(epoch.Add(14*time.Minute + 30*time.Second))
+		Ω(gt.Hour).Should(Equal(14))
+		Ω(gt.Minute).Should(Equal(30))
+	})
+
+	It("rolls over days, months and years", func() {
+		hoursPerYear := time.Duration(DefaultCalendar.HoursPerYear())
+		gt := c.At(epoch.Add((hoursPerYear + 24*31) * time.Minute))
+		Ω(gt.Year).Should(Equal(2))
+		Ω(gt.Month).Should(Equal(2))
+		Ω(gt.Day).Should(Equal(2))
+	})
+
+	It("never runs backwards before the epoch", func() {
+		gt := c.At(epoch.Add(-time.Hour))
+		Ω(gt.Year).Should(Equal(1))
+		Ω(gt.Hour).Should(Equal(0))
+	})
+
+	Describe("GameTime", func() {
+		// month six is Highsun, in summer
+		summer := epoch.Add(time.Duration(24*30*5) * time.Minute)
+
+		It("knows the season", func() {
+			Ω(c.At(summer).Season().Name).Should(Equal("summer"))
+		})
+
+		It("knows when the sun is up", func() {
+			Ω(c.At(summer.Add(12 * time.Minute)).IsDay()).Should(BeTrue())
+			Ω(c.At(summer.Add(22 * time.Minute)).IsNight()).Should(BeTrue())
+			Ω(c.At(summer.Add(4 * time.Minute)).IsNight()).Should(BeTrue())
+		})
+
+		It("formats as a string", func() {
+			Ω(c.At(summer.Add(14*time.Minute + 5*time.Second)).String()).Should(Equal("14:05, day 1 of Highsun, year 1"))
+		})
+	})
+	Describe("ticking", func() {
+		var (
+			ticking  *Clock
+			now      time.Time
+			received chan string
+		)
+
+		BeforeEach(func() {
+			ticking = New(epoch, time.Minute, nil)
+			now = epoch.Add(3 * time.Minute)
+			ticking.SetClock(func() time.Time { return now })
+			em := events.NewEmitter(nil)
+			received = make(chan string, 100)
+			for _, evt := range []string{HourEvent, SunriseEvent, SunsetEvent} {
+				evt := evt
+				em.On(evt, events.HandlerFunc(func(d events.Data) error {
+					received <- evt
+
+					return nil
+				}))
+			}
+			ticking.SetEmitter(em)
+		})
+
+		// counts drains the events received so far into the counts
+		counts := func(seen map[string]int) func() map[string]int {
+			return func() map[string]int {
+				for {
+					select {
+					case evt := <-received:
+						seen[evt]++
+					default:
+						return seen
+					}
+				}
+			}
+		}
+
+		It("emits each hour that begins, and the sunrise and sunset", func() {
+			ticking.Tick()
+			Consistently(received).ShouldNot(Receive())
+
+			// winter's sun rises at 8 and sets at 17
+			now = epoch.Add(8*time.Minute + 30*time.Second)
+			ticking.Tick()
+			Eventually(counts(make(map[string]int))).Should(Equal(map[string]int{HourEvent: 5, SunriseEvent: 1}))
+			now = epoch.Add(17 * time.Minute)
+			ticking.Tick()
+			Eventually(counts(make(map[string]int))).Should(Equal(map[string]int{HourEvent: 9, SunsetEvent: 1}))
+		})
+
+		It("doesn't emit more than a day of hours at once", func() {
+			ticking.Tick()
+			now = now.Add(100 * time.Minute)
+			ticking.Tick()
+
+			Eventually(counts(make(map[string]int))).Should(HaveKeyWithValue(HourEvent, MaxCatchup))
+		})
+
+		It("gives the game time to handlers", func() {
+			data := epoch.Add(17 * time.Minute)
+			Ω(c.At(data).Data()).Should(HaveKeyWithValue("season", "winter"))
+			Ω(c.At(data).Data()).Should(HaveKeyWithValue("hour", 17))
+			Ω(c.At(data).Data()).Should(HaveKeyWithValue("is_day", false))
+		})
+	})
+
+	Describe("LoadCalendar", func() {
+		It("reads calendars and checks them", func() {
+			cal, err := LoadCalendar([]byte(`
+hours_per_day: 20
+days_per_month: 28
+months:
+  - {name: Frostmoot, season: cold}
+  - {name: Greening, season: warm}
+seasons:
+  cold: {sunrise: 8, sunset: 15}
+  warm: {sunrise: 5, sunset: 17}
+`))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cal.HoursPerYear()).Should(Equal(int64(20 * 28 * 2)))
+			Ω(cal.SeasonFor(2)).Should(Equal(Season{Name: "warm", Sunrise: 5, Sunset: 17}))
+
+			_, err = LoadCalendar([]byte("hours_per_day: 20\ndays_per_month: 28\nmonths: [{name: Void, season: none}]"))
+			Ω(err).Should(HaveOccurred())
+			_, err = LoadCalendar([]byte("hours_per_day: 20\ndays_per_month: 28\nmonths: [{name: Dusk, season: dark}]\nseasons: {dark: {sunrise: 12, sunset: 4}}"))
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
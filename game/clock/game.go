@@ -0,0 +1,46 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package clock
+
+import (
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/viper"
+)
+
+var (
+	gameClock *Clock
+	gameOnce  sync.Once
+)
+
+// Game returns the clock for the game, configured from the "clock" section of
+// the Dragonfile. A ratio of game time to real time replaces the hour
+// length, and a calendar file replaces the DefaultCalendar.
+func Game() *Clock {
+	gameOnce.Do(func() {
+		log := logger.NewWithSource("clock")
+		epoch, err := time.Parse(time.RFC3339, viper.GetString("clock.epoch"))
+		if err != nil {
+			log.WithError(err).Warn("Invalid clock epoch, using the zero time instead.")
+		}
+		hourLength := viper.GetDuration("clock.hour_length")
+		if ratio := viper.GetFloat64("clock.ratio"); ratio > 0 {
+			hourLength = time.Duration(float64(time.Hour) / ratio)
+		}
+		var cal *Calendar
+		if path := viper.GetString("clock.calendar"); path != "" {
+			if contents, err := ioutil.ReadFile(path); err != nil {
+				log.WithError(err).Warn("Failed to read the calendar, using the default.")
+			} else if cal, err = LoadCalendar(contents); err != nil {
+				log.WithError(err).Warn("Invalid calendar, using the default.")
+			}
+		}
+
+		gameClock = New(epoch, hourLength, cal)
+	})
+
+	return gameClock
+}
@@ -0,0 +1,414 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package combat
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/random"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Who attack messages are for.
+const (
+	ToAttacker = "attacker"
+	ToDefender = "defender"
+	ToRoom     = "room"
+)
+
+// Armed is a combatant who can wield a weapon, its "damage" prop is the dice
+// it deals, like "2d6".
+type Armed interface {
+	Equipment() map[string]item.Item
+}
+
+// Propped is a combatant whose "damage" prop is the dice dealt without a
+// weapon, like a mob's claws.
+type Propped interface {
+	Prop(key string) interface{}
+}
+
+// Attack is one swing of a combatant at another as it's resolved.
+type Attack struct {
+	id       string
+	attacker Combatant
+	defender Combatant
+	roll     int
+	hit      bool
+	critical bool
+	damage   int
+	kind     string
+	messages map[string]string
+	mutex    *sync.RWMutex
+}
+
+func newAttack(attacker, defender Combatant) *Attack {
+	return &Attack{
+		id:       uuid.NewV1().String(),
+		attacker: attacker,
+		defender: defender,
+		messages: make(map[string]string),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// ID returns the id of the attack, given to the handlers of its events.
+func (a *Attack) ID() string {
+	return a.id
+}
+
+// Kind returns what the attack is, empty for a swing of a weapon or fist
+// and the skill's name for one, like "kick".
+func (a *Attack) Kind() string {
+	return a.kind
+}
+
+// Attacker returns who is attacking.
+func (a *Attack) Attacker() Combatant {
+	return a.attacker
+}
+
+// Defender returns who is attacked.
+func (a *Attack) Defender() Combatant {
+	return a.defender
+}
+
+// Roll returns the d20 rolled to hit, 0 until it's rolled.
+func (a *Attack) Roll() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.roll
+}
+
+// Hit is true if the attack hit.
+func (a *Attack) Hit() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.hit
+}
+
+// Critical is true if the attack was a critical hit, dealing double damage.
+func (a *Attack) Critical() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.critical
+}
+
+// Damage returns the damage the attack deals.
+func (a *Attack) Damage() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.damage
+}
+
+// SetDamage changes the damage the attack deals, never below 0.
+func (a *Attack) SetDamage(damage int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if damage < 0 {
+		damage = 0
+	}
+	a.damage = damage
+}
+
+// AdjustDamage adds the delta to the damage, like -2 for armor soaking some
+// of the blow, and returns what it deals now.
+func (a *Attack) AdjustDamage(delta int) int {
+	a.SetDamage(a.Damage() + delta)
+
+	return a.Damage()
+}
+
+// SetMessage replaces what the attacker, defender or room are told of the
+// attack. An empty message tells them nothing.
+func (a *Attack) SetMessage(to, text string) error {
+	switch to {
+	case ToAttacker, ToDefender, ToRoom:
+	default:
+		return fmt.Errorf("messages are for %q, %q or %q, not %q", ToAttacker, ToDefender, ToRoom, to)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.messages[to] = text
+
+	return nil
+}
+
+// Message returns what the attacker, defender or room are told of the attack,
+// like "You hit a rat. [4]", or "Your kick hits a rat. [4]" for a kind.
+func (a *Attack) Message(to string) string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if text, ok := a.messages[to]; ok {
+		return text
+	}
+
+	you, verb := "You", "miss"
+	them, verbs := capitalize(a.attacker.Name()), "misses"
+	if a.hit {
+		verb, verbs = "hit", "hits"
+	}
+	if a.kind != "" {
+		you, verb = "Your "+a.kind, verbs
+		them += "'s " + a.kind
+	}
+	damage := ""
+	if a.hit {
+		damage = fmt.Sprintf(" [%d]", a.damage)
+	}
+	switch to {
+	case ToAttacker:
+		return fmt.Sprintf("%s %s %s.%s", you, verb, a.defender.Name(), damage)
+	case ToDefender:
+		return fmt.Sprintf("%s %s you.%s", them, verbs, damage)
+	case ToRoom:
+		return fmt.Sprintf("%s %s %s.", them, verbs, a.defender.Name())
+	}
+
+	return ""
+}
+
+func (a *Attack) data() events.Data {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return events.Data{
+		"id":            a.id,
+		"attacker":      a.attacker.ID(),
+		"attacker_name": a.attacker.Name(),
+		"defender":      a.defender.ID(),
+		"defender_name": a.defender.Name(),
+		"room":          a.attacker.Location(),
+		"roll":          a.roll,
+		"hit":           a.hit,
+		"critical":      a.critical,
+		"damage":        a.damage,
+		"kind":          a.kind,
+	}
+}
+
+// Round has everyone fighting attack their targets, in order of initiative:
+// a d20 plus their dexterity bonus. Fights end when the target is gone.
+func (e *Engine) Round() {
+	e.mutex.RLock()
+	var fighters []Combatant
+	for id := range e.targets {
+		fighters = append(fighters, e.combatants[id])
+	}
+	e.mutex.RUnlock()
+
+	if len(fighters) == 0 {
+		return
+	}
+
+	initiative := make(map[string]int, len(fighters))
+	ids := make([]string, len(fighters))
+	for i, c := range fighters {
+		initiative[c.ID()] = random.Range(1, 21) + (c.Stat("dex")-10)/2
+		ids[i] = c.ID()
+	}
+	sort.SliceStable(fighters, func(i, j int) bool {
+		a, b := initiative[fighters[i].ID()], initiative[fighters[j].ID()]
+		if a == b {
+			return fighters[i].ID() < fighters[j].ID()
+		}
+
+		return a > b
+	})
+	sort.Strings(ids)
+	e.emit(RoundEvent, events.Data{"fighters": ids})
+
+	for _, c := range fighters {
+		for n := 0; n < 1+c.Stat("extra_attacks"); n++ {
+			target, ok := e.Target(c)
+			if !ok {
+				break
+			}
+			if target.Location() != c.Location() || !e.present(target) {
+				e.Stop(c)
+
+				break
+			}
+			e.attack(c, target)
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.forget()
+}
+
+// present is true if the combatant is still in a fight, not killed or gone
+func (e *Engine) present(c Combatant) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	_, ok := e.combatants[c.ID()]
+
+	return ok
+}
+
+// attack resolves one attack, through its events, from the roll to hit to
+// the defender's death
+func (e *Engine) attack(attacker, defender Combatant) {
+	at := newAttack(attacker, defender)
+	e.mutex.Lock()
+	e.attacks[at.id] = at
+	e.mutex.Unlock()
+	defer func() {
+		e.mutex.Lock()
+		delete(e.attacks, at.id)
+		e.mutex.Unlock()
+	}()
+
+	if err := e.check(AttackEvent, at.data()); err != nil {
+		return
+	}
+
+	roll := random.Range(1, 21)
+	at.mutex.Lock()
+	at.roll = roll
+	at.critical = roll == 20
+	at.hit = roll == 20 || (roll != 1 && roll+attacker.Stat("hit") >= 10+defender.Stat("ac"))
+	at.mutex.Unlock()
+
+	if at.Hit() && e.check(HitEvent, at.data()) != nil {
+		at.mutex.Lock()
+		at.hit, at.critical = false, false
+		at.mutex.Unlock()
+	}
+	if !at.Hit() {
+		e.confirm(MissEvent, at.data())
+		e.tell(at)
+		e.confirm(AttackEvent, at.data())
+
+		return
+	}
+	e.confirm(HitEvent, at.data())
+
+	damage := e.roll(attacker) + attacker.Stat("damroll")
+	if at.Critical() {
+		damage *= 2
+	}
+	if damage < 1 {
+		damage = 1
+	}
+	at.SetDamage(damage)
+	e.deal(at)
+}
+
+// deal has the attack deal its damage, unless cancelled, and tells everyone
+// about it, returning the damage dealt
+func (e *Engine) deal(at *Attack) int {
+	if e.check(DamageEvent, at.data()) != nil {
+		at.SetDamage(0)
+	}
+
+	hp, dealt := 1, at.Damage()
+	if dealt > 0 {
+		hp = at.defender.AddStat("hp", -dealt)
+		e.confirm(DamageEvent, at.data())
+	}
+	e.tell(at)
+	e.confirm(AttackEvent, at.data())
+
+	if dealt > 0 && hp <= 0 {
+		e.kill(at.defender, at.attacker)
+	}
+
+	return dealt
+}
+
+// Strike deals the damage to the defender through the damage events, like
+// for a skill that always lands. The kind names what struck, like "kick".
+// It returns the damage dealt, 0 if it was cancelled, and the defender may
+// die of it.
+func (e *Engine) Strike(attacker, defender Combatant, damage int, kind string) int {
+	at := newAttack(attacker, defender)
+	at.hit, at.kind = true, kind
+	at.SetDamage(damage)
+	e.mutex.Lock()
+	e.attacks[at.id] = at
+	e.mutex.Unlock()
+	defer func() {
+		e.mutex.Lock()
+		delete(e.attacks, at.id)
+		e.mutex.Unlock()
+	}()
+
+	return e.deal(at)
+}
+
+// roll rolls the attacker's damage dice: their weapon's, their own or the
+// unarmed dice
+func (e *Engine) roll(attacker Combatant) int {
+	e.mutex.RLock()
+	dice := e.unarmed
+	e.mutex.RUnlock()
+
+	if p, ok := attacker.(Propped); ok {
+		if d, ok := p.Prop("damage").(string); ok && d != "" {
+			dice = d
+		}
+	}
+	if a, ok := attacker.(Armed); ok {
+		if d, ok := a.Equipment()["wield"].Props["damage"].(string); ok && d != "" {
+			dice = d
+		}
+	}
+
+	damage, err := random.Roll(dice)
+	if err != nil {
+		return 1
+	}
+
+	return damage
+}
+
+// kill ends the fights of the defender unless their death is cancelled
+func (e *Engine) kill(victim, killer Combatant) {
+	data := events.Data{
+		"victim":      victim.ID(),
+		"victim_name": victim.Name(),
+		"killer":      killer.ID(),
+		"killer_name": killer.Name(),
+		"room":        victim.Location(),
+	}
+	if err := e.check(DeathEvent, data); err != nil {
+		return
+	}
+
+	e.Remove(victim)
+	e.mutex.Lock()
+	delete(e.combatants, victim.ID())
+	onDeath := e.onDeath
+	e.mutex.Unlock()
+
+	send(victim, "You have been killed!")
+	send(killer, fmt.Sprintf("You have killed %s!", victim.Name()))
+	e.tellRoom(victim.Location(), capitalize(victim.Name())+" is dead!", victim, killer)
+	e.confirm(DeathEvent, data)
+	if onDeath != nil {
+		onDeath(victim, killer)
+	}
+}
+
+// tell sends the attack's messages to the attacker, defender and room
+func (e *Engine) tell(at *Attack) {
+	send(at.attacker, at.Message(ToAttacker))
+	send(at.defender, at.Message(ToDefender))
+	e.tellRoom(at.attacker.Location(), at.Message(ToRoom), at.attacker, at.defender)
+}
@@ -0,0 +1,462 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package combat runs fights between players and mobs. A fight starts with
+// one combatant attacking another and goes on a round every combat pulse
+// until one dies, flees or leaves. Each round the fighters act in order of
+// initiative, and each attack passes through a pipeline of events: the
+// attack, the hit or miss, the damage and the death it may cause. The before:
+// handlers of each can cancel that stage, and while an attack resolves its
+// damage and messages can be changed through Attack, by id.
+package combat
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The events of a fight. Cancelling an attack skips it, a hit turns it into
+// a miss, damage stops it from being dealt and a death leaves the victim
+// alive. Each attack's events are given its id, the ids and names of the
+// attacker and defender and the room, with the roll, whether it hit or was
+// critical and the damage once they're known.
+const (
+	StartEvent  = "combat:start"
+	RoundEvent  = "combat:round"
+	AttackEvent = "combat:attack"
+	HitEvent    = "combat:hit"
+	MissEvent   = "combat:miss"
+	DamageEvent = "combat:damage"
+	DeathEvent  = "combat:death"
+	EndEvent    = "combat:end"
+)
+
+// Messages told to combatants who can't do what they tried.
+const (
+	SelfMessage        = "You can't fight yourself."
+	NotHereMessage     = "They aren't here."
+	FightingMessage    = "You're already fighting!"
+	NotFightingMessage = "You aren't fighting anyone."
+	FleeFailedMessage  = "You try to escape, but can't!"
+	CancelMessage      = "You can't do that right now."
+)
+
+// Combatant is anyone who can fight, like a player or a mob. Stats used in
+// combat are "hp", "hit" added to attack rolls, "ac" added to what attacks
+// need to roll, "damroll" added to damage, "dex" for initiative and fleeing
+// and "extra_attacks" for attacks each round beyond the first.
+type Combatant interface {
+	ID() string
+	Name() string
+	Location() string
+	Stat(name string) int
+	AddStat(name string, delta int) int
+}
+
+// Sender is a combatant who is told what happens in fights.
+type Sender interface {
+	Send(text string) error
+}
+
+// Matcher is a combatant found by keywords other than its name.
+type Matcher interface {
+	Matches(keyword string) bool
+}
+
+// Engine tracks who is fighting whom and runs their rounds.
+type Engine struct {
+	targets    map[string]string
+	combatants map[string]Combatant
+	attacks    map[string]*Attack
+	occupants  func(room string) []Combatant
+	lookup     func(id string) Combatant
+	visible    func(c, other Combatant) bool
+	escape     func(Combatant) error
+	onDeath    func(victim, killer Combatant)
+	unarmed    string
+	emitter    *events.Emitter
+	stop       chan struct{}
+	mutex      *sync.RWMutex
+}
+
+// New creates an engine checking and emitting events with the emitter, which
+// may be nil.
+func New(em *events.Emitter) *Engine {
+	return &Engine{
+		targets:    make(map[string]string),
+		combatants: make(map[string]Combatant),
+		attacks:    make(map[string]*Attack),
+		unarmed:    "1d4",
+		emitter:    em,
+		mutex:      new(sync.RWMutex),
+	}
+}
+
+var (
+	globalEngine *Engine
+	globalOnce   sync.Once
+)
+
+// Global returns the game's combat engine.
+func Global() *Engine {
+	globalOnce.Do(func() {
+		globalEngine = New(nil)
+	})
+
+	return globalEngine
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (e *Engine) SetEmitter(em *events.Emitter) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.emitter = em
+}
+
+// SetOccupants sets the function finding who is in a room, they're told
+// about fights there and can be attacked.
+func (e *Engine) SetOccupants(fn func(room string) []Combatant) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.occupants = fn
+}
+
+// SetVisible sets what decides whether a combatant sees another in their
+// room, those they can't see can't be attacked by name. Without it everyone
+// is seen.
+func (e *Engine) SetVisible(fn func(c, other Combatant) bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.visible = fn
+}
+
+// SetLookup sets the function finding combatants by id, for scripts that
+// only know who they are by their id.
+func (e *Engine) SetLookup(fn func(id string) Combatant) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.lookup = fn
+}
+
+// Lookup returns the combatant with the id, or nil if there isn't one.
+func (e *Engine) Lookup(id string) Combatant {
+	e.mutex.RLock()
+	c, ok := e.combatants[id]
+	lookup := e.lookup
+	e.mutex.RUnlock()
+
+	if ok {
+		return c
+	}
+	if lookup != nil {
+		return lookup(id)
+	}
+
+	return nil
+}
+
+// SetEscape sets the function moving combatants who flee out of the room.
+func (e *Engine) SetEscape(fn func(Combatant) error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.escape = fn
+}
+
+// OnDeath sets the function called after a combatant is killed, like to
+// remove a dead mob from the game.
+func (e *Engine) OnDeath(fn func(victim, killer Combatant)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.onDeath = fn
+}
+
+// SetUnarmed changes the damage dice of combatants without a weapon.
+func (e *Engine) SetUnarmed(dice string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.unarmed = dice
+}
+
+// Start has the attacker start fighting the defender, who fights back if
+// they aren't already fighting. The attacker gets the first attack.
+func (e *Engine) Start(attacker, defender Combatant) error {
+	if err := e.Engage(attacker, defender); err != nil {
+		return err
+	}
+	e.attack(attacker, defender)
+
+	return nil
+}
+
+// Engage starts the fight like Start without the first attack, for fights
+// started by something else, like a kick or a spell.
+func (e *Engine) Engage(attacker, defender Combatant) error {
+	switch {
+	case attacker.ID() == defender.ID():
+		return &item.Refused{Message: SelfMessage}
+	case attacker.Location() != defender.Location():
+		return &item.Refused{Message: NotHereMessage}
+	}
+	if _, ok := e.Target(attacker); ok {
+		return &item.Refused{Message: FightingMessage}
+	}
+
+	data := events.Data{
+		"attacker":      attacker.ID(),
+		"attacker_name": attacker.Name(),
+		"defender":      defender.ID(),
+		"defender_name": defender.Name(),
+		"room":          attacker.Location(),
+	}
+	if err := e.check(StartEvent, data); err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	e.combatants[attacker.ID()] = attacker
+	e.combatants[defender.ID()] = defender
+	e.targets[attacker.ID()] = defender.ID()
+	if _, ok := e.targets[defender.ID()]; !ok {
+		e.targets[defender.ID()] = attacker.ID()
+	}
+	e.mutex.Unlock()
+
+	e.confirm(StartEvent, data)
+	send(defender, capitalize(attacker.Name())+" attacks you!")
+
+	return nil
+}
+
+// Target returns who the combatant is fighting.
+func (e *Engine) Target(c Combatant) (Combatant, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	id, ok := e.targets[c.ID()]
+	if !ok {
+		return nil, false
+	}
+	target, ok := e.combatants[id]
+
+	return target, ok
+}
+
+// Fighting is true if the combatant with the id is fighting.
+func (e *Engine) Fighting(id string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	_, ok := e.targets[id]
+
+	return ok
+}
+
+// Stop ends the combatant's fight, others may still be fighting them.
+func (e *Engine) Stop(c Combatant) {
+	e.mutex.Lock()
+	_, ok := e.targets[c.ID()]
+	delete(e.targets, c.ID())
+	e.mutex.Unlock()
+
+	if ok {
+		e.emit(EndEvent, events.Data{"combatant": c.ID(), "name": c.Name()})
+	}
+}
+
+// Remove ends every fight the combatant is in, like when they die or leave
+// the game.
+func (e *Engine) Remove(c Combatant) {
+	e.mutex.RLock()
+	var stopped []Combatant
+	for id, target := range e.targets {
+		if id == c.ID() || target == c.ID() {
+			stopped = append(stopped, e.combatants[id])
+		}
+	}
+	e.mutex.RUnlock()
+
+	for _, other := range stopped {
+		e.Stop(other)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.forget()
+}
+
+// Flee tries to escape the combatant's fight, succeeding more often the
+// more dexterous they are. Those who get away end their fight.
+func (e *Engine) Flee(c Combatant) error {
+	if !e.Fighting(c.ID()) {
+		return &item.Refused{Message: NotFightingMessage}
+	}
+	if random.Intn(100) >= 50+(c.Stat("dex")-10)*5 {
+		return &item.Refused{Message: FleeFailedMessage}
+	}
+
+	e.mutex.RLock()
+	escape := e.escape
+	e.mutex.RUnlock()
+
+	room := c.Location()
+	if escape != nil {
+		if err := escape(c); err != nil {
+			return err
+		}
+	}
+	e.Stop(c)
+	e.tellRoom(room, capitalize(c.Name())+" has fled!", c)
+
+	return nil
+}
+
+// Attack returns the attack with the id while it's being resolved, so the
+// handlers of its events can change it.
+func (e *Engine) Attack(id string) (*Attack, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	at, ok := e.attacks[id]
+
+	return at, ok
+}
+
+// StartRounds ticks rounds every interval in the background until stopped.
+func (e *Engine) StartRounds(interval time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.stop != nil || interval <= 0 {
+		return
+	}
+	e.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.Round()
+			case <-stop:
+				return
+			}
+		}
+	}(e.stop)
+}
+
+// StopRounds halts the rounds.
+func (e *Engine) StopRounds() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+}
+
+// forget drops combatants who aren't in any fight, the mutex must be held
+func (e *Engine) forget() {
+	for id := range e.combatants {
+		if _, ok := e.targets[id]; ok {
+			continue
+		}
+		targeted := false
+		for _, target := range e.targets {
+			if target == id {
+				targeted = true
+
+				break
+			}
+		}
+		if !targeted {
+			delete(e.combatants, id)
+		}
+	}
+}
+
+func (e *Engine) check(evt string, data events.Data) error {
+	e.mutex.RLock()
+	emitter := e.emitter
+	e.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (e *Engine) confirm(evt string, data events.Data) {
+	e.mutex.RLock()
+	emitter := e.emitter
+	e.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (e *Engine) emit(evt string, data events.Data) {
+	e.mutex.RLock()
+	emitter := e.emitter
+	e.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+// tellRoom sends the text to everyone in the room but those it's about
+func (e *Engine) tellRoom(room, text string, about ...Combatant) {
+	e.mutex.RLock()
+	occupants := e.occupants
+	e.mutex.RUnlock()
+
+	if occupants == nil || text == "" {
+		return
+	}
+	skip := make(map[string]bool, len(about))
+	for _, c := range about {
+		skip[c.ID()] = true
+	}
+	for _, c := range occupants(room) {
+		if !skip[c.ID()] {
+			send(c, text)
+		}
+	}
+}
+
+func send(c Combatant, text string) {
+	if s, ok := c.(Sender); ok && text != "" {
+		s.Send(text)
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
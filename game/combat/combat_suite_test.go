@@ -0,0 +1,13 @@
+package combat_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCombat(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Combat Suite")
+}
@@ -0,0 +1,291 @@
+package combat_test
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/random"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fighter is a combatant that records what it's told
+type fighter struct {
+	name  string
+	room  string
+	stats map[string]int
+	worn  map[string]item.Item
+	sent  []string
+}
+
+func newFighter(name string, hp int) *fighter {
+	return &fighter{
+		name:  name,
+		room:  "square",
+		stats: map[string]int{"hp": hp, "dex": 10},
+	}
+}
+
+func (f *fighter) ID() string {
+	return strings.ToLower(f.name)
+}
+
+func (f *fighter) Name() string {
+	return f.name
+}
+
+func (f *fighter) Location() string {
+	return f.room
+}
+
+func (f *fighter) Stat(name string) int {
+	return f.stats[name]
+}
+
+func (f *fighter) AddStat(name string, delta int) int {
+	f.stats[name] += delta
+
+	return f.stats[name]
+}
+
+func (f *fighter) Equipment() map[string]item.Item {
+	return f.worn
+}
+
+func (f *fighter) Level() command.Level {
+	return command.Player
+}
+
+func (f *fighter) Send(text string) error {
+	f.sent = append(f.sent, text)
+
+	return nil
+}
+
+func (f *fighter) told(text string) bool {
+	return strings.Contains(strings.Join(f.sent, "\n"), text)
+}
+
+// rolls is a random source every die rolls the same side of, the highest
+// for rolls(19) and the lowest for rolls(0)
+type rolls int64
+
+func (r rolls) Int63() int64 {
+	return int64(r) << 32
+}
+
+func (r rolls) Seed(int64) {}
+
+var _ = Describe("Engine", func() {
+	var (
+		em              *events.Emitter
+		e               *Engine
+		alice, bob, rat *fighter
+		killed          []string
+	)
+
+	BeforeEach(func() {
+		random.SetSource(rolls(19))
+		em = events.NewEmitter(nil)
+		e = New(em)
+		alice = newFighter("Alice", 30)
+		bob = newFighter("Bob", 30)
+		rat = newFighter("a rat", 20)
+		killed = nil
+		e.SetOccupants(func(room string) []Combatant {
+			var in []Combatant
+			for _, f := range []*fighter{alice, bob, rat} {
+				if f.room == room {
+					in = append(in, f)
+				}
+			}
+
+			return in
+		})
+		e.OnDeath(func(victim, killer Combatant) {
+			killed = append(killed, victim.ID()+" by "+killer.ID())
+		})
+	})
+
+	AfterEach(func() {
+		random.SetSource(rand.NewSource(time.Now().UnixNano()))
+	})
+
+	It("refuses fights that can't happen", func() {
+		Ω(e.Start(alice, alice)).Should(MatchError(SelfMessage))
+		bob.room = "temple"
+		Ω(e.Start(alice, bob)).Should(MatchError(NotHereMessage))
+		bob.room = "square"
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		Ω(e.Start(alice, bob)).Should(MatchError(FightingMessage))
+	})
+
+	It("attacks right away and tells everyone in the room", func() {
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		Ω(e.Fighting("alice")).Should(BeTrue())
+		Ω(e.Fighting("a rat")).Should(BeTrue())
+
+		// a natural 20 crits for double the unarmed 1d4
+		Ω(rat.stats["hp"]).Should(Equal(12))
+		Ω(alice.told("You hit a rat. [8]")).Should(BeTrue())
+		Ω(rat.told("Alice attacks you!")).Should(BeTrue())
+		Ω(rat.told("Alice hits you. [8]")).Should(BeTrue())
+		Ω(bob.told("Alice hits a rat.")).Should(BeTrue())
+	})
+
+	It("misses on a natural 1", func() {
+		random.SetSource(rolls(0))
+		alice.stats["hit"] = 100
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		Ω(rat.stats["hp"]).Should(Equal(20))
+		Ω(alice.told("You miss a rat.")).Should(BeTrue())
+		Ω(bob.told("Alice misses a rat.")).Should(BeTrue())
+	})
+
+	It("deals the damage of the wielded weapon", func() {
+		alice.worn = map[string]item.Item{
+			"wield": {Name: "a long sword", Props: map[string]interface{}{"damage": "1d8+2"}},
+		}
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		// 1d8 rolls a 4 with this source, doubled with the 2 on a crit
+		Ω(rat.stats["hp"]).Should(Equal(8))
+	})
+
+	It("runs rounds until someone dies", func() {
+		rat.stats["hp"] = 40
+		rat.stats["extra_attacks"] = 1
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		e.Round()
+		// both of the rat's attacks and another of Alice's
+		Ω(alice.stats["hp"]).Should(Equal(14))
+		Ω(rat.stats["hp"]).Should(Equal(24))
+
+		e.Round()
+		Ω(rat.stats["hp"]).Should(Equal(24))
+		Ω(alice.stats["hp"]).Should(Equal(-2))
+		Ω(killed).Should(Equal([]string{"alice by a rat"}))
+		Ω(alice.told("You have been killed!")).Should(BeTrue())
+		Ω(bob.told("Alice is dead!")).Should(BeTrue())
+		Ω(e.Fighting("alice")).Should(BeFalse())
+		Ω(e.Fighting("a rat")).Should(BeFalse())
+	})
+
+	It("ends fights when the target leaves", func() {
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		rat.room = "temple"
+		e.Round()
+		Ω(e.Fighting("alice")).Should(BeFalse())
+	})
+
+	It("lets handlers mitigate damage and change messages", func() {
+		em.On("before:"+DamageEvent, events.HandlerFunc(func(d events.Data) error {
+			at, ok := e.Attack(d["id"].(string))
+			Ω(ok).Should(BeTrue())
+			at.AdjustDamage(-3)
+			Ω(at.SetMessage(ToAttacker, "Your fist glances off the rat's hide.")).Should(Succeed())
+
+			return nil
+		}))
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		Ω(rat.stats["hp"]).Should(Equal(15))
+		Ω(alice.told("Your fist glances off the rat's hide.")).Should(BeTrue())
+		Ω(rat.told("Alice hits you. [5]")).Should(BeTrue())
+	})
+
+	It("turns cancelled hits into misses and cancelled deaths into survival", func() {
+		em.On("before:"+HitEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["defender"] == "bob" {
+				return events.ErrHalt
+			}
+
+			return nil
+		}))
+		em.On("before:"+DeathEvent, events.HandlerFunc(func(d events.Data) error {
+			return errors.New("The rat refuses to die.")
+		}))
+
+		Ω(e.Start(alice, bob)).Should(Succeed())
+		Ω(bob.stats["hp"]).Should(Equal(30))
+		Ω(alice.told("You miss Bob.")).Should(BeTrue())
+
+		e.Stop(alice)
+		e.Stop(bob)
+		rat.stats["hp"] = 1
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		Ω(rat.stats["hp"]).Should(BeNumerically("<=", 0))
+		Ω(killed).Should(BeEmpty())
+		Ω(e.Fighting("a rat")).Should(BeTrue())
+	})
+
+	It("engages without attacking and strikes with skills", func() {
+		Ω(e.Engage(alice, rat)).Should(Succeed())
+		Ω(e.Fighting("a rat")).Should(BeTrue())
+		Ω(rat.stats["hp"]).Should(Equal(20))
+
+		Ω(e.Strike(alice, rat, 6, "kick")).Should(Equal(6))
+		Ω(rat.stats["hp"]).Should(Equal(14))
+		Ω(alice.told("Your kick hits a rat. [6]")).Should(BeTrue())
+		Ω(rat.told("Alice's kick hits you. [6]")).Should(BeTrue())
+		Ω(bob.told("Alice's kick hits a rat.")).Should(BeTrue())
+
+		Ω(e.Strike(alice, rat, 20, "kick")).Should(Equal(20))
+		Ω(killed).Should(Equal([]string{"a rat by alice"}))
+	})
+
+	It("lets combatants flee", func() {
+		var escaped []string
+		e.SetEscape(func(c Combatant) error {
+			escaped = append(escaped, c.ID())
+			c.(*fighter).room = "temple"
+
+			return nil
+		})
+		Ω(e.Flee(alice)).Should(MatchError(NotFightingMessage))
+
+		Ω(e.Start(alice, rat)).Should(Succeed())
+		alice.stats["dex"] = 0
+		Ω(e.Flee(alice)).Should(MatchError(FleeFailedMessage))
+		alice.stats["dex"] = 10
+		Ω(e.Flee(alice)).Should(Succeed())
+		Ω(escaped).Should(Equal([]string{"alice"}))
+		Ω(e.Fighting("alice")).Should(BeFalse())
+		Ω(bob.told("Alice has fled!")).Should(BeTrue())
+	})
+
+	Describe("commands", func() {
+		var dispatch func(string)
+
+		BeforeEach(func() {
+			registry := command.NewRegistry()
+			for _, c := range NewCommands(e, func(c command.Caller) Combatant {
+				return c.(*fighter)
+			}) {
+				Ω(registry.Register(c)).Should(Succeed())
+			}
+			dispatch = func(line string) {
+				Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+			}
+		})
+
+		It("starts and flees fights", func() {
+			dispatch("kill")
+			Ω(alice.told("Kill whom?")).Should(BeTrue())
+			dispatch("kill dragon")
+			Ω(alice.told(NotHereMessage)).Should(BeTrue())
+			dispatch("flee")
+			Ω(alice.told(NotFightingMessage)).Should(BeTrue())
+
+			dispatch("kill rat")
+			Ω(alice.told("You hit a rat. [8]")).Should(BeTrue())
+			dispatch("flee")
+			Ω(alice.told("You flee from the fight!")).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,101 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package combat
+
+import (
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the kill and flee commands for fighting with e.
+func NewCommands(e *Engine, resolve command.Resolver[Combatant]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:    "kill",
+			Aliases: []string{"attack", "hit", "k"},
+			Args:    []command.Arg{{Name: "who", Kind: command.Text, Optional: true}},
+			Help:    "Starts a fight with someone in the room.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Combatant) error {
+				if len(ctx.Input.Words) == 0 {
+					return ctx.Send("Kill whom?")
+				}
+				target := e.Find(c, ctx.Input.Words[0])
+				if target == nil {
+					return ctx.Send(NotHereMessage)
+				}
+
+				return e.Start(c, target)
+			}),
+		},
+		{
+			Name:   "flee",
+			Help:   "Tries to run from the fight you're in.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Combatant) error {
+				if err := e.Flee(c); err != nil {
+					return err
+				}
+
+				return ctx.Send("You flee from the fight!")
+			}),
+		},
+	}
+}
+
+// Find returns who in the combatant's room the keyword names, like "2.rat"
+// for the second rat, or nil if there's no one. Those who aren't a Matcher
+// are found by the start of any word of their name, and those the combatant
+// can't see aren't found at all.
+func (e *Engine) Find(c Combatant, keyword string) Combatant {
+	e.mutex.RLock()
+	occupants, visible := e.occupants, e.visible
+	e.mutex.RUnlock()
+
+	if occupants == nil {
+		return nil
+	}
+	n, keyword := item.Ordinal(keyword)
+	keyword = strings.ToLower(keyword)
+	for _, other := range occupants(c.Location()) {
+		if other.ID() == c.ID() || (visible != nil && !visible(c, other)) {
+			continue
+		}
+		matched := false
+		if m, ok := other.(Matcher); ok {
+			matched = m.Matches(keyword)
+		} else {
+			for _, word := range strings.Fields(strings.ToLower(other.Name())) {
+				matched = matched || strings.HasPrefix(word, keyword)
+			}
+		}
+		if matched {
+			n--
+			if n == 0 {
+				return other
+			}
+		}
+	}
+
+	return nil
+}
+
+// handler resolves the caller's combatant for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Combatant], fn func(*command.Context, Combatant) error) command.Handler {
+	return func(ctx *command.Context) error {
+		c := resolve(ctx.Caller)
+		if c == nil {
+			return ctx.Send("You can't fight.")
+		}
+
+		err := fn(ctx, c)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
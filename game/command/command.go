@@ -0,0 +1,367 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package command turns what players type into game actions. Commands are
+// kept in a Registry by name, alias and abbreviation, player input is parsed
+// into the command word and its arguments, and a Dispatcher runs the command
+// found, emitting an event for it so scripts can react to or handle it.
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Level is the permission level needed to use a command, callers can use
+// commands at or below their own level.
+type Level int
+
+// Permission levels
+const (
+	// Player commands can be used by anyone.
+	Player Level = iota
+	// Builder commands change the world, like creating rooms.
+	Builder
+	// Staff commands look after players, like moving them somewhere.
+	Staff
+	// Admin commands manage the game and its players.
+	Admin
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case Player:
+		return "player"
+	case Builder:
+		return "builder"
+	case Staff:
+		return "staff"
+	case Admin:
+		return "admin"
+	default:
+		return fmt.Sprintf("level %d", int(l))
+	}
+}
+
+// ParseLevel returns the level with the name, in any case. No name is the
+// player level.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "player":
+		return Player, nil
+	case "builder":
+		return Builder, nil
+	case "staff":
+		return Staff, nil
+	case "admin":
+		return Admin, nil
+	}
+
+	return Player, fmt.Errorf("level must be player, builder, staff or admin, not %q", name)
+}
+
+// ArgKind is the kind of value an argument takes.
+type ArgKind int
+
+// Argument kinds
+const (
+	// Word arguments take a single word, or a quoted phrase.
+	Word ArgKind = iota
+	// Number arguments take a whole number.
+	Number
+	// Text arguments take the rest of the input as it was typed, they must
+	// come last.
+	Text
+)
+
+// Arg describes an argument a command takes.
+type Arg struct {
+	// Name is how the argument is looked up by the handler and shown in the
+	// command's usage.
+	Name string
+	// Kind is the kind of value the argument takes.
+	Kind ArgKind
+	// Optional arguments can be left out, only arguments after the required
+	// ones should be optional.
+	Optional bool
+}
+
+// Handler runs a command.
+type Handler func(ctx *Context) error
+
+// Command is something players can type.
+type Command struct {
+	// Name is what the player types, names are matched without regard to
+	// case.
+	Name string
+	// Aliases are other names for the command, like "l" for "look".
+	Aliases []string
+	// MinAbbrev is the fewest letters of the name that can be typed for the
+	// command, like 3 for "inv" to mean "inventory". Zero requires the full
+	// name.
+	MinAbbrev int
+	// Args are the arguments the command takes, in order. Input that doesn't
+	// fit them isn't dispatched and the player is shown the usage. Commands
+	// without any are given the input as it was typed.
+	Args []Arg
+	// Level is the permission level needed to use the command, the command
+	// can't be found by callers below it.
+	Level Level
+	// Capability is what callers must be able to do to use the command, on
+	// top of its level, like "force". Empty needs none, and callers that
+	// aren't Capable are only held to the level.
+	Capability string
+	// Lag is the number of pulses a caller waits after the command before
+	// their next command runs, like 2 for a bash in combat. Commands typed
+	// while waiting are queued.
+	Lag int
+	// Help describes the command for the player.
+	Help string
+	// Handler runs the command, it may be nil for commands handled entirely
+	// by listening for their event.
+	Handler Handler
+	// Source is what registered the command, like a plugin's name.
+	Source string
+}
+
+// Usage describes how the command is typed, like "give <item> [count]".
+func (c *Command) Usage() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Kind == Text {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Permits is true if the caller can use the command, they're at or above
+// its level and have its capability.
+func (c *Command) Permits(caller Caller) bool {
+	if caller.Level() < c.Level {
+		return false
+	}
+	if c.Capability == "" {
+		return true
+	}
+	if cc, ok := caller.(Capable); ok {
+		return cc.Can(c.Capability)
+	}
+
+	return true
+}
+
+// matches is true if the word typed is the command's name, one of its
+// aliases or an abbreviation of its name
+func (c *Command) matches(word string) bool {
+	name := strings.ToLower(c.Name)
+	if word == name {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if word == strings.ToLower(alias) {
+			return true
+		}
+	}
+
+	return c.MinAbbrev > 0 && len(word) >= c.MinAbbrev && strings.HasPrefix(name, word)
+}
+
+// ConflictError is returned when a command is registered with a name or
+// alias another command already uses.
+type ConflictError struct {
+	// Name is the name or alias in conflict.
+	Name string
+	// Existing is the command already using it.
+	Existing *Command
+}
+
+// Error describes the conflict.
+func (e *ConflictError) Error() string {
+	source := ""
+	if e.Existing.Source != "" {
+		source = fmt.Sprintf(" from %s", e.Existing.Source)
+	}
+
+	return fmt.Sprintf("%q is already used by the %q command%s", e.Name, e.Existing.Name, source)
+}
+
+// Registry holds the commands players can use. Words are matched to the
+// command with that name first, then alias, then to the first command
+// registered that it abbreviates. It's safe to use from multiple goroutines.
+type Registry struct {
+	commands []*Command
+	names    map[string]*Command
+	mutex    *sync.RWMutex
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]*Command),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+var (
+	globalRegistry *Registry
+	globalOnce     sync.Once
+)
+
+// Global returns the registry of the game's commands, it starts with the help
+// command.
+func Global() *Registry {
+	globalOnce.Do(func() {
+		globalRegistry = NewRegistry()
+		globalRegistry.Register(NewHelp(globalRegistry))
+	})
+
+	return globalRegistry
+}
+
+// Register adds the command, a *ConflictError is returned if its name or one
+// of its aliases is already used.
+func (r *Registry) Register(c *Command) error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("commands must have a name")
+	}
+	for i, arg := range c.Args {
+		if arg.Kind == Text && i != len(c.Args)-1 {
+			return fmt.Errorf("text argument %q of %q must be the last argument", arg.Name, c.Name)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	words := append([]string{c.Name}, c.Aliases...)
+	for _, word := range words {
+		if existing, ok := r.names[strings.ToLower(word)]; ok {
+			return &ConflictError{Name: word, Existing: existing}
+		}
+	}
+	for _, word := range words {
+		r.names[strings.ToLower(word)] = c
+	}
+	r.commands = append(r.commands, c)
+
+	return nil
+}
+
+// Unregister removes the command with the name, returning false if there
+// isn't one.
+func (r *Registry) Unregister(name string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.names[strings.ToLower(name)]
+	if !ok || !strings.EqualFold(c.Name, name) {
+		return false
+	}
+	for word, named := range r.names {
+		if named == c {
+			delete(r.names, word)
+		}
+	}
+	for i, registered := range r.commands {
+		if registered == c {
+			r.commands = append(r.commands[:i], r.commands[i+1:]...)
+
+			break
+		}
+	}
+
+	return true
+}
+
+// Get returns the command with the name or alias given, without matching
+// abbreviations.
+func (r *Registry) Get(name string) (*Command, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	c, ok := r.names[strings.ToLower(name)]
+
+	return c, ok
+}
+
+// Find returns the command a caller at the level means by the word typed.
+// Commands above the level aren't found.
+func (r *Registry) Find(word string, level Level) (*Command, bool) {
+	return r.find(word, func(c *Command) bool {
+		return c.Level <= level
+	})
+}
+
+// FindFor returns the command the caller means by the word typed, only
+// commands it permits them to use are found.
+func (r *Registry) FindFor(word string, caller Caller) (*Command, bool) {
+	return r.find(word, func(c *Command) bool {
+		return c.Permits(caller)
+	})
+}
+
+// find returns the first command the word means that usable is true for
+func (r *Registry) find(word string, usable func(*Command) bool) (*Command, bool) {
+	word = strings.ToLower(word)
+	if word == "" {
+		return nil, false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if c, ok := r.names[word]; ok && usable(c) {
+		return c, true
+	}
+	for _, c := range r.commands {
+		if usable(c) && c.matches(word) {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// Commands returns the commands a caller at the level can use, sorted by
+// name.
+func (r *Registry) Commands(level Level) []*Command {
+	return r.list(func(c *Command) bool {
+		return c.Level <= level
+	})
+}
+
+// CommandsFor returns the commands the caller can use, sorted by name.
+func (r *Registry) CommandsFor(caller Caller) []*Command {
+	return r.list(func(c *Command) bool {
+		return c.Permits(caller)
+	})
+}
+
+// list returns the commands usable is true for, sorted by name
+func (r *Registry) list(usable func(*Command) bool) []*Command {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var commands []*Command
+	for _, c := range r.commands {
+		if usable(c) {
+			commands = append(commands, c)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+
+	return commands
+}
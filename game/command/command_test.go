@@ -0,0 +1,286 @@
+package command_test
+
+import (
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// caller records what it's sent
+type caller struct {
+	level command.Level
+	sent  []string
+}
+
+func (c *caller) ID() string {
+	return "caller-1"
+}
+
+func (c *caller) Level() command.Level {
+	return c.level
+}
+
+func (c *caller) Send(text string) error {
+	c.sent = append(c.sent, text)
+
+	return nil
+}
+
+// staffer is a caller with capabilities
+type staffer struct {
+	caller
+	can map[string]bool
+}
+
+func (s *staffer) Can(capability string) bool {
+	return s.can[capability]
+}
+
+var _ = Describe("Parse", func() {
+	It("splits the command from its words", func() {
+		in := command.Parse("  get   \"long sword\" chest ")
+
+		Ω(in.Line).Should(Equal("get   \"long sword\" chest"))
+		Ω(in.Command).Should(Equal("get"))
+		Ω(in.Rest).Should(Equal("\"long sword\" chest"))
+		Ω(in.Words).Should(Equal([]string{"long sword", "chest"}))
+	})
+
+	It("treats a leading symbol as the command", func() {
+		in := command.Parse("'hello there")
+
+		Ω(in.Command).Should(Equal("'"))
+		Ω(in.Words).Should(Equal([]string{"hello", "there"}))
+	})
+
+	It("handles blank input", func() {
+		Ω(command.Parse("   ").Command).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("Registry", func() {
+	var (
+		registry  *command.Registry
+		inventory *command.Command
+		shutdown  *command.Command
+	)
+
+	BeforeEach(func() {
+		registry = command.NewRegistry()
+		inventory = &command.Command{Name: "inventory", Aliases: []string{"i"}, MinAbbrev: 3}
+		shutdown = &command.Command{Name: "shutdown", Level: command.Admin}
+		Ω(registry.Register(inventory)).Should(Succeed())
+		Ω(registry.Register(shutdown)).Should(Succeed())
+	})
+
+	It("finds commands by name, alias and abbreviation", func() {
+		for _, word := range []string{"INVENTORY", "i", "inv", "invent"} {
+			c, ok := registry.Find(word, command.Player)
+			Ω(ok).Should(BeTrue(), word)
+			Ω(c).Should(Equal(inventory))
+		}
+
+		_, ok := registry.Find("in", command.Player)
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("hides commands above the caller's level", func() {
+		_, ok := registry.Find("shutdown", command.Player)
+		Ω(ok).Should(BeFalse())
+		_, ok = registry.Find("shutdown", command.Admin)
+		Ω(ok).Should(BeTrue())
+		Ω(registry.Commands(command.Player)).Should(Equal([]*command.Command{inventory}))
+	})
+
+	It("refuses names already in use", func() {
+		err := registry.Register(&command.Command{Name: "items", Aliases: []string{"I"}, Source: "shop"})
+
+		Ω(err).Should(BeAssignableToTypeOf(&command.ConflictError{}))
+		Ω(err.(*command.ConflictError).Existing).Should(Equal(inventory))
+		_, ok := registry.Get("items")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("removes commands", func() {
+		Ω(registry.Unregister("i")).Should(BeFalse())
+		Ω(registry.Unregister("Inventory")).Should(BeTrue())
+
+		_, ok := registry.Find("i", command.Player)
+		Ω(ok).Should(BeFalse())
+		Ω(registry.Register(&command.Command{Name: "i"})).Should(Succeed())
+	})
+})
+
+var _ = Describe("Command", func() {
+	give := &command.Command{
+		Name: "give",
+		Args: []command.Arg{
+			{Name: "item"},
+			{Name: "count", Kind: command.Number, Optional: true},
+			{Name: "message", Kind: command.Text, Optional: true},
+		},
+	}
+
+	It("describes its usage", func() {
+		Ω(give.Usage()).Should(Equal("give <item> [count] [message...]"))
+	})
+
+	It("binds input to its arguments", func() {
+		args, err := give.Bind(command.Parse("give \"gold coin\" 3 for  your trouble"))
+
+		Ω(err).Should(BeNil())
+		Ω(args).Should(Equal(map[string]interface{}{
+			"item":    "gold coin",
+			"count":   3,
+			"message": "for  your trouble",
+		}))
+	})
+
+	It("skips optional numbers that aren't given", func() {
+		args, err := give.Bind(command.Parse("give sword thanks"))
+
+		Ω(err).Should(BeNil())
+		Ω(args).ShouldNot(HaveKey("count"))
+		Ω(args).Should(HaveKeyWithValue("message", "thanks"))
+	})
+
+	It("fails for missing or extra arguments", func() {
+		_, err := give.Bind(command.Parse("give"))
+		Ω(err).Should(MatchError("What item?\nUsage: give <item> [count] [message...]"))
+
+		look := &command.Command{Name: "look", Args: []command.Arg{{Name: "target", Optional: true}}}
+		_, err = look.Bind(command.Parse("look at me"))
+		Ω(err).Should(BeAssignableToTypeOf(&command.UsageError{}))
+	})
+})
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		registry   *command.Registry
+		dispatcher *command.Dispatcher
+		em         *events.Emitter
+		c          *caller
+		received   chan events.Data
+	)
+
+	BeforeEach(func() {
+		registry = command.NewRegistry()
+		em = events.NewEmitter(nil)
+		received = make(chan events.Data, 10)
+		dispatcher = command.NewDispatcher(registry, em)
+		c = &caller{}
+	})
+
+	listen := func(evt string) {
+		em.On(evt, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+	}
+
+	It("runs the handler and emits the command's event", func() {
+		var ctx *command.Context
+		registry.Register(&command.Command{
+			Name: "Say",
+			Args: []command.Arg{{Name: "message", Kind: command.Text}},
+			Handler: func(c *command.Context) error {
+				ctx = c
+
+				return c.Send("You say, '" + c.String("message") + "'")
+			},
+		})
+		listen("command:say")
+
+		Ω(dispatcher.Dispatch(c, "say hello there")).Should(Succeed())
+		Ω(ctx.Command.Name).Should(Equal("Say"))
+		Ω(c.sent).Should(Equal([]string{"You say, 'hello there'"}))
+
+		var d events.Data
+		Eventually(received).Should(Receive(&d))
+		Ω(d["caller"]).Should(Equal("caller-1"))
+		Ω(d["args"]).Should(HaveKeyWithValue("message", "hello there"))
+	})
+
+	It("describes commands with help", func() {
+		registry.Register(command.NewHelp(registry))
+		registry.Register(&command.Command{Name: "look", Aliases: []string{"l"}, Help: "Looks around."})
+		registry.Register(&command.Command{Name: "shutdown", Level: command.Admin})
+
+		dispatcher.Dispatch(c, "help")
+		dispatcher.Dispatch(c, "? l")
+		dispatcher.Dispatch(c, "help shutdown")
+
+		Ω(c.sent).Should(Equal([]string{
+			"Commands: help, look",
+			"Usage: look\nAliases: l\n\nLooks around.",
+			"There is no help for \"shutdown\".",
+		}))
+	})
+
+	It("runs commands needing a capability only for capable callers with it", func() {
+		ran := 0
+		registry.Register(&command.Command{
+			Name:       "force",
+			Level:      command.Staff,
+			Capability: "force",
+			Handler: func(*command.Context) error {
+				ran++
+
+				return nil
+			},
+		})
+		s := &staffer{caller: caller{level: command.Staff}, can: map[string]bool{"goto": true}}
+
+		Ω(dispatcher.Dispatch(s, "force")).Should(Equal(command.ErrUnknown))
+		Ω(dispatcher.Dispatch(&caller{level: command.Builder}, "force")).Should(Equal(command.ErrUnknown))
+		s.can["force"] = true
+		Ω(dispatcher.Dispatch(s, "force")).Should(Succeed())
+		Ω(dispatcher.Dispatch(&caller{level: command.Staff}, "force")).Should(Succeed())
+		Ω(ran).Should(Equal(2))
+	})
+
+	It("tells the caller about input it can't run", func() {
+		registry.Register(&command.Command{Name: "get", Args: []command.Arg{{Name: "item"}}})
+		listen(command.UnknownEvent)
+
+		Ω(dispatcher.Dispatch(c, "dance")).Should(Equal(command.ErrUnknown))
+		Ω(dispatcher.Dispatch(c, "get")).Should(BeAssignableToTypeOf(&command.UsageError{}))
+		Ω(dispatcher.Dispatch(c, "")).Should(Succeed())
+		Ω(c.sent).Should(Equal([]string{command.UnknownMessage, "What item?\nUsage: get <item>"}))
+		Eventually(received).Should(Receive())
+	})
+	It("routes input to the caller's mode until it's finished", func() {
+		registry.Register(&command.Command{Name: "look"})
+		m := &lineMode{}
+		dispatcher.Enter(c, m)
+
+		Ω(dispatcher.Dispatch(c, "look")).Should(Succeed())
+		Ω(dispatcher.Dispatch(c, "done")).Should(Succeed())
+		Ω(dispatcher.Mode(c.ID())).Should(BeNil())
+		Ω(dispatcher.Dispatch(c, "dance")).Should(Equal(command.ErrUnknown))
+		Ω(m.lines).Should(Equal([]string{"look", "done"}))
+	})
+
+	It("leaves modes", func() {
+		m := &lineMode{}
+		dispatcher.Enter(c, m)
+
+		Ω(dispatcher.Leave(c.ID())).Should(Equal(m))
+		Ω(dispatcher.Dispatch(c, "look")).Should(Equal(command.ErrUnknown))
+		Ω(m.lines).Should(BeEmpty())
+	})
+})
+
+// lineMode records its input until "done" is typed
+type lineMode struct {
+	lines []string
+}
+
+func (m *lineMode) Input(_ command.Caller, line string) bool {
+	m.lines = append(m.lines, line)
+
+	return line == "done"
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package command
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+)
+
+// Events emitted by a Dispatcher. Each carries the caller's id, the command
+// word typed and the line of input.
+const (
+	// EventPrefix begins the event emitted for each command run, followed by
+	// the command's name in lower case, like "command:look". It includes the
+	// arguments given.
+	EventPrefix = "command:"
+	// UnknownEvent is emitted for input that doesn't match a command.
+	UnknownEvent = "command:unknown"
+)
+
+// UnknownMessage is shown to callers whose input doesn't match a command.
+const UnknownMessage = "Huh?"
+
+// ErrUnknown is returned by Dispatch for input that doesn't match a command.
+var ErrUnknown = errors.New("unknown command")
+
+// Caller is whoever runs a command, usually a player.
+type Caller interface {
+	// ID identifies the caller in events, like the id of their session.
+	ID() string
+	// Level is the caller's permission level.
+	Level() Level
+	// Send shows the caller text, it may contain color codes.
+	Send(text string) error
+}
+
+// Resolver finds the T a command caller controls, like the character they're
+// playing, returning nil if they aren't controlling one. Game packages take
+// one to run their commands without knowing how callers map to characters.
+type Resolver[T any] func(Caller) T
+
+// Capable callers have capabilities, like "goto", that commands can require
+// beyond a level.
+type Capable interface {
+	// Can is true if the caller has the capability.
+	Can(capability string) bool
+}
+
+// Context is what a handler knows about the command being run.
+type Context struct {
+	// Caller is who ran the command.
+	Caller Caller
+	// Command is the command being run.
+	Command *Command
+	// Input is the line typed.
+	Input Input
+	// Args holds the value of each argument given, by name.
+	Args map[string]interface{}
+	// Lag is the number of pulses the caller waits before their next command
+	// runs, it starts as the command's Lag and handlers can change it.
+	Lag int
+	// Dispatcher is running the command, handlers can use it to put the
+	// caller into a mode.
+	Dispatcher *Dispatcher
+}
+
+// Send shows the caller text.
+func (ctx *Context) Send(text string) error {
+	return ctx.Caller.Send(text)
+}
+
+// Has is true if the argument was given.
+func (ctx *Context) Has(name string) bool {
+	_, ok := ctx.Args[name]
+
+	return ok
+}
+
+// String returns the value of a Word or Text argument, empty if it wasn't
+// given.
+func (ctx *Context) String(name string) string {
+	s, _ := ctx.Args[name].(string)
+
+	return s
+}
+
+// Int returns the value of a Number argument, zero if it wasn't given.
+func (ctx *Context) Int(name string) int {
+	n, _ := ctx.Args[name].(int)
+
+	return n
+}
+
+// Mode takes over a caller's input in place of commands, like an editor the
+// caller is writing in.
+type Mode interface {
+	// Input handles a line typed by the caller, returning true once the mode
+	// is finished and the caller is back to typing commands.
+	Input(caller Caller, line string) bool
+}
+
+// Dispatcher runs the commands typed by callers.
+type Dispatcher struct {
+	registry *Registry
+	emitter  *events.Emitter
+	modes    map[string]Mode
+	mutex    *sync.RWMutex
+}
+
+// NewDispatcher creates a dispatcher running commands from the registry and
+// emitting events to em, which may be nil if nothing is listening.
+func NewDispatcher(r *Registry, em *events.Emitter) *Dispatcher {
+	return &Dispatcher{
+		registry: r,
+		emitter:  em,
+		modes:    make(map[string]Mode),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// SetEmitter sets the emitter command events are emitted to.
+func (d *Dispatcher) SetEmitter(em *events.Emitter) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.emitter = em
+}
+
+// Enter routes the caller's input to the mode until it's finished or Leave is
+// called, replacing any mode they were in.
+func (d *Dispatcher) Enter(caller Caller, m Mode) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.modes[caller.ID()] = m
+}
+
+// Leave returns the caller with the id to typing commands, returning the mode
+// they were in, if any.
+func (d *Dispatcher) Leave(id string) Mode {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	m := d.modes[id]
+	delete(d.modes, id)
+
+	return m
+}
+
+// Mode returns the mode the caller with the id is in, nil if they're typing
+// commands.
+func (d *Dispatcher) Mode(id string) Mode {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.modes[id]
+}
+
+// Dispatch runs the command the caller typed, returning the handler's error.
+// Callers are told when their input doesn't match a command, ErrUnknown is
+// returned, or doesn't fit its arguments, a *UsageError is returned. Blank
+// lines are ignored. Input from callers in a mode goes to the mode instead.
+func (d *Dispatcher) Dispatch(caller Caller, line string) error {
+	_, err := d.dispatch(caller, line)
+
+	return err
+}
+
+// dispatch runs the command, returning its context if it was found and the
+// input fit its arguments
+func (d *Dispatcher) dispatch(caller Caller, line string) (*Context, error) {
+	id := caller.ID()
+	if m := d.Mode(id); m != nil {
+		if m.Input(caller, line) {
+			d.leave(id, m)
+		}
+
+		return nil, nil
+	}
+
+	in := Parse(line)
+	if in.Command == "" {
+		return nil, nil
+	}
+
+	data := events.Data{
+		"caller":  caller.ID(),
+		"command": in.Command,
+		"input":   in.Line,
+	}
+	c, ok := d.registry.FindFor(in.Command, caller)
+	if !ok {
+		d.emit(UnknownEvent, data)
+		caller.Send(UnknownMessage)
+
+		return nil, ErrUnknown
+	}
+
+	args, err := c.Bind(in)
+	if err != nil {
+		caller.Send(err.Error())
+
+		return nil, err
+	}
+
+	data["args"] = args
+	d.emit(EventPrefix+strings.ToLower(c.Name), data)
+	ctx := &Context{
+		Caller:     caller,
+		Command:    c,
+		Input:      in,
+		Args:       args,
+		Lag:        c.Lag,
+		Dispatcher: d,
+	}
+	if c.Handler == nil {
+		return ctx, nil
+	}
+
+	return ctx, c.Handler(ctx)
+}
+
+// leave ends the caller's mode if it hasn't been replaced since
+func (d *Dispatcher) leave(id string, m Mode) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.modes[id] == m {
+		delete(d.modes, id)
+	}
+}
+
+func (d *Dispatcher) emit(evt string, data events.Data) {
+	d.mutex.RLock()
+	em := d.emitter
+	d.mutex.RUnlock()
+
+	if em != nil {
+		em.Emit(evt, data)
+	}
+}
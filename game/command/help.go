@@ -0,0 +1,45 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewHelp creates the help command for the registry. Given nothing it lists
+// the commands the caller can use, given a command it shows how the command
+// is used and its help, so commands are documented as they're registered.
+func NewHelp(r *Registry) *Command {
+	return &Command{
+		Name:    "help",
+		Aliases: []string{"?"},
+		Args:    []Arg{{Name: "command", Optional: true}},
+		Help:    "Lists the commands you can use, or describes the command given.",
+		Source:  "game",
+		Handler: func(ctx *Context) error {
+			if !ctx.Has("command") {
+				var names []string
+				for _, c := range r.CommandsFor(ctx.Caller) {
+					names = append(names, c.Name)
+				}
+
+				return ctx.Send("Commands: " + strings.Join(names, ", "))
+			}
+
+			c, ok := r.FindFor(ctx.String("command"), ctx.Caller)
+			if !ok {
+				return ctx.Send(fmt.Sprintf("There is no help for %q.", ctx.String("command")))
+			}
+			text := "Usage: " + c.Usage()
+			if len(c.Aliases) > 0 {
+				text += "\nAliases: " + strings.Join(c.Aliases, ", ")
+			}
+			if c.Help != "" {
+				text += "\n\n" + c.Help
+			}
+
+			return ctx.Send(text)
+		},
+	}
+}
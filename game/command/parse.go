@@ -0,0 +1,155 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Input is a line typed by a player, split into the command word and its
+// arguments.
+type Input struct {
+	// Line is the input as it was typed, without surrounding space.
+	Line string
+	// Command is the first word of the line. A line starting with a symbol,
+	// like "'hello", has the symbol as its command so it can be an alias.
+	Command string
+	// Rest is the text following the command word.
+	Rest string
+	// Words are the words of Rest, phrases in double quotes are kept as a
+	// single word without the quotes.
+	Words []string
+
+	// where each word ends in Rest
+	ends []int
+}
+
+// Parse splits a line of input into words.
+func Parse(line string) Input {
+	line = strings.TrimSpace(line)
+	in := Input{Line: line}
+	if line == "" {
+		return in
+	}
+
+	r, size := utf8.DecodeRuneInString(line)
+	var end int
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		end = strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+	} else {
+		end = size
+	}
+	in.Command = line[:end]
+	in.Rest = strings.TrimSpace(line[end:])
+	in.Words, in.ends = split(in.Rest)
+
+	return in
+}
+
+// split breaks text into words, returning the words and where each ends
+func split(text string) ([]string, []int) {
+	var (
+		words  []string
+		ends   []int
+		word   []rune
+		inWord bool
+		quoted bool
+	)
+	for i, r := range text {
+		switch {
+		case r == '"':
+			if quoted {
+				words, ends = append(words, string(word)), append(ends, i+1)
+				word, inWord, quoted = nil, false, false
+			} else if !inWord {
+				inWord, quoted = true, true
+			} else {
+				word = append(word, r)
+			}
+		case unicode.IsSpace(r) && !quoted:
+			if inWord {
+				words, ends = append(words, string(word)), append(ends, i)
+				word, inWord = nil, false
+			}
+		default:
+			word = append(word, r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words, ends = append(words, string(word)), append(ends, len(text))
+	}
+
+	return words, ends
+}
+
+// UsageError is returned when input doesn't fit the arguments of the command,
+// it's shown to the player.
+type UsageError struct {
+	// Command is the command typed.
+	Command *Command
+	// Problem says what was wrong with the input.
+	Problem string
+}
+
+// Error describes the problem and how the command is used.
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s\nUsage: %s", e.Problem, e.Command.Usage())
+}
+
+// Bind matches the words of the input to the command's arguments, returning
+// the value of each argument given by name. Word and Text arguments are
+// strings, Number arguments are ints. Commands without arguments accept any
+// input, leaving it to the handler.
+func (c *Command) Bind(in Input) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if len(c.Args) == 0 {
+		return args, nil
+	}
+	next := 0
+	for _, arg := range c.Args {
+		if next >= len(in.Words) {
+			if !arg.Optional {
+				return nil, &UsageError{Command: c, Problem: fmt.Sprintf("What %s?", arg.Name)}
+			}
+
+			continue
+		}
+
+		switch arg.Kind {
+		case Text:
+			start := 0
+			if next > 0 {
+				start = in.ends[next-1]
+			}
+			args[arg.Name] = strings.TrimSpace(in.Rest[start:])
+			next = len(in.Words)
+		case Number:
+			n, err := strconv.Atoi(in.Words[next])
+			if err != nil {
+				if arg.Optional {
+					continue
+				}
+
+				return nil, &UsageError{Command: c, Problem: fmt.Sprintf("%s must be a number.", strings.Title(arg.Name))}
+			}
+			args[arg.Name] = n
+			next++
+		default:
+			args[arg.Name] = in.Words[next]
+			next++
+		}
+	}
+	if next < len(in.Words) {
+		return nil, &UsageError{Command: c, Problem: fmt.Sprintf("Too many arguments for %s.", c.Name)}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package command
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// QueueFullMessage is shown to callers who type more commands than their
+// queue holds.
+const QueueFullMessage = "You can't do that many things at once!"
+
+// ErrQueueFull is returned by Push when the caller's queue is full.
+var ErrQueueFull = errors.New("command queue is full")
+
+// Queue paces the commands of a single caller. Commands run as they're pushed
+// unless the caller is waiting out the lag of an earlier command, then
+// they're queued and run one per pulse once the lag passes. Spamming commands
+// can't make a caller act faster than the pulse allows.
+type Queue struct {
+	dispatcher *Dispatcher
+	caller     Caller
+	limit      int
+	lines      []string
+	wait       int
+	busy       bool
+	mutex      *sync.Mutex
+}
+
+// NewQueue creates a queue running the caller's commands with the dispatcher,
+// holding up to limit commands while the caller waits. A limit of zero holds
+// any number.
+func NewQueue(d *Dispatcher, caller Caller, limit int) *Queue {
+	return &Queue{
+		dispatcher: d,
+		caller:     caller,
+		limit:      limit,
+		mutex:      new(sync.Mutex),
+	}
+}
+
+// Push runs the line now if the caller isn't waiting, otherwise it's queued.
+// ErrQueueFull is returned, and the caller told, if the queue is full.
+func (q *Queue) Push(line string) error {
+	q.mutex.Lock()
+	if q.wait > 0 || q.busy || len(q.lines) > 0 {
+		if q.limit > 0 && len(q.lines) >= q.limit {
+			q.mutex.Unlock()
+			q.caller.Send(QueueFullMessage)
+
+			return ErrQueueFull
+		}
+		q.lines = append(q.lines, line)
+		q.mutex.Unlock()
+
+		return nil
+	}
+	q.busy = true
+	q.mutex.Unlock()
+
+	q.run(line)
+
+	return nil
+}
+
+// Pulse counts down the caller's lag, running the next queued command once
+// it has passed.
+func (q *Queue) Pulse() {
+	q.mutex.Lock()
+	if q.wait > 0 {
+		q.wait--
+	}
+	if q.wait > 0 || q.busy || len(q.lines) == 0 {
+		q.mutex.Unlock()
+
+		return
+	}
+	line := q.lines[0]
+	q.lines = q.lines[1:]
+	q.busy = true
+	q.mutex.Unlock()
+
+	q.run(line)
+}
+
+// Wait adds to the pulses the caller waits before their next command, like
+// when they're knocked down in combat.
+func (q *Queue) Wait(pulses int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.wait += pulses
+}
+
+// Waiting returns the pulses left before the caller's next command runs.
+func (q *Queue) Waiting() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.wait
+}
+
+// Len returns the number of commands queued.
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.lines)
+}
+
+// Clear discards the queued commands, returning how many there were.
+func (q *Queue) Clear() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	n := len(q.lines)
+	q.lines = nil
+
+	return n
+}
+
+// run dispatches the line, the caller then waits out the command's lag
+func (q *Queue) run(line string) {
+	ctx, _ := q.dispatcher.dispatch(q.caller, line)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.busy = false
+	if ctx != nil && ctx.Lag > 0 {
+		q.wait += ctx.Lag
+	}
+}
+
+// Pacer keeps a queue for each caller and pulses them together, so every
+// caller's lag passes at the same rate.
+type Pacer struct {
+	dispatcher *Dispatcher
+	limit      int
+	queues     map[string]*Queue
+	mutex      *sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewPacer creates a pacer whose queues run commands with the dispatcher and
+// hold up to limit commands each.
+func NewPacer(d *Dispatcher, limit int) *Pacer {
+	return &Pacer{
+		dispatcher: d,
+		limit:      limit,
+		queues:     make(map[string]*Queue),
+		mutex:      new(sync.Mutex),
+	}
+}
+
+var (
+	globalPacer     *Pacer
+	globalPacerOnce sync.Once
+)
+
+// GlobalPacer returns the pacer for the game's players, it dispatches the
+// commands of the global registry and queues up to input.queue_limit
+// commands for each player. Events aren't emitted until the dispatcher is
+// given an emitter.
+func GlobalPacer() *Pacer {
+	globalPacerOnce.Do(func() {
+		d := NewDispatcher(Global(), nil)
+		globalPacer = NewPacer(d, viper.GetInt("input.queue_limit"))
+	})
+
+	return globalPacer
+}
+
+// Dispatcher returns the dispatcher the queues run commands with.
+func (p *Pacer) Dispatcher() *Dispatcher {
+	return p.dispatcher
+}
+
+// Queue returns the caller's queue, creating it if the caller doesn't have
+// one.
+func (p *Pacer) Queue(caller Caller) *Queue {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	q, ok := p.queues[caller.ID()]
+	if !ok {
+		q = NewQueue(p.dispatcher, caller, p.limit)
+		p.queues[caller.ID()] = q
+	}
+
+	return q
+}
+
+// Remove discards the queue of the caller with the id, and any mode they're
+// in, such as when they leave the game.
+func (p *Pacer) Remove(id string) {
+	p.dispatcher.Leave(id)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.queues, id)
+}
+
+// Pulse pulses every queue.
+func (p *Pacer) Pulse() {
+	p.mutex.Lock()
+	queues := make([]*Queue, 0, len(p.queues))
+	for _, q := range p.queues {
+		queues = append(queues, q)
+	}
+	p.mutex.Unlock()
+
+	for _, q := range queues {
+		q.Pulse()
+	}
+}
+
+// Start pulses the queues from the background at the interval given, until
+// Stop is called.
+func (p *Pacer) Start(interval time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(interval, p.stop, p.done)
+}
+
+// Stop stops pulsing the queues, commands left in them stay queued.
+func (p *Pacer) Stop() {
+	p.mutex.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (p *Pacer) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.Pulse()
+		}
+	}
+}
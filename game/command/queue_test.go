@@ -0,0 +1,110 @@
+package command_test
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/command"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		registry *command.Registry
+		c        *caller
+		q        *command.Queue
+		ran      []string
+	)
+
+	BeforeEach(func() {
+		ran = nil
+		registry = command.NewRegistry()
+		record := func(ctx *command.Context) error {
+			ran = append(ran, ctx.Input.Line)
+
+			return nil
+		}
+		registry.Register(&command.Command{Name: "bash", Lag: 2, Handler: record})
+		registry.Register(&command.Command{Name: "look", Handler: record})
+		registry.Register(&command.Command{Name: "trip", Handler: func(ctx *command.Context) error {
+			ctx.Lag = 1
+
+			return record(ctx)
+		}})
+		c = &caller{}
+		q = command.NewQueue(command.NewDispatcher(registry, nil), c, 2)
+	})
+
+	It("runs commands right away when the caller isn't waiting", func() {
+		q.Push("look")
+		q.Push("look")
+
+		Ω(ran).Should(Equal([]string{"look", "look"}))
+		Ω(q.Len()).Should(BeZero())
+	})
+
+	It("queues commands until the lag passes", func() {
+		q.Push("bash")
+		q.Push("look")
+
+		Ω(ran).Should(Equal([]string{"bash"}))
+		Ω(q.Waiting()).Should(Equal(2))
+		Ω(q.Len()).Should(Equal(1))
+
+		q.Pulse()
+		Ω(ran).Should(HaveLen(1))
+		q.Pulse()
+		Ω(ran).Should(Equal([]string{"bash", "look"}))
+	})
+
+	It("runs one queued command each pulse", func() {
+		q.Wait(1)
+		q.Push("look")
+		q.Push("trip")
+		q.Pulse()
+
+		Ω(ran).Should(Equal([]string{"look"}))
+		q.Pulse()
+		Ω(ran).Should(Equal([]string{"look", "trip"}))
+		Ω(q.Waiting()).Should(Equal(1))
+	})
+
+	It("refuses commands past its limit", func() {
+		q.Push("bash")
+		q.Push("look")
+		q.Push("look")
+
+		Ω(q.Push("look")).Should(Equal(command.ErrQueueFull))
+		Ω(c.sent).Should(Equal([]string{command.QueueFullMessage}))
+		Ω(q.Clear()).Should(Equal(2))
+	})
+})
+
+var _ = Describe("Pacer", func() {
+	It("keeps a queue for each caller", func() {
+		registry := command.NewRegistry()
+		count := 0
+		registry.Register(&command.Command{Name: "bash", Lag: 1, Handler: func(*command.Context) error {
+			count++
+
+			return nil
+		}})
+		pacer := command.NewPacer(command.NewDispatcher(registry, nil), 0)
+		c := &caller{}
+
+		q := pacer.Queue(c)
+		Ω(pacer.Queue(c)).Should(BeIdenticalTo(q))
+
+		q.Push("bash")
+		q.Push("bash")
+		Ω(count).Should(Equal(1))
+
+		pacer.Start(time.Millisecond)
+		defer pacer.Stop()
+		Eventually(func() int { return q.Len() }).Should(BeZero())
+
+		pacer.Remove(c.ID())
+		Ω(pacer.Queue(c)).ShouldNot(BeIdenticalTo(q))
+	})
+})
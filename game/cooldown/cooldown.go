@@ -0,0 +1,216 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package cooldown tracks when keyed actions, like a player's use of a skill,
+// can be performed again.
+package cooldown
+
+import (
+	"sync"
+	"time"
+)
+
+// ChangeHook is called whenever a cooldown is set or cleared, allowing
+// cooldowns to be persisted. Cleared cooldowns have a zero expiration.
+type ChangeHook func(key string, expires time.Time)
+
+// a fixed window of uses for rate limiting
+type window struct {
+	start time.Time
+	count int
+}
+
+// Tracker holds cooldowns and rate limits by key. Keys are arbitrary, but
+// should include whatever the cooldown applies to, like "player:12:fireball".
+type Tracker struct {
+	expires map[string]time.Time
+	windows map[string]*window
+	hooks   []ChangeHook
+	mutex   *sync.Mutex
+	now     func() time.Time
+}
+
+// New creates an empty tracker.
+func New() *Tracker {
+	return &Tracker{
+		expires: make(map[string]time.Time),
+		windows: make(map[string]*window),
+		mutex:   new(sync.Mutex),
+		now:     time.Now,
+	}
+}
+
+var (
+	globalTracker *Tracker
+	globalOnce    sync.Once
+)
+
+// Global returns the tracker shared by the game.
+func Global() *Tracker {
+	globalOnce.Do(func() {
+		globalTracker = New()
+	})
+
+	return globalTracker
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (t *Tracker) SetClock(now func() time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.now = now
+}
+
+// OnChange registers a hook that is called whenever a cooldown is set or
+// cleared.
+func (t *Tracker) OnChange(hook ChangeHook) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.hooks = append(t.hooks, hook)
+}
+
+// Set puts the key on cooldown for the given duration, replacing any existing
+// cooldown.
+func (t *Tracker) Set(key string, d time.Duration) {
+	t.mutex.Lock()
+	expires := t.now().Add(d)
+	t.expires[key] = expires
+	hooks := t.hooks
+	t.mutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(key, expires)
+	}
+}
+
+// Try puts the key on cooldown and returns true if it's ready, otherwise it
+// returns false and leaves the existing cooldown in place.
+func (t *Tracker) Try(key string, d time.Duration) bool {
+	t.mutex.Lock()
+	now := t.now()
+	if expires, ok := t.expires[key]; ok && expires.After(now) {
+		t.mutex.Unlock()
+
+		return false
+	}
+	expires := now.Add(d)
+	t.expires[key] = expires
+	hooks := t.hooks
+	t.mutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(key, expires)
+	}
+
+	return true
+}
+
+// Restore sets the expiration of a key without calling any hooks, it's used
+// to load previously persisted cooldowns. Expired cooldowns are ignored.
+func (t *Tracker) Restore(key string, expires time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if expires.After(t.now()) {
+		t.expires[key] = expires
+	}
+}
+
+// Clear removes the cooldown on the key.
+func (t *Tracker) Clear(key string) {
+	t.mutex.Lock()
+	_, ok := t.expires[key]
+	delete(t.expires, key)
+	delete(t.windows, key)
+	hooks := t.hooks
+	t.mutex.Unlock()
+
+	if ok {
+		for _, hook := range hooks {
+			hook(key, time.Time{})
+		}
+	}
+}
+
+// Remaining returns how long until the key is ready, 0 if it's ready now.
+func (t *Tracker) Remaining(key string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	expires, ok := t.expires[key]
+	if !ok {
+		return 0
+	}
+
+	remaining := expires.Sub(t.now())
+	if remaining <= 0 {
+		delete(t.expires, key)
+
+		return 0
+	}
+
+	return remaining
+}
+
+// Ready determines if the key is no longer on cooldown.
+func (t *Tracker) Ready(key string) bool {
+	return t.Remaining(key) == 0
+}
+
+// Expires returns when the cooldown on the key ends, false is returned if the
+// key isn't on cooldown.
+func (t *Tracker) Expires(key string) (time.Time, bool) {
+	if t.Remaining(key) == 0 {
+		return time.Time{}, false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	expires, ok := t.expires[key]
+
+	return expires, ok
+}
+
+// Snapshot returns all active cooldowns, suitable for persisting and later
+// passing to Restore.
+func (t *Tracker) Snapshot() map[string]time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	snap := make(map[string]time.Time, len(t.expires))
+	for key, expires := range t.expires {
+		if expires.After(now) {
+			snap[key] = expires
+		} else {
+			delete(t.expires, key)
+		}
+	}
+
+	return snap
+}
+
+// Allow is a rate limit, it returns true and counts a use if the key has been
+// used fewer than max times in the current window, otherwise false. Windows
+// start with the first use of the key and last for the given duration.
+func (t *Tracker) Allow(key string, max int, per time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	w, ok := t.windows[key]
+	if !ok || now.Sub(w.start) >= per {
+		w = &window{start: now}
+		t.windows[key] = w
+	}
+
+	if w.count >= max {
+		return false
+	}
+	w.count++
+
+	return true
+}
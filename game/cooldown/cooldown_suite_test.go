@@ -0,0 +1,13 @@
+package cooldown_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCooldown(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cooldown Suite")
+}
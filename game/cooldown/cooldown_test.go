@@ -0,0 +1,83 @@
+package cooldown_test
+
+import (
+	"time"
+
+	. "github.com/bbuck/dragon-mud/game/cooldown"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracker", func() {
+	var (
+		tracker *Tracker
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		tracker = New()
+		tracker.SetClock(func() time.Time {
+			return now
+		})
+	})
+
+	It("is ready for unknown keys", func() {
+		Ω(tracker.Ready("fireball")).Should(BeTrue())
+		Ω(tracker.Remaining("fireball")).Should(Equal(time.Duration(0)))
+	})
+
+	It("tracks remaining time", func() {
+		tracker.Set("fireball", 10*time.Second)
+		now = now.Add(4 * time.Second)
+
+		Ω(tracker.Ready("fireball")).Should(BeFalse())
+		Ω(tracker.Remaining("fireball")).Should(Equal(6 * time.Second))
+
+		now = now.Add(6 * time.Second)
+		Ω(tracker.Ready("fireball")).Should(BeTrue())
+	})
+
+	It("only sets with Try when ready", func() {
+		Ω(tracker.Try("bash", 5*time.Second)).Should(BeTrue())
+		Ω(tracker.Try("bash", 5*time.Second)).Should(BeFalse())
+
+		now = now.Add(5 * time.Second)
+		Ω(tracker.Try("bash", 5*time.Second)).Should(BeTrue())
+	})
+
+	It("calls hooks when cooldowns change", func() {
+		changes := make(map[string]time.Time)
+		tracker.OnChange(func(key string, expires time.Time) {
+			changes[key] = expires
+		})
+
+		tracker.Set("a", time.Second)
+		tracker.Clear("a")
+		tracker.Restore("b", now.Add(time.Minute))
+
+		Ω(changes).Should(HaveLen(1))
+		Ω(changes["a"].IsZero()).Should(BeTrue())
+		Ω(tracker.Ready("b")).Should(BeFalse())
+	})
+
+	It("snapshots active cooldowns", func() {
+		tracker.Set("a", time.Second)
+		tracker.Set("b", time.Minute)
+		now = now.Add(2 * time.Second)
+
+		snap := tracker.Snapshot()
+		Ω(snap).Should(HaveLen(1))
+		Ω(snap).Should(HaveKey("b"))
+	})
+
+	It("rate limits uses within a window", func() {
+		Ω(tracker.Allow("say", 2, time.Minute)).Should(BeTrue())
+		Ω(tracker.Allow("say", 2, time.Minute)).Should(BeTrue())
+		Ω(tracker.Allow("say", 2, time.Minute)).Should(BeFalse())
+
+		now = now.Add(time.Minute)
+		Ω(tracker.Allow("say", 2, time.Minute)).Should(BeTrue())
+	})
+})
@@ -0,0 +1,138 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package craft
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the craft command, which starts and stops making
+// things, and the recipes command, which lists and describes recipes.
+func NewCommands(m *Manager, resolve command.Resolver[Crafter]) []*command.Command {
+	text := []command.Arg{{Name: "recipe", Kind: command.Text, Optional: true}}
+
+	return []*command.Command{
+		{
+			Name:    "craft",
+			Aliases: []string{"make"},
+			Args:    text,
+			Help:    "Starts making something from a recipe, \"craft stop\" stops.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Crafter) error {
+				name := strings.TrimSpace(ctx.String("recipe"))
+				switch strings.ToLower(name) {
+				case "":
+					if r, done, ok := m.Working(c.ID()); ok {
+						return ctx.Send(fmt.Sprintf("You are making %s. (%d/%d)", r.Name, done, r.Ticks))
+					}
+
+					return ctx.Send("Craft what?")
+				case "stop":
+					if !m.Cancel(c.ID()) {
+						return &item.Refused{Message: IdleMessage}
+					}
+
+					return nil
+				}
+				_, err := m.Craft(c, name)
+
+				return err
+			}),
+		},
+		{
+			Name:   "recipes",
+			Args:   text,
+			Help:   "Lists the recipes, or describes one.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Crafter) error {
+				name := ctx.String("recipe")
+				if name == "" {
+					return ctx.Send(listing(m, c))
+				}
+				r, ok := m.recipes.Named(name)
+				if !ok {
+					return &item.Refused{Message: UnknownMessage}
+				}
+
+				return ctx.Send(describe(m, r))
+			}),
+		},
+	}
+}
+
+// listing lists the recipes, marking those the crafter can make now
+func listing(m *Manager, c Crafter) string {
+	recipes := m.recipes.All()
+	if len(recipes) == 0 {
+		return "There are no recipes."
+	}
+	lines := []string{"Recipes:"}
+	for _, r := range recipes {
+		line := "  " + r.Name
+		if m.Ready(c, r) == nil {
+			line += " (ready)"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// describe tells what the recipe takes and makes
+func describe(m *Manager, r Recipe) string {
+	lines := []string{fmt.Sprintf("%s makes %s.", r.Name, m.itemName(r.Makes, r.Count))}
+	var uses []string
+	for _, in := range r.Ingredients {
+		uses = append(uses, m.itemName(in.Item, in.Count))
+	}
+	if len(uses) > 0 {
+		lines = append(lines, "Uses: "+strings.Join(uses, ", "))
+	}
+	var tools []string
+	for _, t := range r.Tools {
+		tools = append(tools, m.itemName(t, 1))
+	}
+	if len(tools) > 0 {
+		lines = append(lines, "Tools: "+strings.Join(tools, ", "))
+	}
+	if r.Skill != "" {
+		lines = append(lines, fmt.Sprintf("Skill: %s %d%%", r.Skill, r.Level))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// itemName names the defined item for players, with how many
+func (m *Manager) itemName(proto string, count int) string {
+	name := proto
+	if def, ok := m.world.Item(proto); ok {
+		name = def.Name
+	}
+	if count > 1 {
+		return fmt.Sprintf("%s (%d)", name, count)
+	}
+
+	return name
+}
+
+// handler resolves the caller's crafter for fn, telling the caller when
+// crafting is refused
+func handler(resolve command.Resolver[Crafter], fn func(*command.Context, Crafter) error) command.Handler {
+	return func(ctx *command.Context) error {
+		c := resolve(ctx.Caller)
+		if c == nil {
+			return ctx.Send("You can't craft anything.")
+		}
+
+		err := fn(ctx, c)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
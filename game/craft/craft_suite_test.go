@@ -0,0 +1,13 @@
+package craft_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCraft(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Craft Suite")
+}
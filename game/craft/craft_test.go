@@ -0,0 +1,276 @@
+package craft_test
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// smith makes things and remembers what they're told
+type smith struct {
+	skills    map[string]int
+	inventory item.List
+	sent      []string
+}
+
+func (s *smith) ID() string {
+	return "smith"
+}
+
+func (s *smith) Name() string {
+	return "Smith"
+}
+
+func (s *smith) Location() string {
+	return "forge"
+}
+
+func (s *smith) Skill(id string) int {
+	return s.skills[id]
+}
+
+func (s *smith) Inventory() item.List {
+	return s.inventory.Copy()
+}
+
+func (s *smith) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	l, err := fn(s.inventory.Copy())
+	if err == nil {
+		s.inventory = l
+	}
+
+	return err
+}
+
+func (s *smith) Level() command.Level {
+	return command.Player
+}
+
+func (s *smith) Send(text string) error {
+	s.sent = append(s.sent, text)
+
+	return nil
+}
+
+func (s *smith) told(text string) bool {
+	for _, sent := range s.sent {
+		if sent == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *smith) count(proto string) int {
+	n := 0
+	for _, it := range s.inventory {
+		if it.Proto == proto {
+			n += it.Quantity()
+		}
+	}
+
+	return n
+}
+
+const recipes = `
+recipes:
+  - id: sword
+    name: Iron Sword
+    makes: sword
+    ingredients:
+      - {item: bar, count: 3}
+    tools: [hammer]
+    skill: smithing
+    level: 20
+    ticks: 3
+    quality: smithing
+  - id: nails
+    name: nails
+    makes: nail
+    count: 10
+    ingredients:
+      - {item: bar}
+`
+
+var _ = Describe("Crafting", func() {
+	var (
+		em      *events.Emitter
+		m       *Manager
+		w       *world.World
+		bob     *smith
+		quality string
+	)
+
+	give := func(proto string, count int) {
+		it, err := item.Create(w, proto, count)
+		Ω(err).ShouldNot(HaveOccurred())
+		bob.inventory = bob.inventory.Add(it)
+	}
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "bar", Zone: "town", Name: "an iron bar", Flags: []string{"stackable"}})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "hammer", Zone: "town", Name: "a hammer"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "sword", Zone: "town", Name: "an iron sword"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "nail", Zone: "town", Name: "a nail", Flags: []string{"stackable"}})).Should(Succeed())
+		rs := NewRecipes()
+		Ω(rs.LoadYAML([]byte(recipes))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(rs, w, em)
+		quality = Fine
+		m.Quality("smithing", func(c Crafter, r Recipe, proficiency int) string {
+			return quality
+		})
+		bob = &smith{skills: map[string]int{"smithing": 30}}
+	})
+
+	It("validates recipes", func() {
+		rs := NewRecipes()
+		Ω(rs.Add(Recipe{ID: "nothing"})).ShouldNot(Succeed())
+		Ω(rs.Add(Recipe{ID: "bad", Makes: "nail", Ingredients: []Ingredient{{Count: 2}}})).ShouldNot(Succeed())
+		Ω(rs.Add(Recipe{ID: "nail", Makes: "nail", Ingredients: []Ingredient{{Item: "bar"}}})).Should(Succeed())
+
+		r, _ := rs.Get("nail")
+		Ω(r.Count).Should(Equal(1))
+		Ω(r.Ticks).Should(Equal(1))
+		Ω(r.Ingredients[0].Count).Should(Equal(1))
+	})
+
+	It("makes items over several pulses", func() {
+		_, err := m.Craft(bob, "iron")
+		Ω(err).Should(MatchError(MissingMessage))
+		give("bar", 4)
+		give("hammer", 1)
+		bob.skills["smithing"] = 10
+		_, err = m.Craft(bob, "iron")
+		Ω(err).Should(MatchError(UnskilledMessage))
+		bob.skills["smithing"] = 30
+
+		r, err := m.Craft(bob, "iron")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.ID).Should(Equal("sword"))
+		_, err = m.Craft(bob, "nails")
+		Ω(err).Should(MatchError(BusyMessage))
+
+		m.Tick()
+		m.Tick()
+		Ω(bob.told("You work on iron sword. (2/3)")).Should(BeTrue())
+		Ω(bob.count("sword")).Should(Equal(0))
+		m.Tick()
+		Ω(bob.count("sword")).Should(Equal(1))
+		Ω(bob.count("bar")).Should(Equal(1))
+		Ω(bob.count("hammer")).Should(Equal(1))
+		Ω(bob.told("You make an iron sword of fine quality.")).Should(BeTrue())
+		for _, it := range bob.inventory {
+			if it.Proto == "sword" {
+				Ω(it.Props["quality"]).Should(Equal(Fine))
+			}
+		}
+		_, _, working := m.Working("smith")
+		Ω(working).Should(BeFalse())
+
+		_, err = m.Craft(bob, "nails")
+		Ω(err).ShouldNot(HaveOccurred())
+		m.Tick()
+		Ω(bob.count("nail")).Should(Equal(10))
+		Ω(bob.count("bar")).Should(Equal(0))
+		Ω(bob.told("You make a nail (10).")).Should(BeTrue())
+	})
+
+	It("uses up ingredients of ruined work", func() {
+		give("bar", 3)
+		give("hammer", 1)
+		quality = Failed
+		_, err := m.Craft(bob, "iron")
+		Ω(err).ShouldNot(HaveOccurred())
+		m.Tick()
+		m.Tick()
+		m.Tick()
+		Ω(bob.count("bar")).Should(Equal(0))
+		Ω(bob.count("sword")).Should(Equal(0))
+		Ω(bob.told("You ruin iron sword.")).Should(BeTrue())
+	})
+
+	It("stops when the crafter cancels or loses what they need", func() {
+		give("bar", 3)
+		give("hammer", 1)
+		_, err := m.Craft(bob, "iron")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(m.Cancel("smith")).Should(BeTrue())
+		Ω(m.Cancel("smith")).Should(BeFalse())
+		Ω(bob.told("You stop making iron sword.")).Should(BeTrue())
+
+		_, err = m.Craft(bob, "iron")
+		Ω(err).ShouldNot(HaveOccurred())
+		bob.inventory = bob.inventory[:1]
+		m.Tick()
+		m.Tick()
+		m.Tick()
+		Ω(bob.count("bar")).Should(Equal(3))
+		Ω(bob.told("You don't have what you need to finish iron sword.")).Should(BeTrue())
+	})
+
+	It("lets before handlers stop crafting and tells of what's made", func(done Done) {
+		finished := make(chan events.Data, 1)
+		em.On("before:"+StartEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["recipe"] == "sword" {
+				return errors.New("The forge is cold.")
+			}
+
+			return nil
+		}))
+		em.On(FinishEvent, events.HandlerFunc(func(d events.Data) error {
+			finished <- d
+
+			return nil
+		}))
+		give("bar", 3)
+		give("hammer", 1)
+
+		_, err := m.Craft(bob, "iron")
+		Ω(err).Should(MatchError("The forge is cold."))
+		_, err = m.Craft(bob, "nails")
+		Ω(err).ShouldNot(HaveOccurred())
+		m.Tick()
+
+		d := <-finished
+		Ω(d["crafter"]).Should(Equal("smith"))
+		Ω(d["quality"]).Should(Equal(Common))
+		close(done)
+	})
+
+	It("crafts with the craft and recipes commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Crafter {
+			return c.(*smith)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatch := func(line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(bob, line)).Should(Succeed())
+		}
+		give("bar", 1)
+
+		dispatch("recipes")
+		Ω(bob.told("Recipes:\n  iron sword\n  nails (ready)")).Should(BeTrue())
+		dispatch("recipes iron")
+		Ω(bob.told("iron sword makes an iron sword.\nUses: an iron bar (3)\nTools: a hammer\nSkill: smithing 20%")).Should(BeTrue())
+		dispatch("craft stop")
+		Ω(bob.told(IdleMessage)).Should(BeTrue())
+		dispatch("craft nails")
+		Ω(bob.told("You begin making nails.")).Should(BeTrue())
+		dispatch("craft")
+		Ω(bob.told("You are making nails. (0/1)")).Should(BeTrue())
+		dispatch("craft stop")
+		Ω(bob.told("You stop making nails.")).Should(BeTrue())
+	})
+})
@@ -0,0 +1,507 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package craft
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The events of crafting. Handlers of before:craft:start can stop it by
+// returning events.ErrHalt, or an error whose message is told to the
+// crafter. Each is given the id and name of the crafter and the id and name
+// of the recipe, finishes are also given the quality of what was made and
+// stops why it stopped, "cancelled" or "missing".
+const (
+	StartEvent  = "craft:start"
+	FinishEvent = "craft:finish"
+	StopEvent   = "craft:stop"
+)
+
+// Messages told to those who can't craft something.
+const (
+	UnknownMessage   = "You don't know how to make that."
+	UnskilledMessage = "You aren't skilled enough to make that."
+	MissingMessage   = "You don't have what you need to make that."
+	BusyMessage      = "You're already making something."
+	IdleMessage      = "You aren't making anything."
+	CancelMessage    = "You can't do that right now."
+)
+
+// Crafter is a character who makes things, like a player.
+type Crafter interface {
+	item.Carrier
+	ID() string
+	// Skill returns how well the skill is known, as a percent.
+	Skill(id string) int
+}
+
+// Sender is a crafter told how their work goes.
+type Sender interface {
+	Send(text string) error
+}
+
+// QualityFunc decides how well what the crafter made turns out, given how
+// well they know the recipe's skill, returning a quality like Fine.
+type QualityFunc func(c Crafter, r Recipe, proficiency int) string
+
+// job is something being crafted
+type job struct {
+	crafter Crafter
+	recipe  Recipe
+	done    int
+}
+
+// Manager runs what's being crafted, a pulse at a time.
+type Manager struct {
+	recipes   *Recipes
+	world     *world.World
+	jobs      map[string]*job
+	qualities map[string]QualityFunc
+	improve   func(c Crafter, skill string)
+	emitter   *events.Emitter
+	stop      chan struct{}
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for the recipes, making items from the
+// world's definitions. The emitter may be nil.
+func NewManager(recipes *Recipes, w *world.World, em *events.Emitter) *Manager {
+	return &Manager{
+		recipes:   recipes,
+		world:     w,
+		jobs:      make(map[string]*job),
+		qualities: make(map[string]QualityFunc),
+		emitter:   em,
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's crafting manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewRecipes(), world.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetImprove sets how crafters get better at the skills they craft with.
+func (m *Manager) SetImprove(fn func(c Crafter, skill string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.improve = fn
+}
+
+// Recipes returns the recipes.
+func (m *Manager) Recipes() *Recipes {
+	return m.recipes
+}
+
+// Quality sets the quality hook with the name, replacing any there was.
+// Recipes without one use the "default" hook, and a roll against the
+// crafter's skill without that.
+func (m *Manager) Quality(name string, fn QualityFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.qualities[name] = fn
+}
+
+// Ready returns why the crafter can't make the recipe, or nil.
+func (m *Manager) Ready(c Crafter, r Recipe) error {
+	if r.Skill != "" && (c.Skill(r.Skill) <= 0 || c.Skill(r.Skill) < r.Level) {
+		return &item.Refused{Message: UnskilledMessage}
+	}
+	if len(Missing(c.Inventory(), r)) > 0 {
+		return &item.Refused{Message: MissingMessage}
+	}
+
+	return nil
+}
+
+// Craft starts the crafter making the recipe with the name, it's finished
+// once its ticks have passed.
+func (m *Manager) Craft(c Crafter, name string) (Recipe, error) {
+	r, ok := m.recipes.Named(name)
+	if !ok {
+		return Recipe{}, &item.Refused{Message: UnknownMessage}
+	}
+	if _, _, busy := m.Working(c.ID()); busy {
+		return r, &item.Refused{Message: BusyMessage}
+	}
+	if err := m.Ready(c, r); err != nil {
+		return r, err
+	}
+	data := craftData(c, r)
+	if err := m.check(StartEvent, data); err != nil {
+		return r, err
+	}
+
+	m.mutex.Lock()
+	m.jobs[c.ID()] = &job{crafter: c, recipe: r}
+	m.mutex.Unlock()
+
+	tell(c, fmt.Sprintf("You begin making %s.", r.Name))
+	m.confirm(StartEvent, data)
+
+	return r, nil
+}
+
+// Working returns what the crafter with the id is making and how many
+// pulses they've worked on it.
+func (m *Manager) Working(id string) (Recipe, int, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Recipe{}, 0, false
+	}
+
+	return j.recipe, j.done, true
+}
+
+// Cancel stops the crafter with the id making what they were, like when they
+// walk away from it. Nothing is used up. It returns false if they weren't
+// making anything.
+func (m *Manager) Cancel(id string) bool {
+	m.mutex.Lock()
+	j, ok := m.jobs[id]
+	delete(m.jobs, id)
+	m.mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+	tell(j.crafter, fmt.Sprintf("You stop making %s.", j.recipe.Name))
+	data := craftData(j.crafter, j.recipe)
+	data["reason"] = "cancelled"
+	m.emit(StopEvent, data)
+
+	return true
+}
+
+// Tick works a pulse on everything being crafted, finishing what's done.
+func (m *Manager) Tick() {
+	m.mutex.Lock()
+	var working, done []job
+	for id, j := range m.jobs {
+		j.done++
+		if j.done >= j.recipe.Ticks {
+			done = append(done, *j)
+			delete(m.jobs, id)
+		} else {
+			working = append(working, *j)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, j := range working {
+		tell(j.crafter, fmt.Sprintf("You work on %s. (%d/%d)", j.recipe.Name, j.done, j.recipe.Ticks))
+	}
+	sort.Slice(done, func(i, j int) bool {
+		return done[i].crafter.ID() < done[j].crafter.ID()
+	})
+	for _, j := range done {
+		m.finish(j.crafter, j.recipe)
+	}
+}
+
+// Start ticks every interval in the background until stopped.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil || interval <= 0 {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+// Stop halts ticking.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// finish uses up the recipe's ingredients and gives the crafter what they
+// made, if they still carry what they need
+func (m *Manager) finish(c Crafter, r Recipe) {
+	data := craftData(c, r)
+	err := c.UpdateInventory(func(l item.List) (item.List, error) {
+		return use(l, r)
+	})
+	if err != nil {
+		tell(c, fmt.Sprintf("You don't have what you need to finish %s.", r.Name))
+		data["reason"] = "missing"
+		m.emit(StopEvent, data)
+
+		return
+	}
+
+	quality := m.quality(c, r)
+	if r.Skill != "" {
+		m.mutex.RLock()
+		improve := m.improve
+		m.mutex.RUnlock()
+
+		if improve != nil {
+			improve(c, r.Skill)
+		}
+	}
+	data["quality"] = quality
+	if quality == Failed {
+		tell(c, fmt.Sprintf("You ruin %s.", r.Name))
+		m.emit(FinishEvent, data)
+
+		return
+	}
+
+	made, err := m.make(r, quality)
+	if err != nil {
+		tell(c, fmt.Sprintf("You can't make %s.", r.Name))
+		data["reason"] = "missing"
+		m.emit(StopEvent, data)
+
+		return
+	}
+	c.UpdateInventory(func(l item.List) (item.List, error) {
+		for _, it := range made {
+			l = l.Add(it)
+		}
+
+		return l, nil
+	})
+	if quality == Common {
+		tell(c, fmt.Sprintf("You make %s.", made[0].Describe()))
+	} else {
+		tell(c, fmt.Sprintf("You make %s of %s quality.", made[0].Describe(), quality))
+	}
+	m.emit(FinishEvent, data)
+}
+
+// make creates what the recipe makes at the quality
+func (m *Manager) make(r Recipe, quality string) (item.List, error) {
+	first, err := item.Create(m.world, r.Makes, r.Count)
+	if err != nil {
+		return nil, err
+	}
+	made := item.List{first}
+	for len(made) < r.Count && !first.Stackable() {
+		it, _ := item.Create(m.world, r.Makes, 1)
+		made = append(made, it)
+	}
+	if quality != Common {
+		for i := range made {
+			if made[i].Props == nil {
+				made[i].Props = make(map[string]interface{})
+			}
+			made[i].Props["quality"] = quality
+		}
+	}
+
+	return made, nil
+}
+
+// quality runs the recipe's quality hook
+func (m *Manager) quality(c Crafter, r Recipe) string {
+	name := r.Quality
+	if name == "" {
+		name = "default"
+	}
+
+	m.mutex.RLock()
+	fn := m.qualities[name]
+	m.mutex.RUnlock()
+
+	prof := 0
+	if r.Skill != "" {
+		prof = c.Skill(r.Skill)
+	}
+	if fn == nil {
+		return roll(r, prof)
+	}
+	if q := fn(c, r, prof); q != "" {
+		return q
+	}
+
+	return Common
+}
+
+// roll decides quality by chance, the more the crafter's skill is above what
+// the recipe needs the better. Recipes without skills always make common
+// items.
+func roll(r Recipe, proficiency int) string {
+	if r.Skill == "" {
+		return Common
+	}
+	score := random.Intn(100) + proficiency - r.Level
+	switch {
+	case score < 10:
+		return Failed
+	case score < 25:
+		return Poor
+	case score < 85:
+		return Common
+	case score < 110:
+		return Fine
+	}
+
+	return Masterwork
+}
+
+// Missing returns what of the recipe's ingredients and tools aren't in the
+// list, as the ids of their item definitions.
+func Missing(l item.List, r Recipe) []string {
+	var missing []string
+	for _, in := range r.Ingredients {
+		if count(l, in.Item) < in.Count {
+			missing = append(missing, in.Item)
+		}
+	}
+	for _, tool := range r.Tools {
+		if count(l, tool) == 0 {
+			missing = append(missing, tool)
+		}
+	}
+
+	return missing
+}
+
+// use returns the list without the recipe's ingredients, if it has them
+// and the recipe's tools
+func use(l item.List, r Recipe) (item.List, error) {
+	if len(Missing(l, r)) > 0 {
+		return l, item.ErrNotFound
+	}
+	for _, in := range r.Ingredients {
+		left := in.Count
+		for _, it := range l {
+			if left == 0 {
+				break
+			}
+			if it.Proto != in.Item {
+				continue
+			}
+			n := it.Quantity()
+			if n > left {
+				n = left
+			}
+			var err error
+			if l, _, err = l.Take(it.ID, n); err != nil {
+				return l, err
+			}
+			left -= n
+		}
+	}
+
+	return l, nil
+}
+
+// count is how many of the item definition are in the list, not counting
+// what's in containers
+func count(l item.List, proto string) int {
+	n := 0
+	for _, it := range l {
+		if it.Proto == proto {
+			n += it.Quantity()
+		}
+	}
+
+	return n
+}
+
+func tell(c Crafter, text string) {
+	if s, ok := c.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+func craftData(c Crafter, r Recipe) events.Data {
+	return events.Data{
+		"crafter":      c.ID(),
+		"crafter_name": c.Name(),
+		"recipe":       r.ID,
+		"recipe_name":  r.Name,
+		"item":         r.Makes,
+	}
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
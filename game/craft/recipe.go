@@ -0,0 +1,234 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package craft lets players make items from others. Recipes are defined in
+// YAML files with the ingredients they use up, the tools that must be carried,
+// the skill they take and how many pulses the work lasts. The quality of what
+// comes out is decided by a named hook, written in Go or Lua, so a master
+// smith can make finer blades than an apprentice.
+package craft
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The qualities of what's crafted, hooks can return others. Items of common
+// quality are made as they're defined, any other is kept in the item's
+// "quality" prop.
+const (
+	// Failed crafts use up their ingredients and make nothing.
+	Failed     = "failed"
+	Poor       = "poor"
+	Common     = "common"
+	Fine       = "fine"
+	Masterwork = "masterwork"
+)
+
+// Ingredient is an item a recipe uses up.
+type Ingredient struct {
+	// Item is the id of the item definition used.
+	Item string `yaml:"item"`
+	// Count is how many are used, one by default.
+	Count int `yaml:"count,omitempty"`
+}
+
+// Recipe defines how to make an item.
+type Recipe struct {
+	// ID is how the recipe is known to scripts.
+	ID string `yaml:"id"`
+	// Name is what players call the recipe, like "iron sword".
+	Name string `yaml:"name"`
+	// Makes is the id of the item definition made.
+	Makes string `yaml:"makes"`
+	// Count is how many are made, one by default.
+	Count       int          `yaml:"count,omitempty"`
+	Ingredients []Ingredient `yaml:"ingredients"`
+	// Tools are the ids of item definitions that must be carried, they
+	// aren't used up.
+	Tools []string `yaml:"tools,omitempty"`
+	// Skill is the id of the skill crafting takes, if any.
+	Skill string `yaml:"skill,omitempty"`
+	// Level is how well the skill must be known, as a percent.
+	Level int `yaml:"level,omitempty"`
+	// Ticks is how many pulses crafting takes, one by default.
+	Ticks int `yaml:"ticks,omitempty"`
+	// Quality names the hook deciding how well the item turns out, the
+	// "default" hook if there's none.
+	Quality string `yaml:"quality,omitempty"`
+}
+
+// validate fills in defaults and checks the recipe makes sense
+func (r *Recipe) validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("recipes need an id")
+	}
+	if r.Makes == "" {
+		return fmt.Errorf("recipe %s: needs an item to make", r.ID)
+	}
+	if r.Name == "" {
+		r.Name = r.ID
+	}
+	r.Name = strings.ToLower(r.Name)
+	if r.Count < 0 || r.Ticks < 0 || r.Level < 0 {
+		return fmt.Errorf("recipe %s: can't have a negative count, ticks or level", r.ID)
+	}
+	if r.Count == 0 {
+		r.Count = 1
+	}
+	if r.Ticks == 0 {
+		r.Ticks = 1
+	}
+	ingredients := make([]Ingredient, len(r.Ingredients))
+	for i, in := range r.Ingredients {
+		if in.Item == "" || in.Count < 0 {
+			return fmt.Errorf("recipe %s: ingredient %d needs an item and can't have a negative count", r.ID, i+1)
+		}
+		if in.Count == 0 {
+			in.Count = 1
+		}
+		ingredients[i] = in
+	}
+	r.Ingredients = ingredients
+
+	return nil
+}
+
+// Named is true if the name is or starts the recipe's name, ignoring case.
+func (r Recipe) Named(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	return name != "" && strings.HasPrefix(r.Name, name)
+}
+
+// File is the layout of a recipe file, a list of recipes.
+type File struct {
+	Recipes []Recipe `yaml:"recipes"`
+}
+
+// Recipes holds the recipes by id.
+type Recipes struct {
+	recipes map[string]Recipe
+	mutex   *sync.RWMutex
+}
+
+// NewRecipes creates an empty set of recipes.
+func NewRecipes() *Recipes {
+	return &Recipes{
+		recipes: make(map[string]Recipe),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+// Add adds the recipe, replacing any with its id.
+func (r *Recipes) Add(recipe Recipe) error {
+	if err := recipe.validate(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.recipes[recipe.ID] = recipe
+
+	return nil
+}
+
+// Get returns the recipe with the id.
+func (r *Recipes) Get(id string) (Recipe, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	recipe, ok := r.recipes[id]
+
+	return recipe, ok
+}
+
+// Named returns the first recipe, by name, with the name.
+func (r *Recipes) Named(name string) (Recipe, bool) {
+	for _, recipe := range r.All() {
+		if recipe.Named(name) {
+			return recipe, true
+		}
+	}
+
+	return Recipe{}, false
+}
+
+// All returns every recipe, sorted by name.
+func (r *Recipes) All() []Recipe {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	recipes := make([]Recipe, 0, len(r.recipes))
+	for _, recipe := range r.recipes {
+		recipes = append(recipes, recipe)
+	}
+	sort.Slice(recipes, func(i, j int) bool {
+		return recipes[i].Name < recipes[j].Name
+	})
+
+	return recipes
+}
+
+// LoadDir adds the recipes in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (r *Recipes) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := r.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the recipes listed in the YAML document, like:
+//   recipes:
+//     - id: iron-sword
+//       name: iron sword
+//       makes: iron-sword
+//       ingredients:
+//         - {item: iron-bar, count: 3}
+//         - {item: leather-strip}
+//       tools: [hammer]
+//       skill: smithing
+//       level: 25
+//       ticks: 4
+func (r *Recipes) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, recipe := range f.Recipes {
+		if err := r.Add(recipe); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
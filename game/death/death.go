@@ -0,0 +1,46 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package death handles what happens when players and mobs die. What they
+// carried and wore is left in a corpse that decays after a while, players
+// pay the death penalties, a share of their experience along with any
+// penalties scripts add, then respawn by the respawn policy.
+package death
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// CorpseProto is the proto of corpses, they aren't made from a definition.
+const CorpseProto = "corpse"
+
+// Things about corpses.
+const (
+	// CorpseFlag marks items that are corpses.
+	CorpseFlag = "corpse"
+	// OwnerProp is the prop of player corpses naming whose they are.
+	OwnerProp = "owner"
+)
+
+// NewCorpse makes the corpse of whoever has the name, holding the items.
+// Owner is the name of the player it was, empty for mobs.
+func NewCorpse(name, owner string, items item.List) item.Item {
+	keywords := append([]string{"corpse"}, strings.Fields(strings.ToLower(name))...)
+	corpse := item.New(world.ItemDef{
+		ID:          CorpseProto,
+		Name:        fmt.Sprintf("the corpse of %s", name),
+		Keywords:    keywords,
+		Description: fmt.Sprintf("The corpse of %s lies here.", name),
+		Type:        "container",
+		Flags:       []string{CorpseFlag, "no_take"},
+	}, 1)
+	if owner != "" {
+		corpse.Props = map[string]interface{}{OwnerProp: owner}
+	}
+	corpse.Contents = items.Copy()
+
+	return corpse
+}
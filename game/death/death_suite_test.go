@@ -0,0 +1,13 @@
+package death_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDeath(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Death Suite")
+}
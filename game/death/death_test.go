@@ -0,0 +1,223 @@
+package death_test
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// victim is a player who dies, remembering what they're told
+type victim struct {
+	name      string
+	location  string
+	stats     map[string]int
+	inventory item.List
+	equipment map[string]item.Item
+	vars      map[string]interface{}
+	sent      []string
+}
+
+func (v *victim) Name() string {
+	return v.name
+}
+
+func (v *victim) Location() string {
+	return v.location
+}
+
+func (v *victim) SetLocation(room string) {
+	v.location = room
+}
+
+func (v *victim) Stat(name string) int {
+	return v.stats[name]
+}
+
+func (v *victim) SetStat(name string, value int) {
+	v.stats[name] = value
+}
+
+func (v *victim) AddStat(name string, delta int) int {
+	v.stats[name] += delta
+
+	return v.stats[name]
+}
+
+func (v *victim) UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error {
+	inv, eq, err := fn(v.inventory.Copy(), v.equipment)
+	if err == nil {
+		v.inventory, v.equipment = inv, eq
+	}
+
+	return err
+}
+
+func (v *victim) Var(key string) interface{} {
+	return v.vars[key]
+}
+
+func (v *victim) Send(text string) error {
+	v.sent = append(v.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		w     *world.World
+		floor *item.Floor
+		em    *events.Emitter
+		m     *Manager
+		now   time.Time
+		ann   *victim
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		Ω(w.AddZone(world.Zone{ID: "crypt", Name: "The Crypt"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "temple", Zone: "town", Name: "The Temple"},
+			{ID: "inn", Zone: "town", Name: "The Inn"},
+			{ID: "tomb", Zone: "crypt", Name: "A Tomb"},
+			{ID: "graves", Zone: "crypt", Name: "The Graves", Flags: map[string]bool{RespawnFlag: true}},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.SetItem(world.ItemDef{ID: "sword", Zone: "town", Name: "a sword", Weight: 5})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "bread", Zone: "town", Name: "some bread", Weight: 1})).Should(Succeed())
+
+		sword, _ := w.Item("sword")
+		bread, _ := w.Item("bread")
+		floor = item.NewFloor()
+		em = events.NewEmitter(nil)
+		m = NewManager(w, floor, movement.New(w, nil), em)
+		m.SetRecall("temple")
+		now = time.Now()
+		m.SetClock(func() time.Time {
+			return now
+		})
+		ann = &victim{
+			name:      "Ann",
+			location:  "tomb",
+			stats:     map[string]int{"xp": 1000, "max_hp": 50},
+			inventory: item.List{item.New(bread, 1)},
+			equipment: map[string]item.Item{"wield": item.New(sword, 1)},
+			vars:      map[string]interface{}{HomeVar: "inn"},
+		}
+	})
+
+	It("leaves a corpse with everything the player had", func() {
+		Ω(m.Die(ann, "a ghoul")).Should(Succeed())
+
+		Ω(ann.inventory).Should(BeEmpty())
+		Ω(ann.equipment).Should(BeEmpty())
+		corpses := floor.In("tomb")
+		Ω(corpses).Should(HaveLen(1))
+		Ω(corpses[0].Name).Should(Equal("the corpse of Ann"))
+		Ω(corpses[0].Props[OwnerProp]).Should(Equal("Ann"))
+		Ω(corpses[0].Contents).Should(HaveLen(2))
+	})
+
+	It("respawns players by the policy", func() {
+		Ω(m.RespawnRoom(ann)).Should(Equal("temple"))
+		Ω(m.SetPolicy("Zone")).Should(Succeed())
+		Ω(m.RespawnRoom(ann)).Should(Equal("graves"))
+		Ω(m.SetPolicy(Home)).Should(Succeed())
+		Ω(m.RespawnRoom(ann)).Should(Equal("inn"))
+		Ω(m.SetPolicy(Here)).Should(Succeed())
+		Ω(m.RespawnRoom(ann)).Should(Equal("tomb"))
+		Ω(m.SetPolicy("heaven")).ShouldNot(Succeed())
+
+		Ω(m.SetPolicy(Home)).Should(Succeed())
+		delete(ann.vars, HomeVar)
+		Ω(m.RespawnRoom(ann)).Should(Equal("temple"))
+	})
+
+	It("makes players pay for dying and restores some health", func() {
+		m.SetExperienceLoss("xp", 10)
+		m.SetHealth(20)
+		var order []string
+		m.Penalty("b", func(v Victim, killer string) {
+			order = append(order, "b:"+killer)
+		})
+		m.Penalty("a", func(v Victim, killer string) {
+			order = append(order, "a:"+killer)
+			v.AddStat("gold", -5)
+		})
+		m.Penalty("c", func(Victim, string) {})
+		m.Penalty("c", nil)
+
+		Ω(m.Die(ann, "a ghoul")).Should(Succeed())
+		Ω(order).Should(Equal([]string{"a:a ghoul", "b:a ghoul"}))
+		Ω(ann.stats["xp"]).Should(Equal(900))
+		Ω(ann.stats["gold"]).Should(Equal(-5))
+		Ω(ann.stats[HPStat]).Should(Equal(10))
+		Ω(ann.location).Should(Equal("temple"))
+		Ω(ann.sent).Should(ContainElement("You lose 100 experience."))
+		Ω(ann.sent).Should(ContainElement(RespawnMessage))
+	})
+
+	It("emits events as players die and respawn", func() {
+		died := make(chan events.Data, 1)
+		respawned := make(chan events.Data, 1)
+		em.On(DeathEvent, events.HandlerFunc(func(d events.Data) error {
+			died <- d
+
+			return nil
+		}))
+		em.On(RespawnEvent, events.HandlerFunc(func(d events.Data) error {
+			respawned <- d
+
+			return nil
+		}))
+
+		Ω(m.Die(ann, "")).Should(Succeed())
+		var d events.Data
+		Eventually(died).Should(Receive(&d))
+		Ω(d["player"]).Should(Equal("Ann"))
+		Ω(d["room"]).Should(Equal("tomb"))
+		Eventually(respawned).Should(Receive(&d))
+		Ω(d["from"]).Should(Equal("tomb"))
+		Ω(d["room"]).Should(Equal("temple"))
+	})
+
+	It("decays corpses, spilling those of players", func() {
+		m.SetDecay(time.Minute, time.Hour)
+		Ω(m.Die(ann, "")).Should(Succeed())
+		sword, _ := w.Item("sword")
+		m.Corpse("a rat", "", "tomb", item.List{item.New(sword, 1)})
+		Ω(floor.In("tomb")).Should(HaveLen(2))
+
+		now = now.Add(2 * time.Minute)
+		m.Update()
+		Ω(floor.In("tomb")).Should(HaveLen(1))
+
+		now = now.Add(time.Hour)
+		m.Update()
+		Ω(floor.In("tomb")).Should(HaveLen(2))
+		for _, it := range floor.In("tomb") {
+			Ω(it.Flag(CorpseFlag)).Should(BeFalse())
+		}
+	})
+
+	It("leaves the corpses of mobs killed", func() {
+		Ω(w.SetNPC(world.NPCDef{ID: "ghoul", Zone: "crypt", Name: "a ghoul"})).Should(Succeed())
+		mobs := mob.NewManager(w, floor, nil)
+		mobs.SetCorpse(m.MobCorpse)
+		ghoul, err := mobs.Spawn("ghoul", "tomb")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(mobs.Kill(ghoul.ID(), "Ann")).Should(BeTrue())
+		Ω(floor.In("tomb")).Should(HaveLen(1))
+		Ω(floor.In("tomb")[0].Name).Should(Equal("the corpse of a ghoul"))
+	})
+})
@@ -0,0 +1,458 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package death
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The events of players dying. DeathEvent is given the name of the player,
+// the name of their killer, which may be empty, the room they died in and
+// the id of their corpse. RespawnEvent is given the name of the player, the
+// room they died in and the room they respawned in.
+const (
+	DeathEvent   = "player:death"
+	RespawnEvent = "player:respawn"
+)
+
+// The policies deciding where players respawn.
+const (
+	// Recall respawns players in the recall room.
+	Recall = "recall"
+	// Zone respawns players in a room of the zone they died in flagged
+	// respawn, like a graveyard, or the recall room if there isn't one.
+	Zone = "zone"
+	// Home respawns players in the room their home var names, or the recall
+	// room if they don't have one.
+	Home = "home"
+	// Here respawns players where they died.
+	Here = "here"
+)
+
+// Policies lists the respawn policies.
+var Policies = []string{Recall, Zone, Home, Here}
+
+// RespawnFlag marks rooms players respawn in by the zone policy.
+const RespawnFlag = "respawn"
+
+// HomeVar is the player var naming the room they respawn in by the home
+// policy.
+const HomeVar = "home"
+
+// The stats of players' health, restored as they respawn.
+const (
+	HPStat    = "hp"
+	MaxHPStat = "max_hp"
+)
+
+// Messages told to players as they die and respawn.
+const (
+	PenaltyMessage = "You lose %d experience."
+	RespawnMessage = "You awaken, barely clinging to life."
+)
+
+// Victim is a character who dies and respawns, like a player.
+type Victim interface {
+	movement.Mover
+	Stat(name string) int
+	SetStat(name string, value int)
+	AddStat(name string, delta int) int
+	UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error
+	Var(key string) interface{}
+}
+
+// Sender is a victim told what happens to them.
+type Sender interface {
+	Send(text string) error
+}
+
+// PenaltyFunc makes the player pay for dying, killer names who killed them
+// and may be empty.
+type PenaltyFunc func(v Victim, killer string)
+
+// corpse is a corpse waiting to decay
+type corpse struct {
+	room    string
+	player  bool
+	decayed time.Time
+}
+
+// Manager leaves corpses, makes players pay for dying and respawns them.
+type Manager struct {
+	world    *world.World
+	floor    *item.Floor
+	movement *movement.Movement
+	policy   string
+	recall   string
+	// mobDecay and playerDecay are how long corpses last
+	mobDecay    time.Duration
+	playerDecay time.Duration
+	xpStat      string
+	xpLoss      int
+	health      int
+	corpses     map[string]corpse
+	penalties   map[string]PenaltyFunc
+	now         func() time.Time
+	stop        chan struct{}
+	emitter     *events.Emitter
+	mutex       *sync.RWMutex
+}
+
+// NewManager creates a manager leaving corpses on the floor of the world's
+// rooms and respawning players with the Movement. The emitter may be nil.
+func NewManager(w *world.World, f *item.Floor, mv *movement.Movement, em *events.Emitter) *Manager {
+	return &Manager{
+		world:       w,
+		floor:       f,
+		movement:    mv,
+		policy:      Recall,
+		mobDecay:    5 * time.Minute,
+		playerDecay: 30 * time.Minute,
+		xpStat:      "xp",
+		corpses:     make(map[string]corpse),
+		penalties:   make(map[string]PenaltyFunc),
+		now:         time.Now,
+		emitter:     em,
+		mutex:       new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's death manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), item.GlobalFloor(), movement.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetPolicy changes where players respawn, by recall by default.
+func (m *Manager) SetPolicy(policy string) error {
+	policy = strings.ToLower(policy)
+	for _, p := range Policies {
+		if p == policy {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+
+			m.policy = policy
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the respawn policy is one of %v, not %q", Policies, policy)
+}
+
+// Policy returns where players respawn.
+func (m *Manager) Policy() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.policy
+}
+
+// SetRecall changes the room players are recalled to. Without one players
+// respawn where they died.
+func (m *Manager) SetRecall(room string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.recall = room
+}
+
+// SetDecay changes how long the corpses of mobs and players last.
+func (m *Manager) SetDecay(mobs, players time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.mobDecay, m.playerDecay = mobs, players
+}
+
+// SetExperienceLoss changes the percent of the experience stat players lose
+// when they die.
+func (m *Manager) SetExperienceLoss(stat string, percent int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if stat != "" {
+		m.xpStat = stat
+	}
+	m.xpLoss = percent
+}
+
+// SetHealth changes the percent of their max hp players respawn with, they
+// always have at least 1.
+func (m *Manager) SetHealth(percent int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.health = percent
+}
+
+// SetClock changes how the manager tells the time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Penalty sets the penalty with the name, replacing any there was, or
+// removing it if fn is nil. Penalties run in order of their names after
+// experience is lost.
+func (m *Manager) Penalty(name string, fn PenaltyFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if fn == nil {
+		delete(m.penalties, name)
+
+		return
+	}
+	m.penalties[name] = fn
+}
+
+// Corpse leaves the corpse of whoever has the name in the room, holding the
+// items, returning it. Owner is the name of the player it was, empty for
+// mobs.
+func (m *Manager) Corpse(name, owner, room string, items item.List) item.Item {
+	c := NewCorpse(name, owner, items)
+
+	m.mutex.Lock()
+	decay := m.mobDecay
+	if owner != "" {
+		decay = m.playerDecay
+	}
+	m.corpses[c.ID] = corpse{room: room, player: owner != "", decayed: m.now().Add(decay)}
+	m.mutex.Unlock()
+
+	m.floor.Add(room, c)
+
+	return c
+}
+
+// MobCorpse leaves the corpse of the mob that died, holding what it carried
+// and wore, for the mob manager.
+func (m *Manager) MobCorpse(mb *mob.Mob, items item.List) {
+	m.Corpse(mb.Name(), "", mb.Location(), items)
+}
+
+// Die leaves the player's corpse with everything they carried and wore,
+// makes them pay the penalties and respawns them. Killer names who killed
+// them and may be empty.
+func (m *Manager) Die(v Victim, killer string) error {
+	room := v.Location()
+	var items item.List
+	v.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+		slots := make([]string, 0, len(eq))
+		for slot := range eq {
+			slots = append(slots, slot)
+		}
+		sort.Strings(slots)
+		for _, slot := range slots {
+			items = items.Add(eq[slot])
+		}
+		for _, it := range inv {
+			items = items.Add(it)
+		}
+
+		return nil, make(map[string]item.Item), nil
+	})
+	c := m.Corpse(v.Name(), v.Name(), room, items)
+
+	m.mutex.RLock()
+	stat, loss := m.xpStat, m.xpLoss
+	names := make([]string, 0, len(m.penalties))
+	for name := range m.penalties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	penalties := make([]PenaltyFunc, len(names))
+	for i, name := range names {
+		penalties[i] = m.penalties[name]
+	}
+	m.mutex.RUnlock()
+
+	if lost := v.Stat(stat) * loss / 100; lost > 0 {
+		v.AddStat(stat, -lost)
+		tell(v, fmt.Sprintf(PenaltyMessage, lost))
+	}
+	for _, fn := range penalties {
+		fn(v, killer)
+	}
+	m.emit(DeathEvent, events.Data{
+		"player": v.Name(),
+		"killer": killer,
+		"room":   room,
+		"corpse": c.ID,
+	})
+
+	return m.Respawn(v)
+}
+
+// Respawn restores the player's health and puts them where the policy says.
+func (m *Manager) Respawn(v Victim) error {
+	m.mutex.RLock()
+	health := m.health
+	m.mutex.RUnlock()
+
+	from, to := v.Location(), m.RespawnRoom(v)
+	hp := v.Stat(MaxHPStat) * health / 100
+	if hp < 1 {
+		hp = 1
+	}
+	v.SetStat(HPStat, hp)
+	tell(v, RespawnMessage)
+	if to != from {
+		if err := m.movement.Teleport(v, to); err != nil {
+			return err
+		}
+	}
+	m.emit(RespawnEvent, events.Data{
+		"player": v.Name(),
+		"from":   from,
+		"room":   to,
+	})
+
+	return nil
+}
+
+// RespawnRoom returns the room the player respawns in by the policy.
+func (m *Manager) RespawnRoom(v Victim) string {
+	m.mutex.RLock()
+	policy, recall := m.policy, m.recall
+	m.mutex.RUnlock()
+
+	here := v.Location()
+	switch policy {
+	case Here:
+		return here
+	case Home:
+		if home, ok := v.Var(HomeVar).(string); ok {
+			if _, ok := m.world.Room(home); ok {
+				return home
+			}
+		}
+	case Zone:
+		if r, ok := m.world.Room(here); ok {
+			for _, other := range m.world.Rooms(r.Zone) {
+				if other.Flag(RespawnFlag) {
+					return other.ID
+				}
+			}
+		}
+	}
+	if _, ok := m.world.Room(recall); ok {
+		return recall
+	}
+
+	return here
+}
+
+// Update decays the corpses that have lasted long enough. What's in the
+// corpses of mobs decays with them, what's in those of players is left on
+// the floor.
+func (m *Manager) Update() {
+	m.mutex.Lock()
+	now := m.now()
+	decayed := make(map[string]corpse)
+	for id, c := range m.corpses {
+		if !now.Before(c.decayed) {
+			decayed[id] = c
+			delete(m.corpses, id)
+		}
+	}
+	m.mutex.Unlock()
+
+	for id, c := range decayed {
+		m.floor.Update(c.room, func(l item.List) (item.List, error) {
+			body, ok := l.Get(id)
+			if !ok {
+				return l, nil
+			}
+			l = l.Without(id)
+			if c.player {
+				for _, it := range body.Contents {
+					l = l.Add(it)
+				}
+			}
+
+			return l, nil
+		})
+	}
+}
+
+// Start decays corpses every interval until stopped.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil || interval <= 0 {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Update()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+// Stop halts the decay.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// tell sends the text to the victim if they can be told things
+func tell(v Victim, text string) {
+	if s, ok := v.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package dialogue
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the talk command, which starts conversations or repeats
+// where the caller is in theirs, and the reply command, which chooses a reply
+// by number or keyword.
+func NewCommands(m *Manager, resolve command.Resolver[Talker]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:   "talk",
+			Args:   []command.Arg{{Name: "npc", Kind: command.Text, Optional: true}},
+			Help:   "Talks to someone, or repeats what they last said.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, t Talker) error {
+				if npc := ctx.String("npc"); npc != "" {
+					return m.Talk(t, npc)
+				}
+				if !m.Show(t) {
+					return ctx.Send("Talk to whom?")
+				}
+
+				return nil
+			}),
+		},
+		{
+			Name:      "reply",
+			Aliases:   []string{"choose", "answer"},
+			MinAbbrev: 3,
+			Args:      []command.Arg{{Name: "choice", Kind: command.Text}},
+			Help:      "Chooses a reply in a conversation by its number or a keyword, \"reply bye\" ends it.",
+			Source:    "game",
+			Handler: handler(resolve, func(ctx *command.Context, t Talker) error {
+				return m.Reply(t, ctx.String("choice"))
+			}),
+		},
+	}
+}
+
+// handler resolves the caller's talker for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Talker], fn func(*command.Context, Talker) error) command.Handler {
+	return func(ctx *command.Context) error {
+		t := resolve(ctx.Caller)
+		if t == nil {
+			return ctx.Send("You can't talk to anyone.")
+		}
+
+		err := fn(ctx, t)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package dialogue lets players hold conversations with NPCs. A conversation
+// is a tree of nodes, each something the NPC says and the replies a player
+// can choose from, picked by number or by keyword. Replies lead to other
+// nodes and can be hidden behind conditions or run actions, both named and
+// written in Go or Lua. Where each player is in their conversation is kept
+// on them, so it's saved with them.
+package dialogue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Tree is a conversation with an NPC.
+type Tree struct {
+	// ID is how the conversation is known to scripts and saved on players.
+	ID string `yaml:"id"`
+	// NPC is the id of the NPC definition that speaks the conversation.
+	NPC string `yaml:"npc"`
+	// Start is the node the conversation begins at, "start" by default.
+	Start string          `yaml:"start,omitempty"`
+	Nodes map[string]Node `yaml:"nodes"`
+}
+
+// Node is something the NPC says.
+type Node struct {
+	Text string `yaml:"text"`
+	// Do names the action run when the conversation reaches the node.
+	Do string `yaml:"do,omitempty"`
+	// Choices are the player's replies, the conversation ends at nodes
+	// without any they can choose.
+	Choices []Choice `yaml:"choices,omitempty"`
+}
+
+// Choice is a reply the player can choose.
+type Choice struct {
+	Text string `yaml:"text"`
+	// Keywords can be typed to choose the reply instead of its number.
+	Keywords []string `yaml:"keywords,omitempty"`
+	// If names the condition that must be true to offer the reply, or false
+	// if it starts with "!".
+	If string `yaml:"if,omitempty"`
+	// Do names the action run when the reply is chosen.
+	Do string `yaml:"do,omitempty"`
+	// Next is the node the reply leads to, the conversation ends without
+	// one.
+	Next string `yaml:"next,omitempty"`
+}
+
+// Matches is true if the input is one of the reply's keywords, or starts a
+// word of its text.
+func (c Choice) Matches(input string) bool {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return false
+	}
+	for _, k := range c.Keywords {
+		if strings.ToLower(k) == input {
+			return true
+		}
+	}
+	for _, word := range strings.Fields(strings.ToLower(c.Text)) {
+		if strings.HasPrefix(word, input) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate fills in defaults and checks the conversation makes sense
+func (t *Tree) validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("dialogues need an id")
+	}
+	if t.Start == "" {
+		t.Start = "start"
+	}
+	if _, ok := t.Nodes[t.Start]; !ok {
+		return fmt.Errorf("dialogue %s: has no %s node to start at", t.ID, t.Start)
+	}
+	for id, node := range t.Nodes {
+		for i, c := range node.Choices {
+			if c.Text == "" {
+				return fmt.Errorf("dialogue %s: node %s choice %d needs text", t.ID, id, i+1)
+			}
+			if _, ok := t.Nodes[c.Next]; c.Next != "" && !ok {
+				return fmt.Errorf("dialogue %s: node %s choice %d leads to missing node %s", t.ID, id, i+1, c.Next)
+			}
+		}
+	}
+
+	return nil
+}
+
+// State is where a player is in a conversation.
+type State struct {
+	Tree string `json:"tree"`
+	Node string `json:"node"`
+	// Speaker is the name of the NPC the player is talking to.
+	Speaker string `json:"speaker"`
+}
+
+// File is the layout of a dialogue file, a list of conversations.
+type File struct {
+	Dialogues []Tree `yaml:"dialogues"`
+}
+
+// Trees holds the conversations by id.
+type Trees struct {
+	trees map[string]Tree
+	mutex *sync.RWMutex
+}
+
+// NewTrees creates an empty set of conversations.
+func NewTrees() *Trees {
+	return &Trees{
+		trees: make(map[string]Tree),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the conversation, replacing any with its id.
+func (t *Trees) Add(tree Tree) error {
+	if err := tree.validate(); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.trees[tree.ID] = tree
+
+	return nil
+}
+
+// Get returns the conversation with the id.
+func (t *Trees) Get(id string) (Tree, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tree, ok := t.trees[id]
+
+	return tree, ok
+}
+
+// ForNPC returns the conversation spoken by the NPC definition with the id.
+func (t *Trees) ForNPC(proto string) (Tree, bool) {
+	for _, tree := range t.All() {
+		if tree.NPC == proto {
+			return tree, true
+		}
+	}
+
+	return Tree{}, false
+}
+
+// All returns every conversation, sorted by id.
+func (t *Trees) All() []Tree {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	trees := make([]Tree, 0, len(t.trees))
+	for _, tree := range t.trees {
+		trees = append(trees, tree)
+	}
+	sort.Slice(trees, func(i, j int) bool {
+		return trees[i].ID < trees[j].ID
+	})
+
+	return trees
+}
+
+// LoadDir adds the conversations in every .yml and .yaml file in the
+// directory. Missing directories are ignored.
+func (t *Trees) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := t.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the conversations listed in the YAML document, like:
+//   dialogues:
+//     - id: innkeeper
+//       npc: innkeeper
+//       nodes:
+//         start:
+//           text: What'll it be?
+//           choices:
+//             - {text: "Any work?", next: work, if: "!on-rats"}
+//             - {text: "Nothing.", keywords: [bye]}
+//         work:
+//           text: Rats in the cellar.
+//           choices:
+//             - {text: "I'll do it.", do: start-rats}
+func (t *Trees) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, tree := range f.Dialogues {
+		if err := t.Add(tree); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
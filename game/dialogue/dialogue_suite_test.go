@@ -0,0 +1,13 @@
+package dialogue_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDialogue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dialogue Suite")
+}
@@ -0,0 +1,248 @@
+package dialogue_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/dialogue"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// talker remembers what they're told and sent
+type talker struct {
+	name         string
+	room         string
+	conversation *State
+	sent         []string
+	data         []string
+}
+
+func (t *talker) ID() string {
+	return strings.ToLower(t.name)
+}
+
+func (t *talker) Name() string {
+	return t.name
+}
+
+func (t *talker) Location() string {
+	return t.room
+}
+
+func (t *talker) Conversation() (State, bool) {
+	if t.conversation == nil {
+		return State{}, false
+	}
+
+	return *t.conversation, true
+}
+
+func (t *talker) SetConversation(s State) {
+	if s == (State{}) {
+		t.conversation = nil
+	} else {
+		t.conversation = &s
+	}
+}
+
+func (t *talker) Level() command.Level {
+	return command.Player
+}
+
+func (t *talker) Send(text string) error {
+	t.sent = append(t.sent, text)
+
+	return nil
+}
+
+func (t *talker) SendData(pkg string, data interface{}) error {
+	t.data = append(t.data, pkg)
+
+	return nil
+}
+
+func (t *talker) last() string {
+	if len(t.sent) == 0 {
+		return ""
+	}
+
+	return t.sent[len(t.sent)-1]
+}
+
+// npc is someone to talk to
+type npc struct {
+	name, proto string
+}
+
+func (n npc) Name() string {
+	return n.name
+}
+
+func (n npc) Proto() string {
+	return n.proto
+}
+
+func (n npc) Matches(keyword string) bool {
+	return strings.Contains(n.name, keyword)
+}
+
+const innkeeper = `
+dialogues:
+  - id: inn
+    npc: innkeeper
+    nodes:
+      start:
+        text: What'll it be?
+        choices:
+          - {text: "Any work?", keywords: [work], next: work, if: "!working"}
+          - {text: "An ale.", next: ale}
+          - {text: "Nothing."}
+      work:
+        text: Rats in the cellar.
+        do: hire
+        choices:
+          - {text: "I'll do it.", next: thanks, do: accept}
+          - {text: "No thanks."}
+      thanks:
+        text: Much obliged.
+      ale:
+        text: Here you go.
+`
+
+var _ = Describe("Dialogue", func() {
+	var (
+		em      *events.Emitter
+		m       *Manager
+		alice   *talker
+		working bool
+		hired   []string
+	)
+
+	BeforeEach(func() {
+		trees := NewTrees()
+		Ω(trees.LoadYAML([]byte(innkeeper))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(trees, em)
+		m.SetNearby(func(room string) []Speaker {
+			if room != "inn" {
+				return nil
+			}
+
+			return []Speaker{npc{"a guard", "guard"}, npc{"the innkeeper", "innkeeper"}}
+		})
+		working, hired = false, nil
+		m.Condition("working", func(t Talker, speaker string) bool {
+			return working
+		})
+		m.Action("hire", func(t Talker, speaker string) {
+			hired = append(hired, speaker)
+		})
+		m.Action("accept", func(t Talker, speaker string) {
+			working = true
+		})
+		alice = &talker{name: "Alice", room: "inn"}
+	})
+
+	It("validates conversations", func() {
+		trees := NewTrees()
+		Ω(trees.Add(Tree{ID: "empty"})).ShouldNot(Succeed())
+		Ω(trees.Add(Tree{ID: "lost", Nodes: map[string]Node{"start": {Choices: []Choice{{Text: "Where?", Next: "nowhere"}}}}})).ShouldNot(Succeed())
+		Ω(trees.Add(Tree{ID: "hello", Start: "hi", Nodes: map[string]Node{"hi": {Text: "Hello."}}})).Should(Succeed())
+	})
+
+	It("walks the tree by number and keyword", func() {
+		Ω(m.Talk(alice, "innkeeper")).Should(Succeed())
+		Ω(alice.last()).Should(Equal("The innkeeper says, \"What'll it be?\"\n  1) Any work?\n  2) An ale.\n  3) Nothing."))
+		Ω(alice.conversation).Should(Equal(&State{Tree: "inn", Node: "start", Speaker: "the innkeeper"}))
+
+		Ω(m.Reply(alice, "work")).Should(Succeed())
+		Ω(hired).Should(Equal([]string{"the innkeeper"}))
+		Ω(m.Reply(alice, "1")).Should(Succeed())
+		Ω(alice.sent).Should(ContainElement("You say, \"I'll do it.\""))
+		Ω(alice.last()).Should(Equal("The innkeeper says, \"Much obliged.\""))
+		Ω(alice.conversation).Should(BeNil())
+		Ω(alice.data).Should(Equal([]string{NodePackage, NodePackage, NodePackage, EndPackage}))
+
+		Ω(m.Talk(alice, "inn")).Should(Succeed())
+		Ω(alice.last()).Should(Equal("The innkeeper says, \"What'll it be?\"\n  1) An ale.\n  2) Nothing."))
+		Ω(m.Reply(alice, "3")).Should(MatchError(NoChoiceMessage))
+		Ω(m.Reply(alice, "nothing")).Should(Succeed())
+		Ω(alice.conversation).Should(BeNil())
+		Ω(m.Reply(alice, "1")).Should(MatchError(NotTalkingMessage))
+	})
+
+	It("ends conversations", func() {
+		Ω(m.Talk(alice, "guard")).Should(MatchError(SilentMessage))
+		Ω(m.Talk(alice, "baker")).Should(MatchError(NoOneMessage))
+
+		Ω(m.Talk(alice, "innkeeper")).Should(Succeed())
+		Ω(m.Reply(alice, "bye")).Should(Succeed())
+		Ω(alice.conversation).Should(BeNil())
+
+		Ω(m.Talk(alice, "innkeeper")).Should(Succeed())
+		alice.room = "street"
+		Ω(m.Reply(alice, "1")).Should(MatchError(GoneMessage))
+		Ω(alice.conversation).Should(BeNil())
+	})
+
+	It("picks up saved conversations", func() {
+		alice.conversation = &State{Tree: "inn", Node: "work", Speaker: "the innkeeper"}
+		Ω(m.Show(alice)).Should(BeTrue())
+		Ω(alice.last()).Should(Equal("The innkeeper says, \"Rats in the cellar.\"\n  1) I'll do it.\n  2) No thanks."))
+
+		alice.conversation = &State{Tree: "gone", Node: "start"}
+		Ω(m.Show(alice)).Should(BeFalse())
+		Ω(alice.conversation).Should(BeNil())
+	})
+
+	It("lets before handlers stop conversations", func(done Done) {
+		chose := make(chan events.Data, 1)
+		em.On("before:"+StartEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["player"] == "bob" {
+				return errors.New("The innkeeper ignores you.")
+			}
+
+			return nil
+		}))
+		em.On(ChooseEvent, events.HandlerFunc(func(d events.Data) error {
+			chose <- d
+
+			return nil
+		}))
+
+		Ω(m.Talk(&talker{name: "Bob", room: "inn"}, "innkeeper")).Should(MatchError("The innkeeper ignores you."))
+		Ω(m.Talk(alice, "innkeeper")).Should(Succeed())
+		Ω(m.Reply(alice, "ale")).Should(Succeed())
+
+		d := <-chose
+		Ω(d["choice"]).Should(Equal("An ale."))
+		Ω(d["node"]).Should(Equal("start"))
+		close(done)
+	})
+
+	It("talks through commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Talker {
+			return c.(*talker)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatch := func(line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+		}
+
+		dispatch("talk")
+		Ω(alice.last()).Should(Equal("Talk to whom?"))
+		dispatch("talk 1.inn")
+		dispatch("talk")
+		Ω(alice.last()).Should(HavePrefix("The innkeeper says, \"What'll it be?\""))
+		dispatch("reply 2")
+		Ω(alice.last()).Should(Equal("The innkeeper says, \"Here you go.\""))
+		dispatch("rep 2")
+		Ω(alice.last()).Should(Equal(NotTalkingMessage))
+	})
+})
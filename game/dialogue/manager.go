@@ -0,0 +1,469 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package dialogue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// The events of conversations. Handlers of before:dialogue:start can stop a
+// player from talking by returning events.ErrHalt, or an error whose message
+// is told to them. Each is given the id and name of the player, the id of the
+// conversation, the node and the name of the NPC. Starts are also given the
+// id of the NPC's definition, choices the text of the reply chosen.
+const (
+	StartEvent  = "dialogue:start"
+	ChooseEvent = "dialogue:choose"
+	EndEvent    = "dialogue:end"
+)
+
+// The packages sent to clients that receive out-of-band data, so they can
+// show conversations as menus. Nodes are sent with the NPC's name, what they
+// say and the choices as a list of their numbers and text. Clients choose by
+// sending ChoosePackage with the number.
+const (
+	NodePackage   = "Dialogue.Node"
+	EndPackage    = "Dialogue.End"
+	ChoosePackage = "Dialogue.Choose"
+)
+
+// Messages told to players who can't talk or reply.
+const (
+	NoOneMessage      = "They aren't here."
+	SilentMessage     = "They have nothing to say to you."
+	NotTalkingMessage = "You aren't talking to anyone."
+	NoChoiceMessage   = "That isn't one of your choices."
+	GoneMessage       = "Who you were talking to is gone."
+	CancelMessage     = "You can't do that right now."
+)
+
+// Talker is a character who holds conversations, like a player.
+type Talker interface {
+	ID() string
+	Name() string
+	Location() string
+	// Conversation returns where the talker is in their conversation.
+	Conversation() (State, bool)
+	// SetConversation changes the talker's conversation, a zero state ends
+	// it.
+	SetConversation(s State)
+}
+
+// Speaker is an NPC that can be talked to, like a mob.
+type Speaker interface {
+	Name() string
+	// Proto is the id of the NPC definition the speaker was made from.
+	Proto() string
+	Matches(keyword string) bool
+}
+
+// Sender is a talker who is told what's said.
+type Sender interface {
+	Send(text string) error
+}
+
+// DataSender is a talker whose client can be sent the conversation as data.
+type DataSender interface {
+	SendData(pkg string, data interface{}) error
+}
+
+// Condition decides whether a reply is offered to the talker.
+type Condition func(t Talker, speaker string) bool
+
+// Action does something when a node is reached or a reply chosen, like
+// starting a quest.
+type Action func(t Talker, speaker string)
+
+// Manager runs conversations.
+type Manager struct {
+	trees      *Trees
+	conditions map[string]Condition
+	actions    map[string]Action
+	nearby     func(room string) []Speaker
+	emitter    *events.Emitter
+	mutex      *sync.RWMutex
+}
+
+// NewManager creates a manager for the conversations, emitting events with
+// the emitter, which may be nil.
+func NewManager(trees *Trees, em *events.Emitter) *Manager {
+	return &Manager{
+		trees:      trees,
+		conditions: make(map[string]Condition),
+		actions:    make(map[string]Action),
+		emitter:    em,
+		mutex:      new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's dialogue manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewTrees(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetNearby sets how to find the NPCs in a room that can be talked to.
+func (m *Manager) SetNearby(fn func(room string) []Speaker) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nearby = fn
+}
+
+// Trees returns the conversations.
+func (m *Manager) Trees() *Trees {
+	return m.trees
+}
+
+// Condition sets the condition with the name, replacing any there was.
+func (m *Manager) Condition(name string, fn Condition) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.conditions[name] = fn
+}
+
+// Action sets the action with the name, replacing any there was.
+func (m *Manager) Action(name string, fn Action) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.actions[name] = fn
+}
+
+// Talk starts the conversation of the NPC matching the keyword in the
+// talker's room, like "2.guard" for the second guard. Any conversation
+// they were having ends.
+func (m *Manager) Talk(t Talker, keyword string) error {
+	speaker := m.find(t.Location(), keyword)
+	if speaker == nil {
+		return &item.Refused{Message: NoOneMessage}
+	}
+	tree, ok := m.trees.ForNPC(speaker.Proto())
+	if !ok {
+		return &item.Refused{Message: SilentMessage}
+	}
+	s := State{Tree: tree.ID, Node: tree.Start, Speaker: speaker.Name()}
+	data := stateData(t, s)
+	data["npc"] = speaker.Proto()
+	if err := m.check(StartEvent, data); err != nil {
+		return err
+	}
+
+	m.End(t)
+	m.confirm(StartEvent, data)
+	m.enter(t, tree, s)
+
+	return nil
+}
+
+// Reply chooses the reply in the talker's conversation numbered or
+// matching the input, "bye" ends the conversation if no reply matches.
+func (m *Manager) Reply(t Talker, input string) error {
+	s, tree, node, ok := m.current(t)
+	if !ok {
+		return &item.Refused{Message: NotTalkingMessage}
+	}
+	if !m.present(t.Location(), s.Speaker) {
+		m.End(t)
+
+		return &item.Refused{Message: GoneMessage}
+	}
+
+	choices := m.available(t, s.Speaker, node)
+	var chosen *Choice
+	if n, err := strconv.Atoi(strings.TrimSpace(input)); err == nil {
+		if n > 0 && n <= len(choices) {
+			chosen = &choices[n-1]
+		}
+	} else {
+		for i := range choices {
+			if choices[i].Matches(input) {
+				chosen = &choices[i]
+
+				break
+			}
+		}
+	}
+	if chosen == nil {
+		word := strings.ToLower(strings.TrimSpace(input))
+		if word == "bye" || word == "goodbye" {
+			m.End(t)
+
+			return nil
+		}
+
+		return &item.Refused{Message: NoChoiceMessage}
+	}
+
+	send(t, fmt.Sprintf("You say, \"%s\"", chosen.Text))
+	data := stateData(t, s)
+	data["choice"] = chosen.Text
+	m.emit(ChooseEvent, data)
+	m.run(chosen.Do, t, s.Speaker)
+
+	// the action may have ended or changed the conversation
+	if now, _, _, ok := m.current(t); !ok || now != s {
+		return nil
+	}
+	if chosen.Next == "" {
+		m.End(t)
+
+		return nil
+	}
+	s.Node = chosen.Next
+	m.enter(t, tree, s)
+
+	return nil
+}
+
+// Show tells the talker what was last said in their conversation and their
+// choices, like when they come back to the game mid-conversation. It returns
+// false if they aren't talking to anyone.
+func (m *Manager) Show(t Talker) bool {
+	s, _, node, ok := m.current(t)
+	if !ok {
+		return false
+	}
+	m.show(t, s, node, m.available(t, s.Speaker, node))
+
+	return true
+}
+
+// End ends the talker's conversation, returning false if they weren't having
+// one.
+func (m *Manager) End(t Talker) bool {
+	s, ok := t.Conversation()
+	if !ok {
+		return false
+	}
+	t.SetConversation(State{})
+	if d, ok := t.(DataSender); ok {
+		d.SendData(EndPackage, nil)
+	}
+	m.emit(EndEvent, stateData(t, s))
+
+	return true
+}
+
+// enter moves the talker to the node, ending the conversation there if they
+// have no replies to choose
+func (m *Manager) enter(t Talker, tree Tree, s State) {
+	node := tree.Nodes[s.Node]
+	t.SetConversation(s)
+	m.run(node.Do, t, s.Speaker)
+	if now, ok := t.Conversation(); !ok || now != s {
+		return
+	}
+
+	choices := m.available(t, s.Speaker, node)
+	m.show(t, s, node, choices)
+	if len(choices) == 0 {
+		m.End(t)
+	}
+}
+
+// show tells the talker what the speaker says and numbers their choices
+func (m *Manager) show(t Talker, s State, node Node, choices []Choice) {
+	lines := []string{fmt.Sprintf("%s says, \"%s\"", capitalize(s.Speaker), node.Text)}
+	list := make([]map[string]interface{}, len(choices))
+	for i, c := range choices {
+		lines = append(lines, fmt.Sprintf("  %d) %s", i+1, c.Text))
+		list[i] = map[string]interface{}{"n": i + 1, "text": c.Text}
+	}
+	send(t, strings.Join(lines, "\n"))
+	if d, ok := t.(DataSender); ok {
+		d.SendData(NodePackage, map[string]interface{}{
+			"speaker": s.Speaker,
+			"text":    node.Text,
+			"choices": list,
+		})
+	}
+}
+
+// current returns the talker's conversation and the node they're at, ending
+// it if it no longer exists
+func (m *Manager) current(t Talker) (State, Tree, Node, bool) {
+	s, ok := t.Conversation()
+	if !ok {
+		return s, Tree{}, Node{}, false
+	}
+	tree, ok := m.trees.Get(s.Tree)
+	node, found := tree.Nodes[s.Node]
+	if !ok || !found {
+		m.End(t)
+
+		return s, tree, node, false
+	}
+
+	return s, tree, node, true
+}
+
+// available returns the node's replies whose conditions allow them
+func (m *Manager) available(t Talker, speaker string, node Node) []Choice {
+	var choices []Choice
+	for _, c := range node.Choices {
+		if m.allowed(c.If, t, speaker) {
+			choices = append(choices, c)
+		}
+	}
+
+	return choices
+}
+
+// allowed is true if the named condition is met, conditions that aren't set
+// are never met
+func (m *Manager) allowed(name string, t Talker, speaker string) bool {
+	if name == "" {
+		return true
+	}
+	negate := strings.HasPrefix(name, "!")
+	name = strings.TrimPrefix(name, "!")
+
+	m.mutex.RLock()
+	fn := m.conditions[name]
+	m.mutex.RUnlock()
+
+	if fn == nil {
+		return false
+	}
+
+	return fn(t, speaker) != negate
+}
+
+// run runs the named action, if there is one
+func (m *Manager) run(name string, t Talker, speaker string) {
+	m.mutex.RLock()
+	fn := m.actions[name]
+	m.mutex.RUnlock()
+
+	if fn != nil {
+		fn(t, speaker)
+	}
+}
+
+// find returns the speaker in the room matching the keyword
+func (m *Manager) find(room, keyword string) Speaker {
+	m.mutex.RLock()
+	nearby := m.nearby
+	m.mutex.RUnlock()
+
+	if nearby == nil {
+		return nil
+	}
+	n, keyword := item.Ordinal(keyword)
+	for _, s := range nearby(room) {
+		if s.Matches(keyword) {
+			n--
+			if n == 0 {
+				return s
+			}
+		}
+	}
+
+	return nil
+}
+
+// present is true if the speaker with the name is in the room, or if there's
+// no way to tell
+func (m *Manager) present(room, name string) bool {
+	m.mutex.RLock()
+	nearby := m.nearby
+	m.mutex.RUnlock()
+
+	if nearby == nil {
+		return true
+	}
+	for _, s := range nearby(room) {
+		if s.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func send(t Talker, text string) {
+	if s, ok := t.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+func stateData(t Talker, s State) events.Data {
+	return events.Data{
+		"player":      t.ID(),
+		"player_name": t.Name(),
+		"dialogue":    s.Tree,
+		"node":        s.Node,
+		"speaker":     s.Speaker,
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
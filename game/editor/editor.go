@@ -0,0 +1,313 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package editor is a line editor for writing text longer than a command,
+// like room descriptions and mail. While a player edits, every line they type
+// goes to the editor rather than running commands. Plain lines are added to
+// the text and lines starting with a dot are editor commands, like .s to save.
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/text/strutil"
+)
+
+// Defaults for new editors.
+const (
+	// DefaultWidth is the width .f formats text to.
+	DefaultWidth = 78
+	// DefaultLimit is the most lines the text can have.
+	DefaultLimit = 200
+)
+
+// Messages shown to the player while editing.
+const (
+	IntroMessage   = "Enter your text, a line at a time. Type .h for help or .s when you're done."
+	SavedMessage   = "Saved."
+	AbortedMessage = "Discarded your changes."
+	LimitMessage   = "Your text can't be any longer."
+	UnknownMessage = "Unknown editor command, type .h for help."
+	HelpMessage    = `Editor commands:
+  .s          save the text and stop editing
+  .q          stop editing without saving
+  .l          list the text with line numbers
+  .c          clear the text
+  .d [n]      delete line n, or the last line
+  .i n text   insert text before line n
+  .r n text   replace line n with text
+  .f          format the text into wrapped paragraphs
+  .h          show this help
+Start a line with .. to add a line beginning with a dot.`
+)
+
+// Editor is the text a player is writing, it's a command.Mode routing their
+// input into it.
+type Editor struct {
+	lines    []string
+	width    int
+	limit    int
+	onSave   func(text string)
+	onCancel func()
+	mutex    *sync.Mutex
+}
+
+// New creates an editor starting with the text, calling save with the new
+// text when the player saves it.
+func New(text string, save func(text string)) *Editor {
+	e := &Editor{
+		width:  DefaultWidth,
+		limit:  DefaultLimit,
+		onSave: save,
+		mutex:  new(sync.Mutex),
+	}
+	if text != "" {
+		e.lines = strings.Split(strings.TrimRight(text, "\n"), "\n")
+	}
+
+	return e
+}
+
+// Open puts the caller into the editor, they're told how to use it and shown
+// the text they're starting with.
+func Open(d *command.Dispatcher, caller command.Caller, e *Editor) {
+	d.Enter(caller, e)
+	caller.Send(IntroMessage)
+	if text := e.list(); text != "" {
+		caller.Send(text)
+	}
+}
+
+// SetWidth sets the width .f formats text to.
+func (e *Editor) SetWidth(width int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.width = width
+}
+
+// SetLimit sets the most lines the text can have, zero allows any number.
+func (e *Editor) SetLimit(lines int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.limit = lines
+}
+
+// OnCancel sets the function called when the player quits without saving.
+func (e *Editor) OnCancel(fn func()) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.onCancel = fn
+}
+
+// Text returns the text as it is now.
+func (e *Editor) Text() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return strings.Join(e.lines, "\n")
+}
+
+// Input adds the line to the text or runs the editor command it contains,
+// returning true once the player saves or quits.
+func (e *Editor) Input(caller command.Caller, line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, ".") || strings.HasPrefix(line, "..") {
+		if strings.HasPrefix(line, "..") {
+			line = line[1:]
+		}
+		if !e.insert(-1, line) {
+			caller.Send(LimitMessage)
+		}
+
+		return false
+	}
+
+	fields := strings.SplitN(line, " ", 3)
+	switch fields[0] {
+	case ".s":
+		e.mutex.Lock()
+		text, fn := strings.Join(e.lines, "\n"), e.onSave
+		e.mutex.Unlock()
+		caller.Send(SavedMessage)
+		if fn != nil {
+			fn(text)
+		}
+
+		return true
+	case ".q":
+		e.mutex.Lock()
+		fn := e.onCancel
+		e.mutex.Unlock()
+		caller.Send(AbortedMessage)
+		if fn != nil {
+			fn()
+		}
+
+		return true
+	case ".h":
+		caller.Send(HelpMessage)
+	case ".l":
+		if text := e.list(); text != "" {
+			caller.Send(text)
+		} else {
+			caller.Send("There's no text yet.")
+		}
+	case ".c":
+		e.mutex.Lock()
+		e.lines = nil
+		e.mutex.Unlock()
+		caller.Send("Cleared.")
+	case ".d":
+		n := e.Len()
+		if len(fields) > 1 {
+			n = e.lineNumber(caller, fields[1])
+		}
+		if n > 0 && e.delete(n) {
+			caller.Send(fmt.Sprintf("Deleted line %d.", n))
+		} else if n == 0 && len(fields) == 1 {
+			caller.Send("There's no text yet.")
+		}
+	case ".i", ".r":
+		if len(fields) < 2 {
+			caller.Send(fmt.Sprintf("Usage: %s <line> <text>", fields[0]))
+
+			break
+		}
+		n := e.lineNumber(caller, fields[1])
+		if n == 0 {
+			break
+		}
+		text := ""
+		if len(fields) > 2 {
+			text = fields[2]
+		}
+		if fields[0] == ".r" {
+			e.replace(n, text)
+			caller.Send(fmt.Sprintf("Replaced line %d.", n))
+		} else if e.insert(n-1, text) {
+			caller.Send(fmt.Sprintf("Inserted before line %d.", n))
+		} else {
+			caller.Send(LimitMessage)
+		}
+	case ".f":
+		e.format()
+		caller.Send("Formatted.")
+	default:
+		caller.Send(UnknownMessage)
+	}
+
+	return false
+}
+
+// Len returns the number of lines in the text.
+func (e *Editor) Len() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.lines)
+}
+
+// lineNumber parses a line number, telling the caller if it isn't one of the
+// lines of the text and returning 0
+func (e *Editor) lineNumber(caller command.Caller, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > e.Len() {
+		caller.Send(fmt.Sprintf("There's no line %s.", s))
+
+		return 0
+	}
+
+	return n
+}
+
+// insert adds the line at the index, or the end if it's negative, returning
+// false if the text is at its limit
+func (e *Editor) insert(i int, line string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.limit > 0 && len(e.lines) >= e.limit {
+		return false
+	}
+	if i < 0 || i >= len(e.lines) {
+		e.lines = append(e.lines, line)
+
+		return true
+	}
+	e.lines = append(e.lines, "")
+	copy(e.lines[i+1:], e.lines[i:])
+	e.lines[i] = line
+
+	return true
+}
+
+// delete removes line n, counting from 1
+func (e *Editor) delete(n int) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if n < 1 || n > len(e.lines) {
+		return false
+	}
+	e.lines = append(e.lines[:n-1], e.lines[n:]...)
+
+	return true
+}
+
+// replace changes line n, counting from 1
+func (e *Editor) replace(n int, line string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if n >= 1 && n <= len(e.lines) {
+		e.lines[n-1] = line
+	}
+}
+
+// format joins each paragraph, separated by blank lines, and wraps it
+func (e *Editor) format() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var (
+		paragraphs []string
+		words      []string
+	)
+	for _, line := range append(e.lines, "") {
+		if strings.TrimSpace(line) == "" {
+			if len(words) > 0 {
+				paragraphs = append(paragraphs, strutil.Wrap(strings.Join(words, " "), e.width))
+				words = nil
+			}
+
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+
+	if len(paragraphs) == 0 {
+		e.lines = nil
+
+		return
+	}
+	e.lines = strings.Split(strings.Join(paragraphs, "\n\n"), "\n")
+}
+
+// list numbers the lines of the text
+func (e *Editor) list() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	numbered := make([]string, len(e.lines))
+	for i, line := range e.lines {
+		numbered[i] = fmt.Sprintf("%3d| %s", i+1, line)
+	}
+
+	return strings.Join(numbered, "\n")
+}
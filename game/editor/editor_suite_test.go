@@ -0,0 +1,13 @@
+package editor_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Editor Suite")
+}
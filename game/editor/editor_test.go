@@ -0,0 +1,141 @@
+package editor_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/editor"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writer records what the editor tells them
+type writer struct {
+	sent []string
+}
+
+func (w *writer) ID() string {
+	return "writer-1"
+}
+
+func (w *writer) Level() command.Level {
+	return command.Builder
+}
+
+func (w *writer) Send(text string) error {
+	w.sent = append(w.sent, text)
+
+	return nil
+}
+
+func (w *writer) last() string {
+	return w.sent[len(w.sent)-1]
+}
+
+var _ = Describe("Editor", func() {
+	var (
+		w     *writer
+		e     *Editor
+		saved *string
+		input func(lines ...string) bool
+	)
+
+	BeforeEach(func() {
+		w = new(writer)
+		saved = nil
+		e = New("", func(text string) {
+			saved = &text
+		})
+		input = func(lines ...string) bool {
+			done := false
+			for _, line := range lines {
+				done = e.Input(w, line)
+			}
+
+			return done
+		}
+	})
+
+	It("appends lines and saves the text", func() {
+		Ω(input("A dusty room.", "Cobwebs hang in the corners.")).Should(BeFalse())
+		Ω(input(".s")).Should(BeTrue())
+
+		Ω(*saved).Should(Equal("A dusty room.\nCobwebs hang in the corners."))
+		Ω(w.last()).Should(Equal(SavedMessage))
+	})
+
+	It("quits without saving", func() {
+		cancelled := false
+		e.OnCancel(func() {
+			cancelled = true
+		})
+
+		Ω(input("draft", ".q")).Should(BeTrue())
+		Ω(saved).Should(BeNil())
+		Ω(cancelled).Should(BeTrue())
+	})
+
+	It("starts from existing text", func() {
+		e = New("one\ntwo\n", nil)
+
+		Ω(e.Len()).Should(Equal(2))
+		e.Input(w, ".l")
+		Ω(w.last()).Should(Equal("  1| one\n  2| two"))
+	})
+
+	It("inserts, replaces and deletes lines", func() {
+		input("one", "three", ".i 2 two", ".r 3 THREE", "four", ".d 1", ".d")
+
+		Ω(e.Text()).Should(Equal("two\nTHREE"))
+	})
+
+	It("adds lines starting with a dot after escaping them", func() {
+		input("..and so on")
+
+		Ω(e.Text()).Should(Equal(".and so on"))
+	})
+
+	It("rejects lines that don't exist", func() {
+		input("one", ".d 5", ".r x text", ".i 2")
+
+		Ω(e.Text()).Should(Equal("one"))
+		Ω(w.sent).Should(Equal([]string{"There's no line 5.", "There's no line x.", "There's no line 2."}))
+	})
+
+	It("formats paragraphs", func() {
+		e.SetWidth(12)
+		input("the quick", "brown fox jumps", "", "", "over  the dog", ".f")
+
+		Ω(e.Text()).Should(Equal("the quick\nbrown fox\njumps\n\nover the dog"))
+	})
+
+	It("clears the text", func() {
+		input("one", ".c")
+
+		Ω(e.Len()).Should(BeZero())
+	})
+
+	It("limits the number of lines", func() {
+		e.SetLimit(1)
+		input("one", "two")
+
+		Ω(e.Text()).Should(Equal("one"))
+		Ω(w.last()).Should(Equal(LimitMessage))
+	})
+
+	It("explains unknown commands", func() {
+		input(".z")
+
+		Ω(w.last()).Should(Equal(UnknownMessage))
+	})
+
+	It("takes over input from the dispatcher", func() {
+		d := command.NewDispatcher(command.NewRegistry(), nil)
+		Open(d, w, e)
+
+		Ω(d.Dispatch(w, "hello")).Should(Succeed())
+		Ω(d.Dispatch(w, ".s")).Should(Succeed())
+		Ω(*saved).Should(Equal("hello"))
+		Ω(d.Mode(w.ID())).Should(BeNil())
+		Ω(w.sent[0]).Should(Equal(IntroMessage))
+	})
+})
@@ -0,0 +1,60 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package effect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// NewCommands creates the affects command, listing the caller's effects.
+func NewCommands(m *Manager, resolve command.Resolver[Bearer]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:      "affects",
+			Aliases:   []string{"effects"},
+			MinAbbrev: 3,
+			Help:      "Lists the effects you're under and how long they'll last.",
+			Source:    "game",
+			Handler: func(ctx *command.Context) error {
+				b := resolve(ctx.Caller)
+				if b == nil {
+					return ctx.Send("You aren't affected by anything.")
+				}
+
+				return ctx.Send(listing(b.Effects()))
+			},
+		},
+	}
+}
+
+// listing describes the effects, one to a line
+func listing(l List) string {
+	if len(l) == 0 {
+		return "You aren't affected by anything."
+	}
+	lines := []string{"You are affected by:"}
+	for _, e := range l {
+		line := "  " + e.Name
+		if e.Stacks > 1 {
+			line += fmt.Sprintf(" (x%d)", e.Stacks)
+		}
+		var mods []string
+		for _, stat := range sortedStats(e.Stats) {
+			mods = append(mods, fmt.Sprintf("%+d %s", e.Stats[stat]*e.Stacks, stat))
+		}
+		if len(mods) > 0 {
+			line += " " + strings.Join(mods, ", ")
+		}
+		if e.Permanent {
+			line += ", permanently"
+		} else {
+			line += fmt.Sprintf(", for %d more ticks", e.Ticks)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
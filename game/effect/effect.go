@@ -0,0 +1,102 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package effect puts buffs and debuffs on characters. An effect changes its
+// bearer's stats while it lasts and wears off after a number of effect
+// pulses, what it does each pulse, like poison hurting or regeneration
+// healing, is set by name from Go or Lua. Effects are kept on their bearer,
+// so players keep them across saves.
+package effect
+
+// How an effect is applied to a bearer who already has one with its name.
+const (
+	// Refresh effects last as long as the longer of the two, the default.
+	Refresh = "refresh"
+	// Extend effects add the new duration to what's left.
+	Extend = "extend"
+	// Stack effects add a stack, up to MaxStacks, with another set of stat
+	// changes, and last the new duration.
+	Stack = "stack"
+	// Ignore effects can't be applied again until the first wears off.
+	Ignore = "ignore"
+)
+
+// Effect is a buff or debuff on a character.
+type Effect struct {
+	// Name identifies the effect, bearers have one effect of each name.
+	Name string `json:"name" yaml:"name"`
+	// Source is what gave the effect, like the id of a skill.
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Ticks is how many more effect pulses the effect lasts, it never wears
+	// off if it's Permanent.
+	Ticks     int  `json:"ticks" yaml:"ticks"`
+	Permanent bool `json:"permanent,omitempty" yaml:"permanent,omitempty"`
+	// Stats are added to the bearer's stats for each stack of the effect and
+	// taken away when it ends.
+	Stats map[string]int `json:"stats,omitempty" yaml:"stats,omitempty"`
+	// Stacking is how the effect is applied again, Refresh by default.
+	Stacking  string `json:"stacking,omitempty" yaml:"stacking,omitempty"`
+	Stacks    int    `json:"stacks" yaml:"stacks"`
+	MaxStacks int    `json:"max_stacks,omitempty" yaml:"max_stacks,omitempty"`
+	// WearOff is told to the bearer when the effect ends.
+	WearOff string `json:"wear_off,omitempty" yaml:"wear_off,omitempty"`
+	// Data is kept for the effect's tick handler, like the damage a poison
+	// does. It must be encodable as JSON.
+	Data map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// Copy returns a deep copy of the effect.
+func (e Effect) Copy() Effect {
+	if e.Stats != nil {
+		stats := make(map[string]int, len(e.Stats))
+		for k, v := range e.Stats {
+			stats[k] = v
+		}
+		e.Stats = stats
+	}
+	if e.Data != nil {
+		data := make(map[string]interface{}, len(e.Data))
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		e.Data = data
+	}
+
+	return e
+}
+
+// List is the effects on a bearer.
+type List []Effect
+
+// Copy returns a deep copy of the list.
+func (l List) Copy() List {
+	if l == nil {
+		return nil
+	}
+	effects := make(List, len(l))
+	for i, e := range l {
+		effects[i] = e.Copy()
+	}
+
+	return effects
+}
+
+// Find returns the index of the effect with the name, or -1.
+func (l List) Find(name string) int {
+	for i, e := range l {
+		if e.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Bearer is a character who can have effects, like a player or a mob.
+type Bearer interface {
+	ID() string
+	Name() string
+	AddStat(stat string, delta int) int
+	Effects() List
+	// UpdateEffects changes the bearer's effects with fn.
+	UpdateEffects(fn func(List) List)
+}
@@ -0,0 +1,13 @@
+package effect_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEffect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Effect Suite")
+}
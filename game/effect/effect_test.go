@@ -0,0 +1,186 @@
+package effect_test
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/effect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// bearer is a character that records what it's told
+type bearer struct {
+	name    string
+	stats   map[string]int
+	effects List
+	sent    []string
+}
+
+func (b *bearer) ID() string {
+	return b.name
+}
+
+func (b *bearer) Name() string {
+	return b.name
+}
+
+func (b *bearer) AddStat(stat string, delta int) int {
+	b.stats[stat] += delta
+
+	return b.stats[stat]
+}
+
+func (b *bearer) Effects() List {
+	return b.effects.Copy()
+}
+
+func (b *bearer) UpdateEffects(fn func(List) List) {
+	b.effects = fn(b.effects.Copy())
+}
+
+func (b *bearer) Level() command.Level {
+	return command.Player
+}
+
+func (b *bearer) Send(text string) error {
+	b.sent = append(b.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		em    *events.Emitter
+		m     *Manager
+		alice *bearer
+		armor Effect
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		m = NewManager(em)
+		alice = &bearer{name: "Alice", stats: map[string]int{"hp": 20, "ac": 0}}
+		armor = Effect{Name: "armor", Source: "armor", Ticks: 2, Stats: map[string]int{"ac": 2}, WearOff: "You feel less protected."}
+	})
+
+	It("applies stat changes until the effect wears off", func() {
+		Ω(m.Apply(alice, armor)).Should(Succeed())
+		Ω(alice.stats["ac"]).Should(Equal(2))
+		e, ok := m.Get(alice, "armor")
+		Ω(ok).Should(BeTrue())
+		Ω(e.Stacks).Should(Equal(1))
+
+		m.Tick()
+		Ω(alice.effects[0].Ticks).Should(Equal(1))
+		m.Tick()
+		Ω(alice.effects).Should(BeEmpty())
+		Ω(alice.stats["ac"]).Should(Equal(0))
+		Ω(alice.sent).Should(Equal([]string{"You feel less protected."}))
+	})
+
+	It("follows stacking rules", func() {
+		Ω(m.Apply(alice, armor)).Should(Succeed())
+		longer := armor
+		longer.Ticks = 5
+		Ω(m.Apply(alice, longer)).Should(Succeed())
+		Ω(alice.effects[0].Ticks).Should(Equal(5))
+		Ω(alice.stats["ac"]).Should(Equal(2))
+
+		longer.Stacking = Extend
+		Ω(m.Apply(alice, longer)).Should(Succeed())
+		Ω(alice.effects[0].Ticks).Should(Equal(10))
+
+		longer.Stacking = Ignore
+		Ω(m.Apply(alice, longer)).Should(MatchError(NoFurtherMessage))
+
+		rage := Effect{Name: "rage", Ticks: 3, Stats: map[string]int{"str": 1}, Stacking: Stack, MaxStacks: 2}
+		for i := 0; i < 3; i++ {
+			Ω(m.Apply(alice, rage)).Should(Succeed())
+		}
+		Ω(alice.stats["str"]).Should(Equal(2))
+		Ω(m.Dispel(alice, "rage")).Should(Succeed())
+		Ω(alice.stats["str"]).Should(Equal(0))
+		Ω(m.Dispel(alice, "rage")).Should(MatchError(NotAffectedMessage))
+	})
+
+	It("runs tick handlers, even for permanent effects", func() {
+		m.OnTick("poison", func(b Bearer, e Effect) {
+			b.AddStat("hp", -e.Data["damage"].(int))
+		})
+		Ω(m.Apply(alice, Effect{Name: "poison", Permanent: true, Data: map[string]interface{}{"damage": 3}})).Should(Succeed())
+		m.Tick()
+		m.Tick()
+		Ω(alice.stats["hp"]).Should(Equal(14))
+		Ω(alice.effects).Should(HaveLen(1))
+	})
+
+	It("rejects effects that don't make sense", func() {
+		Ω(m.Apply(alice, Effect{Ticks: 1})).ShouldNot(Succeed())
+		Ω(m.Apply(alice, Effect{Name: "x"})).ShouldNot(Succeed())
+		Ω(m.Apply(alice, Effect{Name: "x", Ticks: 1, Stacking: "twice"})).ShouldNot(Succeed())
+	})
+
+	It("lets before handlers block effects and hears about expiry", func(done Done) {
+		expired := make(chan events.Data, 1)
+		em.On("before:"+ApplyEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["effect"] == "poison" {
+				return errors.New("Alice is immune to poison.")
+			}
+
+			return nil
+		}))
+		em.On(ExpireEvent, events.HandlerFunc(func(d events.Data) error {
+			expired <- d
+
+			return nil
+		}))
+
+		Ω(m.Apply(alice, Effect{Name: "poison", Ticks: 1})).Should(MatchError("Alice is immune to poison."))
+		armor.Ticks = 1
+		Ω(m.Apply(alice, armor)).Should(Succeed())
+		m.Tick()
+
+		d := <-expired
+		Ω(d["bearer"]).Should(Equal("Alice"))
+		Ω(d["effect"]).Should(Equal("armor"))
+		close(done)
+	})
+
+	It("ticks bearers it's told to track", func() {
+		alice.effects = List{armor}
+		m.Tick()
+		Ω(alice.effects[0].Ticks).Should(Equal(2))
+
+		m.Track(alice)
+		m.Tick()
+		Ω(alice.effects[0].Ticks).Should(Equal(1))
+		m.Forget("Alice")
+		m.Tick()
+		Ω(alice.effects[0].Ticks).Should(Equal(1))
+	})
+
+	It("lists effects with the affects command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Bearer {
+			return c.(*bearer)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+
+		Ω(dispatcher.Dispatch(alice, "aff")).Should(Succeed())
+		rage := Effect{Name: "rage", Permanent: true, Stats: map[string]int{"str": 1, "ac": -1}, Stacking: Stack}
+		m.Apply(alice, rage)
+		m.Apply(alice, rage)
+		m.Apply(alice, armor)
+		Ω(dispatcher.Dispatch(alice, "affects")).Should(Succeed())
+
+		Ω(alice.sent).Should(Equal([]string{
+			"You aren't affected by anything.",
+			"You are affected by:\n  rage (x2) -2 ac, +2 str, permanently\n  armor +2 ac, for 2 more ticks",
+		}))
+	})
+})
@@ -0,0 +1,391 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package effect
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// The events of effects. Handlers of before:effect:apply and
+// before:effect:dispel can stop them by returning events.ErrHalt, or an
+// error whose message is told to whoever tried, like for a bearer immune to
+// poison. Each is given the id and name of the bearer, the effect's name and
+// source and its ticks and stacks. Ticks are emitted every effect pulse for
+// each effect, expiries when an effect wears off.
+const (
+	ApplyEvent  = "effect:apply"
+	TickEvent   = "effect:tick"
+	ExpireEvent = "effect:expire"
+	DispelEvent = "effect:dispel"
+)
+
+// Messages told to those who can't apply or dispel an effect.
+const (
+	NoFurtherMessage   = "That has no further effect."
+	NotAffectedMessage = "They aren't affected by that."
+	CancelMessage      = "You can't do that right now."
+)
+
+// TickFunc is what an effect does each effect pulse, it's given the effect
+// as it is before the pulse counts it down.
+type TickFunc func(b Bearer, e Effect)
+
+// Sender is a bearer told when their effects wear off.
+type Sender interface {
+	Send(text string) error
+}
+
+// Manager applies effects and ticks them down.
+type Manager struct {
+	bearers map[string]Bearer
+	ticks   map[string]TickFunc
+	emitter *events.Emitter
+	stop    chan struct{}
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager checking and emitting events with the
+// emitter, which may be nil.
+func NewManager(em *events.Emitter) *Manager {
+	return &Manager{
+		bearers: make(map[string]Bearer),
+		ticks:   make(map[string]TickFunc),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's effect manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// OnTick sets what effects with the name do each pulse, replacing what they
+// did.
+func (m *Manager) OnTick(name string, fn TickFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ticks[name] = fn
+}
+
+// Apply puts the effect on the bearer, following its stacking rule if they
+// already have it.
+func (m *Manager) Apply(b Bearer, e Effect) error {
+	e = e.Copy()
+	switch {
+	case e.Name == "":
+		return fmt.Errorf("effects need a name")
+	case e.Ticks <= 0 && !e.Permanent:
+		return fmt.Errorf("effect %s: needs ticks or to be permanent", e.Name)
+	}
+	if e.Stacking == "" {
+		e.Stacking = Refresh
+	}
+	switch e.Stacking {
+	case Refresh, Extend, Stack, Ignore:
+	default:
+		return fmt.Errorf("effect %s: stacking must be refresh, extend, stack or ignore", e.Name)
+	}
+	if e.Stacks < 1 {
+		e.Stacks = 1
+	}
+
+	data := effectData(b, e)
+	if err := m.check(ApplyEvent, data); err != nil {
+		return err
+	}
+
+	var changes map[string]int
+	times, ignored := e.Stacks, false
+	b.UpdateEffects(func(l List) List {
+		i := l.Find(e.Name)
+		if i < 0 {
+			changes = e.Stats
+
+			return append(l, e)
+		}
+
+		old := l[i]
+		switch e.Stacking {
+		case Ignore:
+			ignored = true
+		case Extend:
+			old.Ticks += e.Ticks
+		case Stack:
+			if e.MaxStacks == 0 || old.Stacks < e.MaxStacks {
+				old.Stacks++
+				changes, times = old.Stats, 1
+			}
+			old.Ticks = e.Ticks
+		default:
+			if e.Ticks > old.Ticks {
+				old.Ticks = e.Ticks
+			}
+		}
+		old.Permanent = old.Permanent || e.Permanent
+		l[i] = old
+		data["ticks"], data["stacks"] = old.Ticks, old.Stacks
+
+		return l
+	})
+	if ignored {
+		return &item.Refused{Message: NoFurtherMessage}
+	}
+	for _, stat := range sortedStats(changes) {
+		b.AddStat(stat, changes[stat]*times)
+	}
+
+	m.mutex.Lock()
+	m.bearers[b.ID()] = b
+	m.mutex.Unlock()
+
+	m.confirm(ApplyEvent, data)
+
+	return nil
+}
+
+// Get returns the bearer's effect with the name.
+func (m *Manager) Get(b Bearer, name string) (Effect, bool) {
+	l := b.Effects()
+	if i := l.Find(name); i >= 0 {
+		return l[i], true
+	}
+
+	return Effect{}, false
+}
+
+// Dispel ends the bearer's effect with the name before it wears off.
+func (m *Manager) Dispel(b Bearer, name string) error {
+	e, ok := m.Get(b, name)
+	if !ok {
+		return &item.Refused{Message: NotAffectedMessage}
+	}
+	data := effectData(b, e)
+	if err := m.check(DispelEvent, data); err != nil {
+		return err
+	}
+	if _, ok := m.remove(b, name); ok {
+		m.confirm(DispelEvent, data)
+	}
+
+	return nil
+}
+
+// Track ticks the effects of the bearer, like a player who logged in with
+// effects saved on them. Bearers are tracked when an effect is applied.
+func (m *Manager) Track(b Bearer) {
+	if len(b.Effects()) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.bearers[b.ID()] = b
+}
+
+// Forget stops ticking the effects of the bearer with the id, like when they
+// leave the game. Their effects stay on them.
+func (m *Manager) Forget(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.bearers, id)
+}
+
+// Tick runs what each effect does and counts it down, ending those that wear
+// off.
+func (m *Manager) Tick() {
+	m.mutex.RLock()
+	bearers := make([]Bearer, 0, len(m.bearers))
+	for _, b := range m.bearers {
+		bearers = append(bearers, b)
+	}
+	m.mutex.RUnlock()
+
+	for _, b := range bearers {
+		for _, e := range b.Effects() {
+			m.mutex.RLock()
+			fn := m.ticks[e.Name]
+			m.mutex.RUnlock()
+
+			if fn != nil {
+				fn(b, e.Copy())
+			}
+			m.emit(TickEvent, effectData(b, e))
+		}
+
+		var expired []string
+		b.UpdateEffects(func(l List) List {
+			for i := range l {
+				if l[i].Permanent {
+					continue
+				}
+				l[i].Ticks--
+				if l[i].Ticks <= 0 {
+					expired = append(expired, l[i].Name)
+				}
+			}
+
+			return l
+		})
+		for _, name := range expired {
+			if e, ok := m.remove(b, name); ok {
+				m.emit(ExpireEvent, effectData(b, e))
+			}
+		}
+
+		if len(b.Effects()) == 0 {
+			m.Forget(b.ID())
+		}
+	}
+}
+
+// Start ticks every interval in the background until stopped.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil || interval <= 0 {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Tick()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+// Stop halts ticking.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// remove takes the effect off the bearer, undoing its stat changes and
+// telling them it wore off
+func (m *Manager) remove(b Bearer, name string) (Effect, bool) {
+	var (
+		removed Effect
+		ok      bool
+	)
+	b.UpdateEffects(func(l List) List {
+		i := l.Find(name)
+		if i < 0 {
+			return l
+		}
+		removed, ok = l[i], true
+
+		return append(l[:i:i], l[i+1:]...)
+	})
+	if !ok {
+		return removed, false
+	}
+
+	for _, stat := range sortedStats(removed.Stats) {
+		b.AddStat(stat, -removed.Stats[stat]*removed.Stacks)
+	}
+	if s, isSender := b.(Sender); isSender && removed.WearOff != "" {
+		s.Send(removed.WearOff)
+	}
+
+	return removed, true
+}
+
+func effectData(b Bearer, e Effect) events.Data {
+	return events.Data{
+		"bearer":      b.ID(),
+		"bearer_name": b.Name(),
+		"effect":      e.Name,
+		"source":      e.Source,
+		"ticks":       e.Ticks,
+		"stacks":      e.Stacks,
+	}
+}
+
+func sortedStats(stats map[string]int) []string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package equipment
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Slot is a place on a body something can be worn.
+type Slot struct {
+	// ID names the slot, like "finger_l".
+	ID string `yaml:"id"`
+	// Name is shown beside what's worn in the slot, like "on left finger".
+	Name string `yaml:"name"`
+	// Accepts is the wear location items list to be worn in the slot, like
+	// "finger" for either hand's finger slot.
+	Accepts string `yaml:"accepts"`
+}
+
+// Body is the set of slots a kind of body has, bodies are files like:
+//   name: humanoid
+//   races: [human, elf]
+//   slots:
+//     - {id: head, name: on head, accepts: head}
+//     - {id: finger_l, name: on left finger, accepts: finger}
+type Body struct {
+	Name string `yaml:"name"`
+	// Races are the names of the races with the body.
+	Races []string `yaml:"races,omitempty"`
+	Slots []Slot   `yaml:"slots"`
+}
+
+// Slot returns the slot with the id.
+func (b Body) Slot(id string) (Slot, bool) {
+	for _, s := range b.Slots {
+		if strings.EqualFold(s.ID, id) {
+			return s, true
+		}
+	}
+
+	return Slot{}, false
+}
+
+// Humanoid is the body of races without one of their own, with the wear
+// locations of Diku derived games.
+var Humanoid = Body{
+	Name: "humanoid",
+	Slots: []Slot{
+		{ID: "light", Name: "used as light", Accepts: "light"},
+		{ID: "finger_l", Name: "worn on left finger", Accepts: "finger"},
+		{ID: "finger_r", Name: "worn on right finger", Accepts: "finger"},
+		{ID: "neck_1", Name: "worn around neck", Accepts: "neck"},
+		{ID: "neck_2", Name: "worn around neck", Accepts: "neck"},
+		{ID: "body", Name: "worn on body", Accepts: "body"},
+		{ID: "head", Name: "worn on head", Accepts: "head"},
+		{ID: "legs", Name: "worn on legs", Accepts: "legs"},
+		{ID: "feet", Name: "worn on feet", Accepts: "feet"},
+		{ID: "hands", Name: "worn on hands", Accepts: "hands"},
+		{ID: "arms", Name: "worn on arms", Accepts: "arms"},
+		{ID: "shield", Name: "worn as shield", Accepts: "shield"},
+		{ID: "about", Name: "worn about body", Accepts: "about"},
+		{ID: "waist", Name: "worn about waist", Accepts: "waist"},
+		{ID: "wrist_l", Name: "worn around left wrist", Accepts: "wrist"},
+		{ID: "wrist_r", Name: "worn around right wrist", Accepts: "wrist"},
+		{ID: "wield", Name: "wielded", Accepts: "wield"},
+		{ID: "hold", Name: "held", Accepts: "hold"},
+		{ID: "float", Name: "floating nearby", Accepts: "float"},
+	},
+}
+
+// Bodies holds the kinds of bodies and which races have them.
+type Bodies struct {
+	bodies map[string]Body
+	races  map[string]string
+	mutex  *sync.RWMutex
+}
+
+// NewBodies creates a set of bodies with only Humanoid in it.
+func NewBodies() *Bodies {
+	b := &Bodies{
+		bodies: make(map[string]Body),
+		races:  make(map[string]string),
+		mutex:  new(sync.RWMutex),
+	}
+	b.Add(Humanoid)
+
+	return b
+}
+
+// Add adds the body, replacing one with the same name.
+func (b *Bodies) Add(body Body) {
+	name := strings.ToLower(body.Name)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.bodies[name] = body
+	for _, race := range body.Races {
+		b.races[strings.ToLower(race)] = name
+	}
+}
+
+// Body returns the body with the name.
+func (b *Bodies) Body(name string) (Body, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	body, ok := b.bodies[strings.ToLower(name)]
+
+	return body, ok
+}
+
+// ForRace returns the body of the race, Humanoid unless another body lists
+// the race.
+func (b *Bodies) ForRace(race string) Body {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if body, ok := b.bodies[b.races[strings.ToLower(race)]]; ok {
+		return body
+	}
+
+	return b.bodies[strings.ToLower(Humanoid.Name)]
+}
+
+// LoadDir adds the bodies in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (b *Bodies) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := b.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the body described by the YAML document.
+func (b *Bodies) LoadYAML(contents []byte) error {
+	var body Body
+	if err := yaml.UnmarshalStrict(contents, &body); err != nil {
+		return err
+	}
+	if body.Name == "" {
+		return fmt.Errorf("bodies need a name")
+	}
+	ids := make(map[string]bool)
+	for i, s := range body.Slots {
+		switch {
+		case s.ID == "":
+			return fmt.Errorf("slot %d needs an id", i+1)
+		case ids[s.ID]:
+			return fmt.Errorf("there's already a slot %q", s.ID)
+		case s.Accepts == "":
+			return fmt.Errorf("slot %q needs to say what it accepts", s.ID)
+		}
+		ids[s.ID] = true
+		if s.Name == "" {
+			body.Slots[i].Name = s.ID
+		}
+	}
+	b.Add(body)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package equipment_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/game/equipment"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const centaur = `
+name: centaur
+races: [Centaur]
+slots:
+  - {id: head, name: worn on head, accepts: head}
+  - {id: legs_front, accepts: legs}
+  - {id: legs_back, accepts: legs}
+`
+
+var _ = Describe("Bodies", func() {
+	var bodies *Bodies
+
+	BeforeEach(func() {
+		bodies = NewBodies()
+	})
+
+	It("gives races without a body of their own the humanoid body", func() {
+		body := bodies.ForRace("dwarf")
+
+		Ω(body.Name).Should(Equal("humanoid"))
+		slot, ok := body.Slot("finger_r")
+		Ω(ok).Should(BeTrue())
+		Ω(slot.Accepts).Should(Equal("finger"))
+	})
+
+	It("loads bodies from YAML", func() {
+		Ω(bodies.LoadYAML([]byte(centaur))).Should(Succeed())
+
+		body := bodies.ForRace("centaur")
+		Ω(body.Name).Should(Equal("centaur"))
+		Ω(body.Slots).Should(HaveLen(3))
+		Ω(body.Slots[1].Name).Should(Equal("legs_front"))
+	})
+
+	It("refuses bodies with bad slots", func() {
+		Ω(bodies.LoadYAML([]byte("name: blob\nslots:\n  - {id: a}\n"))).ShouldNot(Succeed())
+		Ω(bodies.LoadYAML([]byte("name: blob\nslots:\n  - {id: a, accepts: a}\n  - {id: a, accepts: b}\n"))).ShouldNot(Succeed())
+		Ω(bodies.LoadYAML([]byte("slots: []\n"))).ShouldNot(Succeed())
+		_, ok := bodies.Body("blob")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("loads the bodies in a directory", func() {
+		dir, err := ioutil.TempDir("", "bodies")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Ω(ioutil.WriteFile(filepath.Join(dir, "centaur.yml"), []byte(centaur), 0644)).Should(Succeed())
+		Ω(ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a body"), 0644)).Should(Succeed())
+
+		Ω(bodies.LoadDir(dir)).Should(Succeed())
+		_, ok := bodies.Body("centaur")
+		Ω(ok).Should(BeTrue())
+		Ω(bodies.LoadDir(filepath.Join(dir, "missing"))).Should(Succeed())
+	})
+})
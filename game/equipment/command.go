@@ -0,0 +1,123 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package equipment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the wear, wield, hold, remove and equipment commands for
+// wearing items with m.
+func NewCommands(m *Manager, resolve command.Resolver[Wearer]) []*command.Command {
+	text := []command.Arg{{Name: "what", Kind: command.Text, Optional: true}}
+
+	return []*command.Command{
+		{
+			Name:    "wear",
+			Args:    text,
+			Help:    "Wears an item you carry, \"wear <item> <slot>\" picks where.",
+			Source:  "game",
+			Handler: wearHandler(m, resolve, "Wear what?", ""),
+		},
+		{
+			Name:    "wield",
+			Args:    text,
+			Help:    "Wields a weapon you carry.",
+			Source:  "game",
+			Handler: wearHandler(m, resolve, "Wield what?", "wield"),
+		},
+		{
+			Name:    "hold",
+			Args:    text,
+			Help:    "Holds an item you carry in your hand.",
+			Source:  "game",
+			Handler: wearHandler(m, resolve, "Hold what?", "hold"),
+		},
+		{
+			Name:    "remove",
+			Aliases: []string{"rem"},
+			Args:    text,
+			Help:    "Takes off something you're wearing.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, w Wearer) error {
+				if len(ctx.Input.Words) == 0 {
+					return ctx.Send("Remove what?")
+				}
+				it, _, err := m.Remove(w, ctx.Input.Words[0])
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You stop using %s.", it.Name))
+			}),
+		},
+		{
+			Name:      "equipment",
+			Aliases:   []string{"eq"},
+			MinAbbrev: 3,
+			Help:      "Lists what you're wearing.",
+			Source:    "game",
+			Handler: handler(resolve, func(ctx *command.Context, w Wearer) error {
+				worn := m.Worn(w)
+				if len(worn) == 0 {
+					return ctx.Send("You aren't wearing anything.")
+				}
+				width := 0
+				for _, wn := range worn {
+					if len(wn.Slot.Name) > width {
+						width = len(wn.Slot.Name)
+					}
+				}
+				lines := []string{"You are using:"}
+				for _, wn := range worn {
+					lines = append(lines, fmt.Sprintf("  <%s>%s %s", wn.Slot.Name, strings.Repeat(" ", width-len(wn.Slot.Name)), wn.Item.Name))
+				}
+
+				return ctx.Send(strings.Join(lines, "\n"))
+			}),
+		},
+	}
+}
+
+// wearHandler wears the item named by the first word, where limits the slots
+// it's worn in like the second word does when it's empty
+func wearHandler(m *Manager, resolve command.Resolver[Wearer], prompt, where string) command.Handler {
+	return handler(resolve, func(ctx *command.Context, w Wearer) error {
+		words := ctx.Input.Words
+		if len(words) == 0 {
+			return ctx.Send(prompt)
+		}
+		slot := where
+		if slot == "" && len(words) > 1 {
+			slot = strings.Join(words[1:], "_")
+		}
+		it, worn, err := m.Wear(w, words[0], slot)
+		if err != nil {
+			return err
+		}
+
+		return ctx.Send(fmt.Sprintf("You are using %s, %s.", it.Name, worn.Name))
+	})
+}
+
+// handler resolves the caller's wearer for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Wearer], fn func(*command.Context, Wearer) error) command.Handler {
+	return func(ctx *command.Context) error {
+		w := resolve(ctx.Caller)
+		if w == nil {
+			return ctx.Send("You can't wear anything.")
+		}
+
+		err := fn(ctx, w)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
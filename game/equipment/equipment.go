@@ -0,0 +1,322 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package equipment lets characters wear the items they carry. Where things
+// can be worn depends on the body of a character's race, an item lists the
+// wear locations it fits in its "wear" prop and the stats it changes while
+// worn in its "applies" prop, as Diku areas are imported.
+package equipment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// The events of wearing items. Handlers of the before: events can stop it by
+// returning events.ErrHalt, or an error whose message is told to the wearer.
+// Each is given the wearer's name, the id, kind and name of the item and the
+// id of the slot it's worn in.
+const (
+	WearEvent   = "item:wear"
+	RemoveEvent = "item:remove"
+)
+
+// Messages told to wearers who can't wear or remove something.
+const (
+	CantWearMessage   = "You can't wear that."
+	NotWornMessage    = "You aren't wearing that."
+	CantRemoveMessage = "You can't remove %s."
+	OccupiedMessage   = "You're already wearing something %s."
+	CancelMessage     = "You can't do that right now."
+)
+
+// Wearer is a carrier who can wear what they carry, like a player.
+type Wearer interface {
+	item.Carrier
+	Race() string
+	// Equipment returns what's worn by slot id.
+	Equipment() map[string]item.Item
+	// UpdateEquipment changes what's carried and worn together, nothing
+	// changes if fn fails.
+	UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error
+	AddStat(stat string, delta int) int
+}
+
+// Worn is an item worn in a slot.
+type Worn struct {
+	Slot Slot
+	Item item.Item
+}
+
+// Locations returns the wear locations the item fits, from its "wear" prop.
+func Locations(it item.Item) []string {
+	var locs []string
+	switch wear := it.Props["wear"].(type) {
+	case string:
+		locs = strings.Fields(wear)
+	case []string:
+		locs = wear
+	case []interface{}:
+		for _, loc := range wear {
+			locs = append(locs, fmt.Sprint(loc))
+		}
+	}
+
+	return locs
+}
+
+// Applies returns the changes the item makes to the stats of its wearer, from
+// its "applies" prop.
+func Applies(it item.Item) map[string]int {
+	changes := make(map[string]int)
+	add := func(stat interface{}, n interface{}) {
+		switch n := n.(type) {
+		case int:
+			changes[fmt.Sprint(stat)] += n
+		case float64:
+			changes[fmt.Sprint(stat)] += int(n)
+		}
+	}
+	switch applies := it.Props["applies"].(type) {
+	case map[string]int:
+		for stat, n := range applies {
+			changes[stat] += n
+		}
+	case map[string]interface{}:
+		for stat, n := range applies {
+			add(stat, n)
+		}
+	case map[interface{}]interface{}:
+		for stat, n := range applies {
+			add(stat, n)
+		}
+	}
+
+	return changes
+}
+
+// Manager puts items on and takes them off wearers.
+type Manager struct {
+	bodies  *Bodies
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager fitting items to the bodies, checking and
+// emitting events with the emitter, which may be nil.
+func NewManager(b *Bodies, em *events.Emitter) *Manager {
+	return &Manager{
+		bodies:  b,
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's equipment manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewBodies(), nil)
+	})
+
+	return globalManager
+}
+
+// Bodies returns the bodies items are fit to.
+func (m *Manager) Bodies() *Bodies {
+	return m.bodies
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// Worn returns what the wearer wears in the order of their body's slots,
+// followed by anything in slots their body doesn't have.
+func (m *Manager) Worn(w Wearer) []Worn {
+	eq := w.Equipment()
+	body := m.bodies.ForRace(w.Race())
+	var worn []Worn
+	for _, s := range body.Slots {
+		if it, ok := eq[s.ID]; ok {
+			worn = append(worn, Worn{Slot: s, Item: it})
+			delete(eq, s.ID)
+		}
+	}
+	var others []string
+	for id := range eq {
+		others = append(others, id)
+	}
+	sort.Strings(others)
+	for _, id := range others {
+		worn = append(worn, Worn{Slot: Slot{ID: id, Name: id}, Item: eq[id]})
+	}
+
+	return worn
+}
+
+// Wear puts on the carried item the keyword refers to, in the first free slot
+// that accepts it. Where limits the slots to those with the id or accepting
+// the wear location, like "wield".
+func (m *Manager) Wear(w Wearer, keyword, where string) (item.Item, Slot, error) {
+	it, ok := w.Inventory().Find(keyword)
+	if !ok {
+		return item.Item{}, Slot{}, &item.Refused{Message: item.NotCarriedMessage}
+	}
+
+	eq := w.Equipment()
+	var slot, occupied Slot
+	for _, loc := range Locations(it) {
+		for _, s := range m.bodies.ForRace(w.Race()).Slots {
+			if !strings.EqualFold(s.Accepts, loc) {
+				continue
+			}
+			if where != "" && !strings.EqualFold(where, s.ID) && !strings.EqualFold(where, s.Accepts) {
+				continue
+			}
+			if _, taken := eq[s.ID]; taken {
+				if occupied.ID == "" {
+					occupied = s
+				}
+
+				continue
+			}
+			slot = s
+
+			break
+		}
+		if slot.ID != "" {
+			break
+		}
+	}
+	if slot.ID == "" {
+		if occupied.ID != "" {
+			return item.Item{}, Slot{}, &item.Refused{Message: fmt.Sprintf(OccupiedMessage, occupied.Name)}
+		}
+
+		return item.Item{}, Slot{}, &item.Refused{Message: CantWearMessage}
+	}
+
+	data := wearData(w, it, slot)
+	if err := m.check(WearEvent, data); err != nil {
+		return item.Item{}, Slot{}, err
+	}
+	var worn item.Item
+	err := w.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+		if _, taken := eq[slot.ID]; taken {
+			return inv, eq, &item.Refused{Message: fmt.Sprintf(OccupiedMessage, slot.Name)}
+		}
+		inv, taken, err := inv.Take(it.ID, 1)
+		if err != nil {
+			return inv, eq, &item.Refused{Message: item.NotCarriedMessage}
+		}
+		worn = taken
+		eq[slot.ID] = taken
+
+		return inv, eq, nil
+	})
+	if err != nil {
+		return item.Item{}, Slot{}, err
+	}
+	for stat, n := range Applies(worn) {
+		w.AddStat(stat, n)
+	}
+	m.confirm(WearEvent, data)
+
+	return worn, slot, nil
+}
+
+// Remove takes off the worn item the keyword refers to, it's carried again.
+func (m *Manager) Remove(w Wearer, keyword string) (item.Item, Slot, error) {
+	var found *Worn
+	for _, worn := range m.Worn(w) {
+		if worn.Item.Matches(keyword) {
+			worn := worn
+			found = &worn
+
+			break
+		}
+	}
+	if found == nil {
+		return item.Item{}, Slot{}, &item.Refused{Message: NotWornMessage}
+	}
+	if found.Item.Flag("noremove") {
+		return item.Item{}, Slot{}, &item.Refused{Message: fmt.Sprintf(CantRemoveMessage, found.Item.Name)}
+	}
+
+	data := wearData(w, found.Item, found.Slot)
+	if err := m.check(RemoveEvent, data); err != nil {
+		return item.Item{}, Slot{}, err
+	}
+	var removed item.Item
+	err := w.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+		it, ok := eq[found.Slot.ID]
+		if !ok || it.ID != found.Item.ID {
+			return inv, eq, &item.Refused{Message: NotWornMessage}
+		}
+		removed = it
+		delete(eq, found.Slot.ID)
+
+		return inv.Add(it), eq, nil
+	})
+	if err != nil {
+		return item.Item{}, Slot{}, err
+	}
+	for stat, n := range Applies(removed) {
+		w.AddStat(stat, -n)
+	}
+	m.confirm(RemoveEvent, data)
+
+	return removed, found.Slot, nil
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func wearData(w Wearer, it item.Item, s Slot) events.Data {
+	return events.Data{
+		"actor": w.Name(),
+		"item":  it.ID,
+		"proto": it.Proto,
+		"name":  it.Name,
+		"slot":  s.ID,
+	}
+}
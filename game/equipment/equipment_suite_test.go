@@ -0,0 +1,13 @@
+package equipment_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEquipment(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Equipment Suite")
+}
@@ -0,0 +1,237 @@
+package equipment_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/equipment"
+	"github.com/bbuck/dragon-mud/game/item"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// wearer is a character that records what it's told
+type wearer struct {
+	name  string
+	race  string
+	items item.List
+	worn  map[string]item.Item
+	stats map[string]int
+	sent  []string
+}
+
+func (w *wearer) Name() string {
+	return w.name
+}
+
+func (w *wearer) Location() string {
+	return "square"
+}
+
+func (w *wearer) Race() string {
+	return w.race
+}
+
+func (w *wearer) Inventory() item.List {
+	return w.items.Copy()
+}
+
+func (w *wearer) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	items, err := fn(w.items.Copy())
+	if err != nil {
+		return err
+	}
+	w.items = items
+
+	return nil
+}
+
+func (w *wearer) Equipment() map[string]item.Item {
+	eq := make(map[string]item.Item)
+	for slot, it := range w.worn {
+		eq[slot] = it
+	}
+
+	return eq
+}
+
+func (w *wearer) UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error {
+	items, eq, err := fn(w.items.Copy(), w.Equipment())
+	if err != nil {
+		return err
+	}
+	w.items, w.worn = items, eq
+
+	return nil
+}
+
+func (w *wearer) AddStat(stat string, delta int) int {
+	w.stats[stat] += delta
+
+	return w.stats[stat]
+}
+
+func (w *wearer) ID() string {
+	return w.name
+}
+
+func (w *wearer) Level() command.Level {
+	return command.Player
+}
+
+func (w *wearer) Send(text string) error {
+	w.sent = append(w.sent, text)
+
+	return nil
+}
+
+func (w *wearer) told(text string) bool {
+	return strings.Contains(strings.Join(w.sent, "\n"), text)
+}
+
+func ring(name string) item.Item {
+	return item.Item{
+		ID:    name + "-1",
+		Proto: name,
+		Name:  "a " + name + " ring",
+		Props: map[string]interface{}{
+			"wear":    "finger",
+			"applies": map[interface{}]interface{}{"str": 1, "hp": 5},
+		},
+	}
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		em    *events.Emitter
+		m     *Manager
+		alice *wearer
+		sword item.Item
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		m = NewManager(NewBodies(), em)
+		sword = item.Item{ID: "sword-1", Proto: "sword", Name: "a long sword", Props: map[string]interface{}{"wear": []interface{}{"wield"}}}
+		alice = &wearer{
+			name:  "Alice",
+			race:  "human",
+			items: item.List{ring("gold"), ring("iron"), ring("jade"), sword},
+			stats: map[string]int{"str": 10},
+		}
+	})
+
+	It("reads where items are worn and what they change", func() {
+		Ω(Locations(sword)).Should(Equal([]string{"wield"}))
+		Ω(Applies(ring("gold"))).Should(Equal(map[string]int{"str": 1, "hp": 5}))
+		Ω(Applies(sword)).Should(BeEmpty())
+	})
+
+	It("wears items in free slots and applies their stats", func() {
+		_, slot, err := m.Wear(alice, "gold", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(slot.ID).Should(Equal("finger_l"))
+		_, slot, err = m.Wear(alice, "iron", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(slot.ID).Should(Equal("finger_r"))
+		Ω(alice.stats).Should(Equal(map[string]int{"str": 12, "hp": 10}))
+
+		_, _, err = m.Wear(alice, "jade", "")
+		Ω(err).Should(MatchError("You're already wearing something worn on left finger."))
+		_, _, err = m.Wear(alice, "sword", "head")
+		Ω(err).Should(MatchError(CantWearMessage))
+		Ω(alice.items).Should(HaveLen(2))
+
+		worn := m.Worn(alice)
+		Ω(worn).Should(HaveLen(2))
+		Ω(worn[0].Item.Name).Should(Equal("a gold ring"))
+	})
+
+	It("removes worn items and their stats", func() {
+		m.Wear(alice, "gold", "finger_r")
+		Ω(alice.worn).Should(HaveKey("finger_r"))
+
+		it, _, err := m.Remove(alice, "ring")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(it.Name).Should(Equal("a gold ring"))
+		Ω(alice.worn).Should(BeEmpty())
+		Ω(alice.items).Should(HaveLen(4))
+		Ω(alice.stats).Should(Equal(map[string]int{"str": 10, "hp": 0}))
+
+		_, _, err = m.Remove(alice, "ring")
+		Ω(err).Should(MatchError(NotWornMessage))
+	})
+
+	It("won't remove cursed items", func() {
+		cursed := ring("bone")
+		cursed.Flags = []string{"noremove"}
+		alice.items = item.List{cursed}
+		m.Wear(alice, "bone", "")
+
+		_, _, err := m.Remove(alice, "bone")
+		Ω(err).Should(MatchError("You can't remove a bone ring."))
+	})
+
+	It("lets before handlers refuse and hears about it after", func(done Done) {
+		got := make(chan events.Data, 1)
+		em.On("before:"+WearEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["proto"] == "sword" {
+				return errors.New("The sword burns your hand.")
+			}
+
+			return nil
+		}))
+		em.On(WearEvent, events.HandlerFunc(func(d events.Data) error {
+			got <- d
+
+			return nil
+		}))
+
+		_, _, err := m.Wear(alice, "sword", "")
+		Ω(err).Should(MatchError("The sword burns your hand."))
+		_, _, err = m.Wear(alice, "jade", "")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		d := <-got
+		Ω(d["actor"]).Should(Equal("Alice"))
+		Ω(d["slot"]).Should(Equal("finger_l"))
+		close(done)
+	})
+
+	Describe("commands", func() {
+		var dispatch func(string)
+
+		BeforeEach(func() {
+			registry := command.NewRegistry()
+			for _, c := range NewCommands(m, func(c command.Caller) Wearer {
+				return c.(*wearer)
+			}) {
+				Ω(registry.Register(c)).Should(Succeed())
+			}
+			dispatch = func(line string) {
+				Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+			}
+		})
+
+		It("wears, wields and removes items", func() {
+			dispatch("eq")
+			Ω(alice.told("You aren't wearing anything.")).Should(BeTrue())
+
+			dispatch("wield sword")
+			Ω(alice.told("You are using a long sword, wielded.")).Should(BeTrue())
+			dispatch("wear jade finger_r")
+			Ω(alice.told("You are using a jade ring, worn on right finger.")).Should(BeTrue())
+			dispatch("hold gold")
+			Ω(alice.told(CantWearMessage)).Should(BeTrue())
+
+			dispatch("equipment")
+			Ω(alice.told("You are using:\n  <worn on right finger> a jade ring\n  <wielded>              a long sword")).Should(BeTrue())
+
+			dispatch("remove sword")
+			Ω(alice.told("You stop using a long sword.")).Should(BeTrue())
+		})
+	})
+})
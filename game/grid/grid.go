@@ -0,0 +1,257 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package grid provides geometry over integer coordinates for areas of the
+// world laid out on a grid, like a wilderness where each coordinate is a
+// room. It knows nothing about rooms, callers decide what a coordinate means
+// and what blocks sight.
+package grid
+
+import (
+	"fmt"
+	"math"
+)
+
+// Point is a coordinate on the grid, Y increases to the south.
+type Point struct {
+	X, Y int
+}
+
+// Pt is shorthand for Point{x, y}.
+func Pt(x, y int) Point {
+	return Point{X: x, Y: y}
+}
+
+// Add returns the point offset by o.
+func (p Point) Add(o Point) Point {
+	return Point{X: p.X + o.X, Y: p.Y + o.Y}
+}
+
+// String returns the point as "x,y", suitable for use as a key.
+func (p Point) String() string {
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+// ParsePoint parses a point in the form returned from Point.String.
+func ParsePoint(s string) (Point, error) {
+	var p Point
+	if _, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y); err != nil {
+		return Point{}, fmt.Errorf("invalid point %q, expected \"x,y\"", s)
+	}
+
+	return p, nil
+}
+
+// Euclidean is the straight line distance between the points.
+func Euclidean(a, b Point) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// Manhattan is the distance between the points moving only in the four
+// cardinal directions.
+func Manhattan(a, b Point) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+// Chebyshev is the distance between the points when diagonal moves are
+// allowed and cost the same as cardinal moves.
+func Chebyshev(a, b Point) int {
+	return maxInt(abs(a.X-b.X), abs(a.Y-b.Y))
+}
+
+var (
+	cardinalOffsets = []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	allOffsets      = []Point{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+)
+
+// Neighbors returns the points adjacent to p clockwise from north, including
+// diagonals if requested.
+func Neighbors(p Point, diagonal bool) []Point {
+	offsets := cardinalOffsets
+	if diagonal {
+		offsets = allOffsets
+	}
+
+	neighbors := make([]Point, len(offsets))
+	for i, o := range offsets {
+		neighbors[i] = p.Add(o)
+	}
+
+	return neighbors
+}
+
+// Rect is an axis aligned rectangle, Min is inclusive and Max is exclusive
+// so a Rect{Pt(0, 0), Pt(2, 2)} contains four points.
+type Rect struct {
+	Min, Max Point
+}
+
+// RectAt creates the rectangle with its top left corner at (x, y).
+func RectAt(x, y, width, height int) Rect {
+	return Rect{Min: Pt(x, y), Max: Pt(x+width, y+height)}
+}
+
+// Width of the rectangle.
+func (r Rect) Width() int {
+	return r.Max.X - r.Min.X
+}
+
+// Height of the rectangle.
+func (r Rect) Height() int {
+	return r.Max.Y - r.Min.Y
+}
+
+// Empty determines if the rectangle contains no points.
+func (r Rect) Empty() bool {
+	return r.Min.X >= r.Max.X || r.Min.Y >= r.Max.Y
+}
+
+// Contains determines if the point is inside the rectangle.
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.Min.X && p.X < r.Max.X && p.Y >= r.Min.Y && p.Y < r.Max.Y
+}
+
+// Intersect returns the overlap of the rectangles, which is empty if they
+// don't overlap.
+func (r Rect) Intersect(o Rect) Rect {
+	i := Rect{
+		Min: Pt(maxInt(r.Min.X, o.Min.X), maxInt(r.Min.Y, o.Min.Y)),
+		Max: Pt(minInt(r.Max.X, o.Max.X), minInt(r.Max.Y, o.Max.Y)),
+	}
+	if i.Empty() {
+		return Rect{}
+	}
+
+	return i
+}
+
+// Overlaps determines if the rectangles share any points.
+func (r Rect) Overlaps(o Rect) bool {
+	return !r.Intersect(o).Empty()
+}
+
+// Center returns the middle point of the rectangle, rounding toward Min.
+func (r Rect) Center() Point {
+	return Pt(r.Min.X+r.Width()/2, r.Min.Y+r.Height()/2)
+}
+
+// Points returns every point in the rectangle row by row.
+func (r Rect) Points() []Point {
+	if r.Empty() {
+		return nil
+	}
+
+	points := make([]Point, 0, r.Width()*r.Height())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			points = append(points, Pt(x, y))
+		}
+	}
+
+	return points
+}
+
+// Circle returns every point within radius of the center row by row.
+func Circle(center Point, radius int) []Point {
+	if radius < 0 {
+		return nil
+	}
+
+	var points []Point
+	// compare squared distances, the extra 0.25 rounds the edge out so small
+	// circles aren't diamonds
+	limit := float64(radius*radius) + 0.25
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if float64(x*x+y*y) <= limit {
+				points = append(points, center.Add(Pt(x, y)))
+			}
+		}
+	}
+
+	return points
+}
+
+// Line returns the points on a line from a to b, including both ends, using
+// Bresenham's algorithm.
+func Line(a, b Point) []Point {
+	dx, dy := abs(b.X-a.X), -abs(b.Y-a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+
+	points := make([]Point, 0, maxInt(dx, -dy)+1)
+	err := dx + dy
+	for p := a; ; {
+		points = append(points, p)
+		if p == b {
+			break
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			p.Y += sy
+		}
+	}
+
+	return points
+}
+
+// LineOfSight determines if b can be seen from a, which is true when none of
+// the points between them are blocked. The end points themselves are not
+// checked, so a wall can be seen but not seen through.
+func LineOfSight(a, b Point, blocked func(Point) bool) bool {
+	line := Line(a, b)
+	for _, p := range line[1 : len(line)-1] {
+		if blocked(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Visible returns the points within radius of the origin that are in line of
+// sight, including the origin.
+func Visible(origin Point, radius int, blocked func(Point) bool) []Point {
+	var visible []Point
+	for _, p := range Circle(origin, radius) {
+		if p == origin || LineOfSight(origin, p, blocked) {
+			visible = append(visible, p)
+		}
+	}
+
+	return visible
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
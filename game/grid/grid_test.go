@@ -0,0 +1,82 @@
+package grid_test
+
+import (
+	. "github.com/bbuck/dragon-mud/game/grid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Grid", func() {
+	It("measures distances", func() {
+		a, b := Pt(0, 0), Pt(3, 4)
+
+		Ω(Euclidean(a, b)).Should(Equal(5.0))
+		Ω(Manhattan(a, b)).Should(Equal(7))
+		Ω(Chebyshev(a, b)).Should(Equal(4))
+	})
+
+	It("converts points to and from keys", func() {
+		p, err := ParsePoint(Pt(-3, 12).String())
+
+		Ω(err).Should(BeNil())
+		Ω(p).Should(Equal(Pt(-3, 12)))
+
+		_, err = ParsePoint("north")
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("finds neighbors", func() {
+		Ω(Neighbors(Pt(1, 1), false)).Should(Equal([]Point{Pt(1, 0), Pt(2, 1), Pt(1, 2), Pt(0, 1)}))
+		Ω(Neighbors(Pt(1, 1), true)).Should(HaveLen(8))
+	})
+
+	Describe("Rect", func() {
+		r := RectAt(0, 0, 4, 3)
+
+		It("contains points within its bounds", func() {
+			Ω(r.Contains(Pt(3, 2))).Should(BeTrue())
+			Ω(r.Contains(Pt(4, 2))).Should(BeFalse())
+			Ω(r.Points()).Should(HaveLen(12))
+			Ω(r.Center()).Should(Equal(Pt(2, 1)))
+		})
+
+		It("intersects other rectangles", func() {
+			Ω(r.Intersect(RectAt(2, 1, 10, 10))).Should(Equal(Rect{Min: Pt(2, 1), Max: Pt(4, 3)}))
+			Ω(r.Overlaps(RectAt(4, 0, 2, 2))).Should(BeFalse())
+		})
+	})
+
+	It("finds the points in a circle", func() {
+		Ω(Circle(Pt(5, 5), 0)).Should(Equal([]Point{Pt(5, 5)}))
+		Ω(Circle(Pt(5, 5), 1)).Should(HaveLen(5))
+		Ω(Circle(Pt(0, 0), 2)).Should(ContainElement(Pt(1, 1)))
+	})
+
+	It("draws lines between points", func() {
+		Ω(Line(Pt(0, 0), Pt(3, 1))).Should(Equal([]Point{Pt(0, 0), Pt(1, 0), Pt(2, 1), Pt(3, 1)}))
+		Ω(Line(Pt(2, 2), Pt(2, 2))).Should(Equal([]Point{Pt(2, 2)}))
+		Ω(Line(Pt(0, 3), Pt(0, 0))).Should(HaveLen(4))
+	})
+
+	Describe("line of sight", func() {
+		wall := func(p Point) bool {
+			return p.X == 2
+		}
+
+		It("is blocked by points between the ends", func() {
+			Ω(LineOfSight(Pt(0, 0), Pt(4, 0), wall)).Should(BeFalse())
+			Ω(LineOfSight(Pt(0, 0), Pt(2, 0), wall)).Should(BeTrue())
+			Ω(LineOfSight(Pt(0, 0), Pt(1, 3), wall)).Should(BeTrue())
+		})
+
+		It("limits what is visible", func() {
+			visible := Visible(Pt(0, 0), 3, wall)
+
+			Ω(visible).Should(ContainElement(Pt(0, 0)))
+			Ω(visible).Should(ContainElement(Pt(2, 0)))
+			Ω(visible).ShouldNot(ContainElement(Pt(3, 0)))
+			Ω(visible).Should(ContainElement(Pt(-3, 0)))
+		})
+	})
+})
@@ -0,0 +1,113 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package group
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the group command, which shows the caller's group and
+// invites, joins, leaves, kicks from, hands over and disbands groups. Group
+// members talk on the group channel, see the channels package.
+func NewCommands(m *Manager, resolve command.Resolver[Member]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "group",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "player", Kind: command.Word, Optional: true},
+			},
+			Help: "Shows your group, \"group invite <player>\" asks someone to join it, " +
+				"\"group join\" accepts an invite and \"group leave\" leaves. Leaders can " +
+				"\"group kick <player>\", \"group leader <player>\" to hand the group over " +
+				"and \"group disband\".",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, mem Member) error {
+				name := ctx.String("player")
+				needsName := func(fn func() error) error {
+					if name == "" {
+						return ctx.Send("Usage: " + ctx.Command.Usage())
+					}
+
+					return fn()
+				}
+
+				switch strings.ToLower(ctx.String("action")) {
+				case "":
+					return ctx.Send(listing(m, mem))
+				case "invite":
+					return needsName(func() error {
+						invitee := m.find(name)
+						if invitee == nil {
+							return ctx.Send(fmt.Sprintf("There's nobody called %q in the game.", name))
+						}
+						if err := m.Invite(mem, invitee); err != nil {
+							return err
+						}
+
+						return ctx.Send(fmt.Sprintf("You invite %s to join your group.", invitee.Name()))
+					})
+				case "join", "accept":
+					return m.Join(mem)
+				case "leave", "quit":
+					return m.Leave(mem.Name())
+				case "kick":
+					return needsName(func() error {
+						return m.Kick(mem, name)
+					})
+				case "leader", "lead", "promote":
+					return needsName(func() error {
+						return m.Promote(mem, name)
+					})
+				case "disband":
+					return m.Disband(mem)
+				}
+
+				return ctx.Send("Usage: " + ctx.Command.Usage())
+			}),
+		},
+	}
+}
+
+// listing shows who is in the member's group and where they are
+func listing(m *Manager, mem Member) string {
+	g, ok := m.Of(mem.Name())
+	if !ok {
+		return NotGroupedMessage
+	}
+
+	lines := []string{fmt.Sprintf("%s's group:", g.Leader)}
+	for _, name := range g.Members {
+		line := "  " + name
+		if other := m.find(name); other == nil {
+			line += " (not in the game)"
+		} else if other.Location() == mem.Location() {
+			line += " (here)"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's member for fn, telling them why they were
+// refused
+func handler(resolve command.Resolver[Member], fn func(*command.Context, Member) error) command.Handler {
+	return func(ctx *command.Context) error {
+		mem := resolve(ctx.Caller)
+		if mem == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		err := fn(ctx, mem)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package group lets players band together. A leader invites others, who
+// follow the leader once they join, share the experience any of them earn
+// while together and talk on the group channel. Groups are emitted as they
+// change so combat, quests and scripts can treat them as a unit.
+package group
+
+import (
+	"github.com/bbuck/dragon-mud/game/movement"
+)
+
+// The events of groups changing, each is given the id of the group and its
+// leader. Handlers of before:group:join can stop players joining by
+// returning events.ErrHalt, or an error whose message is told to them.
+// Invites, joins and leaves are given the member, leaves also say whether
+// they were kicked, leader changes are given the leader before and shares
+// the member who earned the amount, the stat and the names of those who
+// shared it.
+const (
+	InviteEvent  = "group:invite"
+	JoinEvent    = "group:join"
+	LeaveEvent   = "group:leave"
+	LeaderEvent  = "group:leader"
+	DisbandEvent = "group:disband"
+	ShareEvent   = "group:share"
+)
+
+// Messages told to members who can't do something.
+const (
+	NotGroupedMessage = "You aren't in a group."
+	NotLeaderMessage  = "Only the group's leader can do that."
+	FullMessage       = "The group is full."
+	CancelMessage     = "You can't join that group right now."
+)
+
+// DefaultMax is how many members a group can have unless it's changed.
+const DefaultMax = 8
+
+// DefaultStat is the stat experience is kept in unless it's changed.
+const DefaultStat = "xp"
+
+// Member is someone who can be in a group, like a player.
+type Member interface {
+	movement.Mover
+	Send(text string) error
+	AddStat(name string, delta int) int
+}
+
+// Group is players playing together.
+type Group struct {
+	ID     string
+	Leader string
+	// Members are the names of everyone in the group in the order they
+	// joined, the leader first.
+	Members []string
+}
+
+// Has is true if the player with the name is in the group.
+func (g Group) Has(name string) bool {
+	return g.index(name) >= 0
+}
+
+// index returns the position of the player with the name among the members,
+// -1 if they aren't one
+func (g Group) index(name string) int {
+	for i, m := range g.Members {
+		if key(m) == key(name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// copy returns the group with its own list of members
+func (g *Group) copy() Group {
+	c := *g
+	c.Members = append([]string(nil), g.Members...)
+
+	return c
+}
@@ -0,0 +1,223 @@
+package group_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/group"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// member is a player who remembers their stats and what they're told
+type member struct {
+	name     string
+	location string
+	stats    map[string]int
+	sent     []string
+}
+
+func newMember(name string) *member {
+	return &member{name: name, location: "square", stats: make(map[string]int)}
+}
+
+func (m *member) ID() string {
+	return strings.ToLower(m.name)
+}
+
+func (m *member) Name() string {
+	return m.name
+}
+
+func (m *member) Location() string {
+	return m.location
+}
+
+func (m *member) SetLocation(room string) {
+	m.location = room
+}
+
+func (m *member) Level() command.Level {
+	return command.Player
+}
+
+func (m *member) AddStat(name string, delta int) int {
+	m.stats[name] += delta
+
+	return m.stats[name]
+}
+
+func (m *member) Send(text string) error {
+	m.sent = append(m.sent, text)
+
+	return nil
+}
+
+func (m *member) told(text string) bool {
+	return strings.Contains(strings.Join(m.sent, "\n"), text)
+}
+
+var _ = Describe("Group", func() {
+	var (
+		em              *events.Emitter
+		mv              *movement.Movement
+		m               *Manager
+		ann, bob, carla *member
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		mv = movement.New(world.New(), nil)
+		m = NewManager(mv, em)
+		ann, bob, carla = newMember("Ann"), newMember("Bob"), newMember("Carla")
+		m.SetLookup(func(name string) Member {
+			for _, mem := range []*member{ann, bob, carla} {
+				if strings.EqualFold(mem.name, name) {
+					return mem
+				}
+			}
+
+			return nil
+		})
+	})
+
+	group := func(leader *member, members ...*member) {
+		for _, mem := range members {
+			Ω(m.Invite(leader, mem)).Should(Succeed())
+			Ω(m.Join(mem)).Should(Succeed())
+		}
+	}
+
+	It("forms groups from invites, with members following the leader", func() {
+		Ω(m.Join(bob)).Should(MatchError("Nobody has invited you to a group."))
+		Ω(m.Invite(ann, ann)).ShouldNot(Succeed())
+		Ω(m.Invite(ann, bob)).Should(Succeed())
+		Ω(bob.told("Ann invites you to join their group")).Should(BeTrue())
+		Ω(m.Join(bob)).Should(Succeed())
+
+		g, ok := m.Of("bob")
+		Ω(ok).Should(BeTrue())
+		Ω(g.Leader).Should(Equal("Ann"))
+		Ω(g.Members).Should(Equal([]string{"Ann", "Bob"}))
+		Ω(mv.Leader(bob)).Should(Equal("ann"))
+		Ω(ann.told("Bob joins the group.")).Should(BeTrue())
+		Ω(m.Allies("ANN", "bob")).Should(BeTrue())
+		Ω(m.Allies("Ann", "Carla")).Should(BeFalse())
+
+		Ω(m.Invite(bob, carla)).Should(MatchError(NotLeaderMessage))
+		Ω(m.Invite(carla, bob)).Should(MatchError("Bob is already in a group."))
+	})
+
+	It("keeps groups to their size", func() {
+		m.SetMax(2)
+		group(ann, bob)
+
+		Ω(m.Invite(ann, carla)).Should(MatchError(FullMessage))
+	})
+
+	It("lets handlers stop players joining", func() {
+		em.On("before:"+JoinEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["member"] == "Carla" {
+				return errors.New("Carla can't join groups.")
+			}
+
+			return nil
+		}))
+		Ω(m.Invite(ann, carla)).Should(Succeed())
+
+		Ω(m.Join(carla)).Should(MatchError("Carla can't join groups."))
+		_, ok := m.Of("Carla")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("passes the lead on when the leader leaves and disbands groups of one", func() {
+		group(ann, bob, carla)
+
+		Ω(m.Leave("Ann")).Should(Succeed())
+		g, _ := m.Of("Carla")
+		Ω(g.Leader).Should(Equal("Bob"))
+		Ω(mv.Leader(carla)).Should(Equal("bob"))
+		Ω(mv.Leader(bob)).Should(BeEmpty())
+		Ω(carla.told("Bob now leads the group.")).Should(BeTrue())
+
+		Ω(m.Leave("Carla")).Should(Succeed())
+		Ω(bob.told("The group disbands.")).Should(BeTrue())
+		_, ok := m.Of("Bob")
+		Ω(ok).Should(BeFalse())
+		Ω(m.Leave("Bob")).Should(MatchError(NotGroupedMessage))
+	})
+
+	It("lets leaders kick, promote and disband", func() {
+		group(ann, bob, carla)
+
+		Ω(m.Kick(bob, "Carla")).Should(MatchError(NotLeaderMessage))
+		Ω(m.Kick(ann, "Carla")).Should(Succeed())
+		Ω(carla.told("You're kicked out of the group.")).Should(BeTrue())
+		Ω(mv.Leader(carla)).Should(BeEmpty())
+
+		Ω(m.Promote(ann, "bob")).Should(Succeed())
+		g, _ := m.Of("Ann")
+		Ω(g.Members).Should(Equal([]string{"Bob", "Ann"}))
+		Ω(mv.Leader(ann)).Should(Equal("bob"))
+		Ω(mv.Leader(bob)).Should(BeEmpty())
+
+		Ω(m.Disband(ann)).Should(MatchError(NotLeaderMessage))
+		Ω(m.Disband(bob)).Should(Succeed())
+		Ω(mv.Leader(ann)).Should(BeEmpty())
+		_, ok := m.Of("Ann")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("shares experience with members in the same room", func() {
+		received := make(chan events.Data, 1)
+		em.On(ShareEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+		group(ann, bob, carla)
+		carla.location = "gate"
+
+		Ω(m.Share("Bob", 101)).Should(Equal(map[string]int{"Bob": 51, "Ann": 50}))
+		Ω(bob.stats[DefaultStat]).Should(Equal(51))
+		Ω(carla.stats[DefaultStat]).Should(Equal(0))
+		Ω(ann.told("You gain 50 xp.")).Should(BeTrue())
+		Eventually(received).Should(Receive(HaveKeyWithValue("members", []string{"Bob", "Ann"})))
+
+		m.SetStat("exp")
+		Ω(m.Share("Carla", 10)).Should(Equal(map[string]int{"Carla": 10}))
+		Ω(carla.stats["exp"]).Should(Equal(10))
+	})
+
+	It("runs the group command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Member {
+			return c.(*member)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+		bob.location = "gate"
+
+		d.Dispatch(ann, "group")
+		d.Dispatch(ann, "group invite nobody")
+		d.Dispatch(ann, "group invite bob")
+		d.Dispatch(bob, "group join")
+		d.Dispatch(ann, "group")
+		d.Dispatch(ann, "group kick")
+		Ω(ann.sent).Should(Equal([]string{
+			NotGroupedMessage,
+			"There's nobody called \"nobody\" in the game.",
+			"You invite Bob to join your group.",
+			"Bob joins the group.",
+			"Ann's group:\n  Ann (here)\n  Bob",
+			"Usage: group [action] [player]",
+		}))
+		Ω(bob.told("You join Ann's group.")).Should(BeTrue())
+	})
+})
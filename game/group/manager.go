@@ -0,0 +1,593 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package group
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+)
+
+// Manager keeps the groups and who's been invited to them.
+type Manager struct {
+	movement *movement.Movement
+	lookup   func(name string) Member
+	groups   map[string]*Group
+	// of holds the id of each member's group, by lower case name
+	of map[string]string
+	// invites holds who invited each player, by lower case name
+	invites map[string]string
+	max     int
+	stat    string
+	seq     uint64
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager whose members follow their leader with the
+// Movement. The emitter may be nil.
+func NewManager(mv *movement.Movement, em *events.Emitter) *Manager {
+	return &Manager{
+		movement: mv,
+		groups:   make(map[string]*Group),
+		of:       make(map[string]string),
+		invites:  make(map[string]string),
+		max:      DefaultMax,
+		stat:     DefaultStat,
+		emitter:  em,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's groups.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(movement.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetLookup sets how members in the game are found by name. Without it
+// nobody can join a group or be told what happens to it.
+func (m *Manager) SetLookup(fn func(name string) Member) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lookup = fn
+}
+
+// SetMax changes how many members a group can have, values below two are
+// ignored.
+func (m *Manager) SetMax(max int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if max >= 2 {
+		m.max = max
+	}
+}
+
+// SetStat changes the stat experience is shared in.
+func (m *Manager) SetStat(stat string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if stat != "" {
+		m.stat = stat
+	}
+}
+
+// Of returns the group the player with the name is in.
+func (m *Manager) Of(name string) (Group, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	g, ok := m.group(name)
+	if !ok {
+		return Group{}, false
+	}
+
+	return g.copy(), true
+}
+
+// Allies is true if the players with the names are in the same group.
+func (m *Manager) Allies(a, b string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	id, ok := m.of[key(a)]
+
+	return ok && m.of[key(b)] == id
+}
+
+// Invite asks the invitee to join the inviter's group, which is formed when
+// they accept. Only leaders invite others to groups that already exist.
+func (m *Manager) Invite(inviter, invitee Member) error {
+	if key(inviter.Name()) == key(invitee.Name()) {
+		return refuse("You can't invite yourself.")
+	}
+
+	m.mutex.Lock()
+	var id string
+	if g, ok := m.group(inviter.Name()); ok {
+		if key(g.Leader) != key(inviter.Name()) {
+			m.mutex.Unlock()
+
+			return refuse(NotLeaderMessage)
+		}
+		if len(g.Members) >= m.max {
+			m.mutex.Unlock()
+
+			return refuse(FullMessage)
+		}
+		id = g.ID
+	}
+	if _, ok := m.of[key(invitee.Name())]; ok {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s is already in a group.", invitee.Name()))
+	}
+	m.invites[key(invitee.Name())] = inviter.Name()
+	m.mutex.Unlock()
+
+	invitee.Send(fmt.Sprintf("%s invites you to join their group, type \"group join\" to accept.", inviter.Name()))
+	m.emit(InviteEvent, events.Data{
+		"group":  id,
+		"leader": inviter.Name(),
+		"member": invitee.Name(),
+	})
+
+	return nil
+}
+
+// Join accepts the member's invite, putting them in the group of who
+// invited them, and has them follow its leader.
+func (m *Manager) Join(member Member) error {
+	m.mutex.RLock()
+	inviter, invited := m.invites[key(member.Name())]
+	lookup := m.lookup
+	m.mutex.RUnlock()
+
+	if !invited {
+		return refuse("Nobody has invited you to a group.")
+	}
+	var leader Member
+	if lookup != nil {
+		leader = lookup(inviter)
+	}
+	if leader == nil {
+		m.mutex.Lock()
+		delete(m.invites, key(member.Name()))
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s isn't in the game anymore.", inviter))
+	}
+	data := events.Data{
+		"leader": leader.Name(),
+		"member": member.Name(),
+	}
+	if err := m.check(JoinEvent, data); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.invites, key(member.Name()))
+	if _, ok := m.of[key(member.Name())]; ok {
+		m.mutex.Unlock()
+
+		return refuse("You're already in a group.")
+	}
+	g, ok := m.group(leader.Name())
+	switch {
+	case ok && key(g.Leader) != key(leader.Name()):
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s doesn't lead a group anymore.", leader.Name()))
+	case ok && len(g.Members) >= m.max:
+		m.mutex.Unlock()
+
+		return refuse(FullMessage)
+	case !ok:
+		m.seq++
+		g = &Group{
+			ID:      fmt.Sprintf("group-%d", m.seq),
+			Leader:  leader.Name(),
+			Members: []string{leader.Name()},
+		}
+		m.groups[g.ID] = g
+		m.of[key(leader.Name())] = g.ID
+	}
+	g.Members = append(g.Members, member.Name())
+	m.of[key(member.Name())] = g.ID
+	joined := g.copy()
+	m.mutex.Unlock()
+
+	if m.movement.Leader(leader) == key(member.Name()) {
+		m.movement.Unfollow(leader)
+	}
+	m.movement.Follow(member, leader)
+	member.Send(fmt.Sprintf("You join %s's group.", leader.Name()))
+	m.tell(joined, fmt.Sprintf("%s joins the group.", member.Name()), member.Name())
+	data["group"] = joined.ID
+	m.confirm(JoinEvent, data)
+
+	return nil
+}
+
+// Leave takes the player with the name out of their group, the next member
+// to have joined leads it if they led it. Groups left with one member are
+// disbanded.
+func (m *Manager) Leave(name string) error {
+	return m.remove(name, false)
+}
+
+// Kick has the leader throw the player with the name out of the group.
+func (m *Manager) Kick(leader Member, name string) error {
+	m.mutex.RLock()
+	g, err := m.led(leader.Name())
+	m.mutex.RUnlock()
+
+	if err != nil {
+		return err
+	}
+	if !g.Has(name) {
+		return refuse(fmt.Sprintf("%s isn't in your group.", name))
+	}
+	if key(name) == key(leader.Name()) {
+		return refuse("You can't kick yourself out, leave the group instead.")
+	}
+
+	return m.remove(name, true)
+}
+
+// Promote has the leader hand the group to the member with the name, who
+// everyone follows from then on.
+func (m *Manager) Promote(leader Member, name string) error {
+	m.mutex.Lock()
+	g, err := m.led(leader.Name())
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	i := g.index(name)
+	if i < 0 {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("%s isn't in your group.", name))
+	}
+	if i == 0 {
+		m.mutex.Unlock()
+
+		return refuse("You already lead the group.")
+	}
+	was := g.Leader
+	g.Leader = g.Members[i]
+	g.Members = append([]string{g.Leader}, append(g.Members[:i:i], g.Members[i+1:]...)...)
+	promoted := g.copy()
+	m.mutex.Unlock()
+
+	m.refollow(promoted.Members, was, promoted.Leader)
+	if mem, to := m.find(was), m.find(promoted.Leader); mem != nil && to != nil {
+		m.movement.Follow(mem, to)
+	}
+	m.tell(promoted, fmt.Sprintf("%s now leads the group.", promoted.Leader), "")
+	m.emit(LeaderEvent, events.Data{
+		"group":  promoted.ID,
+		"leader": promoted.Leader,
+		"was":    was,
+	})
+
+	return nil
+}
+
+// Disband has the leader break the group up.
+func (m *Manager) Disband(leader Member) error {
+	m.mutex.Lock()
+	g, err := m.led(leader.Name())
+	if err != nil {
+		m.mutex.Unlock()
+
+		return err
+	}
+	delete(m.groups, g.ID)
+	for _, name := range g.Members {
+		delete(m.of, key(name))
+	}
+	disbanded := g.copy()
+	m.mutex.Unlock()
+
+	m.refollow(disbanded.Members, disbanded.Leader, "")
+	m.tell(disbanded, "The group disbands.", "")
+	m.emit(DisbandEvent, events.Data{
+		"group":  disbanded.ID,
+		"leader": disbanded.Leader,
+	})
+
+	return nil
+}
+
+// Nearby returns the members of the named player's group in the same room
+// as them, them first, or just them if they aren't in a group. Those who
+// can't be found in the game are left out.
+func (m *Manager) Nearby(name string) []Member {
+	m.mutex.RLock()
+	lookup := m.lookup
+	names := []string{name}
+	if g, ok := m.group(name); ok {
+		for _, other := range g.Members {
+			if key(other) != key(name) {
+				names = append(names, other)
+			}
+		}
+	}
+	m.mutex.RUnlock()
+
+	if lookup == nil {
+		return nil
+	}
+	self := lookup(name)
+	if self == nil {
+		return nil
+	}
+	nearby := []Member{self}
+	for _, other := range names[1:] {
+		if mem := lookup(other); mem != nil && mem.Location() == self.Location() {
+			nearby = append(nearby, mem)
+		}
+	}
+
+	return nearby
+}
+
+// Share splits the amount of experience the named player earned evenly
+// between the members of their group in the same room, they keep what's
+// left over. It returns how much each was given, by name.
+func (m *Manager) Share(name string, amount int) map[string]int {
+	if amount <= 0 {
+		return nil
+	}
+	nearby := m.Nearby(name)
+	if len(nearby) == 0 {
+		return nil
+	}
+
+	m.mutex.RLock()
+	stat := m.stat
+	var id, leader string
+	if g, ok := m.group(name); ok {
+		id, leader = g.ID, g.Leader
+	}
+	m.mutex.RUnlock()
+
+	each, rest := amount/len(nearby), amount%len(nearby)
+	shares := make(map[string]int, len(nearby))
+	var names []string
+	for i, mem := range nearby {
+		share := each
+		if i == 0 {
+			share += rest
+		}
+		if share == 0 {
+			continue
+		}
+		mem.AddStat(stat, share)
+		mem.Send(fmt.Sprintf("You gain %d %s.", share, stat))
+		shares[mem.Name()] = share
+		names = append(names, mem.Name())
+	}
+	m.emit(ShareEvent, events.Data{
+		"group":   id,
+		"leader":  leader,
+		"member":  nearby[0].Name(),
+		"amount":  amount,
+		"stat":    stat,
+		"members": names,
+	})
+
+	return shares
+}
+
+// remove takes the player out of their group, passing its lead on or
+// disbanding it
+func (m *Manager) remove(name string, kicked bool) error {
+	m.mutex.Lock()
+	g, ok := m.group(name)
+	if !ok {
+		m.mutex.Unlock()
+
+		return refuse(NotGroupedMessage)
+	}
+	i := g.index(name)
+	left := g.Members[i]
+	was := g.Leader
+	g.Members = append(g.Members[:i:i], g.Members[i+1:]...)
+	delete(m.of, key(left))
+	disband := len(g.Members) < 2
+	if disband {
+		delete(m.groups, g.ID)
+		for _, other := range g.Members {
+			delete(m.of, key(other))
+		}
+	} else if key(was) == key(left) {
+		g.Leader = g.Members[0]
+	}
+	remaining := g.copy()
+	m.mutex.Unlock()
+
+	m.refollow([]string{left}, was, "")
+	if key(was) == key(left) {
+		next := remaining.Leader
+		if disband {
+			next = ""
+		}
+		m.refollow(remaining.Members, was, next)
+	}
+
+	text, others := "You leave the group.", fmt.Sprintf("%s leaves the group.", left)
+	if kicked {
+		text, others = "You're kicked out of the group.", fmt.Sprintf("%s is kicked out of the group.", left)
+	}
+	if mem := m.find(left); mem != nil {
+		mem.Send(text)
+	}
+	m.tell(remaining, others, "")
+	m.emit(LeaveEvent, events.Data{
+		"group":  remaining.ID,
+		"leader": remaining.Leader,
+		"member": left,
+		"kicked": kicked,
+	})
+	switch {
+	case disband:
+		m.tell(remaining, "The group disbands.", "")
+		m.emit(DisbandEvent, events.Data{
+			"group":  remaining.ID,
+			"leader": remaining.Leader,
+		})
+	case key(was) == key(left):
+		m.tell(remaining, fmt.Sprintf("%s now leads the group.", remaining.Leader), "")
+		m.emit(LeaderEvent, events.Data{
+			"group":  remaining.ID,
+			"leader": remaining.Leader,
+			"was":    was,
+		})
+	}
+
+	return nil
+}
+
+// led returns the group the player leads, the mutex must be locked
+func (m *Manager) led(name string) (*Group, error) {
+	g, ok := m.group(name)
+	if !ok {
+		return nil, refuse(NotGroupedMessage)
+	}
+	if key(g.Leader) != key(name) {
+		return nil, refuse(NotLeaderMessage)
+	}
+
+	return g, nil
+}
+
+// refollow has those named following from follow to instead, or stop
+// following if to is empty. The one named to stops following.
+func (m *Manager) refollow(names []string, from, to string) {
+	var leader Member
+	if to != "" {
+		leader = m.find(to)
+	}
+	for _, name := range names {
+		mem := m.find(name)
+		if mem == nil || m.movement.Leader(mem) != key(from) {
+			continue
+		}
+		m.movement.Unfollow(mem)
+		if leader != nil && key(name) != key(to) {
+			m.movement.Follow(mem, leader)
+		}
+	}
+}
+
+// find returns the member in the game with the name, or nil
+func (m *Manager) find(name string) Member {
+	m.mutex.RLock()
+	lookup := m.lookup
+	m.mutex.RUnlock()
+
+	if lookup == nil {
+		return nil
+	}
+
+	return lookup(name)
+}
+
+// tell sends the text to the members of the group in the game, except the
+// one named
+func (m *Manager) tell(g Group, text, except string) {
+	for _, name := range g.Members {
+		if key(name) == key(except) {
+			continue
+		}
+		if mem := m.find(name); mem != nil {
+			mem.Send(text)
+		}
+	}
+}
+
+// group returns the group the player is in, the mutex must be locked
+func (m *Manager) group(name string) (*Group, bool) {
+	id, ok := m.of[key(name)]
+	if !ok {
+		return nil, false
+	}
+	g, ok := m.groups[id]
+
+	return g, ok
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+// key is how players are known, their name in lower case
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package help
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/perm"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// NewCommands creates the help command, which replaces the one the command
+// registry starts with, the apropos command that searches help and the hedit
+// command builders write articles with.
+func NewCommands(m *Manager) []*command.Command {
+	return []*command.Command{
+		{
+			Name:    "help",
+			Aliases: []string{"?"},
+			Args:    []command.Arg{{Name: "topic", Kind: command.Text, Optional: true}},
+			Help:    "Lists the commands and topics you can read about, or shows the help for one.",
+			Source:  "game",
+			Handler: func(ctx *command.Context) error {
+				level := ctx.Caller.Level()
+				topic := strings.TrimSpace(ctx.String("topic"))
+				if topic == "" {
+					commands, articles := m.Topics(level)
+					text := "Commands: " + strings.Join(commands, ", ")
+					if len(articles) > 0 {
+						text += "\nTopics: " + strings.Join(articles, ", ")
+					}
+
+					return ctx.Send(text)
+				}
+
+				if text, ok := m.Topic(topic, level); ok {
+					return ctx.Send(text)
+				}
+				text := fmt.Sprintf("There is no help for %q.", topic)
+				if found := m.Search(topic, level); len(found) > 0 {
+					text += "\nTry: " + strings.Join(found, ", ")
+				}
+
+				return ctx.Send(text)
+			},
+		},
+		{
+			Name:   "apropos",
+			Args:   []command.Arg{{Name: "words", Kind: command.Text}},
+			Help:   "Lists the help topics with every word given in them.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				found := m.Search(ctx.String("words"), ctx.Caller.Level())
+				if len(found) == 0 {
+					return ctx.Send("No help mentions that.")
+				}
+
+				return ctx.Send("Help on: " + strings.Join(found, ", "))
+			},
+		},
+		{
+			Name: "hedit",
+			Args: []command.Arg{
+				{Name: "topic", Kind: command.Word},
+				{Name: "field", Kind: command.Word, Optional: true},
+				{Name: "value", Kind: command.Text, Optional: true},
+			},
+			Level:      command.Builder,
+			Capability: perm.Build,
+			Help: "Writes the body of a help article in the editor, \"hedit <topic> <field> <value>\" " +
+				"sets its keywords, level or related topics, \"hedit <topic> show\" shows it and " +
+				"\"hedit <topic> remove\" removes it.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				name, field := ctx.String("topic"), strings.ToLower(ctx.String("field"))
+				switch field {
+				case "":
+					a, _ := m.index.Get(name)
+					editor.Open(ctx.Dispatcher, ctx.Caller, editor.New(a.Body, func(body string) {
+						_, err := m.Build(name, "body", strings.TrimRight(body, "\n"))
+						if r, ok := err.(*item.Refused); ok {
+							ctx.Send(r.Message)
+
+							return
+						}
+						if err != nil {
+							logger.NewWithSource("help").WithError(err).WithField("topic", name).Error("Failed to save help articles.")
+							ctx.Send("The help changed but couldn't be saved.")
+
+							return
+						}
+						ctx.Send(fmt.Sprintf("The help on %s is saved.", strings.ToLower(name)))
+					}))
+
+					return nil
+				case "show":
+					a, ok := m.index.Get(name)
+					if !ok {
+						return ctx.Send("There's no such help article.")
+					}
+
+					return ctx.Send(describe(a))
+				case "remove":
+					removed, err := m.Remove(name)
+					if err != nil {
+						return err
+					}
+					if !removed {
+						return ctx.Send("There's no such help article.")
+					}
+
+					return ctx.Send(fmt.Sprintf("The help on %s is removed.", strings.ToLower(name)))
+				}
+				a, err := m.Build(name, field, strings.TrimSpace(ctx.String("value")))
+				if r, ok := err.(*item.Refused); ok {
+					return ctx.Send(r.Message)
+				}
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(describe(a))
+			},
+		},
+	}
+}
+
+// describe shows each field of the article
+func describe(a Article) string {
+	lines := []string{
+		a.Name + ":",
+		"  keywords: " + strings.Join(a.Keywords, ", "),
+		"  level: " + a.Level,
+		"  related: " + strings.Join(a.Related, ", "),
+	}
+	if a.Body != "" {
+		lines = append(lines, "", a.Body)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package help keeps the articles players read with the help command.
+// Articles are written in YAML files or by builders in the game, each with
+// keywords it's also found by, the level needed to read it and the topics
+// related to it. Every registered command has help of its own, from its usage
+// and description, so articles only need writing for what commands don't
+// explain. Help can be searched for words anywhere in it.
+package help
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Article is a help entry.
+type Article struct {
+	// Name is the topic the article is found by, like "combat".
+	Name string `yaml:"name"`
+	// Keywords also find the article, like "fight" and "kill".
+	Keywords []string `yaml:"keywords,omitempty"`
+	// Level is who can read the article, player (the default), builder or
+	// admin.
+	Level string `yaml:"level,omitempty"`
+	// Related are the names of topics worth reading next.
+	Related []string `yaml:"related,omitempty"`
+	Body    string   `yaml:"body"`
+}
+
+// Access returns the level needed to read the article.
+func (a Article) Access() command.Level {
+	level, _ := command.ParseLevel(a.Level)
+
+	return level
+}
+
+// Matches is true if the word is the article's name or one of its keywords.
+func (a Article) Matches(word string) bool {
+	if strings.EqualFold(a.Name, word) {
+		return true
+	}
+	for _, k := range a.Keywords {
+		if strings.EqualFold(k, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate fills in defaults and checks the article makes sense
+func (a *Article) validate() error {
+	a.Name = strings.ToLower(strings.TrimSpace(a.Name))
+	if a.Name == "" {
+		return fmt.Errorf("help articles need a name")
+	}
+	level, err := command.ParseLevel(a.Level)
+	if err != nil {
+		return fmt.Errorf("help %s: %s", a.Name, err)
+	}
+	a.Level = level.String()
+	a.Keywords = words(a.Keywords)
+	a.Related = words(a.Related)
+
+	return nil
+}
+
+// words lower cases and trims each word, dropping empty ones
+func words(list []string) []string {
+	var cleaned []string
+	for _, w := range list {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			cleaned = append(cleaned, w)
+		}
+	}
+
+	return cleaned
+}
+
+// File is the layout of a help file, a list of articles.
+type File struct {
+	Articles []Article `yaml:"articles"`
+}
+
+// Index holds the help articles by name.
+type Index struct {
+	articles map[string]Article
+	mutex    *sync.RWMutex
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{
+		articles: make(map[string]Article),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// Add adds the article, replacing any with its name.
+func (idx *Index) Add(a Article) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.articles[a.Name] = a
+
+	return nil
+}
+
+// Remove removes the article with the name, returning false if there isn't
+// one.
+func (idx *Index) Remove(name string) bool {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	_, ok := idx.articles[name]
+	delete(idx.articles, name)
+
+	return ok
+}
+
+// Get returns the article with the name, without matching keywords.
+func (idx *Index) Get(name string) (Article, bool) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	a, ok := idx.articles[strings.ToLower(strings.TrimSpace(name))]
+
+	return a, ok
+}
+
+// All returns every article, sorted by name.
+func (idx *Index) All() []Article {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	articles := make([]Article, 0, len(idx.articles))
+	for _, a := range idx.articles {
+		articles = append(articles, a)
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		return articles[i].Name < articles[j].Name
+	})
+
+	return articles
+}
+
+// Find returns the article a reader at the level means by the topic, the
+// one with that name first, then with it as a keyword, then the first, by
+// name, whose name starts with it. Articles above the level aren't found.
+func (idx *Index) Find(topic string, level command.Level) (Article, bool) {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if topic == "" {
+		return Article{}, false
+	}
+	if a, ok := idx.Get(topic); ok && a.Access() <= level {
+		return a, true
+	}
+
+	readable := idx.readable(level)
+	for _, a := range readable {
+		if a.Matches(topic) {
+			return a, true
+		}
+	}
+	for _, a := range readable {
+		if strings.HasPrefix(a.Name, topic) {
+			return a, true
+		}
+	}
+
+	return Article{}, false
+}
+
+// Search returns the articles a reader at the level can read that have every
+// word of the query in their name, keywords or body. Those matching by name
+// come first, then by keyword, then by body alone.
+func (idx *Index) Search(query string, level command.Level) []Article {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var found []Article
+	scores := make(map[string]int)
+	for _, a := range idx.readable(level) {
+		if score := a.score(terms); score > 0 {
+			found = append(found, a)
+			scores[a.Name] = score
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return scores[found[i].Name] > scores[found[j].Name]
+	})
+
+	return found
+}
+
+// score rates how well the article matches the terms, zero if any term isn't
+// in it
+func (a Article) score(terms []string) int {
+	keywords := strings.Join(a.Keywords, " ")
+	body := strings.ToLower(a.Body)
+	total := 0
+	for _, t := range terms {
+		switch {
+		case strings.Contains(a.Name, t):
+			total += 3
+		case strings.Contains(keywords, t):
+			total += 2
+		case strings.Contains(body, t):
+			total++
+		default:
+			return 0
+		}
+	}
+
+	return total
+}
+
+// readable returns the articles a reader at the level can read, sorted by
+// name
+func (idx *Index) readable(level command.Level) []Article {
+	var articles []Article
+	for _, a := range idx.All() {
+		if a.Access() <= level {
+			articles = append(articles, a)
+		}
+	}
+
+	return articles
+}
+
+// LoadDir adds the articles in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (idx *Index) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := idx.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the articles listed in the YAML document, like:
+//   articles:
+//     - name: combat
+//       keywords: [fight, kill]
+//       related: [flee, wimpy]
+//       body: |
+//         Attack with "kill <target>", the fight goes on until one of
+//         you dies or flees.
+//     - name: building
+//       level: builder
+//       body: Rooms are made with the dig command.
+func (idx *Index) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, a := range f.Articles {
+		if err := idx.Add(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
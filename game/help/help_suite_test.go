@@ -0,0 +1,13 @@
+package help_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Help Suite")
+}
@@ -0,0 +1,195 @@
+package help_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/help"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// reader is someone reading help who remembers what they're told
+type reader struct {
+	level command.Level
+	sent  []string
+}
+
+func (r *reader) ID() string {
+	return "reader"
+}
+
+func (r *reader) Level() command.Level {
+	return r.level
+}
+
+func (r *reader) Send(text string) error {
+	r.sent = append(r.sent, text)
+
+	return nil
+}
+
+func (r *reader) last() string {
+	if len(r.sent) == 0 {
+		return ""
+	}
+
+	return r.sent[len(r.sent)-1]
+}
+
+const articles = `
+articles:
+  - name: combat
+    keywords: [fight, Kill]
+    related: [flee, building, look]
+    body: |
+      Fights go on until someone dies or flees.
+  - name: flee
+    body: Running from a fight loses some experience.
+  - name: building
+    level: builder
+    body: Rooms are dug out of the void.
+  - name: look
+    related: [combat]
+`
+
+var _ = Describe("Help", func() {
+	var (
+		idx      *Index
+		m        *Manager
+		registry *command.Registry
+	)
+
+	BeforeEach(func() {
+		idx = NewIndex()
+		Ω(idx.LoadYAML([]byte(articles))).Should(Succeed())
+		m = NewManager(idx)
+		registry = command.NewRegistry()
+		Ω(registry.Register(&command.Command{
+			Name:    "look",
+			Aliases: []string{"l"},
+			Args:    []command.Arg{{Name: "at", Optional: true}},
+			Help:    "Looks around the room.",
+		})).Should(Succeed())
+		Ω(registry.Register(&command.Command{
+			Name:  "shutdown",
+			Level: command.Admin,
+			Help:  "Stops the server.",
+		})).Should(Succeed())
+		m.SetRegistry(registry)
+	})
+
+	It("finds articles by name, keyword and the start of their name", func() {
+		a, ok := idx.Find("kill", command.Player)
+		Ω(ok).Should(BeTrue())
+		Ω(a.Name).Should(Equal("combat"))
+		a, ok = idx.Find("fl", command.Player)
+		Ω(ok).Should(BeTrue())
+		Ω(a.Name).Should(Equal("flee"))
+
+		_, ok = idx.Find("building", command.Player)
+		Ω(ok).Should(BeFalse())
+		_, ok = idx.Find("building", command.Builder)
+		Ω(ok).Should(BeTrue())
+	})
+
+	It("refuses articles that don't make sense", func() {
+		Ω(idx.Add(Article{Body: "Nameless."})).ShouldNot(Succeed())
+		Ω(idx.Add(Article{Name: "magic", Level: "wizard"})).ShouldNot(Succeed())
+		Ω(idx.LoadYAML([]byte("articles:\n  - name: x\n    color: red\n"))).ShouldNot(Succeed())
+	})
+
+	It("searches articles and commands for every word", func() {
+		Ω(idx.Search("fight", command.Player)).Should(HaveLen(2))
+		Ω(idx.Search("fight", command.Player)[0].Name).Should(Equal("combat"))
+		Ω(m.Search("flee fight", command.Player)).Should(Equal([]string{"flee", "combat"}))
+		Ω(m.Search("room", command.Player)).Should(Equal([]string{"look"}))
+		Ω(m.Search("room", command.Builder)).Should(Equal([]string{"building", "look"}))
+		Ω(m.Search("server", command.Player)).Should(BeEmpty())
+		Ω(m.Search("server", command.Admin)).Should(Equal([]string{"shutdown"}))
+	})
+
+	It("shows articles with the usage of their commands and what's related", func() {
+		text, ok := m.Topic("fight", command.Player)
+		Ω(ok).Should(BeTrue())
+		Ω(text).Should(Equal("Fights go on until someone dies or flees.\n\nSee also: flee, look"))
+
+		text, ok = m.Topic("l", command.Player)
+		Ω(ok).Should(BeTrue())
+		Ω(text).Should(Equal("Usage: look [at]\nAliases: l\n\nLooks around the room.\n\nSee also: combat"))
+
+		_, ok = m.Topic("shutdown", command.Player)
+		Ω(ok).Should(BeFalse())
+		text, ok = m.Topic("shutdown", command.Admin)
+		Ω(ok).Should(BeTrue())
+		Ω(text).Should(Equal("Usage: shutdown\n\nStops the server."))
+
+		commands, topics := m.Topics(command.Player)
+		Ω(commands).Should(Equal([]string{"look"}))
+		Ω(topics).Should(Equal([]string{"combat", "flee"}))
+	})
+
+	It("saves the articles builders write", func() {
+		dir, err := ioutil.TempDir("", "help")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "help.yml")
+		Ω(m.SetFile(file)).Should(Succeed())
+
+		_, err = m.Build("magic", "body", "Spells cost mana.")
+		Ω(err).ShouldNot(HaveOccurred())
+		a, err := m.Build("magic", "keywords", "spells, cast")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.Keywords).Should(Equal([]string{"spells", "cast"}))
+		_, err = m.Build("magic", "level", "wizard")
+		Ω(err).Should(MatchError(ContainSubstring("level must be")))
+		_, err = m.Build("magic", "color", "red")
+		Ω(err).Should(MatchError("Help articles have no color."))
+
+		other := NewManager(NewIndex())
+		Ω(other.SetFile(file)).Should(Succeed())
+		a, ok := other.Index().Get("magic")
+		Ω(ok).Should(BeTrue())
+		Ω(a.Body).Should(Equal("Spells cost mana."))
+		Ω(other.Index().All()).Should(HaveLen(1))
+
+		removed, err := other.Remove("magic")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(removed).Should(BeTrue())
+		Ω(NewManager(NewIndex()).SetFile(file)).Should(Succeed())
+		Ω(ioutil.ReadFile(file)).ShouldNot(ContainSubstring("magic"))
+	})
+
+	It("runs the help commands", func() {
+		for _, c := range NewCommands(m) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+		r := &reader{}
+		dispatch := func(line string) string {
+			Ω(dispatcher.Dispatch(r, line)).Should(Succeed())
+
+			return r.last()
+		}
+
+		Ω(dispatch("help")).Should(Equal("Commands: apropos, help, look\nTopics: combat, flee"))
+		Ω(dispatch("? flee")).Should(Equal("Running from a fight loses some experience."))
+		Ω(dispatch("help running fight")).Should(Equal("There is no help for \"running fight\".\nTry: flee"))
+		Ω(dispatch("apropos fight")).Should(Equal("Help on: combat, flee"))
+		Ω(dispatch("apropos dragons")).Should(Equal("No help mentions that."))
+		Ω(dispatcher.Dispatch(r, "hedit magic")).Should(MatchError(command.ErrUnknown))
+
+		r.level = command.Builder
+		dispatch("hedit magic")
+		dispatch("Spells cost mana.")
+		Ω(dispatch(".s")).Should(Equal("The help on magic is saved."))
+		Ω(dispatch("hedit magic related combat")).Should(ContainSubstring("related: combat"))
+		Ω(dispatch("help magic")).Should(Equal("Spells cost mana.\n\nSee also: combat"))
+		Ω(dispatch("hedit magic level sorcerer")).Should(ContainSubstring("level must be"))
+		Ω(dispatch("hedit magic remove")).Should(Equal("The help on magic is removed."))
+		Ω(dispatch("hedit magic show")).Should(Equal("There's no such help article."))
+	})
+})
@@ -0,0 +1,304 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package help
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fields are the parts of an article builders set with Build.
+var Fields = []string{"body", "keywords", "level", "related"}
+
+// Manager finds help in the articles and the commands registered, and keeps
+// the articles builders write.
+type Manager struct {
+	index    *Index
+	registry *command.Registry
+	file     string
+	built    map[string]bool
+	mutex    *sync.RWMutex
+}
+
+// NewManager creates a manager for the articles in the index.
+func NewManager(idx *Index) *Manager {
+	return &Manager{
+		index: idx,
+		built: make(map[string]bool),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's help manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewIndex())
+	})
+
+	return globalManager
+}
+
+// Index returns the help articles.
+func (m *Manager) Index() *Index {
+	return m.index
+}
+
+// SetRegistry sets the registry whose commands have help too.
+func (m *Manager) SetRegistry(r *command.Registry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.registry = r
+}
+
+// SetFile sets where the articles builders write are saved, adding those
+// already saved there. Missing files are ignored.
+func (m *Manager) SetFile(path string) error {
+	m.mutex.Lock()
+	m.file = path
+	m.mutex.Unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, a := range f.Articles {
+		if err := m.index.Add(a); err != nil {
+			return err
+		}
+		a, _ = m.index.Get(a.Name)
+		m.mutex.Lock()
+		m.built[a.Name] = true
+		m.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// Topic returns the help a reader at the level gets for the topic, false if
+// there's none. Articles are found first, and one named for a command is
+// shown with the command's usage. Without an article, or with one that's
+// empty, the command is described by its own help.
+func (m *Manager) Topic(topic string, level command.Level) (string, bool) {
+	a, found := m.index.Find(topic, level)
+	var c *command.Command
+	if found {
+		c = m.command(a.Name, level, false)
+	} else {
+		c = m.command(topic, level, true)
+	}
+	if !found && c == nil {
+		return "", false
+	}
+
+	var parts []string
+	if c != nil {
+		usage := "Usage: " + c.Usage()
+		if len(c.Aliases) > 0 {
+			usage += "\nAliases: " + strings.Join(c.Aliases, ", ")
+		}
+		parts = append(parts, usage)
+	}
+	body := strings.TrimRight(a.Body, "\n")
+	if body == "" && c != nil {
+		body = c.Help
+	}
+	if body != "" {
+		parts = append(parts, body)
+	}
+	var related []string
+	for _, name := range a.Related {
+		if _, ok := m.index.Find(name, level); ok || m.command(name, level, false) != nil {
+			related = append(related, name)
+		}
+	}
+	if len(related) > 0 {
+		parts = append(parts, "See also: "+strings.Join(related, ", "))
+	}
+
+	return strings.Join(parts, "\n\n"), true
+}
+
+// Topics returns the names of the commands a reader at the level can use and
+// of the other articles they can read, both sorted.
+func (m *Manager) Topics(level command.Level) (commands []string, articles []string) {
+	m.mutex.RLock()
+	r := m.registry
+	m.mutex.RUnlock()
+
+	if r != nil {
+		for _, c := range r.Commands(level) {
+			commands = append(commands, c.Name)
+		}
+	}
+	for _, a := range m.index.readable(level) {
+		if m.command(a.Name, level, false) == nil {
+			articles = append(articles, a.Name)
+		}
+	}
+
+	return commands, articles
+}
+
+// Search returns the topics a reader at the level can read with every word
+// of the query in them, the articles found by Index.Search followed by the
+// commands with the words in their name or help.
+func (m *Manager) Search(query string, level command.Level) []string {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var topics []string
+	seen := make(map[string]bool)
+	for _, a := range m.index.Search(query, level) {
+		topics = append(topics, a.Name)
+		seen[a.Name] = true
+	}
+
+	m.mutex.RLock()
+	r := m.registry
+	m.mutex.RUnlock()
+
+	if r == nil {
+		return topics
+	}
+	for _, c := range r.Commands(level) {
+		name := strings.ToLower(c.Name)
+		if seen[name] {
+			continue
+		}
+		text := name + " " + strings.ToLower(c.Help)
+		all := true
+		for _, t := range terms {
+			all = all && strings.Contains(text, t)
+		}
+		if all {
+			topics = append(topics, name)
+		}
+	}
+
+	return topics
+}
+
+// Build sets the field of the article with the name, one of Fields, writing
+// the article if there's no such article, and saves it with the articles
+// builders wrote. Keywords and related topics are given as a list separated
+// by spaces or commas.
+func (m *Manager) Build(name, field, value string) (Article, error) {
+	a, ok := m.index.Get(name)
+	if !ok {
+		a = Article{Name: name}
+	}
+	list := words(strings.Fields(strings.Replace(value, ",", " ", -1)))
+	switch strings.ToLower(field) {
+	case "body":
+		a.Body = value
+	case "keywords":
+		a.Keywords = list
+	case "level":
+		a.Level = value
+	case "related":
+		a.Related = list
+	default:
+		return a, &item.Refused{Message: "Help articles have no " + field + "."}
+	}
+	if err := m.index.Add(a); err != nil {
+		return a, &item.Refused{Message: err.Error()}
+	}
+	a, _ = m.index.Get(name)
+
+	m.mutex.Lock()
+	m.built[a.Name] = true
+	m.mutex.Unlock()
+
+	return a, m.save()
+}
+
+// Remove removes the article with the name, returning false if there's no
+// such article. Commands keep the help they're registered with.
+func (m *Manager) Remove(name string) (bool, error) {
+	a, ok := m.index.Get(name)
+	if !ok {
+		return false, nil
+	}
+	m.index.Remove(a.Name)
+
+	m.mutex.Lock()
+	built := m.built[a.Name]
+	delete(m.built, a.Name)
+	m.mutex.Unlock()
+
+	if !built {
+		return true, nil
+	}
+
+	return true, m.save()
+}
+
+// command returns the command a reader at the level means by the word,
+// matching abbreviations only if asked to, nil if there isn't one
+func (m *Manager) command(word string, level command.Level, abbrev bool) *command.Command {
+	m.mutex.RLock()
+	r := m.registry
+	m.mutex.RUnlock()
+
+	if r == nil {
+		return nil
+	}
+	if abbrev {
+		c, _ := r.Find(word, level)
+
+		return c
+	}
+	if c, ok := r.Get(word); ok && c.Level <= level {
+		return c
+	}
+
+	return nil
+}
+
+// save writes the articles builders wrote to the file, if there is one
+func (m *Manager) save() error {
+	m.mutex.RLock()
+	path := m.file
+	var f File
+	for _, a := range m.index.All() {
+		if m.built[a.Name] {
+			f.Articles = append(f.Articles, a)
+		}
+	}
+	m.mutex.RUnlock()
+
+	if path == "" {
+		return nil
+	}
+	contents, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0644)
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package instance gives players and groups copies of template zones to
+// themselves. Taking an exit into a template zone leads into the copy
+// belonging to the player, or their group, which is made as they enter with
+// rooms, doors and mobs of its own. Copies are destroyed once they've been
+// empty for a while or have lasted as long as the template allows, sending
+// anyone still inside to the template's exit room.
+package instance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The events of instances coming and going, each is given the instance's
+// id, the template zone and the owner. Handlers of before:instance:create
+// can keep players out by returning an error saying why.
+const (
+	CreateEvent  = "instance:create"
+	DestroyEvent = "instance:destroy"
+)
+
+// Who instances belong to.
+const (
+	// PerPlayer gives every player an instance of their own.
+	PerPlayer = "player"
+	// PerGroup has the members of a group share one, players who aren't in
+	// a group have their own.
+	PerGroup = "group"
+)
+
+// Separator joins the id of an instance and the template room in the ids
+// of the instance's rooms, like "crypt-1/hall".
+const Separator = "/"
+
+// Template makes a zone a template for instances.
+type Template struct {
+	Zone string `yaml:"zone"`
+	// Per is who each instance belongs to, group unless it's player.
+	Per string `yaml:"per,omitempty"`
+	// Exit is the room outside the zone players are sent to when their
+	// instance is destroyed.
+	Exit string `yaml:"exit"`
+	// Lifetime is how long an instance lasts, like "2h", forever when it's
+	// empty.
+	Lifetime string `yaml:"lifetime,omitempty"`
+	// Empty is how long an instance lasts once nobody is in it, like "10m",
+	// it's destroyed as soon as it's empty when this is empty.
+	Empty string `yaml:"empty,omitempty"`
+
+	lifetime time.Duration
+	empty    time.Duration
+}
+
+// validate checks the template makes sense, reading its durations
+func (t *Template) validate() error {
+	t.Per = strings.ToLower(t.Per)
+	if t.Zone == "" || t.Exit == "" {
+		return fmt.Errorf("instance templates need a zone and an exit")
+	}
+	if t.Per == "" {
+		t.Per = PerGroup
+	}
+	if t.Per != PerGroup && t.Per != PerPlayer {
+		return fmt.Errorf("template %q is per %q, instances are per %s or %s", t.Zone, t.Per, PerPlayer, PerGroup)
+	}
+
+	var err error
+	if t.Lifetime != "" {
+		if t.lifetime, err = time.ParseDuration(t.Lifetime); err != nil {
+			return fmt.Errorf("template %q: lifetime: %s", t.Zone, err)
+		}
+	}
+	if t.Empty != "" {
+		if t.empty, err = time.ParseDuration(t.Empty); err != nil {
+			return fmt.Errorf("template %q: empty: %s", t.Zone, err)
+		}
+	}
+
+	return nil
+}
+
+// Instance is a copy of a template zone. Its id is also the id of the zone
+// its rooms are in.
+type Instance struct {
+	ID       string
+	Template string
+	// Owner is who the instance belongs to, "group:" followed by the group's
+	// id or "player:" and the player's lower case name.
+	Owner   string
+	Created time.Time
+	// Emptied is when the last player left, zero while anyone is inside.
+	Emptied time.Time
+}
+
+// Room returns the id of the instance's copy of the template room.
+func (i Instance) Room(template string) string {
+	return i.ID + Separator + template
+}
+
+// TemplateRoom returns the id of the template room an instance room copies,
+// if it's in an instance.
+func TemplateRoom(room string) (string, bool) {
+	parts := strings.SplitN(room, Separator, 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// File is the layout of an instance file, a list of templates.
+type File struct {
+	Templates []Template `yaml:"templates"`
+}
+
+// Defs holds the templates, by zone.
+type Defs struct {
+	templates map[string]Template
+	mutex     *sync.RWMutex
+}
+
+// NewDefs creates definitions without templates.
+func NewDefs() *Defs {
+	return &Defs{
+		templates: make(map[string]Template),
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+// Add makes the zone a template, replacing what made it one.
+func (d *Defs) Add(t Template) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.templates[t.Zone] = t
+
+	return nil
+}
+
+// Template returns the template of the zone, if it's one.
+func (d *Defs) Template(zone string) (Template, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	t, ok := d.templates[zone]
+
+	return t, ok
+}
+
+// Templates returns every template, sorted by zone.
+func (d *Defs) Templates() []Template {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	templates := make([]Template, 0, len(d.templates))
+	for _, t := range d.templates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Zone < templates[j].Zone
+	})
+
+	return templates
+}
+
+// LoadDir adds the templates in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the templates in the YAML document, like:
+//   templates:
+//     - zone: crypt
+//       per: group
+//       exit: graveyard
+//       lifetime: 2h
+//       empty: 10m
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, t := range f.Templates {
+		if err := d.Add(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
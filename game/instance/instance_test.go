@@ -0,0 +1,252 @@
+package instance_test
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/instance"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// explorer is a player who remembers what they're told
+type explorer struct {
+	name     string
+	location string
+	sent     []string
+}
+
+func (e *explorer) Name() string {
+	return e.name
+}
+
+func (e *explorer) Location() string {
+	return e.location
+}
+
+func (e *explorer) SetLocation(room string) {
+	e.location = room
+}
+
+func (e *explorer) Send(text string) error {
+	e.sent = append(e.sent, text)
+
+	return nil
+}
+
+const templates = `
+templates:
+  - zone: crypt
+    exit: graveyard
+    empty: 10m
+  - zone: tower
+    per: player
+    exit: graveyard
+    lifetime: 1h
+`
+
+var _ = Describe("Defs", func() {
+	It("reads templates and refuses those that make no sense", func() {
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(templates))).Should(Succeed())
+
+		t, ok := defs.Template("crypt")
+		Ω(ok).Should(BeTrue())
+		Ω(t.Per).Should(Equal(PerGroup))
+		Ω(defs.Templates()).Should(HaveLen(2))
+
+		Ω(defs.Add(Template{Zone: "crypt"})).ShouldNot(Succeed())
+		Ω(defs.Add(Template{Zone: "crypt", Exit: "gate", Per: "guild"})).ShouldNot(Succeed())
+		Ω(defs.Add(Template{Zone: "crypt", Exit: "gate", Empty: "soon"})).ShouldNot(Succeed())
+		Ω(defs.LoadYAML([]byte("templates:\n  - zone: x\n    exit: y\n    colour: red\n"))).ShouldNot(Succeed())
+	})
+
+	It("finds the template rooms instance rooms copy", func() {
+		room, ok := TemplateRoom("crypt-1/hall")
+		Ω(ok).Should(BeTrue())
+		Ω(room).Should(Equal("hall"))
+		_, ok = TemplateRoom("hall")
+		Ω(ok).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		w             *world.World
+		em            *events.Emitter
+		mv            *movement.Movement
+		m             *Manager
+		now           time.Time
+		ann, bob, cat *explorer
+		groups        map[string]string
+		reset         []string
+		cleared       []string
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		for _, zone := range []string{"town", "crypt", "tower"} {
+			Ω(w.AddZone(world.Zone{ID: zone, Name: zone})).Should(Succeed())
+		}
+		for _, r := range []world.Room{
+			{ID: "graveyard", Zone: "town"},
+			{ID: "stairs", Zone: "crypt"},
+			{ID: "tomb", Zone: "crypt"},
+			{ID: "top", Zone: "tower"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("graveyard", world.Down, "stairs")).Should(Succeed())
+		Ω(w.Link("stairs", world.North, "tomb")).Should(Succeed())
+		Ω(w.Link("graveyard", world.Up, "top")).Should(Succeed())
+		Ω(w.SetResets("crypt", []world.Reset{
+			{NPC: "ghoul", Room: "tomb", Max: 1},
+			{Script: "seal"},
+		})).Should(Succeed())
+
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(templates))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		mv = movement.New(w, nil)
+		m = NewManager(defs, w, mv, em)
+		mv.SetRouter(m.Route)
+		Ω(m.TakeResets()).Should(Succeed())
+
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time { return now })
+		ann = &explorer{name: "Ann", location: "graveyard"}
+		bob = &explorer{name: "Bob", location: "graveyard"}
+		cat = &explorer{name: "Cat", location: "graveyard"}
+		groups = map[string]string{"Ann": "g1", "Bob": "g1"}
+		m.SetGroup(func(name string) string {
+			return groups[name]
+		})
+		m.SetOccupants(func(room string) []movement.Mover {
+			var in []movement.Mover
+			for _, e := range []*explorer{ann, bob, cat} {
+				if e.location == room {
+					in = append(in, e)
+				}
+			}
+
+			return in
+		})
+		reset, cleared = nil, nil
+		m.SetReset(func(zone string) error {
+			reset = append(reset, zone)
+
+			return nil
+		})
+		m.SetClear(func(room string) {
+			cleared = append(cleared, room)
+		})
+	})
+
+	It("leads groups into an instance of their own", func() {
+		Ω(w.Resets("crypt")).Should(BeEmpty())
+
+		Ω(mv.Move(ann, world.Down)).Should(Succeed())
+		Ω(ann.location).Should(Equal("crypt-1/stairs"))
+		Ω(mv.Move(ann, world.North)).Should(Succeed())
+		Ω(ann.location).Should(Equal("crypt-1/tomb"))
+		Ω(mv.Move(bob, world.Down)).Should(Succeed())
+		Ω(bob.location).Should(Equal("crypt-1/stairs"))
+		Ω(mv.Move(cat, world.Down)).Should(Succeed())
+		Ω(cat.location).Should(Equal("crypt-2/stairs"))
+
+		inst, ok := m.Of("crypt-1/tomb")
+		Ω(ok).Should(BeTrue())
+		Ω(inst.Owner).Should(Equal("group:g1"))
+		Ω(m.Instances()).Should(HaveLen(2))
+		Ω(reset).Should(Equal([]string{"crypt-1", "crypt-2"}))
+		Ω(w.Resets("crypt-1")).Should(Equal([]world.Reset{
+			{NPC: "ghoul", Room: "crypt-1/tomb"},
+			{Script: "seal"},
+		}))
+
+		Ω(mv.Move(ann, world.South)).Should(Succeed())
+		Ω(mv.Move(ann, world.Up)).Should(Succeed())
+		Ω(ann.location).Should(Equal("graveyard"))
+	})
+
+	It("adds the rooms and resets generated for new instances", func() {
+		var made []string
+		m.SetGenerate(func(inst Instance) ([]world.Reset, error) {
+			made = append(made, inst.ID)
+			Ω(w.AddRoom(world.Room{ID: inst.Room("pit"), Zone: inst.ID})).Should(Succeed())
+			Ω(w.Link(inst.Room("tomb"), world.Down, inst.Room("pit"))).Should(Succeed())
+
+			return []world.Reset{{Item: "bones", Room: inst.Room("pit")}}, nil
+		})
+
+		Ω(mv.Move(ann, world.Down)).Should(Succeed())
+		Ω(made).Should(Equal([]string{"crypt-1"}))
+		Ω(mv.Move(ann, world.North)).Should(Succeed())
+		Ω(mv.Move(ann, world.Down)).Should(Succeed())
+		Ω(ann.location).Should(Equal("crypt-1/pit"))
+		Ω(w.Resets("crypt-1")).Should(ContainElement(world.Reset{Item: "bones", Room: "crypt-1/pit"}))
+	})
+
+	It("gives players instances of their own when the template says", func() {
+		Ω(mv.Move(ann, world.Up)).Should(Succeed())
+		Ω(mv.Move(bob, world.Up)).Should(Succeed())
+
+		Ω(ann.location).Should(Equal("tower-1/top"))
+		Ω(bob.location).Should(Equal("tower-2/top"))
+	})
+
+	It("lets handlers keep players out", func() {
+		em.On("before:"+CreateEvent, events.HandlerFunc(func(d events.Data) error {
+			return errors.New("The crypt is sealed.")
+		}))
+
+		Ω(mv.Move(cat, world.Down)).Should(MatchError("The crypt is sealed."))
+		Ω(cat.location).Should(Equal("graveyard"))
+		Ω(m.Instances()).Should(BeEmpty())
+	})
+
+	It("destroys instances that stay empty", func() {
+		destroyed := make(chan events.Data, 1)
+		em.On(DestroyEvent, events.HandlerFunc(func(d events.Data) error {
+			destroyed <- d
+
+			return nil
+		}))
+		mv.Move(ann, world.Down)
+
+		m.Update()
+		Ω(m.Instances()).Should(HaveLen(1))
+		mv.Move(ann, world.Up)
+		m.Update()
+		now = now.Add(5 * time.Minute)
+		m.Update()
+		Ω(m.Instances()).Should(HaveLen(1))
+		now = now.Add(5 * time.Minute)
+		m.Update()
+
+		Ω(m.Instances()).Should(BeEmpty())
+		_, ok := w.Room("crypt-1/stairs")
+		Ω(ok).Should(BeFalse())
+		Ω(cleared).Should(ConsistOf("crypt-1/stairs", "crypt-1/tomb"))
+		Eventually(destroyed).Should(Receive(HaveKeyWithValue("instance", "crypt-1")))
+
+		mv.Move(ann, world.Down)
+		Ω(ann.location).Should(Equal("crypt-2/stairs"))
+	})
+
+	It("sends players out of instances that have lasted their lifetime", func() {
+		mv.Move(ann, world.Up)
+		now = now.Add(time.Hour)
+		m.Update()
+
+		Ω(ann.location).Should(Equal("graveyard"))
+		Ω(strings.Join(ann.sent, "\n")).Should(ContainSubstring(FadeMessage))
+		Ω(m.Instances()).Should(BeEmpty())
+		Ω(m.Destroy("tower-1")).Should(MatchError("There's no instance \"tower-1\"."))
+	})
+})
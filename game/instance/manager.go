@@ -0,0 +1,533 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package instance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// Messages told to players about instances.
+const (
+	CancelMessage = "You can't go there right now."
+	FadeMessage   = "The world around you fades away."
+)
+
+// Manager makes and destroys the instances of template zones, and leads
+// players into theirs.
+type Manager struct {
+	defs      *Defs
+	world     *world.World
+	movement  *movement.Movement
+	group     func(name string) string
+	occupants func(room string) []movement.Mover
+	reset     func(zone string) error
+	clear     func(room string)
+	generate  func(inst Instance) ([]world.Reset, error)
+	// resets holds the resets taken from each template zone, by zone
+	resets    map[string][]world.Reset
+	instances map[string]*Instance
+	// owned holds the id of each owner's instance of each template, by
+	// template zone and owner
+	owned   map[string]string
+	seq     uint64
+	now     func() time.Time
+	stop    chan struct{}
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager copying template zones in the world, which
+// sends players out of destroyed instances with the Movement.
+func NewManager(defs *Defs, w *world.World, mv *movement.Movement, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:      defs,
+		world:     w,
+		movement:  mv,
+		resets:    make(map[string][]world.Reset),
+		instances: make(map[string]*Instance),
+		owned:     make(map[string]string),
+		now:       time.Now,
+		emitter:   em,
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager the game uses.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), world.Global(), movement.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// Defs returns the templates.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetGroup sets how the id of a player's group is found, empty if they
+// aren't in one. Without it every player has instances of their own.
+func (m *Manager) SetGroup(fn func(name string) string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.group = fn
+}
+
+// SetOccupants sets how the players in a room are found. Without it
+// instances always seem empty.
+func (m *Manager) SetOccupants(fn func(room string) []movement.Mover) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetReset sets what runs the resets of a new instance's zone, filling it
+// with mobs and items.
+func (m *Manager) SetReset(fn func(zone string) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reset = fn
+}
+
+// SetClear sets what clears the mobs and items out of a room of an
+// instance being destroyed.
+func (m *Manager) SetClear(fn func(room string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.clear = fn
+}
+
+// SetGenerate sets what adds generated rooms to a new instance, like
+// procedural dungeons, returning resets for them. It runs after the
+// template's rooms are copied and before the instance is reset.
+func (m *Manager) SetGenerate(fn func(inst Instance) ([]world.Reset, error)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.generate = fn
+}
+
+// SetClock changes how the manager tells the time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// TakeResets moves the resets of every template zone out of the world,
+// so the template zones stay empty and only their instances are reset.
+func (m *Manager) TakeResets() error {
+	for _, t := range m.defs.Templates() {
+		resets := m.world.Resets(t.Zone)
+		if len(resets) == 0 {
+			continue
+		}
+		if err := m.world.SetResets(t.Zone, nil); err != nil {
+			return fmt.Errorf("template %q: %s", t.Zone, err)
+		}
+
+		m.mutex.Lock()
+		m.resets[t.Zone] = resets
+		m.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// Route returns the room the mover taking an exit into the room really
+// enters. Rooms of template zones lead into the copy in the instance
+// belonging to the mover, or their group, which is made if there isn't one.
+func (m *Manager) Route(mover movement.Mover, room string) (string, error) {
+	r, ok := m.world.Room(room)
+	if !ok {
+		return room, nil
+	}
+	t, ok := m.defs.Template(r.Zone)
+	if !ok {
+		return room, nil
+	}
+
+	owner := m.owner(mover, t)
+	m.mutex.RLock()
+	id, ok := m.owned[ownedKey(t.Zone, owner)]
+	m.mutex.RUnlock()
+
+	if ok {
+		return id + Separator + room, nil
+	}
+
+	inst, err := m.create(t, owner)
+	if err != nil {
+		return "", err
+	}
+
+	return inst.Room(room), nil
+}
+
+// Instance returns the instance with the id.
+func (m *Manager) Instance(id string) (Instance, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	inst, ok := m.instances[id]
+	if !ok {
+		return Instance{}, false
+	}
+
+	return *inst, true
+}
+
+// Of returns the instance the room is in, if it's in one.
+func (m *Manager) Of(room string) (Instance, bool) {
+	r, ok := m.world.Room(room)
+	if !ok {
+		return Instance{}, false
+	}
+
+	return m.Instance(r.Zone)
+}
+
+// Instances returns every instance, sorted by id.
+func (m *Manager) Instances() []Instance {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	instances := make([]Instance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		instances = append(instances, *inst)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+
+	return instances
+}
+
+// Update destroys the instances that have lasted their template's
+// lifetime, and those found empty on an update that have stayed empty for
+// the template's empty duration.
+func (m *Manager) Update() {
+	m.mutex.RLock()
+	now := m.now()
+	m.mutex.RUnlock()
+
+	for _, inst := range m.Instances() {
+		t, _ := m.defs.Template(inst.Template)
+		if t.lifetime > 0 && now.Sub(inst.Created) >= t.lifetime {
+			m.Destroy(inst.ID)
+
+			continue
+		}
+
+		occupied := len(m.players(inst.ID)) > 0
+		m.mutex.Lock()
+		current, ok := m.instances[inst.ID]
+		if !ok {
+			m.mutex.Unlock()
+
+			continue
+		}
+		emptied := current.Emptied
+		switch {
+		case occupied:
+			current.Emptied = time.Time{}
+		case emptied.IsZero():
+			current.Emptied = now
+		}
+		m.mutex.Unlock()
+
+		if !occupied && !emptied.IsZero() && now.Sub(emptied) >= t.empty {
+			m.Destroy(inst.ID)
+		}
+	}
+}
+
+// Start updates the instances every interval. It does nothing if they're
+// already being updated.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil || interval <= 0 {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Update()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+// Stop halts the updates.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Destroy sends the players in the instance to its template's exit room,
+// clears its rooms and removes them from the world.
+func (m *Manager) Destroy(id string) error {
+	m.mutex.Lock()
+	inst, ok := m.instances[id]
+	if ok {
+		delete(m.instances, id)
+		delete(m.owned, ownedKey(inst.Template, inst.Owner))
+	}
+	sweep := m.clear
+	m.mutex.Unlock()
+
+	if !ok {
+		return refuse(fmt.Sprintf("There's no instance %q.", id))
+	}
+
+	t, _ := m.defs.Template(inst.Template)
+	for _, p := range m.players(id) {
+		if s, ok := p.(movement.Sender); ok {
+			s.Send(FadeMessage)
+		}
+		m.movement.Teleport(p, t.Exit)
+	}
+	if sweep != nil {
+		for _, r := range m.world.Rooms(id) {
+			sweep(r.ID)
+		}
+	}
+	if err := m.world.RemoveZone(id); err != nil {
+		return err
+	}
+	m.emit(DestroyEvent, inst.data())
+
+	return nil
+}
+
+// create copies the template's zone into a new instance for the owner
+func (m *Manager) create(t Template, owner string) (*Instance, error) {
+	z, ok := m.world.Zone(t.Zone)
+	if !ok {
+		return nil, world.ErrNoZone
+	}
+	data := events.Data{"template": t.Zone, "owner": owner}
+	if err := m.check(CreateEvent, data); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	if id, ok := m.owned[ownedKey(t.Zone, owner)]; ok {
+		inst := *m.instances[id]
+		m.mutex.Unlock()
+
+		return &inst, nil
+	}
+	m.seq++
+	inst := &Instance{
+		ID:       fmt.Sprintf("%s-%d", t.Zone, m.seq),
+		Template: t.Zone,
+		Owner:    owner,
+		Created:  m.now(),
+	}
+	resets, taken := m.resets[t.Zone]
+	resets = append([]world.Reset(nil), resets...)
+	reset, generate := m.reset, m.generate
+	if err := m.copyZone(z, inst); err != nil {
+		m.mutex.Unlock()
+
+		return nil, err
+	}
+	m.instances[inst.ID] = inst
+	m.owned[ownedKey(t.Zone, owner)] = inst.ID
+	m.mutex.Unlock()
+
+	if !taken {
+		resets = m.world.Resets(t.Zone)
+	}
+	for i := range resets {
+		if resets[i].Room != "" {
+			resets[i].Room = inst.Room(resets[i].Room)
+		}
+		resets[i].Max = 0
+	}
+	if generate != nil {
+		generated, err := generate(*inst)
+		if err != nil {
+			logger.NewWithSource("instance").WithError(err).WithField("instance", inst.ID).Warn("Instance generation failed.")
+		}
+		resets = append(resets, generated...)
+	}
+	if err := m.world.SetResets(inst.ID, resets); err != nil {
+		return nil, err
+	}
+	if reset != nil {
+		if err := reset(inst.ID); err != nil {
+			logger.NewWithSource("instance").WithError(err).WithField("instance", inst.ID).Warn("Instance reset failed.")
+		}
+	}
+
+	data = inst.data()
+	m.confirm(CreateEvent, data)
+
+	return inst, nil
+}
+
+// copyZone adds the instance's zone and copies of the template's rooms to
+// the world, exits between template rooms lead between the copies
+func (m *Manager) copyZone(z world.Zone, inst *Instance) error {
+	rooms := m.world.Rooms(z.ID)
+	in := make(map[string]bool, len(rooms))
+	for _, r := range rooms {
+		in[r.ID] = true
+	}
+
+	z.ID = inst.ID
+	z.Reset, z.Population, z.Builders, z.File = "", 0, nil, ""
+	if err := m.world.AddZone(z); err != nil {
+		return err
+	}
+	for _, r := range rooms {
+		r.ID, r.Zone = inst.Room(r.ID), inst.ID
+		for d, e := range r.Exits {
+			if in[e.To] {
+				e.To = inst.Room(e.To)
+				r.Exits[d] = e
+			}
+		}
+		if err := m.world.AddRoom(r); err != nil {
+			m.world.RemoveZone(inst.ID)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// owner returns who the mover's instances of the template belong to
+func (m *Manager) owner(mover movement.Mover, t Template) string {
+	m.mutex.RLock()
+	group := m.group
+	m.mutex.RUnlock()
+
+	if t.Per == PerGroup && group != nil {
+		if id := group(mover.Name()); id != "" {
+			return "group:" + id
+		}
+	}
+
+	return "player:" + strings.ToLower(mover.Name())
+}
+
+// players returns the players in the instance's rooms
+func (m *Manager) players(id string) []movement.Mover {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil {
+		return nil
+	}
+	var players []movement.Mover
+	for _, r := range m.world.Rooms(id) {
+		players = append(players, occupants(r.ID)...)
+	}
+
+	return players
+}
+
+// data returns the instance as event data
+func (i Instance) data() events.Data {
+	return events.Data{
+		"instance": i.ID,
+		"template": i.Template,
+		"owner":    i.Owner,
+	}
+}
+
+// ownedKey is how the instance of a template an owner has is found
+func ownedKey(template, owner string) string {
+	return template + "|" + owner
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package item
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// NewCommands creates the get, drop, put, give and inventory commands for
+// handling items with m. Items may be preceded by how many to handle, like
+// "drop 5 coins".
+func NewCommands(m *Manager, resolve command.Resolver[Carrier]) []*command.Command {
+	text := []command.Arg{{Name: "what", Kind: command.Text, Optional: true}}
+
+	return []*command.Command{
+		{
+			Name:    "get",
+			Aliases: []string{"take"},
+			Args:    text,
+			Help:    "Picks up an item, or takes it out of a container with \"get <item> [from] <container>\".",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Carrier) error {
+				count, what, from := parseObject(ctx.Input.Words, "from")
+				if what == "" {
+					return ctx.Send("Get what?")
+				}
+				it, err := m.Get(c, what, count, from)
+				if err != nil {
+					return err
+				}
+				if from != "" {
+					return ctx.Send(fmt.Sprintf("You get %s from %s.", it.Describe(), from))
+				}
+
+				return ctx.Send(fmt.Sprintf("You get %s.", it.Describe()))
+			}),
+		},
+		{
+			Name:   "drop",
+			Args:   text,
+			Help:   "Drops an item you carry.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Carrier) error {
+				count, what, _ := parseObject(ctx.Input.Words)
+				if what == "" {
+					return ctx.Send("Drop what?")
+				}
+				it, err := m.Drop(c, what, count)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You drop %s.", it.Describe()))
+			}),
+		},
+		{
+			Name:   "put",
+			Args:   text,
+			Help:   "Puts an item you carry into a container with \"put <item> [in] <container>\".",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Carrier) error {
+				count, what, into := parseObject(ctx.Input.Words, "in", "into")
+				if what == "" || into == "" {
+					return ctx.Send("Put what in what?")
+				}
+				it, container, err := m.Put(c, what, count, into)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You put %s in %s.", it.Describe(), container.Name))
+			}),
+		},
+		{
+			Name:   "give",
+			Args:   text,
+			Help:   "Gives an item you carry to someone with \"give <item> [to] <someone>\".",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Carrier) error {
+				count, what, to := parseObject(ctx.Input.Words, "to")
+				if what == "" || to == "" {
+					return ctx.Send("Give what to whom?")
+				}
+				it, target, err := m.Give(c, what, count, to)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You give %s to %s.", it.Describe(), target.Name()))
+			}),
+		},
+		{
+			Name:      "inventory",
+			Aliases:   []string{"i"},
+			MinAbbrev: 3,
+			Help:      "Lists what you carry.",
+			Source:    "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Carrier) error {
+				items := c.Inventory()
+				if len(items) == 0 {
+					return ctx.Send("You aren't carrying anything.")
+				}
+				lines := []string{"You are carrying:"}
+				lines = append(lines, listLines(items, "  ")...)
+
+				return ctx.Send(strings.Join(lines, "\n"))
+			}),
+		},
+	}
+}
+
+// handler resolves the caller's carrier for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Carrier], fn func(*command.Context, Carrier) error) command.Handler {
+	return func(ctx *command.Context) error {
+		c := resolve(ctx.Caller)
+		if c == nil {
+			return ctx.Send("You can't carry anything.")
+		}
+
+		err := fn(ctx, c)
+		if r, ok := err.(*Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
+
+// parseObject splits words like "5 coins from bag" into how many, what and
+// the words after the first separator, the separator may be left out if
+// there's one word left.
+func parseObject(words []string, separators ...string) (int, string, string) {
+	count := 0
+	if len(words) > 1 {
+		if n, err := strconv.Atoi(words[0]); err == nil && n > 0 {
+			count, words = n, words[1:]
+		}
+	}
+	if len(words) == 0 {
+		return count, "", ""
+	}
+
+	what, rest := words[0], words[1:]
+	if len(rest) > 0 {
+		for _, sep := range separators {
+			if strings.EqualFold(rest[0], sep) {
+				rest = rest[1:]
+
+				break
+			}
+		}
+	}
+
+	return count, what, strings.Join(rest, " ")
+}
+
+// listLines lists the items, with the contents of containers indented below
+// them
+func listLines(items List, indent string) []string {
+	var lines []string
+	for _, it := range items {
+		lines = append(lines, indent+it.Describe())
+		if len(it.Contents) > 0 {
+			lines = append(lines, listLines(it.Contents, indent+"  ")...)
+		}
+	}
+
+	return lines
+}
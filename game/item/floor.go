@@ -0,0 +1,155 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package item
+
+import (
+	"sort"
+	"sync"
+)
+
+// Floor holds the items lying in each room.
+type Floor struct {
+	rooms map[string]List
+	mutex *sync.RWMutex
+}
+
+// NewFloor creates a floor with nothing on it.
+func NewFloor() *Floor {
+	return &Floor{
+		rooms: make(map[string]List),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+var (
+	globalFloor *Floor
+	floorOnce   sync.Once
+)
+
+// GlobalFloor returns the floor of the game's rooms.
+func GlobalFloor() *Floor {
+	floorOnce.Do(func() {
+		globalFloor = NewFloor()
+	})
+
+	return globalFloor
+}
+
+// In returns the items in the room.
+func (f *Floor) In(room string) List {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	return f.rooms[room].Copy()
+}
+
+// Rooms returns the ids of the rooms with items in them, sorted.
+func (f *Floor) Rooms() []string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	rooms := make([]string, 0, len(f.rooms))
+	for room := range f.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+
+	return rooms
+}
+
+// Snapshot returns a copy of the items in every room, by room id.
+func (f *Floor) Snapshot() map[string]List {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	rooms := make(map[string]List, len(f.rooms))
+	for room, items := range f.rooms {
+		rooms[room] = items.Copy()
+	}
+
+	return rooms
+}
+
+// Restore replaces everything on the floor with the items of a snapshot.
+func (f *Floor) Restore(rooms map[string]List) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.rooms = make(map[string]List, len(rooms))
+	for room, items := range rooms {
+		if len(items) > 0 {
+			f.rooms[room] = items.Copy()
+		}
+	}
+}
+
+// Add leaves the item in the room.
+func (f *Floor) Add(room string, it Item) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.rooms[room] = f.rooms[room].Add(it)
+}
+
+// Update changes the items in the room with fn, nothing changes if it fails.
+func (f *Floor) Update(room string, fn func(List) (List, error)) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	items, err := fn(f.rooms[room].Copy())
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		delete(f.rooms, room)
+	} else {
+		f.rooms[room] = items
+	}
+
+	return nil
+}
+
+// Clear removes every item in the room, returning them.
+func (f *Floor) Clear(room string) List {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	items := f.rooms[room]
+	delete(f.rooms, room)
+
+	return items
+}
+
+// UpdateItem changes the item with the id wherever it lies, even inside a
+// container, returning false if it isn't on the floor.
+func (f *Floor) UpdateItem(id string, fn func(*Item)) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for room, items := range f.rooms {
+		if changed, ok := items.Update(id, fn); ok {
+			f.rooms[room] = changed
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveItem destroys the item with the id wherever it lies, returning false
+// if it isn't on the floor.
+func (f *Floor) RemoveItem(id string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for room, items := range f.rooms {
+		if changed, ok := items.Remove(id); ok {
+			f.rooms[room] = changed
+
+			return true
+		}
+	}
+
+	return false
+}
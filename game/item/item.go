@@ -0,0 +1,435 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package item holds the things characters carry and find lying around. Items
+// are instances made from the item definitions of areas, each with its own id
+// so it can change without changing others of its kind. Items of a kind marked
+// stackable are kept together as one item with a count, containers hold other
+// items up to the weight they can carry.
+package item
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/world"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	// ErrNoProto is returned when creating an item of a kind that isn't
+	// defined.
+	ErrNoProto = errors.New("no such item definition")
+
+	// ErrNotFound is returned when there's no item with an id.
+	ErrNotFound = errors.New("no such item")
+
+	// ErrNotContainer is returned when putting an item into something that
+	// isn't a container.
+	ErrNotContainer = errors.New("item isn't a container")
+
+	// ErrTooHeavy is returned when there's no room for an item's weight.
+	ErrTooHeavy = errors.New("item is too heavy")
+
+	// ErrInsideItself is returned when putting a container into itself.
+	ErrInsideItself = errors.New("item can't go inside itself")
+)
+
+// Flags and props of containers that can be closed and locked.
+const (
+	// ClosedFlag marks containers that are closed, nothing can be put in or
+	// taken out of them.
+	ClosedFlag = "closed"
+	// LockedFlag marks closed containers that are locked.
+	LockedFlag = "locked"
+	// PickproofFlag marks containers whose locks can't be picked.
+	PickproofFlag = "pickproof"
+	// KeyProp is the prop naming the id of the item that locks and unlocks
+	// the container, those without one can't be locked.
+	KeyProp = "key"
+)
+
+// Item is a thing in the game.
+type Item struct {
+	ID string `json:"id"`
+	// Proto is the id of the definition the item was made from.
+	Proto       string   `json:"proto,omitempty"`
+	Name        string   `json:"name"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	// Weight and Value are for one of the item, a stack weighs its count
+	// times as much.
+	Weight int `json:"weight,omitempty"`
+	Value  int `json:"value,omitempty"`
+	// Count is the number of items in a stack, zero is one.
+	Count int `json:"count,omitempty"`
+	// Capacity is the weight a container holds, zero is no limit.
+	Capacity int                    `json:"capacity,omitempty"`
+	Flags    []string               `json:"flags,omitempty"`
+	Props    map[string]interface{} `json:"props,omitempty"`
+	// Contents are the items in a container.
+	Contents List `json:"contents,omitempty"`
+}
+
+// New makes count of the defined item, more than one only for stackable
+// items.
+func New(def world.ItemDef, count int) Item {
+	it := Item{
+		ID:          newID(),
+		Proto:       def.ID,
+		Name:        def.Name,
+		Keywords:    append([]string(nil), def.Keywords...),
+		Description: def.Description,
+		Type:        def.Type,
+		Weight:      def.Weight,
+		Value:       def.Value,
+		Flags:       append([]string(nil), def.Flags...),
+	}
+	for k, v := range def.Props {
+		if k == "capacity" {
+			if n, ok := v.(int); ok {
+				it.Capacity = n
+			}
+
+			continue
+		}
+		if it.Props == nil {
+			it.Props = make(map[string]interface{})
+		}
+		it.Props[k] = v
+	}
+	if count > 1 && it.Stackable() {
+		it.Count = count
+	}
+
+	return it
+}
+
+// Create makes count of the item defined in the world with the id.
+func Create(w *world.World, proto string, count int) (Item, error) {
+	def, ok := w.Item(proto)
+	if !ok {
+		return Item{}, ErrNoProto
+	}
+
+	return New(def, count), nil
+}
+
+// UnmarshalJSON reads either a full item or, as inventories were once saved,
+// just an id.
+func (it *Item) UnmarshalJSON(b []byte) error {
+	var id string
+	if err := json.Unmarshal(b, &id); err == nil {
+		*it = Item{ID: id, Proto: id, Name: id}
+
+		return nil
+	}
+
+	type plain Item
+
+	return json.Unmarshal(b, (*plain)(it))
+}
+
+// Quantity is the number of items in the stack.
+func (it Item) Quantity() int {
+	if it.Count < 1 {
+		return 1
+	}
+
+	return it.Count
+}
+
+// TotalWeight is the weight of the whole stack along with anything inside
+// it.
+func (it Item) TotalWeight() int {
+	return it.Weight*it.Quantity() + it.Contents.Weight()
+}
+
+// Flag is true if the item has the flag.
+func (it Item) Flag(name string) bool {
+	for _, flag := range it.Flags {
+		if strings.EqualFold(flag, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetFlag sets the flag on the item, or removes it.
+func (it *Item) SetFlag(name string, on bool) {
+	flags := make([]string, 0, len(it.Flags)+1)
+	for _, flag := range it.Flags {
+		if !strings.EqualFold(flag, name) {
+			flags = append(flags, flag)
+		}
+	}
+	if on {
+		flags = append(flags, strings.ToLower(name))
+	}
+	it.Flags = flags
+}
+
+// Stackable items are kept in stacks with others of their kind, like coins.
+func (it Item) Stackable() bool {
+	return it.Type == "money" || it.Flag("stackable")
+}
+
+// Container is true for items that hold other items.
+func (it Item) Container() bool {
+	return it.Type == "container" || it.Capacity > 0
+}
+
+// Matches is true if the keyword refers to the item, as the start of one of
+// its keywords or of a word in its name.
+func (it Item) Matches(keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	if keyword == "" {
+		return false
+	}
+	if it.ID == keyword {
+		return true
+	}
+	words := append(strings.Fields(strings.ToLower(it.Name)), it.Keywords...)
+	for _, word := range words {
+		if strings.HasPrefix(strings.ToLower(word), keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Describe names the item for players, with the size of stacks.
+func (it Item) Describe() string {
+	if it.Quantity() > 1 {
+		return it.Name + " (" + strconv.Itoa(it.Quantity()) + ")"
+	}
+
+	return it.Name
+}
+
+// Put returns the container with the item inside it.
+func (it Item) Put(in Item) (Item, error) {
+	if !it.Container() {
+		return it, ErrNotContainer
+	}
+	if in.ID == it.ID || in.Contents.contains(it.ID) {
+		return it, ErrInsideItself
+	}
+	if it.Capacity > 0 && it.Contents.Weight()+in.TotalWeight() > it.Capacity {
+		return it, ErrTooHeavy
+	}
+	it = it.Copy()
+	it.Contents = it.Contents.Add(in)
+
+	return it, nil
+}
+
+// Copy returns a deep copy of the item.
+func (it Item) Copy() Item {
+	it.Keywords = append([]string(nil), it.Keywords...)
+	it.Flags = append([]string(nil), it.Flags...)
+	if it.Props != nil {
+		props := make(map[string]interface{}, len(it.Props))
+		for k, v := range it.Props {
+			props[k] = v
+		}
+		it.Props = props
+	}
+	it.Contents = it.Contents.Copy()
+
+	return it
+}
+
+// stacksWith is true if the items can be kept in one stack
+func (it Item) stacksWith(other Item) bool {
+	return it.Stackable() && other.Stackable() &&
+		it.Proto != "" && it.Proto == other.Proto && it.Name == other.Name &&
+		len(it.Props) == 0 && len(other.Props) == 0 &&
+		len(it.Contents) == 0 && len(other.Contents) == 0
+}
+
+// List is a set of items, like what a player carries or what lies on the
+// floor of a room. Its methods return changed copies.
+type List []Item
+
+// Copy returns a deep copy of the list.
+func (l List) Copy() List {
+	if l == nil {
+		return nil
+	}
+	items := make(List, len(l))
+	for i, it := range l {
+		items[i] = it.Copy()
+	}
+
+	return items
+}
+
+// Weight is the total weight of the items.
+func (l List) Weight() int {
+	total := 0
+	for _, it := range l {
+		total += it.TotalWeight()
+	}
+
+	return total
+}
+
+// Get returns the item with the id.
+func (l List) Get(id string) (Item, bool) {
+	for _, it := range l {
+		if it.ID == id {
+			return it.Copy(), true
+		}
+	}
+
+	return Item{}, false
+}
+
+// Find returns the item the keyword refers to, "2.sword" is the second item
+// matching "sword".
+func (l List) Find(keyword string) (Item, bool) {
+	n, keyword := Ordinal(keyword)
+	for _, it := range l {
+		if it.Matches(keyword) {
+			n--
+			if n == 0 {
+				return it.Copy(), true
+			}
+		}
+	}
+
+	return Item{}, false
+}
+
+// Ordinal splits a keyword like "2.sword" into which match it refers to and
+// the keyword to match, keywords without a number refer to the first match.
+func Ordinal(keyword string) (int, string) {
+	if i := strings.Index(keyword, "."); i > 0 {
+		if nth, err := strconv.Atoi(keyword[:i]); err == nil && nth > 0 {
+			return nth, keyword[i+1:]
+		}
+	}
+
+	return 1, keyword
+}
+
+// Add returns the list with the item added, joining its stack if there is
+// one.
+func (l List) Add(it Item) List {
+	l = l.Copy()
+	for i, other := range l {
+		if other.stacksWith(it) {
+			l[i].Count = other.Quantity() + it.Quantity()
+
+			return l
+		}
+	}
+
+	return append(l, it.Copy())
+}
+
+// Take returns the list without count of the item with the id, and what was
+// taken. Taking part of a stack splits it, count below one takes it all.
+func (l List) Take(id string, count int) (List, Item, error) {
+	for i, it := range l {
+		if it.ID != id {
+			continue
+		}
+		l = l.Copy()
+		if count < 1 || count >= it.Quantity() {
+			return append(l[:i], l[i+1:]...), it.Copy(), nil
+		}
+		l[i].Count = it.Quantity() - count
+		taken := it.Copy()
+		taken.ID = newID()
+		taken.Count = count
+
+		return l, taken, nil
+	}
+
+	return l, Item{}, ErrNotFound
+}
+
+// Replace returns the list with the item changed to it, matching by id.
+func (l List) Replace(it Item) (List, error) {
+	for i, other := range l {
+		if other.ID == it.ID {
+			l = l.Copy()
+			l[i] = it.Copy()
+
+			return l, nil
+		}
+	}
+
+	return l, ErrNotFound
+}
+
+// Update changes the item with the id using fn, looking inside containers
+// too. It returns the changed list and whether the item was found.
+func (l List) Update(id string, fn func(*Item)) (List, bool) {
+	for i, it := range l {
+		if it.ID == id {
+			l = l.Copy()
+			fn(&l[i])
+			l[i].ID = id
+
+			return l, true
+		}
+		if contents, ok := it.Contents.Update(id, fn); ok {
+			l = l.Copy()
+			l[i].Contents = contents
+
+			return l, true
+		}
+	}
+
+	return l, false
+}
+
+// Remove returns the list without the item with the id, looking inside
+// containers too.
+func (l List) Remove(id string) (List, bool) {
+	for i, it := range l {
+		if it.ID == id {
+			l = l.Copy()
+
+			return append(l[:i], l[i+1:]...), true
+		}
+		if contents, ok := it.Contents.Remove(id); ok {
+			l = l.Copy()
+			l[i].Contents = contents
+
+			return l, true
+		}
+	}
+
+	return l, false
+}
+
+// Without returns the list without the item with the id.
+func (l List) Without(id string) List {
+	items, _ := l.Remove(id)
+
+	return items
+}
+
+// contains is true if the item with the id is in the list, or inside an item
+// in it
+func (l List) contains(id string) bool {
+	for _, it := range l {
+		if it.ID == id || it.Contents.contains(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newID() string {
+	return uuid.NewV1().String()
+}
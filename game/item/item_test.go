@@ -0,0 +1,160 @@
+package item_test
+
+import (
+	"encoding/json"
+
+	. "github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	coinDef  = world.ItemDef{ID: "coin", Name: "gold coins", Keywords: []string{"gold"}, Type: "money", Weight: 1}
+	swordDef = world.ItemDef{ID: "sword", Name: "a long sword", Weight: 5}
+	bagDef   = world.ItemDef{ID: "bag", Name: "a leather bag", Type: "container", Weight: 1, Props: map[string]interface{}{"capacity": 10, "color": "brown"}}
+)
+
+var _ = Describe("Item", func() {
+	It("is made from its definition", func() {
+		bag := New(bagDef, 3)
+
+		Ω(bag.ID).ShouldNot(BeEmpty())
+		Ω(bag.Proto).Should(Equal("bag"))
+		Ω(bag.Capacity).Should(Equal(10))
+		Ω(bag.Props).Should(Equal(map[string]interface{}{"color": "brown"}))
+		Ω(bag.Quantity()).Should(Equal(1))
+		Ω(New(coinDef, 3).Quantity()).Should(Equal(3))
+		Ω(New(swordDef, 1).ID).ShouldNot(Equal(New(swordDef, 1).ID))
+	})
+
+	It("is found by keywords and words of its name", func() {
+		coins := New(coinDef, 20)
+
+		Ω(coins.Matches("GOLD")).Should(BeTrue())
+		Ω(coins.Matches("coi")).Should(BeTrue())
+		Ω(coins.Matches("silver")).Should(BeFalse())
+		Ω(coins.Describe()).Should(Equal("gold coins (20)"))
+	})
+
+	It("holds items up to its capacity", func() {
+		bag := New(bagDef, 1)
+		bag, err := bag.Put(New(swordDef, 1))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(bag.TotalWeight()).Should(Equal(6))
+
+		_, err = bag.Put(New(coinDef, 6))
+		Ω(err).Should(Equal(ErrTooHeavy))
+		_, err = bag.Put(bag)
+		Ω(err).Should(Equal(ErrInsideItself))
+		_, err = New(swordDef, 1).Put(bag)
+		Ω(err).Should(Equal(ErrNotContainer))
+	})
+
+	It("reads inventories saved as ids", func() {
+		var items List
+		Ω(json.Unmarshal([]byte(`["torch-1", {"id": "x", "name": "a rope", "count": 2}]`), &items)).Should(Succeed())
+
+		Ω(items).Should(Equal(List{
+			{ID: "torch-1", Proto: "torch-1", Name: "torch-1"},
+			{ID: "x", Name: "a rope", Count: 2},
+		}))
+	})
+})
+
+var _ = Describe("List", func() {
+	var (
+		items      List
+		sword, bag Item
+	)
+
+	BeforeEach(func() {
+		sword, bag = New(swordDef, 1), New(bagDef, 1)
+		items = List{}.Add(sword).Add(New(coinDef, 5)).Add(bag)
+	})
+
+	It("stacks items of a stackable kind", func() {
+		items = items.Add(New(coinDef, 7))
+
+		Ω(items).Should(HaveLen(3))
+		coins, ok := items.Find("gold")
+		Ω(ok).Should(BeTrue())
+		Ω(coins.Quantity()).Should(Equal(12))
+		Ω(items.Weight()).Should(Equal(18))
+	})
+
+	It("finds the nth item matching", func() {
+		items = items.Add(New(swordDef, 1))
+
+		first, _ := items.Find("sword")
+		second, ok := items.Find("2.sword")
+		Ω(ok).Should(BeTrue())
+		Ω(first.ID).Should(Equal(sword.ID))
+		Ω(second.ID).ShouldNot(Equal(sword.ID))
+		_, ok = items.Find("3.sword")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("splits stacks when taking part of them", func() {
+		coins, _ := items.Find("coins")
+		left, taken, err := items.Take(coins.ID, 2)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(taken.Quantity()).Should(Equal(2))
+		Ω(taken.ID).ShouldNot(Equal(coins.ID))
+		rest, _ := left.Get(coins.ID)
+		Ω(rest.Quantity()).Should(Equal(3))
+
+		left, taken, err = left.Take(sword.ID, 0)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(taken.ID).Should(Equal(sword.ID))
+		Ω(left).Should(HaveLen(2))
+
+		_, _, err = left.Take(sword.ID, 0)
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+
+	It("changes and removes items inside containers", func() {
+		items, _, _ = items.Take(sword.ID, 0)
+		bag, _ = bag.Put(sword)
+		items, _ = items.Replace(bag)
+
+		items, ok := items.Update(sword.ID, func(it *Item) {
+			it.Name = "a notched sword"
+		})
+		Ω(ok).Should(BeTrue())
+		inside, _ := items.Find("bag")
+		Ω(inside.Contents[0].Name).Should(Equal("a notched sword"))
+
+		items, ok = items.Remove(sword.ID)
+		Ω(ok).Should(BeTrue())
+		inside, _ = items.Find("bag")
+		Ω(inside.Contents).Should(BeEmpty())
+	})
+
+	It("leaves the original list alone", func() {
+		changed, _ := items.Update(sword.ID, func(it *Item) {
+			it.Name = "a broken sword"
+		})
+
+		original, _ := items.Get(sword.ID)
+		Ω(original.Name).Should(Equal("a long sword"))
+		Ω(changed[0].Name).Should(Equal("a broken sword"))
+	})
+})
+
+var _ = Describe("Floor", func() {
+	It("snapshots and restores what lies in the rooms", func() {
+		f := NewFloor()
+		f.Add("gate", Item{ID: "torch-1", Name: "a torch", Count: 1})
+		snap := f.Snapshot()
+
+		f.Add("gate", Item{ID: "gem-1", Name: "a duped gem", Count: 1})
+		f.Add("vault", Item{ID: "gem-2", Name: "a duped gem", Count: 1})
+		f.Restore(snap)
+
+		Ω(f.Rooms()).Should(Equal([]string{"gate"}))
+		Ω(f.In("gate")).Should(Equal(List{{ID: "torch-1", Name: "a torch", Count: 1}}))
+	})
+})
@@ -0,0 +1,551 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package item
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+)
+
+// The events of handling items. Handlers of the before: events can stop the
+// action by returning events.ErrHalt, or an error whose message is told to
+// the one acting. Each is given the actor's name, the room, the id, kind and
+// name of the item and how many of it, item:put is also given the container's
+// id and item:give the name of who it's given to, and their id if they have
+// one.
+const (
+	GetEvent  = "item:get"
+	DropEvent = "item:drop"
+	PutEvent  = "item:put"
+	GiveEvent = "item:give"
+)
+
+// Messages told to carriers that can't do something with an item.
+const (
+	NotHereMessage    = "You don't see that here."
+	NotCarriedMessage = "You aren't carrying that."
+	TooHeavyMessage   = "You can't carry that much weight."
+	NoTakeMessage     = "You can't take that."
+	NoDropMessage     = "You can't let go of it."
+	NoOneMessage      = "They aren't here."
+	CancelMessage     = "You can't do that right now."
+)
+
+// Carrier is anything that carries items, like a player.
+type Carrier interface {
+	Name() string
+	Location() string
+	Inventory() List
+	// UpdateInventory changes the items carried with fn, nothing changes if
+	// it fails.
+	UpdateInventory(fn func(List) (List, error)) error
+}
+
+// identified is a carrier with an id, like a mob
+type identified interface {
+	ID() string
+}
+
+// Limited is a carrier that can only carry so much weight.
+type Limited interface {
+	CarryLimit() int
+}
+
+// Sender is a carrier that can be told what happens.
+type Sender interface {
+	Send(text string) error
+}
+
+// Refused is returned when a carrier can't do something with an item, its
+// message tells them why.
+type Refused struct {
+	Message string
+}
+
+func (r *Refused) Error() string {
+	return r.Message
+}
+
+func refused(format string, args ...interface{}) *Refused {
+	return &Refused{Message: fmt.Sprintf(format, args...)}
+}
+
+// Manager moves items between carriers, containers and the floor.
+type Manager struct {
+	floor     *Floor
+	emitter   *events.Emitter
+	occupants func(room string) []Carrier
+	visible   func(c Carrier, it Item) bool
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for items on the floor, checking and emitting
+// events with the emitter, which may be nil.
+func NewManager(f *Floor, em *events.Emitter) *Manager {
+	return &Manager{
+		floor:   f,
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	managerOnce   sync.Once
+)
+
+// Global returns the game's item manager, for the global floor.
+func Global() *Manager {
+	managerOnce.Do(func() {
+		globalManager = NewManager(GlobalFloor(), nil)
+	})
+
+	return globalManager
+}
+
+// Floor returns the floor the manager's items lie on.
+func (m *Manager) Floor() *Floor {
+	return m.floor
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how to find the carriers in a room, who can be given
+// items and are told what others do.
+func (m *Manager) SetOccupants(fn func(room string) []Carrier) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetVisible sets what decides whether a carrier sees an item on the floor,
+// those they can't see can't be taken or put into. Without it every item
+// is seen.
+func (m *Manager) SetVisible(fn func(c Carrier, it Item) bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.visible = fn
+}
+
+// Get picks up count of the item the keyword refers to, all of it if count is
+// below one. It's taken from the container the from keyword refers to if
+// given, otherwise from the floor.
+func (m *Manager) Get(c Carrier, keyword string, count int, from string) (Item, error) {
+	room := c.Location()
+	if from != "" {
+		return m.getFrom(c, keyword, count, from)
+	}
+
+	it, ok := m.onFloor(c, room).Find(keyword)
+	if !ok {
+		return Item{}, refused(NotHereMessage)
+	}
+	if it.Flag("no_take") {
+		return Item{}, refused(NoTakeMessage)
+	}
+	if !canCarry(c, weightOf(it, count)) {
+		return Item{}, refused(TooHeavyMessage)
+	}
+	data := itemData(c, it, count)
+	if err := m.check(GetEvent, data); err != nil {
+		return Item{}, err
+	}
+
+	var taken Item
+	err := m.floor.Update(room, func(items List) (List, error) {
+		var err error
+		items, taken, err = items.Take(it.ID, count)
+
+		return items, err
+	})
+	if err != nil {
+		return Item{}, refused(NotHereMessage)
+	}
+	c.UpdateInventory(func(items List) (List, error) {
+		return items.Add(taken), nil
+	})
+	m.confirm(GetEvent, data)
+	m.tell(room, c, fmt.Sprintf("%s gets %s.", c.Name(), taken.Describe()))
+
+	return taken, nil
+}
+
+// getFrom takes the item out of a container carried or on the floor
+func (m *Manager) getFrom(c Carrier, keyword string, count int, from string) (Item, error) {
+	room := c.Location()
+	container, carried := c.Inventory().Find(from)
+	if !carried {
+		var ok bool
+		if container, ok = m.onFloor(c, room).Find(from); !ok {
+			return Item{}, refused(NotHereMessage)
+		}
+	}
+	if !container.Container() {
+		return Item{}, refused("%s isn't a container.", capitalize(container.Name))
+	}
+	if container.Flag(ClosedFlag) {
+		return Item{}, refused("%s is closed.", capitalize(container.Name))
+	}
+	it, ok := container.Contents.Find(keyword)
+	if !ok {
+		return Item{}, refused("There's nothing like that in %s.", container.Name)
+	}
+	if !carried && !canCarry(c, weightOf(it, count)) {
+		return Item{}, refused(TooHeavyMessage)
+	}
+	data := itemData(c, it, count)
+	data["container"] = container.ID
+	if err := m.check(GetEvent, data); err != nil {
+		return Item{}, err
+	}
+
+	var taken Item
+	takeOut := func(items List) (List, error) {
+		var err error
+		items, taken, err = takeFrom(items, container.ID, it.ID, count)
+
+		return items, err
+	}
+	var err error
+	if carried {
+		err = c.UpdateInventory(func(items List) (List, error) {
+			items, err := takeOut(items)
+			if err != nil {
+				return items, err
+			}
+
+			return items.Add(taken), nil
+		})
+	} else if err = m.floor.Update(room, takeOut); err == nil {
+		c.UpdateInventory(func(items List) (List, error) {
+			return items.Add(taken), nil
+		})
+	}
+	if err != nil {
+		return Item{}, refused(NotHereMessage)
+	}
+	m.confirm(GetEvent, data)
+	m.tell(room, c, fmt.Sprintf("%s gets %s from %s.", c.Name(), taken.Describe(), container.Name))
+
+	return taken, nil
+}
+
+// Drop leaves count of the carried item the keyword refers to on the floor,
+// all of it if count is below one.
+func (m *Manager) Drop(c Carrier, keyword string, count int) (Item, error) {
+	it, ok := c.Inventory().Find(keyword)
+	if !ok {
+		return Item{}, refused(NotCarriedMessage)
+	}
+	if it.Flag("no_drop") {
+		return Item{}, refused(NoDropMessage)
+	}
+	data := itemData(c, it, count)
+	if err := m.check(DropEvent, data); err != nil {
+		return Item{}, err
+	}
+
+	taken, err := take(c, it.ID, count)
+	if err != nil {
+		return Item{}, err
+	}
+	room := c.Location()
+	m.floor.Add(room, taken)
+	m.confirm(DropEvent, data)
+	m.tell(room, c, fmt.Sprintf("%s drops %s.", c.Name(), taken.Describe()))
+
+	return taken, nil
+}
+
+// Put places count of the carried item the keyword refers to into the
+// container the into keyword refers to, carried or on the floor.
+func (m *Manager) Put(c Carrier, keyword string, count int, into string) (Item, Item, error) {
+	room := c.Location()
+	it, ok := c.Inventory().Find(keyword)
+	if !ok {
+		return Item{}, Item{}, refused(NotCarriedMessage)
+	}
+	container, carried := c.Inventory().Without(it.ID).Find(into)
+	if !carried {
+		if container, ok = m.onFloor(c, room).Find(into); !ok {
+			return Item{}, Item{}, refused(NotHereMessage)
+		}
+	}
+	if container.Container() && container.Flag(ClosedFlag) {
+		return Item{}, Item{}, refused("%s is closed.", capitalize(container.Name))
+	}
+	if _, err := container.Put(portion(it, count)); err != nil {
+		return Item{}, Item{}, putRefusal(err, container)
+	}
+	data := itemData(c, it, count)
+	data["container"] = container.ID
+	if err := m.check(PutEvent, data); err != nil {
+		return Item{}, Item{}, err
+	}
+
+	var taken Item
+	put := func(items List) (List, error) {
+		con, ok := items.Get(container.ID)
+		if !ok {
+			return items, refused(NotHereMessage)
+		}
+		con, err := con.Put(taken)
+		if err != nil {
+			return items, putRefusal(err, con)
+		}
+
+		return items.Replace(con)
+	}
+	var err error
+	if carried {
+		err = c.UpdateInventory(func(items List) (List, error) {
+			items, t, err := items.Take(it.ID, count)
+			if err != nil {
+				return items, refused(NotCarriedMessage)
+			}
+			taken = t
+
+			return put(items)
+		})
+	} else if taken, err = take(c, it.ID, count); err == nil {
+		if err = m.floor.Update(room, put); err != nil {
+			c.UpdateInventory(func(items List) (List, error) {
+				return items.Add(taken), nil
+			})
+		}
+	}
+	if err != nil {
+		return Item{}, Item{}, err
+	}
+	m.confirm(PutEvent, data)
+	m.tell(room, c, fmt.Sprintf("%s puts %s in %s.", c.Name(), taken.Describe(), container.Name))
+
+	return taken, container, nil
+}
+
+// Give hands count of the carried item the keyword refers to to the carrier
+// in the room whose name starts with to.
+func (m *Manager) Give(c Carrier, keyword string, count int, to string) (Item, Carrier, error) {
+	it, ok := c.Inventory().Find(keyword)
+	if !ok {
+		return Item{}, nil, refused(NotCarriedMessage)
+	}
+	if it.Flag("no_drop") {
+		return Item{}, nil, refused(NoDropMessage)
+	}
+	target := m.find(c, to)
+	if target == nil {
+		return Item{}, nil, refused(NoOneMessage)
+	}
+	if !canCarry(target, weightOf(it, count)) {
+		return Item{}, nil, refused("%s can't carry that much weight.", target.Name())
+	}
+	data := itemData(c, it, count)
+	data["target"] = target.Name()
+	if t, ok := target.(identified); ok {
+		data["target_id"] = t.ID()
+	}
+	if err := m.check(GiveEvent, data); err != nil {
+		return Item{}, nil, err
+	}
+
+	taken, err := take(c, it.ID, count)
+	if err != nil {
+		return Item{}, nil, err
+	}
+	target.UpdateInventory(func(items List) (List, error) {
+		return items.Add(taken), nil
+	})
+	m.confirm(GiveEvent, data)
+	if s, ok := target.(Sender); ok {
+		s.Send(fmt.Sprintf("%s gives you %s.", c.Name(), taken.Describe()))
+	}
+
+	return taken, target, nil
+}
+
+// onFloor returns the items on the floor of the room the carrier sees
+func (m *Manager) onFloor(c Carrier, room string) List {
+	m.mutex.RLock()
+	visible := m.visible
+	m.mutex.RUnlock()
+
+	items := m.floor.In(room)
+	if visible == nil {
+		return items
+	}
+	var seen List
+	for _, it := range items {
+		if visible(c, it) {
+			seen = append(seen, it)
+		}
+	}
+
+	return seen
+}
+
+// find returns the carrier in the room with the carrier whose name starts
+// with name
+func (m *Manager) find(c Carrier, name string) Carrier {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	name = strings.ToLower(name)
+	if occupants == nil || name == "" {
+		return nil
+	}
+	for _, other := range occupants(c.Location()) {
+		lower := strings.ToLower(other.Name())
+		if lower != strings.ToLower(c.Name()) && strings.HasPrefix(lower, name) {
+			return other
+		}
+	}
+
+	return nil
+}
+
+// tell sends the text to everyone in the room but the carrier
+func (m *Manager) tell(room string, c Carrier, text string) {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil {
+		return
+	}
+	for _, other := range occupants(room) {
+		if strings.EqualFold(other.Name(), c.Name()) {
+			continue
+		}
+		if s, ok := other.(Sender); ok {
+			s.Send(text)
+		}
+	}
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refused(CancelMessage)
+		}
+
+		return &Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+// take removes count of the item from what the carrier carries
+func take(c Carrier, id string, count int) (Item, error) {
+	var taken Item
+	err := c.UpdateInventory(func(items List) (List, error) {
+		var err error
+		items, taken, err = items.Take(id, count)
+
+		return items, err
+	})
+	if err != nil {
+		return Item{}, refused(NotCarriedMessage)
+	}
+
+	return taken, nil
+}
+
+// takeFrom takes count of the item out of the container in the list
+func takeFrom(items List, container, id string, count int) (List, Item, error) {
+	con, ok := items.Get(container)
+	if !ok {
+		return items, Item{}, ErrNotFound
+	}
+	contents, taken, err := con.Contents.Take(id, count)
+	if err != nil {
+		return items, Item{}, err
+	}
+	con.Contents = contents
+	items, err = items.Replace(con)
+
+	return items, taken, err
+}
+
+func putRefusal(err error, container Item) error {
+	switch err {
+	case ErrNotContainer:
+		return refused("%s isn't a container.", capitalize(container.Name))
+	case ErrTooHeavy:
+		return refused("%s can't hold that much.", capitalize(container.Name))
+	case ErrInsideItself:
+		return refused("You can't put something inside itself.")
+	}
+
+	return err
+}
+
+// portion is count of the item, all of it if count is below one
+func portion(it Item, count int) Item {
+	if count > 0 && count < it.Quantity() {
+		it.Count = count
+	}
+
+	return it
+}
+
+func weightOf(it Item, count int) int {
+	return portion(it, count).TotalWeight()
+}
+
+func canCarry(c Carrier, weight int) bool {
+	l, ok := c.(Limited)
+	if !ok || l.CarryLimit() <= 0 {
+		return true
+	}
+
+	return c.Inventory().Weight()+weight <= l.CarryLimit()
+}
+
+func itemData(c Carrier, it Item, count int) events.Data {
+	return events.Data{
+		"actor": c.Name(),
+		"room":  c.Location(),
+		"item":  it.ID,
+		"proto": it.Proto,
+		"name":  it.Name,
+		"count": portion(it, count).Quantity(),
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
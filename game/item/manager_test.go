@@ -0,0 +1,262 @@
+package item_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/item"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// carrier is a character that records what it's told
+type carrier struct {
+	name  string
+	room  string
+	limit int
+	items List
+	sent  []string
+}
+
+func (c *carrier) Name() string {
+	return c.name
+}
+
+func (c *carrier) Location() string {
+	return c.room
+}
+
+func (c *carrier) Inventory() List {
+	return c.items.Copy()
+}
+
+func (c *carrier) UpdateInventory(fn func(List) (List, error)) error {
+	items, err := fn(c.items.Copy())
+	if err != nil {
+		return err
+	}
+	c.items = items
+
+	return nil
+}
+
+func (c *carrier) CarryLimit() int {
+	return c.limit
+}
+
+func (c *carrier) ID() string {
+	return c.name
+}
+
+func (c *carrier) Level() command.Level {
+	return command.Player
+}
+
+func (c *carrier) Send(text string) error {
+	c.sent = append(c.sent, text)
+
+	return nil
+}
+
+func (c *carrier) told(text string) bool {
+	return strings.Contains(strings.Join(c.sent, "\n"), text)
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		em         *events.Emitter
+		floor      *Floor
+		m          *Manager
+		alice, bob *carrier
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		floor = NewFloor()
+		m = NewManager(floor, em)
+		alice = &carrier{name: "Alice", room: "square"}
+		bob = &carrier{name: "Bob", room: "square"}
+		m.SetOccupants(func(room string) []Carrier {
+			var in []Carrier
+			for _, c := range []*carrier{alice, bob} {
+				if c.room == room {
+					in = append(in, c)
+				}
+			}
+
+			return in
+		})
+		floor.Add("square", New(swordDef, 1))
+		floor.Add("square", New(coinDef, 10))
+	})
+
+	It("gets and drops items", func() {
+		sword, err := m.Get(alice, "sword", 0, "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(sword.Name).Should(Equal("a long sword"))
+		Ω(alice.items).Should(HaveLen(1))
+		Ω(floor.In("square")).Should(HaveLen(1))
+		Ω(bob.told("Alice gets a long sword.")).Should(BeTrue())
+
+		_, err = m.Get(alice, "sword", 0, "")
+		Ω(err).Should(MatchError(NotHereMessage))
+
+		_, err = m.Drop(alice, "sword", 0)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(alice.items).Should(BeEmpty())
+		Ω(floor.In("square")).Should(HaveLen(2))
+	})
+
+	It("only gets items carriers can see", func() {
+		m.SetVisible(func(c Carrier, it Item) bool {
+			return it.Name != "a long sword"
+		})
+		_, err := m.Get(alice, "sword", 0, "")
+		Ω(err).Should(MatchError(NotHereMessage))
+		_, err = m.Get(alice, "coins", 0, "")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("splits and joins stacks", func() {
+		coins, err := m.Get(alice, "coins", 4, "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(coins.Quantity()).Should(Equal(4))
+		left, _ := floor.In("square").Find("coins")
+		Ω(left.Quantity()).Should(Equal(6))
+
+		m.Get(alice, "coins", 0, "")
+		Ω(alice.items).Should(HaveLen(1))
+		Ω(alice.items[0].Quantity()).Should(Equal(10))
+	})
+
+	It("won't let carriers carry too much", func() {
+		alice.limit = 8
+
+		_, err := m.Get(alice, "sword", 0, "")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Get(alice, "coins", 0, "")
+		Ω(err).Should(MatchError(TooHeavyMessage))
+		_, err = m.Get(alice, "coins", 3, "")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("puts items in containers and gets them out", func() {
+		alice.items = List{New(bagDef, 1), New(swordDef, 1)}
+
+		it, bag, err := m.Put(alice, "sword", 0, "bag")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(it.Name).Should(Equal("a long sword"))
+		Ω(bag.Name).Should(Equal("a leather bag"))
+		Ω(alice.items).Should(HaveLen(1))
+		Ω(alice.items[0].Contents).Should(HaveLen(1))
+		Ω(alice.items.Weight()).Should(Equal(6))
+
+		_, _, err = m.Put(alice, "bag", 0, "bag")
+		Ω(err).Should(MatchError(NotHereMessage))
+
+		_, err = m.Get(alice, "sword", 0, "bag")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(alice.items).Should(HaveLen(2))
+		Ω(alice.items[0].Contents).Should(BeEmpty())
+	})
+
+	It("puts items in containers on the floor", func() {
+		floor.Add("square", New(bagDef, 1))
+		alice.items = List{New(coinDef, 30)}
+
+		_, _, err := m.Put(alice, "coins", 0, "bag")
+		Ω(err).Should(MatchError("A leather bag can't hold that much."))
+		Ω(alice.items[0].Quantity()).Should(Equal(30))
+
+		_, _, err = m.Put(alice, "coins", 5, "bag")
+		Ω(err).ShouldNot(HaveOccurred())
+		bag, _ := floor.In("square").Find("bag")
+		Ω(bag.Contents[0].Quantity()).Should(Equal(5))
+		Ω(alice.items[0].Quantity()).Should(Equal(25))
+	})
+
+	It("keeps closed containers shut", func() {
+		bag := New(bagDef, 1)
+		bag.SetFlag(ClosedFlag, true)
+		bag.Contents = List{New(coinDef, 5)}
+		alice.items = List{bag, New(swordDef, 1)}
+
+		_, _, err := m.Put(alice, "sword", 0, "bag")
+		Ω(err).Should(MatchError("A leather bag is closed."))
+		_, err = m.Get(alice, "coins", 0, "bag")
+		Ω(err).Should(MatchError("A leather bag is closed."))
+
+		bag.SetFlag("Closed", false)
+		Ω(bag.Flag(ClosedFlag)).Should(BeFalse())
+	})
+
+	It("gives items to others in the room", func() {
+		alice.items = List{New(swordDef, 1)}
+
+		_, target, err := m.Give(alice, "sword", 0, "b")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(target.Name()).Should(Equal("Bob"))
+		Ω(bob.items).Should(HaveLen(1))
+		Ω(bob.told("Alice gives you a long sword.")).Should(BeTrue())
+
+		_, _, err = m.Give(bob, "sword", 0, "carol")
+		Ω(err).Should(MatchError(NoOneMessage))
+	})
+
+	It("lets before handlers refuse and hears about it after", func(done Done) {
+		got := make(chan events.Data, 1)
+		em.On("before:"+GetEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["proto"] == "sword" {
+				return errors.New("The sword is stuck fast.")
+			}
+
+			return nil
+		}))
+		em.On(GetEvent, events.HandlerFunc(func(d events.Data) error {
+			got <- d
+
+			return nil
+		}))
+
+		_, err := m.Get(alice, "sword", 0, "")
+		Ω(err).Should(MatchError("The sword is stuck fast."))
+		_, err = m.Get(alice, "coins", 2, "")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		d := <-got
+		Ω(d["actor"]).Should(Equal("Alice"))
+		Ω(d["count"]).Should(Equal(2))
+		close(done)
+	})
+
+	Describe("commands", func() {
+		var dispatch func(*carrier, string)
+
+		BeforeEach(func() {
+			registry := command.NewRegistry()
+			for _, c := range NewCommands(m, func(c command.Caller) Carrier {
+				return c.(*carrier)
+			}) {
+				Ω(registry.Register(c)).Should(Succeed())
+			}
+			dispatch = func(c *carrier, line string) {
+				Ω(command.NewDispatcher(registry, nil).Dispatch(c, line)).Should(Succeed())
+			}
+		})
+
+		It("handles items with counts and separators", func() {
+			dispatch(alice, "get 3 coins")
+			Ω(alice.told("You get gold coins (3).")).Should(BeTrue())
+			dispatch(alice, "give 1 coin to bob")
+			Ω(alice.told("You give gold coins to Bob.")).Should(BeTrue())
+			dispatch(alice, "drop sword")
+			Ω(alice.told("You aren't carrying that.")).Should(BeTrue())
+
+			dispatch(alice, "i")
+			Ω(alice.told("You are carrying:\n  gold coins (2)")).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,99 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package language
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the say command, the speak command, which lists the
+// languages the caller knows or switches the one they speak, and the teach
+// command.
+func NewCommands(m *Manager, resolve command.Resolver[Speaker]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:   "say",
+			Args:   []command.Arg{{Name: "message", Kind: command.Text}},
+			Help:   "Says something to everyone in the room, in the language you speak.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Speaker) error {
+				return m.Say(s, ctx.String("message"))
+			}),
+		},
+		{
+			Name:   "speak",
+			Args:   []command.Arg{{Name: "language", Kind: command.Word, Optional: true}},
+			Help:   "Lists the languages you know, \"speak <language>\" switches the one you speak.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Speaker) error {
+				if id := ctx.String("language"); id != "" {
+					def, err := m.Speak(s, id)
+					if err != nil {
+						return err
+					}
+
+					return ctx.Send(fmt.Sprintf("You now speak %s.", def.Name))
+				}
+
+				return ctx.Send(listing(m, s))
+			}),
+		},
+		{
+			Name: "teach",
+			Args: []command.Arg{
+				{Name: "student", Kind: command.Word},
+				{Name: "language", Kind: command.Word},
+			},
+			Help:   "Teaches someone in the room a language you know well.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, s Speaker) error {
+				student := m.Find(s, ctx.String("student"))
+				if student == nil {
+					return ctx.Send(NoOneMessage)
+				}
+				learned, err := m.Teach(s, student, ctx.String("language"))
+				if err != nil {
+					return err
+				}
+				def, _ := m.defs.Get(ctx.String("language"))
+				if l, ok := student.(Listener); ok {
+					l.Send(fmt.Sprintf("%s teaches you %s, you now know it %d%%.", s.Name(), def.Name, learned))
+				}
+
+				return ctx.Send(fmt.Sprintf("You teach %s %s.", student.Name(), def.Name))
+			}),
+		},
+	}
+}
+
+// listing lists the languages the speaker knows and the one they speak
+func listing(m *Manager, s Speaker) string {
+	lines := []string{fmt.Sprintf("You speak %s.", m.Speaking(s).Name), "You know:"}
+	for _, k := range m.Known(s) {
+		lines = append(lines, fmt.Sprintf("  %-16s %3d%%", k.Name, k.Proficiency))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's speaker for fn, telling the caller when
+// what they do is refused
+func handler(resolve command.Resolver[Speaker], fn func(*command.Context, Speaker) error) command.Handler {
+	return func(ctx *command.Context) error {
+		s := resolve(ctx.Caller)
+		if s == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		err := fn(ctx, s)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package language gives characters tongues of their own. Everyone knows the
+// common languages and each race knows its own, others are learned from
+// those who speak them. What's said in a language is garbled for listeners
+// who know it poorly or not at all, a word at a time, so the better they
+// know it the more of it they understand. Scripts can grant comprehension,
+// like a spell letting its target understand every tongue.
+package language
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fluent is the proficiency of those who know a language fully, as a
+// percent.
+const Fluent = 100
+
+// DefaultSyllables are what words that aren't understood sound like in
+// languages without syllables of their own.
+var DefaultSyllables = []string{"ka", "lo", "ri", "th", "an", "el", "mu", "zo", "ve", "ir"}
+
+// Def defines a language.
+type Def struct {
+	// ID is how the language is known, like "elvish".
+	ID string `yaml:"id"`
+	// Name is what the language is shown as, like "Elvish".
+	Name string `yaml:"name"`
+	// Common languages are known fluently by everyone.
+	Common bool `yaml:"common,omitempty"`
+	// Races are the races that know the language fluently from birth.
+	Races []string `yaml:"races,omitempty"`
+	// Syllables are what words that aren't understood sound like.
+	Syllables []string `yaml:"syllables,omitempty"`
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	d.ID = strings.ToLower(strings.TrimSpace(d.ID))
+	if d.ID == "" || strings.ContainsAny(d.ID, " \t") {
+		return fmt.Errorf("languages need an id that's a single word")
+	}
+	if d.Name == "" {
+		d.Name = strings.Title(d.ID)
+	}
+	for _, s := range d.Syllables {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("language %s: syllables can't be empty", d.ID)
+		}
+	}
+
+	return nil
+}
+
+// Native is true if those of the race know the language from birth.
+func (d Def) Native(race string) bool {
+	if d.Common {
+		return true
+	}
+	for _, r := range d.Races {
+		if strings.EqualFold(r, race) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Garble returns the message as heard by a listener with the proficiency.
+// Each word is understood, or not, the same way every time it's heard, and
+// the more proficient the listener the more words they understand. Words
+// that aren't understood are replaced with the language's syllables.
+func (d Def) Garble(message string, proficiency int) string {
+	if proficiency >= Fluent {
+		return message
+	}
+	syllables := d.Syllables
+	if len(syllables) == 0 {
+		syllables = DefaultSyllables
+	}
+
+	words := strings.Fields(message)
+	for i, word := range words {
+		core := strings.TrimRightFunc(word, unicode.IsPunct)
+		if core == "" {
+			continue
+		}
+		h := hash(d.ID + " " + strings.ToLower(core))
+		if int(h%Fluent) < proficiency {
+			continue
+		}
+
+		var garbled bytes.Buffer
+		for n := (len([]rune(core)) + 2) / 3; n > 0; n-- {
+			h = h*16777619 + 1
+			garbled.WriteString(syllables[(h>>8)%uint32(len(syllables))])
+		}
+		sounds := []rune(garbled.String())
+		if unicode.IsUpper([]rune(core)[0]) {
+			sounds[0] = unicode.ToUpper(sounds[0])
+		}
+		words[i] = string(sounds) + word[len(core):]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// hash returns the FNV hash of s
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+
+	return h.Sum32()
+}
+
+// Defaults returns the languages the game has unless they're replaced:
+// common, which everyone speaks, and the tongues of elves, dwarves and orcs.
+func Defaults() []Def {
+	return []Def{
+		{ID: "common", Common: true},
+		{
+			ID:        "elvish",
+			Races:     []string{"elf", "half-elf"},
+			Syllables: []string{"ae", "lin", "thil", "ia", "el", "rond", "wen", "sil"},
+		},
+		{
+			ID:        "dwarvish",
+			Races:     []string{"dwarf"},
+			Syllables: []string{"khaz", "dum", "gor", "bar", "uk", "dur", "grim", "az"},
+		},
+		{
+			ID:        "orcish",
+			Races:     []string{"orc", "half-orc"},
+			Syllables: []string{"grak", "uz", "gul", "nak", "ash", "burz", "ug", "thrak"},
+		},
+	}
+}
+
+// File is the layout of a language file, a list of languages.
+type File struct {
+	Languages []Def `yaml:"languages"`
+}
+
+// Defs holds the language definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id, or the name.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if def, ok := d.defs[strings.ToLower(id)]; ok {
+		return def, true
+	}
+	for _, def := range d.defs {
+		if strings.EqualFold(def.Name, id) {
+			return def, true
+		}
+	}
+
+	return Def{}, false
+}
+
+// All returns every definition, sorted by id.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// LoadDir adds the languages in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the languages listed in the YAML document, like:
+//   languages:
+//     - id: draconic
+//       name: Draconic
+//       races: [dragonborn]
+//       syllables: [vor, thax, ix, sva, kep]
+//     - id: trade
+//       name: Trade Tongue
+//       common: true
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Languages {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package language_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Language Suite")
+}
@@ -0,0 +1,222 @@
+package language_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/language"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// speaker is a player who remembers what they hear
+type speaker struct {
+	name      string
+	race      string
+	languages map[string]int
+	speaking  string
+	sent      []string
+}
+
+func (s *speaker) ID() string {
+	return strings.ToLower(s.name)
+}
+
+func (s *speaker) Name() string {
+	return s.name
+}
+
+func (s *speaker) Race() string {
+	return s.race
+}
+
+func (s *speaker) Level() command.Level {
+	return command.Player
+}
+
+func (s *speaker) Location() string {
+	return "glade"
+}
+
+func (s *speaker) Language(id string) int {
+	return s.languages[id]
+}
+
+func (s *speaker) SetLanguage(id string, percent int) {
+	s.languages[id] = percent
+}
+
+func (s *speaker) Speaking() string {
+	return s.speaking
+}
+
+func (s *speaker) SetSpeaking(id string) {
+	s.speaking = id
+}
+
+func (s *speaker) Send(text string) error {
+	s.sent = append(s.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Def", func() {
+	elvish := Def{ID: "elvish", Syllables: []string{"ae", "lin"}}
+
+	It("garbles what isn't understood the same way every time", func() {
+		message := "Hello there, friend."
+		Ω(elvish.Garble(message, Fluent)).Should(Equal(message))
+
+		none := elvish.Garble(message, 0)
+		Ω(none).ShouldNot(ContainSubstring("Hello"))
+		Ω(none).ShouldNot(ContainSubstring("friend"))
+		Ω(none).Should(HaveSuffix("."))
+		Ω(none).Should(MatchRegexp(`^[A-Z][a-z]+ [a-z]+, [a-z]+\.$`))
+		Ω(elvish.Garble(message, 0)).Should(Equal(none))
+
+		Ω(elvish.Garble("well well well", 50)).Should(MatchRegexp(`^(\w+) (\w+) (\w+)$`))
+		words := strings.Fields(elvish.Garble("well well well", 50))
+		Ω(words[1]).Should(Equal(words[0]))
+	})
+
+	It("is known by everyone if it's common, otherwise by its races", func() {
+		Ω(Def{ID: "common", Common: true}.Native("orc")).Should(BeTrue())
+		Ω(Def{ID: "elvish", Races: []string{"elf"}}.Native("Elf")).Should(BeTrue())
+		Ω(Def{ID: "elvish", Races: []string{"elf"}}.Native("dwarf")).Should(BeFalse())
+	})
+
+	It("loads languages from YAML", func() {
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(`
+languages:
+  - id: Draconic
+    races: [dragonborn]
+    syllables: [vor, thax]
+`))).Should(Succeed())
+		def, ok := defs.Get("draconic")
+		Ω(ok).Should(BeTrue())
+		Ω(def.Name).Should(Equal("Draconic"))
+		Ω(def.Races).Should(Equal([]string{"dragonborn"}))
+
+		Ω(defs.Add(Def{ID: "two words"})).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		em            *events.Emitter
+		m             *Manager
+		ann, bob, cat *speaker
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		defs := NewDefs()
+		for _, def := range Defaults() {
+			Ω(defs.Add(def)).Should(Succeed())
+		}
+		m = NewManager(defs, em)
+		ann = &speaker{name: "Ann", race: "elf", languages: map[string]int{}}
+		bob = &speaker{name: "Bob", race: "human", languages: map[string]int{}}
+		cat = &speaker{name: "Cat", race: "dwarf", languages: map[string]int{"elvish": 100}}
+		m.SetOccupants(func(string) []Listener {
+			return []Listener{ann, bob, cat}
+		})
+	})
+
+	It("knows languages from race, learning and comprehension", func() {
+		Ω(m.Proficiency(ann, "elvish")).Should(Equal(Fluent))
+		Ω(m.Proficiency(bob, "elvish")).Should(Equal(0))
+		Ω(m.Proficiency(bob, "common")).Should(Equal(Fluent))
+		Ω(m.Proficiency(bob, "klingon")).Should(Equal(0))
+
+		m.Comprehend("tongues", func(k Knower, language string) int {
+			return 60
+		})
+		Ω(m.Proficiency(bob, "elvish")).Should(Equal(60))
+		m.Comprehend("tongues", nil)
+
+		Ω(m.Learn(bob, "Elvish", 150)).Should(Succeed())
+		Ω(bob.languages["elvish"]).Should(Equal(Fluent))
+		Ω(m.Learn(bob, "klingon", 10)).Should(MatchError("There's no language called klingon."))
+
+		var names []string
+		for _, k := range m.Known(cat) {
+			names = append(names, k.ID)
+		}
+		Ω(names).Should(Equal([]string{"common", "dwarvish", "elvish"}))
+	})
+
+	It("garbles speech for those who don't understand it", func() {
+		_, err := m.Speak(bob, "elvish")
+		Ω(err).Should(MatchError("You don't speak Elvish."))
+		def, err := m.Speak(ann, "elvish")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(def.Name).Should(Equal("Elvish"))
+
+		Ω(m.Say(ann, "Hello friend")).Should(Succeed())
+		Ω(ann.sent).Should(Equal([]string{"You say in Elvish, 'Hello friend'"}))
+		Ω(cat.sent).Should(Equal([]string{"Ann says in Elvish, 'Hello friend'"}))
+		Ω(bob.sent).Should(HaveLen(1))
+		Ω(bob.sent[0]).Should(HavePrefix("Ann says something in a tongue you don't know, '"))
+		Ω(bob.sent[0]).ShouldNot(ContainSubstring("friend"))
+
+		Ω(m.Say(bob, "Hi")).Should(Succeed())
+		Ω(ann.sent[1]).Should(Equal("Bob says, 'Hi'"))
+		Ω(m.Say(bob, "  ")).Should(MatchError(SayWhatMessage))
+	})
+
+	It("lets handlers silence speech", func() {
+		em.On("before:"+SayEvent, events.HandlerFunc(func(events.Data) error {
+			return errors.New("You're gagged.")
+		}))
+		Ω(m.Say(ann, "Help")).Should(MatchError("You're gagged."))
+		Ω(bob.sent).Should(BeEmpty())
+	})
+
+	It("teaches languages no better than the teacher knows them", func() {
+		m.SetGain(30)
+		_, err := m.Teach(bob, ann, "elvish")
+		Ω(err).Should(MatchError("You don't know Elvish well enough to teach it."))
+		_, err = m.Teach(ann, ann, "elvish")
+		Ω(err).Should(MatchError(TeachSelfMessage))
+
+		bob.languages["dwarvish"] = 60
+		learned, err := m.Teach(bob, ann, "dwarvish")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(learned).Should(Equal(30))
+		learned, err = m.Teach(bob, ann, "dwarvish")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(learned).Should(Equal(60))
+		_, err = m.Teach(bob, ann, "dwarvish")
+		Ω(err).Should(MatchError("Ann already knows Dwarvish as well as you can teach it."))
+	})
+
+	It("says, speaks and teaches with commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(command.Caller) Speaker {
+			return ann
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "speak")
+		d.Dispatch(ann, "speak orcish")
+		d.Dispatch(ann, "say Well met")
+		d.Dispatch(ann, "teach b elvish")
+		Ω(ann.sent).Should(Equal([]string{
+			"You speak Common.\nYou know:\n  Common           100%\n  Elvish           100%",
+			"You don't speak Orcish.",
+			"You say, 'Well met'",
+			"You teach Bob Elvish.",
+		}))
+		Ω(bob.sent).Should(Equal([]string{
+			"Ann says, 'Well met'",
+			"Ann teaches you Elvish, you now know it 10%.",
+		}))
+	})
+})
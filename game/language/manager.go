@@ -0,0 +1,453 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package language
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// The events of speaking. Handlers of the before: events can stop it by
+// returning events.ErrHalt, or an error whose message is told to the one
+// speaking or teaching. language:say is given the speaker's id and name,
+// the room, the language and the message as it was said. language:learn is
+// given the id of who learned it, the language, their new proficiency and
+// the name of who taught them, empty if no one did.
+const (
+	SayEvent   = "language:say"
+	LearnEvent = "language:learn"
+)
+
+// Messages told to those who can't speak or teach.
+const (
+	NoLanguageMessage = "There's no language called %s."
+	CantSpeakMessage  = "You don't speak %s."
+	SayWhatMessage    = "Say what?"
+	NoOneMessage      = "They aren't here."
+	TeachSelfMessage  = "You can't teach yourself."
+	CantTeachMessage  = "You don't know %s well enough to teach it."
+	KnowsMessage      = "%s already knows %s as well as you can teach it."
+	CancelMessage     = "You can't do that right now."
+)
+
+// DefaultLanguage is the language spoken by those who haven't chosen one.
+const DefaultLanguage = "common"
+
+// Teaching limits. Teachers must know a language at least TeacherProficiency
+// to teach it, and students learn DefaultGain each lesson unless it's
+// changed, never better than their teacher knows it.
+const (
+	TeacherProficiency = 50
+	DefaultGain        = 10
+)
+
+// Knower is anyone who knows languages, like a player.
+type Knower interface {
+	Name() string
+	Race() string
+	// Language returns how well the language was learned, as a percent.
+	Language(id string) int
+	SetLanguage(id string, percent int)
+}
+
+// Speaker is a knower who speaks in a room.
+type Speaker interface {
+	Knower
+	Location() string
+	// Speaking returns the id of the language being spoken, empty for the
+	// default.
+	Speaking() string
+	SetSpeaking(id string)
+	Send(text string) error
+}
+
+// Listener is anyone who hears what's said in a room. Those who aren't a
+// Knower understand every language.
+type Listener interface {
+	Name() string
+	Send(text string) error
+}
+
+// identified is a knower with an id, like a mob
+type identified interface {
+	ID() string
+}
+
+// ComprehendFunc returns how well the knower understands the language
+// besides what they've learned, like from a spell, as a percent.
+type ComprehendFunc func(k Knower, language string) int
+
+// Known is a language a knower knows.
+type Known struct {
+	Def
+	Proficiency int
+}
+
+// Manager decides who understands what's said and teaches languages.
+type Manager struct {
+	defs       *Defs
+	fallback   string
+	gain       int
+	occupants  func(room string) []Listener
+	comprehend map[string]ComprehendFunc
+	emitter    *events.Emitter
+	mutex      *sync.RWMutex
+}
+
+// NewManager creates a manager for the languages, checking and emitting
+// events with the emitter, which may be nil.
+func NewManager(defs *Defs, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:       defs,
+		fallback:   DefaultLanguage,
+		gain:       DefaultGain,
+		comprehend: make(map[string]ComprehendFunc),
+		emitter:    em,
+		mutex:      new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's language manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how to find who hears what's said in a room.
+func (m *Manager) SetOccupants(fn func(room string) []Listener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetDefault changes the language spoken by those who haven't chosen one.
+func (m *Manager) SetDefault(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.fallback = strings.ToLower(id)
+}
+
+// SetGain changes how much students learn from each lesson, as a percent.
+func (m *Manager) SetGain(percent int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.gain = percent
+}
+
+// Defs returns the language definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// Comprehend sets the comprehension hook with the name, replacing any there
+// was, or removing it if fn is nil. Knowers understand a language as well as
+// the best of what they learned and what the hooks return.
+func (m *Manager) Comprehend(name string, fn ComprehendFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if fn == nil {
+		delete(m.comprehend, name)
+
+		return
+	}
+	m.comprehend[name] = fn
+}
+
+// Proficiency returns how well the knower understands the language, as a
+// percent, zero if there's no such language.
+func (m *Manager) Proficiency(k Knower, id string) int {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return 0
+	}
+	if def.Native(k.Race()) {
+		return Fluent
+	}
+
+	m.mutex.RLock()
+	hooks := make([]ComprehendFunc, 0, len(m.comprehend))
+	for _, fn := range m.comprehend {
+		hooks = append(hooks, fn)
+	}
+	m.mutex.RUnlock()
+
+	best := k.Language(def.ID)
+	for _, fn := range hooks {
+		if p := fn(k, def.ID); p > best {
+			best = p
+		}
+	}
+	if best > Fluent {
+		best = Fluent
+	}
+
+	return best
+}
+
+// Known returns the languages the knower understands at all, sorted by id.
+func (m *Manager) Known(k Knower) []Known {
+	var known []Known
+	for _, def := range m.defs.All() {
+		if p := m.Proficiency(k, def.ID); p > 0 {
+			known = append(known, Known{Def: def, Proficiency: p})
+		}
+	}
+
+	return known
+}
+
+// Speaking returns the language the speaker speaks in, the default if they
+// haven't chosen one or no longer know it.
+func (m *Manager) Speaking(s Speaker) Def {
+	if id := s.Speaking(); id != "" {
+		if def, ok := m.defs.Get(id); ok && m.Proficiency(s, def.ID) > 0 {
+			return def
+		}
+	}
+
+	m.mutex.RLock()
+	fallback := m.fallback
+	m.mutex.RUnlock()
+
+	if def, ok := m.defs.Get(fallback); ok {
+		return def
+	}
+
+	return Def{ID: fallback, Name: strings.Title(fallback), Common: true}
+}
+
+// Speak switches the language the speaker speaks in.
+func (m *Manager) Speak(s Speaker, id string) (Def, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return Def{}, refused(NoLanguageMessage, id)
+	}
+	if m.Proficiency(s, def.ID) < 1 {
+		return Def{}, refused(CantSpeakMessage, def.Name)
+	}
+	s.SetSpeaking(def.ID)
+
+	return def, nil
+}
+
+// Say says the message in the language the speaker speaks, everyone in
+// their room hears it as well as they understand the language.
+func (m *Manager) Say(s Speaker, message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return refused(SayWhatMessage)
+	}
+	def := m.Speaking(s)
+	data := events.Data{
+		"speaker":  idOf(s),
+		"name":     s.Name(),
+		"room":     s.Location(),
+		"language": def.ID,
+		"message":  message,
+	}
+	if err := m.check(SayEvent, data); err != nil {
+		return err
+	}
+
+	m.mutex.RLock()
+	occupants, fallback := m.occupants, m.fallback
+	m.mutex.RUnlock()
+
+	in := ""
+	if def.ID != fallback {
+		in = " in " + def.Name
+	}
+	s.Send(fmt.Sprintf("You say%s, '%s'", in, message))
+	if occupants != nil {
+		for _, l := range occupants(s.Location()) {
+			if strings.EqualFold(l.Name(), s.Name()) {
+				continue
+			}
+			l.Send(m.Heard(l, s.Name(), def, message))
+		}
+	}
+	m.confirm(SayEvent, data)
+
+	return nil
+}
+
+// Heard returns what the listener hears of the message said in the
+// language.
+func (m *Manager) Heard(l Listener, speaker string, def Def, message string) string {
+	proficiency := Fluent
+	if k, ok := l.(Knower); ok {
+		proficiency = m.Proficiency(k, def.ID)
+	}
+	heard := def.Garble(message, proficiency)
+
+	m.mutex.RLock()
+	fallback := m.fallback
+	m.mutex.RUnlock()
+
+	switch {
+	case proficiency < 1:
+		return fmt.Sprintf("%s says something in a tongue you don't know, '%s'", speaker, heard)
+	case def.ID == fallback:
+		return fmt.Sprintf("%s says, '%s'", speaker, heard)
+	}
+
+	return fmt.Sprintf("%s says in %s, '%s'", speaker, def.Name, heard)
+}
+
+// Learn changes how well the knower has learned the language, as a percent
+// from 0 to Fluent.
+func (m *Manager) Learn(k Knower, id string, percent int) error {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return refused(NoLanguageMessage, id)
+	}
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > Fluent {
+		percent = Fluent
+	}
+	data := events.Data{
+		"knower":      idOf(k),
+		"language":    def.ID,
+		"proficiency": percent,
+		"teacher":     "",
+	}
+	if err := m.check(LearnEvent, data); err != nil {
+		return err
+	}
+	k.SetLanguage(def.ID, percent)
+	m.confirm(LearnEvent, data)
+
+	return nil
+}
+
+// Teach teaches the student the language for a lesson, returning how well
+// they know it after. Students learn no better than their teacher knows it.
+func (m *Manager) Teach(teacher, student Knower, id string) (int, error) {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return 0, refused(NoLanguageMessage, id)
+	}
+	if strings.EqualFold(teacher.Name(), student.Name()) {
+		return 0, refused(TeachSelfMessage)
+	}
+	limit := m.Proficiency(teacher, def.ID)
+	if limit < TeacherProficiency {
+		return 0, refused(CantTeachMessage, def.Name)
+	}
+	learned := student.Language(def.ID)
+	if learned >= limit || m.Proficiency(student, def.ID) >= limit {
+		return 0, refused(KnowsMessage, student.Name(), def.Name)
+	}
+
+	m.mutex.RLock()
+	gain := m.gain
+	m.mutex.RUnlock()
+
+	learned += gain
+	if learned > limit {
+		learned = limit
+	}
+	data := events.Data{
+		"knower":      idOf(student),
+		"language":    def.ID,
+		"proficiency": learned,
+		"teacher":     teacher.Name(),
+	}
+	if err := m.check(LearnEvent, data); err != nil {
+		return 0, err
+	}
+	student.SetLanguage(def.ID, learned)
+	m.confirm(LearnEvent, data)
+
+	return learned, nil
+}
+
+// Find returns the knower in the speaker's room whose name starts with
+// name, other than the speaker.
+func (m *Manager) Find(s Speaker, name string) Knower {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	name = strings.ToLower(name)
+	if occupants == nil || name == "" {
+		return nil
+	}
+	for _, l := range occupants(s.Location()) {
+		k, ok := l.(Knower)
+		lower := strings.ToLower(l.Name())
+		if ok && lower != strings.ToLower(s.Name()) && strings.HasPrefix(lower, name) {
+			return k
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+// idOf returns the knower's id, or their lower cased name if they have none
+func idOf(k Knower) string {
+	if i, ok := k.(identified); ok {
+		return i.ID()
+	}
+
+	return strings.ToLower(k.Name())
+}
+
+func refused(format string, args ...interface{}) *item.Refused {
+	return &item.Refused{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package lock
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the open, close, lock, unlock, pick and bash
+// commands, each taking a direction, "door" or a container.
+func NewCommands(m *Manager, resolve command.Resolver[Opener]) []*command.Command {
+	commands := []struct {
+		name, help string
+		fn         func(Opener, string) (Target, error)
+	}{
+		{"open", "Opens a door or container.", m.Open},
+		{"close", "Closes a door or container.", m.Close},
+		{"lock", "Locks a door or container with its key.", m.Lock},
+		{"unlock", "Unlocks a door or container with its key.", m.Unlock},
+		{"pick", "Tries to pick the lock of a door or container.", m.Pick},
+		{"bash", "Tries to break a door open.", m.Bash},
+	}
+
+	cmds := make([]*command.Command, len(commands))
+	for i, c := range commands {
+		fn := c.fn
+		cmds[i] = &command.Command{
+			Name:   c.name,
+			Args:   []command.Arg{{Name: "target", Kind: command.Text}},
+			Help:   c.help,
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				o := resolve(ctx.Caller)
+				if o == nil {
+					return ctx.Send("You aren't playing anyone.")
+				}
+
+				_, err := fn(o, ctx.String("target"))
+				if r, ok := err.(*item.Refused); ok {
+					return ctx.Send(r.Message)
+				}
+
+				return err
+			},
+		}
+	}
+
+	return cmds
+}
@@ -0,0 +1,488 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package lock opens, closes, locks and unlocks the doors of exits and
+// containers. Locks open with the key item they name, or by picking or
+// bashing them. Every action is checked and emitted as an event, so scripts
+// can stop it or run puzzles of their own, and doors change on both sides
+// at once.
+package lock
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The events of handling doors and containers. Handlers of the before:
+// events can stop the action by returning events.ErrHalt, or an error whose
+// message is told to the one acting. Each is given the actor's name and the
+// room, the direction of doors or the id of containers and the key of the
+// lock, door:pick and door:bash are also given whether they succeeded.
+const (
+	OpenEvent   = "door:open"
+	CloseEvent  = "door:close"
+	LockEvent   = "door:lock"
+	UnlockEvent = "door:unlock"
+	PickEvent   = "door:pick"
+	BashEvent   = "door:bash"
+)
+
+// PickSkill is the skill of picking locks, the percent chance of picking
+// one.
+const PickSkill = "pick_lock"
+
+// BashStat is the stat that helps bash doors open.
+const BashStat = "str"
+
+// Messages told to openers that can't do something.
+const (
+	NotHereMessage     = "You don't see that here."
+	NoDoorMessage      = "There's no door that way."
+	NotClosableMessage = "You can't open or close that."
+	IsOpenMessage      = "It's already open."
+	IsClosedMessage    = "It's already closed."
+	LockedMessage      = "It's locked."
+	NotLockedMessage   = "It isn't locked."
+	OpenFirstMessage   = "You need to close it first."
+	NoLockMessage      = "It has no lock."
+	NoKeyMessage       = "You don't have the key."
+	PickproofMessage   = "The lock can't be picked."
+	BashproofMessage   = "It's too sturdy to bash open."
+	PickFailMessage    = "You fail to pick the lock."
+	BashFailMessage    = "You slam into it, but it holds."
+	CancelMessage      = "You can't do that right now."
+)
+
+// Opener is anything that opens doors and containers, like a player.
+type Opener interface {
+	Name() string
+	Location() string
+	Inventory() item.List
+	UpdateInventory(fn func(item.List) (item.List, error)) error
+}
+
+// Sender is an opener that can be told what happens.
+type Sender interface {
+	Send(text string) error
+}
+
+// Skilled is an opener with skills, for picking locks.
+type Skilled interface {
+	Skill(id string) int
+}
+
+// Statted is an opener with stats, for bashing doors.
+type Statted interface {
+	Stat(name string) int
+}
+
+// Target is a door or container.
+type Target struct {
+	// Name is how the target is described, like "the north door".
+	Name string
+	Room string
+	// Direction is the exit of doors, empty for containers.
+	Direction world.Direction
+	// Item is the id of containers, Carried if the opener carries it.
+	Item    string
+	Carried bool
+	Closed  bool
+	Locked  bool
+	// Key is the id of the item that locks and unlocks the target, it has
+	// no lock without one.
+	Key       string
+	Pickproof bool
+	Bashproof bool
+}
+
+// Door is true if the target is the door of an exit.
+func (t Target) Door() bool {
+	return t.Direction != ""
+}
+
+// Manager opens, closes, locks and unlocks doors and containers.
+type Manager struct {
+	world     *world.World
+	floor     *item.Floor
+	emitter   *events.Emitter
+	occupants func(room string) []Opener
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for the doors of the world and containers on
+// the floor, checking and emitting events with the emitter, which may be
+// nil.
+func NewManager(w *world.World, f *item.Floor, em *events.Emitter) *Manager {
+	return &Manager{
+		world:   w,
+		floor:   f,
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's lock manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), item.GlobalFloor(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how to find the openers in a room, who are told what
+// others do.
+func (m *Manager) SetOccupants(fn func(room string) []Opener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// Find returns the door or container the keyword refers to: the door of
+// the exit in a direction, the first door in the room for "door", or a
+// container carried or on the floor.
+func (m *Manager) Find(o Opener, keyword string) (Target, error) {
+	room := o.Location()
+	r, ok := m.world.Room(room)
+	if !ok {
+		return Target{}, &item.Refused{Message: NotHereMessage}
+	}
+	keyword = strings.TrimSpace(keyword)
+	if strings.EqualFold(keyword, "door") {
+		for _, e := range r.SortedExits(false) {
+			if e.Door {
+				return doorTarget(room, e), nil
+			}
+		}
+
+		return Target{}, &item.Refused{Message: "There's no door here."}
+	}
+	if e, ok := r.Exit(world.ParseDirection(keyword)); ok {
+		if !e.Door {
+			return Target{}, &item.Refused{Message: NoDoorMessage}
+		}
+
+		return doorTarget(room, e), nil
+	}
+
+	it, carried := o.Inventory().Find(keyword)
+	if !carried {
+		if it, ok = m.floor.In(room).Find(keyword); !ok {
+			return Target{}, &item.Refused{Message: NotHereMessage}
+		}
+	}
+	if !it.Container() {
+		return Target{}, &item.Refused{Message: NotClosableMessage}
+	}
+	key, _ := it.Props[item.KeyProp].(string)
+
+	return Target{
+		Name:      it.Name,
+		Room:      room,
+		Item:      it.ID,
+		Carried:   carried,
+		Closed:    it.Flag(item.ClosedFlag),
+		Locked:    it.Flag(item.LockedFlag),
+		Key:       key,
+		Pickproof: it.Flag(item.PickproofFlag),
+	}, nil
+}
+
+// doorTarget returns the target of the exit's door
+func doorTarget(room string, e world.Exit) Target {
+	name := fmt.Sprintf("the %s door", e.Direction)
+	switch e.Direction {
+	case world.Up:
+		name = "the door above"
+	case world.Down:
+		name = "the door below"
+	}
+
+	return Target{
+		Name:      name,
+		Room:      room,
+		Direction: e.Direction,
+		Closed:    e.Closed,
+		Locked:    e.Locked,
+		Key:       e.Key,
+		Pickproof: e.Pickproof,
+		Bashproof: e.Bashproof,
+	}
+}
+
+// Open opens the door or container the keyword refers to.
+func (m *Manager) Open(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case !t.Closed:
+		return t, &item.Refused{Message: IsOpenMessage}
+	case t.Locked:
+		return t, &item.Refused{Message: LockedMessage}
+	}
+
+	return m.act(o, t, OpenEvent, false, false, "open")
+}
+
+// Close closes the door or container the keyword refers to.
+func (m *Manager) Close(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case t.Closed:
+		return t, &item.Refused{Message: IsClosedMessage}
+	}
+
+	return m.act(o, t, CloseEvent, true, false, "close")
+}
+
+// Lock locks the door or container the keyword refers to with its key,
+// which the opener must carry.
+func (m *Manager) Lock(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case t.Key == "":
+		return t, &item.Refused{Message: NoLockMessage}
+	case !t.Closed:
+		return t, &item.Refused{Message: OpenFirstMessage}
+	case t.Locked:
+		return t, &item.Refused{Message: "It's already locked."}
+	case !HasKey(o, t.Key):
+		return t, &item.Refused{Message: NoKeyMessage}
+	}
+
+	return m.act(o, t, LockEvent, true, true, "lock")
+}
+
+// Unlock unlocks the door or container the keyword refers to with its key,
+// which the opener must carry.
+func (m *Manager) Unlock(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case t.Key == "" && !t.Locked:
+		return t, &item.Refused{Message: NoLockMessage}
+	case !t.Locked:
+		return t, &item.Refused{Message: NotLockedMessage}
+	case !HasKey(o, t.Key):
+		return t, &item.Refused{Message: NoKeyMessage}
+	}
+
+	return m.act(o, t, UnlockEvent, true, false, "unlock")
+}
+
+// Pick tries to unlock the door or container the keyword refers to without
+// its key, succeeding as often as the opener's pick lock skill says.
+func (m *Manager) Pick(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case !t.Locked:
+		return t, &item.Refused{Message: NotLockedMessage}
+	case t.Pickproof:
+		return t, &item.Refused{Message: PickproofMessage}
+	}
+
+	chance := 0
+	if s, ok := o.(Skilled); ok {
+		chance = s.Skill(PickSkill)
+	}
+
+	return m.attempt(o, t, PickEvent, chance, false, "pick the lock of", PickFailMessage)
+}
+
+// Bash tries to break open the door the keyword refers to, the opener's
+// strength making it likelier, leaving it open and unlocked.
+func (m *Manager) Bash(o Opener, keyword string) (Target, error) {
+	t, err := m.Find(o, keyword)
+	switch {
+	case err != nil:
+		return t, err
+	case !t.Door():
+		return t, &item.Refused{Message: "You can only bash doors."}
+	case !t.Closed:
+		return t, &item.Refused{Message: IsOpenMessage}
+	case t.Bashproof:
+		return t, &item.Refused{Message: BashproofMessage}
+	}
+
+	chance := 20
+	if s, ok := o.(Statted); ok {
+		chance += (s.Stat(BashStat) - 10) * 5
+	}
+
+	return m.attempt(o, t, BashEvent, chance, true, "bash open", BashFailMessage)
+}
+
+// HasKey is true if the opener carries the key, an item made from the
+// definition with its id.
+func HasKey(o Opener, key string) bool {
+	for _, it := range o.Inventory() {
+		if it.Proto == key || it.ID == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Set leaves the door or container closed, locked or open, without
+// checking keys or events, like a puzzle opening a door.
+func (m *Manager) Set(o Opener, t Target, closed, locked bool) error {
+	if t.Door() {
+		return m.world.SetDoor(t.Room, t.Direction, closed, locked)
+	}
+
+	closed = closed || locked
+	update := func(l item.List) (item.List, error) {
+		l, ok := l.Update(t.Item, func(it *item.Item) {
+			it.SetFlag(item.ClosedFlag, closed)
+			it.SetFlag(item.LockedFlag, locked)
+		})
+		if !ok {
+			return l, &item.Refused{Message: NotHereMessage}
+		}
+
+		return l, nil
+	}
+	if t.Carried {
+		return o.UpdateInventory(update)
+	}
+
+	return m.floor.Update(t.Room, update)
+}
+
+// act checks the event, changes the target and tells everyone about it
+func (m *Manager) act(o Opener, t Target, evt string, closed, locked bool, verb string) (Target, error) {
+	data := targetData(o, t)
+	if err := m.check(evt, data); err != nil {
+		return t, err
+	}
+	if err := m.Set(o, t, closed, locked); err != nil {
+		return t, err
+	}
+	t.Closed, t.Locked = closed || locked, locked
+	m.confirm(evt, data)
+	tell(o, fmt.Sprintf("You %s %s.", verb, t.Name))
+	m.tellRoom(o, fmt.Sprintf("%s %ss %s.", o.Name(), verb, t.Name))
+
+	return t, nil
+}
+
+// attempt checks the event then rolls the chance of picking or bashing the
+// target, leaving it unlocked, and open if it's bashed, on success
+func (m *Manager) attempt(o Opener, t Target, evt string, chance int, open bool, verb, failed string) (Target, error) {
+	data := targetData(o, t)
+	if err := m.check(evt, data); err != nil {
+		return t, err
+	}
+	success := random.Intn(100) < chance
+	data["success"] = success
+	if success {
+		if err := m.Set(o, t, !open && t.Closed, false); err != nil {
+			return t, err
+		}
+		t.Closed, t.Locked = !open && t.Closed, false
+	}
+	m.confirm(evt, data)
+	if !success {
+		return t, &item.Refused{Message: failed}
+	}
+	tell(o, fmt.Sprintf("You %s %s.", verb, t.Name))
+	m.tellRoom(o, fmt.Sprintf("%s manages to %s %s.", o.Name(), verb, t.Name))
+
+	return t, nil
+}
+
+// targetData returns what events are told about the opener's target
+func targetData(o Opener, t Target) events.Data {
+	data := events.Data{
+		"actor": o.Name(),
+		"room":  t.Room,
+		"key":   t.Key,
+	}
+	if t.Door() {
+		data["direction"] = string(t.Direction)
+	} else {
+		data["item"] = t.Item
+	}
+
+	return data
+}
+
+// tell sends the text to the opener if they can be told things
+func tell(o Opener, text string) {
+	if s, ok := o.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+// tellRoom sends the text to everyone in the opener's room but them
+func (m *Manager) tellRoom(o Opener, text string) {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil {
+		return
+	}
+	for _, other := range occupants(o.Location()) {
+		if !strings.EqualFold(other.Name(), o.Name()) {
+			tell(other, text)
+		}
+	}
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
@@ -0,0 +1,233 @@
+package lock_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/lock"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// opener is a player who remembers what they're told
+type opener struct {
+	name      string
+	room      string
+	inventory item.List
+	skills    map[string]int
+	stats     map[string]int
+	sent      []string
+}
+
+func (o *opener) ID() string {
+	return strings.ToLower(o.name)
+}
+
+func (o *opener) Name() string {
+	return o.name
+}
+
+func (o *opener) Location() string {
+	return o.room
+}
+
+func (o *opener) Level() command.Level {
+	return command.Player
+}
+
+func (o *opener) Inventory() item.List {
+	return o.inventory.Copy()
+}
+
+func (o *opener) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	list, err := fn(o.inventory.Copy())
+	if err == nil {
+		o.inventory = list
+	}
+
+	return err
+}
+
+func (o *opener) Skill(id string) int {
+	return o.skills[id]
+}
+
+func (o *opener) Stat(name string) int {
+	return o.stats[name]
+}
+
+func (o *opener) Send(text string) error {
+	o.sent = append(o.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		w        *world.World
+		floor    *item.Floor
+		em       *events.Emitter
+		m        *Manager
+		ann, bob *opener
+		key      item.Item
+	)
+
+	// door returns the exit of the room in the direction
+	door := func(room string, d world.Direction) world.Exit {
+		e, err := w.Exit(room, d)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return e
+	}
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "keep", Name: "The Keep"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "hall", Zone: "keep", Name: "The Hall"},
+			{ID: "vault", Zone: "keep", Name: "The Vault"},
+			{ID: "cell", Zone: "keep", Name: "A Cell"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("hall", world.North, "vault")).Should(Succeed())
+		Ω(w.Link("hall", world.Down, "cell")).Should(Succeed())
+		for _, side := range []struct {
+			room string
+			dir  world.Direction
+		}{{"hall", world.North}, {"vault", world.South}} {
+			Ω(w.UpdateExit(side.room, side.dir, func(e *world.Exit) {
+				e.Door, e.Closed, e.Locked, e.Key = true, true, true, "iron-key"
+			})).Should(Succeed())
+		}
+		Ω(w.UpdateExit("hall", world.Down, func(e *world.Exit) {
+			e.Door, e.Closed, e.Bashproof = true, true, true
+		})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "iron-key", Zone: "keep", Name: "an iron key", Type: "key"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "chest", Zone: "keep", Name: "a wooden chest", Type: "container", Flags: []string{item.ClosedFlag, item.LockedFlag}, Props: map[string]interface{}{
+			item.KeyProp: "iron-key",
+		}})).Should(Succeed())
+
+		floor = item.NewFloor()
+		chest, _ := item.Create(w, "chest", 1)
+		floor.Add("hall", chest)
+		key, _ = item.Create(w, "iron-key", 1)
+		em = events.NewEmitter(nil)
+		m = NewManager(w, floor, em)
+		ann = &opener{name: "Ann", room: "hall", skills: map[string]int{}, stats: map[string]int{}}
+		bob = &opener{name: "Bob", room: "hall"}
+		m.SetOccupants(func(room string) []Opener {
+			return []Opener{ann, bob}
+		})
+	})
+
+	It("unlocks and opens doors with their keys on both sides", func() {
+		_, err := m.Open(ann, "north")
+		Ω(err).Should(MatchError(LockedMessage))
+		_, err = m.Unlock(ann, "n")
+		Ω(err).Should(MatchError(NoKeyMessage))
+
+		ann.inventory = item.List{key}
+		t, err := m.Unlock(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t.Name).Should(Equal("the north door"))
+		Ω(door("vault", world.South).Locked).Should(BeFalse())
+		_, err = m.Open(ann, "door")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(door("vault", world.South).Closed).Should(BeFalse())
+		Ω(ann.sent).Should(ContainElement("You open the north door."))
+		Ω(bob.sent).Should(ContainElement("Ann opens the north door."))
+
+		_, err = m.Lock(ann, "north")
+		Ω(err).Should(MatchError(OpenFirstMessage))
+		_, err = m.Close(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Lock(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(door("hall", world.North).Locked).Should(BeTrue())
+		_, err = m.Lock(ann, "down")
+		Ω(err).Should(MatchError(NoLockMessage))
+		_, err = m.Open(ann, "east")
+		Ω(err).Should(MatchError(NotHereMessage))
+	})
+
+	It("opens and closes containers", func() {
+		ann.inventory = item.List{key}
+		_, err := m.Open(ann, "chest")
+		Ω(err).Should(MatchError(LockedMessage))
+		_, err = m.Unlock(ann, "chest")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Open(ann, "chest")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		chest, _ := floor.In("hall").Find("chest")
+		Ω(chest.Flag(item.ClosedFlag)).Should(BeFalse())
+		Ω(chest.Flag(item.LockedFlag)).Should(BeFalse())
+		_, err = m.Open(ann, "key")
+		Ω(err).Should(MatchError(NotClosableMessage))
+	})
+
+	It("picks and bashes locks", func() {
+		_, err := m.Pick(ann, "north")
+		Ω(err).Should(MatchError(PickFailMessage))
+		ann.skills[PickSkill] = 100
+		_, err = m.Pick(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(door("hall", world.North).Locked).Should(BeFalse())
+		Ω(door("hall", world.North).Closed).Should(BeTrue())
+
+		_, err = m.Bash(ann, "down")
+		Ω(err).Should(MatchError(BashproofMessage))
+		_, err = m.Bash(ann, "chest")
+		Ω(err).Should(HaveOccurred())
+		ann.stats[BashStat] = 26
+		_, err = m.Bash(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(door("vault", world.South).Closed).Should(BeFalse())
+	})
+
+	It("lets scripts stop or hear about doors", func() {
+		em.On("before:"+PickEvent, events.HandlerFunc(func(d events.Data) error {
+			return errors.New("The lock is rusted shut.")
+		}))
+		unlocked := make(chan events.Data, 1)
+		em.On(UnlockEvent, events.HandlerFunc(func(d events.Data) error {
+			unlocked <- d
+
+			return nil
+		}))
+
+		ann.skills[PickSkill] = 100
+		_, err := m.Pick(ann, "north")
+		Ω(err).Should(MatchError("The lock is rusted shut."))
+
+		ann.inventory = item.List{key}
+		_, err = m.Unlock(ann, "north")
+		Ω(err).ShouldNot(HaveOccurred())
+		var d events.Data
+		Eventually(unlocked).Should(Receive(&d))
+		Ω(d["actor"]).Should(Equal("Ann"))
+		Ω(d["direction"]).Should(Equal("north"))
+		Ω(d["key"]).Should(Equal("iron-key"))
+	})
+
+	It("runs the door commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Opener {
+			return c.(*opener)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "open north")
+		Ω(ann.sent).Should(ContainElement(LockedMessage))
+		d.Dispatch(ann, "close down")
+		Ω(ann.sent).Should(ContainElement(IsClosedMessage))
+	})
+})
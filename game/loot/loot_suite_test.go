@@ -0,0 +1,13 @@
+package loot_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLoot(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Loot Suite")
+}
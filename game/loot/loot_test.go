@@ -0,0 +1,214 @@
+package loot_test
+
+import (
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/loot"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// looter carries what they're given and remembers what they're told
+type looter struct {
+	name      string
+	limit     int
+	inventory item.List
+	sent      []string
+}
+
+func (l *looter) Name() string {
+	return l.name
+}
+
+func (l *looter) Location() string {
+	return "cave"
+}
+
+func (l *looter) Inventory() item.List {
+	return l.inventory.Copy()
+}
+
+func (l *looter) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	list, err := fn(l.inventory.Copy())
+	if err == nil {
+		l.inventory = list
+	}
+
+	return err
+}
+
+func (l *looter) CarryLimit() int {
+	return l.limit
+}
+
+func (l *looter) Send(text string) error {
+	l.sent = append(l.sent, text)
+
+	return nil
+}
+
+// count returns how many of the item the looter has
+func (l *looter) count(proto string) int {
+	n := 0
+	for _, it := range l.inventory {
+		if it.Proto == proto {
+			n += it.Quantity()
+		}
+	}
+
+	return n
+}
+
+const tables = `
+tables:
+  - id: goblin
+    rolls: 2
+    entries:
+      - item: coin
+        min: 2
+        max: 2
+        per_level: 5
+  - id: boss
+    entries:
+      - table: gems
+        min_level: 10
+      - item: dagger
+        max_level: 9
+  - id: gems
+    entries:
+      - item: ruby
+`
+
+var _ = Describe("Tables", func() {
+	var t *loot.Tables
+
+	BeforeEach(func() {
+		t = loot.NewTables()
+		Ω(t.LoadYAML([]byte(tables))).Should(Succeed())
+	})
+
+	It("rolls entries, combining drops and scaling them with the level", func() {
+		Ω(t.Roll("goblin", 1)).Should(Equal([]loot.Drop{{Item: "coin", Count: 4}}))
+		Ω(t.Roll("goblin", 10)).Should(Equal([]loot.Drop{{Item: "coin", Count: 8}}))
+	})
+
+	It("rolls nested tables and leaves out entries for other levels", func() {
+		Ω(t.Roll("boss", 3)).Should(Equal([]loot.Drop{{Item: "dagger", Count: 1}}))
+		Ω(t.Roll("boss", 12)).Should(Equal([]loot.Drop{{Item: "ruby", Count: 1}}))
+	})
+
+	It("stops tables that include each other", func() {
+		Ω(t.Add(loot.Table{ID: "loop", Entries: []loot.Entry{{Table: "loop"}}})).Should(Succeed())
+
+		Ω(t.Roll("loop", 1)).Should(BeEmpty())
+	})
+
+	It("rejects tables that don't make sense", func() {
+		Ω(t.Add(loot.Table{})).ShouldNot(Succeed())
+		Ω(t.Add(loot.Table{ID: "bad", Entries: []loot.Entry{{Item: "coin", Table: "gems"}}})).ShouldNot(Succeed())
+		Ω(t.Add(loot.Table{ID: "bad", Entries: []loot.Entry{{Item: "coin", MinLevel: 5, MaxLevel: 2}}})).ShouldNot(Succeed())
+		_, err := t.Roll("missing", 1)
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		w        *world.World
+		floor    *item.Floor
+		em       *events.Emitter
+		m        *loot.Manager
+		ann, bob *looter
+		goblin   loot.Context
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "hills", Name: "Hills"})).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "goblin", Zone: "hills", Name: "a goblin", Level: 1, Props: map[string]interface{}{
+			loot.TableProp: "goblin",
+		}})).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "rat", Zone: "hills", Name: "a rat"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "coin", Zone: "hills", Name: "a copper coin", Type: "money", Weight: 1})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "tooth", Zone: "hills", Name: "a rat tooth", Weight: 1})).Should(Succeed())
+
+		defs := loot.NewTables()
+		Ω(defs.LoadYAML([]byte(tables))).Should(Succeed())
+		floor = item.NewFloor()
+		em = events.NewEmitter(nil)
+		m = loot.NewManager(defs, w, floor, em)
+		ann = &looter{name: "Ann"}
+		bob = &looter{name: "Bob"}
+		m.SetMembers(func(killer string) []item.Carrier {
+			return []item.Carrier{ann, bob}
+		})
+		goblin = loot.Context{Mob: "goblin-1", Proto: "goblin", Name: "a goblin", Room: "cave", Killer: "Ann"}
+	})
+
+	It("shares loot in turn by default", func() {
+		m.Modify("teeth", func(ctx loot.Context) []loot.Drop {
+			return append(ctx.Drops, loot.Drop{Item: "tooth", Count: 3})
+		})
+
+		made, err := m.Loot(goblin)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(made).Should(HaveLen(4))
+		Ω(ann.count("coin")).Should(Equal(4))
+		Ω(ann.count("tooth")).Should(Equal(1))
+		Ω(bob.count("tooth")).Should(Equal(2))
+		Ω(bob.sent).Should(ContainElement("You receive a rat tooth from a goblin."))
+	})
+
+	It("gives the killer everything, or leaves it all on the floor", func() {
+		Ω(m.SetRule("Killer")).Should(Succeed())
+		_, err := m.Loot(goblin)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(ann.count("coin")).Should(Equal(4))
+
+		Ω(m.SetRule(loot.FreeForAll)).Should(Succeed())
+		_, err = m.Loot(goblin)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(floor.In("cave")).Should(HaveLen(1))
+		Ω(m.SetRule("finders_keepers")).ShouldNot(Succeed())
+	})
+
+	It("leaves what players can't carry on the floor", func() {
+		Ω(m.SetRule(loot.Killer)).Should(Succeed())
+		ann.limit = 2
+
+		_, err := m.Loot(goblin)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(ann.count("coin")).Should(Equal(0))
+		Ω(floor.In("cave")).Should(HaveLen(1))
+	})
+
+	It("runs modifiers in order, for mobs without tables too", func() {
+		m.Modify("b", func(ctx loot.Context) []loot.Drop {
+			return []loot.Drop{{Item: "tooth", Count: ctx.Drops[0].Count * 2}}
+		})
+		m.Modify("a", func(ctx loot.Context) []loot.Drop {
+			return []loot.Drop{{Item: "tooth", Count: 1}}
+		})
+
+		Ω(m.Roll(loot.Context{Proto: "rat"})).Should(Equal([]loot.Drop{{Item: "tooth", Count: 2}}))
+		Ω(m.Unmodify("a")).Should(BeTrue())
+		Ω(m.Unmodify("a")).Should(BeFalse())
+	})
+
+	It("drops loot when mobs die", func() {
+		given := make(chan events.Data, 1)
+		em.On(loot.GiveEvent, events.HandlerFunc(func(d events.Data) error {
+			given <- d
+
+			return nil
+		}))
+
+		Ω(m.Died(events.Data{"mob": "goblin-1", "proto": "goblin", "name": "a goblin", "room": "cave", "killer": "Ann"})).Should(Succeed())
+		var d events.Data
+		Eventually(given).Should(Receive(&d))
+		Ω(d["player"]).Should(Equal("Ann"))
+		Ω(d["item_proto"]).Should(Equal("coin"))
+	})
+})
@@ -0,0 +1,334 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package loot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// TableProp is the prop of NPCs naming the loot table rolled when mobs made
+// from them die.
+const TableProp = "loot"
+
+// The rules loot is shared by among the members of the killer's group who
+// are in the room.
+const (
+	// Killer gives everything to whoever made the kill.
+	Killer = "killer"
+	// RoundRobin gives each item to the next member in turn.
+	RoundRobin = "round_robin"
+	// Random gives each item to a member at random.
+	Random = "random"
+	// FreeForAll leaves everything on the floor for anyone to take.
+	FreeForAll = "free_for_all"
+)
+
+// Rules lists the rules loot is shared by.
+var Rules = []string{Killer, RoundRobin, Random, FreeForAll}
+
+// GiveEvent is emitted when a player is given loot, with the id, proto and
+// room of the mob it came from, the name of the player and the id, proto and
+// count of the item.
+const GiveEvent = "loot:given"
+
+// ReceiveMessage tells players the loot they're given and where it's from.
+const ReceiveMessage = "You receive %s from %s."
+
+// Context is a mob that died, and what it drops.
+type Context struct {
+	Mob   string
+	Proto string
+	Name  string
+	Room  string
+	// Killer is the name of who killed the mob, empty if no one did.
+	Killer string
+	Level  int
+	Drops  []Drop
+}
+
+// Modifier changes what a mob drops, returning the new drops.
+type Modifier func(ctx Context) []Drop
+
+// Manager rolls the loot of mobs that die and shares it out.
+type Manager struct {
+	tables    *Tables
+	world     *world.World
+	floor     *item.Floor
+	rule      string
+	members   func(killer string) []item.Carrier
+	modifiers map[string]Modifier
+	// turns holds whose turn it is for round robin loot, by the names of
+	// everyone sharing it
+	turns   map[string]int
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager rolling the tables, making items from the
+// world's definitions and leaving what no one's given on the floor. The
+// emitter may be nil.
+func NewManager(tables *Tables, w *world.World, f *item.Floor, em *events.Emitter) *Manager {
+	return &Manager{
+		tables:    tables,
+		world:     w,
+		floor:     f,
+		rule:      RoundRobin,
+		modifiers: make(map[string]Modifier),
+		turns:     make(map[string]int),
+		emitter:   em,
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's loot manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewTables(), world.Global(), item.GlobalFloor(), nil)
+	})
+
+	return globalManager
+}
+
+// Tables returns the loot tables.
+func (m *Manager) Tables() *Tables {
+	return m.tables
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetRule changes the rule loot is shared by, round robin by default.
+func (m *Manager) SetRule(rule string) error {
+	rule = strings.ToLower(rule)
+	for _, r := range Rules {
+		if r == rule {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+
+			m.rule = rule
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the loot rule is one of %v, not %q", Rules, rule)
+}
+
+// Rule returns the rule loot is shared by.
+func (m *Manager) Rule() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.rule
+}
+
+// SetMembers sets how those sharing the loot of a kill are found, the killer
+// first. Without it loot is always left on the floor.
+func (m *Manager) SetMembers(fn func(killer string) []item.Carrier) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.members = fn
+}
+
+// Modify sets the modifier with the name, replacing any there was.
+// Modifiers run in order of their names after the mob's table is rolled.
+func (m *Manager) Modify(name string, fn Modifier) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.modifiers[name] = fn
+}
+
+// Unmodify removes the modifier with the name, returning false if there
+// wasn't one.
+func (m *Manager) Unmodify(name string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.modifiers[name]
+	delete(m.modifiers, name)
+
+	return ok
+}
+
+// Roll returns what the mob drops, from its NPC's table and the modifiers.
+// Mobs whose NPCs have no table drop only what modifiers give them. The
+// level of the NPC is used if the context has none.
+func (m *Manager) Roll(ctx Context) ([]Drop, error) {
+	def, ok := m.world.NPC(ctx.Proto)
+	if !ok {
+		return nil, fmt.Errorf("there's no NPC %q", ctx.Proto)
+	}
+	if ctx.Level == 0 {
+		ctx.Level = def.Level
+	}
+	if table, ok := def.Props[TableProp].(string); ok && table != "" {
+		drops, err := m.tables.Roll(table, ctx.Level)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Drops = append(ctx.Drops, drops...)
+	}
+
+	m.mutex.RLock()
+	names := make([]string, 0, len(m.modifiers))
+	for name := range m.modifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	modifiers := make([]Modifier, len(names))
+	for i, name := range names {
+		modifiers[i] = m.modifiers[name]
+	}
+	m.mutex.RUnlock()
+
+	for _, fn := range modifiers {
+		ctx.Drops = fn(ctx)
+	}
+	drops := make([]Drop, 0, len(ctx.Drops))
+	for _, d := range ctx.Drops {
+		if d.Item != "" && d.Count > 0 {
+			drops = append(drops, d)
+		}
+	}
+
+	return drops, nil
+}
+
+// Loot rolls what the mob drops and shares it by the rule, returning the
+// items made. What isn't given to anyone is left on the floor of its room.
+func (m *Manager) Loot(ctx Context) (item.List, error) {
+	drops, err := m.Roll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var made item.List
+	for _, d := range drops {
+		first, err := item.Create(m.world, d.Item, d.Count)
+		if err != nil {
+			return made, fmt.Errorf("loot %q: %s", d.Item, err)
+		}
+		made = append(made, first)
+		for i := 1; i < d.Count && !first.Stackable(); i++ {
+			it, _ := item.Create(m.world, d.Item, 1)
+			made = append(made, it)
+		}
+	}
+
+	m.mutex.RLock()
+	rule, members := m.rule, m.members
+	m.mutex.RUnlock()
+
+	var sharing []item.Carrier
+	if members != nil && ctx.Killer != "" && rule != FreeForAll {
+		sharing = members(ctx.Killer)
+	}
+	for _, it := range made {
+		to := m.recipient(rule, sharing)
+		if to == nil || !give(to, it) {
+			m.floor.Add(ctx.Room, it)
+
+			continue
+		}
+		if s, ok := to.(item.Sender); ok {
+			s.Send(fmt.Sprintf(ReceiveMessage, it.Describe(), ctx.Name))
+		}
+		m.emit(GiveEvent, events.Data{
+			"mob":        ctx.Mob,
+			"proto":      ctx.Proto,
+			"room":       ctx.Room,
+			"player":     to.Name(),
+			"item":       it.ID,
+			"item_proto": it.Proto,
+			"count":      it.Quantity(),
+		})
+	}
+
+	return made, nil
+}
+
+// Died looks after the loot of a mob that died, it handles mob:died events.
+func (m *Manager) Died(data events.Data) error {
+	ctx := Context{}
+	ctx.Mob, _ = data["mob"].(string)
+	ctx.Proto, _ = data["proto"].(string)
+	ctx.Name, _ = data["name"].(string)
+	ctx.Room, _ = data["room"].(string)
+	ctx.Killer, _ = data["killer"].(string)
+	if _, err := m.Loot(ctx); err != nil {
+		logger.NewWithSource("loot").WithError(err).WithField("mob", ctx.Mob).Warn("Failed to drop a mob's loot.")
+	}
+
+	return nil
+}
+
+// recipient returns who's given the next item by the rule, nil if no one is
+func (m *Manager) recipient(rule string, sharing []item.Carrier) item.Carrier {
+	if len(sharing) == 0 {
+		return nil
+	}
+	switch rule {
+	case Random:
+		return sharing[random.Intn(len(sharing))]
+	case RoundRobin:
+		names := make([]string, len(sharing))
+		for i, c := range sharing {
+			names[i] = strings.ToLower(c.Name())
+		}
+		sort.Strings(names)
+		key := strings.Join(names, ",")
+
+		m.mutex.Lock()
+		turn := m.turns[key] % len(sharing)
+		m.turns[key] = turn + 1
+		m.mutex.Unlock()
+
+		return sharing[turn]
+	}
+
+	return sharing[0]
+}
+
+// give puts the item in the carrier's inventory, returning false if they
+// can't carry it
+func give(c item.Carrier, it item.Item) bool {
+	err := c.UpdateInventory(func(l item.List) (item.List, error) {
+		if lim, ok := c.(item.Limited); ok && lim.CarryLimit() > 0 && l.Weight()+it.TotalWeight() > lim.CarryLimit() {
+			return nil, &item.Refused{Message: item.TooHeavyMessage}
+		}
+
+		return l.Add(it), nil
+	})
+
+	return err == nil
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package loot decides what mobs leave behind when they die. Loot tables are
+// defined in YAML files with weighted entries, which are items or other
+// tables, that may only drop from mobs of some levels and drop more from
+// stronger ones. NPCs name their table with the "loot" prop. What's rolled
+// can be changed by modifiers, written in Go or Lua, then it's shared among
+// the killer's group by the distribution rule.
+package loot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/random"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MaxDepth is how deeply tables may be nested in each other, tables nested
+// deeper are ignored so tables that include each other can't roll forever.
+const MaxDepth = 8
+
+// Entry is something a loot table drops.
+type Entry struct {
+	// Item is the id of the item definition dropped.
+	Item string `yaml:"item,omitempty"`
+	// Table is the id of another table rolled instead of dropping an item.
+	Table string `yaml:"table,omitempty"`
+	// Weight is how likely the entry is rolled over others, one by default.
+	Weight int `yaml:"weight,omitempty"`
+	// Min and Max are how many of the item drop, one by default.
+	Min int `yaml:"min,omitempty"`
+	Max int `yaml:"max,omitempty"`
+	// MinLevel and MaxLevel limit the levels of mobs the entry drops from,
+	// zero is no limit.
+	MinLevel int `yaml:"min_level,omitempty"`
+	MaxLevel int `yaml:"max_level,omitempty"`
+	// PerLevel drops one more of the item for every so many levels of the
+	// mob, zero drops the same from every level.
+	PerLevel int `yaml:"per_level,omitempty"`
+}
+
+// fits is true if the entry drops from mobs of the level
+func (e Entry) fits(level int) bool {
+	return (e.MinLevel == 0 || level >= e.MinLevel) && (e.MaxLevel == 0 || level <= e.MaxLevel)
+}
+
+// Table is a list of entries, some of which are rolled each time the table
+// is.
+type Table struct {
+	ID string `yaml:"id"`
+	// Rolls is how many entries are rolled, one by default.
+	Rolls int `yaml:"rolls,omitempty"`
+	// Nothing is the weight of rolling nothing at all.
+	Nothing int     `yaml:"nothing,omitempty"`
+	Entries []Entry `yaml:"entries"`
+}
+
+// validate fills in defaults and checks the table makes sense
+func (t *Table) validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("loot tables need an id")
+	}
+	if t.Rolls < 0 || t.Nothing < 0 {
+		return fmt.Errorf("loot table %s: can't have negative rolls or nothing", t.ID)
+	}
+	if t.Rolls == 0 {
+		t.Rolls = 1
+	}
+	entries := make([]Entry, len(t.Entries))
+	for i, e := range t.Entries {
+		switch {
+		case (e.Item == "") == (e.Table == ""):
+			return fmt.Errorf("loot table %s: entry %d needs exactly one of an item or a table", t.ID, i+1)
+		case e.Weight < 0 || e.Min < 0 || e.Max < 0 || e.MinLevel < 0 || e.MaxLevel < 0 || e.PerLevel < 0:
+			return fmt.Errorf("loot table %s: entry %d can't have negative numbers", t.ID, i+1)
+		case e.MaxLevel > 0 && e.MaxLevel < e.MinLevel:
+			return fmt.Errorf("loot table %s: entry %d has a max_level below its min_level", t.ID, i+1)
+		}
+		if e.Weight == 0 {
+			e.Weight = 1
+		}
+		if e.Min == 0 {
+			e.Min = 1
+		}
+		if e.Max < e.Min {
+			e.Max = e.Min
+		}
+		entries[i] = e
+	}
+	t.Entries = entries
+
+	return nil
+}
+
+// Drop is some of an item that's dropped.
+type Drop struct {
+	// Item is the id of the item definition.
+	Item  string
+	Count int
+}
+
+// File is the layout of a loot file, a list of tables.
+type File struct {
+	Tables []Table `yaml:"tables"`
+}
+
+// Tables holds the loot tables by id.
+type Tables struct {
+	tables map[string]Table
+	mutex  *sync.RWMutex
+}
+
+// NewTables creates an empty set of tables.
+func NewTables() *Tables {
+	return &Tables{
+		tables: make(map[string]Table),
+		mutex:  new(sync.RWMutex),
+	}
+}
+
+// Add adds the table, replacing any with its id.
+func (t *Tables) Add(table Table) error {
+	if err := table.validate(); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.tables[table.ID] = table
+
+	return nil
+}
+
+// Get returns the table with the id.
+func (t *Tables) Get(id string) (Table, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	table, ok := t.tables[id]
+
+	return table, ok
+}
+
+// All returns every table, sorted by id.
+func (t *Tables) All() []Table {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tables := make([]Table, 0, len(t.tables))
+	for _, table := range t.tables {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].ID < tables[j].ID
+	})
+
+	return tables
+}
+
+// Roll rolls the table with the id for a mob of the level, returning what
+// drops. Drops of the same item are combined.
+func (t *Tables) Roll(id string, level int) ([]Drop, error) {
+	if _, ok := t.Get(id); !ok {
+		return nil, fmt.Errorf("there's no loot table %q", id)
+	}
+	counts := make(map[string]int)
+	var order []string
+	t.roll(id, level, 0, func(item string, count int) {
+		if _, ok := counts[item]; !ok {
+			order = append(order, item)
+		}
+		counts[item] += count
+	})
+
+	drops := make([]Drop, len(order))
+	for i, item := range order {
+		drops[i] = Drop{Item: item, Count: counts[item]}
+	}
+
+	return drops, nil
+}
+
+// roll rolls the table, calling drop with what drops
+func (t *Tables) roll(id string, level, depth int, drop func(item string, count int)) {
+	table, ok := t.Get(id)
+	if !ok || depth >= MaxDepth {
+		return
+	}
+	var entries []Entry
+	total := table.Nothing
+	for _, e := range table.Entries {
+		if e.fits(level) {
+			entries = append(entries, e)
+			total += e.Weight
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	for i := 0; i < table.Rolls; i++ {
+		roll := random.Intn(total)
+		for _, e := range entries {
+			if roll >= e.Weight {
+				roll -= e.Weight
+
+				continue
+			}
+			if e.Table != "" {
+				t.roll(e.Table, level, depth+1, drop)
+
+				break
+			}
+			count := random.Range(e.Min, e.Max+1)
+			if e.PerLevel > 0 {
+				count += level / e.PerLevel
+			}
+			drop(e.Item, count)
+
+			break
+		}
+	}
+}
+
+// LoadDir adds the tables in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (t *Tables) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := t.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the tables in the YAML document, like:
+//   tables:
+//     - id: goblin
+//       rolls: 2
+//       nothing: 2
+//       entries:
+//         - item: copper-coin
+//           weight: 4
+//           min: 1
+//           max: 6
+//           per_level: 2
+//         - table: gems
+//           min_level: 5
+//     - id: gems
+//       entries:
+//         - item: ruby
+//         - item: emerald
+//           weight: 2
+func (t *Tables) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, table := range f.Tables {
+		if err := t.Add(table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
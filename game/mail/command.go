@@ -0,0 +1,214 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package mail
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// NewCommands creates the mail command. With nothing after it, it lists the
+// caller's letters, otherwise it's followed by what to do:
+//   mail read <n>             reads a letter
+//   mail take <n>             takes what's attached to a letter
+//   mail delete <n>           deletes a letter
+//   mail attach <item>        attaches something carried to the next letter
+//   mail pay <amount>         attaches money to the next letter
+//   mail detach               takes back what's attached to the next letter
+//   mail write <name> [subj]  writes a letter in the editor, sending it on save
+func NewCommands(m *Manager, resolve command.Resolver[Correspondent]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "mail",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "rest", Kind: command.Text, Optional: true},
+			},
+			Help: "Lists your mail, \"mail read|take|delete <n>\" handles a letter, " +
+				"\"mail attach <item>\", \"mail pay <amount>\" and \"mail detach\" change what's " +
+				"attached to your next letter and \"mail write <name> [subject]\" writes it.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Correspondent) error {
+				rest := strings.TrimSpace(ctx.String("rest"))
+				switch action := strings.ToLower(ctx.String("action")); action {
+				case "", "list":
+					letters, err := m.Letters(c.ID())
+					if err != nil {
+						return err
+					}
+
+					return ctx.Send(listing(m, letters, m.Pending(c.ID())))
+				case "read", "take", "delete":
+					n, err := strconv.Atoi(rest)
+					if err != nil {
+						return ctx.Send(fmt.Sprintf("Which letter do you want to %s?", action))
+					}
+
+					return letterAction(ctx, m, c, action, n)
+				case "attach":
+					if rest == "" {
+						return ctx.Send("Attach what?")
+					}
+					it, err := m.Attach(c, rest)
+					if err != nil {
+						return err
+					}
+
+					return ctx.Send(fmt.Sprintf("You attach %s to your next letter.", it.Name))
+				case "pay":
+					amount, err := m.Currency().Parse(rest)
+					if err != nil {
+						return ctx.Send(err.Error())
+					}
+					if err := m.Pay(c, amount); err != nil {
+						return err
+					}
+
+					return ctx.Send(fmt.Sprintf("You attach %s to your next letter.", m.Currency().Format(amount)))
+				case "detach":
+					d := m.Detach(c)
+					if len(d.Items) == 0 && d.Money == 0 {
+						return ctx.Send("Nothing is attached to your next letter.")
+					}
+
+					return ctx.Send("You take back what was attached to your next letter.")
+				case "write", "send":
+					words := strings.Fields(rest)
+					if len(words) == 0 {
+						return ctx.Send("Who do you want to write to?")
+					}
+					to, subject := words[0], strings.Join(words[1:], " ")
+					e := editor.New("", func(body string) {
+						l, err := m.Send(c, to, subject, body)
+						if r, ok := err.(*item.Refused); ok {
+							ctx.Send(r.Message)
+
+							return
+						}
+						if err != nil {
+							logger.NewWithSource("mail").WithError(err).WithField("to", to).Error("Failed to send a letter.")
+							ctx.Send("Your letter couldn't be sent.")
+
+							return
+						}
+						ctx.Send(fmt.Sprintf("You send your letter to %s.", l.To))
+					})
+					editor.Open(ctx.Dispatcher, ctx.Caller, e)
+
+					return nil
+				}
+
+				return ctx.Send(ctx.Command.Help)
+			}),
+		},
+	}
+}
+
+// letterAction reads, takes from or deletes letter n
+func letterAction(ctx *command.Context, m *Manager, c Correspondent, action string, n int) error {
+	switch action {
+	case "read":
+		l, err := m.Read(c.ID(), n)
+		if err != nil {
+			return err
+		}
+
+		return ctx.Send(show(m, l))
+	case "take":
+		l, err := m.Take(c, n)
+		if err != nil {
+			return err
+		}
+
+		return ctx.Send(fmt.Sprintf("You take %s from the letter.", attachments(m, l.Items, l.Money)))
+	}
+	if err := m.Delete(c.ID(), n); err != nil {
+		return err
+	}
+
+	return ctx.Send("You delete the letter.")
+}
+
+// listing lists the letters with who they're from and their subjects
+func listing(m *Manager, letters []Letter, d Draft) string {
+	var lines []string
+	if len(letters) == 0 {
+		lines = append(lines, "You have no mail.")
+	} else {
+		lines = append(lines, "Your mail:")
+	}
+	for i, l := range letters {
+		mark := " "
+		if !l.Read {
+			mark = "*"
+		}
+		line := fmt.Sprintf("%s%3d. %-12s %s", mark, i+1, l.From, l.Subject)
+		if l.Attached() {
+			line += " (attached)"
+		}
+		lines = append(lines, line)
+	}
+	if len(d.Items) > 0 || d.Money > 0 {
+		lines = append(lines, fmt.Sprintf("Attached to your next letter: %s.", attachments(m, d.Items, d.Money)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// show shows the whole letter
+func show(m *Manager, l Letter) string {
+	lines := []string{
+		"From: " + l.From,
+		"Sent: " + l.Sent.Format("Jan 2 2006 15:04"),
+		"Subject: " + l.Subject,
+	}
+	if l.Attached() {
+		lines = append(lines, "Attached: "+attachments(m, l.Items, l.Money))
+	}
+	if l.Body != "" {
+		lines = append(lines, "", strings.TrimRight(l.Body, "\n"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// attachments names the items and money
+func attachments(m *Manager, items item.List, money int) string {
+	var names []string
+	for _, it := range items {
+		if it.Quantity() > 1 {
+			names = append(names, fmt.Sprintf("%s (%d)", it.Name, it.Quantity()))
+		} else {
+			names = append(names, it.Name)
+		}
+	}
+	if money > 0 {
+		names = append(names, m.Currency().Format(money))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// handler resolves the caller's correspondent for fn, telling the caller
+// when something is refused
+func handler(resolve command.Resolver[Correspondent], fn func(*command.Context, Correspondent) error) command.Handler {
+	return func(ctx *command.Context) error {
+		c := resolve(ctx.Caller)
+		if c == nil {
+			return ctx.Send("You can't use mail.")
+		}
+
+		err := fn(ctx, c)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
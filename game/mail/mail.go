@@ -0,0 +1,152 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package mail lets players write letters to each other, whether or not the
+// other is in the game. Letters are written in the line editor and can carry
+// items and money, which stay with the letter until its reader takes them.
+// Each player's mailbox is saved as soon as it changes.
+package mail
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// Letter is a piece of mail.
+type Letter struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body,omitempty"`
+	Sent    time.Time `json:"sent"`
+	Read    bool      `json:"read,omitempty"`
+	// Items are attached to the letter until its reader takes them.
+	Items item.List `json:"items,omitempty"`
+	// Money is attached to the letter, in the smallest coin.
+	Money int `json:"money,omitempty"`
+}
+
+// Attached is true if anything is attached to the letter.
+func (l Letter) Attached() bool {
+	return len(l.Items) > 0 || l.Money > 0
+}
+
+// copy returns a deep copy of the letter
+func (l Letter) copy() Letter {
+	l.Items = l.Items.Copy()
+
+	return l
+}
+
+// Store persists mailboxes, keyed by the lower case name of their owner.
+type Store interface {
+	// Load returns the letters in the mailbox, oldest first. Mailboxes that
+	// were never saved are empty.
+	Load(box string) ([]Letter, error)
+	// Save replaces the letters in the mailbox.
+	Save(box string, letters []Letter) error
+}
+
+// DirStore saves each mailbox as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving mailboxes to the directory, the
+// directory is created when the first mailbox is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Load reads the mailbox's file.
+func (s *DirStore) Load(box string) ([]Letter, error) {
+	if strings.ContainsAny(box, `/\.`) {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, strings.ToLower(box)+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var letters []Letter
+	err = json.Unmarshal(contents, &letters)
+
+	return letters, err
+}
+
+// Save writes the letters to <box>.json, through a temporary file so a
+// crash never leaves a partially written mailbox behind.
+func (s *DirStore) Save(box string, letters []Letter) error {
+	if strings.ContainsAny(box, `/\.`) {
+		return ErrNoRecipient
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(letters, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, strings.ToLower(box)+".json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// MemoryStore keeps mailboxes in memory, they're lost when the server stops.
+type MemoryStore struct {
+	boxes map[string][]Letter
+	mutex *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		boxes: make(map[string][]Letter),
+		mutex: new(sync.Mutex),
+	}
+}
+
+// Load returns a copy of the letters in the mailbox.
+func (s *MemoryStore) Load(box string) ([]Letter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return copyLetters(s.boxes[strings.ToLower(box)]), nil
+}
+
+// Save stores a copy of the letters.
+func (s *MemoryStore) Save(box string, letters []Letter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.boxes[strings.ToLower(box)] = copyLetters(letters)
+
+	return nil
+}
+
+func copyLetters(letters []Letter) []Letter {
+	if letters == nil {
+		return nil
+	}
+	copied := make([]Letter, len(letters))
+	for i, l := range letters {
+		copied[i] = l.copy()
+	}
+
+	return copied
+}
@@ -0,0 +1,306 @@
+package mail_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/game/shop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writer sends and reads mail and remembers what they're told
+type writer struct {
+	name      string
+	stats     map[string]int
+	inventory item.List
+	limit     int
+	sent      []string
+}
+
+func (w *writer) ID() string {
+	return strings.ToLower(w.name)
+}
+
+func (w *writer) Name() string {
+	return w.name
+}
+
+func (w *writer) Location() string {
+	return "post office"
+}
+
+func (w *writer) Stat(name string) int {
+	return w.stats[name]
+}
+
+func (w *writer) AddStat(name string, delta int) int {
+	w.stats[name] += delta
+
+	return w.stats[name]
+}
+
+func (w *writer) Inventory() item.List {
+	return w.inventory.Copy()
+}
+
+func (w *writer) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	l, err := fn(w.inventory.Copy())
+	if err == nil {
+		w.inventory = l
+	}
+
+	return err
+}
+
+func (w *writer) CarryLimit() int {
+	return w.limit
+}
+
+func (w *writer) Level() command.Level {
+	return command.Player
+}
+
+func (w *writer) Send(text string) error {
+	w.sent = append(w.sent, text)
+
+	return nil
+}
+
+func (w *writer) told(text string) bool {
+	for _, s := range w.sent {
+		if s == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failingStore keeps mailboxes in memory, or fails to save them
+type failingStore struct {
+	*MemoryStore
+	broken bool
+}
+
+func (s *failingStore) Save(box string, letters []Letter) error {
+	if s.broken {
+		return errors.New("disk full")
+	}
+
+	return s.MemoryStore.Save(box, letters)
+}
+
+var _ = Describe("Mail", func() {
+	var (
+		em       *events.Emitter
+		m        *Manager
+		ann, bob *writer
+		notified []string
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		m = NewManager(NewMemoryStore(), em)
+		m.SetMoney("coins", shop.DefaultCurrency)
+		m.SetExists(func(name string) bool {
+			name = strings.ToLower(name)
+
+			return name == "ann" || name == "bob"
+		})
+		notified = nil
+		m.SetNotify(func(to string, l Letter) {
+			notified = append(notified, to+": "+l.Subject)
+		})
+		ann = &writer{
+			name:      "Ann",
+			stats:     map[string]int{"coins": 150},
+			inventory: item.List{{ID: "ring-1", Name: "a gold ring", Keywords: []string{"ring"}, Weight: 1}},
+		}
+		bob = &writer{name: "Bob", stats: map[string]int{}}
+	})
+
+	It("delivers letters with what's attached to them", func() {
+		it, err := m.Attach(ann, "ring")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(it.Name).Should(Equal("a gold ring"))
+		Ω(ann.inventory).Should(BeEmpty())
+		Ω(m.Pay(ann, 200)).Should(MatchError(PoorMessage))
+		Ω(m.Pay(ann, 120)).Should(Succeed())
+		Ω(ann.stats["coins"]).Should(Equal(30))
+
+		l, err := m.Send(ann, "Bob", "A gift", "Happy birthday!")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(l.From).Should(Equal("Ann"))
+		Ω(notified).Should(Equal([]string{"Bob: A gift"}))
+		Ω(m.Pending("ann").Items).Should(BeEmpty())
+		Ω(m.Unread("bob")).Should(Equal(1))
+
+		read, err := m.Read("bob", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(read.Body).Should(Equal("Happy birthday!"))
+		Ω(m.Unread("bob")).Should(Equal(0))
+		Ω(m.Delete("bob", 1)).Should(MatchError(AttachedMessage))
+
+		bob.limit = 1
+		bob.inventory = item.List{{ID: "rock-1", Name: "a rock", Weight: 1}}
+		_, err = m.Take(bob, 1)
+		Ω(err).Should(MatchError(TooHeavyMessage))
+		bob.limit = 0
+		taken, err := m.Take(bob, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(taken.Money).Should(Equal(120))
+		Ω(bob.stats["coins"]).Should(Equal(120))
+		_, ok := bob.inventory.Find("ring")
+		Ω(ok).Should(BeTrue())
+		_, err = m.Take(bob, 1)
+		Ω(err).Should(MatchError(NothingMessage))
+		Ω(m.Delete("bob", 1)).Should(Succeed())
+		Ω(m.Letters("bob")).Should(BeEmpty())
+	})
+
+	It("refuses letters that can't be sent", func() {
+		_, err := m.Send(ann, "Bob", "Nothing", " ")
+		Ω(err).Should(MatchError(EmptyMessage))
+		_, err = m.Send(ann, "Cat", "Hello", "Hi there.")
+		Ω(err).Should(MatchError(NoRecipientMessage))
+		_, err = m.Attach(ann, "sword")
+		Ω(err).Should(MatchError(NotCarriedMessage))
+		_, err = m.Read("bob", 1)
+		Ω(err).Should(MatchError(NoLetterMessage))
+	})
+
+	It("keeps what's attached when sending fails and gives it back", func() {
+		_, err := m.Attach(ann, "ring")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(m.Pay(ann, 50)).Should(Succeed())
+		_, err = m.Send(ann, "Cat", "Lost", "Where are you?")
+		Ω(err).Should(HaveOccurred())
+		Ω(m.Pending("ann").Money).Should(Equal(50))
+
+		d := m.Detach(ann)
+		Ω(d.Items).Should(HaveLen(1))
+		Ω(ann.stats["coins"]).Should(Equal(150))
+		_, ok := ann.inventory.Find("ring")
+		Ω(ok).Should(BeTrue())
+	})
+
+	It("gives back every draft, like before the server stops", func() {
+		_, err := m.Attach(ann, "ring")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(m.Pay(ann, 50)).Should(Succeed())
+		Ω(m.Pay(bob, 10)).Should(MatchError(PoorMessage))
+
+		returned := m.ReturnDrafts(func(id string) Correspondent {
+			if id == ann.ID() {
+				return ann
+			}
+
+			return nil
+		})
+		Ω(returned).Should(Equal(1))
+		Ω(ann.stats["coins"]).Should(Equal(150))
+		_, ok := ann.inventory.Find("ring")
+		Ω(ok).Should(BeTrue())
+		Ω(m.Pending("ann").Items).Should(BeEmpty())
+	})
+
+	It("only gives what's attached once the letter is saved", func() {
+		store := &failingStore{MemoryStore: NewMemoryStore()}
+		m.SetStore(store)
+		_, err := m.Deliver(Letter{From: "Ann", To: "Bob", Items: item.List{{ID: "ring-1", Name: "a gold ring", Keywords: []string{"ring"}}}, Money: 20})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		store.broken = true
+		_, err = m.Take(bob, 1)
+		Ω(err).Should(MatchError("disk full"))
+		Ω(bob.inventory).Should(BeEmpty())
+		Ω(bob.stats["coins"]).Should(Equal(0))
+
+		store.broken = false
+		_, err = m.Take(bob, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(bob.inventory).Should(HaveLen(1))
+		Ω(bob.stats["coins"]).Should(Equal(20))
+	})
+
+	It("lets before handlers stop letters and tells of those sent", func(done Done) {
+		sent := make(chan events.Data, 1)
+		em.On("before:"+SendEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["to"] == "ann" {
+				return errors.New("Ann isn't taking mail.")
+			}
+
+			return nil
+		}))
+		em.On(SendEvent, events.HandlerFunc(func(d events.Data) error {
+			sent <- d
+
+			return nil
+		}))
+
+		_, err := m.Deliver(Letter{From: "Postmaster", To: "ann", Body: "Hello."})
+		Ω(err).Should(MatchError("Ann isn't taking mail."))
+		l, err := m.Deliver(Letter{From: "Postmaster", To: "Bob", Body: "Welcome!", Money: 10})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(l.Subject).Should(Equal("(no subject)"))
+
+		d := <-sent
+		Ω(d["from"]).Should(Equal("Postmaster"))
+		Ω(d["money"]).Should(Equal(10))
+		close(done)
+	})
+
+	It("saves mailboxes to a directory", func() {
+		dir, err := ioutil.TempDir("", "mail")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		saving := NewManager(NewDirStore(dir), nil)
+		_, err = saving.Deliver(Letter{From: "Ann", To: "Bob", Subject: "Hi", Items: item.List{{ID: "ring-1", Name: "a gold ring"}}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		letters, err := NewManager(NewDirStore(dir), nil).Letters("bob")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(letters).Should(HaveLen(1))
+		Ω(letters[0].Items[0].Name).Should(Equal("a gold ring"))
+	})
+
+	It("writes and reads mail with the mail command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Correspondent {
+			return c.(*writer)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+		dispatch := func(w *writer, line string) {
+			Ω(dispatcher.Dispatch(w, line)).Should(Succeed())
+		}
+
+		dispatch(ann, "mail attach ring")
+		Ω(ann.told("You attach a gold ring to your next letter.")).Should(BeTrue())
+		dispatch(ann, "mail pay 1 gold")
+		Ω(ann.told("You attach 1 gold to your next letter.")).Should(BeTrue())
+		dispatch(ann, "mail write bob A gift")
+		dispatch(ann, "For you.")
+		dispatch(ann, ".s")
+		Ω(ann.told("You send your letter to bob.")).Should(BeTrue())
+
+		dispatch(bob, "mail")
+		Ω(bob.told("Your mail:\n*  1. Ann          A gift (attached)")).Should(BeTrue())
+		dispatch(bob, "mail delete 1")
+		Ω(bob.told(AttachedMessage)).Should(BeTrue())
+		dispatch(bob, "mail take 1")
+		Ω(bob.told("You take a gold ring, 1 gold from the letter.")).Should(BeTrue())
+		dispatch(bob, "mail read 2")
+		Ω(bob.told(NoLetterMessage)).Should(BeTrue())
+	})
+})
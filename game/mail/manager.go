@@ -0,0 +1,461 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package mail
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/spf13/viper"
+)
+
+// ErrNoRecipient is returned when mail is sent to someone who doesn't exist.
+var ErrNoRecipient = errors.New(NoRecipientMessage)
+
+// SendEvent is emitted when a letter is sent. Handlers of before:mail:send
+// can stop it by returning events.ErrHalt, or an error whose message is told
+// to the sender. It's given who the letter is from and to, its subject, how
+// many items are attached and the money attached.
+const SendEvent = "mail:send"
+
+// Messages told to players who can't do something with their mail.
+const (
+	NoRecipientMessage = "There's no one by that name."
+	NoLetterMessage    = "There's no such letter."
+	NothingMessage     = "Nothing is attached to that letter."
+	AttachedMessage    = "You'll have to take what's attached first."
+	EmptyMessage       = "You can't send an empty letter."
+	NotCarriedMessage  = "You aren't carrying that."
+	PoorMessage        = "You don't have that much."
+	NoMoneyMessage     = "You can't send money."
+	TooHeavyMessage    = "You can't carry that much weight."
+	CancelMessage      = "You can't do that right now."
+)
+
+// Correspondent is someone who sends and reads mail, like a player. Their
+// mailbox is kept under their ID.
+type Correspondent interface {
+	item.Carrier
+	ID() string
+	Stat(name string) int
+	AddStat(name string, delta int) int
+}
+
+// Draft is what's attached to the next letter a correspondent sends. Drafts
+// are only kept in memory, what's attached has to be given back with Detach
+// or ReturnDrafts before the correspondent leaves or the server stops.
+type Draft struct {
+	Items item.List
+	Money int
+}
+
+// Manager delivers letters and keeps them until their readers delete them.
+type Manager struct {
+	store    Store
+	exists   func(name string) bool
+	notify   func(to string, l Letter)
+	stat     string
+	currency shop.Currency
+	drafts   map[string]Draft
+	emitter  *events.Emitter
+	mutex    *sync.RWMutex
+}
+
+// NewManager creates a manager keeping mailboxes in the store. The emitter
+// may be nil.
+func NewManager(s Store, em *events.Emitter) *Manager {
+	return &Manager{
+		store:    s,
+		currency: shop.DefaultCurrency,
+		drafts:   make(map[string]Draft),
+		emitter:  em,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's mail manager, saving mailboxes in the directory
+// given by the mail.dir setting.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDirStore(viper.GetString("mail.dir")), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetStore changes where mailboxes are kept, like to keep them in memory.
+func (m *Manager) SetStore(s Store) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.store = s
+}
+
+// SetExists sets how to tell if there's a player with a name mail can be
+// sent to. Without it mail can be sent to anyone.
+func (m *Manager) SetExists(fn func(name string) bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.exists = fn
+}
+
+// SetNotify sets the function told of each letter delivered, like to tell
+// the recipient if they're in the game.
+func (m *Manager) SetNotify(fn func(to string, l Letter)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.notify = fn
+}
+
+// SetMoney sets the stat money is sent from and the currency it's counted
+// in. Without a stat money can't be sent.
+func (m *Manager) SetMoney(stat string, c shop.Currency) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stat, m.currency = stat, c
+}
+
+// Currency returns the currency money is counted in.
+func (m *Manager) Currency() shop.Currency {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.currency
+}
+
+// Attach moves the item the keyword names from what the correspondent
+// carries onto their next letter.
+func (m *Manager) Attach(c Correspondent, keyword string) (item.Item, error) {
+	it, ok := c.Inventory().Find(keyword)
+	if !ok {
+		return item.Item{}, &item.Refused{Message: NotCarriedMessage}
+	}
+	err := c.UpdateInventory(func(items item.List) (item.List, error) {
+		items, _, err := items.Take(it.ID, 0)
+
+		return items, err
+	})
+	if err != nil {
+		return item.Item{}, &item.Refused{Message: NotCarriedMessage}
+	}
+
+	m.mutex.Lock()
+	d := m.drafts[c.ID()]
+	d.Items = d.Items.Add(it)
+	m.drafts[c.ID()] = d
+	m.mutex.Unlock()
+
+	return it, nil
+}
+
+// Pay moves the amount of the correspondent's money onto their next letter.
+func (m *Manager) Pay(c Correspondent, amount int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stat == "" {
+		return &item.Refused{Message: NoMoneyMessage}
+	}
+	if amount <= 0 || c.Stat(m.stat) < amount {
+		return &item.Refused{Message: PoorMessage}
+	}
+	c.AddStat(m.stat, -amount)
+	d := m.drafts[c.ID()]
+	d.Money += amount
+	m.drafts[c.ID()] = d
+
+	return nil
+}
+
+// Pending returns what's attached to the correspondent's next letter.
+func (m *Manager) Pending(id string) Draft {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	d := m.drafts[id]
+	d.Items = d.Items.Copy()
+
+	return d
+}
+
+// Detach gives back everything attached to the correspondent's next letter,
+// returning what it was.
+func (m *Manager) Detach(c Correspondent) Draft {
+	m.mutex.Lock()
+	d, stat := m.drafts[c.ID()], m.stat
+	delete(m.drafts, c.ID())
+	m.mutex.Unlock()
+
+	if len(d.Items) > 0 {
+		c.UpdateInventory(func(items item.List) (item.List, error) {
+			for _, it := range d.Items {
+				items = items.Add(it)
+			}
+
+			return items, nil
+		})
+	}
+	if d.Money > 0 && stat != "" {
+		c.AddStat(stat, d.Money)
+	}
+
+	return d
+}
+
+// ReturnDrafts gives back everything attached to unsent letters to the
+// correspondents find returns for their IDs, like before the server stops,
+// returning how many drafts were given back. The drafts of those it can't
+// find are kept.
+func (m *Manager) ReturnDrafts(find func(id string) Correspondent) int {
+	m.mutex.RLock()
+	ids := make([]string, 0, len(m.drafts))
+	for id := range m.drafts {
+		ids = append(ids, id)
+	}
+	m.mutex.RUnlock()
+
+	returned := 0
+	for _, id := range ids {
+		if c := find(id); c != nil {
+			m.Detach(c)
+			returned++
+		}
+	}
+
+	return returned
+}
+
+// Send sends the correspondent's letter with what's attached to it.
+func (m *Manager) Send(c Correspondent, to, subject, body string) (Letter, error) {
+	d := m.Pending(c.ID())
+	l := Letter{
+		From:    c.Name(),
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Items:   d.Items,
+		Money:   d.Money,
+	}
+	if strings.TrimSpace(body) == "" && !l.Attached() {
+		return l, &item.Refused{Message: EmptyMessage}
+	}
+
+	m.mutex.Lock()
+	delete(m.drafts, c.ID())
+	m.mutex.Unlock()
+
+	l, err := m.Deliver(l)
+	if err != nil {
+		m.mutex.Lock()
+		m.drafts[c.ID()] = d
+		m.mutex.Unlock()
+	}
+
+	return l, err
+}
+
+// Deliver puts the letter in its recipient's mailbox, like mail sent by a
+// script. Whatever is attached is created by sending it.
+func (m *Manager) Deliver(l Letter) (Letter, error) {
+	m.mutex.RLock()
+	exists, notify := m.exists, m.notify
+	m.mutex.RUnlock()
+
+	l.To = strings.TrimSpace(l.To)
+	if l.To == "" || strings.ContainsAny(l.To, `/\. `) || (exists != nil && !exists(l.To)) {
+		return l, &item.Refused{Message: NoRecipientMessage}
+	}
+	if strings.TrimSpace(l.Subject) == "" {
+		l.Subject = "(no subject)"
+	}
+	l.Sent, l.Read = time.Now(), false
+	data := events.Data{
+		"from":    l.From,
+		"to":      l.To,
+		"subject": l.Subject,
+		"items":   len(l.Items),
+		"money":   l.Money,
+	}
+	if err := m.check(SendEvent, data); err != nil {
+		return l, err
+	}
+
+	err := m.update(l.To, func(letters []Letter) ([]Letter, error) {
+		return append(letters, l.copy()), nil
+	})
+	if err != nil {
+		return l, err
+	}
+	if notify != nil {
+		notify(l.To, l.copy())
+	}
+	m.confirm(SendEvent, data)
+
+	return l, nil
+}
+
+// Letters returns the letters in the mailbox of the player with the name,
+// oldest first.
+func (m *Manager) Letters(name string) ([]Letter, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.store.Load(strings.ToLower(name))
+}
+
+// Unread returns how many letters in the mailbox haven't been read.
+func (m *Manager) Unread(name string) int {
+	letters, _ := m.Letters(name)
+	n := 0
+	for _, l := range letters {
+		if !l.Read {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Read returns letter n of the mailbox, counting from 1, marking it read.
+func (m *Manager) Read(name string, n int) (Letter, error) {
+	var l Letter
+	err := m.update(name, func(letters []Letter) ([]Letter, error) {
+		if n < 1 || n > len(letters) {
+			return nil, &item.Refused{Message: NoLetterMessage}
+		}
+		letters[n-1].Read = true
+		l = letters[n-1].copy()
+
+		return letters, nil
+	})
+
+	return l, err
+}
+
+// Delete removes letter n of the mailbox, counting from 1. Letters with
+// something attached can't be deleted until it's taken.
+func (m *Manager) Delete(name string, n int) error {
+	return m.update(name, func(letters []Letter) ([]Letter, error) {
+		if n < 1 || n > len(letters) {
+			return nil, &item.Refused{Message: NoLetterMessage}
+		}
+		if letters[n-1].Attached() {
+			return nil, &item.Refused{Message: AttachedMessage}
+		}
+
+		return append(letters[:n-1], letters[n:]...), nil
+	})
+}
+
+// Take gives the correspondent what's attached to letter n of their
+// mailbox, counting from 1. It's given once the letter is saved without it,
+// so a letter that can't be saved keeps what's attached.
+func (m *Manager) Take(c Correspondent, n int) (Letter, error) {
+	m.mutex.RLock()
+	stat := m.stat
+	m.mutex.RUnlock()
+
+	var l Letter
+	err := m.update(c.ID(), func(letters []Letter) ([]Letter, error) {
+		if n < 1 || n > len(letters) {
+			return nil, &item.Refused{Message: NoLetterMessage}
+		}
+		l = letters[n-1].copy()
+		if !l.Attached() {
+			return nil, &item.Refused{Message: NothingMessage}
+		}
+		if lim, ok := c.(item.Limited); ok && lim.CarryLimit() > 0 && c.Inventory().Weight()+l.Items.Weight() > lim.CarryLimit() {
+			return nil, &item.Refused{Message: TooHeavyMessage}
+		}
+		letters[n-1].Items, letters[n-1].Money = nil, 0
+
+		return letters, nil
+	})
+	if err != nil {
+		return l, err
+	}
+
+	if len(l.Items) > 0 {
+		c.UpdateInventory(func(items item.List) (item.List, error) {
+			for _, it := range l.Items {
+				items = items.Add(it)
+			}
+
+			return items, nil
+		})
+	}
+	if l.Money > 0 && stat != "" {
+		c.AddStat(stat, l.Money)
+	}
+
+	return l, nil
+}
+
+// update changes the letters in the mailbox with fn and saves them, nothing
+// changes if fn fails
+func (m *Manager) update(name string, fn func([]Letter) ([]Letter, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	box := strings.ToLower(name)
+	letters, err := m.store.Load(box)
+	if err != nil {
+		return err
+	}
+	letters, err = fn(letters)
+	if err != nil {
+		return err
+	}
+
+	return m.store.Save(box, letters)
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package minimap
+
+import (
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// Viewer is a player looking at a map.
+type Viewer interface {
+	Location() string
+	Send(text string) error
+}
+
+// DataSender is a viewer whose client can be sent the map as data.
+type DataSender interface {
+	SendData(pkg string, data interface{}) error
+}
+
+// NewCommand creates the map command, showing the caller the rooms around
+// them or, with "map area", their whole zone.
+func NewCommand(m *Mapper, resolve command.Resolver[Viewer]) *command.Command {
+	return &command.Command{
+		Name:   "map",
+		Args:   []command.Arg{{Name: "area", Kind: command.Word, Optional: true}},
+		Help:   "Shows a map of the rooms around you. Type \"map area\" to see the whole area.",
+		Source: "game",
+		Handler: func(ctx *command.Context) error {
+			v := resolve(ctx.Caller)
+			if v == nil {
+				return ctx.Send("You aren't playing anyone.")
+			}
+
+			var (
+				drawn *Map
+				ok    bool
+			)
+			switch area := ctx.String("area"); {
+			case area == "":
+				drawn, ok = m.Minimap(v.Location(), 0)
+			case strings.EqualFold(area, "area"):
+				drawn, ok = m.Area(v.Location())
+			default:
+				return ctx.Send("Type \"map\" for the rooms around you or \"map area\" for the whole area.")
+			}
+			if !ok {
+				return ctx.Send("You aren't anywhere.")
+			}
+			if d, ok := v.(DataSender); ok {
+				d.SendData(Package, drawn)
+			}
+
+			return ctx.Send(drawn.String() + "\n\n" + drawn.Legend())
+		},
+	}
+}
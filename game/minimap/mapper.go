@@ -0,0 +1,120 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package minimap
+
+import (
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// DefaultRadius is how far minimaps reach unless it's changed.
+const DefaultRadius = 3
+
+// Mapper draws the game's maps with the same radius and terrain.
+type Mapper struct {
+	world   *world.World
+	radius  int
+	terrain []Terrain
+	mutex   *sync.RWMutex
+}
+
+// NewMapper creates a mapper for the world.
+func NewMapper(w *world.World) *Mapper {
+	return &Mapper{
+		world:   w,
+		radius:  DefaultRadius,
+		terrain: append([]Terrain(nil), DefaultTerrain...),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalMapper *Mapper
+	globalOnce   sync.Once
+)
+
+// Global returns the mapper the game uses.
+func Global() *Mapper {
+	globalOnce.Do(func() {
+		globalMapper = NewMapper(world.Global())
+	})
+
+	return globalMapper
+}
+
+// SetRadius changes how far minimaps reach, at least one room.
+func (m *Mapper) SetRadius(radius int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if radius < 1 {
+		radius = 1
+	}
+	m.radius = radius
+}
+
+// Radius returns how far minimaps reach.
+func (m *Mapper) Radius() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.radius
+}
+
+// SetTerrain changes the symbols rooms are drawn with.
+func (m *Mapper) SetTerrain(terrain []Terrain) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.terrain = append([]Terrain(nil), terrain...)
+}
+
+// SetSymbol draws rooms with the flag with the symbol, replacing the
+// flag's symbol if it has one or adding it last if it doesn't. An empty
+// symbol removes the flag's terrain.
+func (m *Mapper) SetSymbol(flag, symbol string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, t := range m.terrain {
+		if t.Flag != flag {
+			continue
+		}
+		if symbol == "" {
+			m.terrain = append(m.terrain[:i:i], m.terrain[i+1:]...)
+		} else {
+			m.terrain[i].Symbol = symbol
+		}
+
+		return
+	}
+	if symbol != "" {
+		m.terrain = append(m.terrain, Terrain{Flag: flag, Symbol: symbol})
+	}
+}
+
+// Terrain returns the symbols rooms are drawn with.
+func (m *Mapper) Terrain() []Terrain {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]Terrain(nil), m.terrain...)
+}
+
+// Minimap maps the rooms around the room, as far as the radius reaches or
+// the mapper's radius if it isn't positive. It's false if there's no such
+// room.
+func (m *Mapper) Minimap(room string, radius int) (*Map, bool) {
+	if radius < 1 {
+		radius = m.Radius()
+	}
+
+	return Draw(m.world, room, Options{Radius: radius, Terrain: m.Terrain()})
+}
+
+// Area maps every room of the room's zone that can be reached from it. It's
+// false if there's no such room.
+func (m *Mapper) Area(room string) (*Map, bool) {
+	return Draw(m.world, room, Options{Zone: true, Terrain: m.Terrain()})
+}
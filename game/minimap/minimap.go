@@ -0,0 +1,339 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package minimap draws maps of the rooms around a place in the world. Rooms
+// are laid out on a grid by walking the exits between them in the compass
+// directions, so rooms whose exits don't agree with the compass are left
+// off. Maps are drawn as text, one symbol per room joined by lines for their
+// exits, and can be sent to clients as data to draw themselves.
+package minimap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bbuck/dragon-mud/game/grid"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The symbols of rooms on maps, other than their terrain.
+const (
+	// HereSymbol marks the room the map is drawn around.
+	HereSymbol = "@"
+	// RoomSymbol marks rooms without any terrain.
+	RoomSymbol = "#"
+)
+
+// Package is the GMCP package maps are sent to clients as.
+const Package = "Room.Map"
+
+// offsets are where the compass directions lead on the grid
+var offsets = map[world.Direction]grid.Point{
+	world.North:     {X: 0, Y: -1},
+	world.Northeast: {X: 1, Y: -1},
+	world.East:      {X: 1, Y: 0},
+	world.Southeast: {X: 1, Y: 1},
+	world.South:     {X: 0, Y: 1},
+	world.Southwest: {X: -1, Y: 1},
+	world.West:      {X: -1, Y: 0},
+	world.Northwest: {X: -1, Y: -1},
+}
+
+// Terrain is the symbol rooms with a flag are drawn with.
+type Terrain struct {
+	Flag   string
+	Symbol string
+}
+
+// DefaultTerrain is the terrain maps are drawn with unless it's changed,
+// rooms are drawn with the first that matches.
+var DefaultTerrain = []Terrain{
+	{"water", "~"},
+	{"water_noswim", "~"},
+	{"forest", "*"},
+	{"mountain", "^"},
+	{"road", "="},
+}
+
+// ParseTerrain reads terrain like "forest:*", each symbol must be a single
+// character.
+func ParseTerrain(list []string) ([]Terrain, error) {
+	terrain := make([]Terrain, 0, len(list))
+	for _, s := range list {
+		parts := strings.SplitN(s, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("terrain %q must look like \"forest:*\"", s)
+		}
+		symbol := strings.TrimSpace(parts[1])
+		if utf8.RuneCountInString(symbol) != 1 {
+			return nil, fmt.Errorf("terrain %q must have a single character symbol", s)
+		}
+		terrain = append(terrain, Terrain{Flag: strings.ToLower(strings.TrimSpace(parts[0])), Symbol: symbol})
+	}
+
+	return terrain, nil
+}
+
+// Options change how much of the world a map shows.
+type Options struct {
+	// Radius is how many rooms from the center the map reaches, zero is no
+	// limit.
+	Radius int
+	// Zone keeps the map to the rooms of the center's zone.
+	Zone bool
+	// Terrain is the symbols rooms are drawn with, the first that matches
+	// wins.
+	Terrain []Terrain
+}
+
+// Room is a room placed on a map.
+type Room struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Zone string `json:"zone"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	// Terrain is the flag the room is drawn for, empty if it has none.
+	Terrain string `json:"terrain,omitempty"`
+	Symbol  string `json:"symbol"`
+	// Exits are the directions of the room's exits, with the rooms they
+	// lead to.
+	Exits map[world.Direction]string `json:"exits"`
+	// Doors are the directions of closed doors.
+	Doors []world.Direction `json:"doors,omitempty"`
+}
+
+// Map is the rooms around a room laid out on a grid, Y increases to the
+// south.
+type Map struct {
+	// Center is the room the map is drawn around, at 0,0.
+	Center string `json:"center"`
+	Rooms  []Room `json:"rooms"`
+	placed map[grid.Point]int
+}
+
+// Draw maps the rooms around the center. It's false if there's no such
+// room.
+func Draw(w *world.World, center string, opts Options) (*Map, bool) {
+	start, ok := w.Room(center)
+	if !ok {
+		return nil, false
+	}
+
+	m := &Map{Center: center, placed: make(map[grid.Point]int)}
+	seen := map[string]bool{center: true}
+	queue := []grid.Point{{}}
+	m.place(start, grid.Point{}, opts.Terrain)
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		r, _ := w.Room(m.Rooms[m.placed[at]].ID)
+		for _, e := range r.SortedExits(false) {
+			offset, ok := offsets[e.Direction]
+			if !ok || seen[e.To] {
+				continue
+			}
+			p := at.Add(offset)
+			if _, taken := m.placed[p]; taken {
+				continue
+			}
+			if opts.Radius > 0 && grid.Chebyshev(grid.Point{}, p) > opts.Radius {
+				continue
+			}
+			to, ok := w.Room(e.To)
+			if !ok || (opts.Zone && to.Zone != start.Zone) {
+				continue
+			}
+			seen[e.To] = true
+			m.place(to, p, opts.Terrain)
+			queue = append(queue, p)
+		}
+	}
+
+	return m, true
+}
+
+// place puts the room on the map at the point
+func (m *Map) place(r world.Room, p grid.Point, terrain []Terrain) {
+	placed := Room{
+		ID:     r.ID,
+		Name:   r.Name,
+		Zone:   r.Zone,
+		X:      p.X,
+		Y:      p.Y,
+		Symbol: RoomSymbol,
+		Exits:  make(map[world.Direction]string),
+	}
+	for _, t := range terrain {
+		if r.Flag(t.Flag) {
+			placed.Terrain, placed.Symbol = t.Flag, t.Symbol
+
+			break
+		}
+	}
+	for _, e := range r.SortedExits(false) {
+		placed.Exits[e.Direction] = e.To
+		if e.Door && e.Closed {
+			placed.Doors = append(placed.Doors, e.Direction)
+		}
+	}
+	m.placed[p] = len(m.Rooms)
+	m.Rooms = append(m.Rooms, placed)
+}
+
+// At returns the room at the point, if one was placed there.
+func (m *Map) At(x, y int) (Room, bool) {
+	i, ok := m.placed[grid.Pt(x, y)]
+	if !ok {
+		return Room{}, false
+	}
+
+	return m.Rooms[i], true
+}
+
+// Bounds returns the smallest rectangle holding every room on the map.
+func (m *Map) Bounds() grid.Rect {
+	var b grid.Rect
+	for i, r := range m.Rooms {
+		if i == 0 {
+			b = grid.RectAt(r.X, r.Y, 1, 1)
+
+			continue
+		}
+		b.Min.X, b.Min.Y = minInt(b.Min.X, r.X), minInt(b.Min.Y, r.Y)
+		b.Max.X, b.Max.Y = maxInt(b.Max.X, r.X+1), maxInt(b.Max.Y, r.Y+1)
+	}
+
+	return b
+}
+
+// joined is true if the room has an exit in the direction leading to the
+// room placed next to it that way
+func (m *Map) joined(r Room, d world.Direction) bool {
+	to, ok := m.At(r.X+offsets[d].X, r.Y+offsets[d].Y)
+	if !ok {
+		return false
+	}
+	if r.Exits[d] == to.ID {
+		return true
+	}
+
+	return to.Exits[d.Reverse()] == r.ID
+}
+
+// closed is true if there's a closed door between the room and the one next
+// to it in the direction
+func (m *Map) closed(r Room, d world.Direction) bool {
+	to, _ := m.At(r.X+offsets[d].X, r.Y+offsets[d].Y)
+
+	return hasDoor(r.Doors, d) || hasDoor(to.Doors, d.Reverse())
+}
+
+func hasDoor(doors []world.Direction, d world.Direction) bool {
+	for _, door := range doors {
+		if door == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String draws the map as text, the center marked with HereSymbol. Rooms
+// are joined by - and | for their exits, + where a door is closed, and / and
+// \ diagonally, X where diagonals cross.
+func (m *Map) String() string {
+	if len(m.Rooms) == 0 {
+		return ""
+	}
+	b := m.Bounds()
+	width, height := b.Width()*2-1, b.Height()*2-1
+	canvas := make([][]string, height)
+	for y := range canvas {
+		canvas[y] = make([]string, width)
+		for x := range canvas[y] {
+			canvas[y][x] = " "
+		}
+	}
+	set := func(x, y int, s string) {
+		if canvas[y][x] != " " && canvas[y][x] != s {
+			s = "X"
+		}
+		canvas[y][x] = s
+	}
+
+	for _, r := range m.Rooms {
+		x, y := (r.X-b.Min.X)*2, (r.Y-b.Min.Y)*2
+		symbol := r.Symbol
+		if r.ID == m.Center {
+			symbol = HereSymbol
+		}
+		canvas[y][x] = symbol
+		if m.joined(r, world.East) {
+			if m.closed(r, world.East) {
+				set(x+1, y, "+")
+			} else {
+				set(x+1, y, "-")
+			}
+		}
+		if m.joined(r, world.South) {
+			if m.closed(r, world.South) {
+				set(x, y+1, "+")
+			} else {
+				set(x, y+1, "|")
+			}
+		}
+		if m.joined(r, world.Southeast) {
+			set(x+1, y+1, "\\")
+		}
+		if m.joined(r, world.Southwest) {
+			set(x-1, y+1, "/")
+		}
+	}
+
+	lines := make([]string, height)
+	for y, row := range canvas {
+		lines[y] = strings.TrimRight(strings.Join(row, ""), " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Legend describes the symbols of terrain on the map, sorted by flag.
+func (m *Map) Legend() string {
+	symbols := make(map[string]string)
+	for _, r := range m.Rooms {
+		if r.Terrain != "" {
+			symbols[r.Terrain] = r.Symbol
+		}
+	}
+	flags := make([]string, 0, len(symbols))
+	for flag := range symbols {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+
+	parts := []string{HereSymbol + " you"}
+	for _, flag := range flags {
+		parts = append(parts, fmt.Sprintf("%s %s", symbols[flag], strings.Replace(flag, "_", " ", -1)))
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,13 @@
+package minimap_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMinimap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Minimap Suite")
+}
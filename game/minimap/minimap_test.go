@@ -0,0 +1,148 @@
+package minimap_test
+
+import (
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/minimap"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// viewer is a player who remembers what they're told and sent
+type viewer struct {
+	location string
+	sent     []string
+	data     map[string]interface{}
+}
+
+func (v *viewer) ID() string {
+	return "ann"
+}
+
+func (v *viewer) Name() string {
+	return "Ann"
+}
+
+func (v *viewer) Location() string {
+	return v.location
+}
+
+func (v *viewer) Level() command.Level {
+	return command.Player
+}
+
+func (v *viewer) Send(text string) error {
+	v.sent = append(v.sent, text)
+
+	return nil
+}
+
+func (v *viewer) SendData(pkg string, data interface{}) error {
+	v.data[pkg] = data
+
+	return nil
+}
+
+var _ = Describe("Minimap", func() {
+	var (
+		w *world.World
+		m *Mapper
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		Ω(w.AddZone(world.Zone{ID: "wilds", Name: "The Wilds"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "square", Zone: "town", Name: "Town Square"},
+			{ID: "gate", Zone: "town", Name: "The Gate"},
+			{ID: "road", Zone: "town", Name: "The North Road", Flags: map[string]bool{"road": true}},
+			{ID: "shop", Zone: "town", Name: "A Shop"},
+			{ID: "house", Zone: "town", Name: "A House"},
+			{ID: "garden", Zone: "town", Name: "A Garden", Flags: map[string]bool{"forest": true}},
+			{ID: "field", Zone: "wilds", Name: "A Field"},
+			{ID: "cellar", Zone: "town", Name: "A Cellar"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("square", world.North, "gate")).Should(Succeed())
+		Ω(w.Link("gate", world.North, "road")).Should(Succeed())
+		Ω(w.Link("square", world.East, "shop")).Should(Succeed())
+		Ω(w.Link("shop", world.East, "field")).Should(Succeed())
+		Ω(w.Link("square", world.Southeast, "garden")).Should(Succeed())
+		Ω(w.Link("square", world.Down, "cellar")).Should(Succeed())
+		Ω(w.SetExit("square", world.Exit{Direction: world.West, To: "house", Door: true, Closed: true})).Should(Succeed())
+		m = NewMapper(w)
+	})
+
+	It("draws the rooms around a room", func() {
+		drawn, ok := m.Minimap("square", 1)
+		Ω(ok).Should(BeTrue())
+		Ω(drawn.String()).Should(Equal(strings.Join([]string{
+			"  #",
+			"  |",
+			"#+@-#",
+			"   \\",
+			"    *",
+		}, "\n")))
+		Ω(drawn.Legend()).Should(Equal("@ you  * forest"))
+
+		garden, ok := drawn.At(1, 1)
+		Ω(ok).Should(BeTrue())
+		Ω(garden.ID).Should(Equal("garden"))
+		Ω(garden.Terrain).Should(Equal("forest"))
+		_, ok = m.Minimap("nowhere", 1)
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("keeps area maps to the zone", func() {
+		drawn, ok := m.Minimap("square", 2)
+		Ω(ok).Should(BeTrue())
+		Ω(drawn.Rooms).Should(HaveLen(7))
+
+		drawn, ok = m.Area("square")
+		Ω(ok).Should(BeTrue())
+		Ω(drawn.Rooms).Should(HaveLen(6))
+		_, ok = drawn.At(2, 0)
+		Ω(ok).Should(BeFalse())
+		road, _ := drawn.At(0, -2)
+		Ω(road.Symbol).Should(Equal("="))
+	})
+
+	It("changes the terrain symbols", func() {
+		m.SetSymbol("forest", "T")
+		m.SetSymbol("road", "")
+		m.SetSymbol("garden", "g")
+		Ω(m.Terrain()).Should(ContainElement(Terrain{Flag: "forest", Symbol: "T"}))
+		Ω(m.Terrain()).Should(ContainElement(Terrain{Flag: "garden", Symbol: "g"}))
+		Ω(m.Terrain()).ShouldNot(ContainElement(Terrain{Flag: "road", Symbol: "="}))
+
+		terrain, err := ParseTerrain([]string{"Swamp: %", "sand:."})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(terrain).Should(Equal([]Terrain{{Flag: "swamp", Symbol: "%"}, {Flag: "sand", Symbol: "."}}))
+		_, err = ParseTerrain([]string{"swamp:%%"})
+		Ω(err).Should(HaveOccurred())
+		_, err = ParseTerrain([]string{"swamp"})
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("runs the map command", func() {
+		registry := command.NewRegistry()
+		Ω(registry.Register(NewCommand(m, func(c command.Caller) Viewer {
+			return c.(*viewer)
+		}))).Should(Succeed())
+		d := command.NewDispatcher(registry, nil)
+		ann := &viewer{location: "square", data: make(map[string]interface{})}
+
+		d.Dispatch(ann, "map area")
+		Ω(ann.sent).Should(HaveLen(1))
+		Ω(ann.sent[0]).Should(HavePrefix("  ="))
+		Ω(ann.data).Should(HaveKey(Package))
+
+		d.Dispatch(ann, "map world")
+		Ω(ann.sent[1]).Should(ContainSubstring("map area"))
+	})
+})
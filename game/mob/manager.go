@@ -0,0 +1,260 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package mob
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The events of mobs coming and going, each is given the mob's id, proto,
+// name and room. Handlers of SpawnEvent can change the new mob through the
+// mob script module before players meet it, DeathEvent is also given the
+// name of the killer if there was one.
+const (
+	SpawnEvent = "mob:spawned"
+	DeathEvent = "mob:died"
+)
+
+var (
+	// ErrNoProto is returned when spawning a mob without an NPC definition.
+	ErrNoProto = errors.New("no such NPC")
+
+	// ErrNoRoom is returned when spawning a mob in a room that doesn't exist.
+	ErrNoRoom = errors.New("no such room")
+)
+
+// Manager keeps track of the mobs in the game.
+type Manager struct {
+	world   *world.World
+	floor   *item.Floor
+	mobs    map[string]*Mob
+	scripts map[string]ResetScript
+	corpse  func(mob *Mob, items item.List)
+	seq     uint64
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager spawning the NPCs of the world, leaving what
+// mobs carry on the floor when they die and emitting events with the
+// emitter, which may be nil.
+func NewManager(w *world.World, f *item.Floor, em *events.Emitter) *Manager {
+	return &Manager{
+		world:   w,
+		floor:   f,
+		mobs:    make(map[string]*Mob),
+		scripts: make(map[string]ResetScript),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's mob manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), item.GlobalFloor(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetCorpse sets what's left of mobs that die, given what they carried and
+// wore. Without it their things are left on the floor.
+func (m *Manager) SetCorpse(fn func(mob *Mob, items item.List)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.corpse = fn
+}
+
+// Spawn creates a mob from the NPC definition with the id in the room.
+func (m *Manager) Spawn(proto, room string) (*Mob, error) {
+	return m.spawn(proto, room, nil)
+}
+
+// spawn creates the mob, outfitting it before it's in the game
+func (m *Manager) spawn(proto, room string, outfit func(*Mob) error) (*Mob, error) {
+	def, ok := m.world.NPC(proto)
+	if !ok {
+		return nil, ErrNoProto
+	}
+	if _, ok := m.world.Room(room); !ok {
+		return nil, ErrNoRoom
+	}
+
+	mob := New(def)
+	mob.SetLocation(room)
+	if outfit != nil {
+		if err := outfit(mob); err != nil {
+			return nil, err
+		}
+	}
+	m.add(mob)
+	m.emit(SpawnEvent, mobData(mob))
+
+	return mob, nil
+}
+
+// add tracks the mob, ordering it after every mob already tracked
+func (m *Manager) add(mob *Mob) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.seq++
+	mob.seq = m.seq
+	m.mobs[mob.ID()] = mob
+}
+
+// Get returns the mob with the id, nil if there isn't one.
+func (m *Manager) Get(id string) *Mob {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.mobs[id]
+}
+
+// All returns every mob in the game, in the order they spawned.
+func (m *Manager) All() []*Mob {
+	return m.filter(func(*Mob) bool {
+		return true
+	})
+}
+
+// In returns the mobs in the room, in the order they spawned.
+func (m *Manager) In(room string) []*Mob {
+	return m.filter(func(mob *Mob) bool {
+		return mob.Location() == room
+	})
+}
+
+// Find returns the mob in the room the keyword refers to, like "guard" or
+// "2.guard" for the second, nil if there isn't one.
+func (m *Manager) Find(room, keyword string) *Mob {
+	n, keyword := item.Ordinal(keyword)
+	for _, mob := range m.In(room) {
+		if mob.Matches(keyword) {
+			n--
+			if n == 0 {
+				return mob
+			}
+		}
+	}
+
+	return nil
+}
+
+// Count returns how many mobs made from the NPC definition are in the game.
+func (m *Manager) Count(proto string) int {
+	return len(m.filter(func(mob *Mob) bool {
+		return mob.Proto() == proto
+	}))
+}
+
+// Population returns how many mobs of the zone's NPCs are in the game.
+func (m *Manager) Population(zone string) int {
+	return len(m.filter(func(mob *Mob) bool {
+		return mob.Zone() == zone
+	}))
+}
+
+// Kill removes the mob from the game, leaving what it carried and wore in
+// its corpse, or on the floor of its room without one. Killer names who
+// killed it and may be empty. It returns false if there's no such mob.
+func (m *Manager) Kill(id, killer string) bool {
+	m.mutex.Lock()
+	mob, ok := m.mobs[id]
+	delete(m.mobs, id)
+	corpse := m.corpse
+	m.mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+	room := mob.Location()
+	items := mob.strip()
+	if corpse != nil {
+		corpse(mob, items)
+	} else {
+		for _, it := range items {
+			m.floor.Add(room, it)
+		}
+	}
+	data := mobData(mob)
+	data["killer"] = killer
+	m.emit(DeathEvent, data)
+
+	return true
+}
+
+// Remove takes the mob and everything it has out of the game without it
+// dying, like when the place it's in goes away. It returns false if there's
+// no such mob.
+func (m *Manager) Remove(id string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.mobs[id]
+	delete(m.mobs, id)
+
+	return ok
+}
+
+// filter returns the mobs fn is true for, in the order they spawned
+func (m *Manager) filter(fn func(*Mob) bool) []*Mob {
+	m.mutex.RLock()
+	var mobs []*Mob
+	for _, mob := range m.mobs {
+		mobs = append(mobs, mob)
+	}
+	m.mutex.RUnlock()
+
+	var matched []*Mob
+	for _, mob := range mobs {
+		if fn(mob) {
+			matched = append(matched, mob)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].seq < matched[j].seq
+	})
+
+	return matched
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+func mobData(mob *Mob) events.Data {
+	return events.Data{
+		"mob":   mob.ID(),
+		"proto": mob.Proto(),
+		"name":  mob.Name(),
+		"room":  mob.Location(),
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package mob brings the NPCs defined in the world to life. Mobs are made
+// from their definitions by zone resets, which the scheduler runs for each
+// zone, or by scripts. Each mob carries and wears items like players do.
+package mob
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Mob is an NPC in the game.
+type Mob struct {
+	id          string
+	proto       string
+	zone        string
+	name        string
+	keywords    []string
+	description string
+	level       int
+	stats       map[string]int
+	flags       map[string]bool
+	props       map[string]interface{}
+	room        string
+	inventory   item.List
+	equipment   map[string]item.Item
+	effects     effect.List
+	seq         uint64
+	mutex       *sync.RWMutex
+}
+
+// New creates a mob from its definition, it isn't in any room until it's
+// given a location.
+func New(def world.NPCDef) *Mob {
+	m := &Mob{
+		id:          fmt.Sprintf("%s-%s", def.ID, uuid.NewV1().String()),
+		proto:       def.ID,
+		zone:        def.Zone,
+		name:        def.Name,
+		keywords:    append([]string(nil), def.Keywords...),
+		description: def.Description,
+		level:       def.Level,
+		stats:       make(map[string]int, len(def.Stats)),
+		flags:       make(map[string]bool, len(def.Flags)),
+		props:       make(map[string]interface{}, len(def.Props)),
+		equipment:   make(map[string]item.Item),
+		mutex:       new(sync.RWMutex),
+	}
+	for stat, n := range def.Stats {
+		m.stats[stat] = n
+	}
+	for _, f := range def.Flags {
+		m.flags[strings.ToLower(f)] = true
+	}
+	for k, v := range def.Props {
+		m.props[k] = v
+	}
+
+	return m
+}
+
+// ID returns the id of this mob, unique among all mobs.
+func (m *Mob) ID() string {
+	return m.id
+}
+
+// Proto returns the id of the NPC definition the mob was made from.
+func (m *Mob) Proto() string {
+	return m.proto
+}
+
+// Zone returns the zone the mob's definition belongs to.
+func (m *Mob) Zone() string {
+	return m.zone
+}
+
+// Name returns the name of the mob, like "a town guard".
+func (m *Mob) Name() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.name
+}
+
+// SetName changes the name of the mob.
+func (m *Mob) SetName(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.name = name
+}
+
+// Description returns what players see when they look at the mob.
+func (m *Mob) Description() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.description
+}
+
+// SetDescription changes the description of the mob.
+func (m *Mob) SetDescription(desc string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.description = desc
+}
+
+// Level returns the level of the mob.
+func (m *Mob) Level() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.level
+}
+
+// SetLevel changes the level of the mob.
+func (m *Mob) SetLevel(level int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.level = level
+}
+
+// Matches is true if the keyword is one of the mob's keywords or the start
+// of a word in its name.
+func (m *Mob) Matches(keyword string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keyword = strings.ToLower(keyword)
+	if keyword == "" {
+		return false
+	}
+	for _, k := range m.keywords {
+		if strings.ToLower(k) == keyword {
+			return true
+		}
+	}
+	for _, word := range strings.Fields(strings.ToLower(m.name)) {
+		if strings.HasPrefix(word, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Race returns the race of the mob from its "race" prop, used to find its
+// body.
+func (m *Mob) Race() string {
+	race, _ := m.Prop("race").(string)
+
+	return race
+}
+
+// Stat returns the value of the stat, zero if the mob doesn't have it.
+func (m *Mob) Stat(name string) int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.stats[name]
+}
+
+// Stats returns a copy of the mob's stats.
+func (m *Mob) Stats() map[string]int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := make(map[string]int, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+
+	return stats
+}
+
+// SetStat changes the stat.
+func (m *Mob) SetStat(name string, value int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats[name] = value
+}
+
+// AddStat adds delta to the stat, returning its new value.
+func (m *Mob) AddStat(name string, delta int) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats[name] += delta
+
+	return m.stats[name]
+}
+
+// Flag is true if the flag is set.
+func (m *Mob) Flag(name string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.flags[strings.ToLower(name)]
+}
+
+// SetFlag sets or clears the flag.
+func (m *Mob) SetFlag(name string, on bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if on {
+		m.flags[strings.ToLower(name)] = true
+	} else {
+		delete(m.flags, strings.ToLower(name))
+	}
+}
+
+// Flags returns the names of the flags that are set, sorted.
+func (m *Mob) Flags() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	flags := make([]string, 0, len(m.flags))
+	for f := range m.flags {
+		flags = append(flags, f)
+	}
+	sort.Strings(flags)
+
+	return flags
+}
+
+// Props returns a copy of the mob's props.
+func (m *Mob) Props() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	props := make(map[string]interface{}, len(m.props))
+	for k, v := range m.props {
+		props[k] = v
+	}
+
+	return props
+}
+
+// Prop returns the prop, nil if it isn't set.
+func (m *Mob) Prop(key string) interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.props[key]
+}
+
+// SetProp sets the prop, nil removes it.
+func (m *Mob) SetProp(key string, value interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if value == nil {
+		delete(m.props, key)
+	} else {
+		m.props[key] = value
+	}
+}
+
+// Location returns the id of the room the mob is in.
+func (m *Mob) Location() string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.room
+}
+
+// SetLocation moves the mob into the room.
+func (m *Mob) SetLocation(room string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.room = room
+}
+
+// Inventory returns the items the mob carries.
+func (m *Mob) Inventory() item.List {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.inventory.Copy()
+}
+
+// UpdateInventory changes the items the mob carries with fn, nothing changes
+// if it fails.
+func (m *Mob) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	items, err := fn(m.inventory.Copy())
+	if err != nil {
+		return err
+	}
+	m.inventory = items
+
+	return nil
+}
+
+// Equipment returns the items the mob wears, by slot.
+func (m *Mob) Equipment() map[string]item.Item {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return copyEquipment(m.equipment)
+}
+
+// UpdateEquipment changes the items the mob carries and wears with fn
+// together, nothing changes if it fails.
+func (m *Mob) UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	items, eq, err := fn(m.inventory.Copy(), copyEquipment(m.equipment))
+	if err != nil {
+		return err
+	}
+	m.inventory, m.equipment = items, eq
+
+	return nil
+}
+
+// Effects returns the buffs and debuffs on the mob.
+func (m *Mob) Effects() effect.List {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.effects.Copy()
+}
+
+// UpdateEffects changes the mob's effects with fn.
+func (m *Mob) UpdateEffects(fn func(effect.List) effect.List) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.effects = fn(m.effects.Copy())
+}
+
+// strip takes everything the mob carries and wears from it
+func (m *Mob) strip() item.List {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	items := m.inventory
+	slots := make([]string, 0, len(m.equipment))
+	for slot := range m.equipment {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	for _, slot := range slots {
+		items = items.Add(m.equipment[slot])
+	}
+	m.inventory, m.equipment = nil, make(map[string]item.Item)
+
+	return items
+}
+
+func copyEquipment(eq map[string]item.Item) map[string]item.Item {
+	worn := make(map[string]item.Item, len(eq))
+	for slot, it := range eq {
+		worn[slot] = it.Copy()
+	}
+
+	return worn
+}
@@ -0,0 +1,13 @@
+package mob_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMob(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mob Suite")
+}
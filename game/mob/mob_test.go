@@ -0,0 +1,242 @@
+package mob_test
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/sched"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var guardDef = world.NPCDef{
+	ID:       "guard",
+	Zone:     "town",
+	Name:     "a town guard",
+	Keywords: []string{"guard"},
+	Level:    3,
+	Stats:    map[string]int{"hp": 20},
+	Flags:    []string{"Sentinel"},
+	Props:    map[string]interface{}{"race": "human"},
+}
+
+var _ = Describe("Mob", func() {
+	It("is made from its definition", func() {
+		guard := New(guardDef)
+
+		Ω(guard.ID()).Should(HavePrefix("guard-"))
+		Ω(guard.ID()).ShouldNot(Equal(New(guardDef).ID()))
+		Ω(guard.Proto()).Should(Equal("guard"))
+		Ω(guard.Zone()).Should(Equal("town"))
+		Ω(guard.Race()).Should(Equal("human"))
+		Ω(guard.Flag("sentinel")).Should(BeTrue())
+		Ω(guard.Matches("guard")).Should(BeTrue())
+		Ω(guard.Matches("tow")).Should(BeTrue())
+		Ω(guard.Matches("bandit")).Should(BeFalse())
+
+		guard.AddStat("hp", -5)
+		Ω(guard.Stat("hp")).Should(Equal(15))
+		Ω(guardDef.Stats["hp"]).Should(Equal(20))
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		w     *world.World
+		floor *item.Floor
+		em    *events.Emitter
+		m     *Manager
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town", Population: 3})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "gate", Zone: "town", Name: "The Gate"})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "square", Zone: "town", Name: "The Square"})).Should(Succeed())
+		Ω(w.SetExit("gate", world.Exit{Direction: world.South, To: "square", Door: true})).Should(Succeed())
+		Ω(w.SetNPC(guardDef)).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "rat", Zone: "town", Name: "a rat"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "spear", Zone: "town", Name: "a spear", Props: map[string]interface{}{"applies": map[string]int{"hit": 2}}})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "torch", Zone: "town", Name: "a torch"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "chest", Zone: "town", Name: "a chest", Type: "container"})).Should(Succeed())
+		floor = item.NewFloor()
+		em = events.NewEmitter(nil)
+		m = NewManager(w, floor, em)
+	})
+
+	It("spawns mobs and finds them", func() {
+		first, err := m.Spawn("guard", "gate")
+		Ω(err).ShouldNot(HaveOccurred())
+		second, _ := m.Spawn("guard", "gate")
+		m.Spawn("rat", "square")
+
+		Ω(m.In("gate")).Should(Equal([]*Mob{first, second}))
+		Ω(m.Find("gate", "guard")).Should(Equal(first))
+		Ω(m.Find("gate", "2.guard")).Should(Equal(second))
+		Ω(m.Find("gate", "rat")).Should(BeNil())
+		Ω(m.Count("guard")).Should(Equal(2))
+		Ω(m.Population("town")).Should(Equal(3))
+		Ω(m.Get(second.ID())).Should(Equal(second))
+
+		_, err = m.Spawn("dragon", "gate")
+		Ω(err).Should(Equal(ErrNoProto))
+		_, err = m.Spawn("rat", "nowhere")
+		Ω(err).Should(Equal(ErrNoRoom))
+	})
+
+	It("leaves what dead mobs had on the floor", func(done Done) {
+		died := make(chan events.Data, 1)
+		em.On(DeathEvent, events.HandlerFunc(func(d events.Data) error {
+			died <- d
+
+			return nil
+		}))
+		guard, _ := m.Spawn("guard", "gate")
+		torch, _ := item.Create(w, "torch", 1)
+		guard.UpdateInventory(func(item.List) (item.List, error) {
+			return item.List{torch}, nil
+		})
+
+		Ω(m.Kill(guard.ID(), "Alice")).Should(BeTrue())
+		Ω(m.Kill(guard.ID(), "Alice")).Should(BeFalse())
+		Ω(m.In("gate")).Should(BeEmpty())
+		Ω(floor.In("gate")).Should(HaveLen(1))
+
+		d := <-died
+		Ω(d["proto"]).Should(Equal("guard"))
+		Ω(d["killer"]).Should(Equal("Alice"))
+		close(done)
+	})
+
+	It("removes mobs without them dying", func() {
+		guard, _ := m.Spawn("guard", "gate")
+
+		Ω(m.Remove(guard.ID())).Should(BeTrue())
+		Ω(m.Remove(guard.ID())).Should(BeFalse())
+		Ω(m.In("gate")).Should(BeEmpty())
+		Ω(floor.In("gate")).Should(BeEmpty())
+	})
+
+	It("restores mobs from a snapshot", func() {
+		guard, _ := m.Spawn("guard", "gate")
+		guard.SetStat("hp", 3)
+		guard.SetFlag("angry", true)
+		snap := m.Snapshot()
+
+		Ω(m.Kill(guard.ID(), "")).Should(BeTrue())
+		m.Spawn("rat", "square")
+		replaced := m.Restore(snap)
+
+		Ω(replaced).Should(HaveLen(1))
+		Ω(m.All()).Should(HaveLen(1))
+		restored := m.Get(guard.ID())
+		Ω(restored).ShouldNot(BeNil())
+		Ω(restored.State()).Should(Equal(snap[0]))
+		Ω(restored.Flag("angry")).Should(BeTrue())
+		Ω(m.In("gate")).Should(Equal([]*Mob{restored}))
+	})
+
+	It("emits an event for new mobs", func(done Done) {
+		spawned := make(chan events.Data, 1)
+		em.On(SpawnEvent, events.HandlerFunc(func(d events.Data) error {
+			spawned <- d
+
+			return nil
+		}))
+		rat, _ := m.Spawn("rat", "square")
+
+		d := <-spawned
+		Ω(d["mob"]).Should(Equal(rat.ID()))
+		Ω(d["room"]).Should(Equal("square"))
+		close(done)
+	})
+
+	Describe("resets", func() {
+		BeforeEach(func() {
+			Ω(w.SetResets("town", []world.Reset{
+				{NPC: "guard", Room: "gate", Max: 1, Give: []string{"torch"}, Equip: map[string]string{"wield": "spear"}},
+				{NPC: "rat", Room: "square"},
+				{Item: "chest", Room: "square", Put: []string{"torch"}},
+				{Exit: world.South, Room: "gate", Door: world.DoorLocked},
+			})).Should(Succeed())
+		})
+
+		It("puts the zone back as it was", func() {
+			Ω(m.ResetZone("town")).Should(Succeed())
+
+			guards := m.In("gate")
+			Ω(guards).Should(HaveLen(1))
+			Ω(guards[0].Inventory()[0].Proto).Should(Equal("torch"))
+			Ω(guards[0].Equipment()["wield"].Proto).Should(Equal("spear"))
+			Ω(guards[0].Stat("hit")).Should(Equal(2))
+			chest, _ := floor.In("square").Find("chest")
+			Ω(chest.Contents).Should(HaveLen(1))
+			e, _ := w.Exit("gate", world.South)
+			Ω(e.Locked).Should(BeTrue())
+		})
+
+		It("keeps to the population limits", func() {
+			for i := 0; i < 3; i++ {
+				Ω(m.ResetZone("town")).Should(Succeed())
+			}
+
+			Ω(m.Count("guard")).Should(Equal(1))
+			Ω(m.Count("rat")).Should(Equal(2))
+			Ω(floor.In("square")).Should(HaveLen(1))
+		})
+
+		It("runs the resets it can", func() {
+			Ω(w.SetResets("town", []world.Reset{
+				{NPC: "ghost", Room: "gate"},
+				{NPC: "rat", Room: "square"},
+			})).Should(Succeed())
+
+			Ω(m.ResetZone("town")).Should(MatchError("zone town reset 1: no such NPC"))
+			Ω(m.Count("rat")).Should(Equal(1))
+			Ω(m.ResetZone("nowhere")).Should(Equal(world.ErrNoZone))
+		})
+
+		It("runs reset scripts", func() {
+			var ran []string
+			m.HandleReset("relight", func(zone, room string) error {
+				ran = append(ran, zone+":"+room)
+
+				return nil
+			})
+			reset := make(chan events.Data, 1)
+			em.On(ResetEvent, events.HandlerFunc(func(d events.Data) error {
+				reset <- d
+
+				return nil
+			}))
+			Ω(w.SetResets("town", []world.Reset{
+				{Script: "sweep"},
+				{Script: "relight", Room: "square"},
+				{Script: "relight"},
+			})).Should(Succeed())
+
+			Ω(m.ResetZone("town")).Should(MatchError("zone town reset 1: no reset script \"sweep\""))
+			Ω(ran).Should(Equal([]string{"town:square", "town:"}))
+			Eventually(reset).Should(Receive(HaveKeyWithValue("zone", "town")))
+		})
+
+		It("schedules zone resets", func() {
+			s := sched.New(nil)
+			Ω(m.Schedule(s, "@every 15m")).Should(Succeed())
+
+			jobs := s.Jobs()
+			Ω(jobs).Should(HaveLen(1))
+			Ω(jobs[0].Name).Should(Equal(ResetJob("town")))
+			Ω(jobs[0].Spec).Should(Equal("@every 15m"))
+
+			s.RunDue(time.Now().Add(time.Hour))
+			Eventually(func() int {
+				return m.Count("rat")
+			}).Should(Equal(1))
+		})
+	})
+})
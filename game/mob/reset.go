@@ -0,0 +1,195 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package mob
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/equipment"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/sched"
+)
+
+// ResetEvent is emitted once a zone's resets have run, given the zone.
+const ResetEvent = "zone:reset"
+
+// ResetScript does what a script reset names, given the zone resetting and
+// the reset's room, which may be empty.
+type ResetScript func(zone, room string) error
+
+// HandleReset sets what the script resets with the name do, replacing what
+// they did.
+func (m *Manager) HandleReset(name string, fn ResetScript) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.scripts[name] = fn
+}
+
+// ResetZone runs the resets of the zone in order, spawning the NPCs and
+// items that are missing, leaving doors on both sides as the resets say and
+// running the reset scripts. NPCs aren't spawned past the reset's Max or the
+// zone's Population, items aren't left in a room already holding one. Every
+// reset that can be run is, the first that fails is returned.
+func (m *Manager) ResetZone(zone string) error {
+	z, ok := m.world.Zone(zone)
+	if !ok {
+		return world.ErrNoZone
+	}
+
+	var first error
+	for i, r := range m.world.Resets(zone) {
+		var err error
+		switch {
+		case r.NPC != "":
+			err = m.resetNPC(z, r)
+		case r.Item != "":
+			err = m.resetItem(r)
+		case r.Exit != "":
+			err = m.world.SetDoor(r.Room, r.Exit, r.Door != world.DoorOpen, r.Door == world.DoorLocked)
+		case r.Script != "":
+			err = m.resetScript(zone, r)
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("zone %s reset %d: %s", zone, i+1, err)
+		}
+	}
+	m.emit(ResetEvent, events.Data{"zone": zone})
+
+	return first
+}
+
+// ResetAll resets every zone in the world, returning the first failure.
+func (m *Manager) ResetAll() error {
+	var first error
+	for _, z := range m.world.Zones() {
+		if err := m.ResetZone(z.ID); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Schedule has the scheduler reset each zone on its own schedule, or every
+// spec for zones without one. Zones already scheduled are rescheduled.
+func (m *Manager) Schedule(s *sched.Scheduler, spec string) error {
+	log := logger.NewWithSource("mob")
+	for _, z := range m.world.Zones() {
+		zoneSpec := z.Reset
+		if zoneSpec == "" {
+			zoneSpec = spec
+		}
+		zone := z.ID
+		_, err := s.Add(ResetJob(zone), zoneSpec, func() {
+			if err := m.ResetZone(zone); err != nil {
+				log.WithError(err).WithField("zone", zone).Warn("Zone reset failed.")
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("zone %s: %s", zone, err)
+		}
+	}
+
+	return nil
+}
+
+// ResetJob returns the name of the scheduler job resetting the zone.
+func ResetJob(zone string) string {
+	return "reset:" + zone
+}
+
+func (m *Manager) resetNPC(z world.Zone, r world.Reset) error {
+	if r.Max > 0 && m.Count(r.NPC) >= r.Max {
+		return nil
+	}
+	if z.Population > 0 && m.Population(z.ID) >= z.Population {
+		return nil
+	}
+
+	_, err := m.spawn(r.NPC, r.Room, func(mob *Mob) error {
+		var items item.List
+		for _, proto := range r.Give {
+			it, err := item.Create(m.world, proto, 1)
+			if err != nil {
+				return fmt.Errorf("%s: %s", proto, err)
+			}
+			items = items.Add(it)
+		}
+		eq := make(map[string]item.Item, len(r.Equip))
+		for slot, proto := range r.Equip {
+			it, err := item.Create(m.world, proto, 1)
+			if err != nil {
+				return fmt.Errorf("%s: %s", proto, err)
+			}
+			eq[slot] = it
+		}
+
+		for _, it := range eq {
+			for stat, n := range equipment.Applies(it) {
+				mob.AddStat(stat, n)
+			}
+		}
+
+		return mob.UpdateEquipment(func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error) {
+			return items, eq, nil
+		})
+	})
+
+	return err
+}
+
+func (m *Manager) resetItem(r world.Reset) error {
+	for _, it := range m.floor.In(r.Room) {
+		if it.Proto == r.Item {
+			return nil
+		}
+	}
+	if r.Max > 0 && m.floorCount(r.Item) >= r.Max {
+		return nil
+	}
+
+	it, err := item.Create(m.world, r.Item, 1)
+	if err != nil {
+		return err
+	}
+	for _, proto := range r.Put {
+		in, err := item.Create(m.world, proto, 1)
+		if err != nil {
+			return fmt.Errorf("%s: %s", proto, err)
+		}
+		it.Contents = it.Contents.Add(in)
+	}
+	m.floor.Add(r.Room, it)
+
+	return nil
+}
+
+func (m *Manager) resetScript(zone string, r world.Reset) error {
+	m.mutex.RLock()
+	fn, ok := m.scripts[r.Script]
+	m.mutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("no reset script %q", r.Script)
+	}
+
+	return fn(zone, r.Room)
+}
+
+// floorCount returns how many items made from the proto lie on floors
+func (m *Manager) floorCount(proto string) int {
+	count := 0
+	for _, room := range m.floor.Rooms() {
+		for _, it := range m.floor.In(room) {
+			if it.Proto == proto {
+				count++
+			}
+		}
+	}
+
+	return count
+}
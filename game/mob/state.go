@@ -0,0 +1,132 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package mob
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// State is everything about a mob, so the mobs in the game can be put back
+// as they were. It can be encoded as JSON.
+type State struct {
+	ID          string                 `json:"id"`
+	Proto       string                 `json:"proto"`
+	Zone        string                 `json:"zone"`
+	Name        string                 `json:"name"`
+	Keywords    []string               `json:"keywords,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Level       int                    `json:"level,omitempty"`
+	Stats       map[string]int         `json:"stats,omitempty"`
+	Flags       []string               `json:"flags,omitempty"`
+	Props       map[string]interface{} `json:"props,omitempty"`
+	Room        string                 `json:"room"`
+	Inventory   item.List              `json:"inventory,omitempty"`
+	Equipment   map[string]item.Item   `json:"equipment,omitempty"`
+	Effects     effect.List            `json:"effects,omitempty"`
+}
+
+// State returns a copy of everything about the mob.
+func (m *Mob) State() State {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	s := State{
+		ID:          m.id,
+		Proto:       m.proto,
+		Zone:        m.zone,
+		Name:        m.name,
+		Keywords:    append([]string(nil), m.keywords...),
+		Description: m.description,
+		Level:       m.level,
+		Stats:       make(map[string]int, len(m.stats)),
+		Props:       make(map[string]interface{}, len(m.props)),
+		Room:        m.room,
+		Inventory:   m.inventory.Copy(),
+		Equipment:   copyEquipment(m.equipment),
+		Effects:     m.effects.Copy(),
+	}
+	for stat, n := range m.stats {
+		s.Stats[stat] = n
+	}
+	for flag, on := range m.flags {
+		if on {
+			s.Flags = append(s.Flags, flag)
+		}
+	}
+	sort.Strings(s.Flags)
+	for k, v := range m.props {
+		s.Props[k] = v
+	}
+
+	return s
+}
+
+// FromState creates the mob the state describes, with its id.
+func FromState(s State) *Mob {
+	m := &Mob{
+		id:          s.ID,
+		proto:       s.Proto,
+		zone:        s.Zone,
+		name:        s.Name,
+		keywords:    append([]string(nil), s.Keywords...),
+		description: s.Description,
+		level:       s.Level,
+		stats:       make(map[string]int, len(s.Stats)),
+		flags:       make(map[string]bool, len(s.Flags)),
+		props:       make(map[string]interface{}, len(s.Props)),
+		room:        s.Room,
+		inventory:   s.Inventory.Copy(),
+		equipment:   copyEquipment(s.Equipment),
+		effects:     s.Effects.Copy(),
+		mutex:       new(sync.RWMutex),
+	}
+	for stat, n := range s.Stats {
+		m.stats[stat] = n
+	}
+	for _, f := range s.Flags {
+		m.flags[strings.ToLower(f)] = true
+	}
+	for k, v := range s.Props {
+		m.props[k] = v
+	}
+
+	return m
+}
+
+// Snapshot returns the state of every mob in the game, in the order they
+// spawned.
+func (m *Manager) Snapshot() []State {
+	mobs := m.All()
+	states := make([]State, len(mobs))
+	for i, mob := range mobs {
+		states[i] = mob.State()
+	}
+
+	return states
+}
+
+// Restore replaces every mob in the game with the mobs the states describe,
+// in their order, like when the game is rolled back. Mobs are replaced
+// without dying or spawning, no events are emitted. The mobs replaced are
+// returned.
+func (m *Manager) Restore(states []State) []*Mob {
+	replaced := m.All()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.mobs = make(map[string]*Mob, len(states))
+	for _, s := range states {
+		mob := FromState(s)
+		m.seq++
+		mob.seq = m.seq
+		m.mobs[mob.id] = mob
+	}
+
+	return replaced
+}
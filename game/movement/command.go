@@ -0,0 +1,107 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package movement
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// NewCommands creates the commands for moving with m, one for each standard
+// direction along with "go" for any direction and "follow".
+func NewCommands(m *Movement, resolve command.Resolver[Mover]) []*command.Command {
+	var cmds []*command.Command
+	for _, d := range world.Directions {
+		d := d
+		cmds = append(cmds, &command.Command{
+			Name:    string(d),
+			Aliases: []string{d.Abbreviation()},
+			Help:    fmt.Sprintf("Moves you %s.", d),
+			Source:  "game",
+			Handler: func(ctx *command.Context) error {
+				return move(ctx, m, resolve, d)
+			},
+		})
+	}
+
+	cmds = append(cmds, &command.Command{
+		Name:   "go",
+		Args:   []command.Arg{{Name: "direction", Kind: command.Word}},
+		Help:   "Moves you through the exit in the direction, including special exits like portals.",
+		Source: "game",
+		Handler: func(ctx *command.Context) error {
+			return move(ctx, m, resolve, world.ParseDirection(ctx.String("direction")))
+		},
+	}, &command.Command{
+		Name:      "follow",
+		MinAbbrev: 3,
+		Args:      []command.Arg{{Name: "name", Kind: command.Word, Optional: true}},
+		Help:      "Follows someone in the room wherever they go. Follow yourself, or no one, to stop following.",
+		Source:    "game",
+		Handler: func(ctx *command.Context) error {
+			return follow(ctx, m, resolve)
+		},
+	})
+
+	return cmds
+}
+
+func move(ctx *command.Context, m *Movement, resolve command.Resolver[Mover], d world.Direction) error {
+	mover := resolve(ctx.Caller)
+	if mover == nil {
+		return ctx.Send(NowhereMessage)
+	}
+
+	err := m.Move(mover, d)
+	if b, ok := err.(*Blocked); ok {
+		return ctx.Send(b.Message)
+	}
+
+	return err
+}
+
+func follow(ctx *command.Context, m *Movement, resolve command.Resolver[Mover]) error {
+	mover := resolve(ctx.Caller)
+	if mover == nil {
+		return ctx.Send(NowhereMessage)
+	}
+
+	name := strings.ToLower(ctx.String("name"))
+	if name == "" || name == "self" || name == key(mover) {
+		if m.Leader(mover) == "" {
+			return ctx.Send("You aren't following anyone.")
+		}
+		m.Unfollow(mover)
+
+		return ctx.Send("You stop following.")
+	}
+
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	var leader Mover
+	if occupants != nil {
+		for _, other := range occupants(mover.Location()) {
+			if strings.HasPrefix(key(other), name) && key(other) != key(mover) {
+				leader = other
+
+				break
+			}
+		}
+	}
+	if leader == nil {
+		return ctx.Send("They aren't here.")
+	}
+	if err := m.Follow(mover, leader); err != nil {
+		return ctx.Send(err.Error())
+	}
+	if s, ok := leader.(Sender); ok {
+		s.Send(fmt.Sprintf("%s now follows you.", mover.Name()))
+	}
+
+	return ctx.Send(fmt.Sprintf("You now follow %s.", leader.Name()))
+}
@@ -0,0 +1,448 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package movement moves players and NPCs through the exits of the world.
+// Before anyone moves the room:exit and room:enter events are checked so
+// handlers, like Lua triggers, can stop them, and once they've moved the
+// events are emitted and anyone following along comes too.
+package movement
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The events of moving between rooms. Handlers of before:room:exit and
+// before:room:enter can stop the move by returning events.ErrHalt, or an
+// error whose message is told to the mover. Both are given the mover's name,
+// the rooms it moves from and to and the direction.
+const (
+	ExitEvent  = "room:exit"
+	EnterEvent = "room:enter"
+)
+
+// TeleportEvent is emitted when a mover is put in a room without taking an
+// exit, given the mover's name and the rooms it moved from and to.
+const TeleportEvent = "room:teleport"
+
+// Messages told to movers that can't go somewhere.
+const (
+	NoExitMessage   = "You can't go that way."
+	ClosedMessage   = "The door is closed."
+	TooBigMessage   = "You're too big to fit through."
+	CancelMessage   = "You can't go that way right now."
+	NowhereMessage  = "You aren't anywhere."
+	FollowedMessage = "You follow %s %s."
+)
+
+// Mover is anything that moves between rooms, like a player.
+type Mover interface {
+	Name() string
+	Location() string
+	SetLocation(room string)
+}
+
+// Sender is a mover that can be told what happens to it.
+type Sender interface {
+	Send(text string) error
+}
+
+// Sized is a mover with a size, checked against the size of exits.
+type Sized interface {
+	Size() int
+}
+
+// Flagged is a mover with flags, checked against the terrain of rooms.
+type Flagged interface {
+	Flag(name string) bool
+}
+
+// Blocked is returned when a mover can't go somewhere, its message tells
+// them why.
+type Blocked struct {
+	Message string
+}
+
+func (b *Blocked) Error() string {
+	return b.Message
+}
+
+func blocked(format string, args ...interface{}) *Blocked {
+	return &Blocked{Message: fmt.Sprintf(format, args...)}
+}
+
+// Terrain limits who can enter rooms with a flag, like "air" rooms only those
+// who fly can enter.
+type Terrain struct {
+	// Requires is the flag movers need to enter.
+	Requires string
+	// Message tells movers without it why they can't.
+	Message string
+}
+
+// DefaultTerrain is the terrain a Movement starts with, keyed by the room flag
+// it applies to.
+var DefaultTerrain = map[string]Terrain{
+	"air":          {Requires: "fly", Message: "You'd need to fly to go there."},
+	"water_noswim": {Requires: "swim", Message: "You'd need to swim to go there."},
+}
+
+// Movement moves movers through the world and keeps track of who follows
+// whom.
+type Movement struct {
+	world     *world.World
+	emitter   *events.Emitter
+	occupants func(room string) []Mover
+	router    func(mover Mover, room string) (string, error)
+	describe  func(mover Mover, r world.Room) string
+	terrain   map[string]Terrain
+	// leaders holds who each mover follows, by lower case name
+	leaders map[string]string
+	mutex   *sync.RWMutex
+}
+
+// New creates a Movement for the world, checking and emitting events with the
+// emitter, which may be nil.
+func New(w *world.World, em *events.Emitter) *Movement {
+	terrain := make(map[string]Terrain, len(DefaultTerrain))
+	for flag, t := range DefaultTerrain {
+		terrain[flag] = t
+	}
+
+	return &Movement{
+		world:   w,
+		emitter: em,
+		terrain: terrain,
+		leaders: make(map[string]string),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalMovement *Movement
+	globalOnce     sync.Once
+)
+
+// Global returns the game's Movement, for the global world.
+func Global() *Movement {
+	globalOnce.Do(func() {
+		globalMovement = New(world.Global(), nil)
+	})
+
+	return globalMovement
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Movement) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how to find the movers in a room, who are told when
+// someone comes and goes and who may follow them. Without it movers move
+// alone and unseen.
+func (m *Movement) SetOccupants(fn func(room string) []Mover) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetRouter sets what decides the room a mover taking an exit really
+// enters, given the room the exit leads to, like the mover's own copy of
+// it. An error keeps them from going, telling them why.
+func (m *Movement) SetRouter(fn func(mover Mover, room string) (string, error)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.router = fn
+}
+
+// SetDescriber sets what describes the rooms movers enter to them, like
+// leaving out what's too dark to see. Without it they're told the room's
+// RoomDescription.
+func (m *Movement) SetDescriber(fn func(mover Mover, r world.Room) string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.describe = fn
+}
+
+// description returns what the mover sees of the room
+func (m *Movement) description(mover Mover, r world.Room) string {
+	m.mutex.RLock()
+	describe := m.describe
+	m.mutex.RUnlock()
+
+	if describe == nil {
+		return RoomDescription(r)
+	}
+
+	return describe(mover, r)
+}
+
+// SetTerrain limits entering rooms with the flag, an empty Requires removes
+// the limit.
+func (m *Movement) SetTerrain(flag string, t Terrain) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	flag = strings.ToLower(flag)
+	if t.Requires == "" {
+		delete(m.terrain, flag)
+
+		return
+	}
+	m.terrain[flag] = t
+}
+
+// Follow has the follower move wherever the leader goes. Following someone
+// who follows the follower, directly or not, fails.
+func (m *Movement) Follow(follower, leader Mover) error {
+	f, l := key(follower), key(leader)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for next := l; next != ""; next = m.leaders[next] {
+		if next == f {
+			return blocked("You can't follow %s, they follow you.", leader.Name())
+		}
+	}
+	m.leaders[f] = l
+
+	return nil
+}
+
+// Unfollow stops the follower following anyone.
+func (m *Movement) Unfollow(follower Mover) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.leaders, key(follower))
+}
+
+// Leader returns the lower case name of who the follower follows, if anyone.
+func (m *Movement) Leader(follower Mover) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.leaders[key(follower)]
+}
+
+// Move takes the mover through the exit in the direction from the room
+// they're in, along with those following them. Movers that can't go get a
+// *Blocked error saying why.
+func (m *Movement) Move(mover Mover, d world.Direction) error {
+	return m.move(mover, d, map[string]bool{key(mover): true})
+}
+
+func (m *Movement) move(mover Mover, d world.Direction, moved map[string]bool) error {
+	from, ok := m.world.Room(mover.Location())
+	if !ok {
+		return blocked(NowhereMessage)
+	}
+	e, ok := from.Exit(d)
+	if !ok {
+		return blocked(NoExitMessage)
+	}
+	to, ok := m.world.Room(e.To)
+	if !ok {
+		return blocked(NoExitMessage)
+	}
+	if err := m.check(mover, e, to); err != nil {
+		return err
+	}
+
+	m.mutex.RLock()
+	emitter, occupants, router := m.emitter, m.occupants, m.router
+	m.mutex.RUnlock()
+
+	if router != nil {
+		room, err := router(mover, to.ID)
+		if err != nil {
+			if b, ok := err.(*Blocked); ok {
+				return b
+			}
+
+			return &Blocked{Message: err.Error()}
+		}
+		if to, ok = m.world.Room(room); !ok {
+			return blocked(NoExitMessage)
+		}
+	}
+
+	data := events.Data{
+		"mover":     mover.Name(),
+		"from":      from.ID,
+		"to":        to.ID,
+		"direction": string(d),
+	}
+	if emitter != nil {
+		for _, evt := range []string{ExitEvent, EnterEvent} {
+			if err := emitter.Check(evt, data); err != nil {
+				if err == events.ErrHalt {
+					return blocked(CancelMessage)
+				}
+
+				return &Blocked{Message: err.Error()}
+			}
+		}
+	}
+
+	var left []Mover
+	if occupants != nil {
+		left = occupants(from.ID)
+	}
+	mover.SetLocation(to.ID)
+	tell(left, mover, fmt.Sprintf("%s leaves %s.", mover.Name(), d))
+	if occupants != nil {
+		tell(occupants(to.ID), mover, arrival(mover, d))
+	}
+	if s, ok := mover.(Sender); ok {
+		s.Send(m.description(mover, to))
+	}
+	if emitter != nil {
+		emitter.Confirm(ExitEvent, data)
+		emitter.Confirm(EnterEvent, data)
+	}
+
+	leader := key(mover)
+	for _, f := range left {
+		if moved[key(f)] || f.Location() != from.ID || m.Leader(f) != leader {
+			continue
+		}
+		moved[key(f)] = true
+		if s, ok := f.(Sender); ok {
+			s.Send(fmt.Sprintf(FollowedMessage, mover.Name(), d))
+		}
+		if err := m.move(f, d, moved); err != nil {
+			if s, ok := f.(Sender); ok {
+				s.Send(err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Teleport puts the mover straight into the room, as staff go where they
+// like. Those in the rooms see them vanish and appear, no exits are taken so
+// nothing can stop them and nobody follows.
+func (m *Movement) Teleport(mover Mover, room string) error {
+	to, ok := m.world.Room(room)
+	if !ok {
+		return blocked("There's no room %q.", room)
+	}
+	from := mover.Location()
+
+	m.mutex.RLock()
+	emitter, occupants := m.emitter, m.occupants
+	m.mutex.RUnlock()
+
+	var left []Mover
+	if occupants != nil {
+		left = occupants(from)
+	}
+	mover.SetLocation(to.ID)
+	if occupants != nil && from != to.ID {
+		tell(left, mover, fmt.Sprintf("%s vanishes.", mover.Name()))
+		tell(occupants(to.ID), mover, fmt.Sprintf("%s appears out of nowhere.", mover.Name()))
+	}
+	if s, ok := mover.(Sender); ok {
+		s.Send(m.description(mover, to))
+	}
+	if emitter != nil {
+		emitter.Emit(TeleportEvent, events.Data{
+			"mover": mover.Name(),
+			"from":  from,
+			"to":    to.ID,
+		})
+	}
+
+	return nil
+}
+
+// check returns why the mover can't take the exit into the room, if they
+// can't
+func (m *Movement) check(mover Mover, e world.Exit, to world.Room) error {
+	if e.Door && e.Closed {
+		return blocked(ClosedMessage)
+	}
+	if e.Size > 0 {
+		if s, ok := mover.(Sized); ok && s.Size() > e.Size {
+			return blocked(TooBigMessage)
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for flag, t := range m.terrain {
+		if !to.Flag(flag) {
+			continue
+		}
+		f, ok := mover.(Flagged)
+		if !ok || !f.Flag(t.Requires) {
+			return blocked("%s", t.Message)
+		}
+	}
+
+	return nil
+}
+
+// RoomDescription returns what a mover sees entering the room, its name,
+// description and the exits that aren't hidden.
+func RoomDescription(r world.Room) string {
+	lines := []string{r.Name}
+	if r.Description != "" {
+		lines = append(lines, r.Description)
+	}
+	var exits []string
+	for _, e := range r.SortedExits(false) {
+		exits = append(exits, string(e.Direction))
+	}
+	if len(exits) == 0 {
+		exits = []string{"none"}
+	}
+	lines = append(lines, "[Exits: "+strings.Join(exits, " ")+"]")
+
+	return strings.Join(lines, "\n")
+}
+
+// arrival is what those in the room are told when the mover arrives
+func arrival(mover Mover, d world.Direction) string {
+	if back := d.Reverse(); back != "" {
+		switch back {
+		case world.Up:
+			return fmt.Sprintf("%s arrives from above.", mover.Name())
+		case world.Down:
+			return fmt.Sprintf("%s arrives from below.", mover.Name())
+		}
+
+		return fmt.Sprintf("%s arrives from the %s.", mover.Name(), back)
+	}
+
+	return fmt.Sprintf("%s arrives.", mover.Name())
+}
+
+// tell sends the text to every mover but the one it's about
+func tell(movers []Mover, about Mover, text string) {
+	for _, other := range movers {
+		if key(other) == key(about) {
+			continue
+		}
+		if s, ok := other.(Sender); ok {
+			s.Send(text)
+		}
+	}
+}
+
+func key(mover Mover) string {
+	return strings.ToLower(mover.Name())
+}
@@ -0,0 +1,13 @@
+package movement_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMovement(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Movement Suite")
+}
@@ -0,0 +1,267 @@
+package movement_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mover is a character that records what it's told
+type mover struct {
+	name     string
+	location string
+	size     int
+	flags    map[string]bool
+	sent     []string
+}
+
+func (m *mover) Name() string {
+	return m.name
+}
+
+func (m *mover) Location() string {
+	return m.location
+}
+
+func (m *mover) SetLocation(room string) {
+	m.location = room
+}
+
+func (m *mover) Size() int {
+	return m.size
+}
+
+func (m *mover) Flag(name string) bool {
+	return m.flags[name]
+}
+
+func (m *mover) ID() string {
+	return m.name
+}
+
+func (m *mover) Level() command.Level {
+	return command.Player
+}
+
+func (m *mover) Send(text string) error {
+	m.sent = append(m.sent, text)
+
+	return nil
+}
+
+func (m *mover) told(text string) bool {
+	return strings.Contains(strings.Join(m.sent, "\n"), text)
+}
+
+var _ = Describe("Movement", func() {
+	var (
+		w          *world.World
+		em         *events.Emitter
+		m          *Movement
+		alice, bob *mover
+		movers     []*mover
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "square", Zone: "town", Name: "Town Square", Description: "A busy square."},
+			{ID: "gate", Zone: "town", Name: "The Gate"},
+			{ID: "shop", Zone: "town", Name: "A Shop"},
+			{ID: "sky", Zone: "town", Name: "The Sky", Flags: map[string]bool{"air": true}},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("square", world.North, "gate")).Should(Succeed())
+		Ω(w.Link("square", world.Up, "sky")).Should(Succeed())
+		Ω(w.SetExit("square", world.Exit{Direction: world.East, To: "shop", Door: true, Closed: true})).Should(Succeed())
+		Ω(w.SetExit("gate", world.Exit{Direction: "crack", To: "shop", Size: 1})).Should(Succeed())
+
+		em = events.NewEmitter(nil)
+		m = New(w, em)
+		alice = &mover{name: "Alice", location: "square"}
+		bob = &mover{name: "Bob", location: "square"}
+		movers = []*mover{alice, bob}
+		m.SetOccupants(func(room string) []Mover {
+			var in []Mover
+			for _, mv := range movers {
+				if mv.location == room {
+					in = append(in, mv)
+				}
+			}
+
+			return in
+		})
+	})
+
+	It("moves through exits, describing the new room", func() {
+		Ω(m.Move(alice, world.North)).Should(Succeed())
+		Ω(alice.location).Should(Equal("gate"))
+		Ω(alice.told("The Gate\n[Exits: south crack]")).Should(BeTrue())
+		Ω(bob.told("Alice leaves north.")).Should(BeTrue())
+
+		Ω(m.Move(alice, world.South)).Should(Succeed())
+		Ω(bob.told("Alice arrives from the north.")).Should(BeTrue())
+	})
+
+	It("teleports past closed doors and terrain, leaving followers behind", func() {
+		Ω(m.Follow(bob, alice)).Should(Succeed())
+		Ω(m.Teleport(alice, "sky")).Should(Succeed())
+		Ω(alice.location).Should(Equal("sky"))
+		Ω(alice.told("The Sky")).Should(BeTrue())
+		Ω(bob.location).Should(Equal("square"))
+		Ω(bob.told("Alice vanishes.")).Should(BeTrue())
+
+		Ω(m.Teleport(bob, "sky")).Should(Succeed())
+		Ω(alice.told("Bob appears out of nowhere.")).Should(BeTrue())
+		Ω(m.Teleport(bob, "moon")).Should(MatchError("There's no room \"moon\"."))
+	})
+
+	It("blocks missing exits, closed doors, small exits and terrain", func() {
+		Ω(m.Move(alice, world.West)).Should(MatchError(NoExitMessage))
+		Ω(m.Move(alice, world.East)).Should(MatchError(ClosedMessage))
+		Ω(m.Move(alice, world.Up)).Should(MatchError("You'd need to fly to go there."))
+		Ω(alice.location).Should(Equal("square"))
+
+		alice.flags = map[string]bool{"fly": true}
+		Ω(m.Move(alice, world.Up)).Should(Succeed())
+
+		bob.location, bob.size = "gate", 2
+		Ω(m.Move(bob, "crack")).Should(MatchError(TooBigMessage))
+		bob.size = 1
+		Ω(m.Move(bob, "crack")).Should(Succeed())
+		Ω(bob.told("Bob arrives.")).Should(BeFalse())
+	})
+
+	It("takes movers where the router sends them", func() {
+		m.SetRouter(func(mv Mover, room string) (string, error) {
+			if mv.Name() == "Bob" {
+				return "", errors.New("The way is barred to you.")
+			}
+			if room == "gate" {
+				return "shop", nil
+			}
+
+			return room, nil
+		})
+
+		Ω(m.Move(alice, world.North)).Should(Succeed())
+		Ω(alice.location).Should(Equal("shop"))
+		Ω(m.Move(bob, world.North)).Should(MatchError("The way is barred to you."))
+		Ω(bob.location).Should(Equal("square"))
+	})
+
+	It("lets before handlers cancel the move", func() {
+		em.On("before:"+ExitEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["mover"] == "Alice" {
+				return errors.New("The guard stops you.")
+			}
+
+			return nil
+		}))
+		em.On("before:"+EnterEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["to"] == "gate" && d["direction"] == "north" {
+				return events.ErrHalt
+			}
+
+			return nil
+		}))
+
+		Ω(m.Move(alice, world.Up)).ShouldNot(Succeed())
+		alice.flags = map[string]bool{"fly": true}
+		Ω(m.Move(alice, world.Up)).Should(MatchError("The guard stops you."))
+		Ω(m.Move(bob, world.North)).Should(MatchError(CancelMessage))
+		Ω(alice.location).Should(Equal("square"))
+		Ω(bob.location).Should(Equal("square"))
+	})
+
+	It("emits the events once moved", func(done Done) {
+		entered := make(chan events.Data, 1)
+		em.On(EnterEvent, events.HandlerFunc(func(d events.Data) error {
+			entered <- d
+
+			return nil
+		}))
+
+		Ω(m.Move(alice, world.North)).Should(Succeed())
+		d := <-entered
+		Ω(d["mover"]).Should(Equal("Alice"))
+		Ω(d["from"]).Should(Equal("square"))
+		Ω(d["to"]).Should(Equal("gate"))
+		close(done)
+	})
+
+	It("brings followers along", func() {
+		carol := &mover{name: "Carol", location: "square"}
+		movers = append(movers, carol)
+		Ω(m.Follow(bob, alice)).Should(Succeed())
+		Ω(m.Follow(carol, bob)).Should(Succeed())
+		Ω(m.Follow(alice, carol)).ShouldNot(Succeed())
+		Ω(m.Leader(carol)).Should(Equal("bob"))
+
+		Ω(m.Move(alice, world.North)).Should(Succeed())
+		Ω(bob.location).Should(Equal("gate"))
+		Ω(carol.location).Should(Equal("gate"))
+		Ω(bob.told("You follow Alice north.")).Should(BeTrue())
+
+		m.Unfollow(bob)
+		Ω(m.Move(alice, world.South)).Should(Succeed())
+		Ω(bob.location).Should(Equal("gate"))
+	})
+
+	It("leaves followers that can't go behind", func() {
+		alice.flags = map[string]bool{"fly": true}
+		Ω(m.Follow(bob, alice)).Should(Succeed())
+
+		Ω(m.Move(alice, world.Up)).Should(Succeed())
+		Ω(bob.location).Should(Equal("square"))
+		Ω(bob.told("You'd need to fly to go there.")).Should(BeTrue())
+	})
+
+	Describe("commands", func() {
+		var registry *command.Registry
+
+		BeforeEach(func() {
+			registry = command.NewRegistry()
+			for _, c := range NewCommands(m, func(c command.Caller) Mover {
+				return c.(*mover)
+			}) {
+				Ω(registry.Register(c)).Should(Succeed())
+			}
+		})
+
+		dispatch := func(caller *mover, line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(caller, line)).Should(Succeed())
+		}
+
+		It("moves by direction, abbreviation and go", func() {
+			dispatch(alice, "n")
+			Ω(alice.location).Should(Equal("gate"))
+			dispatch(alice, "go crack")
+			Ω(alice.location).Should(Equal("shop"))
+		})
+
+		It("tells callers why they can't go", func() {
+			dispatch(alice, "east")
+			Ω(alice.told(ClosedMessage)).Should(BeTrue())
+		})
+
+		It("follows and stops following", func() {
+			dispatch(bob, "follow al")
+			Ω(bob.told("You now follow Alice.")).Should(BeTrue())
+			Ω(alice.told("Bob now follows you.")).Should(BeTrue())
+			Ω(m.Leader(bob)).Should(Equal("alice"))
+
+			dispatch(bob, "follow")
+			Ω(m.Leader(bob)).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,258 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package olc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/perm"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// NewCommands creates the redit, medit, oedit and zedit commands builders
+// edit rooms, NPCs, items and zones with. Each takes the same actions:
+//   <cmd> <id>                  starts editing what has the id
+//   <cmd> new <id> [zone]       starts making something new
+//   <cmd> set <field> [value]   changes a field, a description without a
+//                               value is written in the editor
+//   <cmd> show                  shows the draft
+//   <cmd> diff                  shows how the draft differs from the world
+//   <cmd> commit                changes the world to match the draft
+//   <cmd> cancel                throws the draft away
+// Given nothing redit and zedit edit the builder's room and its zone. zedit
+// also saves changed zones to their area files with "zedit save [zone]".
+func NewCommands(m *Manager, resolve command.Resolver[Builder]) []*command.Command {
+	return []*command.Command{
+		editCommand(m, resolve, "redit", RoomKind),
+		editCommand(m, resolve, "medit", NPCKind),
+		editCommand(m, resolve, "oedit", ItemKind),
+		editCommand(m, resolve, "zedit", ZoneKind),
+	}
+}
+
+// editCommand creates the command editing the kind of thing
+func editCommand(m *Manager, resolve command.Resolver[Builder], name, kind string) *command.Command {
+	help := fmt.Sprintf("Edits %ss: \"%s <id>\" or \"%s new <id> [zone]\" starts a draft, "+
+		"\"%s set <field> <value>\" changes it (%s) and show, diff, commit or cancel finish it.",
+		kind, name, name, name, strings.Join(Settable[kind], ", "))
+	if kind == ZoneKind {
+		help += " \"zedit save [zone]\" saves changed zones to their area files."
+	}
+
+	return &command.Command{
+		Name: name,
+		Args: []command.Arg{
+			{Name: "action", Kind: command.Word, Optional: true},
+			{Name: "rest", Kind: command.Text, Optional: true},
+		},
+		Level:      command.Builder,
+		Capability: perm.Build,
+		Help:       help,
+		Source:     "game",
+		Handler: handler(resolve, func(ctx *command.Context, b Builder) error {
+			action, rest := ctx.String("action"), strings.TrimSpace(ctx.String("rest"))
+			show := func(d *Draft, err error) error {
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(describe(d))
+			}
+			switch strings.ToLower(action) {
+			case "":
+				if d, ok := m.Draft(b); ok && d.Kind == kind {
+					return ctx.Send(describe(d))
+				}
+				id, ok := here(m, b, kind)
+				if !ok {
+					return ctx.Send(ctx.Command.Help)
+				}
+
+				return show(m.Edit(b, kind, id))
+			case "new":
+				words := strings.Fields(rest)
+				if len(words) == 0 {
+					return ctx.Send(ctx.Command.Help)
+				}
+				var zone string
+				if len(words) > 1 {
+					zone = words[1]
+				}
+
+				return show(m.Create(b, kind, words[0], zone))
+			case "set":
+				field, value := rest, ""
+				if i := strings.IndexAny(rest, " \t"); i >= 0 {
+					field, value = rest[:i], strings.TrimSpace(rest[i:])
+				}
+				if strings.EqualFold(field, "description") && value == "" {
+					return describeInEditor(ctx, m, b)
+				}
+
+				return show(m.Set(b, field, value))
+			case "show":
+				d, ok := m.Draft(b)
+				if !ok {
+					return ctx.Send(NoDraftMessage)
+				}
+
+				return ctx.Send(describe(d))
+			case "diff":
+				changes, err := m.Diff(b)
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(diffText(changes))
+			case "commit":
+				d, err := m.Commit(b)
+				if err != nil {
+					return sendRefused(ctx, err)
+				}
+
+				return ctx.Send(fmt.Sprintf("The %s %s is committed, \"zedit save\" writes it to its area file.", d.Kind, d.ID))
+			case "cancel":
+				if !m.Cancel(b) {
+					return ctx.Send(NoDraftMessage)
+				}
+
+				return ctx.Send("Your draft is thrown away.")
+			case "save":
+				if kind == ZoneKind {
+					return save(ctx, m, rest)
+				}
+			}
+
+			return show(m.Edit(b, kind, action))
+		}),
+	}
+}
+
+// here returns the id of the builder's room or its zone, for commands given
+// nothing to edit
+func here(m *Manager, b Builder, kind string) (string, bool) {
+	r, ok := m.world.Room(b.Location())
+	switch {
+	case !ok:
+		return "", false
+	case kind == RoomKind:
+		return r.ID, true
+	case kind == ZoneKind:
+		return r.Zone, true
+	}
+
+	return "", false
+}
+
+// describeInEditor writes the description of the builder's draft in the
+// editor
+func describeInEditor(ctx *command.Context, m *Manager, b Builder) error {
+	d, ok := m.Draft(b)
+	if !ok {
+		return ctx.Send(NoDraftMessage)
+	}
+	var text string
+	for _, f := range d.Fields() {
+		if f.Name == "description" {
+			text = f.Value
+		}
+	}
+	editor.Open(ctx.Dispatcher, ctx.Caller, editor.New(text, func(text string) {
+		d, err := m.Set(b, "description", strings.TrimRight(text, "\n"))
+		if err != nil {
+			sendRefused(ctx, err)
+
+			return
+		}
+		ctx.Send(describe(d))
+	}))
+
+	return nil
+}
+
+// save saves the zone, or every changed zone without one
+func save(ctx *command.Context, m *Manager, zone string) error {
+	if zone != "" {
+		if err := m.Save(zone); err != nil {
+			return sendRefused(ctx, err)
+		}
+
+		return ctx.Send(fmt.Sprintf("The zone %s is saved.", zone))
+	}
+
+	saved, err := m.SaveAll()
+	if err != nil {
+		logger.NewWithSource("olc").WithError(err).Error("Failed to save a zone.")
+	}
+	switch {
+	case len(saved) == 0 && err == nil:
+		return ctx.Send("No zones have unsaved changes.")
+	case len(saved) > 0:
+		ctx.Send("Saved: " + strings.Join(saved, ", "))
+	}
+	if err != nil {
+		return ctx.Send("Saving stopped at a zone that couldn't be saved: " + err.Error())
+	}
+
+	return nil
+}
+
+// sendRefused tells the builder why they were refused, other errors are
+// returned
+func sendRefused(ctx *command.Context, err error) error {
+	if r, ok := err.(*item.Refused); ok {
+		return ctx.Send(r.Message)
+	}
+
+	return err
+}
+
+// describe lists the fields of the draft
+func describe(d *Draft) string {
+	title := fmt.Sprintf("Editing %s %s", d.Kind, d.ID)
+	if d.New {
+		title += " (new)"
+	}
+	lines := []string{title + ":"}
+	for _, f := range d.Fields() {
+		lines = append(lines, fmt.Sprintf("  %s: %s", f.Name, f.Value))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// diffText lists the changes
+func diffText(changes []Change) string {
+	if len(changes) == 0 {
+		return "There are no changes."
+	}
+	lines := []string{"Changes:"}
+	for _, c := range changes {
+		switch {
+		case c.Old == "":
+			lines = append(lines, fmt.Sprintf("  + %s: %s", c.Field, c.New))
+		case c.New == "":
+			lines = append(lines, fmt.Sprintf("  - %s: %s", c.Field, c.Old))
+		default:
+			lines = append(lines, fmt.Sprintf("  ~ %s: %s -> %s", c.Field, c.Old, c.New))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's builder for fn
+func handler(resolve command.Resolver[Builder], fn func(*command.Context, Builder) error) command.Handler {
+	return func(ctx *command.Context) error {
+		b := resolve(ctx.Caller)
+		if b == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		return fn(ctx, b)
+	}
+}
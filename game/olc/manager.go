@@ -0,0 +1,490 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package olc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/spf13/viper"
+)
+
+// Events emitted as builders change the world. Handlers of
+// before:olc:commit can stop a draft being committed by returning
+// events.ErrHalt, or an error whose message is told to the builder. Commits
+// are given the kind, id and zone of what changed, whether it's new and the
+// name of the builder, saves are given the zone.
+const (
+	CommitEvent = "olc:commit"
+	SaveEvent   = "olc:save"
+)
+
+// Messages told to builders who can't do something.
+const (
+	NoDraftMessage = "You aren't editing anything."
+	DeniedMessage  = "You aren't allowed to build there."
+	CancelMessage  = "You can't do that right now."
+)
+
+// Builder is someone changing the world, like a player.
+type Builder interface {
+	ID() string
+	Name() string
+	Location() string
+	Level() command.Level
+}
+
+// Request is a builder wanting to edit something, for access hooks to allow
+// or refuse.
+type Request struct {
+	Builder Builder
+	Kind    string
+	ID      string
+	Zone    string
+}
+
+// Access allows a request by returning nil, or refuses it with an error
+// whose message is told to the builder.
+type Access func(Request) error
+
+type namedAccess struct {
+	name string
+	fn   Access
+}
+
+// Manager keeps the drafts of builders and the zones they've changed.
+type Manager struct {
+	world   *world.World
+	dir     string
+	drafts  map[string]*Draft
+	editors map[string]string
+	unsaved map[string]bool
+	access  []namedAccess
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager for building in the world, zones not loaded
+// from an area file are saved to the directory. The emitter may be nil.
+func NewManager(w *world.World, dir string, em *events.Emitter) *Manager {
+	return &Manager{
+		world:   w,
+		dir:     dir,
+		drafts:  make(map[string]*Draft),
+		editors: make(map[string]string),
+		unsaved: make(map[string]bool),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's building manager, for the game's world with new
+// zones saved to the directory given by the world.dir setting.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), viper.GetString("world.dir"), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetAccess sets the access hook with the name, replacing any there was, or
+// removes it if fn is nil. Every hook must allow a request, after the zone's
+// own list of builders, they're asked in the order their names were first
+// set.
+func (m *Manager) SetAccess(name string, fn Access) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var access []namedAccess
+	found := false
+	for _, na := range m.access {
+		if na.name == name {
+			found = true
+			if fn == nil {
+				continue
+			}
+			na.fn = fn
+		}
+		access = append(access, na)
+	}
+	if !found && fn != nil {
+		access = append(access, namedAccess{name, fn})
+	}
+	m.access = access
+}
+
+// Edit starts a draft of the room, NPC, item or zone with the id for the
+// builder. Builders edit one thing at a time, they must commit or cancel
+// changes they've made before starting another, and only one builder edits
+// a thing at once.
+func (m *Manager) Edit(b Builder, kind, id string) (*Draft, error) {
+	d, ok := m.current(kind, id)
+	if !ok {
+		return nil, refuse(fmt.Sprintf("There's no %s %q.", kind, id))
+	}
+
+	return m.start(b, d)
+}
+
+// Create starts a draft of a new room, NPC, item or zone with the id. Rooms,
+// NPCs and items are made in the zone given, or that of the builder's room
+// without one.
+func (m *Manager) Create(b Builder, kind, id, zone string) (*Draft, error) {
+	if id == "" || strings.ContainsAny(id, " \t") {
+		return nil, refuse("Ids are a single word.")
+	}
+	if _, exists := m.current(kind, id); exists {
+		return nil, refuse(fmt.Sprintf("There's already a %s %q.", kind, id))
+	}
+	if zone == "" && kind != ZoneKind {
+		r, ok := m.world.Room(b.Location())
+		if !ok {
+			return nil, refuse("Say which zone it's in.")
+		}
+		zone = r.Zone
+	}
+	if _, ok := m.world.Zone(zone); !ok && kind != ZoneKind {
+		return nil, refuse(fmt.Sprintf("There's no zone %q.", zone))
+	}
+
+	d := &Draft{Kind: kind, ID: id, New: true}
+	switch kind {
+	case RoomKind:
+		d.Room = world.Room{ID: id, Zone: zone, Name: "A new room"}
+	case NPCKind:
+		d.NPC = world.NPCDef{ID: id, Zone: zone, Name: "a new NPC"}
+	case ItemKind:
+		d.Item = world.ItemDef{ID: id, Zone: zone, Name: "a new item"}
+	case ZoneKind:
+		d.Zone = world.Zone{ID: id, Name: id}
+	default:
+		return nil, refuse("Builders can't make " + kind + "s.")
+	}
+
+	return m.start(b, d)
+}
+
+// Draft returns a copy of the builder's draft.
+func (m *Manager) Draft(b Builder) (*Draft, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	d, ok := m.drafts[b.ID()]
+	if !ok {
+		return nil, false
+	}
+
+	return d.copy(), true
+}
+
+// Set changes a field of the builder's draft, see Draft.Set. Only admins
+// change who builds in a zone.
+func (m *Manager) Set(b Builder, field, value string) (*Draft, error) {
+	if strings.EqualFold(field, "builders") && b.Level() < command.Admin {
+		return nil, refuse("Only admins choose who builds in a zone.")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	d, ok := m.drafts[b.ID()]
+	if !ok {
+		return nil, refuse(NoDraftMessage)
+	}
+	changed := d.copy()
+	if err := changed.Set(field, value); err != nil {
+		return nil, err
+	}
+	m.drafts[b.ID()] = changed
+
+	return changed.copy(), nil
+}
+
+// Diff returns how the builder's draft differs from what's in the world now.
+func (m *Manager) Diff(b Builder) ([]Change, error) {
+	d, ok := m.Draft(b)
+	if !ok {
+		return nil, refuse(NoDraftMessage)
+	}
+	var old []Field
+	if live, ok := m.current(d.Kind, d.ID); ok {
+		old = live.Fields()
+	}
+
+	return Diff(old, d.Fields()), nil
+}
+
+// Cancel throws away the builder's draft, returning false if they had none.
+func (m *Manager) Cancel(b Builder) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	d, ok := m.drafts[b.ID()]
+	if ok {
+		m.drop(b.ID(), d)
+	}
+
+	return ok
+}
+
+// Commit changes the world to match the builder's draft, ending it. The
+// zones changed are remembered until they're saved.
+func (m *Manager) Commit(b Builder) (*Draft, error) {
+	d, ok := m.Draft(b)
+	if !ok {
+		return nil, refuse(NoDraftMessage)
+	}
+	if err := m.allowed(b, d); err != nil {
+		return nil, err
+	}
+	data := events.Data{
+		"kind":    d.Kind,
+		"id":      d.ID,
+		"zone":    d.ZoneID(),
+		"new":     d.New,
+		"builder": b.Name(),
+	}
+	if err := m.check(CommitEvent, data); err != nil {
+		return nil, err
+	}
+
+	zones, err := m.apply(d)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	for _, zone := range zones {
+		m.unsaved[zone] = true
+	}
+	if current, ok := m.drafts[b.ID()]; ok {
+		m.drop(b.ID(), current)
+	}
+	m.mutex.Unlock()
+	m.confirm(CommitEvent, data)
+
+	return d, nil
+}
+
+// Unsaved returns the ids of the zones changed since they were last saved,
+// sorted.
+func (m *Manager) Unsaved() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	zones := make([]string, 0, len(m.unsaved))
+	for zone := range m.unsaved {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	return zones
+}
+
+// Save writes the zone back to its area file.
+func (m *Manager) Save(zone string) error {
+	if err := m.world.SaveZone(zone, m.dir); err != nil {
+		if err == world.ErrNoZone {
+			return refuse(fmt.Sprintf("There's no zone %q.", zone))
+		}
+
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.unsaved, zone)
+	m.mutex.Unlock()
+	m.confirm(SaveEvent, events.Data{"zone": zone})
+
+	return nil
+}
+
+// SaveAll saves every zone changed since it was last saved, returning those
+// saved. It stops at the first zone that fails.
+func (m *Manager) SaveAll() ([]string, error) {
+	var saved []string
+	for _, zone := range m.Unsaved() {
+		if err := m.Save(zone); err != nil {
+			return saved, err
+		}
+		saved = append(saved, zone)
+	}
+
+	return saved, nil
+}
+
+// start makes the draft the builder's if they may edit it
+func (m *Manager) start(b Builder, d *Draft) (*Draft, error) {
+	if err := m.allowed(b, d); err != nil {
+		return nil, err
+	}
+
+	key := d.Kind + ":" + d.ID
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if editor, ok := m.editors[key]; ok && editor != b.ID() {
+		return nil, refuse(fmt.Sprintf("Someone else is editing the %s %s.", d.Kind, d.ID))
+	}
+	if old, ok := m.drafts[b.ID()]; ok && old.Kind+":"+old.ID != key {
+		if old.New || m.changed(old) {
+			return nil, refuse(fmt.Sprintf("You're still editing the %s %s, commit or cancel it first.", old.Kind, old.ID))
+		}
+		m.drop(b.ID(), old)
+	}
+	m.drafts[b.ID()] = d
+	m.editors[key] = b.ID()
+
+	return d.copy(), nil
+}
+
+// changed is true if the draft differs from the world
+func (m *Manager) changed(d *Draft) bool {
+	live, ok := m.current(d.Kind, d.ID)
+
+	return !ok || len(Diff(live.Fields(), d.Fields())) > 0
+}
+
+// drop ends the builder's draft, the mutex must be locked
+func (m *Manager) drop(id string, d *Draft) {
+	delete(m.drafts, id)
+	delete(m.editors, d.Kind+":"+d.ID)
+}
+
+// current returns a draft of what's in the world with the id
+func (m *Manager) current(kind, id string) (*Draft, bool) {
+	d := &Draft{Kind: kind, ID: id}
+	var ok bool
+	switch kind {
+	case RoomKind:
+		d.Room, ok = m.world.Room(id)
+	case NPCKind:
+		d.NPC, ok = m.world.NPC(id)
+	case ItemKind:
+		d.Item, ok = m.world.Item(id)
+	case ZoneKind:
+		d.Zone, ok = m.world.Zone(id)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return d.copy(), true
+}
+
+// apply changes the world to match the draft, returning the zones changed
+func (m *Manager) apply(d *Draft) ([]string, error) {
+	zones := []string{d.ZoneID()}
+	var err error
+	switch d.Kind {
+	case RoomKind:
+		for _, e := range d.Room.SortedExits(true) {
+			if _, ok := m.world.Room(e.To); !ok && e.To != d.ID {
+				return nil, refuse(fmt.Sprintf("The exit %s leads to %q, there's no such room.", e.Direction, e.To))
+			}
+		}
+		if old, exists := m.world.Room(d.ID); exists {
+			if old.Zone != d.Room.Zone {
+				zones = append(zones, old.Zone)
+			}
+			err = m.world.UpdateRoom(d.Room)
+		} else {
+			err = m.world.AddRoom(d.Room)
+		}
+	case NPCKind:
+		err = m.world.SetNPC(d.NPC)
+	case ItemKind:
+		err = m.world.SetItem(d.Item)
+	case ZoneKind:
+		if _, exists := m.world.Zone(d.ID); exists {
+			err = m.world.UpdateZone(d.Zone)
+		} else {
+			err = m.world.AddZone(d.Zone)
+		}
+	}
+	if err == world.ErrNoZone {
+		return nil, refuse(fmt.Sprintf("There's no zone %q.", d.ZoneID()))
+	}
+
+	return zones, err
+}
+
+// allowed checks the builder may edit the draft, builders listed by its zone
+// and admins may, and then asks the access hooks
+func (m *Manager) allowed(b Builder, d *Draft) error {
+	if z, ok := m.world.Zone(d.ZoneID()); ok && len(z.Builders) > 0 && b.Level() < command.Admin {
+		listed := false
+		for _, name := range z.Builders {
+			listed = listed || strings.EqualFold(name, b.Name())
+		}
+		if !listed {
+			return refuse(DeniedMessage)
+		}
+	}
+
+	m.mutex.RLock()
+	access := m.access
+	m.mutex.RUnlock()
+
+	req := Request{Builder: b, Kind: d.Kind, ID: d.ID, Zone: d.ZoneID()}
+	for _, na := range access {
+		if err := na.fn(req); err != nil {
+			if _, ok := err.(*item.Refused); ok {
+				return err
+			}
+
+			return refuse(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package olc lets builders change the world while the game runs. Each
+// builder stages their changes to one room, NPC, item or zone in a draft,
+// which can be shown and compared with what's in the world before it's
+// committed. Committed changes are live at once and saved back to the area
+// files of their zones when a builder asks, so the files stay the source of
+// the world.
+package olc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// Kinds of things builders edit.
+const (
+	RoomKind = "room"
+	NPCKind  = "npc"
+	ItemKind = "item"
+	ZoneKind = "zone"
+)
+
+// Draft is a builder's staged changes to a room, NPC, item or zone, only the
+// one of its Kind is used. The world doesn't change until it's committed.
+type Draft struct {
+	Kind string
+	ID   string
+	// New drafts create something that isn't in the world yet.
+	New  bool
+	Room world.Room
+	NPC  world.NPCDef
+	Item world.ItemDef
+	Zone world.Zone
+}
+
+// copy returns a deep copy of the draft
+func (d *Draft) copy() *Draft {
+	c := *d
+	c.Room.Flags = make(map[string]bool, len(d.Room.Flags))
+	for k, v := range d.Room.Flags {
+		c.Room.Flags[k] = v
+	}
+	c.Room.Exits = make(map[world.Direction]world.Exit, len(d.Room.Exits))
+	for k, v := range d.Room.Exits {
+		c.Room.Exits[k] = v
+	}
+	c.NPC.Keywords = append([]string(nil), d.NPC.Keywords...)
+	c.NPC.Flags = append([]string(nil), d.NPC.Flags...)
+	if d.NPC.Stats != nil {
+		c.NPC.Stats = make(map[string]int, len(d.NPC.Stats))
+		for k, v := range d.NPC.Stats {
+			c.NPC.Stats[k] = v
+		}
+	}
+	c.Item.Keywords = append([]string(nil), d.Item.Keywords...)
+	c.Item.Flags = append([]string(nil), d.Item.Flags...)
+	c.Zone.Builders = append([]string(nil), d.Zone.Builders...)
+
+	return &c
+}
+
+// ZoneID returns the id of the zone the draft belongs to.
+func (d *Draft) ZoneID() string {
+	switch d.Kind {
+	case RoomKind:
+		return d.Room.Zone
+	case NPCKind:
+		return d.NPC.Zone
+	case ItemKind:
+		return d.Item.Zone
+	}
+
+	return d.Zone.ID
+}
+
+// Field is one part of what's being edited, as builders see it.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the parts of the draft, in the order they're shown. Each of
+// a room's exits is a field of its own, like "exit north".
+func (d *Draft) Fields() []Field {
+	switch d.Kind {
+	case RoomKind:
+		r := d.Room
+		fields := []Field{
+			{"name", r.Name},
+			{"zone", r.Zone},
+			{"description", r.Description},
+			{"flags", strings.Join(flagNames(r.Flags), " ")},
+		}
+		for _, e := range r.SortedExits(true) {
+			value := e.To
+			if e.Door {
+				value += " (door)"
+			}
+			fields = append(fields, Field{"exit " + string(e.Direction), value})
+		}
+
+		return fields
+	case NPCKind:
+		n := d.NPC
+		var stats []string
+		for stat, v := range n.Stats {
+			stats = append(stats, fmt.Sprintf("%s=%d", stat, v))
+		}
+		sort.Strings(stats)
+
+		return []Field{
+			{"name", n.Name},
+			{"keywords", strings.Join(n.Keywords, " ")},
+			{"description", n.Description},
+			{"level", strconv.Itoa(n.Level)},
+			{"stats", strings.Join(stats, " ")},
+			{"flags", strings.Join(n.Flags, " ")},
+		}
+	case ItemKind:
+		i := d.Item
+
+		return []Field{
+			{"name", i.Name},
+			{"keywords", strings.Join(i.Keywords, " ")},
+			{"description", i.Description},
+			{"type", i.Type},
+			{"weight", strconv.Itoa(i.Weight)},
+			{"value", strconv.Itoa(i.Value)},
+			{"flags", strings.Join(i.Flags, " ")},
+		}
+	}
+	z := d.Zone
+
+	return []Field{
+		{"name", z.Name},
+		{"description", z.Description},
+		{"reset", z.Reset},
+		{"population", strconv.Itoa(z.Population)},
+		{"builders", strings.Join(z.Builders, " ")},
+	}
+}
+
+// Change is a field that differs between the world and a draft, Old is empty
+// for fields the world doesn't have and New for those the draft removes.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff returns the changes between the fields of what's in the world and
+// those of a draft, in the order of the draft's fields followed by any it
+// removed.
+func Diff(old, new []Field) []Change {
+	before := make(map[string]string, len(old))
+	for _, f := range old {
+		before[f.Name] = f.Value
+	}
+	seen := make(map[string]bool, len(new))
+	var changes []Change
+	for _, f := range new {
+		seen[f.Name] = true
+		if was, ok := before[f.Name]; !ok || was != f.Value {
+			changes = append(changes, Change{Field: f.Name, Old: was, New: f.Value})
+		}
+	}
+	for _, f := range old {
+		if !seen[f.Name] {
+			changes = append(changes, Change{Field: f.Name, Old: f.Value})
+		}
+	}
+
+	return changes
+}
+
+// flagNames returns the names of the flags that are set, sorted
+func flagNames(flags map[string]bool) []string {
+	var names []string
+	for name, on := range flags {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,13 @@
+package olc_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestOLC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OLC Suite")
+}
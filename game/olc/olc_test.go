@@ -0,0 +1,265 @@
+package olc_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mason is a builder who remembers what they're told
+type mason struct {
+	name     string
+	location string
+	level    command.Level
+	sent     []string
+}
+
+func (m *mason) ID() string {
+	return strings.ToLower(m.name)
+}
+
+func (m *mason) Name() string {
+	return m.name
+}
+
+func (m *mason) Location() string {
+	return m.location
+}
+
+func (m *mason) Level() command.Level {
+	return m.level
+}
+
+func (m *mason) Send(text string) error {
+	m.sent = append(m.sent, text)
+
+	return nil
+}
+
+func (m *mason) last() string {
+	if len(m.sent) == 0 {
+		return ""
+	}
+
+	return m.sent[len(m.sent)-1]
+}
+
+const town = `
+zone:
+  id: town
+  name: The Town
+rooms:
+  - id: square
+    name: Town Square
+    exits:
+      north: gate
+  - id: gate
+    name: Town Gate
+    exits:
+      south: square
+npcs:
+  - id: guard
+    name: a town guard
+items:
+  - id: torch
+    name: a torch
+`
+
+var _ = Describe("OLC", func() {
+	var (
+		w    *world.World
+		m    *Manager
+		dir  string
+		file string
+		ann  *mason
+		bob  *mason
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "olc")
+		Ω(err).ShouldNot(HaveOccurred())
+		file = filepath.Join(dir, "town.yml")
+		Ω(ioutil.WriteFile(file, []byte(town), 0644)).Should(Succeed())
+		w = world.New()
+		Ω(w.LoadDir(dir)).Should(Succeed())
+		m = NewManager(w, dir, nil)
+		ann = &mason{name: "Ann", location: "square", level: command.Builder}
+		bob = &mason{name: "Bob", location: "gate", level: command.Builder}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("stages changes until they're committed", func() {
+		_, err := m.Edit(ann, RoomKind, "square")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "name", "The Square")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "flags", "outdoors lit")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "exit", "n none")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		r, _ := w.Room("square")
+		Ω(r.Name).Should(Equal("Town Square"))
+		changes, err := m.Diff(ann)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(changes).Should(Equal([]Change{
+			{Field: "name", Old: "Town Square", New: "The Square"},
+			{Field: "flags", New: "lit outdoors"},
+			{Field: "exit north", Old: "gate"},
+		}))
+
+		_, err = m.Commit(ann)
+		Ω(err).ShouldNot(HaveOccurred())
+		r, _ = w.Room("square")
+		Ω(r.Name).Should(Equal("The Square"))
+		Ω(r.Flag("lit")).Should(BeTrue())
+		Ω(r.Exits).Should(BeEmpty())
+		_, ok := m.Draft(ann)
+		Ω(ok).Should(BeFalse())
+		Ω(m.Unsaved()).Should(Equal([]string{"town"}))
+	})
+
+	It("refuses values that don't make sense", func() {
+		_, err := m.Edit(ann, NPCKind, "guard")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "level", "high")
+		Ω(err).Should(MatchError("That should be a whole number, zero or more."))
+		_, err = m.Set(ann, "color", "red")
+		Ω(err).Should(MatchError(ContainSubstring("There's no color to set")))
+		_, err = m.Set(ann, "name", "")
+		Ω(err).Should(MatchError("Names can't be empty."))
+		d, err := m.Set(ann, "stat", "hp 30")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(d.NPC.Stats).Should(Equal(map[string]int{"hp": 30}))
+
+		_, err = m.Edit(ann, ZoneKind, "town")
+		Ω(err).Should(MatchError(ContainSubstring("You're still editing the npc guard")))
+		Ω(m.Cancel(ann)).Should(BeTrue())
+		_, err = m.Edit(ann, ZoneKind, "town")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "reset", "whenever")
+		Ω(err).Should(MatchError(ContainSubstring("That isn't a schedule")))
+		_, err = m.Set(ann, "builders", "Ann")
+		Ω(err).Should(MatchError("Only admins choose who builds in a zone."))
+		_, err = m.Set(ann, "name", "Old Town")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = m.Create(ann, RoomKind, "hall", "")
+		Ω(err).Should(MatchError(ContainSubstring("You're still editing")))
+		m.Cancel(ann)
+		_, err = m.Create(ann, RoomKind, "hall", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(ann, "exit", "up attic")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Commit(ann)
+		Ω(err).Should(MatchError(ContainSubstring("there's no such room")))
+	})
+
+	It("lets one builder edit a thing at a time and only those allowed", func() {
+		_, err := m.Edit(ann, ItemKind, "torch")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Edit(bob, ItemKind, "torch")
+		Ω(err).Should(MatchError("Someone else is editing the item torch."))
+
+		admin := &mason{name: "Cat", level: command.Admin}
+		_, err = m.Edit(admin, ZoneKind, "town")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Set(admin, "builders", "Ann")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Commit(admin)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Edit(bob, RoomKind, "gate")
+		Ω(err).Should(MatchError(DeniedMessage))
+
+		m.SetAccess("no-torches", func(req Request) error {
+			if req.ID == "torch" {
+				return errors.New("Torches are finished.")
+			}
+
+			return nil
+		})
+		_, err = m.Commit(ann)
+		Ω(err).Should(MatchError("Torches are finished."))
+	})
+
+	It("lets before handlers stop commits and saves zones to their files", func() {
+		em := events.NewEmitter(nil)
+		m.SetEmitter(em)
+		em.On("before:"+CommitEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["id"] == "guard" {
+				return events.ErrHalt
+			}
+
+			return nil
+		}))
+
+		m.Edit(ann, NPCKind, "guard")
+		_, err := m.Commit(ann)
+		Ω(err).Should(MatchError(CancelMessage))
+		m.Cancel(ann)
+
+		_, err = m.Create(ann, ItemKind, "lamp", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		m.Set(ann, "keywords", "lamp oil")
+		_, err = m.Commit(ann)
+		Ω(err).ShouldNot(HaveOccurred())
+		def, ok := w.Item("lamp")
+		Ω(ok).Should(BeTrue())
+		Ω(def.Zone).Should(Equal("town"))
+
+		saved, err := m.SaveAll()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(saved).Should(Equal([]string{"town"}))
+		Ω(m.Unsaved()).Should(BeEmpty())
+		a, err := world.ReadArea(file)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.Items).Should(HaveLen(2))
+	})
+
+	It("runs the building commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Builder {
+			return c.(*mason)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+		dispatch := func(line string) string {
+			Ω(dispatcher.Dispatch(ann, line)).Should(Succeed())
+
+			return ann.last()
+		}
+
+		Ω(dispatch("redit")).Should(HavePrefix("Editing room square:\n  name: Town Square"))
+		dispatch("redit set description")
+		dispatch("Cobbles everywhere.")
+		Ω(dispatch(".s")).Should(ContainSubstring("description: Cobbles everywhere."))
+		Ω(dispatch("redit diff")).Should(Equal("Changes:\n  + description: Cobbles everywhere."))
+		Ω(dispatch("redit commit")).Should(Equal("The room square is committed, \"zedit save\" writes it to its area file."))
+		Ω(dispatch("medit new rat")).Should(HavePrefix("Editing npc rat (new):"))
+		Ω(dispatch("medit cancel")).Should(Equal("Your draft is thrown away."))
+		Ω(dispatch("oedit sword")).Should(Equal("There's no item \"sword\"."))
+		Ω(dispatch("zedit save")).Should(Equal("Saved: town"))
+		Ω(dispatch("zedit save")).Should(Equal("No zones have unsaved changes."))
+		contents, err := ioutil.ReadFile(file)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(contents)).Should(ContainSubstring("Cobbles everywhere."))
+
+		ann.level = command.Player
+		Ω(dispatcher.Dispatch(ann, "redit")).Should(MatchError(command.ErrUnknown))
+	})
+})
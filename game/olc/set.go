@@ -0,0 +1,248 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package olc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/sched"
+)
+
+// Settable are the fields builders set on each kind of draft.
+var Settable = map[string][]string{
+	RoomKind: {"name", "description", "zone", "flags", "exit", "door"},
+	NPCKind:  {"name", "keywords", "description", "level", "stat", "flags"},
+	ItemKind: {"name", "keywords", "description", "type", "weight", "value", "flags"},
+	ZoneKind: {"name", "description", "reset", "population", "builders"},
+}
+
+// Set changes the field of the draft, refusing values that don't make
+// sense. Flags given are toggled, lists like keywords are replaced. Exits are
+// set with a direction and the room they lead to, or "none" to remove them,
+// and a door is put in or taken out of an exit by giving its direction.
+// Stats are set with their name and value, zero removes them.
+func (d *Draft) Set(field, value string) error {
+	field, value = strings.ToLower(field), strings.TrimSpace(value)
+	if field == "name" && value == "" {
+		return refuse("Names can't be empty.")
+	}
+
+	switch d.Kind {
+	case RoomKind:
+		return d.setRoom(field, value)
+	case NPCKind:
+		return d.setNPC(field, value)
+	case ItemKind:
+		return d.setItem(field, value)
+	}
+
+	return d.setZone(field, value)
+}
+
+func (d *Draft) setRoom(field, value string) error {
+	r := &d.Room
+	switch field {
+	case "name":
+		r.Name = value
+	case "description":
+		r.Description = value
+	case "zone":
+		if value == "" {
+			return refuse("Rooms must be in a zone.")
+		}
+		r.Zone = value
+	case "flags":
+		if r.Flags == nil {
+			r.Flags = make(map[string]bool)
+		}
+		for _, flag := range strings.Fields(strings.ToLower(value)) {
+			r.Flags[flag] = !r.Flags[flag]
+			if !r.Flags[flag] {
+				delete(r.Flags, flag)
+			}
+		}
+	case "exit":
+		parts := strings.Fields(value)
+		if len(parts) != 2 {
+			return refuse("Set an exit with its direction and the room it leads to, or none.")
+		}
+		dir := world.ParseDirection(parts[0])
+		if dir == "" {
+			return refuse("That isn't a direction.")
+		}
+		if r.Exits == nil {
+			r.Exits = make(map[world.Direction]world.Exit)
+		}
+		if strings.EqualFold(parts[1], "none") {
+			delete(r.Exits, dir)
+
+			return nil
+		}
+		e := r.Exits[dir]
+		e.Direction, e.To = dir, parts[1]
+		r.Exits[dir] = e
+	case "door":
+		dir := world.ParseDirection(value)
+		e, ok := r.Exits[dir]
+		if !ok {
+			return refuse("There's no exit that way.")
+		}
+		e.Door = !e.Door
+		if !e.Door {
+			e.Closed, e.Locked, e.Key, e.Pickproof, e.Bashproof = false, false, "", false, false
+		}
+		r.Exits[dir] = e
+	default:
+		return unknownField(RoomKind, field)
+	}
+
+	return nil
+}
+
+func (d *Draft) setNPC(field, value string) error {
+	n := &d.NPC
+	switch field {
+	case "name":
+		n.Name = value
+	case "keywords":
+		n.Keywords = strings.Fields(strings.ToLower(value))
+	case "description":
+		n.Description = value
+	case "level":
+		level, err := amount(value)
+		if err != nil {
+			return err
+		}
+		n.Level = level
+	case "stat":
+		parts := strings.Fields(strings.ToLower(value))
+		if len(parts) != 2 {
+			return refuse("Set a stat with its name and value.")
+		}
+		v, err := amount(parts[1])
+		if err != nil {
+			return err
+		}
+		if n.Stats == nil {
+			n.Stats = make(map[string]int)
+		}
+		if v == 0 {
+			delete(n.Stats, parts[0])
+		} else {
+			n.Stats[parts[0]] = v
+		}
+	case "flags":
+		n.Flags = toggle(n.Flags, value)
+	default:
+		return unknownField(NPCKind, field)
+	}
+
+	return nil
+}
+
+func (d *Draft) setItem(field, value string) error {
+	i := &d.Item
+	switch field {
+	case "name":
+		i.Name = value
+	case "keywords":
+		i.Keywords = strings.Fields(strings.ToLower(value))
+	case "description":
+		i.Description = value
+	case "type":
+		i.Type = strings.ToLower(value)
+	case "weight", "value":
+		v, err := amount(value)
+		if err != nil {
+			return err
+		}
+		if field == "weight" {
+			i.Weight = v
+		} else {
+			i.Value = v
+		}
+	case "flags":
+		i.Flags = toggle(i.Flags, value)
+	default:
+		return unknownField(ItemKind, field)
+	}
+
+	return nil
+}
+
+func (d *Draft) setZone(field, value string) error {
+	z := &d.Zone
+	switch field {
+	case "name":
+		z.Name = value
+	case "description":
+		z.Description = value
+	case "reset":
+		if value != "" {
+			if _, err := sched.Parse(value); err != nil {
+				return refuse("That isn't a schedule: " + err.Error())
+			}
+		}
+		z.Reset = value
+	case "population":
+		population, err := amount(value)
+		if err != nil {
+			return err
+		}
+		z.Population = population
+	case "builders":
+		z.Builders = strings.Fields(value)
+	default:
+		return unknownField(ZoneKind, field)
+	}
+
+	return nil
+}
+
+// toggle sets each flag in value that isn't in flags and removes each that
+// is
+func toggle(flags []string, value string) []string {
+	set := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		set[f] = true
+	}
+	for _, f := range strings.Fields(strings.ToLower(value)) {
+		set[f] = !set[f]
+	}
+	var toggled []string
+	for _, f := range flags {
+		if set[f] {
+			toggled = append(toggled, f)
+			delete(set, f)
+		}
+	}
+	for _, f := range strings.Fields(strings.ToLower(value)) {
+		if set[f] {
+			toggled = append(toggled, f)
+			delete(set, f)
+		}
+	}
+
+	return toggled
+}
+
+// amount reads a whole number that isn't negative
+func amount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, refuse("That should be a whole number, zero or more.")
+	}
+
+	return n, nil
+}
+
+func unknownField(kind, field string) error {
+	return refuse("There's no " + field + " to set, try " + strings.Join(Settable[kind], ", ") + ".")
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package path finds routes between rooms. The world is not loaded by this
+// package, instead the caller describes the graph through the Options given to
+// Find which lets the same search be used regardless of how rooms are stored.
+package path
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// DefaultMaxNodes is the number of rooms a search will expand before giving
+// up when no limit is provided.
+const DefaultMaxNodes = 10000
+
+var (
+	// ErrNoPath is returned when the destination can't be reached from the
+	// starting room.
+	ErrNoPath = errors.New("no path exists between the rooms")
+
+	// ErrSearchLimit is returned when a search expands more rooms than allowed
+	// without reaching the destination.
+	ErrSearchLimit = errors.New("search limit reached before finding a path")
+)
+
+// Node identifies a room, any comparable value (like a string or number ID)
+// can be used.
+type Node interface{}
+
+// Exit is a one way connection from one room to another.
+type Exit struct {
+	// Name of the exit, typically the direction like "north".
+	Name string
+	// To is the room the exit leads to.
+	To Node
+	// Cost of moving through the exit, values less than or equal to 0 are
+	// treated as 1.
+	Cost float64
+	// Closed exits have a door that must be opened first.
+	Closed bool
+	// Locked exits have a door that must be unlocked first.
+	Locked bool
+}
+
+// Options control how the graph is searched.
+type Options struct {
+	// Exits returns the exits leading out of the given room, it's required.
+	Exits func(Node) ([]Exit, error)
+	// Heuristic estimates the cost of the remaining path from a room to the
+	// destination, it must never overestimate. A nil heuristic performs a
+	// uniform cost search.
+	Heuristic func(from, to Node) float64
+	// IgnoreClosed excludes exits with closed doors from the search.
+	IgnoreClosed bool
+	// CanUnlock allows locked exits to be used.
+	CanUnlock bool
+	// DoorCost is added to the cost of moving through a closed or locked
+	// exit.
+	DoorCost float64
+	// MaxNodes limits the number of rooms expanded, 0 uses DefaultMaxNodes.
+	MaxNodes int
+}
+
+// Step is a single move along a path.
+type Step struct {
+	Exit string
+	Room Node
+}
+
+// Path is the result of a successful search.
+type Path struct {
+	Start Node
+	Steps []Step
+	Cost  float64
+}
+
+// Rooms returns every room along the path including the start.
+func (p *Path) Rooms() []Node {
+	rooms := []Node{p.Start}
+	for _, step := range p.Steps {
+		rooms = append(rooms, step.Room)
+	}
+
+	return rooms
+}
+
+// Find searches for the cheapest path between from and to using A* (or a
+// uniform cost search if no heuristic is given).
+func Find(from, to Node, opts Options) (*Path, error) {
+	if opts.Exits == nil {
+		return nil, errors.New("path: Exits must be provided to search")
+	}
+
+	maxNodes := opts.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = DefaultMaxNodes
+	}
+
+	estimate := func(n Node) float64 {
+		if opts.Heuristic == nil {
+			return 0
+		}
+
+		return opts.Heuristic(n, to)
+	}
+
+	start := &searchNode{room: from, priority: estimate(from)}
+	best := map[Node]*searchNode{from: start}
+	open := &searchQueue{start}
+	closed := make(map[Node]bool)
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*searchNode)
+		if closed[current.room] {
+			continue
+		}
+
+		if current.room == to {
+			return current.path(from), nil
+		}
+
+		closed[current.room] = true
+		if len(closed) > maxNodes {
+			return nil, ErrSearchLimit
+		}
+
+		exits, err := opts.Exits(current.room)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, exit := range exits {
+			if closed[exit.To] || !opts.allows(exit) {
+				continue
+			}
+
+			cost := current.cost + opts.costOf(exit)
+			if prev, ok := best[exit.To]; ok && prev.cost <= cost {
+				continue
+			}
+
+			next := &searchNode{
+				room:     exit.To,
+				exit:     exit.Name,
+				parent:   current,
+				cost:     cost,
+				priority: cost + estimate(exit.To),
+			}
+			best[exit.To] = next
+			heap.Push(open, next)
+		}
+	}
+
+	return nil, ErrNoPath
+}
+
+// allows determines if the exit can be used based on the door options.
+func (o Options) allows(exit Exit) bool {
+	if exit.Locked && !o.CanUnlock {
+		return false
+	}
+
+	if exit.Closed && o.IgnoreClosed {
+		return false
+	}
+
+	return true
+}
+
+// costOf determines the cost of moving through the exit.
+func (o Options) costOf(exit Exit) float64 {
+	cost := exit.Cost
+	if cost <= 0 {
+		cost = 1
+	}
+
+	if exit.Closed || exit.Locked {
+		cost += o.DoorCost
+	}
+
+	return cost
+}
+
+// a room reached during the search along with how it was reached
+type searchNode struct {
+	room     Node
+	exit     string
+	parent   *searchNode
+	cost     float64
+	priority float64
+}
+
+// path walks back up the parents to build the path that reached this node
+func (n *searchNode) path(start Node) *Path {
+	var steps []Step
+	for cur := n; cur.parent != nil; cur = cur.parent {
+		steps = append(steps, Step{Exit: cur.exit, Room: cur.room})
+	}
+
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+
+	return &Path{Start: start, Steps: steps, Cost: n.cost}
+}
+
+// searchQueue is a min heap of search nodes ordered by priority.
+type searchQueue []*searchNode
+
+func (q searchQueue) Len() int {
+	return len(q)
+}
+
+func (q searchQueue) Less(i, j int) bool {
+	return q[i].priority < q[j].priority
+}
+
+func (q searchQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *searchQueue) Push(x interface{}) {
+	*q = append(*q, x.(*searchNode))
+}
+
+func (q *searchQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return n
+}
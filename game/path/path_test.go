@@ -0,0 +1,100 @@
+package path_test
+
+import (
+	. "github.com/bbuck/dragon-mud/game/path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path", func() {
+	// a small world:
+	//
+	//   a --north--> b --north--> c
+	//   a --east---> d --north--> c   (d to c has a locked door)
+	//   a --west---> e (cost 5) --north--> c
+	world := map[string][]Exit{
+		"a": {
+			{Name: "north", To: "b"},
+			{Name: "east", To: "d"},
+			{Name: "west", To: "e", Cost: 5},
+		},
+		"b": {{Name: "north", To: "c", Closed: true}},
+		"d": {{Name: "north", To: "c", Locked: true}},
+		"e": {{Name: "north", To: "c"}},
+	}
+	exits := func(n Node) ([]Exit, error) {
+		return world[n.(string)], nil
+	}
+
+	It("finds the cheapest path", func() {
+		p, err := Find("a", "c", Options{Exits: exits})
+
+		Ω(err).Should(BeNil())
+		Ω(p.Rooms()).Should(Equal([]Node{"a", "b", "c"}))
+		Ω(p.Steps[0].Exit).Should(Equal("north"))
+		Ω(p.Cost).Should(Equal(float64(2)))
+	})
+
+	It("avoids closed doors when asked", func() {
+		p, err := Find("a", "c", Options{Exits: exits, IgnoreClosed: true})
+
+		Ω(err).Should(BeNil())
+		Ω(p.Rooms()).Should(Equal([]Node{"a", "e", "c"}))
+		Ω(p.Cost).Should(Equal(float64(6)))
+	})
+
+	It("uses locked doors when able to unlock them", func() {
+		p, err := Find("a", "c", Options{Exits: exits, IgnoreClosed: true, CanUnlock: true, DoorCost: 1})
+
+		Ω(err).Should(BeNil())
+		Ω(p.Rooms()).Should(Equal([]Node{"a", "d", "c"}))
+		Ω(p.Cost).Should(Equal(float64(3)))
+	})
+
+	It("returns an empty path when already there", func() {
+		p, err := Find("a", "a", Options{Exits: exits})
+
+		Ω(err).Should(BeNil())
+		Ω(p.Steps).Should(BeEmpty())
+	})
+
+	It("reports unreachable rooms", func() {
+		_, err := Find("c", "a", Options{Exits: exits})
+
+		Ω(err).Should(Equal(ErrNoPath))
+	})
+
+	It("stops at the search limit", func() {
+		line := func(n Node) ([]Exit, error) {
+			return []Exit{{Name: "east", To: n.(int) + 1}}, nil
+		}
+		_, err := Find(0, -1, Options{Exits: line, MaxNodes: 50})
+
+		Ω(err).Should(Equal(ErrSearchLimit))
+	})
+
+	It("uses the heuristic to guide the search", func() {
+		expanded := 0
+		grid := func(n Node) ([]Exit, error) {
+			expanded++
+			x := n.(int)
+
+			return []Exit{{Name: "east", To: x + 1}, {Name: "west", To: x - 1}}, nil
+		}
+		distance := func(from, to Node) float64 {
+			d := from.(int) - to.(int)
+			if d < 0 {
+				d = -d
+			}
+
+			return float64(d)
+		}
+
+		p, err := Find(0, 10, Options{Exits: grid, Heuristic: distance})
+
+		Ω(err).Should(BeNil())
+		Ω(p.Steps).Should(HaveLen(10))
+		Ω(expanded).Should(BeNumerically("<=", 11))
+	})
+})
@@ -0,0 +1,89 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package perm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the roles command listing the roles and who has them,
+// and the role command admins give players roles with. Players must exist
+// to be given a role, exists may be nil to allow anyone.
+func NewCommands(m *Manager, exists func(name string) bool) []*command.Command {
+	return []*command.Command{
+		{
+			Name:   "roles",
+			Level:  command.Staff,
+			Help:   "Lists the roles players can have, what they can do and who has them.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				return ctx.Send(listing(m))
+			},
+		},
+		{
+			Name: "role",
+			Args: []command.Arg{
+				{Name: "player", Kind: command.Word},
+				{Name: "role", Kind: command.Word, Optional: true},
+			},
+			Level:      command.Admin,
+			Capability: Grant,
+			Help: "Shows the role of a player, or gives them the role, \"role <player> player\" " +
+				"takes their role away.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				name := ctx.String("player")
+				if !ctx.Has("role") {
+					return ctx.Send(fmt.Sprintf("%s is a %s.", name, m.RoleOf(name).Name))
+				}
+				if exists != nil && !exists(name) {
+					return ctx.Send(fmt.Sprintf("There's no player called %q.", name))
+				}
+				r, ok := m.Role(ctx.String("role"))
+				if !ok {
+					return ctx.Send(fmt.Sprintf("There's no role %q.", ctx.String("role")))
+				}
+				if r.Access() > ctx.Caller.Level() {
+					return ctx.Send("You can't give a role above your own.")
+				}
+				if err := m.Assign(name, r.Name); err != nil {
+					if refused, ok := err.(*item.Refused); ok {
+						return ctx.Send(refused.Message)
+					}
+
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("%s is now a %s.", name, r.Name))
+			},
+		},
+	}
+}
+
+// listing describes each role, its level and capabilities and who has it
+func listing(m *Manager) string {
+	holders := make(map[string][]string)
+	for name, role := range m.Staff() {
+		holders[role] = append(holders[role], name)
+	}
+
+	var lines []string
+	for _, r := range m.Roles() {
+		line := fmt.Sprintf("%s (%s)", r.Name, r.Access())
+		if len(r.Capabilities) > 0 {
+			line += ": " + strings.Join(r.Capabilities, ", ")
+		}
+		lines = append(lines, line)
+		if names := holders[r.Name]; len(names) > 0 {
+			sort.Strings(names)
+			lines = append(lines, "  held by "+strings.Join(names, ", "))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package perm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AssignEvent is emitted when a player is given a role, with their name, the
+// role and the role they had.
+const AssignEvent = "perm:assign"
+
+// Manager keeps the roles and who has them.
+type Manager struct {
+	roles map[string]Role
+	// saved are the roles read from the file, written back with the staff
+	saved   []Role
+	staff   map[string]string
+	file    string
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager with the default roles and no staff.
+func NewManager() *Manager {
+	m := &Manager{
+		roles: make(map[string]Role),
+		staff: make(map[string]string),
+		mutex: new(sync.RWMutex),
+	}
+	for _, r := range Defaults {
+		m.Define(r)
+	}
+
+	return m
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's roles.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager()
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetFile sets where roles are saved, defining the roles and giving the
+// staff those saved there. Missing files are ignored.
+func (m *Manager) SetFile(path string) error {
+	m.mutex.Lock()
+	m.file = path
+	m.mutex.Unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, r := range f.Roles {
+		if err := m.Define(r); err != nil {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.saved = f.Roles
+	for name, role := range f.Staff {
+		if _, ok := m.roles[strings.ToLower(role)]; !ok {
+			return fmt.Errorf("%s has the role %q, which isn't defined", name, role)
+		}
+		m.staff[name] = strings.ToLower(role)
+	}
+
+	return nil
+}
+
+// Define adds the role, replacing any with its name. Those who have it keep
+// it with its new level and capabilities.
+func (m *Manager) Define(r Role) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	r.Name = strings.ToLower(r.Name)
+	r.Capabilities = append([]string(nil), r.Capabilities...)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.roles[r.Name] = r
+
+	return nil
+}
+
+// Role returns the role with the name.
+func (m *Manager) Role(name string) (Role, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	r, ok := m.roles[strings.ToLower(name)]
+
+	return r, ok
+}
+
+// Roles returns every role, from the lowest level to the highest and by name
+// within a level.
+func (m *Manager) Roles() []Role {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	roles := make([]Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		roles = append(roles, r)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Access() != roles[j].Access() {
+			return roles[i].Access() < roles[j].Access()
+		}
+
+		return roles[i].Name < roles[j].Name
+	})
+
+	return roles
+}
+
+// Staff returns the names of the players with a role other than player,
+// mapped to the role they have.
+func (m *Manager) Staff() map[string]string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	staff := make(map[string]string, len(m.staff))
+	for name, role := range m.staff {
+		staff[name] = role
+	}
+
+	return staff
+}
+
+// RoleOf returns the role of the player, the player role if they haven't
+// been given one.
+func (m *Manager) RoleOf(player string) Role {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if name, ok := m.find(player); ok {
+		if r, ok := m.roles[m.staff[name]]; ok {
+			return r
+		}
+	}
+
+	return m.roles[PlayerRole]
+}
+
+// Level returns the command level of the player.
+func (m *Manager) Level(player string) command.Level {
+	return m.RoleOf(player).Access()
+}
+
+// Can is true if the player's role has the capability.
+func (m *Manager) Can(player, capability string) bool {
+	return m.RoleOf(player).Can(capability)
+}
+
+// Assign gives the player the role, the player role takes away the one they
+// had. Roles are saved as they're given.
+func (m *Manager) Assign(player, role string) error {
+	role = strings.ToLower(role)
+	if strings.TrimSpace(player) == "" {
+		return refuse("Who should have the role?")
+	}
+
+	m.mutex.Lock()
+	if _, ok := m.roles[role]; !ok {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("There's no role %q.", role))
+	}
+	was := PlayerRole
+	if name, ok := m.find(player); ok {
+		was = m.staff[name]
+		delete(m.staff, name)
+	}
+	if role != PlayerRole {
+		m.staff[player] = role
+	}
+	emitter := m.emitter
+	m.mutex.Unlock()
+
+	if emitter != nil {
+		emitter.Emit(AssignEvent, events.Data{
+			"player": player,
+			"role":   role,
+			"was":    was,
+		})
+	}
+
+	return m.save()
+}
+
+// find returns the name the player's role is kept under, in any case, the
+// mutex must be locked
+func (m *Manager) find(player string) (string, bool) {
+	for name := range m.staff {
+		if strings.EqualFold(name, player) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// save writes the roles read from the file and the staff to the file, if
+// there is one
+func (m *Manager) save() error {
+	m.mutex.RLock()
+	path := m.file
+	f := File{Roles: m.saved, Staff: make(map[string]string, len(m.staff))}
+	for name, role := range m.staff {
+		f.Staff[name] = role
+	}
+	m.mutex.RUnlock()
+
+	if path == "" {
+		return nil
+	}
+	contents, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0644)
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
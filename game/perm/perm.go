@@ -0,0 +1,92 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package perm gives players roles, like builder or admin. A role sets the
+// command level of those who have it and the capabilities they have on top
+// of it, such as going anywhere or forcing others to act, so the game's
+// staff can be given exactly the powers they need. Players without a role
+// are just players.
+package perm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// Capabilities the game's own commands need, scripts may require others.
+const (
+	Build      = "build"
+	Goto       = "goto"
+	Transfer   = "transfer"
+	Force      = "force"
+	Shutdown   = "shutdown"
+	Reboot     = "reboot"
+	Copyover   = "copyover"
+	Grant      = "grant"
+	Ban        = "ban"
+	Reset      = "reset"
+	Checkpoint = "checkpoint"
+	Rollback   = "rollback"
+	// All is every capability, including those scripts make up.
+	All = "*"
+)
+
+// PlayerRole is the role of everyone who hasn't been given another.
+const PlayerRole = "player"
+
+// Role is a rank players are given.
+type Role struct {
+	Name string `yaml:"name"`
+	// Level is the command level of those with the role, "player",
+	// "builder", "staff" or "admin".
+	Level        string   `yaml:"level,omitempty"`
+	Capabilities []string `yaml:"capabilities,omitempty"`
+}
+
+// Defaults are the roles the game starts with, files and scripts can change
+// them or add more.
+var Defaults = []Role{
+	{Name: PlayerRole},
+	{Name: "builder", Level: "builder", Capabilities: []string{Build}},
+	{Name: "staff", Level: "staff", Capabilities: []string{Build, Goto, Transfer, Ban, Reset}},
+	{Name: "admin", Level: "admin", Capabilities: []string{All}},
+}
+
+// Access returns the command level of those with the role, player if its
+// level isn't one.
+func (r Role) Access() command.Level {
+	level, _ := command.ParseLevel(r.Level)
+
+	return level
+}
+
+// Can is true if the role has the capability, or all of them.
+func (r Role) Can(capability string) bool {
+	for _, c := range r.Capabilities {
+		if c == All || strings.EqualFold(c, capability) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Role) validate() error {
+	if strings.TrimSpace(r.Name) == "" || strings.ContainsAny(r.Name, " \t") {
+		return errors.New("roles must be named with a single word")
+	}
+	if _, err := command.ParseLevel(r.Level); err != nil {
+		return fmt.Errorf("role %q: %s", r.Name, err)
+	}
+
+	return nil
+}
+
+// File is how roles and who has them are saved, staff maps the names of
+// players to their role.
+type File struct {
+	Roles []Role            `yaml:"roles,omitempty"`
+	Staff map[string]string `yaml:"staff,omitempty"`
+}
@@ -0,0 +1,117 @@
+package perm_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/perm"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// admin is a caller giving out roles who remembers what they're told
+type admin struct {
+	level command.Level
+	sent  []string
+}
+
+func (a *admin) ID() string {
+	return "admin"
+}
+
+func (a *admin) Level() command.Level {
+	return a.level
+}
+
+func (a *admin) Send(text string) error {
+	a.sent = append(a.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Perm", func() {
+	var (
+		m   *Manager
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "perm")
+		Ω(err).ShouldNot(HaveOccurred())
+		m = NewManager()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("gives players the player role until they're given another", func() {
+		Ω(m.RoleOf("Ann").Name).Should(Equal(PlayerRole))
+		Ω(m.Level("Ann")).Should(Equal(command.Player))
+		Ω(m.Can("Ann", Build)).Should(BeFalse())
+
+		Ω(m.Assign("Ann", "Staff")).Should(Succeed())
+		Ω(m.Level("ann")).Should(Equal(command.Staff))
+		Ω(m.Can("ANN", Goto)).Should(BeTrue())
+		Ω(m.Can("Ann", Force)).Should(BeFalse())
+
+		Ω(m.Assign("Ann", "admin")).Should(Succeed())
+		Ω(m.Can("Ann", "anything")).Should(BeTrue())
+		Ω(m.Assign("ann", PlayerRole)).Should(Succeed())
+		Ω(m.Staff()).Should(BeEmpty())
+		Ω(m.Assign("Ann", "king")).Should(MatchError("There's no role \"king\"."))
+	})
+
+	It("defines roles, refusing levels that don't exist", func() {
+		Ω(m.Define(Role{Name: "Warden", Level: "staff", Capabilities: []string{Goto}})).Should(Succeed())
+		Ω(m.Define(Role{Name: "jester", Level: "fool"})).ShouldNot(Succeed())
+		Ω(m.Define(Role{Name: "two words"})).ShouldNot(Succeed())
+
+		var names []string
+		for _, r := range m.Roles() {
+			names = append(names, r.Name)
+		}
+		Ω(names).Should(Equal([]string{"player", "builder", "staff", "warden", "admin"}))
+	})
+
+	It("saves the staff with the roles read from the file", func() {
+		path := filepath.Join(dir, "staff.yml")
+		Ω(ioutil.WriteFile(path, []byte("roles:\n  - name: warden\n    level: staff\n    capabilities: [goto]\nstaff:\n  Bob: warden\n"), 0644)).Should(Succeed())
+		Ω(m.SetFile(path)).Should(Succeed())
+		Ω(m.Can("bob", Goto)).Should(BeTrue())
+
+		Ω(m.Assign("Ann", "builder")).Should(Succeed())
+		loaded := NewManager()
+		Ω(loaded.SetFile(path)).Should(Succeed())
+		Ω(loaded.Staff()).Should(Equal(map[string]string{"Ann": "builder", "Bob": "warden"}))
+		Ω(loaded.Level("bob")).Should(Equal(command.Staff))
+	})
+
+	It("lets admins give roles no higher than their own", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(name string) bool { return name != "Nobody" }) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+		a := &admin{level: command.Admin}
+
+		d.Dispatch(a, "role Ann staff")
+		d.Dispatch(a, "role Ann")
+		d.Dispatch(a, "role Nobody staff")
+		d.Dispatch(a, "roles")
+		Ω(a.sent).Should(Equal([]string{
+			"Ann is now a staff.",
+			"Ann is a staff.",
+			"There's no player called \"Nobody\".",
+			"player (player)\nbuilder (builder): build\nstaff (staff): build, goto, transfer, ban, reset\n  held by Ann\nadmin (admin): *",
+		}))
+
+		Ω(m.Define(Role{Name: "owner", Level: "admin", Capabilities: []string{All}})).Should(Succeed())
+		a.level = command.Staff
+		Ω(d.Dispatch(a, "role Ann owner")).Should(Equal(command.ErrUnknown))
+	})
+})
@@ -0,0 +1,537 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound is returned when there's no player or character with a name.
+var ErrNotFound = errors.New("no such player")
+
+// Store persists players, keyed by their lower case name.
+type Store interface {
+	// Save creates or replaces the player's record.
+	Save(r Record) error
+	// Load returns the player's record, or ErrNotFound.
+	Load(name string) (Record, error)
+}
+
+// Lister is implemented by stores that can list the players they hold.
+type Lister interface {
+	// Names returns the lower case names of every player saved, sorted.
+	Names() ([]string, error)
+}
+
+// DirStore saves each player as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+	// Upgrader upgrades old saves as they're loaded, the game's Upgrades if
+	// it's nil.
+	Upgrader *Upgrader
+}
+
+// NewDirStore creates a store saving players to the directory, the directory
+// is created when the first player is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Save writes the record to <name>.json, through a temporary file so a crash
+// never leaves a partially written player behind.
+func (s *DirStore) Save(r Record) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	r.Version = upgraderOr(s.Upgrader).Current()
+	contents, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, strings.ToLower(r.Name)+".json")
+	return fs.WriteFile(path, contents, 0600)
+}
+
+// Load reads the player's file.
+func (s *DirStore) Load(name string) (Record, error) {
+	var r Record
+	if strings.ContainsAny(name, `/\.`) {
+		return r, ErrNotFound
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, strings.ToLower(name)+".json"))
+	if os.IsNotExist(err) {
+		return r, ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+
+	return upgraderOr(s.Upgrader).Decode(contents)
+}
+
+// Names lists the players with files in the directory.
+func (s *DirStore) Names() ([]string, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// upgraderOr returns the upgrader, or the game's if it's nil
+func upgraderOr(u *Upgrader) *Upgrader {
+	if u == nil {
+		return Upgrades()
+	}
+
+	return u
+}
+
+// MemoryStore keeps players in memory, they're lost when the server stops.
+type MemoryStore struct {
+	records map[string]Record
+	mutex   *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]Record),
+		mutex:   new(sync.Mutex),
+	}
+}
+
+// Save stores a copy of the record.
+func (s *MemoryStore) Save(r Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[strings.ToLower(r.Name)] = r.copy()
+
+	return nil
+}
+
+// Load returns a copy of the record.
+func (s *MemoryStore) Load(name string) (Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.records[strings.ToLower(name)]
+	if !ok {
+		return r, ErrNotFound
+	}
+
+	return r.copy(), nil
+}
+
+// Names lists the players stored.
+func (s *MemoryStore) Names() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := make([]string, 0, len(s.records))
+	for name := range s.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DataStore saves players in the "players" bucket of a data store, like the
+// game's database.
+type DataStore struct {
+	// Upgrader upgrades old saves as they're loaded, the game's Upgrades if
+	// it's nil.
+	Upgrader *Upgrader
+	bucket   *data.Bucket
+}
+
+// NewDataStore creates a store saving players in the data store.
+func NewDataStore(s data.Store) *DataStore {
+	return &DataStore{bucket: data.NewBucket(s, "players")}
+}
+
+// Save stores the record as JSON.
+func (s *DataStore) Save(r Record) error {
+	r.Version = upgraderOr(s.Upgrader).Current()
+
+	return s.bucket.Save(strings.ToLower(r.Name), r)
+}
+
+// Load reads the player's record.
+func (s *DataStore) Load(name string) (Record, error) {
+	var contents json.RawMessage
+	err := s.bucket.Load(strings.ToLower(name), &contents)
+	if err == data.ErrNotFound {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	return upgraderOr(s.Upgrader).Decode(contents)
+}
+
+// Names lists the players in the bucket.
+func (s *DataStore) Names() ([]string, error) {
+	names, err := s.bucket.Keys()
+	sort.Strings(names)
+
+	return names, err
+}
+
+// Manager keeps the players in the game, loading them when they enter and
+// saving them as they change.
+type Manager struct {
+	store      Store
+	characters character.Store
+	players    map[string]*Player
+	now        func() time.Time
+	mutex      *sync.Mutex
+	saving     *sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewManager creates a manager saving players to the store. Characters that
+// were never played are loaded from the character store, which may be nil.
+func NewManager(s Store, characters character.Store) *Manager {
+	return &Manager{
+		store:      s,
+		characters: characters,
+		players:    make(map[string]*Player),
+		now:        time.Now,
+		mutex:      new(sync.Mutex),
+		saving:     new(sync.Mutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager of the game's players, saved in the directory
+// given by the player.dir setting, or the game's database if the
+// player.store setting is "database".
+func Global() *Manager {
+	globalOnce.Do(func() {
+		if viper.GetString("player.store") == data.DatabaseStore {
+			globalManager = NewManager(NewDataStore(data.Storage()), character.GlobalStore())
+		} else {
+			globalManager = NewManager(NewDirStore(viper.GetString("player.dir")), character.GlobalStore())
+		}
+	})
+
+	return globalManager
+}
+
+// SetClock replaces the function used to get the current time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Load returns the player, loading them into the game if they aren't already.
+func (m *Manager) Load(name string) (*Player, error) {
+	key := strings.ToLower(name)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if p, ok := m.players[key]; ok {
+		return p, nil
+	}
+
+	var p *Player
+	r, err := m.store.Load(name)
+	switch {
+	case err == nil:
+		p = New(r)
+	case err == ErrNotFound && m.characters != nil:
+		c, cerr := m.characters.Load(name)
+		if cerr == character.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		if cerr != nil {
+			return nil, cerr
+		}
+		p = FromCharacter(c)
+	default:
+		return nil, err
+	}
+	m.players[key] = p
+
+	return p, nil
+}
+
+// Add puts the player into the game without loading them, replacing a player
+// with the same name.
+func (m *Manager) Add(p *Player) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.players[strings.ToLower(p.Name())] = p
+}
+
+// Get returns the player if they're in the game, nil if they aren't.
+func (m *Manager) Get(name string) *Player {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.players[strings.ToLower(name)]
+}
+
+// Lookup returns the record of the player with the name, whether or not
+// they're in the game. They aren't loaded into it.
+func (m *Manager) Lookup(name string) (Record, error) {
+	if p := m.Get(name); p != nil {
+		return p.Record(), nil
+	}
+	r, err := m.store.Load(name)
+	if err != ErrNotFound || m.characters == nil {
+		return r, err
+	}
+	c, err := m.characters.Load(name)
+	if err == character.ErrNotFound {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	return FromCharacter(c).Record(), nil
+}
+
+// Exists is true if there's a player or character with the name, whether or
+// not they're in the game.
+func (m *Manager) Exists(name string) bool {
+	_, err := m.Lookup(name)
+
+	return err == nil
+}
+
+// Players returns every player in the game, sorted by name.
+func (m *Manager) Players() []*Player {
+	m.mutex.Lock()
+	players := make([]*Player, 0, len(m.players))
+	for _, p := range m.players {
+		players = append(players, p)
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Name() < players[j].Name()
+	})
+
+	return players
+}
+
+// Records returns the record of every player, those in the game as they are
+// now. Players who aren't in the game are only included if the store is a
+// Lister, characters never played aren't.
+func (m *Manager) Records() ([]Record, error) {
+	var records []Record
+	online := make(map[string]bool)
+	for _, p := range m.Players() {
+		records = append(records, p.Record())
+		online[strings.ToLower(p.Name())] = true
+	}
+
+	if l, ok := m.store.(Lister); ok {
+		names, err := l.Names()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if online[name] {
+				continue
+			}
+			r, err := m.store.Load(name)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return strings.ToLower(records[i].Name) < strings.ToLower(records[j].Name)
+	})
+
+	return records, nil
+}
+
+// Restore puts the players back as the records have them, like when the game
+// is rolled back. Players in the game are changed in place and saved with
+// the next save, the rest are saved now. Players without a record are left
+// alone. Every record is restored even if some fail, the first error is
+// returned.
+func (m *Manager) Restore(records []Record) error {
+	var firstErr error
+	for _, r := range records {
+		if p := m.Get(r.Name); p != nil {
+			p.Restore(r)
+
+			continue
+		}
+		if err := m.store.Save(r); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Save saves the player if they changed since they were last saved.
+func (m *Manager) Save(p *Player) error {
+	m.saving.Lock()
+	defer m.saving.Unlock()
+
+	if !p.Dirty() {
+		return nil
+	}
+
+	m.mutex.Lock()
+	now := m.now().UTC()
+	m.mutex.Unlock()
+
+	r, changes := p.snapshot()
+	r.Saved = now
+	if err := m.store.Save(r); err != nil {
+		return err
+	}
+	p.markSaved(changes, now)
+
+	return nil
+}
+
+// SaveAll saves every player in the game that changed, returning the number
+// saved. Every player is tried, the first error is returned.
+func (m *Manager) SaveAll() (int, error) {
+	var (
+		saved    int
+		firstErr error
+	)
+	for _, p := range m.Players() {
+		if !p.Dirty() {
+			continue
+		}
+		if err := m.Save(p); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+		saved++
+	}
+
+	return saved, firstErr
+}
+
+// Unload saves the player and removes them from the game.
+func (m *Manager) Unload(name string) error {
+	p := m.Get(name)
+	if p == nil {
+		return nil
+	}
+	if err := m.Save(p); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.players, strings.ToLower(name))
+
+	return nil
+}
+
+// Start saves changed players from the background at the interval given,
+// until Stop is called.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(interval, m.stop, m.done)
+}
+
+// Stop stops saving players from the background and saves them one last
+// time.
+func (m *Manager) Stop() error {
+	m.mutex.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	_, err := m.SaveAll()
+
+	return err
+}
+
+func (m *Manager) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	log := logger.NewWithSource("players")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if n, err := m.SaveAll(); err != nil {
+				log.WithError(err).Error("Failed to save players.")
+			} else if n > 0 {
+				log.WithField("count", n).Debug("Saved players.")
+			}
+		}
+	}
+}
@@ -0,0 +1,690 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package player holds the characters being played. A Player is the one
+// representation of a character shared by the server and scripts, changes
+// made through it are tracked so only players that changed are saved.
+package player
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/quest"
+)
+
+// Record is everything saved about a player.
+type Record struct {
+	Name    string `json:"name"`
+	Account string `json:"account"`
+	Race    string `json:"race,omitempty"`
+	Class   string `json:"class,omitempty"`
+	// Stats are the player's numeric stats, like hit points and strength.
+	Stats map[string]int `json:"stats"`
+	// Location is the id of the room the player is in.
+	Location string `json:"location,omitempty"`
+	// Inventory holds the items the player carries.
+	Inventory item.List `json:"inventory"`
+	// Equipment holds the items the player wears, by slot.
+	Equipment map[string]item.Item `json:"equipment,omitempty"`
+	// Skills are how well the player knows each skill or spell they've
+	// learned, as a percent, by skill id.
+	Skills map[string]int `json:"skills,omitempty"`
+	// Languages are how well the player has learned each language, as a
+	// percent, by language id.
+	Languages map[string]int `json:"languages,omitempty"`
+	// Speaking is the id of the language the player speaks, empty for the
+	// game's default.
+	Speaking string `json:"speaking,omitempty"`
+	// Effects are the buffs and debuffs on the player.
+	Effects effect.List `json:"effects,omitempty"`
+	// Quests are the player's progress on the quests they've started, by
+	// quest id.
+	Quests map[string]quest.State `json:"quests,omitempty"`
+	// Conversation is where the player is in the conversation they're
+	// having, if they're having one.
+	Conversation *dialogue.State `json:"conversation,omitempty"`
+	// Channels are the player's standing on each channel they've joined,
+	// left or been muted on, by channel id.
+	Channels map[string]channels.Membership `json:"channels,omitempty"`
+	// Flags are named switches, like "afk" or "newbie".
+	Flags map[string]bool `json:"flags"`
+	// Vars are values scripts keep for the player, they must be encodable as
+	// JSON.
+	Vars map[string]interface{} `json:"vars"`
+	// Created is when the character was made.
+	Created time.Time `json:"created"`
+	// Saved is when the player was last saved.
+	Saved time.Time `json:"saved,omitempty"`
+	// Version is the version of the record the save was made with, older
+	// saves are upgraded as they're loaded.
+	Version int `json:"version,omitempty"`
+}
+
+// copy returns a deep copy of the record
+func (r Record) copy() Record {
+	stats := make(map[string]int, len(r.Stats))
+	for k, v := range r.Stats {
+		stats[k] = v
+	}
+	flags := make(map[string]bool, len(r.Flags))
+	for k, v := range r.Flags {
+		flags[k] = v
+	}
+	vars := make(map[string]interface{}, len(r.Vars))
+	for k, v := range r.Vars {
+		vars[k] = v
+	}
+	skills := make(map[string]int, len(r.Skills))
+	for k, v := range r.Skills {
+		skills[k] = v
+	}
+	languages := make(map[string]int, len(r.Languages))
+	for k, v := range r.Languages {
+		languages[k] = v
+	}
+	r.Stats, r.Skills, r.Languages, r.Flags, r.Vars = stats, skills, languages, flags, vars
+	r.Inventory = r.Inventory.Copy()
+	r.Equipment = copyEquipment(r.Equipment)
+	r.Effects = r.Effects.Copy()
+	quests := make(map[string]quest.State, len(r.Quests))
+	for id, s := range r.Quests {
+		quests[id] = s.Copy()
+	}
+	r.Quests = quests
+	memberships := make(map[string]channels.Membership, len(r.Channels))
+	for id, ms := range r.Channels {
+		memberships[id] = ms
+	}
+	r.Channels = memberships
+	if r.Conversation != nil {
+		conversation := *r.Conversation
+		r.Conversation = &conversation
+	}
+
+	return r
+}
+
+// copyEquipment returns a deep copy of the worn items
+func copyEquipment(eq map[string]item.Item) map[string]item.Item {
+	worn := make(map[string]item.Item, len(eq))
+	for slot, it := range eq {
+		worn[slot] = it.Copy()
+	}
+
+	return worn
+}
+
+// Player is a character in the game.
+type Player struct {
+	record  Record
+	changes uint64
+	saved   uint64
+	onStat  func(stat string, value int)
+	mutex   *sync.RWMutex
+}
+
+// New creates a player from a saved record.
+func New(r Record) *Player {
+	r = r.copy()
+
+	return &Player{
+		record: r,
+		mutex:  new(sync.RWMutex),
+	}
+}
+
+// FromCharacter creates a player for a newly made character, it's dirty as
+// it was never saved.
+func FromCharacter(c *character.Character) *Player {
+	p := New(Record{
+		Name:    c.Name,
+		Account: c.Account,
+		Race:    c.Race,
+		Class:   c.Class,
+		Stats:   c.Stats,
+		Created: c.Created,
+	})
+	p.changes = 1
+
+	return p
+}
+
+// Name returns the character's name.
+func (p *Player) Name() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Name
+}
+
+// Account returns the name of the player's account.
+func (p *Player) Account() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Account
+}
+
+// Race returns the name of the player's race.
+func (p *Player) Race() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Race
+}
+
+// Class returns the name of the player's class.
+func (p *Player) Class() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Class
+}
+
+// OnStat sets the function called with each stat as it changes, like to
+// update the player's prompt.
+func (p *Player) OnStat(fn func(stat string, value int)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.onStat = fn
+}
+
+// Stat returns the value of the stat, zero if it isn't set.
+func (p *Player) Stat(name string) int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Stats[name]
+}
+
+// Stats returns a copy of every stat.
+func (p *Player) Stats() map[string]int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	stats := make(map[string]int, len(p.record.Stats))
+	for k, v := range p.record.Stats {
+		stats[k] = v
+	}
+
+	return stats
+}
+
+// SetStat changes the stat.
+func (p *Player) SetStat(name string, value int) {
+	p.mutex.Lock()
+	if old, ok := p.record.Stats[name]; ok && old == value {
+		p.mutex.Unlock()
+
+		return
+	}
+	p.record.Stats[name] = value
+	p.changes++
+	fn := p.onStat
+	p.mutex.Unlock()
+
+	if fn != nil {
+		fn(name, value)
+	}
+}
+
+// AddStat adds to the stat, returning its new value.
+func (p *Player) AddStat(name string, delta int) int {
+	p.mutex.Lock()
+	value := p.record.Stats[name] + delta
+	p.mutex.Unlock()
+
+	p.SetStat(name, value)
+
+	return value
+}
+
+// Location returns the id of the room the player is in.
+func (p *Player) Location() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Location
+}
+
+// SetLocation moves the player to the room with the id.
+func (p *Player) SetLocation(room string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.record.Location != room {
+		p.record.Location = room
+		p.changes++
+	}
+}
+
+// Inventory returns the items the player carries.
+func (p *Player) Inventory() item.List {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Inventory.Copy()
+}
+
+// HasItem is true if the player carries the item with the id, or an item
+// made from the definition with the id.
+func (p *Player) HasItem(id string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return findItem(p.record.Inventory, id) >= 0
+}
+
+// AddItem gives the player the item.
+func (p *Player) AddItem(it item.Item) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.record.Inventory = p.record.Inventory.Add(it)
+	p.changes++
+}
+
+// RemoveItem takes the item with the id, or one made from the definition with
+// the id, from the player. It returns false if they didn't have it.
+func (p *Player) RemoveItem(id string) (item.Item, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	i := findItem(p.record.Inventory, id)
+	if i < 0 {
+		return item.Item{}, false
+	}
+	it := p.record.Inventory[i]
+	p.record.Inventory = append(p.record.Inventory[:i:i], p.record.Inventory[i+1:]...)
+	p.changes++
+
+	return it, true
+}
+
+// UpdateInventory changes the items the player carries with fn, nothing
+// changes if it fails.
+func (p *Player) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	items, err := fn(p.record.Inventory.Copy())
+	if err != nil {
+		return err
+	}
+	p.record.Inventory = items
+	p.changes++
+
+	return nil
+}
+
+// Equipment returns the items the player wears, by slot.
+func (p *Player) Equipment() map[string]item.Item {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return copyEquipment(p.record.Equipment)
+}
+
+// UpdateEquipment changes the items the player carries and wears with fn
+// together, nothing changes if it fails.
+func (p *Player) UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	items, eq, err := fn(p.record.Inventory.Copy(), copyEquipment(p.record.Equipment))
+	if err != nil {
+		return err
+	}
+	p.record.Inventory, p.record.Equipment = items, eq
+	p.changes++
+
+	return nil
+}
+
+// Skill returns how well the player knows the skill, as a percent, zero if
+// they never learned it.
+func (p *Player) Skill(id string) int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Skills[id]
+}
+
+// SetSkill changes how well the player knows the skill, zero forgets it.
+func (p *Player) SetSkill(id string, percent int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.record.Skills[id] == percent {
+		return
+	}
+	if percent <= 0 {
+		delete(p.record.Skills, id)
+	} else {
+		p.record.Skills[id] = percent
+	}
+	p.changes++
+}
+
+// Skills returns a copy of the skills the player knows.
+func (p *Player) Skills() map[string]int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	skills := make(map[string]int, len(p.record.Skills))
+	for k, v := range p.record.Skills {
+		skills[k] = v
+	}
+
+	return skills
+}
+
+// Language returns how well the player has learned the language, as a
+// percent, zero if they never learned it.
+func (p *Player) Language(id string) int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Languages[id]
+}
+
+// SetLanguage changes how well the player has learned the language, zero
+// forgets it.
+func (p *Player) SetLanguage(id string, percent int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.record.Languages[id] == percent {
+		return
+	}
+	if percent <= 0 {
+		delete(p.record.Languages, id)
+	} else {
+		p.record.Languages[id] = percent
+	}
+	p.changes++
+}
+
+// Languages returns a copy of the languages the player has learned.
+func (p *Player) Languages() map[string]int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	languages := make(map[string]int, len(p.record.Languages))
+	for k, v := range p.record.Languages {
+		languages[k] = v
+	}
+
+	return languages
+}
+
+// Speaking returns the id of the language the player speaks, empty for the
+// game's default.
+func (p *Player) Speaking() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Speaking
+}
+
+// SetSpeaking changes the language the player speaks.
+func (p *Player) SetSpeaking(id string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.record.Speaking == id {
+		return
+	}
+	p.record.Speaking = id
+	p.changes++
+}
+
+// Effects returns the buffs and debuffs on the player.
+func (p *Player) Effects() effect.List {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Effects.Copy()
+}
+
+// UpdateEffects changes the player's effects with fn.
+func (p *Player) UpdateEffects(fn func(effect.List) effect.List) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.record.Effects = fn(p.record.Effects.Copy())
+	p.changes++
+}
+
+// Quest returns the player's progress on the quest with the id.
+func (p *Player) Quest(id string) (quest.State, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	s, ok := p.record.Quests[id]
+
+	return s.Copy(), ok
+}
+
+// SetQuest changes the player's progress on the quest, a zero state forgets
+// it.
+func (p *Player) SetQuest(id string, s quest.State) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if s.Status == "" {
+		delete(p.record.Quests, id)
+	} else {
+		p.record.Quests[id] = s.Copy()
+	}
+	p.changes++
+}
+
+// Quests returns a copy of the player's progress on every quest they've
+// started.
+func (p *Player) Quests() map[string]quest.State {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	quests := make(map[string]quest.State, len(p.record.Quests))
+	for id, s := range p.record.Quests {
+		quests[id] = s.Copy()
+	}
+
+	return quests
+}
+
+// Conversation returns where the player is in the conversation they're
+// having.
+func (p *Player) Conversation() (dialogue.State, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.record.Conversation == nil {
+		return dialogue.State{}, false
+	}
+
+	return *p.record.Conversation, true
+}
+
+// SetConversation changes where the player is in their conversation, a zero
+// state ends it.
+func (p *Player) SetConversation(s dialogue.State) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if s == (dialogue.State{}) {
+		p.record.Conversation = nil
+	} else {
+		p.record.Conversation = &s
+	}
+	p.changes++
+}
+
+// Membership returns the player's standing on the channel with the id.
+func (p *Player) Membership(channel string) (channels.Membership, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	ms, ok := p.record.Channels[channel]
+
+	return ms, ok
+}
+
+// SetMembership changes the player's standing on the channel with the id.
+func (p *Player) SetMembership(channel string, ms channels.Membership) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.record.Channels[channel] = ms
+	p.changes++
+}
+
+// Flag is true if the flag is set.
+func (p *Player) Flag(name string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Flags[strings.ToLower(name)]
+}
+
+// SetFlag sets or clears the flag.
+func (p *Player) SetFlag(name string, on bool) {
+	name = strings.ToLower(name)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.record.Flags[name] == on {
+		return
+	}
+	if on {
+		p.record.Flags[name] = true
+	} else {
+		delete(p.record.Flags, name)
+	}
+	p.changes++
+}
+
+// Flags returns the names of the flags that are set, sorted.
+func (p *Player) Flags() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	flags := make([]string, 0, len(p.record.Flags))
+	for name := range p.record.Flags {
+		flags = append(flags, name)
+	}
+	sort.Strings(flags)
+
+	return flags
+}
+
+// Var returns a value kept for the player by scripts.
+func (p *Player) Var(key string) interface{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.Vars[key]
+}
+
+// SetVar keeps a value for the player, nil removes it.
+func (p *Player) SetVar(key string, value interface{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if value == nil {
+		delete(p.record.Vars, key)
+	} else {
+		p.record.Vars[key] = value
+	}
+	p.changes++
+}
+
+// Restore replaces everything about the player with the record, keeping
+// their name, like when the game is rolled back to a checkpoint. The stats
+// that changed are passed to the OnStat function.
+func (p *Player) Restore(r Record) {
+	r = r.copy()
+
+	p.mutex.Lock()
+	r.Name = p.record.Name
+	old := p.record.Stats
+	p.record = r
+	p.changes++
+	fn := p.onStat
+	p.mutex.Unlock()
+
+	if fn == nil {
+		return
+	}
+	names := make([]string, 0, len(r.Stats))
+	for name, value := range r.Stats {
+		if old[name] != value {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, r.Stats[name])
+	}
+}
+
+// Dirty is true if the player changed since they were last saved.
+func (p *Player) Dirty() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.changes != p.saved
+}
+
+// Record returns a copy of everything saved about the player.
+func (p *Player) Record() Record {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.copy()
+}
+
+// snapshot returns the record and the changes it includes
+func (p *Player) snapshot() (Record, uint64) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.record.copy(), p.changes
+}
+
+// markSaved records that changes up to the given count were saved, later
+// changes leave the player dirty
+func (p *Player) markSaved(changes uint64, at time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.saved = changes
+	p.record.Saved = at
+}
+
+// findItem returns the index of the item with the id, or made from the
+// definition with the id
+func findItem(items item.List, id string) int {
+	for i, it := range items {
+		if it.ID == id {
+			return i
+		}
+	}
+	for i, it := range items {
+		if it.Proto == id {
+			return i
+		}
+	}
+
+	return -1
+}
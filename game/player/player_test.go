@@ -0,0 +1,437 @@
+package player_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/quest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// failingStore fails every save
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) Save(Record) error {
+	return errors.New("disk full")
+}
+
+var _ = Describe("Player", func() {
+	var p *Player
+
+	BeforeEach(func() {
+		p = New(Record{Name: "Fili", Stats: map[string]int{"hp": 20}})
+	})
+
+	It("starts clean", func() {
+		Ω(p.Dirty()).Should(BeFalse())
+	})
+
+	It("tracks stat changes", func() {
+		var changed []string
+		p.OnStat(func(stat string, value int) {
+			changed = append(changed, stat)
+		})
+
+		p.SetStat("hp", 20)
+		Ω(p.Dirty()).Should(BeFalse())
+
+		Ω(p.AddStat("hp", -5)).Should(Equal(15))
+		p.SetStat("mana", 10)
+		Ω(p.Dirty()).Should(BeTrue())
+		Ω(p.Stats()).Should(Equal(map[string]int{"hp": 15, "mana": 10}))
+		Ω(changed).Should(Equal([]string{"hp", "mana"}))
+	})
+
+	It("keeps an inventory", func() {
+		p.AddItem(item.Item{ID: "sword-1", Proto: "sword", Name: "a sword"})
+		p.AddItem(item.Item{ID: "shield-1", Proto: "shield", Name: "a shield"})
+
+		Ω(p.HasItem("sword-1")).Should(BeTrue())
+		Ω(p.HasItem("shield")).Should(BeTrue())
+		_, ok := p.RemoveItem("sword")
+		Ω(ok).Should(BeTrue())
+		_, ok = p.RemoveItem("sword-1")
+		Ω(ok).Should(BeFalse())
+		Ω(p.Inventory()).Should(Equal(item.List{{ID: "shield-1", Proto: "shield", Name: "a shield"}}))
+	})
+
+	It("wears items from its inventory", func() {
+		p.AddItem(item.Item{ID: "helm-1", Proto: "helm", Name: "a helm"})
+
+		err := p.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+			inv, helm, err := inv.Take("helm-1", 1)
+			eq["head"] = helm
+
+			return inv, eq, err
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Inventory()).Should(BeEmpty())
+		Ω(p.Equipment()).Should(HaveKey("head"))
+		Ω(p.Record().Equipment["head"].Name).Should(Equal("a helm"))
+	})
+
+	It("learns and forgets skills", func() {
+		p.SetSkill("kick", 40)
+		p.SetSkill("bash", 10)
+		p.SetSkill("bash", 0)
+
+		Ω(p.Skill("kick")).Should(Equal(40))
+		Ω(p.Skills()).Should(Equal(map[string]int{"kick": 40}))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("learns and speaks languages", func() {
+		p.SetLanguage("elvish", 30)
+		p.SetLanguage("orcish", 5)
+		p.SetLanguage("orcish", 0)
+		p.SetSpeaking("elvish")
+
+		Ω(p.Language("elvish")).Should(Equal(30))
+		Ω(p.Languages()).Should(Equal(map[string]int{"elvish": 30}))
+		Ω(p.Record().Speaking).Should(Equal("elvish"))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("keeps effects in its record", func() {
+		p.UpdateEffects(func(l effect.List) effect.List {
+			return append(l, effect.Effect{Name: "armor", Ticks: 4, Stats: map[string]int{"ac": 2}})
+		})
+
+		r := p.Record()
+		r.Effects[0].Stats["ac"] = 10
+		Ω(p.Effects()[0].Stats["ac"]).Should(Equal(2))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("tracks quests", func() {
+		p.SetQuest("rats", quest.State{Status: quest.Active, Progress: []int{2}})
+		p.SetQuest("wolves", quest.State{Status: quest.Completed, Finished: 1})
+		p.SetQuest("wolves", quest.State{})
+
+		s, ok := p.Quest("rats")
+		Ω(ok).Should(BeTrue())
+		s.Progress[0] = 5
+		Ω(p.Quests()).Should(Equal(map[string]quest.State{"rats": {Status: quest.Active, Progress: []int{2}}}))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("remembers its conversation", func() {
+		_, talking := p.Conversation()
+		Ω(talking).Should(BeFalse())
+
+		p.SetConversation(dialogue.State{Tree: "innkeeper", Node: "start", Speaker: "the innkeeper"})
+		Ω(p.Record().Conversation).Should(Equal(&dialogue.State{Tree: "innkeeper", Node: "start", Speaker: "the innkeeper"}))
+		p.SetConversation(dialogue.State{})
+		_, talking = p.Conversation()
+		Ω(talking).Should(BeFalse())
+	})
+
+	It("remembers its channels", func() {
+		_, ok := p.Membership("gossip")
+		Ω(ok).Should(BeFalse())
+
+		p.SetMembership("gossip", channels.Membership{Joined: true, Muted: true})
+		ms, ok := p.Membership("gossip")
+		Ω(ok).Should(BeTrue())
+		Ω(ms.Muted).Should(BeTrue())
+		Ω(p.Record().Channels).Should(HaveKey("gossip"))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("sets flags and variables", func() {
+		p.SetFlag("AFK", true)
+		p.SetFlag("newbie", true)
+		p.SetFlag("newbie", false)
+		p.SetVar("quest", "dragon")
+
+		Ω(p.Flag("afk")).Should(BeTrue())
+		Ω(p.Flags()).Should(Equal([]string{"afk"}))
+		Ω(p.Var("quest")).Should(Equal("dragon"))
+	})
+
+	It("returns copies of its record", func() {
+		r := p.Record()
+		r.Stats["hp"] = 1
+
+		Ω(p.Stat("hp")).Should(Equal(20))
+	})
+
+	It("starts dirty when made from a new character", func() {
+		p = FromCharacter(&character.Character{Name: "Kili", Race: "Dwarf", Stats: map[string]int{"str": 15}})
+
+		Ω(p.Dirty()).Should(BeTrue())
+		Ω(p.Race()).Should(Equal("Dwarf"))
+		Ω(p.Stat("str")).Should(Equal(15))
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		store      *MemoryStore
+		characters *character.MemoryStore
+		m          *Manager
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		store = NewMemoryStore()
+		characters = character.NewMemoryStore()
+		m = NewManager(store, characters)
+		now = time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time {
+			return now
+		})
+	})
+
+	It("loads new characters and saves them", func() {
+		characters.Save(&character.Character{Name: "Fili", Account: "Thorin"})
+
+		p, err := m.Load("fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(m.Get("FILI")).Should(Equal(p))
+		Ω(m.Save(p)).Should(Succeed())
+		Ω(p.Dirty()).Should(BeFalse())
+
+		r, err := store.Load("Fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Account).Should(Equal("Thorin"))
+		Ω(r.Saved).Should(Equal(now))
+	})
+
+	It("loads saved players over their characters", func() {
+		characters.Save(&character.Character{Name: "Fili"})
+		store.Save(Record{Name: "Fili", Location: "hall"})
+
+		p, err := m.Load("Fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Location()).Should(Equal("hall"))
+	})
+
+	It("saves players in a data store", func() {
+		characters.Save(&character.Character{Name: "Fili", Account: "Thorin"})
+		m = NewManager(NewDataStore(data.NewMemoryStore()), characters)
+
+		p, err := m.Load("Fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		p.SetLocation("hall")
+		Ω(m.Unload("Fili")).Should(Succeed())
+
+		r, err := m.Lookup("fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Location).Should(Equal("hall"))
+		Ω(r.Account).Should(Equal("Thorin"))
+	})
+
+	It("fails for unknown characters", func() {
+		_, err := m.Load("nobody")
+
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+
+	It("knows who exists without loading them", func() {
+		characters.Save(&character.Character{Name: "Fili"})
+		store.Save(Record{Name: "Kili"})
+
+		Ω(m.Exists("fili")).Should(BeTrue())
+		Ω(m.Exists("Kili")).Should(BeTrue())
+		Ω(m.Exists("nobody")).Should(BeFalse())
+		r, err := m.Lookup("kili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Name).Should(Equal("Kili"))
+		Ω(m.Get("fili")).Should(BeNil())
+	})
+
+	It("only saves players that changed", func() {
+		a := New(Record{Name: "Fili"})
+		b := New(Record{Name: "Kili"})
+		m.Add(a)
+		m.Add(b)
+		b.SetLocation("gate")
+
+		Ω(m.SaveAll()).Should(Equal(1))
+		Ω(m.SaveAll()).Should(Equal(0))
+		_, err := store.Load("Fili")
+		Ω(err).Should(Equal(ErrNotFound))
+	})
+
+	It("saves players as they're unloaded", func() {
+		p := New(Record{Name: "Fili"})
+		m.Add(p)
+		p.SetFlag("afk", true)
+
+		Ω(m.Unload("fili")).Should(Succeed())
+		Ω(m.Get("fili")).Should(BeNil())
+		r, err := store.Load("fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Flags).Should(HaveKey("afk"))
+	})
+
+	It("keeps players dirty when saving fails", func() {
+		m = NewManager(failingStore{store}, nil)
+		p := New(Record{Name: "Fili"})
+		m.Add(p)
+		p.SetStat("hp", 1)
+
+		_, err := m.SaveAll()
+		Ω(err).Should(HaveOccurred())
+		Ω(p.Dirty()).Should(BeTrue())
+		Ω(m.Unload("fili")).ShouldNot(Succeed())
+		Ω(m.Get("fili")).ShouldNot(BeNil())
+	})
+
+	It("restores players in and out of the game", func() {
+		fili := New(Record{Name: "Fili", Stats: map[string]int{"hp": 20, "gold": 5}})
+		m.Add(fili)
+		store.Save(Record{Name: "Kili", Location: "gate"})
+
+		records, err := m.Records()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(records).Should(HaveLen(2))
+		Ω(records[0].Name).Should(Equal("Fili"))
+		Ω(records[1].Location).Should(Equal("gate"))
+
+		var changed []string
+		fili.OnStat(func(stat string, value int) {
+			changed = append(changed, stat)
+		})
+		fili.SetStat("gold", 500)
+		fili.AddItem(item.Item{ID: "duped-1", Name: "a duped gem"})
+		store.Save(Record{Name: "Kili", Location: "vault"})
+		changed = nil
+
+		Ω(m.Restore(records)).Should(Succeed())
+		Ω(fili.Stat("gold")).Should(Equal(5))
+		Ω(fili.Inventory()).Should(BeEmpty())
+		Ω(fili.Dirty()).Should(BeTrue())
+		Ω(changed).Should(Equal([]string{"gold"}))
+		r, err := store.Load("kili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Location).Should(Equal("gate"))
+	})
+
+	It("autosaves in the background", func() {
+		p := New(Record{Name: "Fili"})
+		m.Add(p)
+		m.Start(5 * time.Millisecond)
+		defer m.Stop()
+		p.SetStat("hp", 3)
+
+		Eventually(p.Dirty).Should(BeFalse())
+	})
+})
+
+var _ = Describe("DirStore", func() {
+	It("saves and loads records", func() {
+		dir, err := ioutil.TempDir("", "players")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		store := NewDirStore(dir)
+
+		Ω(store.Save(Record{Name: "Fili", Inventory: item.List{{ID: "sword-1", Name: "a sword", Count: 2}}, Vars: map[string]interface{}{"n": 1}})).Should(Succeed())
+		r, err := store.Load("fili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Inventory).Should(Equal(item.List{{ID: "sword-1", Name: "a sword", Count: 2}}))
+		Ω(r.Vars["n"]).Should(Equal(float64(1)))
+
+		_, err = store.Load("../fili")
+		Ω(err).Should(Equal(ErrNotFound))
+		Ω(store.Names()).Should(Equal([]string{"fili"}))
+	})
+})
+
+var _ = Describe("Upgrader", func() {
+	var (
+		u     *Upgrader
+		dir   string
+		store *DirStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "players")
+		Ω(err).ShouldNot(HaveOccurred())
+		u = NewUpgrader()
+		store = NewDirStore(dir)
+		store.Upgrader = u
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("upgrades old saves as they're loaded", func() {
+		Ω(store.Save(Record{Name: "Kili", Stats: map[string]int{"hp": 10}})).Should(Succeed())
+		Ω(u.Register(Upgrade{Version: 2, Name: "health", Up: func(save map[string]interface{}) error {
+			stats := save["stats"].(map[string]interface{})
+			stats["health"] = stats["hp"]
+			delete(stats, "hp")
+
+			return nil
+		}})).Should(Succeed())
+		Ω(u.Register(Upgrade{Version: 1, Name: "newbie", Up: func(save map[string]interface{}) error {
+			save["flags"] = map[string]interface{}{"newbie": true}
+
+			return nil
+		}})).Should(Succeed())
+		Ω(u.Current()).Should(Equal(2))
+
+		r, err := store.Load("kili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Version).Should(Equal(2))
+		Ω(r.Stats).Should(Equal(map[string]int{"health": 10}))
+		Ω(r.Flags).Should(Equal(map[string]bool{"newbie": true}))
+
+		r.Flags = nil
+		Ω(store.Save(r)).Should(Succeed())
+		r, err = store.Load("kili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Flags).Should(BeEmpty())
+	})
+
+	It("leaves saves alone that can't be upgraded", func() {
+		Ω(store.Save(Record{Name: "Kili"})).Should(Succeed())
+		u.Register(Upgrade{Version: 1, Name: "broken", Up: func(map[string]interface{}) error {
+			return errors.New("no stats")
+		}})
+
+		_, err := store.Load("kili")
+		Ω(err).Should(MatchError("upgrading Kili to version 1 (broken): no stats"))
+
+		u.Unregister(1)
+		r, err := store.Load("kili")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Version).Should(Equal(0))
+	})
+
+	It("refuses saves newer than it knows", func() {
+		up := Upgrade{Version: 3, Name: "mana", Up: func(map[string]interface{}) error { return nil }}
+		u.Register(up)
+		Ω(store.Save(Record{Name: "Kili"})).Should(Succeed())
+		u.Unregister(3)
+
+		_, err := store.Load("kili")
+		Ω(err).Should(Equal(&VersionError{Name: "Kili", Version: 3, Current: 0}))
+	})
+
+	It("keeps one upgrade to a version", func() {
+		up := func(map[string]interface{}) error { return nil }
+		Ω(u.Register(Upgrade{Version: 1, Name: "a", Up: up})).Should(Succeed())
+		Ω(u.Register(Upgrade{Version: 1, Name: "a", Up: up})).Should(Succeed())
+		Ω(u.Register(Upgrade{Version: 1, Name: "b", Up: up})).Should(Equal(ErrVersionTaken))
+		Ω(u.Register(Upgrade{Version: 0, Name: "c", Up: up})).ShouldNot(Succeed())
+		Ω(u.List()).Should(HaveLen(1))
+	})
+})
@@ -0,0 +1,195 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrVersionTaken is returned when registering an upgrade to a version
+// another upgrade already has.
+var ErrVersionTaken = errors.New("another upgrade has the version")
+
+// UpgradeFunc changes a save of the version before its upgrade into one of
+// the upgrade's version. The save is the player's record decoded from JSON,
+// it's changed in place.
+type UpgradeFunc func(save map[string]interface{}) error
+
+// Upgrade changes saves made before a change to the record, like a renamed
+// stat or a field moved into another, so they still load.
+type Upgrade struct {
+	// Version is the version saves have once upgraded, upgrades run from the
+	// lowest version to the highest.
+	Version int
+	Name    string
+	Up      UpgradeFunc
+}
+
+// VersionError is returned when loading a save newer than the upgrades know
+// about, like one made before the game was rolled back to older code. It's
+// left alone so it isn't saved over without what the newer code added.
+type VersionError struct {
+	Name    string
+	Version int
+	Current int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("the save of %s is version %d, newer than version %d", e.Name, e.Version, e.Current)
+}
+
+// Upgrader knows the upgrades of the record and brings old saves up to
+// date as they're loaded. Saves are of the highest version of its upgrades,
+// version 0 without any, saves from before versions were recorded are
+// version 0.
+type Upgrader struct {
+	upgrades map[int]Upgrade
+	mutex    *sync.RWMutex
+}
+
+// NewUpgrader creates an upgrader without upgrades.
+func NewUpgrader() *Upgrader {
+	return &Upgrader{
+		upgrades: make(map[int]Upgrade),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalUpgrader *Upgrader
+	upgraderOnce   sync.Once
+)
+
+// Upgrades returns the upgrades of the game's player saves, the server and
+// scripts register theirs here.
+func Upgrades() *Upgrader {
+	upgraderOnce.Do(func() {
+		globalUpgrader = NewUpgrader()
+	})
+
+	return globalUpgrader
+}
+
+// Register adds the upgrade, replacing one with the same version and name so
+// scripts can register theirs again when they're reloaded.
+func (u *Upgrader) Register(up Upgrade) error {
+	if up.Version < 1 {
+		return fmt.Errorf("upgrade %q has version %d, versions start at 1", up.Name, up.Version)
+	}
+	if up.Up == nil {
+		return fmt.Errorf("upgrade %q doesn't change anything", up.Name)
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if old, ok := u.upgrades[up.Version]; ok && old.Name != up.Name {
+		return ErrVersionTaken
+	}
+	u.upgrades[up.Version] = up
+
+	return nil
+}
+
+// Unregister removes the upgrade with the version.
+func (u *Upgrader) Unregister(version int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	delete(u.upgrades, version)
+}
+
+// Current returns the version saves are made with, the highest version of
+// the upgrades.
+func (u *Upgrader) Current() int {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	current := 0
+	for version := range u.upgrades {
+		if version > current {
+			current = version
+		}
+	}
+
+	return current
+}
+
+// List returns the upgrades from the lowest version to the highest.
+func (u *Upgrader) List() []Upgrade {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	list := make([]Upgrade, 0, len(u.upgrades))
+	for _, up := range u.upgrades {
+		list = append(list, up)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list
+}
+
+// Upgrade runs each upgrade newer than the save on it in turn, recording the
+// version it reaches in the save. The version the save had is returned, when
+// an upgrade fails the save is left part way.
+func (u *Upgrader) Upgrade(save map[string]interface{}) (int, error) {
+	from := versionOf(save)
+	name, _ := save["name"].(string)
+	if current := u.Current(); from > current {
+		return from, &VersionError{Name: name, Version: from, Current: current}
+	}
+	for _, up := range u.List() {
+		if up.Version <= from {
+			continue
+		}
+		if err := up.Up(save); err != nil {
+			return from, fmt.Errorf("upgrading %s to version %d (%s): %s", name, up.Version, up.Name, err)
+		}
+		save["version"] = up.Version
+	}
+
+	return from, nil
+}
+
+// Decode reads a record saved as JSON, upgrading it first if it's older than
+// the current version.
+func (u *Upgrader) Decode(contents []byte) (Record, error) {
+	var (
+		r    Record
+		save map[string]interface{}
+	)
+	if err := json.Unmarshal(contents, &save); err != nil {
+		return r, err
+	}
+	if versionOf(save) != u.Current() {
+		if _, err := u.Upgrade(save); err != nil {
+			return r, err
+		}
+		upgraded, err := json.Marshal(save)
+		if err != nil {
+			return r, err
+		}
+		contents = upgraded
+	}
+	err := json.Unmarshal(contents, &r)
+
+	return r, err
+}
+
+// versionOf returns the version recorded in the save, 0 if there's none
+func versionOf(save map[string]interface{}) int {
+	switch v := save["version"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+
+	return 0
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package presence
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+)
+
+// Player vars holding what players write about themselves.
+const (
+	TitleVar = "title"
+	PlanVar  = "plan"
+)
+
+// MaxTitle is the longest title a player can set.
+const MaxTitle = 45
+
+// Person is a player setting what's shown about them.
+type Person interface {
+	Name() string
+	Var(key string) interface{}
+	SetVar(key string, value interface{})
+}
+
+// NewCommands creates the who and finger commands, the title and plan
+// commands players describe themselves with and the last command admins see
+// where players logged in from with.
+func NewCommands(m *Manager, resolve command.Resolver[Person]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "who",
+			Args: []command.Arg{{Name: "filter", Kind: command.Text, Optional: true}},
+			Help: "Lists the players in the game, filter it with races, classes, flags " +
+				"or the start of names, like \"who elf afk\".",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				text, err := m.WhoListing(strings.Fields(ctx.String("filter"))...)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(text)
+			},
+		},
+		{
+			Name:   "finger",
+			Args:   []command.Arg{{Name: "player", Kind: command.Word}},
+			Help:   "Shows what's known about a player and when they were last on.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				text, ok, err := m.Finger(ctx.String("player"))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return ctx.Send("There's no one by that name.")
+				}
+
+				return ctx.Send(text)
+			},
+		},
+		{
+			Name:   "title",
+			Args:   []command.Arg{{Name: "title", Kind: command.Text, Optional: true}},
+			Help:   "Sets the title shown after your name, \"title clear\" removes it.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, p Person) error {
+				title := strings.TrimSpace(ctx.String("title"))
+				switch {
+				case title == "":
+					current, _ := p.Var(TitleVar).(string)
+					if current == "" {
+						return ctx.Send("You have no title.")
+					}
+
+					return ctx.Send("Your title is: " + current)
+				case strings.EqualFold(title, "clear"):
+					p.SetVar(TitleVar, nil)
+
+					return ctx.Send("Your title is removed.")
+				case len(title) > MaxTitle:
+					return ctx.Send(fmt.Sprintf("Titles can't be longer than %d letters.", MaxTitle))
+				}
+				p.SetVar(TitleVar, title)
+
+				return ctx.Send("Your title is now: " + title)
+			}),
+		},
+		{
+			Name:   "plan",
+			Help:   "Writes what finger shows about you in the editor.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, p Person) error {
+				plan, _ := p.Var(PlanVar).(string)
+				editor.Open(ctx.Dispatcher, ctx.Caller, editor.New(plan, func(text string) {
+					p.SetVar(PlanVar, strings.TrimRight(text, "\n"))
+				}))
+
+				return nil
+			}),
+		},
+		{
+			Name:   "last",
+			Args:   []command.Arg{{Name: "player", Kind: command.Word}},
+			Level:  command.Admin,
+			Help:   "Lists when a player logged in and where from.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				name := ctx.String("player")
+				logins, err := m.History(name)
+				if err != nil {
+					return err
+				}
+				if len(logins) == 0 {
+					return ctx.Send(fmt.Sprintf("%s has no logins.", name))
+				}
+				lines := []string{fmt.Sprintf("Logins of %s:", name)}
+				for i := len(logins) - 1; i >= 0; i-- {
+					l := logins[i]
+					out := "still on"
+					if !l.Out.IsZero() {
+						out = l.Out.Format(timeFormat)
+					}
+					lines = append(lines, fmt.Sprintf("  %s - %s from %s", l.In.Format(timeFormat), out, l.Address))
+				}
+
+				return ctx.Send(strings.Join(lines, "\n"))
+			},
+		},
+	}
+}
+
+// handler resolves the caller's player for fn
+func handler(resolve command.Resolver[Person], fn func(*command.Context, Person) error) command.Handler {
+	return func(ctx *command.Context) error {
+		p := resolve(ctx.Caller)
+		if p == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		return fn(ctx, p)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package presence
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/text/tmpl"
+)
+
+// Names of the templates the commands render, admins replace them with
+// views named presence.<name>, like views/presence/who.view, or the
+// settings in [presence].
+const (
+	// WhoTemplate is the whole who listing, it's given the rendered lines
+	// of each player as players, how many there are as count and the filter
+	// typed.
+	WhoTemplate = "who"
+	// WhoLineTemplate is one player in the who listing, it's given their
+	// name, title, titled (their name followed by any title), race, class,
+	// flags (like " (afk)") and idle (like "5m").
+	WhoLineTemplate = "who_line"
+	// FingerTemplate describes a player, it's given what who lines are along
+	// with their plan and status, which says whether they're on or when they
+	// last were.
+	FingerTemplate = "finger"
+)
+
+// Defaults are the templates used until they're replaced.
+var Defaults = map[string]string{
+	WhoTemplate:     "Players in the game:\n{{players}}\n{{count}} {{pluralize \"player\" count}} shown.",
+	WhoLineTemplate: "  {{titled}}{{flags}}",
+	FingerTemplate:  "{{titled}}\nRace: {{race}}  Class: {{class}}\n{{status}}\n{{plan}}",
+}
+
+// SetTemplate replaces the template with the name, an empty text returns it
+// to the view or default. Templates that don't render aren't set.
+func (m *Manager) SetTemplate(name, text string) error {
+	if text != "" {
+		if _, err := tmpl.RenderOnce(text, map[string]interface{}{}); err != nil {
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if text == "" {
+		delete(m.templates, name)
+	} else {
+		m.templates[name] = text
+	}
+
+	return nil
+}
+
+// Render renders the template with the name, using the text it was set to,
+// the view presence.<name> or its default, in that order. Strings in data
+// are shown as they are.
+func (m *Manager) Render(name string, data map[string]interface{}) (string, error) {
+	m.mutex.RLock()
+	text, ok := m.templates[name]
+	m.mutex.RUnlock()
+
+	raw := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if s, isString := v.(string); isString {
+			v = template.HTML(s)
+		}
+		raw[k] = v
+	}
+	if ok {
+		return tmpl.RenderOnce(text, raw)
+	}
+	if r, err := tmpl.Template("presence." + name); err == nil {
+		return r.Render(raw)
+	}
+
+	return tmpl.RenderOnce(Defaults[name], raw)
+}
+
+// WhoListing renders the who listing of the players matching the filters.
+func (m *Manager) WhoListing(filters ...string) (string, error) {
+	profiles := m.Who(filters...)
+	lines := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		line, err := m.Render(WhoLineTemplate, profileData(p))
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+
+	return m.Render(WhoTemplate, map[string]interface{}{
+		"players": strings.Join(lines, "\n"),
+		"count":   len(profiles),
+		"filter":  strings.Join(filters, " "),
+	})
+}
+
+// Finger renders what's known about the player with the name, false if
+// there's no such player.
+func (m *Manager) Finger(name string) (string, bool, error) {
+	p, ok := m.Profile(name)
+	if !ok {
+		return "", false, nil
+	}
+	data := profileData(p)
+	data["plan"] = p.Plan
+	switch last, seen := m.Last(p.Name); {
+	case p.Online && p.Idle >= time.Minute:
+		data["status"] = fmt.Sprintf("On now, idle %s.", idle(p.Idle))
+	case p.Online:
+		data["status"] = "On now."
+	case !seen:
+		data["status"] = "Has never been on."
+	case last.Out.IsZero():
+		data["status"] = "Last on " + last.In.Format(timeFormat) + "."
+	default:
+		data["status"] = "Last on " + last.Out.Format(timeFormat) + "."
+	}
+	text, err := m.Render(FingerTemplate, data)
+
+	return strings.TrimRight(text, "\n"), true, err
+}
+
+// how times are shown to players
+const timeFormat = "Mon Jan 2 2006 15:04"
+
+// profileData is what who lines are given about the player
+func profileData(p Profile) map[string]interface{} {
+	var flags string
+	if len(p.Flags) > 0 {
+		flags = " (" + strings.Join(p.Flags, ", ") + ")"
+	}
+	titled := p.Name
+	if p.Title != "" {
+		titled += " " + p.Title
+	}
+
+	return map[string]interface{}{
+		"name":   p.Name,
+		"title":  p.Title,
+		"titled": titled,
+		"race":   p.Race,
+		"class":  p.Class,
+		"flags":  flags,
+		"idle":   idle(p.Idle),
+	}
+}
+
+// idle describes how long a player has been idle, nothing under a minute
+func idle(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return ""
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+
+	return fmt.Sprintf("%dh", int(d/time.Hour))
+}
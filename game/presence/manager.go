@@ -0,0 +1,241 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package presence
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/spf13/viper"
+)
+
+// Events emitted as players come and go, both are given the player's name
+// and the address they're playing from.
+const (
+	LoginEvent  = "presence:login"
+	LogoutEvent = "presence:logout"
+)
+
+// DefaultHistory is how many logins are remembered for each player.
+const DefaultHistory = 20
+
+// Manager remembers logins and finds the profiles of players.
+type Manager struct {
+	store     Store
+	history   int
+	online    func() []Profile
+	lookup    func(name string) (Profile, bool)
+	templates map[string]string
+	now       func() time.Time
+	emitter   *events.Emitter
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager saving logins to the store. The emitter may
+// be nil.
+func NewManager(s Store, em *events.Emitter) *Manager {
+	return &Manager{
+		store:     s,
+		history:   DefaultHistory,
+		templates: make(map[string]string),
+		now:       time.Now,
+		emitter:   em,
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's presence manager, saving logins in the
+// directory given by the presence.dir setting.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDirStore(viper.GetString("presence.dir")), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetStore changes where logins are kept.
+func (m *Manager) SetStore(s Store) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.store = s
+}
+
+// SetClock replaces the function used to get the current time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// SetHistory changes how many logins are remembered for each player, the
+// oldest are forgotten first. Below one keeps the default.
+func (m *Manager) SetHistory(n int) {
+	if n < 1 {
+		n = DefaultHistory
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.history = n
+}
+
+// SetProfiles sets how to find the profiles of the players in the game and
+// of any player by name, online or not.
+func (m *Manager) SetProfiles(online func() []Profile, lookup func(name string) (Profile, bool)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.online, m.lookup = online, lookup
+}
+
+// Login remembers the player entering the game from the address.
+func (m *Manager) Login(name, address string) error {
+	err := m.update(name, func(logins []Login, now time.Time) []Login {
+		return append(logins, Login{In: now, Address: address})
+	})
+	if err != nil {
+		return err
+	}
+	m.emit(LoginEvent, events.Data{"name": name, "address": address})
+
+	return nil
+}
+
+// Logout remembers the player leaving the game.
+func (m *Manager) Logout(name string) error {
+	var address string
+	err := m.update(name, func(logins []Login, now time.Time) []Login {
+		if n := len(logins); n > 0 && logins[n-1].Out.IsZero() {
+			logins[n-1].Out = now
+			address = logins[n-1].Address
+		}
+
+		return logins
+	})
+	if err != nil {
+		return err
+	}
+	m.emit(LogoutEvent, events.Data{"name": name, "address": address})
+
+	return nil
+}
+
+// History returns the player's remembered logins, oldest first.
+func (m *Manager) History(name string) ([]Login, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.store.Load(strings.ToLower(name))
+}
+
+// Last returns the player's latest login.
+func (m *Manager) Last(name string) (Login, bool) {
+	logins, err := m.History(name)
+	if err != nil || len(logins) == 0 {
+		return Login{}, false
+	}
+
+	return logins[len(logins)-1], true
+}
+
+// Who returns the players in the game matching every filter, sorted by
+// name. A filter matches players of the race or class it names, with the
+// flag it names or whose names start with it.
+func (m *Manager) Who(filters ...string) []Profile {
+	m.mutex.RLock()
+	online := m.online
+	m.mutex.RUnlock()
+
+	if online == nil {
+		return nil
+	}
+	var profiles []Profile
+	for _, p := range online() {
+		if matches(p, filters) {
+			profiles = append(profiles, p)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles
+}
+
+// Profile returns the profile of the player with the name, online or not.
+func (m *Manager) Profile(name string) (Profile, bool) {
+	m.mutex.RLock()
+	lookup := m.lookup
+	m.mutex.RUnlock()
+
+	if lookup == nil {
+		return Profile{}, false
+	}
+
+	return lookup(name)
+}
+
+// matches is true if the profile matches every filter
+func matches(p Profile, filters []string) bool {
+	for _, f := range filters {
+		f = strings.ToLower(f)
+		switch {
+		case f == "":
+		case strings.EqualFold(p.Race, f), strings.EqualFold(p.Class, f), p.Flagged(f):
+		case strings.HasPrefix(strings.ToLower(p.Name), f):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// update changes the player's logins with fn and saves them, keeping only
+// the latest
+func (m *Manager) update(name string, fn func([]Login, time.Time) []Login) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := strings.ToLower(name)
+	logins, err := m.store.Load(key)
+	if err != nil {
+		return err
+	}
+	logins = fn(logins, m.now())
+	if len(logins) > m.history {
+		logins = logins[len(logins)-m.history:]
+	}
+
+	return m.store.Save(key, logins)
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package presence shows who's playing and who has played. The who command
+// lists the players in the game, finger describes one whether or not they're
+// in it, and every login is remembered so players can see when others were
+// last on. What the commands show is rendered from templates admins can
+// replace, and players fill in their own title and plan.
+package presence
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Login is one time a player was in the game.
+type Login struct {
+	In time.Time `json:"in"`
+	// Out is when they left, zero while they're still in the game.
+	Out     time.Time `json:"out,omitempty"`
+	Address string    `json:"address,omitempty"`
+}
+
+// Profile is what's shown about a player.
+type Profile struct {
+	Name  string
+	Title string
+	Race  string
+	Class string
+	// Plan is what the player wrote about themself.
+	Plan   string
+	Flags  []string
+	Online bool
+	// Idle is how long since the player last typed, while they're online.
+	Idle time.Duration
+}
+
+// Flagged is true if the player has the flag.
+func (p Profile) Flagged(flag string) bool {
+	for _, f := range p.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Store persists each player's logins, by their lower case name.
+type Store interface {
+	// Load returns the player's logins, oldest first.
+	Load(name string) ([]Login, error)
+	// Save replaces the player's logins.
+	Save(name string, logins []Login) error
+}
+
+// DirStore saves each player's logins as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving logins to the directory, the directory
+// is created when the first logins are saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Load reads the player's file, players who never logged in have no logins.
+func (s *DirStore) Load(name string) ([]Login, error) {
+	if strings.ContainsAny(name, `/\.`) {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(s.Dir, strings.ToLower(name)+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var logins []Login
+	err = json.Unmarshal(contents, &logins)
+
+	return logins, err
+}
+
+// Save writes the logins to <name>.json through a temporary file, renaming
+// it into place once it's complete.
+func (s *DirStore) Save(name string, logins []Login) error {
+	if strings.ContainsAny(name, `/\.`) {
+		return os.ErrInvalid
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(logins, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, strings.ToLower(name)+".json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// MemoryStore keeps logins in memory, forgetting them when the server stops.
+type MemoryStore struct {
+	logins map[string][]Login
+	mutex  *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		logins: make(map[string][]Login),
+		mutex:  new(sync.Mutex),
+	}
+}
+
+// Load returns a copy of the player's logins.
+func (s *MemoryStore) Load(name string) ([]Login, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Login(nil), s.logins[strings.ToLower(name)]...), nil
+}
+
+// Save stores a copy of the logins.
+func (s *MemoryStore) Save(name string, logins []Login) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.logins[strings.ToLower(name)] = append([]Login(nil), logins...)
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package presence_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/presence"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// visitor is a player who writes about themself and remembers what they're
+// told
+type visitor struct {
+	name  string
+	level command.Level
+	vars  map[string]interface{}
+	sent  []string
+}
+
+func (v *visitor) ID() string {
+	return strings.ToLower(v.name)
+}
+
+func (v *visitor) Name() string {
+	return v.name
+}
+
+func (v *visitor) Level() command.Level {
+	return v.level
+}
+
+func (v *visitor) Var(key string) interface{} {
+	return v.vars[key]
+}
+
+func (v *visitor) SetVar(key string, value interface{}) {
+	if value == nil {
+		delete(v.vars, key)
+	} else {
+		v.vars[key] = value
+	}
+}
+
+func (v *visitor) Send(text string) error {
+	v.sent = append(v.sent, text)
+
+	return nil
+}
+
+func (v *visitor) last() string {
+	if len(v.sent) == 0 {
+		return ""
+	}
+
+	return v.sent[len(v.sent)-1]
+}
+
+var _ = Describe("Presence", func() {
+	var (
+		m        *Manager
+		now      time.Time
+		profiles map[string]Profile
+	)
+
+	BeforeEach(func() {
+		m = NewManager(NewMemoryStore(), nil)
+		now = time.Date(2017, 5, 1, 20, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time {
+			return now
+		})
+		profiles = map[string]Profile{
+			"ann": {Name: "Ann", Title: "the Brave", Race: "elf", Class: "ranger", Online: true},
+			"bob": {Name: "Bob", Race: "dwarf", Class: "warrior", Flags: []string{"afk"}, Online: true, Idle: 5 * time.Minute},
+			"cat": {Name: "Cat", Race: "elf", Class: "mage", Plan: "Gone fishing."},
+		}
+		m.SetProfiles(func() []Profile {
+			var online []Profile
+			for _, p := range profiles {
+				if p.Online {
+					online = append(online, p)
+				}
+			}
+
+			return online
+		}, func(name string) (Profile, bool) {
+			p, ok := profiles[strings.ToLower(name)]
+
+			return p, ok
+		})
+	})
+
+	It("remembers logins, forgetting the oldest", func() {
+		m.SetHistory(2)
+		Ω(m.Login("Ann", "10.0.0.1")).Should(Succeed())
+		now = now.Add(time.Hour)
+		Ω(m.Logout("ann")).Should(Succeed())
+		Ω(m.Login("Ann", "10.0.0.2")).Should(Succeed())
+		Ω(m.Login("Ann", "10.0.0.3")).Should(Succeed())
+
+		logins, err := m.History("ANN")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(logins).Should(HaveLen(2))
+		Ω(logins[0].Address).Should(Equal("10.0.0.2"))
+		last, ok := m.Last("ann")
+		Ω(ok).Should(BeTrue())
+		Ω(last.Out.IsZero()).Should(BeTrue())
+		_, ok = m.Last("nobody")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("filters who by race, class, flag and name", func() {
+		names := func(filters ...string) []string {
+			var names []string
+			for _, p := range m.Who(filters...) {
+				names = append(names, p.Name)
+			}
+
+			return names
+		}
+
+		Ω(names()).Should(Equal([]string{"Ann", "Bob"}))
+		Ω(names("elf")).Should(Equal([]string{"Ann"}))
+		Ω(names("AFK")).Should(Equal([]string{"Bob"}))
+		Ω(names("b", "warrior")).Should(Equal([]string{"Bob"}))
+		Ω(names("elf", "warrior")).Should(BeEmpty())
+	})
+
+	It("renders who and finger from templates", func() {
+		text, err := m.WhoListing()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(text).Should(Equal("Players in the game:\n  Ann the Brave\n  Bob (afk)\n2 players shown."))
+
+		Ω(m.SetTemplate(WhoLineTemplate, "{{name}} the {{race}} {{idle}}")).Should(Succeed())
+		text, err = m.WhoListing("dwarf")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(text).Should(Equal("Players in the game:\nBob the dwarf 5m\n1 player shown."))
+		Ω(m.SetTemplate(WhoLineTemplate, "")).Should(Succeed())
+
+		Ω(m.Login("Cat", "10.0.0.4")).Should(Succeed())
+		Ω(m.Logout("Cat")).Should(Succeed())
+		text, ok, err := m.Finger("cat")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(ok).Should(BeTrue())
+		Ω(text).Should(Equal("Cat\nRace: elf  Class: mage\nLast on Mon May 1 2017 20:00.\nGone fishing."))
+		text, _, _ = m.Finger("bob")
+		Ω(text).Should(ContainSubstring("On now, idle 5m."))
+		_, ok, _ = m.Finger("dan")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("saves logins to a directory", func() {
+		dir, err := ioutil.TempDir("", "logins")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Ω(NewManager(NewDirStore(dir), nil).Login("Ann", "10.0.0.1")).Should(Succeed())
+		logins, err := NewDirStore(dir).Load("ann")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(logins).Should(HaveLen(1))
+		Ω(logins[0].Address).Should(Equal("10.0.0.1"))
+	})
+
+	It("runs the presence commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Person {
+			return c.(*visitor)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatcher := command.NewDispatcher(registry, nil)
+		ann := &visitor{name: "Ann", vars: map[string]interface{}{}}
+		dispatch := func(line string) string {
+			Ω(dispatcher.Dispatch(ann, line)).Should(Succeed())
+
+			return ann.last()
+		}
+
+		Ω(dispatch("who ranger")).Should(Equal("Players in the game:\n  Ann the Brave\n1 player shown."))
+		Ω(dispatch("finger nobody")).Should(Equal("There's no one by that name."))
+		Ω(dispatch("title the Bold")).Should(Equal("Your title is now: the Bold"))
+		Ω(ann.vars[TitleVar]).Should(Equal("the Bold"))
+		Ω(dispatch("title clear")).Should(Equal("Your title is removed."))
+		Ω(ann.vars).ShouldNot(HaveKey(TitleVar))
+
+		dispatch("plan")
+		dispatch("Hunting orcs.")
+		dispatch(".s")
+		Ω(ann.vars[PlanVar]).Should(Equal("Hunting orcs."))
+
+		Ω(dispatcher.Dispatch(ann, "last ann")).Should(MatchError(command.ErrUnknown))
+		ann.level = command.Admin
+		Ω(m.Login("Ann", "10.0.0.1")).Should(Succeed())
+		Ω(dispatch("last ann")).Should(ContainSubstring("still on from 10.0.0.1"))
+	})
+})
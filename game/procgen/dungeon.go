@@ -0,0 +1,263 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package procgen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/world"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Dungeon describes the dungeons generated for the instances of a template
+// zone.
+type Dungeon struct {
+	// Zone is the template zone, each of its instances gets a dungeon.
+	Zone string `yaml:"zone"`
+	// Entrance is the template room the dungeon leads off, through an exit
+	// in the Direction, down unless it's set.
+	Entrance  string          `yaml:"entrance"`
+	Direction world.Direction `yaml:"direction,omitempty"`
+	Algorithm string          `yaml:"algorithm"`
+	Width     int             `yaml:"width"`
+	Height    int             `yaml:"height"`
+	// Rooms is how many rooms a walk makes, half the grid when it's zero.
+	Rooms int `yaml:"rooms,omitempty"`
+	// MinSize is the smallest BSP splits the grid, 3 when it's zero.
+	MinSize   int            `yaml:"min_size,omitempty"`
+	Templates []RoomTemplate `yaml:"templates,omitempty"`
+	Spawns    []Spawn        `yaml:"spawns,omitempty"`
+}
+
+// RoomTemplate describes rooms of a dungeon.
+type RoomTemplate struct {
+	// Kind is the kind of room the template is for, any when it's empty.
+	Kind        string   `yaml:"kind,omitempty"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Flags       []string `yaml:"flags,omitempty"`
+	// Weight is how likely the template is chosen over others, 1 when it's
+	// zero.
+	Weight int `yaml:"weight,omitempty"`
+}
+
+// Spawn puts an NPC or an item in rooms of a dungeon.
+type Spawn struct {
+	NPC  string `yaml:"npc,omitempty"`
+	Item string `yaml:"item,omitempty"`
+	// Kind is the kind of room it's put in, any when it's empty.
+	Kind string `yaml:"kind,omitempty"`
+	// Chance is the percent of rooms it's put in.
+	Chance int `yaml:"chance"`
+	// Max limits how many of it a dungeon has, zero is no limit.
+	Max int `yaml:"max,omitempty"`
+}
+
+// kinds lists the kinds of room templates and spawns are for
+var kinds = []string{Entrance, DeadEnd, Corridor, Chamber}
+
+// validate checks the dungeon makes sense, filling in its defaults
+func (d *Dungeon) validate() error {
+	d.Algorithm = strings.ToLower(d.Algorithm)
+	if d.Direction == "" {
+		d.Direction = world.Down
+	}
+	d.Direction = world.ParseDirection(string(d.Direction))
+	if d.MinSize == 0 {
+		d.MinSize = 3
+	}
+	if d.Rooms == 0 && d.Width*d.Height > 1 {
+		d.Rooms = d.Width * d.Height / 2
+	} else if d.Rooms == 0 {
+		d.Rooms = 1
+	}
+
+	switch {
+	case d.Zone == "" || d.Entrance == "":
+		return fmt.Errorf("dungeons need a zone and an entrance")
+	case d.Direction == "":
+		return fmt.Errorf("dungeon %q: there's no direction to enter it", d.Zone)
+	case !known(Algorithms, d.Algorithm):
+		return fmt.Errorf("dungeon %q: the algorithm is one of %v, not %q", d.Zone, Algorithms, d.Algorithm)
+	case d.Width < 1 || d.Height < 1:
+		return fmt.Errorf("dungeon %q: the width and height must be at least 1", d.Zone)
+	case d.Rooms < 1 || d.MinSize < 1:
+		return fmt.Errorf("dungeon %q: rooms and min_size can't be negative", d.Zone)
+	}
+	for i, t := range d.Templates {
+		switch {
+		case t.Name == "":
+			return fmt.Errorf("dungeon %q: templates[%d]: a name is required", d.Zone, i)
+		case t.Kind != "" && !known(kinds, t.Kind):
+			return fmt.Errorf("dungeon %q: templates[%d]: the kind is one of %v, not %q", d.Zone, i, kinds, t.Kind)
+		case t.Weight < 0:
+			return fmt.Errorf("dungeon %q: templates[%d]: weight can't be negative", d.Zone, i)
+		}
+	}
+	for i, s := range d.Spawns {
+		switch {
+		case (s.NPC == "") == (s.Item == ""):
+			return fmt.Errorf("dungeon %q: spawns[%d]: a spawn needs exactly one of npc or item", d.Zone, i)
+		case s.Kind != "" && !known(kinds, s.Kind):
+			return fmt.Errorf("dungeon %q: spawns[%d]: the kind is one of %v, not %q", d.Zone, i, kinds, s.Kind)
+		case s.Chance < 1 || s.Chance > 100:
+			return fmt.Errorf("dungeon %q: spawns[%d]: chance must be a percent from 1 to 100", d.Zone, i)
+		case s.Max < 0:
+			return fmt.Errorf("dungeon %q: spawns[%d]: max can't be negative", d.Zone, i)
+		}
+	}
+
+	return nil
+}
+
+// Layout lays out a new dungeon with its algorithm.
+func (d Dungeon) Layout() *Layout {
+	if d.Algorithm == Walk {
+		return NewWalk(d.Width, d.Height, d.Rooms)
+	}
+
+	return NewBSP(d.Width, d.Height, d.MinSize)
+}
+
+// known is true if s is in the list
+func known(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// File is the layout of a dungeon file, a list of dungeons.
+type File struct {
+	Dungeons []Dungeon `yaml:"dungeons"`
+}
+
+// Defs holds the dungeons, by template zone.
+type Defs struct {
+	dungeons map[string]Dungeon
+	mutex    *sync.RWMutex
+}
+
+// NewDefs creates definitions without dungeons.
+func NewDefs() *Defs {
+	return &Defs{
+		dungeons: make(map[string]Dungeon),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// Add adds the dungeon, replacing any for the same zone.
+func (d *Defs) Add(dungeon Dungeon) error {
+	if err := dungeon.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.dungeons[dungeon.Zone] = dungeon
+
+	return nil
+}
+
+// Dungeon returns the dungeon generated for the template zone, if there is
+// one.
+func (d *Defs) Dungeon(zone string) (Dungeon, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	dungeon, ok := d.dungeons[zone]
+
+	return dungeon, ok
+}
+
+// Dungeons returns every dungeon, sorted by zone.
+func (d *Defs) Dungeons() []Dungeon {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	dungeons := make([]Dungeon, 0, len(d.dungeons))
+	for _, dungeon := range d.dungeons {
+		dungeons = append(dungeons, dungeon)
+	}
+	sort.Slice(dungeons, func(i, j int) bool {
+		return dungeons[i].Zone < dungeons[j].Zone
+	})
+
+	return dungeons
+}
+
+// LoadDir adds the dungeons in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the dungeons in the YAML document, like:
+//   dungeons:
+//     - zone: catacombs
+//       entrance: catacomb-stairs
+//       direction: down
+//       algorithm: bsp
+//       width: 12
+//       height: 12
+//       templates:
+//         - kind: chamber
+//           name: A Burial Chamber
+//           description: Niches full of bones line the walls.
+//         - name: A Narrow Tunnel
+//           weight: 3
+//       spawns:
+//         - npc: skeleton
+//           kind: chamber
+//           chance: 50
+//         - item: gold-coins
+//           kind: dead_end
+//           chance: 30
+//           max: 3
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, dungeon := range f.Dungeons {
+		if err := d.Add(dungeon); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
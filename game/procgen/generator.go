@@ -0,0 +1,155 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package procgen
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/grid"
+	"github.com/bbuck/dragon-mud/game/instance"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// DefaultTemplate describes rooms of dungeons without a template for them.
+var DefaultTemplate = RoomTemplate{
+	Name:        "A Dark Passage",
+	Description: "Rough stone walls close in around you.",
+}
+
+// Generator makes dungeons in the world.
+type Generator struct {
+	defs  *Defs
+	world *world.World
+}
+
+// NewGenerator creates a generator making the dungeons in the definitions
+// in the world.
+func NewGenerator(defs *Defs, w *world.World) *Generator {
+	return &Generator{
+		defs:  defs,
+		world: w,
+	}
+}
+
+var (
+	globalGenerator *Generator
+	globalOnce      sync.Once
+)
+
+// Global returns the generator the game uses.
+func Global() *Generator {
+	globalOnce.Do(func() {
+		globalGenerator = NewGenerator(NewDefs(), world.Global())
+	})
+
+	return globalGenerator
+}
+
+// Defs returns the dungeons.
+func (g *Generator) Defs() *Defs {
+	return g.defs
+}
+
+// Generate makes a dungeon in the instance if its template has one,
+// returning the resets filling it with mobs and loot.
+func (g *Generator) Generate(inst instance.Instance) ([]world.Reset, error) {
+	d, ok := g.defs.Dungeon(inst.Template)
+	if !ok {
+		return nil, nil
+	}
+
+	return g.Materialize(d, inst.ID, inst.Room(d.Entrance))
+}
+
+// Materialize lays out the dungeon as rooms of the zone, joined to the
+// entrance room, returning the resets filling it with mobs and loot.
+func (g *Generator) Materialize(d Dungeon, zone, entrance string) ([]world.Reset, error) {
+	if _, ok := g.world.Room(entrance); !ok {
+		return nil, fmt.Errorf("dungeon %q: there's no entrance room %q", d.Zone, entrance)
+	}
+	l := d.Layout()
+	id := func(p grid.Point) string {
+		return fmt.Sprintf("%s%s%d.%d", zone, instance.Separator, p.X, p.Y)
+	}
+
+	rooms := l.Rooms()
+	for _, p := range rooms {
+		t := pick(d.Templates, l.Kind(p))
+		r := world.Room{
+			ID:          id(p),
+			Zone:        zone,
+			Name:        t.Name,
+			Description: t.Description,
+			Flags:       make(map[string]bool, len(t.Flags)),
+		}
+		for _, flag := range t.Flags {
+			r.Flags[flag] = true
+		}
+		if err := g.world.AddRoom(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range rooms {
+		for dir, n := range map[world.Direction]grid.Point{world.East: p.Add(grid.Pt(1, 0)), world.South: p.Add(grid.Pt(0, 1))} {
+			if l.Has(n) {
+				if err := g.world.Link(id(p), dir, id(n)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := g.world.Link(entrance, d.Direction, id(l.Start)); err != nil {
+		return nil, err
+	}
+
+	var resets []world.Reset
+	counts := make([]int, len(d.Spawns))
+	for _, p := range rooms {
+		kind := l.Kind(p)
+		for i, s := range d.Spawns {
+			if (s.Kind != "" && s.Kind != kind) || (s.Max > 0 && counts[i] >= s.Max) {
+				continue
+			}
+			if random.Intn(100) >= s.Chance {
+				continue
+			}
+			counts[i]++
+			resets = append(resets, world.Reset{NPC: s.NPC, Item: s.Item, Room: id(p)})
+		}
+	}
+
+	return resets, nil
+}
+
+// pick chooses a template for a room of the kind by weight, from those for
+// the kind if there are any, or those for any room
+func pick(templates []RoomTemplate, kind string) RoomTemplate {
+	for _, want := range []string{kind, ""} {
+		var matching []RoomTemplate
+		total := 0
+		for _, t := range templates {
+			if t.Kind != want {
+				continue
+			}
+			if t.Weight == 0 {
+				t.Weight = 1
+			}
+			matching = append(matching, t)
+			total += t.Weight
+		}
+		if total == 0 {
+			continue
+		}
+		roll := random.Intn(total)
+		for _, t := range matching {
+			if roll < t.Weight {
+				return t
+			}
+			roll -= t.Weight
+		}
+	}
+
+	return DefaultTemplate
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package procgen generates dungeons for instances. Layouts of rooms on a
+// grid are made by an algorithm, BSP or a drunken walk, then decorated with
+// room templates, mobs and loot chosen by how each room fits in the layout.
+// Every instance of a dungeon's template zone gets a dungeon of its own,
+// leading off one of the template's rooms.
+package procgen
+
+import (
+	"github.com/bbuck/dragon-mud/game/grid"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The algorithms layouts are made with.
+const (
+	// BSP splits the grid in two again and again, putting a room in each
+	// part too small to split and joining the parts with corridors.
+	BSP = "bsp"
+	// Walk wanders the grid at random, making a room everywhere it goes.
+	Walk = "walk"
+)
+
+// Algorithms lists the algorithms layouts are made with.
+var Algorithms = []string{BSP, Walk}
+
+// The kinds of room in a layout, by how they fit into it.
+const (
+	// Entrance is the room the dungeon is entered from.
+	Entrance = "entrance"
+	// DeadEnd is a room with one way out.
+	DeadEnd = "dead_end"
+	// Corridor is a room with two ways out.
+	Corridor = "corridor"
+	// Chamber is a room with three or more ways out.
+	Chamber = "chamber"
+)
+
+// Layout is the rooms of a dungeon on a grid, rooms next to each other
+// north, south, east or west are joined.
+type Layout struct {
+	// Start is the room the dungeon is entered from.
+	Start grid.Point
+	cells map[grid.Point]bool
+	order []grid.Point
+}
+
+// newLayout creates a layout without rooms
+func newLayout() *Layout {
+	return &Layout{cells: make(map[grid.Point]bool)}
+}
+
+// carve makes a room at the point, the first room made is the start
+func (l *Layout) carve(p grid.Point) {
+	if l.cells[p] {
+		return
+	}
+	if len(l.order) == 0 {
+		l.Start = p
+	}
+	l.cells[p] = true
+	l.order = append(l.order, p)
+}
+
+// Rooms returns the points with rooms, in the order they were made.
+func (l *Layout) Rooms() []grid.Point {
+	return append([]grid.Point(nil), l.order...)
+}
+
+// Has is true if there's a room at the point.
+func (l *Layout) Has(p grid.Point) bool {
+	return l.cells[p]
+}
+
+// Neighbors returns the rooms joined to the room at the point, clockwise
+// from north.
+func (l *Layout) Neighbors(p grid.Point) []grid.Point {
+	var neighbors []grid.Point
+	for _, n := range grid.Neighbors(p, false) {
+		if l.cells[n] {
+			neighbors = append(neighbors, n)
+		}
+	}
+
+	return neighbors
+}
+
+// Kind returns the kind of the room at the point.
+func (l *Layout) Kind(p grid.Point) string {
+	if p == l.Start {
+		return Entrance
+	}
+	switch len(l.Neighbors(p)) {
+	case 0, 1:
+		return DeadEnd
+	case 2:
+		return Corridor
+	}
+
+	return Chamber
+}
+
+// NewBSP lays out rooms in a grid of the size with binary space
+// partitioning, parts are never split smaller than minSize across.
+func NewBSP(width, height, minSize int) *Layout {
+	if minSize < 1 {
+		minSize = 1
+	}
+	l := newLayout()
+
+	var split func(r grid.Rect) grid.Point
+	split = func(r grid.Rect) grid.Point {
+		wide := r.Width() >= 2*minSize
+		tall := r.Height() >= 2*minSize
+		if !wide && !tall {
+			c := r.Center()
+			l.carve(c)
+
+			return c
+		}
+
+		var a, b grid.Rect
+		if wide && (!tall || random.Intn(2) == 0) {
+			at := random.Range(minSize, r.Width()-minSize+1)
+			a = grid.RectAt(r.Min.X, r.Min.Y, at, r.Height())
+			b = grid.RectAt(r.Min.X+at, r.Min.Y, r.Width()-at, r.Height())
+		} else {
+			at := random.Range(minSize, r.Height()-minSize+1)
+			a = grid.RectAt(r.Min.X, r.Min.Y, r.Width(), at)
+			b = grid.RectAt(r.Min.X, r.Min.Y+at, r.Width(), r.Height()-at)
+		}
+		from, to := split(a), split(b)
+		l.corridor(from, to)
+
+		return from
+	}
+	split(grid.RectAt(0, 0, width, height))
+
+	return l
+}
+
+// corridor makes rooms along a path from one point to the other, across
+// then down
+func (l *Layout) corridor(from, to grid.Point) {
+	p := from
+	for p.X != to.X {
+		if p.X < to.X {
+			p.X++
+		} else {
+			p.X--
+		}
+		l.carve(p)
+	}
+	for p.Y != to.Y {
+		if p.Y < to.Y {
+			p.Y++
+		} else {
+			p.Y--
+		}
+		l.carve(p)
+	}
+}
+
+// NewWalk lays out the number of rooms in a grid of the size by walking
+// from its center in random directions, never leaving it.
+func NewWalk(width, height, rooms int) *Layout {
+	l := newLayout()
+	if width < 1 || height < 1 {
+		return l
+	}
+	if rooms > width*height {
+		rooms = width * height
+	}
+
+	bounds := grid.RectAt(0, 0, width, height)
+	p := bounds.Center()
+	l.carve(p)
+	for len(l.order) < rooms {
+		var next []grid.Point
+		for _, n := range grid.Neighbors(p, false) {
+			if bounds.Contains(n) {
+				next = append(next, n)
+			}
+		}
+		p = next[random.Intn(len(next))]
+		l.carve(p)
+	}
+
+	return l
+}
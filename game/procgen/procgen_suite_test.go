@@ -0,0 +1,13 @@
+package procgen_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestProcgen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Procgen Suite")
+}
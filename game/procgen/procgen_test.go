@@ -0,0 +1,183 @@
+package procgen_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/grid"
+	"github.com/bbuck/dragon-mud/game/instance"
+	. "github.com/bbuck/dragon-mud/game/procgen"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// reachable returns how many rooms of the layout can be reached from its
+// start
+func reachable(l *Layout) int {
+	seen := map[grid.Point]bool{l.Start: true}
+	queue := []grid.Point{l.Start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, n := range l.Neighbors(p) {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return len(seen)
+}
+
+const catacombs = `
+dungeons:
+  - zone: catacombs
+    entrance: stairs
+    algorithm: walk
+    width: 6
+    height: 6
+    rooms: 10
+    templates:
+      - kind: entrance
+        name: The Foot of the Stairs
+      - name: A Narrow Tunnel
+        flags: [Dark]
+    spawns:
+      - npc: skeleton
+        chance: 100
+        max: 2
+      - item: torch
+        kind: entrance
+        chance: 100
+`
+
+var _ = Describe("Layouts", func() {
+	bounds := grid.RectAt(0, 0, 10, 8)
+
+	It("splits the grid into connected rooms with BSP", func() {
+		for i := 0; i < 20; i++ {
+			l := NewBSP(10, 8, 3)
+			rooms := l.Rooms()
+			Ω(len(rooms)).Should(BeNumerically(">", 1))
+			for _, p := range rooms {
+				Ω(bounds.Contains(p)).Should(BeTrue())
+			}
+			Ω(reachable(l)).Should(Equal(len(rooms)))
+		}
+	})
+
+	It("walks the grid making as many connected rooms as asked", func() {
+		for i := 0; i < 20; i++ {
+			l := NewWalk(10, 8, 25)
+			rooms := l.Rooms()
+			Ω(rooms).Should(HaveLen(25))
+			Ω(rooms[0]).Should(Equal(l.Start))
+			for _, p := range rooms {
+				Ω(bounds.Contains(p)).Should(BeTrue())
+			}
+			Ω(reachable(l)).Should(Equal(25))
+		}
+		Ω(NewWalk(2, 2, 10).Rooms()).Should(HaveLen(4))
+	})
+
+	It("tells rooms apart by how they fit in", func() {
+		l := NewWalk(1, 3, 3)
+
+		Ω(l.Kind(l.Start)).Should(Equal(Entrance))
+		Ω(l.Kind(grid.Pt(0, 0))).Should(Equal(DeadEnd))
+		Ω(l.Kind(grid.Pt(0, 2))).Should(Equal(DeadEnd))
+	})
+})
+
+var _ = Describe("Defs", func() {
+	It("loads dungeons, filling in defaults", func() {
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(catacombs))).Should(Succeed())
+
+		d, ok := defs.Dungeon("catacombs")
+		Ω(ok).Should(BeTrue())
+		Ω(d.Direction).Should(Equal(world.Down))
+		Ω(d.Templates).Should(HaveLen(2))
+		Ω(defs.Dungeons()).Should(HaveLen(1))
+	})
+
+	It("rejects dungeons that don't make sense", func() {
+		defs := NewDefs()
+		base := Dungeon{Zone: "pit", Entrance: "edge", Algorithm: BSP, Width: 4, Height: 4}
+
+		bad := base
+		bad.Algorithm = "maze"
+		Ω(defs.Add(bad)).ShouldNot(Succeed())
+		bad = base
+		bad.Width = 0
+		Ω(defs.Add(bad)).ShouldNot(Succeed())
+		bad = base
+		bad.Templates = []RoomTemplate{{Kind: "throne", Name: "A Throne Room"}}
+		Ω(defs.Add(bad)).ShouldNot(Succeed())
+		bad = base
+		bad.Spawns = []Spawn{{NPC: "rat", Item: "cheese", Chance: 10}}
+		Ω(defs.Add(bad)).ShouldNot(Succeed())
+		bad = base
+		bad.Spawns = []Spawn{{NPC: "rat", Chance: 101}}
+		Ω(defs.Add(bad)).ShouldNot(Succeed())
+		Ω(defs.Add(base)).Should(Succeed())
+	})
+})
+
+var _ = Describe("Generator", func() {
+	var (
+		w    *world.World
+		defs *Defs
+		g    *Generator
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "catacombs-1", Name: "Catacombs"})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "catacombs-1/stairs", Zone: "catacombs-1"})).Should(Succeed())
+		defs = NewDefs()
+		Ω(defs.LoadYAML([]byte(catacombs))).Should(Succeed())
+		g = NewGenerator(defs, w)
+	})
+
+	It("makes a dungeon in instances of its template", func() {
+		resets, err := g.Generate(instance.Instance{ID: "catacombs-1", Template: "catacombs"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		rooms := w.Rooms("catacombs-1")
+		Ω(rooms).Should(HaveLen(11))
+		stairs, _ := w.Room("catacombs-1/stairs")
+		start, ok := w.Room(stairs.Exits[world.Down].To)
+		Ω(ok).Should(BeTrue())
+		Ω(start.Name).Should(Equal("The Foot of the Stairs"))
+		Ω(start.Exits[world.Up].To).Should(Equal("catacombs-1/stairs"))
+		for _, r := range rooms {
+			if r.ID != stairs.ID && r.ID != start.ID {
+				Ω(r.Name).Should(Equal("A Narrow Tunnel"))
+				Ω(r.Flag("dark")).Should(BeTrue())
+			}
+		}
+
+		Ω(resets).Should(ContainElement(world.Reset{Item: "torch", Room: start.ID}))
+		skeletons := 0
+		for _, r := range resets {
+			if r.NPC == "skeleton" {
+				skeletons++
+			}
+		}
+		Ω(skeletons).Should(Equal(2))
+	})
+
+	It("leaves instances of other templates alone", func() {
+		resets, err := g.Generate(instance.Instance{ID: "crypt-1", Template: "crypt"})
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resets).Should(BeEmpty())
+	})
+
+	It("fails without the entrance room", func() {
+		_, err := g.Generate(instance.Instance{ID: "catacombs-2", Template: "catacombs"})
+
+		Ω(err).Should(HaveOccurred())
+	})
+})
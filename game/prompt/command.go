@@ -0,0 +1,43 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// NewCommand creates the prompt command for players' prompts in the manager.
+// Given nothing it shows the caller's template and the variables they can use,
+// given a template it becomes their prompt.
+func NewCommand(m *Manager) *command.Command {
+	return &command.Command{
+		Name:   "prompt",
+		Args:   []command.Arg{{Name: "template", Kind: command.Text, Optional: true}},
+		Help:   "Shows your prompt and the variables it can use, or changes it to the template given. Use %% for a percent sign.",
+		Source: "game",
+		Handler: func(ctx *command.Context) error {
+			p := m.Get(ctx.Caller.ID())
+			if p == nil {
+				return ctx.Send("You don't have a prompt.")
+			}
+
+			if !ctx.Has("template") {
+				lines := []string{fmt.Sprintf("Your prompt is %q.", p.Template()), "Variables:"}
+				for _, v := range m.registry.Variables() {
+					lines = append(lines, fmt.Sprintf("  %%%c  %s", v.Code, v.Description))
+				}
+
+				return ctx.Send(strings.Join(lines, "\n"))
+			}
+
+			if err := p.SetTemplate(ctx.String("template")); err != nil {
+				return ctx.Send(err.Error() + ".")
+			}
+
+			return ctx.Send("Prompt set.")
+		},
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package prompt builds the prompts players see after output from templates
+// they choose, like "%h/%H hp %m mana> ". Each code in a template is a
+// variable showing one of the player's stats, the game updates the stats as
+// they change and prompts referencing them are rendered again.
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/server/output"
+	"github.com/spf13/viper"
+)
+
+// Stats are the values prompts are rendered from, by name.
+type Stats map[string]interface{}
+
+// Variable is a code that can be used in prompt templates.
+type Variable struct {
+	// Code is the letter following % in templates, codes are case sensitive
+	// so %h and %H can differ.
+	Code byte
+	// Stat is the stat shown, prompts using the variable are rendered again
+	// when it changes.
+	Stat string
+	// Description says what the variable shows, for players choosing a
+	// prompt.
+	Description string
+	// Format renders the variable, it's given all of the player's stats so
+	// it can combine them. The stat is shown as it is if Format is nil.
+	Format func(Stats) string
+}
+
+// render shows the variable for the stats
+func (v *Variable) render(stats Stats) string {
+	if v.Format != nil {
+		return v.Format(stats)
+	}
+	value, ok := stats[v.Stat]
+	if !ok || value == nil {
+		return "?"
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Registry holds the variables prompts can use.
+type Registry struct {
+	variables map[byte]*Variable
+	mutex     *sync.RWMutex
+}
+
+// NewRegistry creates a registry without any variables.
+func NewRegistry() *Registry {
+	return &Registry{
+		variables: make(map[byte]*Variable),
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalRegistry *Registry
+	globalOnce     sync.Once
+)
+
+// Global returns the registry of the game's prompt variables, it starts with
+// the current and maximum hit points (%h and %H), mana (%m and %M) and moves
+// (%v and %V).
+func Global() *Registry {
+	globalOnce.Do(func() {
+		globalRegistry = NewRegistry()
+		defaults := []Variable{
+			{Code: 'h', Stat: "hp", Description: "current hit points"},
+			{Code: 'H', Stat: "max_hp", Description: "maximum hit points"},
+			{Code: 'm', Stat: "mana", Description: "current mana"},
+			{Code: 'M', Stat: "max_mana", Description: "maximum mana"},
+			{Code: 'v', Stat: "moves", Description: "current moves"},
+			{Code: 'V', Stat: "max_moves", Description: "maximum moves"},
+		}
+		for _, v := range defaults {
+			globalRegistry.Register(v)
+		}
+	})
+
+	return globalRegistry
+}
+
+// Register adds the variable, failing if its code is taken. Codes must be
+// letters.
+func (r *Registry) Register(v Variable) error {
+	if !isLetter(v.Code) {
+		return fmt.Errorf("prompt variable code %q must be a letter", v.Code)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.variables[v.Code]; ok {
+		return fmt.Errorf("prompt variable %%%c is already registered", v.Code)
+	}
+	r.variables[v.Code] = &v
+
+	return nil
+}
+
+// Get returns the variable with the code.
+func (r *Registry) Get(code byte) (Variable, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v, ok := r.variables[code]
+	if !ok {
+		return Variable{}, false
+	}
+
+	return *v, true
+}
+
+// Variables returns every variable, sorted by code.
+func (r *Registry) Variables() []Variable {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	vars := make([]Variable, 0, len(r.variables))
+	for _, v := range r.variables {
+		vars = append(vars, *v)
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Code < vars[j].Code
+	})
+
+	return vars
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// a piece of a parsed template, either text or a variable
+type part struct {
+	text     string
+	variable *Variable
+}
+
+// parse splits the template into its parts, failing on unknown codes
+func (r *Registry) parse(template string) ([]part, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var (
+		parts []part
+		text  []byte
+	)
+	for i := 0; i < len(template); i++ {
+		b := template[i]
+		if b != '%' {
+			text = append(text, b)
+
+			continue
+		}
+		i++
+		if i >= len(template) {
+			return nil, fmt.Errorf("prompt %q ends with %%", template)
+		}
+		if template[i] == '%' {
+			text = append(text, '%')
+
+			continue
+		}
+		v, ok := r.variables[template[i]]
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt variable %%%c", template[i])
+		}
+		if len(text) > 0 {
+			parts = append(parts, part{text: string(text)})
+			text = nil
+		}
+		parts = append(parts, part{variable: v})
+	}
+	if len(text) > 0 {
+		parts = append(parts, part{text: string(text)})
+	}
+
+	return parts, nil
+}
+
+// Prompt is a player's prompt, rendered from their template and stats.
+type Prompt struct {
+	registry *Registry
+	template string
+	parts    []part
+	stats    Stats
+	rendered string
+	onChange func(string)
+	mutex    *sync.Mutex
+}
+
+// New creates a prompt using the registry's variables. The function given,
+// which may be nil, is called with the prompt each time it renders
+// differently.
+func New(r *Registry, template string, onChange func(string)) (*Prompt, error) {
+	p := &Prompt{
+		registry: r,
+		stats:    make(Stats),
+		onChange: onChange,
+		mutex:    new(sync.Mutex),
+	}
+	if err := p.SetTemplate(template); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Template returns the prompt's template.
+func (p *Prompt) Template() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.template
+}
+
+// SetTemplate changes the template, failing if it uses unknown variables.
+func (p *Prompt) SetTemplate(template string) error {
+	parts, err := p.registry.parse(template)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.template = template
+	p.parts = parts
+	p.mutex.Unlock()
+
+	p.refresh()
+
+	return nil
+}
+
+// Set updates a stat, the prompt is rendered again if it uses it.
+func (p *Prompt) Set(stat string, value interface{}) {
+	p.SetStats(Stats{stat: value})
+}
+
+// SetStats updates several stats at once.
+func (p *Prompt) SetStats(stats Stats) {
+	p.mutex.Lock()
+	changed := false
+	for stat, value := range stats {
+		if old, ok := p.stats[stat]; !ok || !same(old, value) {
+			p.stats[stat] = value
+			changed = changed || p.uses(stat)
+		}
+	}
+	p.mutex.Unlock()
+
+	if changed {
+		p.refresh()
+	}
+}
+
+// same is true if both values are comparable and equal, values like tables
+// from scripts can't be compared and are always treated as changed
+func same(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || !ta.Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// Stats returns a copy of the stats.
+func (p *Prompt) Stats() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := make(Stats, len(p.stats))
+	for k, v := range p.stats {
+		stats[k] = v
+	}
+
+	return stats
+}
+
+// String returns the rendered prompt.
+func (p *Prompt) String() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.rendered
+}
+
+// uses is true if a variable in the template shows the stat, the mutex must
+// be held
+func (p *Prompt) uses(stat string) bool {
+	for _, part := range p.parts {
+		if part.variable != nil && (part.variable.Stat == stat || part.variable.Format != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refresh renders the prompt, passing it on if it changed
+func (p *Prompt) refresh() {
+	p.mutex.Lock()
+	buf := new(bytes.Buffer)
+	for _, part := range p.parts {
+		if part.variable != nil {
+			buf.WriteString(part.variable.render(p.stats))
+		} else {
+			buf.WriteString(part.text)
+		}
+	}
+	rendered := buf.String()
+	changed := rendered != p.rendered
+	p.rendered = rendered
+	fn := p.onChange
+	p.mutex.Unlock()
+
+	if changed && fn != nil {
+		fn(rendered)
+	}
+}
+
+// Manager keeps the prompt of each player by their session id.
+type Manager struct {
+	registry *Registry
+	prompts  map[string]*Prompt
+	mutex    *sync.Mutex
+}
+
+// NewManager creates a manager whose prompts use the registry's variables.
+func NewManager(r *Registry) *Manager {
+	return &Manager{
+		registry: r,
+		prompts:  make(map[string]*Prompt),
+		mutex:    new(sync.Mutex),
+	}
+}
+
+var (
+	globalManager     *Manager
+	globalManagerOnce sync.Once
+)
+
+// GlobalManager returns the manager of the players' prompts, which use the
+// global variables.
+func GlobalManager() *Manager {
+	globalManagerOnce.Do(func() {
+		globalManager = NewManager(Global())
+	})
+
+	return globalManager
+}
+
+// Attach gives the player a prompt drawn by their output writer, starting
+// from the prompt.default template. A player already having a prompt keeps
+// it.
+func (m *Manager) Attach(id string, out *output.Writer) (*Prompt, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if p, ok := m.prompts[id]; ok {
+		return p, nil
+	}
+	p, err := New(m.registry, viper.GetString("prompt.default"), out.SetPrompt)
+	if err != nil {
+		return nil, err
+	}
+	m.prompts[id] = p
+
+	return p, nil
+}
+
+// Get returns the player's prompt, or nil if they don't have one.
+func (m *Manager) Get(id string) *Prompt {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.prompts[id]
+}
+
+// Remove discards the player's prompt, such as when they leave the game.
+func (m *Manager) Remove(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.prompts, id)
+}
@@ -0,0 +1,114 @@
+package prompt_test
+
+import (
+	"fmt"
+
+	. "github.com/bbuck/dragon-mud/game/prompt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prompt", func() {
+	var (
+		registry *Registry
+		rendered []string
+		p        *Prompt
+	)
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+		registry.Register(Variable{Code: 'h', Stat: "hp", Description: "current hit points"})
+		registry.Register(Variable{Code: 'H', Stat: "max_hp", Description: "maximum hit points"})
+		rendered = nil
+
+		var err error
+		p, err = New(registry, "%h/%H hp> ", func(s string) {
+			rendered = append(rendered, s)
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("renders unknown stats as ?", func() {
+		Ω(p.String()).Should(Equal("?/? hp> "))
+	})
+
+	It("renders the stats set", func() {
+		p.SetStats(Stats{"hp": 12, "max_hp": 20})
+
+		Ω(p.String()).Should(Equal("12/20 hp> "))
+		Ω(rendered).Should(Equal([]string{"?/? hp> ", "12/20 hp> "}))
+	})
+
+	It("only renders again when a stat it shows changes", func() {
+		p.Set("hp", 10)
+		p.Set("hp", 10)
+		p.Set("gold", 100)
+
+		Ω(rendered).Should(HaveLen(2))
+		Ω(p.Stats()).Should(HaveKeyWithValue("gold", 100))
+	})
+
+	It("escapes percent signs", func() {
+		Ω(p.SetTemplate("%h%% ")).Should(Succeed())
+		p.Set("hp", 50)
+
+		Ω(p.String()).Should(Equal("50% "))
+	})
+
+	It("rejects unknown variables", func() {
+		err := p.SetTemplate("%q> ")
+
+		Ω(err).Should(HaveOccurred())
+		Ω(p.Template()).Should(Equal("%h/%H hp> "))
+	})
+
+	It("rejects templates ending in %", func() {
+		Ω(p.SetTemplate("%h%")).ShouldNot(Succeed())
+	})
+
+	It("renders variables with a format from all stats", func() {
+		registry.Register(Variable{
+			Code: 'p',
+			Stat: "hp",
+			Format: func(s Stats) string {
+				return fmt.Sprintf("%d%%", s["hp"].(int)*100/s["max_hp"].(int))
+			},
+		})
+		p.SetStats(Stats{"hp": 5, "max_hp": 20})
+		Ω(p.SetTemplate("%p> ")).Should(Succeed())
+
+		Ω(p.String()).Should(Equal("25%> "))
+		p.Set("max_hp", 10)
+		Ω(p.String()).Should(Equal("50%> "))
+	})
+
+	It("treats uncomparable values as changed", func() {
+		Ω(func() {
+			p.Set("hp", []int{1})
+			p.Set("hp", []int{1})
+		}).ShouldNot(Panic())
+	})
+})
+
+var _ = Describe("Registry", func() {
+	It("rejects codes already registered", func() {
+		r := NewRegistry()
+
+		Ω(r.Register(Variable{Code: 'g', Stat: "gold"})).Should(Succeed())
+		Ω(r.Register(Variable{Code: 'g', Stat: "gems"})).ShouldNot(Succeed())
+	})
+
+	It("rejects codes that aren't letters", func() {
+		Ω(NewRegistry().Register(Variable{Code: '%'})).ShouldNot(Succeed())
+	})
+
+	It("lists variables by code", func() {
+		var codes []byte
+		for _, v := range Global().Variables() {
+			codes = append(codes, v.Code)
+		}
+
+		Ω(string(codes)).Should(ContainSubstring("HMVhmv"))
+	})
+})
@@ -0,0 +1,116 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package pvp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the pvp command, which shows and changes whether the
+// caller fights players, and the arena command listing the arenas and
+// joining and leaving their queues.
+func NewCommands(m *Manager, resolve command.Resolver[Fighter]) []*command.Command {
+	return []*command.Command{
+		{
+			Name: "pvp",
+			Args: []command.Arg{
+				{Name: "setting", Kind: command.Word, Optional: true},
+			},
+			Help: "Shows whether you've agreed to fight other players and the rule of " +
+				"where you are, \"pvp on\" agrees and \"pvp off\" stops.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, f Fighter) error {
+				switch strings.ToLower(ctx.String("setting")) {
+				case "":
+					agreed := "You haven't agreed to fight players."
+					if f.Flag(Flag) {
+						agreed = "You've agreed to fight players."
+					}
+
+					return ctx.Send(fmt.Sprintf("%s The rule here is %s.", agreed, m.Rule(f.Location())))
+				case "on":
+					if err := m.SetPvP(f, true); err != nil {
+						return err
+					}
+
+					return ctx.Send("You agree to fight players.")
+				case "off":
+					if err := m.SetPvP(f, false); err != nil {
+						return err
+					}
+
+					return ctx.Send("You no longer fight players.")
+				}
+
+				return ctx.Send("Usage: " + ctx.Command.Usage())
+			}),
+		},
+		{
+			Name: "arena",
+			Args: []command.Arg{
+				{Name: "action", Kind: command.Word, Optional: true},
+				{Name: "arena", Kind: command.Word, Optional: true},
+			},
+			Help: "Lists the arenas and who is waiting to fight in them, \"arena join " +
+				"<arena>\" waits for an opponent and \"arena leave\" stops waiting or " +
+				"forfeits your match.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, f Fighter) error {
+				switch strings.ToLower(ctx.String("action")) {
+				case "", "list":
+					return ctx.Send(arenas(m, f))
+				case "join":
+					if ctx.String("arena") == "" {
+						return ctx.Send("Usage: " + ctx.Command.Usage())
+					}
+
+					return m.Join(f, ctx.String("arena"))
+				case "leave", "forfeit":
+					return m.Leave(f.Name())
+				}
+
+				return ctx.Send("Usage: " + ctx.Command.Usage())
+			}),
+		},
+	}
+}
+
+// arenas lists the arenas, how many wait for each and the fighter's match
+func arenas(m *Manager, f Fighter) string {
+	defs := m.defs.Arenas()
+	if len(defs) == 0 {
+		return "There are no arenas."
+	}
+
+	lines := make([]string, 0, len(defs)+1)
+	for _, a := range defs {
+		lines = append(lines, fmt.Sprintf("%s (%s), %d waiting", a.Name, a.ID, len(m.Queue(a.ID))))
+	}
+	if mt, ok := m.Match(f.Name()); ok {
+		lines = append(lines, fmt.Sprintf("You're fighting %s.", mt.Opponent(f.Name())))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's fighter for fn, telling them why they were
+// refused
+func handler(resolve command.Resolver[Fighter], fn func(*command.Context, Fighter) error) command.Handler {
+	return func(ctx *command.Context) error {
+		f := resolve(ctx.Caller)
+		if f == nil {
+			return ctx.Send("You aren't playing anyone.")
+		}
+
+		err := fn(ctx, f)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,527 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package pvp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// DefaultCooldown is how long after fighting a player someone must wait to
+// turn their pvp flag off, unless it's changed.
+const DefaultCooldown = 5 * time.Minute
+
+// Match is two players fighting in an arena.
+type Match struct {
+	ID    string
+	Arena string
+	// Players are the names of the two players, the one who queued first
+	// first.
+	Players []string
+	// From holds the room each player was in before the match, by name.
+	From map[string]string
+}
+
+// Opponent returns the name of the player's opponent.
+func (mt Match) Opponent(name string) string {
+	for _, p := range mt.Players {
+		if key(p) != key(name) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// Manager decides who may fight whom, and runs the arenas' queues and
+// matches.
+type Manager struct {
+	defs     *Defs
+	world    *world.World
+	movement *movement.Movement
+	lookup   func(name string) Fighter
+	rule     string
+	cooldown time.Duration
+	// fought holds when each player last fought another, by lower case name
+	fought map[string]time.Time
+	// queues holds the names of those waiting for each arena, by id
+	queues  map[string][]string
+	matches map[string]*Match
+	// in holds the id of the match each player is in, by lower case name
+	in      map[string]string
+	seq     uint64
+	now     func() time.Time
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager following the definitions, whose rooms are
+// in the world and who moves players to and from arenas with the Movement.
+// Zones without a rule ask for consent. The emitter may be nil.
+func NewManager(defs *Defs, w *world.World, mv *movement.Movement, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:     defs,
+		world:    w,
+		movement: mv,
+		rule:     Consent,
+		cooldown: DefaultCooldown,
+		fought:   make(map[string]time.Time),
+		queues:   make(map[string][]string),
+		matches:  make(map[string]*Match),
+		in:       make(map[string]string),
+		now:      time.Now,
+		emitter:  em,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's pvp rules and arenas.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), world.Global(), movement.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// Defs returns the rules of zones and the arenas.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetLookup sets how players in the game are found by name. Without it no
+// matches are made.
+func (m *Manager) SetLookup(fn func(name string) Fighter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lookup = fn
+}
+
+// SetDefault changes the rule zones without one follow.
+func (m *Manager) SetDefault(rule string) error {
+	p := Policy{Zone: "default", Rule: rule}
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.rule = p.Rule
+
+	return nil
+}
+
+// SetCooldown changes how long after fighting a player someone must wait to
+// turn their pvp flag off.
+func (m *Manager) SetCooldown(d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.cooldown = d
+}
+
+// SetClock changes how the manager tells the time, for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Rule returns the rule of the zone the room is in.
+func (m *Manager) Rule(room string) string {
+	if r, ok := m.world.Room(room); ok {
+		if rule, ok := m.defs.Rule(r.Zone); ok {
+			return rule
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.rule
+}
+
+// Safe is true if nobody may fight in the room.
+func (m *Manager) Safe(room string) bool {
+	r, ok := m.world.Room(room)
+
+	return ok && r.Flag(SafeFlag)
+}
+
+// Attack returns why the attacker can't attack the defender, both players,
+// nil if they can.
+func (m *Manager) Attack(attacker, defender Fighter) error {
+	room := attacker.Location()
+	if m.Safe(room) {
+		return refuse(SafeRoomMessage)
+	}
+
+	m.mutex.RLock()
+	theirs, fighting := m.in[key(attacker.Name())]
+	_, defending := m.in[key(defender.Name())]
+	m.mutex.RUnlock()
+
+	if fighting || defending {
+		if theirs != m.matchOf(defender.Name()) {
+			return refuse(MatchMessage)
+		}
+
+		return nil
+	}
+
+	switch m.Rule(room) {
+	case Safe:
+		return refuse(SafeZoneMessage)
+	case Arena:
+		return refuse(ArenaMessage)
+	case Consent:
+		if !attacker.Flag(Flag) {
+			return refuse(NoConsentMessage)
+		}
+		if !defender.Flag(Flag) {
+			return refuse(fmt.Sprintf("%s hasn't agreed to fight players.", defender.Name()))
+		}
+	}
+
+	m.mutex.Lock()
+	now := m.now()
+	m.fought[key(attacker.Name())] = now
+	m.fought[key(defender.Name())] = now
+	m.mutex.Unlock()
+
+	return nil
+}
+
+// SetPvP turns the fighter's pvp flag on or off. Those who fought a player
+// recently must wait to turn it off.
+func (m *Manager) SetPvP(f Fighter, on bool) error {
+	m.mutex.RLock()
+	last, ok := m.fought[key(f.Name())]
+	wait := m.cooldown - m.now().Sub(last)
+	m.mutex.RUnlock()
+
+	if !on && ok && wait > 0 {
+		return refuse(CooldownMessage)
+	}
+	f.SetFlag(Flag, on)
+	m.emit(FlagEvent, events.Data{
+		"player": f.Name(),
+		"on":     on,
+	})
+
+	return nil
+}
+
+// Match returns the match the player with the name is in.
+func (m *Manager) Match(name string) (Match, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	mt, ok := m.matches[m.in[key(name)]]
+	if !ok {
+		return Match{}, false
+	}
+
+	return mt.copy(), true
+}
+
+// Queue returns the names of those waiting for the arena, first first.
+func (m *Manager) Queue(arena string) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]string(nil), m.queues[strings.ToLower(arena)]...)
+}
+
+// Join puts the fighter in the arena's queue, starting a match when there's
+// someone to fight.
+func (m *Manager) Join(f Fighter, arena string) error {
+	a, ok := m.defs.Arena(arena)
+	if !ok {
+		return refuse(fmt.Sprintf("There's no arena %q.", arena))
+	}
+
+	m.mutex.Lock()
+	if _, ok := m.in[key(f.Name())]; ok {
+		m.mutex.Unlock()
+
+		return refuse("You're already in a match.")
+	}
+	if queued, ok := m.queued(f.Name()); ok {
+		m.mutex.Unlock()
+
+		return refuse(fmt.Sprintf("You're already waiting for %s.", queued))
+	}
+	m.queues[a.ID] = append(m.queues[a.ID], f.Name())
+	m.mutex.Unlock()
+
+	f.Send(fmt.Sprintf("You join the queue for %s.", a.Name))
+	m.emit(QueueEvent, events.Data{
+		"arena":  a.ID,
+		"player": f.Name(),
+	})
+	m.pair(a)
+
+	return nil
+}
+
+// Leave takes the player with the name out of the queue they're in, or
+// forfeits the match they're in.
+func (m *Manager) Leave(name string) error {
+	m.mutex.Lock()
+	if id, ok := m.in[key(name)]; ok {
+		mt := m.matches[id].copy()
+		m.mutex.Unlock()
+
+		m.end(mt, mt.Opponent(name), true)
+
+		return nil
+	}
+	arena, ok := m.queued(name)
+	if ok {
+		var rest []string
+		for _, queued := range m.queues[arena] {
+			if key(queued) != key(name) {
+				rest = append(rest, queued)
+			}
+		}
+		m.queues[arena] = rest
+	}
+	m.mutex.Unlock()
+
+	if !ok {
+		return refuse("You aren't waiting for an arena or in a match.")
+	}
+	if f := m.find(name); f != nil {
+		f.Send("You leave the queue.")
+	}
+
+	return nil
+}
+
+// Defeat ends the match the loser and winner are in with the winner
+// winning, restoring the loser instead of letting them die. It's false if
+// they aren't matched against each other, and the death should be handled
+// as usual.
+func (m *Manager) Defeat(loser, winner string) bool {
+	m.mutex.RLock()
+	id, ok := m.in[key(loser)]
+	matched := ok && m.in[key(winner)] == id
+	var mt Match
+	if matched {
+		mt = m.matches[id].copy()
+	}
+	m.mutex.RUnlock()
+
+	if !matched {
+		return false
+	}
+	if f := m.find(loser); f != nil {
+		hp := f.Stat("max_hp")
+		if hp < 1 {
+			hp = 1
+		}
+		f.SetStat("hp", hp)
+	}
+	m.end(mt, winner, false)
+
+	return true
+}
+
+// pair starts matches between those waiting for the arena while there are
+// two of them in the game
+func (m *Manager) pair(a ArenaDef) {
+	for {
+		m.mutex.Lock()
+		lookup := m.lookup
+		if lookup == nil || len(m.queues[a.ID]) < 2 {
+			m.mutex.Unlock()
+
+			return
+		}
+		names := append([]string(nil), m.queues[a.ID][:2]...)
+		m.queues[a.ID] = m.queues[a.ID][2:]
+		m.mutex.Unlock()
+
+		fighters := make([]Fighter, 0, 2)
+		for _, name := range names {
+			if f := lookup(name); f != nil {
+				fighters = append(fighters, f)
+			}
+		}
+		if len(fighters) < 2 {
+			// whoever is still here waits at the front for the next
+			m.mutex.Lock()
+			for _, f := range fighters {
+				m.queues[a.ID] = append([]string{f.Name()}, m.queues[a.ID]...)
+			}
+			m.mutex.Unlock()
+
+			continue
+		}
+		m.start(a, fighters)
+	}
+}
+
+// start puts the fighters in the arena's rooms and begins their match
+func (m *Manager) start(a ArenaDef, fighters []Fighter) {
+	m.mutex.Lock()
+	m.seq++
+	mt := &Match{
+		ID:    fmt.Sprintf("%s-%d", a.ID, m.seq),
+		Arena: a.ID,
+		From:  make(map[string]string),
+	}
+	for _, f := range fighters {
+		mt.Players = append(mt.Players, f.Name())
+		mt.From[f.Name()] = f.Location()
+		m.in[key(f.Name())] = mt.ID
+	}
+	m.matches[mt.ID] = mt
+	m.mutex.Unlock()
+
+	for i, f := range fighters {
+		m.movement.Teleport(f, a.Rooms[i%len(a.Rooms)])
+		f.Send(fmt.Sprintf("Your match in %s against %s begins!", a.Name, mt.Opponent(f.Name())))
+	}
+	m.emit(StartEvent, events.Data{
+		"arena":   a.ID,
+		"match":   mt.ID,
+		"players": append([]string(nil), mt.Players...),
+	})
+}
+
+// end finishes the match with the winner winning, sending its players back
+// out of the arena
+func (m *Manager) end(mt Match, winner string, forfeit bool) {
+	m.mutex.Lock()
+	if _, ok := m.matches[mt.ID]; !ok {
+		m.mutex.Unlock()
+
+		return
+	}
+	delete(m.matches, mt.ID)
+	for _, p := range mt.Players {
+		delete(m.in, key(p))
+	}
+	m.mutex.Unlock()
+
+	a, _ := m.defs.Arena(mt.Arena)
+	loser := mt.Opponent(winner)
+	text := fmt.Sprintf("%s wins the match against %s!", winner, loser)
+	if forfeit {
+		text = fmt.Sprintf("%s forfeits the match, %s wins!", loser, winner)
+	}
+	for _, p := range mt.Players {
+		f := m.find(p)
+		if f == nil {
+			continue
+		}
+		f.Send(text)
+		to := a.Exit
+		if to == "" {
+			to = mt.From[p]
+		}
+		if to != "" {
+			m.movement.Teleport(f, to)
+		}
+	}
+	m.emit(EndEvent, events.Data{
+		"arena":   mt.Arena,
+		"match":   mt.ID,
+		"players": append([]string(nil), mt.Players...),
+		"winner":  winner,
+		"loser":   loser,
+		"forfeit": forfeit,
+	})
+}
+
+// matchOf returns the id of the match the player is in, empty if they
+// aren't in one
+func (m *Manager) matchOf(name string) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.in[key(name)]
+}
+
+// queued returns the id of the arena the player is waiting for, the mutex
+// must be locked
+func (m *Manager) queued(name string) (string, bool) {
+	for arena, names := range m.queues {
+		for _, queued := range names {
+			if key(queued) == key(name) {
+				return arena, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// find returns the player in the game with the name, or nil
+func (m *Manager) find(name string) Fighter {
+	m.mutex.RLock()
+	lookup := m.lookup
+	m.mutex.RUnlock()
+
+	if lookup == nil {
+		return nil
+	}
+
+	return lookup(name)
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
+
+// copy returns the match with its own players and rooms
+func (mt *Match) copy() Match {
+	c := *mt
+	c.Players = append([]string(nil), mt.Players...)
+	c.From = make(map[string]string, len(mt.From))
+	for name, room := range mt.From {
+		c.From[name] = room
+	}
+
+	return c
+}
+
+// key is how players are known, their name in lower case
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package pvp decides when players may fight each other. Each zone has a
+// rule: nobody fights in safe zones, anyone does in open ones and in zones
+// asking for consent only players who have turned their pvp flag on fight
+// each other. Nobody fights in rooms flagged safe, whatever their zone.
+// Arenas are zones where players queue to be matched against each other,
+// and losing a match restores the loser rather than killing them.
+package pvp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The events of arenas and the pvp flag. Flag changes are given the player
+// and whether the flag is on, queues the arena and player, and match starts
+// and ends the arena, the match id and both players, ends also the winner
+// and loser, who forfeited if the loser left.
+const (
+	FlagEvent  = "pvp:flag"
+	QueueEvent = "arena:queue"
+	StartEvent = "arena:start"
+	EndEvent   = "arena:end"
+)
+
+// The rules zones follow.
+const (
+	// Safe zones let no player attack another.
+	Safe = "safe"
+	// Consent zones let players attack others if both have the pvp flag.
+	Consent = "consent"
+	// Open zones let any player attack another.
+	Open = "open"
+	// Arena zones let players fight only the opponent they're matched with.
+	Arena = "arena"
+)
+
+// Rules are the rules a zone can follow.
+var Rules = []string{Safe, Consent, Open, Arena}
+
+// Flag is the player flag saying they've agreed to fight players.
+const Flag = "pvp"
+
+// SafeFlag is the room flag keeping anyone from fighting in the room.
+const SafeFlag = "safe"
+
+// Messages told to players who can't fight.
+const (
+	SafeRoomMessage  = "You can't fight here."
+	SafeZoneMessage  = "Players can't fight each other here."
+	NoConsentMessage = "You haven't agreed to fight players, type \"pvp on\" if you want to."
+	MatchMessage     = "You can only fight your opponent."
+	ArenaMessage     = "Players only fight here when they're matched in the arena."
+	CooldownMessage  = "You fought a player too recently to stop now."
+)
+
+// Fighter is a player who may fight other players.
+type Fighter interface {
+	Name() string
+	Location() string
+	SetLocation(room string)
+	Send(text string) error
+	Flag(name string) bool
+	SetFlag(name string, on bool)
+	Stat(name string) int
+	SetStat(name string, value int)
+}
+
+// Policy is the rule a zone follows.
+type Policy struct {
+	Zone string `yaml:"zone"`
+	Rule string `yaml:"rule"`
+}
+
+// validate checks the policy makes sense
+func (p *Policy) validate() error {
+	p.Rule = strings.ToLower(p.Rule)
+	if p.Zone == "" {
+		return fmt.Errorf("pvp policies need a zone")
+	}
+	for _, r := range Rules {
+		if p.Rule == r {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("zone %q has the rule %q, rules are %s", p.Zone, p.Rule, strings.Join(Rules, ", "))
+}
+
+// ArenaDef defines an arena.
+type ArenaDef struct {
+	// ID is what players join the arena's queue with.
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// Zone is the zone the arena is, it follows the arena rule.
+	Zone string `yaml:"zone"`
+	// Rooms are where matched players start, each in the next one.
+	Rooms []string `yaml:"rooms"`
+	// Exit is where players go when their match ends, the room they were in
+	// before when it's empty.
+	Exit string `yaml:"exit,omitempty"`
+}
+
+// validate checks the arena makes sense
+func (a *ArenaDef) validate() error {
+	a.ID = strings.ToLower(a.ID)
+	if a.ID == "" || a.Zone == "" {
+		return fmt.Errorf("arenas need an id and a zone")
+	}
+	if len(a.Rooms) == 0 {
+		return fmt.Errorf("arena %q has no rooms", a.ID)
+	}
+	if a.Name == "" {
+		a.Name = a.ID
+	}
+
+	return nil
+}
+
+// File is the layout of a pvp file, the rules of zones and the arenas.
+type File struct {
+	Zones  []Policy   `yaml:"zones,omitempty"`
+	Arenas []ArenaDef `yaml:"arenas,omitempty"`
+}
+
+// Defs holds the rules of zones and the arenas.
+type Defs struct {
+	rules  map[string]string
+	arenas map[string]ArenaDef
+	mutex  *sync.RWMutex
+}
+
+// NewDefs creates definitions with no rules or arenas.
+func NewDefs() *Defs {
+	return &Defs{
+		rules:  make(map[string]string),
+		arenas: make(map[string]ArenaDef),
+		mutex:  new(sync.RWMutex),
+	}
+}
+
+// AddPolicy sets the rule of the zone, replacing the one it had.
+func (d *Defs) AddPolicy(p Policy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.rules[p.Zone] = p.Rule
+
+	return nil
+}
+
+// AddArena adds the arena, replacing any with its id. Its zone follows the
+// arena rule.
+func (d *Defs) AddArena(a ArenaDef) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	a.Rooms = append([]string(nil), a.Rooms...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.arenas[a.ID] = a
+	d.rules[a.Zone] = Arena
+
+	return nil
+}
+
+// Rule returns the rule the zone follows, if it has one.
+func (d *Defs) Rule(zone string) (string, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	r, ok := d.rules[zone]
+
+	return r, ok
+}
+
+// Arena returns the arena with the id.
+func (d *Defs) Arena(id string) (ArenaDef, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	a, ok := d.arenas[strings.ToLower(id)]
+
+	return a, ok
+}
+
+// Arenas returns every arena, sorted by id.
+func (d *Defs) Arenas() []ArenaDef {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	arenas := make([]ArenaDef, 0, len(d.arenas))
+	for _, a := range d.arenas {
+		arenas = append(arenas, a)
+	}
+	sort.Slice(arenas, func(i, j int) bool {
+		return arenas[i].ID < arenas[j].ID
+	})
+
+	return arenas
+}
+
+// LoadDir adds the rules and arenas in every .yml and .yaml file in the
+// directory. Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the rules and arenas in the YAML document, like:
+//   zones:
+//     - {zone: town, rule: safe}
+//     - {zone: wilds, rule: open}
+//   arenas:
+//     - id: pit
+//       name: The Pit
+//       zone: colosseum
+//       rooms: [pit-north, pit-south]
+//       exit: colosseum-gate
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, p := range f.Zones {
+		if err := d.AddPolicy(p); err != nil {
+			return err
+		}
+	}
+	for _, a := range f.Arenas {
+		if err := d.AddArena(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package pvp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPvP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PvP Suite")
+}
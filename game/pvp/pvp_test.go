@@ -0,0 +1,265 @@
+package pvp_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/movement"
+	. "github.com/bbuck/dragon-mud/game/pvp"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fighter is a player who remembers their flags, stats and what they're
+// told
+type fighter struct {
+	name     string
+	location string
+	flags    map[string]bool
+	stats    map[string]int
+	sent     []string
+}
+
+func newFighter(name, location string) *fighter {
+	return &fighter{
+		name:     name,
+		location: location,
+		flags:    make(map[string]bool),
+		stats:    map[string]int{"hp": 20, "max_hp": 30},
+	}
+}
+
+func (f *fighter) ID() string {
+	return strings.ToLower(f.name)
+}
+
+func (f *fighter) Name() string {
+	return f.name
+}
+
+func (f *fighter) Location() string {
+	return f.location
+}
+
+func (f *fighter) SetLocation(room string) {
+	f.location = room
+}
+
+func (f *fighter) Level() command.Level {
+	return command.Player
+}
+
+func (f *fighter) Send(text string) error {
+	f.sent = append(f.sent, text)
+
+	return nil
+}
+
+func (f *fighter) Flag(name string) bool {
+	return f.flags[name]
+}
+
+func (f *fighter) SetFlag(name string, on bool) {
+	f.flags[name] = on
+}
+
+func (f *fighter) Stat(name string) int {
+	return f.stats[name]
+}
+
+func (f *fighter) SetStat(name string, value int) {
+	f.stats[name] = value
+}
+
+func (f *fighter) told(text string) bool {
+	return strings.Contains(strings.Join(f.sent, "\n"), text)
+}
+
+const rules = `
+zones:
+  - {zone: town, rule: safe}
+  - {zone: wilds, rule: open}
+arenas:
+  - id: pit
+    name: The Pit
+    zone: colosseum
+    rooms: [pit-north, pit-south]
+    exit: gate
+`
+
+var _ = Describe("PvP", func() {
+	var (
+		em         *events.Emitter
+		m          *Manager
+		now        time.Time
+		ann, bob   *fighter
+		carla, dan *fighter
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		for _, zone := range []string{"town", "wilds", "fields", "colosseum"} {
+			Ω(w.AddZone(world.Zone{ID: zone, Name: zone})).Should(Succeed())
+		}
+		for _, r := range []world.Room{
+			{ID: "square", Zone: "town"},
+			{ID: "forest", Zone: "wilds"},
+			{ID: "meadow", Zone: "fields"},
+			{ID: "shrine", Zone: "fields", Flags: map[string]bool{SafeFlag: true}},
+			{ID: "gate", Zone: "colosseum"},
+			{ID: "pit-north", Zone: "colosseum"},
+			{ID: "pit-south", Zone: "colosseum"},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(rules))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, w, movement.New(w, nil), em)
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time { return now })
+		ann, bob = newFighter("Ann", "meadow"), newFighter("Bob", "meadow")
+		carla, dan = newFighter("Carla", "square"), newFighter("Dan", "forest")
+		m.SetLookup(func(name string) Fighter {
+			for _, f := range []*fighter{ann, bob, carla, dan} {
+				if strings.EqualFold(f.name, name) {
+					return f
+				}
+			}
+
+			return nil
+		})
+	})
+
+	It("follows the rule of each zone", func() {
+		Ω(m.Rule("square")).Should(Equal(Safe))
+		Ω(m.Rule("forest")).Should(Equal(Open))
+		Ω(m.Rule("gate")).Should(Equal(Arena))
+		Ω(m.Rule("meadow")).Should(Equal(Consent))
+		Ω(m.SetDefault("chaos")).ShouldNot(Succeed())
+		Ω(m.SetDefault("Open")).Should(Succeed())
+		Ω(m.Rule("meadow")).Should(Equal(Open))
+
+		carla.location, bob.location = "square", "square"
+		Ω(m.Attack(carla, bob)).Should(MatchError(SafeZoneMessage))
+		ann.location, bob.location = "forest", "forest"
+		Ω(m.Attack(ann, bob)).Should(Succeed())
+		ann.location, bob.location = "gate", "gate"
+		Ω(m.Attack(ann, bob)).Should(MatchError(ArenaMessage))
+	})
+
+	It("asks both players for consent and keeps them to it for a while", func() {
+		Ω(m.Attack(ann, bob)).Should(MatchError(NoConsentMessage))
+		Ω(m.SetPvP(ann, true)).Should(Succeed())
+		Ω(m.Attack(ann, bob)).Should(MatchError("Bob hasn't agreed to fight players."))
+		Ω(m.SetPvP(bob, true)).Should(Succeed())
+		Ω(m.Attack(ann, bob)).Should(Succeed())
+
+		Ω(m.SetPvP(bob, false)).Should(MatchError(CooldownMessage))
+		now = now.Add(DefaultCooldown)
+		Ω(m.SetPvP(bob, false)).Should(Succeed())
+		Ω(bob.Flag(Flag)).Should(BeFalse())
+	})
+
+	It("keeps fights out of safe rooms", func() {
+		m.SetDefault(Open)
+		ann.location, bob.location = "shrine", "shrine"
+
+		Ω(m.Attack(ann, bob)).Should(MatchError(SafeRoomMessage))
+		Ω(m.Safe("shrine")).Should(BeTrue())
+		Ω(m.Safe("meadow")).Should(BeFalse())
+	})
+
+	It("matches players waiting for an arena", func() {
+		received := make(chan events.Data, 1)
+		em.On(StartEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+
+		Ω(m.Join(ann, "colosseum")).Should(MatchError("There's no arena \"colosseum\"."))
+		Ω(m.Join(ann, "Pit")).Should(Succeed())
+		Ω(m.Join(ann, "pit")).Should(MatchError("You're already waiting for pit."))
+		Ω(m.Queue("pit")).Should(Equal([]string{"Ann"}))
+		Ω(m.Join(bob, "pit")).Should(Succeed())
+
+		Ω(m.Queue("pit")).Should(BeEmpty())
+		mt, ok := m.Match("bob")
+		Ω(ok).Should(BeTrue())
+		Ω(mt.Players).Should(Equal([]string{"Ann", "Bob"}))
+		Ω(ann.location).Should(Equal("pit-north"))
+		Ω(bob.location).Should(Equal("pit-south"))
+		Ω(ann.told("Your match in The Pit against Bob begins!")).Should(BeTrue())
+		Eventually(received).Should(Receive(HaveKeyWithValue("players", []string{"Ann", "Bob"})))
+
+		Ω(m.Attack(ann, bob)).Should(Succeed())
+		dan.location = "pit-north"
+		Ω(m.Attack(dan, ann)).Should(MatchError(MatchMessage))
+		Ω(m.Attack(ann, dan)).Should(MatchError(MatchMessage))
+	})
+
+	It("restores the loser of a match instead of letting them die", func() {
+		received := make(chan events.Data, 1)
+		em.On(EndEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+		m.Join(ann, "pit")
+		m.Join(bob, "pit")
+		bob.stats["hp"] = -3
+
+		Ω(m.Defeat("Dan", "Ann")).Should(BeFalse())
+		Ω(m.Defeat("Bob", "Ann")).Should(BeTrue())
+		Ω(bob.stats["hp"]).Should(Equal(30))
+		Ω(ann.location).Should(Equal("gate"))
+		Ω(bob.location).Should(Equal("gate"))
+		Ω(bob.told("Ann wins the match against Bob!")).Should(BeTrue())
+		Eventually(received).Should(Receive(HaveKeyWithValue("winner", "Ann")))
+		_, ok := m.Match("Ann")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("lets players leave the queue and forfeit matches", func() {
+		m.Join(ann, "pit")
+		Ω(m.Leave("Ann")).Should(Succeed())
+		Ω(m.Queue("pit")).Should(BeEmpty())
+		Ω(m.Leave("Ann")).Should(MatchError("You aren't waiting for an arena or in a match."))
+
+		m.Join(ann, "pit")
+		m.Join(carla, "pit")
+		Ω(m.Leave("carla")).Should(Succeed())
+		Ω(ann.told("Carla forfeits the match, Ann wins!")).Should(BeTrue())
+		Ω(carla.location).Should(Equal("gate"))
+	})
+
+	It("runs the pvp and arena commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Fighter {
+			return c.(*fighter)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "pvp")
+		d.Dispatch(ann, "pvp on")
+		d.Dispatch(ann, "arena")
+		d.Dispatch(ann, "arena join pit")
+		d.Dispatch(ann, "arena")
+		d.Dispatch(ann, "arena leave")
+		Ω(ann.sent).Should(Equal([]string{
+			"You haven't agreed to fight players. The rule here is consent.",
+			"You agree to fight players.",
+			"The Pit (pit), 0 waiting",
+			"You join the queue for The Pit.",
+			"The Pit (pit), 1 waiting",
+			"You leave the queue.",
+		}))
+	})
+})
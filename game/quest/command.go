@@ -0,0 +1,133 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package quest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the quest command, which lists the caller's quests and
+// accepts, describes and abandons them.
+func NewCommands(m *Manager, resolve command.Resolver[Questor]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:    "quest",
+			Aliases: []string{"quests"},
+			Args:    []command.Arg{{Name: "action", Kind: command.Word, Optional: true}, {Name: "quest", Kind: command.Text, Optional: true}},
+			Help:    "Lists your quests and those offered here, \"quest accept\", \"quest info\" or \"quest abandon\" a quest by name.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, q Questor) error {
+				name := ctx.String("quest")
+				action := strings.ToLower(ctx.String("action"))
+				if action == "" || action == "list" {
+					return ctx.Send(listing(m, q))
+				}
+				if name == "" {
+					return ctx.Send(fmt.Sprintf("%s which quest?", strings.Title(action)))
+				}
+
+				switch action {
+				case "accept":
+					_, err := m.Accept(q, name)
+
+					return err
+				case "info":
+					def, ok := m.find(q, name)
+					if !ok {
+						return &item.Refused{Message: UnknownMessage}
+					}
+
+					return ctx.Send(describe(def, q))
+				case "abandon":
+					def, ok := m.find(q, name)
+					if !ok {
+						return &item.Refused{Message: NotOnMessage}
+					}
+
+					return m.Abandon(q, def.ID)
+				}
+
+				return ctx.Send("You can accept, info or abandon a quest.")
+			}),
+		},
+	}
+}
+
+// find returns the quest with the name the questor is on or is offered
+func (m *Manager) find(q Questor, name string) (Def, bool) {
+	for _, def := range m.defs.All() {
+		if s, ok := q.Quest(def.ID); ok && s.Status == Active && def.Named(name) {
+			return def, true
+		}
+	}
+	for _, def := range m.Offered(q) {
+		if def.Named(name) {
+			return def, true
+		}
+	}
+
+	return Def{}, false
+}
+
+// listing lists the questor's active quests and those offered to them
+func listing(m *Manager, q Questor) string {
+	var lines []string
+	for _, def := range m.defs.All() {
+		if s, ok := q.Quest(def.ID); ok && s.Status == Active {
+			lines = append(lines, "  "+def.Name)
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "You aren't on any quests.")
+	} else {
+		lines = append([]string{"You are on these quests:"}, lines...)
+	}
+	if offered := m.Offered(q); len(offered) > 0 {
+		lines = append(lines, "Offered here:")
+		for _, def := range offered {
+			lines = append(lines, "  "+def.Name)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// describe tells the questor about the quest and their progress on it
+func describe(def Def, q Questor) string {
+	lines := []string{def.Name}
+	if def.Summary != "" {
+		lines = append(lines, def.Summary)
+	}
+	s, _ := q.Quest(def.ID)
+	for i, o := range def.Objectives {
+		done := 0
+		if s.Status == Active && i < len(s.Progress) {
+			done = s.Progress[i]
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%d/%d)", o.Description, done, o.Count))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's questor for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Questor], fn func(*command.Context, Questor) error) command.Handler {
+	return func(ctx *command.Context) error {
+		q := resolve(ctx.Caller)
+		if q == nil {
+			return ctx.Send("You can't go on quests.")
+		}
+
+		err := fn(ctx, q)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
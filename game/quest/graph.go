@@ -0,0 +1,27 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package quest
+
+import "github.com/bbuck/dragon-mud/data"
+
+// LinkGraph replaces the quests in the graph with the definitions, each
+// quest related to those it requires.
+func (d *Defs) LinkGraph(g data.Graph) error {
+	if err := g.Clear(data.QuestLabel); err != nil {
+		return err
+	}
+	for _, def := range d.All() {
+		for _, id := range def.Requires {
+			err := g.Link(data.Edge{
+				From: data.Node{Label: data.QuestLabel, ID: def.ID},
+				Kind: data.RequiresKind,
+				To:   data.Node{Label: data.QuestLabel, ID: id},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
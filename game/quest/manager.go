@@ -0,0 +1,402 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package quest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The events of quests. Handlers of before:quest:start and
+// before:quest:abandon can stop them by returning events.ErrHalt, or an error
+// whose message is told to the player. Each is given the id and name of the
+// player and the id and name of the quest, progress is also given the
+// objective's number, kind and target and how much of its count is done.
+const (
+	StartEvent    = "quest:start"
+	ProgressEvent = "quest:progress"
+	CompleteEvent = "quest:complete"
+	AbandonEvent  = "quest:abandon"
+)
+
+// Messages told to players who can't start or abandon a quest.
+const (
+	UnknownMessage  = "You don't know of any such quest."
+	NoGiverMessage  = "No one here offers that quest."
+	ActiveMessage   = "You're already on that quest."
+	DoneMessage     = "You've already finished that quest."
+	NotReadyMessage = "You aren't ready for that quest yet."
+	NotOnMessage    = "You aren't on that quest."
+	CancelMessage   = "You can't do that right now."
+)
+
+// Questor is a character who goes on quests, like a player. Their "level"
+// stat decides which quests they can start.
+type Questor interface {
+	ID() string
+	Name() string
+	Location() string
+	Stat(name string) int
+	AddStat(name string, delta int) int
+	// Quest returns the questor's state for the quest with the id.
+	Quest(id string) (State, bool)
+	// SetQuest changes the state of the quest, a zero state forgets it.
+	SetQuest(id string, s State)
+	Quests() map[string]State
+}
+
+// Sender is a questor told how their quests go.
+type Sender interface {
+	Send(text string) error
+}
+
+// Manager starts quests and moves them forward.
+type Manager struct {
+	defs    *Defs
+	world   *world.World
+	nearby  func(room string) []string
+	clock   func() time.Time
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager for the defined quests, making reward items
+// from the world's definitions. The emitter may be nil.
+func NewManager(defs *Defs, w *world.World, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:    defs,
+		world:   w,
+		clock:   time.Now,
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's quest manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), world.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetNearby sets how to find the NPCs in a room, as the ids of their
+// definitions, so players can accept the quests they give.
+func (m *Manager) SetNearby(fn func(room string) []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nearby = fn
+}
+
+// SetClock changes where the manager gets the time quests start.
+func (m *Manager) SetClock(fn func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.clock = fn
+}
+
+// Defs returns the quest definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// Offered returns the quests given by NPCs in the questor's room that they
+// can start, sorted by name.
+func (m *Manager) Offered(q Questor) []Def {
+	m.mutex.RLock()
+	nearby := m.nearby
+	m.mutex.RUnlock()
+
+	if nearby == nil {
+		return nil
+	}
+	givers := make(map[string]bool)
+	for _, proto := range nearby(q.Location()) {
+		givers[proto] = true
+	}
+	var offered []Def
+	for _, def := range m.defs.All() {
+		if def.Giver != "" && givers[def.Giver] && m.startable(q, def) == nil {
+			offered = append(offered, def)
+		}
+	}
+
+	return offered
+}
+
+// Accept starts the quest with the name offered by an NPC in the questor's
+// room.
+func (m *Manager) Accept(q Questor, name string) (Def, error) {
+	for _, def := range m.Offered(q) {
+		if def.Named(name) {
+			return def, m.Start(q, def.ID)
+		}
+	}
+
+	return Def{}, &item.Refused{Message: NoGiverMessage}
+}
+
+// Start puts the questor on the quest with the id, if they're ready for it.
+func (m *Manager) Start(q Questor, id string) error {
+	def, ok := m.defs.Get(id)
+	if !ok {
+		return &item.Refused{Message: UnknownMessage}
+	}
+	if err := m.startable(q, def); err != nil {
+		return err
+	}
+	data := questData(q, def)
+	if err := m.check(StartEvent, data); err != nil {
+		return err
+	}
+
+	m.mutex.RLock()
+	now := m.clock()
+	m.mutex.RUnlock()
+
+	old, _ := q.Quest(id)
+	q.SetQuest(id, State{
+		Status:   Active,
+		Progress: make([]int, len(def.Objectives)),
+		Started:  now,
+		Finished: old.Finished,
+	})
+	tell(q, fmt.Sprintf("You begin the quest %s.", def.Name))
+	m.confirm(StartEvent, data)
+
+	return nil
+}
+
+// Abandon takes the questor off the quest with the id, losing their
+// progress.
+func (m *Manager) Abandon(q Questor, id string) error {
+	def, ok := m.defs.Get(id)
+	s, on := q.Quest(id)
+	if !ok || !on || s.Status != Active {
+		return &item.Refused{Message: NotOnMessage}
+	}
+	data := questData(q, def)
+	if err := m.check(AbandonEvent, data); err != nil {
+		return err
+	}
+
+	if s.Finished > 0 {
+		q.SetQuest(id, State{Status: Completed, Started: s.Started, Finished: s.Finished})
+	} else {
+		q.SetQuest(id, State{})
+	}
+	tell(q, fmt.Sprintf("You abandon the quest %s.", def.Name))
+	m.confirm(AbandonEvent, data)
+
+	return nil
+}
+
+// Progress counts n of the kind done to the target towards the questor's
+// active quests, finishing those whose objectives are all met. It returns
+// how many quests moved forward.
+func (m *Manager) Progress(q Questor, kind, target string, n int) int {
+	kind = strings.ToLower(kind)
+	quests := q.Quests()
+	ids := make([]string, 0, len(quests))
+	for id := range quests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	advanced := 0
+	for _, id := range ids {
+		s := quests[id]
+		def, ok := m.defs.Get(id)
+		if !ok || s.Status != Active {
+			continue
+		}
+		if len(s.Progress) < len(def.Objectives) {
+			s.Progress = append(s.Progress, make([]int, len(def.Objectives)-len(s.Progress))...)
+		}
+
+		var moved []int
+		for i, o := range def.Objectives {
+			if o.Kind != kind || !strings.EqualFold(o.Target, target) || s.Progress[i] >= o.Count {
+				continue
+			}
+			s.Progress[i] += n
+			if s.Progress[i] > o.Count {
+				s.Progress[i] = o.Count
+			}
+			moved = append(moved, i)
+		}
+		if len(moved) == 0 {
+			continue
+		}
+		advanced++
+		q.SetQuest(id, s)
+
+		for _, i := range moved {
+			o := def.Objectives[i]
+			tell(q, fmt.Sprintf("%s: %s (%d/%d)", def.Name, o.Description, s.Progress[i], o.Count))
+			data := questData(q, def)
+			data["objective"] = i + 1
+			data["kind"] = o.Kind
+			data["target"] = o.Target
+			data["progress"] = s.Progress[i]
+			data["count"] = o.Count
+			m.emit(ProgressEvent, data)
+		}
+		if s.Met(def) {
+			m.complete(q, def, s)
+		}
+	}
+
+	return advanced
+}
+
+// Complete finishes the questor's quest with the id whether its objectives
+// are met or not, giving its reward.
+func (m *Manager) Complete(q Questor, id string) error {
+	def, ok := m.defs.Get(id)
+	s, on := q.Quest(id)
+	if !ok || !on || s.Status != Active {
+		return &item.Refused{Message: NotOnMessage}
+	}
+	m.complete(q, def, s)
+
+	return nil
+}
+
+// startable returns why the questor can't start the quest, or nil
+func (m *Manager) startable(q Questor, def Def) error {
+	if s, ok := q.Quest(def.ID); ok {
+		if s.Status == Active {
+			return &item.Refused{Message: ActiveMessage}
+		}
+		if !def.Repeatable {
+			return &item.Refused{Message: DoneMessage}
+		}
+	}
+	if q.Stat("level") < def.Level {
+		return &item.Refused{Message: NotReadyMessage}
+	}
+	for _, id := range def.Requires {
+		if s, ok := q.Quest(id); !ok || s.Finished == 0 {
+			return &item.Refused{Message: NotReadyMessage}
+		}
+	}
+
+	return nil
+}
+
+// complete marks the quest done and gives its reward
+func (m *Manager) complete(q Questor, def Def, s State) {
+	s.Status = Completed
+	s.Progress = nil
+	s.Finished++
+	q.SetQuest(def.ID, s)
+
+	for _, stat := range sortedKeys(def.Reward.Stats) {
+		q.AddStat(stat, def.Reward.Stats[stat])
+	}
+	if c, ok := q.(item.Carrier); ok && m.world != nil {
+		for _, proto := range sortedKeys(def.Reward.Items) {
+			it, err := item.Create(m.world, proto, def.Reward.Items[proto])
+			if err != nil {
+				continue
+			}
+			c.UpdateInventory(func(l item.List) (item.List, error) {
+				return l.Add(it), nil
+			})
+		}
+	}
+
+	tell(q, fmt.Sprintf("You have completed the quest %s!", def.Name))
+	if def.Reward.Message != "" {
+		tell(q, def.Reward.Message)
+	}
+	m.emit(CompleteEvent, questData(q, def))
+}
+
+func tell(q Questor, text string) {
+	if s, ok := q.(Sender); ok {
+		s.Send(text)
+	}
+}
+
+func questData(q Questor, def Def) events.Data {
+	return events.Data{
+		"player":      q.ID(),
+		"player_name": q.Name(),
+		"quest":       def.ID,
+		"quest_name":  def.Name,
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
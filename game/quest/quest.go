@@ -0,0 +1,269 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package quest gives players quests to finish. Quests are defined in YAML
+// files or from scripts with objectives to kill, collect, visit or talk to
+// things, and rewards for finishing them. Each player's progress on their
+// quests is kept on them so it's saved with them, and moves forward as the
+// game tells the manager what players do.
+package quest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Kinds of objective the game tracks, scripts can progress kinds of their
+// own.
+const (
+	// Kill objectives are met by killing mobs made from an NPC definition.
+	Kill = "kill"
+	// Collect objectives are met by picking up items made from an item
+	// definition.
+	Collect = "collect"
+	// Visit objectives are met by entering a room.
+	Visit = "visit"
+	// Talk objectives are met by talking to an NPC, as scripts decide.
+	Talk = "talk"
+)
+
+// The status of a quest a player has started.
+const (
+	Active    = "active"
+	Completed = "completed"
+)
+
+// Objective is something to do for a quest.
+type Objective struct {
+	// Kind is what's done, like Kill.
+	Kind string `yaml:"kind"`
+	// Target is the id of what it's done to, like an NPC definition for Kill
+	// or a room for Visit.
+	Target string `yaml:"target"`
+	// Count is how many times it's done, one by default.
+	Count int `yaml:"count,omitempty"`
+	// Description tells players what to do, like "Kill 5 rats".
+	Description string `yaml:"description,omitempty"`
+}
+
+// Reward is what finishing a quest gives.
+type Reward struct {
+	// Stats are added to the player's stats, like {xp: 100, gold: 20}.
+	Stats map[string]int `yaml:"stats,omitempty"`
+	// Items are the ids of item definitions and how many of each to give.
+	Items map[string]int `yaml:"items,omitempty"`
+	// Message is told to the player when they finish.
+	Message string `yaml:"message,omitempty"`
+}
+
+// Def defines a quest.
+type Def struct {
+	// ID is how the quest is known to scripts and saved on players.
+	ID string `yaml:"id"`
+	// Name is what players call the quest, like "Rat Problem".
+	Name string `yaml:"name"`
+	// Summary tells players what the quest is about.
+	Summary string `yaml:"summary,omitempty"`
+	// Giver is the id of the NPC definition offering the quest, players
+	// accept it in the same room as one. Quests without givers are started
+	// by scripts.
+	Giver string `yaml:"giver,omitempty"`
+	// Level is the lowest "level" stat that can start the quest.
+	Level int `yaml:"level,omitempty"`
+	// Requires are the ids of quests that must be done first.
+	Requires []string `yaml:"requires,omitempty"`
+	// Repeatable quests can be started again once they're done.
+	Repeatable bool        `yaml:"repeatable,omitempty"`
+	Objectives []Objective `yaml:"objectives"`
+	Reward     Reward      `yaml:"reward,omitempty"`
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("quests need an id")
+	}
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+	if len(d.Objectives) == 0 {
+		return fmt.Errorf("quest %s: needs objectives", d.ID)
+	}
+	objectives := make([]Objective, len(d.Objectives))
+	for i, o := range d.Objectives {
+		if o.Kind == "" || o.Target == "" {
+			return fmt.Errorf("quest %s: objective %d needs a kind and target", d.ID, i+1)
+		}
+		if o.Count < 0 {
+			return fmt.Errorf("quest %s: objective %d can't have a negative count", d.ID, i+1)
+		}
+		if o.Count == 0 {
+			o.Count = 1
+		}
+		o.Kind = strings.ToLower(o.Kind)
+		if o.Description == "" {
+			o.Description = fmt.Sprintf("%s %s", strings.Title(o.Kind), o.Target)
+			if o.Count > 1 {
+				o.Description += fmt.Sprintf(" %d times", o.Count)
+			}
+		}
+		objectives[i] = o
+	}
+	d.Objectives = objectives
+
+	return nil
+}
+
+// Named is true if the name is or starts the quest's name, ignoring case.
+func (d Def) Named(name string) bool {
+	name = strings.ToLower(name)
+
+	return name != "" && strings.HasPrefix(strings.ToLower(d.Name), name)
+}
+
+// State is a player's progress on a quest.
+type State struct {
+	// Status is Active or Completed.
+	Status string `json:"status"`
+	// Progress counts what's been done towards each objective, in order.
+	Progress []int     `json:"progress,omitempty"`
+	Started  time.Time `json:"started"`
+	// Finished counts how many times the quest has been done.
+	Finished int `json:"finished,omitempty"`
+}
+
+// Copy returns a copy of the state that shares nothing with it.
+func (s State) Copy() State {
+	s.Progress = append([]int(nil), s.Progress...)
+
+	return s
+}
+
+// Met is true if the state has done each of the quest's objectives.
+func (s State) Met(d Def) bool {
+	for i, o := range d.Objectives {
+		if i >= len(s.Progress) || s.Progress[i] < o.Count {
+			return false
+		}
+	}
+
+	return true
+}
+
+// File is the layout of a quest file, a list of quests.
+type File struct {
+	Quests []Def `yaml:"quests"`
+}
+
+// Defs holds the quest definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[id]
+
+	return def, ok
+}
+
+// All returns every definition, sorted by name.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+
+	return defs
+}
+
+// LoadDir adds the quests in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the quests listed in the YAML document, like:
+//   quests:
+//     - id: rat-problem
+//       name: Rat Problem
+//       giver: innkeeper
+//       objectives:
+//         - {kind: kill, target: rat, count: 5}
+//       reward: {stats: {xp: 100}}
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Quests {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
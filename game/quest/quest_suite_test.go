@@ -0,0 +1,13 @@
+package quest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestQuest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quest Suite")
+}
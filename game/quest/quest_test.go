@@ -0,0 +1,267 @@
+package quest_test
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// hero goes on quests and remembers what they're told
+type hero struct {
+	name      string
+	stats     map[string]int
+	quests    map[string]State
+	inventory item.List
+	sent      []string
+}
+
+func (h *hero) ID() string {
+	return strings.ToLower(h.name)
+}
+
+func (h *hero) Name() string {
+	return h.name
+}
+
+func (h *hero) Location() string {
+	return "inn"
+}
+
+func (h *hero) Stat(name string) int {
+	return h.stats[name]
+}
+
+func (h *hero) AddStat(name string, delta int) int {
+	h.stats[name] += delta
+
+	return h.stats[name]
+}
+
+func (h *hero) Quest(id string) (State, bool) {
+	s, ok := h.quests[id]
+
+	return s.Copy(), ok
+}
+
+func (h *hero) SetQuest(id string, s State) {
+	if s.Status == "" {
+		delete(h.quests, id)
+	} else {
+		h.quests[id] = s.Copy()
+	}
+}
+
+func (h *hero) Quests() map[string]State {
+	quests := make(map[string]State)
+	for id, s := range h.quests {
+		quests[id] = s.Copy()
+	}
+
+	return quests
+}
+
+func (h *hero) Inventory() item.List {
+	return h.inventory.Copy()
+}
+
+func (h *hero) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	l, err := fn(h.inventory.Copy())
+	if err == nil {
+		h.inventory = l
+	}
+
+	return err
+}
+
+func (h *hero) Level() command.Level {
+	return command.Player
+}
+
+func (h *hero) Send(text string) error {
+	h.sent = append(h.sent, text)
+
+	return nil
+}
+
+func (h *hero) told(text string) bool {
+	for _, s := range h.sent {
+		if s == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+const quests = `
+quests:
+  - id: rats
+    name: Rat Problem
+    summary: The cellar is full of rats.
+    giver: innkeeper
+    objectives:
+      - {kind: kill, target: rat, count: 3, description: Kill 3 rats}
+      - {kind: visit, target: cellar}
+    reward:
+      stats: {xp: 100}
+      items: {cheese: 2}
+      message: The innkeeper thanks you.
+  - id: king-rat
+    name: The Rat King
+    requires: [rats]
+    level: 5
+    objectives:
+      - {kind: kill, target: rat-king}
+  - id: deliveries
+    name: Deliveries
+    giver: innkeeper
+    repeatable: true
+    objectives:
+      - {kind: talk, target: baker}
+`
+
+var _ = Describe("Quests", func() {
+	var (
+		em      *events.Emitter
+		m       *Manager
+		alice   *hero
+		started time.Time
+	)
+
+	BeforeEach(func() {
+		w := world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "cheese", Zone: "town", Name: "a wedge of cheese", Flags: []string{"stackable"}})).Should(Succeed())
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(quests))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, w, em)
+		started = time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time {
+			return started
+		})
+		m.SetNearby(func(room string) []string {
+			return []string{"innkeeper"}
+		})
+		alice = &hero{name: "Alice", stats: map[string]int{"level": 1}, quests: map[string]State{}}
+	})
+
+	It("validates definitions", func() {
+		defs := NewDefs()
+		Ω(defs.Add(Def{ID: "empty"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "aimless", Objectives: []Objective{{Kind: "kill"}}})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "hunt", Objectives: []Objective{{Kind: "KILL", Target: "wolf", Count: 2}}})).Should(Succeed())
+
+		def, _ := defs.Get("hunt")
+		Ω(def.Name).Should(Equal("hunt"))
+		Ω(def.Objectives[0]).Should(Equal(Objective{Kind: Kill, Target: "wolf", Count: 2, Description: "Kill wolf 2 times"}))
+	})
+
+	It("tracks progress and rewards finished quests", func() {
+		def, err := m.Accept(alice, "rat")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(def.ID).Should(Equal("rats"))
+		Ω(alice.quests["rats"]).Should(Equal(State{Status: Active, Progress: []int{0, 0}, Started: started}))
+
+		Ω(m.Progress(alice, Kill, "rat", 2)).Should(Equal(1))
+		Ω(m.Progress(alice, Kill, "wolf", 1)).Should(Equal(0))
+		Ω(alice.told("Rat Problem: Kill 3 rats (2/3)")).Should(BeTrue())
+		m.Progress(alice, Kill, "rat", 5)
+		Ω(alice.quests["rats"].Progress).Should(Equal([]int{3, 0}))
+		Ω(m.Progress(alice, Kill, "rat", 1)).Should(Equal(0))
+
+		m.Progress(alice, Visit, "cellar", 1)
+		Ω(alice.quests["rats"].Status).Should(Equal(Completed))
+		Ω(alice.quests["rats"].Finished).Should(Equal(1))
+		Ω(alice.stats["xp"]).Should(Equal(100))
+		Ω(alice.inventory).Should(HaveLen(1))
+		Ω(alice.inventory[0].Quantity()).Should(Equal(2))
+		Ω(alice.told("You have completed the quest Rat Problem!")).Should(BeTrue())
+		Ω(alice.told("The innkeeper thanks you.")).Should(BeTrue())
+
+		Ω(m.Start(alice, "rats")).Should(MatchError(DoneMessage))
+	})
+
+	It("checks who's ready for a quest", func() {
+		Ω(m.Start(alice, "nothing")).Should(MatchError(UnknownMessage))
+		Ω(m.Start(alice, "king-rat")).Should(MatchError(NotReadyMessage))
+		alice.quests["rats"] = State{Status: Completed, Finished: 1}
+		Ω(m.Start(alice, "king-rat")).Should(MatchError(NotReadyMessage))
+		alice.stats["level"] = 5
+		Ω(m.Start(alice, "king-rat")).Should(Succeed())
+		Ω(m.Start(alice, "king-rat")).Should(MatchError(ActiveMessage))
+
+		_, err := m.Accept(alice, "rat king")
+		Ω(err).Should(MatchError(NoGiverMessage))
+	})
+
+	It("repeats and abandons quests", func() {
+		Ω(m.Start(alice, "deliveries")).Should(Succeed())
+		Ω(m.Complete(alice, "deliveries")).Should(Succeed())
+		Ω(m.Start(alice, "deliveries")).Should(Succeed())
+		Ω(m.Abandon(alice, "deliveries")).Should(Succeed())
+		Ω(alice.quests["deliveries"]).Should(Equal(State{Status: Completed, Started: started, Finished: 1}))
+		Ω(m.Abandon(alice, "deliveries")).Should(MatchError(NotOnMessage))
+
+		Ω(m.Start(alice, "rats")).Should(Succeed())
+		Ω(m.Abandon(alice, "rats")).Should(Succeed())
+		Ω(alice.quests).ShouldNot(HaveKey("rats"))
+	})
+
+	It("lets before handlers stop quests from starting", func(done Done) {
+		completed := make(chan events.Data, 1)
+		em.On("before:"+StartEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["quest"] == "rats" {
+				return errors.New("The innkeeper ignores you.")
+			}
+
+			return nil
+		}))
+		em.On(CompleteEvent, events.HandlerFunc(func(d events.Data) error {
+			completed <- d
+
+			return nil
+		}))
+
+		Ω(m.Start(alice, "rats")).Should(MatchError("The innkeeper ignores you."))
+		Ω(m.Start(alice, "deliveries")).Should(Succeed())
+		m.Progress(alice, Talk, "Baker", 1)
+
+		d := <-completed
+		Ω(d["player"]).Should(Equal("alice"))
+		Ω(d["quest_name"]).Should(Equal("Deliveries"))
+		close(done)
+	})
+
+	It("lists and describes quests with the quest command", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Questor {
+			return c.(*hero)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatch := func(line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+		}
+
+		dispatch("quests")
+		Ω(alice.told("You aren't on any quests.\nOffered here:\n  Deliveries\n  Rat Problem")).Should(BeTrue())
+		dispatch("quest accept rat")
+		m.Progress(alice, Kill, "rat", 1)
+		dispatch("quest info rat")
+		Ω(alice.told("Rat Problem\nThe cellar is full of rats.\n  Kill 3 rats (1/3)\n  Visit cellar (0/1)")).Should(BeTrue())
+		dispatch("quest abandon rat")
+		Ω(alice.told("You abandon the quest Rat Problem.")).Should(BeTrue())
+		dispatch("quest abandon rat")
+		Ω(alice.told(NotOnMessage)).Should(BeTrue())
+	})
+})
@@ -0,0 +1,250 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package save periodically saves what changed in the game, like players and
+// the zones builders edited, and saves it all one last time when the server
+// stops. Each kind of thing saved registers a saver, which saves only what
+// changed since it last ran and reports how many were saved.
+package save
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// CompletedEvent is emitted after every save with the reason it was run,
+// the number saved by each saver, the errors of those that failed, the total
+// saved and how long it took in seconds.
+const CompletedEvent = "save:completed"
+
+// The reasons saves are run.
+const (
+	Autosave = "autosave"
+	Shutdown = "shutdown"
+	Manual   = "manual"
+)
+
+// Saver saves what changed since it last ran, returning the number saved.
+type Saver func() (int, error)
+
+// Stats describes a save.
+type Stats struct {
+	Reason   string
+	Started  time.Time
+	Duration time.Duration
+	// Saved is the number each saver saved, by name.
+	Saved map[string]int
+	// Failed is the error of each saver that failed, by name.
+	Failed map[string]error
+}
+
+// Total returns the number saved by every saver.
+func (s Stats) Total() int {
+	total := 0
+	for _, n := range s.Saved {
+		total += n
+	}
+
+	return total
+}
+
+// Data returns the stats as the data of CompletedEvent.
+func (s Stats) Data() events.Data {
+	saved := make(map[string]interface{}, len(s.Saved))
+	for name, n := range s.Saved {
+		saved[name] = n
+	}
+	failed := make(map[string]interface{}, len(s.Failed))
+	for name, err := range s.Failed {
+		failed[name] = err.Error()
+	}
+
+	return events.Data{
+		"reason":   s.Reason,
+		"saved":    saved,
+		"failed":   failed,
+		"total":    s.Total(),
+		"duration": s.Duration.Seconds(),
+	}
+}
+
+// Manager runs the savers of the game.
+type Manager struct {
+	savers  map[string]Saver
+	emitter *events.Emitter
+	stop    chan struct{}
+	done    chan struct{}
+	saving  *sync.Mutex
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager without any savers. The emitter may be nil.
+func NewManager(em *events.Emitter) *Manager {
+	return &Manager{
+		savers:  make(map[string]Saver),
+		emitter: em,
+		saving:  new(sync.Mutex),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's save manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// Register adds the saver with the name, replacing any there was.
+func (m *Manager) Register(name string, s Saver) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.savers[name] = s
+}
+
+// Unregister removes the saver with the name.
+func (m *Manager) Unregister(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.savers, name)
+}
+
+// Names returns the names of the savers, sorted.
+func (m *Manager) Names() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	names := make([]string, 0, len(m.savers))
+	for name := range m.savers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Save runs every saver in the order of their names, one save at a time.
+// Every saver is run even if others fail, the first error is returned along
+// with the stats, which are emitted with CompletedEvent.
+func (m *Manager) Save(reason string) (Stats, error) {
+	m.saving.Lock()
+	defer m.saving.Unlock()
+
+	stats := Stats{
+		Reason:  reason,
+		Started: time.Now(),
+		Saved:   make(map[string]int),
+		Failed:  make(map[string]error),
+	}
+	var firstErr error
+	for _, name := range m.Names() {
+		m.mutex.RLock()
+		s, ok := m.savers[name]
+		m.mutex.RUnlock()
+		if !ok {
+			continue
+		}
+
+		n, err := s()
+		stats.Saved[name] = n
+		if err != nil {
+			stats.Failed[name] = err
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	stats.Duration = time.Since(stats.Started)
+	m.emit(CompletedEvent, stats.Data())
+
+	return stats, firstErr
+}
+
+// Start saves from the background at the interval given, until Stop is
+// called. Intervals of zero or less don't save from the background.
+func (m *Manager) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(interval, m.stop, m.done)
+}
+
+// Stop stops saving from the background and saves one last time.
+func (m *Manager) Stop() (Stats, error) {
+	m.mutex.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	return m.Save(Shutdown)
+}
+
+func (m *Manager) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	log := logger.NewWithSource("save")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			stats, err := m.Save(Autosave)
+			l := log.WithFields(logger.Fields{
+				"saved":    stats.Total(),
+				"duration": stats.Duration.String(),
+			})
+			if err != nil {
+				l.WithError(err).Error("Failed to save everything that changed.")
+			} else if stats.Total() > 0 {
+				l.Debug("Saved what changed.")
+			}
+		}
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
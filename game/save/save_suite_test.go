@@ -0,0 +1,13 @@
+package save_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Save Suite")
+}
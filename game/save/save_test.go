@@ -0,0 +1,79 @@
+package save_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/save"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	var (
+		m  *Manager
+		em *events.Emitter
+	)
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		m = NewManager(em)
+	})
+
+	It("runs every saver and emits the stats", func() {
+		completed := make(chan events.Data, 1)
+		em.On(CompletedEvent, events.HandlerFunc(func(d events.Data) error {
+			completed <- d
+
+			return nil
+		}))
+		m.Register("players", func() (int, error) {
+			return 3, nil
+		})
+		m.Register("zones", func() (int, error) {
+			return 1, errors.New("disk full")
+		})
+		m.Register("boards", func() (int, error) {
+			return 0, nil
+		})
+		m.Unregister("boards")
+
+		stats, err := m.Save(Manual)
+		Ω(err).Should(MatchError("disk full"))
+		Ω(m.Names()).Should(Equal([]string{"players", "zones"}))
+		Ω(stats.Reason).Should(Equal(Manual))
+		Ω(stats.Total()).Should(Equal(4))
+		Ω(stats.Failed).Should(HaveKey("zones"))
+
+		var d events.Data
+		Eventually(completed).Should(Receive(&d))
+		Ω(d["reason"]).Should(Equal(Manual))
+		Ω(d["total"]).Should(Equal(4))
+		Ω(d["saved"]).Should(HaveKeyWithValue("players", 3))
+		Ω(d["failed"]).Should(HaveKeyWithValue("zones", "disk full"))
+	})
+
+	It("saves in the background and once more when stopped", func() {
+		var runs int32
+		m.Register("players", func() (int, error) {
+			atomic.AddInt32(&runs, 1)
+
+			return 0, nil
+		})
+
+		m.Start(5 * time.Millisecond)
+		Eventually(func() int32 {
+			return atomic.LoadInt32(&runs)
+		}).Should(BeNumerically(">=", 2))
+
+		stats, err := m.Stop()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(stats.Reason).Should(Equal(Shutdown))
+		after := atomic.LoadInt32(&runs)
+		time.Sleep(20 * time.Millisecond)
+		Ω(atomic.LoadInt32(&runs)).Should(Equal(after))
+	})
+})
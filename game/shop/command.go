@@ -0,0 +1,154 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package shop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the list, buy, sell and value commands for trading
+// with the shop in the caller's room. Items may be preceded by how many, like
+// "buy 20 arrows".
+func NewCommands(m *Manager, resolve command.Resolver[Customer]) []*command.Command {
+	text := []command.Arg{{Name: "what", Kind: command.Text, Optional: true}}
+
+	return []*command.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"wares"},
+			Help:    "Lists what the shop here sells and what it costs.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Customer) error {
+				def, wares, err := m.Wares(c)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(listing(m, def, wares, m.Balance(c)))
+			}),
+		},
+		{
+			Name:   "buy",
+			Args:   text,
+			Help:   "Buys something the shop here sells.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Customer) error {
+				count, what := parseCount(ctx.Input.Words)
+				if what == "" {
+					return ctx.Send("Buy what?")
+				}
+				r, err := m.Buy(c, what, count)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You buy %s for %s.", describe(r), m.Currency().Format(r.Total)))
+			}),
+		},
+		{
+			Name:   "sell",
+			Args:   text,
+			Help:   "Sells something you carry to the shop here.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Customer) error {
+				count, what := parseCount(ctx.Input.Words)
+				if what == "" {
+					return ctx.Send("Sell what?")
+				}
+				r, err := m.Sell(c, what, count)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You sell %s for %s.", describe(r), m.Currency().Format(r.Total)))
+			}),
+		},
+		{
+			Name:      "value",
+			Args:      text,
+			MinAbbrev: 3,
+			Help:      "Asks what the shop here would pay for something you carry.",
+			Source:    "game",
+			Handler: handler(resolve, func(ctx *command.Context, c Customer) error {
+				count, what := parseCount(ctx.Input.Words)
+				if what == "" {
+					return ctx.Send("Value what?")
+				}
+				_, r, err := m.Value(c, what, count)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("The shop would pay %s for %s.", m.Currency().Format(r.Total), describe(r)))
+			}),
+		},
+	}
+}
+
+// listing lists the shop's wares with their prices and how many are left
+func listing(m *Manager, def Def, wares []Ware, balance int) string {
+	lines := []string{def.Name + " sells:"}
+	for _, w := range wares {
+		if w.Price < 0 {
+			continue
+		}
+		line := fmt.Sprintf("  %s - %s", w.Item.Name, m.Currency().Format(w.Price))
+		switch {
+		case w.Stock == 0:
+			line += " (sold out)"
+		case w.Stock > 0:
+			line += fmt.Sprintf(" (%d left)", w.Stock)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 1 {
+		lines = []string{def.Name + " has nothing for sale."}
+	}
+	lines = append(lines, fmt.Sprintf("You have %s.", m.Currency().Format(balance)))
+
+	return strings.Join(lines, "\n")
+}
+
+// describe names what was traded, with how many
+func describe(r Receipt) string {
+	if r.Count > 1 {
+		return fmt.Sprintf("%s (%d)", r.Item.Name, r.Count)
+	}
+
+	return r.Item.Name
+}
+
+// parseCount splits words like "20 arrows" into how many and what
+func parseCount(words []string) (int, string) {
+	count := 0
+	if len(words) > 1 {
+		if n, err := strconv.Atoi(words[0]); err == nil && n > 0 {
+			count, words = n, words[1:]
+		}
+	}
+
+	return count, strings.Join(words, " ")
+}
+
+// handler resolves the caller's customer for fn, telling the caller when a
+// sale is refused
+func handler(resolve command.Resolver[Customer], fn func(*command.Context, Customer) error) command.Handler {
+	return func(ctx *command.Context) error {
+		c := resolve(ctx.Caller)
+		if c == nil {
+			return ctx.Send("You can't trade.")
+		}
+
+		err := fn(ctx, c)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package shop
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Denomination is a coin and how many of the smallest coin it's worth.
+type Denomination struct {
+	Name  string
+	Value int
+}
+
+// Currency is the coins money is counted in, largest first. Characters keep
+// their money as a single stat counted in the smallest coin.
+type Currency []Denomination
+
+// DefaultCurrency is gold, silver and copper, ten of each worth one of the
+// next.
+var DefaultCurrency = Currency{{"gold", 100}, {"silver", 10}, {"copper", 1}}
+
+// ParseCurrency reads denominations like "gold:100", one must be worth 1.
+func ParseCurrency(denominations []string) (Currency, error) {
+	var c Currency
+	smallest := false
+	for _, d := range denominations {
+		parts := strings.SplitN(d, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("denomination %q must look like \"gold:100\"", d)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("denomination %q must be worth a whole number of coins", d)
+		}
+		smallest = smallest || value == 1
+		c = append(c, Denomination{Name: strings.ToLower(strings.TrimSpace(parts[0])), Value: value})
+	}
+	if !smallest {
+		return nil, fmt.Errorf("one denomination must be worth 1")
+	}
+	sort.SliceStable(c, func(i, j int) bool {
+		return c[i].Value > c[j].Value
+	})
+
+	return c, nil
+}
+
+// Format describes the amount in the fewest coins, like "3 gold, 4 copper".
+func (c Currency) Format(amount int) string {
+	if amount <= 0 {
+		return "nothing"
+	}
+	var parts []string
+	for _, d := range c {
+		if n := amount / d.Value; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, d.Name))
+			amount -= n * d.Value
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Parse reads an amount like "3 gold 4 silver", "3g 4s" or a number of the
+// smallest coin.
+func (c Currency) Parse(s string) (int, error) {
+	fields := strings.Fields(strings.ToLower(strings.Replace(s, ",", " ", -1)))
+	if len(fields) == 1 {
+		if n, err := strconv.Atoi(fields[0]); err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+
+	total := 0
+	for i := 0; i < len(fields); i++ {
+		word := fields[i]
+		n, err := strconv.Atoi(word)
+		if err == nil {
+			if i+1 == len(fields) {
+				return 0, fmt.Errorf("%d of what?", n)
+			}
+			i++
+			word = fields[i]
+		} else {
+			j := strings.IndexFunc(word, func(r rune) bool {
+				return r < '0' || r > '9'
+			})
+			if j <= 0 {
+				return 0, fmt.Errorf("%q isn't an amount of money", word)
+			}
+			n, _ = strconv.Atoi(word[:j])
+			word = word[j:]
+		}
+		d, ok := c.denomination(word)
+		if !ok {
+			return 0, fmt.Errorf("%q isn't a coin", word)
+		}
+		total += n * d.Value
+	}
+
+	return total, nil
+}
+
+// denomination returns the coin the word names or starts
+func (c Currency) denomination(word string) (Denomination, bool) {
+	for _, d := range c {
+		if d.Name == word || (word != "" && strings.HasPrefix(d.Name, word)) {
+			return d, true
+		}
+	}
+
+	return Denomination{}, false
+}
@@ -0,0 +1,697 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package shop
+
+import (
+	"sync"
+
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The events of trading. Handlers of before:shop:buy and before:shop:sell can
+// stop the sale by returning events.ErrHalt, or an error whose message is
+// told to the player. Each is given the id and name of the player, the id of
+// the shop, the id and name of the item, how many, the price of each and the
+// total.
+const (
+	BuyEvent  = "shop:buy"
+	SellEvent = "shop:sell"
+)
+
+// Which way a sale goes, as given to pricers and written in the ledger.
+const (
+	Buying  = "buy"
+	Selling = "sell"
+)
+
+// Messages told to players who can't buy or sell something.
+const (
+	NoShopMessage     = "There's no shop here."
+	NotSoldMessage    = "The shop doesn't sell that."
+	SoldOutMessage    = "The shop doesn't have that many."
+	PoorMessage       = "You can't afford that."
+	TooHeavyMessage   = "You can't carry that much weight."
+	NotCarriedMessage = "You aren't carrying that."
+	NotBoughtMessage  = "The shop doesn't buy that."
+	WorthlessMessage  = "The shop won't pay anything for that."
+	EmptyFirstMessage = "You'll have to empty it first."
+	LedgerMessage     = "The shop can't trade right now."
+	CancelMessage     = "You can't do that right now."
+	TooManyMessage    = "You can't trade that many at once."
+)
+
+// MaxCount is the most of an item bought or sold at once.
+const MaxCount = 1000
+
+// the largest int, totals that would be larger are refused
+const maxInt = int(^uint(0) >> 1)
+
+// Customer is a character who trades with shops, like a player. Their money
+// is a stat.
+type Customer interface {
+	item.Carrier
+	ID() string
+	Stat(name string) int
+	AddStat(name string, delta int) int
+}
+
+// Ledger is where sales are written, like the audit log.
+type Ledger interface {
+	Append(r audit.Record) (*audit.Record, error)
+}
+
+// Quote is the price of a sale as it's being worked out.
+type Quote struct {
+	// Shop is the id of the shop.
+	Shop string
+	// Action is Buying or Selling, from the customer's side.
+	Action string
+	// Customer is the id of who's trading.
+	Customer string
+	// Item is one of what's traded.
+	Item  item.Item
+	Count int
+	// Base is what the shop would charge or pay for each without pricers.
+	Base int
+	// Price is what each costs as changed by the pricers before.
+	Price int
+}
+
+// Pricer changes the price of each item in a sale, returning what it should
+// be. Prices below zero refuse the sale.
+type Pricer func(q Quote) int
+
+type namedPricer struct {
+	name string
+	fn   Pricer
+}
+
+// Ware is something a shop has for sale.
+type Ware struct {
+	Item item.Item
+	// Price is what one costs the customer it was listed for.
+	Price int
+	// Stock is how many the shop has, below zero is no limit.
+	Stock int
+	// sold is true for items players sold the shop
+	sold bool
+	// stock is the definition the ware is sold from
+	stock Stock
+}
+
+// Receipt is what was traded.
+type Receipt struct {
+	Shop  Def
+	Item  item.Item
+	Count int
+	// Price is what each cost.
+	Price int
+	Total int
+}
+
+// Manager runs the game's shops.
+type Manager struct {
+	defs     *Defs
+	world    *world.World
+	currency Currency
+	stat     string
+	nearby   func(room string) []string
+	ledger   Ledger
+	pricers  []namedPricer
+	// stock counts what's left of limited stock, by shop then item
+	stock map[string]map[string]int
+	// goods are what players sold each shop
+	goods   map[string]item.List
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager for the defined shops, making what they sell
+// from the world's definitions. Money is kept in the "coins" stat and counted
+// in the default currency until changed. The emitter may be nil.
+func NewManager(defs *Defs, w *world.World, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:     defs,
+		world:    w,
+		currency: DefaultCurrency,
+		stat:     "coins",
+		stock:    make(map[string]map[string]int),
+		goods:    make(map[string]item.List),
+		emitter:  em,
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's shop manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), world.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetNearby sets how to find the NPCs in a room, as the ids of their
+// definitions, so players can trade with the shops they keep.
+func (m *Manager) SetNearby(fn func(room string) []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nearby = fn
+}
+
+// SetLedger sets where sales are written, they aren't without one.
+func (m *Manager) SetLedger(l Ledger) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ledger = l
+}
+
+// SetCurrency changes the stat money is kept in and the coins it's counted
+// in.
+func (m *Manager) SetCurrency(stat string, c Currency) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stat = stat
+	m.currency = c
+}
+
+// Currency returns the coins money is counted in.
+func (m *Manager) Currency() Currency {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.currency
+}
+
+// Balance returns how much money the customer has.
+func (m *Manager) Balance(c Customer) int {
+	m.mutex.RLock()
+	stat := m.stat
+	m.mutex.RUnlock()
+
+	return c.Stat(stat)
+}
+
+// Defs returns the shop definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// SetPricer sets the pricer with the name, replacing any there was, or
+// removes it if p is nil. Pricers run in the order their names were first
+// set.
+func (m *Manager) SetPricer(name string, p Pricer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// pricers are replaced rather than changed, sales may be running over
+	// the old ones
+	var pricers []namedPricer
+	found := false
+	for _, np := range m.pricers {
+		if np.name == name {
+			found = true
+			if p == nil {
+				continue
+			}
+			np.fn = p
+		}
+		pricers = append(pricers, np)
+	}
+	if !found && p != nil {
+		pricers = append(pricers, namedPricer{name, p})
+	}
+	m.pricers = pricers
+}
+
+// Restock refills the limited stock of the shop with the id, what players
+// sold it stays for sale.
+func (m *Manager) Restock(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.stock, id)
+}
+
+// Here returns the shop kept by an NPC in the room.
+func (m *Manager) Here(room string) (Def, bool) {
+	m.mutex.RLock()
+	nearby := m.nearby
+	m.mutex.RUnlock()
+
+	if nearby == nil {
+		return Def{}, false
+	}
+	keepers := make(map[string]bool)
+	for _, proto := range nearby(room) {
+		keepers[proto] = true
+	}
+	for _, def := range m.defs.All() {
+		if keepers[def.Keeper] {
+			return def, true
+		}
+	}
+
+	return Def{}, false
+}
+
+// Wares returns the shop in the customer's room and what it has for sale,
+// priced for them.
+func (m *Manager) Wares(c Customer) (Def, []Ware, error) {
+	def, ok := m.Here(c.Location())
+	if !ok {
+		return Def{}, nil, &item.Refused{Message: NoShopMessage}
+	}
+
+	return def, m.wares(def, c), nil
+}
+
+// Buy buys count of the ware the keyword refers to from the shop in the
+// customer's room, one if count is below one.
+func (m *Manager) Buy(c Customer, keyword string, count int) (Receipt, error) {
+	def, wares, err := m.Wares(c)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if count < 1 {
+		count = 1
+	}
+	if count > MaxCount {
+		return Receipt{}, &item.Refused{Message: TooManyMessage}
+	}
+	w, ok := findWare(wares, keyword)
+	if !ok {
+		return Receipt{}, &item.Refused{Message: NotSoldMessage}
+	}
+	if w.Stock >= 0 && w.Stock < count {
+		return Receipt{}, &item.Refused{Message: SoldOutMessage}
+	}
+	price := m.price(def, Buying, c, w.Item, count, m.base(def, w))
+	if price < 0 {
+		return Receipt{}, &item.Refused{Message: NotSoldMessage}
+	}
+	total, ok := times(price, count)
+	if !ok {
+		return Receipt{}, &item.Refused{Message: PoorMessage}
+	}
+	r := Receipt{Shop: def, Item: w.Item, Count: count, Price: price, Total: total}
+	if m.Balance(c) < r.Total {
+		return Receipt{}, &item.Refused{Message: PoorMessage}
+	}
+	if l, ok := c.(item.Limited); ok && l.CarryLimit() > 0 {
+		weight, ok := times(w.Item.Weight, count)
+		if !ok || c.Inventory().Weight()+weight > l.CarryLimit() {
+			return Receipt{}, &item.Refused{Message: TooHeavyMessage}
+		}
+	}
+	data := saleData(c, r)
+	if err := m.check(BuyEvent, data); err != nil {
+		return Receipt{}, err
+	}
+
+	bought, ok := m.take(def, w, count)
+	if !ok {
+		return Receipt{}, &item.Refused{Message: SoldOutMessage}
+	}
+	if err := m.record(c, Buying, r); err != nil {
+		m.putBack(def, w, bought)
+
+		return Receipt{}, err
+	}
+	m.pay(c, -r.Total)
+	c.UpdateInventory(func(l item.List) (item.List, error) {
+		for _, it := range bought {
+			l = l.Add(it)
+		}
+
+		return l, nil
+	})
+	m.confirm(BuyEvent, data)
+
+	return r, nil
+}
+
+// Sell sells count of the item the keyword refers to that the customer
+// carries to the shop in their room, one if count is below one.
+func (m *Manager) Sell(c Customer, keyword string, count int) (Receipt, error) {
+	def, r, err := m.Value(c, keyword, count)
+	if err != nil {
+		return Receipt{}, err
+	}
+	data := saleData(c, r)
+	if err := m.check(SellEvent, data); err != nil {
+		return Receipt{}, err
+	}
+
+	var sold item.Item
+	err = c.UpdateInventory(func(l item.List) (item.List, error) {
+		var err error
+		l, sold, err = l.Take(r.Item.ID, r.Count)
+
+		return l, err
+	})
+	if err != nil {
+		return Receipt{}, &item.Refused{Message: NotCarriedMessage}
+	}
+	if err := m.record(c, Selling, r); err != nil {
+		c.UpdateInventory(func(l item.List) (item.List, error) {
+			return l.Add(sold), nil
+		})
+
+		return Receipt{}, err
+	}
+	m.pay(c, r.Total)
+	m.stockSold(def, sold)
+	m.confirm(SellEvent, data)
+
+	return r, nil
+}
+
+// Value returns the shop in the customer's room and what it would pay them
+// for count of the item the keyword refers to, one if count is below one.
+func (m *Manager) Value(c Customer, keyword string, count int) (Def, Receipt, error) {
+	def, ok := m.Here(c.Location())
+	if !ok {
+		return Def{}, Receipt{}, &item.Refused{Message: NoShopMessage}
+	}
+	it, ok := c.Inventory().Find(keyword)
+	if !ok {
+		return def, Receipt{}, &item.Refused{Message: NotCarriedMessage}
+	}
+	if !def.Accepts(it.Type) && !def.stocks(it.Proto) {
+		return def, Receipt{}, &item.Refused{Message: NotBoughtMessage}
+	}
+	if len(it.Contents) > 0 {
+		return def, Receipt{}, &item.Refused{Message: EmptyFirstMessage}
+	}
+	if count < 1 {
+		count = 1
+	}
+	if count > it.Quantity() {
+		count = it.Quantity()
+	}
+	if count > MaxCount {
+		return def, Receipt{}, &item.Refused{Message: TooManyMessage}
+	}
+	price := m.price(def, Selling, c, it, count, it.Value*def.Markdown/100)
+	if price <= 0 {
+		return def, Receipt{}, &item.Refused{Message: WorthlessMessage}
+	}
+	total, ok := times(price, count)
+	if !ok {
+		return def, Receipt{}, &item.Refused{Message: TooManyMessage}
+	}
+
+	return def, Receipt{Shop: def, Item: it, Count: count, Price: price, Total: total}, nil
+}
+
+// times multiplies the amount by the count, which is at least one, false if
+// the result doesn't fit in an int
+func times(amount, count int) (int, bool) {
+	if amount > maxInt/count || amount < -maxInt/count {
+		return 0, false
+	}
+
+	return amount * count, true
+}
+
+// wares lists what the shop has for sale, stock first
+func (m *Manager) wares(def Def, c Customer) []Ware {
+	m.mutex.RLock()
+	left := m.stock[def.ID]
+	goods := m.goods[def.ID].Copy()
+	m.mutex.RUnlock()
+
+	var wares []Ware
+	for _, s := range def.Stock {
+		idef, ok := m.world.Item(s.Item)
+		if !ok {
+			continue
+		}
+		w := Ware{Item: item.New(idef, 1), Stock: -1, stock: s}
+		if s.Count > 0 {
+			w.Stock = s.Count
+			if n, ok := left[s.Item]; ok {
+				w.Stock = n
+			}
+		}
+		wares = append(wares, w)
+	}
+	for _, it := range goods {
+		wares = append(wares, Ware{Item: it, Stock: it.Quantity(), sold: true})
+	}
+	for i := range wares {
+		wares[i].Price = m.price(def, Buying, c, wares[i].Item, 1, m.base(def, wares[i]))
+	}
+
+	return wares
+}
+
+// base is what the shop charges for one of the ware before pricers
+func (m *Manager) base(def Def, w Ware) int {
+	if w.stock.Price > 0 {
+		return w.stock.Price
+	}
+
+	return w.Item.Value * def.Markup / 100
+}
+
+// price runs the pricers over the base price of each item in the sale
+func (m *Manager) price(def Def, action string, c Customer, it item.Item, count, base int) int {
+	m.mutex.RLock()
+	pricers := m.pricers
+	m.mutex.RUnlock()
+
+	one := it.Copy()
+	one.Count = 0
+	q := Quote{
+		Shop:     def.ID,
+		Action:   action,
+		Customer: c.ID(),
+		Item:     one,
+		Count:    count,
+		Base:     base,
+		Price:    base,
+	}
+	for _, p := range pricers {
+		q.Price = p.fn(q)
+		if q.Price < 0 {
+			break
+		}
+	}
+
+	return q.Price
+}
+
+// take removes count of the ware from the shop, returning the items bought
+func (m *Manager) take(def Def, w Ware, count int) (item.List, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if w.sold {
+		goods, taken, err := m.goods[def.ID].Take(w.Item.ID, count)
+		if err != nil || taken.Quantity() < count {
+			return nil, false
+		}
+		m.goods[def.ID] = goods
+
+		return item.List{taken}, true
+	}
+	if w.Stock >= 0 {
+		left := m.stockLeft(def)
+		if left[w.stock.Item] < count {
+			return nil, false
+		}
+		left[w.stock.Item] -= count
+	}
+	if w.Item.Stackable() {
+		return item.List{item.New(mustDef(m.world, w.stock.Item), count)}, true
+	}
+	bought := make(item.List, count)
+	for i := range bought {
+		bought[i] = item.New(mustDef(m.world, w.stock.Item), 1)
+	}
+
+	return bought, true
+}
+
+// putBack returns items taken from the shop for a sale that didn't happen
+func (m *Manager) putBack(def Def, w Ware, bought item.List) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, it := range bought {
+		if w.sold {
+			m.goods[def.ID] = m.goods[def.ID].Add(it)
+		} else if w.Stock >= 0 {
+			m.stockLeft(def)[w.stock.Item] += it.Quantity()
+		}
+	}
+}
+
+// stockSold adds what a customer sold to the shop's stock, or to its goods
+// if it isn't something the shop has a limited stock of
+func (m *Manager) stockSold(def Def, it item.Item) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, s := range def.Stock {
+		if s.Item != it.Proto {
+			continue
+		}
+		if s.Count > 0 {
+			m.stockLeft(def)[s.Item] += it.Quantity()
+		}
+
+		return
+	}
+	m.goods[def.ID] = m.goods[def.ID].Add(it)
+}
+
+// stockLeft returns how much of the shop's limited stock is left, filling it
+// if it hasn't been counted since it was restocked. The manager must be
+// locked.
+func (m *Manager) stockLeft(def Def) map[string]int {
+	left, ok := m.stock[def.ID]
+	if !ok {
+		left = make(map[string]int)
+		for _, s := range def.Stock {
+			if s.Count > 0 {
+				left[s.Item] = s.Count
+			}
+		}
+		m.stock[def.ID] = left
+	}
+
+	return left
+}
+
+// record writes the sale to the ledger, if there is one
+func (m *Manager) record(c Customer, action string, r Receipt) error {
+	m.mutex.RLock()
+	ledger := m.ledger
+	m.mutex.RUnlock()
+
+	if ledger == nil {
+		return nil
+	}
+	balance := m.Balance(c)
+	if action == Buying {
+		balance -= r.Total
+	} else {
+		balance += r.Total
+	}
+	_, err := ledger.Append(audit.Record{
+		Category: audit.Economy,
+		Actor:    c.ID(),
+		Action:   action,
+		Target:   r.Shop.ID,
+		Data: map[string]interface{}{
+			"item":    r.Item.Proto,
+			"name":    r.Item.Name,
+			"count":   r.Count,
+			"price":   r.Price,
+			"total":   r.Total,
+			"balance": balance,
+		},
+	})
+	if err != nil {
+		return &item.Refused{Message: LedgerMessage}
+	}
+
+	return nil
+}
+
+// pay adds the amount to the customer's money
+func (m *Manager) pay(c Customer, amount int) {
+	m.mutex.RLock()
+	stat := m.stat
+	m.mutex.RUnlock()
+
+	c.AddStat(stat, amount)
+}
+
+// findWare returns the ware the keyword refers to, "2.sword" is the second
+// matching "sword"
+func findWare(wares []Ware, keyword string) (Ware, bool) {
+	n, keyword := item.Ordinal(keyword)
+	for _, w := range wares {
+		if w.Item.Matches(keyword) {
+			n--
+			if n == 0 {
+				return w, true
+			}
+		}
+	}
+
+	return Ware{}, false
+}
+
+// mustDef returns the item definition, which was found when the ware was
+// listed
+func mustDef(w *world.World, proto string) world.ItemDef {
+	def, _ := w.Item(proto)
+
+	return def
+}
+
+func saleData(c Customer, r Receipt) events.Data {
+	return events.Data{
+		"player":      c.ID(),
+		"player_name": c.Name(),
+		"shop":        r.Shop.ID,
+		"item":        r.Item.Proto,
+		"name":        r.Item.Name,
+		"count":       r.Count,
+		"price":       r.Price,
+		"total":       r.Total,
+	}
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
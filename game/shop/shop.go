@@ -0,0 +1,220 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package shop lets players buy and sell items with shopkeepers. Shops are
+// defined in YAML files with the NPC who keeps them, what they stock and
+// what they'll buy. Prices start from the value of items and can be changed
+// by pricing hooks, like scripts charging strangers more. Money is a stat
+// counted in the smallest coin of the currency, and every sale is written to
+// a ledger so the economy can be accounted for.
+package shop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Stock is an item a shop sells.
+type Stock struct {
+	// Item is the id of the item definition sold.
+	Item string `yaml:"item"`
+	// Count is how many the shop has when it's restocked, zero is no limit.
+	Count int `yaml:"count,omitempty"`
+	// Price replaces what the item's value and the shop's markup would
+	// charge.
+	Price int `yaml:"price,omitempty"`
+}
+
+// Def defines a shop.
+type Def struct {
+	// ID is how the shop is known to scripts and in the ledger.
+	ID string `yaml:"id"`
+	// Name is what players call the shop, like "The Rusty Anvil".
+	Name string `yaml:"name"`
+	// Keeper is the id of the NPC definition that keeps the shop, players
+	// trade in the same room as one.
+	Keeper string  `yaml:"keeper"`
+	Stock  []Stock `yaml:"stock,omitempty"`
+	// Buys are the types of item the shop buys from players, like weapon, it
+	// buys nothing if there are none and anything if one is "any".
+	Buys []string `yaml:"buys,omitempty"`
+	// Markup is the percent of an item's value the shop charges, 100 by
+	// default.
+	Markup int `yaml:"markup,omitempty"`
+	// Markdown is the percent of an item's value the shop pays, 50 by
+	// default.
+	Markdown int `yaml:"markdown,omitempty"`
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("shops need an id")
+	}
+	if d.Keeper == "" {
+		return fmt.Errorf("shop %s: needs a keeper", d.ID)
+	}
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+	if d.Markup < 0 || d.Markdown < 0 {
+		return fmt.Errorf("shop %s: can't have a negative markup or markdown", d.ID)
+	}
+	if d.Markup == 0 {
+		d.Markup = 100
+	}
+	if d.Markdown == 0 {
+		d.Markdown = 50
+	}
+	for i, s := range d.Stock {
+		if s.Item == "" {
+			return fmt.Errorf("shop %s: stock %d needs an item", d.ID, i+1)
+		}
+		if s.Count < 0 || s.Price < 0 {
+			return fmt.Errorf("shop %s: stock %d can't have a negative count or price", d.ID, i+1)
+		}
+	}
+
+	return nil
+}
+
+// Accepts is true if the shop buys items of the type.
+func (d Def) Accepts(kind string) bool {
+	for _, b := range d.Buys {
+		if strings.EqualFold(b, "any") || strings.EqualFold(b, kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stocks is true if the shop sells items made from the definition
+func (d Def) stocks(proto string) bool {
+	for _, s := range d.Stock {
+		if s.Item == proto {
+			return true
+		}
+	}
+
+	return false
+}
+
+// File is the layout of a shop file, a list of shops.
+type File struct {
+	Shops []Def `yaml:"shops"`
+}
+
+// Defs holds the shop definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[id]
+
+	return def, ok
+}
+
+// All returns every definition, sorted by id.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// LoadDir adds the shops in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the shops listed in the YAML document, like:
+//   shops:
+//     - id: smithy
+//       name: The Rusty Anvil
+//       keeper: blacksmith
+//       stock:
+//         - {item: dagger}
+//         - {item: longsword, count: 2, price: 250}
+//       buys: [weapon, armor]
+//       markdown: 40
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Shops {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package shop_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestShop(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Shop Suite")
+}
@@ -0,0 +1,344 @@
+package shop_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// buyer trades with shops and remembers what they're told
+type buyer struct {
+	name      string
+	stats     map[string]int
+	inventory item.List
+	sent      []string
+}
+
+func (b *buyer) ID() string {
+	return strings.ToLower(b.name)
+}
+
+func (b *buyer) Name() string {
+	return b.name
+}
+
+func (b *buyer) Location() string {
+	return "market"
+}
+
+func (b *buyer) Stat(name string) int {
+	return b.stats[name]
+}
+
+func (b *buyer) AddStat(name string, delta int) int {
+	b.stats[name] += delta
+
+	return b.stats[name]
+}
+
+func (b *buyer) Inventory() item.List {
+	return b.inventory.Copy()
+}
+
+func (b *buyer) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	l, err := fn(b.inventory.Copy())
+	if err == nil {
+		b.inventory = l
+	}
+
+	return err
+}
+
+func (b *buyer) Level() command.Level {
+	return command.Player
+}
+
+func (b *buyer) Send(text string) error {
+	b.sent = append(b.sent, text)
+
+	return nil
+}
+
+func (b *buyer) told(text string) bool {
+	for _, s := range b.sent {
+		if s == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ledger keeps the records written to it, or fails to
+type ledger struct {
+	records []audit.Record
+	broken  bool
+}
+
+func (l *ledger) Append(r audit.Record) (*audit.Record, error) {
+	if l.broken {
+		return nil, errors.New("disk full")
+	}
+	l.records = append(l.records, r)
+
+	return &r, nil
+}
+
+const shops = `
+shops:
+  - id: smithy
+    name: The Rusty Anvil
+    keeper: blacksmith
+    stock:
+      - {item: dagger}
+      - {item: longsword, count: 1, price: 250}
+      - {item: arrow}
+    buys: [weapon]
+`
+
+var _ = Describe("Shops", func() {
+	var (
+		em    *events.Emitter
+		m     *Manager
+		l     *ledger
+		alice *buyer
+		w     *world.World
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "The Town"})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "dagger", Zone: "town", Name: "a dagger", Type: "weapon", Value: 40})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "longsword", Zone: "town", Name: "a longsword", Type: "weapon", Value: 200})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "arrow", Zone: "town", Name: "an arrow", Type: "weapon", Value: 2, Flags: []string{"stackable"}})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "axe", Zone: "town", Name: "a rusty axe", Type: "weapon", Value: 30})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "bread", Zone: "town", Name: "a loaf of bread", Type: "food", Value: 3})).Should(Succeed())
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(shops))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, w, em)
+		m.SetNearby(func(room string) []string {
+			if room == "market" {
+				return []string{"blacksmith"}
+			}
+
+			return nil
+		})
+		l = &ledger{}
+		m.SetLedger(l)
+		alice = &buyer{name: "Alice", stats: map[string]int{"coins": 300}}
+	})
+
+	It("counts money in denominations", func() {
+		c, err := ParseCurrency([]string{"copper:1", "gold:100", "silver:10"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c).Should(Equal(DefaultCurrency))
+		_, err = ParseCurrency([]string{"gold:100"})
+		Ω(err).Should(HaveOccurred())
+		_, err = ParseCurrency([]string{"gold"})
+		Ω(err).Should(HaveOccurred())
+
+		Ω(c.Format(345)).Should(Equal("3 gold, 4 silver, 5 copper"))
+		Ω(c.Format(302)).Should(Equal("3 gold, 2 copper"))
+		Ω(c.Format(0)).Should(Equal("nothing"))
+
+		for in, amount := range map[string]int{"3 gold 4 silver": 340, "3g, 5c": 305, "42": 42, "1 gold 1 gold": 200} {
+			n, err := c.Parse(in)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(n).Should(Equal(amount), in)
+		}
+		_, err = c.Parse("3 rubies")
+		Ω(err).Should(HaveOccurred())
+		_, err = c.Parse("3")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = c.Parse("gold")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("validates definitions", func() {
+		defs := NewDefs()
+		Ω(defs.Add(Def{ID: "stall"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "stall", Keeper: "baker", Stock: []Stock{{Count: 2}}})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "stall", Keeper: "baker"})).Should(Succeed())
+
+		def, _ := defs.Get("stall")
+		Ω(def.Name).Should(Equal("stall"))
+		Ω(def.Markup).Should(Equal(100))
+		Ω(def.Markdown).Should(Equal(50))
+	})
+
+	It("sells stock and writes sales to the ledger", func() {
+		r, err := m.Buy(alice, "long", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Total).Should(Equal(250))
+		Ω(alice.stats["coins"]).Should(Equal(50))
+		Ω(alice.inventory).Should(HaveLen(1))
+		Ω(alice.inventory[0].Proto).Should(Equal("longsword"))
+
+		alice.stats["coins"] = 1000
+		_, err = m.Buy(alice, "long", 1)
+		Ω(err).Should(MatchError(SoldOutMessage))
+		m.Restock("smithy")
+		_, err = m.Buy(alice, "long", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		r, err = m.Buy(alice, "arrow", 20)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Total).Should(Equal(40))
+		_, err = m.Buy(alice, "dagger", 2)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(alice.inventory).Should(HaveLen(5))
+
+		_, err = m.Buy(alice, "bread", 1)
+		Ω(err).Should(MatchError(NotSoldMessage))
+		alice.stats["coins"] = 10
+		_, err = m.Buy(alice, "dagger", 1)
+		Ω(err).Should(MatchError(PoorMessage))
+
+		Ω(l.records).Should(HaveLen(4))
+		Ω(l.records[0]).Should(Equal(audit.Record{
+			Category: audit.Economy,
+			Actor:    "alice",
+			Action:   Buying,
+			Target:   "smithy",
+			Data: map[string]interface{}{
+				"item":    "longsword",
+				"name":    "a longsword",
+				"count":   1,
+				"price":   250,
+				"total":   250,
+				"balance": 50,
+			},
+		}))
+	})
+
+	It("buys what players carry and sells it on", func() {
+		axe, _ := item.Create(w, "axe", 1)
+		bread, _ := item.Create(w, "bread", 1)
+		alice.inventory = item.List{axe, bread}
+
+		_, r, err := m.Value(alice, "axe", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Total).Should(Equal(15))
+		_, _, err = m.Value(alice, "bread", 1)
+		Ω(err).Should(MatchError(NotBoughtMessage))
+		_, _, err = m.Value(alice, "shield", 1)
+		Ω(err).Should(MatchError(NotCarriedMessage))
+
+		_, err = m.Sell(alice, "axe", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(alice.stats["coins"]).Should(Equal(315))
+		Ω(alice.inventory).Should(HaveLen(1))
+		Ω(l.records[0].Action).Should(Equal(Selling))
+		Ω(l.records[0].Data["balance"]).Should(Equal(315))
+
+		_, wares, err := m.Wares(alice)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(wares).Should(HaveLen(4))
+		Ω(wares[3].Item.ID).Should(Equal(axe.ID))
+		Ω(wares[3].Price).Should(Equal(30))
+		_, err = m.Buy(alice, "axe", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = m.Buy(alice, "axe", 1)
+		Ω(err).Should(MatchError(NotSoldMessage))
+	})
+
+	It("refuses counts and totals too large to trade", func() {
+		_, err := m.Buy(alice, "arrow", (1<<62)+1000000)
+		Ω(err).Should(MatchError(TooManyMessage))
+
+		m.SetPricer("gouger", func(q Quote) int {
+			return int(^uint(0)>>1) / 2
+		})
+		_, err = m.Buy(alice, "arrow", 3)
+		Ω(err).Should(MatchError(PoorMessage))
+		Ω(alice.stats["coins"]).Should(Equal(300))
+		Ω(alice.inventory).Should(BeEmpty())
+		Ω(l.records).Should(BeEmpty())
+	})
+
+	It("changes prices with pricers", func() {
+		m.SetPricer("stranger", func(q Quote) int {
+			if q.Action == Buying && q.Customer == "alice" {
+				return q.Price * 2
+			}
+
+			return q.Price
+		})
+		m.SetPricer("no-arrows", func(q Quote) int {
+			if q.Item.Proto == "arrow" {
+				return -1
+			}
+
+			return q.Price
+		})
+
+		r, err := m.Buy(alice, "dagger", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Price).Should(Equal(80))
+		_, err = m.Buy(alice, "arrow", 1)
+		Ω(err).Should(MatchError(NotSoldMessage))
+
+		m.SetPricer("no-arrows", nil)
+		_, err = m.Buy(alice, "arrow", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("doesn't trade when the ledger can't be written", func() {
+		l.broken = true
+		_, err := m.Buy(alice, "long", 1)
+		Ω(err).Should(MatchError(LedgerMessage))
+		Ω(alice.stats["coins"]).Should(Equal(300))
+		Ω(alice.inventory).Should(BeEmpty())
+
+		l.broken = false
+		_, err = m.Buy(alice, "long", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("lets before handlers stop sales", func() {
+		em.On("before:"+BuyEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["item"] == "dagger" {
+				return errors.New("The smith won't sell you a dagger.")
+			}
+
+			return nil
+		}))
+
+		_, err := m.Buy(alice, "dagger", 1)
+		Ω(err).Should(MatchError("The smith won't sell you a dagger."))
+		Ω(l.records).Should(BeEmpty())
+	})
+
+	It("trades with the list, buy, sell and value commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(c command.Caller) Customer {
+			return c.(*buyer)
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		dispatch := func(line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+		}
+
+		dispatch("list")
+		Ω(alice.told("The Rusty Anvil sells:\n  a dagger - 4 silver\n  a longsword - 2 gold, 5 silver (1 left)\n  an arrow - 2 copper\nYou have 3 gold.")).Should(BeTrue())
+		dispatch("buy 5 arrow")
+		Ω(alice.told("You buy an arrow (5) for 1 silver.")).Should(BeTrue())
+		dispatch("value 2 arrow")
+		Ω(alice.told("The shop would pay 2 copper for an arrow (2).")).Should(BeTrue())
+		dispatch("sell dagger")
+		Ω(alice.told(NotCarriedMessage)).Should(BeTrue())
+		dispatch("sell 5 arrow")
+		Ω(alice.told("You sell an arrow (5) for 5 copper.")).Should(BeTrue())
+	})
+})
@@ -0,0 +1,157 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package skill
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+)
+
+// NewCommands creates the practice, skills and cast commands and a command
+// for each skill defined now whose name is a single word, like "kick".
+func NewCommands(m *Manager, resolve command.Resolver[Learner]) []*command.Command {
+	text := func(name string) []command.Arg {
+		return []command.Arg{{Name: name, Kind: command.Text, Optional: true}}
+	}
+	cmds := []*command.Command{
+		{
+			Name:      "practice",
+			Aliases:   []string{"prac"},
+			MinAbbrev: 4,
+			Args:      text("skill"),
+			Help:      "Lists the skills you can learn, or practices one of them.",
+			Source:    "game",
+			Handler: handler(resolve, func(ctx *command.Context, l Learner) error {
+				name := ctx.String("skill")
+				if name == "" {
+					return ctx.Send(listing(m.Learnable(l), l, fmt.Sprintf("You have %d practice sessions left.", l.Stat("practices"))))
+				}
+				def, ok := m.Defs().Named(Skill, name)
+				if !ok {
+					if def, ok = m.Defs().Named(Spell, name); !ok {
+						return ctx.Send(fmt.Sprintf(CantLearnMessage, name))
+					}
+				}
+				prof, err := m.Practice(l, def.ID)
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(fmt.Sprintf("You practice %s, you now know it %d%%.", def.Name, prof))
+			}),
+		},
+		{
+			Name:    "skills",
+			Aliases: []string{"spells"},
+			Help:    "Lists the skills and spells you know.",
+			Source:  "game",
+			Handler: handler(resolve, func(ctx *command.Context, l Learner) error {
+				var known []Def
+				for _, def := range m.Defs().All() {
+					if l.Skill(def.ID) > 0 {
+						known = append(known, def)
+					}
+				}
+				if len(known) == 0 {
+					return ctx.Send("You don't know any skills.")
+				}
+
+				return ctx.Send(listing(known, l, ""))
+			}),
+		},
+		{
+			Name:   "cast",
+			Args:   []command.Arg{{Name: "spell", Kind: command.Word, Optional: true}, {Name: "target", Kind: command.Text, Optional: true}},
+			Help:   "Casts a spell you know, \"cast \"magic missile\" rat\" for spells with spaces.",
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, l Learner) error {
+				name := ctx.String("spell")
+				if name == "" {
+					return ctx.Send("Cast what?")
+				}
+				def, ok := m.Defs().Named(Spell, name)
+				if !ok {
+					return &item.Refused{Message: UnknownMessage}
+				}
+
+				return use(ctx, m, l, def)
+			}),
+		},
+	}
+
+	for _, def := range m.Defs().All() {
+		if def.Kind != Skill || strings.Contains(def.Name, " ") {
+			continue
+		}
+		def := def
+		help := def.Help
+		if help == "" {
+			help = fmt.Sprintf("Uses the %s skill.", def.Name)
+		}
+		cmds = append(cmds, &command.Command{
+			Name:   def.Name,
+			Args:   text("target"),
+			Lag:    def.Lag,
+			Help:   help,
+			Source: "game",
+			Handler: handler(resolve, func(ctx *command.Context, l Learner) error {
+				return use(ctx, m, l, def)
+			}),
+		})
+	}
+
+	return cmds
+}
+
+// use uses the skill with the rest of the input, the caller waits out its
+// lag whether it works or not
+func use(ctx *command.Context, m *Manager, l Learner, def Def) error {
+	u, err := m.Use(l, def.ID, ctx.String("target"))
+	ctx.Lag = 0
+	if u != nil {
+		ctx.Lag = u.Lag
+	}
+
+	return err
+}
+
+// listing lists the skills with how well the learner knows them, after the
+// heading if there is one
+func listing(defs []Def, l Learner, heading string) string {
+	var lines []string
+	if heading != "" {
+		lines = append(lines, heading)
+	}
+	width := 0
+	for _, def := range defs {
+		if len(def.Name) > width {
+			width = len(def.Name)
+		}
+	}
+	for _, def := range defs {
+		lines = append(lines, fmt.Sprintf("  %s%s %3d%%", def.Name, strings.Repeat(" ", width-len(def.Name)), l.Skill(def.ID)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// handler resolves the caller's learner for fn, telling the caller when an
+// action is refused
+func handler(resolve command.Resolver[Learner], fn func(*command.Context, Learner) error) command.Handler {
+	return func(ctx *command.Context) error {
+		l := resolve(ctx.Caller)
+		if l == nil {
+			return ctx.Send("You can't use skills.")
+		}
+
+		err := fn(ctx, l)
+		if r, ok := err.(*item.Refused); ok {
+			return ctx.Send(r.Message)
+		}
+
+		return err
+	}
+}
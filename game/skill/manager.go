@@ -0,0 +1,389 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package skill
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/cooldown"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// The events of using skills. Handlers of the before: events can stop it by
+// returning events.ErrHalt, or an error whose message is told to the user.
+// Each is given the id, name and kind of the skill, the id and name of its
+// user and of its target if it has one and the room. Uses are confirmed with
+// whether the skill worked as "success", improvements with the new percent.
+const (
+	UseEvent      = "skill:use"
+	PracticeEvent = "skill:practice"
+	ImproveEvent  = "skill:improve"
+)
+
+// Messages told to those who can't use or practice a skill.
+const (
+	UnknownMessage    = "You don't know how to do that."
+	ReadyMessage      = "You aren't ready to do that again yet."
+	CostMessage       = "You don't have enough %s."
+	WhomMessage       = "Use it on whom?"
+	FailedMessage     = "You failed."
+	NothingMessage    = "Nothing happens."
+	CantLearnMessage  = "You can't learn %s."
+	NoPracticeMessage = "You have no practice sessions left."
+	LearnedMessage    = "You are already learned at %s."
+	CancelMessage     = "You can't do that right now."
+)
+
+// Learner is a combatant who learns and uses skills, like a player. Their
+// "level" stat decides what they can learn, "practices" is how many practice
+// sessions they have left and "int" how much they learn from each.
+type Learner interface {
+	combat.Combatant
+	Class() string
+	// Skill returns how well the skill is known, as a percent.
+	Skill(id string) int
+	SetSkill(id string, percent int)
+}
+
+// Use is a skill being used, what its handler is given.
+type Use struct {
+	Skill Def
+	User  Learner
+	// Target is who the skill is used on, nil for skills without targets.
+	Target combat.Combatant
+	// Args is what was typed after the skill's name and target.
+	Args string
+	// Proficiency is how well the user knows the skill, as a percent.
+	Proficiency int
+	// Lag is how many pulses the user waits before their next command, the
+	// handler can change it.
+	Lag int
+}
+
+// Handler does what a skill does once it's used, its error is told to the
+// user.
+type Handler func(*Use) error
+
+// Manager uses and teaches skills.
+type Manager struct {
+	defs      *Defs
+	handlers  map[string]Handler
+	cooldowns *cooldown.Tracker
+	combat    *combat.Engine
+	adept     int
+	emitter   *events.Emitter
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for the defined skills, fighting in the
+// combat engine. The emitter may be nil.
+func NewManager(defs *Defs, fights *combat.Engine, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:      defs,
+		handlers:  make(map[string]Handler),
+		cooldowns: cooldown.New(),
+		combat:    fights,
+		adept:     75,
+		emitter:   em,
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's skill manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), combat.Global(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetAdept changes the percent practicing can teach a skill up to, using it
+// is the only way to get better.
+func (m *Manager) SetAdept(percent int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.adept = percent
+}
+
+// Defs returns the skill definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// Handle sets what the skill with the id does, replacing its handler.
+func (m *Manager) Handle(id string, fn Handler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[id] = fn
+}
+
+// Use has the learner use the skill, on the target named at the start of
+// args if it takes one. The use is returned whether the skill works or not,
+// so its lag can be waited out, and is nil if it couldn't be tried.
+func (m *Manager) Use(user Learner, id, args string) (*Use, error) {
+	def, ok := m.defs.Get(id)
+	prof := user.Skill(id)
+	if !ok || prof <= 0 {
+		return nil, &item.Refused{Message: UnknownMessage}
+	}
+	key := fmt.Sprintf("skill:%s:%s", user.ID(), id)
+	if !m.cooldowns.Ready(key) {
+		return nil, &item.Refused{Message: ReadyMessage}
+	}
+	stats := make([]string, 0, len(def.Cost))
+	for stat := range def.Cost {
+		stats = append(stats, stat)
+	}
+	sort.Strings(stats)
+	for _, stat := range stats {
+		if user.Stat(stat) < def.Cost[stat] {
+			return nil, &item.Refused{Message: fmt.Sprintf(CostMessage, stat)}
+		}
+	}
+	target, rest, err := m.target(user, def, args)
+	if err != nil {
+		return nil, err
+	}
+
+	data := events.Data{
+		"skill":     def.ID,
+		"name":      def.Name,
+		"kind":      def.Kind,
+		"user":      user.ID(),
+		"user_name": user.Name(),
+		"room":      user.Location(),
+	}
+	if target != nil {
+		data["target"] = target.ID()
+		data["target_name"] = target.Name()
+	}
+	if err := m.check(UseEvent, data); err != nil {
+		return nil, err
+	}
+	if def.Target == TargetOffensive && !m.combat.Fighting(user.ID()) {
+		if err := m.combat.Engage(user, target); err != nil {
+			return nil, err
+		}
+	}
+	for _, stat := range stats {
+		user.AddStat(stat, -def.Cost[stat])
+	}
+	m.cooldowns.Set(key, def.Wait())
+
+	u := &Use{
+		Skill:       def,
+		User:        user,
+		Target:      target,
+		Args:        rest,
+		Proficiency: prof,
+		Lag:         def.Lag,
+	}
+	m.improve(user, def)
+	if random.Intn(100) >= prof {
+		data["success"] = false
+		m.confirm(UseEvent, data)
+
+		return u, &item.Refused{Message: FailedMessage}
+	}
+
+	m.mutex.RLock()
+	fn := m.handlers[id]
+	m.mutex.RUnlock()
+
+	if fn == nil {
+		return u, &item.Refused{Message: NothingMessage}
+	}
+	if err := fn(u); err != nil {
+		return u, err
+	}
+	data["success"] = true
+	m.confirm(UseEvent, data)
+
+	return u, nil
+}
+
+// Practice spends one of the learner's practice sessions on the skill,
+// returning how well they know it now.
+func (m *Manager) Practice(user Learner, id string) (int, error) {
+	def, ok := m.defs.Get(id)
+	if !ok || !def.Learnable(user.Class(), user.Stat("level")) {
+		return 0, &item.Refused{Message: fmt.Sprintf(CantLearnMessage, id)}
+	}
+	if user.Stat("practices") <= 0 {
+		return 0, &item.Refused{Message: NoPracticeMessage}
+	}
+
+	m.mutex.RLock()
+	adept := m.adept
+	m.mutex.RUnlock()
+
+	prof := user.Skill(id)
+	if prof >= adept {
+		return prof, &item.Refused{Message: fmt.Sprintf(LearnedMessage, def.Name)}
+	}
+	data := events.Data{
+		"skill":     def.ID,
+		"name":      def.Name,
+		"kind":      def.Kind,
+		"user":      user.ID(),
+		"user_name": user.Name(),
+		"room":      user.Location(),
+	}
+	if err := m.check(PracticeEvent, data); err != nil {
+		return prof, err
+	}
+
+	prof += 5 + user.Stat("int")/3
+	if prof > adept {
+		prof = adept
+	}
+	user.AddStat("practices", -1)
+	user.SetSkill(id, prof)
+	data["percent"] = prof
+	m.confirm(PracticeEvent, data)
+
+	return prof, nil
+}
+
+// Learnable returns the skills the learner knows or can practice now.
+func (m *Manager) Learnable(user Learner) []Def {
+	var defs []Def
+	for _, def := range m.defs.All() {
+		if user.Skill(def.ID) > 0 || def.Learnable(user.Class(), user.Stat("level")) {
+			defs = append(defs, def)
+		}
+	}
+
+	return defs
+}
+
+// Improve may teach the learner a little more of the skill with the id they
+// used some other way, like crafting with it. Skills they don't know aren't
+// improved.
+func (m *Manager) Improve(user Learner, id string) {
+	if def, ok := m.defs.Get(id); ok && user.Skill(id) > 0 {
+		m.improve(user, def)
+	}
+}
+
+// target finds who the skill is used on from the start of args, returning
+// the rest of args
+func (m *Manager) target(user Learner, def Def, args string) (combat.Combatant, string, error) {
+	name, rest := args, ""
+	for i, r := range args {
+		if r == ' ' {
+			name, rest = args[:i], args[i+1:]
+
+			break
+		}
+	}
+
+	switch def.Target {
+	case TargetSelf:
+		return user, args, nil
+	case TargetCharacter:
+		if name == "" {
+			return user, rest, nil
+		}
+		if name == "self" || name == "me" {
+			return user, rest, nil
+		}
+	case TargetOffensive:
+		if name == "" {
+			if target, ok := m.combat.Target(user); ok {
+				return target, rest, nil
+			}
+
+			return nil, "", &item.Refused{Message: WhomMessage}
+		}
+	default:
+		return nil, args, nil
+	}
+
+	target := m.combat.Find(user, name)
+	if target == nil {
+		return nil, "", &item.Refused{Message: combat.NotHereMessage}
+	}
+
+	return target, rest, nil
+}
+
+// improve may teach the user a little more of the skill, the less they know
+// the more likely
+func (m *Manager) improve(user Learner, def Def) {
+	prof := user.Skill(def.ID)
+	if prof >= 100 || random.Intn(100) >= (100-prof)/4 {
+		return
+	}
+	user.SetSkill(def.ID, prof+1)
+	if s, ok := user.(combat.Sender); ok {
+		s.Send(fmt.Sprintf("You have become better at %s!", def.Name))
+	}
+	m.emit(ImproveEvent, events.Data{
+		"skill":     def.ID,
+		"name":      def.Name,
+		"user":      user.ID(),
+		"user_name": user.Name(),
+		"percent":   prof + 1,
+	})
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Emit(evt, data)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package skill gives characters skills and spells. Skills are defined in
+// YAML files with what they cost, how long until they can be used again, who
+// they're used on and which classes can learn them, and what they do is
+// written in Lua for each skill. Characters practice skills to learn them and
+// get better at them by using them, the better they know a skill the more
+// often it works.
+package skill
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Kinds of skill, spells are cast with the cast command while skills are
+// commands of their own.
+const (
+	Skill = "skill"
+	Spell = "spell"
+)
+
+// Who a skill can be used on.
+const (
+	// TargetNone skills aren't used on anyone, like a light spell.
+	TargetNone = "none"
+	// TargetSelf skills are only used on their user.
+	TargetSelf = "self"
+	// TargetCharacter skills are used on anyone in the room, their user if
+	// no one is named, like a heal.
+	TargetCharacter = "character"
+	// TargetOffensive skills are used on an enemy in the room, who they're
+	// fighting if no one is named, and start a fight, like a kick.
+	TargetOffensive = "offensive"
+)
+
+// Def defines a skill or spell.
+type Def struct {
+	// ID is how the skill is known to scripts and saved on characters.
+	ID string `yaml:"id"`
+	// Name is what's typed to use the skill, like "kick" or "magic missile".
+	Name string `yaml:"name"`
+	// Kind is Skill or Spell, skills by default.
+	Kind string `yaml:"kind,omitempty"`
+	// Target is who the skill is used on, none by default.
+	Target string `yaml:"target,omitempty"`
+	// Cost is what using the skill takes from its user's stats, like
+	// {mana: 15}.
+	Cost map[string]int `yaml:"cost,omitempty"`
+	// Cooldown is how long until the skill can be used again, like "30s".
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// Lag is how many pulses its user waits before their next command.
+	Lag int `yaml:"lag,omitempty"`
+	// Classes are the classes that can learn the skill and the level they
+	// can learn it at. Anyone can learn skills without classes.
+	Classes map[string]int `yaml:"classes,omitempty"`
+	// Help describes the skill to players.
+	Help string `yaml:"help,omitempty"`
+}
+
+// Wait returns the skill's cooldown, zero if it doesn't have one.
+func (d Def) Wait() time.Duration {
+	wait, _ := time.ParseDuration(d.Cooldown)
+
+	return wait
+}
+
+// Learnable is true if the class can learn the skill at the level.
+func (d Def) Learnable(class string, level int) bool {
+	if len(d.Classes) == 0 {
+		return true
+	}
+	min, ok := d.Classes[strings.ToLower(class)]
+
+	return ok && level >= min
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("skills need an id")
+	}
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+	d.Name = strings.ToLower(d.Name)
+	if d.Kind == "" {
+		d.Kind = Skill
+	}
+	if d.Target == "" {
+		d.Target = TargetNone
+	}
+	switch {
+	case d.Kind != Skill && d.Kind != Spell:
+		return fmt.Errorf("skill %s: kind must be %q or %q", d.ID, Skill, Spell)
+	case d.Target != TargetNone && d.Target != TargetSelf && d.Target != TargetCharacter && d.Target != TargetOffensive:
+		return fmt.Errorf("skill %s: target must be none, self, character or offensive", d.ID)
+	case d.Lag < 0:
+		return fmt.Errorf("skill %s: lag can't be negative", d.ID)
+	}
+	if d.Cooldown != "" {
+		if wait, err := time.ParseDuration(d.Cooldown); err != nil || wait <= 0 {
+			return fmt.Errorf("skill %s: cooldown must be a duration, like \"30s\"", d.ID)
+		}
+	}
+	classes := make(map[string]int, len(d.Classes))
+	for class, level := range d.Classes {
+		classes[strings.ToLower(class)] = level
+	}
+	d.Classes = classes
+
+	return nil
+}
+
+// File is the layout of a skill file, a list of skills.
+type File struct {
+	Skills []Def `yaml:"skills"`
+}
+
+// Defs holds the skill definitions by id.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its id.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.ID] = def
+
+	return nil
+}
+
+// Get returns the definition with the id.
+func (d *Defs) Get(id string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[id]
+
+	return def, ok
+}
+
+// Named returns the definition of the kind whose name is or starts with
+// the name, like "magic" for "magic missile". Exact names win.
+func (d *Defs) Named(kind, name string) (Def, bool) {
+	name = strings.ToLower(name)
+	var found []Def
+	for _, def := range d.All() {
+		if def.Kind != kind {
+			continue
+		}
+		if def.Name == name {
+			return def, true
+		}
+		if name != "" && strings.HasPrefix(def.Name, name) {
+			found = append(found, def)
+		}
+	}
+	if len(found) == 0 {
+		return Def{}, false
+	}
+
+	return found[0], true
+}
+
+// All returns every definition, sorted by name.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+
+	return defs
+}
+
+// LoadDir adds the skills in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the skills listed in the YAML document, like:
+//   skills:
+//     - id: kick
+//       target: offensive
+//       cooldown: 6s
+//       lag: 2
+//       classes: {warrior: 1}
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Skills {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package skill_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSkill(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Skill Suite")
+}
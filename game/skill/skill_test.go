@@ -0,0 +1,286 @@
+package skill_test
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/random"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// learner is a character that records what it's told
+type learner struct {
+	name   string
+	class  string
+	stats  map[string]int
+	skills map[string]int
+	sent   []string
+}
+
+func (l *learner) ID() string {
+	return strings.ToLower(l.name)
+}
+
+func (l *learner) Name() string {
+	return l.name
+}
+
+func (l *learner) Class() string {
+	return l.class
+}
+
+func (l *learner) Location() string {
+	return "square"
+}
+
+func (l *learner) Stat(name string) int {
+	return l.stats[name]
+}
+
+func (l *learner) AddStat(name string, delta int) int {
+	l.stats[name] += delta
+
+	return l.stats[name]
+}
+
+func (l *learner) Skill(id string) int {
+	return l.skills[id]
+}
+
+func (l *learner) SetSkill(id string, percent int) {
+	l.skills[id] = percent
+}
+
+func (l *learner) Level() command.Level {
+	return command.Player
+}
+
+func (l *learner) Send(text string) error {
+	l.sent = append(l.sent, text)
+
+	return nil
+}
+
+func (l *learner) told(text string) bool {
+	return strings.Contains(strings.Join(l.sent, "\n"), text)
+}
+
+// rolls is a random source every die rolls the same side of, the lowest for
+// rolls(0) and the highest for rolls(99)
+type rolls int64
+
+func (r rolls) Int63() int64 {
+	return int64(r) << 32
+}
+
+func (r rolls) Seed(int64) {}
+
+const skillFile = `
+skills:
+  - id: kick
+    target: offensive
+    cooldown: 6s
+    lag: 2
+    classes: {Warrior: 1}
+  - id: heal
+    name: Cure Light
+    kind: spell
+    target: character
+    cost: {mana: 10}
+  - id: bash
+    classes: {warrior: 10}
+`
+
+var _ = Describe("Defs", func() {
+	var defs *Defs
+
+	BeforeEach(func() {
+		defs = NewDefs()
+	})
+
+	It("loads skills with defaults", func() {
+		Ω(defs.LoadYAML([]byte(skillFile))).Should(Succeed())
+
+		kick, ok := defs.Get("kick")
+		Ω(ok).Should(BeTrue())
+		Ω(kick.Name).Should(Equal("kick"))
+		Ω(kick.Kind).Should(Equal(Skill))
+		Ω(kick.Wait()).Should(Equal(6 * time.Second))
+		Ω(kick.Learnable("warrior", 1)).Should(BeTrue())
+		Ω(kick.Learnable("mage", 50)).Should(BeFalse())
+
+		heal, ok := defs.Named(Spell, "cure")
+		Ω(ok).Should(BeTrue())
+		Ω(heal.ID).Should(Equal("heal"))
+		Ω(heal.Learnable("mage", 1)).Should(BeTrue())
+		_, ok = defs.Named(Skill, "cure")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("rejects skills that don't make sense", func() {
+		Ω(defs.Add(Def{Name: "nameless"})).Should(MatchError("skills need an id"))
+		Ω(defs.Add(Def{ID: "x", Kind: "prayer"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "x", Target: "everyone"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{ID: "x", Cooldown: "soon"})).ShouldNot(Succeed())
+		Ω(defs.LoadYAML([]byte("skills:\n  - id: x\n    colour: red\n"))).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		em         *events.Emitter
+		fights     *combat.Engine
+		m          *Manager
+		alice, rat *learner
+	)
+
+	BeforeEach(func() {
+		random.SetSource(rolls(0))
+		em = events.NewEmitter(nil)
+		fights = combat.New(em)
+		alice = &learner{
+			name:   "Alice",
+			class:  "warrior",
+			stats:  map[string]int{"hp": 30, "mana": 15, "level": 5, "practices": 2, "int": 15},
+			skills: map[string]int{"kick": 50, "heal": 50},
+		}
+		rat = &learner{name: "a rat", stats: map[string]int{"hp": 20}, skills: map[string]int{}}
+		fights.SetOccupants(func(string) []combat.Combatant {
+			return []combat.Combatant{alice, rat}
+		})
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(skillFile))).Should(Succeed())
+		m = NewManager(defs, fights, em)
+		m.Handle("kick", func(u *Use) error {
+			fights.Strike(u.User, u.Target, 5, "kick")
+
+			return nil
+		})
+		m.Handle("heal", func(u *Use) error {
+			if u.Target.Stat("hp") >= 30 {
+				return errors.New("They don't need healing.")
+			}
+			u.Target.AddStat("hp", 10)
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		random.SetSource(rand.NewSource(time.Now().UnixNano()))
+	})
+
+	It("uses offensive skills on enemies, starting a fight", func() {
+		u, err := m.Use(alice, "kick", "rat")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(u.Target).Should(Equal(rat))
+		Ω(u.Lag).Should(Equal(2))
+		Ω(rat.stats["hp"]).Should(Equal(15))
+		Ω(fights.Fighting("alice")).Should(BeTrue())
+		Ω(alice.told("Your kick hits a rat. [5]")).Should(BeTrue())
+
+		_, err = m.Use(alice, "kick", "")
+		Ω(err).Should(MatchError(ReadyMessage))
+	})
+
+	It("defaults targets and checks costs", func() {
+		_, err := m.Use(alice, "kick", "")
+		Ω(err).Should(MatchError(WhomMessage))
+		_, err = m.Use(alice, "kick", "dragon")
+		Ω(err).Should(MatchError(combat.NotHereMessage))
+		_, err = m.Use(alice, "bash", "")
+		Ω(err).Should(MatchError(UnknownMessage))
+
+		_, err = m.Use(alice, "heal", "")
+		Ω(err).Should(MatchError("They don't need healing."))
+		Ω(alice.stats["mana"]).Should(Equal(5))
+		_, err = m.Use(alice, "heal", "rat")
+		Ω(err).Should(MatchError("You don't have enough mana."))
+	})
+
+	It("fails as often as the skill isn't known and improves with use", func() {
+		random.SetSource(rolls(99))
+		alice.stats["mana"] = 20
+		_, err := m.Use(alice, "heal", "rat")
+		Ω(err).Should(MatchError(FailedMessage))
+		Ω(rat.stats["hp"]).Should(Equal(20))
+		Ω(alice.skills["heal"]).Should(Equal(50))
+
+		random.SetSource(rolls(0))
+		_, err = m.Use(alice, "heal", "rat")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rat.stats["hp"]).Should(Equal(30))
+		Ω(alice.skills["heal"]).Should(Equal(51))
+		Ω(alice.told("You have become better at cure light!")).Should(BeTrue())
+	})
+
+	It("lets before handlers stop skills", func() {
+		em.On("before:"+UseEvent, events.HandlerFunc(func(d events.Data) error {
+			return errors.New("Your legs are bound.")
+		}))
+		_, err := m.Use(alice, "kick", "rat")
+		Ω(err).Should(MatchError("Your legs are bound."))
+		Ω(fights.Fighting("alice")).Should(BeFalse())
+	})
+
+	It("practices skills the learner's class can learn", func() {
+		alice.skills = map[string]int{}
+		prof, err := m.Practice(alice, "kick")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(prof).Should(Equal(10))
+		Ω(alice.stats["practices"]).Should(Equal(1))
+
+		_, err = m.Practice(alice, "bash")
+		Ω(err).Should(MatchError("You can't learn bash."))
+
+		m.SetAdept(12)
+		prof, _ = m.Practice(alice, "kick")
+		Ω(prof).Should(Equal(12))
+		_, err = m.Practice(alice, "kick")
+		Ω(err).Should(MatchError(NoPracticeMessage))
+
+		learnable := m.Learnable(alice)
+		Ω(learnable).Should(HaveLen(2))
+		Ω(learnable[0].ID).Should(Equal("heal"))
+	})
+
+	Describe("commands", func() {
+		var dispatch func(string)
+
+		BeforeEach(func() {
+			registry := command.NewRegistry()
+			for _, c := range NewCommands(m, func(c command.Caller) Learner {
+				return c.(*learner)
+			}) {
+				Ω(registry.Register(c)).Should(Succeed())
+			}
+			dispatch = func(line string) {
+				Ω(command.NewDispatcher(registry, nil).Dispatch(alice, line)).Should(Succeed())
+			}
+		})
+
+		It("uses, casts and practices skills", func() {
+			dispatch("kick rat")
+			Ω(rat.stats["hp"]).Should(Equal(15))
+			dispatch("cast cure rat")
+			Ω(rat.stats["hp"]).Should(Equal(25))
+			dispatch("cast fireball")
+			Ω(alice.told(UnknownMessage)).Should(BeTrue())
+
+			dispatch("skills")
+			Ω(alice.told("  cure light  51%\n  kick        51%")).Should(BeTrue())
+			dispatch("practice")
+			Ω(alice.told("You have 2 practice sessions left.")).Should(BeTrue())
+			dispatch("practice cure")
+			Ω(alice.told("You practice cure light, you now know it 61%.")).Should(BeTrue())
+		})
+	})
+})
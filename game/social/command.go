@@ -0,0 +1,126 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package social
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/perm"
+)
+
+// Source is the source of the commands socials are performed with.
+const Source = "socials"
+
+// NewCommands creates the socials command, which lists the socials, and the
+// social command builders add and change them with. Each social's own
+// command is registered by Manager.SetRegistry.
+func NewCommands(m *Manager) []*command.Command {
+	return []*command.Command{
+		{
+			Name:   "socials",
+			Help:   "Lists the socials.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				return ctx.Send(listing(m))
+			},
+		},
+		{
+			Name: "social",
+			Args: []command.Arg{
+				{Name: "name", Kind: command.Word, Optional: true},
+				{Name: "part", Kind: command.Word, Optional: true},
+				{Name: "message", Kind: command.Text, Optional: true},
+			},
+			Level:      command.Builder,
+			Capability: perm.Build,
+			Help: "Shows a social, \"social <name> <part> <message>\" sets one of its messages " +
+				"(" + strings.Join(Parts, ", ") + ") and \"social <name> remove\" removes it.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				name, part := ctx.String("name"), strings.ToLower(ctx.String("part"))
+				switch {
+				case name == "":
+					return ctx.Send(listing(m))
+				case part == "":
+					def, ok := m.defs.Get(name)
+					if !ok {
+						return ctx.Send(UnknownMessage)
+					}
+
+					return ctx.Send(describe(def))
+				case part == "remove":
+					removed, err := m.Remove(name)
+					if err != nil {
+						return err
+					}
+					if !removed {
+						return ctx.Send(UnknownMessage)
+					}
+
+					return ctx.Send(fmt.Sprintf("The %s social is removed.", strings.ToLower(name)))
+				}
+				def, err := m.Build(name, part, strings.TrimSpace(ctx.String("message")))
+				if r, ok := err.(*item.Refused); ok {
+					return ctx.Send(r.Message)
+				}
+				if err != nil {
+					return err
+				}
+
+				return ctx.Send(describe(def))
+			},
+		},
+	}
+}
+
+// socialCommand creates the command the social is performed with
+func socialCommand(m *Manager, def Def, resolve command.Resolver[Actor]) *command.Command {
+	return &command.Command{
+		Name:   def.Name,
+		Args:   []command.Arg{{Name: "target", Kind: command.Word, Optional: true}},
+		Help:   fmt.Sprintf("The %s social, aimed at someone or no one.", def.Name),
+		Source: Source,
+		Handler: func(ctx *command.Context) error {
+			a := resolve(ctx.Caller)
+			if a == nil {
+				return ctx.Send("You can't do that.")
+			}
+
+			err := m.Perform(a, def.Name, ctx.String("target"))
+			if r, ok := err.(*item.Refused); ok {
+				return ctx.Send(r.Message)
+			}
+
+			return err
+		},
+	}
+}
+
+// listing lists the names of the socials
+func listing(m *Manager) string {
+	defs := m.defs.All()
+	if len(defs) == 0 {
+		return "There are no socials."
+	}
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		names = append(names, def.Name)
+	}
+
+	return "Socials: " + strings.Join(names, ", ")
+}
+
+// describe shows each message of the social
+func describe(def Def) string {
+	lines := []string{def.Name + ":"}
+	for _, part := range Parts {
+		if msg, _ := def.Part(part); msg != "" {
+			lines = append(lines, fmt.Sprintf("  %s: %s", part, msg))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,404 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package social
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PerformEvent is emitted when a social is performed. Handlers of
+// before:social:perform can stop it by returning events.ErrHalt, or an error
+// whose message is told to the actor. It's given the name of the social, the
+// id and name of the actor, the id and name of the target (empty if there
+// isn't one) and the room.
+const PerformEvent = "social:perform"
+
+// Messages told to those who can't perform a social.
+const (
+	UnknownMessage    = "There's no such social."
+	NotHereMessage    = "They aren't here."
+	UntargetedMessage = "You can't do that to someone."
+	CancelMessage     = "You can't do that right now."
+)
+
+// Actor is someone who performs socials or sees them, like a player.
+type Actor interface {
+	ID() string
+	Name() string
+	Location() string
+}
+
+// Sender is an actor who's told about socials.
+type Sender interface {
+	Send(text string) error
+}
+
+// Matcher is an actor found by keywords other than their name, like an NPC.
+type Matcher interface {
+	Matches(keyword string) bool
+}
+
+// Manager performs socials and keeps those builders make.
+type Manager struct {
+	defs      *Defs
+	occupants func(room string) []Actor
+	registry  *command.Registry
+	resolve   command.Resolver[Actor]
+	file      string
+	built     map[string]bool
+	emitter   *events.Emitter
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for the socials. The emitter may be nil.
+func NewManager(defs *Defs, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:    defs,
+		built:   make(map[string]bool),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's social manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), nil)
+	})
+
+	return globalManager
+}
+
+// SetEmitter changes the emitter events are checked and emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how to find who's in a room, who see socials performed
+// there and may be aimed at. Without it socials are performed alone.
+func (m *Manager) SetOccupants(fn func(room string) []Actor) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetRegistry registers the command of each social with the registry, and
+// of every social defined after. Every social is registered even if some
+// fail, the first failure is returned.
+func (m *Manager) SetRegistry(r *command.Registry, resolve command.Resolver[Actor]) error {
+	m.mutex.Lock()
+	m.registry, m.resolve = r, resolve
+	m.mutex.Unlock()
+
+	var first error
+	for _, def := range m.defs.All() {
+		if err := m.register(def); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// SetFile sets where the socials builders make are saved, defining those
+// already saved there. Missing files are ignored.
+func (m *Manager) SetFile(path string) error {
+	m.mutex.Lock()
+	m.file = path
+	m.mutex.Unlock()
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Socials {
+		if err := m.Define(def); err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		m.built[def.Name] = true
+		m.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// Defs returns the social definitions.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// Define adds the social, replacing any with its name, and registers its
+// command if there's a registry.
+func (m *Manager) Define(def Def) error {
+	if err := m.defs.Add(def); err != nil {
+		return err
+	}
+	def, _ = m.defs.Get(strings.TrimSpace(def.Name))
+
+	return m.register(def)
+}
+
+// Build changes the part of the social with the name, like "at.room",
+// defining it if there's no such social, and saves it with the socials
+// builders made.
+func (m *Manager) Build(name, part, message string) (Def, error) {
+	def, ok := m.defs.Get(name)
+	if !ok {
+		def = Def{Name: name}
+	}
+	if !def.SetPart(part, message) {
+		return def, &item.Refused{Message: "Socials have no " + part + " message."}
+	}
+	if err := m.Define(def); err != nil {
+		return def, &item.Refused{Message: err.Error()}
+	}
+	def, _ = m.defs.Get(name)
+
+	m.mutex.Lock()
+	m.built[def.Name] = true
+	m.mutex.Unlock()
+
+	return def, m.save()
+}
+
+// Remove removes the social with the name and its command, returning false
+// if there's no such social.
+func (m *Manager) Remove(name string) (bool, error) {
+	def, ok := m.defs.Get(name)
+	if !ok {
+		return false, nil
+	}
+	m.defs.Remove(def.Name)
+
+	m.mutex.Lock()
+	r, built := m.registry, m.built[def.Name]
+	delete(m.built, def.Name)
+	m.mutex.Unlock()
+
+	if r != nil {
+		if c, ok := r.Get(def.Name); ok && c.Source == Source {
+			r.Unregister(c.Name)
+		}
+	}
+	if !built {
+		return true, nil
+	}
+
+	return true, m.save()
+}
+
+// Perform has the actor perform the social with the name, aimed at whoever
+// in their room the target names, or at no one if it's empty. "self" and
+// "me" aim it at the actor.
+func (m *Manager) Perform(a Actor, name, target string) error {
+	def, ok := m.defs.Get(name)
+	if !ok {
+		return &item.Refused{Message: UnknownMessage}
+	}
+	msgs, other, err := m.aim(def, a, strings.TrimSpace(target))
+	if err != nil {
+		return err
+	}
+	data := events.Data{
+		"social":      def.Name,
+		"actor":       a.ID(),
+		"name":        a.Name(),
+		"target":      "",
+		"target_name": "",
+		"room":        a.Location(),
+	}
+	targetName := ""
+	if other != nil {
+		targetName = other.Name()
+		data["target"] = other.ID()
+		data["target_name"] = targetName
+	}
+	if err := m.check(PerformEvent, data); err != nil {
+		return err
+	}
+
+	fill := strings.NewReplacer("{actor}", a.Name(), "{target}", targetName)
+	tell(a, fill.Replace(msgs.Actor))
+	if other != nil {
+		tell(other, fill.Replace(msgs.Target))
+	}
+	if msgs.Room != "" {
+		for _, o := range m.occupantsOf(a.Location()) {
+			if o.ID() == a.ID() || (other != nil && o.ID() == other.ID()) {
+				continue
+			}
+			tell(o, fill.Replace(msgs.Room))
+		}
+	}
+	m.confirm(PerformEvent, data)
+
+	return nil
+}
+
+// aim picks the social's messages for the target and finds who it names
+func (m *Manager) aim(def Def, a Actor, target string) (Messages, Actor, error) {
+	switch strings.ToLower(target) {
+	case "":
+		return def.Alone, nil, nil
+	case "self", "me":
+		if def.Self.Actor == "" {
+			return def.Alone, nil, nil
+		}
+
+		return def.Self, nil, nil
+	}
+	if def.At.Actor == "" {
+		return Messages{}, nil, &item.Refused{Message: UntargetedMessage}
+	}
+	other := m.find(a, target)
+	if other == nil {
+		return Messages{}, nil, &item.Refused{Message: NotHereMessage}
+	}
+
+	return def.At, other, nil
+}
+
+// find returns who in the actor's room the keyword names, like "2.guard"
+// for the second guard
+func (m *Manager) find(a Actor, keyword string) Actor {
+	n, keyword := item.Ordinal(keyword)
+	keyword = strings.ToLower(keyword)
+	for _, other := range m.occupantsOf(a.Location()) {
+		if other.ID() == a.ID() {
+			continue
+		}
+		matched := false
+		if mt, ok := other.(Matcher); ok {
+			matched = mt.Matches(keyword)
+		} else {
+			for _, word := range strings.Fields(strings.ToLower(other.Name())) {
+				matched = matched || strings.HasPrefix(word, keyword)
+			}
+		}
+		if matched {
+			n--
+			if n == 0 {
+				return other
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) occupantsOf(room string) []Actor {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil {
+		return nil
+	}
+
+	return occupants(room)
+}
+
+// register adds the social's command to the registry, replacing the one it
+// had before
+func (m *Manager) register(def Def) error {
+	m.mutex.RLock()
+	r, resolve := m.registry, m.resolve
+	m.mutex.RUnlock()
+
+	if r == nil {
+		return nil
+	}
+	if c, ok := r.Get(def.Name); ok && c.Source == Source {
+		r.Unregister(c.Name)
+	}
+
+	return r.Register(socialCommand(m, def, resolve))
+}
+
+// save writes the socials builders made to the file, if there is one
+func (m *Manager) save() error {
+	m.mutex.RLock()
+	path := m.file
+	var f File
+	for _, def := range m.defs.All() {
+		if m.built[def.Name] {
+			f.Socials = append(f.Socials, def)
+		}
+	}
+	m.mutex.RUnlock()
+
+	if path == "" {
+		return nil
+	}
+	contents, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0644)
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return &item.Refused{Message: CancelMessage}
+		}
+
+		return &item.Refused{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+// tell sends the text to the actor if it's not empty and they can be told
+func tell(a Actor, text string) {
+	if s, ok := a.(Sender); ok && text != "" {
+		s.Send(text)
+	}
+}
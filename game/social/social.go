@@ -0,0 +1,265 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package social lets players act out how they feel, like smiling or bowing
+// to someone. Socials are defined in YAML files or by scripts with what the
+// one performing them, the one they're aimed at and everyone else in the
+// room are told, and each becomes a command. Builders can add and change
+// socials in the game, those are saved to a file of their own.
+package social
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Messages are what each side of a social is told, {actor} and {target} are
+// replaced with the names of the one performing it and the one it's aimed
+// at.
+type Messages struct {
+	// Actor is what the one performing the social is told.
+	Actor string `yaml:"actor,omitempty"`
+	// Target is what the one it's aimed at is told.
+	Target string `yaml:"target,omitempty"`
+	// Room is what everyone else in the room is told.
+	Room string `yaml:"room,omitempty"`
+}
+
+// Def defines a social.
+type Def struct {
+	// Name is the command the social is performed with, like "smile".
+	Name string `yaml:"name"`
+	// Alone are the messages when it isn't aimed at anyone.
+	Alone Messages `yaml:"alone"`
+	// At are the messages when it's aimed at someone else, socials without
+	// them can't be.
+	At Messages `yaml:"at,omitempty"`
+	// Self are the messages when it's aimed at the actor, Alone is used
+	// without them.
+	Self Messages `yaml:"self,omitempty"`
+}
+
+// Parts are the messages of a social as builders name them.
+var Parts = []string{
+	"alone.actor", "alone.room",
+	"at.actor", "at.target", "at.room",
+	"self.actor", "self.room",
+}
+
+// Part returns the message of the social with the name, like "at.room".
+func (d Def) Part(name string) (string, bool) {
+	msg := d.part(name)
+	if msg == nil {
+		return "", false
+	}
+
+	return *msg, true
+}
+
+// SetPart changes the message of the social with the name, returning false
+// if there's no such part.
+func (d *Def) SetPart(name, message string) bool {
+	msg := d.part(name)
+	if msg == nil {
+		return false
+	}
+	*msg = message
+
+	return true
+}
+
+// part returns where the message with the name is kept
+func (d *Def) part(name string) *string {
+	switch strings.ToLower(name) {
+	case "alone.actor":
+		return &d.Alone.Actor
+	case "alone.room":
+		return &d.Alone.Room
+	case "at.actor":
+		return &d.At.Actor
+	case "at.target":
+		return &d.At.Target
+	case "at.room":
+		return &d.At.Room
+	case "self.actor":
+		return &d.Self.Actor
+	case "self.room":
+		return &d.Self.Room
+	}
+
+	return nil
+}
+
+// validate fills in defaults and checks the definition makes sense
+func (d *Def) validate() error {
+	d.Name = strings.ToLower(strings.TrimSpace(d.Name))
+	if d.Name == "" || strings.ContainsAny(d.Name, " \t") {
+		return fmt.Errorf("socials need a name that's a single word")
+	}
+	if d.Alone.Actor == "" {
+		return fmt.Errorf("social %s: needs an alone.actor message", d.Name)
+	}
+	if d.At.Actor == "" && (d.At.Target != "" || d.At.Room != "") {
+		return fmt.Errorf("social %s: needs an at.actor message to be aimed at someone", d.Name)
+	}
+
+	return nil
+}
+
+// Defaults are the socials the game starts with, in the layout of social
+// files.
+const Defaults = `
+socials:
+  - name: smile
+    alone: {actor: "You smile.", room: "{actor} smiles."}
+    at: {actor: "You smile at {target}.", target: "{actor} smiles at you.", room: "{actor} smiles at {target}."}
+    self: {actor: "You smile to yourself.", room: "{actor} smiles to themself."}
+  - name: nod
+    alone: {actor: "You nod.", room: "{actor} nods."}
+    at: {actor: "You nod to {target}.", target: "{actor} nods to you.", room: "{actor} nods to {target}."}
+  - name: bow
+    alone: {actor: "You bow deeply.", room: "{actor} bows deeply."}
+    at: {actor: "You bow before {target}.", target: "{actor} bows before you.", room: "{actor} bows before {target}."}
+  - name: wave
+    alone: {actor: "You wave.", room: "{actor} waves."}
+    at: {actor: "You wave to {target}.", target: "{actor} waves to you.", room: "{actor} waves to {target}."}
+  - name: laugh
+    alone: {actor: "You laugh.", room: "{actor} laughs."}
+    at: {actor: "You laugh at {target}.", target: "{actor} laughs at you.", room: "{actor} laughs at {target}."}
+    self: {actor: "You laugh at yourself.", room: "{actor} laughs at themself."}
+  - name: shrug
+    alone: {actor: "You shrug.", room: "{actor} shrugs."}
+    at: {actor: "You shrug at {target}.", target: "{actor} shrugs at you.", room: "{actor} shrugs at {target}."}
+  - name: sigh
+    alone: {actor: "You sigh.", room: "{actor} sighs."}
+`
+
+// File is the layout of a social file, a list of socials.
+type File struct {
+	Socials []Def `yaml:"socials"`
+}
+
+// Defs holds the social definitions by name.
+type Defs struct {
+	defs  map[string]Def
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates an empty set of definitions.
+func NewDefs() *Defs {
+	return &Defs{
+		defs:  make(map[string]Def),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// Add adds the definition, replacing any with its name.
+func (d *Defs) Add(def Def) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.defs[def.Name] = def
+
+	return nil
+}
+
+// Remove removes the definition with the name, returning false if there
+// isn't one.
+func (d *Defs) Remove(name string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	name = strings.ToLower(name)
+	_, ok := d.defs[name]
+	delete(d.defs, name)
+
+	return ok
+}
+
+// Get returns the definition with the name.
+func (d *Defs) Get(name string) (Def, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	def, ok := d.defs[strings.ToLower(name)]
+
+	return def, ok
+}
+
+// All returns every definition, sorted by name.
+func (d *Defs) All() []Def {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	defs := make([]Def, 0, len(d.defs))
+	for _, def := range d.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+
+	return defs
+}
+
+// LoadDir adds the socials in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the socials listed in the YAML document, like:
+//   socials:
+//     - name: grin
+//       alone: {actor: "You grin.", room: "{actor} grins."}
+//       at:
+//         actor: "You grin at {target}."
+//         target: "{actor} grins at you."
+//         room: "{actor} grins at {target}."
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, def := range f.Socials {
+		if err := d.Add(def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
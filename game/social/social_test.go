@@ -0,0 +1,181 @@
+package social_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/social"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mime performs socials and remembers what they're told
+type mime struct {
+	name  string
+	room  string
+	level command.Level
+	sent  []string
+}
+
+func (m *mime) ID() string {
+	return strings.ToLower(m.name)
+}
+
+func (m *mime) Name() string {
+	return m.name
+}
+
+func (m *mime) Location() string {
+	return m.room
+}
+
+func (m *mime) Level() command.Level {
+	return m.level
+}
+
+func (m *mime) Send(text string) error {
+	m.sent = append(m.sent, text)
+
+	return nil
+}
+
+func (m *mime) told(text string) bool {
+	for _, sent := range m.sent {
+		if sent == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ = Describe("Socials", func() {
+	var (
+		em            *events.Emitter
+		m             *Manager
+		ann, bob, cat *mime
+	)
+
+	BeforeEach(func() {
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(Defaults))).Should(Succeed())
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, em)
+		ann, bob, cat = &mime{name: "Ann", room: "inn"}, &mime{name: "Bob", room: "inn"}, &mime{name: "Cat", room: "inn"}
+		m.SetOccupants(func(room string) []Actor {
+			return []Actor{ann, bob, cat}
+		})
+	})
+
+	It("validates socials", func() {
+		defs := NewDefs()
+		Ω(defs.Add(Def{Name: "grin"})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{Name: "two words", Alone: Messages{Actor: "You grin."}})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{Name: "grin", Alone: Messages{Actor: "You grin."}, At: Messages{Room: "{actor} grins."}})).ShouldNot(Succeed())
+		Ω(defs.Add(Def{Name: "Grin", Alone: Messages{Actor: "You grin."}})).Should(Succeed())
+		_, ok := defs.Get("grin")
+		Ω(ok).Should(BeTrue())
+	})
+
+	It("tells each side what they see", func() {
+		Ω(m.Perform(ann, "smile", "")).Should(Succeed())
+		Ω(ann.told("You smile.")).Should(BeTrue())
+		Ω(bob.told("Ann smiles.")).Should(BeTrue())
+
+		Ω(m.Perform(ann, "bow", "b")).Should(Succeed())
+		Ω(ann.told("You bow before Bob.")).Should(BeTrue())
+		Ω(bob.told("Ann bows before you.")).Should(BeTrue())
+		Ω(cat.told("Ann bows before Bob.")).Should(BeTrue())
+		Ω(bob.sent).Should(HaveLen(2))
+
+		Ω(m.Perform(ann, "laugh", "self")).Should(Succeed())
+		Ω(cat.told("Ann laughs at themself.")).Should(BeTrue())
+		Ω(m.Perform(ann, "nod", "me")).Should(Succeed())
+		Ω(ann.told("You nod.")).Should(BeTrue())
+	})
+
+	It("refuses socials that can't be performed", func() {
+		Ω(m.Perform(ann, "dance", "")).Should(MatchError(UnknownMessage))
+		Ω(m.Perform(ann, "bow", "dan")).Should(MatchError(NotHereMessage))
+		Ω(m.Perform(ann, "sigh", "bob")).Should(MatchError(UntargetedMessage))
+		Ω(m.Perform(ann, "bow", "ann")).Should(MatchError(NotHereMessage))
+	})
+
+	It("lets before handlers stop socials and tells of those performed", func(done Done) {
+		performed := make(chan events.Data, 1)
+		em.On("before:"+PerformEvent, events.HandlerFunc(func(d events.Data) error {
+			if d["target"] == "cat" {
+				return errors.New("The cat ignores you.")
+			}
+
+			return nil
+		}))
+		em.On(PerformEvent, events.HandlerFunc(func(d events.Data) error {
+			performed <- d
+
+			return nil
+		}))
+
+		Ω(m.Perform(ann, "wave", "cat")).Should(MatchError("The cat ignores you."))
+		Ω(m.Perform(ann, "wave", "bob")).Should(Succeed())
+
+		d := <-performed
+		Ω(d["social"]).Should(Equal("wave"))
+		Ω(d["target_name"]).Should(Equal("Bob"))
+		close(done)
+	})
+
+	It("performs socials with commands and lets builders make them", func() {
+		dir, err := ioutil.TempDir("", "socials")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "built.yml")
+		Ω(m.SetFile(file)).Should(Succeed())
+
+		registry := command.NewRegistry()
+		resolve := func(c command.Caller) Actor {
+			return c.(*mime)
+		}
+		for _, c := range NewCommands(m) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		Ω(m.SetRegistry(registry, resolve)).Should(Succeed())
+		dispatch := func(line string) {
+			Ω(command.NewDispatcher(registry, nil).Dispatch(ann, line)).Should(Succeed())
+		}
+
+		dispatch("wave bob")
+		Ω(bob.told("Ann waves to you.")).Should(BeTrue())
+		dispatch("socials")
+		Ω(ann.told("Socials: bow, laugh, nod, shrug, sigh, smile, wave")).Should(BeTrue())
+
+		ann.level = command.Builder
+		dispatch("social grin at.actor You grin at {target}.")
+		Ω(ann.told("social grin: needs an alone.actor message")).Should(BeTrue())
+		dispatch("social grin alone.actor You grin.")
+		dispatch("social grin alone.room {actor} grins.")
+		dispatch("social grin")
+		Ω(ann.told("grin:\n  alone.actor: You grin.\n  alone.room: {actor} grins.")).Should(BeTrue())
+		dispatch("social grin nose.actor You grin.")
+		Ω(ann.told("Socials have no nose.actor message.")).Should(BeTrue())
+		dispatch("grin")
+		Ω(cat.told("Ann grins.")).Should(BeTrue())
+
+		saved := NewManager(NewDefs(), nil)
+		Ω(saved.SetFile(file)).Should(Succeed())
+		_, ok := saved.Defs().Get("grin")
+		Ω(ok).Should(BeTrue())
+		Ω(saved.Defs().All()).Should(HaveLen(1))
+
+		dispatch("social grin remove")
+		Ω(ann.told("The grin social is removed.")).Should(BeTrue())
+		_, found := registry.Get("grin")
+		Ω(found).Should(BeFalse())
+	})
+})
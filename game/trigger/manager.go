@@ -0,0 +1,348 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package trigger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// script is the source of a script as it was when it was read
+type script struct {
+	modified time.Time
+	size     int64
+	source   []byte
+}
+
+// Manager runs the triggers of the world's rooms, NPCs and items.
+type Manager struct {
+	world     *world.World
+	dir       string
+	run       Runner
+	occupants func(room string) []Entity
+	entities  func() []Entity
+	now       func() time.Time
+	// scripts holds the scripts that have been read, by path
+	scripts map[string]script
+	// timers holds when each timer trigger last ran
+	timers map[string]time.Time
+	stop   chan struct{}
+	mutex  *sync.RWMutex
+}
+
+// NewManager creates a manager running the triggers of the world with the
+// scripts in the directory.
+func NewManager(w *world.World, dir string) *Manager {
+	return &Manager{
+		world:   w,
+		dir:     dir,
+		now:     time.Now,
+		scripts: make(map[string]script),
+		timers:  make(map[string]time.Time),
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager the game uses.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), "triggers")
+	})
+
+	return globalManager
+}
+
+// SetDir changes the directory scripts are read from, forgetting those
+// already read.
+func (m *Manager) SetDir(dir string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.dir = dir
+	m.scripts = make(map[string]script)
+}
+
+// SetRunner sets what runs the scripts. Without it triggers do nothing.
+func (m *Manager) SetRunner(run Runner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.run = run
+}
+
+// SetOccupants sets how the mobs and items in a room are found, for the
+// triggers of what's around when something happens in a room. Without it
+// only the room's triggers run.
+func (m *Manager) SetOccupants(fn func(room string) []Entity) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetEntities sets how every mob and item in the game is found, for their
+// timers. Without it only the timers of rooms run.
+func (m *Manager) SetEntities(fn func() []Entity) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entities = fn
+}
+
+// SetClock changes where the time timers go by comes from.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// Reload forgets the scripts that have been read, so they're read again
+// the next time they run.
+func (m *Manager) Reload() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.scripts = make(map[string]script)
+}
+
+// Fire runs the entity's triggers of the kind, telling their scripts about
+// the entity along with the data. It returns how many ran.
+func (m *Manager) Fire(e Entity, on string, data events.Data) int {
+	ran := 0
+	for _, t := range Triggers(m.world, e) {
+		if t.On != on {
+			continue
+		}
+		d := e.data()
+		for k, v := range data {
+			d[k] = v
+		}
+		if on == world.TriggerSpeech {
+			keyword, ok := Heard(t, fmt.Sprint(data["message"]))
+			if !ok {
+				continue
+			}
+			d["keyword"] = keyword
+		}
+		if m.runTrigger(e, t, d) {
+			ran++
+		}
+	}
+
+	return ran
+}
+
+// Enter runs the enter triggers of the room and what's in it for the actor
+// entering it.
+func (m *Manager) Enter(room, actor string) int {
+	return m.fireIn(room, world.TriggerEnter, events.Data{"actor": actor})
+}
+
+// Leave runs the leave triggers of the room and what's in it for the actor
+// leaving it.
+func (m *Manager) Leave(room, actor string) int {
+	return m.fireIn(room, world.TriggerLeave, events.Data{"actor": actor})
+}
+
+// Speech runs the speech triggers of the room and what's in it that have
+// keywords in the message, or none at all.
+func (m *Manager) Speech(room, speaker, message string) int {
+	return m.fireIn(room, world.TriggerSpeech, events.Data{"actor": speaker, "message": message})
+}
+
+// Give runs the give triggers of the item and the NPC given it.
+func (m *Manager) Give(actor string, it, to Entity) int {
+	data := events.Data{
+		"actor":      actor,
+		"item":       it.ID,
+		"item_proto": it.Proto,
+		"target":     to.ID,
+	}
+	ran := m.Fire(it, world.TriggerGive, data)
+	if to.Kind == NPCKind {
+		ran += m.Fire(to, world.TriggerGive, data)
+	}
+
+	return ran
+}
+
+// Death runs the death triggers of the mob, killer names who killed it and
+// may be empty.
+func (m *Manager) Death(mob Entity, killer string) int {
+	return m.Fire(mob, world.TriggerDeath, events.Data{"killer": killer})
+}
+
+// fireIn runs the triggers of the room and everything in it
+func (m *Manager) fireIn(room, on string, data events.Data) int {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	ran := m.Fire(Room(room), on, data)
+	if occupants != nil {
+		for _, e := range occupants(room) {
+			ran += m.Fire(e, on, data)
+		}
+	}
+
+	return ran
+}
+
+// Update runs the timer triggers that are due. Timers start counting when
+// they're first seen, so nothing runs as the game starts.
+func (m *Manager) Update() {
+	m.mutex.RLock()
+	entities, now := m.entities, m.now()
+	m.mutex.RUnlock()
+
+	var all []Entity
+	for _, z := range m.world.Zones() {
+		for _, r := range m.world.Rooms(z.ID) {
+			if len(r.Triggers) > 0 {
+				all = append(all, Room(r.ID))
+			}
+		}
+	}
+	if entities != nil {
+		all = append(all, entities()...)
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range all {
+		for i, t := range Triggers(m.world, e) {
+			every := t.Interval()
+			if every <= 0 {
+				continue
+			}
+			key := fmt.Sprintf("%s:%s:%d", e.Kind, e.ID, i)
+			seen[key] = true
+
+			m.mutex.Lock()
+			last, ok := m.timers[key]
+			due := ok && now.Sub(last) >= every
+			if !ok || due {
+				m.timers[key] = now
+			}
+			m.mutex.Unlock()
+
+			if due {
+				m.runTrigger(e, t, e.data())
+			}
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key := range m.timers {
+		if !seen[key] {
+			delete(m.timers, key)
+		}
+	}
+}
+
+// Start updates the timers every interval until stopped.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil || interval <= 0 {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Update()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+// Stop halts the updates.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// runTrigger runs the trigger's script, logging why it couldn't. It returns
+// false if the script didn't run.
+func (m *Manager) runTrigger(e Entity, t world.Trigger, data events.Data) bool {
+	m.mutex.RLock()
+	run := m.run
+	m.mutex.RUnlock()
+
+	if run == nil {
+		return false
+	}
+	log := logger.NewWithSource("trigger").WithField("script", t.Script).WithField(e.Kind, e.ID)
+	source, err := m.source(t.Script)
+	if err != nil {
+		log.WithError(err).Warn("Failed to read a trigger script.")
+
+		return false
+	}
+	data["on"] = t.On
+	data["script"] = t.Script
+	if err := run(t.Script, source, data); err != nil {
+		log.WithError(err).Warn("Trigger script failed.")
+	}
+
+	return true
+}
+
+// source returns the script with the path in the triggers directory,
+// reading it again if it's changed since it was last read
+func (m *Manager) source(name string) ([]byte, error) {
+	m.mutex.RLock()
+	path := filepath.Join(m.dir, filepath.FromSlash(name))
+	cached, ok := m.scripts[path]
+	m.mutex.RUnlock()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if ok && fi.ModTime().Equal(cached.modified) && fi.Size() == cached.size {
+		return cached.source, nil
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		logger.NewWithSource("trigger").WithField("script", name).Info("Reloaded a changed trigger script.")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.scripts[path] = script{modified: fi.ModTime(), size: fi.Size(), source: source}
+
+	return source, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package trigger runs the scripts area files attach to rooms, NPCs and
+// items when things happen around them: someone entering or leaving, speech
+// with the right keywords, items being given, NPCs dying and timers. Scripts
+// are read from the triggers directory as they're needed and read again
+// whenever they change, so builders can edit them while the game runs.
+package trigger
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// The kinds of entity triggers are attached to.
+const (
+	RoomKind = "room"
+	NPCKind  = "npc"
+	ItemKind = "item"
+)
+
+// Entity is a room, mob or item in the game that may have triggers.
+type Entity struct {
+	Kind string
+	// ID is the id of the room, mob or item.
+	ID string
+	// Proto is the id of the mob's NPC or the item's definition, rooms are
+	// their own.
+	Proto string
+	// Room is where the entity is.
+	Room string
+}
+
+// Room returns the entity of the room.
+func Room(id string) Entity {
+	return Entity{Kind: RoomKind, ID: id, Proto: id, Room: id}
+}
+
+// data returns what scripts are told about the entity
+func (e Entity) data() events.Data {
+	return events.Data{
+		"self":  e.ID,
+		"kind":  e.Kind,
+		"proto": e.Proto,
+		"room":  e.Room,
+	}
+}
+
+// Runner runs the source of a trigger script, the data telling it what
+// happened, like the server does with a Lua engine.
+type Runner func(script string, source []byte, data events.Data) error
+
+// Triggers returns the triggers attached to the entity in the world.
+func Triggers(w *world.World, e Entity) []world.Trigger {
+	switch e.Kind {
+	case RoomKind:
+		if r, ok := w.Room(e.Proto); ok {
+			return r.Triggers
+		}
+	case NPCKind:
+		if def, ok := w.NPC(e.Proto); ok {
+			return def.Triggers
+		}
+	case ItemKind:
+		if def, ok := w.Item(e.Proto); ok {
+			return def.Triggers
+		}
+	}
+
+	return nil
+}
+
+// Heard returns the keyword of the speech trigger in the message, which is
+// empty for triggers without keywords. It's false if the message has none
+// of them.
+func Heard(t world.Trigger, message string) (string, bool) {
+	if len(t.Keywords) == 0 {
+		return "", true
+	}
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\''
+	})
+	for _, keyword := range t.Keywords {
+		for _, word := range words {
+			if word == strings.ToLower(keyword) {
+				return keyword, true
+			}
+		}
+	}
+
+	return "", false
+}
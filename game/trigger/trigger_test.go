@@ -0,0 +1,159 @@
+package trigger_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	. "github.com/bbuck/dragon-mud/game/trigger"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// run is a script that ran
+type run struct {
+	script string
+	source string
+	data   events.Data
+}
+
+var _ = Describe("Heard", func() {
+	It("matches speech with a keyword, or any speech without keywords", func() {
+		t := world.Trigger{On: world.TriggerSpeech, Keywords: []string{"job", "Work"}}
+
+		keyword, ok := Heard(t, "Got any WORK for me?")
+		Ω(ok).Should(BeTrue())
+		Ω(keyword).Should(Equal("Work"))
+		_, ok = Heard(t, "I'm a jobber.")
+		Ω(ok).Should(BeFalse())
+		_, ok = Heard(world.Trigger{On: world.TriggerSpeech}, "hello")
+		Ω(ok).Should(BeTrue())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		w    *world.World
+		dir  string
+		m    *Manager
+		now  time.Time
+		ran  []run
+		mobs []Entity
+	)
+
+	write := func(name, source string) {
+		Ω(ioutil.WriteFile(filepath.Join(dir, name), []byte(source), 0644)).Should(Succeed())
+	}
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		Ω(w.AddRoom(world.Room{ID: "square", Zone: "town", Name: "Town Square", Triggers: []world.Trigger{
+			{On: world.TriggerEnter, Script: "fountain.lua"},
+			{On: world.TriggerTimer, Every: "1m", Script: "bell.lua"},
+		}})).Should(Succeed())
+		Ω(w.SetNPC(world.NPCDef{ID: "guard", Zone: "town", Name: "a guard", Triggers: []world.Trigger{
+			{On: world.TriggerSpeech, Keywords: []string{"job"}, Script: "job.lua"},
+			{On: world.TriggerGive, Script: "thanks.lua"},
+			{On: world.TriggerDeath, Script: "cry.lua"},
+			{On: world.TriggerEnter, Script: "missing.lua"},
+		}})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "letter", Zone: "town", Name: "a letter", Triggers: []world.Trigger{
+			{On: world.TriggerGive, Script: "letter.lua"},
+		}})).Should(Succeed())
+
+		var err error
+		dir, err = ioutil.TempDir("", "triggers")
+		Ω(err).ShouldNot(HaveOccurred())
+		for _, name := range []string{"fountain.lua", "bell.lua", "job.lua", "thanks.lua", "cry.lua", "letter.lua"} {
+			write(name, "-- "+name)
+		}
+
+		m = NewManager(w, dir)
+		ran = nil
+		m.SetRunner(func(script string, source []byte, data events.Data) error {
+			ran = append(ran, run{script, string(source), data})
+
+			return errors.New("ignored")
+		})
+		mobs = []Entity{{Kind: NPCKind, ID: "guard-1", Proto: "guard", Room: "square"}}
+		m.SetOccupants(func(room string) []Entity {
+			var in []Entity
+			for _, e := range mobs {
+				if e.Room == room {
+					in = append(in, e)
+				}
+			}
+
+			return in
+		})
+		m.SetEntities(func() []Entity {
+			return mobs
+		})
+		now = time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		m.SetClock(func() time.Time { return now })
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("runs the triggers of the room and what's in it", func() {
+		Ω(m.Enter("square", "Alice")).Should(Equal(1))
+		Ω(ran).Should(HaveLen(1))
+		Ω(ran[0].script).Should(Equal("fountain.lua"))
+		Ω(ran[0].source).Should(Equal("-- fountain.lua"))
+		Ω(ran[0].data).Should(Equal(events.Data{
+			"self":   "square",
+			"kind":   RoomKind,
+			"proto":  "square",
+			"room":   "square",
+			"actor":  "Alice",
+			"on":     world.TriggerEnter,
+			"script": "fountain.lua",
+		}))
+
+		Ω(m.Leave("square", "Alice")).Should(BeZero())
+		Ω(m.Speech("square", "Alice", "Hello there.")).Should(BeZero())
+		Ω(m.Speech("square", "Alice", "Any job going?")).Should(Equal(1))
+		Ω(ran[1].data["self"]).Should(Equal("guard-1"))
+		Ω(ran[1].data["keyword"]).Should(Equal("job"))
+	})
+
+	It("runs give and death triggers", func() {
+		letter := Entity{Kind: ItemKind, ID: "letter-1", Proto: "letter", Room: "square"}
+
+		Ω(m.Give("Alice", letter, mobs[0])).Should(Equal(2))
+		Ω(ran[0].script).Should(Equal("letter.lua"))
+		Ω(ran[1].data["item_proto"]).Should(Equal("letter"))
+		Ω(m.Death(mobs[0], "Alice")).Should(Equal(1))
+		Ω(ran[2].data["killer"]).Should(Equal("Alice"))
+	})
+
+	It("runs timers when they're due", func() {
+		m.Update()
+		Ω(ran).Should(BeEmpty())
+		now = now.Add(30 * time.Second)
+		m.Update()
+		Ω(ran).Should(BeEmpty())
+		now = now.Add(30 * time.Second)
+		m.Update()
+		Ω(ran).Should(HaveLen(1))
+		Ω(ran[0].script).Should(Equal("bell.lua"))
+		m.Update()
+		Ω(ran).Should(HaveLen(1))
+	})
+
+	It("reads scripts again when they change", func() {
+		m.Enter("square", "Alice")
+		write("fountain.lua", "-- the fountain sparkles")
+		m.Enter("square", "Alice")
+
+		Ω(ran[1].source).Should(Equal("-- the fountain sparkles"))
+	})
+})
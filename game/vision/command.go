@@ -0,0 +1,51 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package vision
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/movement"
+)
+
+// Looker is a viewer who can be told what they see, like a player.
+type Looker interface {
+	Viewer
+	Send(text string) error
+}
+
+// NewCommands creates the look command, describing the caller's room, and
+// the scan command, telling them who they see in the rooms around it.
+func NewCommands(m *Manager, resolve command.Resolver[Looker]) []*command.Command {
+	return []*command.Command{
+		{
+			Name:   "look",
+			Help:   "Looks around the room you're in.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				l := resolve(ctx.Caller)
+				if l == nil {
+					return ctx.Send("You aren't playing anyone.")
+				}
+				r, ok := m.world.Room(l.Location())
+				if !ok {
+					return ctx.Send(movement.NowhereMessage)
+				}
+
+				return ctx.Send(m.Describe(l, r))
+			},
+		},
+		{
+			Name:   "scan",
+			Help:   "Looks into the rooms around you for anyone there.",
+			Source: "game",
+			Handler: func(ctx *command.Context) error {
+				l := resolve(ctx.Caller)
+				if l == nil {
+					return ctx.Send("You aren't playing anyone.")
+				}
+
+				return ctx.Send(m.Scan(l))
+			},
+		},
+	}
+}
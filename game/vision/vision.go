@@ -0,0 +1,431 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package vision decides what can be seen. Rooms have a light level, from
+// their flags, the time of day outdoors and the light sources carried,
+// worn or lying in them, and those in a room too dark can't see what's in
+// it. Characters and items may also be invisible, and scripts can hook in to
+// hide things for reasons of their own. Looking, scanning and targeting
+// others all go through here.
+package vision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// Room flags setting their light.
+const (
+	// DarkFlag leaves a room without light of its own.
+	DarkFlag = "dark"
+	// LitFlag keeps a room lit, even at night.
+	LitFlag = "lit"
+	// IndoorsFlag keeps the time of day from changing a room's light.
+	IndoorsFlag = "indoors"
+)
+
+// Flags of characters and items.
+const (
+	// BlindFlag keeps a character from seeing anything.
+	BlindFlag = "blind"
+	// DarkvisionFlag lets a character see without light.
+	DarkvisionFlag = "darkvision"
+	// InvisibleFlag hides a character or item from those without
+	// SeeInvisibleFlag.
+	InvisibleFlag    = "invisible"
+	SeeInvisibleFlag = "see_invisible"
+	// GlowFlag makes an item a light source, like a glowing sword.
+	GlowFlag = "glow"
+)
+
+// Light sources are items of the LightType, or that glow, giving off the
+// light in their LightProp, or DefaultLight without one.
+const (
+	LightType = "light"
+	LightProp = "light"
+)
+
+// The light of rooms. Rooms have DefaultLight unless they're dark, and
+// those in rooms with less than SeeLight can't see.
+const (
+	DefaultLight = 2
+	SeeLight     = 1
+)
+
+// Messages told to those who can't see.
+const (
+	DarkMessage  = "It's too dark to see."
+	BlindMessage = "You can't see a thing!"
+)
+
+// Viewer is anyone who looks at things, like a player.
+type Viewer interface {
+	Name() string
+	Location() string
+}
+
+// Flagged is a viewer or thing with flags.
+type Flagged interface {
+	Flag(name string) bool
+}
+
+// identified is a viewer with an id, like a mob
+type identified interface {
+	ID() string
+}
+
+// carrier is an occupant carrying items, that may be light sources
+type carrier interface {
+	Inventory() item.List
+}
+
+// wearer is an occupant wearing items, that may be light sources
+type wearer interface {
+	Equipment() map[string]item.Item
+}
+
+// Target is something a viewer might see, a character or an item.
+type Target struct {
+	ID   string
+	Name string
+	// Item is true for items, false for characters.
+	Item bool
+	flag func(string) bool
+}
+
+// Flag is true if the target has the flag.
+func (t Target) Flag(name string) bool {
+	return t.flag != nil && t.flag(name)
+}
+
+// CharacterTarget returns the target of the character.
+func CharacterTarget(c Viewer) Target {
+	t := Target{ID: c.Name(), Name: c.Name()}
+	if i, ok := c.(identified); ok {
+		t.ID = i.ID()
+	}
+	if f, ok := c.(Flagged); ok {
+		t.flag = f.Flag
+	}
+
+	return t
+}
+
+// ItemTarget returns the target of the item.
+func ItemTarget(it item.Item) Target {
+	return Target{ID: it.ID, Name: it.Name, Item: true, flag: it.Flag}
+}
+
+// HookFunc decides whether the viewer sees the target, false hides it.
+type HookFunc func(v Viewer, t Target) bool
+
+// Manager decides what viewers can see.
+type Manager struct {
+	world     *world.World
+	floor     *item.Floor
+	daylight  func() bool
+	day       int
+	night     int
+	occupants func(room string) []Viewer
+	hooks     map[string]HookFunc
+	mutex     *sync.RWMutex
+}
+
+// NewManager creates a manager for the world's rooms and the items on the
+// floor.
+func NewManager(w *world.World, f *item.Floor) *Manager {
+	return &Manager{
+		world: w,
+		floor: f,
+		night: -DefaultLight,
+		hooks: make(map[string]HookFunc),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the game's vision manager.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(world.Global(), item.GlobalFloor())
+	})
+
+	return globalManager
+}
+
+// SetDaylight sets how to tell whether it's day, like from the game clock.
+// Without it it's always day.
+func (m *Manager) SetDaylight(fn func() bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.daylight = fn
+}
+
+// SetModifiers changes the light added to rooms that aren't indoors by day
+// and at night, 0 and -DefaultLight unless they're changed so it's dark
+// outside at night.
+func (m *Manager) SetModifiers(day, night int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.day, m.night = day, night
+}
+
+// SetOccupants sets how to find the characters in a room, who are seen
+// looking and whose light sources light it.
+func (m *Manager) SetOccupants(fn func(room string) []Viewer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// Hook sets the hook with the name, replacing any there was, or removing it
+// if fn is nil. Targets are only seen if every hook agrees.
+func (m *Manager) Hook(name string, fn HookFunc) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if fn == nil {
+		delete(m.hooks, name)
+
+		return
+	}
+	m.hooks[name] = fn
+}
+
+// Light returns the light level of the room.
+func (m *Manager) Light(room string) int {
+	r, ok := m.world.Room(room)
+	if !ok {
+		return 0
+	}
+
+	m.mutex.RLock()
+	daylight, day, night, occupants := m.daylight, m.day, m.night, m.occupants
+	m.mutex.RUnlock()
+
+	level := DefaultLight
+	switch {
+	case r.Flag(LitFlag):
+	case r.Flag(DarkFlag):
+		level = 0
+	case !r.Flag(IndoorsFlag) && (daylight == nil || daylight()):
+		level += day
+	case !r.Flag(IndoorsFlag):
+		level += night
+	}
+	for _, it := range m.floor.In(room) {
+		level += Emits(it)
+	}
+	if occupants != nil {
+		for _, o := range occupants(room) {
+			if c, ok := o.(carrier); ok {
+				for _, it := range c.Inventory() {
+					level += Emits(it)
+				}
+			}
+			if w, ok := o.(wearer); ok {
+				for _, it := range w.Equipment() {
+					level += Emits(it)
+				}
+			}
+		}
+	}
+	if level < 0 {
+		level = 0
+	}
+
+	return level
+}
+
+// Emits returns the light the item gives off, zero if it isn't a light
+// source.
+func Emits(it item.Item) int {
+	if it.Type != LightType && !it.Flag(GlowFlag) {
+		return 0
+	}
+	switch light := it.Props[LightProp].(type) {
+	case int:
+		return light
+	case float64:
+		return int(light)
+	}
+
+	return DefaultLight
+}
+
+// CanSee is true if the viewer can see in the room, they aren't blind and
+// it's light enough or they see in the dark.
+func (m *Manager) CanSee(v Viewer, room string) bool {
+	f, flagged := v.(Flagged)
+	if flagged && f.Flag(BlindFlag) {
+		return false
+	}
+	if flagged && f.Flag(DarkvisionFlag) {
+		return true
+	}
+
+	return m.Light(room) >= SeeLight
+}
+
+// Sees is true if the viewer sees the target in their room.
+func (m *Manager) Sees(v Viewer, t Target) bool {
+	if !m.CanSee(v, v.Location()) {
+		return false
+	}
+
+	return m.visible(v, t)
+}
+
+// visible is true if the target isn't hidden from the viewer, no matter the
+// light
+func (m *Manager) visible(v Viewer, t Target) bool {
+	if t.Flag(InvisibleFlag) {
+		if f, ok := v.(Flagged); !ok || !f.Flag(SeeInvisibleFlag) {
+			return false
+		}
+	}
+
+	m.mutex.RLock()
+	names := make([]string, 0, len(m.hooks))
+	for name := range m.hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hooks := make([]HookFunc, len(names))
+	for i, name := range names {
+		hooks[i] = m.hooks[name]
+	}
+	m.mutex.RUnlock()
+
+	for _, fn := range hooks {
+		if !fn(v, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SeesCharacter is true if the viewer sees the character in their room.
+func (m *Manager) SeesCharacter(v, c Viewer) bool {
+	if strings.EqualFold(v.Name(), c.Name()) {
+		return true
+	}
+
+	return m.Sees(v, CharacterTarget(c))
+}
+
+// SeesItem is true if the viewer sees the item in their room.
+func (m *Manager) SeesItem(v Viewer, it item.Item) bool {
+	return m.Sees(v, ItemTarget(it))
+}
+
+// Items returns the items the viewer sees in the list.
+func (m *Manager) Items(v Viewer, items item.List) item.List {
+	if !m.CanSee(v, v.Location()) {
+		return nil
+	}
+	var seen item.List
+	for _, it := range items {
+		if m.visible(v, ItemTarget(it)) {
+			seen = append(seen, it)
+		}
+	}
+
+	return seen
+}
+
+// Others returns who else the viewer sees in the room, which needn't be
+// the one they're in.
+func (m *Manager) Others(v Viewer, room string) []Viewer {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil || !m.CanSee(v, room) {
+		return nil
+	}
+	var seen []Viewer
+	for _, o := range occupants(room) {
+		if !strings.EqualFold(o.Name(), v.Name()) && m.visible(v, CharacterTarget(o)) {
+			seen = append(seen, o)
+		}
+	}
+
+	return seen
+}
+
+// Describe returns what the viewer sees of the room: its description, the
+// items on the floor and who else is there, or that it's too dark to see.
+func (m *Manager) Describe(v Viewer, r world.Room) string {
+	if f, ok := v.(Flagged); ok && f.Flag(BlindFlag) {
+		return BlindMessage
+	}
+	if !m.CanSee(v, r.ID) {
+		return DarkMessage
+	}
+
+	lines := []string{movement.RoomDescription(r)}
+	for _, it := range m.floor.In(r.ID) {
+		if m.visible(v, ItemTarget(it)) {
+			lines = append(lines, fmt.Sprintf("%s is here.", capitalize(it.Describe())))
+		}
+	}
+	for _, o := range m.Others(v, r.ID) {
+		lines = append(lines, fmt.Sprintf("%s is here.", capitalize(o.Name())))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Scan returns who the viewer sees in each room next to theirs, through
+// open exits that aren't hidden, in order of the exits.
+func (m *Manager) Scan(v Viewer) string {
+	if f, ok := v.(Flagged); ok && f.Flag(BlindFlag) {
+		return BlindMessage
+	}
+	r, ok := m.world.Room(v.Location())
+	if !ok {
+		return movement.NowhereMessage
+	}
+
+	var lines []string
+	for _, e := range r.SortedExits(false) {
+		if e.Closed {
+			continue
+		}
+		var names []string
+		for _, o := range m.Others(v, e.To) {
+			names = append(names, o.Name())
+		}
+		if len(names) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", e.Direction, strings.Join(names, ", ")))
+		}
+	}
+	if len(lines) == 0 {
+		return "You don't see anyone nearby."
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// capitalize upper cases the first letter of s
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,13 @@
+package vision_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestVision(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Vision Suite")
+}
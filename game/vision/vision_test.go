@@ -0,0 +1,207 @@
+package vision_test
+
+import (
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/item"
+	. "github.com/bbuck/dragon-mud/game/vision"
+	"github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// looker is a player who remembers what they see
+type looker struct {
+	name      string
+	room      string
+	flags     map[string]bool
+	inventory item.List
+	sent      []string
+}
+
+func (l *looker) ID() string {
+	return strings.ToLower(l.name)
+}
+
+func (l *looker) Name() string {
+	return l.name
+}
+
+func (l *looker) Location() string {
+	return l.room
+}
+
+func (l *looker) Level() command.Level {
+	return command.Player
+}
+
+func (l *looker) Flag(name string) bool {
+	return l.flags[name]
+}
+
+func (l *looker) Inventory() item.List {
+	return l.inventory
+}
+
+func (l *looker) Send(text string) error {
+	l.sent = append(l.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		w               *world.World
+		floor           *item.Floor
+		m               *Manager
+		day             bool
+		ann, bob, ghost *looker
+		torch, sword    item.Item
+	)
+
+	BeforeEach(func() {
+		w = world.New()
+		Ω(w.AddZone(world.Zone{ID: "vale", Name: "The Vale"})).Should(Succeed())
+		for _, r := range []world.Room{
+			{ID: "field", Zone: "vale", Name: "A Field"},
+			{ID: "inn", Zone: "vale", Name: "The Inn", Flags: map[string]bool{IndoorsFlag: true}},
+			{ID: "cave", Zone: "vale", Name: "A Cave", Flags: map[string]bool{DarkFlag: true}},
+			{ID: "square", Zone: "vale", Name: "The Square", Flags: map[string]bool{LitFlag: true}},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		Ω(w.Link("field", world.North, "inn")).Should(Succeed())
+		Ω(w.Link("field", world.East, "cave")).Should(Succeed())
+		Ω(w.Link("field", world.West, "square")).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "torch", Zone: "vale", Name: "a torch", Type: LightType})).Should(Succeed())
+		Ω(w.SetItem(world.ItemDef{ID: "sword", Zone: "vale", Name: "a glowing sword", Type: "weapon", Flags: []string{GlowFlag}, Props: map[string]interface{}{
+			LightProp: 1,
+		}})).Should(Succeed())
+		torch, _ = item.Create(w, "torch", 1)
+		sword, _ = item.Create(w, "sword", 1)
+
+		floor = item.NewFloor()
+		m = NewManager(w, floor)
+		day = true
+		m.SetDaylight(func() bool {
+			return day
+		})
+		ann = &looker{name: "Ann", room: "field"}
+		bob = &looker{name: "Bob", room: "field"}
+		ghost = &looker{name: "Ghost", room: "inn", flags: map[string]bool{InvisibleFlag: true}}
+		m.SetOccupants(func(room string) []Viewer {
+			var viewers []Viewer
+			for _, l := range []*looker{ann, bob, ghost} {
+				if l.room == room {
+					viewers = append(viewers, l)
+				}
+			}
+
+			return viewers
+		})
+	})
+
+	It("lights rooms by their flags and the time of day", func() {
+		Ω(m.Light("field")).Should(Equal(DefaultLight))
+		Ω(m.Light("cave")).Should(Equal(0))
+		Ω(m.Light("nowhere")).Should(Equal(0))
+
+		day = false
+		Ω(m.Light("field")).Should(Equal(0))
+		Ω(m.Light("inn")).Should(Equal(DefaultLight))
+		Ω(m.Light("square")).Should(Equal(DefaultLight))
+
+		m.SetModifiers(1, -1)
+		Ω(m.Light("field")).Should(Equal(1))
+		day = true
+		Ω(m.Light("field")).Should(Equal(3))
+	})
+
+	It("adds the light of light sources carried and on the floor", func() {
+		Ω(Emits(torch)).Should(Equal(DefaultLight))
+		Ω(Emits(sword)).Should(Equal(1))
+
+		bob.room = "cave"
+		Ω(m.CanSee(bob, "cave")).Should(BeFalse())
+		bob.inventory = item.List{torch}
+		Ω(m.Light("cave")).Should(Equal(DefaultLight))
+		Ω(m.CanSee(bob, "cave")).Should(BeTrue())
+
+		floor.Add("cave", sword)
+		Ω(m.Light("cave")).Should(Equal(DefaultLight + 1))
+	})
+
+	It("lets those with darkvision see in the dark, and nobody blind", func() {
+		ann.room = "cave"
+		Ω(m.CanSee(ann, "cave")).Should(BeFalse())
+		ann.flags = map[string]bool{DarkvisionFlag: true}
+		Ω(m.CanSee(ann, "cave")).Should(BeTrue())
+
+		bob.flags = map[string]bool{BlindFlag: true}
+		Ω(m.CanSee(bob, "field")).Should(BeFalse())
+		Ω(m.SeesCharacter(bob, bob)).Should(BeTrue())
+	})
+
+	It("hides the invisible and what hooks hide", func() {
+		ann.room = "inn"
+		Ω(m.SeesCharacter(ann, ghost)).Should(BeFalse())
+		ann.flags = map[string]bool{SeeInvisibleFlag: true}
+		Ω(m.SeesCharacter(ann, ghost)).Should(BeTrue())
+
+		m.Hook("veil", func(v Viewer, t Target) bool {
+			return t.Item || t.ID != "ghost"
+		})
+		Ω(m.SeesCharacter(ann, ghost)).Should(BeFalse())
+		Ω(m.SeesItem(ann, torch)).Should(BeTrue())
+
+		m.Hook("veil", nil)
+		Ω(m.SeesCharacter(ann, ghost)).Should(BeTrue())
+	})
+
+	It("describes what viewers see of rooms", func() {
+		floor.Add("field", torch)
+		r, _ := w.Room("field")
+		Ω(m.Describe(ann, r)).Should(Equal("A Field\n[Exits: north east west]\nA torch is here.\nBob is here."))
+
+		day = false
+		floor.Clear("field")
+		Ω(m.Describe(ann, r)).Should(Equal(DarkMessage))
+		Ω(m.Items(ann, item.List{torch})).Should(BeEmpty())
+
+		ann.flags = map[string]bool{BlindFlag: true}
+		Ω(m.Describe(ann, r)).Should(Equal(BlindMessage))
+	})
+
+	It("scans the rooms around through open exits", func() {
+		bob.room = "square"
+		ann.flags = map[string]bool{SeeInvisibleFlag: true}
+		Ω(m.Scan(ann)).Should(Equal("north: Ghost\nwest: Bob"))
+
+		bob.room = "cave"
+		Ω(m.Scan(ann)).Should(Equal("north: Ghost"))
+
+		Ω(w.UpdateExit("field", world.North, func(e *world.Exit) {
+			e.Door, e.Closed = true, true
+		})).Should(Succeed())
+		Ω(m.Scan(ann)).Should(Equal("You don't see anyone nearby."))
+	})
+
+	It("looks and scans with commands", func() {
+		registry := command.NewRegistry()
+		for _, c := range NewCommands(m, func(command.Caller) Looker {
+			return ann
+		}) {
+			Ω(registry.Register(c)).Should(Succeed())
+		}
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "look")
+		d.Dispatch(ann, "scan")
+		Ω(ann.sent).Should(Equal([]string{
+			"A Field\n[Exits: north east west]\nBob is here.",
+			"You don't see anyone nearby.",
+		}))
+	})
+})
@@ -0,0 +1,34 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package weather
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+)
+
+// Watcher is a player looking at the weather.
+type Watcher interface {
+	Location() string
+	Send(text string) error
+}
+
+// NewCommand creates the weather command, telling the caller the weather
+// where they are.
+func NewCommand(m *Manager, resolve command.Resolver[Watcher]) *command.Command {
+	return &command.Command{
+		Name:   "weather",
+		Help:   "Shows the weather where you are, if you can see the sky.",
+		Source: "game",
+		Handler: func(ctx *command.Context) error {
+			w := resolve(ctx.Caller)
+			if w == nil {
+				return ctx.Send("You aren't playing anyone.")
+			}
+			if desc := m.Describe(w.Location()); desc != "" {
+				return ctx.Send(desc)
+			}
+
+			return ctx.Send("You can't see the sky from here.")
+		},
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package weather
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/clock"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// CancelMessage is told when a handler halts a change of weather without
+// saying why.
+const CancelMessage = "The weather doesn't change."
+
+// Listener is someone told when the weather changes.
+type Listener interface {
+	Send(text string) error
+}
+
+// Manager keeps the weather of every zone, changing it as game hours pass.
+type Manager struct {
+	defs      *Defs
+	world     *world.World
+	clock     *clock.Clock
+	climate   string
+	occupants func(room string) []Listener
+	// states holds the weather of each zone, by id
+	states  map[string]string
+	emitter *events.Emitter
+	mutex   *sync.RWMutex
+}
+
+// NewManager creates a manager giving the zones of the world weather of the
+// climates in the definitions, in the seasons the clock tells.
+func NewManager(defs *Defs, w *world.World, clk *clock.Clock, em *events.Emitter) *Manager {
+	return &Manager{
+		defs:    defs,
+		world:   w,
+		clock:   clk,
+		climate: DefaultClimate.ID,
+		states:  make(map[string]string),
+		emitter: em,
+		mutex:   new(sync.RWMutex),
+	}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager the game uses.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(NewDefs(), world.Global(), clock.Game(), nil)
+	})
+
+	return globalManager
+}
+
+// Defs returns the climates.
+func (m *Manager) Defs() *Defs {
+	return m.defs
+}
+
+// SetEmitter changes the emitter events are emitted with.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// SetOccupants sets how those in a room are found, to tell them of changes
+// in the weather. Without it nobody is told.
+func (m *Manager) SetOccupants(fn func(room string) []Listener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.occupants = fn
+}
+
+// SetDefault changes the climate of zones without one.
+func (m *Manager) SetDefault(id string) error {
+	c, ok := m.defs.Climate(id)
+	if !ok {
+		return fmt.Errorf("there's no climate %q", id)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.climate = c.ID
+
+	return nil
+}
+
+// Climate returns the climate of the zone.
+func (m *Manager) Climate(zone string) Climate {
+	if c, ok := m.defs.ClimateOf(zone); ok {
+		return c
+	}
+
+	m.mutex.RLock()
+	id := m.climate
+	m.mutex.RUnlock()
+
+	if c, ok := m.defs.Climate(id); ok {
+		return c
+	}
+
+	return DefaultClimate
+}
+
+// Current returns the weather of the zone, choosing it if the zone has none
+// yet.
+func (m *Manager) Current(zone string) string {
+	m.mutex.RLock()
+	cond, ok := m.states[zone]
+	m.mutex.RUnlock()
+
+	if ok {
+		return cond
+	}
+
+	cond = m.Climate(zone).Pick(m.season())
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if current, ok := m.states[zone]; ok {
+		return current
+	}
+	m.states[zone] = cond
+
+	return cond
+}
+
+// Outdoors is true if the weather reaches the room.
+func (m *Manager) Outdoors(room string) bool {
+	r, ok := m.world.Room(room)
+
+	return ok && !r.Flag(IndoorsFlag)
+}
+
+// Describe returns how the weather looks from the room, empty if it's
+// indoors.
+func (m *Manager) Describe(room string) string {
+	r, ok := m.world.Room(room)
+	if !ok || r.Flag(IndoorsFlag) {
+		return ""
+	}
+
+	return Descriptions[m.Current(r.Zone)]
+}
+
+// Set changes the weather of the zone, telling the players outdoors in it.
+func (m *Manager) Set(zone, condition string) error {
+	if !Known(condition) {
+		return refuse(fmt.Sprintf("There's no weather %q.", condition))
+	}
+	if _, ok := m.world.Zone(zone); !ok {
+		return refuse(fmt.Sprintf("There's no zone %q.", zone))
+	}
+
+	return m.change(zone, condition)
+}
+
+// Update gives each zone of the world the chance its climate has of
+// changing weather, run as each game hour begins.
+func (m *Manager) Update() {
+	season := m.season()
+	for _, z := range m.world.Zones() {
+		c := m.Climate(z.ID)
+		current := m.Current(z.ID)
+		if random.Intn(100) >= c.Change {
+			continue
+		}
+		if cond := c.Pick(season); cond != current {
+			m.change(z.ID, cond)
+		}
+	}
+}
+
+// Data returns the weather of the zone as event data, with its zone,
+// weather, climate, season and whether it's day.
+func (m *Manager) Data(zone string) events.Data {
+	now := m.clock.Now()
+
+	return events.Data{
+		"zone":    zone,
+		"weather": m.Current(zone),
+		"climate": m.Climate(zone).ID,
+		"season":  now.Season().Name,
+		"is_day":  now.IsDay(),
+	}
+}
+
+// change gives the zone the weather, if handlers allow it
+func (m *Manager) change(zone, condition string) error {
+	data := m.Data(zone)
+	from := data["weather"].(string)
+	if from == condition {
+		return nil
+	}
+	data["from"] = from
+	data["weather"] = condition
+	if err := m.check(ChangeEvent, data); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.states[zone] = condition
+	m.mutex.Unlock()
+
+	m.broadcast(zone, m.Climate(zone).Message(condition))
+	m.confirm(ChangeEvent, data)
+
+	return nil
+}
+
+// broadcast tells those in the zone's rooms that aren't indoors the text
+func (m *Manager) broadcast(zone, text string) {
+	m.mutex.RLock()
+	occupants := m.occupants
+	m.mutex.RUnlock()
+
+	if occupants == nil || text == "" {
+		return
+	}
+	for _, r := range m.world.Rooms(zone) {
+		if r.Flag(IndoorsFlag) {
+			continue
+		}
+		for _, l := range occupants(r.ID) {
+			l.Send(text)
+		}
+	}
+}
+
+// season returns the name of the season it is in the game
+func (m *Manager) season() string {
+	return m.clock.Now().Season().Name
+}
+
+func (m *Manager) check(evt string, data events.Data) error {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter == nil {
+		return nil
+	}
+	if err := emitter.Check(evt, data); err != nil {
+		if err == events.ErrHalt {
+			return refuse(CancelMessage)
+		}
+
+		return refuse(err.Error())
+	}
+
+	return nil
+}
+
+func (m *Manager) confirm(evt string, data events.Data) {
+	m.mutex.RLock()
+	emitter := m.emitter
+	m.mutex.RUnlock()
+
+	if emitter != nil {
+		emitter.Confirm(evt, data)
+	}
+}
+
+func refuse(message string) error {
+	return &item.Refused{Message: message}
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package weather gives every zone weather of its own. Each zone follows a
+// climate weighing how likely each kind of weather is in each season, and
+// every game hour the weather of each zone may change, telling the players
+// outdoors in it.
+package weather
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/random"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ChangeEvent is emitted when the weather of a zone changes, given the zone,
+// the weather it had as from and the weather it has as weather, the zone's
+// climate and the season. Handlers of before:weather:change can stop it.
+const ChangeEvent = "weather:change"
+
+// The kinds of weather.
+const (
+	Clear  = "clear"
+	Cloudy = "cloudy"
+	Fog    = "fog"
+	Rain   = "rain"
+	Storm  = "storm"
+	Snow   = "snow"
+)
+
+// Conditions are every kind of weather, in the order they're weighed.
+var Conditions = []string{Clear, Cloudy, Fog, Rain, Storm, Snow}
+
+// IndoorsFlag is the room flag keeping the weather out, players in the room
+// aren't told of changes and can't see the sky.
+const IndoorsFlag = "indoors"
+
+// Messages are what players outdoors are told when the weather becomes each
+// kind, unless the zone's climate has its own.
+var Messages = map[string]string{
+	Clear:  "The clouds part and the sky clears.",
+	Cloudy: "Clouds gather overhead.",
+	Fog:    "A thick fog rolls in.",
+	Rain:   "It starts to rain.",
+	Storm:  "Thunder rumbles as a storm breaks overhead.",
+	Snow:   "Snow begins to fall.",
+}
+
+// Descriptions are how each kind of weather looks to players outdoors.
+var Descriptions = map[string]string{
+	Clear:  "The sky is clear.",
+	Cloudy: "The sky is overcast.",
+	Fog:    "A thick fog hangs in the air.",
+	Rain:   "It's raining.",
+	Storm:  "A storm rages overhead.",
+	Snow:   "It's snowing.",
+}
+
+// DefaultChange is the percent chance the weather of a zone changes each
+// game hour, for climates not giving one.
+const DefaultChange = 25
+
+// Climate is the weather a zone has through the year.
+type Climate struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name,omitempty"`
+	// Zones are the ids of the zones with the climate.
+	Zones []string `yaml:"zones,omitempty"`
+	// Change is the percent chance each game hour the weather changes.
+	Change int `yaml:"change,omitempty"`
+	// Weights weigh how likely each kind of weather is, in seasons without
+	// weights of their own.
+	Weights map[string]int `yaml:"weights,omitempty"`
+	// Seasons weigh the weather by season name.
+	Seasons map[string]map[string]int `yaml:"seasons,omitempty"`
+	// Messages replace the ones players are told when the weather becomes
+	// each kind.
+	Messages map[string]string `yaml:"messages,omitempty"`
+}
+
+// DefaultClimate is the mild climate zones without one have.
+var DefaultClimate = Climate{
+	ID:      "temperate",
+	Name:    "Temperate",
+	Change:  DefaultChange,
+	Weights: map[string]int{Clear: 4, Cloudy: 3, Fog: 1, Rain: 2, Storm: 1},
+	Seasons: map[string]map[string]int{
+		"winter": {Clear: 3, Cloudy: 3, Fog: 1, Storm: 1, Snow: 3},
+		"summer": {Clear: 6, Cloudy: 2, Rain: 1, Storm: 1},
+	},
+}
+
+// For returns the weights of the weather in the season.
+func (c Climate) For(season string) map[string]int {
+	if w, ok := c.Seasons[season]; ok {
+		return w
+	}
+
+	return c.Weights
+}
+
+// Message returns what players are told when the weather becomes the
+// condition.
+func (c Climate) Message(condition string) string {
+	if msg, ok := c.Messages[condition]; ok {
+		return msg
+	}
+
+	return Messages[condition]
+}
+
+// Pick chooses weather for the season by its weights, Clear if the season
+// has none.
+func (c Climate) Pick(season string) string {
+	weights := c.For(season)
+	total := 0
+	for _, cond := range Conditions {
+		total += weights[cond]
+	}
+	if total <= 0 {
+		return Clear
+	}
+
+	roll := random.Intn(total)
+	for _, cond := range Conditions {
+		if roll < weights[cond] {
+			return cond
+		}
+		roll -= weights[cond]
+	}
+
+	return Clear
+}
+
+// validate checks the climate makes sense
+func (c *Climate) validate() error {
+	c.ID = strings.ToLower(c.ID)
+	if c.ID == "" {
+		return fmt.Errorf("climates need an id")
+	}
+	if c.Name == "" {
+		c.Name = c.ID
+	}
+	if c.Change == 0 {
+		c.Change = DefaultChange
+	}
+	if c.Change < 0 || c.Change > 100 {
+		return fmt.Errorf("climate %q changes %d%% of hours, it must be between 0 and 100", c.ID, c.Change)
+	}
+	if len(c.Weights) == 0 && len(c.Seasons) == 0 {
+		return fmt.Errorf("climate %q weighs no weather", c.ID)
+	}
+	if err := checkWeights(c.ID, c.Weights); err != nil {
+		return err
+	}
+	for _, w := range c.Seasons {
+		if err := checkWeights(c.ID, w); err != nil {
+			return err
+		}
+	}
+	for cond := range c.Messages {
+		if !Known(cond) {
+			return fmt.Errorf("climate %q has a message for %q, weather is %s", c.ID, cond, strings.Join(Conditions, ", "))
+		}
+	}
+
+	return nil
+}
+
+// checkWeights checks the weights are of known weather and not negative
+func checkWeights(id string, weights map[string]int) error {
+	for cond, w := range weights {
+		if !Known(cond) {
+			return fmt.Errorf("climate %q weighs %q, weather is %s", id, cond, strings.Join(Conditions, ", "))
+		}
+		if w < 0 {
+			return fmt.Errorf("climate %q weighs %q below zero", id, cond)
+		}
+	}
+
+	return nil
+}
+
+// Known is true for the kinds of weather in Conditions.
+func Known(condition string) bool {
+	for _, cond := range Conditions {
+		if cond == condition {
+			return true
+		}
+	}
+
+	return false
+}
+
+// File is the layout of a weather file, a list of climates.
+type File struct {
+	Climates []Climate `yaml:"climates"`
+}
+
+// Defs holds the climates and which zones have them.
+type Defs struct {
+	climates map[string]Climate
+	// zones holds the climate id of each zone
+	zones map[string]string
+	mutex *sync.RWMutex
+}
+
+// NewDefs creates definitions with only the DefaultClimate.
+func NewDefs() *Defs {
+	return &Defs{
+		climates: map[string]Climate{DefaultClimate.ID: DefaultClimate},
+		zones:    make(map[string]string),
+		mutex:    new(sync.RWMutex),
+	}
+}
+
+// AddClimate adds the climate, replacing any with its id, and gives it to
+// its zones.
+func (d *Defs) AddClimate(c Climate) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	c.Zones = append([]string(nil), c.Zones...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.climates[c.ID] = c
+	for _, zone := range c.Zones {
+		d.zones[zone] = c.ID
+	}
+
+	return nil
+}
+
+// Climate returns the climate with the id.
+func (d *Defs) Climate(id string) (Climate, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	c, ok := d.climates[strings.ToLower(id)]
+
+	return c, ok
+}
+
+// ClimateOf returns the climate of the zone, if it has one.
+func (d *Defs) ClimateOf(zone string) (Climate, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	c, ok := d.climates[d.zones[zone]]
+
+	return c, ok
+}
+
+// Climates returns every climate, sorted by id.
+func (d *Defs) Climates() []Climate {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	climates := make([]Climate, 0, len(d.climates))
+	for _, c := range d.climates {
+		climates = append(climates, c)
+	}
+	sort.Slice(climates, func(i, j int) bool {
+		return climates[i].ID < climates[j].ID
+	})
+
+	return climates
+}
+
+// LoadDir adds the climates in every .yml and .yaml file in the directory.
+// Missing directories are ignored.
+func (d *Defs) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := d.LoadYAML(contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the climates in the YAML document, like:
+//   climates:
+//     - id: desert
+//       zones: [dunes, oasis]
+//       change: 10
+//       weights: {clear: 8, cloudy: 1}
+//       seasons:
+//         winter: {clear: 5, cloudy: 2, rain: 1}
+//       messages:
+//         cloudy: A hot wind drives clouds of sand across the sky.
+func (d *Defs) LoadYAML(contents []byte) error {
+	var f File
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return err
+	}
+	for _, c := range f.Climates {
+		if err := d.AddClimate(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
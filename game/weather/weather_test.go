@@ -0,0 +1,218 @@
+package weather_test
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/clock"
+	"github.com/bbuck/dragon-mud/game/command"
+	. "github.com/bbuck/dragon-mud/game/weather"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// rolls is a random source always rolling the lowest number for rolls(0)
+type rolls int64
+
+func (r rolls) Int63() int64 {
+	return int64(r) << 32
+}
+
+func (r rolls) Seed(int64) {}
+
+// watcher is a player who remembers what they're told
+type watcher struct {
+	name     string
+	location string
+	sent     []string
+}
+
+func (w *watcher) ID() string {
+	return strings.ToLower(w.name)
+}
+
+func (w *watcher) Name() string {
+	return w.name
+}
+
+func (w *watcher) Location() string {
+	return w.location
+}
+
+func (w *watcher) Level() command.Level {
+	return command.Player
+}
+
+func (w *watcher) Send(text string) error {
+	w.sent = append(w.sent, text)
+
+	return nil
+}
+
+const climates = `
+climates:
+  - id: wet
+    name: Wet
+    zones: [marsh]
+    change: 100
+    weights: {rain: 1}
+    seasons:
+      winter: {snow: 1}
+    messages:
+      snow: Wet snow falls on the marsh.
+`
+
+var _ = Describe("Defs", func() {
+	var defs *Defs
+
+	BeforeEach(func() {
+		defs = NewDefs()
+	})
+
+	It("gives zones the climates naming them", func() {
+		Ω(defs.LoadYAML([]byte(climates))).Should(Succeed())
+
+		c, ok := defs.ClimateOf("marsh")
+		Ω(ok).Should(BeTrue())
+		Ω(c.ID).Should(Equal("wet"))
+		Ω(c.For("winter")).Should(Equal(map[string]int{Snow: 1}))
+		Ω(c.For("summer")).Should(Equal(map[string]int{Rain: 1}))
+		Ω(c.Message(Snow)).Should(Equal("Wet snow falls on the marsh."))
+		Ω(c.Message(Rain)).Should(Equal(Messages[Rain]))
+		_, ok = defs.ClimateOf("town")
+		Ω(ok).Should(BeFalse())
+		Ω(defs.Climates()).Should(HaveLen(2))
+	})
+
+	It("refuses climates that make no sense", func() {
+		Ω(defs.AddClimate(Climate{Weights: map[string]int{Rain: 1}})).ShouldNot(Succeed())
+		Ω(defs.AddClimate(Climate{ID: "x"})).ShouldNot(Succeed())
+		Ω(defs.AddClimate(Climate{ID: "x", Weights: map[string]int{"hail": 1}})).ShouldNot(Succeed())
+		Ω(defs.AddClimate(Climate{ID: "x", Weights: map[string]int{Rain: -1}})).ShouldNot(Succeed())
+		Ω(defs.AddClimate(Climate{ID: "x", Change: 101, Weights: map[string]int{Rain: 1}})).ShouldNot(Succeed())
+		Ω(defs.LoadYAML([]byte("climates:\n  - id: x\n    colour: grey\n"))).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		em       *events.Emitter
+		m        *Manager
+		ann, bob *watcher
+	)
+
+	BeforeEach(func() {
+		random.SetSource(rolls(0))
+		w := world.New()
+		for _, zone := range []string{"town", "marsh"} {
+			Ω(w.AddZone(world.Zone{ID: zone, Name: zone})).Should(Succeed())
+		}
+		for _, r := range []world.Room{
+			{ID: "square", Zone: "town"},
+			{ID: "bog", Zone: "marsh"},
+			{ID: "hut", Zone: "marsh", Flags: map[string]bool{IndoorsFlag: true}},
+		} {
+			Ω(w.AddRoom(r)).Should(Succeed())
+		}
+		defs := NewDefs()
+		Ω(defs.LoadYAML([]byte(climates))).Should(Succeed())
+		epoch := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		clk := clock.New(epoch, time.Hour, nil)
+		clk.SetClock(func() time.Time { return epoch.Add(12 * time.Hour) })
+		em = events.NewEmitter(nil)
+		m = NewManager(defs, w, clk, em)
+		ann = &watcher{name: "Ann", location: "bog"}
+		bob = &watcher{name: "Bob", location: "hut"}
+		m.SetOccupants(func(room string) []Listener {
+			var in []Listener
+			for _, p := range []*watcher{ann, bob} {
+				if p.location == room {
+					in = append(in, p)
+				}
+			}
+
+			return in
+		})
+	})
+
+	AfterEach(func() {
+		random.SetSource(rand.NewSource(time.Now().UnixNano()))
+	})
+
+	It("chooses weather by the climate and season of the zone", func() {
+		Ω(m.Current("marsh")).Should(Equal(Snow))
+		Ω(m.Current("town")).Should(Equal(Clear))
+		Ω(m.Climate("town").ID).Should(Equal(DefaultClimate.ID))
+		Ω(m.SetDefault("dry")).ShouldNot(Succeed())
+		Ω(m.SetDefault("wet")).Should(Succeed())
+		Ω(m.Climate("town").ID).Should(Equal("wet"))
+
+		Ω(m.Data("marsh")).Should(Equal(events.Data{
+			"zone":    "marsh",
+			"weather": Snow,
+			"climate": "wet",
+			"season":  "winter",
+			"is_day":  true,
+		}))
+	})
+
+	It("tells those outdoors when the weather changes", func() {
+		received := make(chan events.Data, 1)
+		em.On(ChangeEvent, events.HandlerFunc(func(d events.Data) error {
+			received <- d
+
+			return nil
+		}))
+
+		Ω(m.Set("marsh", "hail")).Should(MatchError("There's no weather \"hail\"."))
+		Ω(m.Set("moon", Fog)).Should(MatchError("There's no zone \"moon\"."))
+		Ω(m.Set("marsh", Fog)).Should(Succeed())
+		Ω(m.Current("marsh")).Should(Equal(Fog))
+		Ω(ann.sent).Should(Equal([]string{Messages[Fog]}))
+		Ω(bob.sent).Should(BeEmpty())
+		Eventually(received).Should(Receive(And(
+			HaveKeyWithValue("from", Snow),
+			HaveKeyWithValue("weather", Fog),
+		)))
+
+		Ω(m.Describe("bog")).Should(Equal(Descriptions[Fog]))
+		Ω(m.Describe("hut")).Should(BeEmpty())
+		Ω(m.Outdoors("hut")).Should(BeFalse())
+	})
+
+	It("lets handlers stop the weather changing", func() {
+		em.On("before:"+ChangeEvent, events.HandlerFunc(func(d events.Data) error {
+			return events.ErrHalt
+		}))
+
+		Ω(m.Set("marsh", Fog)).Should(MatchError(CancelMessage))
+		Ω(m.Current("marsh")).Should(Equal(Snow))
+	})
+
+	It("changes the weather as hours pass", func() {
+		m.Set("marsh", Fog)
+		m.Update()
+
+		Ω(m.Current("marsh")).Should(Equal(Snow))
+		Ω(m.Current("town")).Should(Equal(Clear))
+		Ω(ann.sent).Should(ContainElement("Wet snow falls on the marsh."))
+	})
+
+	It("runs the weather command", func() {
+		registry := command.NewRegistry()
+		Ω(registry.Register(NewCommand(m, func(c command.Caller) Watcher {
+			return c.(*watcher)
+		}))).Should(Succeed())
+		d := command.NewDispatcher(registry, nil)
+
+		d.Dispatch(ann, "weather")
+		d.Dispatch(bob, "weather")
+		Ω(ann.sent).Should(Equal([]string{Descriptions[Snow]}))
+		Ω(bob.sent).Should(Equal([]string{"You can't see the sky from here."}))
+	})
+})
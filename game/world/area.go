@@ -0,0 +1,612 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/bbuck/dragon-mud/sched"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Area is the contents of an area file, a zone with its rooms and the NPCs
+// and items found in it. Area files are YAML:
+//   zone:
+//     id: town
+//     name: The Town
+//     builders: [Ann]
+//   rooms:
+//     - id: square
+//       name: Town Square
+//       description: A busy square.
+//       flags: [outdoors]
+//       exits:
+//         north: gate
+//         east:
+//           to: shop
+//           door: true
+//           closed: true
+//       triggers:
+//         - on: enter
+//           script: town/fountain.lua
+//   npcs:
+//     - id: guard
+//       name: a town guard
+//       keywords: [guard]
+//       stats: {hp: 20}
+//       triggers:
+//         - on: speech
+//           keywords: [job, work]
+//           script: town/guard-job.lua
+//         - on: timer
+//           every: 5m
+//           script: town/guard-yawn.lua
+//   items:
+//     - id: torch
+//       name: a torch
+//       type: light
+//   resets:
+//     - npc: guard
+//       room: gate
+//       give: [torch]
+//     - script: relight-lamps
+//
+// Exits are keyed by direction, which may be abbreviated, and are either the
+// id of the room they lead to or a table with the fields of an Exit. Rooms,
+// NPCs and items may have triggers, scripts run when things happen around
+// them.
+type Area struct {
+	Zone   Zone
+	Rooms  []Room
+	NPCs   []NPCDef
+	Items  []ItemDef
+	Resets []Reset
+}
+
+// AreaError is a problem with an area file. Path locates it in the file, like
+// "rooms[2] (square).exits.north".
+type AreaError struct {
+	File    string
+	Path    string
+	Message string
+}
+
+// Error returns the location and message of the problem.
+func (e *AreaError) Error() string {
+	loc := e.File
+	if e.Path != "" {
+		if loc != "" {
+			loc += ": "
+		}
+		loc += e.Path
+	}
+	if loc == "" {
+		return e.Message
+	}
+
+	return loc + ": " + e.Message
+}
+
+// AreaErrors are all the problems found loading area files.
+type AreaErrors []*AreaError
+
+// Error returns each problem on its own line.
+func (es AreaErrors) Error() string {
+	lines := make([]string, len(es))
+	for i, e := range es {
+		lines[i] = e.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// areaFile is the layout of an area file
+type areaFile struct {
+	Zone   Zone       `yaml:"zone"`
+	Rooms  []areaRoom `yaml:"rooms,omitempty"`
+	NPCs   []NPCDef   `yaml:"npcs,omitempty"`
+	Items  []ItemDef  `yaml:"items,omitempty"`
+	Resets []Reset    `yaml:"resets,omitempty"`
+}
+
+type areaRoom struct {
+	ID          string    `yaml:"id"`
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description,omitempty"`
+	Flags       []string  `yaml:"flags,omitempty"`
+	Exits       exitList  `yaml:"exits,omitempty"`
+	Triggers    []Trigger `yaml:"triggers,omitempty"`
+}
+
+type areaExit struct {
+	direction   string
+	To          string `yaml:"to"`
+	Description string `yaml:"description,omitempty"`
+	Door        bool   `yaml:"door,omitempty"`
+	Closed      bool   `yaml:"closed,omitempty"`
+	Locked      bool   `yaml:"locked,omitempty"`
+	Key         string `yaml:"key,omitempty"`
+	Pickproof   bool   `yaml:"pickproof,omitempty"`
+	Bashproof   bool   `yaml:"bashproof,omitempty"`
+	Hidden      bool   `yaml:"hidden,omitempty"`
+	Size        int    `yaml:"size,omitempty"`
+}
+
+// plainExit has the fields of an areaExit without its YAML methods
+type plainExit areaExit
+
+// UnmarshalYAML reads either the id of a room or a full exit.
+func (e *areaExit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var to string
+	if err := unmarshal(&to); err == nil {
+		e.To = to
+
+		return nil
+	}
+
+	return unmarshal((*plainExit)(e))
+}
+
+// MarshalYAML writes exits with nothing but a destination as its id.
+func (e areaExit) MarshalYAML() (interface{}, error) {
+	if (e == areaExit{direction: e.direction, To: e.To}) {
+		return e.To, nil
+	}
+
+	return plainExit(e), nil
+}
+
+// exitList keeps exits in the order they're written
+type exitList []areaExit
+
+// UnmarshalYAML reads the exits keyed by direction.
+func (l *exitList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var order yaml.MapSlice
+	if err := unmarshal(&order); err != nil {
+		return err
+	}
+	var exits map[interface{}]areaExit
+	if err := unmarshal(&exits); err != nil {
+		return err
+	}
+	for _, item := range order {
+		e := exits[item.Key]
+		// YAML reads a bare n, the abbreviation of north, as false
+		if item.Key == false {
+			e.direction = "n"
+		} else {
+			e.direction = fmt.Sprint(item.Key)
+		}
+		*l = append(*l, e)
+	}
+
+	return nil
+}
+
+// MarshalYAML writes the exits keyed by direction, in order.
+func (l exitList) MarshalYAML() (interface{}, error) {
+	items := make(yaml.MapSlice, len(l))
+	for i, e := range l {
+		items[i] = yaml.MapItem{Key: e.direction, Value: e}
+	}
+
+	return items, nil
+}
+
+// ParseArea reads an area from YAML, checking it's valid. The file name is
+// kept as the zone's file and used in errors, which are AreaErrors listing
+// every problem found.
+func ParseArea(file string, contents []byte) (*Area, error) {
+	var f areaFile
+	if err := yaml.UnmarshalStrict(contents, &f); err != nil {
+		return nil, yamlErrors(file, err)
+	}
+	a, errs := f.area(file)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	a.Zone.File = file
+
+	return a, nil
+}
+
+// ReadArea reads and parses the area file.
+func ReadArea(path string) (*Area, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseArea(path, contents)
+}
+
+// MarshalArea writes the area as YAML.
+func MarshalArea(a *Area) ([]byte, error) {
+	f := areaFile{
+		Zone:   a.Zone,
+		NPCs:   a.NPCs,
+		Items:  a.Items,
+		Resets: a.Resets,
+	}
+	for _, r := range a.Rooms {
+		room := areaRoom{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			Triggers:    r.Triggers,
+		}
+		room.Flags = sortedFlags(r.Flags)
+		for _, e := range r.SortedExits(true) {
+			room.Exits = append(room.Exits, areaExit{
+				direction:   string(e.Direction),
+				To:          e.To,
+				Description: e.Description,
+				Door:        e.Door,
+				Closed:      e.Closed,
+				Locked:      e.Locked,
+				Key:         e.Key,
+				Pickproof:   e.Pickproof,
+				Bashproof:   e.Bashproof,
+				Hidden:      e.Hidden,
+				Size:        e.Size,
+			})
+		}
+		f.Rooms = append(f.Rooms, room)
+	}
+
+	return yaml.Marshal(f)
+}
+
+// WriteArea saves the area to the file, through a temporary file so a crash
+// never leaves a partially written area behind.
+func WriteArea(path string, a *Area) error {
+	contents, err := MarshalArea(a)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return fs.WriteFile(path, contents, 0644)
+}
+
+// LoadArea adds the area's zone along with its rooms, NPCs and items. A zone
+// already in the world is replaced, keeping the exits other zones have into
+// it.
+func (w *World) LoadArea(a *Area) error {
+	id := a.Zone.ID
+	if id == "" {
+		return ErrNoID
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for i, r := range a.Rooms {
+		if old, ok := w.rooms[r.ID]; ok && old.Zone != id {
+			return &AreaError{File: a.Zone.File, Path: roomPath(i, r.ID), Message: fmt.Sprintf("id is already used by a room in zone %q", old.Zone)}
+		}
+	}
+	for i, def := range a.NPCs {
+		if old, ok := w.npcs[def.ID]; ok && old.Zone != id {
+			return &AreaError{File: a.Zone.File, Path: fmt.Sprintf("npcs[%d]", i), Message: fmt.Sprintf("id %q is already used by an NPC in zone %q", def.ID, old.Zone)}
+		}
+	}
+	for i, def := range a.Items {
+		if old, ok := w.items[def.ID]; ok && old.Zone != id {
+			return &AreaError{File: a.Zone.File, Path: fmt.Sprintf("items[%d]", i), Message: fmt.Sprintf("id %q is already used by an item in zone %q", def.ID, old.Zone)}
+		}
+	}
+
+	for room := range w.zoneRooms[id] {
+		delete(w.rooms, room)
+	}
+	w.removeDefs(id)
+
+	w.zones[id] = a.Zone
+	rooms := make(map[string]bool, len(a.Rooms))
+	for _, r := range a.Rooms {
+		r.Zone = id
+		w.rooms[r.ID] = r.copy()
+		rooms[r.ID] = true
+	}
+	w.zoneRooms[id] = rooms
+	for _, def := range a.NPCs {
+		def.Zone = id
+		w.npcs[def.ID] = def
+	}
+	for _, def := range a.Items {
+		def.Zone = id
+		w.items[def.ID] = def
+	}
+	w.setResets(id, a.Resets)
+
+	return nil
+}
+
+// ExportArea returns the zone with everything in it, for saving.
+func (w *World) ExportArea(zone string) (*Area, error) {
+	z, ok := w.Zone(zone)
+	if !ok {
+		return nil, ErrNoZone
+	}
+
+	return &Area{
+		Zone:   z,
+		Rooms:  w.Rooms(zone),
+		NPCs:   w.NPCs(zone),
+		Items:  w.Items(zone),
+		Resets: w.Resets(zone),
+	}, nil
+}
+
+// SaveZone writes the zone back to the file it was loaded from, zones that
+// weren't loaded from a file are saved to <id>.yml in dir.
+func (w *World) SaveZone(zone, dir string) error {
+	a, err := w.ExportArea(zone)
+	if err != nil {
+		return err
+	}
+	if a.Zone.File == "" {
+		a.Zone.File = filepath.Join(dir, zone+".yml")
+		w.UpdateZone(a.Zone)
+	}
+
+	return WriteArea(a.Zone.File, a)
+}
+
+// LoadDir loads every .yml and .yaml file in the directory, then checks every
+// exit leads to a room that exists. Files with problems are skipped and the
+// rest are loaded, the problems are returned together as AreaErrors. Missing
+// directories are ignored.
+func (w *World) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var (
+		areas []*Area
+		errs  AreaErrors
+	)
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		a, err := ReadArea(filepath.Join(dir, fi.Name()))
+		if err == nil {
+			err = w.LoadArea(a)
+		}
+		switch e := err.(type) {
+		case nil:
+			areas = append(areas, a)
+		case AreaErrors:
+			errs = append(errs, e...)
+		case *AreaError:
+			errs = append(errs, e)
+		default:
+			return err
+		}
+	}
+
+	for _, a := range areas {
+		for i, r := range a.Rooms {
+			for _, e := range r.SortedExits(true) {
+				if _, ok := w.Room(e.To); !ok {
+					errs = append(errs, &AreaError{
+						File:    a.Zone.File,
+						Path:    roomPath(i, r.ID) + ".exits." + string(e.Direction),
+						Message: fmt.Sprintf("leads to unknown room %q", e.To),
+					})
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// area checks the file and converts it into an Area
+func (f *areaFile) area(file string) (*Area, AreaErrors) {
+	var errs AreaErrors
+	fail := func(path, format string, args ...interface{}) {
+		errs = append(errs, &AreaError{File: file, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	a := &Area{Zone: f.Zone}
+	if msg := checkID(f.Zone.ID); msg != "" {
+		fail("zone.id", "%s", msg)
+	}
+	if f.Zone.Reset != "" {
+		if _, err := sched.Parse(f.Zone.Reset); err != nil {
+			fail("zone.reset", "%s", err)
+		}
+	}
+	if f.Zone.Population < 0 {
+		fail("zone.population", "population can't be negative")
+	}
+
+	ids := make(map[string]bool)
+	for i, fr := range f.Rooms {
+		path := roomPath(i, fr.ID)
+		if msg := checkID(fr.ID); msg != "" {
+			fail(path+".id", "%s", msg)
+		} else if ids[fr.ID] {
+			fail(path+".id", "another room has the id %q", fr.ID)
+		}
+		ids[fr.ID] = true
+		if fr.Name == "" {
+			fail(path+".name", "a name is required")
+		}
+
+		r := Room{
+			ID:          fr.ID,
+			Zone:        f.Zone.ID,
+			Name:        fr.Name,
+			Description: fr.Description,
+			Flags:       make(map[string]bool, len(fr.Flags)),
+			Exits:       make(map[Direction]Exit, len(fr.Exits)),
+			Triggers:    fr.Triggers,
+		}
+		for _, flag := range fr.Flags {
+			r.Flags[strings.ToLower(flag)] = true
+		}
+		for _, fe := range fr.Exits {
+			d := ParseDirection(fe.direction)
+			epath := path + ".exits." + fe.direction
+			switch {
+			case d == "":
+				fail(path+".exits", "exits need a direction")
+			case r.Exits[d].Direction != "":
+				fail(epath, "there's already an exit %s", d)
+			case fe.To == "":
+				fail(epath, "the room it leads to is required")
+			case !fe.Door && (fe.Closed || fe.Locked || fe.Key != "" || fe.Pickproof || fe.Bashproof):
+				fail(epath, "only doors can be closed, locked or have a key")
+			case fe.Locked && !fe.Closed:
+				fail(epath, "locked doors must be closed")
+			case fe.Size < 0:
+				fail(epath, "size can't be negative")
+			}
+			r.Exits[d] = Exit{
+				Direction:   d,
+				To:          fe.To,
+				Description: fe.Description,
+				Door:        fe.Door,
+				Closed:      fe.Closed,
+				Locked:      fe.Locked,
+				Key:         fe.Key,
+				Pickproof:   fe.Pickproof,
+				Bashproof:   fe.Bashproof,
+				Hidden:      fe.Hidden,
+				Size:        fe.Size,
+			}
+		}
+		checkTriggers(path, fr.Triggers, fail)
+		a.Rooms = append(a.Rooms, r)
+	}
+
+	ids = make(map[string]bool)
+	for i, def := range f.NPCs {
+		path := fmt.Sprintf("npcs[%d]", i)
+		if msg := checkID(def.ID); msg != "" {
+			fail(path+".id", "%s", msg)
+		} else if ids[def.ID] {
+			fail(path+".id", "another NPC has the id %q", def.ID)
+		}
+		ids[def.ID] = true
+		if def.Name == "" {
+			fail(path+".name", "a name is required")
+		}
+		checkTriggers(path, def.Triggers, fail)
+		def.Zone = f.Zone.ID
+		a.NPCs = append(a.NPCs, def)
+	}
+
+	ids = make(map[string]bool)
+	for i, def := range f.Items {
+		path := fmt.Sprintf("items[%d]", i)
+		if msg := checkID(def.ID); msg != "" {
+			fail(path+".id", "%s", msg)
+		} else if ids[def.ID] {
+			fail(path+".id", "another item has the id %q", def.ID)
+		}
+		ids[def.ID] = true
+		if def.Name == "" {
+			fail(path+".name", "a name is required")
+		}
+		checkTriggers(path, def.Triggers, fail)
+		def.Zone = f.Zone.ID
+		a.Items = append(a.Items, def)
+	}
+
+	for i, r := range f.Resets {
+		if msg := r.check(); msg != "" {
+			fail(fmt.Sprintf("resets[%d]", i), "%s", msg)
+		}
+		if r.Exit != "" {
+			r.Exit = ParseDirection(string(r.Exit))
+		}
+		a.Resets = append(a.Resets, r)
+	}
+
+	return a, errs
+}
+
+func roomPath(i int, id string) string {
+	if id == "" {
+		return fmt.Sprintf("rooms[%d]", i)
+	}
+
+	return fmt.Sprintf("rooms[%d] (%s)", i, id)
+}
+
+// checkTriggers fails each trigger that's invalid
+func checkTriggers(path string, triggers []Trigger, fail func(path, format string, args ...interface{})) {
+	for i, t := range triggers {
+		if msg := t.check(); msg != "" {
+			fail(fmt.Sprintf("%s.triggers[%d]", path, i), "%s", msg)
+		}
+	}
+}
+
+// checkID returns why the id can't be used, or an empty string if it can
+func checkID(id string) string {
+	if id == "" {
+		return "an id is required"
+	}
+	if strings.ContainsAny(id, " \t\r\n") {
+		return fmt.Sprintf("the id %q can't contain spaces", id)
+	}
+
+	return ""
+}
+
+// typeName matches the Go type in YAML errors, which means nothing to
+// builders
+var typeName = regexp.MustCompile(` in type [\w.]+`)
+
+// yamlErrors converts the errors reading YAML into AreaErrors, keeping the
+// line numbers yaml gives
+func yamlErrors(file string, err error) AreaErrors {
+	var messages []string
+	if te, ok := err.(*yaml.TypeError); ok {
+		messages = te.Errors
+	} else {
+		messages = []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+
+	errs := make(AreaErrors, len(messages))
+	for i, msg := range messages {
+		errs[i] = &AreaError{File: file, Message: typeName.ReplaceAllString(msg, "")}
+	}
+
+	return errs
+}
+
+func sortedFlags(flags map[string]bool) []string {
+	var names []string
+	for name, on := range flags {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
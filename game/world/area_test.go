@@ -0,0 +1,257 @@
+package world_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const townArea = `
+zone:
+  id: town
+  name: The Town
+  reset: "@every 10m"
+  population: 5
+rooms:
+  - id: square
+    name: Town Square
+    description: A busy square.
+    flags: [Outdoors]
+    exits:
+      n: gate
+      east:
+        to: shop
+        door: true
+        closed: true
+        locked: true
+        key: shop-key
+    triggers:
+      - on: enter
+        script: fountain.lua
+  - id: gate
+    name: North Gate
+    exits:
+      south: square
+  - id: shop
+    name: The Shop
+    exits:
+      west: square
+      up:
+        to: gate
+        size: 1
+npcs:
+  - id: guard
+    name: a town guard
+    keywords: [guard]
+    stats: {hp: 20}
+    triggers:
+      - on: speech
+        keywords: [job]
+        script: guard.lua
+items:
+  - id: shop-key
+    name: a brass key
+    type: key
+`
+
+var _ = Describe("Area", func() {
+	It("parses area files", func() {
+		a, err := ParseArea("town.yml", []byte(townArea))
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(a.Zone.Name).Should(Equal("The Town"))
+		Ω(a.Zone.Reset).Should(Equal("@every 10m"))
+		Ω(a.Zone.Population).Should(Equal(5))
+		Ω(a.Rooms).Should(HaveLen(3))
+		Ω(a.Rooms[0].Flag("outdoors")).Should(BeTrue())
+		Ω(a.Rooms[0].Exits[North].To).Should(Equal("gate"))
+		Ω(a.Rooms[0].Exits[East]).Should(Equal(Exit{Direction: East, To: "shop", Door: true, Closed: true, Locked: true, Key: "shop-key"}))
+		Ω(a.Rooms[2].Exits[Up].Size).Should(Equal(1))
+		Ω(a.NPCs[0].Stats).Should(Equal(map[string]int{"hp": 20}))
+		Ω(a.NPCs[0].Zone).Should(Equal("town"))
+		Ω(a.Rooms[0].Triggers).Should(Equal([]Trigger{{On: TriggerEnter, Script: "fountain.lua"}}))
+		Ω(a.NPCs[0].Triggers[0].Keywords).Should(Equal([]string{"job"}))
+		Ω(a.Items[0].Type).Should(Equal("key"))
+	})
+
+	It("locates problems in the file", func() {
+		_, err := ParseArea("town.yml", []byte(`
+zone:
+  id: town
+rooms:
+  - id: square
+    exits:
+      north:
+        locked: true
+  - id: square
+    name: Again
+`))
+
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(Equal(`town.yml: rooms[0] (square).name: a name is required
+town.yml: rooms[0] (square).exits.north: the room it leads to is required
+town.yml: rooms[1] (square).id: another room has the id "square"`))
+	})
+
+	It("checks resets", func() {
+		_, err := ParseArea("town.yml", []byte(`
+zone: {id: town, reset: "sometimes", population: -1}
+resets:
+  - {npc: guard, room: gate, give: [torch]}
+  - {npc: guard, item: torch, room: gate}
+  - {item: torch, give: [torch], room: gate}
+  - {exit: n, door: ajar, room: gate}
+`))
+
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(HavePrefix(`town.yml: zone.reset: `))
+		Ω(err.Error()).Should(ContainSubstring("\ntown.yml: zone.population: population can't be negative\ntown.yml: resets[1]"))
+		Ω(err.Error()).Should(HaveSuffix(`town.yml: resets[1]: a reset needs exactly one of npc, item, exit or script
+town.yml: resets[2]: only NPCs can be given or equipped with items
+town.yml: resets[3]: door must be open, closed or locked`))
+	})
+
+	It("checks triggers", func() {
+		_, err := ParseArea("town.yml", []byte(`
+zone: {id: town}
+rooms:
+  - id: square
+    name: Town Square
+    triggers:
+      - {on: sneeze, script: achoo.lua}
+      - {on: timer, every: often, script: bell.lua}
+npcs:
+  - id: guard
+    name: a town guard
+    triggers:
+      - {on: death, keywords: [help], script: cry.lua}
+      - {on: enter}
+`))
+
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(Equal(`town.yml: rooms[0] (square).triggers[0]: triggers run on one of [enter leave speech give death timer], not "sneeze"
+town.yml: rooms[0] (square).triggers[1]: timer triggers need every to be a duration like 5m, not "often"
+town.yml: npcs[0].triggers[0]: only speech triggers have keywords
+town.yml: npcs[0].triggers[1]: the script is required`))
+	})
+
+	It("reports unknown fields with their lines", func() {
+		_, err := ParseArea("town.yml", []byte("zone:\n  id: town\n  colour: red\n"))
+
+		Ω(err).Should(BeAssignableToTypeOf(AreaErrors{}))
+		Ω(err.Error()).Should(Equal("town.yml: line 3: field colour not found"))
+	})
+
+	It("writes areas back out", func() {
+		a, err := ParseArea("town.yml", []byte(townArea))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		contents, err := MarshalArea(a)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(contents)).Should(ContainSubstring("    north: gate\n"))
+
+		again, err := ParseArea("town.yml", contents)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(again).Should(Equal(a))
+	})
+
+	Context("in the world", func() {
+		var (
+			w   *World
+			dir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "areas")
+			Ω(err).ShouldNot(HaveOccurred())
+			w = New()
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("loads a directory of areas", func() {
+			ioutil.WriteFile(filepath.Join(dir, "town.yml"), []byte(townArea), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "road.yaml"), []byte(`
+zone: {id: road, name: The Road}
+rooms:
+  - id: road
+    name: A Road
+    exits: {south: gate, north: nowhere}
+`), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "broken.yml"), []byte("zone: {}\n"), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an area"), 0644)
+
+			err := w.LoadDir(dir)
+			Ω(err).Should(BeAssignableToTypeOf(AreaErrors{}))
+			Ω(err.(AreaErrors)).Should(HaveLen(2))
+			Ω(err.Error()).Should(ContainSubstring("broken.yml: zone.id: an id is required"))
+			Ω(err.Error()).Should(ContainSubstring(`road.yaml: rooms[0] (road).exits.north: leads to unknown room "nowhere"`))
+
+			Ω(w.Zones()).Should(HaveLen(2))
+			_, ok := w.Room("road")
+			Ω(ok).Should(BeTrue())
+			def, ok := w.NPC("guard")
+			Ω(ok).Should(BeTrue())
+			Ω(def.Name).Should(Equal("a town guard"))
+		})
+
+		It("reloads zones keeping exits into them", func() {
+			a, _ := ParseArea("town.yml", []byte(townArea))
+			Ω(w.LoadArea(a)).Should(Succeed())
+			Ω(w.AddZone(Zone{ID: "road"})).Should(Succeed())
+			Ω(w.AddRoom(Room{ID: "road", Zone: "road"})).Should(Succeed())
+			Ω(w.Link("road", South, "gate")).Should(Succeed())
+
+			a.Rooms = a.Rooms[1:]
+			Ω(w.LoadArea(a)).Should(Succeed())
+			_, ok := w.Room("square")
+			Ω(ok).Should(BeFalse())
+			Ω(w.Rooms("town")).Should(HaveLen(2))
+			_, err := w.Exit("road", South)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("won't let zones share ids", func() {
+			a, _ := ParseArea("town.yml", []byte(townArea))
+			Ω(w.LoadArea(a)).Should(Succeed())
+
+			a.Zone.ID = "other"
+			err := w.LoadArea(a)
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(Equal(`town.yml: rooms[0] (square): id is already used by a room in zone "town"`))
+		})
+
+		It("saves builder changes back to the zone's file", func() {
+			path := filepath.Join(dir, "town.yml")
+			ioutil.WriteFile(path, []byte(townArea), 0644)
+			Ω(w.LoadDir(dir)).Should(Succeed())
+			Ω(w.AddRoom(Room{ID: "well", Zone: "town", Name: "The Well"})).Should(Succeed())
+			Ω(w.Link("square", West, "well")).Should(Succeed())
+
+			Ω(w.SaveZone("town", dir)).Should(Succeed())
+			a, err := ReadArea(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(a.Rooms).Should(HaveLen(4))
+			Ω(a.Rooms[2].ID).Should(Equal("square"))
+			Ω(a.Rooms[2].Exits[West].To).Should(Equal("well"))
+		})
+
+		It("saves new zones to the directory", func() {
+			Ω(w.AddZone(Zone{ID: "caves", Name: "The Caves"})).Should(Succeed())
+
+			Ω(w.SaveZone("caves", dir)).Should(Succeed())
+			z, _ := w.Zone("caves")
+			Ω(z.File).Should(Equal(filepath.Join(dir, "caves.yml")))
+			_, err := os.Stat(z.File)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+import "sort"
+
+// NPCDef describes a kind of non player character, NPCs placed in the world
+// are made from it.
+type NPCDef struct {
+	ID   string `yaml:"id"`
+	Zone string `yaml:"-"`
+	Name string `yaml:"name"`
+	// Keywords are the words players use to refer to the NPC, like "guard".
+	Keywords    []string       `yaml:"keywords,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Level       int            `yaml:"level,omitempty"`
+	Stats       map[string]int `yaml:"stats,omitempty"`
+	Flags       []string       `yaml:"flags,omitempty"`
+	// Props hold anything else about the NPC, for the systems and scripts
+	// that use it.
+	Props    map[string]interface{} `yaml:"props,omitempty"`
+	Triggers []Trigger              `yaml:"triggers,omitempty"`
+}
+
+// ItemDef describes a kind of item, items in the game are made from it.
+type ItemDef struct {
+	ID          string   `yaml:"id"`
+	Zone        string   `yaml:"-"`
+	Name        string   `yaml:"name"`
+	Keywords    []string `yaml:"keywords,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	// Type is what the item is, like "weapon" or "container".
+	Type   string   `yaml:"type,omitempty"`
+	Weight int      `yaml:"weight,omitempty"`
+	Value  int      `yaml:"value,omitempty"`
+	Flags  []string `yaml:"flags,omitempty"`
+	// Props hold anything else about the item, like a weapon's damage.
+	Props    map[string]interface{} `yaml:"props,omitempty"`
+	Triggers []Trigger              `yaml:"triggers,omitempty"`
+}
+
+// SetNPC adds the NPC definition to its zone, replacing any with the same id.
+func (w *World) SetNPC(def NPCDef) error {
+	if def.ID == "" {
+		return ErrNoID
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.zones[def.Zone]; !ok {
+		return ErrNoZone
+	}
+	w.npcs[def.ID] = def
+
+	return nil
+}
+
+// NPC returns the NPC definition with the id.
+func (w *World) NPC(id string) (NPCDef, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	def, ok := w.npcs[id]
+
+	return def, ok
+}
+
+// NPCs returns the NPC definitions of the zone, sorted by id.
+func (w *World) NPCs(zone string) []NPCDef {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var defs []NPCDef
+	for _, def := range w.npcs {
+		if def.Zone == zone {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// SetItem adds the item definition to its zone, replacing any with the same
+// id.
+func (w *World) SetItem(def ItemDef) error {
+	if def.ID == "" {
+		return ErrNoID
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.zones[def.Zone]; !ok {
+		return ErrNoZone
+	}
+	w.items[def.ID] = def
+
+	return nil
+}
+
+// Item returns the item definition with the id.
+func (w *World) Item(id string) (ItemDef, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	def, ok := w.items[id]
+
+	return def, ok
+}
+
+// Items returns the item definitions of the zone, sorted by id.
+func (w *World) Items(zone string) []ItemDef {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var defs []ItemDef
+	for _, def := range w.items {
+		if def.Zone == zone {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+
+	return defs
+}
+
+// removeDefs removes the NPC and item definitions and the resets of the zone
+func (w *World) removeDefs(zone string) {
+	delete(w.resets, zone)
+	for id, def := range w.npcs {
+		if def.Zone == zone {
+			delete(w.npcs, id)
+		}
+	}
+	for id, def := range w.items {
+		if def.Zone == zone {
+			delete(w.items, id)
+		}
+	}
+}
@@ -0,0 +1,750 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package diku imports the .are area files of Diku derived MUDs, in the
+// formats used by ROM 2.4 and by Merc, into the world's native areas. Rooms,
+// mobiles, objects and resets are converted, anything else in the file (like
+// shops, specials and helps) is skipped with a warning so operators know
+// what's left to port by hand.
+package diku
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/world"
+)
+
+// Options control how areas are imported.
+type Options struct {
+	// Zone is the id of the imported zone, the name of the file without its
+	// extension is used if it's empty.
+	Zone string
+	// Prefix is put before the vnums of rooms, mobiles and objects to make
+	// their ids, areas imported together need the same prefix for their
+	// exits to join up.
+	Prefix string
+}
+
+// ImportFile reads and imports the area file.
+func ImportFile(path string, opts Options) (*world.Area, []string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return Import(path, contents, opts)
+}
+
+// Import converts the contents of an area file, returning warnings about
+// anything that couldn't be converted. The file name is used for the zone's
+// id and in errors.
+func Import(file string, contents []byte, opts Options) (*world.Area, []string, error) {
+	if opts.Zone == "" {
+		base := filepath.Base(file)
+		opts.Zone = strings.Replace(strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base))), " ", "-", -1)
+	}
+	im := &importer{
+		s:     newScanner(file, contents),
+		opts:  opts,
+		area:  &world.Area{Zone: world.Zone{ID: opts.Zone, Name: opts.Zone}},
+		items: make(map[string]int),
+	}
+	if err := im.run(); err != nil {
+		return nil, im.warnings, err
+	}
+
+	return im.area, im.warnings, nil
+}
+
+type importer struct {
+	s        *scanner
+	opts     Options
+	area     *world.Area
+	warnings []string
+	// lastNPC is the index of the reset that last loaded an NPC, items given
+	// with later resets go to it
+	lastNPC int
+	// items holds the index of the reset that last loaded each item, so
+	// later resets can put things inside it
+	items  map[string]int
+	extras int
+}
+
+func (im *importer) warn(format string, args ...interface{}) {
+	im.warnings = append(im.warnings, fmt.Sprintf("%s:%d: %s", im.s.file, im.s.line, fmt.Sprintf(format, args...)))
+}
+
+func (im *importer) id(vnum int) string {
+	return im.opts.Prefix + strconv.Itoa(vnum)
+}
+
+func (im *importer) run() error {
+	s := im.s
+	im.lastNPC = -1
+	for {
+		s.skipSpace()
+		if s.eof() {
+			break
+		}
+		if c := s.next(); c != '#' {
+			return s.errorf("expected a section like #ROOMS, found %q", c)
+		}
+		section, err := s.word()
+		if err != nil {
+			return err
+		}
+		section = strings.ToUpper(section)
+		if section == "$" {
+			break
+		}
+
+		switch section {
+		case "AREA":
+			err = im.readArea()
+		case "AREADATA":
+			err = im.readAreaData()
+		case "MOBILES":
+			err = im.readMobiles()
+		case "OBJECTS":
+			err = im.readObjects()
+		case "ROOMS":
+			err = im.readRooms()
+		case "RESETS":
+			err = im.readResets()
+		default:
+			im.warn("the #%s section isn't imported", section)
+			im.skipSection()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if im.extras > 0 {
+		im.warnings = append(im.warnings, fmt.Sprintf("%s: %d extra descriptions of rooms weren't imported", s.file, im.extras))
+	}
+	im.applyDoors()
+
+	return nil
+}
+
+// readArea reads the name of the area. ROM writes the file name, area name
+// and credits as strings followed by the range of vnums, Merc writes the
+// credits and name on the same line as #AREA.
+func (im *importer) readArea() error {
+	s := im.s
+	if line := s.rest(); line != "" {
+		im.area.Zone.Name = areaName(strings.TrimSuffix(line, "~"))
+
+		return nil
+	}
+	if _, err := s.str(); err != nil {
+		return err
+	}
+	name, err := s.str()
+	if err != nil {
+		return err
+	}
+	credits, err := s.str()
+	if err != nil {
+		return err
+	}
+	if _, err := s.number(); err != nil {
+		return err
+	}
+	if _, err := s.number(); err != nil {
+		return err
+	}
+	im.area.Zone.Name = name
+	im.area.Zone.Description = credits
+
+	return nil
+}
+
+// readAreaData reads the name from the keyed #AREADATA section written by
+// ROM's online editor
+func (im *importer) readAreaData() error {
+	s := im.s
+	for {
+		key, err := s.word()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(key) {
+		case "end":
+			return nil
+		case "name":
+			name, err := s.str()
+			if err != nil {
+				return err
+			}
+			im.area.Zone.Name = name
+		case "credits":
+			credits, err := s.str()
+			if err != nil {
+				return err
+			}
+			im.area.Zone.Description = credits
+		default:
+			s.rest()
+		}
+	}
+}
+
+// areaName takes the name from Merc's "{ 5 35} Author  Name"
+func areaName(line string) string {
+	if i := strings.Index(line, "}"); i >= 0 {
+		fields := strings.Fields(line[i+1:])
+		if len(fields) > 1 {
+			return strings.Join(fields[1:], " ")
+		}
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// skipSection skips to the line starting the next section
+func (im *importer) skipSection() {
+	s := im.s
+	s.rest()
+	for !s.eof() {
+		m := s.mark()
+		line := s.rest()
+		if len(line) > 1 && line[0] == '#' && (line[1] == '$' || (line[1] >= 'A' && line[1] <= 'Z')) {
+			s.reset(m)
+
+			return
+		}
+	}
+}
+
+// vnum reads the #vnum starting the next entry of a section, 0 ends it
+func (im *importer) vnum() (int, error) {
+	s := im.s
+	c, err := s.letter()
+	if err != nil {
+		return 0, err
+	}
+	if c != '#' {
+		return 0, s.errorf("expected #vnum, found %q", c)
+	}
+
+	return s.number()
+}
+
+func (im *importer) readMobiles() error {
+	s := im.s
+	for {
+		vnum, err := im.vnum()
+		if err != nil || vnum == 0 {
+			return err
+		}
+		var r reader
+		r.s = s
+		keywords, short, long, desc := r.str(), r.str(), r.str(), r.str()
+		if r.err != nil {
+			return r.err
+		}
+
+		def := world.NPCDef{
+			ID:          im.id(vnum),
+			Name:        short,
+			Keywords:    strings.Fields(keywords),
+			Description: desc,
+			Stats:       make(map[string]int),
+			Props: map[string]interface{}{
+				"vnum": vnum,
+				"long": long,
+			},
+		}
+		if def.Name == "" {
+			def.Name = keywords
+		}
+
+		if s.restEndsString() {
+			// ROM follows the description with the race
+			def.Props["race"] = r.str()
+			def.Flags = names(r.flag(), actFlags)
+			r.flag()
+			def.Stats["alignment"] = r.number()
+			r.number()
+			def.Level = r.number()
+			def.Stats["hitroll"] = r.number()
+			hit, mana, damage := r.dice(), r.dice(), r.dice()
+			def.Props["damage_type"] = r.word()
+			for i := 0; i < 4; i++ {
+				r.number()
+			}
+			for i := 0; i < 4; i++ {
+				r.flag()
+			}
+			r.word()
+			r.word()
+			def.Props["sex"] = r.word()
+			def.Stats["gold"] = r.number()
+			r.flag()
+			r.flag()
+			def.Props["size"] = r.word()
+			r.word()
+			if r.err != nil {
+				return r.err
+			}
+			def.Stats["hp"] = hit.average()
+			def.Stats["mana"] = mana.average()
+			def.Props["hit_dice"] = hit.String()
+			def.Props["damage"] = damage.String()
+
+			if err := im.skipMobileExtras(); err != nil {
+				return err
+			}
+		} else {
+			def.Flags = names(r.flag(), actFlags)
+			r.flag()
+			def.Stats["alignment"] = r.number()
+			r.letter()
+			def.Level = r.number()
+			def.Stats["hitroll"] = r.number()
+			r.number()
+			hit, damage := r.dice(), r.dice()
+			def.Stats["gold"] = r.number()
+			r.number()
+			r.number()
+			r.number()
+			sex := r.number()
+			if r.err != nil {
+				return r.err
+			}
+			def.Stats["hp"] = hit.average()
+			def.Props["hit_dice"] = hit.String()
+			def.Props["damage"] = damage.String()
+			def.Props["sex"] = lookup([]string{"neutral", "male", "female"}, sex)
+		}
+		im.area.NPCs = append(im.area.NPCs, def)
+	}
+}
+
+// skipMobileExtras skips the flag changes and programs ROM allows after a
+// mobile
+func (im *importer) skipMobileExtras() error {
+	s := im.s
+	for {
+		m := s.mark()
+		c, err := s.letter()
+		if err != nil {
+			return err
+		}
+		var r reader
+		r.s = s
+		switch c {
+		case 'F':
+			r.word()
+			r.flag()
+		case 'M':
+			im.warn("mobile programs aren't imported")
+			r.word()
+			r.number()
+			r.str()
+		default:
+			s.reset(m)
+
+			return nil
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+}
+
+func (im *importer) readObjects() error {
+	s := im.s
+	for {
+		vnum, err := im.vnum()
+		if err != nil || vnum == 0 {
+			return err
+		}
+		var r reader
+		r.s = s
+		keywords, short, long, material := r.str(), r.str(), r.str(), r.str()
+		if r.err != nil {
+			return r.err
+		}
+
+		def := world.ItemDef{
+			ID:          im.id(vnum),
+			Name:        short,
+			Keywords:    strings.Fields(keywords),
+			Description: long,
+			Props:       map[string]interface{}{"vnum": vnum},
+		}
+		if def.Name == "" {
+			def.Name = keywords
+		}
+
+		s.skipSpace()
+		var values []string
+		if isDigit(s.peek()) {
+			// Merc numbers the type, has four values and no level
+			def.Type = lookup(itemTypes, r.number())
+			def.Flags = names(r.flag(), extraFlags)
+			def.Props["wear"] = names(r.flag(), wearFlags)
+			for i := 0; i < 4; i++ {
+				values = append(values, strconv.FormatUint(r.flag(), 10))
+			}
+			def.Weight = r.number()
+			def.Value = r.number()
+			r.number()
+		} else {
+			if material != "" {
+				def.Props["material"] = material
+			}
+			def.Type = r.word()
+			def.Flags = names(r.flag(), extraFlags)
+			def.Props["wear"] = names(r.flag(), wearFlags)
+			for i := 0; i < 5; i++ {
+				values = append(values, r.word())
+			}
+			def.Props["level"] = r.number()
+			def.Weight = r.number()
+			def.Value = r.number()
+			r.letter()
+		}
+		if r.err != nil {
+			return r.err
+		}
+		def.Props["values"] = values
+		if def.Type == "weapon" && len(values) > 2 {
+			def.Props["damage"] = values[1] + "d" + values[2]
+		}
+
+		if err := im.readObjectExtras(&def); err != nil {
+			return err
+		}
+		im.area.Items = append(im.area.Items, def)
+	}
+}
+
+// readObjectExtras reads the extra descriptions and stat changes following
+// an object
+func (im *importer) readObjectExtras(def *world.ItemDef) error {
+	s := im.s
+	extras := make(map[string]string)
+	changes := make(map[string]int)
+	for {
+		m := s.mark()
+		c, err := s.letter()
+		if err != nil {
+			return err
+		}
+		var r reader
+		r.s = s
+		switch c {
+		case 'E':
+			keywords, desc := r.str(), r.str()
+			extras[keywords] = desc
+		case 'A':
+			stat, mod := r.number(), r.number()
+			if name := lookup(applies, stat); name != "" {
+				changes[name] += mod
+			}
+		case 'F':
+			im.warn("the affects of object %s aren't imported", def.ID)
+			r.letter()
+			r.number()
+			r.number()
+			r.flag()
+		default:
+			s.reset(m)
+			if len(extras) > 0 {
+				def.Props["extra"] = extras
+			}
+			if len(changes) > 0 {
+				def.Props["applies"] = changes
+			}
+
+			return nil
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+}
+
+func (im *importer) readRooms() error {
+	s := im.s
+	for {
+		vnum, err := im.vnum()
+		if err != nil || vnum == 0 {
+			return err
+		}
+		var r reader
+		r.s = s
+		room := world.Room{
+			ID:          im.id(vnum),
+			Zone:        im.area.Zone.ID,
+			Name:        r.str(),
+			Description: r.str(),
+			Flags:       make(map[string]bool),
+			Exits:       make(map[world.Direction]world.Exit),
+		}
+		r.number()
+		for _, flag := range names(r.flag(), roomFlags) {
+			room.Flags[flag] = true
+		}
+		if sector := lookup(sectors, r.number()); sector != "" {
+			room.Flags[sector] = true
+		}
+		if r.err != nil {
+			return r.err
+		}
+		if room.Name == "" {
+			room.Name = "Room " + strconv.Itoa(vnum)
+		}
+
+	entries:
+		for {
+			c, err := s.letter()
+			if err != nil {
+				return err
+			}
+			switch c {
+			case 'S':
+				break entries
+			case 'D':
+				door := r.number()
+				desc, _ := r.str(), r.str()
+				locks, key, to := r.number(), r.number(), r.number()
+				if r.err != nil {
+					return r.err
+				}
+				if door < 0 || door >= len(directions) {
+					im.warn("room %d has an exit in unknown direction %d", vnum, door)
+
+					continue
+				}
+				if to <= 0 {
+					continue
+				}
+				e := world.Exit{
+					Direction:   directions[door],
+					To:          im.id(to),
+					Description: desc,
+					Door:        locks != 0,
+				}
+				if key > 0 {
+					e.Key = im.id(key)
+				}
+				room.Exits[e.Direction] = e
+			case 'E':
+				r.str()
+				r.str()
+				im.extras++
+			case 'H', 'M':
+				r.number()
+			case 'C', 'O':
+				r.str()
+			default:
+				return s.errorf("unexpected %q in room %d", c, vnum)
+			}
+			if r.err != nil {
+				return r.err
+			}
+		}
+		im.area.Rooms = append(im.area.Rooms, room)
+	}
+}
+
+func (im *importer) readResets() error {
+	s := im.s
+	for {
+		c, err := s.letter()
+		if err != nil {
+			return err
+		}
+		if c == 'S' {
+			s.rest()
+
+			return nil
+		}
+		nums := s.numbers()
+		arg := func(i int) int {
+			if i < len(nums) {
+				return nums[i]
+			}
+
+			return 0
+		}
+		need := map[byte]int{'M': 4, 'O': 4, 'P': 4, 'G': 3, 'E': 4, 'D': 4, 'R': 3}
+		if n, ok := need[c]; ok && len(nums) < n {
+			im.warn("the %c reset needs %d numbers", c, n)
+
+			continue
+		}
+
+		resets := &im.area.Resets
+		switch c {
+		case '*':
+			// a comment
+		case 'M':
+			im.lastNPC = len(*resets)
+			*resets = append(*resets, world.Reset{NPC: im.id(arg(1)), Max: max(arg(2), 0), Room: im.id(arg(3))})
+		case 'O':
+			item := im.id(arg(1))
+			im.items[item] = len(*resets)
+			*resets = append(*resets, world.Reset{Item: item, Room: im.id(arg(3))})
+		case 'P':
+			i, ok := im.items[im.id(arg(3))]
+			if !ok {
+				im.warn("object %d is put in object %d, which isn't loaded into a room first", arg(1), arg(3))
+
+				continue
+			}
+			(*resets)[i].Put = append((*resets)[i].Put, im.id(arg(1)))
+		case 'G', 'E':
+			if im.lastNPC < 0 {
+				im.warn("object %d is given to a mobile before any are loaded", arg(1))
+
+				continue
+			}
+			npc := &(*resets)[im.lastNPC]
+			if c == 'G' {
+				npc.Give = append(npc.Give, im.id(arg(1)))
+
+				continue
+			}
+			slot := lookup(wearLocations, arg(3))
+			if slot == "" {
+				im.warn("object %d is worn in unknown location %d", arg(1), arg(3))
+
+				continue
+			}
+			if npc.Equip == nil {
+				npc.Equip = make(map[string]string)
+			}
+			npc.Equip[slot] = im.id(arg(1))
+		case 'D':
+			if arg(2) < 0 || arg(2) >= len(directions) || lookup(doorStates, arg(3)) == "" {
+				im.warn("the door reset of room %d is invalid", arg(1))
+
+				continue
+			}
+			*resets = append(*resets, world.Reset{Room: im.id(arg(1)), Exit: directions[arg(2)], Door: doorStates[arg(3)]})
+		case 'R':
+			im.warn("the exits of room %d aren't randomized", arg(1))
+		default:
+			im.warn("unknown reset %q", c)
+		}
+	}
+}
+
+// applyDoors leaves doors as their resets do, dropping resets for exits that
+// don't exist or aren't doors
+func (im *importer) applyDoors() {
+	rooms := make(map[string]*world.Room)
+	for i := range im.area.Rooms {
+		rooms[im.area.Rooms[i].ID] = &im.area.Rooms[i]
+	}
+
+	var resets []world.Reset
+	for _, r := range im.area.Resets {
+		if r.Exit == "" {
+			resets = append(resets, r)
+
+			continue
+		}
+		room, ok := rooms[r.Room]
+		if !ok {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: room %s has a door reset but isn't in the area", im.s.file, r.Room))
+
+			continue
+		}
+		e, ok := room.Exits[r.Exit]
+		if !ok || !e.Door {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: room %s has no door %s to reset", im.s.file, r.Room, r.Exit))
+
+			continue
+		}
+		e.Closed = r.Door != world.DoorOpen
+		e.Locked = r.Door == world.DoorLocked
+		room.Exits[r.Exit] = e
+		resets = append(resets, r)
+	}
+	im.area.Resets = resets
+}
+
+// reader keeps the first error from a run of reads so entries with many
+// fields can be read without checking each one
+type reader struct {
+	s   *scanner
+	err error
+}
+
+func (r *reader) str() string {
+	if r.err != nil {
+		return ""
+	}
+	v, err := r.s.str()
+	r.err = err
+
+	return v
+}
+
+func (r *reader) word() string {
+	if r.err != nil {
+		return ""
+	}
+	v, err := r.s.word()
+	r.err = err
+
+	return v
+}
+
+func (r *reader) number() int {
+	if r.err != nil {
+		return 0
+	}
+	v, err := r.s.number()
+	r.err = err
+
+	return v
+}
+
+func (r *reader) flag() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := r.s.flag()
+	r.err = err
+
+	return v
+}
+
+func (r *reader) letter() byte {
+	if r.err != nil {
+		return 0
+	}
+	v, err := r.s.letter()
+	r.err = err
+
+	return v
+}
+
+func (r *reader) dice() dice {
+	if r.err != nil {
+		return dice{}
+	}
+	v, err := r.s.dice()
+	r.err = err
+
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
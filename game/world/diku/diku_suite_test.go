@@ -0,0 +1,13 @@
+package diku_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiku(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diku Suite")
+}
@@ -0,0 +1,269 @@
+package diku_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/world"
+	. "github.com/bbuck/dragon-mud/game/world/diku"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const romArea = `#AREA
+midgaard.are~
+Midgaard~
+{ 5 35} Diku    Midgaard~
+3000 3399
+
+#MOBILES
+#3000
+wizard~
+the wizard~
+A wizard walks around behind the counter, talking to himself.
+~
+The wizard looks old and senile.
+~
+human~
+ABV DFJ 900 0
+33 20 10d10+1000 100d10+100 1d8+32 blast
+-15 -15 -15 7
+EFNU 0 0 0
+stand stand male 10000
+AHMV ABCDEFGHIJK medium 0
+F off E
+#0
+
+#OBJECTS
+#3010
+sword short~
+a short sword~
+A short sword lies here.~
+steel~
+weapon 0 AN
+sword 2 4 slash 0
+5 6 100 P
+E
+sword short~
+It's sharp.~
+A
+18 2
+#3011
+chest~
+a wooden chest~
+A chest sits in the corner.~
+wood~
+container 0 0
+50 A 0 20 100
+0 40 10 P
+#0
+
+#ROOMS
+#3001
+The Temple~
+You are in the temple.
+~
+0 CD 0
+D0
+The altar is north.~
+~
+0 -1 3002
+D1
+~
+door~
+1 3011 3003
+E
+altar~
+A marble altar.~
+S
+#3002
+The Altar~
+A large altar.
+~
+0 0 1
+D2
+~
+~
+0 -1 3001
+S
+#0
+
+#RESETS
+* the temple
+M 0 3000 1 3001 1	the wizard
+E 1 3010 0 16	a short sword
+G 1 3011 0
+O 0 3011 0 3002
+P 0 3010 0 3011 1
+D 0 3001 1 2
+R 0 3001 6
+S
+
+#SHOPS
+3000 2 3 4 10 0 105 15 0 23
+0
+
+#SPECIALS
+M 3000 spec_cast_mage
+S
+
+#$
+`
+
+const mercArea = `#AREA	{ 1 50} Merc    The Road~
+
+#MOBILES
+#100
+guard~
+the guard~
+A guard stands here.
+~
+He looks tough.
+~
+1|2 0 500 S
+5 2 4 2d8+20 1d6+1
+50 300
+8 8 1
+
+#0
+
+#OBJECTS
+#101
+key iron~
+an iron key~
+An iron key is here.~
+~
+18 0 1
+0 0 0 0
+1 10 0
+#0
+
+#ROOMS
+#102
+A Road~
+A dusty road.
+~
+0 0 2
+D0
+~
+gate~
+1 101 103
+S
+#103
+The Gate~
+A gate.
+~
+0 8 1
+S
+#0
+
+#RESETS
+M 0 100 2 102
+G 1 101 0
+S
+
+#$
+`
+
+var _ = Describe("Import", func() {
+	Context("ROM areas", func() {
+		var (
+			a        *world.Area
+			warnings []string
+		)
+
+		BeforeEach(func() {
+			var err error
+			a, warnings, err = Import("areas/Midgaard.are", []byte(romArea), Options{Prefix: "mg"})
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("names the zone", func() {
+			Ω(a.Zone.ID).Should(Equal("midgaard"))
+			Ω(a.Zone.Name).Should(Equal("Midgaard"))
+		})
+
+		It("imports rooms and exits", func() {
+			Ω(a.Rooms).Should(HaveLen(2))
+			r := a.Rooms[0]
+			Ω(r.ID).Should(Equal("mg3001"))
+			Ω(r.Name).Should(Equal("The Temple"))
+			Ω(r.Description).Should(Equal("You are in the temple."))
+			Ω(r.Flags).Should(Equal(map[string]bool{"no_mob": true, "indoors": true}))
+			Ω(r.Exits[world.North]).Should(Equal(world.Exit{Direction: world.North, To: "mg3002", Description: "The altar is north."}))
+			Ω(r.Exits[world.East]).Should(Equal(world.Exit{Direction: world.East, To: "mg3003", Door: true, Closed: true, Locked: true, Key: "mg3011"}))
+			Ω(a.Rooms[1].Flag("city")).Should(BeTrue())
+		})
+
+		It("imports mobiles", func() {
+			Ω(a.NPCs).Should(HaveLen(1))
+			npc := a.NPCs[0]
+			Ω(npc.ID).Should(Equal("mg3000"))
+			Ω(npc.Name).Should(Equal("the wizard"))
+			Ω(npc.Keywords).Should(Equal([]string{"wizard"}))
+			Ω(npc.Level).Should(Equal(33))
+			Ω(npc.Flags).Should(Equal([]string{"sentinel", "nopurge"}))
+			Ω(npc.Stats).Should(Equal(map[string]int{"hp": 1055, "mana": 650, "hitroll": 20, "alignment": 900, "gold": 10000}))
+			Ω(npc.Props["race"]).Should(Equal("human"))
+			Ω(npc.Props["damage"]).Should(Equal("1d8+32"))
+		})
+
+		It("imports objects", func() {
+			Ω(a.Items).Should(HaveLen(2))
+			sword := a.Items[0]
+			Ω(sword.Name).Should(Equal("a short sword"))
+			Ω(sword.Type).Should(Equal("weapon"))
+			Ω(sword.Weight).Should(Equal(6))
+			Ω(sword.Value).Should(Equal(100))
+			Ω(sword.Props["wear"]).Should(Equal([]string{"take", "wield"}))
+			Ω(sword.Props["damage"]).Should(Equal("2d4"))
+			Ω(sword.Props["extra"]).Should(Equal(map[string]string{"sword short": "It's sharp."}))
+			Ω(sword.Props["applies"]).Should(Equal(map[string]int{"hitroll": 2}))
+			Ω(a.Items[1].Props["values"]).Should(Equal([]string{"50", "A", "0", "20", "100"}))
+		})
+
+		It("imports resets", func() {
+			Ω(a.Resets).Should(Equal([]world.Reset{
+				{NPC: "mg3000", Room: "mg3001", Max: 1, Give: []string{"mg3011"}, Equip: map[string]string{"wield": "mg3010"}},
+				{Item: "mg3011", Room: "mg3002", Put: []string{"mg3010"}},
+				{Room: "mg3001", Exit: world.East, Door: world.DoorLocked},
+			}))
+		})
+
+		It("warns about what it skips", func() {
+			Ω(warnings).Should(ContainElement(ContainSubstring("exits of room 3001 aren't randomized")))
+			Ω(warnings).Should(ContainElement(ContainSubstring("#SHOPS section isn't imported")))
+			Ω(warnings).Should(ContainElement(ContainSubstring("#SPECIALS section isn't imported")))
+			Ω(warnings).Should(ContainElement("areas/Midgaard.are: 1 extra descriptions of rooms weren't imported"))
+		})
+
+		It("writes valid area files", func() {
+			contents, err := world.MarshalArea(a)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = world.ParseArea("midgaard.yml", contents)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	It("imports Merc areas", func() {
+		a, warnings, err := Import("road.are", []byte(mercArea), Options{Zone: "road"})
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(warnings).Should(BeEmpty())
+		Ω(a.Zone.Name).Should(Equal("The Road"))
+		Ω(a.NPCs[0].Flags).Should(Equal([]string{"sentinel"}))
+		Ω(a.NPCs[0].Stats["hp"]).Should(Equal(29))
+		Ω(a.NPCs[0].Props["sex"]).Should(Equal("male"))
+		Ω(a.Items[0].Type).Should(Equal("key"))
+		Ω(a.Items[0].Props["wear"]).Should(Equal([]string{"take"}))
+		Ω(a.Rooms[0].Exits[world.North]).Should(Equal(world.Exit{Direction: world.North, To: "103", Door: true, Key: "101"}))
+		Ω(a.Rooms[1].Flag("indoors")).Should(BeTrue())
+		Ω(a.Resets).Should(Equal([]world.Reset{{NPC: "100", Room: "102", Max: 2, Give: []string{"101"}}}))
+	})
+
+	It("reports where files are broken", func() {
+		_, _, err := Import("bad.are", []byte("#ROOMS\n#100\nA Room~\nA room.\n~\n0 0 x\nS\n#0\n"), Options{})
+
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(Equal("bad.are:6: expected a number"))
+	})
+})
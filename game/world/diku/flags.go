@@ -0,0 +1,231 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package diku
+
+import "github.com/bbuck/dragon-mud/game/world"
+
+// The tables below name the bits of each kind of flag, by bit. Merc and ROM
+// kept Diku's bits where they overlap so one table serves all three.
+
+var actFlags = []string{
+	1:  "sentinel",
+	2:  "scavenger",
+	5:  "aggressive",
+	6:  "stay_area",
+	7:  "wimpy",
+	8:  "pet",
+	9:  "train",
+	10: "practice",
+	14: "undead",
+	16: "cleric",
+	17: "mage",
+	18: "thief",
+	19: "warrior",
+	20: "noalign",
+	21: "nopurge",
+	22: "outdoors",
+	24: "indoors",
+	26: "healer",
+	27: "gain",
+	28: "update_always",
+	29: "changer",
+}
+
+var roomFlags = []string{
+	0:  "dark",
+	2:  "no_mob",
+	3:  "indoors",
+	9:  "private",
+	10: "safe",
+	11: "solitary",
+	12: "pet_shop",
+	13: "no_recall",
+	14: "imp_only",
+	15: "gods_only",
+	16: "heroes_only",
+	17: "newbies_only",
+	18: "law",
+	19: "nowhere",
+}
+
+var extraFlags = []string{
+	0:  "glow",
+	1:  "hum",
+	2:  "dark",
+	3:  "lock",
+	4:  "evil",
+	5:  "invis",
+	6:  "magic",
+	7:  "nodrop",
+	8:  "bless",
+	9:  "anti_good",
+	10: "anti_evil",
+	11: "anti_neutral",
+	12: "noremove",
+	13: "inventory",
+	14: "nopurge",
+	15: "rot_death",
+	16: "vis_death",
+	18: "nonmetal",
+	19: "nolocate",
+	20: "melt_drop",
+	21: "had_timer",
+	22: "sell_extract",
+	24: "burn_proof",
+	25: "nouncurse",
+}
+
+var wearFlags = []string{
+	0:  "take",
+	1:  "finger",
+	2:  "neck",
+	3:  "body",
+	4:  "head",
+	5:  "legs",
+	6:  "feet",
+	7:  "hands",
+	8:  "arms",
+	9:  "shield",
+	10: "about",
+	11: "waist",
+	12: "wrist",
+	13: "wield",
+	14: "hold",
+	15: "no_sac",
+	16: "float",
+}
+
+// sectors name the terrain of rooms, they're added to rooms as flags
+var sectors = []string{
+	0:  "indoors",
+	1:  "city",
+	2:  "field",
+	3:  "forest",
+	4:  "hills",
+	5:  "mountain",
+	6:  "water_swim",
+	7:  "water_noswim",
+	8:  "underwater",
+	9:  "air",
+	10: "desert",
+}
+
+// itemTypes name the numbered item types of Diku and Merc, ROM writes the
+// names
+var itemTypes = []string{
+	1:  "light",
+	2:  "scroll",
+	3:  "wand",
+	4:  "staff",
+	5:  "weapon",
+	8:  "treasure",
+	9:  "armor",
+	10: "potion",
+	11: "clothing",
+	12: "furniture",
+	13: "trash",
+	15: "container",
+	17: "drink_con",
+	18: "key",
+	19: "food",
+	20: "money",
+	22: "boat",
+	23: "npc_corpse",
+	24: "pc_corpse",
+	25: "fountain",
+	26: "pill",
+	27: "protect",
+	28: "map",
+	29: "portal",
+	30: "warp_stone",
+	31: "room_key",
+	32: "gem",
+	33: "jewelry",
+	34: "jukebox",
+}
+
+// wearLocations name where equip resets put items
+var wearLocations = []string{
+	0:  "light",
+	1:  "finger_l",
+	2:  "finger_r",
+	3:  "neck_1",
+	4:  "neck_2",
+	5:  "body",
+	6:  "head",
+	7:  "legs",
+	8:  "feet",
+	9:  "hands",
+	10: "arms",
+	11: "shield",
+	12: "about",
+	13: "waist",
+	14: "wrist_l",
+	15: "wrist_r",
+	16: "wield",
+	17: "hold",
+	18: "float",
+}
+
+// applies name the stats objects change
+var applies = []string{
+	1:  "str",
+	2:  "dex",
+	3:  "int",
+	4:  "wis",
+	5:  "con",
+	6:  "sex",
+	8:  "level",
+	9:  "age",
+	10: "height",
+	11: "weight",
+	12: "mana",
+	13: "hp",
+	14: "moves",
+	15: "gold",
+	16: "exp",
+	17: "ac",
+	18: "hitroll",
+	19: "damroll",
+	20: "saves",
+	21: "saving_rod",
+	22: "saving_petri",
+	23: "saving_breath",
+	24: "saving_spell",
+}
+
+var doorStates = []string{
+	0: world.DoorOpen,
+	1: world.DoorClosed,
+	2: world.DoorLocked,
+}
+
+var directions = []world.Direction{
+	world.North,
+	world.East,
+	world.South,
+	world.West,
+	world.Up,
+	world.Down,
+}
+
+// names returns the names of the bits set, unnamed bits are left out
+func names(bits uint64, table []string) []string {
+	var set []string
+	for bit, name := range table {
+		if name != "" && bits&(1<<uint(bit)) != 0 {
+			set = append(set, name)
+		}
+	}
+
+	return set
+}
+
+// lookup returns the name at i in the table, or an empty string
+func lookup(table []string, i int) string {
+	if i < 0 || i >= len(table) {
+		return ""
+	}
+
+	return table[i]
+}
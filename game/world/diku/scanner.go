@@ -0,0 +1,300 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package diku
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scanner reads the values of an area file the way Diku derived servers do,
+// with numbers, flags, words and strings ended by a tilde separated by any
+// whitespace.
+type scanner struct {
+	file string
+	data []byte
+	pos  int
+	line int
+}
+
+func newScanner(file string, data []byte) *scanner {
+	return &scanner{
+		file: file,
+		data: data,
+		line: 1,
+	}
+}
+
+// mark is a position to return to after looking ahead
+type mark struct {
+	pos, line int
+}
+
+func (s *scanner) mark() mark {
+	return mark{pos: s.pos, line: s.line}
+}
+
+func (s *scanner) reset(m mark) {
+	s.pos, s.line = m.pos, m.line
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", s.file, s.line, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) eof() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *scanner) peek() byte {
+	if s.eof() {
+		return 0
+	}
+
+	return s.data[s.pos]
+}
+
+func (s *scanner) next() byte {
+	c := s.peek()
+	if !s.eof() {
+		s.pos++
+		if c == '\n' {
+			s.line++
+		}
+	}
+
+	return c
+}
+
+func (s *scanner) skipSpace() {
+	for !s.eof() && isSpace(s.peek()) {
+		s.next()
+	}
+}
+
+// letter returns the next character that isn't whitespace.
+func (s *scanner) letter() (byte, error) {
+	s.skipSpace()
+	if s.eof() {
+		return 0, s.errorf("unexpected end of file")
+	}
+
+	return s.next(), nil
+}
+
+// number reads a whole number, numbers joined by | are added together.
+func (s *scanner) number() (int, error) {
+	s.skipSpace()
+	negative := false
+	switch s.peek() {
+	case '-':
+		negative = true
+		s.next()
+	case '+':
+		s.next()
+	}
+	if !isDigit(s.peek()) {
+		return 0, s.errorf("expected a number")
+	}
+	n := 0
+	for isDigit(s.peek()) {
+		n = n*10 + int(s.next()-'0')
+	}
+	if negative {
+		n = -n
+	}
+	if s.peek() == '|' {
+		s.next()
+		more, err := s.number()
+		if err != nil {
+			return 0, err
+		}
+		n += more
+	}
+
+	return n, nil
+}
+
+// flag reads a set of bits, written as a number or as letters where A is the
+// first bit, Z the 26th and a through z the rest. Flags joined by | are
+// combined.
+func (s *scanner) flag() (uint64, error) {
+	s.skipSpace()
+	if s.peek() == '-' || s.peek() == '+' {
+		n, err := s.number()
+
+		return uint64(n), err
+	}
+
+	var bits uint64
+	read := false
+	for c := s.peek(); isFlagLetter(c); c = s.peek() {
+		if c >= 'a' {
+			bits |= 1 << uint(c-'a'+26)
+		} else {
+			bits |= 1 << uint(c-'A')
+		}
+		s.next()
+		read = true
+	}
+	if isDigit(s.peek()) {
+		var n uint64
+		for isDigit(s.peek()) {
+			n = n*10 + uint64(s.next()-'0')
+		}
+		bits |= n
+		read = true
+	}
+	if !read {
+		return 0, s.errorf("expected flags")
+	}
+	if s.peek() == '|' {
+		s.next()
+		more, err := s.flag()
+		if err != nil {
+			return 0, err
+		}
+		bits |= more
+	}
+
+	return bits, nil
+}
+
+// word reads up to the next whitespace, or between quotes if the word starts
+// with one.
+func (s *scanner) word() (string, error) {
+	s.skipSpace()
+	if s.eof() {
+		return "", s.errorf("unexpected end of file")
+	}
+	end := func(c byte) bool {
+		return isSpace(c)
+	}
+	if q := s.peek(); q == '\'' || q == '"' {
+		s.next()
+		end = func(c byte) bool {
+			return c == q
+		}
+	}
+	start := s.pos
+	for !s.eof() && !end(s.peek()) {
+		s.next()
+	}
+	w := string(s.data[start:s.pos])
+	if !s.eof() && !isSpace(s.peek()) {
+		s.next()
+	}
+
+	return w, nil
+}
+
+// str reads a string ended by a tilde, which may span lines.
+func (s *scanner) str() (string, error) {
+	s.skipSpace()
+	start := s.pos
+	for !s.eof() && s.peek() != '~' {
+		s.next()
+	}
+	if s.eof() {
+		return "", s.errorf("unexpected end of file in a string")
+	}
+	text := string(s.data[start:s.pos])
+	s.next()
+
+	text = strings.Replace(text, "\r", "", -1)
+
+	return strings.TrimRight(text, " \t\n"), nil
+}
+
+// rest returns the rest of the line, moving to the start of the next.
+func (s *scanner) rest() string {
+	start := s.pos
+	for !s.eof() && s.peek() != '\n' {
+		s.next()
+	}
+	text := string(s.data[start:s.pos])
+	s.next()
+
+	return strings.TrimSpace(text)
+}
+
+// restEndsString is true if the rest of the line ends with a tilde, without
+// moving
+func (s *scanner) restEndsString() bool {
+	m := s.mark()
+	defer s.reset(m)
+	s.skipSpace()
+
+	return strings.HasSuffix(s.rest(), "~")
+}
+
+// numbers reads the numbers at the start of the rest of the line, ignoring
+// anything after them like comments.
+func (s *scanner) numbers() []int {
+	var nums []int
+	for _, field := range strings.Fields(s.rest()) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+
+	return nums
+}
+
+// dice reads dice written as NdS+B.
+func (s *scanner) dice() (dice, error) {
+	var (
+		d   dice
+		err error
+	)
+	if d.count, err = s.number(); err != nil {
+		return d, err
+	}
+	if c, err := s.letter(); err != nil || (c != 'd' && c != 'D') {
+		return d, s.errorf("expected dice like 1d6+2")
+	}
+	if d.size, err = s.number(); err != nil {
+		return d, err
+	}
+	m := s.mark()
+	if c, _ := s.letter(); c != '+' {
+		s.reset(m)
+
+		return d, nil
+	}
+	d.bonus, err = s.number()
+
+	return d, err
+}
+
+type dice struct {
+	count, size, bonus int
+}
+
+// average is the average total of a roll
+func (d dice) average() int {
+	return d.count*(d.size+1)/2 + d.bonus
+}
+
+func (d dice) String() string {
+	if d.bonus == 0 {
+		return fmt.Sprintf("%dd%d", d.count, d.size)
+	}
+
+	return fmt.Sprintf("%dd%d+%d", d.count, d.size, d.bonus)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFlagLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
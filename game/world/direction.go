@@ -0,0 +1,112 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+import "strings"
+
+// Direction names an exit. The standard directions have abbreviations and
+// reverses, any other name (like "portal") can be used for special exits.
+type Direction string
+
+// The standard directions, in the order exits are listed.
+const (
+	North     Direction = "north"
+	East      Direction = "east"
+	South     Direction = "south"
+	West      Direction = "west"
+	Up        Direction = "up"
+	Down      Direction = "down"
+	Northeast Direction = "northeast"
+	Northwest Direction = "northwest"
+	Southeast Direction = "southeast"
+	Southwest Direction = "southwest"
+)
+
+// Directions lists the standard directions in the order exits are listed.
+var Directions = []Direction{North, East, South, West, Up, Down, Northeast, Northwest, Southeast, Southwest}
+
+var (
+	reverses = map[Direction]Direction{
+		North:     South,
+		East:      West,
+		South:     North,
+		West:      East,
+		Up:        Down,
+		Down:      Up,
+		Northeast: Southwest,
+		Northwest: Southeast,
+		Southeast: Northwest,
+		Southwest: Northeast,
+	}
+
+	abbreviations = map[string]Direction{
+		"n":  North,
+		"e":  East,
+		"s":  South,
+		"w":  West,
+		"u":  Up,
+		"d":  Down,
+		"ne": Northeast,
+		"nw": Northwest,
+		"se": Southeast,
+		"sw": Southwest,
+	}
+)
+
+// ParseDirection reads a direction typed by a player, standard directions
+// may be abbreviated ("n", "ne"). Anything else is returned lower cased.
+func ParseDirection(s string) Direction {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if d, ok := abbreviations[s]; ok {
+		return d
+	}
+
+	return Direction(s)
+}
+
+// Standard is true for the ten compass and vertical directions.
+func (d Direction) Standard() bool {
+	_, ok := reverses[d]
+
+	return ok
+}
+
+// Reverse returns the opposite direction, or an empty direction for names
+// that aren't standard.
+func (d Direction) Reverse() Direction {
+	return reverses[d]
+}
+
+// Abbreviation returns the short form of a standard direction, like "ne", or
+// an empty string for names that aren't standard.
+func (d Direction) Abbreviation() string {
+	for abbrev, dir := range abbreviations {
+		if dir == d {
+			return abbrev
+		}
+	}
+
+	return ""
+}
+
+// order is used to sort exits, standard directions come first
+func (d Direction) order() int {
+	for i, dir := range Directions {
+		if dir == d {
+			return i
+		}
+	}
+
+	return len(Directions)
+}
+
+// less sorts standard directions in their listed order followed by any
+// others by name
+func less(a, b Direction) bool {
+	oa, ob := a.order(), b.order()
+	if oa != ob {
+		return oa < ob
+	}
+
+	return a < b
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+import "github.com/bbuck/dragon-mud/data"
+
+// LinkGraph replaces the rooms in the graph with those of the world, each
+// exit becoming a relationship from its room to the one it leads to with the
+// direction it's in. Hidden and closed exits are linked too, scripts can
+// tell them apart by the relationship's properties. Rooms joined by more
+// than one exit are linked once, with the last direction.
+func (w *World) LinkGraph(g data.Graph) error {
+	if err := g.Clear(data.RoomLabel); err != nil {
+		return err
+	}
+	for _, z := range w.Zones() {
+		for _, r := range w.Rooms(z.ID) {
+			for _, e := range r.SortedExits(true) {
+				err := g.Link(data.Edge{
+					From: data.Node{Label: data.RoomLabel, ID: r.ID},
+					Kind: data.ExitKind,
+					To:   data.Node{Label: data.RoomLabel, ID: e.To},
+					Props: map[string]interface{}{
+						"direction": string(e.Direction),
+						"zone":      z.ID,
+						"hidden":    e.Hidden,
+						"closed":    e.Closed,
+					},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
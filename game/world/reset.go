@@ -0,0 +1,117 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+// Door states a reset can leave an exit in.
+const (
+	DoorOpen   = "open"
+	DoorClosed = "closed"
+	DoorLocked = "locked"
+)
+
+// Reset puts something back into the world when its zone resets. Each reset
+// does one of four things:
+//   - loads the NPC into the room, giving it the Give items and wearing the
+//     Equip items
+//   - loads the Item into the room, with the Put items inside it
+//   - sets the Door of the room's Exit to open, closed or locked
+//   - runs the Script, a reset scripts have handled by name, given the zone
+//     and the room if it has one, for anything else like restocking shops
+//     or resetting puzzles
+type Reset struct {
+	NPC    string `yaml:"npc,omitempty"`
+	Item   string `yaml:"item,omitempty"`
+	Script string `yaml:"script,omitempty"`
+	Room   string `yaml:"room,omitempty"`
+	// Max limits how many of the NPC or item can be in the world, zero is no
+	// limit.
+	Max int `yaml:"max,omitempty"`
+	// Give lists the ids of items the NPC carries.
+	Give []string `yaml:"give,omitempty"`
+	// Equip holds the ids of items the NPC wears, by where they're worn.
+	Equip map[string]string `yaml:"equip,omitempty"`
+	// Put lists the ids of items inside the item.
+	Put  []string  `yaml:"put,omitempty"`
+	Exit Direction `yaml:"exit,omitempty"`
+	Door string    `yaml:"door,omitempty"`
+}
+
+// copy returns a deep copy of the reset
+func (r Reset) copy() Reset {
+	r.Give = append([]string(nil), r.Give...)
+	r.Put = append([]string(nil), r.Put...)
+	if r.Equip != nil {
+		equip := make(map[string]string, len(r.Equip))
+		for slot, item := range r.Equip {
+			equip[slot] = item
+		}
+		r.Equip = equip
+	}
+
+	return r
+}
+
+// check returns why the reset is invalid, or an empty string if it's valid
+func (r Reset) check() string {
+	kinds := 0
+	for _, set := range []bool{r.NPC != "", r.Item != "", r.Exit != "", r.Script != ""} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds != 1:
+		return "a reset needs exactly one of npc, item, exit or script"
+	case r.Room == "" && r.Script == "":
+		return "the room is required"
+	case r.NPC == "" && (len(r.Give) > 0 || len(r.Equip) > 0):
+		return "only NPCs can be given or equipped with items"
+	case r.Item == "" && len(r.Put) > 0:
+		return "items can only be put in items"
+	case r.Exit != "" && r.Door != DoorOpen && r.Door != DoorClosed && r.Door != DoorLocked:
+		return "door must be open, closed or locked"
+	case r.Exit == "" && r.Door != "":
+		return "only exits have a door"
+	}
+
+	return ""
+}
+
+// SetResets replaces the resets of the zone.
+func (w *World) SetResets(zone string, resets []Reset) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.zones[zone]; !ok {
+		return ErrNoZone
+	}
+	w.setResets(zone, resets)
+
+	return nil
+}
+
+func (w *World) setResets(zone string, resets []Reset) {
+	if len(resets) == 0 {
+		delete(w.resets, zone)
+
+		return
+	}
+	copies := make([]Reset, len(resets))
+	for i, r := range resets {
+		copies[i] = r.copy()
+	}
+	w.resets[zone] = copies
+}
+
+// Resets returns the resets of the zone, in the order they're run.
+func (w *World) Resets(zone string) []Reset {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var resets []Reset
+	for _, r := range w.resets[zone] {
+		resets = append(resets, r.copy())
+	}
+
+	return resets
+}
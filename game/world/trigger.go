@@ -0,0 +1,92 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package world
+
+import (
+	"fmt"
+	"time"
+)
+
+// What triggers run on.
+const (
+	// TriggerEnter runs when someone enters the room, for the room and the
+	// NPCs and items in it.
+	TriggerEnter = "enter"
+	// TriggerLeave runs when someone leaves the room, for the room and the
+	// NPCs and items in it.
+	TriggerLeave = "leave"
+	// TriggerSpeech runs when someone speaks in the room, for the room and
+	// the NPCs and items in it, if what they said has one of the keywords.
+	TriggerSpeech = "speech"
+	// TriggerGive runs when an item is given to an NPC, for the NPC and the
+	// item.
+	TriggerGive = "give"
+	// TriggerDeath runs when an NPC dies.
+	TriggerDeath = "death"
+	// TriggerTimer runs every so often.
+	TriggerTimer = "timer"
+)
+
+// TriggerKinds lists what triggers can run on.
+var TriggerKinds = []string{TriggerEnter, TriggerLeave, TriggerSpeech, TriggerGive, TriggerDeath, TriggerTimer}
+
+// Trigger attaches a script to a room, NPC or item, run when something
+// happens around it.
+type Trigger struct {
+	On string `yaml:"on"`
+	// Script is the path of the Lua script in the triggers directory.
+	Script string `yaml:"script"`
+	// Keywords are the words that set off speech triggers, any speech does
+	// when there are none.
+	Keywords []string `yaml:"keywords,omitempty"`
+	// Every is how often timer triggers run, like "5m".
+	Every string `yaml:"every,omitempty"`
+}
+
+// Interval returns how often the timer trigger runs, zero if it isn't one.
+func (t Trigger) Interval() time.Duration {
+	if t.On != TriggerTimer {
+		return 0
+	}
+	d, _ := time.ParseDuration(t.Every)
+
+	return d
+}
+
+// check returns why the trigger is invalid, or an empty string if it's valid
+func (t Trigger) check() string {
+	known := false
+	for _, kind := range TriggerKinds {
+		if t.On == kind {
+			known = true
+		}
+	}
+	switch {
+	case !known:
+		return fmt.Sprintf("triggers run on one of %v, not %q", TriggerKinds, t.On)
+	case t.Script == "":
+		return "the script is required"
+	case t.On != TriggerSpeech && len(t.Keywords) > 0:
+		return "only speech triggers have keywords"
+	case t.On != TriggerTimer && t.Every != "":
+		return "only timer triggers run every so often"
+	case t.On == TriggerTimer && t.Interval() <= 0:
+		return fmt.Sprintf("timer triggers need every to be a duration like 5m, not %q", t.Every)
+	}
+
+	return ""
+}
+
+// copyTriggers returns a copy of the triggers
+func copyTriggers(triggers []Trigger) []Trigger {
+	if triggers == nil {
+		return nil
+	}
+	copied := make([]Trigger, len(triggers))
+	for i, t := range triggers {
+		t.Keywords = append([]string(nil), t.Keywords...)
+		copied[i] = t
+	}
+
+	return copied
+}
@@ -0,0 +1,562 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package world models the places in the game. Rooms belong to zones and are
+// joined by exits, forming a graph kept in memory by a World which is safe
+// to use from any goroutine. Values returned from a World are copies,
+// changes are made through its methods.
+package world
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/path"
+)
+
+var (
+	// ErrNoZone is returned when there's no zone with an id.
+	ErrNoZone = errors.New("no such zone")
+
+	// ErrNoRoom is returned when there's no room with an id.
+	ErrNoRoom = errors.New("no such room")
+
+	// ErrNoExit is returned when a room has no exit in a direction.
+	ErrNoExit = errors.New("no exit in that direction")
+
+	// ErrExists is returned when adding a zone or room with an id already
+	// used.
+	ErrExists = errors.New("id is already used")
+
+	// ErrNoID is returned when adding a zone or room without an id.
+	ErrNoID = errors.New("an id is required")
+
+	// ErrNoDoor is returned when changing the door of an exit without one.
+	ErrNoDoor = errors.New("exit has no door")
+)
+
+// Zone groups rooms that are built and reset together, like a town or a
+// dungeon.
+type Zone struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Reset is how often the zone's resets run, like "@every 15m". The game's
+	// default is used when it's empty.
+	Reset string `yaml:"reset,omitempty"`
+	// Population limits how many NPCs the zone's resets keep in the world,
+	// zero is no limit.
+	Population int `yaml:"population,omitempty"`
+	// Builders are the names of the builders allowed to change the zone in
+	// the game, anyone who can build may when it's empty.
+	Builders []string `yaml:"builders,omitempty"`
+	// File is the area file the zone was loaded from, builder changes are
+	// saved back to it.
+	File string `yaml:"-"`
+}
+
+// Exit leads from a room to another in a direction.
+type Exit struct {
+	Direction Direction
+	// To is the id of the room the exit leads to.
+	To          string
+	Description string
+	// Door exits can be closed, Closed and Locked are their current state.
+	Door   bool
+	Closed bool
+	Locked bool
+	// Key is the id of the item that locks and unlocks the door.
+	Key string
+	// Pickproof and Bashproof doors can't be picked or bashed open.
+	Pickproof bool
+	Bashproof bool
+	// Hidden exits aren't listed to players, though they can be used.
+	Hidden bool
+	// Size is the size of the largest mover that fits through, zero lets
+	// anything through.
+	Size int
+}
+
+// Room is a place in the world.
+type Room struct {
+	ID          string
+	Zone        string
+	Name        string
+	Description string
+	// Flags are named switches, like "dark" or "indoors".
+	Flags map[string]bool
+	// Exits are keyed by their direction.
+	Exits map[Direction]Exit
+	// Triggers are the scripts run when things happen in the room.
+	Triggers []Trigger
+}
+
+// Flag is true if the flag is set on the room.
+func (r Room) Flag(name string) bool {
+	return r.Flags[strings.ToLower(name)]
+}
+
+// Exit returns the exit in the direction.
+func (r Room) Exit(d Direction) (Exit, bool) {
+	e, ok := r.Exits[d]
+
+	return e, ok
+}
+
+// SortedExits returns the room's exits with standard directions first, in
+// the order of Directions, hidden exits are left out unless asked for.
+func (r Room) SortedExits(hidden bool) []Exit {
+	exits := make([]Exit, 0, len(r.Exits))
+	for _, e := range r.Exits {
+		if e.Hidden && !hidden {
+			continue
+		}
+		exits = append(exits, e)
+	}
+	sort.Slice(exits, func(i, j int) bool {
+		return less(exits[i].Direction, exits[j].Direction)
+	})
+
+	return exits
+}
+
+// copy returns a deep copy of the room
+func (r Room) copy() Room {
+	flags := make(map[string]bool, len(r.Flags))
+	for k, v := range r.Flags {
+		flags[strings.ToLower(k)] = v
+	}
+	exits := make(map[Direction]Exit, len(r.Exits))
+	for d, e := range r.Exits {
+		e.Direction = d
+		exits[d] = e
+	}
+	r.Flags, r.Exits = flags, exits
+	r.Triggers = copyTriggers(r.Triggers)
+
+	return r
+}
+
+// World is the graph of zones and rooms.
+type World struct {
+	zones map[string]Zone
+	rooms map[string]Room
+	// zoneRooms holds the ids of the rooms in each zone
+	zoneRooms map[string]map[string]bool
+	npcs      map[string]NPCDef
+	items     map[string]ItemDef
+	resets    map[string][]Reset
+	mutex     *sync.RWMutex
+}
+
+// New creates an empty world.
+func New() *World {
+	return &World{
+		zones:     make(map[string]Zone),
+		rooms:     make(map[string]Room),
+		zoneRooms: make(map[string]map[string]bool),
+		npcs:      make(map[string]NPCDef),
+		items:     make(map[string]ItemDef),
+		resets:    make(map[string][]Reset),
+		mutex:     new(sync.RWMutex),
+	}
+}
+
+var (
+	globalWorld *World
+	globalOnce  sync.Once
+)
+
+// Global returns the game's world.
+func Global() *World {
+	globalOnce.Do(func() {
+		globalWorld = New()
+	})
+
+	return globalWorld
+}
+
+// AddZone adds the zone, failing with ErrExists if its id is used.
+func (w *World) AddZone(z Zone) error {
+	if z.ID == "" {
+		return ErrNoID
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.zones[z.ID]; ok {
+		return ErrExists
+	}
+	w.zones[z.ID] = z
+	w.zoneRooms[z.ID] = make(map[string]bool)
+
+	return nil
+}
+
+// UpdateZone replaces the name and description of the zone with the same id,
+// it keeps the zone's file unless a new one is given.
+func (w *World) UpdateZone(z Zone) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	old, ok := w.zones[z.ID]
+	if !ok {
+		return ErrNoZone
+	}
+	if z.File == "" {
+		z.File = old.File
+	}
+	w.zones[z.ID] = z
+
+	return nil
+}
+
+// RemoveZone removes the zone and every room in it.
+func (w *World) RemoveZone(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	rooms, ok := w.zoneRooms[id]
+	if !ok {
+		return ErrNoZone
+	}
+	for room := range rooms {
+		w.removeRoom(room)
+	}
+	w.removeDefs(id)
+	delete(w.zones, id)
+	delete(w.zoneRooms, id)
+
+	return nil
+}
+
+// Zone returns the zone with the id.
+func (w *World) Zone(id string) (Zone, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	z, ok := w.zones[id]
+
+	return z, ok
+}
+
+// Zones returns every zone, sorted by id.
+func (w *World) Zones() []Zone {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	zones := make([]Zone, 0, len(w.zones))
+	for _, z := range w.zones {
+		zones = append(zones, z)
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ID < zones[j].ID
+	})
+
+	return zones
+}
+
+// AddRoom adds the room to its zone, which must already be in the world.
+// Its exits may lead to rooms that haven't been added yet, so zones can be
+// loaded in any order.
+func (w *World) AddRoom(r Room) error {
+	if r.ID == "" {
+		return ErrNoID
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.rooms[r.ID]; ok {
+		return ErrExists
+	}
+	rooms, ok := w.zoneRooms[r.Zone]
+	if !ok {
+		return ErrNoZone
+	}
+	w.rooms[r.ID] = r.copy()
+	rooms[r.ID] = true
+
+	return nil
+}
+
+// UpdateRoom replaces the room with the same id, it may move to another
+// zone.
+func (w *World) UpdateRoom(r Room) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	old, ok := w.rooms[r.ID]
+	if !ok {
+		return ErrNoRoom
+	}
+	if _, ok := w.zoneRooms[r.Zone]; !ok {
+		return ErrNoZone
+	}
+	delete(w.zoneRooms[old.Zone], r.ID)
+	w.zoneRooms[r.Zone][r.ID] = true
+	w.rooms[r.ID] = r.copy()
+
+	return nil
+}
+
+// RemoveRoom removes the room along with every exit leading to it.
+func (w *World) RemoveRoom(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.rooms[id]; !ok {
+		return ErrNoRoom
+	}
+	w.removeRoom(id)
+
+	return nil
+}
+
+func (w *World) removeRoom(id string) {
+	r := w.rooms[id]
+	delete(w.zoneRooms[r.Zone], id)
+	delete(w.rooms, id)
+	for _, other := range w.rooms {
+		for d, e := range other.Exits {
+			if e.To == id {
+				delete(other.Exits, d)
+			}
+		}
+	}
+}
+
+// Room returns the room with the id.
+func (w *World) Room(id string) (Room, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	r, ok := w.rooms[id]
+	if !ok {
+		return r, false
+	}
+
+	return r.copy(), true
+}
+
+// Rooms returns the rooms in the zone, sorted by id.
+func (w *World) Rooms(zone string) []Room {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	rooms := make([]Room, 0, len(w.zoneRooms[zone]))
+	for id := range w.zoneRooms[zone] {
+		rooms = append(rooms, w.rooms[id].copy())
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	return rooms
+}
+
+// SetExit adds the exit to the room, replacing any exit in its direction.
+func (w *World) SetExit(room string, e Exit) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	r, ok := w.rooms[room]
+	if !ok {
+		return ErrNoRoom
+	}
+	r.Exits[e.Direction] = e
+
+	return nil
+}
+
+// Link joins the rooms with an exit in the direction and, for standard
+// directions, one back the other way.
+func (w *World) Link(from string, d Direction, to string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	a, ok := w.rooms[from]
+	if !ok {
+		return ErrNoRoom
+	}
+	b, ok := w.rooms[to]
+	if !ok {
+		return ErrNoRoom
+	}
+	a.Exits[d] = Exit{Direction: d, To: to}
+	if back := d.Reverse(); back != "" {
+		b.Exits[back] = Exit{Direction: back, To: from}
+	}
+
+	return nil
+}
+
+// RemoveExit removes the room's exit in the direction.
+func (w *World) RemoveExit(room string, d Direction) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	r, ok := w.rooms[room]
+	if !ok {
+		return ErrNoRoom
+	}
+	if _, ok := r.Exits[d]; !ok {
+		return ErrNoExit
+	}
+	delete(r.Exits, d)
+
+	return nil
+}
+
+// UpdateExit changes the room's exit in the direction with fn, like opening
+// its door. The direction of the exit can't be changed.
+func (w *World) UpdateExit(room string, d Direction, fn func(*Exit)) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	r, ok := w.rooms[room]
+	if !ok {
+		return ErrNoRoom
+	}
+	e, ok := r.Exits[d]
+	if !ok {
+		return ErrNoExit
+	}
+	fn(&e)
+	e.Direction = d
+	r.Exits[d] = e
+
+	return nil
+}
+
+// SetDoor opens, closes, locks or unlocks the door of the room's exit in the
+// direction, along with the door on the other side leading back. Locked
+// doors are always closed.
+func (w *World) SetDoor(room string, d Direction, closed, locked bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	r, ok := w.rooms[room]
+	if !ok {
+		return ErrNoRoom
+	}
+	e, ok := r.Exits[d]
+	if !ok {
+		return ErrNoExit
+	}
+	if !e.Door {
+		return ErrNoDoor
+	}
+	closed = closed || locked
+	e.Closed, e.Locked = closed, locked
+	r.Exits[d] = e
+	if other, ok := w.rooms[e.To]; ok {
+		if back, ok := other.Exits[d.Reverse()]; ok && back.Door && back.To == room {
+			back.Closed, back.Locked = closed, locked
+			other.Exits[back.Direction] = back
+		}
+	}
+
+	return nil
+}
+
+// DoorState is whether the door of a room's exit is closed and locked.
+type DoorState struct {
+	Room      string    `json:"room"`
+	Direction Direction `json:"direction"`
+	Closed    bool      `json:"closed,omitempty"`
+	Locked    bool      `json:"locked,omitempty"`
+}
+
+// Doors returns the state of every door in the world, sorted by room and
+// then direction.
+func (w *World) Doors() []DoorState {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var doors []DoorState
+	for id, r := range w.rooms {
+		for d, e := range r.Exits {
+			if e.Door {
+				doors = append(doors, DoorState{Room: id, Direction: d, Closed: e.Closed, Locked: e.Locked})
+			}
+		}
+	}
+	sort.Slice(doors, func(i, j int) bool {
+		if doors[i].Room != doors[j].Room {
+			return doors[i].Room < doors[j].Room
+		}
+
+		return less(doors[i].Direction, doors[j].Direction)
+	})
+
+	return doors
+}
+
+// RestoreDoors leaves each door as the states have it, like when the game
+// is rolled back. Doors that are gone are skipped, each side of a door is
+// restored on its own.
+func (w *World) RestoreDoors(doors []DoorState) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, door := range doors {
+		r, ok := w.rooms[door.Room]
+		if !ok {
+			continue
+		}
+		e, ok := r.Exits[door.Direction]
+		if !ok || !e.Door {
+			continue
+		}
+		e.Closed, e.Locked = door.Closed || door.Locked, door.Locked
+		r.Exits[door.Direction] = e
+	}
+}
+
+// Exit returns the room's exit in the direction.
+func (w *World) Exit(room string, d Direction) (Exit, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	r, ok := w.rooms[room]
+	if !ok {
+		return Exit{}, ErrNoRoom
+	}
+	e, ok := r.Exits[d]
+	if !ok {
+		return Exit{}, ErrNoExit
+	}
+
+	return e, nil
+}
+
+// PathExits describes a room's exits for path.Find, exits to rooms that
+// aren't in the world are left out.
+func (w *World) PathExits(n path.Node) ([]path.Exit, error) {
+	id, _ := n.(string)
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	r, ok := w.rooms[id]
+	if !ok {
+		return nil, ErrNoRoom
+	}
+	exits := make([]path.Exit, 0, len(r.Exits))
+	for _, e := range r.SortedExits(true) {
+		if _, ok := w.rooms[e.To]; !ok {
+			continue
+		}
+		exits = append(exits, path.Exit{
+			Name:   string(e.Direction),
+			To:     e.To,
+			Closed: e.Closed,
+			Locked: e.Locked,
+		})
+	}
+
+	return exits, nil
+}
@@ -0,0 +1,194 @@
+package world_test
+
+import (
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/game/path"
+	. "github.com/bbuck/dragon-mud/game/world"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Direction", func() {
+	It("parses abbreviations", func() {
+		Ω(ParseDirection("NE")).Should(Equal(Northeast))
+		Ω(ParseDirection(" u ")).Should(Equal(Up))
+		Ω(ParseDirection("Portal")).Should(Equal(Direction("portal")))
+	})
+
+	It("reverses standard directions", func() {
+		Ω(North.Reverse()).Should(Equal(South))
+		Ω(Southwest.Reverse()).Should(Equal(Northeast))
+		Ω(Direction("portal").Reverse()).Should(Equal(Direction("")))
+	})
+
+	It("abbreviates standard directions", func() {
+		Ω(Northeast.Abbreviation()).Should(Equal("ne"))
+		Ω(Down.Abbreviation()).Should(Equal("d"))
+		Ω(Direction("portal").Abbreviation()).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("World", func() {
+	var w *World
+
+	BeforeEach(func() {
+		w = New()
+		Ω(w.AddZone(Zone{ID: "town", Name: "Town"})).Should(Succeed())
+		Ω(w.AddRoom(Room{ID: "square", Zone: "town", Name: "Town Square", Flags: map[string]bool{"Outdoors": true}})).Should(Succeed())
+		Ω(w.AddRoom(Room{ID: "gate", Zone: "town", Name: "North Gate"})).Should(Succeed())
+		Ω(w.Link("square", North, "gate")).Should(Succeed())
+	})
+
+	It("rejects duplicate and orphaned rooms", func() {
+		Ω(w.AddZone(Zone{ID: "town"})).Should(Equal(ErrExists))
+		Ω(w.AddRoom(Room{ID: "square", Zone: "town"})).Should(Equal(ErrExists))
+		Ω(w.AddRoom(Room{ID: "cave", Zone: "hills"})).Should(Equal(ErrNoZone))
+		Ω(w.AddRoom(Room{Zone: "town"})).Should(Equal(ErrNoID))
+	})
+
+	It("links rooms both ways", func() {
+		e, err := w.Exit("gate", South)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(e.To).Should(Equal("square"))
+		_, err = w.Exit("gate", North)
+		Ω(err).Should(Equal(ErrNoExit))
+	})
+
+	It("returns copies of rooms", func() {
+		r, _ := w.Room("square")
+		r.Name = "Changed"
+		delete(r.Exits, North)
+
+		r, ok := w.Room("square")
+		Ω(ok).Should(BeTrue())
+		Ω(r.Name).Should(Equal("Town Square"))
+		Ω(r.Flag("outdoors")).Should(BeTrue())
+		Ω(r.Exits).Should(HaveKey(North))
+	})
+
+	It("lists rooms by zone", func() {
+		Ω(w.AddZone(Zone{ID: "hills"})).Should(Succeed())
+		Ω(w.AddRoom(Room{ID: "cave", Zone: "hills"})).Should(Succeed())
+
+		rooms := w.Rooms("town")
+		Ω(rooms).Should(HaveLen(2))
+		Ω(rooms[0].ID).Should(Equal("gate"))
+		Ω(w.Rooms("hills")[0].ID).Should(Equal("cave"))
+		Ω(w.Zones()).Should(HaveLen(2))
+	})
+
+	It("moves rooms between zones", func() {
+		Ω(w.AddZone(Zone{ID: "hills"})).Should(Succeed())
+		Ω(w.UpdateRoom(Room{ID: "gate", Zone: "hills", Name: "Old Gate"})).Should(Succeed())
+
+		Ω(w.Rooms("town")).Should(HaveLen(1))
+		Ω(w.Rooms("hills")[0].Name).Should(Equal("Old Gate"))
+	})
+
+	It("sorts exits and hides hidden ones", func() {
+		Ω(w.SetExit("square", Exit{Direction: "trapdoor", To: "gate", Hidden: true})).Should(Succeed())
+		Ω(w.SetExit("square", Exit{Direction: Up, To: "gate"})).Should(Succeed())
+		Ω(w.SetExit("square", Exit{Direction: East, To: "gate"})).Should(Succeed())
+
+		r, _ := w.Room("square")
+		dirs := func(exits []Exit) []Direction {
+			var ds []Direction
+			for _, e := range exits {
+				ds = append(ds, e.Direction)
+			}
+
+			return ds
+		}
+		Ω(dirs(r.SortedExits(false))).Should(Equal([]Direction{North, East, Up}))
+		Ω(dirs(r.SortedExits(true))).Should(Equal([]Direction{North, East, Up, "trapdoor"}))
+	})
+
+	It("updates doors", func() {
+		Ω(w.UpdateExit("square", North, func(e *Exit) {
+			e.Door, e.Closed, e.Locked = true, true, true
+			e.Direction = South
+		})).Should(Succeed())
+
+		e, err := w.Exit("square", North)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(e.Locked).Should(BeTrue())
+		Ω(e.Direction).Should(Equal(North))
+		Ω(w.UpdateExit("square", West, func(*Exit) {})).Should(Equal(ErrNoExit))
+	})
+
+	It("sets doors on both sides", func() {
+		Ω(w.SetDoor("square", North, true, false)).Should(Equal(ErrNoDoor))
+		for _, side := range []struct {
+			room string
+			dir  Direction
+		}{{"square", North}, {"gate", South}} {
+			Ω(w.UpdateExit(side.room, side.dir, func(e *Exit) {
+				e.Door = true
+			})).Should(Succeed())
+		}
+
+		Ω(w.SetDoor("gate", South, false, true)).Should(Succeed())
+		e, _ := w.Exit("square", North)
+		Ω(e.Closed).Should(BeTrue())
+		Ω(e.Locked).Should(BeTrue())
+		Ω(w.SetDoor("square", North, false, false)).Should(Succeed())
+		e, _ = w.Exit("gate", South)
+		Ω(e.Closed).Should(BeFalse())
+	})
+
+	It("restores the state of doors", func() {
+		Ω(w.UpdateExit("square", North, func(e *Exit) {
+			e.Door, e.Locked = true, true
+		})).Should(Succeed())
+		doors := w.Doors()
+		Ω(doors).Should(Equal([]DoorState{{Room: "square", Direction: North, Locked: true}}))
+
+		Ω(w.SetDoor("square", North, false, false)).Should(Succeed())
+		w.RestoreDoors(append(doors, DoorState{Room: "nowhere", Direction: Up}))
+		e, _ := w.Exit("square", North)
+		Ω(e.Closed).Should(BeTrue())
+		Ω(e.Locked).Should(BeTrue())
+	})
+
+	It("removes exits to removed rooms", func() {
+		Ω(w.RemoveRoom("gate")).Should(Succeed())
+
+		r, _ := w.Room("square")
+		Ω(r.Exits).Should(BeEmpty())
+		Ω(w.Rooms("town")).Should(HaveLen(1))
+	})
+
+	It("removes zones with their rooms", func() {
+		Ω(w.RemoveZone("town")).Should(Succeed())
+
+		_, ok := w.Room("square")
+		Ω(ok).Should(BeFalse())
+		Ω(w.Zones()).Should(BeEmpty())
+	})
+
+	It("finds paths through the world", func() {
+		Ω(w.AddRoom(Room{ID: "road", Zone: "town"})).Should(Succeed())
+		Ω(w.Link("gate", North, "road")).Should(Succeed())
+
+		p, err := path.Find("square", "road", path.Options{Exits: w.PathExits})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Rooms()).Should(Equal([]path.Node{"square", "gate", "road"}))
+	})
+
+	It("links its exits in a graph", func() {
+		g := data.NewMemoryGraph()
+		stale := data.Node{Label: data.RoomLabel, ID: "ruins"}
+		Ω(g.Link(data.Edge{From: stale, Kind: data.ExitKind, To: stale})).Should(Succeed())
+		Ω(w.LinkGraph(g)).Should(Succeed())
+
+		square := data.Node{Label: data.RoomLabel, ID: "square"}
+		edges, err := g.Edges(square, data.ExitKind)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(edges).Should(HaveLen(1))
+		Ω(edges[0].To.ID).Should(Equal("gate"))
+		Ω(edges[0].Props["direction"]).Should(Equal("north"))
+		Ω(g.Edges(stale, "")).Should(BeEmpty())
+	})
+})
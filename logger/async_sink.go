@@ -0,0 +1,176 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBufferSize is the number of entries an AsyncSink holds when the
+// configuration doesn't give a size.
+const DefaultBufferSize = 1024
+
+// Overflow decides what an AsyncSink does with entries logged while its
+// buffer is full.
+type Overflow int
+
+// Overflow policies
+const (
+	// OverflowBlock waits for room in the buffer, so nothing is lost but the
+	// code logging is held up until the sink catches up.
+	OverflowBlock Overflow = iota
+	// OverflowDrop drops entries less severe than errors, errors still wait
+	// for room so failures are never lost. The number dropped is logged once
+	// the sink catches up.
+	OverflowDrop
+)
+
+// ParseOverflow converts the name of a policy, "block" or "drop", to an
+// Overflow.
+func ParseOverflow(name string) (Overflow, error) {
+	switch strings.ToLower(name) {
+	case "block":
+		return OverflowBlock, nil
+	case "", "drop":
+		return OverflowDrop, nil
+	default:
+		return 0, fmt.Errorf("unknown log overflow policy %q", name)
+	}
+}
+
+// an entry waiting to be written, or a request to be told when everything
+// before it has been written
+type asyncItem struct {
+	entry   *Entry
+	flushed chan struct{}
+}
+
+// AsyncSink buffers entries and writes them to another sink from the
+// background, so logging doesn't wait on slow files or network sinks. Fatal
+// and panic entries are written immediately, after everything buffered,
+// since the server exits right after logging them.
+type AsyncSink struct {
+	sink     Sink
+	queue    chan asyncItem
+	overflow Overflow
+	dropped  uint64
+	closed   bool
+	mutex    *sync.RWMutex
+	done     chan struct{}
+}
+
+// NewAsyncSink creates a sink buffering up to size entries for sink, handling
+// a full buffer with the overflow policy.
+func NewAsyncSink(sink Sink, size int, overflow Overflow) *AsyncSink {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	s := &AsyncSink{
+		sink:     sink,
+		queue:    make(chan asyncItem, size),
+		overflow: overflow,
+		mutex:    new(sync.RWMutex),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+// Write buffers the entry, fatal and panic entries are written before
+// returning.
+func (s *AsyncSink) Write(e *Entry) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		return s.sink.Write(e)
+	}
+
+	if e.Level <= FatalLevel {
+		s.flush()
+
+		return s.sink.Write(e)
+	}
+
+	item := asyncItem{entry: e}
+	if s.overflow == OverflowBlock || e.Level <= ErrorLevel {
+		s.queue <- item
+
+		return nil
+	}
+
+	select {
+	case s.queue <- item:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of entries dropped and not yet reported.
+func (s *AsyncSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Flush waits until every entry buffered before it's called is written.
+func (s *AsyncSink) Flush() {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.closed {
+		s.flush()
+	}
+}
+
+// Close writes the buffered entries and closes the sink they're written to.
+func (s *AsyncSink) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mutex.Unlock()
+
+	<-s.done
+
+	return s.sink.Close()
+}
+
+// flush must be called with the read lock held
+func (s *AsyncSink) flush() {
+	flushed := make(chan struct{})
+	s.queue <- asyncItem{flushed: flushed}
+	<-flushed
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	for item := range s.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+
+			continue
+		}
+
+		if n := atomic.SwapUint64(&s.dropped, 0); n > 0 {
+			writeEntry(s.sink, &Entry{
+				Time:    time.Now(),
+				Level:   WarnLevel,
+				Message: "Log buffer was full, entries were dropped.",
+				Fields:  Fields{"prefix": "logger", "dropped": n},
+			})
+		}
+		writeEntry(s.sink, item.entry)
+	}
+}
@@ -0,0 +1,117 @@
+package logger_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// gatedSink holds every write until it's opened, signaling started as each
+// write begins
+type gatedSink struct {
+	gate    chan struct{}
+	started chan struct{}
+	mutex   sync.Mutex
+	entries []*logger.Entry
+	closed  bool
+}
+
+func (g *gatedSink) Write(e *logger.Entry) error {
+	select {
+	case g.started <- struct{}{}:
+	default:
+	}
+	<-g.gate
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.entries = append(g.entries, e)
+
+	return nil
+}
+
+func (g *gatedSink) Close() error {
+	g.closed = true
+
+	return nil
+}
+
+func (g *gatedSink) messages() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	msgs := make([]string, len(g.entries))
+	for i, e := range g.entries {
+		msgs[i] = e.Message
+	}
+
+	return msgs
+}
+
+var _ = Describe("AsyncSink", func() {
+	var inner *gatedSink
+
+	entry := func(level logger.LogLevel, msg string) *logger.Entry {
+		return &logger.Entry{Time: time.Now(), Level: level, Message: msg}
+	}
+
+	BeforeEach(func() {
+		inner = &gatedSink{
+			gate:    make(chan struct{}),
+			started: make(chan struct{}, 10),
+		}
+	})
+
+	It("writes in the background", func() {
+		sink := logger.NewAsyncSink(inner, 10, logger.OverflowBlock)
+		Ω(sink.Write(entry(logger.InfoLevel, "one"))).Should(Succeed())
+		Ω(sink.Write(entry(logger.InfoLevel, "two"))).Should(Succeed())
+		Ω(inner.messages()).Should(BeEmpty())
+
+		close(inner.gate)
+		sink.Flush()
+		Ω(inner.messages()).Should(Equal([]string{"one", "two"}))
+	})
+
+	It("drops less severe entries when full", func() {
+		sink := logger.NewAsyncSink(inner, 1, logger.OverflowDrop)
+		// the first is taken by the writer, the second fills the buffer
+		sink.Write(entry(logger.InfoLevel, "one"))
+		Eventually(inner.started).Should(Receive())
+		sink.Write(entry(logger.InfoLevel, "two"))
+		sink.Write(entry(logger.InfoLevel, "three"))
+
+		Ω(sink.Dropped()).Should(Equal(uint64(1)))
+
+		close(inner.gate)
+		sink.Flush()
+		Ω(inner.messages()).Should(Equal([]string{
+			"one",
+			"Log buffer was full, entries were dropped.",
+			"two",
+		}))
+	})
+
+	It("writes everything buffered before closing", func() {
+		sink := logger.NewAsyncSink(inner, 10, logger.OverflowBlock)
+		sink.Write(entry(logger.InfoLevel, "one"))
+		close(inner.gate)
+
+		Ω(sink.Close()).Should(Succeed())
+		Ω(inner.messages()).Should(Equal([]string{"one"}))
+		Ω(inner.closed).Should(BeTrue())
+	})
+
+	It("writes fatal entries immediately after the buffer", func() {
+		sink := logger.NewAsyncSink(inner, 10, logger.OverflowBlock)
+		close(inner.gate)
+		sink.Write(entry(logger.InfoLevel, "one"))
+		sink.Write(entry(logger.FatalLevel, "the end"))
+
+		Ω(inner.messages()).Should(Equal([]string{"one", "the end"}))
+	})
+})
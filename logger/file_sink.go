@@ -0,0 +1,282 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the UTC timestamp added to the names of rotated files, it sorts in the same
+// order as the times it represents
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Rotation controls when a FileSink starts a new file and how many of the old
+// files are kept. Zero values disable each setting.
+type Rotation struct {
+	// MaxSize is the size in bytes a file can grow to before it's rotated.
+	MaxSize int64
+	// Interval is how long a file is written to before it's rotated.
+	Interval time.Duration
+	// MaxAge is how long rotated files are kept.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, the oldest are removed
+	// first.
+	MaxBackups int
+	// Compress rotated files with gzip.
+	Compress bool
+}
+
+// FileSink appends formatted entries to a file, creating it if necessary.
+// Rotated files are renamed with the time (in UTC) they were rotated, so
+// "game.log" becomes "game-2017-01-02T15-04-05.000.log".
+type FileSink struct {
+	path     string
+	format   Format
+	rotation Rotation
+	file     *os.File
+	size     int64
+	opened   time.Time
+	mutex    *sync.Mutex
+	now      func() time.Time
+}
+
+// NewFileSink opens the file at path for appending, it's never rotated.
+func NewFileSink(path string, format Format) (*FileSink, error) {
+	return NewRotatingFileSink(path, format, Rotation{})
+}
+
+// NewRotatingFileSink opens the file at path for appending, rotating it as
+// described by rotation.
+func NewRotatingFileSink(path string, format Format, rotation Rotation) (*FileSink, error) {
+	s := &FileSink{
+		path:     path,
+		format:   format,
+		rotation: rotation,
+		mutex:    new(sync.Mutex),
+		now:      time.Now,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (s *FileSink) SetClock(now func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.now = now
+	s.opened = now()
+}
+
+// Write formats and appends the entry, rotating the file first if needed.
+func (s *FileSink) Write(e *Entry) error {
+	b, err := s.format(e)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.shouldRotate(int64(len(b))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(b)
+	s.size += int64(n)
+
+	return err
+}
+
+// Rotate starts a new file immediately.
+func (s *FileSink) Rotate() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rotate()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.opened = s.now()
+
+	return nil
+}
+
+// empty files are never rotated, even if the entry alone is larger than the
+// maximum size
+func (s *FileSink) shouldRotate(next int64) bool {
+	if s.size == 0 {
+		return false
+	}
+
+	if s.rotation.MaxSize > 0 && s.size+next > s.rotation.MaxSize {
+		return true
+	}
+
+	return s.rotation.Interval > 0 && s.now().Sub(s.opened) >= s.rotation.Interval
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	backup := s.backupName(s.now())
+	if err := os.Rename(s.path, backup); err != nil {
+		// keep logging to the current file rather than losing entries
+		s.open()
+
+		return err
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.rotation.Compress {
+		if err := compressFile(backup); err != nil {
+			return err
+		}
+	}
+
+	return s.removeOldBackups()
+}
+
+// split the path into the part before the timestamp and the extension
+func (s *FileSink) nameParts() (string, string) {
+	ext := filepath.Ext(s.path)
+
+	return strings.TrimSuffix(s.path, ext) + "-", ext
+}
+
+func (s *FileSink) backupName(t time.Time) string {
+	prefix, ext := s.nameParts()
+
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backups returns the rotated files, newest first, along with the time each
+// was rotated
+func (s *FileSink) backups() ([]string, []time.Time, error) {
+	prefix, ext := s.nameParts()
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		names []string
+		times []time.Time
+	)
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for _, name := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix))
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		times = append(times, t)
+	}
+
+	return names, times, nil
+}
+
+func (s *FileSink) removeOldBackups() error {
+	if s.rotation.MaxBackups <= 0 && s.rotation.MaxAge <= 0 {
+		return nil
+	}
+
+	names, times, err := s.backups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := s.now().Add(-s.rotation.MaxAge)
+	for i, name := range names {
+		tooMany := s.rotation.MaxBackups > 0 && i >= s.rotation.MaxBackups
+		tooOld := s.rotation.MaxAge > 0 && times[i].Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compressFile replaces the file with a gzipped copy named with ".gz"
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".compress")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, in); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,112 @@
+package logger_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileSink", func() {
+	var (
+		dir   string
+		path  string
+		now   time.Time
+		entry *logger.Entry
+	)
+
+	plain := func(e *logger.Entry) ([]byte, error) {
+		return []byte(e.Message + "\n"), nil
+	}
+
+	files := func() []string {
+		infos, _ := ioutil.ReadDir(dir)
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+
+		return names
+	}
+
+	open := func(rotation logger.Rotation) *logger.FileSink {
+		sink, err := logger.NewRotatingFileSink(path, plain, rotation)
+		Ω(err).Should(BeNil())
+		sink.SetClock(func() time.Time {
+			return now
+		})
+
+		return sink
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "logger")
+		path = filepath.Join(dir, "game.log")
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		entry = &logger.Entry{Message: "0123456789"}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("rotates files that grow too large", func() {
+		sink := open(logger.Rotation{MaxSize: 15})
+		defer sink.Close()
+
+		sink.Write(entry)
+		now = now.Add(time.Second)
+		sink.Write(entry)
+
+		Ω(files()).Should(Equal([]string{"game-2017-01-01T00-00-01.000.log", "game.log"}))
+		contents, _ := ioutil.ReadFile(path)
+		Ω(string(contents)).Should(Equal("0123456789\n"))
+	})
+
+	It("rotates files on an interval", func() {
+		sink := open(logger.Rotation{Interval: time.Hour})
+		defer sink.Close()
+
+		sink.Write(entry)
+		now = now.Add(30 * time.Minute)
+		sink.Write(entry)
+		Ω(files()).Should(HaveLen(1))
+
+		now = now.Add(30 * time.Minute)
+		sink.Write(entry)
+		Ω(files()).Should(HaveLen(2))
+	})
+
+	It("removes old backups", func() {
+		sink := open(logger.Rotation{MaxSize: 1, MaxBackups: 2, MaxAge: 3 * time.Hour})
+		defer sink.Close()
+
+		for i := 0; i < 5; i++ {
+			sink.Write(entry)
+			now = now.Add(time.Hour)
+		}
+		Ω(files()).Should(HaveLen(3))
+
+		now = now.Add(3 * time.Hour)
+		sink.Rotate()
+		Ω(files()).Should(HaveLen(2))
+	})
+
+	It("compresses backups", func() {
+		sink := open(logger.Rotation{Compress: true})
+		defer sink.Close()
+
+		sink.Write(entry)
+		Ω(sink.Rotate()).Should(Succeed())
+
+		names := files()
+		Ω(names).Should(HaveLen(2))
+		Ω(strings.HasSuffix(names[0], ".log.gz")).Should(BeTrue())
+	})
+})
@@ -0,0 +1,104 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// sinks attached while the server runs, receiving entries alongside the
+// configured sinks
+var (
+	attachedSinks = make(map[uint64]Sink)
+	nextAttached  uint64
+	attachMutex   = new(sync.RWMutex)
+)
+
+// AttachSink adds a sink that receives every logged entry in addition to the
+// configured sinks, until the returned function is called to detach it.
+func AttachSink(s Sink) (detach func()) {
+	attachMutex.Lock()
+	defer attachMutex.Unlock()
+
+	id := nextAttached
+	nextAttached++
+	attachedSinks[id] = s
+
+	return func() {
+		attachMutex.Lock()
+		defer attachMutex.Unlock()
+
+		delete(attachedSinks, id)
+	}
+}
+
+// sinkHook passes every entry logged through logrus on to a sink, dropping
+// any that are more detailed than the level of their source
+type sinkHook struct {
+	sink Sink
+}
+
+// newSinkLogger creates a logrus logger that only writes through the sink
+func newSinkLogger(s Sink) Log {
+	l := logrus.New()
+	l.Formatter = discardFormatter{}
+	l.Out = ioutil.Discard
+	l.Hooks.Add(&sinkHook{sink: s})
+	setRoot(l)
+
+	return newLogrusLogger(l)
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *sinkHook) Fire(le *logrus.Entry) error {
+	level := logrusLevelToLogLevel(le.Level)
+	source, _ := le.Data["prefix"].(string)
+	if !enabled(source, level) {
+		return nil
+	}
+
+	fields := make(Fields, len(le.Data))
+	for k, v := range le.Data {
+		fields[k] = v
+	}
+
+	e := &Entry{
+		Time:    le.Time,
+		Level:   level,
+		Message: le.Message,
+		Fields:  fields,
+	}
+	writeEntry(h.sink, e)
+
+	attachMutex.RLock()
+	defer attachMutex.RUnlock()
+
+	for _, s := range attachedSinks {
+		writeEntry(s, e)
+	}
+
+	return nil
+}
+
+func writeEntry(s Sink, e *Entry) {
+	if err := s.Write(e); err != nil {
+		// the log itself is failing so there is nowhere better to report it
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to write log entry: %s\n", err)
+	}
+}
+
+// discardFormatter skips formatting entries that logrus would otherwise
+// write to its output, sinks do their own formatting
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
@@ -1,7 +1,6 @@
 package logger_test
 
 import (
-	"github.com/Sirupsen/logrus"
 	"github.com/bbuck/dragon-mud/logger"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,28 +9,28 @@ import (
 var _ = Describe("Level", func() {
 	Describe("generating log level from string", func() {
 		It("should default to debug", func() {
-			Ω(logger.GetLogLevel("")).Should(Equal(logrus.DebugLevel))
+			Ω(logger.GetLogLevel("")).Should(Equal(logger.DebugLevel))
 		})
 
 		It("should choose fatal level", func() {
-			Ω(logger.GetLogLevel("fatal")).Should(Equal(logrus.FatalLevel))
+			Ω(logger.GetLogLevel("fatal")).Should(Equal(logger.FatalLevel))
 		})
 
 		It("should choose panic level", func() {
-			Ω(logger.GetLogLevel("panic")).Should(Equal(logrus.PanicLevel))
+			Ω(logger.GetLogLevel("panic")).Should(Equal(logger.PanicLevel))
 		})
 
 		It("should choose warn level", func() {
-			Ω(logger.GetLogLevel("warn")).Should(Equal(logrus.WarnLevel))
-			Ω(logger.GetLogLevel("warning")).Should(Equal(logrus.WarnLevel))
+			Ω(logger.GetLogLevel("warn")).Should(Equal(logger.WarnLevel))
+			Ω(logger.GetLogLevel("warning")).Should(Equal(logger.WarnLevel))
 		})
 
 		It("should choose info level", func() {
-			Ω(logger.GetLogLevel("info")).Should(Equal(logrus.InfoLevel))
+			Ω(logger.GetLogLevel("info")).Should(Equal(logger.InfoLevel))
 		})
 
 		It("should choose debug level", func() {
-			Ω(logger.GetLogLevel("debug")).Should(Equal(logrus.DebugLevel))
+			Ω(logger.GetLogLevel("debug")).Should(Equal(logger.DebugLevel))
 		})
 	})
 })
@@ -0,0 +1,111 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Levels can be set for each source (the name given to NewWithSource) so that
+// detailed logging can be turned on for a single part of the game while it
+// runs. Sources without their own level use the default level.
+var (
+	levelMutex   = new(sync.RWMutex)
+	defaultLevel = DebugLevel
+	sourceLevels = make(map[string]LogLevel)
+	root         *logrus.Logger
+)
+
+// SetLevel sets the most detailed level logged for the source.
+func SetLevel(source string, level LogLevel) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+
+	sourceLevels[source] = level
+	applyLevels()
+}
+
+// ClearLevel removes the level set for the source so it uses the default
+// level again.
+func ClearLevel(source string) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+
+	delete(sourceLevels, source)
+	applyLevels()
+}
+
+// SetDefaultLevel sets the level for sources without their own level.
+func SetDefaultLevel(level LogLevel) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+
+	defaultLevel = level
+	applyLevels()
+}
+
+// DefaultLevel returns the level used by sources without their own level.
+func DefaultLevel() LogLevel {
+	levelMutex.RLock()
+	defer levelMutex.RUnlock()
+
+	return defaultLevel
+}
+
+// LevelFor returns the most detailed level logged for the source.
+func LevelFor(source string) LogLevel {
+	levelMutex.RLock()
+	defer levelMutex.RUnlock()
+
+	if level, ok := sourceLevels[source]; ok {
+		return level
+	}
+
+	return defaultLevel
+}
+
+// SourceLevels returns a copy of the levels set for individual sources.
+func SourceLevels() map[string]LogLevel {
+	levelMutex.RLock()
+	defer levelMutex.RUnlock()
+
+	levels := make(map[string]LogLevel, len(sourceLevels))
+	for source, level := range sourceLevels {
+		levels[source] = level
+	}
+
+	return levels
+}
+
+// enabled determines if an entry from the source at the level is logged
+func enabled(source string, level LogLevel) bool {
+	return level <= LevelFor(source)
+}
+
+// logrus drops entries before they reach the sinks when they're more detailed
+// than its level, so it's set to the most detailed level of any source and the
+// sink hook filters the rest. levelMutex must be held.
+func applyLevels() {
+	if root == nil {
+		return
+	}
+
+	most := defaultLevel
+	for _, level := range sourceLevels {
+		if level > most {
+			most = level
+		}
+	}
+	root.Level = logLevelToLogrusLevel(most)
+}
+
+// setRoot makes l the logger whose level follows the source levels
+func setRoot(l *logrus.Logger) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+
+	root = l
+	applyLevels()
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// levelsResponse is the JSON body returned by the levels handler
+type levelsResponse struct {
+	Default string            `json:"default"`
+	Sources map[string]string `json:"sources"`
+}
+
+// LevelsHandler serves the log levels so they can be changed while the server
+// runs. GET returns the default level and the level of each source that has
+// its own. POST sets the level given in the "level" parameter for the
+// "source" parameter, or the default level when no source is given. DELETE
+// removes the level set for the "source" parameter. Every request responds
+// with the levels after the change. Levels are only changed by requests
+// canChange allows, with a nil canChange the levels are read only.
+func LevelsHandler(canChange func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := r.FormValue("source")
+
+		if r.Method != http.MethodGet && (canChange == nil || !canChange(r)) {
+			http.Error(w, "changing log levels isn't allowed", http.StatusForbidden)
+
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			level, err := ParseLogLevel(r.FormValue("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			if source == "" {
+				SetDefaultLevel(level)
+			} else {
+				SetLevel(source, level)
+			}
+			New().WithFields(Fields{
+				"source": source,
+				"level":  level.String(),
+			}).Info("Log level changed.")
+		case http.MethodDelete:
+			if source == "" {
+				http.Error(w, "a source is required", http.StatusBadRequest)
+
+				return
+			}
+			ClearLevel(source)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		resp := levelsResponse{
+			Default: DefaultLevel().String(),
+			Sources: make(map[string]string),
+		}
+		for s, l := range SourceLevels() {
+			resp.Sources[s] = l.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,134 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source levels", func() {
+	BeforeEach(func() {
+		logger.TestLog()
+		logger.SetDefaultLevel(logger.InfoLevel)
+	})
+
+	AfterEach(func() {
+		for source := range logger.SourceLevels() {
+			logger.ClearLevel(source)
+		}
+	})
+
+	It("logs debug messages from only the source given a debug level", func() {
+		logger.SetLevel("combat", logger.DebugLevel)
+		logger.NewWithSource("combat").Debug("swing")
+		logger.NewWithSource("weather").Debug("rain")
+
+		Ω(logger.TestBuffer.String()).Should(ContainSubstring("swing"))
+		Ω(logger.TestBuffer.String()).ShouldNot(ContainSubstring("rain"))
+	})
+
+	It("quiets a source below the default level", func() {
+		logger.SetLevel("weather", logger.ErrorLevel)
+		logger.NewWithSource("weather").Info("rain")
+		logger.NewWithSource("combat").Info("swing")
+
+		Ω(logger.TestBuffer.String()).Should(ContainSubstring("swing"))
+		Ω(logger.TestBuffer.String()).ShouldNot(ContainSubstring("rain"))
+	})
+
+	It("returns to the default level when cleared", func() {
+		logger.SetLevel("combat", logger.DebugLevel)
+		logger.ClearLevel("combat")
+
+		Ω(logger.LevelFor("combat")).Should(Equal(logger.InfoLevel))
+		logger.NewWithSource("combat").Debug("swing")
+		Ω(logger.TestBuffer.String()).ShouldNot(ContainSubstring("swing"))
+	})
+
+	It("sets the source level through a source logger", func() {
+		log := logger.NewWithSource("combat")
+		log.SetLevel(logger.WarnLevel)
+
+		Ω(log.Level()).Should(Equal(logger.WarnLevel))
+		Ω(logger.DefaultLevel()).Should(Equal(logger.InfoLevel))
+	})
+
+	Describe("handler", func() {
+		var handler http.Handler
+
+		levels := func(rec *httptest.ResponseRecorder) map[string]interface{} {
+			var body map[string]interface{}
+			Ω(json.Unmarshal(rec.Body.Bytes(), &body)).Should(Succeed())
+
+			return body
+		}
+
+		post := func(form url.Values) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/debug/log/levels", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer s3cret")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			return rec
+		}
+
+		BeforeEach(func() {
+			handler = logger.LevelsHandler(func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer s3cret"
+			})
+		})
+
+		It("sets a source level", func() {
+			rec := post(url.Values{"source": {"combat"}, "level": {"debug"}})
+
+			Ω(rec.Code).Should(Equal(http.StatusOK))
+			Ω(logger.LevelFor("combat")).Should(Equal(logger.DebugLevel))
+			Ω(levels(rec)["sources"]).Should(HaveKeyWithValue("combat", "debug"))
+		})
+
+		It("sets the default level without a source", func() {
+			rec := post(url.Values{"level": {"warn"}})
+
+			Ω(levels(rec)["default"]).Should(Equal("warn"))
+		})
+
+		It("rejects unknown levels", func() {
+			rec := post(url.Values{"source": {"combat"}, "level": {"loud"}})
+
+			Ω(rec.Code).Should(Equal(http.StatusBadRequest))
+		})
+
+		It("clears a source level", func() {
+			logger.SetLevel("combat", logger.DebugLevel)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/debug/log/levels?source=combat", nil)
+			req.Header.Set("Authorization", "Bearer s3cret")
+			handler.ServeHTTP(rec, req)
+
+			Ω(rec.Code).Should(Equal(http.StatusOK))
+			Ω(levels(rec)["sources"]).ShouldNot(HaveKey("combat"))
+		})
+
+		It("only lets allowed requests change levels", func() {
+			logger.SetLevel("combat", logger.DebugLevel)
+			for _, h := range []http.Handler{handler, logger.LevelsHandler(nil)} {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/debug/log/levels?source=combat", nil))
+				Ω(rec.Code).Should(Equal(http.StatusForbidden))
+
+				rec = httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/log/levels", nil))
+				Ω(rec.Code).Should(Equal(http.StatusOK))
+			}
+			Ω(logger.LevelFor("combat")).Should(Equal(logger.DebugLevel))
+		})
+	})
+})
@@ -4,6 +4,7 @@ package logger
 
 import "io"
 
+// LogLevel is the severity of a log message, lower levels are more severe.
 type LogLevel uint8
 
 // Log level definitions for the Log interface
@@ -16,6 +17,26 @@ const (
 	DebugLevel
 )
 
+// String returns the name of the level as accepted by GetLogLevel.
+func (l LogLevel) String() string {
+	switch l {
+	case PanicLevel:
+		return "panic"
+	case FatalLevel:
+		return "fatal"
+	case ErrorLevel:
+		return "error"
+	case WarnLevel:
+		return "warn"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	default:
+		return "unknown"
+	}
+}
+
 // Fields is a map containing any fields to log with.
 type Fields map[string]interface{}
 
@@ -50,5 +71,6 @@ type Log interface {
 	WithField(key string, value interface{}) Log
 	WithFields(fields Fields) Log
 	SetLevel(LogLevel)
+	Level() LogLevel
 	SetOut(io.Writer)
 }
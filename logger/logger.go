@@ -8,17 +8,18 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/bbuck/dragon-mud/errs"
 	"github.com/bbuck/dragon-mud/output"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
-	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
 
 var (
 	log         Log
+	sink        Sink
+	ring        = NewRingSink(DefaultRingSize)
 	initialized = false
 )
 
@@ -32,10 +33,11 @@ var (
 // TestLog should never be called in normal code, it's purpose is to bypass the
 // logger generated from configuration settings
 func TestLog() Log {
-	log = newLogrus(new(logrus.JSONFormatter))
+	initialized = true
 	TestBuffer = new(bytes.Buffer)
-	log.SetOut(TestBuffer)
-	log.SetLevel(DebugLevel)
+	ring.SetSize(DefaultRingSize)
+	log = newSinkLogger(MultiSink{NewWriterSink(TestBuffer, JSONFormat), ring})
+	SetDefaultLevel(DebugLevel)
 
 	return log
 }
@@ -49,14 +51,80 @@ func New() Log {
 			return TestLog()
 		}
 
-		log = newLogrus(&prefixed.TextFormatter{DisableTimestamp: false})
-		log.SetOut(ConfigureTargets(viper.Get("log.targets")))
-		log.SetLevel(GetLogLevel(viper.GetString("log.level")))
+		var err error
+		sink, err = configuredSink()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Failed to configure logging: %s\n", err)
+			os.Exit(errs.ErrLoggerLoad)
+		}
+
+		log = newSinkLogger(sink)
+		SetDefaultLevel(GetLogLevel(viper.GetString("log.level")))
 	}
 
 	return log
 }
 
+// configuredSink builds the sink every entry is written to from the log
+// settings: the targets, buffered if buffer_size is set, along with the ring
+// buffer read by Tail, all behind sampling if it's enabled.
+func configuredSink() (Sink, error) {
+	s, err := ConfigureSinks(viper.Get("log.targets"))
+	if err != nil {
+		return nil, err
+	}
+
+	if size := viper.GetInt("log.buffer_size"); size > 0 {
+		overflow, err := ParseOverflow(viper.GetString("log.overflow"))
+		if err != nil {
+			s.Close()
+
+			return nil, err
+		}
+		s = NewAsyncSink(s, size, overflow)
+	}
+
+	ring.SetSize(viper.GetInt("log.ring_size"))
+	s = MultiSink{s, ring}
+
+	if window := viper.GetString("log.sample_window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			s.Close()
+
+			return nil, fmt.Errorf("invalid log sample_window: %s", err)
+		}
+		if every := viper.GetInt("log.sample_every"); d > 0 && every > 1 {
+			s = NewSamplingSink(s, d, every)
+		}
+	}
+
+	return s, nil
+}
+
+// Close closes the sinks log entries are written to, writing any entries
+// still buffered first. It should only be called as the server exits.
+func Close() error {
+	if sink == nil {
+		return nil
+	}
+
+	return sink.Close()
+}
+
+// Tail returns up to the last n entries logged from the source, oldest first.
+// Only the most recent entries are kept for each source, log.ring_size of
+// them, and an n of zero or less returns all of those.
+func Tail(source string, n int) []*Entry {
+	return ring.Tail(source, n)
+}
+
+// TailSources returns the sorted names of the sources that Tail has entries
+// for.
+func TailSources() []string {
+	return ring.Sources()
+}
+
 // NewWithSource returns a log with a predefined "source" field attached to it.
 // This should be the primary method used to fetch a logger for use in other
 // parts fo the code.
@@ -66,8 +134,79 @@ func NewWithSource(source string) Log {
 	return log.WithField("prefix", source)
 }
 
+// ParseLogLevel converts the name of a level to a LogLevel, unlike GetLogLevel
+// unknown names are an error instead of defaulting to debug.
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error", "panic", "fatal":
+		return GetLogLevel(level), nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
 type logTarget struct {
-	Type, Target string
+	Type, Target, Format string
+
+	// file rotation settings
+	MaxSize     int    `mapstructure:"max_size"`
+	RotateEvery string `mapstructure:"rotate_every"`
+	MaxAge      string `mapstructure:"max_age"`
+	MaxBackups  int    `mapstructure:"max_backups"`
+	Compress    bool
+
+	// syslog settings
+	Network, Address, Tag string
+
+	// sentry settings
+	Environment string
+	BatchSize   int    `mapstructure:"batch_size"`
+	FlushEvery  string `mapstructure:"flush_every"`
+}
+
+// rotation converts the rotation settings of a file target, max_size is given
+// in megabytes and durations as strings like "24h".
+func (t logTarget) rotation() (Rotation, error) {
+	r := Rotation{
+		MaxSize:    int64(t.MaxSize) * 1024 * 1024,
+		MaxBackups: t.MaxBackups,
+		Compress:   t.Compress,
+	}
+
+	var err error
+	if t.RotateEvery != "" {
+		if r.Interval, err = time.ParseDuration(t.RotateEvery); err != nil {
+			return r, fmt.Errorf("invalid rotate_every for log target %q: %s", t.Target, err)
+		}
+	}
+	if t.MaxAge != "" {
+		if r.MaxAge, err = time.ParseDuration(t.MaxAge); err != nil {
+			return r, fmt.Errorf("invalid max_age for log target %q: %s", t.Target, err)
+		}
+	}
+
+	return r, nil
+}
+
+// sentryOptions converts the settings of a sentry target, the environment
+// defaults to the one the server is running in.
+func (t logTarget) sentryOptions() (SentryOptions, error) {
+	opts := SentryOptions{
+		Environment: t.Environment,
+		BatchSize:   t.BatchSize,
+	}
+	if opts.Environment == "" {
+		opts.Environment = viper.GetString("env")
+	}
+
+	if t.FlushEvery != "" {
+		var err error
+		if opts.FlushEvery, err = time.ParseDuration(t.FlushEvery); err != nil {
+			return opts, fmt.Errorf("invalid flush_every for sentry log target: %s", err)
+		}
+	}
+
+	return opts, nil
 }
 
 // GetLogLevel converts a string value to a logrus.Level value for use in
@@ -91,9 +230,110 @@ func GetLogLevel(level string) LogLevel {
 	}
 }
 
+// ConfigureSinks takes a 'JSON' list of log targets and builds the sink that
+// writes to all of them. Each target has a type:
+//   terminal: writes to stdout, or stderr if the target is "error"
+//   file: appends to the file at target, rotating it based on max_size (in
+//     megabytes), rotate_every, max_age, max_backups and compress
+//   json: like file, but always in the JSON lines format. A target of
+//     "terminal" or "error" writes JSON to stdout or stderr.
+//   syslog: sends to the syslog daemon at network and address (the local
+//     daemon if empty) tagged with tag
+//   sentry: reports errors to the Sentry compatible DSN in target, sending
+//     batch_size errors at a time or every flush_every
+// Terminal and file targets default to the text format unless format is
+// "json". Without any targets entries are written to stdout.
+func ConfigureSinks(targets interface{}) (Sink, error) {
+	if targets == nil {
+		return NewWriterSink(output.Stdout(), TextFormat), nil
+	}
+
+	var logTargets []logTarget
+	if err := mapstructure.Decode(targets, &logTargets); err != nil {
+		return nil, fmt.Errorf("failed to process log targets: %s", err)
+	}
+
+	var sinks MultiSink
+	for _, target := range logTargets {
+		s, err := sinkForTarget(target)
+		if err != nil {
+			sinks.Close()
+
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	switch len(sinks) {
+	case 0:
+		return NewWriterSink(output.Stdout(), TextFormat), nil
+	case 1:
+		return sinks[0], nil
+	default:
+		return sinks, nil
+	}
+}
+
+func sinkForTarget(target logTarget) (Sink, error) {
+	if target.Type == "syslog" {
+		tag := target.Tag
+		if tag == "" {
+			tag = "dragon-mud"
+		}
+
+		return NewSyslogSink(target.Network, target.Address, tag)
+	}
+
+	if target.Type == "sentry" {
+		opts, err := target.sentryOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSentrySink(target.Target, opts)
+	}
+
+	format, err := FormatFor(target.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Type == "json" {
+		if target.Target == "terminal" || target.Target == "error" {
+			return consoleSink(target.Target, JSONFormat), nil
+		}
+		target.Type = "file"
+		format = JSONFormat
+	}
+
+	switch target.Type {
+	case "terminal":
+		return consoleSink(target.Target, format), nil
+	case "file":
+		rotation, err := target.rotation()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRotatingFileSink(target.Target, format, rotation)
+	default:
+		return nil, fmt.Errorf("unknown log target type %q", target.Type)
+	}
+}
+
+func consoleSink(target string, format Format) Sink {
+	if target == "error" {
+		return NewWriterSink(output.Stderr(), format)
+	}
+
+	return NewWriterSink(output.Stdout(), format)
+}
+
 // ConfigureTargets takes a 'JSON' map that defines what log targets there
 // should be and converts them into an io.Writer suitable for being the target
-// of a logrus.Output, this can be a io.MultiWriter or just a writer
+// of a logrus.Output, this can be a io.MultiWriter or just a writer.
+//
+// Deprecated: targets are written through sinks, see ConfigureSinks.
 func ConfigureTargets(targets interface{}) io.Writer {
 	if targets != nil {
 		var (
@@ -129,11 +369,14 @@ func ConfigureTargets(targets interface{}) io.Writer {
 			}
 		}
 
-		if len(writers) > 1 {
+		switch len(writers) {
+		case 0:
+			return output.Stdout()
+		case 1:
+			return writers[0]
+		default:
 			return io.MultiWriter(writers...)
 		}
-
-		return writers[0]
 	}
 
 	return output.Stdout()
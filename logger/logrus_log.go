@@ -39,8 +39,13 @@ func (ll *logrusLogger) WithFields(fs Fields) Log {
 	return newLogrusEntryLogger(l)
 }
 
+// SetLevel sets the default level, sources with their own level are unchanged
 func (ll *logrusLogger) SetLevel(lvl LogLevel) {
-	ll.Logger.Level = logLevelToLogrusLevel(lvl)
+	SetDefaultLevel(lvl)
+}
+
+func (ll *logrusLogger) Level() LogLevel {
+	return DefaultLevel()
 }
 
 func (ll *logrusLogger) SetOut(w io.Writer) {
@@ -78,8 +83,26 @@ func (ll *logrusEntryLogger) WithFields(fs Fields) Log {
 	return newLogrusEntryLogger(l)
 }
 
+// SetLevel sets the level of the entry's source, or the default level if it
+// has no source
 func (ll *logrusEntryLogger) SetLevel(lvl LogLevel) {
-	ll.Entry.Level = logLevelToLogrusLevel(lvl)
+	if source := ll.source(); source != "" {
+		SetLevel(source, lvl)
+
+		return
+	}
+
+	SetDefaultLevel(lvl)
+}
+
+func (ll *logrusEntryLogger) Level() LogLevel {
+	return LevelFor(ll.source())
+}
+
+func (ll *logrusEntryLogger) source() string {
+	source, _ := ll.Data["prefix"].(string)
+
+	return source
 }
 
 func (ll *logrusEntryLogger) SetOut(w io.Writer) {
@@ -118,3 +141,20 @@ func logLevelToLogrusLevel(lvl LogLevel) logrus.Level {
 		return logrus.WarnLevel
 	}
 }
+
+func logrusLevelToLogLevel(lvl logrus.Level) LogLevel {
+	switch lvl {
+	case logrus.PanicLevel:
+		return PanicLevel
+	case logrus.FatalLevel:
+		return FatalLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultRingSize is the number of entries kept for each source when the
+// configuration doesn't give a size.
+const DefaultRingSize = 200
+
+// RingSink keeps the most recent entries from each source in memory so they
+// can be read back without searching log files. Entries without a source are
+// kept under the empty source.
+type RingSink struct {
+	size  int
+	rings map[string]*entryRing
+	mutex *sync.RWMutex
+}
+
+// entryRing is a fixed size buffer overwriting its oldest entry once full
+type entryRing struct {
+	entries []*Entry
+	next    int
+	full    bool
+}
+
+// NewRingSink creates a sink keeping the last size entries from each source,
+// a size of zero or less keeps nothing.
+func NewRingSink(size int) *RingSink {
+	return &RingSink{
+		size:  size,
+		rings: make(map[string]*entryRing),
+		mutex: new(sync.RWMutex),
+	}
+}
+
+// SetSize changes the number of entries kept for each source, discarding the
+// entries kept so far.
+func (r *RingSink) SetSize(size int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.size = size
+	r.rings = make(map[string]*entryRing)
+}
+
+// Write keeps the entry, replacing the oldest from its source if the buffer
+// for the source is full.
+func (r *RingSink) Write(e *Entry) error {
+	source, _ := e.Fields["prefix"].(string)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.size <= 0 {
+		return nil
+	}
+
+	ring, ok := r.rings[source]
+	if !ok {
+		ring = &entryRing{entries: make([]*Entry, r.size)}
+		r.rings[source] = ring
+	}
+
+	ring.entries[ring.next] = e
+	ring.next = (ring.next + 1) % r.size
+	if ring.next == 0 {
+		ring.full = true
+	}
+
+	return nil
+}
+
+// Close does nothing, the entries are kept so they can still be read.
+func (r *RingSink) Close() error {
+	return nil
+}
+
+// Tail returns up to the last n entries from the source, oldest first. An n
+// of zero or less returns every entry kept for the source.
+func (r *RingSink) Tail(source string, n int) []*Entry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ring, ok := r.rings[source]
+	if !ok {
+		return []*Entry{}
+	}
+
+	count := ring.next
+	if ring.full {
+		count = r.size
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	tail := make([]*Entry, n)
+	start := ring.next - n
+	if start < 0 {
+		start += r.size
+	}
+	for i := range tail {
+		tail[i] = ring.entries[(start+i)%r.size]
+	}
+
+	return tail
+}
+
+// Sources returns the sorted names of the sources with entries kept.
+func (r *RingSink) Sources() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	sources := make([]string, 0, len(r.rings))
+	for source := range r.rings {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	return sources
+}
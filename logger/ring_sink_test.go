@@ -0,0 +1,65 @@
+package logger_test
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RingSink", func() {
+	var ring *logger.RingSink
+
+	write := func(source string, count int) {
+		for i := 1; i <= count; i++ {
+			ring.Write(&logger.Entry{
+				Message: fmt.Sprintf("%s %d", source, i),
+				Fields:  logger.Fields{"prefix": source},
+			})
+		}
+	}
+
+	messages := func(entries []*logger.Entry) []string {
+		msgs := make([]string, len(entries))
+		for i, e := range entries {
+			msgs[i] = e.Message
+		}
+
+		return msgs
+	}
+
+	BeforeEach(func() {
+		ring = logger.NewRingSink(3)
+	})
+
+	It("returns the latest entries oldest first", func() {
+		write("combat", 2)
+
+		Ω(messages(ring.Tail("combat", 0))).Should(Equal([]string{"combat 1", "combat 2"}))
+		Ω(messages(ring.Tail("combat", 1))).Should(Equal([]string{"combat 2"}))
+	})
+
+	It("drops the oldest entries once full", func() {
+		write("combat", 5)
+
+		Ω(messages(ring.Tail("combat", 10))).Should(Equal([]string{"combat 3", "combat 4", "combat 5"}))
+	})
+
+	It("keeps each source separately", func() {
+		write("combat", 4)
+		write("weather", 1)
+
+		Ω(messages(ring.Tail("weather", 0))).Should(Equal([]string{"weather 1"}))
+		Ω(ring.Tail("magic", 0)).Should(BeEmpty())
+		Ω(ring.Sources()).Should(Equal([]string{"combat", "weather"}))
+	})
+
+	It("keeps nothing without a size", func() {
+		ring = logger.NewRingSink(0)
+		write("combat", 1)
+
+		Ω(ring.Tail("combat", 0)).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,182 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// entries are duplicates when they come from the same source at the same
+// level with the same message, fields are ignored since they often hold
+// details that change every time
+type sampleKey struct {
+	source  string
+	level   LogLevel
+	message string
+}
+
+// the duplicates of an entry seen during the current window
+type sampleCount struct {
+	start      time.Time
+	seen       int
+	suppressed int
+}
+
+// SamplingSink limits how often the same entry is written, so a script
+// failing in a loop can't drown out everything else. Within each window the
+// first occurrence of an entry is written and then only every nth, the rest
+// are counted and summarized in a single entry once the window ends. Fatal
+// and panic entries are always written.
+type SamplingSink struct {
+	sink      Sink
+	window    time.Duration
+	every     int
+	counts    map[sampleKey]*sampleCount
+	mutex     *sync.Mutex
+	now       func() time.Time
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce *sync.Once
+}
+
+// NewSamplingSink creates a sink writing the first and then every nth
+// duplicate entry within each window to sink.
+func NewSamplingSink(sink Sink, window time.Duration, every int) *SamplingSink {
+	if every < 1 {
+		every = 1
+	}
+
+	s := &SamplingSink{
+		sink:      sink,
+		window:    window,
+		every:     every,
+		counts:    make(map[sampleKey]*sampleCount),
+		mutex:     new(sync.Mutex),
+		now:       time.Now,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		closeOnce: new(sync.Once),
+	}
+	go s.run()
+
+	return s
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (s *SamplingSink) SetClock(now func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.now = now
+}
+
+// Write writes the entry unless it's a duplicate being suppressed, a summary
+// of the previous window is written first if it suppressed any.
+func (s *SamplingSink) Write(e *Entry) error {
+	if e.Level <= FatalLevel {
+		return s.sink.Write(e)
+	}
+
+	source, _ := e.Fields["prefix"].(string)
+	key := sampleKey{source: source, level: e.Level, message: e.Message}
+
+	s.mutex.Lock()
+	now := s.now()
+	var summary *Entry
+	c, ok := s.counts[key]
+	if !ok || now.Sub(c.start) >= s.window {
+		if ok {
+			summary = s.summary(key, c, now)
+		}
+		c = &sampleCount{start: now}
+		s.counts[key] = c
+	}
+	write := c.seen%s.every == 0
+	c.seen++
+	if !write {
+		c.suppressed++
+	}
+	s.mutex.Unlock()
+
+	if summary != nil {
+		writeEntry(s.sink, summary)
+	}
+	if !write {
+		return nil
+	}
+
+	return s.sink.Write(e)
+}
+
+// Close writes summaries for every entry suppressed and closes the sink the
+// entries are written to.
+func (s *SamplingSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	s.summarize(true)
+
+	return s.sink.Close()
+}
+
+// summarize writes summaries for windows that have ended, or all of them if
+// all is true, and forgets them
+func (s *SamplingSink) summarize(all bool) {
+	s.mutex.Lock()
+	now := s.now()
+	var summaries []*Entry
+	for key, c := range s.counts {
+		if !all && now.Sub(c.start) < s.window {
+			continue
+		}
+		if summary := s.summary(key, c, now); summary != nil {
+			summaries = append(summaries, summary)
+		}
+		delete(s.counts, key)
+	}
+	s.mutex.Unlock()
+
+	for _, summary := range summaries {
+		writeEntry(s.sink, summary)
+	}
+}
+
+// summary builds the entry reporting the duplicates suppressed, or nil if
+// there weren't any
+func (s *SamplingSink) summary(key sampleKey, c *sampleCount, now time.Time) *Entry {
+	if c.suppressed == 0 {
+		return nil
+	}
+
+	return &Entry{
+		Time:    now,
+		Level:   key.level,
+		Message: fmt.Sprintf("Suppressed %d duplicates of %q.", c.suppressed, key.message),
+		Fields: Fields{
+			"prefix":     key.source,
+			"suppressed": c.suppressed,
+			"window":     s.window.String(),
+		},
+	}
+}
+
+// summaries are written as windows end even if the entry isn't logged again
+func (s *SamplingSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.summarize(false)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package logger_test
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SamplingSink", func() {
+	var (
+		buf  *bytes.Buffer
+		sink *logger.SamplingSink
+		now  time.Time
+	)
+
+	write := func(msg string, count int) {
+		for i := 0; i < count; i++ {
+			sink.Write(&logger.Entry{
+				Time:    now,
+				Level:   logger.ErrorLevel,
+				Message: msg,
+				Fields:  logger.Fields{"prefix": "server_engine(1)"},
+			})
+		}
+	}
+
+	lines := func() []string {
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	BeforeEach(func() {
+		buf = new(bytes.Buffer)
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		sink = logger.NewSamplingSink(logger.NewWriterSink(buf, logger.JSONFormat), time.Minute, 10)
+		sink.SetClock(func() time.Time { return now })
+	})
+
+	AfterEach(func() {
+		sink.Close()
+	})
+
+	It("writes the first and every nth duplicate", func() {
+		write("attempt to call a nil value", 25)
+
+		Ω(lines()).Should(HaveLen(3))
+	})
+
+	It("doesn't sample different messages together", func() {
+		write("attempt to call a nil value", 1)
+		write("attempt to index a nil value", 1)
+
+		Ω(lines()).Should(HaveLen(2))
+	})
+
+	It("summarizes suppressed duplicates when the window ends", func() {
+		write("attempt to call a nil value", 5)
+		now = now.Add(time.Minute)
+		write("attempt to call a nil value", 1)
+
+		Ω(lines()).Should(HaveLen(3))
+		Ω(lines()[1]).Should(ContainSubstring(`"suppressed":4`))
+		Ω(lines()[1]).Should(ContainSubstring("Suppressed 4 duplicates"))
+	})
+
+	It("summarizes suppressed duplicates when closed", func() {
+		write("attempt to call a nil value", 3)
+		sink.Close()
+
+		Ω(lines()).Should(HaveLen(2))
+		Ω(lines()[1]).Should(ContainSubstring(`"suppressed":2`))
+	})
+
+	It("always writes fatal entries", func() {
+		for i := 0; i < 3; i++ {
+			sink.Write(&logger.Entry{Time: now, Level: logger.FatalLevel, Message: "the end"})
+		}
+
+		Ω(lines()).Should(HaveLen(3))
+	})
+})
@@ -0,0 +1,364 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// defaults for sentry options left as zero
+const (
+	DefaultSentryBatchSize  = 10
+	DefaultSentryFlushEvery = 5 * time.Second
+)
+
+// the most events waiting to be sent, errors logged beyond this while the
+// endpoint is slow or down are dropped
+const maxPendingSentryEvents = 1000
+
+// fields promoted to tags on sentry events, so failures can be grouped by the
+// engine or script they came from
+var sentryTagFields = []string{"engine", "plugin", "script", "event", "command"}
+
+// SentryOptions configure a SentrySink.
+type SentryOptions struct {
+	// Environment the server is running in, like "production".
+	Environment string
+	// BatchSize is the number of events that causes pending events to be sent
+	// before the next flush.
+	BatchSize int
+	// FlushEvery is how often pending events are sent.
+	FlushEvery time.Duration
+}
+
+// SentrySink reports error (and more severe) entries to a Sentry compatible
+// endpoint, less severe entries are ignored. Entries are sent in batches from
+// the background so logging an error never waits on the network. Each event
+// has the stack where the entry was logged, the source of the entry as its
+// logger and the engine, plugin, script, event and command fields as tags.
+type SentrySink struct {
+	storeURL   string
+	auth       string
+	options    SentryOptions
+	serverName string
+	client     *http.Client
+	pending    []*sentryEvent
+	mutex      *sync.Mutex
+	flushing   *sync.Mutex
+	kick       chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  *sync.Once
+}
+
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	Platform    string                 `json:"platform"`
+	Message     string                 `json:"message"`
+	Environment string                 `json:"environment,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   *sentryExceptions      `json:"exception,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Filename string `json:"filename"`
+	Function string `json:"function"`
+	Module   string `json:"module"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// NewSentrySink creates a sink reporting to the project in the DSN, given as
+// "https://key@sentry.example.com/project".
+func NewSentrySink(dsn string, opts SentryOptions) (*SentrySink, error) {
+	storeURL, auth, err := parseSentryDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultSentryBatchSize
+	}
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = DefaultSentryFlushEvery
+	}
+	hostname, _ := os.Hostname()
+
+	s := &SentrySink{
+		storeURL:   storeURL,
+		auth:       auth,
+		options:    opts,
+		serverName: hostname,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		mutex:      new(sync.Mutex),
+		flushing:   new(sync.Mutex),
+		kick:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		closeOnce:  new(sync.Once),
+	}
+	go s.run()
+
+	return s, nil
+}
+
+// parseSentryDSN splits the DSN into the URL events are posted to and the
+// authentication header sent with them
+func parseSentryDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid sentry dsn: %s", err)
+	}
+
+	project := path.Base(u.Path)
+	if u.User == nil || u.User.Username() == "" || u.Host == "" || project == "." || project == "/" {
+		return "", "", fmt.Errorf("invalid sentry dsn %q, expected https://key@host/project", dsn)
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=dragon-mud/1.0, sentry_key=%s", u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	prefix := strings.TrimSuffix(path.Dir(u.Path), "/")
+	storeURL := fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project)
+
+	return storeURL, auth, nil
+}
+
+// Write queues error entries to be sent with the stack they were logged
+// from.
+func (s *SentrySink) Write(e *Entry) error {
+	if e.Level > ErrorLevel {
+		return nil
+	}
+
+	evt := s.event(e)
+
+	s.mutex.Lock()
+	if len(s.pending) >= maxPendingSentryEvents {
+		s.mutex.Unlock()
+
+		return fmt.Errorf("sentry queue is full, dropped %q", e.Message)
+	}
+	s.pending = append(s.pending, evt)
+	full := len(s.pending) >= s.options.BatchSize
+	s.mutex.Unlock()
+
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush sends all pending events, returning the first error from the
+// endpoint.
+func (s *SentrySink) Flush() error {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+
+	s.mutex.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mutex.Unlock()
+
+	var first error
+	for _, evt := range batch {
+		if err := s.send(evt); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Close stops sending in the background and sends any pending events.
+func (s *SentrySink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+
+	return s.Flush()
+}
+
+func (s *SentrySink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.options.FlushEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+
+		if err := s.Flush(); err != nil {
+			// reporting this through the log would only queue another event
+			fmt.Fprintf(os.Stderr, "ERROR: Failed to send errors to sentry: %s\n", err)
+		}
+	}
+}
+
+func (s *SentrySink) send(evt *sentryEvent) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// event converts the entry, capturing the stack of the code that logged it
+func (s *SentrySink) event(e *Entry) *sentryEvent {
+	evt := &sentryEvent{
+		EventID:     strings.Replace(uuid.NewV1().String(), "-", "", -1),
+		Timestamp:   e.Time.UTC().Format("2006-01-02T15:04:05"),
+		Level:       e.Level.String(),
+		Platform:    "go",
+		Message:     e.Message,
+		Environment: s.options.Environment,
+		ServerName:  s.serverName,
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]interface{}, len(e.Fields)),
+	}
+	if evt.Level == "panic" {
+		evt.Level = "fatal"
+	}
+	evt.Logger, _ = e.Fields["prefix"].(string)
+
+	exception := sentryException{
+		Type:       "error",
+		Value:      e.Message,
+		Stacktrace: sentryStacktrace{Frames: sentryFrames()},
+	}
+
+	for k, v := range e.Fields {
+		if err, ok := v.(error); ok {
+			if k == "error" {
+				exception.Type = fmt.Sprintf("%T", err)
+				exception.Value = err.Error()
+			}
+			v = err.Error()
+		}
+		evt.Extra[k] = v
+	}
+	for _, k := range sentryTagFields {
+		if v, ok := e.Fields[k]; ok {
+			evt.Tags[k] = fmt.Sprint(v)
+		}
+	}
+	if msg, ok := e.Fields["error"].(string); ok {
+		exception.Value = msg
+	}
+	evt.Exception = &sentryExceptions{Values: []sentryException{exception}}
+
+	return evt
+}
+
+// sentryFrames captures the current stack, oldest call first as sentry
+// expects, without the frames from logging itself
+func sentryFrames() []sentryFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []sentryFrame
+	for {
+		frame, more := frames.Next()
+		if !isLoggingFrame(frame.Function) {
+			module, function := splitFunctionName(frame.Function)
+			stack = append(stack, sentryFrame{
+				Filename: frame.File,
+				Function: function,
+				Module:   module,
+				Lineno:   frame.Line,
+				InApp:    strings.HasPrefix(module, "github.com/bbuck/dragon-mud") && !strings.Contains(module, "/vendor/"),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+
+	return stack
+}
+
+func isLoggingFrame(function string) bool {
+	return strings.HasPrefix(function, "github.com/bbuck/dragon-mud/logger.") ||
+		strings.Contains(function, "/logrus.")
+}
+
+// splitFunctionName splits "github.com/a/b.(*T).Method" into the package and
+// the function within it
+func splitFunctionName(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		slash = 0
+	}
+	dot := strings.Index(name[slash:], ".")
+	if dot < 0 {
+		return "", name
+	}
+
+	return name[:slash+dot], name[slash+dot+1:]
+}
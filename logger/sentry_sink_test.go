@@ -0,0 +1,124 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SentrySink", func() {
+	var (
+		server *httptest.Server
+		mutex  sync.Mutex
+		events []map[string]interface{}
+		auths  []string
+		paths  []string
+		sink   *logger.SentrySink
+	)
+
+	received := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return len(events)
+	}
+
+	entry := func(level logger.LogLevel, msg string) *logger.Entry {
+		return &logger.Entry{
+			Time:    time.Now(),
+			Level:   level,
+			Message: msg,
+			Fields: logger.Fields{
+				"prefix": "server_engine(1)",
+				"plugin": "combat",
+				"error":  errors.New("attempt to index a nil value"),
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		events, auths, paths = nil, nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var evt map[string]interface{}
+			json.Unmarshal(body, &evt)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			events = append(events, evt)
+			auths = append(auths, r.Header.Get("X-Sentry-Auth"))
+			paths = append(paths, r.URL.Path)
+		}))
+
+		dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/42"
+		var err error
+		sink, err = logger.NewSentrySink(dsn, logger.SentryOptions{
+			Environment: "test",
+			BatchSize:   2,
+			FlushEvery:  time.Hour,
+		})
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		sink.Close()
+		server.Close()
+	})
+
+	It("sends errors once a batch is full", func() {
+		Ω(sink.Write(entry(logger.ErrorLevel, "Combat script failed."))).Should(Succeed())
+		Consistently(received, "50ms").Should(Equal(0))
+
+		Ω(sink.Write(entry(logger.FatalLevel, "Combat script failed again."))).Should(Succeed())
+		Eventually(received).Should(Equal(2))
+
+		Ω(paths[0]).Should(Equal("/api/42/store/"))
+		Ω(auths[0]).Should(ContainSubstring("sentry_key=public"))
+	})
+
+	It("ignores entries less severe than errors", func() {
+		Ω(sink.Write(entry(logger.WarnLevel, "Combat is slow."))).Should(Succeed())
+		Ω(sink.Flush()).Should(Succeed())
+
+		Ω(received()).Should(Equal(0))
+	})
+
+	It("includes the engine and script context", func() {
+		Ω(sink.Write(entry(logger.ErrorLevel, "Combat script failed."))).Should(Succeed())
+		Ω(sink.Flush()).Should(Succeed())
+
+		Ω(received()).Should(Equal(1))
+		evt := events[0]
+		Ω(evt["logger"]).Should(Equal("server_engine(1)"))
+		Ω(evt["environment"]).Should(Equal("test"))
+		Ω(evt["tags"]).Should(HaveKeyWithValue("plugin", "combat"))
+
+		exception := evt["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+		Ω(exception["value"]).Should(Equal("attempt to index a nil value"))
+		frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+		Ω(frames).ShouldNot(BeEmpty())
+	})
+
+	It("sends pending errors when closed", func() {
+		Ω(sink.Write(entry(logger.ErrorLevel, "Combat script failed."))).Should(Succeed())
+		Ω(sink.Close()).Should(Succeed())
+
+		Ω(received()).Should(Equal(1))
+	})
+
+	It("rejects invalid DSNs", func() {
+		_, err := logger.NewSentrySink("https://sentry.example.com/42", logger.SentryOptions{})
+
+		Ω(err).ShouldNot(BeNil())
+	})
+})
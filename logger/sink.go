@@ -0,0 +1,186 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+// Entry is a single message written to the log.
+type Entry struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Fields  Fields
+}
+
+// Sink is a destination for log entries. Each sink formats entries itself so
+// the same entry can be written as text to the terminal and as JSON to a
+// file.
+type Sink interface {
+	Write(*Entry) error
+	Close() error
+}
+
+// Format converts an entry into the bytes written by a sink.
+type Format func(*Entry) ([]byte, error)
+
+// the formatter used for text output, matching the terminal output of the
+// server before sinks existed
+var textFormatter = &prefixed.TextFormatter{}
+
+// TextFormat writes entries as human readable lines, prefixed with the source
+// of the entry.
+func TextFormat(e *Entry) ([]byte, error) {
+	return textFormatter.Format(e.logrusEntry())
+}
+
+// JSONFormat writes each entry as a JSON object on its own line, with the
+// time, level and message in the "time", "level" and "msg" fields.
+func JSONFormat(e *Entry) ([]byte, error) {
+	line, err := json.Marshal(e.jsonData())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// jsonData flattens the entry into the object written by JSONFormat
+func (e *Entry) jsonData() map[string]interface{} {
+	data := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		// errors don't marshal to anything useful on their own
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	data["time"] = e.Time.Format(time.RFC3339Nano)
+	data["level"] = e.Level.String()
+	data["msg"] = e.Message
+
+	return data
+}
+
+// FormatFor returns the format with the given name, "text" or "json".
+func FormatFor(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "text":
+		return TextFormat, nil
+	case "json":
+		return JSONFormat, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", name)
+	}
+}
+
+// plainText renders the message followed by sorted key=value pairs, it's
+// used where the destination adds its own timestamp and level
+func plainText(e *Entry) string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(e.Message)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, " %s=%v", k, e.Fields[k])
+	}
+
+	return buf.String()
+}
+
+func (e *Entry) logrusEntry() *logrus.Entry {
+	return &logrus.Entry{
+		Data:    logrus.Fields(e.Fields),
+		Time:    e.Time,
+		Level:   logLevelToLogrusLevel(e.Level),
+		Message: e.Message,
+	}
+}
+
+// WriterSink writes formatted entries to a writer, like the terminal.
+type WriterSink struct {
+	w      io.Writer
+	format Format
+	mutex  *sync.Mutex
+}
+
+// NewWriterSink creates a sink writing entries to w in the given format.
+func NewWriterSink(w io.Writer, format Format) *WriterSink {
+	return &WriterSink{
+		w:      w,
+		format: format,
+		mutex:  new(sync.Mutex),
+	}
+}
+
+// Write formats and writes the entry.
+func (s *WriterSink) Write(e *Entry) error {
+	b, err := s.format(e)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err = s.w.Write(b)
+
+	return err
+}
+
+// Close closes the writer if it can be closed, the standard streams are left
+// open.
+func (s *WriterSink) Close() error {
+	if s.w == os.Stdout || s.w == os.Stderr {
+		return nil
+	}
+
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// MultiSink writes each entry to several sinks.
+type MultiSink []Sink
+
+// Write writes the entry to every sink, returning the first error.
+func (m MultiSink) Write(e *Entry) error {
+	var first error
+	for _, s := range m {
+		if err := s.Write(e); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Close closes every sink, returning the first error.
+func (m MultiSink) Close() error {
+	var first error
+	for _, s := range m {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
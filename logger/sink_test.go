@@ -0,0 +1,83 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sinks", func() {
+	entry := &logger.Entry{
+		Time:    time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Level:   logger.WarnLevel,
+		Message: "the dragon stirs",
+		Fields:  logger.Fields{"prefix": "world", "error": errors.New("too loud")},
+	}
+
+	It("formats entries as JSON lines", func() {
+		b, err := logger.JSONFormat(entry)
+		Ω(err).Should(BeNil())
+		Ω(b[len(b)-1]).Should(Equal(byte('\n')))
+
+		var data map[string]interface{}
+		Ω(json.Unmarshal(b, &data)).Should(Succeed())
+		Ω(data["msg"]).Should(Equal("the dragon stirs"))
+		Ω(data["level"]).Should(Equal("warn"))
+		Ω(data["error"]).Should(Equal("too loud"))
+	})
+
+	It("writes to every sink", func() {
+		var a, b bytes.Buffer
+		sink := logger.MultiSink{
+			logger.NewWriterSink(&a, logger.TextFormat),
+			logger.NewWriterSink(&b, logger.JSONFormat),
+		}
+
+		Ω(sink.Write(entry)).Should(Succeed())
+		Ω(a.String()).Should(ContainSubstring("the dragon stirs"))
+		Ω(b.String()).Should(ContainSubstring(`"msg":"the dragon stirs"`))
+	})
+
+	Describe("configuration", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "logger")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("builds sinks from targets", func() {
+			path := filepath.Join(dir, "game.json")
+			sink, err := logger.ConfigureSinks([]map[string]interface{}{
+				{"type": "json", "target": path},
+				{"type": "file", "target": filepath.Join(dir, "game.log")},
+			})
+			Ω(err).Should(BeNil())
+
+			Ω(sink.Write(entry)).Should(Succeed())
+			Ω(sink.Close()).Should(Succeed())
+			contents, _ := ioutil.ReadFile(path)
+			Ω(string(contents)).Should(ContainSubstring(`"msg":"the dragon stirs"`))
+		})
+
+		It("rejects unknown targets and formats", func() {
+			_, err := logger.ConfigureSinks([]map[string]interface{}{{"type": "carrier pigeon"}})
+			Ω(err).ShouldNot(BeNil())
+
+			_, err = logger.ConfigureSinks([]map[string]interface{}{{"type": "file", "target": "x.log", "format": "xml"}})
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+})
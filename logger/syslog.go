@@ -0,0 +1,49 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// +build !windows,!nacl,!plan9
+
+package logger
+
+import "log/syslog"
+
+// SyslogSink sends entries to a syslog daemon, mapping log levels to syslog
+// priorities. Syslog records the time itself so entries are sent as the
+// message followed by their fields.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog daemon at the address, an empty
+// network and address connect to the local daemon.
+func NewSyslogSink(network, address, tag string) (Sink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogSink{w: w}, nil
+}
+
+// Write sends the entry at the priority matching its level.
+func (s *SyslogSink) Write(e *Entry) error {
+	msg := plainText(e)
+	switch e.Level {
+	case PanicLevel:
+		return s.w.Crit(msg)
+	case FatalLevel:
+		return s.w.Crit(msg)
+	case ErrorLevel:
+		return s.w.Err(msg)
+	case WarnLevel:
+		return s.w.Warning(msg)
+	case InfoLevel:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
+
+// Close disconnects from the daemon.
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// +build windows nacl plan9
+
+package logger
+
+import "errors"
+
+// NewSyslogSink fails, syslog isn't available on this platform.
+func NewSyslogSink(network, address, tag string) (Sink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// TailHandler serves the entries returned by Tail as a JSON list, oldest
+// first, in the same shape JSONFormat writes them. The "source" parameter
+// selects the source and "n" limits the number of entries. Without a source
+// the handler lists the sources that have entries instead.
+func TailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		var body interface{}
+		if source := r.FormValue("source"); source != "" {
+			var n int
+			if s := r.FormValue("n"); s != "" {
+				var err error
+				if n, err = strconv.Atoi(s); err != nil {
+					http.Error(w, "n must be a whole number", http.StatusBadRequest)
+
+					return
+				}
+			}
+
+			entries := Tail(source, n)
+			data := make([]map[string]interface{}, len(entries))
+			for i, e := range entries {
+				data[i] = e.jsonData()
+			}
+			body = data
+		} else {
+			body = map[string][]string{"sources": TailSources()}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+}
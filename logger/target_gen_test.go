@@ -14,8 +14,8 @@ var _ = Describe("TargetGen", func() {
 	var (
 		complexMap = []map[string]interface{}{
 			{
-				"type":   "os",
-				"target": "stdout",
+				"type":   "terminal",
+				"target": "terminal",
 			},
 			{
 				"type":   "file",
@@ -24,8 +24,8 @@ var _ = Describe("TargetGen", func() {
 		}
 		simpleMap = []map[string]interface{}{
 			{
-				"type":   "os",
-				"target": "stderr",
+				"type":   "terminal",
+				"target": "error",
 			},
 		}
 	)
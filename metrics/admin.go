@@ -0,0 +1,57 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	adminToken      string
+	adminTokenMutex = new(sync.RWMutex)
+)
+
+// SetAdminToken sets the token requests must carry to reach admin handlers,
+// as "Authorization: Bearer <token>". An empty token, the default, refuses
+// every admin request.
+func SetAdminToken(token string) {
+	adminTokenMutex.Lock()
+	defer adminTokenMutex.Unlock()
+
+	adminToken = token
+}
+
+// IsAdmin returns true if the request carries the admin token.
+func IsAdmin(r *http.Request) bool {
+	adminTokenMutex.RLock()
+	token := adminToken
+	adminTokenMutex.RUnlock()
+
+	if token == "" {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	given := strings.TrimPrefix(auth, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// Admin only lets requests carrying the admin token through to the handler,
+// others are refused as unauthorized.
+func Admin(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dragon-mud"`)
+			http.Error(w, "the admin token is required", http.StatusUnauthorized)
+
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package metrics keeps counters, gauges and histograms describing what the
+// server is doing. The global registry is published through expvar so any
+// dashboard that reads expvar output can graph it.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the upper bounds used for histograms that don't specify
+// their own.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Counter is a value that only increases, like the number of logins.
+type Counter struct {
+	value uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add increases the counter by n.
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+// Gauge is a value that can go up and down, like the number of players
+// online.
+type Gauge struct {
+	bits uint64
+}
+
+// Set replaces the value of the gauge.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add changes the gauge by delta, which may be negative.
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&g.bits)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&g.bits, old, next) {
+			return
+		}
+	}
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Histogram counts observed values in buckets, like how long commands take
+// to run.
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+	mutex   *sync.Mutex
+}
+
+// HistogramSnapshot is the state of a histogram at a point in time. Buckets
+// maps each upper bound to the number of observations less than or equal to
+// it.
+type HistogramSnapshot struct {
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Histogram{
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)),
+		mutex:   new(sync.Mutex),
+	}
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.count++
+	h.sum += v
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	snap := HistogramSnapshot{
+		Count:   h.count,
+		Sum:     h.sum,
+		Buckets: make(map[string]uint64, len(h.buckets)),
+	}
+	for i, bound := range h.buckets {
+		snap.Buckets[fmt.Sprint(bound)] = h.counts[i]
+	}
+
+	return snap
+}
+
+// Registry holds named metrics. Names are unique across all kinds of metric,
+// asking for a counter with the name of an existing gauge is an error.
+type Registry struct {
+	metrics map[string]interface{}
+	mutex   *sync.Mutex
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]interface{}),
+		mutex:   new(sync.Mutex),
+	}
+}
+
+var (
+	globalRegistry *Registry
+	globalOnce     sync.Once
+)
+
+// Global returns the registry for the server, published to expvar as
+// "metrics".
+func Global() *Registry {
+	globalOnce.Do(func() {
+		globalRegistry = NewRegistry()
+		expvar.Publish("metrics", expvar.Func(func() interface{} {
+			return globalRegistry.Snapshot()
+		}))
+	})
+
+	return globalRegistry
+}
+
+// Counter returns the counter with the given name, creating it if necessary.
+func (r *Registry) Counter(name string) (*Counter, error) {
+	m, err := r.getOrCreate(name, "counter", func() interface{} {
+		return new(Counter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.(*Counter), nil
+}
+
+// Gauge returns the gauge with the given name, creating it if necessary.
+func (r *Registry) Gauge(name string) (*Gauge, error) {
+	m, err := r.getOrCreate(name, "gauge", func() interface{} {
+		return new(Gauge)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.(*Gauge), nil
+}
+
+// Histogram returns the histogram with the given name, creating it with the
+// buckets if necessary (nil uses DefaultBuckets). Buckets are ignored if the
+// histogram already exists.
+func (r *Registry) Histogram(name string, buckets []float64) (*Histogram, error) {
+	m, err := r.getOrCreate(name, "histogram", func() interface{} {
+		return newHistogram(buckets)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.(*Histogram), nil
+}
+
+// Snapshot returns the current value of every metric keyed by name, counters
+// are uint64, gauges float64 and histograms HistogramSnapshot.
+func (r *Registry) Snapshot() map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	snap := make(map[string]interface{}, len(r.metrics))
+	for name, m := range r.metrics {
+		switch metric := m.(type) {
+		case *Counter:
+			snap[name] = metric.Value()
+		case *Gauge:
+			snap[name] = metric.Value()
+		case *Histogram:
+			snap[name] = metric.Snapshot()
+		}
+	}
+
+	return snap
+}
+
+func (r *Registry) getOrCreate(name, kind string, create func() interface{}) (interface{}, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if kindOf(m) != kind {
+			return nil, fmt.Errorf("metric %q is a %s, not a %s", name, kindOf(m), kind)
+		}
+
+		return m, nil
+	}
+
+	m := create()
+	r.metrics[name] = m
+
+	return m, nil
+}
+
+func kindOf(m interface{}) string {
+	switch m.(type) {
+	case *Counter:
+		return "counter"
+	case *Gauge:
+		return "gauge"
+	case *Histogram:
+		return "histogram"
+	default:
+		return "unknown"
+	}
+}
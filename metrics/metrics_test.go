@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/bbuck/dragon-mud/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *Registry
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+	})
+
+	It("counts", func() {
+		c, err := registry.Counter("logins")
+		Ω(err).Should(BeNil())
+
+		c.Inc()
+		c.Add(2)
+		Ω(c.Value()).Should(Equal(uint64(3)))
+	})
+
+	It("returns the same metric for the same name", func() {
+		a, _ := registry.Gauge("online")
+		b, _ := registry.Gauge("online")
+
+		a.Set(5)
+		b.Add(-2)
+		Ω(a.Value()).Should(Equal(float64(3)))
+	})
+
+	It("rejects names used by another kind of metric", func() {
+		registry.Counter("logins")
+		_, err := registry.Gauge("logins")
+
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("buckets histogram observations", func() {
+		h, _ := registry.Histogram("command_time", []float64{1, 0.1})
+		h.Observe(0.05)
+		h.Observe(0.5)
+		h.Observe(2)
+
+		snap := h.Snapshot()
+		Ω(snap.Count).Should(Equal(uint64(3)))
+		Ω(snap.Sum).Should(BeNumerically("~", 2.55, 0.0001))
+		Ω(snap.Buckets).Should(Equal(map[string]uint64{"0.1": 1, "1": 2}))
+	})
+
+	It("snapshots every metric", func() {
+		c, _ := registry.Counter("a")
+		c.Inc()
+		g, _ := registry.Gauge("b")
+		g.Set(1.5)
+		registry.Histogram("c", nil)
+
+		snap := registry.Snapshot()
+		Ω(snap["a"]).Should(Equal(uint64(1)))
+		Ω(snap["b"]).Should(Equal(1.5))
+		Ω(snap["c"]).Should(BeAssignableToTypeOf(HistogramSnapshot{}))
+	})
+})
+
+var _ = Describe("Admin", func() {
+	var handler http.Handler
+
+	serve := func(auth string) int {
+		req := httptest.NewRequest("GET", "/admin/bans", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	BeforeEach(func() {
+		handler = Admin(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	})
+
+	AfterEach(func() {
+		SetAdminToken("")
+	})
+
+	It("refuses everyone without a token", func() {
+		Ω(serve("")).Should(Equal(http.StatusUnauthorized))
+		Ω(serve("Bearer ")).Should(Equal(http.StatusUnauthorized))
+	})
+
+	It("only lets requests with the token through", func() {
+		SetAdminToken("s3cret")
+
+		Ω(serve("Bearer s3cret")).Should(Equal(http.StatusNoContent))
+		Ω(serve("Bearer guess")).Should(Equal(http.StatusUnauthorized))
+		Ω(serve("s3cret")).Should(Equal(http.StatusUnauthorized))
+	})
+})
+
+var _ = Describe("Handler", func() {
+	AfterEach(func() {
+		SetAdminToken("")
+	})
+
+	It("only serves log entries to requests with the admin token", func() {
+		SetAdminToken("s3cret")
+		tail := func(auth string) int {
+			req := httptest.NewRequest("GET", "/debug/log/tail?source=combat", nil)
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, req)
+
+			return rec.Code
+		}
+
+		Ω(tail("")).Should(Equal(http.StatusUnauthorized))
+		Ω(tail("Bearer s3cret")).Should(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,52 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+var (
+	handlers      = make(map[string]http.Handler)
+	handlersMutex = new(sync.Mutex)
+)
+
+// Handle adds a handler served alongside the metrics at the path, like the
+// admin API of a part of the game. It must be called before Serve.
+func Handle(path string, handler http.Handler) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+
+	handlers[path] = handler
+}
+
+// Serve publishes the expvar output, including the global registry, on the
+// address at /debug/vars, and the log levels and latest log entries at
+// /debug/log/levels and /debug/log/tail, along with any handlers added with
+// Handle. Only requests carrying the admin token read log entries or change
+// log levels. It blocks until the server fails.
+func Serve(addr string) error {
+	return http.ListenAndServe(addr, Handler())
+}
+
+// Handler returns the handler Serve serves on its address.
+func Handler() http.Handler {
+	Global()
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/log/levels", logger.LevelsHandler(IsAdmin))
+	mux.Handle("/debug/log/tail", Admin(logger.TailHandler()))
+
+	handlersMutex.Lock()
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
+	handlersMutex.Unlock()
+
+	return mux
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/bbuck/dragon-mud/ansi"
+	"github.com/bbuck/dragon-mud/text/strutil"
 )
 
 // ColorSupport defines the level of color support, whether it be Mono, Basic
@@ -33,6 +34,9 @@ const (
 type Console struct {
 	writer io.Writer
 	ColorSupport
+	// Width is the number of characters that fit on a line, lines printed
+	// wider than this are wrapped. Lines aren't wrapped if it's zero.
+	Width int
 }
 
 var (
@@ -102,7 +106,7 @@ func (c *Console) Println(text interface{}) {
 		str = fmt.Sprintf("%v", text)
 	}
 
-	fmt.Fprintf(c.writer, "%s\n", c.colorize(str))
+	fmt.Fprintf(c.writer, "%s\n", c.colorize(c.wrap(str)))
 }
 
 // Write makes Console conform to io.Writer and can therefore be used as a
@@ -118,7 +122,7 @@ func (c *Console) Write(p []byte) (n int, err error) {
 // color codes.
 func (c *Console) Printf(format string, params ...interface{}) {
 	text := fmt.Sprintf(format, params...)
-	fmt.Fprintf(c.writer, "%s", c.colorize(text))
+	fmt.Fprintf(c.writer, "%s", c.colorize(c.wrap(text)))
 }
 
 // PlainPrintln will print the string ignoring color codes in the text.
@@ -131,6 +135,23 @@ func (c *Console) PlainPrintf(format string, params ...interface{}) {
 	fmt.Fprintf(c.writer, format, params...)
 }
 
+// wrap breaks lines wider than the console, lines that fit are left as they
+// are so spacing used for alignment is kept
+func (c *Console) wrap(str string) string {
+	if c.Width < 1 || ansi.VisibleLength(str) <= c.Width {
+		return str
+	}
+
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		if ansi.VisibleLength(line) > c.Width {
+			lines[i] = strutil.Wrap(line, c.Width)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (c *Console) colorize(str string) string {
 	switch c.ColorSupport {
 	case Color256:
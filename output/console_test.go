@@ -208,6 +208,24 @@ var _ = Describe("Console", func() {
 		})
 	})
 
+	Describe("Width", func() {
+		BeforeEach(func() {
+			console.Width = 10
+		})
+
+		It("wraps lines wider than the console", func() {
+			console.Println("the [r]red[x] dragon sleeps\nto  be")
+
+			Ω(buffer.String()).Should(Equal("the red\ndragon\nsleeps\nto  be\n"))
+		})
+
+		It("leaves lines that fit alone", func() {
+			console.Printf("%-4s|%4s", "a", "b")
+
+			Ω(buffer.String()).Should(Equal("a   |   b"))
+		})
+	})
+
 	Describe("native consoles", func() {
 		Describe("Stdout()", func() {
 			var (
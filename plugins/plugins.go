@@ -9,9 +9,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/bbuck/dragon-mud/data/migrate"
 	"github.com/bbuck/dragon-mud/errs"
 	"github.com/bbuck/dragon-mud/logger"
 	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/i18n"
+	"github.com/bbuck/dragon-mud/text/namegen"
 	"github.com/bbuck/dragon-mud/text/tmpl"
 )
 
@@ -112,6 +115,50 @@ func LoadViews() error {
 	return nil
 }
 
+// LoadLocales loads the message catalogs from each plugin's locales directory
+// followed by the root locales directory, so root translations can override
+// those provided by plugins.
+func LoadLocales() error {
+	catalog := i18n.Global()
+	for _, p := range Paths {
+		if err := catalog.LoadDir(filepath.Join(p, "locales")); err != nil {
+			return err
+		}
+	}
+
+	return catalog.LoadDir(filepath.Join(Root, "locales"))
+}
+
+// LoadNames trains the name generator with the corpus files in each plugin's
+// names directory followed by the root names directory.
+func LoadNames() error {
+	gen := namegen.Global()
+	for _, p := range Paths {
+		if err := gen.LoadDir(filepath.Join(p, "names")); err != nil {
+			return err
+		}
+	}
+
+	return gen.LoadDir(filepath.Join(Root, "names"))
+}
+
+// LoadMigrations registers the SQL migrations in the root migrations
+// directory in the game's namespace and those in each plugin's migrations
+// directory in a namespace named after the plugin.
+func LoadMigrations() error {
+	migrator := migrate.Global()
+	if err := migrator.LoadDir(migrate.Game, filepath.Join(Root, "migrations")); err != nil {
+		return err
+	}
+	for i, p := range Paths {
+		if err := migrator.LoadDir(Names[i], filepath.Join(p, "migrations")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // LoadCommands runs all the init.lua files for commands in the users codebase
 // and with all plugins.
 func LoadCommands(eng *lua.Engine) error {
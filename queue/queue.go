@@ -0,0 +1,328 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package queue runs named background jobs with retries. Jobs are persisted
+// as they're enqueued and removed once they succeed, so work that was pending
+// when the server stopped is picked up again when it starts.
+package queue
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+)
+
+// DefaultMaxAttempts is the number of times a job is run before it's
+// considered failed, unless the job specifies otherwise.
+const DefaultMaxAttempts = 5
+
+// Job is a unit of work for the worker registered under Name.
+type Job struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Payload     map[string]interface{} `json:"payload"`
+	Attempts    int                    `json:"attempts"`
+	MaxAttempts int                    `json:"max_attempts"`
+	RunAt       time.Time              `json:"run_at"`
+	LastError   string                 `json:"last_error,omitempty"`
+	Failed      bool                   `json:"failed"`
+}
+
+// Worker performs a job, returning an error causes the job to be retried
+// until it runs out of attempts.
+type Worker func(*Job) error
+
+// Options customize a job when it's enqueued.
+type Options struct {
+	// Delay before the job is first run.
+	Delay time.Duration
+	// MaxAttempts before the job is failed, 0 uses DefaultMaxAttempts.
+	MaxAttempts int
+}
+
+// Queue holds pending jobs and the workers that run them. Due jobs are run one
+// at a time in the background once the queue is started.
+type Queue struct {
+	// RetryDelay is the base delay before retrying a failed job, each retry
+	// waits RetryDelay * attempts^2.
+	RetryDelay time.Duration
+
+	log     logger.Log
+	store   Store
+	jobs    map[string]*Job
+	workers map[string]Worker
+	mutex   *sync.Mutex
+	stop    chan struct{}
+	running bool
+	now     func() time.Time
+}
+
+// New creates a queue persisting jobs to the store.
+func New(store Store, log logger.Log) *Queue {
+	return &Queue{
+		RetryDelay: 10 * time.Second,
+		log:        log,
+		store:      store,
+		jobs:       make(map[string]*Job),
+		workers:    make(map[string]Worker),
+		mutex:      new(sync.Mutex),
+		now:        time.Now,
+	}
+}
+
+var (
+	globalQueue *Queue
+	globalOnce  sync.Once
+)
+
+// Global returns the queue for the game, storing jobs in "queue.dir" and
+// starting it on first use. Jobs left from a previous run are loaded.
+func Global() *Queue {
+	globalOnce.Do(func() {
+		log := logger.NewWithSource("queue")
+		globalQueue = New(NewDirStore(viper.GetString("queue.dir")), log)
+		if delay := viper.GetDuration("queue.retry_delay"); delay > 0 {
+			globalQueue.RetryDelay = delay
+		}
+		if err := globalQueue.Load(); err != nil {
+			log.WithError(err).Error("Failed to load queued jobs.")
+		}
+		globalQueue.Start()
+	})
+
+	return globalQueue
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (q *Queue) SetClock(now func() time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.now = now
+}
+
+// Load adds every job in the store to the queue.
+func (q *Queue) Load() error {
+	jobs, err := q.store.Load()
+	if err != nil {
+		return err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, job := range jobs {
+		q.jobs[job.ID] = job
+	}
+
+	return nil
+}
+
+// Register sets the worker for jobs with the given name, replacing any
+// existing worker.
+func (q *Queue) Register(name string, worker Worker) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.workers[name] = worker
+}
+
+// Enqueue adds a job and persists it, returning the ID of the job.
+func (q *Queue) Enqueue(name string, payload map[string]interface{}, opts Options) (string, error) {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	q.mutex.Lock()
+	job := &Job{
+		ID:          uuid.NewV1().String(),
+		Name:        name,
+		Payload:     payload,
+		MaxAttempts: maxAttempts,
+		RunAt:       q.now().Add(opts.Delay),
+	}
+	q.mutex.Unlock()
+
+	if err := q.store.Save(job); err != nil {
+		return "", err
+	}
+
+	q.mutex.Lock()
+	q.jobs[job.ID] = job
+	q.mutex.Unlock()
+
+	return job.ID, nil
+}
+
+// Pending returns the number of jobs waiting to run (including retries).
+func (q *Queue) Pending() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	count := 0
+	for _, job := range q.jobs {
+		if !job.Failed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Failed returns copies of the jobs that ran out of attempts, oldest first.
+func (q *Queue) Failed() []Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var failed []Job
+	for _, job := range q.jobs {
+		if job.Failed {
+			failed = append(failed, *job)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].RunAt.Before(failed[j].RunAt)
+	})
+
+	return failed
+}
+
+// Retry resets a failed job so it runs again, returning false if there is no
+// failed job with the ID.
+func (q *Queue) Retry(id string) bool {
+	q.mutex.Lock()
+	job, ok := q.jobs[id]
+	if !ok || !job.Failed {
+		q.mutex.Unlock()
+
+		return false
+	}
+	job.Failed = false
+	job.Attempts = 0
+	job.RunAt = q.now()
+	saved := *job
+	q.mutex.Unlock()
+
+	q.save(&saved)
+
+	return true
+}
+
+// Start begins running due jobs in the background.
+func (q *Queue) Start() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.running {
+		return
+	}
+	q.running = true
+	q.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.RunDue()
+			case <-stop:
+				return
+			}
+		}
+	}(q.stop)
+}
+
+// Stop halts background processing, a job that is running will finish.
+func (q *Queue) Stop() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.running {
+		close(q.stop)
+		q.running = false
+	}
+}
+
+// RunDue runs every job that is due and has a registered worker, oldest
+// first, returning the number of jobs run.
+func (q *Queue) RunDue() int {
+	q.mutex.Lock()
+	now := q.now()
+	var due []*Job
+	for _, job := range q.jobs {
+		if !job.Failed && !job.RunAt.After(now) && q.workers[job.Name] != nil {
+			due = append(due, job)
+		}
+	}
+	q.mutex.Unlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+
+	for _, job := range due {
+		q.run(job)
+	}
+
+	return len(due)
+}
+
+// run the job and record the result
+func (q *Queue) run(job *Job) {
+	q.mutex.Lock()
+	worker := q.workers[job.Name]
+	job.Attempts++
+	attempt := *job
+	q.mutex.Unlock()
+
+	err := q.perform(worker, &attempt)
+
+	q.mutex.Lock()
+	if err == nil {
+		delete(q.jobs, job.ID)
+		q.mutex.Unlock()
+
+		if serr := q.store.Delete(job.ID); serr != nil {
+			q.log.WithError(serr).WithField("job", job.ID).Error("Failed to remove completed job.")
+		}
+
+		return
+	}
+
+	job.LastError = err.Error()
+	if job.Attempts >= job.MaxAttempts {
+		job.Failed = true
+		q.log.WithError(err).WithField("job", job.ID).WithField("name", job.Name).Error("Job failed and will not be retried.")
+	} else {
+		job.RunAt = q.now().Add(q.RetryDelay * time.Duration(job.Attempts*job.Attempts))
+		q.log.WithError(err).WithField("job", job.ID).WithField("name", job.Name).Warn("Job failed, it will be retried.")
+	}
+	saved := *job
+	q.mutex.Unlock()
+
+	q.save(&saved)
+}
+
+// call the worker, turning panics into errors
+func (q *Queue) perform(worker Worker, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return worker(job)
+}
+
+func (q *Queue) save(job *Job) {
+	if err := q.store.Save(job); err != nil {
+		q.log.WithError(err).WithField("job", job.ID).Error("Failed to save job.")
+	}
+}
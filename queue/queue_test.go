@@ -0,0 +1,140 @@
+package queue_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+	. "github.com/bbuck/dragon-mud/queue"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		q   *Queue
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+		q = New(NewMemoryStore(), logger.TestLog())
+		q.SetClock(func() time.Time {
+			return now
+		})
+	})
+
+	It("runs jobs with the registered worker", func() {
+		var got interface{}
+		q.Register("mail", func(job *Job) error {
+			got = job.Payload["to"]
+
+			return nil
+		})
+		_, err := q.Enqueue("mail", map[string]interface{}{"to": "everyone"}, Options{})
+
+		Ω(err).Should(BeNil())
+		Ω(q.RunDue()).Should(Equal(1))
+		Ω(got).Should(Equal("everyone"))
+		Ω(q.Pending()).Should(Equal(0))
+	})
+
+	It("waits for a worker to be registered", func() {
+		q.Enqueue("mail", nil, Options{})
+
+		Ω(q.RunDue()).Should(Equal(0))
+		Ω(q.Pending()).Should(Equal(1))
+	})
+
+	It("waits until delayed jobs are due", func() {
+		q.Register("mail", func(*Job) error { return nil })
+		q.Enqueue("mail", nil, Options{Delay: time.Minute})
+
+		Ω(q.RunDue()).Should(Equal(0))
+		now = now.Add(time.Minute)
+		Ω(q.RunDue()).Should(Equal(1))
+	})
+
+	It("retries failed jobs with a growing delay", func() {
+		attempts := 0
+		q.RetryDelay = time.Second
+		q.Register("mail", func(*Job) error {
+			attempts++
+
+			return errors.New("smtp down")
+		})
+		q.Enqueue("mail", nil, Options{MaxAttempts: 3})
+
+		q.RunDue()
+		now = now.Add(time.Second)
+		q.RunDue()
+		now = now.Add(3 * time.Second)
+		Ω(q.RunDue()).Should(Equal(0))
+		now = now.Add(time.Second)
+		q.RunDue()
+
+		Ω(attempts).Should(Equal(3))
+		Ω(q.Pending()).Should(Equal(0))
+		failed := q.Failed()
+		Ω(failed).Should(HaveLen(1))
+		Ω(failed[0].LastError).Should(Equal("smtp down"))
+	})
+
+	It("fails jobs that panic", func() {
+		q.Register("worldgen", func(*Job) error {
+			panic("boom")
+		})
+		q.Enqueue("worldgen", nil, Options{MaxAttempts: 1})
+		q.RunDue()
+
+		failed := q.Failed()
+		Ω(failed).Should(HaveLen(1))
+		Ω(failed[0].LastError).Should(ContainSubstring("boom"))
+	})
+
+	It("retries failed jobs on request", func() {
+		q.Register("mail", func(*Job) error { return errors.New("nope") })
+		id, _ := q.Enqueue("mail", nil, Options{MaxAttempts: 1})
+		q.RunDue()
+
+		Ω(q.Retry(id)).Should(BeTrue())
+		Ω(q.Pending()).Should(Equal(1))
+		Ω(q.Retry("missing")).Should(BeFalse())
+	})
+
+	Context("with a directory store", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir, _ = ioutil.TempDir("", "queue")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("loads jobs saved by a previous queue", func() {
+			first := New(NewDirStore(dir), logger.TestLog())
+			first.Enqueue("mail", map[string]interface{}{"to": "bob"}, Options{})
+
+			second := New(NewDirStore(dir), logger.TestLog())
+			Ω(second.Load()).Should(Succeed())
+			Ω(second.Pending()).Should(Equal(1))
+
+			var to interface{}
+			second.Register("mail", func(job *Job) error {
+				to = job.Payload["to"]
+
+				return nil
+			})
+			second.RunDue()
+
+			Ω(to).Should(Equal("bob"))
+			files, _ := ioutil.ReadDir(dir)
+			Ω(files).Should(BeEmpty())
+		})
+	})
+})
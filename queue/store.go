@@ -0,0 +1,143 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package queue
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store persists jobs so they survive restarts.
+type Store interface {
+	// Save creates or replaces the job.
+	Save(*Job) error
+	// Delete removes the job with the given ID.
+	Delete(id string) error
+	// Load returns every saved job.
+	Load() ([]*Job, error)
+}
+
+// DirStore saves each job as a JSON file in a directory.
+type DirStore struct {
+	Dir string
+}
+
+// NewDirStore creates a store saving jobs to the directory, the directory is
+// created when the first job is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{Dir: dir}
+}
+
+// Save writes the job to <id>.json, writing to a temporary file first so a
+// crash never leaves a partially written job behind.
+func (s *DirStore) Save(job *Job) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, job.ID+".json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Delete removes the job file, missing files are ignored.
+func (s *DirStore) Delete(id string) error {
+	err := os.Remove(filepath.Join(s.Dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Load reads every job file in the directory.
+func (s *DirStore) Load() ([]*Job, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(s.Dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		job := new(Job)
+		if err := json.Unmarshal(contents, job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// MemoryStore keeps jobs in memory, they're lost when the server stops.
+type MemoryStore struct {
+	jobs  map[string]Job
+	mutex *sync.Mutex
+}
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:  make(map[string]Job),
+		mutex: new(sync.Mutex),
+	}
+}
+
+// Save stores a copy of the job.
+func (s *MemoryStore) Save(job *Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs[job.ID] = *job
+
+	return nil
+}
+
+// Delete removes the job.
+func (s *MemoryStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.jobs, id)
+
+	return nil
+}
+
+// Load returns copies of every stored job.
+func (s *MemoryStore) Load() ([]*Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		j := job
+		jobs = append(jobs, &j)
+	}
+
+	return jobs, nil
+}
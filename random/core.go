@@ -4,29 +4,36 @@ package random
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// the generator is shared by every goroutine rolling numbers, rand.Rand isn't
+// safe to use from more than one at once so it's only used holding the mutex
 var (
-	source    = rand.NewSource(time.Now().UnixNano())
-	generator = rand.New(source)
+	generator      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	generatorMutex = new(sync.Mutex)
 )
 
 // Intn wraps rand.Intn
 func Intn(max int) int {
+	generatorMutex.Lock()
+	defer generatorMutex.Unlock()
+
 	return generator.Intn(max)
 }
 
 // Range generates a number between the min and max values provided.
 func Range(min, max int) int {
-	value := generator.Intn(max - min)
-
-	return value + min
+	return Intn(max-min) + min
 }
 
 // SetSource is used exclusively for testing, it should never be used outside
 // of an _test file. This will allow setting a known generator with a predicatble
 // source of random numbers for test prediction.
 func SetSource(source rand.Source) {
+	generatorMutex.Lock()
+	defer generatorMutex.Unlock()
+
 	generator = rand.New(source)
 }
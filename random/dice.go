@@ -3,10 +3,15 @@
 package random
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
 
+// MaxDice is the most dice Roll rolls at once, each die is rolled on its own
+// so more would tie up the caller.
+const MaxDice = 1000
+
 var validSides = map[string]func() int{
 	"2":   D2,
 	"4":   D4,
@@ -68,3 +73,52 @@ func RollDie(die string) []int {
 
 	return rolls
 }
+
+// Roll rolls dice written like "2d6+3", "d8", "1d4-1" or a plain number,
+// returning the total. Dice can have any number of sides, up to MaxDice of
+// them are rolled.
+func Roll(expr string) (int, error) {
+	expr = strings.ToLower(strings.Replace(expr, " ", "", -1))
+	bonus := 0
+	if i := strings.LastIndexAny(expr, "+-"); i > 0 {
+		n, err := strconv.Atoi(expr[i:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid dice %q", expr)
+		}
+		expr, bonus = expr[:i], n
+	}
+
+	parts := strings.Split(expr, "d")
+	if len(parts) == 1 {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, fmt.Errorf("invalid dice %q", expr)
+		}
+
+		return n + bonus, nil
+	}
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid dice %q", expr)
+	}
+	count := 1
+	if parts[0] != "" {
+		var err error
+		if count, err = strconv.Atoi(parts[0]); err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid dice %q", expr)
+		}
+		if count > MaxDice {
+			return 0, fmt.Errorf("too many dice in %q, at most %d are rolled", expr, MaxDice)
+		}
+	}
+	sides, err := strconv.Atoi(parts[1])
+	if err != nil || sides < 1 {
+		return 0, fmt.Errorf("invalid dice %q", expr)
+	}
+
+	total := bonus
+	for i := 0; i < count; i++ {
+		total += Range(1, sides+1)
+	}
+
+	return total, nil
+}
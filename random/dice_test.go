@@ -2,6 +2,7 @@ package random_test
 
 import (
 	"math/rand"
+	"sync"
 
 	. "github.com/bbuck/dragon-mud/random"
 
@@ -96,4 +97,46 @@ var _ = Describe("Die", func() {
 			}
 		})
 	})
+
+	Describe("Roll", func() {
+		It("adds bonuses to the dice", func() {
+			for i := 0; i < 100; i++ {
+				n, err := Roll("2d7+3")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(n).Should(BeNumerically(">=", 5))
+				Ω(n).Should(BeNumerically("<=", 17))
+			}
+			Ω(Roll("1d1-1")).Should(Equal(0))
+			Ω(Roll("4")).Should(Equal(4))
+		})
+
+		It("rejects invalid dice", func() {
+			for _, expr := range []string{"", "2d", "d0", "xd6", "1d6+x", "1d2d3"} {
+				_, err := Roll(expr)
+				Ω(err).Should(HaveOccurred(), expr)
+			}
+		})
+
+		It("rejects more than MaxDice dice", func() {
+			_, err := Roll("1000000000d6")
+			Ω(err).Should(MatchError(`too many dice in "1000000000d6", at most 1000 are rolled`))
+			n, err := Roll("1000d1")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(n).Should(Equal(1000))
+		})
+
+		It("can be rolled from many goroutines at once", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						Roll("2d6")
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	})
 })
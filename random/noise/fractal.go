@@ -0,0 +1,46 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package noise
+
+// Fractal options control how octaves of noise are layered. Each octave
+// samples the noise at Lacunarity times the frequency of the previous octave
+// with Persistence times its amplitude, adding finer detail.
+type Fractal struct {
+	Octaves     int
+	Frequency   float64
+	Persistence float64
+	Lacunarity  float64
+}
+
+// DefaultFractal is four octaves, each adding detail at twice the frequency
+// and half the amplitude of the one before.
+var DefaultFractal = Fractal{
+	Octaves:     4,
+	Frequency:   1,
+	Persistence: 0.5,
+	Lacunarity:  2,
+}
+
+// Noise2 layers octaves of the noise at the point, the result is normalized
+// so it stays between -1 and 1.
+func (f Fractal) Noise2(n Noise2D, x, y float64) float64 {
+	octaves := f.Octaves
+	if octaves < 1 {
+		octaves = 1
+	}
+
+	var total, scale float64
+	frequency, amplitude := f.Frequency, 1.0
+	for i := 0; i < octaves; i++ {
+		total += n.Noise2(x*frequency, y*frequency) * amplitude
+		scale += amplitude
+		frequency *= f.Lacunarity
+		amplitude *= f.Persistence
+	}
+
+	if scale == 0 {
+		return 0
+	}
+
+	return total / scale
+}
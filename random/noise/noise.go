@@ -0,0 +1,63 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package noise provides seeded coherent noise for procedural generation.
+// Coherent noise changes smoothly between nearby points, so sampling it on a
+// grid produces natural looking terrain, moisture or temperature maps. The
+// same seed always produces the same noise, so a world can be regenerated
+// from its seed.
+package noise
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// Noise2D produces a value between -1 and 1 for any point on a plane.
+type Noise2D interface {
+	Noise2(x, y float64) float64
+}
+
+// SeedFromString hashes the string into a seed, allowing worlds or regions
+// to be seeded by name.
+func SeedFromString(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	return int64(h.Sum64())
+}
+
+// the permutation table shared by each kind of noise, it's doubled so
+// lookups of perm[i + perm[j]] never need to wrap
+type permutation [512]uint8
+
+func newPermutation(seed int64) *permutation {
+	r := rand.New(rand.NewSource(seed))
+	p := new(permutation)
+	for i, v := range r.Perm(256) {
+		p[i] = uint8(v)
+		p[i+256] = uint8(v)
+	}
+
+	return p
+}
+
+// floor returns the largest integer less than or equal to x, it's faster
+// than math.Floor for the values used here
+func floor(x float64) int {
+	i := int(x)
+	if x < float64(i) {
+		return i - 1
+	}
+
+	return i
+}
+
+// fade is Perlin's quintic curve, 6t^5 - 15t^4 + 10t^3, which eases
+// coordinates toward lattice points so noise has no visible creases
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
@@ -0,0 +1,13 @@
+package noise_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Noise Suite")
+}
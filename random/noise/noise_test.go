@@ -0,0 +1,62 @@
+package noise_test
+
+import (
+	"math"
+
+	. "github.com/bbuck/dragon-mud/random/noise"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Noise", func() {
+	DescribeTable("generators",
+		func(build func(int64) Noise2D) {
+			a, b, other := build(42), build(42), build(7)
+
+			differs := false
+			for i := 0; i < 200; i++ {
+				x, y := float64(i)*0.37-20, float64(i)*0.73-40
+				n := a.Noise2(x, y)
+
+				Ω(n).Should(BeNumerically(">=", -1))
+				Ω(n).Should(BeNumerically("<=", 1))
+				Ω(b.Noise2(x, y)).Should(Equal(n))
+				if other.Noise2(x, y) != n {
+					differs = true
+				}
+
+				// nearby points have similar values
+				Ω(math.Abs(a.Noise2(x+0.001, y) - n)).Should(BeNumerically("<", 0.05))
+			}
+
+			Ω(differs).Should(BeTrue())
+		},
+		Entry("perlin", func(seed int64) Noise2D { return NewPerlin(seed) }),
+		Entry("simplex", func(seed int64) Noise2D { return NewSimplex(seed) }),
+		Entry("value", func(seed int64) Noise2D { return NewValue(seed) }),
+	)
+
+	It("is 0 at integer coordinates for Perlin noise", func() {
+		p := NewPerlin(1)
+
+		Ω(p.Noise2(3, 4)).Should(BeZero())
+		Ω(p.Noise3(-2, 5, 7)).Should(BeZero())
+	})
+
+	It("layers fractal noise within range", func() {
+		p := NewPerlin(1)
+		for i := 0; i < 100; i++ {
+			n := DefaultFractal.Noise2(p, float64(i)*0.13, float64(i)*0.29)
+
+			Ω(n).Should(BeNumerically(">=", -1))
+			Ω(n).Should(BeNumerically("<=", 1))
+		}
+	})
+
+	It("hashes strings into stable seeds", func() {
+		Ω(SeedFromString("overworld")).Should(Equal(SeedFromString("overworld")))
+		Ω(SeedFromString("overworld")).ShouldNot(Equal(SeedFromString("underdark")))
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package noise
+
+// Perlin is Ken Perlin's improved gradient noise in two or three dimensions.
+// Noise is 0 at every integer coordinate, so sample it at fractional
+// coordinates (scale the inputs down) for interesting results.
+type Perlin struct {
+	perm *permutation
+}
+
+// NewPerlin creates Perlin noise for the seed.
+func NewPerlin(seed int64) *Perlin {
+	return &Perlin{perm: newPermutation(seed)}
+}
+
+// Noise2 returns the noise at the point, between -1 and 1.
+func (p *Perlin) Noise2(x, y float64) float64 {
+	return p.Noise3(x, y, 0)
+}
+
+// Noise3 returns the noise at the point, between -1 and 1.
+func (p *Perlin) Noise3(x, y, z float64) float64 {
+	fx, fy, fz := floor(x), floor(y), floor(z)
+	xi, yi, zi := fx&255, fy&255, fz&255
+	x -= float64(fx)
+	y -= float64(fy)
+	z -= float64(fz)
+	u, v, w := fade(x), fade(y), fade(z)
+
+	perm := p.perm
+	a := int(perm[xi]) + yi
+	aa := int(perm[a]) + zi
+	ab := int(perm[a+1]) + zi
+	b := int(perm[xi+1]) + yi
+	ba := int(perm[b]) + zi
+	bb := int(perm[b+1]) + zi
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad3(perm[aa], x, y, z), grad3(perm[ba], x-1, y, z)),
+			lerp(u, grad3(perm[ab], x, y-1, z), grad3(perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad3(perm[aa+1], x, y, z-1), grad3(perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad3(perm[ab+1], x, y-1, z-1), grad3(perm[bb+1], x-1, y-1, z-1))))
+}
+
+// grad3 picks one of twelve gradient directions from the hash and returns
+// its dot product with the distance vector
+func grad3(hash uint8, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+
+	var v float64
+	switch {
+	case h < 4:
+		v = y
+	case h == 12 || h == 14:
+		v = x
+	default:
+		v = z
+	}
+
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+
+	return u + v
+}
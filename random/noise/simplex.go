@@ -0,0 +1,72 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package noise
+
+import "math"
+
+var (
+	skew2   = 0.5 * (math.Sqrt(3) - 1)
+	unskew2 = (3 - math.Sqrt(3)) / 6
+)
+
+// gradients for 2D simplex noise, the edges of a cube projected to a plane
+var simplexGrads = [12][2]float64{
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+	{1, 0}, {-1, 0}, {1, 0}, {-1, 0},
+	{0, 1}, {0, -1}, {0, 1}, {0, -1},
+}
+
+// Simplex is two dimensional simplex noise. It's similar to Perlin noise but
+// samples a triangular grid, which is faster and has fewer directional
+// artifacts.
+type Simplex struct {
+	perm *permutation
+}
+
+// NewSimplex creates simplex noise for the seed.
+func NewSimplex(seed int64) *Simplex {
+	return &Simplex{perm: newPermutation(seed)}
+}
+
+// Noise2 returns the noise at the point, between -1 and 1.
+func (s *Simplex) Noise2(x, y float64) float64 {
+	// find the triangle containing the point by skewing the plane onto a
+	// square grid
+	skew := (x + y) * skew2
+	i, j := floor(x+skew), floor(y+skew)
+	unskew := float64(i+j) * unskew2
+	x0 := x - (float64(i) - unskew)
+	y0 := y - (float64(j) - unskew)
+
+	// the middle corner depends on which half of the square the point is in
+	i1, j1 := 0, 1
+	if x0 > y0 {
+		i1, j1 = 1, 0
+	}
+
+	x1 := x0 - float64(i1) + unskew2
+	y1 := y0 - float64(j1) + unskew2
+	x2 := x0 - 1 + 2*unskew2
+	y2 := y0 - 1 + 2*unskew2
+
+	ii, jj := i&255, j&255
+	perm := s.perm
+	g0 := perm[ii+int(perm[jj])] % 12
+	g1 := perm[ii+i1+int(perm[jj+j1])] % 12
+	g2 := perm[ii+1+int(perm[jj+1])] % 12
+
+	// the result is scaled so it falls between -1 and 1
+	return 70 * (simplexCorner(g0, x0, y0) + simplexCorner(g1, x1, y1) + simplexCorner(g2, x2, y2))
+}
+
+// contribution of a single corner of the triangle to the noise
+func simplexCorner(g uint8, x, y float64) float64 {
+	t := 0.5 - x*x - y*y
+	if t < 0 {
+		return 0
+	}
+	t *= t
+	grad := simplexGrads[g]
+
+	return t * t * (grad[0]*x + grad[1]*y)
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package noise
+
+// Value is two dimensional value noise, random values at each integer
+// coordinate smoothly interpolated between. It's blockier than gradient noise
+// but cheap, and unlike Perlin noise isn't 0 at integer coordinates.
+type Value struct {
+	perm *permutation
+}
+
+// NewValue creates value noise for the seed.
+func NewValue(seed int64) *Value {
+	return &Value{perm: newPermutation(seed)}
+}
+
+// Noise2 returns the noise at the point, between -1 and 1.
+func (v *Value) Noise2(x, y float64) float64 {
+	fx, fy := floor(x), floor(y)
+	xi, yi := fx&255, fy&255
+	u, w := fade(x-float64(fx)), fade(y-float64(fy))
+
+	return lerp(w,
+		lerp(u, v.lattice(xi, yi), v.lattice(xi+1, yi)),
+		lerp(u, v.lattice(xi, yi+1), v.lattice(xi+1, yi+1)))
+}
+
+// the random value at an integer coordinate, between -1 and 1
+func (v *Value) lattice(x, y int) float64 {
+	return float64(v.perm[int(v.perm[x])+y])/127.5 - 1
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package sched
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule determines when a job should next be run after a given time.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// CronSchedule is a parsed five field cron expression, each field is stored as
+// a bit set of the values that match.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// when both day fields are restricted a day matching either is valid
+	domStar, dowStar bool
+}
+
+// EverySchedule runs a job on a fixed interval, like "@every 5m".
+type EverySchedule struct {
+	Interval time.Duration
+}
+
+// Next returns the time one interval after t.
+func (e EverySchedule) Next(t time.Time) time.Time {
+	return t.Add(e.Interval)
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse converts a cron expression into a Schedule. Standard five field
+// expressions ("minute hour day-of-month month day-of-week") are supported
+// with *, ranges (1-5), steps (*/15) and lists (1,15) along with month and
+// weekday names. The descriptors @yearly, @monthly, @weekly, @daily, @hourly
+// and "@every <duration>" are also accepted.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every interval must be at least one second, got %s", d)
+		}
+
+		return EverySchedule{d}, nil
+	}
+
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, found %d", spec, len(fields))
+	}
+
+	cs := &CronSchedule{
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	var err error
+	if cs.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if cs.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if cs.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if cs.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if cs.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for sunday
+	if cs.dow&(1<<7) > 0 {
+		cs.dow |= 1
+	}
+
+	return cs, nil
+}
+
+// Next finds the first minute after t that matches the schedule. If no
+// matching time is found within five years the zero time is returned.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) > 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) > 0
+
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// parse a comma separated list of values, ranges and steps into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:idx]
+		}
+
+		start, end := b.min, b.max
+		if part != "*" {
+			rng := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseValue(rng[0], b); err != nil {
+				return 0, err
+			}
+			end = start
+			if len(rng) == 2 {
+				if end, err = parseValue(rng[1], b); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				end = b.max
+			}
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if n, ok := b.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in cron expression", s)
+	}
+	if n < b.min || n > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", n, b.min, b.max)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package sched_test
+
+import (
+	"time"
+
+	. "github.com/bbuck/dragon-mud/sched"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron", func() {
+	// a Sunday
+	start := time.Date(2017, time.January, 1, 12, 30, 15, 0, time.UTC)
+
+	DescribeTable("Next",
+		func(spec string, expected time.Time) {
+			s, err := Parse(spec)
+			Ω(err).Should(BeNil())
+			Ω(s.Next(start)).Should(Equal(expected))
+		},
+		Entry("every minute", "* * * * *", time.Date(2017, time.January, 1, 12, 31, 0, 0, time.UTC)),
+		Entry("nightly at 3am", "0 3 * * *", time.Date(2017, time.January, 2, 3, 0, 0, 0, time.UTC)),
+		Entry("every fifteen minutes", "*/15 * * * *", time.Date(2017, time.January, 1, 12, 45, 0, 0, time.UTC)),
+		Entry("weekdays by name", "0 9 * * mon-fri", time.Date(2017, time.January, 2, 9, 0, 0, 0, time.UTC)),
+		Entry("lists of months", "0 0 1 mar,jun *", time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("hourly descriptor", "@hourly", time.Date(2017, time.January, 1, 13, 0, 0, 0, time.UTC)),
+		Entry("every descriptor", "@every 90s", start.Add(90*time.Second)),
+	)
+
+	It("returns the zero time for impossible dates", func() {
+		s, err := Parse("0 0 30 feb *")
+		Ω(err).Should(BeNil())
+		Ω(s.Next(start).IsZero()).Should(BeTrue())
+	})
+
+	DescribeTable("invalid expressions",
+		func(spec string) {
+			_, err := Parse(spec)
+			Ω(err).ShouldNot(BeNil())
+		},
+		Entry("too few fields", "* * *"),
+		Entry("out of range values", "60 * * * *"),
+		Entry("backwards ranges", "0 5-2 * * *"),
+		Entry("bad steps", "*/0 * * * *"),
+		Entry("bad durations", "@every soon"),
+	)
+})
@@ -0,0 +1,13 @@
+package sched_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSched(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sched Suite")
+}
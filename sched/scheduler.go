@@ -0,0 +1,189 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package sched
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+)
+
+// a named function run on a schedule
+type scheduledJob struct {
+	Name     string
+	Spec     string
+	Next     time.Time
+	schedule Schedule
+	fn       func()
+}
+
+// JobInfo describes a scheduled job without exposing the function it runs.
+type JobInfo struct {
+	Name string
+	Spec string
+	Next time.Time
+}
+
+// Scheduler runs jobs when their schedule comes due. The scheduler checks for
+// due jobs once every second after it's been started.
+type Scheduler struct {
+	log     logger.Log
+	jobs    map[string]*scheduledJob
+	mutex   *sync.Mutex
+	nextID  uint64
+	stop    chan struct{}
+	running bool
+	now     func() time.Time
+}
+
+// New creates a new scheduler, it will not run any jobs until Start is called.
+func New(log logger.Log) *Scheduler {
+	return &Scheduler{
+		log:   log,
+		jobs:  make(map[string]*scheduledJob),
+		mutex: new(sync.Mutex),
+		now:   time.Now,
+	}
+}
+
+var (
+	globalScheduler *Scheduler
+	globalOnce      sync.Once
+)
+
+// Global returns the game wide scheduler, starting it on first use.
+func Global() *Scheduler {
+	globalOnce.Do(func() {
+		globalScheduler = New(logger.NewWithSource("scheduler"))
+		globalScheduler.Start()
+	})
+
+	return globalScheduler
+}
+
+// Add schedules fn to run according to the cron expression. If a job with the
+// same name already exists it's replaced. If name is empty a unique name is
+// generated. The name of the job is returned.
+func (s *Scheduler) Add(name, spec string, fn func()) (string, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if name == "" {
+		s.nextID++
+		name = fmt.Sprintf("job-%d", s.nextID)
+	}
+	s.jobs[name] = &scheduledJob{
+		Name:     name,
+		Spec:     spec,
+		Next:     schedule.Next(s.now()),
+		schedule: schedule,
+		fn:       fn,
+	}
+
+	return name, nil
+}
+
+// Cancel removes the job with the given name, returning whether or not a job
+// was removed.
+func (s *Scheduler) Cancel(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.jobs[name]
+	delete(s.jobs, name)
+
+	return ok
+}
+
+// Jobs lists all scheduled jobs sorted by name.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		infos = append(infos, JobInfo{
+			Name: job.Name,
+			Spec: job.Spec,
+			Next: job.Next,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// Start begins checking for due jobs in the background.
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.RunDue(s.now())
+			case <-stop:
+				return
+			}
+		}
+	}(s.stop)
+}
+
+// Stop halts the scheduler, jobs that are already running will finish.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.running {
+		close(s.stop)
+		s.running = false
+	}
+}
+
+// RunDue runs every job that was due at or before the given time and
+// reschedules them. Jobs are run in their own goroutines.
+func (s *Scheduler) RunDue(now time.Time) {
+	s.mutex.Lock()
+	var due []*scheduledJob
+	for _, job := range s.jobs {
+		if !job.Next.IsZero() && !job.Next.After(now) {
+			due = append(due, job)
+			job.Next = job.schedule.Next(now)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, job := range due {
+		go s.run(job)
+	}
+}
+
+// run a single job, making sure a panicking job doesn't bring the server down
+func (s *Scheduler) run(job *scheduledJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.WithField("job", job.Name).WithField("panic", r).Error("Scheduled job panicked.")
+		}
+	}()
+
+	job.fn()
+}
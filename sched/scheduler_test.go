@@ -0,0 +1,64 @@
+package sched_test
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/logger"
+	. "github.com/bbuck/dragon-mud/sched"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler", func() {
+	var s *Scheduler
+
+	BeforeEach(func() {
+		s = New(logger.TestLog())
+	})
+
+	It("generates names for unnamed jobs", func() {
+		name, err := s.Add("", "* * * * *", func() {})
+		Ω(err).Should(BeNil())
+		Ω(name).Should(Equal("job-1"))
+	})
+
+	It("lists and cancels jobs", func() {
+		s.Add("restock", "0 3 * * *", func() {})
+		s.Add("lottery", "0 0 * * sun", func() {})
+
+		jobs := s.Jobs()
+		Ω(jobs).Should(HaveLen(2))
+		Ω(jobs[0].Name).Should(Equal("lottery"))
+		Ω(jobs[1].Spec).Should(Equal("0 3 * * *"))
+
+		Ω(s.Cancel("lottery")).Should(BeTrue())
+		Ω(s.Cancel("lottery")).Should(BeFalse())
+		Ω(s.Jobs()).Should(HaveLen(1))
+	})
+
+	It("runs jobs that are due", func() {
+		ran := make(chan bool, 1)
+		s.Add("tick", "* * * * *", func() {
+			ran <- true
+		})
+
+		s.RunDue(time.Now().Add(2 * time.Minute))
+		Eventually(ran).Should(Receive())
+	})
+
+	It("does not run jobs that aren't due", func() {
+		ran := make(chan bool, 1)
+		s.Add("later", "* * * * *", func() {
+			ran <- true
+		})
+
+		s.RunDue(time.Now().Add(-time.Minute))
+		Consistently(ran).ShouldNot(Receive())
+	})
+
+	It("rejects invalid schedules", func() {
+		_, err := s.Add("bad", "nope", func() {})
+		Ω(err).ShouldNot(BeNil())
+	})
+})
@@ -6,10 +6,45 @@ import (
 	"sync/atomic"
 
 	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/admin"
+	"github.com/bbuck/dragon-mud/game/advance"
+	"github.com/bbuck/dragon-mud/game/ban"
+	"github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/checkpoint"
+	"github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/game/clock"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/equipment"
+	"github.com/bbuck/dragon-mud/game/group"
+	"github.com/bbuck/dragon-mud/game/instance"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/language"
+	"github.com/bbuck/dragon-mud/game/lock"
+	"github.com/bbuck/dragon-mud/game/loot"
+	"github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/game/perm"
+	"github.com/bbuck/dragon-mud/game/presence"
+	"github.com/bbuck/dragon-mud/game/pvp"
+	"github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/game/save"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/game/social"
+	"github.com/bbuck/dragon-mud/game/weather"
 	"github.com/bbuck/dragon-mud/logger"
 	"github.com/bbuck/dragon-mud/plugins"
 	"github.com/bbuck/dragon-mud/scripting/keys"
 	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server/session"
 	uuid "github.com/satori/go.uuid"
 	"github.com/spf13/viper"
 )
@@ -41,6 +76,45 @@ func Initialize() {
 	ClientEmitter = events.NewEmitter(logger.NewWithSource("emitter(client)"))
 	EntityEmitter = events.NewEmitter(logger.NewWithSource("emitter(entity)"))
 
+	if viper.GetBool("log.error_events") {
+		events.BridgeLogErrors(ServerEmitter)
+	}
+	session.Global().SetEmitter(ServerEmitter)
+	command.GlobalPacer().Dispatcher().SetEmitter(ServerEmitter)
+	movement.Global().SetEmitter(ServerEmitter)
+	item.Global().SetEmitter(ServerEmitter)
+	equipment.Global().SetEmitter(ServerEmitter)
+	mob.Global().SetEmitter(ServerEmitter)
+	combat.Global().SetEmitter(ServerEmitter)
+	skill.Global().SetEmitter(ServerEmitter)
+	effect.Global().SetEmitter(ServerEmitter)
+	quest.Global().SetEmitter(ServerEmitter)
+	dialogue.Global().SetEmitter(ServerEmitter)
+	shop.Global().SetEmitter(ServerEmitter)
+	craft.Global().SetEmitter(ServerEmitter)
+	channels.Global().SetEmitter(ServerEmitter)
+	social.Global().SetEmitter(ServerEmitter)
+	mail.Global().SetEmitter(ServerEmitter)
+	board.Global().SetEmitter(ServerEmitter)
+	presence.Global().SetEmitter(ServerEmitter)
+	olc.Global().SetEmitter(ServerEmitter)
+	perm.Global().SetEmitter(ServerEmitter)
+	admin.Global().SetEmitter(ServerEmitter)
+	ban.Global().SetEmitter(ServerEmitter)
+	group.Global().SetEmitter(ServerEmitter)
+	clan.Global().SetEmitter(ServerEmitter)
+	pvp.Global().SetEmitter(ServerEmitter)
+	clock.Game().SetEmitter(ServerEmitter)
+	weather.Global().SetEmitter(ServerEmitter)
+	instance.Global().SetEmitter(ServerEmitter)
+	loot.Global().SetEmitter(ServerEmitter)
+	advance.Global().SetEmitter(ServerEmitter)
+	death.Global().SetEmitter(ServerEmitter)
+	lock.Global().SetEmitter(ServerEmitter)
+	language.Global().SetEmitter(ServerEmitter)
+	save.Global().SetEmitter(ServerEmitter)
+	checkpoint.Global().SetEmitter(ServerEmitter)
+
 	size := viper.GetInt("scripting.server.engine_pool_size")
 	if size < 0 {
 		size = 0
@@ -60,6 +134,18 @@ func GlobalEmit(evt string, data events.Data) {
 	EntityEmitter.Emit(evt, data)
 }
 
+// RunTrigger runs the source of a trigger script in a server engine, with
+// what set it off in the global table trigger.
+func RunTrigger(script string, source []byte, data events.Data) error {
+	eng := ServerPool.Get()
+	defer eng.Release()
+
+	eng.SetGlobal("trigger", eng.TableFromMap(data))
+	defer eng.SetGlobal("trigger", nil)
+
+	return eng.DoString(string(source))
+}
+
 // ServerEngineMutator is a mutator function for the server EnginePool to use
 // to "build" a server engine.
 func ServerEngineMutator(eng *lua.Engine) {
@@ -8,6 +8,8 @@ const (
 	Pool            = "engine pool"
 	Logger          = "logger"
 	RootCmd         = "root command"
+	Locale          = "locale"
+	LogLevel        = "log level"
 
 	TalonRowMetatable  = "talon row metatable"
 	TalonRowsMetatable = "talon rows metatable"
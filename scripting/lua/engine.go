@@ -17,6 +17,10 @@ import (
 type Engine struct {
 	state *lua.LState
 	Meta  map[string]interface{}
+
+	// the chunk each function loaded by Go was loaded from, functions scripts
+	// load themselves aren't recorded
+	chunks map[*lua.FunctionProto]string
 }
 
 // ScriptFunction is a type alias for a function that receives an Engine and
@@ -34,7 +38,8 @@ func NewEngine(opts ...EngineOptions) *Engine {
 			SkipOpenLibs:        true,
 			IncludeGoStackTrace: true,
 		}),
-		Meta: make(map[string]interface{}),
+		Meta:   make(map[string]interface{}),
+		chunks: make(map[*lua.FunctionProto]string),
 	}
 	eng.OpenBase()
 	eng.OpenPackage()
@@ -153,7 +158,14 @@ func (e *Engine) OpenLibs() {
 
 // DoFile runs the file through the Lua interpreter.
 func (e *Engine) DoFile(fn string) error {
-	return e.state.DoFile(fn)
+	lfn, err := e.state.LoadFile(fn)
+	if err != nil {
+		return err
+	}
+	e.recordChunk(lfn.Proto, fn)
+	e.state.Push(lfn)
+
+	return e.state.PCall(0, lua.MultRet, nil)
 }
 
 // LoadString runs the given string through the Lua interpreter, wrapping it
@@ -164,6 +176,7 @@ func (e *Engine) LoadString(src string) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	e.recordChunk(fn.Proto, fn.Proto.SourceName)
 
 	return e.ValueFor(fn), nil
 }
@@ -175,13 +188,24 @@ func (e *Engine) LoadFile(fpath string) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	e.recordChunk(fn.Proto, fpath)
 
 	return e.ValueFor(fn), nil
 }
 
-// DoString runs the given string through the Lua interpreter.
+// DoString runs the given string through the Lua interpreter. Functions the
+// string defines are only known to be loaded by Go while it runs, so strings
+// run over and over don't build up.
 func (e *Engine) DoString(src string) error {
-	return e.state.DoString(src)
+	fn, err := e.state.LoadString(src)
+	if err != nil {
+		return err
+	}
+	e.recordChunk(fn.Proto, fn.Proto.SourceName)
+	defer e.forgetChunk(fn.Proto)
+	e.state.Push(fn)
+
+	return e.state.PCall(0, lua.MultRet, nil)
 }
 
 // RaiseError will throw an error in the Lua engine.
@@ -194,6 +218,53 @@ func (e *Engine) ArgumentError(n int, msg string) {
 	e.state.ArgError(n, msg)
 }
 
+// CallerChunks returns the chunks the Lua functions calling into Go were
+// loaded from, nearest first, the path of a file or "<string>" for code run
+// from a string. Chunks are only known for code Go loaded, scripts can name
+// the chunks they load with load or loadstring anything they like so false
+// is returned if any of the callers were loaded by a script.
+func (e *Engine) CallerChunks() ([]string, bool) {
+	var chunks []string
+	for level := 1; ; level++ {
+		dbg, ok := e.state.GetStack(level)
+		if !ok {
+			break
+		}
+		fn, err := e.state.GetInfo("f", dbg, lua.LNil)
+		if err != nil {
+			continue
+		}
+		lfn, ok := fn.(*lua.LFunction)
+		if !ok || lfn.IsG {
+			continue
+		}
+		chunk, ok := e.chunks[lfn.Proto]
+		if !ok {
+			return nil, false
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, true
+}
+
+// record the chunk the function and each function it defines were loaded
+// from
+func (e *Engine) recordChunk(proto *lua.FunctionProto, chunk string) {
+	e.chunks[proto] = chunk
+	for _, p := range proto.FunctionPrototypes {
+		e.recordChunk(p, chunk)
+	}
+}
+
+// forget where the function and each function it defines were loaded from
+func (e *Engine) forgetChunk(proto *lua.FunctionProto) {
+	delete(e.chunks, proto)
+	for _, p := range proto.FunctionPrototypes {
+		e.forgetChunk(p)
+	}
+}
+
 // SetGlobal allows for setting global variables in the loaded code.
 func (e *Engine) SetGlobal(name string, val interface{}) {
 	v := e.ValueFor(val)
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/yuin/gopher-lua"
 )
@@ -59,6 +60,24 @@ func (v *Value) AsRaw() interface{} {
 // Inspect is similar to AsString except that it's designed to display values
 // for debug purposes.
 func (v *Value) Inspect(indent string) string {
+	return v.InspectDepth(indent, 0)
+}
+
+// InspectDepth behaves like Inspect except that tables nested deeper than
+// maxDepth are rendered as {...}, a maxDepth of 0 or less has no limit. Tables
+// that have already been rendered higher up in the value are rendered as
+// <cycle> instead of recursing forever.
+func (v *Value) InspectDepth(indent string, maxDepth int) string {
+	if maxDepth <= 0 {
+		maxDepth = -1
+	}
+
+	return v.inspect(indent, maxDepth, make(map[*lua.LTable]bool))
+}
+
+// inspect renders the value, depth is the number of table levels left to
+// expand where a negative depth is unlimited.
+func (v *Value) inspect(indent string, depth int, seen map[*lua.LTable]bool) string {
 	nextIndent := indent + "  "
 
 	switch v.lval.Type() {
@@ -98,22 +117,47 @@ func (v *Value) Inspect(indent string) string {
 			return vals[0].AsString()
 		}
 	case lua.LTTable:
+		tbl := v.asTable()
+		if seen[tbl] {
+			return "<cycle>"
+		}
+
 		vals, err := v.Invoke("inspect", 1, v)
-		if err != nil || len(vals) == 0 {
-			buf := new(bytes.Buffer)
-			buf.WriteString("{\n")
-			v.ForEach(func(key, val *Value) {
-				buf.WriteString(nextIndent)
-				buf.WriteString(fmt.Sprintf("[%s] = %s", key.Inspect(nextIndent), val.Inspect(nextIndent)))
-				buf.WriteString(",\n")
-			})
-			buf.WriteString(indent)
-			buf.WriteString("}")
-
-			return buf.String()
+		if err == nil && len(vals) > 0 {
+			return vals[0].inspect(nextIndent, depth, seen)
+		}
+
+		var keys []*Value
+		v.ForEach(func(key, _ *Value) {
+			keys = append(keys, key)
+		})
+		if len(keys) == 0 {
+			return "{}"
+		}
+		if depth == 0 {
+			return "{...}"
+		}
+		sort.Sort(inspectKeys(keys))
+
+		seen[tbl] = true
+		defer delete(seen, tbl)
+
+		childDepth := depth
+		if depth > 0 {
+			childDepth--
+		}
+
+		buf := new(bytes.Buffer)
+		buf.WriteString("{\n")
+		for _, key := range keys {
+			buf.WriteString(nextIndent)
+			buf.WriteString(fmt.Sprintf("[%s] = %s", key.inspect(nextIndent, childDepth, seen), v.RawGet(key).inspect(nextIndent, childDepth, seen)))
+			buf.WriteString(",\n")
 		}
+		buf.WriteString(indent)
+		buf.WriteString("}")
 
-		return vals[0].Inspect(nextIndent)
+		return buf.String()
 	case lua.LTFunction:
 		return "<function>"
 	}
@@ -121,6 +165,32 @@ func (v *Value) Inspect(indent string) string {
 	return "nil"
 }
 
+// inspectKeys sorts table keys for display, numbers come first in numeric
+// order followed by everything else ordered by its string value.
+type inspectKeys []*Value
+
+func (k inspectKeys) Len() int {
+	return len(k)
+}
+
+func (k inspectKeys) Swap(i, j int) {
+	k[i], k[j] = k[j], k[i]
+}
+
+func (k inspectKeys) Less(i, j int) bool {
+	a, b := k[i], k[j]
+	switch {
+	case a.IsNumber() && b.IsNumber():
+		return a.AsNumber() < b.AsNumber()
+	case a.IsNumber():
+		return true
+	case b.IsNumber():
+		return false
+	default:
+		return a.String() < b.String()
+	}
+}
+
 // AsString returns the LValue as a Go string
 func (v *Value) AsString() string {
 	return lua.LVAsString(v.lval)
@@ -490,8 +560,9 @@ func (v *Value) Call(retCount int, argList ...interface{}) ([]*Value, error) {
 		}
 
 		retVals := make([]*Value, retCount)
-		for i := 0; i < retCount; i++ {
+		for i := retCount - 1; i >= 0; i-- {
 			retVals[i] = v.owner.ValueFor(v.owner.state.Get(-1))
+			v.owner.state.Pop(1)
 		}
 
 		return retVals, nil
@@ -321,6 +321,19 @@ var _ = Describe("LuaValue", func() {
 		})
 	})
 
+	Describe("Call()", func() {
+		It("returns every result in order and leaves the stack as it was", func() {
+			Ω(engine.DoString(`function pair(a) return a, a * 2 end`)).Should(Succeed())
+			size := engine.StackSize()
+
+			results, err := engine.GetGlobal("pair").Call(2, 3)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(results[0].AsNumber()).Should(Equal(float64(3)))
+			Ω(results[1].AsNumber()).Should(Equal(float64(6)))
+			Ω(engine.StackSize()).Should(Equal(size))
+		})
+	})
+
 	Describe("AsMapStringInterface()", func() {
 		var (
 			table *Value
@@ -376,4 +389,27 @@ var _ = Describe("LuaValue", func() {
 			Ω(s[1]).Should(Equal(float64(1)))
 		})
 	})
+
+	Describe("Inspect()", func() {
+		It("sorts keys with numbers first", func() {
+			engine.DoString(`t = {b = 2, a = 1, "x"}`)
+			str := engine.GetGlobal("t").Inspect("")
+
+			Ω(str).Should(Equal("{\n  [1] = \"x\",\n  [\"a\"] = 1,\n  [\"b\"] = 2,\n}"))
+		})
+
+		It("renders cycles without recursing", func() {
+			engine.DoString(`t = {}; t.self = t`)
+			str := engine.GetGlobal("t").Inspect("")
+
+			Ω(str).Should(Equal("{\n  [\"self\"] = <cycle>,\n}"))
+		})
+
+		It("limits the depth of nested tables", func() {
+			engine.DoString(`t = {a = {b = {}}, c = {d = 1}}`)
+			str := engine.GetGlobal("t").InspectDepth("", 1)
+
+			Ω(str).Should(Equal("{\n  [\"a\"] = {...},\n  [\"c\"] = {...},\n}"))
+		})
+	})
 })
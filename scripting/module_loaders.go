@@ -8,22 +8,79 @@ import (
 )
 
 var simpleModuleMap = map[string]lua.TableMap{
-	"tmpl":     modules.Tmpl,
-	"password": modules.Password,
-	"die":      modules.Die,
-	"random":   modules.Random,
-	"events":   modules.Events,
-	"log":      modules.Log,
-	"sutil":    modules.Sutil,
-	"cli":      modules.Cli,
-	"config":   modules.Config,
-	"time":     modules.Time,
-	"uuid":     modules.UUID,
+	"tmpl":      modules.Tmpl,
+	"password":  modules.Password,
+	"die":       modules.Die,
+	"random":    modules.Random,
+	"events":    modules.Events,
+	"log":       modules.Log,
+	"sutil":     modules.Sutil,
+	"cli":       modules.Cli,
+	"config":    modules.Config,
+	"time":      modules.Time,
+	"uuid":      modules.UUID,
+	"db":        modules.DB,
+	"color":     modules.Color,
+	"fs":        modules.FS,
+	"sched":     modules.Sched,
+	"i18n":      modules.I18n,
+	"str":       modules.Str,
+	"re":        modules.Re,
+	"inspect":   modules.Inspect,
+	"path":      modules.Path,
+	"namegen":   modules.Namegen,
+	"cooldown":  modules.Cooldown,
+	"cache":     modules.Cache,
+	"metrics":   modules.Metrics,
+	"queue":     modules.Queue,
+	"noise":     modules.Noise,
+	"grid":      modules.Grid,
+	"audit":     modules.Audit,
+	"oob":       modules.OOB,
+	"cmd":       modules.Cmd,
+	"prompt":    modules.Prompt,
+	"character": modules.Character,
+	"player":    modules.Player,
+	"item":      modules.Item,
+	"mob":       modules.Mob,
+	"ai":        modules.AI,
+	"combat":    modules.Combat,
+	"skill":     modules.Skill,
+	"effect":    modules.Effect,
+	"quest":     modules.Quest,
+	"dialogue":  modules.Dialogue,
+	"shop":      modules.Shop,
+	"craft":     modules.Craft,
+	"channels":  modules.Channels,
+	"social":    modules.Social,
+	"mail":      modules.Mail,
+	"board":     modules.Board,
+	"presence":  modules.Presence,
+	"help":      modules.Help,
+	"olc":       modules.OLC,
+	"perm":      modules.Perm,
+	"clan":      modules.Clan,
+	"weather":   modules.Weather,
+	"triggers":  modules.Triggers,
+	"loot":      modules.Loot,
+	"advance":   modules.Advance,
+	"death":     modules.Death,
+	"minimap":   modules.Minimap,
+	"lock":      modules.Lock,
+	"vision":    modules.Vision,
+	"language":  modules.Language,
+	"storage":   modules.Storage,
+	"model":     modules.Model,
 }
 
 var complexModuleMap = map[string]func(*lua.Engine){
-	"talon": modules.TalonLoader,
-	"fn":    modules.ScriptLoader("modules/fn.lua"),
+	"talon":     modules.TalonLoader,
+	"check":     modules.ScriptLoader("modules/check.lua"),
+	"fn":        modules.ScriptLoader("modules/fn.lua"),
+	"tbl":       modules.ScriptLoader("modules/tbl.lua"),
+	"mathx":     modules.ScriptLoader("modules/mathx.lua"),
+	"encoding":  modules.EncodingLoader,
+	"serialize": modules.SerializeLoader,
 }
 
 // OpenLibs will open all modules given to the function as defined in the
@@ -0,0 +1,114 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/advance"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Advance lets scripts award experience and decide what players gain as
+// they level up.
+//   award(player, xp): number
+//     gives the player experience, returning how many levels they gained or
+//     nil if there's no such player.
+//   needed(level): number
+//     returns the experience it takes to reach the level, -1 if it can't be
+//     reached.
+//   level(xp): number
+//     returns the level the experience reaches.
+//   hook(name, fn)
+//     @param name: string = the name of the hook, hooks run in order of
+//       their names
+//     @param fn: function(player, level) = given the name of the player and
+//       the level they reached, gives them what they gain
+//     sets the hook, replacing any with the name.
+//   unhook(name)
+//     removes the hook with the name.
+//   gain(gain): boolean, string
+//     @param gain: table = the class, race, min_level, max_level, every,
+//       stats and skills of the gain, as in the advancement file
+//     adds the gain, returning false and why if it doesn't make sense.
+//   remort(player): boolean, string
+//     starts the player over from the first level, returning false and why
+//     if they can't.
+var Advance = lua.TableMap{
+	"award": func(engine *lua.Engine) int {
+		xp := engine.PopInt()
+		a := advancer(engine.PopString())
+		if a == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(advance.Global().Award(a, xp))
+
+		return 1
+	},
+	"needed": func(level int) int {
+		return advance.Global().Needed(level)
+	},
+	"level": func(xp int) int {
+		return advance.Global().Config().Curve.Level(xp)
+	},
+	"hook": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		advance.Global().Hook(name, func(a advance.Advancer, level int) {
+			if _, err := fn.Call(0, a.Name(), level); err != nil {
+				log("advance").WithError(err).WithField("hook", name).WithField("engine", nameForEngine(engine)).Error("Level up hook failed.")
+			}
+		})
+
+		return 0
+	},
+	"unhook": func(name string) {
+		advance.Global().Hook(name, nil)
+	},
+	"gain": func(engine *lua.Engine) int {
+		return pushResult(engine, advance.Global().AddGain(gainFromTable(engine.PopTable())))
+	},
+	"remort": func(engine *lua.Engine) int {
+		a := advancer(engine.PopString())
+		if a == nil {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+
+		return pushResult(engine, advance.Global().Remort(a))
+	},
+}
+
+// advancer returns the combatant with the id if they level up
+func advancer(id string) advance.Advancer {
+	a, _ := combat.Global().Lookup(id).(advance.Advancer)
+
+	return a
+}
+
+func gainFromTable(t *lua.Value) advance.Gain {
+	g := advance.Gain{
+		Class:    t.Get("class").AsString(),
+		Race:     t.Get("race").AsString(),
+		MinLevel: int(t.Get("min_level").AsNumber()),
+		MaxLevel: int(t.Get("max_level").AsNumber()),
+		Every:    int(t.Get("every").AsNumber()),
+	}
+	if stats := t.Get("stats"); stats.IsTable() {
+		g.Stats = make(map[string]int)
+		stats.ForEach(func(k, v *lua.Value) {
+			g.Stats[k.AsString()] = int(v.AsNumber())
+		})
+	}
+	if skills := t.Get("skills"); skills.IsTable() {
+		g.Skills = make(map[string]int)
+		skills.ForEach(func(k, v *lua.Value) {
+			g.Skills[k.AsString()] = int(v.AsNumber())
+		})
+	}
+
+	return g
+}
@@ -0,0 +1,86 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/advance"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// climber is a student who levels up
+type climber struct {
+	student
+}
+
+func (c *climber) Race() string {
+	return "gnome"
+}
+
+func (c *climber) SetStat(name string, value int) {
+	c.stats[name] = value
+}
+
+var _ = Describe("Advance Lua Module", func() {
+	var (
+		engine *lua.Engine
+		sage   *climber
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "advance")
+		engine.DoString(`advance = require("advance")`)
+		sage = &climber{student{brawler: brawler{id: "sage", stats: map[string]int{}}, skills: map[string]int{}}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "sage" {
+				return sage
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		advance.Global().Hook("lua-wisdom", nil)
+		advance.Global().SetConfig(advance.Config{Curve: advance.DefaultCurve})
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("awards experience, running gains and hooks as players level", func() {
+		res, err := testReturn(engine, `
+			local leveled = {}
+			advance.gain({class = "sage", stats = {wisdom = 2}, skills = {lore = 10}})
+			advance.hook("lua-wisdom", function(player, level)
+				table.insert(leveled, player .. ":" .. level)
+			end)
+
+			return {
+				levels = advance.award("sage", advance.needed(3)),
+				missing = advance.award("nobody", 10) == nil,
+				level = advance.level(advance.needed(3)),
+				hooked = leveled[2],
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("levels").AsNumber()).Should(BeEquivalentTo(2))
+		Ω(res[0].Get("missing").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("level").AsNumber()).Should(BeEquivalentTo(3))
+		Ω(res[0].Get("hooked").AsString()).Should(Equal("sage:3"))
+		Ω(sage.stats["wisdom"]).Should(Equal(4))
+		Ω(sage.skills["lore"]).Should(Equal(20))
+	})
+
+	It("explains why players can't remort", func() {
+		res, err := testReturn(engine, `
+			local _, why = advance.remort("sage")
+
+			return why
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsString()).Should(Equal(advance.NoRemortMessage))
+	})
+})
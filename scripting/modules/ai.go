@@ -0,0 +1,128 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/ai"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// AI lets scripts write the actions and conditions behavior trees are built
+// from. Mobs run the tree named in their "behavior" prop, trees are loaded
+// from files in the behavior directory.
+//   action(name, fn)
+//     @param name: string = the name trees use for the action
+//     @param fn: function(mob, args) = run with the id of the mob and the
+//       args the tree gives the action, it returns "success", "failure" or
+//       "running", or true for success and false or nil for failure
+//     adds the action, replacing any action or condition with the name.
+//   condition(name, fn)
+//     @param name: string = the name trees use for the condition
+//     @param fn: function(mob, args): boolean = run like an action, the
+//       condition succeeds when it returns a true value
+//     adds the condition, replacing any action or condition with the name.
+//   get(mob, key): any
+//     returns the value of the key on the mob's blackboard, nil if it isn't
+//     set.
+//   set(mob, key, value)
+//     sets the key on the mob's blackboard, nil removes it.
+//   tick(mob): string
+//     ticks the mob's tree now, returning "success", "failure" or "running".
+//   trees(): table
+//     returns the names of the loaded trees, sorted.
+var AI = lua.TableMap{
+	"action": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		ai.Global().Leaves().Action(name, luaLeaf(engine, fn, func(ret *lua.Value) ai.Status {
+			switch {
+			case ret.IsString():
+				switch strings.ToLower(ret.AsString()) {
+				case "success":
+					return ai.Success
+				case "running":
+					return ai.Running
+				}
+
+				return ai.Failure
+			case ret.IsTrue():
+				return ai.Success
+			}
+
+			return ai.Failure
+		}))
+
+		return 0
+	},
+	"condition": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		ai.Global().Leaves().Action(name, luaLeaf(engine, fn, func(ret *lua.Value) ai.Status {
+			if ret.IsTrue() {
+				return ai.Success
+			}
+
+			return ai.Failure
+		}))
+
+		return 0
+	},
+	"get": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		id := engine.PopString()
+		engine.PushValue(engine.ValueFor(ai.Global().Board(id).Get(key)))
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		value := engine.PopValue()
+		key := engine.PopString()
+		id := engine.PopString()
+		if value.IsNil() {
+			ai.Global().Board(id).Set(key, nil)
+		} else {
+			ai.Global().Board(id).Set(key, value.AsRaw())
+		}
+
+		return 0
+	},
+	"tick": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		m := mob.Global().Get(id)
+		if m == nil {
+			engine.PushValue(ai.Failure.String())
+
+			return 1
+		}
+		engine.PushValue(ai.Global().TickMob(m).String())
+
+		return 1
+	},
+	"trees": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromSlice(ai.Global().Trees().Names()))
+
+		return 1
+	},
+}
+
+// luaLeaf runs the function for a tree, turning what it returns into a
+// status with result
+func luaLeaf(engine *lua.Engine, fn *lua.Value, result func(*lua.Value) ai.Status) ai.LeafFunc {
+	return func(ctx *ai.Context) ai.Status {
+		args := engine.TableFromMap(ctx.Args)
+		ret, err := fn.Call(1, ctx.Mob.ID(), args)
+		if err != nil {
+			log("ai").WithError(err).WithField("engine", nameForEngine(engine)).Error("Behavior tree leaf failed.")
+
+			return ai.Failure
+		}
+		if len(ret) == 0 {
+			return ai.Failure
+		}
+
+		return result(ret[0])
+	}
+}
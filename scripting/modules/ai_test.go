@@ -0,0 +1,56 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/ai"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AI Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "ai")
+		engine.DoString(`ai = require("ai")`)
+		world.Global().AddZone(world.Zone{ID: "luatest", Name: "Lua Test"})
+		world.Global().AddRoom(world.Room{ID: "lua-den", Zone: "luatest", Name: "A Den"})
+		world.Global().SetNPC(world.NPCDef{ID: "lua-owl", Zone: "luatest", Name: "an owl", Props: map[string]interface{}{"behavior": "lua-hunt"}})
+		Ω(ai.Global().Trees().Add("lua-hunt", ai.Spec{Selector: []ai.Spec{
+			{Condition: "lua-fed"},
+			{Action: "lua-hunt", Args: map[string]interface{}{"prey": "mouse"}},
+		}})).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("runs actions and conditions written in Lua", func() {
+		owl, err := mob.Global().Spawn("lua-owl", "lua-den")
+		Ω(err).ShouldNot(HaveOccurred())
+		engine.SetGlobal("id", owl.ID())
+
+		res, err := testReturn(engine, `
+			ai.condition("lua-fed", function(mob)
+				return ai.get(mob, "ate") ~= nil
+			end)
+			ai.action("lua-hunt", function(mob, args)
+				if args.prey ~= "mouse" then
+					return false
+				end
+				ai.set(mob, "ate", args.prey)
+				return "running"
+			end)
+			return {ai.tick(id), ai.tick(id), ai.get(id, "ate"), ai.tick("nobody")}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"running", "success", "mouse", "failure"}))
+	})
+})
@@ -0,0 +1,125 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Audit records admin actions, permission changes, economy transactions and
+// logins in the audit log, which is kept apart from the application log and
+// can't be changed without it being detected.
+//   record(category, action[, details]): number
+//     @param category: string = the kind of action, "admin", "permission",
+//       "economy" and "login" are used by the server
+//     @param action: string = what was done, like "ban" or "transfer"
+//     @param details: table = optional "actor" (who did it, defaults to the
+//       engine), "target" (who or what it was done to) and "data" (a table of
+//       any other details)
+//     adds a record to the audit log, returning its sequence number.
+//   query([filter]): table
+//     @param filter: table = optional "category", "actor", "action" and
+//       "target" to match, "since" and "until" (unix seconds) and "limit" to
+//       keep only the most recent records
+//     returns the matching records oldest first, each with seq, time (unix
+//       seconds), category, actor, action, target and data.
+//   verify(): boolean, string
+//     returns true if no records have been changed or removed, otherwise
+//       false and a description of where the log was changed.
+var Audit = lua.TableMap{
+	"record": func(engine *lua.Engine) int {
+		var details *lua.Value
+		if engine.StackSize() >= 3 {
+			details = engine.PopValue()
+		}
+		action := engine.PopString()
+		category := engine.PopString()
+
+		r := audit.Record{
+			Category: category,
+			Action:   action,
+			Actor:    nameForEngine(engine),
+		}
+		if details != nil && details.IsTable() {
+			if actor := details.Get("actor"); actor.IsString() {
+				r.Actor = actor.AsString()
+			}
+			if target := details.Get("target"); target.IsString() {
+				r.Target = target.AsString()
+			}
+			if data := details.Get("data"); data.IsTable() {
+				if m, ok := serializableValue(data.AsRaw()).(map[string]interface{}); ok {
+					r.Data = m
+				}
+			}
+		}
+
+		rec, err := audit.Global().Append(r)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(float64(rec.Seq))
+
+		return 1
+	},
+	"query": func(engine *lua.Engine) int {
+		var f audit.Filter
+		if engine.StackSize() >= 1 {
+			filter := engine.PopValue()
+			if filter.IsTable() {
+				f.Category = filter.Get("category").AsString()
+				f.Actor = filter.Get("actor").AsString()
+				f.Action = filter.Get("action").AsString()
+				f.Target = filter.Get("target").AsString()
+				if since := filter.Get("since"); since.IsNumber() {
+					f.Since = time.Unix(0, int64(since.AsNumber()*float64(time.Second)))
+				}
+				if until := filter.Get("until"); until.IsNumber() {
+					f.Until = time.Unix(0, int64(until.AsNumber()*float64(time.Second)))
+				}
+				f.Limit = int(filter.Get("limit").AsNumber())
+			}
+		}
+
+		records, err := audit.Global().Query(f)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		list := engine.NewTable()
+		for _, r := range records {
+			tbl := engine.NewTable()
+			tbl.Set("seq", float64(r.Seq))
+			tbl.Set("time", unixSeconds(r.Time))
+			tbl.Set("category", r.Category)
+			tbl.Set("actor", r.Actor)
+			tbl.Set("action", r.Action)
+			tbl.Set("target", r.Target)
+			tbl.Set("data", serializedToLua(engine, r.Data))
+			list.Append(tbl)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"verify": func(engine *lua.Engine) int {
+		if err := audit.Global().Verify(); err != nil {
+			engine.PushValue(false)
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+
+		engine.PushValue(true)
+
+		return 1
+	},
+}
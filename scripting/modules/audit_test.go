@@ -0,0 +1,75 @@
+package modules_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/keys"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		// the global audit log is opened once, at the first path set
+		dir, _ := ioutil.TempDir("", "audit")
+		viper.Set("audit.path", filepath.Join(dir, "audit.log"))
+
+		engine = lua.NewEngine()
+		engine.Meta[keys.EngineID] = "audit test"
+		scripting.OpenLibs(engine, "audit")
+		engine.DoString(`audit = require("audit")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("records and queries actions", func() {
+		res, err := testReturn(engine, `
+			audit.record("economy", "transfer", {
+				actor = "lua-alice",
+				target = "lua-bob",
+				data = {gold = 25},
+			})
+			local records = audit.query({actor = "lua-alice"})
+
+			return records[#records]
+		`)
+
+		Ω(err).Should(BeNil())
+		rec := res[0]
+		Ω(rec.Get("category").AsString()).Should(Equal("economy"))
+		Ω(rec.Get("target").AsString()).Should(Equal("lua-bob"))
+		Ω(rec.Get("data").Get("gold").AsNumber()).Should(Equal(float64(25)))
+	})
+
+	It("defaults the actor to the engine", func() {
+		res, err := testReturn(engine, `
+			audit.record("admin", "reload")
+			local records = audit.query({actor = "engine(audit test)", limit = 1})
+
+			return records[1].action
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsString()).Should(Equal("reload"))
+	})
+
+	It("verifies the log", func() {
+		res, err := testReturn(engine, `
+			audit.record("login", "login", {actor = "lua-carol"})
+
+			return audit.verify()
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+})
@@ -0,0 +1,125 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Board lets scripts put up boards, decide who uses them and post on them.
+//   define(board): boolean, string
+//     @param board: table = the board's id, name, room and max, as in board
+//       files
+//     adds the board, replacing any with its id, returning false and why if
+//       it doesn't make sense.
+//   access(name, fn)
+//     @param name: string = names the hook, so reloaded scripts replace it
+//     @param fn: function(request): boolean, string = given the board (its
+//       id), action ("read", "post" or "remove"), player (their id) and, for
+//       removals, author and subject, returns false and why to refuse,
+//       anything else allows it
+//     sets the access hook, replacing any with the name, a nil fn removes it.
+//   post(board, author, subject, body): boolean, string
+//     puts a post on the board as the author named, returning false and why
+//       if it can't be.
+//   posts(board): table
+//     returns the posts on the board, oldest first, each with author,
+//       subject, body and posted (unix seconds).
+//   remove(board, n): boolean, string
+//     takes post n, counting from 1, off the board.
+//   here(room): string
+//     returns the id of the board in the room, nil if there's none.
+var Board = lua.TableMap{
+	"define": func(engine *lua.Engine) int {
+		t := engine.PopTable()
+
+		return pushResult(engine, board.Global().Defs().Add(board.Def{
+			ID:   t.Get("id").AsString(),
+			Name: t.Get("name").AsString(),
+			Room: t.Get("room").AsString(),
+			Max:  int(t.Get("max").AsNumber()),
+		}))
+	},
+	"access": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		name := engine.PopString()
+		if !fn.IsFunction() {
+			board.Global().SetAccess(name, nil)
+
+			return 0
+		}
+		board.Global().SetAccess(name, func(req board.Request) error {
+			rt := engine.NewTable()
+			rt.Set("board", req.Board.ID)
+			rt.Set("action", req.Action)
+			rt.Set("player", req.Reader.ID())
+			if req.Action == board.RemoveAction {
+				rt.Set("author", req.Post.Author)
+				rt.Set("subject", req.Post.Subject)
+			}
+			ret, err := fn.Call(2, rt)
+			if err != nil {
+				log("board").WithError(err).WithField("engine", nameForEngine(engine)).Error("Board access hook failed.")
+
+				return nil
+			}
+			if len(ret) == 0 || !ret[0].IsBool() || ret[0].AsBool() {
+				return nil
+			}
+			if len(ret) > 1 && ret[1].IsString() {
+				return errors.New(ret[1].AsString())
+			}
+
+			return errors.New(board.DeniedMessage)
+		})
+
+		return 0
+	},
+	"post": func(engine *lua.Engine) int {
+		body := engine.PopString()
+		subject := engine.PopString()
+		author := engine.PopString()
+		_, err := board.Global().Add(engine.PopString(), board.Post{
+			Author:  author,
+			Subject: subject,
+			Body:    body,
+		})
+
+		return pushResult(engine, err)
+	},
+	"posts": func(engine *lua.Engine) int {
+		posts, _ := board.Global().Posts(engine.PopString())
+		t := engine.NewTable()
+		for _, p := range posts {
+			pt := engine.NewTable()
+			pt.Set("author", p.Author)
+			pt.Set("subject", p.Subject)
+			pt.Set("body", p.Body)
+			pt.Set("posted", p.Posted.Unix())
+			t.Append(pt)
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"remove": func(engine *lua.Engine) int {
+		n := engine.PopInt()
+		_, err := board.Global().Delete(engine.PopString(), n)
+
+		return pushResult(engine, err)
+	},
+	"here": func(engine *lua.Engine) int {
+		def, ok := board.Global().Defs().In(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(def.ID)
+
+		return 1
+	},
+}
@@ -0,0 +1,60 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// noticer is a brawler who reads boards
+type noticer struct {
+	brawler
+}
+
+func (n *noticer) Level() command.Level {
+	return command.Player
+}
+
+var _ = Describe("Board Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "board")
+		engine.DoString(`board = require("board")`)
+		board.Global().SetStore(board.NewMemoryStore())
+	})
+
+	AfterEach(func() {
+		board.Global().SetAccess("lua-members", nil)
+		engine.Close()
+	})
+
+	It("defines boards, posts on them and guards them", func() {
+		res, err := testReturn(engine, `
+			local _, invalid = board.define({id = "lua-board"})
+			board.define({id = "lua-board", name = "the arena board", room = "lua-arena", max = 5})
+			board.post("lua-board", "Herald", "Tournament", "Tomorrow at noon.")
+			board.post("lua-board", "Herald", "Cancelled", "")
+			board.remove("lua-board", 2)
+			board.access("lua-members", function(req)
+				if req.player ~= "champion" then
+					return false, "Only champions read this board."
+				end
+			end)
+			local posts = board.posts("lua-board")
+			return {invalid ~= nil, board.here("lua-arena"), #posts, posts[1].subject}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "lua-board", float64(1), "Tournament"}))
+
+		_, err = board.Global().Here(&noticer{brawler{id: "champion"}})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = board.Global().Here(&noticer{brawler{id: "squire"}})
+		Ω(err).Should(MatchError("Only champions read this board."))
+	})
+})
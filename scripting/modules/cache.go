@@ -0,0 +1,126 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/cache"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Cache is a key/value store shared by every engine for memoizing expensive
+// lookups like pathfinding results or computed stats. The cache holds a
+// limited number of entries (cache.max_entries), evicting the least recently
+// used when full, and entries expire after a time to live (cache.default_ttl
+// unless given). Values are copied in and out of the cache, so tables
+// retrieved are new tables and functions can't be stored.
+//   get(key): any
+//     @param key: string = the key to look up
+//     returns the cached value or nil if it's missing or expired.
+//   set(key, value[, ttl])
+//     @param key: string = the key to store the value under
+//     @param value: any = the value to cache, nil removes the key
+//     @param ttl: number = the number of seconds until the value expires,
+//       0 never expires, defaults to cache.default_ttl
+//     stores the value in the cache.
+//   has(key): boolean
+//     @param key: string = the key to look for
+//     returns whether a value is cached for the key.
+//   remember(key, ttl, fn): any
+//     @param key: string = the key to look up
+//     @param ttl: number = seconds to cache the result for if it's computed
+//     @param fn: function(): any = computes the value when it's not cached
+//     returns the cached value, calling fn and caching its result if there
+//     is no value cached.
+//   delete(key)
+//     @param key: string = the key to remove
+//     removes the key from the cache.
+//   clear()
+//     removes everything from the cache.
+//   stats(): table
+//     returns a table of hits, misses, evictions and size.
+var Cache = lua.TableMap{
+	"get": func(engine *lua.Engine) int {
+		val, ok := cache.Global().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+
+		engine.PushValue(serializedToLua(engine, val))
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		ttl := cache.Global().DefaultTTL
+		if engine.StackSize() >= 3 {
+			ttl = secondsToDuration(engine.PopFloat())
+		}
+		val := engine.PopValue()
+		key := engine.PopString()
+
+		if val.IsNil() {
+			cache.Global().Delete(key)
+
+			return 0
+		}
+
+		cache.Global().SetWithTTL(key, val.AsRaw(), ttl)
+
+		return 0
+	},
+	"has": func(key string) bool {
+		_, ok := cache.Global().Get(key)
+
+		return ok
+	},
+	"remember": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		ttl := secondsToDuration(engine.PopFloat())
+		key := engine.PopString()
+
+		if val, ok := cache.Global().Get(key); ok {
+			engine.PushValue(serializedToLua(engine, val))
+
+			return 1
+		}
+
+		if !fn.IsFunction() {
+			engine.ArgumentError(3, "expected a function")
+
+			return 0
+		}
+
+		ret, err := fn.Call(1)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		if !ret[0].IsNil() {
+			cache.Global().SetWithTTL(key, ret[0].AsRaw(), ttl)
+		}
+		engine.PushValue(ret[0])
+
+		return 1
+	},
+	"delete": func(key string) {
+		cache.Global().Delete(key)
+	},
+	"clear": func() {
+		cache.Global().Clear()
+	},
+	"stats": func(engine *lua.Engine) int {
+		stats := cache.Global().Stats()
+		tbl := engine.NewTable()
+		tbl.Set("hits", stats.Hits)
+		tbl.Set("misses", stats.Misses)
+		tbl.Set("evictions", stats.Evictions)
+		tbl.Set("size", stats.Size)
+
+		engine.PushValue(tbl)
+
+		return 1
+	},
+}
@@ -0,0 +1,67 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "cache")
+		engine.DoString(`cache = require("cache")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("stores copies of tables", func() {
+		res, err := testReturn(engine, `
+			local stats = {str = 10, tags = {"strong"}}
+			cache.set("test:stats", stats, 60)
+			stats.str = 1
+			local cached = cache.get("test:stats")
+
+			return {cached.str, cached.tags[1], cache.has("test:stats")}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(10), "strong", true}))
+	})
+
+	It("removes values set to nil", func() {
+		res, err := testReturn(engine, `
+			cache.set("test:nil", 1)
+			cache.set("test:nil", nil)
+
+			return cache.get("test:nil") == nil
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+
+	It("remembers computed values", func() {
+		res, err := testReturn(engine, `
+			local calls = 0
+			local compute = function()
+				calls = calls + 1
+
+				return "path"
+			end
+			local a = cache.remember("test:remember", 60, compute)
+			local b = cache.remember("test:remember", 60, compute)
+
+			return {a, b, calls}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"path", "path", float64(1)}))
+	})
+})
@@ -0,0 +1,178 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Channels lets plugins create channels of their own, decide who talks
+// together on them and speak on them for players or the game.
+//   create(channel): boolean, string
+//     @param channel: table = the channel's id, name, aliases, format, self,
+//       history, quiet, flag and scope, as in channel files
+//     adds the channel and the command it's spoken on, replacing any with
+//       its id, returning false and why if it doesn't make sense.
+//   scope(name, fn)
+//     @param name: string = the name channels give as their scope
+//     @param fn: function(player): string = given the id of a player,
+//       returns the group they talk in, like their clan, or nil if they
+//       can't use the channel
+//     sets the scope hook, replacing any with the name.
+//   send(player, channel, message): boolean, string
+//     says the message as the player, returning false and why if they can't.
+//   announce(channel, speaker, message, scope): boolean, string
+//     says the message as the speaker named to everyone listening in the
+//       scope, which channels without one ignore and may be left out.
+//   history(channel, scope): table
+//     returns what was said in the scope, oldest first, each with time (unix
+//       seconds), speaker and message.
+//   join(player, channel): boolean, string
+//   leave(player, channel): boolean, string
+//     starts or stops the player listening to the channel.
+//   mute(player, channel, minutes): boolean, string
+//     stops the player speaking on the channel for the minutes given, or
+//       until they're unmuted if left out.
+//   unmute(player, channel): boolean, string
+//   listening(player, channel): boolean
+//     returns true if the player hears the channel.
+var Channels = lua.TableMap{
+	"create": func(engine *lua.Engine) int {
+		return pushResult(engine, channels.Global().Create(channelFromTable(engine.PopTable())))
+	},
+	"scope": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		channels.Global().Scope(name, func(m channels.Member) string {
+			ret, err := fn.Call(1, m.ID())
+			if err != nil {
+				log("channels").WithError(err).WithField("engine", nameForEngine(engine)).Error("Channel scope hook failed.")
+
+				return ""
+			}
+			if len(ret) == 0 || !ret[0].IsString() {
+				return ""
+			}
+
+			return ret[0].AsString()
+		})
+
+		return 0
+	},
+	"send": func(engine *lua.Engine) int {
+		message := engine.PopString()
+		id := engine.PopString()
+		m := member(engine.PopString())
+		if m == nil {
+			engine.PushValue(false)
+			engine.PushValue(channels.DeniedMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, channels.Global().Send(m, id, message))
+	},
+	"announce": func(engine *lua.Engine) int {
+		var scope string
+		if engine.StackSize() >= 4 {
+			scope = engine.PopString()
+		}
+		message := engine.PopString()
+		speaker := engine.PopString()
+		id := engine.PopString()
+
+		return pushResult(engine, channels.Global().Announce(id, speaker, message, scope))
+	},
+	"history": func(engine *lua.Engine) int {
+		var scope string
+		if engine.StackSize() >= 2 {
+			scope = engine.PopString()
+		}
+		id := engine.PopString()
+		t := engine.NewTable()
+		for _, l := range channels.Global().Lines(id, scope) {
+			lt := engine.NewTable()
+			lt.Set("time", l.Time.Unix())
+			lt.Set("speaker", l.Speaker)
+			lt.Set("message", l.Message)
+			t.Append(lt)
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"join": func(engine *lua.Engine) int {
+		return membership(engine, channels.Global().Join)
+	},
+	"leave": func(engine *lua.Engine) int {
+		return membership(engine, channels.Global().Leave)
+	},
+	"mute": func(engine *lua.Engine) int {
+		var until time.Time
+		if engine.StackSize() >= 3 {
+			if minutes := engine.PopInt(); minutes > 0 {
+				until = time.Now().Add(time.Duration(minutes) * time.Minute)
+			}
+		}
+
+		return membership(engine, func(m channels.Member, id string) error {
+			return channels.Global().Mute(m, id, until)
+		})
+	},
+	"unmute": func(engine *lua.Engine) int {
+		return membership(engine, channels.Global().Unmute)
+	},
+	"listening": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		m := member(engine.PopString())
+		engine.PushValue(m != nil && channels.Global().Listening(m, id))
+
+		return 1
+	},
+}
+
+// member returns the combatant with the id if they use channels
+func member(id string) channels.Member {
+	m, _ := combat.Global().Lookup(id).(channels.Member)
+
+	return m
+}
+
+// membership changes the standing of the player on the channel given to the
+// function with fn
+func membership(engine *lua.Engine, fn func(channels.Member, string) error) int {
+	id := engine.PopString()
+	m := member(engine.PopString())
+	if m == nil {
+		engine.PushValue(false)
+		engine.PushValue(channels.DeniedMessage)
+
+		return 2
+	}
+
+	return pushResult(engine, fn(m, id))
+}
+
+func channelFromTable(t *lua.Value) channels.Def {
+	def := channels.Def{
+		ID:      t.Get("id").AsString(),
+		Name:    t.Get("name").AsString(),
+		Format:  t.Get("format").AsString(),
+		Self:    t.Get("self").AsString(),
+		History: int(t.Get("history").AsNumber()),
+		Quiet:   t.Get("quiet").AsBool(),
+		Flag:    t.Get("flag").AsString(),
+		Scope:   t.Get("scope").AsString(),
+	}
+	if aliases := t.Get("aliases"); aliases.IsTable() {
+		aliases.ForEach(func(_, alias *lua.Value) {
+			def.Aliases = append(def.Aliases, alias.AsString())
+		})
+	}
+
+	return def
+}
@@ -0,0 +1,103 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// chatter is a brawler who talks on channels
+type chatter struct {
+	brawler
+	members map[string]channels.Membership
+	sent    []string
+}
+
+func (c *chatter) Send(text string) error {
+	c.sent = append(c.sent, text)
+
+	return nil
+}
+
+func (c *chatter) Flag(name string) bool {
+	return false
+}
+
+func (c *chatter) Membership(channel string) (channels.Membership, bool) {
+	ms, ok := c.members[channel]
+
+	return ms, ok
+}
+
+func (c *chatter) SetMembership(channel string, ms channels.Membership) {
+	c.members[channel] = ms
+}
+
+var _ = Describe("Channels Lua Module", func() {
+	var (
+		engine     *lua.Engine
+		bard, monk *chatter
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "channels")
+		engine.DoString(`channels = require("channels")`)
+		bard = &chatter{brawler: brawler{id: "bard"}, members: make(map[string]channels.Membership)}
+		monk = &chatter{brawler: brawler{id: "monk"}, members: make(map[string]channels.Membership)}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			switch id {
+			case "bard":
+				return bard
+			case "monk":
+				return monk
+			}
+
+			return nil
+		})
+		channels.Global().SetMembers(func() []channels.Member {
+			return []channels.Member{bard, monk}
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		channels.Global().SetMembers(nil)
+		engine.Close()
+	})
+
+	It("creates channels and speaks on them", func() {
+		res, err := testReturn(engine, `
+			local _, invalid = channels.create({id = ""})
+			channels.create({
+				id = "lua-guild",
+				format = "<{speaker}> {message}",
+				scope = "lua-guild",
+				quiet = true,
+			})
+			channels.scope("lua-guild", function(player)
+				if player == "bard" or player == "monk" then
+					return "lutes"
+				end
+			end)
+			local _, deaf = channels.send("bard", "lua-guild", "hello")
+			channels.join("bard", "lua-guild")
+			channels.join("monk", "lua-guild")
+			local sent = channels.send("bard", "lua-guild", "hello")
+			channels.announce("lua-guild", "The Hall", "welcome", "lutes")
+			channels.mute("monk", "lua-guild", 5)
+			local _, muted = channels.send("monk", "lua-guild", "hi")
+			local history = channels.history("lua-guild", "lutes")
+			return {invalid ~= nil, deaf, sent, muted, #history, history[1].speaker, channels.listening("monk", "lua-guild")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{
+			true, channels.NotListeningMessage, true, channels.MutedMessage, float64(2), "bard", true,
+		}))
+		Ω(monk.sent).Should(Equal([]string{"<bard> hello", "<The Hall> welcome"}))
+	})
+})
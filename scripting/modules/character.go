@@ -0,0 +1,170 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Character lets plugins define what characters can be made of. Races and
+// classes are offered to new players in the order they're added, adding one
+// with a name already used replaces it.
+//   add_race(definition)
+//     @param definition: table = describes the race, with the fields
+//       name: string = the name of the race
+//       description: string = optional text shown when choosing a race
+//       stats: table = optional bonuses added to rolled stats, like
+//         {str = 2, int = -1}
+//     adds the race.
+//   add_class(definition)
+//     @param definition: table = describes the class, with the fields of a
+//       race and races: table = optional list of the races that can be the
+//       class, any race can if it's left out
+//     adds the class.
+//   races(): table
+//     returns a list of the races, as tables like their definitions.
+//   classes([race]): table
+//     @param race: string = optional race the classes must allow
+//     returns a list of the classes, as tables like their definitions.
+//   reserve(names...)
+//     @param names: string = names characters can't have
+//     reserves the names.
+//   ban(words...)
+//     @param words: string = words that can't be part of a character's name
+//     bans the words.
+//   validate_name(name): string
+//     @param name: string = a name for a new character
+//     returns why the name can't be used, or nil if it can.
+//   load(name): table
+//     @param name: string = the name of a character
+//     returns the saved character, with its name, account, race, class,
+//     stats and created time, or nil if there's no such character.
+var Character = lua.TableMap{
+	"add_race": func(engine *lua.Engine) int {
+		def := engine.PopTable()
+		if !def.IsTable() || def.Get("name").AsString() == "" {
+			engine.ArgumentError(1, "expected a table with the name of the race")
+
+			return 0
+		}
+		character.Global().AddRace(character.Race{
+			Name:        def.Get("name").AsString(),
+			Description: def.Get("description").AsString(),
+			Stats:       luaStats(def.Get("stats")),
+		})
+
+		return 0
+	},
+	"add_class": func(engine *lua.Engine) int {
+		def := engine.PopTable()
+		if !def.IsTable() || def.Get("name").AsString() == "" {
+			engine.ArgumentError(1, "expected a table with the name of the class")
+
+			return 0
+		}
+		class := character.Class{
+			Name:        def.Get("name").AsString(),
+			Description: def.Get("description").AsString(),
+			Stats:       luaStats(def.Get("stats")),
+		}
+		if races := def.Get("races"); races.IsTable() {
+			races.ForEach(func(_, race *lua.Value) {
+				class.Races = append(class.Races, race.AsString())
+			})
+		}
+		character.Global().AddClass(class)
+
+		return 0
+	},
+	"races": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, race := range character.Global().Races() {
+			tbl := engine.NewTable()
+			tbl.Set("name", race.Name)
+			tbl.Set("description", race.Description)
+			tbl.Set("stats", statsTable(engine, race.Stats))
+			list.Append(tbl)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"classes": func(engine *lua.Engine) int {
+		race := ""
+		if engine.StackSize() > 0 {
+			race = engine.PopString()
+		}
+		list := engine.NewTable()
+		for _, class := range character.Global().Classes(race) {
+			races := engine.NewTable()
+			for _, r := range class.Races {
+				races.Append(r)
+			}
+			tbl := engine.NewTable()
+			tbl.Set("name", class.Name)
+			tbl.Set("description", class.Description)
+			tbl.Set("stats", statsTable(engine, class.Stats))
+			tbl.Set("races", races)
+			list.Append(tbl)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"reserve": func(names ...string) {
+		character.Global().Reserve(names...)
+	},
+	"ban": func(words ...string) {
+		character.Global().Ban(words...)
+	},
+	"validate_name": func(engine *lua.Engine) int {
+		if err := character.Global().ValidateName(engine.PopString()); err != nil {
+			engine.PushValue(err.Error())
+
+			return 1
+		}
+		engine.PushValue(engine.Nil())
+
+		return 1
+	},
+	"load": func(engine *lua.Engine) int {
+		c, err := character.GlobalStore().Load(engine.PopString())
+		if err != nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		tbl := engine.NewTable()
+		tbl.Set("name", c.Name)
+		tbl.Set("account", c.Account)
+		tbl.Set("race", c.Race)
+		tbl.Set("class", c.Class)
+		tbl.Set("stats", statsTable(engine, c.Stats))
+		tbl.Set("created", c.Created.Unix())
+		engine.PushValue(tbl)
+
+		return 1
+	},
+}
+
+// luaStats reads a table of stat bonuses
+func luaStats(v *lua.Value) map[string]int {
+	stats := make(map[string]int)
+	if v.IsTable() {
+		v.ForEach(func(k, bonus *lua.Value) {
+			stats[k.AsString()] = int(bonus.AsNumber())
+		})
+	}
+
+	return stats
+}
+
+func statsTable(engine *lua.Engine, stats map[string]int) *lua.Value {
+	tbl := engine.NewTable()
+	for k, v := range stats {
+		tbl.Set(k, v)
+	}
+
+	return tbl
+}
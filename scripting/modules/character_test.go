@@ -0,0 +1,66 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Character Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "character")
+		engine.DoString(`character = require("character")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("adds races and classes", func() {
+		err := engine.DoString(`
+			character.add_race{name = "Gnome", description = "Small and clever.", stats = {int = 2, str = -1}}
+			character.add_class{name = "Tinker", races = {"gnome"}, stats = {int = 1}}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		race, ok := character.Global().Race("gnome")
+		Ω(ok).Should(BeTrue())
+		Ω(race.Stats).Should(Equal(map[string]int{"int": 2, "str": -1}))
+		class, ok := character.Global().Class("tinker")
+		Ω(ok).Should(BeTrue())
+		Ω(class.Allows("Gnome")).Should(BeTrue())
+		Ω(class.Allows("Elf")).Should(BeFalse())
+
+		res, err := testReturn(engine, `
+			local races = character.races()
+			local classes = character.classes("gnome")
+			return {races[#races].name, races[#races].stats.int, classes[#classes].races[1]}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"Gnome", float64(2), "gnome"}))
+	})
+
+	It("validates names", func() {
+		res, err := testReturn(engine, `
+			character.reserve("Gandalf")
+			character.ban("heck")
+			return {character.validate_name("gandalf"), character.validate_name("Heckler"), character.validate_name("Frodo") == nil}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"that name is reserved", "that name isn't allowed", true}))
+	})
+
+	It("returns nil for characters that don't exist", func() {
+		res, err := testReturn(engine, `return character.load("../nobody") == nil`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+})
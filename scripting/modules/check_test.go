@@ -0,0 +1,71 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "check")
+		engine.DoString(`check = require("check")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("failed checks",
+		func(script, message string) {
+			err := engine.DoString(script)
+
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring(message))
+		},
+		Entry("arg", `check.arg(1, 10, "string")`, "bad argument #1 (expected string, got number)"),
+		Entry("named arg", `check.arg(2, 42, "?table|string", "target")`, "bad argument #2 'target' (expected table, string or nil, got number)"),
+		Entry("integer", `check.arg(1, 1.5, "integer")`, "expected integer, got number"),
+		Entry("fields", `check.fields({name = "orc"}, {name = "string", hp = "number"}, 1)`, `bad argument #1 (field "hp" expected number, got nil)`),
+		Entry("nested fields", `check.fields({stats = {str = "ten"}}, {stats = {str = "number"}})`, `invalid table, field "stats.str" expected number, got string`),
+		Entry("one_of", `check.one_of(1, "up", {"north", "south"}, "dir")`, `bad argument #1 'dir' (expected one of "north", "south", got "up")`),
+		Entry("range", `check.range(1, 12, 1, 10)`, "expected a number from 1 to 10, got 12"),
+		Entry("that", `check.that(false, "%d is too many", 3)`, "3 is too many"),
+		Entry("unknown type", `check.arg(1, 1, "strng")`, `check: unknown type "strng"`),
+	)
+
+	It("returns checked values", func() {
+		res, err := testReturn(engine, `
+			return {
+				check.arg(1, "orc", "string"),
+				check.arg(1, 3, "integer|string"),
+				check.fields({hp = 5}, {hp = "number", name = "?string"}).hp,
+				check.one_of(1, "north", {"north", "south"}),
+				check.range(1, 5, 1),
+				check.is({}, "list"),
+			}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"orc", float64(3), float64(5), "north", float64(5), true}))
+	})
+
+	It("blames the caller of the checked function", func() {
+		err := engine.DoString(`
+			local function greet(name)
+				check.arg(1, name, "string")
+			end
+
+			greet(42) -- caller
+		`)
+
+		Ω(err).ShouldNot(BeNil())
+		Ω(err.Error()).Should(ContainSubstring(":6: bad argument #1"))
+	})
+})
@@ -0,0 +1,101 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Clan lets scripts see the clans players are in and build clan features
+// the game doesn't have, keeping them to ranks with permissions of their
+// own.
+//   of(name): string
+//     returns the id of the player's clan, nil if they aren't in one.
+//   info(id): table
+//     returns the clan's id, name, treasury, members (names mapped to ranks),
+//       ranks (highest first) and rooms, nil if there's no such clan.
+//   can(name, permission): boolean
+//     returns whether the player's rank in their clan grants the permission.
+//   permission(name): boolean, string
+//     lets ranks grant the permission, returning false and why if it can't
+//       be one.
+//   owner(room): string
+//     returns the id of the clan owning the room, nil if none does.
+//   get(id, key): string
+//     returns the data kept under the key on the clan, nil if there is none.
+//   set(id, key, value): boolean, string
+//     keeps the value under the key on the clan, an empty value removes it,
+//       returning false and why if there's no such clan.
+var Clan = lua.TableMap{
+	"of": func(engine *lua.Engine) int {
+		c, ok := clan.Global().Of(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(c.ID)
+
+		return 1
+	},
+	"info": func(engine *lua.Engine) int {
+		c, ok := clan.Global().Clan(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		t := engine.NewTable()
+		t.Set("id", c.ID)
+		t.Set("name", c.Name)
+		t.Set("treasury", c.Treasury)
+		t.Set("members", engine.TableFromMap(c.Members))
+		ranks := make([]string, 0, len(c.Ranks))
+		for _, r := range c.Ranks {
+			ranks = append(ranks, r.Name)
+		}
+		t.Set("ranks", engine.TableFromSlice(ranks))
+		t.Set("rooms", engine.TableFromSlice(c.Rooms))
+		engine.PushValue(t)
+
+		return 1
+	},
+	"can": func(name, permission string) bool {
+		return clan.Global().Can(name, permission)
+	},
+	"permission": func(engine *lua.Engine) int {
+		return pushResult(engine, clan.Global().AddPermission(engine.PopString()))
+	},
+	"owner": func(engine *lua.Engine) int {
+		c, ok := clan.Global().Owner(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(c.ID)
+
+		return 1
+	},
+	"get": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		c, ok := clan.Global().Clan(engine.PopString())
+		value, found := c.Data[key]
+		if !ok || !found {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(value)
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		value := engine.PopString()
+		key := engine.PopString()
+		id := engine.PopString()
+
+		return pushResult(engine, clan.Global().SetData(id, key, value))
+	},
+}
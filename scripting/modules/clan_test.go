@@ -0,0 +1,69 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// clanMember founds clans for scripts to look at
+type clanMember struct {
+	name string
+}
+
+func (m clanMember) Name() string {
+	return m.name
+}
+
+func (m clanMember) Location() string {
+	return ""
+}
+
+func (m clanMember) Send(text string) error {
+	return nil
+}
+
+func (m clanMember) Stat(name string) int {
+	return 0
+}
+
+func (m clanMember) AddStat(name string, delta int) int {
+	return delta
+}
+
+var _ = Describe("Clan Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "clan")
+		engine.DoString(`clan = require("clan")`)
+		_, err := clan.Global().Create(clanMember{"LuaFounder"}, "Lua Lodge")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		clan.Global().Leave("LuaFounder")
+		engine.Close()
+	})
+
+	It("looks up clans and keeps data and permissions for scripts", func() {
+		res, err := testReturn(engine, `
+			local id = clan.of("luafounder")
+			local info = clan.info(id)
+			local added = clan.permission("lua-feast")
+			local bad = clan.permission("two words")
+			local set = clan.set(id, "banner", "oak")
+			local missing = clan.set("nobody", "banner", "oak")
+			return {id, info.name, info.members.LuaFounder, clan.of("LuaStranger") == nil,
+				added, bad, set, missing, clan.get(id, "banner"), clan.can("LuaFounder", "lua-feast")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{
+			"lua-lodge", "Lua Lodge", "leader", true, true, false, true, false, "oak", true,
+		}))
+	})
+})
@@ -0,0 +1,284 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/editor"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Cmd lets plugins add commands players can type. A command can't use a name
+// or alias already taken by another, including the game's own commands, and
+// its help is shown by the help command. Server scripts run in several
+// engines, so a command registered again from another engine replaces the
+// earlier registration rather than conflicting with it.
+//   register(definition)
+//     @param definition: table = describes the command, with the fields
+//       name: string = what players type
+//       aliases: table = optional list of other names, like {"c"}
+//       abbrev: number = optional fewest letters of the name that can be
+//         typed for the command
+//       args: table = optional list of arguments, each a table with a name,
+//         a kind of "word", "number" or "text" and optional = true if it can
+//         be left out
+//       level: string = optional permission level needed, "player" (the
+//         default), "builder", "staff" or "admin"
+//       capability: string = optional capability callers must also have,
+//         like "force", see the perm module
+//       lag: number = optional pulses the player waits after the command
+//         before their next command runs
+//       help: string = optional description shown by the help command
+//       handler: function(ctx) = optional function running the command, ctx
+//         has the caller (their id), command, input, rest, words and args
+//         typed along with send(text) to reply, wait(pulses) to change the
+//         lag and edit(text, on_save[, on_cancel]) to have the caller write
+//         in the line editor, starting from text, on_save is called with the
+//         text they save. A string returned is sent to the caller. Commands
+//         without a handler can be handled by listening for the
+//         "command:<name>" event.
+//     @errors raises an error if the name or an alias is already used
+//     adds the command.
+//   unregister(name): boolean
+//     @param name: string = the name of the command
+//     removes the command, returning false if there wasn't one.
+//   exists(name): boolean
+//     @param name: string = a command name or alias
+//     returns whether a command uses the name.
+//   list(): table
+//     returns the names of every command, sorted.
+//   usage(name): string
+//     @param name: string = a command name or alias
+//     returns how the command is typed, like "give <item> [count]", or nil if
+//     there's no such command.
+//   help(name): string
+//     @param name: string = a command name or alias
+//     returns the help of the command, or nil if there's no such command.
+var Cmd = lua.TableMap{
+	"register": func(engine *lua.Engine) int {
+		def := engine.PopTable()
+		c, ok := luaCommand(engine, def)
+		if !ok {
+			return 0
+		}
+
+		if err := registerLuaCommand(engine, c); err != nil {
+			engine.RaiseError(err.Error())
+		}
+
+		return 0
+	},
+	"unregister": func(name string) bool {
+		luaCommandsMutex.Lock()
+		delete(luaCommandEngines, strings.ToLower(name))
+		luaCommandsMutex.Unlock()
+
+		return command.Global().Unregister(name)
+	},
+	"exists": func(name string) bool {
+		_, ok := command.Global().Get(name)
+
+		return ok
+	},
+	"list": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, c := range command.Global().Commands(command.Admin) {
+			list.Append(c.Name)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"usage": func(engine *lua.Engine) int {
+		c, ok := command.Global().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(c.Usage())
+
+		return 1
+	},
+	"help": func(engine *lua.Engine) int {
+		c, ok := command.Global().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(c.Help)
+
+		return 1
+	},
+}
+
+// the engine that registered each command from Lua, by lower case name
+var (
+	luaCommandEngines = make(map[string]*lua.Engine)
+	luaCommandsMutex  = new(sync.Mutex)
+)
+
+// luaSource is the source of commands registered by scripts
+const luaSource = "lua"
+
+var luaArgKinds = map[string]command.ArgKind{
+	"":       command.Word,
+	"word":   command.Word,
+	"number": command.Number,
+	"text":   command.Text,
+}
+
+// luaCommand builds a command from its definition, raising an argument error
+// if it's invalid
+func luaCommand(engine *lua.Engine, def *lua.Value) (*command.Command, bool) {
+	if !def.IsTable() {
+		engine.ArgumentError(1, "expected a table defining the command")
+
+		return nil, false
+	}
+
+	c := &command.Command{
+		Name:       def.Get("name").AsString(),
+		MinAbbrev:  int(def.Get("abbrev").AsNumber()),
+		Lag:        int(def.Get("lag").AsNumber()),
+		Help:       def.Get("help").AsString(),
+		Capability: def.Get("capability").AsString(),
+		Source:     luaSource,
+	}
+	if c.Name == "" {
+		engine.ArgumentError(1, "commands must have a name")
+
+		return nil, false
+	}
+
+	if aliases := def.Get("aliases"); aliases.IsTable() {
+		aliases.ForEach(func(_, alias *lua.Value) {
+			c.Aliases = append(c.Aliases, alias.AsString())
+		})
+	}
+
+	level, err := command.ParseLevel(def.Get("level").AsString())
+	if err != nil {
+		engine.ArgumentError(1, "level must be player, builder, staff or admin")
+
+		return nil, false
+	}
+	c.Level = level
+
+	valid := true
+	if args := def.Get("args"); args.IsTable() {
+		args.ForEach(func(_, arg *lua.Value) {
+			kind, ok := luaArgKinds[strings.ToLower(arg.Get("kind").AsString())]
+			if !ok || arg.Get("name").AsString() == "" {
+				valid = false
+
+				return
+			}
+			c.Args = append(c.Args, command.Arg{
+				Name:     arg.Get("name").AsString(),
+				Kind:     kind,
+				Optional: arg.Get("optional").AsBool(),
+			})
+		})
+	}
+	if !valid {
+		engine.ArgumentError(1, "arguments need a name and a kind of word, number or text")
+
+		return nil, false
+	}
+
+	if handler := def.Get("handler"); handler.IsFunction() {
+		c.Handler = luaCommandHandler(engine, handler)
+	}
+
+	return c, true
+}
+
+// registerLuaCommand adds the command, replacing one registered by a script in
+// another engine
+func registerLuaCommand(engine *lua.Engine, c *command.Command) error {
+	luaCommandsMutex.Lock()
+	defer luaCommandsMutex.Unlock()
+
+	registry := command.Global()
+	key := strings.ToLower(c.Name)
+	if existing, ok := registry.Get(c.Name); ok && existing.Source == luaSource && strings.EqualFold(existing.Name, c.Name) {
+		if owner := luaCommandEngines[key]; owner != nil && owner != engine {
+			registry.Unregister(existing.Name)
+		}
+	}
+
+	if err := registry.Register(c); err != nil {
+		return err
+	}
+	luaCommandEngines[key] = engine
+
+	return nil
+}
+
+// luaCommandHandler runs the function on the engine that registered it
+func luaCommandHandler(engine *lua.Engine, fn *lua.Value) command.Handler {
+	return func(ctx *command.Context) error {
+		words := engine.NewTable()
+		for _, word := range ctx.Input.Words {
+			words.Append(word)
+		}
+		args := engine.NewTable()
+		for name, value := range ctx.Args {
+			args.Set(name, value)
+		}
+
+		tbl := engine.NewTable()
+		tbl.Set("caller", ctx.Caller.ID())
+		tbl.Set("command", ctx.Command.Name)
+		tbl.Set("input", ctx.Input.Line)
+		tbl.Set("rest", ctx.Input.Rest)
+		tbl.Set("words", words)
+		tbl.Set("args", args)
+		tbl.Set("send", func(text string) {
+			ctx.Send(text)
+		})
+		tbl.Set("wait", func(pulses int) {
+			ctx.Lag = pulses
+		})
+		tbl.Set("edit", func(eng *lua.Engine) int {
+			var onCancel *lua.Value
+			if eng.StackSize() > 2 {
+				onCancel = eng.PopValue()
+			}
+			onSave := eng.PopValue()
+			text := eng.PopString()
+			if !onSave.IsFunction() {
+				eng.ArgumentError(2, "expected a function to call with the saved text")
+
+				return 0
+			}
+
+			e := editor.New(text, func(saved string) {
+				onSave.Call(0, saved)
+			})
+			if onCancel != nil && onCancel.IsFunction() {
+				e.OnCancel(func() {
+					onCancel.Call(0)
+				})
+			}
+			editor.Open(ctx.Dispatcher, ctx.Caller, e)
+
+			return 0
+		})
+
+		ret, err := fn.Call(1, tbl)
+		if err != nil {
+			return err
+		}
+		if len(ret) > 0 && ret[0].IsString() {
+			return ctx.Send(ret[0].AsString())
+		}
+
+		return nil
+	}
+}
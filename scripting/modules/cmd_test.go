@@ -0,0 +1,134 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// cmdCaller records the replies to the commands it runs
+type cmdCaller struct {
+	sent []string
+}
+
+func (c *cmdCaller) ID() string {
+	return "cmd-tester"
+}
+
+func (c *cmdCaller) Level() command.Level {
+	return command.Player
+}
+
+func (c *cmdCaller) Send(text string) error {
+	c.sent = append(c.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Cmd Lua Module", func() {
+	var (
+		engine     *lua.Engine
+		caller     *cmdCaller
+		dispatcher *command.Dispatcher
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "cmd")
+		engine.DoString(`cmd = require("cmd")`)
+		caller = new(cmdCaller)
+		dispatcher = command.NewDispatcher(command.Global(), nil)
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{"cast", "wave"} {
+			command.Global().Unregister(name)
+		}
+		engine.Close()
+	})
+
+	It("registers commands run by Lua handlers", func() {
+		err := engine.DoString(`
+			cmd.register{
+				name = "cast",
+				aliases = {"c"},
+				args = {{name = "spell"}, {name = "target", kind = "text", optional = true}},
+				help = "Casts a spell.",
+				handler = function(ctx)
+					ctx.send("You begin chanting.")
+
+					return "You cast " .. ctx.args.spell .. " at " .. (ctx.args.target or "nothing") .. "."
+				end,
+			}
+		`)
+		Ω(err).Should(BeNil())
+
+		Ω(dispatcher.Dispatch(caller, "c fireball the big rat")).Should(Succeed())
+		Ω(caller.sent).Should(Equal([]string{"You begin chanting.", "You cast fireball at the big rat."}))
+
+		res, err := testReturn(engine, `return {cmd.exists("c"), cmd.usage("cast"), cmd.help("cast")}`)
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "cast <spell> [target...]", "Casts a spell."}))
+	})
+
+	It("shows the help of Lua commands", func() {
+		engine.DoString(`cmd.register{name = "wave", help = "Waves at everyone."}`)
+
+		dispatcher.Dispatch(caller, "help wave")
+		dispatcher.Dispatch(caller, "help")
+
+		Ω(caller.sent[0]).Should(Equal("Usage: wave\n\nWaves at everyone."))
+		Ω(caller.sent[1]).Should(ContainSubstring("wave"))
+	})
+
+	It("refuses names already in use", func() {
+		err := engine.DoString(`cmd.register{name = "assist", aliases = {"help"}}`)
+
+		Ω(err).ShouldNot(BeNil())
+		Ω(err.Error()).Should(ContainSubstring(`"help" is already used by the "help" command from game`))
+
+		engine.DoString(`cmd.register{name = "wave"}`)
+		err = engine.DoString(`cmd.register{name = "wave"}`)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("replaces commands registered by another engine", func() {
+		other := lua.NewEngine()
+		defer other.Close()
+		scripting.OpenLibs(other, "cmd")
+
+		Ω(engine.DoString(`cmd.register{name = "wave", handler = function() return "one" end}`)).Should(Succeed())
+		Ω(other.DoString(`require("cmd").register{name = "wave", handler = function() return "two" end}`)).Should(Succeed())
+
+		dispatcher.Dispatch(caller, "wave")
+		Ω(caller.sent).Should(Equal([]string{"two"}))
+	})
+
+	It("opens the line editor", func() {
+		err := engine.DoString(`
+			cmd.register{
+				name = "wave",
+				handler = function(ctx)
+					ctx.edit("first", function(text) saved = text end)
+				end,
+			}
+		`)
+		Ω(err).Should(BeNil())
+
+		dispatcher.Dispatch(caller, "wave")
+		dispatcher.Dispatch(caller, "second")
+		dispatcher.Dispatch(caller, ".s")
+
+		Ω(engine.GetGlobal("saved").AsString()).Should(Equal("first\nsecond"))
+		Ω(dispatcher.Mode(caller.ID())).Should(BeNil())
+	})
+
+	It("checks the definition", func() {
+		Ω(engine.DoString(`cmd.register{help = "no name"}`)).ShouldNot(Succeed())
+		Ω(engine.DoString(`cmd.register{name = "wave", level = "god"}`)).ShouldNot(Succeed())
+		Ω(engine.DoString(`cmd.register{name = "wave", args = {{name = "x", kind = "color"}}}`)).ShouldNot(Succeed())
+	})
+})
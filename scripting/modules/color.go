@@ -0,0 +1,111 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/ansi"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/bbuck/dragon-mud/text/strutil"
+)
+
+// map alignment names used in scripts to their alignment values, anything
+// unknown is treated as left aligned
+var alignments = map[string]strutil.Alignment{
+	"left":   strutil.AlignLeft,
+	"right":  strutil.AlignRight,
+	"center": strutil.AlignCenter,
+}
+
+// Color provides access to processing inline color codes from scripts. Color
+// codes are the same used throughout the game, such as "[R]danger[x]" where
+// [R] starts bright red and [x] resets.
+//   colorize(text[, session]): string
+//     @param text: string = the text containing color codes to process
+//     @param session: string = optional id of the session the text is for
+//     converts color codes into ANSI escape sequences. If the player of the
+//     session turned color off then the color codes are stripped instead.
+//   strip(text): string
+//     @param text: string = the text to remove color from
+//     removes all color codes and ANSI escape sequences from the text.
+//   length(text): number
+//     @param text: string = the text to measure
+//     returns the number of visible characters in the text, ignoring color
+//     codes and escape sequences. Use this instead of # when aligning output.
+//   pad(text, width[, align]): string
+//     @param text: string = the text to pad
+//     @param width: number = the visible width the result should fill
+//     @param align: string = "left" = one of "left", "right" or "center"
+//     pads the text with spaces to the given visible width, text already
+//     wider than width is returned untouched.
+//   enabled([session]): boolean
+//     @param session: string = optional id of the player's session
+//     returns whether the player of the session wants color, color is
+//     enabled unless explicitly turned off.
+//   set_enabled(session, enabled): boolean
+//     @param session: string = the id of the player's session
+//     @param enabled: boolean = whether to enable color output
+//     turn color on or off for the player of the session, typically based on
+//     their preferences. Returns false if there is no open session with the
+//     id.
+var Color = lua.TableMap{
+	"colorize": func(engine *lua.Engine) int {
+		id := ""
+		if engine.StackSize() >= 2 {
+			id = engine.PopString()
+		}
+		text := engine.PopString()
+		if colorEnabled(id) {
+			engine.PushValue(ansi.Colorize(text))
+		} else {
+			engine.PushValue(ansi.Strip(text))
+		}
+
+		return 1
+	},
+	"strip":  ansi.Strip,
+	"length": ansi.VisibleLength,
+	"pad": func(engine *lua.Engine) int {
+		align := "left"
+		if engine.StackSize() >= 3 {
+			align = engine.PopString()
+		}
+		width := engine.PopInt()
+		text := engine.PopString()
+
+		engine.PushValue(strutil.Pad(text, width, alignments[align], " "))
+
+		return 1
+	},
+	"enabled": func(engine *lua.Engine) int {
+		id := ""
+		if engine.StackSize() >= 1 {
+			id = engine.PopString()
+		}
+		engine.PushValue(colorEnabled(id))
+
+		return 1
+	},
+	"set_enabled": func(id string, enabled bool) bool {
+		s := session.Global().Get(id)
+		if s == nil {
+			return false
+		}
+		s.SetColorEnabled(enabled)
+
+		return true
+	},
+}
+
+// color is on by default, only disabled when the player of the session
+// turned it off
+func colorEnabled(id string) bool {
+	if id == "" {
+		return true
+	}
+	if s := session.Global().Get(id); s != nil {
+		return s.ColorEnabled()
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/ansi"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Color Module", func() {
+	var (
+		e   *lua.Engine
+		err error
+	)
+
+	BeforeEach(func() {
+		e = lua.NewEngine()
+		scripting.OpenLibs(e, "color")
+		e.DoString(`
+			color = require("color")
+		`)
+	})
+
+	AfterEach(func() {
+		e.Close()
+	})
+
+	It("colorizes text by default", func() {
+		err = e.DoString(`result = color.colorize("[R]danger[x]")`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("result").AsString()).Should(Equal(ansi.Colorize("[R]danger[x]")))
+	})
+
+	Context("with a session", func() {
+		var s *session.Session
+
+		BeforeEach(func() {
+			s = session.Global().Open(&dataConn{sent: make(map[string]interface{})})
+			e.SetGlobal("id", s.ID())
+		})
+
+		AfterEach(func() {
+			s.Close()
+		})
+
+		It("strips color when the player turned it off", func() {
+			err = e.DoString(`
+				found = color.set_enabled(id, false)
+				enabled = color.enabled(id)
+				result = color.colorize("[R]danger[x]", id)
+				other = color.colorize("[R]danger[x]")
+			`)
+			Ω(err).Should(BeNil())
+			Ω(e.GetGlobal("found").AsBool()).Should(BeTrue())
+			Ω(e.GetGlobal("enabled").AsBool()).Should(BeFalse())
+			Ω(e.GetGlobal("result").AsString()).Should(Equal("danger"))
+			Ω(e.GetGlobal("other").AsString()).Should(Equal(ansi.Colorize("[R]danger[x]")))
+			Ω(s.ColorEnabled()).Should(BeFalse())
+		})
+
+		It("keeps the preference with the session, not the engine", func() {
+			err = e.DoString(`color.set_enabled(id, false)`)
+			Ω(err).Should(BeNil())
+
+			other := lua.NewEngine()
+			defer other.Close()
+			scripting.OpenLibs(other, "color")
+			other.SetGlobal("id", s.ID())
+			err = other.DoString(`result = require("color").colorize("[R]danger[x]", id)`)
+			Ω(err).Should(BeNil())
+			Ω(other.GetGlobal("result").AsString()).Should(Equal("danger"))
+		})
+	})
+
+	It("returns false setting color for a missing session", func() {
+		err = e.DoString(`result = color.set_enabled("missing", false)`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("result").AsBool()).Should(BeFalse())
+	})
+
+	It("measures visible length", func() {
+		err = e.DoString(`result = color.length("[R]danger[x]")`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("result").AsNumber()).Should(Equal(float64(6)))
+	})
+
+	It("pads to a visible width", func() {
+		err = e.DoString(`result = color.pad("[R]hp[x]", 6, "right")`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("result").AsString()).Should(Equal("    [R]hp[x]"))
+	})
+})
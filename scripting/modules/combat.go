@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Combat lets scripts start and stop fights and take part in resolving
+// attacks. Handlers of the "combat:" events are given the id of the attack,
+// while it resolves its damage and messages can be changed, like armor
+// soaking damage in "before:combat:damage" or a proc adding a message in
+// "combat:hit".
+//   start(attacker, defender): boolean, string
+//     has the combatant with the attacker id start fighting the defender,
+//     returning false and why if they can't.
+//   stop(id)
+//     ends every fight the combatant is in.
+//   fighting(id): boolean
+//     returns true if the combatant is fighting.
+//   target(id): string
+//     returns the id of who the combatant is fighting, nil if they aren't.
+//   strike(attacker, defender, damage[, kind]): number
+//     deals the damage through the damage events, like for a skill that
+//     always lands, returning what was dealt. The kind names what struck in
+//     messages, like "kick".
+//   damage(attack): number
+//     returns the damage the attack deals, nil if it isn't resolving.
+//   set_damage(attack, damage): boolean
+//     changes the damage the attack deals, returning false if it isn't
+//     resolving.
+//   adjust(attack, delta): number
+//     adds delta to the attack's damage, returning what it deals now.
+//   message(attack, to, text): boolean
+//     replaces what "attacker", "defender" or "room" are told of the attack,
+//     an empty text tells them nothing.
+var Combat = lua.TableMap{
+	"start": func(engine *lua.Engine) int {
+		defender := combat.Global().Lookup(engine.PopString())
+		attacker := combat.Global().Lookup(engine.PopString())
+		if attacker == nil || defender == nil {
+			engine.PushValue(false)
+			engine.PushValue(combat.NotHereMessage)
+
+			return 2
+		}
+		if err := combat.Global().Start(attacker, defender); err != nil {
+			engine.PushValue(false)
+			if r, ok := err.(*item.Refused); ok {
+				engine.PushValue(r.Message)
+			} else {
+				engine.PushValue(err.Error())
+			}
+
+			return 2
+		}
+		engine.PushValue(true)
+
+		return 1
+	},
+	"stop": func(engine *lua.Engine) int {
+		if c := combat.Global().Lookup(engine.PopString()); c != nil {
+			combat.Global().Remove(c)
+		}
+
+		return 0
+	},
+	"fighting": func(engine *lua.Engine) int {
+		engine.PushValue(combat.Global().Fighting(engine.PopString()))
+
+		return 1
+	},
+	"target": func(engine *lua.Engine) int {
+		c := combat.Global().Lookup(engine.PopString())
+		if c != nil {
+			if target, ok := combat.Global().Target(c); ok {
+				engine.PushValue(target.ID())
+
+				return 1
+			}
+		}
+		engine.PushValue(engine.Nil())
+
+		return 1
+	},
+	"strike": func(engine *lua.Engine) int {
+		var kind string
+		if engine.StackSize() >= 4 {
+			kind = engine.PopString()
+		}
+		damage := engine.PopInt()
+		defender := combat.Global().Lookup(engine.PopString())
+		attacker := combat.Global().Lookup(engine.PopString())
+		if attacker == nil || defender == nil {
+			engine.PushValue(0)
+
+			return 1
+		}
+		engine.PushValue(combat.Global().Strike(attacker, defender, damage, kind))
+
+		return 1
+	},
+	"damage": func(engine *lua.Engine) int {
+		at, ok := combat.Global().Attack(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(at.Damage())
+
+		return 1
+	},
+	"set_damage": func(engine *lua.Engine) int {
+		damage := engine.PopInt()
+		at, ok := combat.Global().Attack(engine.PopString())
+		if ok {
+			at.SetDamage(damage)
+		}
+		engine.PushValue(ok)
+
+		return 1
+	},
+	"adjust": func(engine *lua.Engine) int {
+		delta := engine.PopInt()
+		at, ok := combat.Global().Attack(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(at.AdjustDamage(delta))
+
+		return 1
+	},
+	"message": func(engine *lua.Engine) int {
+		text := engine.PopString()
+		to := engine.PopString()
+		at, ok := combat.Global().Attack(engine.PopString())
+		engine.PushValue(ok && at.SetMessage(to, text) == nil)
+
+		return 1
+	},
+}
@@ -0,0 +1,114 @@
+package modules_test
+
+import (
+	"math/rand"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/random"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// brawler is a combatant for scripts to fight with
+type brawler struct {
+	id    string
+	stats map[string]int
+}
+
+func (b *brawler) ID() string {
+	return b.id
+}
+
+func (b *brawler) Name() string {
+	return b.id
+}
+
+func (b *brawler) Location() string {
+	return "lua-arena"
+}
+
+func (b *brawler) Stat(name string) int {
+	return b.stats[name]
+}
+
+func (b *brawler) AddStat(name string, delta int) int {
+	b.stats[name] += delta
+
+	return b.stats[name]
+}
+
+var _ = Describe("Combat Lua Module", func() {
+	var (
+		engine      *lua.Engine
+		em          *events.Emitter
+		ogre, troll *brawler
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "combat")
+		engine.DoString(`combat = require("combat")`)
+		em = events.NewEmitter(nil)
+		ogre = &brawler{id: "ogre", stats: map[string]int{"hp": 100, "hit": 100}}
+		troll = &brawler{id: "troll", stats: map[string]int{"hp": 100, "hit": 100}}
+		combat.Global().SetEmitter(em)
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			switch id {
+			case "ogre":
+				return ogre
+			case "troll":
+				return troll
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().Remove(ogre)
+		combat.Global().SetEmitter(nil)
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("starts and stops fights", func() {
+		res, err := testReturn(engine, `
+			local started = combat.start("ogre", "troll")
+			local _, why = combat.start("ogre", "ogre")
+			local target = combat.target("troll")
+			combat.stop("ogre")
+			return {started, why, target, combat.fighting("troll")}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, combat.SelfMessage, "ogre", false}))
+	})
+
+	It("strikes through the damage events", func() {
+		res, err := testReturn(engine, `return combat.strike("ogre", "troll", 7, "kick")`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsNumber()).Should(Equal(float64(7)))
+		Ω(troll.stats["hp"]).Should(Equal(93))
+	})
+
+	It("changes attacks as they resolve", func() {
+		em.On("before:"+combat.DamageEvent, events.HandlerFunc(func(d events.Data) error {
+			engine.SetGlobal("attack", d["id"])
+
+			return engine.DoString(`
+				combat.set_damage(attack, 10)
+				combat.adjust(attack, -4)
+				combat.message(attack, "room", "")
+			`)
+		}))
+
+		random.SetSource(rand.NewSource(1))
+		Ω(engine.DoString(`combat.start("ogre", "troll")`)).Should(Succeed())
+		Ω(troll.stats["hp"]).Should(Equal(94))
+	})
+})
@@ -0,0 +1,135 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/cooldown"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Cooldown tracks when skills, commands and other actions can be used again.
+// Cooldowns are shared by every engine and identified by keys that should
+// include what the cooldown applies to, like "player:12:fireball". Times are
+// given in seconds and may be fractional.
+//   set(key, seconds)
+//     @param key: string = the cooldown to set
+//     @param seconds: number = how long until the key is ready again
+//     puts the key on cooldown, replacing any existing cooldown.
+//   try(key, seconds): boolean
+//     @param key: string = the cooldown to check and set
+//     @param seconds: number = how long until the key is ready again
+//     if the key is ready it's put on cooldown and true is returned,
+//     otherwise false is returned and the cooldown is left alone.
+//   ready(key): boolean
+//     @param key: string = the cooldown to check
+//     returns whether the key is off cooldown.
+//   remaining(key): number
+//     @param key: string = the cooldown to check
+//     returns the number of seconds until the key is ready, 0 if it's ready.
+//   expires(key): number
+//     @param key: string = the cooldown to check
+//     returns the Unix timestamp the cooldown ends at or nil if it's ready.
+//   clear(key)
+//     @param key: string = the cooldown to remove
+//     makes the key ready immediately.
+//   allow(key, max, seconds): boolean
+//     @param key: string = the rate limit to check
+//     @param max: number = the number of uses allowed in the window
+//     @param seconds: number = the length of the window, starting with the
+//       first use
+//     returns true and counts a use if the limit hasn't been reached,
+//     otherwise false.
+//   snapshot(): table
+//     returns a map of every active cooldown key to the Unix timestamp it
+//     expires at, for persisting cooldowns.
+//   restore(cooldowns)
+//     @param cooldowns: table = a map of keys to Unix timestamps, like the one
+//       returned from snapshot
+//     loads persisted cooldowns without calling change hooks, expired
+//     cooldowns are ignored.
+//   on_change(fn)
+//     @param fn: function(key, expires) = called whenever a cooldown is set or
+//       cleared, expires is the Unix timestamp it ends at or nil if it was
+//       cleared
+//     registers a hook to persist cooldowns as they change.
+var Cooldown = lua.TableMap{
+	"set": func(key string, seconds float64) {
+		cooldown.Global().Set(key, secondsToDuration(seconds))
+	},
+	"try": func(key string, seconds float64) bool {
+		return cooldown.Global().Try(key, secondsToDuration(seconds))
+	},
+	"ready": func(key string) bool {
+		return cooldown.Global().Ready(key)
+	},
+	"remaining": func(key string) float64 {
+		return cooldown.Global().Remaining(key).Seconds()
+	},
+	"expires": func(engine *lua.Engine) int {
+		expires, ok := cooldown.Global().Expires(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+
+		engine.PushValue(unixSeconds(expires))
+
+		return 1
+	},
+	"clear": func(key string) {
+		cooldown.Global().Clear(key)
+	},
+	"allow": func(key string, max int, seconds float64) bool {
+		return cooldown.Global().Allow(key, max, secondsToDuration(seconds))
+	},
+	"snapshot": func(engine *lua.Engine) int {
+		tbl := engine.NewTable()
+		for key, expires := range cooldown.Global().Snapshot() {
+			tbl.Set(key, unixSeconds(expires))
+		}
+
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"restore": func(engine *lua.Engine) int {
+		tracker := cooldown.Global()
+		engine.PopTable().ForEach(func(key, expires *lua.Value) {
+			tracker.Restore(key.AsString(), time.Unix(0, int64(expires.AsNumber()*float64(time.Second))))
+		})
+
+		return 0
+	},
+	"on_change": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		if !fn.IsFunction() {
+			engine.ArgumentError(1, "expected a function")
+
+			return 0
+		}
+
+		cooldown.Global().OnChange(func(key string, expires time.Time) {
+			var exp interface{} = engine.Nil()
+			if !expires.IsZero() {
+				exp = unixSeconds(expires)
+			}
+
+			if _, err := fn.Call(0, key, exp); err != nil {
+				log("cooldown").WithError(err).WithField("engine", nameForEngine(engine)).Error("Cooldown change hook failed.")
+			}
+		})
+
+		return 0
+	},
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
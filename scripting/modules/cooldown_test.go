@@ -0,0 +1,72 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cooldown Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "cooldown")
+		engine.DoString(`cooldown = require("cooldown")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("puts keys on cooldown", func() {
+		res, err := testReturn(engine, `
+			local before = cooldown.ready("test:fireball")
+			cooldown.set("test:fireball", 60)
+			local remaining = cooldown.remaining("test:fireball")
+
+			return {before, cooldown.ready("test:fireball"), remaining > 59 and remaining <= 60, cooldown.expires("test:fireball") ~= nil}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false, true, true}))
+	})
+
+	It("tries and clears cooldowns", func() {
+		res, err := testReturn(engine, `
+			local first = cooldown.try("test:bash", 60)
+			local second = cooldown.try("test:bash", 60)
+			cooldown.clear("test:bash")
+
+			return {first, second, cooldown.ready("test:bash"), cooldown.expires("test:bash") == nil}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false, true, true}))
+	})
+
+	It("restores snapshots", func() {
+		res, err := testReturn(engine, `
+			cooldown.set("test:snap", 60)
+			local snap = cooldown.snapshot()
+			cooldown.clear("test:snap")
+			cooldown.restore({["test:snap"] = snap["test:snap"]})
+
+			return cooldown.ready("test:snap")
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsBool()).Should(BeFalse())
+	})
+
+	It("rate limits", func() {
+		res, err := testReturn(engine, `
+			return {cooldown.allow("test:shout", 1, 60), cooldown.allow("test:shout", 1, 60)}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false}))
+	})
+})
@@ -0,0 +1,123 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Craft lets scripts define recipes, decide how well crafted items turn out
+// and set players to work.
+//   define(recipe): boolean, string
+//     @param recipe: table = the recipe's id, name, makes, count,
+//       ingredients, tools, skill, level, ticks and quality, as in recipe
+//       files
+//     adds the recipe, replacing any with its id, returning false and why if
+//     it doesn't make sense.
+//   quality(name, fn)
+//     @param name: string = the name recipes give as their quality, or
+//       "default" for those that don't
+//     @param fn: function(crafter, recipe, proficiency): string = given the
+//       id of the crafter, the id of the recipe and how well they know its
+//       skill, returns "failed", "poor", "common", "fine", "masterwork" or a
+//       quality of its own
+//     sets the quality hook, replacing any with the name.
+//   craft(player, recipe): boolean, string
+//     starts the player making the recipe with the name, returning false and
+//     why if they can't.
+//   cancel(player): boolean
+//     stops what the player is making, returning false if they weren't.
+//   working(player): string, number
+//     returns the id of the recipe the player is making and how many pulses
+//     they've worked on it, nil if they aren't making anything.
+var Craft = lua.TableMap{
+	"define": func(engine *lua.Engine) int {
+		return pushResult(engine, craft.Global().Recipes().Add(recipeFromTable(engine.PopTable())))
+	},
+	"quality": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		craft.Global().Quality(name, func(c craft.Crafter, r craft.Recipe, proficiency int) string {
+			ret, err := fn.Call(1, c.ID(), r.ID, proficiency)
+			if err != nil {
+				log("craft").WithError(err).WithField("engine", nameForEngine(engine)).Error("Crafting quality hook failed.")
+
+				return craft.Common
+			}
+			if len(ret) == 0 || !ret[0].IsString() {
+				return craft.Common
+			}
+
+			return ret[0].AsString()
+		})
+
+		return 0
+	},
+	"craft": func(engine *lua.Engine) int {
+		name := engine.PopString()
+		c := crafter(engine.PopString())
+		if c == nil {
+			engine.PushValue(false)
+			engine.PushValue(craft.UnknownMessage)
+
+			return 2
+		}
+		_, err := craft.Global().Craft(c, name)
+
+		return pushResult(engine, err)
+	},
+	"cancel": func(engine *lua.Engine) int {
+		engine.PushValue(craft.Global().Cancel(engine.PopString()))
+
+		return 1
+	},
+	"working": func(engine *lua.Engine) int {
+		r, done, ok := craft.Global().Working(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(r.ID)
+		engine.PushValue(done)
+
+		return 2
+	},
+}
+
+// crafter returns the combatant with the id if they can craft
+func crafter(id string) craft.Crafter {
+	c, _ := combat.Global().Lookup(id).(craft.Crafter)
+
+	return c
+}
+
+func recipeFromTable(t *lua.Value) craft.Recipe {
+	r := craft.Recipe{
+		ID:      t.Get("id").AsString(),
+		Name:    t.Get("name").AsString(),
+		Makes:   t.Get("makes").AsString(),
+		Count:   int(t.Get("count").AsNumber()),
+		Skill:   t.Get("skill").AsString(),
+		Level:   int(t.Get("level").AsNumber()),
+		Ticks:   int(t.Get("ticks").AsNumber()),
+		Quality: t.Get("quality").AsString(),
+	}
+	if ingredients := t.Get("ingredients"); ingredients.IsTable() {
+		ingredients.ForEach(func(_, in *lua.Value) {
+			r.Ingredients = append(r.Ingredients, craft.Ingredient{
+				Item:  in.Get("item").AsString(),
+				Count: int(in.Get("count").AsNumber()),
+			})
+		})
+	}
+	if tools := t.Get("tools"); tools.IsTable() {
+		tools.ForEach(func(_, tool *lua.Value) {
+			r.Tools = append(r.Tools, tool.AsString())
+		})
+	}
+
+	return r
+}
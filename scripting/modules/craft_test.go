@@ -0,0 +1,92 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// artisan is a shopper who makes things
+type artisan struct {
+	shopper
+	skills map[string]int
+}
+
+func (a *artisan) Skill(id string) int {
+	return a.skills[id]
+}
+
+var _ = Describe("Craft Lua Module", func() {
+	var (
+		engine *lua.Engine
+		weaver *artisan
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "craft")
+		engine.DoString(`craft = require("craft")`)
+		weaver = &artisan{shopper: shopper{brawler: brawler{id: "weaver", stats: map[string]int{}}}, skills: map[string]int{"weaving": 50}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "weaver" {
+				return weaver
+			}
+
+			return nil
+		})
+		world.Global().AddZone(world.Zone{ID: "lua-loom", Name: "The Loom"})
+		Ω(world.Global().SetItem(world.ItemDef{ID: "lua-thread", Zone: "lua-loom", Name: "some thread", Flags: []string{"stackable"}})).Should(Succeed())
+		Ω(world.Global().SetItem(world.ItemDef{ID: "lua-cloak", Zone: "lua-loom", Name: "a cloak"})).Should(Succeed())
+		thread, err := item.Create(world.Global(), "lua-thread", 2)
+		Ω(err).ShouldNot(HaveOccurred())
+		weaver.inventory = item.List{thread}
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		craft.Global().Cancel("weaver")
+		engine.Close()
+	})
+
+	It("defines recipes, decides quality and crafts for players", func() {
+		res, err := testReturn(engine, `
+			local _, invalid = craft.define({id = "lua-broken"})
+			craft.define({
+				id = "lua-cloak",
+				name = "lua cloak",
+				makes = "lua-cloak",
+				ingredients = {{item = "lua-thread", count = 2}},
+				skill = "weaving",
+				quality = "lua-weave",
+			})
+			craft.quality("lua-weave", function(crafter, recipe, proficiency)
+				if crafter == "weaver" and proficiency >= 50 then
+					return "fine"
+				end
+			end)
+			local started = craft.craft("weaver", "lua cloak")
+			local recipe, done = craft.working("weaver")
+			return {invalid ~= nil, started, recipe, done}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, true, "lua-cloak", float64(0)}))
+
+		craft.Global().Tick()
+		Ω(weaver.inventory).Should(HaveLen(1))
+		Ω(weaver.inventory[0].Proto).Should(Equal("lua-cloak"))
+		Ω(weaver.inventory[0].Props["quality"]).Should(Equal(craft.Fine))
+
+		res, err = testReturn(engine, `
+			local _, why = craft.craft("weaver", "lua cloak")
+			return {craft.working("weaver") == nil, why, craft.cancel("weaver")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, craft.MissingMessage, false}))
+	})
+})
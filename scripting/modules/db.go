@@ -0,0 +1,370 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/talon"
+)
+
+// DB provides a simplified, table based interface to the database for content
+// systems that just need to persist and fetch data without working with lazy
+// row sets. Every row is fully loaded and handed back as a plain Lua table.
+//   query(cypher[, params]): table
+//     @param cypher: string - the query to execute on the database server
+//     @param params: table = nil - named parameters to bind into the query,
+//       referenced in the query as {name}
+//     @errors raises an error if there is an issue with the database connection
+//       or the query construction
+//     executes the query and returns a list of rows, each row being a table
+//     keyed by the names of the returned columns. Nodes are returned as
+//     tables with id, labels and properties keys, relationships as tables with
+//     id, name, start_node_id, end_node_id and properties keys and paths as
+//     a list of nodes and relationships.
+//   exec(cypher[, params]): table
+//     @param cypher: string - the query to execute on the database server
+//     @param params: table = nil - named parameters to bind into the query
+//     @errors raises an error if there is an issue with the database connection
+//       or the query construction
+//     executes a query that does not return rows and returns a table of stats
+//     about what was changed (nodes_created, nodes_deleted, labels_added,
+//     properties_set, relationships_created, relationships_deleted).
+//   transaction(fn): any
+//     @param fn: function(tx) - function that performs work within the
+//       transaction, tx is a table with query and exec functions identical to
+//       the ones on this module except they run within the transaction
+//     @errors raises an error if the transaction could not be started or
+//       committed, or re-raises any error raised from fn after rolling back
+//     runs fn inside of a transaction, committing when fn returns and rolling
+//     back if it raises an error. The first value returned from fn is returned
+//     from transaction.
+// The graph functions work with whichever graph the game keeps, in Neo4j or
+// in memory. Nodes are given as "Label:id", like "Room:town-square", labels,
+// kinds and property names are letters, digits and underscores.
+//   link(from, kind, to[, props])
+//     @param props: table = nil - properties of the relationship
+//     @errors raises an error if a node or name isn't valid or the graph
+//       can't be changed
+//     relates the nodes with a relationship of the kind, replacing the
+//     properties of any there was.
+//   unlink(from, kind, to)
+//     @errors raises an error if the graph can't be changed
+//     removes the relationship of the kind between the nodes.
+//   edges(node[, kind]): table
+//     @param kind: string = nil - the kind of relationship, any if it's nil
+//     @errors raises an error if the graph can't be read
+//     returns a list of the relationships from the node, each a table with
+//     from, kind, to and props keys.
+//   inbound(node[, kind]): table
+//     returns the relationships to the node, like edges.
+//   path(from, to[, kind]): table
+//     @param kind: string = nil - the kind of relationship to follow, any if
+//       it's nil
+//     returns the list of nodes along the shortest path between the nodes,
+//     including both, nil if there's no path.
+var DB = lua.TableMap{
+	"query": func(engine *lua.Engine) int {
+		return dbQuery(engine, data.DB())
+	},
+	"exec": func(engine *lua.Engine) int {
+		return dbExec(engine, data.DB())
+	},
+	"transaction": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		if !fn.IsFunction() {
+			engine.ArgumentError(1, "expected a function")
+
+			return 0
+		}
+
+		tx, err := data.DB().Begin()
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		txTable := engine.NewTable()
+		txTable.Set("query", func(eng *lua.Engine) int {
+			return dbQuery(eng, tx)
+		})
+		txTable.Set("exec", func(eng *lua.Engine) int {
+			return dbExec(eng, tx)
+		})
+
+		results, err := fn.Call(1, txTable)
+		if err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				log("db").WithError(rerr).Error("Failed to roll back transaction.")
+			}
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		if err := tx.Commit(); err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(results[0])
+
+		return 1
+	},
+	"link": func(engine *lua.Engine) int {
+		var props map[string]interface{}
+		if engine.StackSize() > 3 {
+			props = engine.PopValue().AsMapStringInterface()
+		}
+		to, kind, from := engine.PopString(), engine.PopString(), engine.PopString()
+		err := graphEdge(from, kind, to, props, func(e data.Edge) error {
+			return data.GlobalGraph().Link(e)
+		})
+		if err != nil {
+			engine.RaiseError(err.Error())
+		}
+
+		return 0
+	},
+	"unlink": func(engine *lua.Engine) int {
+		to, kind, from := engine.PopString(), engine.PopString(), engine.PopString()
+		err := graphEdge(from, kind, to, nil, func(e data.Edge) error {
+			return data.GlobalGraph().Unlink(e.From, e.Kind, e.To)
+		})
+		if err != nil {
+			engine.RaiseError(err.Error())
+		}
+
+		return 0
+	},
+	"edges": func(engine *lua.Engine) int {
+		return graphEdges(engine, data.GlobalGraph().Edges)
+	},
+	"inbound": func(engine *lua.Engine) int {
+		return graphEdges(engine, data.GlobalGraph().Inbound)
+	},
+	"path": func(engine *lua.Engine) int {
+		kind := ""
+		if engine.StackSize() > 2 {
+			kind = engine.PopString()
+		}
+		to, err := graphNode(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		from, err := graphNode(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		path, err := data.GlobalGraph().Path(from, to, kind)
+		if err == data.ErrNotFound {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		list := engine.NewTable()
+		for _, n := range path {
+			list.Append(n.String())
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+}
+
+// graphNode parses a node given as "Label:id"
+func graphNode(s string) (data.Node, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return data.Node{}, fmt.Errorf("nodes are given as \"Label:id\", not %q", s)
+	}
+
+	return data.Node{Label: parts[0], ID: parts[1]}, nil
+}
+
+// graphEdge parses the nodes of an edge and passes it to fn
+func graphEdge(from, kind, to string, props map[string]interface{}, fn func(data.Edge) error) error {
+	f, err := graphNode(from)
+	if err != nil {
+		return err
+	}
+	t, err := graphNode(to)
+	if err != nil {
+		return err
+	}
+
+	return fn(data.Edge{From: f, Kind: kind, To: t, Props: props})
+}
+
+// graphEdges pulls the node and optional kind off the stack, pushing the
+// relationships find returns as a list of tables
+func graphEdges(engine *lua.Engine, find func(data.Node, string) ([]data.Edge, error)) int {
+	kind := ""
+	if engine.StackSize() > 1 {
+		kind = engine.PopString()
+	}
+	n, err := graphNode(engine.PopString())
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+	edges, err := find(n, kind)
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+	list := engine.NewTable()
+	for _, e := range edges {
+		tbl := engine.NewTable()
+		tbl.Set("from", e.From.String())
+		tbl.Set("kind", e.Kind)
+		tbl.Set("to", e.To.String())
+		tbl.Set("props", engine.TableFromMap(e.Props))
+		list.Append(tbl)
+	}
+	engine.PushValue(list)
+
+	return 1
+}
+
+// dbQuerier is satisfied by both the database and transactions, allowing the
+// same query and exec implementations to be used for both.
+type dbQuerier interface {
+	Cypher(string) *talon.Query
+	CypherP(string, talon.Properties) (*talon.Query, error)
+}
+
+func dbQuery(engine *lua.Engine, q dbQuerier) int {
+	query, err := getDBQuery(engine, q)
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+
+	rows, err := query.Query()
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+	defer rows.Close()
+
+	all, err := rows.All()
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+
+	list := engine.NewTable()
+	for _, row := range all {
+		tbl := engine.NewTable()
+		for i, field := range row.Metadata.Fields {
+			if val, ok := row.GetIndex(i); ok {
+				tbl.Set(field, dbToLua(engine, val))
+			}
+		}
+		list.Append(tbl)
+	}
+
+	engine.PushValue(list)
+
+	return 1
+}
+
+func dbExec(engine *lua.Engine, q dbQuerier) int {
+	query, err := getDBQuery(engine, q)
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+
+	result, err := query.Exec()
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+
+	stats := engine.NewTable()
+	stats.Set("labels_added", result.Stats.LabelsAdded)
+	stats.Set("nodes_created", result.Stats.NodesCreated)
+	stats.Set("nodes_deleted", result.Stats.NodesDeleted)
+	stats.Set("properties_set", result.Stats.PropertiesSet)
+	stats.Set("relationships_created", result.Stats.RelationshipsCreated)
+	stats.Set("relationships_deleted", result.Stats.RelationshipsDeleted)
+
+	engine.PushValue(stats)
+
+	return 1
+}
+
+// pull the query and optional params off the stack and build the query
+func getDBQuery(engine *lua.Engine, q dbQuerier) (*talon.Query, error) {
+	var params map[string]interface{}
+	if engine.StackSize() >= 2 {
+		params = engine.PopValue().AsMapStringInterface()
+	}
+	cypher := engine.PopString()
+
+	if len(params) == 0 {
+		return q.Cypher(cypher), nil
+	}
+
+	return q.CypherP(cypher, talon.Properties(params))
+}
+
+// convert database values into plain Lua tables
+func dbToLua(engine *lua.Engine, v interface{}) interface{} {
+	switch t := v.(type) {
+	case *talon.Node:
+		tbl := engine.NewTable()
+		tbl.Set("id", t.ID)
+		tbl.Set("labels", engine.TableFromSlice(t.Labels))
+		tbl.Set("properties", engine.TableFromMap(map[string]interface{}(t.Properties)))
+
+		return tbl
+	case *talon.Relationship:
+		tbl := engine.NewTable()
+		tbl.Set("id", t.ID)
+		tbl.Set("name", t.Name)
+		if t.Bounded {
+			tbl.Set("start_node_id", t.StartNodeID)
+			tbl.Set("end_node_id", t.EndNodeID)
+		}
+		tbl.Set("properties", engine.TableFromMap(map[string]interface{}(t.Properties)))
+
+		return tbl
+	case talon.Path:
+		tbl := engine.NewTable()
+		for _, ent := range t {
+			tbl.Append(dbToLua(engine, ent))
+		}
+
+		return tbl
+	case map[string]interface{}:
+		return engine.TableFromMap(t)
+	case []interface{}:
+		return engine.TableFromSlice(t)
+	default:
+		return v
+	}
+}
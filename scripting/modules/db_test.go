@@ -0,0 +1,106 @@
+package modules_test
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/config"
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DB", func() {
+	loadLiveTestEnvVariables()
+
+	if !performLiveTest {
+		fmt.Println("Skipping live db module test. Set TALON_PERFORM_LIVE_TEST to 1 to execute live tests.")
+
+		return
+	}
+
+	config.Setup(nil)
+
+	var (
+		p = lua.NewEnginePool(1, func(eng *lua.Engine) {
+			scripting.OpenLibs(eng, "db")
+		})
+	)
+
+	AfterEach(func() {
+		data.DB().Cypher("MATCH (n:DBModuleTestNode) DELETE n").Exec()
+	})
+
+	Describe("query", func() {
+		BeforeEach(func() {
+			data.DB().Cypher(`
+				CREATE (:DBModuleTestNode {name: "first"}),
+				       (:DBModuleTestNode {name: "second"})
+			`).Exec()
+		})
+
+		It("returns rows as tables using params", func() {
+			eng := p.Get()
+			defer eng.Release()
+			err := eng.DoString(`
+				local db = require("db")
+
+				function get_name()
+					local rows = db.query("MATCH (n:DBModuleTestNode {name: {name}}) RETURN n, n.name AS name", {name = "second"})
+
+					return #rows, rows[1].name, rows[1].n.properties.name
+				end
+			`)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			values, err := eng.Call("get_name", 3)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(values[0].AsNumber()).Should(Equal(float64(1)))
+			Ω(values[1].AsString()).Should(Equal("second"))
+			Ω(values[2].AsString()).Should(Equal("second"))
+		})
+	})
+
+	Describe("transaction", func() {
+		It("commits when the function succeeds", func() {
+			eng := p.Get()
+			defer eng.Release()
+			err := eng.DoString(`
+				local db = require("db")
+
+				db.transaction(function(tx)
+					tx.exec("CREATE (:DBModuleTestNode {name: {name}})", {name = "committed"})
+				end)
+			`)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			rows, err := data.DB().Cypher("MATCH (n:DBModuleTestNode {name: 'committed'}) RETURN n").Query()
+			Ω(err).ShouldNot(HaveOccurred())
+			all, err := rows.All()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(all).Should(HaveLen(1))
+		})
+
+		It("rolls back when the function raises an error", func() {
+			eng := p.Get()
+			defer eng.Release()
+			err := eng.DoString(`
+				local db = require("db")
+
+				db.transaction(function(tx)
+					tx.exec("CREATE (:DBModuleTestNode {name: 'rolled_back'})")
+					error("failure")
+				end)
+			`)
+			Ω(err).Should(HaveOccurred())
+
+			rows, err := data.DB().Cypher("MATCH (n:DBModuleTestNode {name: 'rolled_back'}) RETURN n").Query()
+			Ω(err).ShouldNot(HaveOccurred())
+			all, err := rows.All()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(all).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,92 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Death lets scripts decide what players pay for dying and where they
+// respawn.
+//   penalty(name, fn)
+//     @param name: string = the name of the penalty, penalties run in order
+//       of their names
+//     @param fn: function(player, killer) = given the name of the player
+//       who died and who killed them, which may be empty, makes them pay
+//     sets the penalty, replacing any with the name.
+//   forgive(name)
+//     removes the penalty with the name.
+//   policy([policy]): string
+//     @param policy: string = optional new policy, "recall", "zone", "home"
+//       or "here"
+//     @errors raises an error if the policy isn't known
+//     returns where players respawn, after changing it if one was given.
+//   respawn_room(player): string
+//     returns the room the player would respawn in, nil if there's no such
+//     player.
+//   kill(player, [killer]): boolean, string
+//     kills the player, leaving their corpse and respawning them, returning
+//     false and why if they can't die.
+var Death = lua.TableMap{
+	"penalty": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		death.Global().Penalty(name, func(v death.Victim, killer string) {
+			if _, err := fn.Call(0, v.Name(), killer); err != nil {
+				log("death").WithError(err).WithField("penalty", name).WithField("engine", nameForEngine(engine)).Error("Death penalty failed.")
+			}
+		})
+
+		return 0
+	},
+	"forgive": func(name string) {
+		death.Global().Penalty(name, nil)
+	},
+	"policy": func(engine *lua.Engine) int {
+		if engine.StackSize() > 0 {
+			if err := death.Global().SetPolicy(engine.PopString()); err != nil {
+				engine.RaiseError(err.Error())
+
+				return 0
+			}
+		}
+		engine.PushValue(death.Global().Policy())
+
+		return 1
+	},
+	"respawn_room": func(engine *lua.Engine) int {
+		v := victim(engine.PopString())
+		if v == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(death.Global().RespawnRoom(v))
+
+		return 1
+	},
+	"kill": func(engine *lua.Engine) int {
+		killer := ""
+		if engine.StackSize() > 1 {
+			killer = engine.PopString()
+		}
+		v := victim(engine.PopString())
+		if v == nil {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+
+		return pushResult(engine, death.Global().Die(v, killer))
+	},
+}
+
+// victim returns the combatant with the id if they can die like players
+func victim(id string) death.Victim {
+	v, _ := combat.Global().Lookup(id).(death.Victim)
+
+	return v
+}
@@ -0,0 +1,88 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mortal is a brawler who dies like a player
+type mortal struct {
+	brawler
+}
+
+func (m *mortal) SetLocation(string) {}
+
+func (m *mortal) SetStat(name string, value int) {
+	m.stats[name] = value
+}
+
+func (m *mortal) UpdateEquipment(fn func(item.List, map[string]item.Item) (item.List, map[string]item.Item, error)) error {
+	_, _, err := fn(nil, nil)
+
+	return err
+}
+
+func (m *mortal) Var(string) interface{} {
+	return nil
+}
+
+var _ = Describe("Death Lua Module", func() {
+	var (
+		engine *lua.Engine
+		ghost  *mortal
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "death")
+		engine.DoString(`death = require("death")`)
+		ghost = &mortal{brawler{id: "ghost", stats: map[string]int{"gold": 100, "max_hp": 10}}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "ghost" {
+				return ghost
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		death.Global().Penalty("lua-gold", nil)
+		death.Global().SetPolicy(death.Recall)
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("runs penalties as players die", func() {
+		res, err := testReturn(engine, `
+			local killers = {}
+			death.penalty("lua-gold", function(player, killer)
+				table.insert(killers, killer)
+			end)
+			local policy = death.policy("here")
+			local killed = death.kill("ghost", "a wraith")
+			local _, why = death.kill("nobody")
+
+			return {
+				policy = policy,
+				room = death.respawn_room("ghost"),
+				killed = killed,
+				why = why,
+				killer = killers[1],
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("policy").AsString()).Should(Equal("here"))
+		Ω(res[0].Get("room").AsString()).Should(Equal("lua-arena"))
+		Ω(res[0].Get("killed").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("why").AsString()).Should(Equal("There's no such player."))
+		Ω(res[0].Get("killer").AsString()).Should(Equal("a wraith"))
+		Ω(ghost.stats["hp"]).Should(Equal(1))
+	})
+})
@@ -0,0 +1,120 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Dialogue lets scripts write the conditions and actions conversations name
+// and move players through them.
+//   condition(name, fn)
+//     @param name: string = the name used in a reply's "if"
+//     @param fn: function(player, speaker): boolean = given the id of the
+//       player and the name of the NPC, returns whether to offer the reply
+//     sets the condition, replacing any with the name.
+//   action(name, fn)
+//     @param name: string = the name used in a node's or reply's "do"
+//     @param fn: function(player, speaker) = run when the node is reached or
+//       the reply chosen
+//     sets the action, replacing any with the name.
+//   talk(player, npc): boolean, string
+//     starts the conversation of the NPC matching the keyword in the
+//     player's room, returning false and why if it didn't.
+//   reply(player, choice): boolean, string
+//     chooses the reply with the number or keyword for the player.
+//   stop(player): boolean
+//     ends the player's conversation, returning false if they weren't having
+//     one.
+//   state(player): table
+//     returns the tree, node and speaker of the player's conversation, nil
+//     if they aren't having one.
+var Dialogue = lua.TableMap{
+	"condition": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		dialogue.Global().Condition(name, func(t dialogue.Talker, speaker string) bool {
+			ret, err := fn.Call(1, t.ID(), speaker)
+			if err != nil {
+				log("dialogue").WithError(err).WithField("engine", nameForEngine(engine)).Error("Dialogue condition failed.")
+
+				return false
+			}
+
+			return len(ret) > 0 && ret[0].IsTrue()
+		})
+
+		return 0
+	},
+	"action": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		dialogue.Global().Action(name, func(t dialogue.Talker, speaker string) {
+			if _, err := fn.Call(0, t.ID(), speaker); err != nil {
+				log("dialogue").WithError(err).WithField("engine", nameForEngine(engine)).Error("Dialogue action failed.")
+			}
+		})
+
+		return 0
+	},
+	"talk": func(engine *lua.Engine) int {
+		npc := engine.PopString()
+		t := talker(engine.PopString())
+		if t == nil {
+			engine.PushValue(false)
+			engine.PushValue(dialogue.NoOneMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, dialogue.Global().Talk(t, npc))
+	},
+	"reply": func(engine *lua.Engine) int {
+		choice := engine.PopString()
+		t := talker(engine.PopString())
+		if t == nil {
+			engine.PushValue(false)
+			engine.PushValue(dialogue.NotTalkingMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, dialogue.Global().Reply(t, choice))
+	},
+	"stop": func(engine *lua.Engine) int {
+		t := talker(engine.PopString())
+		engine.PushValue(t != nil && dialogue.Global().End(t))
+
+		return 1
+	},
+	"state": func(engine *lua.Engine) int {
+		t := talker(engine.PopString())
+		if t == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		s, ok := t.Conversation()
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		st := engine.NewTable()
+		st.Set("tree", s.Tree)
+		st.Set("node", s.Node)
+		st.Set("speaker", s.Speaker)
+		engine.PushValue(st)
+
+		return 1
+	},
+}
+
+// talker returns the combatant with the id if they hold conversations
+func talker(id string) dialogue.Talker {
+	t, _ := combat.Global().Lookup(id).(dialogue.Talker)
+
+	return t
+}
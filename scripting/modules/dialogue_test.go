@@ -0,0 +1,96 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// gossip is a brawler who holds conversations
+type gossip struct {
+	brawler
+	conversation dialogue.State
+}
+
+func (g *gossip) Conversation() (dialogue.State, bool) {
+	return g.conversation, g.conversation != (dialogue.State{})
+}
+
+func (g *gossip) SetConversation(s dialogue.State) {
+	g.conversation = s
+}
+
+// oracle is an NPC to talk to
+type oracle struct{}
+
+func (oracle) Name() string {
+	return "the oracle"
+}
+
+func (oracle) Proto() string {
+	return "lua-oracle"
+}
+
+func (oracle) Matches(keyword string) bool {
+	return keyword == "oracle"
+}
+
+var _ = Describe("Dialogue Lua Module", func() {
+	var (
+		engine *lua.Engine
+		seeker *gossip
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "dialogue")
+		engine.DoString(`dialogue = require("dialogue")`)
+		seeker = &gossip{brawler: brawler{id: "seeker", stats: map[string]int{}}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "seeker" {
+				return seeker
+			}
+
+			return nil
+		})
+		dialogue.Global().SetNearby(func(string) []dialogue.Speaker {
+			return []dialogue.Speaker{oracle{}}
+		})
+		Ω(dialogue.Global().Trees().Add(dialogue.Tree{ID: "lua-oracle", NPC: "lua-oracle", Nodes: map[string]dialogue.Node{
+			"start": {Text: "Ask.", Choices: []dialogue.Choice{
+				{Text: "My fate?", If: "lua-worthy", Next: "fate"},
+				{Text: "Nothing."},
+			}},
+			"fate": {Text: "Glory.", Do: "lua-foretell", Choices: []dialogue.Choice{{Text: "Thanks."}}},
+		}})).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		dialogue.Global().SetNearby(nil)
+		engine.Close()
+	})
+
+	It("writes conditions and actions and moves players along", func() {
+		res, err := testReturn(engine, `
+			dialogue.condition("lua-worthy", function(player, speaker)
+				return player == "seeker"
+			end)
+			dialogue.action("lua-foretell", function(player, speaker)
+				foretold = speaker
+			end)
+			local talked = dialogue.talk("seeker", "oracle")
+			local replied = dialogue.reply("seeker", "fate")
+			local node = dialogue.state("seeker").node
+			local stopped = dialogue.stop("seeker")
+			return {talked, replied, node, foretold, stopped, dialogue.state("seeker") == nil, dialogue.stop("seeker")}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, true, "fate", "the oracle", true, true, false}))
+	})
+})
@@ -0,0 +1,163 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Effect lets scripts put buffs and debuffs on characters and write what they
+// do each effect pulse.
+//   apply(bearer, effect): boolean, string
+//     @param bearer: string = the id of the character
+//     @param effect: table = the effect's name, source, ticks, permanent,
+//       stats, stacking, max_stacks, wear_off message and data for its tick
+//       handler
+//     puts the effect on the character, returning false and why if it
+//     can't.
+//   dispel(bearer, name): boolean, string
+//     ends the character's effect early, returning false and why if it
+//     didn't.
+//   has(bearer, name): boolean
+//     returns whether the character has the effect.
+//   list(bearer): table
+//     returns the character's effects, each as the table apply takes plus
+//     its stacks.
+//   on_tick(name, fn)
+//     @param name: string = the effect's name
+//     @param fn: function(bearer, effect) = run every effect pulse with the
+//       id of each character with the effect and the effect as a table
+//     sets what the effect does each pulse, like poison hurting its bearer.
+var Effect = lua.TableMap{
+	"apply": func(engine *lua.Engine) int {
+		e := effectFromTable(engine.PopTable())
+		b := bearer(engine.PopString())
+		if b == nil {
+			engine.PushValue(false)
+			engine.PushValue(effect.NotAffectedMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, effect.Global().Apply(b, e))
+	},
+	"dispel": func(engine *lua.Engine) int {
+		name := engine.PopString()
+		b := bearer(engine.PopString())
+		if b == nil {
+			engine.PushValue(false)
+			engine.PushValue(effect.NotAffectedMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, effect.Global().Dispel(b, name))
+	},
+	"has": func(engine *lua.Engine) int {
+		name := engine.PopString()
+		b := bearer(engine.PopString())
+		has := false
+		if b != nil {
+			_, has = effect.Global().Get(b, name)
+		}
+		engine.PushValue(has)
+
+		return 1
+	},
+	"list": func(engine *lua.Engine) int {
+		t := engine.NewTable()
+		if b := bearer(engine.PopString()); b != nil {
+			for _, e := range b.Effects() {
+				t.Append(effectTable(engine, e))
+			}
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"on_tick": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		effect.Global().OnTick(name, func(b effect.Bearer, e effect.Effect) {
+			if _, err := fn.Call(0, b.ID(), effectTable(engine, e)); err != nil {
+				log("effect").WithError(err).WithField("engine", nameForEngine(engine)).Error("Effect tick handler failed.")
+			}
+		})
+
+		return 0
+	},
+}
+
+// bearer returns the combatant with the id if they can have effects
+func bearer(id string) effect.Bearer {
+	b, _ := combat.Global().Lookup(id).(effect.Bearer)
+
+	return b
+}
+
+// pushResult pushes true, or false and the message of the error
+func pushResult(engine *lua.Engine, err error) int {
+	if err == nil {
+		engine.PushValue(true)
+
+		return 1
+	}
+	engine.PushValue(false)
+	if r, ok := err.(*item.Refused); ok {
+		engine.PushValue(r.Message)
+	} else {
+		engine.PushValue(err.Error())
+	}
+
+	return 2
+}
+
+func effectFromTable(t *lua.Value) effect.Effect {
+	e := effect.Effect{
+		Name:      t.Get("name").AsString(),
+		Source:    t.Get("source").AsString(),
+		Ticks:     int(t.Get("ticks").AsNumber()),
+		Permanent: t.Get("permanent").IsTrue(),
+		Stacking:  t.Get("stacking").AsString(),
+		MaxStacks: int(t.Get("max_stacks").AsNumber()),
+		WearOff:   t.Get("wear_off").AsString(),
+	}
+	e.Stats = intMap(t.Get("stats"))
+	if data := t.Get("data"); data.IsTable() {
+		e.Data = data.AsMapStringInterface()
+	}
+
+	return e
+}
+
+func effectTable(engine *lua.Engine, e effect.Effect) *lua.Value {
+	t := engine.NewTable()
+	t.Set("name", e.Name)
+	t.Set("source", e.Source)
+	t.Set("ticks", e.Ticks)
+	t.Set("permanent", e.Permanent)
+	t.Set("stats", engine.TableFromMap(e.Stats))
+	t.Set("stacking", e.Stacking)
+	t.Set("stacks", e.Stacks)
+	t.Set("max_stacks", e.MaxStacks)
+	t.Set("wear_off", e.WearOff)
+	t.Set("data", engine.TableFromMap(e.Data))
+
+	return t
+}
+
+// intMap reads a table of numbers by name, nil if it isn't a table
+func intMap(t *lua.Value) map[string]int {
+	if !t.IsTable() {
+		return nil
+	}
+	m := make(map[string]int)
+	t.ForEach(func(k, v *lua.Value) {
+		m[k.AsString()] = int(v.AsNumber())
+	})
+
+	return m
+}
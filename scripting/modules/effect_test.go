@@ -0,0 +1,79 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// patient is a brawler who can be affected
+type patient struct {
+	brawler
+	effects effect.List
+}
+
+func (p *patient) Effects() effect.List {
+	return p.effects.Copy()
+}
+
+func (p *patient) UpdateEffects(fn func(effect.List) effect.List) {
+	p.effects = fn(p.effects.Copy())
+}
+
+var _ = Describe("Effect Lua Module", func() {
+	var (
+		engine *lua.Engine
+		ill    *patient
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "effect")
+		engine.DoString(`effect = require("effect")`)
+		ill = &patient{brawler: brawler{id: "ill", stats: map[string]int{"hp": 20}}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "ill" {
+				return ill
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("applies, ticks and dispels effects", func() {
+		res, err := testReturn(engine, `
+			effect.on_tick("lua-poison", function(bearer, e)
+				poisoned = bearer .. " " .. e.data.damage
+			end)
+			local ok = effect.apply("ill", {name = "lua-poison", ticks = 3, stats = {hp = -2}, data = {damage = 4}})
+			local _, why = effect.apply("nobody", {name = "lua-poison", ticks = 1})
+			local _, invalid = effect.apply("ill", {name = "lua-poison", ticks = 1, stacking = "twice"})
+			local listed = effect.list("ill")[1]
+			return {ok, why, invalid ~= nil, listed.name, listed.stacks, effect.has("ill", "lua-poison")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, effect.NotAffectedMessage, true, "lua-poison", float64(1), true}))
+		Ω(ill.stats["hp"]).Should(Equal(18))
+
+		effect.Global().Tick()
+		Ω(engine.GetGlobal("poisoned").AsString()).Should(Equal("ill 4"))
+
+		res, err = testReturn(engine, `
+			local ok = effect.dispel("ill", "lua-poison")
+			local _, why = effect.dispel("ill", "lua-poison")
+			return {ok, why, effect.has("ill", "lua-poison")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, effect.NotAffectedMessage, false}))
+		Ω(ill.stats["hp"]).Should(Equal(20))
+	})
+})
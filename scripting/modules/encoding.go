@@ -0,0 +1,152 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// EncodingLoader registers the encoding module along with the nested base64
+// and hex tables.
+func EncodingLoader(engine *lua.Engine) {
+	mod := engine.RegisterModule("encoding", Encoding)
+
+	b64 := engine.NewTable()
+	b64.Set("encode", encodeFunc(base64.StdEncoding.EncodeToString))
+	b64.Set("decode", decodeFunc(base64.StdEncoding.DecodeString))
+	b64.Set("url_encode", encodeFunc(base64.URLEncoding.EncodeToString))
+	b64.Set("url_decode", decodeFunc(base64.URLEncoding.DecodeString))
+	mod.RawSet("base64", b64)
+
+	hx := engine.NewTable()
+	hx.Set("encode", encodeFunc(hex.EncodeToString))
+	hx.Set("decode", decodeFunc(hex.DecodeString))
+	mod.RawSet("hex", hx)
+}
+
+// Encoding provides common encodings used when exchanging data with web
+// services or storing binary data in strings. Lua strings are byte strings so
+// any data can be encoded, decoding raises an error on malformed input.
+//   base64.encode(data): string
+//     @param data: string = the bytes to encode
+//     returns the standard base64 encoding of data.
+//   base64.decode(str): string
+//     @param str: string = standard base64 encoded data
+//     @errors raises an error if str is not valid base64
+//     returns the decoded bytes.
+//   base64.url_encode(data): string
+//     @param data: string = the bytes to encode
+//     returns the URL and file name safe base64 encoding of data.
+//   base64.url_decode(str): string
+//     @param str: string = URL safe base64 encoded data
+//     @errors raises an error if str is not valid base64
+//     returns the decoded bytes.
+//   hex.encode(data): string
+//     @param data: string = the bytes to encode
+//     returns the lowercase hexadecimal encoding of data.
+//   hex.decode(str): string
+//     @param str: string = hexadecimal encoded data
+//     @errors raises an error if str is not valid hex
+//     returns the decoded bytes.
+//   url_escape(str): string
+//     @param str: string = the value to escape
+//     returns str escaped so it can be safely placed in a URL query.
+//   url_unescape(str): string
+//     @param str: string = a URL query escaped value
+//     @errors raises an error if str contains invalid escapes
+//     returns the unescaped value.
+//   build_query(params): string
+//     @param params: table = map of keys to values to encode, values that are
+//       lists produce the key once for each value
+//     returns a URL query string (without a leading "?") sorted by key.
+//   parse_query(query): table
+//     @param query: string = a URL query string (without a leading "?")
+//     @errors raises an error if the query is malformed
+//     returns a map of keys to values, keys given more than once map to a
+//     list of their values.
+var Encoding = lua.TableMap{
+	"url_escape": func(str string) string {
+		return url.QueryEscape(str)
+	},
+	"url_unescape": func(engine *lua.Engine) int {
+		str, err := url.QueryUnescape(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(str)
+
+		return 1
+	},
+	"build_query": func(engine *lua.Engine) int {
+		params := engine.PopTable()
+
+		values := url.Values{}
+		params.ForEach(func(key, val *lua.Value) {
+			if val.IsTable() {
+				val.ForEach(func(_, item *lua.Value) {
+					values.Add(key.AsString(), item.AsString())
+				})
+			} else {
+				values.Add(key.AsString(), val.AsString())
+			}
+		})
+
+		engine.PushValue(values.Encode())
+
+		return 1
+	},
+	"parse_query": func(engine *lua.Engine) int {
+		values, err := url.ParseQuery(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		tbl := engine.NewTable()
+		for key, vals := range values {
+			if len(vals) == 1 {
+				tbl.Set(key, vals[0])
+			} else {
+				tbl.Set(key, engine.TableFromSlice(vals))
+			}
+		}
+
+		engine.PushValue(tbl)
+
+		return 1
+	},
+}
+
+// wrap an encoding function as a Lua function
+func encodeFunc(encode func([]byte) string) func(*lua.Engine) int {
+	return func(engine *lua.Engine) int {
+		engine.PushValue(encode([]byte(engine.PopString())))
+
+		return 1
+	}
+}
+
+// wrap a decoding function as a Lua function that raises errors for invalid
+// input
+func decodeFunc(decode func(string) ([]byte, error)) func(*lua.Engine) int {
+	return func(engine *lua.Engine) int {
+		bs, err := decode(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(string(bs))
+
+		return 1
+	}
+}
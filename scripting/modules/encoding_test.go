@@ -0,0 +1,64 @@
+package modules_test
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encoding Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "encoding")
+		engine.DoString(`encoding = require("encoding")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("encoding and decoding",
+		func(fn, input, expected string) {
+			res, err := testReturn(engine, fmt.Sprintf("return encoding.%s(%q)", fn, input))
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsString()).Should(Equal(expected))
+		},
+		Entry("base64.encode", "base64.encode", "dragon?", "ZHJhZ29uPw=="),
+		Entry("base64.decode", "base64.decode", "ZHJhZ29uPw==", "dragon?"),
+		Entry("base64.url_encode", "base64.url_encode", "dragon?", "ZHJhZ29uPw=="),
+		Entry("base64.url_decode", "base64.url_decode", "Pz8_", "???"),
+		Entry("hex.encode", "hex.encode", "mud", "6d7564"),
+		Entry("hex.decode", "hex.decode", "6d7564", "mud"),
+		Entry("url_escape", "url_escape", "a b&c", "a+b%26c"),
+		Entry("url_unescape", "url_unescape", "a+b%26c", "a b&c"))
+
+	DescribeTable("invalid input raises errors",
+		func(script string) {
+			err := engine.DoString(script)
+
+			Ω(err).ShouldNot(BeNil())
+		},
+		Entry("base64", `encoding.base64.decode("not base64!")`),
+		Entry("hex", `encoding.hex.decode("xyz")`),
+		Entry("url", `encoding.url_unescape("%zz")`))
+
+	It("builds and parses query strings", func() {
+		res, err := testReturn(engine, `
+			local q = encoding.build_query({name = "Bob Smith", tag = {"a", "b"}})
+			local parsed = encoding.parse_query(q)
+
+			return {q, parsed.name, parsed.tag[1], parsed.tag[2]}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"name=Bob+Smith&tag=a&tag=b", "Bob Smith", "a", "b"}))
+	})
+})
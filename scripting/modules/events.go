@@ -29,7 +29,9 @@ import (
 //     @param event: string = the event to associate the given handler to.
 //     @param handler: function = a function to execute if the event specified
 //       is emitted.
-//     registers the given function to handle the given event
+//     registers the given function to handle the given event, handlers of
+//     before: events like before:room:exit can stop what's about to happen
+//     by returning Halt or a message saying why
 //   once(event, handler: function)
 //     @param event: string = the event to associate the given handler to.
 //     @param handler: function = a function to execute if the event specified
@@ -207,11 +209,9 @@ type externalLuaHandler struct {
 }
 
 // Call will seek to emit the event to an engine within this pool's internal
-// emitter.
+// emitter, returning the error a handler returns so scripts can halt events.
 func (elh *externalLuaHandler) Call(d events.Data) error {
-	emitToPool(elh.pool, elh.event, d)
-
-	return nil
+	return emitToPool(elh.pool, elh.event, d)
 }
 
 // Source returns the pool assicaited with this external handler allowing only
@@ -235,10 +235,10 @@ func externalEmitterForEngine(eng *lua.Engine) *events.Emitter {
 
 // send the event to an engine within the pool using that engines internal
 // event emitter
-func emitToPool(p *lua.EnginePool, evt string, data events.Data) {
+func emitToPool(p *lua.EnginePool, evt string, data events.Data) error {
 	eng := p.Get()
 	defer eng.Release()
 	emitter := internalEmitterForEngine(eng.Engine)
-	done := emitter.Emit(evt, data)
-	<-done
+
+	return emitter.EmitSync(evt, data)
 }
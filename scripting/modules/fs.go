@@ -0,0 +1,206 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/fs"
+	"github.com/bbuck/dragon-mud/plugins"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/spf13/viper"
+)
+
+var sandboxNameRx = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// FS gives scripts a safe place to store flat files. Instead of opening up the
+// io and os libraries each plugin can request a sandboxed data directory that
+// all file operations are restricted to. Sandboxes are stored in the data
+// directory of the project (scripting.fs.root, relative to the project root
+// unless absolute) and limited to a maximum number of bytes
+// (scripting.fs.quota).
+//   sandbox([name]): fs.Sandbox
+//     @param name: string = the name of the calling plugin = the name of the
+//       sandbox, made up of only letters, numbers, dashes and underscores.
+//       Plugins may only open the sandbox named after them, scripts of the
+//       game itself may open any sandbox but have to name it. Plugins are
+//       known by the files the game loaded their code from, code loaded with
+//       load or loadstring can't open sandboxes.
+//     @errors raises an error if the name is not valid, belongs to another
+//       plugin or the caller was loaded with load or loadstring
+//     returns a sandbox restricted to the data directory with the given name.
+//   fs.Sandbox
+//     all paths are relative to the sandbox, any path attempting to reach
+//     outside of the sandbox will raise an error.
+//     read(path): string
+//       @param path: string = the file to read
+//       returns the contents of the file or nil if it doesn't exist.
+//     write(path, contents)
+//       @param path: string = the file to write, parent directories are
+//         created as necessary
+//       @param contents: string = the new contents of the file
+//       @errors raises an error if the write would exceed the quota
+//       replaces the contents of the file.
+//     exists(path): boolean
+//       @param path: string = the file or directory to check for
+//       returns whether the file or directory exists.
+//     list([dir]): table
+//       @param dir: string = "." = the directory to list
+//       returns a sorted list of names in the directory, directories end
+//       with a "/".
+//     remove(path)
+//       @param path: string = the file or empty directory to remove
+//       deletes the file or directory.
+//     size(): number
+//       returns the number of bytes currently stored in the sandbox.
+//     quota(): number
+//       returns the maximum number of bytes the sandbox can hold, 0 means
+//       there is no limit.
+var FS = lua.TableMap{
+	"sandbox": func(engine *lua.Engine) int {
+		name := ""
+		if engine.StackSize() >= 1 {
+			name = engine.PopString()
+		}
+		chunks, ok := engine.CallerChunks()
+		if !ok {
+			engine.RaiseError("sandboxes can't be opened from code loaded with load or loadstring")
+
+			return 0
+		}
+		plugin := callingPlugin(chunks)
+		switch {
+		case plugin != "" && name == "":
+			name = plugin
+		case plugin != "" && name != plugin:
+			engine.ArgumentError(1, fmt.Sprintf("the %s plugin may only open its own sandbox", plugin))
+
+			return 0
+		}
+		if !sandboxNameRx.MatchString(name) {
+			engine.ArgumentError(1, "sandbox names may only contain letters, numbers, dashes and underscores")
+
+			return 0
+		}
+
+		root := viper.GetString("scripting.fs.root")
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(plugins.Root, root)
+		}
+		box := fs.NewSandbox(filepath.Join(root, name), viper.GetInt64("scripting.fs.quota"))
+		engine.PushValue(sandboxTable(engine, box))
+
+		return 1
+	},
+}
+
+// the plugin of the nearest caller that's part of one, empty if none are
+func callingPlugin(chunks []string) string {
+	for _, chunk := range chunks {
+		if plugin := pluginOf(chunk); plugin != "" {
+			return plugin
+		}
+	}
+
+	return ""
+}
+
+// the name of the plugin the script source is part of, empty if it's not
+// part of a plugin
+func pluginOf(source string) string {
+	if source == "" {
+		return ""
+	}
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(plugins.Root, source)
+	}
+	rel, err := filepath.Rel(plugins.PluginRoot, source)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	return strings.SplitN(rel, string(filepath.Separator), 2)[0]
+}
+
+// build the Lua table wrapping the sandbox methods
+func sandboxTable(engine *lua.Engine, box *fs.Sandbox) *lua.Value {
+	tbl := engine.NewTable()
+	tbl.Set("read", func(eng *lua.Engine) int {
+		contents, err := box.Read(eng.PopString())
+		if os.IsNotExist(err) {
+			eng.PushValue(eng.Nil())
+
+			return 1
+		}
+		if err != nil {
+			eng.RaiseError(err.Error())
+
+			return 0
+		}
+
+		eng.PushValue(string(contents))
+
+		return 1
+	})
+	tbl.Set("write", func(eng *lua.Engine) int {
+		contents := eng.PopString()
+		name := eng.PopString()
+		if err := box.Write(name, []byte(contents)); err != nil {
+			eng.RaiseError(err.Error())
+		}
+
+		return 0
+	})
+	tbl.Set("exists", func(eng *lua.Engine) int {
+		eng.PushValue(box.Exists(eng.PopString()))
+
+		return 1
+	})
+	tbl.Set("list", func(eng *lua.Engine) int {
+		dir := "."
+		if eng.StackSize() >= 1 {
+			dir = eng.PopString()
+		}
+
+		names, err := box.List(dir)
+		if err != nil {
+			eng.RaiseError(err.Error())
+
+			return 0
+		}
+
+		eng.PushValue(eng.TableFromSlice(names))
+
+		return 1
+	})
+	tbl.Set("remove", func(eng *lua.Engine) int {
+		if err := box.Remove(eng.PopString()); err != nil {
+			eng.RaiseError(err.Error())
+		}
+
+		return 0
+	})
+	tbl.Set("size", func(eng *lua.Engine) int {
+		size, err := box.Size()
+		if err != nil {
+			eng.RaiseError(err.Error())
+
+			return 0
+		}
+
+		eng.PushValue(size)
+
+		return 1
+	})
+	tbl.Set("quota", func(eng *lua.Engine) int {
+		eng.PushValue(box.Quota)
+
+		return 1
+	})
+
+	return tbl
+}
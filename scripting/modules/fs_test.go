@@ -0,0 +1,137 @@
+package modules_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/plugins"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FS Module", func() {
+	var (
+		e    *lua.Engine
+		root string
+		err  error
+	)
+
+	BeforeEach(func() {
+		root, err = ioutil.TempDir("", "fs_module")
+		Ω(err).Should(BeNil())
+		viper.Set("scripting.fs.root", root)
+		viper.Set("scripting.fs.quota", 32)
+
+		e = lua.NewEngine()
+		scripting.OpenLibs(e, "fs")
+		err = e.DoString(`
+			box = require("fs").sandbox("test_plugin")
+		`)
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		e.Close()
+		os.RemoveAll(root)
+	})
+
+	It("writes, reads and lists files", func() {
+		err = e.DoString(`
+			box.write("notes/first.txt", "hello")
+			contents = box.read("notes/first.txt")
+			missing = box.read("missing.txt")
+			listing = box.list("notes")
+		`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("contents").AsString()).Should(Equal("hello"))
+		Ω(e.GetGlobal("missing").IsNil()).Should(BeTrue())
+		Ω(e.GetGlobal("listing").AsSliceInterface()).Should(Equal([]interface{}{"first.txt"}))
+	})
+
+	It("does not allow escaping the sandbox", func() {
+		err = e.DoString(`box.read("../../etc/passwd")`)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("enforces the quota", func() {
+		err = e.DoString(`box.write("big.txt", string.rep("x", 64))`)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	Context("when called from a plugin", func() {
+		var (
+			pluginRoot string
+			script     string
+		)
+
+		BeforeEach(func() {
+			pluginRoot = plugins.PluginRoot
+			plugins.PluginRoot = filepath.Join(root, "plugins")
+			script = filepath.Join(plugins.PluginRoot, "other_plugin", "init.lua")
+			Ω(os.MkdirAll(filepath.Dir(script), 0755)).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			plugins.PluginRoot = pluginRoot
+		})
+
+		It("opens the plugin's own sandbox", func() {
+			Ω(ioutil.WriteFile(script, []byte(`
+				require("fs").sandbox().write("own.txt", "mine")
+				require("fs").sandbox("other_plugin").write("named.txt", "mine")
+			`), 0644)).Should(Succeed())
+			Ω(e.DoFile(script)).Should(Succeed())
+			Ω(filepath.Join(root, "other_plugin", "own.txt")).Should(BeAnExistingFile())
+			Ω(filepath.Join(root, "other_plugin", "named.txt")).Should(BeAnExistingFile())
+		})
+
+		It("does not open another plugin's sandbox", func() {
+			Ω(ioutil.WriteFile(script, []byte(`
+				require("fs").sandbox("test_plugin")
+			`), 0644)).Should(Succeed())
+			Ω(e.DoFile(script)).ShouldNot(Succeed())
+		})
+
+		It("does not open sandboxes from code loaded with loadstring", func() {
+			Ω(ioutil.WriteFile(script, []byte(`
+				loadstring([[require("fs").sandbox("test_plugin").write("pwn.txt", "x")]])()
+			`), 0644)).Should(Succeed())
+			err = e.DoFile(script)
+			Ω(err).Should(MatchError(ContainSubstring("load or loadstring")))
+			Ω(filepath.Join(root, "test_plugin", "pwn.txt")).ShouldNot(BeAnExistingFile())
+		})
+
+		It("does not open sandboxes from code loaded with load", func() {
+			Ω(ioutil.WriteFile(script, []byte(`
+				local sent = false
+				handler = load(function()
+					if sent then
+						return nil
+					end
+					sent = true
+
+					return [[require("fs").sandbox("test_plugin").write("pwn.txt", "x")]]
+				end, "`+filepath.Join(root, "game.lua")+`")
+			`), 0644)).Should(Succeed())
+			Ω(e.DoFile(script)).Should(Succeed())
+			err = e.DoString(`handler()`)
+			Ω(err).Should(MatchError(ContainSubstring("load or loadstring")))
+			Ω(filepath.Join(root, "test_plugin", "pwn.txt")).ShouldNot(BeAnExistingFile())
+		})
+	})
+
+	It("requires scripts outside of plugins to name the sandbox", func() {
+		err = e.DoString(`require("fs").sandbox()`)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("rejects invalid sandbox names", func() {
+		err = e.DoString(`require("fs").sandbox("../other")`)
+		Ω(err).ShouldNot(BeNil())
+	})
+})
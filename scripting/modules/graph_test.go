@@ -0,0 +1,57 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DB Lua Module graph", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		data.SetGraph(data.NewMemoryGraph())
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "db")
+		engine.DoString(`db = require("db")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("links, follows and unlinks relationships", func() {
+		res, err := testReturn(engine, `
+			db.link("Faction:guild", "ALLIED", "Faction:crown", {since = 12})
+			db.link("Faction:crown", "ALLIED", "Faction:church")
+			db.link("Faction:guild", "RIVAL", "Faction:thieves")
+			local edges = db.edges("Faction:guild", "ALLIED")
+			local inbound = db.inbound("Faction:church")
+			local path = db.path("Faction:guild", "Faction:church", "ALLIED")
+			db.unlink("Faction:crown", "ALLIED", "Faction:church")
+
+			return {
+				count = #db.edges("Faction:guild"),
+				to = edges[1].to,
+				since = edges[1].props.since,
+				from = inbound[1].from,
+				hops = #path,
+				middle = path[2],
+				gone = db.path("Faction:guild", "Faction:church") == nil,
+				bad = pcall(db.link, "guild", "ALLIED", "Faction:crown"),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("count").AsNumber()).Should(BeEquivalentTo(2))
+		Ω(res[0].Get("to").AsString()).Should(Equal("Faction:crown"))
+		Ω(res[0].Get("since").AsNumber()).Should(BeEquivalentTo(12))
+		Ω(res[0].Get("from").AsString()).Should(Equal("Faction:crown"))
+		Ω(res[0].Get("hops").AsNumber()).Should(BeEquivalentTo(3))
+		Ω(res[0].Get("middle").AsString()).Should(Equal("Faction:crown"))
+		Ω(res[0].Get("gone").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("bad").AsBool()).Should(BeFalse())
+	})
+})
@@ -0,0 +1,275 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/grid"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Grid provides geometry for areas laid out on a grid of coordinates, like a
+// wilderness where each coordinate is a room. Points are tables with x and y
+// fields ({x = 1, y = 2}) or lists ({1, 2}), y increases to the south.
+// Rectangles are tables with x, y, w and h fields where x and y are the top
+// left corner. Points returned from the module always have x and y fields.
+//   point(x, y): table
+//     returns a new point.
+//   key(point): string
+//     returns the point as "x,y", useful for keying rooms by coordinate.
+//   parse(key): table
+//     @param key: string = a key returned from grid.key
+//     returns the point for the key, or nil and an error message.
+//   distance(a, b[, metric]): number
+//     @param metric: string = "euclidean" = "euclidean" for a straight line,
+//       "manhattan" for cardinal moves only or "chebyshev" when diagonal moves
+//       cost the same as cardinal moves
+//     returns the distance between the points.
+//   neighbors(point[, diagonal]): table
+//     @param diagonal: boolean = false = include diagonal neighbors
+//     returns the adjacent points clockwise from north.
+//   in_rect(rect, point): boolean
+//     returns whether the point is inside the rectangle.
+//   rect_points(rect): table
+//     returns every point in the rectangle row by row.
+//   intersect(a, b): table
+//     returns the rectangle where a and b overlap, or nil if they don't.
+//   circle(center, radius): table
+//     returns every point within radius of the center.
+//   line(a, b): table
+//     returns the points on a line from a to b, including both.
+//   line_of_sight(a, b, blocked): boolean
+//     @param blocked: function(point): boolean = returns true if the point
+//       blocks sight, like a wall or mountain
+//     returns whether b can be seen from a, the ends themselves are never
+//     checked.
+//   visible(origin, radius, blocked): table
+//     @param blocked: function(point): boolean = as with line_of_sight
+//     returns every point within radius that can be seen from the origin.
+var Grid = lua.TableMap{
+	"point": func(engine *lua.Engine) int {
+		y := engine.PopInt()
+		x := engine.PopInt()
+		engine.PushValue(gridPointToLua(engine, grid.Pt(x, y)))
+
+		return 1
+	},
+	"key": func(engine *lua.Engine) int {
+		p, ok := popGridPoint(engine, 1)
+		if !ok {
+			return 0
+		}
+
+		engine.PushValue(p.String())
+
+		return 1
+	},
+	"parse": func(engine *lua.Engine) int {
+		p, err := grid.ParsePoint(engine.PopString())
+		if err != nil {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+
+		engine.PushValue(gridPointToLua(engine, p))
+
+		return 1
+	},
+	"distance": func(engine *lua.Engine) int {
+		metric := "euclidean"
+		if engine.StackSize() >= 3 {
+			metric = engine.PopString()
+		}
+		b, okb := popGridPoint(engine, 2)
+		a, oka := popGridPoint(engine, 1)
+		if !oka || !okb {
+			return 0
+		}
+
+		switch metric {
+		case "euclidean":
+			engine.PushValue(grid.Euclidean(a, b))
+		case "manhattan":
+			engine.PushValue(grid.Manhattan(a, b))
+		case "chebyshev":
+			engine.PushValue(grid.Chebyshev(a, b))
+		default:
+			engine.ArgumentError(3, "expected \"euclidean\", \"manhattan\" or \"chebyshev\"")
+
+			return 0
+		}
+
+		return 1
+	},
+	"neighbors": func(engine *lua.Engine) int {
+		diagonal := false
+		if engine.StackSize() >= 2 {
+			diagonal = engine.PopBool()
+		}
+		p, ok := popGridPoint(engine, 1)
+		if !ok {
+			return 0
+		}
+
+		engine.PushValue(gridPointsToLua(engine, grid.Neighbors(p, diagonal)))
+
+		return 1
+	},
+	"in_rect": func(engine *lua.Engine) int {
+		p, ok := popGridPoint(engine, 2)
+		r := popGridRect(engine)
+		if !ok {
+			return 0
+		}
+
+		engine.PushValue(r.Contains(p))
+
+		return 1
+	},
+	"rect_points": func(engine *lua.Engine) int {
+		engine.PushValue(gridPointsToLua(engine, popGridRect(engine).Points()))
+
+		return 1
+	},
+	"intersect": func(engine *lua.Engine) int {
+		b := popGridRect(engine)
+		a := popGridRect(engine)
+		i := a.Intersect(b)
+		if i.Empty() {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+
+		engine.PushValue(engine.TableFromMap(map[string]int{
+			"x": i.Min.X,
+			"y": i.Min.Y,
+			"w": i.Width(),
+			"h": i.Height(),
+		}))
+
+		return 1
+	},
+	"circle": func(engine *lua.Engine) int {
+		radius := engine.PopInt()
+		center, ok := popGridPoint(engine, 1)
+		if !ok {
+			return 0
+		}
+
+		engine.PushValue(gridPointsToLua(engine, grid.Circle(center, radius)))
+
+		return 1
+	},
+	"line": func(engine *lua.Engine) int {
+		b, okb := popGridPoint(engine, 2)
+		a, oka := popGridPoint(engine, 1)
+		if !oka || !okb {
+			return 0
+		}
+
+		engine.PushValue(gridPointsToLua(engine, grid.Line(a, b)))
+
+		return 1
+	},
+	"line_of_sight": func(engine *lua.Engine) int {
+		blocked := popGridBlocked(engine, 3)
+		b, okb := popGridPoint(engine, 2)
+		a, oka := popGridPoint(engine, 1)
+		if blocked == nil || !oka || !okb {
+			return 0
+		}
+
+		engine.PushValue(grid.LineOfSight(a, b, blocked))
+
+		return 1
+	},
+	"visible": func(engine *lua.Engine) int {
+		blocked := popGridBlocked(engine, 3)
+		radius := engine.PopInt()
+		origin, ok := popGridPoint(engine, 1)
+		if blocked == nil || !ok {
+			return 0
+		}
+
+		engine.PushValue(gridPointsToLua(engine, grid.Visible(origin, radius, blocked)))
+
+		return 1
+	},
+}
+
+// pop a point given as {x = 1, y = 2} or {1, 2}, raising an argument error if
+// the value isn't a point
+func popGridPoint(engine *lua.Engine, arg int) (grid.Point, bool) {
+	v := engine.PopValue()
+	if !v.IsTable() {
+		engine.ArgumentError(arg, "expected a point")
+
+		return grid.Point{}, false
+	}
+
+	x, y := v.Get("x"), v.Get("y")
+	if x.IsNil() && y.IsNil() {
+		x, y = v.RawGet(1), v.RawGet(2)
+	}
+	if !x.IsNumber() || !y.IsNumber() {
+		engine.ArgumentError(arg, "expected a point")
+
+		return grid.Point{}, false
+	}
+
+	return grid.Pt(int(x.AsNumber()), int(y.AsNumber())), true
+}
+
+// pop a rectangle given as {x = 0, y = 0, w = 10, h = 5}, missing fields
+// default to 0
+func popGridRect(engine *lua.Engine) grid.Rect {
+	v := engine.PopTable()
+
+	return grid.RectAt(
+		int(v.Get("x").AsNumber()),
+		int(v.Get("y").AsNumber()),
+		int(v.Get("w").AsNumber()),
+		int(v.Get("h").AsNumber()),
+	)
+}
+
+// pop the function deciding if a point blocks sight, errors raised by the
+// function are raised again so they aren't mistaken for clear sight
+func popGridBlocked(engine *lua.Engine, arg int) func(grid.Point) bool {
+	fn := engine.PopValue()
+	if !fn.IsFunction() {
+		engine.ArgumentError(arg, "expected a function")
+
+		return nil
+	}
+
+	return func(p grid.Point) bool {
+		ret, err := fn.Call(1, gridPointToLua(engine, p))
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return true
+		}
+
+		return len(ret) > 0 && ret[0].IsTrue()
+	}
+}
+
+func gridPointToLua(engine *lua.Engine, p grid.Point) *lua.Value {
+	tbl := engine.NewTable()
+	tbl.Set("x", p.X)
+	tbl.Set("y", p.Y)
+
+	return tbl
+}
+
+func gridPointsToLua(engine *lua.Engine, points []grid.Point) *lua.Value {
+	tbl := engine.NewTable()
+	for _, p := range points {
+		tbl.Append(gridPointToLua(engine, p))
+	}
+
+	return tbl
+}
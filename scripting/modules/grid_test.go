@@ -0,0 +1,75 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Grid Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "grid")
+		engine.DoString(`grid = require("grid")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("helpers",
+		func(script string, expected interface{}) {
+			res, err := testReturn(engine, "return "+script)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsRaw()).Should(Equal(expected))
+		},
+		Entry("key", `grid.key({3, -4})`, "3,-4"),
+		Entry("parse", `grid.key(grid.parse("3,-4"))`, "3,-4"),
+		Entry("distance", `grid.distance({x = 0, y = 0}, grid.point(3, 4))`, float64(5)),
+		Entry("manhattan distance", `grid.distance({0, 0}, {3, 4}, "manhattan")`, float64(7)),
+		Entry("neighbors", `#grid.neighbors({0, 0}, true)`, float64(8)),
+		Entry("in_rect", `grid.in_rect({x = 0, y = 0, w = 4, h = 3}, {3, 2})`, true),
+		Entry("outside rect", `grid.in_rect({x = 0, y = 0, w = 4, h = 3}, {4, 2})`, false),
+		Entry("rect_points", `#grid.rect_points({x = 1, y = 1, w = 2, h = 2})`, float64(4)),
+		Entry("intersect", `grid.intersect({x = 0, y = 0, w = 4, h = 4}, {x = 2, y = 2, w = 4, h = 4}).w`, float64(2)),
+		Entry("no intersection", `grid.intersect({x = 0, y = 0, w = 1, h = 1}, {x = 2, y = 2, w = 1, h = 1}) == nil`, true),
+		Entry("circle", `#grid.circle({0, 0}, 1)`, float64(5)),
+		Entry("line", `grid.line({0, 0}, {3, 1})[3].y`, float64(1)),
+	)
+
+	It("checks line of sight", func() {
+		res, err := testReturn(engine, `
+			local function wall(p)
+				return p.x == 2
+			end
+
+			local seen = {}
+			for _, p in ipairs(grid.visible({0, 0}, 3, wall)) do
+				seen[grid.key(p)] = true
+			end
+
+			return {
+				grid.line_of_sight({0, 0}, {4, 0}, wall),
+				grid.line_of_sight({0, 0}, {2, 0}, wall),
+				seen["2,0"] == true,
+				seen["3,0"] == true,
+			}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{false, true, true, false}))
+	})
+
+	It("reports invalid points", func() {
+		err := engine.DoString(`grid.key("north")`)
+
+		Ω(err).ShouldNot(BeNil())
+		Ω(err.Error()).Should(ContainSubstring("expected a point"))
+	})
+})
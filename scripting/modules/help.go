@@ -0,0 +1,70 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/help"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Help lets scripts write help articles and search them.
+//   add(article): boolean, string
+//     @param article: table = the article's name, keywords, level, related
+//       and body, as in help files
+//     adds the article, replacing any with its name, returning false and why
+//       if it doesn't make sense.
+//   get(topic): string
+//     returns the help shown for the topic, an article or a command, nil if
+//       there's none.
+//   search(words): table
+//     returns the names of the topics with every word in them.
+//   remove(name): boolean
+//     removes the article with the name, returning false if there's none.
+var Help = lua.TableMap{
+	"add": func(engine *lua.Engine) int {
+		t := engine.PopTable()
+		a := help.Article{
+			Name:  t.Get("name").AsString(),
+			Level: t.Get("level").AsString(),
+			Body:  t.Get("body").AsString(),
+		}
+		if keywords := t.Get("keywords"); keywords.IsTable() {
+			keywords.ForEach(func(_, k *lua.Value) {
+				a.Keywords = append(a.Keywords, k.AsString())
+			})
+		}
+		if related := t.Get("related"); related.IsTable() {
+			related.ForEach(func(_, r *lua.Value) {
+				a.Related = append(a.Related, r.AsString())
+			})
+		}
+
+		return pushResult(engine, help.Global().Index().Add(a))
+	},
+	"get": func(engine *lua.Engine) int {
+		text, ok := help.Global().Topic(engine.PopString(), command.Admin)
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(text)
+
+		return 1
+	},
+	"search": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromSlice(help.Global().Search(engine.PopString(), command.Admin)))
+
+		return 1
+	},
+	"remove": func(engine *lua.Engine) int {
+		removed, err := help.Global().Remove(engine.PopString())
+		if err != nil {
+			log("help").WithError(err).WithField("engine", nameForEngine(engine)).Error("Failed to save help articles.")
+		}
+		engine.PushValue(removed)
+
+		return 1
+	},
+}
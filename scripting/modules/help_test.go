@@ -0,0 +1,38 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/help"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Help Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "help")
+		engine.DoString(`help = require("help")`)
+	})
+
+	AfterEach(func() {
+		help.Global().Remove("lua-magic")
+		engine.Close()
+	})
+
+	It("adds, finds and removes articles", func() {
+		res, err := testReturn(engine, `
+			local ok = help.add({name = "lua-magic", keywords = {"lua-spells"}, body = "Spells cost mana."})
+			local bad, why = help.add({name = "lua-bad", level = "wizard"})
+			local text = help.get("lua-spells")
+			local found = help.search("mana spells")
+			local removed = help.remove("lua-magic")
+			return {ok, bad, why ~= nil, text, found[1], removed, help.get("lua-magic") == nil}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false, true, "Spells cost mana.", "lua-magic", true, true}))
+	})
+})
@@ -0,0 +1,99 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/scripting/keys"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/i18n"
+)
+
+// I18n provides translated game text from the message catalogs found in the
+// locales directories of the game and it's plugins. Each engine has it's own
+// locale, client engines should set it to the player's preferred locale.
+//   t(key[, vars]): string
+//     @param key: string = the dotted key of the message, like "combat.miss"
+//     @param vars: table = nil = values to replace {name} references with in
+//       the message, a "count" value also selects the plural form to use
+//     translates the key into the engine's current locale, falling back to
+//     the default locale. If no message exists the key is returned.
+//   translate(locale, key[, vars]): string
+//     @param locale: string = the locale to translate into
+//     @param key: string = the dotted key of the message
+//     @param vars: table = nil = values to replace {name} references with
+//     the same as t except the locale is provided instead of using the
+//     engine's locale, useful for sending messages to other players.
+//   has(key): boolean
+//     @param key: string = the dotted key of the message
+//     returns whether or not a message exists for the key in the current
+//     locale or the default locale.
+//   locale(): string
+//     returns the locale for this engine, the default locale unless it has
+//     been changed.
+//   set_locale(locale)
+//     @param locale: string = the locale to use, like "en" or "pt-BR"
+//     change the locale used by t for this engine.
+//   locales(): table
+//     returns a list of all locales that have messages loaded.
+var I18n = lua.TableMap{
+	"t": func(engine *lua.Engine) int {
+		vars := popTranslationVars(engine, 2)
+		key := engine.PopString()
+
+		engine.PushValue(i18n.Global().T(localeForEngine(engine), key, vars))
+
+		return 1
+	},
+	"translate": func(engine *lua.Engine) int {
+		vars := popTranslationVars(engine, 3)
+		key := engine.PopString()
+		locale := engine.PopString()
+
+		engine.PushValue(i18n.Global().T(locale, key, vars))
+
+		return 1
+	},
+	"has": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		engine.PushValue(i18n.Global().Has(localeForEngine(engine), key))
+
+		return 1
+	},
+	"locale": func(engine *lua.Engine) int {
+		engine.PushValue(localeForEngine(engine))
+
+		return 1
+	},
+	"set_locale": func(engine *lua.Engine) int {
+		engine.Meta[keys.Locale] = engine.PopString()
+
+		return 0
+	},
+	"locales": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromSlice(i18n.Global().Locales()))
+
+		return 1
+	},
+}
+
+// pop the optional vars table if the function was given argc arguments
+func popTranslationVars(engine *lua.Engine, argc int) map[string]interface{} {
+	if engine.StackSize() < argc {
+		return nil
+	}
+
+	if vars := engine.PopValue(); vars.IsTable() {
+		return vars.AsMapStringInterface()
+	}
+
+	return nil
+}
+
+// the locale set for the engine, or the catalogs default locale
+func localeForEngine(engine *lua.Engine) string {
+	if locale, ok := engine.Meta[keys.Locale].(string); ok && locale != "" {
+		return locale
+	}
+
+	return i18n.Global().DefaultLocale
+}
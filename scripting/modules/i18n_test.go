@@ -0,0 +1,55 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/i18n"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("I18n Module", func() {
+	var (
+		e   *lua.Engine
+		err error
+	)
+
+	i18n.Global().LoadYAML("en", []byte(`i18n_test: "Hello, {name}!"`))
+	i18n.Global().LoadYAML("es", []byte(`i18n_test: "¡Hola, {name}!"`))
+
+	BeforeEach(func() {
+		e = lua.NewEngine()
+		scripting.OpenLibs(e, "i18n")
+		err = e.DoString(`
+			local i18n = require("i18n")
+
+			default = i18n.t("i18n_test", {name = "World"})
+			other = i18n.translate("es", "i18n_test", {name = "Mundo"})
+			i18n.set_locale("es")
+			changed = i18n.t("i18n_test", {name = "Mundo"})
+			locale = i18n.locale()
+		`)
+	})
+
+	AfterEach(func() {
+		e.Close()
+	})
+
+	It("doesn't fail", func() {
+		Ω(err).Should(BeNil())
+	})
+
+	It("translates into the default locale", func() {
+		Ω(e.GetGlobal("default").AsString()).Should(Equal("Hello, World!"))
+	})
+
+	It("translates into a given locale", func() {
+		Ω(e.GetGlobal("other").AsString()).Should(Equal("¡Hola, Mundo!"))
+	})
+
+	It("translates into the engine's locale once set", func() {
+		Ω(e.GetGlobal("locale").AsString()).Should(Equal("es"))
+		Ω(e.GetGlobal("changed").AsString()).Should(Equal("¡Hola, Mundo!"))
+	})
+})
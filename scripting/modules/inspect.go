@@ -0,0 +1,47 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Inspect renders Lua values into readable strings for debugging. Unlike
+// tostring, tables are expanded with their keys sorted, nested tables are
+// indented and tables that contain themselves are rendered as <cycle> rather
+// than looping forever. Tables with an inspect function control how they are
+// rendered. To log an inspected value see log.inspect.
+//   to_string(value[, depth]): string
+//     @param value: any = the value to render
+//     @param depth: number = 0 = the number of levels of nested tables to
+//       expand, deeper tables are rendered as {...}, 0 means no limit
+//     returns a readable representation of value.
+//   print(value[, depth])
+//     @param value: any = the value to render
+//     @param depth: number = 0 = the number of levels of nested tables to
+//       expand, 0 means no limit
+//     writes the rendered value to standard out.
+var Inspect = lua.TableMap{
+	"to_string": func(engine *lua.Engine) int {
+		engine.PushValue(inspectValue(engine))
+
+		return 1
+	},
+	"print": func(engine *lua.Engine) int {
+		fmt.Println(inspectValue(engine))
+
+		return 0
+	},
+}
+
+// pop the value and optional depth from the stack and render it
+func inspectValue(engine *lua.Engine) string {
+	depth := 0
+	if engine.StackSize() >= 2 {
+		depth = engine.PopInt()
+	}
+
+	return engine.PopValue().InspectDepth("", depth)
+}
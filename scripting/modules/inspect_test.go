@@ -0,0 +1,49 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "inspect")
+		engine.DoString(`inspect = require("inspect")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("renders nested tables", func() {
+		res, err := testReturn(engine, `return inspect.to_string({name = "Bob", stats = {hp = 10}})`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsString()).Should(Equal("{\n  [\"name\"] = \"Bob\",\n  [\"stats\"] = {\n    [\"hp\"] = 10,\n  },\n}"))
+	})
+
+	It("handles cyclic tables", func() {
+		res, err := testReturn(engine, `
+			local room = {name = "Town"}
+			room.exits = {north = room}
+
+			return inspect.to_string(room)
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsString()).Should(ContainSubstring(`["north"] = <cycle>`))
+	})
+
+	It("limits depth", func() {
+		res, err := testReturn(engine, `return inspect.to_string({a = {b = {c = 1}}}, 2)`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsString()).Should(Equal("{\n  [\"a\"] = {\n    [\"b\"] = {...},\n  },\n}"))
+	})
+})
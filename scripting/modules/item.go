@@ -0,0 +1,245 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Item lets scripts make items and change them wherever they are, on the
+// floor of a room or carried by a player in the game. Items are given to
+// scripts as tables with their id, proto, name, description, type, weight,
+// value, count, capacity, keywords, flags, props and contents.
+//   spawn(room, proto[, count]): string
+//     leaves count (default 1) of the item defined with the id in the room,
+//     returning the new item's id or nil if there's no such definition.
+//   room(room): table
+//     returns the items lying in the room.
+//   inventory(name): table
+//     returns the items the player carries, nil if they aren't in the game.
+//   worn(name): table
+//     returns the items the player wears keyed by slot id, nil if they aren't
+//     in the game.
+//   set(id, key, value): boolean
+//     changes the name, description, type, weight, value or count of the
+//     item, any other key is kept in its props and nil removes it. Returns
+//     false if the item can't be found.
+//   set_flag(id, flag, on): boolean
+//     sets or clears the item's flag, returning false if it can't be found.
+//   destroy(id): boolean
+//     removes the item from the game, returning false if it can't be found.
+var Item = lua.TableMap{
+	"spawn": func(engine *lua.Engine) int {
+		count := 1
+		if engine.StackSize() >= 3 {
+			count = engine.PopInt()
+		}
+		proto := engine.PopString()
+		room := engine.PopString()
+		it, err := item.Create(world.Global(), proto, count)
+		if err != nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		item.GlobalFloor().Add(room, it)
+		engine.PushValue(it.ID)
+
+		return 1
+	},
+	"room": func(engine *lua.Engine) int {
+		engine.PushValue(itemsTable(engine, item.GlobalFloor().In(engine.PopString())))
+
+		return 1
+	},
+	"inventory": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(itemsTable(engine, p.Inventory()))
+
+		return 1
+	},
+	"worn": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		tbl := engine.NewTable()
+		for slot, it := range p.Equipment() {
+			tbl.Set(slot, itemTable(engine, it))
+		}
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		value := engine.PopValue()
+		key := engine.PopString()
+		id := engine.PopString()
+		engine.PushValue(updateItem(id, func(it *item.Item) {
+			setItemField(it, key, value)
+		}))
+
+		return 1
+	},
+	"set_flag": func(id, flag string, on bool) bool {
+		return updateItem(id, func(it *item.Item) {
+			var flags []string
+			for _, f := range it.Flags {
+				if !strings.EqualFold(f, flag) {
+					flags = append(flags, f)
+				}
+			}
+			if on {
+				flags = append(flags, strings.ToLower(flag))
+			}
+			it.Flags = flags
+		})
+	},
+	"destroy": func(id string) bool {
+		if item.GlobalFloor().RemoveItem(id) {
+			return true
+		}
+
+		remove := func(items item.List) (item.List, bool) {
+			return items.Remove(id)
+		}
+
+		return updateInventories(remove) || updateWorn(remove)
+	},
+}
+
+var errItemNotHere = errors.New("item not carried")
+
+// updateItem changes the item on the floor or carried by a player
+func updateItem(id string, fn func(*item.Item)) bool {
+	if item.GlobalFloor().UpdateItem(id, fn) {
+		return true
+	}
+
+	if updateInventories(func(items item.List) (item.List, bool) {
+		return items.Update(id, fn)
+	}) {
+		return true
+	}
+
+	return updateWorn(func(items item.List) (item.List, bool) {
+		return items.Update(id, fn)
+	})
+}
+
+// updateInventories changes the first player inventory fn finds what it's
+// looking for in
+func updateInventories(fn func(item.List) (item.List, bool)) bool {
+	for _, p := range player.Global().Players() {
+		err := p.UpdateInventory(func(items item.List) (item.List, error) {
+			items, ok := fn(items)
+			if !ok {
+				return items, errItemNotHere
+			}
+
+			return items, nil
+		})
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateWorn changes what a player wears like updateInventories, an item fn
+// removes is no longer worn
+func updateWorn(fn func(item.List) (item.List, bool)) bool {
+	for _, p := range player.Global().Players() {
+		err := p.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+			for slot, it := range eq {
+				worn, ok := fn(item.List{it})
+				if !ok {
+					continue
+				}
+				if len(worn) == 0 {
+					delete(eq, slot)
+				} else {
+					eq[slot] = worn[0]
+				}
+
+				return inv, eq, nil
+			}
+
+			return inv, eq, errItemNotHere
+		})
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setItemField(it *item.Item, key string, value *lua.Value) {
+	switch key {
+	case "name":
+		it.Name = value.AsString()
+	case "description":
+		it.Description = value.AsString()
+	case "type":
+		it.Type = value.AsString()
+	case "weight":
+		it.Weight = int(value.AsNumber())
+	case "value":
+		it.Value = int(value.AsNumber())
+	case "count":
+		it.Count = int(value.AsNumber())
+	default:
+		if value.IsNil() {
+			delete(it.Props, key)
+
+			return
+		}
+		if it.Props == nil {
+			it.Props = make(map[string]interface{})
+		}
+		it.Props[key] = value.AsRaw()
+	}
+}
+
+func itemsTable(engine *lua.Engine, items item.List) *lua.Value {
+	list := engine.NewTable()
+	for _, it := range items {
+		list.Append(itemTable(engine, it))
+	}
+
+	return list
+}
+
+func itemTable(engine *lua.Engine, it item.Item) *lua.Value {
+	tbl := engine.NewTable()
+	tbl.Set("id", it.ID)
+	tbl.Set("proto", it.Proto)
+	tbl.Set("name", it.Name)
+	tbl.Set("description", it.Description)
+	tbl.Set("type", it.Type)
+	tbl.Set("weight", it.Weight)
+	tbl.Set("value", it.Value)
+	tbl.Set("count", it.Quantity())
+	tbl.Set("capacity", it.Capacity)
+	tbl.Set("keywords", engine.TableFromSlice(it.Keywords))
+	tbl.Set("flags", engine.TableFromSlice(it.Flags))
+	tbl.Set("props", engine.TableFromMap(it.Props))
+	tbl.Set("contents", itemsTable(engine, it.Contents))
+
+	return tbl
+}
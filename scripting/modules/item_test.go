@@ -0,0 +1,87 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Item Lua Module", func() {
+	var (
+		engine *lua.Engine
+		p      *player.Player
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "item")
+		engine.DoString(`item = require("item")`)
+		world.Global().AddZone(world.Zone{ID: "luatest", Name: "Lua Test"})
+		world.Global().SetItem(world.ItemDef{ID: "lua-coin", Zone: "luatest", Name: "copper coins", Type: "money"})
+		p = player.New(player.Record{Name: "Itemtester"})
+		player.Global().Add(p)
+		item.GlobalFloor().Clear("lua-room")
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("spawns items and lists them", func() {
+		res, err := testReturn(engine, `
+			local id = item.spawn("lua-room", "lua-coin", 5)
+			local missing = item.spawn("lua-room", "nothing")
+			local items = item.room("lua-room")
+			return {items[1].id == id, items[1].count, items[1].name, missing == nil}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, float64(5), "copper coins", true}))
+	})
+
+	It("changes and destroys items wherever they are", func() {
+		coins, err := item.Create(world.Global(), "lua-coin", 2)
+		Ω(err).ShouldNot(HaveOccurred())
+		p.AddItem(coins)
+		engine.SetGlobal("id", coins.ID)
+
+		res, err := testReturn(engine, `
+			local named = item.set(id, "name", "shiny coins")
+			item.set(id, "minted", "royal")
+			item.set_flag(id, "cursed", true)
+			return {named, item.inventory("itemtester")[1].props.minted, item.set("nothing", "name", "x")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "royal", false}))
+		Ω(p.Inventory()[0].Name).Should(Equal("shiny coins"))
+		Ω(p.Inventory()[0].Flag("cursed")).Should(BeTrue())
+
+		Ω(engine.DoString(`destroyed = item.destroy(id)`)).Should(Succeed())
+		Ω(engine.GetGlobal("destroyed").AsBool()).Should(BeTrue())
+		Ω(p.Inventory()).Should(BeEmpty())
+	})
+
+	It("lists and changes what players wear", func() {
+		helm, err := item.Create(world.Global(), "lua-coin", 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		p.UpdateEquipment(func(inv item.List, eq map[string]item.Item) (item.List, map[string]item.Item, error) {
+			eq["head"] = helm
+
+			return inv, eq, nil
+		})
+		engine.SetGlobal("id", helm.ID)
+
+		res, err := testReturn(engine, `
+			local named = item.set(id, "name", "a coin hat")
+			return {named, item.worn("itemtester").head.name, item.destroy(id)}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "a coin hat", true}))
+		Ω(p.Equipment()).Should(BeEmpty())
+	})
+})
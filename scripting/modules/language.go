@@ -0,0 +1,122 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/language"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Language lets scripts teach languages and grant magical comprehension.
+//   proficiency(player, language): number
+//     returns how well the player understands the language as a percent,
+//     0 if there's no such player or language.
+//   learn(player, language, percent): boolean, string
+//     changes how well the player has learned the language, returning false
+//     and why if they can't learn it.
+//   speak(player, language): boolean, string
+//     switches the language the player speaks in, returning false and why
+//     if they can't speak it.
+//   say(player, message): boolean, string
+//     says the message for the player in the language they speak, returning
+//     false and why if it wasn't said.
+//   garble(language, message, proficiency): string
+//     returns what someone with the proficiency hears of the message said
+//     in the language, the message as it is if there's no such language.
+//   comprehend(name, fn)
+//     @param fn: function(player, language): number = given the id of the
+//       player and the language, returns how well they understand it
+//       besides what they've learned, as a percent
+//     sets the comprehension hook, replacing any with the name.
+//   uncomprehend(name)
+//     removes the comprehension hook with the name.
+var Language = lua.TableMap{
+	"proficiency": func(id, lang string) int {
+		k, ok := combat.Global().Lookup(id).(language.Knower)
+		if !ok {
+			return 0
+		}
+
+		return language.Global().Proficiency(k, lang)
+	},
+	"learn": func(engine *lua.Engine) int {
+		percent := engine.PopInt()
+		lang := engine.PopString()
+		k, ok := combat.Global().Lookup(engine.PopString()).(language.Knower)
+		if !ok {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+
+		return pushResult(engine, language.Global().Learn(k, lang, percent))
+	},
+	"speak": func(engine *lua.Engine) int {
+		lang := engine.PopString()
+		s := speaker(engine.PopString())
+		if s == nil {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+		_, err := language.Global().Speak(s, lang)
+
+		return pushResult(engine, err)
+	},
+	"say": func(engine *lua.Engine) int {
+		message := engine.PopString()
+		s := speaker(engine.PopString())
+		if s == nil {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+
+		return pushResult(engine, language.Global().Say(s, message))
+	},
+	"garble": func(lang, message string, proficiency int) string {
+		def, ok := language.Global().Defs().Get(lang)
+		if !ok {
+			return message
+		}
+
+		return def.Garble(message, proficiency)
+	},
+	"comprehend": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		language.Global().Comprehend(name, func(k language.Knower, lang string) int {
+			id := k.Name()
+			if c, ok := k.(combat.Combatant); ok {
+				id = c.ID()
+			}
+			ret, err := fn.Call(1, id, lang)
+			if err != nil {
+				log("language").WithError(err).WithField("hook", name).WithField("engine", nameForEngine(engine)).Error("Comprehension hook failed.")
+
+				return 0
+			}
+			if len(ret) == 0 || ret[0].IsNil() {
+				return 0
+			}
+
+			return int(ret[0].AsNumber())
+		})
+
+		return 0
+	},
+	"uncomprehend": func(name string) {
+		language.Global().Comprehend(name, nil)
+	},
+}
+
+// speaker returns the combatant with the id if they speak like players
+func speaker(id string) language.Speaker {
+	s, _ := combat.Global().Lookup(id).(language.Speaker)
+
+	return s
+}
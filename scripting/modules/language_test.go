@@ -0,0 +1,119 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/language"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// linguist is a brawler who speaks languages like a player
+type linguist struct {
+	brawler
+	race      string
+	languages map[string]int
+	speaking  string
+	sent      []string
+}
+
+func (l *linguist) Race() string {
+	return l.race
+}
+
+func (l *linguist) Language(id string) int {
+	return l.languages[id]
+}
+
+func (l *linguist) SetLanguage(id string, percent int) {
+	l.languages[id] = percent
+}
+
+func (l *linguist) Speaking() string {
+	return l.speaking
+}
+
+func (l *linguist) SetSpeaking(id string) {
+	l.speaking = id
+}
+
+func (l *linguist) Send(text string) error {
+	l.sent = append(l.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Language Lua Module", func() {
+	var (
+		engine *lua.Engine
+		sage   *linguist
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "language")
+		engine.DoString(`language = require("language")`)
+		for _, def := range language.Defaults() {
+			language.Global().Defs().Add(def)
+		}
+		sage = &linguist{brawler: brawler{id: "sage", stats: map[string]int{}}, race: "human", languages: map[string]int{}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "sage" {
+				return sage
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		language.Global().Comprehend("lua-tongues", nil)
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("teaches languages and grants comprehension", func() {
+		res, err := testReturn(engine, `
+			local before = language.proficiency("sage", "orcish")
+			local spoke, why = language.speak("sage", "orcish")
+			language.comprehend("lua-tongues", function(player, lang)
+				if player == "sage" and lang == "orcish" then
+					return 60
+				end
+			end)
+			local magic = language.proficiency("sage", "orcish")
+			language.uncomprehend("lua-tongues")
+			local learned = language.learn("sage", "elvish", 30)
+			local _, missing = language.learn("nobody", "elvish", 30)
+
+			return {
+				before = before,
+				spoke = spoke,
+				why = why,
+				magic = magic,
+				after = language.proficiency("sage", "orcish"),
+				learned = learned,
+				elvish = language.proficiency("sage", "elvish"),
+				missing = missing,
+				speaks = language.speak("sage", "elvish"),
+				fluent = language.garble("elvish", "hello there", 100),
+				unknown = language.garble("klingon", "hello there", 0),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("before").AsNumber()).Should(BeEquivalentTo(0))
+		Ω(res[0].Get("spoke").AsBool()).Should(BeFalse())
+		Ω(res[0].Get("why").AsString()).Should(Equal("You don't speak Orcish."))
+		Ω(res[0].Get("magic").AsNumber()).Should(BeEquivalentTo(60))
+		Ω(res[0].Get("after").AsNumber()).Should(BeEquivalentTo(0))
+		Ω(res[0].Get("learned").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("elvish").AsNumber()).Should(BeEquivalentTo(30))
+		Ω(res[0].Get("missing").AsString()).Should(Equal("There's no such player."))
+		Ω(res[0].Get("speaks").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("fluent").AsString()).Should(Equal("hello there"))
+		Ω(res[0].Get("unknown").AsString()).Should(Equal("hello there"))
+		Ω(sage.speaking).Should(Equal("elvish"))
+	})
+})
@@ -0,0 +1,88 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/lock"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Lock lets scripts work doors and containers, for puzzles with locks of
+// their own. Handlers of the before:door:* events can stop players opening,
+// closing, locking, unlocking, picking or bashing them.
+//   door(room, direction): string
+//     returns the state of the door, "open", "closed" or "locked", or nil if
+//     the exit has no door.
+//   set_door(room, direction, state): boolean, string
+//     @param state: string = "open", "closed" or "locked"
+//     leaves the door, and the one on the other side, in the state without
+//     needing a key, returning false and why if it can't.
+//   open(player, target): boolean, string
+//   close(player, target): boolean, string
+//   lock(player, target): boolean, string
+//   unlock(player, target): boolean, string
+//   pick(player, target): boolean, string
+//   bash(player, target): boolean, string
+//     @param target: string = a direction, "door" or a container's keyword
+//     has the player act on the door or container as if they typed the
+//     command, returning false and why if they couldn't.
+var Lock = lua.TableMap{
+	"door": func(engine *lua.Engine) int {
+		d := world.ParseDirection(engine.PopString())
+		e, err := world.Global().Exit(engine.PopString(), d)
+		if err != nil || !e.Door {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		state := world.DoorOpen
+		switch {
+		case e.Locked:
+			state = world.DoorLocked
+		case e.Closed:
+			state = world.DoorClosed
+		}
+		engine.PushValue(state)
+
+		return 1
+	},
+	"set_door": func(engine *lua.Engine) int {
+		state := engine.PopString()
+		d := world.ParseDirection(engine.PopString())
+		room := engine.PopString()
+		if state != world.DoorOpen && state != world.DoorClosed && state != world.DoorLocked {
+			engine.PushValue(false)
+			engine.PushValue("The state is one of open, closed or locked.")
+
+			return 2
+		}
+
+		return pushResult(engine, world.Global().SetDoor(room, d, state != world.DoorOpen, state == world.DoorLocked))
+	},
+	"open":   lockAction((*lock.Manager).Open),
+	"close":  lockAction((*lock.Manager).Close),
+	"lock":   lockAction((*lock.Manager).Lock),
+	"unlock": lockAction((*lock.Manager).Unlock),
+	"pick":   lockAction((*lock.Manager).Pick),
+	"bash":   lockAction((*lock.Manager).Bash),
+}
+
+// lockAction has the player given to the function act on their target with
+// the global lock manager
+func lockAction(fn func(*lock.Manager, lock.Opener, string) (lock.Target, error)) func(*lua.Engine) int {
+	return func(engine *lua.Engine) int {
+		target := engine.PopString()
+		o, ok := combat.Global().Lookup(engine.PopString()).(lock.Opener)
+		if !ok {
+			engine.PushValue(false)
+			engine.PushValue("There's no such player.")
+
+			return 2
+		}
+		_, err := fn(lock.Global(), o, target)
+
+		return pushResult(engine, err)
+	}
+}
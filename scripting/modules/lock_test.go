@@ -0,0 +1,58 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "lock")
+		engine.DoString(`lock = require("lock")`)
+		world.Global().AddZone(world.Zone{ID: "lua-gaol", Name: "The Gaol"})
+		world.Global().AddRoom(world.Room{ID: "lua-yard", Zone: "lua-gaol", Name: "The Yard"})
+		world.Global().AddRoom(world.Room{ID: "lua-cell", Zone: "lua-gaol", Name: "A Cell"})
+		world.Global().Link("lua-yard", world.East, "lua-cell")
+		world.Global().UpdateExit("lua-yard", world.East, func(e *world.Exit) {
+			e.Door = true
+		})
+		world.Global().UpdateExit("lua-cell", world.West, func(e *world.Exit) {
+			e.Door = true
+		})
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("sets doors for puzzles", func() {
+		res, err := testReturn(engine, `
+			local locked = lock.set_door("lua-yard", "e", "locked")
+			local _, why = lock.set_door("lua-yard", "east", "ajar")
+			local _, who = lock.open("nobody", "east")
+
+			return {
+				locked = locked,
+				why = why,
+				who = who,
+				yard = lock.door("lua-yard", "east"),
+				cell = lock.door("lua-cell", "west"),
+				none = lock.door("lua-yard", "north") == nil,
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("locked").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("why").AsString()).Should(Equal("The state is one of open, closed or locked."))
+		Ω(res[0].Get("who").AsString()).Should(Equal("There's no such player."))
+		Ω(res[0].Get("yard").AsString()).Should(Equal(world.DoorLocked))
+		Ω(res[0].Get("cell").AsString()).Should(Equal(world.DoorLocked))
+		Ω(res[0].Get("none").AsBool()).Should(BeTrue())
+	})
+})
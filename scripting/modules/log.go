@@ -31,37 +31,119 @@ import (
 //     @param data: table = associated data to log with the message, if any
 //       additional data is required.
 //     log message with data on the debug level, data can be omitted or nil
+//   fatal(msg[, data])
+//     @param msg: string = the message to log according the log configuraiton
+//       provided for the application
+//     @param data: table = associated data to log with the message, if any
+//       additional data is required.
+//     log message with data on the error level, marked as fatal, and then
+//     raise the message as an error to stop the script. The server keeps
+//     running.
+//   inspect(value[, label])
+//     @param value: any = the value to inspect, see the inspect module for
+//       details on how values are rendered
+//     @param label: string = "inspect" = a message to log alongside the value
+//     log the inspected value on the debug level under the "value" field
+//   with(fields): table
+//     @param fields: table = data to include with every message
+//     returns a child logger with the same logging functions (including
+//     with) that adds the fields to each message. Child loggers can be called
+//     with either : or . (child:info("msg")).
+//   set_level([level])
+//     @param level: string = "debug", "info", "warn", "error" or "fatal", nil
+//       resets to the server's level
+//     sets the most detailed level logged by this engine, messages more
+//     detailed than the server's level are never logged.
+//   level(): string
+//     returns the name of the most detailed level logged by this engine.
+//   tail(source[, n]): table
+//     @param source: string = the source of the entries, such as the name of
+//       a plugin
+//     @param n: number = 0 = the most entries to return, 0 returns every entry
+//       kept
+//     returns the latest entries logged from the source, oldest first. Each
+//     entry has time (unix seconds), level, message and fields.
+//   sources(): table
+//     returns the sorted names of the sources tail has entries for.
 var Log = lua.TableMap{
-	"error": func(eng *lua.Engine) int {
-		performLog(eng, func(l logger.Log, msg string) {
-			l.Error(msg)
-		})
+	"error":   logFunc(nil, nil, logger.ErrorLevel),
+	"warn":    logFunc(nil, nil, logger.WarnLevel),
+	"info":    logFunc(nil, nil, logger.InfoLevel),
+	"debug":   logFunc(nil, nil, logger.DebugLevel),
+	"fatal":   logFunc(nil, nil, logger.FatalLevel),
+	"inspect": inspectFunc(nil, nil),
+	"with":    withFunc(nil, nil),
+	"set_level": func(eng *lua.Engine) int {
+		if eng.StackSize() == 0 {
+			delete(eng.Meta, keys.LogLevel)
+
+			return 0
+		}
+
+		val := eng.PopValue()
+		if val.IsNil() {
+			delete(eng.Meta, keys.LogLevel)
+
+			return 0
+		}
+
+		lvl, err := logger.ParseLogLevel(val.AsString())
+		if err != nil {
+			eng.ArgumentError(1, err.Error())
+
+			return 0
+		}
+		eng.Meta[keys.LogLevel] = lvl
 
 		return 0
 	},
-	"warn": func(eng *lua.Engine) int {
-		performLog(eng, func(l logger.Log, msg string) {
-			l.Warn(msg)
-		})
+	"level": func(eng *lua.Engine) int {
+		eng.PushValue(engineLogLevel(eng).String())
 
-		return 0
+		return 1
 	},
-	"info": func(eng *lua.Engine) int {
-		performLog(eng, func(l logger.Log, msg string) {
-			l.Info(msg)
-		})
+	"tail": func(eng *lua.Engine) int {
+		var n int
+		if eng.StackSize() >= 2 {
+			n = eng.PopInt()
+		}
+		source := eng.PopString()
 
-		return 0
+		tbl := eng.NewTable()
+		for _, e := range logger.Tail(source, n) {
+			tbl.Append(logEntryToLua(eng, e))
+		}
+		eng.PushValue(tbl)
+
+		return 1
 	},
-	"debug": func(eng *lua.Engine) int {
-		performLog(eng, func(l logger.Log, msg string) {
-			l.Debug(msg)
-		})
+	"sources": func(eng *lua.Engine) int {
+		eng.PushValue(serializedToLua(eng, logger.TailSources()))
 
-		return 0
+		return 1
 	},
 }
 
+// build the table for an entry returned by tail, errors in the fields are
+// converted to their messages
+func logEntryToLua(eng *lua.Engine, e *logger.Entry) *lua.Value {
+	fields := make(map[string]interface{}, len(e.Fields))
+	for k, v := range e.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+
+	tbl := eng.NewTable()
+	tbl.Set("time", unixSeconds(e.Time))
+	tbl.Set("level", e.Level.String())
+	tbl.Set("message", e.Message)
+	tbl.Set("fields", serializedToLua(eng, fields))
+
+	return tbl
+}
+
 func loggerForEngine(eng *lua.Engine) logger.Log {
 	if log, ok := eng.Meta[keys.Logger].(logger.Log); ok {
 		return log
@@ -78,19 +160,128 @@ func loggerForEngine(eng *lua.Engine) logger.Log {
 	return l
 }
 
-func performLog(eng *lua.Engine, fn func(logger.Log, string)) {
-	data := eng.Nil()
-	if eng.StackSize() >= 2 {
-		data = eng.PopTable()
+// the level set for the engine with set_level, or the level of its logger
+func engineLogLevel(eng *lua.Engine) logger.LogLevel {
+	if lvl, ok := eng.Meta[keys.LogLevel].(logger.LogLevel); ok {
+		return lvl
+	}
+
+	return loggerForEngine(eng).Level()
+}
+
+// logArgs pops all arguments in order, dropping the first if it's the child
+// logger table (the function was called with a colon).
+func logArgs(eng *lua.Engine, self *lua.Value) []*lua.Value {
+	args := make([]*lua.Value, eng.StackSize())
+	for i := len(args) - 1; i >= 0; i-- {
+		args[i] = eng.PopValue()
 	}
-	msg := eng.PopString()
 
+	if self != nil && len(args) > 0 && args[0].Equals(self) {
+		args = args[1:]
+	}
+
+	return args
+}
+
+// the engine's logger with the child logger's fields, if any
+func fieldLogger(eng *lua.Engine, fields logger.Fields) logger.Log {
 	log := loggerForEngine(eng)
+	if len(fields) > 0 {
+		log = log.WithFields(fields)
+	}
+
+	return log
+}
+
+// logFunc builds a function logging at the level, self and fields are nil for
+// the module itself and set for child loggers.
+func logFunc(self *lua.Value, fields logger.Fields, lvl logger.LogLevel) func(*lua.Engine) int {
+	return func(eng *lua.Engine) int {
+		args := logArgs(eng, self)
+
+		var msg string
+		if len(args) > 0 {
+			msg = args[0].AsString()
+		}
+
+		if lvl <= engineLogLevel(eng) {
+			log := fieldLogger(eng, fields)
+			if len(args) > 1 && args[1].IsTable() {
+				log = log.WithFields(logger.Fields(args[1].AsMapStringInterface()))
+			}
 
-	if !data.IsNil() && data.IsTable() {
-		m := data.AsMapStringInterface()
-		log = log.WithFields(logger.Fields(m))
+			switch lvl {
+			case logger.ErrorLevel:
+				log.Error(msg)
+			case logger.WarnLevel:
+				log.Warn(msg)
+			case logger.InfoLevel:
+				log.Info(msg)
+			case logger.DebugLevel:
+				log.Debug(msg)
+			case logger.FatalLevel:
+				// a script failing shouldn't take the whole server down
+				log.WithField("fatal", true).Error(msg)
+			}
+		}
+
+		if lvl == logger.FatalLevel {
+			eng.RaiseError("%s", msg)
+		}
+
+		return 0
 	}
+}
+
+func inspectFunc(self *lua.Value, fields logger.Fields) func(*lua.Engine) int {
+	return func(eng *lua.Engine) int {
+		args := logArgs(eng, self)
+		if len(args) == 0 || logger.DebugLevel > engineLogLevel(eng) {
+			return 0
+		}
+
+		label := "inspect"
+		if len(args) >= 2 {
+			label = args[1].AsString()
+		}
+
+		fieldLogger(eng, fields).WithField("value", args[0].Inspect("")).Debug(label)
+
+		return 0
+	}
+}
+
+func withFunc(self *lua.Value, fields logger.Fields) func(*lua.Engine) int {
+	return func(eng *lua.Engine) int {
+		args := logArgs(eng, self)
+
+		childFields := make(logger.Fields, len(fields))
+		for k, v := range fields {
+			childFields[k] = v
+		}
+		if len(args) > 0 && args[0].IsTable() {
+			for k, v := range args[0].AsMapStringInterface() {
+				childFields[k] = v
+			}
+		}
+
+		eng.PushValue(childLogger(eng, childFields))
+
+		return 1
+	}
+}
+
+// build a child logger table whose functions log with the fields
+func childLogger(eng *lua.Engine, fields logger.Fields) *lua.Value {
+	tbl := eng.NewTable()
+	tbl.Set("error", logFunc(tbl, fields, logger.ErrorLevel))
+	tbl.Set("warn", logFunc(tbl, fields, logger.WarnLevel))
+	tbl.Set("info", logFunc(tbl, fields, logger.InfoLevel))
+	tbl.Set("debug", logFunc(tbl, fields, logger.DebugLevel))
+	tbl.Set("fatal", logFunc(tbl, fields, logger.FatalLevel))
+	tbl.Set("inspect", inspectFunc(tbl, fields))
+	tbl.Set("with", withFunc(tbl, fields))
 
-	fn(log, msg)
+	return tbl
 }
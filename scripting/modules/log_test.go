@@ -37,4 +37,96 @@ var _ = Describe("Log", func() {
 		Entry("debug() without data", `log.debug("Information log 1")`),
 		Entry("debug() doens't fail", `log.debug("Information log 2", nil)`),
 		Entry("debug() with data", `log.debug("Information log 3", {is_test = true})`))
+
+	Describe("child loggers", func() {
+		It("log with their fields", func() {
+			logger.TestBuffer.Reset()
+			err := e.DoString(`
+				local child = log.with({plugin = "combat"})
+				child:info("Child log")
+				child.with({round = 2}).warn("Grandchild log")
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(logger.TestBuffer.String()).Should(ContainSubstring(`"plugin":"combat"`))
+			Ω(logger.TestBuffer.String()).Should(ContainSubstring(`"round":2`))
+		})
+	})
+
+	Describe("fatal", func() {
+		It("logs and stops the script", func() {
+			logger.TestBuffer.Reset()
+			err := e.DoString(`
+				log.fatal("Cannot continue", {reason = "test"})
+				never_set = true
+			`)
+
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("Cannot continue"))
+			Ω(logger.TestBuffer.String()).Should(ContainSubstring(`"fatal":true`))
+			Ω(e.GetGlobal("never_set").IsNil()).Should(BeTrue())
+		})
+	})
+
+	Describe("levels", func() {
+		AfterEach(func() {
+			e.DoString(`log.set_level()`)
+		})
+
+		It("filters messages below the engine's level", func() {
+			logger.TestBuffer.Reset()
+			err := e.DoString(`
+				log.set_level("warn")
+				log.info("Hidden")
+				log.with({a = 1}).debug("Hidden")
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(logger.TestBuffer.Len()).Should(Equal(0))
+
+			err = e.DoString(`log.error("Shown")`)
+			Ω(err).Should(BeNil())
+			Ω(logger.TestBuffer.Len()).Should(BeNumerically(">", 0))
+		})
+
+		It("reports the current level", func() {
+			Ω(e.DoString(`log.set_level("info")`)).Should(Succeed())
+			res, err := testReturn(e, `return log.level()`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsString()).Should(Equal("info"))
+
+			e.DoString(`log.set_level(nil)`)
+			res, _ = testReturn(e, `return log.level()`)
+			Ω(res[0].AsString()).Should(Equal("debug"))
+		})
+
+		It("rejects unknown levels", func() {
+			Ω(e.DoString(`log.set_level("loud")`)).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("tail", func() {
+		It("returns the latest entries from the source", func() {
+			res, err := testReturn(e, `
+				log.info("First tail")
+				log.warn("Second tail", {round = 3})
+				return log.tail("", 2)
+			`)
+
+			Ω(err).Should(BeNil())
+			first, second := res[0].RawGet(1), res[0].RawGet(2)
+			Ω(first.Get("message").AsString()).Should(Equal("First tail"))
+			Ω(second.Get("message").AsString()).Should(Equal("Second tail"))
+			Ω(second.Get("level").AsString()).Should(Equal("warn"))
+			Ω(second.Get("fields").Get("round").AsNumber()).Should(Equal(float64(3)))
+		})
+
+		It("returns nothing for unknown sources", func() {
+			res, err := testReturn(e, `return #log.tail("no such source")`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsNumber()).Should(Equal(float64(0)))
+		})
+	})
 })
@@ -0,0 +1,119 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/loot"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Loot lets scripts change what mobs drop as they die and roll loot tables.
+//   modify(name, fn)
+//     @param name: string = the name of the modifier, modifiers run in order
+//       of their names
+//     @param fn: function(ctx): table = given the mob, proto, name, room,
+//       killer and level of the mob that died, along with the drops rolled
+//       so far as a list of tables with an item and count, returns the new
+//       list of drops, or nil to leave them as they are
+//     sets the modifier, replacing any with the name.
+//   unmodify(name): boolean
+//     removes the modifier with the name, returning false if there wasn't
+//     one.
+//   roll(table, level): table, string
+//     returns a list of the drops, tables with an item and count, of
+//     rolling the table for a mob of the level, or nil and why it couldn't.
+//   rule([rule]): string
+//     @param rule: string = optional new rule, "killer", "round_robin",
+//       "random" or "free_for_all"
+//     @errors raises an error if the rule isn't known
+//     returns the rule loot is shared by, after changing it if one was
+//     given.
+var Loot = lua.TableMap{
+	"modify": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		loot.Global().Modify(name, func(ctx loot.Context) []loot.Drop {
+			tbl := engine.NewTable()
+			tbl.Set("mob", ctx.Mob)
+			tbl.Set("proto", ctx.Proto)
+			tbl.Set("name", ctx.Name)
+			tbl.Set("room", ctx.Room)
+			tbl.Set("killer", ctx.Killer)
+			tbl.Set("level", ctx.Level)
+			tbl.Set("drops", dropsToTable(engine, ctx.Drops))
+
+			ret, err := fn.Call(1, tbl)
+			if err != nil {
+				log("loot").WithError(err).WithField("modifier", name).WithField("engine", nameForEngine(engine)).Error("Loot modifier failed.")
+
+				return ctx.Drops
+			}
+			if len(ret) == 0 || !ret[0].IsTable() {
+				return ctx.Drops
+			}
+
+			return dropsFromTable(ret[0])
+		})
+
+		return 0
+	},
+	"unmodify": func(name string) bool {
+		return loot.Global().Unmodify(name)
+	},
+	"roll": func(engine *lua.Engine) int {
+		level := engine.PopInt()
+		drops, err := loot.Global().Tables().Roll(engine.PopString(), level)
+		if err != nil {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+		engine.PushValue(dropsToTable(engine, drops))
+
+		return 1
+	},
+	"rule": func(engine *lua.Engine) int {
+		if engine.StackSize() > 0 {
+			if err := loot.Global().SetRule(engine.PopString()); err != nil {
+				engine.RaiseError(err.Error())
+
+				return 0
+			}
+		}
+		engine.PushValue(loot.Global().Rule())
+
+		return 1
+	},
+}
+
+// dropsToTable lists the drops as tables with an item and count
+func dropsToTable(engine *lua.Engine, drops []loot.Drop) *lua.Value {
+	list := engine.NewTable()
+	for _, d := range drops {
+		tbl := engine.NewTable()
+		tbl.Set("item", d.Item)
+		tbl.Set("count", d.Count)
+		list.Append(tbl)
+	}
+
+	return list
+}
+
+// dropsFromTable reads a list of tables with an item and count, a missing
+// count is one
+func dropsFromTable(list *lua.Value) []loot.Drop {
+	var drops []loot.Drop
+	list.ForEach(func(_, d *lua.Value) {
+		if !d.IsTable() {
+			return
+		}
+		count := 1
+		if c := d.Get("count"); c.IsNumber() {
+			count = int(c.AsNumber())
+		}
+		drops = append(drops, loot.Drop{Item: d.Get("item").AsString(), Count: count})
+	})
+
+	return drops
+}
@@ -0,0 +1,73 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/loot"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loot Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "loot")
+		engine.DoString(`loot = require("loot")`)
+		world.Global().AddZone(world.Zone{ID: "lua-lair", Name: "The Lair"})
+		Ω(world.Global().SetNPC(world.NPCDef{ID: "lua-dragon", Zone: "lua-lair", Name: "a dragon", Level: 20, Props: map[string]interface{}{
+			loot.TableProp: "lua-hoard",
+		}})).Should(Succeed())
+		Ω(loot.Global().Tables().Add(loot.Table{ID: "lua-hoard", Entries: []loot.Entry{
+			{Item: "lua-gold", Min: 5, Max: 5},
+		}})).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		loot.Global().Unmodify("lua-double")
+		loot.Global().SetRule(loot.RoundRobin)
+		engine.Close()
+	})
+
+	It("registers modifiers that change what mobs drop", func() {
+		engine.DoString(`
+			loot.modify("lua-double", function(ctx)
+				local drops = {}
+				for _, d in ipairs(ctx.drops) do
+					table.insert(drops, {item = d.item, count = d.count * 2})
+				end
+				table.insert(drops, {item = "lua-scale"})
+
+				return drops
+			end)
+		`)
+
+		drops, err := loot.Global().Roll(loot.Context{Proto: "lua-dragon"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(drops).Should(Equal([]loot.Drop{{Item: "lua-gold", Count: 10}, {Item: "lua-scale", Count: 1}}))
+
+		res, err := testReturn(engine, `return loot.unmodify("lua-double")`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+
+	It("rolls tables and changes the rule", func() {
+		res, err := testReturn(engine, `
+			local drops = loot.roll("lua-hoard", 1)
+			local _, missing = loot.roll("lua-nothing", 1)
+
+			return {item = drops[1].item, count = drops[1].count, missing = missing ~= nil, rule = loot.rule("killer")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("item").AsString()).Should(Equal("lua-gold"))
+		Ω(res[0].Get("count").AsNumber()).Should(BeEquivalentTo(5))
+		Ω(res[0].Get("missing").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("rule").AsString()).Should(Equal(loot.Killer))
+
+		_, err = testReturn(engine, `loot.rule("finders_keepers")`)
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,79 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Mail lets scripts send letters and see what players have been sent.
+//   deliver(letter): boolean, string
+//     @param letter: table = the letter's from, to, subject, body and money,
+//       the money is created by sending it
+//     puts the letter in its recipient's mailbox, returning false and why if
+//       it can't be.
+//   send(player, to, subject, body): boolean, string
+//     sends a letter as the player with whatever they've attached to it.
+//   letters(name): table
+//     returns the letters in the player's mailbox, oldest first, each with
+//       from, subject, body, sent (unix seconds), read, items (how many are
+//       attached) and money.
+//   unread(name): number
+//     returns how many letters the player hasn't read.
+var Mail = lua.TableMap{
+	"deliver": func(engine *lua.Engine) int {
+		t := engine.PopTable()
+		_, err := mail.Global().Deliver(mail.Letter{
+			From:    t.Get("from").AsString(),
+			To:      t.Get("to").AsString(),
+			Subject: t.Get("subject").AsString(),
+			Body:    t.Get("body").AsString(),
+			Money:   int(t.Get("money").AsNumber()),
+		})
+
+		return pushResult(engine, err)
+	},
+	"send": func(engine *lua.Engine) int {
+		body := engine.PopString()
+		subject := engine.PopString()
+		to := engine.PopString()
+		c, ok := combat.Global().Lookup(engine.PopString()).(mail.Correspondent)
+		if !ok {
+			engine.PushValue(false)
+			engine.PushValue(mail.NoRecipientMessage)
+
+			return 2
+		}
+		_, err := mail.Global().Send(c, to, subject, body)
+
+		return pushResult(engine, err)
+	},
+	"letters": func(engine *lua.Engine) int {
+		letters, err := mail.Global().Letters(engine.PopString())
+		if err != nil {
+			log("mail").WithError(err).WithField("engine", nameForEngine(engine)).Error("Failed to load a mailbox.")
+		}
+		t := engine.NewTable()
+		for _, l := range letters {
+			lt := engine.NewTable()
+			lt.Set("from", l.From)
+			lt.Set("subject", l.Subject)
+			lt.Set("body", l.Body)
+			lt.Set("sent", l.Sent.Unix())
+			lt.Set("read", l.Read)
+			lt.Set("items", len(l.Items))
+			lt.Set("money", l.Money)
+			t.Append(lt)
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"unread": func(engine *lua.Engine) int {
+		engine.PushValue(mail.Global().Unread(engine.PopString()))
+
+		return 1
+	},
+}
@@ -0,0 +1,50 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mail Lua Module", func() {
+	var (
+		engine *lua.Engine
+		sender *shopper
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "mail")
+		engine.DoString(`mail = require("mail")`)
+		mail.Global().SetStore(mail.NewMemoryStore())
+		sender = &shopper{brawler: brawler{id: "sender"}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "sender" {
+				return sender
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("delivers letters and reads mailboxes", func() {
+		res, err := testReturn(engine, `
+			mail.deliver({from = "Postmaster", to = "lua-reader", subject = "Welcome", money = 5})
+			local sent = mail.send("sender", "lua-reader", "Hi", "Hello there.")
+			local _, empty = mail.send("sender", "lua-reader", "Hi", "")
+			local letters = mail.letters("lua-reader")
+			return {sent, empty, #letters, letters[1].money, letters[2].from, mail.unread("lua-reader")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, mail.EmptyMessage, float64(2), float64(5), "sender", float64(2)}))
+	})
+})
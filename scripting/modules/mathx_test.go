@@ -0,0 +1,73 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mathx Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "mathx")
+		engine.DoString(`mathx = require("mathx")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("number helpers",
+		func(script string, expected float64) {
+			res, err := testReturn(engine, "return "+script)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsNumber()).Should(BeNumerically("~", expected, 0.0001))
+		},
+		Entry("clamp below", "mathx.clamp(-5, 0, 10)", 0.0),
+		Entry("clamp above", "mathx.clamp(15, 0, 10)", 10.0),
+		Entry("clamp within", "mathx.clamp(5, 0, 10)", 5.0),
+		Entry("lerp", "mathx.lerp(10, 20, 0.25)", 12.5),
+		Entry("inverse_lerp", "mathx.inverse_lerp(10, 20, 15)", 0.5),
+		Entry("round", "mathx.round(2.5)", 3.0),
+		Entry("round negative", "mathx.round(-2.5)", -3.0),
+		Entry("round_to whole", "mathx.round_to(17, 5)", 15.0),
+		Entry("round_to decimal", "mathx.round_to(3.14159, 0.01)", 3.14),
+		Entry("percent", "mathx.percent(25, 200)", 12.5),
+		Entry("percent of zero", "mathx.percent(25, 0)", 0.0),
+		Entry("percent_of", "mathx.percent_of(25, 80)", 20.0),
+		Entry("sign", "mathx.sign(-3)", -1.0))
+
+	DescribeTable("vectors",
+		func(script, expected string) {
+			res, err := testReturn(engine, "return tostring("+script+")")
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsString()).Should(Equal(expected))
+		},
+		Entry("addition", "mathx.vec2(1, 2) + mathx.vec2(3, 4)", "vec2(4, 6)"),
+		Entry("subtraction", "mathx.vec3(5, 5, 5) - mathx.vec3(1, 2, 3)", "vec3(4, 3, 2)"),
+		Entry("scalar multiplication", "mathx.vec2(1, 2) * 3", "vec2(3, 6)"),
+		Entry("leading scalar", "2 * mathx.vec2(1, 2)", "vec2(2, 4)"),
+		Entry("division", "mathx.vec2(4, 8) / 4", "vec2(1, 2)"),
+		Entry("negation", "-mathx.vec2(1, -2)", "vec2(-1, 2)"),
+		Entry("normalize", "mathx.vec2(3, 4):normalize()", "vec2(0.6, 0.8)"),
+		Entry("lerp", "mathx.vec2(0, 0):lerp(mathx.vec2(10, 20), 0.5)", "vec2(5, 10)"),
+		Entry("cross", "mathx.vec3(1, 0, 0):cross(mathx.vec3(0, 1, 0))", "vec3(0, 0, 1)"))
+
+	It("measures vectors", func() {
+		res, err := testReturn(engine, `
+			local a, b = mathx.vec2(0, 0), mathx.vec2(3, 4)
+
+			return {b:length(), a:distance(b), b:dot(mathx.vec2(1, 1)), a == mathx.vec2(), mathx.is_vec2(a), mathx.is_vec3(a)}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(5), float64(5), float64(7), true, true, false}))
+	})
+})
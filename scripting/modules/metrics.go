@@ -0,0 +1,221 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"regexp"
+
+	"github.com/bbuck/dragon-mud/metrics"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+var metricNameRx = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.:]*$`)
+
+// Metrics lets scripts report what they're doing through the server's
+// metrics registry, where it's available next to the server's own metrics.
+// Metrics are shared by all engines, so the same name always refers to the
+// same metric. Names may contain letters, numbers, underscores, dots and
+// colons and must be unique across all kinds of metrics. Metric methods can
+// be called with either : or . (metrics.counter("logins"):inc()).
+//   counter(name): metrics.Counter
+//     @param name: string = the name of the counter
+//     @errors raises an error if the name is invalid or used by another kind
+//       of metric
+//     returns the counter, a value that only goes up.
+//   gauge(name): metrics.Gauge
+//     @param name: string = the name of the gauge
+//     @errors raises an error if the name is invalid or used by another kind
+//       of metric
+//     returns the gauge, a value that goes up and down.
+//   histogram(name[, buckets]): metrics.Histogram
+//     @param name: string = the name of the histogram
+//     @param buckets: table = nil = list of bucket upper bounds, only used
+//       when the histogram is first created
+//     @errors raises an error if the name is invalid or used by another kind
+//       of metric
+//     returns the histogram, which counts observed values in buckets.
+//   snapshot(): table
+//     returns the current value of every metric keyed by name.
+//   metrics.Counter
+//     inc([n])
+//       @param n: number = 1 = the amount to increase the counter by
+//     value(): number
+//   metrics.Gauge
+//     set(value)
+//     inc([n])
+//       @param n: number = 1 = the amount to increase the gauge by
+//     dec([n])
+//       @param n: number = 1 = the amount to decrease the gauge by
+//     value(): number
+//   metrics.Histogram
+//     observe(value)
+//     count(): number
+//     sum(): number
+var Metrics = lua.TableMap{
+	"counter": func(engine *lua.Engine) int {
+		name := popMetricName(engine)
+		if name == "" {
+			return 0
+		}
+
+		counter, err := metrics.Global().Counter(name)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		tbl := engine.NewTable()
+		tbl.Set("inc", func(eng *lua.Engine) int {
+			counter.Add(uint64(metricAmount(eng)))
+
+			return 0
+		})
+		tbl.Set("value", func(eng *lua.Engine) int {
+			metricArgs(eng)
+			eng.PushValue(counter.Value())
+
+			return 1
+		})
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"gauge": func(engine *lua.Engine) int {
+		name := popMetricName(engine)
+		if name == "" {
+			return 0
+		}
+
+		gauge, err := metrics.Global().Gauge(name)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		tbl := engine.NewTable()
+		tbl.Set("set", func(eng *lua.Engine) int {
+			if args := metricArgs(eng); len(args) > 0 {
+				gauge.Set(args[0].AsNumber())
+			}
+
+			return 0
+		})
+		tbl.Set("inc", func(eng *lua.Engine) int {
+			gauge.Add(metricAmount(eng))
+
+			return 0
+		})
+		tbl.Set("dec", func(eng *lua.Engine) int {
+			gauge.Add(-metricAmount(eng))
+
+			return 0
+		})
+		tbl.Set("value", func(eng *lua.Engine) int {
+			metricArgs(eng)
+			eng.PushValue(gauge.Value())
+
+			return 1
+		})
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"histogram": func(engine *lua.Engine) int {
+		var buckets []float64
+		if engine.StackSize() >= 2 {
+			engine.PopTable().ForEach(func(_, bound *lua.Value) {
+				buckets = append(buckets, bound.AsNumber())
+			})
+		}
+		name := popMetricName(engine)
+		if name == "" {
+			return 0
+		}
+
+		histogram, err := metrics.Global().Histogram(name, buckets)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		tbl := engine.NewTable()
+		tbl.Set("observe", func(eng *lua.Engine) int {
+			if args := metricArgs(eng); len(args) > 0 {
+				histogram.Observe(args[0].AsNumber())
+			}
+
+			return 0
+		})
+		tbl.Set("count", func(eng *lua.Engine) int {
+			metricArgs(eng)
+			eng.PushValue(histogram.Snapshot().Count)
+
+			return 1
+		})
+		tbl.Set("sum", func(eng *lua.Engine) int {
+			metricArgs(eng)
+			eng.PushValue(histogram.Snapshot().Sum)
+
+			return 1
+		})
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"snapshot": func(engine *lua.Engine) int {
+		tbl := engine.NewTable()
+		for name, value := range metrics.Global().Snapshot() {
+			if hist, ok := value.(metrics.HistogramSnapshot); ok {
+				h := engine.NewTable()
+				h.Set("count", hist.Count)
+				h.Set("sum", hist.Sum)
+				h.Set("buckets", engine.TableFromMap(hist.Buckets))
+				tbl.Set(name, h)
+			} else {
+				tbl.Set(name, value)
+			}
+		}
+
+		engine.PushValue(tbl)
+
+		return 1
+	},
+}
+
+func popMetricName(engine *lua.Engine) string {
+	name := engine.PopString()
+	if !metricNameRx.MatchString(name) {
+		engine.ArgumentError(1, "metric names may only contain letters, numbers, underscores, dots and colons")
+
+		return ""
+	}
+
+	return name
+}
+
+// metricArgs pops all arguments off the stack in order, dropping the metric
+// table itself when the method is called with a colon.
+func metricArgs(engine *lua.Engine) []*lua.Value {
+	args := make([]*lua.Value, engine.StackSize())
+	for i := len(args) - 1; i >= 0; i-- {
+		args[i] = engine.PopValue()
+	}
+
+	if len(args) > 0 && args[0].IsTable() {
+		args = args[1:]
+	}
+
+	return args
+}
+
+// metricAmount pops the optional amount for inc and dec, defaulting to 1
+func metricAmount(engine *lua.Engine) float64 {
+	if args := metricArgs(engine); len(args) > 0 && args[0].IsNumber() {
+		return args[0].AsNumber()
+	}
+
+	return 1
+}
@@ -0,0 +1,69 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "metrics")
+		engine.DoString(`metrics = require("metrics")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("updates counters with either call style", func() {
+		res, err := testReturn(engine, `
+			local quests = metrics.counter("test.quests_completed")
+			quests:inc()
+			quests.inc(2)
+
+			return metrics.counter("test.quests_completed"):value()
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsNumber()).Should(Equal(float64(3)))
+	})
+
+	It("updates gauges", func() {
+		res, err := testReturn(engine, `
+			local online = metrics.gauge("test.online")
+			online:set(10)
+			online:inc()
+			online:dec(3)
+
+			return online:value()
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsNumber()).Should(Equal(float64(8)))
+	})
+
+	It("observes histogram values", func() {
+		res, err := testReturn(engine, `
+			local h = metrics.histogram("test.damage", {10, 50, 100})
+			h:observe(5)
+			h:observe(75)
+			local snap = metrics.snapshot()["test.damage"]
+
+			return {h:count(), h:sum(), snap.buckets["50"]}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(2), float64(80), float64(1)}))
+	})
+
+	It("raises errors for invalid or conflicting names", func() {
+		Ω(engine.DoString(`metrics.counter("bad name")`)).ShouldNot(BeNil())
+		Ω(engine.DoString(`metrics.counter("test.conflict"); metrics.gauge("test.conflict")`)).ShouldNot(BeNil())
+	})
+})
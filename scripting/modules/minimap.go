@@ -0,0 +1,100 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/minimap"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Minimap lets scripts draw maps of the world and change how rooms are
+// drawn on them.
+//   draw(room, [radius]): string
+//     @param radius: number = optional number of rooms the map reaches, the
+//       game's minimap radius if it isn't given
+//     returns the map of the rooms around the room as text, nil if there's
+//     no such room.
+//   area(room): string
+//     returns the map of every room of the room's zone reachable from it as
+//     text, nil if there's no such room.
+//   rooms(room, [radius]): table
+//     returns the rooms on the map around the room as a list of tables with
+//     an id, name, zone, x, y, terrain, symbol and exits by direction, the
+//     room itself at 0, 0, nil if there's no such room.
+//   symbol(flag, symbol)
+//     draws rooms with the flag with the symbol, an empty symbol draws them
+//     like any other room.
+//   radius([radius]): number
+//     returns how many rooms minimaps reach, after changing it if a radius
+//     was given.
+var Minimap = lua.TableMap{
+	"draw": func(engine *lua.Engine) int {
+		radius := 0
+		if engine.StackSize() > 1 {
+			radius = engine.PopInt()
+		}
+		drawn, ok := minimap.Global().Minimap(engine.PopString(), radius)
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(drawn.String())
+
+		return 1
+	},
+	"area": func(engine *lua.Engine) int {
+		drawn, ok := minimap.Global().Area(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(drawn.String())
+
+		return 1
+	},
+	"rooms": func(engine *lua.Engine) int {
+		radius := 0
+		if engine.StackSize() > 1 {
+			radius = engine.PopInt()
+		}
+		drawn, ok := minimap.Global().Minimap(engine.PopString(), radius)
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		list := engine.NewTable()
+		for _, r := range drawn.Rooms {
+			tbl := engine.NewTable()
+			tbl.Set("id", r.ID)
+			tbl.Set("name", r.Name)
+			tbl.Set("zone", r.Zone)
+			tbl.Set("x", r.X)
+			tbl.Set("y", r.Y)
+			tbl.Set("terrain", r.Terrain)
+			tbl.Set("symbol", r.Symbol)
+			exits := engine.NewTable()
+			for d, to := range r.Exits {
+				exits.Set(string(d), to)
+			}
+			tbl.Set("exits", exits)
+			list.Append(tbl)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"symbol": func(flag, symbol string) {
+		minimap.Global().SetSymbol(flag, symbol)
+	},
+	"radius": func(engine *lua.Engine) int {
+		if engine.StackSize() > 0 {
+			minimap.Global().SetRadius(engine.PopInt())
+		}
+		engine.PushValue(minimap.Global().Radius())
+
+		return 1
+	},
+}
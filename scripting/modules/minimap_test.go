@@ -0,0 +1,52 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/minimap"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Minimap Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "minimap")
+		engine.DoString(`minimap = require("minimap")`)
+		world.Global().AddZone(world.Zone{ID: "lua-map", Name: "The Map"})
+		world.Global().AddRoom(world.Room{ID: "lua-hall", Zone: "lua-map", Name: "A Hall"})
+		world.Global().AddRoom(world.Room{ID: "lua-pond", Zone: "lua-map", Name: "A Pond", Flags: map[string]bool{"lua-pond": true}})
+		world.Global().Link("lua-hall", world.East, "lua-pond")
+	})
+
+	AfterEach(func() {
+		minimap.Global().SetSymbol("lua-pond", "")
+		minimap.Global().SetRadius(minimap.DefaultRadius)
+		engine.Close()
+	})
+
+	It("draws maps around rooms", func() {
+		res, err := testReturn(engine, `
+			minimap.symbol("lua-pond", "o")
+			local rooms = minimap.rooms("lua-hall")
+
+			return {
+				drawn = minimap.draw("lua-hall", 1),
+				area = minimap.area("lua-hall"),
+				missing = minimap.draw("lua-nowhere") == nil,
+				east = rooms[1].exits.east,
+				radius = minimap.radius(5),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("drawn").AsString()).Should(Equal("@-o"))
+		Ω(res[0].Get("area").AsString()).Should(Equal("@-o"))
+		Ω(res[0].Get("missing").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("east").AsString()).Should(Equal("lua-pond"))
+		Ω(res[0].Get("radius").AsNumber()).Should(BeEquivalentTo(5))
+	})
+})
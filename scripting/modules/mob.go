@@ -0,0 +1,185 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Mob lets scripts bring NPCs into the game and change them, like handlers of
+// "mob:spawned" dressing up a new mob. Mobs are given to scripts as tables
+// with their id, proto, zone, name, description, level, room, stats, flags
+// and props.
+//   spawn(proto, room): string
+//     creates a mob from the NPC defined with the id in the room, returning
+//     its id or nil if there's no such NPC or room.
+//   get(id): table
+//     returns the mob with the id, nil if there isn't one.
+//   room(room): table
+//     returns the mobs in the room.
+//   count(proto): number
+//     returns how many mobs made from the NPC definition are in the game.
+//   set(id, key, value): boolean
+//     changes the name, description, level or room of the mob, any other key
+//     is kept in its props and nil removes it. Returns false if there's no
+//     such mob.
+//   set_stat(id, stat, value): boolean
+//     sets the mob's stat, returning false if there's no such mob.
+//   set_flag(id, flag, on): boolean
+//     sets or clears the mob's flag, returning false if there's no such mob.
+//   kill(id[, killer]): boolean
+//     removes the mob from the game, leaving what it had on the floor,
+//     returning false if there's no such mob.
+//   reset(zone): boolean
+//     runs the zone's resets now, returning false if any failed.
+//   on_reset(name, fn)
+//     @param name: string = the script area files name in their resets
+//     @param fn: function(zone, room): boolean, string = run each time one of
+//       the resets runs, with the zone and the reset's room, which may be
+//       nil; it returns false and why when the reset failed
+//     sets what the reset script does, like relocking doors, restocking
+//     shops or resetting puzzles, replacing what it did.
+var Mob = lua.TableMap{
+	"spawn": func(engine *lua.Engine) int {
+		room := engine.PopString()
+		proto := engine.PopString()
+		m, err := mob.Global().Spawn(proto, room)
+		if err != nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(m.ID())
+
+		return 1
+	},
+	"get": func(engine *lua.Engine) int {
+		m := mob.Global().Get(engine.PopString())
+		if m == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(mobTable(engine, m))
+
+		return 1
+	},
+	"room": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, m := range mob.Global().In(engine.PopString()) {
+			list.Append(mobTable(engine, m))
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"count": func(proto string) int {
+		return mob.Global().Count(proto)
+	},
+	"set": func(engine *lua.Engine) int {
+		value := engine.PopValue()
+		key := engine.PopString()
+		m := mob.Global().Get(engine.PopString())
+		if m == nil {
+			engine.PushValue(false)
+
+			return 1
+		}
+		switch key {
+		case "name":
+			m.SetName(value.AsString())
+		case "description":
+			m.SetDescription(value.AsString())
+		case "level":
+			m.SetLevel(int(value.AsNumber()))
+		case "room":
+			m.SetLocation(value.AsString())
+		default:
+			if value.IsNil() {
+				m.SetProp(key, nil)
+			} else {
+				m.SetProp(key, value.AsRaw())
+			}
+		}
+		engine.PushValue(true)
+
+		return 1
+	},
+	"set_stat": func(id, stat string, value int) bool {
+		m := mob.Global().Get(id)
+		if m == nil {
+			return false
+		}
+		m.SetStat(stat, value)
+
+		return true
+	},
+	"set_flag": func(id, flag string, on bool) bool {
+		m := mob.Global().Get(id)
+		if m == nil {
+			return false
+		}
+		m.SetFlag(flag, on)
+
+		return true
+	},
+	"kill": func(engine *lua.Engine) int {
+		killer := ""
+		if engine.StackSize() >= 2 {
+			killer = engine.PopString()
+		}
+		engine.PushValue(mob.Global().Kill(engine.PopString(), killer))
+
+		return 1
+	},
+	"reset": func(zone string) bool {
+		return mob.Global().ResetZone(zone) == nil
+	},
+	"on_reset": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		mob.Global().HandleReset(name, func(zone, room string) error {
+			args := []interface{}{zone, engine.Nil()}
+			if room != "" {
+				args[1] = room
+			}
+			ret, err := fn.Call(2, args...)
+			if err != nil {
+				log("mob").WithError(err).WithField("engine", nameForEngine(engine)).Error("Reset script failed.")
+
+				return err
+			}
+			if len(ret) > 0 && ret[0].IsBool() && ret[0].IsFalse() {
+				if len(ret) > 1 && ret[1].IsString() {
+					return errors.New(ret[1].AsString())
+				}
+
+				return fmt.Errorf("reset script %q failed", name)
+			}
+
+			return nil
+		})
+
+		return 0
+	},
+}
+
+func mobTable(engine *lua.Engine, m *mob.Mob) *lua.Value {
+	tbl := engine.NewTable()
+	tbl.Set("id", m.ID())
+	tbl.Set("proto", m.Proto())
+	tbl.Set("zone", m.Zone())
+	tbl.Set("name", m.Name())
+	tbl.Set("description", m.Description())
+	tbl.Set("level", m.Level())
+	tbl.Set("room", m.Location())
+	tbl.Set("stats", engine.TableFromMap(m.Stats()))
+	tbl.Set("flags", engine.TableFromSlice(m.Flags()))
+	tbl.Set("props", engine.TableFromMap(m.Props()))
+
+	return tbl
+}
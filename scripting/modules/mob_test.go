@@ -0,0 +1,75 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mob Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "mob")
+		engine.DoString(`mob = require("mob")`)
+		world.Global().AddZone(world.Zone{ID: "luatest", Name: "Lua Test"})
+		world.Global().AddRoom(world.Room{ID: "lua-den", Zone: "luatest", Name: "A Den"})
+		world.Global().SetNPC(world.NPCDef{ID: "lua-rat", Zone: "luatest", Name: "a rat", Stats: map[string]int{"hp": 3}})
+		for _, m := range mob.Global().In("lua-den") {
+			mob.Global().Kill(m.ID(), "")
+		}
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("spawns and changes mobs", func() {
+		res, err := testReturn(engine, `
+			local id = mob.spawn("lua-rat", "lua-den")
+			mob.set(id, "name", "a huge rat")
+			mob.set(id, "mood", "angry")
+			mob.set_stat(id, "hp", 10)
+			local rat = mob.room("lua-den")[1]
+			return {rat.id == id, rat.name, rat.props.mood, rat.stats.hp, mob.spawn("lua-rat", "nowhere") == nil}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "a huge rat", "angry", float64(10), true}))
+	})
+
+	It("kills mobs", func() {
+		rat, err := mob.Global().Spawn("lua-rat", "lua-den")
+		Ω(err).ShouldNot(HaveOccurred())
+		engine.SetGlobal("id", rat.ID())
+
+		Ω(engine.DoString(`killed = mob.kill(id, "Alice")`)).Should(Succeed())
+		Ω(engine.GetGlobal("killed").AsBool()).Should(BeTrue())
+		Ω(mob.Global().Get(rat.ID())).Should(BeNil())
+	})
+
+	It("handles reset scripts", func() {
+		Ω(engine.DoString(`
+			mob.on_reset("lua-lock", function(zone, room)
+				locked = zone .. ":" .. room
+				return true
+			end)
+			mob.on_reset("lua-jam", function(zone, room)
+				return false, "the lock is jammed"
+			end)
+		`)).Should(Succeed())
+		defer world.Global().SetResets("luatest", nil)
+
+		world.Global().SetResets("luatest", []world.Reset{{Script: "lua-lock", Room: "lua-den"}})
+		Ω(mob.Global().ResetZone("luatest")).Should(Succeed())
+		Ω(engine.GetGlobal("locked").AsString()).Should(Equal("luatest:lua-den"))
+
+		world.Global().SetResets("luatest", []world.Reset{{Script: "lua-jam"}})
+		Ω(mob.Global().ResetZone("luatest")).Should(MatchError("zone luatest reset 1: the lock is jammed"))
+	})
+})
@@ -0,0 +1,252 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/data/model"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Model lets plugins declare the types they save, like bounties or guild
+// halls, and create, find and query records of them without writing
+// queries. Records are tables of their fields along with their id.
+//   define(name, fields)
+//     @param name: string - the name of the model, letters, digits and
+//       underscores
+//     @param fields: table - each field's name to a table describing it,
+//       with the keys:
+//         type: string - "string", "number", "bool" or "table"
+//         required: boolean = false - the field must have a value
+//         default: any = nil - the value of the field when it has none
+//         min, max: number = nil - bounds of numbers or the length of
+//           strings
+//         pattern: string = nil - a regular expression strings must match
+//         choices: table = nil - the list of values the field may have
+//         index: boolean = false - keep an index of the field so records
+//           can be looked up by it quickly
+//         unique: boolean = false - no two records may share a value, unique
+//           fields are indexed
+//     @errors raises an error if the definition isn't valid
+//     defines the model, replacing any with the same name.
+//   create(name, record): table, string
+//     @errors raises an error if the model isn't defined, as do the other
+//       functions
+//     saves a new record, given an id if it has none, returning it or nil and
+//     why it isn't valid.
+//   find(name, id): table
+//     returns the record with the id, nil if there's none.
+//   update(name, id, changes): table, string
+//     changes the fields of the record, returning it or nil and why it
+//     couldn't be changed.
+//   delete(name, id): boolean, string
+//     removes the record, returning false and why if it couldn't be removed.
+//   where(name, conditions): table
+//     @param conditions: table - field names to the values they must have
+//     returns the list of records with each of the values, sorted by id.
+//   query(name, options): table
+//     @param options: table - with the keys:
+//       where: table = nil - field names to the values they must have
+//       order: string = "id" - the field records are sorted by
+//       desc: boolean = false - sort from the highest value to the lowest
+//       limit: number = nil - the most records to return
+//     returns the list of records matching the options.
+//   count(name[, conditions]): number
+//     returns the number of records with each of the values.
+var Model = lua.TableMap{
+	"define": func(engine *lua.Engine) int {
+		fields := engine.PopValue().AsMapStringInterface()
+		name := engine.PopString()
+
+		def := model.Def{Name: name}
+		names := make([]string, 0, len(fields))
+		for field := range fields {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+		for _, field := range names {
+			opts, ok := fields[field].(map[string]interface{})
+			if !ok {
+				engine.RaiseError(fmt.Sprintf("field %s of %s must be a table describing it", field, name))
+
+				return 0
+			}
+			def.Fields = append(def.Fields, luaField(field, opts))
+		}
+
+		if _, err := model.Global().Define(def); err != nil {
+			engine.RaiseError(err.Error())
+		}
+
+		return 0
+	},
+	"create": func(engine *lua.Engine) int {
+		record := engine.PopValue().AsMapStringInterface()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		r, err := m.Create(record)
+
+		return pushRecord(engine, r, err)
+	},
+	"find": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		r, err := m.Find(id)
+		if err != nil {
+			if err != data.ErrNotFound {
+				engine.RaiseError(err.Error())
+
+				return 0
+			}
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(serializedToLua(engine, map[string]interface{}(r)))
+
+		return 1
+	},
+	"update": func(engine *lua.Engine) int {
+		changes := engine.PopValue().AsMapStringInterface()
+		id := engine.PopString()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		r, err := m.Update(id, changes)
+
+		return pushRecord(engine, r, err)
+	},
+	"delete": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		return pushResult(engine, m.Delete(id))
+	},
+	"where": func(engine *lua.Engine) int {
+		where := engine.PopValue().AsMapStringInterface()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		records, err := m.Where(where)
+
+		return pushRecords(engine, records, err)
+	},
+	"query": func(engine *lua.Engine) int {
+		opts := engine.PopValue().AsMapStringInterface()
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		q := model.Query{}
+		q.Where, _ = opts["where"].(map[string]interface{})
+		q.OrderBy, _ = opts["order"].(string)
+		q.Desc, _ = opts["desc"].(bool)
+		if limit, ok := opts["limit"].(float64); ok {
+			q.Limit = int(limit)
+		}
+
+		records, err := m.Query(q)
+
+		return pushRecords(engine, records, err)
+	},
+	"count": func(engine *lua.Engine) int {
+		var where map[string]interface{}
+		if engine.StackSize() > 1 {
+			where = engine.PopValue().AsMapStringInterface()
+		}
+		m := luaModel(engine, engine.PopString())
+		if m == nil {
+			return 0
+		}
+
+		count, err := m.Count(where)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		engine.PushValue(count)
+
+		return 1
+	},
+}
+
+// luaField converts the Lua description of a field
+func luaField(name string, opts map[string]interface{}) model.Field {
+	f := model.Field{Name: name, Default: opts["default"]}
+	f.Type, _ = opts["type"].(string)
+	f.Required, _ = opts["required"].(bool)
+	f.Pattern, _ = opts["pattern"].(string)
+	f.Index, _ = opts["index"].(bool)
+	f.Unique, _ = opts["unique"].(bool)
+	f.Choices, _ = opts["choices"].([]interface{})
+	if min, ok := opts["min"].(float64); ok {
+		f.Min = &min
+	}
+	if max, ok := opts["max"].(float64); ok {
+		f.Max = &max
+	}
+
+	return f
+}
+
+// luaModel returns the model with the name, raising an error if it isn't
+// defined
+func luaModel(engine *lua.Engine, name string) *model.Model {
+	m, err := model.Global().Get(name)
+	if err != nil {
+		engine.RaiseError(fmt.Sprintf("%s: %s", name, err))
+
+		return nil
+	}
+
+	return m
+}
+
+// pushRecord pushes the record, or nil and why there isn't one
+func pushRecord(engine *lua.Engine, r model.Record, err error) int {
+	if err != nil {
+		engine.PushValue(engine.Nil())
+		engine.PushValue(err.Error())
+
+		return 2
+	}
+	engine.PushValue(serializedToLua(engine, map[string]interface{}(r)))
+
+	return 1
+}
+
+// pushRecords pushes the records as a list, raising the error if there is
+// one
+func pushRecords(engine *lua.Engine, records []model.Record, err error) int {
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+	list := engine.NewTable()
+	for _, r := range records {
+		list.Append(serializedToLua(engine, map[string]interface{}(r)))
+	}
+	engine.PushValue(list)
+
+	return 1
+}
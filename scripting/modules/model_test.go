@@ -0,0 +1,67 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/data/model"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Model Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		model.Global().SetStore(data.NewMemoryStore())
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "model")
+		engine.DoString(`model = require("model")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("defines models and saves their records", func() {
+		res, err := testReturn(engine, `
+			model.define("lua_bounty", {
+				target = {type = "string", required = true, index = true},
+				reward = {type = "number", required = true, min = 1},
+				status = {type = "string", default = "open", choices = {"open", "claimed"}},
+			})
+			local first = model.create("lua_bounty", {target = "grimble", reward = 50})
+			model.create("lua_bounty", {id = "second", target = "grimble", reward = 80})
+			local bad, why = model.create("lua_bounty", {target = "snaggle", reward = 0})
+			local claimed = model.update("lua_bounty", "second", {status = "claimed"})
+			local top = model.query("lua_bounty", {order = "reward", desc = true, limit = 1})
+			local grimble = #model.where("lua_bounty", {target = "grimble"})
+			local deleted = model.delete("lua_bounty", first.id)
+
+			return {
+				status = first.status,
+				bad = bad == nil,
+				why = why,
+				claimed = claimed.status,
+				top = top[1].id,
+				grimble = grimble,
+				deleted = deleted,
+				gone = model.find("lua_bounty", first.id) == nil,
+				count = model.count("lua_bounty"),
+				unknown = pcall(model.find, "nothing", "x"),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("status").AsString()).Should(Equal("open"))
+		Ω(res[0].Get("bad").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("why").AsString()).Should(Equal("reward must be at least 1"))
+		Ω(res[0].Get("claimed").AsString()).Should(Equal("claimed"))
+		Ω(res[0].Get("top").AsString()).Should(Equal("second"))
+		Ω(res[0].Get("grimble").AsNumber()).Should(BeEquivalentTo(2))
+		Ω(res[0].Get("deleted").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("gone").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("count").AsNumber()).Should(BeEquivalentTo(1))
+		Ω(res[0].Get("unknown").AsBool()).Should(BeFalse())
+	})
+})
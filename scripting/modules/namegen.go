@@ -0,0 +1,79 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/namegen"
+)
+
+// Namegen generates pronounceable names for procedurally created NPCs, items
+// and places. Each style is trained from example names, loaded at startup
+// from the .txt files in the names directory of the project and each plugin
+// (one name per line, the file name is the style) or trained from scripts.
+//   generate(style[, min_length[, max_length]]): string
+//     @param style: string = the style of name to generate, like "elven"
+//     @param min_length: number = 0 = the fewest letters the name can have
+//     @param max_length: number = 0 = the most letters the name can have, 0
+//       means there is no limit
+//     @errors raises an error if the style is unknown or no name can be
+//       generated within the length
+//     returns a new capitalized name in the given style.
+//   train(style, names)
+//     @param style: string = the style to add names to, created if it does not
+//       exist
+//     @param names: table = list of example names
+//     adds example names to the style.
+//   has(style): boolean
+//     @param style: string = the style to check for
+//     returns whether the style has been defined.
+//   styles(): table
+//     returns a sorted list of all defined styles.
+var Namegen = lua.TableMap{
+	"generate": func(engine *lua.Engine) int {
+		args := make([]int, 0, 2)
+		for engine.StackSize() > 1 {
+			args = append([]int{engine.PopInt()}, args...)
+		}
+		style := engine.PopString()
+
+		var minLen, maxLen int
+		if len(args) > 0 {
+			minLen = args[0]
+		}
+		if len(args) > 1 {
+			maxLen = args[1]
+		}
+
+		name, err := namegen.Global().Generate(style, minLen, maxLen)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(name)
+
+		return 1
+	},
+	"train": func(engine *lua.Engine) int {
+		list := engine.PopTable()
+		style := engine.PopString()
+
+		var names []string
+		list.ForEach(func(_, name *lua.Value) {
+			names = append(names, name.AsString())
+		})
+		namegen.Global().Train(style, names...)
+
+		return 0
+	},
+	"has": func(style string) bool {
+		return namegen.Global().Has(style)
+	},
+	"styles": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromSlice(namegen.Global().Styles()))
+
+		return 1
+	},
+}
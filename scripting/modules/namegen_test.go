@@ -0,0 +1,50 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namegen Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "namegen")
+		engine.DoString(`
+			namegen = require("namegen")
+			namegen.train("test-orcish", {"Grommash", "Gorgash", "Durgash", "Morgrom", "Grukk", "Durak"})
+		`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("generates names for trained styles", func() {
+		res, err := testReturn(engine, `
+			local name = namegen.generate("test-orcish", 3, 10)
+
+			return {#name >= 3 and #name <= 10, namegen.has("test-orcish")}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, true}))
+	})
+
+	It("lists styles", func() {
+		res, err := testReturn(engine, `return namegen.styles()`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(ContainElement("test-orcish"))
+	})
+
+	It("raises an error for unknown styles", func() {
+		err := engine.DoString(`namegen.generate("nope")`)
+
+		Ω(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,164 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"sync"
+
+	"github.com/bbuck/dragon-mud/random/noise"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// the number of generators kept before they're all discarded, scripts rarely
+// use more than a handful of seeds
+const maxNoiseGenerators = 64
+
+type noiseKey struct {
+	kind string
+	seed int64
+}
+
+var (
+	noiseGenerators = make(map[noiseKey]noise.Noise2D)
+	noiseMutex      = new(sync.Mutex)
+)
+
+var noiseBuilders = map[string]func(int64) noise.Noise2D{
+	"perlin":  func(seed int64) noise.Noise2D { return noise.NewPerlin(seed) },
+	"simplex": func(seed int64) noise.Noise2D { return noise.NewSimplex(seed) },
+	"value":   func(seed int64) noise.Noise2D { return noise.NewValue(seed) },
+}
+
+// Noise provides seeded coherent noise for generating terrain, weather and
+// other maps. Noise changes smoothly between nearby points and is always the
+// same for the same seed, so a wilderness can be generated on demand one room
+// at a time. Seeds may be whole numbers or strings, such as the name of the
+// area being generated. All noise is between -1 and 1.
+//   perlin(seed, x, y[, z]): number
+//     @param seed: number | string = the seed for the noise
+//     @param x, y, z: number = the point to sample, z defaults to 0
+//     returns Perlin noise at the point, it's 0 at whole number coordinates
+//     so scale coordinates down before sampling (x / 10).
+//   simplex(seed, x, y): number
+//     @param seed: number | string = the seed for the noise
+//     @param x, y: number = the point to sample
+//     returns simplex noise at the point.
+//   value(seed, x, y): number
+//     @param seed: number | string = the seed for the noise
+//     @param x, y: number = the point to sample
+//     returns value noise at the point, cheaper and blockier than perlin or
+//     simplex.
+//   fractal(kind, seed, x, y[, options]): number
+//     @param kind: string = "perlin", "simplex" or "value"
+//     @param seed: number | string = the seed for the noise
+//     @param x, y: number = the point to sample
+//     @param options: table = optional "octaves" (4), "frequency" (1),
+//       "persistence" (0.5) and "lacunarity" (2)
+//     returns layered octaves of noise, adding finer detail like the rough
+//     edges of a coastline.
+//   seed(name): number
+//     @param name: string = the name to hash
+//     returns a numeric seed for the name.
+var Noise = lua.TableMap{
+	"perlin": func(engine *lua.Engine) int {
+		var z float64
+		if engine.StackSize() >= 4 {
+			z = engine.PopFloat()
+		}
+		y := engine.PopFloat()
+		x := engine.PopFloat()
+		gen := noiseGenerator("perlin", popNoiseSeed(engine))
+
+		engine.PushValue(gen.(*noise.Perlin).Noise3(x, y, z))
+
+		return 1
+	},
+	"simplex": func(engine *lua.Engine) int {
+		return pushNoise(engine, "simplex")
+	},
+	"value": func(engine *lua.Engine) int {
+		return pushNoise(engine, "value")
+	},
+	"fractal": func(engine *lua.Engine) int {
+		fractal := noise.DefaultFractal
+		if engine.StackSize() >= 5 {
+			opts := engine.PopValue()
+			if opts.IsTable() {
+				if v := opts.Get("octaves"); v.IsNumber() {
+					fractal.Octaves = int(v.AsNumber())
+				}
+				if v := opts.Get("frequency"); v.IsNumber() {
+					fractal.Frequency = v.AsNumber()
+				}
+				if v := opts.Get("persistence"); v.IsNumber() {
+					fractal.Persistence = v.AsNumber()
+				}
+				if v := opts.Get("lacunarity"); v.IsNumber() {
+					fractal.Lacunarity = v.AsNumber()
+				}
+			}
+		}
+		y := engine.PopFloat()
+		x := engine.PopFloat()
+		seed := popNoiseSeed(engine)
+		kind := engine.PopString()
+		if _, ok := noiseBuilders[kind]; !ok {
+			engine.ArgumentError(1, "expected \"perlin\", \"simplex\" or \"value\"")
+
+			return 0
+		}
+
+		engine.PushValue(fractal.Noise2(noiseGenerator(kind, seed), x, y))
+
+		return 1
+	},
+	"seed": func(name string) float64 {
+		return float64(noiseSeed(name))
+	},
+}
+
+// pop x, y and the seed and push the noise of the given kind at the point
+func pushNoise(engine *lua.Engine, kind string) int {
+	y := engine.PopFloat()
+	x := engine.PopFloat()
+	gen := noiseGenerator(kind, popNoiseSeed(engine))
+
+	engine.PushValue(gen.Noise2(x, y))
+
+	return 1
+}
+
+// seeds can be numbers or strings, strings are hashed into a number
+func popNoiseSeed(engine *lua.Engine) int64 {
+	seed := engine.PopValue()
+	if seed.IsString() {
+		return noiseSeed(seed.AsString())
+	}
+
+	return int64(seed.AsNumber())
+}
+
+// hash the name into a seed that a Lua number can represent exactly, so the
+// name and the seed returned by noise.seed produce the same noise
+func noiseSeed(name string) int64 {
+	return noise.SeedFromString(name) & (1<<53 - 1)
+}
+
+// fetch or build the generator for the kind and seed
+func noiseGenerator(kind string, seed int64) noise.Noise2D {
+	noiseMutex.Lock()
+	defer noiseMutex.Unlock()
+
+	key := noiseKey{kind, seed}
+	if gen, ok := noiseGenerators[key]; ok {
+		return gen
+	}
+
+	if len(noiseGenerators) >= maxNoiseGenerators {
+		noiseGenerators = make(map[noiseKey]noise.Noise2D)
+	}
+	gen := noiseBuilders[kind](seed)
+	noiseGenerators[key] = gen
+
+	return gen
+}
@@ -0,0 +1,59 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Noise Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "noise")
+		engine.DoString(`noise = require("noise")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("sampling noise",
+		func(sample string) {
+			res, err := testReturn(engine, `
+				local a = `+sample+`
+				local b = `+sample+`
+
+				return {a, a == b}
+			`)
+
+			Ω(err).Should(BeNil())
+			values := res[0].AsSliceInterface()
+			Ω(values[0]).Should(BeNumerically(">=", -1))
+			Ω(values[0]).Should(BeNumerically("<=", 1))
+			Ω(values[1]).Should(BeTrue())
+		},
+		Entry("perlin", `noise.perlin(1, 1.5, 2.25)`),
+		Entry("perlin in 3D", `noise.perlin("overworld", 1.5, 2.25, 0.5)`),
+		Entry("simplex", `noise.simplex(1, 1.5, 2.25)`),
+		Entry("value", `noise.value(noise.seed("overworld"), 1.5, 2.25)`),
+		Entry("fractal", `noise.fractal("simplex", 1, 1.5, 2.25, {octaves = 6})`),
+	)
+
+	It("treats names and their seeds the same", func() {
+		res, err := testReturn(engine, `return noise.simplex("overworld", 0.5, 0.5) == noise.simplex(noise.seed("overworld"), 0.5, 0.5)`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+
+	It("rejects unknown kinds of fractal noise", func() {
+		err := engine.DoString(`noise.fractal("pink", 1, 0.5, 0.5)`)
+
+		Ω(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"errors"
+
+	"github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// OLC lets scripts decide who builds what and save what builders change.
+//   access(name, fn)
+//     @param name: string = names the hook, so reloaded scripts replace it
+//     @param fn: function(request): boolean, string = given the builder (their
+//       id and name), kind ("room", "npc", "item" or "zone"), id and zone,
+//       returns false and why to refuse, anything else allows it
+//     sets the access hook, replacing any with the name, a nil fn removes it.
+//   unsaved(): table
+//     returns the ids of the zones changed since they were saved.
+//   save(zone): boolean, string
+//     saves the zone to its area file, every changed zone without one,
+//       returning false and why if it can't be.
+var OLC = lua.TableMap{
+	"access": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		name := engine.PopString()
+		if !fn.IsFunction() {
+			olc.Global().SetAccess(name, nil)
+
+			return 0
+		}
+		olc.Global().SetAccess(name, func(req olc.Request) error {
+			rt := engine.NewTable()
+			rt.Set("builder", req.Builder.ID())
+			rt.Set("name", req.Builder.Name())
+			rt.Set("kind", req.Kind)
+			rt.Set("id", req.ID)
+			rt.Set("zone", req.Zone)
+			ret, err := fn.Call(2, rt)
+			if err != nil {
+				log("olc").WithError(err).WithField("engine", nameForEngine(engine)).Error("Building access hook failed.")
+
+				return nil
+			}
+			if len(ret) == 0 || !ret[0].IsBool() || ret[0].AsBool() {
+				return nil
+			}
+			if len(ret) > 1 && ret[1].IsString() {
+				return errors.New(ret[1].AsString())
+			}
+
+			return errors.New(olc.DeniedMessage)
+		})
+
+		return 0
+	},
+	"unsaved": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromSlice(olc.Global().Unsaved()))
+
+		return 1
+	},
+	"save": func(engine *lua.Engine) int {
+		var zone string
+		if engine.StackSize() > 0 {
+			zone = engine.PopString()
+		}
+		if zone != "" {
+			return pushResult(engine, olc.Global().Save(zone))
+		}
+		_, err := olc.Global().SaveAll()
+
+		return pushResult(engine, err)
+	},
+}
@@ -0,0 +1,47 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OLC Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "olc")
+		engine.DoString(`olc = require("olc")`)
+	})
+
+	AfterEach(func() {
+		olc.Global().SetAccess("lua-keep", nil)
+		engine.Close()
+	})
+
+	It("guards building and saves zones", func() {
+		res, err := testReturn(engine, `
+			olc.access("lua-keep", function(req)
+				if req.kind == "zone" and req.builder ~= "lua-architect" then
+					return false, "Only the architect makes zones."
+				end
+			end)
+			local ok, why = olc.save("lua-nowhere")
+			return {#olc.unsaved(), ok, why}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(0), false, "There's no zone \"lua-nowhere\"."}))
+
+		mason := &noticer{brawler{id: "lua-mason"}}
+		_, err = olc.Global().Create(mason, olc.ZoneKind, "lua-keep", "")
+		Ω(err).Should(MatchError("Only the architect makes zones."))
+		architect := &noticer{brawler{id: "lua-architect"}}
+		_, err = olc.Global().Create(architect, olc.ZoneKind, "lua-keep", "")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(olc.Global().Cancel(architect)).Should(BeTrue())
+	})
+})
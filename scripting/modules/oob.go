@@ -0,0 +1,99 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server/session"
+)
+
+// OOB sends structured, out-of-band data to player clients alongside the game
+// text, as GMCP packages (or MSDP variables for older clients) over telnet or
+// data messages for WebSocket clients. Plugins name their own packages, "Char.Vitals" and
+// "Room.Info" are common ones clients know how to display. Clients that can't
+// receive data silently ignore it. Data sent by clients is emitted as the
+// "session:data" event with the session, package and data.
+//   send(session, package[, data]): boolean
+//     @param session: string = the id of the session to send to
+//     @param package: string = the name of the package, like "Char.Vitals"
+//     @param data: any = optional value sent as JSON, such as
+//       {hp = 10, maxhp = 20}
+//     sends the package, returning false if there is no open session with
+//       the id.
+//   send_character(name, package[, data]): boolean
+//     @param name: string = the name of the character being played
+//     @param package: string = the name of the package
+//     @param data: any = optional value sent as JSON
+//     sends the package to the session playing the character, returning
+//       false if the character isn't being played.
+//   broadcast(package[, data]): number
+//     @param package: string = the name of the package
+//     @param data: any = optional value sent as JSON
+//     sends the package to every session playing a character, returning the
+//       number of sessions sent to.
+var OOB = lua.TableMap{
+	"send": func(engine *lua.Engine) int {
+		data := oobData(engine, 3)
+		pkg := engine.PopString()
+		id := engine.PopString()
+
+		s := session.Global().Get(id)
+		engine.PushValue(oobSend(engine, s, pkg, data))
+
+		return 1
+	},
+	"send_character": func(engine *lua.Engine) int {
+		data := oobData(engine, 3)
+		pkg := engine.PopString()
+		name := engine.PopString()
+
+		s := session.Global().ForCharacter(name)
+		engine.PushValue(oobSend(engine, s, pkg, data))
+
+		return 1
+	},
+	"broadcast": func(engine *lua.Engine) int {
+		data := oobData(engine, 2)
+		pkg := engine.PopString()
+
+		sent := 0
+		for _, s := range session.Global().Sessions() {
+			if s.State() == session.Playing && oobSend(engine, s, pkg, data) {
+				sent++
+			}
+		}
+		engine.PushValue(sent)
+
+		return 1
+	},
+}
+
+// oobData pops the data argument if it was given, it's the argument at
+// position n
+func oobData(engine *lua.Engine, n int) interface{} {
+	if engine.StackSize() < n {
+		return nil
+	}
+
+	return serializableValue(engine.PopValue().AsRaw())
+}
+
+// oobSend sends the package to the session, if there is one
+func oobSend(engine *lua.Engine, s *session.Session, pkg string, data interface{}) bool {
+	if s == nil {
+		return false
+	}
+
+	if err := s.SendData(pkg, data); err != nil {
+		log("oob").WithError(err).WithFields(logger.Fields{
+			"engine":  nameForEngine(engine),
+			"session": s.ID(),
+			"package": pkg,
+		}).Warn("Failed to send out-of-band data.")
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,96 @@
+package modules_test
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server"
+	"github.com/bbuck/dragon-mud/server/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// dataConn records the data sent to it
+type dataConn struct {
+	net.Conn
+	sent map[string]interface{}
+}
+
+func (c *dataConn) Close() error {
+	return nil
+}
+
+func (c *dataConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}
+}
+
+func (c *dataConn) Transport() string {
+	return server.Telnet
+}
+
+func (c *dataConn) SendData(kind string, data interface{}) error {
+	c.sent[kind] = data
+
+	return nil
+}
+
+func (c *dataConn) OnData(func(string, json.RawMessage)) {}
+
+var _ = Describe("OOB Lua Module", func() {
+	var (
+		engine *lua.Engine
+		conn   *dataConn
+		s      *session.Session
+	)
+
+	BeforeEach(func() {
+		conn = &dataConn{sent: make(map[string]interface{})}
+		s = session.Global().Open(conn)
+
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "oob")
+		engine.DoString(`oob = require("oob")`)
+	})
+
+	AfterEach(func() {
+		s.Close()
+		engine.Close()
+	})
+
+	It("sends data to a session", func() {
+		res, err := testReturn(engine, `
+			return oob.send("`+s.ID()+`", "Char.Vitals", {hp = 10, maxhp = 20})
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsRaw()).Should(Equal(true))
+		Ω(conn.sent).Should(HaveKeyWithValue("Char.Vitals", map[string]interface{}{
+			"hp":    int64(10),
+			"maxhp": int64(20),
+		}))
+	})
+
+	It("sends data to the session playing a character", func() {
+		session.Global().Play(s, "oob-tester")
+		res, err := testReturn(engine, `
+			return oob.send_character("oob-tester", "Room.Info", {name = "Cave"}),
+				oob.broadcast("Core.Goodbye")
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsNumber()).Should(BeNumerically(">=", 1))
+		Ω(res[1].AsRaw()).Should(Equal(true))
+		Ω(conn.sent).Should(HaveKey("Room.Info"))
+		Ω(conn.sent).Should(HaveKeyWithValue("Core.Goodbye", BeNil()))
+	})
+
+	It("returns false without a session", func() {
+		res, err := testReturn(engine, `return oob.send("missing", "Char.Vitals")`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsRaw()).Should(Equal(false))
+	})
+})
@@ -0,0 +1,154 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+
+	"github.com/bbuck/dragon-mud/game/path"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Path finds routes through the world for mob AI, tracking skills and any
+// other system that needs to move from one room to another. The search is
+// A* when a heuristic is given and a uniform cost search otherwise. Rooms can
+// be IDs (strings or numbers) or tables with an id field, tables are given
+// back to the exits and heuristic functions and in the result as is.
+//   find(from, to, opts): table
+//     @param from: any = the room to start from
+//     @param to: any = the destination room
+//     @param opts: table = options describing the world and the searcher
+//       exits: function(room): table = required, returns a list of exits
+//         leading out of the room, each exit is a table with the keys:
+//           to: any = the room the exit leads to
+//           name: string = the name of the exit, like "north"
+//           cost: number = 1 = cost of moving through the exit
+//           closed: boolean = false = there is a closed door on the exit
+//           locked: boolean = false = the door on the exit is locked
+//       heuristic: function(room, to): number = estimate of the remaining
+//         cost from room to the destination, must never overestimate
+//       ignore_closed: boolean = false = avoid exits with closed doors
+//       can_unlock: boolean = false = allow moving through locked doors
+//       door_cost: number = 0 = extra cost for moving through doors
+//       max_nodes: number = 10000 = the most rooms to look at before giving
+//         up
+//     @errors raises an error if exits is missing or if exits or heuristic
+//       raise an error
+//     returns nil and a message if no path is found, otherwise a table with
+//     rooms (every room along the path, including from and to), exits (the
+//     name of each exit taken) and cost.
+var Path = lua.TableMap{
+	"find": func(engine *lua.Engine) int {
+		opts := engine.PopTable()
+		to := engine.PopValue()
+		from := engine.PopValue()
+
+		exitsFn := opts.Get("exits")
+		if !exitsFn.IsFunction() {
+			engine.ArgumentError(3, "exits must be a function")
+
+			return 0
+		}
+
+		rooms := make(map[path.Node]*lua.Value)
+		nodeFor := func(room *lua.Value) path.Node {
+			key := pathNodeKey(room)
+			if _, ok := rooms[key]; !ok {
+				rooms[key] = room
+			}
+
+			return key
+		}
+
+		options := path.Options{
+			IgnoreClosed: opts.Get("ignore_closed").IsTrue(),
+			CanUnlock:    opts.Get("can_unlock").IsTrue(),
+			Exits: func(n path.Node) ([]path.Exit, error) {
+				ret, err := exitsFn.Call(1, rooms[n])
+				if err != nil {
+					return nil, err
+				}
+
+				var exits []path.Exit
+				ret[0].ForEach(func(_, exit *lua.Value) {
+					exits = append(exits, path.Exit{
+						Name:   exit.Get("name").AsString(),
+						To:     nodeFor(exit.Get("to")),
+						Cost:   exit.Get("cost").AsNumber(),
+						Closed: exit.Get("closed").IsTrue(),
+						Locked: exit.Get("locked").IsTrue(),
+					})
+				})
+
+				return exits, nil
+			},
+		}
+		if cost := opts.Get("door_cost"); cost.IsNumber() {
+			options.DoorCost = cost.AsNumber()
+		}
+		if limit := opts.Get("max_nodes"); limit.IsNumber() {
+			options.MaxNodes = int(limit.AsNumber())
+		}
+
+		var callbackErr error
+		if heuristic := opts.Get("heuristic"); heuristic.IsFunction() {
+			options.Heuristic = func(a, b path.Node) float64 {
+				ret, err := heuristic.Call(1, rooms[a], rooms[b])
+				if err != nil {
+					callbackErr = err
+
+					return 0
+				}
+
+				return ret[0].AsNumber()
+			}
+		}
+
+		result, err := path.Find(nodeFor(from), nodeFor(to), options)
+		if callbackErr != nil {
+			err = callbackErr
+		}
+		if err == path.ErrNoPath || err == path.ErrSearchLimit {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		roomList := engine.NewTable()
+		roomList.Append(rooms[result.Start])
+		exitList := engine.NewTable()
+		for _, step := range result.Steps {
+			roomList.Append(rooms[step.Room])
+			exitList.Append(step.Exit)
+		}
+
+		tbl := engine.NewTable()
+		tbl.Set("rooms", roomList)
+		tbl.Set("exits", exitList)
+		tbl.Set("cost", result.Cost)
+		engine.PushValue(tbl)
+
+		return 1
+	},
+}
+
+// rooms given as tables are identified by their id field, everything else is
+// identified by its value
+func pathNodeKey(room *lua.Value) path.Node {
+	if room.IsTable() {
+		room = room.Get("id")
+	}
+
+	switch room.AsRaw().(type) {
+	case string, float64, bool:
+		return room.AsRaw()
+	default:
+		return fmt.Sprint(room.AsRaw())
+	}
+}
@@ -0,0 +1,82 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "path")
+		engine.DoString(`
+			path = require("path")
+
+			world = {
+				town = {{name = "north", to = "gate"}, {name = "east", to = "shop", closed = true}},
+				gate = {{name = "north", to = "forest", locked = true}},
+				shop = {{name = "up", to = "forest", cost = 3}},
+				forest = {},
+			}
+
+			function exits(room)
+				return world[room]
+			end
+		`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("finds a path around locked doors", func() {
+		res, err := testReturn(engine, `
+			local p = path.find("town", "forest", {exits = exits})
+
+			return {p.cost, p.exits[1], p.exits[2], p.rooms[1], p.rooms[2], p.rooms[3]}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(4), "east", "up", "town", "shop", "forest"}))
+	})
+
+	It("returns nil when no path exists", func() {
+		res, err := testReturn(engine, `
+			local p, msg = path.find("town", "forest", {exits = exits, ignore_closed = true})
+
+			return {p == nil, msg}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "no path exists between the rooms"}))
+	})
+
+	It("supports rooms given as tables", func() {
+		res, err := testReturn(engine, `
+			local rooms = {}
+			rooms.a = {id = 1, exits = {}}
+			rooms.b = {id = 2, exits = {}}
+			table.insert(rooms.a.exits, {name = "south", to = rooms.b})
+			local p = path.find(rooms.a, rooms.b, {
+				exits = function(room) return room.exits end,
+				heuristic = function(room, to) return 0 end,
+			})
+
+			return {p.rooms[2] == rooms.b, p.exits[1]}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, "south"}))
+	})
+
+	It("raises an error without an exits function", func() {
+		err := engine.DoString(`path.find("town", "forest", {})`)
+
+		Ω(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,68 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/perm"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Perm lets scripts see what players are allowed to do, so plugins can keep
+// their own powers to the staff, and define the roles players are given.
+//   role(name): string
+//     returns the role of the player, "player" if they haven't been given one.
+//   level(name): string
+//     returns the command level of the player, "player", "builder", "staff"
+//       or "admin".
+//   can(name, capability): boolean
+//     returns whether the player's role has the capability, like "goto".
+//   assign(name, role): boolean, string
+//     gives the player the role, returning false and why if there's no such
+//       role.
+//   define(role): boolean, string
+//     @param role: table = the role's name, level and a list of capabilities,
+//       "*" being all of them
+//     adds the role, replacing any with its name, returning false and why if
+//       it doesn't make sense.
+//   roles(): table
+//     returns the names of the roles, from the lowest level to the highest.
+var Perm = lua.TableMap{
+	"role": func(name string) string {
+		return perm.Global().RoleOf(name).Name
+	},
+	"level": func(name string) string {
+		return perm.Global().Level(name).String()
+	},
+	"can": func(name, capability string) bool {
+		return perm.Global().Can(name, capability)
+	},
+	"assign": func(engine *lua.Engine) int {
+		role := engine.PopString()
+		name := engine.PopString()
+
+		return pushResult(engine, perm.Global().Assign(name, role))
+	},
+	"define": func(engine *lua.Engine) int {
+		t := engine.PopTable()
+		r := perm.Role{
+			Name:  t.Get("name").AsString(),
+			Level: t.Get("level").AsString(),
+		}
+		if capabilities := t.Get("capabilities"); capabilities.IsTable() {
+			capabilities.ForEach(func(_, c *lua.Value) {
+				r.Capabilities = append(r.Capabilities, c.AsString())
+			})
+		}
+
+		return pushResult(engine, perm.Global().Define(r))
+	},
+	"roles": func(engine *lua.Engine) int {
+		var names []string
+		for _, r := range perm.Global().Roles() {
+			names = append(names, r.Name)
+		}
+		engine.PushValue(engine.TableFromSlice(names))
+
+		return 1
+	},
+}
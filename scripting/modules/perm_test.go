@@ -0,0 +1,41 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/perm"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Perm Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "perm")
+		engine.DoString(`perm = require("perm")`)
+	})
+
+	AfterEach(func() {
+		perm.Global().Assign("LuaWarden", perm.PlayerRole)
+		engine.Close()
+	})
+
+	It("defines roles, gives them out and checks them", func() {
+		res, err := testReturn(engine, `
+			local ok = perm.define({name = "lua-warden", level = "staff", capabilities = {"goto", "jail"}})
+			local bad, why = perm.define({name = "lua-jester", level = "fool"})
+			local before = perm.role("LuaWarden")
+			local assigned = perm.assign("LuaWarden", "lua-warden")
+			local missing = perm.assign("LuaWarden", "lua-king")
+			return {ok, bad, why ~= nil, before, assigned, missing, perm.role("luawarden"),
+				perm.level("LuaWarden"), perm.can("LuaWarden", "jail"), perm.can("LuaWarden", "force")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{
+			true, false, true, "player", true, false, "lua-warden", "staff", true, false,
+		}))
+	})
+})
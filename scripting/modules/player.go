@@ -0,0 +1,309 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Player gives scripts the same view of the players in the game as the
+// server, changes made here are saved along with the player. Each function
+// takes the name of a character in the game and does nothing, returning nil
+// or false, for characters who aren't.
+//   online(name): boolean
+//     returns whether the character is in the game.
+//   list(): table
+//     returns the names of the players in the game, sorted.
+//   info(name): table
+//     returns the player's name, account, race, class, location and created
+//     time.
+//   stat(name, stat): number
+//     returns the value of the stat, 0 if it isn't set.
+//   set_stat(name, stat, value)
+//     changes the stat.
+//   add_stat(name, stat, delta): number
+//     adds to the stat, returning its new value.
+//   stats(name): table
+//     returns every stat by name.
+//   location(name): string
+//     returns the id of the room the player is in.
+//   set_location(name, room)
+//     moves the player to the room with the id.
+//   items(name): table
+//     returns the ids of the items the player carries.
+//   give(name, proto[, count]): string
+//     gives the player count (default 1) of the item defined with the id,
+//     returning the new item's id or nil if there's no such player or item.
+//   take(name, item): boolean
+//     removes the item with the id, or one made from the definition with the
+//     id, from the player's inventory, returning false if they didn't have
+//     it.
+//   flag(name, flag): boolean
+//     returns whether the flag is set.
+//   set_flag(name, flag, on)
+//     sets or clears the flag.
+//   get(name, key): any
+//     returns a value kept for the player by scripts.
+//   set(name, key, value)
+//     keeps a value for the player, it must be a string, number, boolean or
+//     a table of them. nil removes it.
+//   save(name): boolean
+//     @errors raises an error if the player can't be saved
+//     saves the player now if they changed, returning false if they aren't
+//     in the game.
+//   version(): number
+//     returns the version players are saved with, the highest version of the
+//     upgrades.
+//   upgrade(version, name, fn): boolean, string
+//     @param version: number = the version saves have once upgraded, starting
+//       at 1, upgrades run from the lowest version to the highest
+//     @param name: string = what the upgrade changes
+//     @param fn: function(save): table = given the save of a player older
+//       than the version as a table, changes it to the version, returning the
+//       new save or nil if it changed the table given
+//     registers the upgrade, returning false and why if another upgrade has
+//     the version. Players are upgraded as they're loaded.
+var Player = lua.TableMap{
+	"online": func(name string) bool {
+		return player.Global().Get(name) != nil
+	},
+	"list": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, p := range player.Global().Players() {
+			list.Append(p.Name())
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"info": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		r := p.Record()
+		tbl := engine.NewTable()
+		tbl.Set("name", r.Name)
+		tbl.Set("account", r.Account)
+		tbl.Set("race", r.Race)
+		tbl.Set("class", r.Class)
+		tbl.Set("location", r.Location)
+		tbl.Set("created", r.Created.Unix())
+		engine.PushValue(tbl)
+
+		return 1
+	},
+	"stat": func(engine *lua.Engine) int {
+		stat := engine.PopString()
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(p.Stat(stat))
+
+		return 1
+	},
+	"set_stat": func(name, stat string, value int) {
+		if p := player.Global().Get(name); p != nil {
+			p.SetStat(stat, value)
+		}
+	},
+	"add_stat": func(engine *lua.Engine) int {
+		delta := engine.PopInt()
+		stat := engine.PopString()
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(p.AddStat(stat, delta))
+
+		return 1
+	},
+	"stats": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(statsTable(engine, p.Stats()))
+
+		return 1
+	},
+	"location": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(p.Location())
+
+		return 1
+	},
+	"set_location": func(name, room string) {
+		if p := player.Global().Get(name); p != nil {
+			p.SetLocation(room)
+		}
+	},
+	"items": func(engine *lua.Engine) int {
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		list := engine.NewTable()
+		for _, it := range p.Inventory() {
+			list.Append(it.ID)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+	"give": func(engine *lua.Engine) int {
+		count := 1
+		if engine.StackSize() >= 3 {
+			count = engine.PopInt()
+		}
+		proto := engine.PopString()
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		it, err := item.Create(world.Global(), proto, count)
+		if err != nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		p.AddItem(it)
+		engine.PushValue(it.ID)
+
+		return 1
+	},
+	"take": func(name, id string) bool {
+		p := player.Global().Get(name)
+		if p == nil {
+			return false
+		}
+		_, ok := p.RemoveItem(id)
+
+		return ok
+	},
+	"flag": func(name, flag string) bool {
+		p := player.Global().Get(name)
+
+		return p != nil && p.Flag(flag)
+	},
+	"set_flag": func(name, flag string, on bool) {
+		if p := player.Global().Get(name); p != nil {
+			p.SetFlag(flag, on)
+		}
+	},
+	"get": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(p.Var(key))
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		value := engine.PopValue()
+		key := engine.PopString()
+		p := player.Global().Get(engine.PopString())
+		if p == nil {
+			return 0
+		}
+		if value.IsNil() {
+			p.SetVar(key, nil)
+		} else {
+			p.SetVar(key, value.AsRaw())
+		}
+
+		return 0
+	},
+	"save": func(engine *lua.Engine) int {
+		m := player.Global()
+		p := m.Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(false)
+
+			return 1
+		}
+		if err := m.Save(p); err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		engine.PushValue(true)
+
+		return 1
+	},
+	"version": func() int {
+		return player.Upgrades().Current()
+	},
+	"upgrade": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		version := engine.PopInt()
+
+		return pushResult(engine, player.Upgrades().Register(player.Upgrade{
+			Version: version,
+			Name:    name,
+			Up: func(save map[string]interface{}) error {
+				tbl := serializedToLua(engine, save).(*lua.Value)
+				ret, err := fn.Call(1, tbl)
+				if err != nil {
+					return err
+				}
+				if len(ret) > 0 && ret[0].IsTable() {
+					tbl = ret[0]
+				}
+				upgraded := upgradedSave(tbl.AsMapStringInterface())
+				for k := range save {
+					delete(save, k)
+				}
+				for k, v := range upgraded {
+					save[k] = v
+				}
+
+				return nil
+			},
+		}))
+	},
+}
+
+// upgradedSave prepares a save an upgrade script changed for decoding, Lua
+// can't tell empty lists from empty tables so both are dropped and load as
+// empty
+func upgradedSave(save map[string]interface{}) map[string]interface{} {
+	cleaned := make(map[string]interface{}, len(save))
+	for k, v := range serializableValue(save).(map[string]interface{}) {
+		if m, ok := v.(map[string]interface{}); ok {
+			if len(m) == 0 {
+				continue
+			}
+			v = upgradedSave(m)
+		}
+		cleaned[k] = v
+	}
+
+	return cleaned
+}
@@ -0,0 +1,97 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Player Lua Module", func() {
+	var (
+		engine *lua.Engine
+		p      *player.Player
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "player")
+		engine.DoString(`player = require("player")`)
+		p = player.New(player.Record{Name: "Luatester", Race: "Elf", Stats: map[string]int{"hp": 10}})
+		player.Global().Add(p)
+		world.Global().AddZone(world.Zone{ID: "luatest", Name: "Lua Test"})
+		world.Global().SetItem(world.ItemDef{ID: "torch-1", Zone: "luatest", Name: "a torch"})
+		world.Global().SetItem(world.ItemDef{ID: "rope-1", Zone: "luatest", Name: "a rope"})
+	})
+
+	AfterEach(func() {
+		player.Global().Add(player.New(player.Record{Name: "Luatester"}))
+		engine.Close()
+	})
+
+	It("shares stats with Go", func() {
+		res, err := testReturn(engine, `
+			player.set_stat("luatester", "mana", 5)
+			return {player.stat("luatester", "hp"), player.add_stat("luatester", "hp", -3), player.online("luatester")}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(10), float64(7), true}))
+		Ω(p.Stat("mana")).Should(Equal(5))
+		Ω(p.Dirty()).Should(BeTrue())
+	})
+
+	It("moves players and changes their items and flags", func() {
+		err := engine.DoString(`
+			player.set_location("luatester", "temple")
+			player.give("luatester", "torch-1")
+			rope = player.give("luatester", "rope-1")
+			missing = player.give("luatester", "nothing")
+			took = player.take("luatester", "torch-1")
+			player.set_flag("luatester", "afk", true)
+			player.set("luatester", "title", "the Brave")
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(engine.GetGlobal("took").AsBool()).Should(BeTrue())
+		Ω(p.Location()).Should(Equal("temple"))
+		Ω(engine.GetGlobal("missing").IsNil()).Should(BeTrue())
+		Ω(p.Inventory()).Should(HaveLen(1))
+		Ω(p.Inventory()[0].ID).Should(Equal(engine.GetGlobal("rope").AsString()))
+		Ω(p.HasItem("rope-1")).Should(BeTrue())
+		Ω(p.Flag("afk")).Should(BeTrue())
+		Ω(p.Var("title")).Should(Equal("the Brave"))
+	})
+
+	It("registers upgrades of player saves", func() {
+		defer player.Upgrades().Unregister(900)
+		res, err := testReturn(engine, `
+			return player.upgrade(900, "titles", function(save)
+				save.vars = {title = save.title}
+				save.title = nil
+				save.inventory = {}
+			end)
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsBool()).Should(BeTrue())
+		res, err = testReturn(engine, `return player.version()`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsNumber()).Should(Equal(float64(900)))
+
+		r, err := player.Upgrades().Decode([]byte(`{"name": "Ori", "title": "the Scribe", "inventory": [], "stats": {"hp": 7}}`))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Version).Should(Equal(900))
+		Ω(r.Vars).Should(Equal(map[string]interface{}{"title": "the Scribe"}))
+		Ω(r.Stats).Should(Equal(map[string]int{"hp": 7}))
+	})
+
+	It("returns nil for players who aren't online", func() {
+		res, err := testReturn(engine, `return {player.stat("nobody", "hp") == nil, player.online("nobody"), player.info("luatester").race}`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false, "Elf"}))
+	})
+})
@@ -0,0 +1,77 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/presence"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Presence lets scripts see who's playing and change how who and finger
+// look.
+//   who(filter...): table
+//     returns the names of the players in the game matching every filter,
+//       a race, class, flag or the start of a name.
+//   finger(name): string
+//     returns what finger shows about the player, nil if there's no such
+//       player.
+//   history(name): table
+//     returns the player's remembered logins, oldest first, each with in and
+//       out (unix seconds, out is 0 while they're on) and address.
+//   template(name, text): boolean, string
+//     @param name: string = "who", "who_line" or "finger"
+//     @param text: string = the template, empty to go back to the default
+//     replaces the template, returning false and why if it doesn't render.
+var Presence = lua.TableMap{
+	"who": func(engine *lua.Engine) int {
+		filters := make([]string, engine.StackSize())
+		for i := len(filters) - 1; i >= 0; i-- {
+			filters[i] = engine.PopString()
+		}
+		var names []string
+		for _, p := range presence.Global().Who(filters...) {
+			names = append(names, p.Name)
+		}
+		engine.PushValue(engine.TableFromSlice(names))
+
+		return 1
+	},
+	"finger": func(engine *lua.Engine) int {
+		text, ok, err := presence.Global().Finger(engine.PopString())
+		if err != nil {
+			log("presence").WithError(err).WithField("engine", nameForEngine(engine)).Error("Failed to render finger.")
+		}
+		if !ok || err != nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(text)
+
+		return 1
+	},
+	"history": func(engine *lua.Engine) int {
+		logins, _ := presence.Global().History(engine.PopString())
+		t := engine.NewTable()
+		for _, l := range logins {
+			lt := engine.NewTable()
+			lt.Set("in", l.In.Unix())
+			out := int64(0)
+			if !l.Out.IsZero() {
+				out = l.Out.Unix()
+			}
+			lt.Set("out", out)
+			lt.Set("address", l.Address)
+			t.Append(lt)
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"template": func(engine *lua.Engine) int {
+		text := engine.PopString()
+		name := engine.PopString()
+
+		return pushResult(engine, presence.Global().SetTemplate(name, text))
+	},
+}
@@ -0,0 +1,43 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/presence"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Presence Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "presence")
+		engine.DoString(`presence = require("presence")`)
+		presence.Global().SetStore(presence.NewMemoryStore())
+		presence.Global().SetProfiles(func() []presence.Profile {
+			return []presence.Profile{{Name: "Lua-Ann", Race: "elf", Online: true}, {Name: "Lua-Bob", Race: "orc", Online: true}}
+		}, func(name string) (presence.Profile, bool) {
+			return presence.Profile{Name: "Lua-Ann", Race: "elf"}, name == "lua-ann"
+		})
+	})
+
+	AfterEach(func() {
+		presence.Global().SetProfiles(nil, nil)
+		presence.Global().SetTemplate(presence.FingerTemplate, "")
+		engine.Close()
+	})
+
+	It("lists players, their logins and renders finger", func() {
+		Ω(presence.Global().Login("lua-ann", "10.0.0.1")).Should(Succeed())
+		res, err := testReturn(engine, `
+			local ok = presence.template("finger", "{{name}} is an {{race}}")
+			local logins = presence.history("lua-ann")
+			return {#presence.who(), presence.who("orc")[1], logins[1].address, logins[1].out, ok, presence.finger("lua-ann")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(2), "Lua-Bob", "10.0.0.1", float64(0), true, "Lua-Ann is an elf"}))
+	})
+})
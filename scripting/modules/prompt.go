@@ -0,0 +1,143 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/prompt"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Prompt lets plugins add prompt variables and keep players' prompts up to
+// date. Prompts are rendered again whenever a stat they show is set, so
+// scripts should set stats as they change rather than redrawing prompts.
+//   register(code, stat, description[, format])
+//     @param code: string = the letter following % in templates, like "g"
+//     @param stat: string = the stat the variable shows, like "gold"
+//     @param description: string = what the variable shows, listed for
+//       players by the prompt command
+//     @param format: function(stats) = optional function given the player's
+//       stats, as a table, returning the text to show
+//     @errors raises an error if the code isn't a letter or is already used
+//     adds the variable.
+//   set(session, stat, value): boolean
+//     @param session: string = the id of the player's session
+//     @param stat: string = the name of the stat
+//     @param value: any = the new value
+//     updates a stat of the player's prompt, returning false if they don't
+//     have one.
+//   template(session[, template]): string
+//     @param session: string = the id of the player's session
+//     @param template: string = optional new template, like "%h hp> "
+//     @errors raises an error if the template uses unknown variables
+//     returns the player's template, after changing it if one was given, or
+//     nil if they don't have a prompt.
+//   render(session): string
+//     @param session: string = the id of the player's session
+//     returns the player's prompt as it's shown, or nil if they don't have
+//     one.
+//   variables(): table
+//     returns a list of the variables, each a table with a code, stat and
+//     description.
+var Prompt = lua.TableMap{
+	"register": func(engine *lua.Engine) int {
+		var format *lua.Value
+		if engine.StackSize() > 3 {
+			format = engine.PopValue()
+		}
+		description := engine.PopString()
+		stat := engine.PopString()
+		code := engine.PopString()
+		if len(code) != 1 {
+			engine.ArgumentError(1, "expected a single letter code")
+
+			return 0
+		}
+
+		v := prompt.Variable{
+			Code:        code[0],
+			Stat:        stat,
+			Description: description,
+		}
+		if format != nil && format.IsFunction() {
+			v.Format = luaPromptFormat(engine, format)
+		}
+		if err := prompt.Global().Register(v); err != nil {
+			engine.RaiseError(err.Error())
+		}
+
+		return 0
+	},
+	"set": func(id, stat string, value interface{}) bool {
+		p := prompt.GlobalManager().Get(id)
+		if p == nil {
+			return false
+		}
+		p.Set(stat, value)
+
+		return true
+	},
+	"template": func(engine *lua.Engine) int {
+		var template *lua.Value
+		if engine.StackSize() > 1 {
+			template = engine.PopValue()
+		}
+		p := prompt.GlobalManager().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		if template != nil && template.IsString() {
+			if err := p.SetTemplate(template.AsString()); err != nil {
+				engine.RaiseError(err.Error())
+
+				return 0
+			}
+		}
+		engine.PushValue(p.Template())
+
+		return 1
+	},
+	"render": func(engine *lua.Engine) int {
+		p := prompt.GlobalManager().Get(engine.PopString())
+		if p == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(p.String())
+
+		return 1
+	},
+	"variables": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, v := range prompt.Global().Variables() {
+			tbl := engine.NewTable()
+			tbl.Set("code", string(v.Code))
+			tbl.Set("stat", v.Stat)
+			tbl.Set("description", v.Description)
+			list.Append(tbl)
+		}
+		engine.PushValue(list)
+
+		return 1
+	},
+}
+
+// luaPromptFormat renders a variable with the function on the engine that
+// registered it
+func luaPromptFormat(engine *lua.Engine, fn *lua.Value) func(prompt.Stats) string {
+	return func(stats prompt.Stats) string {
+		tbl := engine.NewTable()
+		for name, value := range stats {
+			tbl.Set(name, value)
+		}
+
+		ret, err := fn.Call(1, tbl)
+		if err != nil || len(ret) == 0 {
+			return "?"
+		}
+
+		return ret[0].AsString()
+	}
+}
@@ -0,0 +1,90 @@
+package modules_test
+
+import (
+	"bytes"
+
+	"github.com/bbuck/dragon-mud/game/prompt"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/server/output"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prompt Lua Module", func() {
+	var (
+		engine *lua.Engine
+		out    *output.Writer
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "prompt")
+		engine.DoString(`prompt = require("prompt")`)
+		out = output.NewWriter(new(bytes.Buffer), 0)
+		p, err := prompt.GlobalManager().Attach("prompt-tester", out)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.SetTemplate("%h hp> ")).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		prompt.GlobalManager().Remove("prompt-tester")
+		engine.Close()
+	})
+
+	It("sets stats of players' prompts", func() {
+		err := engine.DoString(`
+			ok = prompt.set("prompt-tester", "hp", 42)
+			missing = prompt.set("nobody", "hp", 1)
+			shown = prompt.render("prompt-tester")
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(engine.GetGlobal("ok").AsBool()).Should(BeTrue())
+		Ω(engine.GetGlobal("missing").AsBool()).Should(BeFalse())
+		Ω(engine.GetGlobal("shown").AsString()).Should(Equal("42 hp> "))
+	})
+
+	It("changes players' templates", func() {
+		err := engine.DoString(`
+			before = prompt.template("prompt-tester")
+			after = prompt.template("prompt-tester", "%H max> ")
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(engine.GetGlobal("before").AsString()).Should(Equal("%h hp> "))
+		Ω(engine.GetGlobal("after").AsString()).Should(Equal("%H max> "))
+		Ω(engine.DoString(`prompt.template("prompt-tester", "%q")`)).ShouldNot(Succeed())
+	})
+
+	It("registers variables rendered by Lua", func() {
+		err := engine.DoString(`
+			prompt.register("x", "xp", "experience to level", function(stats)
+				return tostring(stats.tnl - stats.xp) .. " tnl"
+			end)
+			prompt.template("prompt-tester", "%x> ")
+			prompt.set("prompt-tester", "tnl", 100)
+			prompt.set("prompt-tester", "xp", 40)
+			shown = prompt.render("prompt-tester")
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(engine.GetGlobal("shown").AsString()).Should(Equal("60 tnl> "))
+		Ω(engine.DoString(`prompt.register("x", "xp", "again")`)).ShouldNot(Succeed())
+	})
+
+	It("lists variables", func() {
+		err := engine.DoString(`
+			found = false
+			for _, v in ipairs(prompt.variables()) do
+				if v.code == "h" and v.stat == "hp" then
+					found = true
+				end
+			end
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(engine.GetGlobal("found").AsBool()).Should(BeTrue())
+	})
+})
@@ -0,0 +1,192 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Quest lets scripts define quests and move players through them, so quests
+// can be written without any files.
+//   define(quest): boolean, string
+//     @param quest: table = the quest's id, name, summary, giver, level,
+//       requires, repeatable, objectives and reward, as in quest files
+//     adds the quest, replacing any with its id, returning false and why if
+//     it doesn't make sense.
+//   start(player, quest): boolean, string
+//     puts the player on the quest, returning false and why if they can't.
+//   progress(player, kind, target[, n]): number
+//     counts n, or one, of the kind done to the target towards the player's
+//     quests, like progress(id, "talk", "baker"). Returns how many quests
+//     moved forward.
+//   complete(player, quest): boolean, string
+//     finishes the player's quest, giving its reward.
+//   abandon(player, quest): boolean, string
+//     takes the player off the quest.
+//   status(player, quest): string, table
+//     returns "active" or "completed" and how much of each objective is done,
+//     nil if the player never started it.
+//   get(quest): table
+//     returns the definition of the quest, nil if there isn't one.
+//   all(): table
+//     returns the ids of every quest, sorted by name.
+var Quest = lua.TableMap{
+	"define": func(engine *lua.Engine) int {
+		return pushResult(engine, quest.Global().Defs().Add(questFromTable(engine.PopTable())))
+	},
+	"start": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		q := questor(engine.PopString())
+		if q == nil {
+			engine.PushValue(false)
+			engine.PushValue(quest.UnknownMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, quest.Global().Start(q, id))
+	},
+	"progress": func(engine *lua.Engine) int {
+		n := 1
+		if engine.StackSize() >= 4 {
+			n = engine.PopInt()
+		}
+		target := engine.PopString()
+		kind := engine.PopString()
+		advanced := 0
+		if q := questor(engine.PopString()); q != nil {
+			advanced = quest.Global().Progress(q, kind, target, n)
+		}
+		engine.PushValue(advanced)
+
+		return 1
+	},
+	"complete": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		q := questor(engine.PopString())
+		if q == nil {
+			engine.PushValue(false)
+			engine.PushValue(quest.NotOnMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, quest.Global().Complete(q, id))
+	},
+	"abandon": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		q := questor(engine.PopString())
+		if q == nil {
+			engine.PushValue(false)
+			engine.PushValue(quest.NotOnMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, quest.Global().Abandon(q, id))
+	},
+	"status": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		q := questor(engine.PopString())
+		if q == nil {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		s, ok := q.Quest(id)
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(s.Status)
+		engine.PushValue(engine.TableFromSlice(s.Progress))
+
+		return 2
+	},
+	"get": func(engine *lua.Engine) int {
+		def, ok := quest.Global().Defs().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		t := engine.NewTable()
+		t.Set("id", def.ID)
+		t.Set("name", def.Name)
+		t.Set("summary", def.Summary)
+		t.Set("giver", def.Giver)
+		t.Set("level", def.Level)
+		t.Set("requires", engine.TableFromSlice(def.Requires))
+		t.Set("repeatable", def.Repeatable)
+		objectives := engine.NewTable()
+		for _, o := range def.Objectives {
+			ot := engine.NewTable()
+			ot.Set("kind", o.Kind)
+			ot.Set("target", o.Target)
+			ot.Set("count", o.Count)
+			ot.Set("description", o.Description)
+			objectives.Append(ot)
+		}
+		t.Set("objectives", objectives)
+		reward := engine.NewTable()
+		reward.Set("stats", engine.TableFromMap(def.Reward.Stats))
+		reward.Set("items", engine.TableFromMap(def.Reward.Items))
+		reward.Set("message", def.Reward.Message)
+		t.Set("reward", reward)
+		engine.PushValue(t)
+
+		return 1
+	},
+	"all": func(engine *lua.Engine) int {
+		var ids []string
+		for _, def := range quest.Global().Defs().All() {
+			ids = append(ids, def.ID)
+		}
+		engine.PushValue(engine.TableFromSlice(ids))
+
+		return 1
+	},
+}
+
+// questor returns the combatant with the id if they go on quests
+func questor(id string) quest.Questor {
+	q, _ := combat.Global().Lookup(id).(quest.Questor)
+
+	return q
+}
+
+func questFromTable(t *lua.Value) quest.Def {
+	def := quest.Def{
+		ID:         t.Get("id").AsString(),
+		Name:       t.Get("name").AsString(),
+		Summary:    t.Get("summary").AsString(),
+		Giver:      t.Get("giver").AsString(),
+		Level:      int(t.Get("level").AsNumber()),
+		Repeatable: t.Get("repeatable").IsTrue(),
+	}
+	if requires := t.Get("requires"); requires.IsTable() {
+		requires.ForEach(func(_, v *lua.Value) {
+			def.Requires = append(def.Requires, v.AsString())
+		})
+	}
+	if objectives := t.Get("objectives"); objectives.IsTable() {
+		objectives.ForEach(func(_, o *lua.Value) {
+			def.Objectives = append(def.Objectives, quest.Objective{
+				Kind:        o.Get("kind").AsString(),
+				Target:      o.Get("target").AsString(),
+				Count:       int(o.Get("count").AsNumber()),
+				Description: o.Get("description").AsString(),
+			})
+		})
+	}
+	if reward := t.Get("reward"); reward.IsTable() {
+		def.Reward.Stats = intMap(reward.Get("stats"))
+		def.Reward.Items = intMap(reward.Get("items"))
+		def.Reward.Message = reward.Get("message").AsString()
+	}
+
+	return def
+}
@@ -0,0 +1,92 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// adventurer is a brawler who goes on quests
+type adventurer struct {
+	brawler
+	quests map[string]quest.State
+}
+
+func (a *adventurer) Quest(id string) (quest.State, bool) {
+	s, ok := a.quests[id]
+
+	return s, ok
+}
+
+func (a *adventurer) SetQuest(id string, s quest.State) {
+	a.quests[id] = s
+}
+
+func (a *adventurer) Quests() map[string]quest.State {
+	quests := make(map[string]quest.State)
+	for id, s := range a.quests {
+		quests[id] = s.Copy()
+	}
+
+	return quests
+}
+
+var _ = Describe("Quest Lua Module", func() {
+	var (
+		engine *lua.Engine
+		hero   *adventurer
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "quest")
+		engine.DoString(`quest = require("quest")`)
+		hero = &adventurer{brawler: brawler{id: "hero", stats: map[string]int{}}, quests: map[string]quest.State{}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "hero" {
+				return hero
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("defines quests and moves players through them", func() {
+		res, err := testReturn(engine, `
+			local _, invalid = quest.define({id = "lua-broken"})
+			quest.define({
+				id = "lua-bread",
+				name = "Bread Run",
+				objectives = {
+					{kind = "talk", target = "baker"},
+					{kind = "collect", target = "bread", count = 2},
+				},
+				reward = {stats = {xp = 10}},
+			})
+			local started = quest.start("hero", "lua-bread")
+			local moved = quest.progress("hero", "talk", "baker")
+			local status, progress = quest.status("hero", "lua-bread")
+			return {invalid ~= nil, started, moved, status, progress[1], progress[2], quest.get("lua-bread").objectives[2].count}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, true, float64(1), quest.Active, float64(1), float64(0), float64(2)}))
+
+		res, err = testReturn(engine, `
+			quest.progress("hero", "collect", "bread", 2)
+			local _, why = quest.abandon("hero", "lua-bread")
+			return {quest.status("hero", "lua-bread"), why}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{quest.Completed, quest.NotOnMessage}))
+		Ω(hero.stats["xp"]).Should(Equal(10))
+	})
+})
@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/queue"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Queue runs heavyweight work, like sending mass mail or generating parts of
+// the world, in the background instead of while handling player input. Jobs
+// are saved as they're enqueued so they survive a restart, and failed jobs
+// are retried with an increasing delay.
+//   enqueue(name[, payload[, options]]): string
+//     @param name: string = the name of the worker that will run the job
+//     @param payload: table = data passed to the worker, it's saved as JSON
+//       so it may only contain strings, numbers, booleans and tables
+//     @param options: table = optional, supports "delay" (seconds before the
+//       job is first run) and "max_attempts" (runs before the job is failed)
+//     adds a job to the queue and returns its id.
+//   worker(name, fn)
+//     @param name: string = the name of the jobs this worker runs
+//     @param fn: function(payload, job) = called for each job, raising an
+//       error retries the job. job contains the id, name and attempt number.
+//     registers the worker for the jobs, replacing any existing worker. Jobs
+//     wait in the queue until a worker is registered.
+//   pending(): number
+//     returns the number of jobs waiting to run.
+//   failed(): table
+//     returns a list of jobs that ran out of attempts, each with id, name,
+//     payload, attempts and error.
+//   retry(id): boolean
+//     @param id: string = the id of a failed job
+//     runs a failed job again, returning false if no failed job has the id.
+var Queue = lua.TableMap{
+	"enqueue": func(engine *lua.Engine) int {
+		var opts *lua.Value
+		if engine.StackSize() >= 3 {
+			opts = engine.PopValue()
+		}
+		var payload *lua.Value
+		if engine.StackSize() >= 2 {
+			payload = engine.PopValue()
+		}
+		name := engine.PopString()
+		if name == "" {
+			engine.ArgumentError(1, "job name cannot be empty")
+
+			return 0
+		}
+
+		data, ok := queuePayload(payload)
+		if !ok {
+			engine.ArgumentError(2, "expected a table with string keys")
+
+			return 0
+		}
+
+		var options queue.Options
+		if opts != nil && opts.IsTable() {
+			options.Delay = secondsToDuration(opts.Get("delay").AsNumber())
+			options.MaxAttempts = int(opts.Get("max_attempts").AsNumber())
+		}
+
+		id, err := queue.Global().Enqueue(name, data, options)
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(id)
+
+		return 1
+	},
+	"worker": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		name := engine.PopString()
+		if !fn.IsFunction() {
+			engine.ArgumentError(2, "expected a function")
+
+			return 0
+		}
+
+		queue.Global().Register(name, func(job *queue.Job) error {
+			info := engine.TableFromMap(map[string]interface{}{
+				"id":       job.ID,
+				"name":     job.Name,
+				"attempts": job.Attempts,
+			})
+			if _, err := fn.Call(0, serializedToLua(engine, job.Payload), info); err != nil {
+				log("queue").WithError(err).WithField("engine", nameForEngine(engine)).WithField("job", job.ID).Debug("Queue worker raised an error.")
+
+				return err
+			}
+
+			return nil
+		})
+
+		return 0
+	},
+	"pending": func() int {
+		return queue.Global().Pending()
+	},
+	"failed": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, job := range queue.Global().Failed() {
+			tbl := engine.NewTable()
+			tbl.Set("id", job.ID)
+			tbl.Set("name", job.Name)
+			tbl.Set("payload", serializedToLua(engine, job.Payload))
+			tbl.Set("attempts", job.Attempts)
+			tbl.Set("error", job.LastError)
+			list.Append(tbl)
+		}
+
+		engine.PushValue(list)
+
+		return 1
+	},
+	"retry": func(id string) bool {
+		return queue.Global().Retry(id)
+	},
+}
+
+// convert a Lua payload into a map that can be saved, nil payloads and empty
+// tables become empty maps
+func queuePayload(payload *lua.Value) (map[string]interface{}, bool) {
+	if payload == nil || payload.IsNil() {
+		return make(map[string]interface{}), true
+	}
+	if !payload.IsTable() {
+		return nil, false
+	}
+
+	switch raw := serializableValue(payload.AsRaw()).(type) {
+	case map[string]interface{}:
+		return raw, true
+	case []interface{}:
+		if len(raw) == 0 {
+			return make(map[string]interface{}), true
+		}
+	}
+
+	return nil, false
+}
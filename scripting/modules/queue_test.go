@@ -0,0 +1,81 @@
+package modules_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/bbuck/dragon-mud/queue"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queue Lua Module", func() {
+	var (
+		engine *lua.Engine
+		dir    string
+	)
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "queue")
+		viper.Set("queue.dir", dir)
+		// jobs are run explicitly so they don't run on the engine while the
+		// test is using it
+		queue.Global().Stop()
+
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "queue")
+		engine.DoString(`queue = require("queue")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("runs enqueued jobs with the worker", func() {
+		engine.DoString(`
+			sent = {}
+			queue.worker("test:mail", function(payload, job)
+				sent[#sent + 1] = payload.to .. ":" .. job.attempts
+			end)
+			queue.enqueue("test:mail", {to = "bob"})
+		`)
+		queue.Global().RunDue()
+
+		res, err := testReturn(engine, `return sent`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"bob:1"}))
+	})
+
+	It("lists jobs that failed", func() {
+		engine.DoString(`
+			queue.worker("test:fail", function()
+				error("no luck")
+			end)
+			id = queue.enqueue("test:fail", {n = 1}, {max_attempts = 1})
+		`)
+		queue.Global().RunDue()
+
+		res, err := testReturn(engine, `
+			for _, job in ipairs(queue.failed()) do
+				if job.id == id then
+					return {job.name, job.payload.n, job.attempts}
+				end
+			end
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"test:fail", float64(1), float64(1)}))
+	})
+
+	It("rejects payloads that aren't tables", func() {
+		err := engine.DoString(`queue.enqueue("test:mail", "bob")`)
+
+		Ω(err).ShouldNot(BeNil())
+	})
+})
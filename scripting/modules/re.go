@@ -0,0 +1,323 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+const (
+	// maxRegexpLength is the longest pattern scripts are allowed to compile.
+	maxRegexpLength = 1024
+
+	// maxRegexpMatches caps the number of matches returned from find_all and
+	// performed by replace and split so a single call can't build giant tables.
+	maxRegexpMatches = 1000
+
+	// maxRegexpCacheSize is the number of compiled patterns kept around, once
+	// reached the cache is cleared and starts filling again.
+	maxRegexpCacheSize = 256
+)
+
+var (
+	regexpCache      = make(map[string]*regexp.Regexp)
+	regexpCacheMutex sync.Mutex
+)
+
+// Re exposes Go's regular expressions (RE2 syntax) to Lua, which are far more
+// capable than Lua patterns and guaranteed to run in time linear to the size
+// of the input. Patterns longer than 1024 characters are rejected and no
+// function will produce more than 1000 matches. All functions that take a
+// pattern accept either a pattern string or a compiled regexp.
+//   compile(pattern): re.Regexp
+//     @param pattern: string = the regular expression to compile
+//     @errors raises an error if the pattern is invalid or too long
+//     returns a compiled regexp with the same functions as this module minus
+//     the pattern argument, so rx.match(str) instead of re.match(pattern, str)
+//   match(pattern, str): boolean
+//     @param pattern: string | re.Regexp = the expression to test with
+//     @param str: string = the string to test
+//     returns whether the str matches the pattern anywhere.
+//   find(pattern, str): re.Match
+//     @param pattern: string | re.Regexp = the expression to search with
+//     @param str: string = the string to search
+//     returns the first match in str or nil if nothing matched.
+//   find_all(pattern, str[, limit]): table
+//     @param pattern: string | re.Regexp = the expression to search with
+//     @param str: string = the string to search
+//     @param limit: number = 1000 = the maximum number of matches to find
+//     returns a list of all matches found in str
+//   replace(pattern, str, replacement): string
+//     @param pattern: string | re.Regexp = the expression to search with
+//     @param str: string = the string to perform replacements in
+//     @param replacement: string | function(re.Match): string = the value
+//       to replace matches with, strings can refer to captures with $1 or
+//       ${name} and functions are given the match and return its replacement
+//     returns str with every match replaced.
+//   split(pattern, str[, limit]): table
+//     @param pattern: string | re.Regexp = the expression to split on
+//     @param str: string = the string to split
+//     @param limit: number = 1000 = the maximum number of parts to return
+//     returns a list of the substrings between matches.
+//   escape(str): string
+//     @param str: string = text that should be matched literally
+//     returns str with all regular expression characters escaped.
+//   re.Match
+//     match: string = the full text that was matched
+//     start: number = the index of the first character of the match
+//     stop: number = the index of the last character of the match
+//     captures: table = list of the values of each capture group, groups that
+//       did not participate in the match are empty strings
+//     named: table = map of named capture groups to their values
+var Re = lua.TableMap{
+	"compile": func(engine *lua.Engine) int {
+		rx, err := fetchRx(engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(regexpTable(engine, rx))
+
+		return 1
+	},
+	"match": func(engine *lua.Engine) int {
+		str := engine.PopString()
+		rx := popRegexp(engine)
+		if rx == nil {
+			return 0
+		}
+
+		return reMatch(engine, rx, str)
+	},
+	"find": func(engine *lua.Engine) int {
+		str := engine.PopString()
+		rx := popRegexp(engine)
+		if rx == nil {
+			return 0
+		}
+
+		return reFind(engine, rx, str)
+	},
+	"find_all": func(engine *lua.Engine) int {
+		limit := popRegexpLimit(engine, 3)
+		str := engine.PopString()
+		rx := popRegexp(engine)
+		if rx == nil {
+			return 0
+		}
+
+		return reFindAll(engine, rx, str, limit)
+	},
+	"replace": func(engine *lua.Engine) int {
+		repl := engine.PopValue()
+		str := engine.PopString()
+		rx := popRegexp(engine)
+		if rx == nil {
+			return 0
+		}
+
+		return reReplace(engine, rx, str, repl)
+	},
+	"split": func(engine *lua.Engine) int {
+		limit := popRegexpLimit(engine, 3)
+		str := engine.PopString()
+		rx := popRegexp(engine)
+		if rx == nil {
+			return 0
+		}
+
+		return reSplit(engine, rx, str, limit)
+	},
+	"escape": func(str string) string {
+		return regexp.QuoteMeta(str)
+	},
+}
+
+// fetchRx compiles the pattern, reusing previously compiled patterns when
+// possible.
+func fetchRx(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxRegexpLength {
+		return nil, fmt.Errorf("regular expression is longer than %d characters", maxRegexpLength)
+	}
+
+	regexpCacheMutex.Lock()
+	defer regexpCacheMutex.Unlock()
+
+	if r, ok := regexpCache[pattern]; ok {
+		return r, nil
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(regexpCache) >= maxRegexpCacheSize {
+		regexpCache = make(map[string]*regexp.Regexp)
+	}
+	regexpCache[pattern] = r
+
+	return r, nil
+}
+
+// popRegexp pulls either a pattern string or a compiled regexp table off the
+// stack, raising an error and returning nil if it's not valid.
+func popRegexp(engine *lua.Engine) *regexp.Regexp {
+	val := engine.PopValue()
+	if val.IsTable() {
+		val = val.Get("pattern")
+	}
+
+	rx, err := fetchRx(val.AsString())
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return nil
+	}
+
+	return rx
+}
+
+// popRegexpLimit pops the optional limit if there are n arguments on the
+// stack, otherwise returning the maximum number of matches.
+func popRegexpLimit(engine *lua.Engine, n int) int {
+	if engine.StackSize() < n {
+		return maxRegexpMatches
+	}
+
+	limit := engine.PopInt()
+	if limit <= 0 || limit > maxRegexpMatches {
+		return maxRegexpMatches
+	}
+
+	return limit
+}
+
+// build the Lua table wrapping a compiled regexp
+func regexpTable(engine *lua.Engine, rx *regexp.Regexp) *lua.Value {
+	tbl := engine.NewTable()
+	tbl.Set("pattern", rx.String())
+	tbl.Set("match", func(eng *lua.Engine) int {
+		return reMatch(eng, rx, eng.PopString())
+	})
+	tbl.Set("find", func(eng *lua.Engine) int {
+		return reFind(eng, rx, eng.PopString())
+	})
+	tbl.Set("find_all", func(eng *lua.Engine) int {
+		limit := popRegexpLimit(eng, 2)
+
+		return reFindAll(eng, rx, eng.PopString(), limit)
+	})
+	tbl.Set("replace", func(eng *lua.Engine) int {
+		repl := eng.PopValue()
+
+		return reReplace(eng, rx, eng.PopString(), repl)
+	})
+	tbl.Set("split", func(eng *lua.Engine) int {
+		limit := popRegexpLimit(eng, 2)
+
+		return reSplit(eng, rx, eng.PopString(), limit)
+	})
+
+	return tbl
+}
+
+func reMatch(engine *lua.Engine, rx *regexp.Regexp, str string) int {
+	engine.PushValue(rx.MatchString(str))
+
+	return 1
+}
+
+func reFind(engine *lua.Engine, rx *regexp.Regexp, str string) int {
+	loc := rx.FindStringSubmatchIndex(str)
+	if loc == nil {
+		engine.PushValue(engine.Nil())
+
+		return 1
+	}
+
+	engine.PushValue(regexpMatchTable(engine, rx, str, loc))
+
+	return 1
+}
+
+func reFindAll(engine *lua.Engine, rx *regexp.Regexp, str string, limit int) int {
+	list := engine.NewTable()
+	for _, loc := range rx.FindAllStringSubmatchIndex(str, limit) {
+		list.Append(regexpMatchTable(engine, rx, str, loc))
+	}
+
+	engine.PushValue(list)
+
+	return 1
+}
+
+func reReplace(engine *lua.Engine, rx *regexp.Regexp, str string, repl *lua.Value) int {
+	locs := rx.FindAllStringSubmatchIndex(str, maxRegexpMatches)
+
+	var (
+		result []byte
+		last   int
+	)
+	for _, loc := range locs {
+		result = append(result, str[last:loc[0]]...)
+		if repl.IsFunction() {
+			ret, err := repl.Call(1, regexpMatchTable(engine, rx, str, loc))
+			if err != nil {
+				engine.RaiseError(err.Error())
+
+				return 0
+			}
+			result = append(result, ret[0].AsString()...)
+		} else {
+			result = rx.ExpandString(result, repl.AsString(), str, loc)
+		}
+		last = loc[1]
+	}
+	result = append(result, str[last:]...)
+
+	engine.PushValue(string(result))
+
+	return 1
+}
+
+func reSplit(engine *lua.Engine, rx *regexp.Regexp, str string, limit int) int {
+	engine.PushValue(engine.TableFromSlice(rx.Split(str, limit)))
+
+	return 1
+}
+
+// convert a match location into a re.Match table
+func regexpMatchTable(engine *lua.Engine, rx *regexp.Regexp, str string, loc []int) *lua.Value {
+	match := engine.NewTable()
+	match.Set("match", str[loc[0]:loc[1]])
+	match.Set("start", loc[0]+1)
+	match.Set("stop", loc[1])
+
+	captures := engine.NewTable()
+	named := engine.NewTable()
+	for i, name := range rx.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+
+		var capture string
+		if loc[2*i] >= 0 {
+			capture = str[loc[2*i]:loc[2*i+1]]
+		}
+		captures.Append(capture)
+		if name != "" {
+			named.Set(name, capture)
+		}
+	}
+	match.Set("captures", captures)
+	match.Set("named", named)
+
+	return match
+}
@@ -0,0 +1,118 @@
+package modules_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Re Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "re")
+		engine.DoString(`re = require("re")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("match()",
+		func(pattern, str string, expected bool) {
+			res, err := testReturn(engine, fmt.Sprintf("return re.match(%q, %q)", pattern, str))
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsBool()).Should(Equal(expected))
+		},
+		Entry("matches a simple pattern", `^say (.+)$`, "say hello", true),
+		Entry("fails to match", `^say (.+)$`, "tell bob hello", false),
+		Entry("supports flags", `(?i)^LOOK$`, "look", true))
+
+	It("raises an error for invalid patterns", func() {
+		err := engine.DoString(`re.match("(a", "a")`)
+
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("rejects patterns that are too long", func() {
+		err := engine.DoString(fmt.Sprintf("re.compile(%q)", strings.Repeat("a", 2000)))
+
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("finds matches with captures", func() {
+		res, err := testReturn(engine, `
+			local m = re.find("(?P<verb>\\w+) (\\w+)", "  get sword")
+
+			return {m.match, m.start, m.stop, m.captures[1], m.captures[2], m.named.verb}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"get sword", float64(3), float64(11), "get", "sword", "get"}))
+	})
+
+	It("returns nil when nothing is found", func() {
+		res, err := testReturn(engine, `return re.find("\\d", "abc")`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].IsNil()).Should(BeTrue())
+	})
+
+	It("finds all matches up to a limit", func() {
+		res, err := testReturn(engine, `
+			local found = {}
+			for _, m in ipairs(re.find_all("\\d+", "1 22 333", 2)) do
+				table.insert(found, m.match)
+			end
+
+			return found
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"1", "22"}))
+	})
+
+	DescribeTable("replace()",
+		func(script, expected string) {
+			res, err := testReturn(engine, script)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsString()).Should(Equal(expected))
+		},
+		Entry("with a template", `return re.replace("(\\w+)@(\\w+)", "bob@home", "$2:$1")`, "home:bob"),
+		Entry("with named captures", `return re.replace("(?P<n>\\d+)", "a1b2", "<${n}>")`, "a<1>b<2>"),
+		Entry("with a function", `return re.replace("\\w+", "hi there", function(m) return m.match:upper() end)`, "HI THERE"))
+
+	It("splits strings", func() {
+		res, err := testReturn(engine, `return re.split(",\\s*", "a, b,c")`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"a", "b", "c"}))
+	})
+
+	It("escapes patterns", func() {
+		res, err := testReturn(engine, `return re.match(re.escape("1+1"), "1+1=2")`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsBool()).Should(BeTrue())
+	})
+
+	It("works with compiled expressions", func() {
+		res, err := testReturn(engine, `
+			local rx = re.compile("^(n|s|e|w)$")
+
+			return {rx.match("n"), rx.match("up"), re.match(rx, "e"), rx.find("w").captures[1]}
+		`)
+
+		Ω(err).Should(BeNil())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, false, true, "w"}))
+	})
+})
@@ -0,0 +1,96 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/sched"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Sched provides cron style scheduling of recurring work, such as nightly
+// restocks or weekly lottery draws. Schedules use standard five field cron
+// expressions ("minute hour day-of-month month day-of-week") in server time
+// along with the descriptors @hourly, @daily, @weekly, @monthly, @yearly and
+// "@every <duration>" (like "@every 10m").
+//   cron(spec, fn): string
+//     @param spec: string = the cron expression defining when to run fn
+//     @param fn: function = the function to run when the schedule is due
+//     @errors raises an error if the cron expression is invalid
+//     schedules fn and returns a generated name for the job that can be used
+//     to cancel it.
+//   job(name, spec, fn): string
+//     @param name: string = the unique name of this job
+//     @param spec: string = the cron expression defining when to run fn
+//     @param fn: function = the function to run when the schedule is due
+//     @errors raises an error if the cron expression is invalid
+//     schedules a named job, replacing any existing job with the same name.
+//     Scripts run in several engines (like server scripts) should always use
+//     named jobs so the job is only scheduled once.
+//   cancel(name): boolean
+//     @param name: string = the name of the job to cancel
+//     stops the job from running again, returns true if the job existed.
+//   list(): table
+//     returns a list of all scheduled jobs as tables containing the name,
+//     spec and next (a unix timestamp of the next run) of the job.
+var Sched = lua.TableMap{
+	"cron": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		spec := engine.PopString()
+
+		return scheduleLuaJob(engine, "", spec, fn, 2)
+	},
+	"job": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		spec := engine.PopString()
+		name := engine.PopString()
+		if name == "" {
+			engine.ArgumentError(1, "job name cannot be empty")
+
+			return 0
+		}
+
+		return scheduleLuaJob(engine, name, spec, fn, 3)
+	},
+	"cancel": func(name string) bool {
+		return sched.Global().Cancel(name)
+	},
+	"list": func(engine *lua.Engine) int {
+		list := engine.NewTable()
+		for _, info := range sched.Global().Jobs() {
+			tbl := engine.NewTable()
+			tbl.Set("name", info.Name)
+			tbl.Set("spec", info.Spec)
+			tbl.Set("next", info.Next.Unix())
+			list.Append(tbl)
+		}
+
+		engine.PushValue(list)
+
+		return 1
+	},
+}
+
+// add the job to the scheduler, the function is run on the engine that
+// scheduled it. fnArg is the argument position of fn for error reporting.
+func scheduleLuaJob(engine *lua.Engine, name, spec string, fn *lua.Value, fnArg int) int {
+	if !fn.IsFunction() {
+		engine.ArgumentError(fnArg, "expected a function")
+
+		return 0
+	}
+
+	name, err := sched.Global().Add(name, spec, func() {
+		if _, err := fn.Call(0); err != nil {
+			log("sched").WithError(err).WithField("engine", nameForEngine(engine)).Error("Scheduled job failed.")
+		}
+	})
+	if err != nil {
+		engine.RaiseError(err.Error())
+
+		return 0
+	}
+
+	engine.PushValue(name)
+
+	return 1
+}
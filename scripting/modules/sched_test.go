@@ -0,0 +1,63 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/sched"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sched Module", func() {
+	var (
+		e   *lua.Engine
+		err error
+	)
+
+	BeforeEach(func() {
+		e = lua.NewEngine()
+		scripting.OpenLibs(e, "sched")
+		err = e.DoString(`
+			sched = require("sched")
+
+			sched.job("sched_module_test", "0 3 * * *", function() end)
+			generated = sched.cron("@hourly", function() end)
+			jobs = sched.list()
+		`)
+	})
+
+	AfterEach(func() {
+		sched.Global().Cancel("sched_module_test")
+		sched.Global().Cancel(e.GetGlobal("generated").AsString())
+		e.Close()
+	})
+
+	It("doesn't fail", func() {
+		Ω(err).Should(BeNil())
+	})
+
+	It("schedules named and generated jobs", func() {
+		names := make([]string, 0)
+		for _, info := range sched.Global().Jobs() {
+			names = append(names, info.Name)
+		}
+		Ω(names).Should(ContainElement("sched_module_test"))
+		Ω(names).Should(ContainElement(e.GetGlobal("generated").AsString()))
+	})
+
+	It("lists jobs", func() {
+		Ω(e.GetGlobal("jobs").AsSliceInterface()).ShouldNot(BeEmpty())
+	})
+
+	It("cancels jobs", func() {
+		err = e.DoString(`cancelled = sched.cancel("sched_module_test")`)
+		Ω(err).Should(BeNil())
+		Ω(e.GetGlobal("cancelled").AsBool()).Should(BeTrue())
+	})
+
+	It("raises errors for invalid expressions", func() {
+		err = e.DoString(`sched.cron("not cron", function() end)`)
+		Ω(err).ShouldNot(BeNil())
+	})
+})
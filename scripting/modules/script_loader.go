@@ -1,18 +1,26 @@
 package modules
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/bbuck/dragon-mud/assets"
 	"github.com/bbuck/dragon-mud/scripting/lua"
 )
 
+// ScriptLoader returns a loader that registers the Lua module bundled in the
+// assets at scriptName as a preloaded module, the module is required by the
+// name of the file without the extension (so "modules/tbl.lua" is "tbl").
 func ScriptLoader(scriptName string) func(*lua.Engine) {
-	script := string(assets.MustAsset("modules/fn.lua"))
+	script := string(assets.MustAsset(scriptName))
+	name := strings.TrimSuffix(filepath.Base(scriptName), ".lua")
+
 	return func(eng *lua.Engine) {
 		mod, err := eng.LoadString(script)
 		if err != nil {
 			log("script_loader").WithError(err).WithField("file", scriptName).Fatal("Failed to load script file in engine")
 		}
 
-		eng.GetEnviron().Get("package").Get("preload").RawSet("fn", mod)
+		eng.GetEnviron().Get("package").Get("preload").RawSet(name, mod)
 	}
 }
@@ -0,0 +1,211 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+// SerializeLoader registers the serialize module with a nested table for each
+// supported format.
+func SerializeLoader(engine *lua.Engine) {
+	mod := engine.RegisterModule("serialize", Serialize)
+
+	formats := map[string]struct {
+		encode func(interface{}, bool) (string, error)
+		decode func(string) (interface{}, error)
+	}{
+		"json": {encodeJSON, decodeJSON},
+		"yaml": {encodeYAML, decodeYAML},
+		"toml": {encodeTOML, decodeTOML},
+	}
+	for name, format := range formats {
+		encode, decode := format.encode, format.decode
+		tbl := engine.NewTable()
+		tbl.Set("encode", func(eng *lua.Engine) int {
+			pretty := false
+			if eng.StackSize() >= 2 {
+				pretty = eng.PopBool()
+			}
+
+			str, err := encode(serializableValue(eng.PopValue().AsRaw()), pretty)
+			if err != nil {
+				eng.RaiseError(err.Error())
+
+				return 0
+			}
+
+			eng.PushValue(str)
+
+			return 1
+		})
+		tbl.Set("decode", func(eng *lua.Engine) int {
+			val, err := decode(eng.PopString())
+			if err != nil {
+				eng.RaiseError(err.Error())
+
+				return 0
+			}
+
+			eng.PushValue(serializedToLua(eng, val))
+
+			return 1
+		})
+		mod.RawSet(name, tbl)
+	}
+}
+
+// Serialize converts Lua values to and from common text formats so tooling
+// scripts can read and write area files, plugin manifests and data exchanged
+// with web services. Each format is a nested table with identical functions.
+// Tables with a value at index 1 are treated as lists, all other tables are
+// treated as maps. Functions and other values that can't be represented are
+// dropped when encoding.
+//   json, yaml, toml
+//     encode(value[, pretty]): string
+//       @param value: any = the value to encode, TOML requires a map
+//       @param pretty: boolean = false = indent JSON output, YAML and TOML
+//         are always indented
+//       @errors raises an error if the value cannot be encoded
+//       returns value encoded in the format.
+//     decode(str): any
+//       @param str: string = the text to parse
+//       @errors raises an error if str is not valid for the format
+//       returns the decoded value, dates and times are returned as RFC 3339
+//       strings.
+var Serialize = lua.TableMap{}
+
+func encodeJSON(v interface{}, pretty bool) (string, error) {
+	var (
+		bs  []byte
+		err error
+	)
+	if pretty {
+		bs, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		bs, err = json.Marshal(v)
+	}
+
+	return string(bs), err
+}
+
+func decodeJSON(str string) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal([]byte(str), &v)
+
+	return v, err
+}
+
+func encodeYAML(v interface{}, _ bool) (string, error) {
+	bs, err := yaml.Marshal(v)
+
+	return string(bs), err
+}
+
+func decodeYAML(str string) (interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal([]byte(str), &v)
+
+	return v, err
+}
+
+func encodeTOML(v interface{}, _ bool) (string, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("toml can only encode tables with string keys, not %T", v)
+	}
+
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return "", err
+	}
+
+	return tree.ToTomlString()
+}
+
+func decodeTOML(str string) (interface{}, error) {
+	tree, err := toml.Load(str)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree.ToMap(), nil
+}
+
+// serializableValue prepares values from Lua for encoding, whole numbers are
+// converted to integers so they aren't written as 1.0 or 1e+06 and map keys
+// without a value (such as functions) are removed.
+func serializableValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < math.MaxInt64 {
+			return int64(t)
+		}
+
+		return t
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if val != nil {
+				m[k] = serializableValue(val)
+			}
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = serializableValue(val)
+		}
+
+		return s
+	default:
+		return v
+	}
+}
+
+// serializedToLua converts decoded values into Lua values, building tables
+// for any maps or slices.
+func serializedToLua(engine *lua.Engine, v interface{}) interface{} {
+	if v == nil {
+		return engine.Nil()
+	}
+
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		tbl := engine.NewTable()
+		for _, key := range rv.MapKeys() {
+			tbl.Set(fmt.Sprint(key.Interface()), serializedToLua(engine, rv.MapIndex(key).Interface()))
+		}
+
+		return tbl
+	case reflect.Slice, reflect.Array:
+		tbl := engine.NewTable()
+		for i := 0; i < rv.Len(); i++ {
+			tbl.Append(serializedToLua(engine, rv.Index(i).Interface()))
+		}
+
+		return tbl
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,61 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Serialize Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "serialize")
+		engine.DoString(`serialize = require("serialize")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	DescribeTable("encode()",
+		func(script, expected string) {
+			res, err := testReturn(engine, script)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsString()).Should(Equal(expected))
+		},
+		Entry("json", `return serialize.json.encode({name = "Bob", level = 3, tags = {"a", "b"}})`, `{"level":3,"name":"Bob","tags":["a","b"]}`),
+		Entry("pretty json", `return serialize.json.encode({1.5, true}, true)`, "[\n  1.5,\n  true\n]"),
+		Entry("yaml", `return serialize.yaml.encode({name = "Bob", level = 3})`, "level: 3\nname: Bob\n"),
+		Entry("toml", `return serialize.toml.encode({name = "Bob", level = 3})`, "level = 3\nname = \"Bob\"\n"))
+
+	DescribeTable("decode()",
+		func(format, input string) {
+			engine.SetGlobal("input", input)
+			res, err := testReturn(engine, `
+				local v = serialize.`+format+`.decode(input)
+
+				return {v.area.name, v.area.rooms, v.area.exits[2]}
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"Town", float64(12), "south"}))
+		},
+		Entry("json", "json", `{"area": {"name": "Town", "rooms": 12, "exits": ["north", "south"]}}`),
+		Entry("yaml", "yaml", "area:\n  name: Town\n  rooms: 12\n  exits:\n    - north\n    - south\n"),
+		Entry("toml", "toml", "[area]\nname = \"Town\"\nrooms = 12\nexits = [\"north\", \"south\"]\n"))
+
+	DescribeTable("invalid input raises errors",
+		func(script string) {
+			Ω(engine.DoString(script)).ShouldNot(BeNil())
+		},
+		Entry("json", `serialize.json.decode("{")`),
+		Entry("yaml", `serialize.yaml.decode("a: [")`),
+		Entry("toml", `serialize.toml.decode("a = ")`),
+		Entry("toml requires a map", `serialize.toml.encode({1, 2})`))
+})
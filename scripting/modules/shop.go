@@ -0,0 +1,168 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Shop lets scripts change what shops charge and trade for players.
+//   pricing(name, fn)
+//     @param name: string = names the pricer, so reloaded scripts replace it
+//     @param fn: function(quote): number = given the shop, action ("buy" or
+//       "sell"), customer, item (its id, proto, name, type and value), count,
+//       base and price of each so far, returns the new price of each. Nil
+//       keeps the price, below zero refuses the sale.
+//     sets the pricer, replacing any with the name, a nil fn removes it.
+//   buy(player, item[, count]): boolean, string
+//     buys count, or one, of the item from the shop in the player's room,
+//     returning false and why if they can't.
+//   sell(player, item[, count]): boolean, string
+//     sells count, or one, of the item the player carries.
+//   value(player, item[, count]): number, string
+//     returns what the shop in the player's room would pay, nil and why if
+//     it wouldn't.
+//   balance(player): number
+//     returns how much money the player has, in the smallest coin.
+//   format(amount): string
+//     describes the amount in coins, like "3 gold, 4 silver".
+//   parse(text): number, string
+//     reads an amount like "3 gold 4 silver", returning nil and why if it
+//     can't.
+//   restock(shop)
+//     refills the limited stock of the shop with the id.
+var Shop = lua.TableMap{
+	"pricing": func(engine *lua.Engine) int {
+		fn := engine.PopValue()
+		name := engine.PopString()
+		if !fn.IsFunction() {
+			shop.Global().SetPricer(name, nil)
+
+			return 0
+		}
+		shop.Global().SetPricer(name, func(q shop.Quote) int {
+			quote := engine.NewTable()
+			quote.Set("shop", q.Shop)
+			quote.Set("action", q.Action)
+			quote.Set("customer", q.Customer)
+			it := engine.NewTable()
+			it.Set("id", q.Item.ID)
+			it.Set("proto", q.Item.Proto)
+			it.Set("name", q.Item.Name)
+			it.Set("type", q.Item.Type)
+			it.Set("value", q.Item.Value)
+			quote.Set("item", it)
+			quote.Set("count", q.Count)
+			quote.Set("base", q.Base)
+			quote.Set("price", q.Price)
+			ret, err := fn.Call(1, quote)
+			if err != nil {
+				log("shop").WithError(err).WithField("engine", nameForEngine(engine)).Error("Shop pricer failed.")
+
+				return q.Price
+			}
+			if len(ret) == 0 || !ret[0].IsNumber() {
+				return q.Price
+			}
+
+			return int(ret[0].AsNumber())
+		})
+
+		return 0
+	},
+	"buy": func(engine *lua.Engine) int {
+		count, keyword, c := tradeArgs(engine)
+		if c == nil {
+			engine.PushValue(false)
+			engine.PushValue(shop.NoShopMessage)
+
+			return 2
+		}
+		_, err := shop.Global().Buy(c, keyword, count)
+
+		return pushResult(engine, err)
+	},
+	"sell": func(engine *lua.Engine) int {
+		count, keyword, c := tradeArgs(engine)
+		if c == nil {
+			engine.PushValue(false)
+			engine.PushValue(shop.NoShopMessage)
+
+			return 2
+		}
+		_, err := shop.Global().Sell(c, keyword, count)
+
+		return pushResult(engine, err)
+	},
+	"value": func(engine *lua.Engine) int {
+		count, keyword, c := tradeArgs(engine)
+		if c == nil {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(shop.NoShopMessage)
+
+			return 2
+		}
+		_, r, err := shop.Global().Value(c, keyword, count)
+		if err != nil {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+		engine.PushValue(r.Total)
+
+		return 1
+	},
+	"balance": func(engine *lua.Engine) int {
+		balance := 0
+		if c := customer(engine.PopString()); c != nil {
+			balance = shop.Global().Balance(c)
+		}
+		engine.PushValue(balance)
+
+		return 1
+	},
+	"format": func(engine *lua.Engine) int {
+		engine.PushValue(shop.Global().Currency().Format(engine.PopInt()))
+
+		return 1
+	},
+	"parse": func(engine *lua.Engine) int {
+		amount, err := shop.Global().Currency().Parse(engine.PopString())
+		if err != nil {
+			engine.PushValue(engine.Nil())
+			engine.PushValue(err.Error())
+
+			return 2
+		}
+		engine.PushValue(amount)
+
+		return 1
+	},
+	"restock": func(engine *lua.Engine) int {
+		shop.Global().Restock(engine.PopString())
+
+		return 0
+	},
+}
+
+// tradeArgs pops the player, item and optional count given to buy, sell and
+// value
+func tradeArgs(engine *lua.Engine) (int, string, shop.Customer) {
+	count := 1
+	if engine.StackSize() >= 3 {
+		count = engine.PopInt()
+	}
+	keyword := engine.PopString()
+
+	return count, keyword, customer(engine.PopString())
+}
+
+// customer returns the combatant with the id if they can trade
+func customer(id string) shop.Customer {
+	c, _ := combat.Global().Lookup(id).(shop.Customer)
+
+	return c
+}
@@ -0,0 +1,87 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// shopper is a brawler who carries what they buy
+type shopper struct {
+	brawler
+	inventory item.List
+}
+
+func (s *shopper) Inventory() item.List {
+	return s.inventory.Copy()
+}
+
+func (s *shopper) UpdateInventory(fn func(item.List) (item.List, error)) error {
+	l, err := fn(s.inventory.Copy())
+	if err == nil {
+		s.inventory = l
+	}
+
+	return err
+}
+
+var _ = Describe("Shop Lua Module", func() {
+	var (
+		engine *lua.Engine
+		patron *shopper
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "shop")
+		engine.DoString(`shop = require("shop")`)
+		patron = &shopper{brawler: brawler{id: "patron", stats: map[string]int{"coins": 100}}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "patron" {
+				return patron
+			}
+
+			return nil
+		})
+		world.Global().AddZone(world.Zone{ID: "lua-market", Name: "The Market"})
+		Ω(world.Global().SetItem(world.ItemDef{ID: "lua-apple", Zone: "lua-market", Name: "an apple", Type: "food", Value: 5, Flags: []string{"stackable"}})).Should(Succeed())
+		Ω(shop.Global().Defs().Add(shop.Def{ID: "lua-stall", Keeper: "lua-grocer", Stock: []shop.Stock{{Item: "lua-apple"}}, Buys: []string{"food"}})).Should(Succeed())
+		shop.Global().SetNearby(func(string) []string {
+			return []string{"lua-grocer"}
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		shop.Global().SetNearby(nil)
+		shop.Global().SetPricer("lua-haggle", nil)
+		engine.Close()
+	})
+
+	It("prices sales and trades for players", func() {
+		res, err := testReturn(engine, `
+			shop.pricing("lua-haggle", function(quote)
+				if quote.action == "buy" and quote.item.proto == "lua-apple" then
+					return quote.price * 2
+				end
+			end)
+			local bought = shop.buy("patron", "apple", 3)
+			local value = shop.value("patron", "apple", 2)
+			local sold = shop.sell("patron", "apple")
+			local _, why = shop.sell("patron", "pear")
+			local amount = shop.parse("1 gold 2 copper")
+			return {bought, value, sold, why, shop.balance("patron"), shop.format(amount)}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, float64(4), true, shop.NotCarriedMessage, float64(72), "1 gold, 2 copper"}))
+		Ω(patron.inventory).Should(HaveLen(1))
+		Ω(patron.inventory[0].Quantity()).Should(Equal(2))
+	})
+})
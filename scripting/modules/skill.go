@@ -0,0 +1,153 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Skill lets scripts write what skills and spells do and teach them to
+// players. Skills are defined in the files of the skills directory, a handler
+// written here runs each time one works.
+//   handle(skill, fn)
+//     @param skill: string = the id of the skill
+//     @param fn: function(use): boolean, string = run with a table of the
+//       skill's id, the user and target ids, the args typed after the target
+//       and the user's proficiency, it returns false and a message to tell
+//       the user when the skill can't be used that way
+//     sets what the skill does, replacing its handler.
+//   use(user, skill[, args]): boolean, string
+//     has the character use the skill they know, on the target named first
+//     in args, returning false and why if it didn't work.
+//   learn(user, skill, percent): boolean
+//     sets how well the character knows the skill, 0 forgets it. Returns
+//     false if there's no such character.
+//   known(user, skill): number
+//     returns how well the character knows the skill, as a percent.
+//   get(skill): table
+//     returns the definition of the skill, nil if there isn't one.
+//   all(): table
+//     returns the ids of every skill, sorted by name.
+var Skill = lua.TableMap{
+	"handle": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		id := engine.PopString()
+		skill.Global().Handle(id, func(u *skill.Use) error {
+			use := engine.NewTable()
+			use.Set("skill", u.Skill.ID)
+			use.Set("user", u.User.ID())
+			if u.Target != nil {
+				use.Set("target", u.Target.ID())
+			}
+			use.Set("args", u.Args)
+			use.Set("proficiency", u.Proficiency)
+			ret, err := fn.Call(2, use)
+			if err != nil {
+				log("skill").WithError(err).WithField("engine", nameForEngine(engine)).Error("Skill handler failed.")
+
+				return &item.Refused{Message: skill.NothingMessage}
+			}
+			if len(ret) > 0 && ret[0].IsBool() && ret[0].IsFalse() {
+				if len(ret) > 1 && ret[1].IsString() {
+					return &item.Refused{Message: ret[1].AsString()}
+				}
+
+				return &item.Refused{Message: skill.FailedMessage}
+			}
+
+			return nil
+		})
+
+		return 0
+	},
+	"use": func(engine *lua.Engine) int {
+		var args string
+		if engine.StackSize() >= 3 {
+			args = engine.PopString()
+		}
+		id := engine.PopString()
+		user := learner(engine.PopString())
+		if user == nil {
+			engine.PushValue(false)
+			engine.PushValue(skill.UnknownMessage)
+
+			return 2
+		}
+		if _, err := skill.Global().Use(user, id, args); err != nil {
+			engine.PushValue(false)
+			if r, ok := err.(*item.Refused); ok {
+				engine.PushValue(r.Message)
+			} else {
+				engine.PushValue(err.Error())
+			}
+
+			return 2
+		}
+		engine.PushValue(true)
+
+		return 1
+	},
+	"learn": func(engine *lua.Engine) int {
+		percent := engine.PopInt()
+		id := engine.PopString()
+		user := learner(engine.PopString())
+		if user != nil {
+			user.SetSkill(id, percent)
+		}
+		engine.PushValue(user != nil)
+
+		return 1
+	},
+	"known": func(engine *lua.Engine) int {
+		id := engine.PopString()
+		user := learner(engine.PopString())
+		if user == nil {
+			engine.PushValue(0)
+
+			return 1
+		}
+		engine.PushValue(user.Skill(id))
+
+		return 1
+	},
+	"get": func(engine *lua.Engine) int {
+		def, ok := skill.Global().Defs().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		t := engine.NewTable()
+		t.Set("id", def.ID)
+		t.Set("name", def.Name)
+		t.Set("kind", def.Kind)
+		t.Set("target", def.Target)
+		t.Set("cost", engine.TableFromMap(def.Cost))
+		t.Set("cooldown", def.Cooldown)
+		t.Set("lag", def.Lag)
+		t.Set("classes", engine.TableFromMap(def.Classes))
+		t.Set("help", def.Help)
+		engine.PushValue(t)
+
+		return 1
+	},
+	"all": func(engine *lua.Engine) int {
+		var ids []string
+		for _, def := range skill.Global().Defs().All() {
+			ids = append(ids, def.ID)
+		}
+		engine.PushValue(engine.TableFromSlice(ids))
+
+		return 1
+	},
+}
+
+// learner returns the combatant with the id if they can learn skills
+func learner(id string) skill.Learner {
+	l, _ := combat.Global().Lookup(id).(skill.Learner)
+
+	return l
+}
@@ -0,0 +1,76 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// student is a brawler who learns skills
+type student struct {
+	brawler
+	skills map[string]int
+}
+
+func (s *student) Class() string {
+	return "sage"
+}
+
+func (s *student) Skill(id string) int {
+	return s.skills[id]
+}
+
+func (s *student) SetSkill(id string, percent int) {
+	s.skills[id] = percent
+}
+
+var _ = Describe("Skill Lua Module", func() {
+	var (
+		engine *lua.Engine
+		sage   *student
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "skill")
+		engine.DoString(`skill = require("skill")`)
+		sage = &student{brawler: brawler{id: "sage", stats: map[string]int{"mana": 5}}, skills: map[string]int{}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "sage" {
+				return sage
+			}
+
+			return nil
+		})
+		Ω(skill.Global().Defs().Add(skill.Def{ID: "lua-chant", Name: "chant", Cost: map[string]int{"mana": 2}})).Should(Succeed())
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("teaches, handles and uses skills", func() {
+		res, err := testReturn(engine, `
+			skill.handle("lua-chant", function(use)
+				if use.args == "loudly" then
+					return false, "Hush!"
+				end
+				chanted = use.user .. " " .. use.proficiency
+			end)
+			local before = skill.use("sage", "lua-chant")
+			skill.learn("sage", "lua-chant", 100)
+			local ok = skill.use("sage", "lua-chant")
+			local _, why = skill.use("sage", "lua-chant", "loudly")
+			return {before, ok, why, chanted, skill.known("sage", "lua-chant"), skill.get("lua-chant").name}
+		`)
+
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{false, true, "Hush!", "sage 100", float64(100), "chant"}))
+		Ω(sage.stats["mana"]).Should(Equal(1))
+	})
+})
@@ -0,0 +1,98 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/social"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Social lets scripts define socials and have players and NPCs perform them.
+//   define(social): boolean, string
+//     @param social: table = the social's name and its alone, at and self
+//       messages, each a table of actor, target and room, as in social files
+//     adds the social and the command it's performed with, replacing any
+//       with its name, returning false and why if it doesn't make sense.
+//   perform(actor, social, target): boolean, string
+//     @param actor: string = the id of the player or NPC performing it
+//     @param target: string = names who in the room it's aimed at, like
+//       "guard", or "self", leave it out to aim at no one
+//     performs the social, returning false and why if it can't be.
+//   get(name): table
+//     returns the social's messages as define takes them, nil if there's no
+//       such social.
+//   all(): table
+//     returns the names of every social.
+var Social = lua.TableMap{
+	"define": func(engine *lua.Engine) int {
+		return pushResult(engine, social.Global().Define(socialFromTable(engine.PopTable())))
+	},
+	"perform": func(engine *lua.Engine) int {
+		var target string
+		if engine.StackSize() >= 3 {
+			target = engine.PopString()
+		}
+		name := engine.PopString()
+		a, ok := combat.Global().Lookup(engine.PopString()).(social.Actor)
+		if !ok {
+			engine.PushValue(false)
+			engine.PushValue(social.NotHereMessage)
+
+			return 2
+		}
+
+		return pushResult(engine, social.Global().Perform(a, name, target))
+	},
+	"get": func(engine *lua.Engine) int {
+		def, ok := social.Global().Defs().Get(engine.PopString())
+		if !ok {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		t := engine.NewTable()
+		t.Set("name", def.Name)
+		for key, msgs := range map[string]social.Messages{"alone": def.Alone, "at": def.At, "self": def.Self} {
+			mt := engine.NewTable()
+			mt.Set("actor", msgs.Actor)
+			mt.Set("target", msgs.Target)
+			mt.Set("room", msgs.Room)
+			t.Set(key, mt)
+		}
+		engine.PushValue(t)
+
+		return 1
+	},
+	"all": func(engine *lua.Engine) int {
+		var names []string
+		for _, def := range social.Global().Defs().All() {
+			names = append(names, def.Name)
+		}
+		engine.PushValue(engine.TableFromSlice(names))
+
+		return 1
+	},
+}
+
+func socialFromTable(t *lua.Value) social.Def {
+	messages := func(key string) social.Messages {
+		mt := t.Get(key)
+		if !mt.IsTable() {
+			return social.Messages{}
+		}
+
+		return social.Messages{
+			Actor:  mt.Get("actor").AsString(),
+			Target: mt.Get("target").AsString(),
+			Room:   mt.Get("room").AsString(),
+		}
+	}
+
+	return social.Def{
+		Name:  t.Get("name").AsString(),
+		Alone: messages("alone"),
+		At:    messages("at"),
+		Self:  messages("self"),
+	}
+}
@@ -0,0 +1,73 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/social"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// performer is a brawler who's told about socials
+type performer struct {
+	brawler
+	sent []string
+}
+
+func (p *performer) Send(text string) error {
+	p.sent = append(p.sent, text)
+
+	return nil
+}
+
+var _ = Describe("Social Lua Module", func() {
+	var (
+		engine       *lua.Engine
+		jester, king *performer
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "social")
+		engine.DoString(`social = require("social")`)
+		jester = &performer{brawler: brawler{id: "jester"}}
+		king = &performer{brawler: brawler{id: "king"}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			if id == "jester" {
+				return jester
+			}
+
+			return nil
+		})
+		social.Global().SetOccupants(func(room string) []social.Actor {
+			return []social.Actor{jester, king}
+		})
+	})
+
+	AfterEach(func() {
+		combat.Global().SetLookup(nil)
+		social.Global().SetOccupants(nil)
+		social.Global().Remove("lua-curtsy")
+		engine.Close()
+	})
+
+	It("defines socials and performs them", func() {
+		res, err := testReturn(engine, `
+			local _, invalid = social.define({name = "lua-curtsy"})
+			social.define({
+				name = "lua-curtsy",
+				alone = {actor = "You curtsy."},
+				at = {actor = "You curtsy to {target}.", target = "{actor} curtsies to you."},
+			})
+			local performed = social.perform("jester", "lua-curtsy", "king")
+			local _, missing = social.perform("jester", "lua-curtsy", "queen")
+			return {invalid ~= nil, performed, missing, social.get("lua-curtsy").at.target}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{true, true, social.NotHereMessage, "{actor} curtsies to you."}))
+		Ω(jester.sent).Should(Equal([]string{"You curtsy to king."}))
+		Ω(king.sent).Should(Equal([]string{"jester curtsies to you."}))
+	})
+})
@@ -0,0 +1,76 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"encoding/json"
+
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// pluginBucket prefixes the buckets of plugins, so they can't reach the
+// players and accounts the game saves in the same storage
+const pluginBucket = "plugin:"
+
+// Storage lets plugins save data in the game's storage, whichever database
+// it's in. Values are saved as JSON, so functions and other values that
+// can't be represented are dropped.
+//   get(bucket, key): any
+//     returns the value saved under the key in the bucket, nil if there's
+//     none.
+//   set(bucket, key, value): boolean, string
+//     saves the value under the key in the bucket, returning false and why
+//     if it couldn't be saved.
+//   delete(bucket, key): boolean, string
+//     removes the value under the key in the bucket, returning false and why
+//     if it couldn't be removed.
+//   keys(bucket): table
+//     returns the keys of the bucket, sorted.
+var Storage = lua.TableMap{
+	"get": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		bucket := engine.PopString()
+		var v interface{}
+		err := data.NewBucket(data.Storage(), pluginBucket+bucket).Load(key, &v)
+		if err != nil {
+			if err != data.ErrNotFound {
+				log("storage").WithError(err).WithField("bucket", bucket).WithField("engine", nameForEngine(engine)).Error("Failed to load plugin data.")
+			}
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(serializedToLua(engine, v))
+
+		return 1
+	},
+	"set": func(engine *lua.Engine) int {
+		value := serializableValue(engine.PopValue().AsRaw())
+		key := engine.PopString()
+		bucket := engine.PopString()
+		contents, err := json.Marshal(value)
+		if err == nil {
+			err = data.Storage().Put(pluginBucket+bucket, key, contents)
+		}
+
+		return pushResult(engine, err)
+	},
+	"delete": func(engine *lua.Engine) int {
+		key := engine.PopString()
+		bucket := engine.PopString()
+
+		return pushResult(engine, data.Storage().Delete(pluginBucket+bucket, key))
+	},
+	"keys": func(engine *lua.Engine) int {
+		keys, err := data.Storage().Keys(pluginBucket + engine.PopString())
+		if err != nil {
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+		engine.PushValue(engine.TableFromSlice(keys))
+
+		return 1
+	},
+}
@@ -0,0 +1,56 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage Lua Module", func() {
+	var (
+		engine *lua.Engine
+		store  *data.MemoryStore
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "storage")
+		engine.DoString(`storage = require("storage")`)
+		store = data.NewMemoryStore()
+		data.SetStorage(store)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("saves plugin data in its own buckets", func() {
+		res, err := testReturn(engine, `
+			local saved = storage.set("lua-bank", "ann", {gold = 10, items = {"sword"}})
+			storage.set("lua-bank", "bob", 5)
+			local missing = storage.get("lua-bank", "nobody")
+			local deleted = storage.delete("lua-bank", "bob")
+
+			return {
+				saved = saved,
+				gold = storage.get("lua-bank", "ann").gold,
+				item = storage.get("lua-bank", "ann").items[1],
+				missing = missing == nil,
+				deleted = deleted,
+				keys = storage.keys("lua-bank"),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("saved").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("gold").AsNumber()).Should(BeEquivalentTo(10))
+		Ω(res[0].Get("item").AsString()).Should(Equal("sword"))
+		Ω(res[0].Get("missing").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("deleted").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("keys").AsSliceInterface()).Should(Equal([]interface{}{"ann"}))
+		Ω(store.Keys("plugin:lua-bank")).Should(Equal([]string{"ann"}))
+		Ω(store.Keys("lua-bank")).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,108 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/inflect"
+	"github.com/bbuck/dragon-mud/text/strutil"
+)
+
+// Str contains string helpers for formatting game output. All width based
+// functions measure visible characters, so color codes are not counted.
+//   wrap(text, width): string
+//     @param text: string = the text to wrap
+//     @param width: number = the maximum number of visible characters per
+//       line, typically the player's terminal width
+//     breaks the text into lines at word boundaries, keeping existing line
+//     breaks.
+//   pad(text, width[, fill]): string
+//     @param text: string = the text to pad
+//     @param width: number = the visible width to fill
+//     @param fill: string = " " = the string used to fill the space
+//     left aligns the text, filling the right side up to width.
+//   lpad(text, width[, fill]): string
+//     the same as pad except the text is right aligned, filling on the left.
+//   center(text, width[, fill]): string
+//     the same as pad except the text is centered, filling on both sides.
+//   titlecase(text): string
+//     @param text: string = the text to titlecase
+//     capitalizes each word except short words (of, the, and, ...) that
+//     aren't first, "sword of the north" becomes "Sword of the North".
+//   article(word): string
+//     @param word: string = the word that will follow the article
+//     returns "a" or "an" depending on how the word sounds.
+//   with_article(word): string
+//     @param word: string = the word to prefix
+//     returns the word with it's article, like "an apple".
+//   distance(a, b): number
+//     @param a: string = the first string to compare
+//     @param b: string = the second string to compare
+//     returns the Levenshtein distance, the number of single character edits
+//     required to turn a into b.
+//   closest(input, candidates[, max_distance]): string | nil
+//     @param input: string = the (possibly misspelled) input
+//     @param candidates: table = a list of valid choices
+//     @param max_distance: number = 2 = the largest distance to consider
+//     finds the candidate closest to input, ignoring case, useful for
+//     suggesting commands. Returns nil if nothing is close enough.
+//   escape(text): string
+//     @param text: string = text to make safe for templates
+//     escapes template tags and color codes so player provided text is
+//     displayed as written when rendered or colorized.
+var Str = lua.TableMap{
+	"wrap": func(text string, width int) string {
+		return strutil.Wrap(text, width)
+	},
+	"pad": func(engine *lua.Engine) int {
+		return padString(engine, strutil.AlignLeft)
+	},
+	"lpad": func(engine *lua.Engine) int {
+		return padString(engine, strutil.AlignRight)
+	},
+	"center": func(engine *lua.Engine) int {
+		return padString(engine, strutil.AlignCenter)
+	},
+	"titlecase":    inflect.Titlecase,
+	"article":      inflect.Article,
+	"with_article": inflect.WithArticle,
+	"distance": func(a, b string) int {
+		return strutil.Levenshtein(a, b)
+	},
+	"closest": func(engine *lua.Engine) int {
+		max := 2
+		if engine.StackSize() >= 3 {
+			max = engine.PopInt()
+		}
+		candidates := engine.PopTable()
+		input := engine.PopString()
+
+		var strs []string
+		candidates.ForEach(func(_, val *lua.Value) {
+			strs = append(strs, val.AsString())
+		})
+
+		if match, ok := strutil.Closest(input, strs, max); ok {
+			engine.PushValue(match)
+		} else {
+			engine.PushValue(engine.Nil())
+		}
+
+		return 1
+	},
+	"escape": strutil.EscapeTemplate,
+}
+
+// pad the string with the given alignment, fill is optional
+func padString(engine *lua.Engine, align strutil.Alignment) int {
+	fill := " "
+	if engine.StackSize() >= 3 {
+		fill = engine.PopString()
+	}
+	width := engine.PopInt()
+	text := engine.PopString()
+
+	engine.PushValue(strutil.Pad(text, width, align, fill))
+
+	return 1
+}
@@ -0,0 +1,48 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Str Module", func() {
+	p := lua.NewEnginePool(4, func(eng *lua.Engine) {
+		scripting.OpenLibs(eng, "str")
+		eng.DoString(`str = require("str")`)
+	})
+
+	DescribeTable("results",
+		func(script string, expected interface{}) {
+			eng := p.Get()
+			defer eng.Release()
+
+			res, err := testReturn(eng.Engine, script)
+			Ω(err).Should(BeNil())
+			Ω(res).Should(HaveLen(1))
+			Ω(res[0].AsRaw()).Should(Equal(expected))
+		},
+		Entry("wrap()", `return str.wrap("the quick brown fox", 10)`, "the quick\nbrown fox"),
+		Entry("pad()", `return str.pad("hp", 4)`, "hp  "),
+		Entry("lpad()", `return str.lpad("[R]hp[x]", 4, ".")`, "..[R]hp[x]"),
+		Entry("center()", `return str.center("hp", 6, "*")`, "**hp**"),
+		Entry("titlecase()", `return str.titlecase("ring of the ancients")`, "Ring of the Ancients"),
+		Entry("article()", `return str.article("orc")`, "an"),
+		Entry("with_article()", `return str.with_article("goblin")`, "a goblin"),
+		Entry("distance()", `return str.distance("kitten", "sitting")`, float64(3)),
+		Entry("closest()", `return str.closest("lok", {"look", "inventory"})`, "look"),
+		Entry("escape()", `return str.escape("{{x}} [r]")`, "\\{{x}} [[r]]"),
+	)
+
+	It("returns nil when nothing is close", func() {
+		eng := p.Get()
+		defer eng.Release()
+
+		res, err := testReturn(eng.Engine, `return str.closest("xyzzy", {"look"})`)
+		Ω(err).Should(BeNil())
+		Ω(res[0].IsNil()).Should(BeTrue())
+	})
+})
@@ -1,14 +1,11 @@
 package modules
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/bbuck/dragon-mud/scripting/lua"
 )
 
-var regexpCache = make(map[string]*regexp.Regexp)
-
 // Sutil contains several features that Lua string handling lacks, things like
 // joining and regex matching and splitting and trimming and various other
 // things.
@@ -138,16 +135,3 @@ var Sutil = lua.TableMap{
 		return 1
 	},
 }
-
-func fetchRx(rx string) (*regexp.Regexp, error) {
-	if r, ok := regexpCache[rx]; ok {
-		return r, nil
-	}
-
-	r, err := regexp.Compile(rx)
-	if err == nil {
-		regexpCache[rx] = r
-	}
-
-	return r, err
-}
@@ -0,0 +1,113 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tbl Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "tbl")
+		engine.DoString(`tbl = require("tbl")`)
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	Describe("deep_copy()", func() {
+		It("copies nested tables", func() {
+			res, err := testReturn(engine, `
+				local orig = {a = {b = 1}}
+				local c = tbl.deep_copy(orig)
+				c.a.b = 2
+
+				return orig.a.b
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsNumber()).Should(Equal(float64(1)))
+		})
+
+		It("preserves cycles", func() {
+			res, err := testReturn(engine, `
+				local orig = {}
+				orig.self = orig
+				local c = tbl.deep_copy(orig)
+
+				return c.self == c and c ~= orig
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsBool()).Should(BeTrue())
+		})
+	})
+
+	Describe("deep_merge()", func() {
+		It("merges nested maps without modifying the inputs", func() {
+			res, err := testReturn(engine, `
+				local a = {stats = {str = 10, dex = 8}}
+				local b = {stats = {dex = 12}, name = "Bob"}
+				local m = tbl.deep_merge(a, b)
+
+				return {m.stats.str, m.stats.dex, m.name, a.stats.dex}
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(10), float64(12), "Bob", float64(8)}))
+		})
+	})
+
+	DescribeTable("list helpers",
+		func(script string, expected []interface{}) {
+			res, err := testReturn(engine, script)
+			var r []interface{}
+			if err == nil && len(res) > 0 {
+				r = res[0].AsSliceInterface()
+			}
+
+			Ω(err).Should(BeNil())
+			Ω(r).Should(Equal(expected))
+		},
+		Entry("map", `return tbl.map({1, 2, 3}, function(v) return v * 2 end)`, []interface{}{float64(2), float64(4), float64(6)}),
+		Entry("filter", `return tbl.filter({1, 2, 3, 4}, function(v) return v % 2 == 0 end)`, []interface{}{float64(2), float64(4)}),
+		Entry("reduce", `return {tbl.reduce({1, 2, 3}, 0, function(a, v) return a + v end)}`, []interface{}{float64(6)}),
+		Entry("unique", `return tbl.unique({"a", "b", "a", "c"})`, []interface{}{"a", "b", "c"}),
+		Entry("union", `return tbl.union({"a", "b"}, {"b", "c"})`, []interface{}{"a", "b", "c"}),
+		Entry("intersection", `return tbl.intersection({"a", "b", "c"}, {"c", "a"})`, []interface{}{"a", "c"}),
+		Entry("difference", `return tbl.difference({"a", "b", "c"}, {"b"})`, []interface{}{"a", "c"}))
+
+	Describe("sort_by()", func() {
+		It("is stable", func() {
+			res, err := testReturn(engine, `
+				local list = {
+					{name = "a", lvl = 2},
+					{name = "b", lvl = 1},
+					{name = "c", lvl = 2},
+					{name = "d", lvl = 1},
+				}
+
+				return tbl.map(tbl.sort_by(list, "lvl"), function(v) return v.name end)
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{"b", "d", "a", "c"}))
+		})
+
+		It("sorts descending with a key function", func() {
+			res, err := testReturn(engine, `
+				return tbl.sort_by({1, 3, 2}, function(v) return v end, true)
+			`)
+
+			Ω(err).Should(BeNil())
+			Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(3), float64(2), float64(1)}))
+		})
+	})
+})
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bbuck/dragon-mud/game/clock"
 	"github.com/bbuck/dragon-mud/scripting/lua"
 )
 
@@ -80,6 +81,33 @@ import (
 //     given the nature of durations being numbers, if a generated duration has
 //     overlapping periods you can expect to get different components back, for
 //     example "8d" (8 days) = {weeks = 1, days = 1}
+//   gameTimeData: table = {
+//     year: number = the game year, starting at 1,
+//     month: number = the month of the game year, starting at 1,
+//     month_name: string = the calendar name of the month,
+//     day: number = the day of the month, starting at 1,
+//     hour: number = the hour of the day (0-23 by default),
+//     minute: number = the minute of the hour (0-59),
+//     season: string = the name of the current season,
+//     sunrise: number = the hour the sun rises in the current season,
+//     sunset: number = the hour the sun sets in the current season,
+//     is_day: boolean = true if the sun is currently up
+//   }
+//   game(): gameTimeData
+//     returns the current in-game date and time as tracked by the game clock.
+//   game_string(): string
+//     returns the current game time formatted for display, like
+//     "14:05, day 3 of Highsun, year 12".
+//   is_day(): boolean
+//     returns true if the sun is up in the game world.
+//   is_night(): boolean
+//     returns true if the sun is down in the game world.
+//   calendar(): table
+//     returns the game's calendar, hours_per_day, days_per_month, months (a
+//       list of tables with a name and season) and seasons (tables with a
+//       sunrise and sunset hour, by name). While the game runs every game
+//       hour is emitted as "time:hour" with the game time, and the hours the
+//       sun rises and sets as "time:sunrise" and "time:sunset".
 //   time.Instant
 //     format(format): string
 //       @param format: string = the format that will be used to produce a
@@ -172,6 +200,46 @@ var Time = lua.TableMap{
 
 		return &iv
 	},
+	// current game clock details
+	"game": func(engine *lua.Engine) int {
+		engine.PushValue(gameTimeTable(engine, clock.Game().Now()))
+
+		return 1
+	},
+	"game_string": func() string {
+		return clock.Game().Now().String()
+	},
+	"is_day": func() bool {
+		return clock.Game().Now().IsDay()
+	},
+	"is_night": func() bool {
+		return clock.Game().Now().IsNight()
+	},
+	"calendar": func(engine *lua.Engine) int {
+		cal := clock.Game().Calendar
+		tbl := engine.NewTable()
+		tbl.Set("hours_per_day", cal.HoursPerDay)
+		tbl.Set("days_per_month", cal.DaysPerMonth)
+		months := engine.NewTable()
+		for _, m := range cal.Months {
+			mt := engine.NewTable()
+			mt.Set("name", m.Name)
+			mt.Set("season", m.Season)
+			months.Append(mt)
+		}
+		tbl.Set("months", months)
+		seasons := engine.NewTable()
+		for name, season := range cal.Seasons {
+			st := engine.NewTable()
+			st.Set("sunrise", season.Sunrise)
+			st.Set("sunset", season.Sunset)
+			seasons.Set(name, st)
+		}
+		tbl.Set("seasons", seasons)
+		engine.PushValue(tbl)
+
+		return 1
+	},
 	// create a duration based on the given value
 	"duration": func(eng *lua.Engine) int {
 		if eng.StackSize() == 0 {
@@ -248,6 +316,24 @@ var Time = lua.TableMap{
 	},
 }
 
+// convert a game time into a table for use in scripts
+func gameTimeTable(engine *lua.Engine, gt clock.GameTime) *lua.Value {
+	season := gt.Season()
+	tbl := engine.NewTable()
+	tbl.Set("year", gt.Year)
+	tbl.Set("month", gt.Month)
+	tbl.Set("month_name", gt.MonthName())
+	tbl.Set("day", gt.Day)
+	tbl.Set("hour", gt.Hour)
+	tbl.Set("minute", gt.Minute)
+	tbl.Set("season", season.Name)
+	tbl.Set("sunrise", season.Sunrise)
+	tbl.Set("sunset", season.Sunset)
+	tbl.Set("is_day", gt.IsDay())
+
+	return tbl
+}
+
 // instantValue represents a moment in time, by default without a time zone
 // (technically _with_ one (UTC) but a standard one.
 type instantValue time.Time
@@ -0,0 +1,58 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Time Module", func() {
+	Describe("game()", func() {
+		var (
+			e   *lua.Engine
+			err error
+		)
+
+		BeforeEach(func() {
+			e = lua.NewEngine()
+			scripting.OpenLibs(e, "time")
+			err = e.DoString(`
+				local time = require("time")
+
+				game = time.game()
+				day_or_night = time.is_day() ~= time.is_night()
+				calendar = time.calendar()
+				first_month = calendar.months[1].name
+				winter_sunrise = calendar.seasons.winter.sunrise
+			`)
+		})
+
+		AfterEach(func() {
+			e.Close()
+		})
+
+		It("doesn't fail", func() {
+			Ω(err).Should(BeNil())
+		})
+
+		It("returns the game calendar details", func() {
+			game := e.GetGlobal("game").AsMapStringInterface()
+			Ω(game).Should(HaveKey("year"))
+			Ω(game).Should(HaveKey("month_name"))
+			Ω(game).Should(HaveKey("season"))
+			Ω(game["hour"]).Should(BeNumerically(">=", 0))
+			Ω(game["hour"]).Should(BeNumerically("<", 24))
+		})
+
+		It("is either day or night", func() {
+			Ω(e.GetGlobal("day_or_night").AsBool()).Should(BeTrue())
+		})
+
+		It("returns the calendar", func() {
+			Ω(e.GetGlobal("first_month").AsString()).Should(Equal("Deepwinter"))
+			Ω(e.GetGlobal("winter_sunrise").AsNumber()).Should(BeNumerically("==", 8))
+		})
+	})
+})
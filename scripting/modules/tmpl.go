@@ -3,6 +3,7 @@ package modules
 import (
 	"github.com/bbuck/dragon-mud/logger"
 	"github.com/bbuck/dragon-mud/scripting/lua"
+	"github.com/bbuck/dragon-mud/text/inflect"
 	"github.com/bbuck/dragon-mud/text/tmpl"
 )
 
@@ -32,6 +33,28 @@ import (
 //     set of data containing the rendered children before rendering the final
 //     layout template which can position the child templates via normal
 //     rendering means.
+//   render_string(body, data): string
+//     @param body: string = the uncompiled body of a template
+//     @param data: table = a table of data to provide to the rendering
+//     compile and render a template a single time without registering it,
+//     useful for one off messages. Prefer register/render for anything
+//     rendered frequently.
+//   pluralize(word, count): string
+//     @param word: string = the singular form of the word to pluralize
+//     @param count: number = the number of things the word refers to
+//     returns the word untouched for a count of one, otherwise the plural
+//     form of the word. Also available in templates as
+//     {{pluralize word count}}.
+//   possessive(name): string
+//     @param name: string = the name or pronoun to make possessive
+//     returns the possessive form of the name, "Bob's", "James'" or "your".
+//     Also available in templates as {{possessive name}}.
+//   capitalize(text): string
+//     @param text: string = the text to capitalize
+//     upper cases the first letter of the text, ignoring any leading color
+//     codes. Also available in templates as {{capitalize text}}.
+//   templates can additionally use {{color "R" text}} to wrap text in the
+//   given color code, resetting the color after the text.
 var Tmpl = lua.TableMap{
 	"register": func(name, contents string) bool {
 		err := tmpl.Register(name, contents)
@@ -73,6 +96,32 @@ var Tmpl = lua.TableMap{
 
 		return 1
 	},
+	"render_string": func(engine *lua.Engine) int {
+		data := make(map[string]interface{})
+		if engine.StackSize() >= 2 {
+			if tbl := engine.PopValue(); tbl.IsTable() {
+				data = tbl.AsMapStringInterface()
+			}
+		}
+		body := engine.PopString()
+
+		result, err := tmpl.RenderOnce(body, data)
+		if err != nil {
+			log("tmpl").WithError(err).Error("Failed to render template string from script.")
+			engine.RaiseError(err.Error())
+
+			return 0
+		}
+
+		engine.PushValue(result)
+
+		return 1
+	},
+	"pluralize": func(word string, count int) string {
+		return inflect.Pluralize(word, count)
+	},
+	"possessive": inflect.Possessive,
+	"capitalize": inflect.Capitalize,
 	"render_in_layout": func(eng *lua.Engine) int {
 		ldata := eng.PopValue()
 		children := eng.PopValue()
@@ -136,4 +136,36 @@ var _ = Describe("tmpl Module", func() {
 			})
 		})
 	})
+
+	Describe("helpers", func() {
+		var e *lua.Engine
+
+		e = lua.NewEngine()
+		scripting.OpenLibs(e, "tmpl")
+		e.DoString(`
+			local tmpl = require("tmpl")
+
+			function test_render_string()
+				return tmpl.render_string("{{possessive name}} {{pluralize item count}}", {name = "you", item = "coin", count = 2})
+			end
+
+			function test_inflections()
+				return tmpl.pluralize("wolf", 2), tmpl.possessive("Bob"), tmpl.capitalize("orc")
+			end
+		`)
+
+		It("renders one off templates with helpers", func() {
+			values, err := e.Call("test_render_string", 1)
+			Ω(err).Should(BeNil())
+			Ω(values[0].AsString()).Should(Equal("your coins"))
+		})
+
+		It("exposes the inflection helpers to scripts", func() {
+			values, err := e.Call("test_inflections", 3)
+			Ω(err).Should(BeNil())
+			Ω(values[0].AsString()).Should(Equal("wolves"))
+			Ω(values[1].AsString()).Should(Equal("Bob's"))
+			Ω(values[2].AsString()).Should(Equal("Orc"))
+		})
+	})
 })
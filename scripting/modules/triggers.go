@@ -0,0 +1,36 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/trigger"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Triggers lets scripts set off the triggers of rooms and what's in them,
+// for things the game itself doesn't, like speech from a say command.
+// Trigger scripts are told what set them off in the global table trigger.
+//   speech(room, speaker, message): number
+//     runs the speech triggers in the room the message has keywords for,
+//       returning how many ran.
+//   enter(room, actor): number
+//     runs the enter triggers in the room, returning how many ran.
+//   leave(room, actor): number
+//     runs the leave triggers in the room, returning how many ran.
+//   reload()
+//     forgets the trigger scripts that have been read, so they're all read
+//       again. Changed scripts are read again anyway.
+var Triggers = lua.TableMap{
+	"speech": func(room, speaker, message string) int {
+		return trigger.Global().Speech(room, speaker, message)
+	},
+	"enter": func(room, actor string) int {
+		return trigger.Global().Enter(room, actor)
+	},
+	"leave": func(room, actor string) int {
+		return trigger.Global().Leave(room, actor)
+	},
+	"reload": func() {
+		trigger.Global().Reload()
+	},
+}
@@ -0,0 +1,62 @@
+package modules_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/trigger"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Triggers Lua Module", func() {
+	var (
+		engine *lua.Engine
+		dir    string
+		heard  []string
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "triggers")
+		engine.DoString(`triggers = require("triggers")`)
+		world.Global().AddZone(world.Zone{ID: "lua-temple", Name: "The Temple"})
+		world.Global().AddRoom(world.Room{ID: "lua-altar", Zone: "lua-temple", Triggers: []world.Trigger{
+			{On: world.TriggerSpeech, Keywords: []string{"pray"}, Script: "altar.lua"},
+		}})
+
+		var err error
+		dir, err = ioutil.TempDir("", "triggers")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(ioutil.WriteFile(filepath.Join(dir, "altar.lua"), []byte("-- glow"), 0644)).Should(Succeed())
+		heard = nil
+		trigger.Global().SetDir(dir)
+		trigger.Global().SetRunner(func(script string, _ []byte, d events.Data) error {
+			heard = append(heard, d["actor"].(string))
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		trigger.Global().SetRunner(nil)
+		os.RemoveAll(dir)
+		engine.Close()
+	})
+
+	It("sets off the speech triggers of rooms", func() {
+		res, err := testReturn(engine, `
+			triggers.reload()
+			return {triggers.speech("lua-altar", "Ann", "I pray"), triggers.speech("lua-altar", "Bob", "hello")}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{float64(1), float64(0)}))
+		Ω(heard).Should(Equal([]string{"Ann"}))
+	})
+})
@@ -0,0 +1,82 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/vision"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Vision lets scripts ask what can be seen and hide things from view.
+//   light(room): number
+//     returns the light level of the room, 0 if there's no such room.
+//   can_see(viewer, [room]): boolean
+//     @param room: string = optional room to look in, where the viewer is
+//       if it isn't given
+//     returns true if the player or mob with the id can see in the room.
+//   sees(viewer, target): boolean
+//     returns true if the viewer sees the player or mob with the target id
+//     in their room.
+//   hook(name, fn)
+//     @param fn: function(viewer, target, is_item): boolean = given the id
+//       of the viewer and the id of the character or item they're looking
+//       at, returns false to hide it
+//     sets the hook, replacing any with the name.
+//   unhook(name)
+//     removes the hook with the name.
+//   modifiers(day, night)
+//     changes the light added to rooms outdoors by day and at night.
+var Vision = lua.TableMap{
+	"light": func(room string) int {
+		return vision.Global().Light(room)
+	},
+	"can_see": func(engine *lua.Engine) int {
+		room := ""
+		if engine.StackSize() > 1 {
+			room = engine.PopString()
+		}
+		c := combat.Global().Lookup(engine.PopString())
+		if c == nil {
+			engine.PushValue(false)
+
+			return 1
+		}
+		if room == "" {
+			room = c.Location()
+		}
+		engine.PushValue(vision.Global().CanSee(c, room))
+
+		return 1
+	},
+	"sees": func(viewer, target string) bool {
+		c, other := combat.Global().Lookup(viewer), combat.Global().Lookup(target)
+		if c == nil || other == nil || c.Location() != other.Location() {
+			return false
+		}
+
+		return vision.Global().SeesCharacter(c, other)
+	},
+	"hook": func(engine *lua.Engine) int {
+		fn := engine.PopFunction()
+		name := engine.PopString()
+		vision.Global().Hook(name, func(v vision.Viewer, t vision.Target) bool {
+			ret, err := fn.Call(1, vision.CharacterTarget(v).ID, t.ID, t.Item)
+			if err != nil {
+				log("vision").WithError(err).WithField("hook", name).WithField("engine", nameForEngine(engine)).Error("Vision hook failed.")
+
+				return true
+			}
+
+			return len(ret) == 0 || ret[0].IsNil() || ret[0].AsBool()
+		})
+
+		return 0
+	},
+	"unhook": func(name string) {
+		vision.Global().Hook(name, nil)
+	},
+	"modifiers": func(day, night int) {
+		vision.Global().SetModifiers(day, night)
+	},
+}
@@ -0,0 +1,78 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/vision"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vision Lua Module", func() {
+	var (
+		engine       *lua.Engine
+		thief, guard *brawler
+	)
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "vision")
+		engine.DoString(`vision = require("vision")`)
+		world.Global().AddZone(world.Zone{ID: "lua-sewers", Name: "The Sewers"})
+		world.Global().AddRoom(world.Room{ID: "lua-arena", Zone: "lua-sewers", Name: "The Arena", Flags: map[string]bool{vision.IndoorsFlag: true}})
+		world.Global().AddRoom(world.Room{ID: "lua-drain", Zone: "lua-sewers", Name: "A Drain", Flags: map[string]bool{vision.DarkFlag: true}})
+		thief = &brawler{id: "thief", stats: map[string]int{}}
+		guard = &brawler{id: "guard", stats: map[string]int{}}
+		combat.Global().SetLookup(func(id string) combat.Combatant {
+			switch id {
+			case "thief":
+				return thief
+			case "guard":
+				return guard
+			}
+
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		vision.Global().Hook("lua-shadows", nil)
+		combat.Global().SetLookup(nil)
+		engine.Close()
+	})
+
+	It("lets scripts hide things", func() {
+		res, err := testReturn(engine, `
+			local before = vision.sees("guard", "thief")
+			vision.hook("lua-shadows", function(viewer, target, is_item)
+				return target ~= "thief" or is_item
+			end)
+			local after = vision.sees("guard", "thief")
+			local seen = vision.sees("thief", "guard")
+			vision.unhook("lua-shadows")
+
+			return {
+				light = vision.light("lua-arena"),
+				drain = vision.light("lua-drain"),
+				can_see = vision.can_see("guard"),
+				in_drain = vision.can_see("guard", "lua-drain"),
+				before = before,
+				after = after,
+				seen = seen,
+				again = vision.sees("guard", "thief"),
+			}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].Get("light").AsNumber()).Should(BeEquivalentTo(vision.DefaultLight))
+		Ω(res[0].Get("drain").AsNumber()).Should(BeEquivalentTo(0))
+		Ω(res[0].Get("can_see").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("in_drain").AsBool()).Should(BeFalse())
+		Ω(res[0].Get("before").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("after").AsBool()).Should(BeFalse())
+		Ω(res[0].Get("seen").AsBool()).Should(BeTrue())
+		Ω(res[0].Get("again").AsBool()).Should(BeTrue())
+	})
+})
@@ -0,0 +1,57 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package modules
+
+import (
+	"github.com/bbuck/dragon-mud/game/weather"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+)
+
+// Weather lets scripts see and change the weather of zones, for gameplay
+// depending on it.
+//   current(zone): string
+//     returns the weather of the zone, like "rain" or "snow".
+//   conditions(zone): table
+//     returns the zone, its weather, climate, the season and whether it's
+//       day, like the weather:change event.
+//   outdoors(room): boolean
+//     returns whether the weather reaches the room.
+//   describe(room): string
+//     returns how the weather looks from the room, nil if it's indoors.
+//   climate(zone): string
+//     returns the id of the zone's climate.
+//   set(zone, weather): boolean, string
+//     changes the weather of the zone, telling the players outdoors in it,
+//       returning false and why if it can't.
+var Weather = lua.TableMap{
+	"current": func(zone string) string {
+		return weather.Global().Current(zone)
+	},
+	"conditions": func(engine *lua.Engine) int {
+		engine.PushValue(engine.TableFromMap(weather.Global().Data(engine.PopString())))
+
+		return 1
+	},
+	"outdoors": func(room string) bool {
+		return weather.Global().Outdoors(room)
+	},
+	"describe": func(engine *lua.Engine) int {
+		desc := weather.Global().Describe(engine.PopString())
+		if desc == "" {
+			engine.PushValue(engine.Nil())
+
+			return 1
+		}
+		engine.PushValue(desc)
+
+		return 1
+	},
+	"climate": func(zone string) string {
+		return weather.Global().Climate(zone).ID
+	},
+	"set": func(engine *lua.Engine) int {
+		condition := engine.PopString()
+
+		return pushResult(engine, weather.Global().Set(engine.PopString(), condition))
+	},
+}
@@ -0,0 +1,43 @@
+package modules_test
+
+import (
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/scripting/lua"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Weather Lua Module", func() {
+	var engine *lua.Engine
+
+	BeforeEach(func() {
+		engine = lua.NewEngine()
+		scripting.OpenLibs(engine, "weather")
+		engine.DoString(`weather = require("weather")`)
+		world.Global().AddZone(world.Zone{ID: "lua-skies", Name: "The Skies"})
+		world.Global().AddRoom(world.Room{ID: "lua-field", Zone: "lua-skies"})
+		world.Global().AddRoom(world.Room{ID: "lua-cellar", Zone: "lua-skies", Flags: map[string]bool{"indoors": true}})
+	})
+
+	AfterEach(func() {
+		engine.Close()
+	})
+
+	It("shows and changes the weather of zones", func() {
+		res, err := testReturn(engine, `
+			local set = weather.set("lua-skies", "storm")
+			local bad, why = weather.set("lua-skies", "hail")
+			local c = weather.conditions("lua-skies")
+			return {set, bad, why, weather.current("lua-skies"), c.weather, c.climate,
+				weather.outdoors("lua-field"), weather.outdoors("lua-cellar"),
+				weather.describe("lua-field"), weather.describe("lua-cellar") == nil}
+		`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(res[0].AsSliceInterface()).Should(Equal([]interface{}{
+			true, false, "There's no weather \"hail\".", "storm", "storm", "temperate",
+			true, false, "A storm rages overhead.", true,
+		}))
+	})
+})
@@ -0,0 +1,248 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package copyover upgrades the server in place without disconnecting
+// players. The running server hands its listening sockets and the
+// connections of playing sessions to a new process started from its binary,
+// along with what each session needs to carry on. The new process takes the
+// place of the old one, keeping its pid, and resumes every session where it
+// left off.
+package copyover
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/bbuck/dragon-mud/telnet"
+)
+
+// EnvVar names the file the state is handed over in, it's only set in the
+// environment of the new process.
+const EnvVar = "DRAGON_COPYOVER"
+
+// ErrNoFile is returned for sockets that can't be handed over, like those of
+// TLS connections, which can't carry on without their encryption state.
+var ErrNoFile = errors.New("the socket can't be handed to another process")
+
+// Socket is a socket handed to the new process by its descriptor, which is
+// kept across the exec.
+type Socket struct {
+	FD   uintptr `json:"fd"`
+	Name string  `json:"name"`
+}
+
+// Connection is a playing session handed over along with its connection.
+type Connection struct {
+	Socket
+	Session session.Handover `json:"session"`
+	Telnet  telnet.State     `json:"telnet"`
+}
+
+// State is everything the old process hands the new one.
+type State struct {
+	Created time.Time `json:"created"`
+	// Listeners are the listening sockets, by name.
+	Listeners   map[string]Socket `json:"listeners"`
+	Connections []Connection      `json:"connections"`
+}
+
+// filer is implemented by the net package's connections and listeners,
+// File returns a duplicate of their descriptor
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Handoff collects what's handed to the new process.
+type Handoff struct {
+	state State
+	files []*os.File
+}
+
+// NewHandoff creates a handoff of nothing.
+func NewHandoff() *Handoff {
+	return &Handoff{
+		state: State{Listeners: make(map[string]Socket)},
+	}
+}
+
+// Listener hands over the listening socket with the name, like "telnet".
+func (h *Handoff) Listener(name string, l net.Listener) error {
+	f, err := h.file(l)
+	if err != nil {
+		return err
+	}
+	h.state.Listeners[name] = Socket{FD: f.Fd(), Name: name}
+
+	return nil
+}
+
+// Connection hands over the connection of a playing session, with what was
+// negotiated with its telnet client.
+func (h *Handoff) Connection(c net.Conn, s session.Handover, t telnet.State) error {
+	f, err := h.file(c)
+	if err != nil {
+		return err
+	}
+	h.state.Connections = append(h.state.Connections, Connection{
+		Socket:  Socket{FD: f.Fd(), Name: s.ID},
+		Session: s,
+		Telnet:  t,
+	})
+
+	return nil
+}
+
+// Len returns the number of connections handed over.
+func (h *Handoff) Len() int {
+	return len(h.state.Connections)
+}
+
+// Save writes the state to a file in dir, the system's temporary directory
+// if it's empty, and keeps the sockets open across an exec. The path of the
+// file is returned.
+func (h *Handoff) Save(dir string) (string, error) {
+	for _, f := range h.files {
+		if err := inherit(f.Fd()); err != nil {
+			return "", err
+		}
+	}
+
+	h.state.Created = time.Now()
+	encoded, err := json.Marshal(h.state)
+	if err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(dir, "dragon-copyover-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(encoded); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// Exec saves the state and replaces this process with the executable,
+// passing it the args and the environment along with EnvVar. It only
+// returns if the executable couldn't be started, the sockets are still open
+// and should be closed with Close.
+func (h *Handoff) Exec(exe string, args, env []string) error {
+	path, err := h.Save("")
+	if err != nil {
+		return err
+	}
+
+	vars := make([]string, 0, len(env)+1)
+	for _, v := range env {
+		if strings.HasPrefix(v, EnvVar+"=") {
+			continue
+		}
+		vars = append(vars, v)
+	}
+	vars = append(vars, EnvVar+"="+path)
+
+	err = syscall.Exec(exe, args, vars)
+	os.Remove(path)
+
+	return err
+}
+
+// Close closes the copies of the sockets held for the new process, the
+// sockets themselves stay open.
+func (h *Handoff) Close() {
+	for _, f := range h.files {
+		f.Close()
+	}
+	h.files = nil
+}
+
+// file duplicates the socket's descriptor, keeping it until the exec
+func (h *Handoff) file(v interface{}) (*os.File, error) {
+	fv, ok := v.(filer)
+	if !ok {
+		return nil, ErrNoFile
+	}
+	f, err := fv.File()
+	if err != nil {
+		return nil, err
+	}
+	h.files = append(h.files, f)
+
+	return f, nil
+}
+
+// inherit clears close-on-exec from the descriptor
+func inherit(fd uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFD, 0); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// Load returns the state handed over if this process was started by a
+// copyover, ok is false if it wasn't. The state is only returned once, its
+// file is removed and EnvVar cleared so a later reboot starts fresh.
+func Load() (state *State, ok bool, err error) {
+	path := os.Getenv(EnvVar)
+	if path == "" {
+		return nil, false, nil
+	}
+	os.Unsetenv(EnvVar)
+	defer os.Remove(path)
+
+	state, err = LoadFile(path)
+
+	return state, true, err
+}
+
+// LoadFile reads the state saved to the file.
+func LoadFile(path string) (*State, error) {
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := new(State)
+	if err := json.Unmarshal(encoded, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Listener returns the listening socket handed over with the name, nil if
+// there's none.
+func (s *State) Listener(name string) (net.Listener, error) {
+	sock, ok := s.Listeners[name]
+	if !ok {
+		return nil, nil
+	}
+	f := os.NewFile(sock.FD, sock.Name)
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// Conn returns the connection handed over. Like Listener, it takes the
+// socket, it can't be taken twice.
+func (c Connection) Conn() (net.Conn, error) {
+	f := os.NewFile(c.FD, c.Name)
+	defer f.Close()
+
+	return net.FileConn(f)
+}
@@ -0,0 +1,13 @@
+package copyover_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCopyover(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Copyover Suite")
+}
@@ -0,0 +1,121 @@
+package copyover_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+
+	. "github.com/bbuck/dragon-mud/server/copyover"
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/bbuck/dragon-mud/telnet"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Copyover", func() {
+	var (
+		dir      string
+		listener net.Listener
+		client   net.Conn
+		conn     net.Conn
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "copyover")
+		Ω(err).Should(BeNil())
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).Should(BeNil())
+		client, err = net.Dial("tcp", listener.Addr().String())
+		Ω(err).Should(BeNil())
+		conn, err = listener.Accept()
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		conn.Close()
+		listener.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("hands over listeners and connections", func() {
+		h := NewHandoff()
+		Ω(h.Listener("telnet", listener)).Should(Succeed())
+		handover := session.Handover{ID: "s1", Character: "Izuna"}
+		Ω(h.Connection(conn, handover, telnet.State{GMCP: telnet.Option{Enabled: true}})).Should(Succeed())
+		Ω(h.Len()).Should(Equal(1))
+
+		path, err := h.Save(dir)
+		Ω(err).Should(BeNil())
+
+		state, err := LoadFile(path)
+		Ω(err).Should(BeNil())
+		Ω(state.Connections).Should(HaveLen(1))
+		c := state.Connections[0]
+		Ω(c.Session).Should(Equal(handover))
+		Ω(c.Telnet.GMCP.Enabled).Should(BeTrue())
+
+		// descriptors are kept across an exec
+		flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, c.FD, syscall.F_GETFD, 0)
+		Ω(errno).Should(BeZero())
+		Ω(flags & syscall.FD_CLOEXEC).Should(BeZero())
+
+		// in place of the exec, the new process's copies of the sockets
+		// outlive the old one's
+		fd, err := syscall.Dup(int(c.FD))
+		Ω(err).Should(BeNil())
+		c.FD = uintptr(fd)
+		fd, err = syscall.Dup(int(state.Listeners["telnet"].FD))
+		Ω(err).Should(BeNil())
+		state.Listeners["telnet"] = Socket{FD: uintptr(fd), Name: "telnet"}
+		h.Close()
+
+		resumed, err := c.Conn()
+		Ω(err).Should(BeNil())
+		defer resumed.Close()
+		resumed.Write([]byte("still here\n"))
+		line, err := bufio.NewReader(client).ReadString('\n')
+		Ω(err).Should(BeNil())
+		Ω(line).Should(Equal("still here\n"))
+
+		l, err := state.Listener("telnet")
+		Ω(err).Should(BeNil())
+		defer l.Close()
+		Ω(l.Addr().String()).Should(Equal(listener.Addr().String()))
+		missing, err := state.Listener("tls")
+		Ω(err).Should(BeNil())
+		Ω(missing).Should(BeNil())
+	})
+
+	It("refuses sockets that can't be handed over", func() {
+		h := NewHandoff()
+		defer h.Close()
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		Ω(h.Connection(server, session.Handover{ID: "s1"}, telnet.State{})).Should(Equal(ErrNoFile))
+	})
+
+	It("only loads the state it was started with", func() {
+		_, ok, err := Load()
+		Ω(ok).Should(BeFalse())
+		Ω(err).Should(BeNil())
+
+		path, err := NewHandoff().Save(dir)
+		Ω(err).Should(BeNil())
+		os.Setenv(EnvVar, path)
+
+		state, ok, err := Load()
+		Ω(ok).Should(BeTrue())
+		Ω(err).Should(BeNil())
+		Ω(state.Listeners).Should(BeEmpty())
+		Ω(os.Getenv(EnvVar)).Should(BeEmpty())
+		_, err = os.Stat(path)
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+	})
+})
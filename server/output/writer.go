@@ -0,0 +1,291 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package output renders game text for a player's client. Text is written
+// once with color codes, like "[r]red[x]", and each player's Writer wraps and
+// colors it for what their client can display. Messages are batched into as
+// few writes as possible and the player's prompt is redrawn after them, so
+// game code never writes to connections directly. Long responses can be paged,
+// shown a screenful at a time with a [MORE] prompt between them.
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	console "github.com/bbuck/dragon-mud/output"
+)
+
+// Settings describe how text is rendered for a client.
+type Settings struct {
+	// Width is the number of characters lines are wrapped to, lines aren't
+	// wrapped if it's zero.
+	Width int
+	// Color is the color the client can display, color codes are converted
+	// to it or removed.
+	Color console.ColorSupport
+}
+
+// MorePrompt is drawn in place of the prompt while a paged response has more
+// to show.
+const MorePrompt = "[MORE] Press enter to continue or q to stop. "
+
+// Writer sends messages to a client. Messages sent within the delay of the
+// first one are written together, followed by the prompt. It's safe to use
+// from multiple goroutines.
+type Writer struct {
+	w           io.Writer
+	delay       time.Duration
+	settings    Settings
+	pending     []string
+	prompt      string
+	promptShown bool
+	pageLength  int
+	more        []string
+	timer       *time.Timer
+	mutex       *sync.Mutex
+}
+
+// NewWriter creates a writer sending to w, holding messages for the delay
+// given so they can be batched. Messages are written as they're sent if the
+// delay is zero.
+func NewWriter(w io.Writer, delay time.Duration) *Writer {
+	return &Writer{
+		w:     w,
+		delay: delay,
+		mutex: new(sync.Mutex),
+	}
+}
+
+// Settings returns how text is currently rendered.
+func (w *Writer) Settings() Settings {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.settings
+}
+
+// SetSettings changes how text is rendered from now on.
+func (w *Writer) SetSettings(s Settings) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.settings = s
+}
+
+// Send queues a message, it's written on a line of its own.
+func (w *Writer) Send(text string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.queue(w.render(text))
+}
+
+// Sendf queues a formatted message.
+func (w *Writer) Sendf(format string, params ...interface{}) error {
+	return w.Send(fmt.Sprintf(format, params...))
+}
+
+// Write queues the text as a message, so a Writer can be used where an
+// io.Writer is expected.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.Send(string(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes waiting messages now, followed by the prompt.
+func (w *Writer) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.flush()
+}
+
+// PageLength returns the number of lines shown on each page of a paged
+// response, zero if they aren't paged.
+func (w *Writer) PageLength() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.pageLength
+}
+
+// SetPageLength sets the number of lines shown on each page of a paged
+// response, zero shows them in full.
+func (w *Writer) SetPageLength(lines int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pageLength = lines
+}
+
+// Page queues a long response, such as a help file, showing it a page at a
+// time. Whatever remains of a response being paged is discarded.
+func (w *Writer) Page(text string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	text = strings.TrimSuffix(w.render(text), "\r\n")
+	w.more = strings.Split(text, "\r\n")
+
+	return w.queue(w.nextPage())
+}
+
+// Paging is true while a paged response has more to show.
+func (w *Writer) Paging() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return len(w.more) > 0
+}
+
+// Continue handles a line typed while paging, "q" stops paging and anything
+// else shows the next page. False is returned if nothing is being paged, so
+// the line should be handled as usual.
+func (w *Writer) Continue(input string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.more) == 0 {
+		return false
+	}
+	if strings.EqualFold(strings.TrimSpace(input), "q") {
+		w.more = nil
+		w.showPrompt()
+
+		return true
+	}
+	w.queue(w.nextPage())
+
+	return true
+}
+
+// SetPrompt sets the prompt drawn after output, it may contain color codes.
+// An empty prompt isn't drawn.
+func (w *Writer) SetPrompt(prompt string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.prompt = prompt
+}
+
+// ShowPrompt draws the prompt if it isn't already showing, writing any
+// messages waiting before it.
+func (w *Writer) ShowPrompt() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.showPrompt()
+}
+
+// ClearPrompt records that the prompt is no longer on the player's screen,
+// as when they press enter after typing a command. Output following a prompt
+// that's still showing starts on a new line.
+func (w *Writer) ClearPrompt() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.promptShown = false
+}
+
+// queue adds rendered text to the messages waiting to be written, writing
+// them now if there's no delay. The mutex must be held.
+func (w *Writer) queue(text string) error {
+	w.pending = append(w.pending, text)
+	if w.delay <= 0 {
+		return w.flush()
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.delay, func() {
+			w.Flush()
+		})
+	}
+
+	return nil
+}
+
+// nextPage removes the next page from the response being paged, the mutex
+// must be held
+func (w *Writer) nextPage() string {
+	page := w.more
+	if w.pageLength > 0 && len(page) > w.pageLength {
+		page = page[:w.pageLength]
+	}
+	w.more = w.more[len(page):]
+
+	return strings.Join(page, "\r\n")
+}
+
+// showPrompt draws the prompt unless it's showing, the mutex must be held
+func (w *Writer) showPrompt() error {
+	if len(w.pending) > 0 {
+		return w.flush()
+	}
+	prompt := w.currentPrompt()
+	if prompt == "" || w.promptShown {
+		return nil
+	}
+	w.promptShown = true
+	_, err := io.WriteString(w.w, prompt)
+
+	return err
+}
+
+// currentPrompt is the rendered prompt, or MorePrompt while paging. The
+// mutex must be held.
+func (w *Writer) currentPrompt() string {
+	if len(w.more) > 0 {
+		return MorePrompt
+	}
+	if w.prompt == "" {
+		return ""
+	}
+
+	return w.render(w.prompt)
+}
+
+// flush writes the pending messages and prompt, the mutex must be held
+func (w *Writer) flush() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	if w.promptShown {
+		buf.WriteString("\r\n")
+	}
+	for _, text := range w.pending {
+		buf.WriteString(text)
+		if !strings.HasSuffix(text, "\r\n") {
+			buf.WriteString("\r\n")
+		}
+	}
+	w.pending = nil
+	prompt := w.currentPrompt()
+	buf.WriteString(prompt)
+	w.promptShown = prompt != ""
+	_, err := w.w.Write(buf.Bytes())
+
+	return err
+}
+
+// render wraps and colors the text, ending lines with CRLF as telnet expects
+func (w *Writer) render(text string) string {
+	buf := new(bytes.Buffer)
+	c := console.NewConsole(buf)
+	c.ColorSupport = w.settings.Color
+	c.Width = w.settings.Width
+	c.Printf("%s", strings.Replace(text, "\r\n", "\n", -1))
+
+	return strings.Replace(buf.String(), "\n", "\r\n", -1)
+}
@@ -0,0 +1,166 @@
+package output_test
+
+import (
+	"sync"
+	"time"
+
+	console "github.com/bbuck/dragon-mud/output"
+	. "github.com/bbuck/dragon-mud/server/output"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// client records each write made to it
+type client struct {
+	writes []string
+	mutex  *sync.Mutex
+}
+
+func newClient() *client {
+	return &client{mutex: new(sync.Mutex)}
+}
+
+func (c *client) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.writes = append(c.writes, string(p))
+
+	return len(p), nil
+}
+
+func (c *client) received() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]string(nil), c.writes...)
+}
+
+var _ = Describe("Writer", func() {
+	var (
+		c *client
+		w *Writer
+	)
+
+	BeforeEach(func() {
+		c = newClient()
+		w = NewWriter(c, 0)
+	})
+
+	It("writes each message on its own line", func() {
+		w.Send("one")
+		w.Sendf("%d\r\n", 2)
+
+		Ω(c.received()).Should(Equal([]string{"one\r\n", "2\r\n"}))
+	})
+
+	It("renders for the client's settings", func() {
+		w.SetSettings(Settings{Width: 10, Color: console.ColorMono})
+		w.Send("[r]the red dragon[x]\nsleeps")
+
+		Ω(c.received()).Should(Equal([]string{"the red\r\ndragon\r\nsleeps\r\n"}))
+
+		w.SetSettings(Settings{Color: console.ColorBasic})
+		w.Send("[r]red")
+
+		Ω(c.received()[1]).Should(Equal("\033[31;22mred\r\n"))
+	})
+
+	It("batches messages sent within the delay", func() {
+		w = NewWriter(c, 10*time.Millisecond)
+		w.Send("one")
+		w.Send("two")
+
+		Ω(c.received()).Should(BeEmpty())
+		Eventually(c.received).Should(Equal([]string{"one\r\ntwo\r\n"}))
+	})
+
+	It("writes waiting messages when flushed", func() {
+		w = NewWriter(c, time.Hour)
+		w.Send("one")
+		Ω(w.Flush()).Should(Succeed())
+		Ω(w.Flush()).Should(Succeed())
+
+		Ω(c.received()).Should(Equal([]string{"one\r\n"}))
+	})
+
+	Describe("paging", func() {
+		BeforeEach(func() {
+			w.SetPrompt("> ")
+			w.SetPageLength(2)
+		})
+
+		It("shows long responses a page at a time", func() {
+			w.Page("one\ntwo\nthree\nfour\nfive")
+
+			Ω(w.Paging()).Should(BeTrue())
+			Ω(c.received()).Should(Equal([]string{"one\r\ntwo\r\n" + MorePrompt}))
+
+			w.ClearPrompt()
+			Ω(w.Continue("")).Should(BeTrue())
+			w.ClearPrompt()
+			Ω(w.Continue("")).Should(BeTrue())
+
+			Ω(w.Paging()).Should(BeFalse())
+			Ω(c.received()[1:]).Should(Equal([]string{
+				"three\r\nfour\r\n" + MorePrompt,
+				"five\r\n> ",
+			}))
+			Ω(w.Continue("look")).Should(BeFalse())
+		})
+
+		It("pages lines as they're wrapped", func() {
+			w.SetSettings(Settings{Width: 10})
+			w.Page("the red dragon sleeps")
+
+			Ω(c.received()).Should(Equal([]string{"the red\r\ndragon\r\n" + MorePrompt}))
+		})
+
+		It("stops when the player quits", func() {
+			w.Page("one\ntwo\nthree")
+			w.ClearPrompt()
+			w.Continue("Q")
+
+			Ω(w.Paging()).Should(BeFalse())
+			Ω(c.received()[1:]).Should(Equal([]string{"> "}))
+		})
+
+		It("shows short responses in full", func() {
+			w.Page("one\ntwo")
+			w.SetPageLength(0)
+			w.Page("one\ntwo\nthree")
+
+			Ω(w.Paging()).Should(BeFalse())
+			Ω(c.received()).Should(Equal([]string{"one\r\ntwo\r\n> ", "\r\none\r\ntwo\r\nthree\r\n> "}))
+		})
+	})
+
+	Describe("prompt", func() {
+		BeforeEach(func() {
+			w.SetPrompt("[g]>[x] ")
+		})
+
+		It("is drawn after output", func() {
+			w.Send("hello")
+
+			Ω(c.received()).Should(Equal([]string{"hello\r\n> "}))
+		})
+
+		It("moves output following it to a new line", func() {
+			w.ShowPrompt()
+			w.Send("hello")
+
+			Ω(c.received()).Should(Equal([]string{"> ", "\r\nhello\r\n> "}))
+		})
+
+		It("isn't drawn twice", func() {
+			w.ShowPrompt()
+			w.ShowPrompt()
+			w.ClearPrompt()
+			w.ShowPrompt()
+
+			Ω(c.received()).Should(Equal([]string{"> ", "> "}))
+		})
+	})
+})
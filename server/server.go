@@ -0,0 +1,84 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package server holds what the game's network transports have in common.
+// Telnet, telnet over TLS and WebSocket clients are all wrapped in a Conn and
+// handed to Handle, so the game treats them the same.
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+
+	console "github.com/bbuck/dragon-mud/output"
+	"github.com/bbuck/dragon-mud/server/output"
+)
+
+// Transport names returned by Conn.Transport.
+const (
+	Telnet    = "telnet"
+	TelnetTLS = "telnet+tls"
+	WebSocket = "websocket"
+)
+
+// Conn is a connection from a player's client. Reads return the text typed by
+// the player one line at a time, ending in a newline, and writes send text to
+// be displayed.
+type Conn interface {
+	io.ReadWriteCloser
+
+	// RemoteAddr is the address of the client.
+	RemoteAddr() net.Addr
+	// Transport is the name of the protocol the client connected with.
+	Transport() string
+	// SendData sends structured data outside of the game text, such as the
+	// player's vitals for a client to display in a status bar. Transports
+	// that can't carry data silently ignore it.
+	SendData(kind string, data interface{}) error
+	// OnData sets the function called with structured data sent by the
+	// client, data is the JSON sent with it. It's called while reading.
+	OnData(fn func(kind string, data json.RawMessage))
+}
+
+// Terminal describes the display of a player's client, zero sizes weren't
+// reported.
+type Terminal struct {
+	// Width and Height are the size of the window in characters.
+	Width  int
+	Height int
+	// Type is the terminal the client emulates, like "XTERM-256COLOR".
+	Type string
+	// Color is the color the client can display.
+	Color console.ColorSupport
+	// Charset is the character set the client displays text in, text is
+	// converted to it by the connection.
+	Charset string
+}
+
+// TerminalReporter is implemented by connections whose clients describe their
+// display, such as telnet clients supporting NAWS and TTYPE.
+type TerminalReporter interface {
+	// OnTerminal sets the function called each time the client reports a
+	// change. It's called while reading.
+	OnTerminal(fn func(Terminal))
+}
+
+// Compressor is implemented by connections that can compress what's sent to
+// the client, like telnet clients supporting MCCP. Players can turn it off if
+// their client handles it poorly.
+type Compressor interface {
+	// SetCompression turns compression on or off, it may not start until the
+	// client agrees to it.
+	SetCompression(enabled bool) error
+	// Compressing is true while output is being compressed.
+	Compressing() bool
+	// CompressionStats returns the bytes written while compressing and the
+	// bytes actually sent for them.
+	CompressionStats() (raw, sent uint64)
+}
+
+// Handle takes over a new connection from any transport.
+func Handle(c Conn) {
+	output.NewWriter(c, 0).Send("You were connected successfully, closing connection.")
+	c.Close()
+}
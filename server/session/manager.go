@@ -0,0 +1,519 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package session
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	console "github.com/bbuck/dragon-mud/output"
+	"github.com/bbuck/dragon-mud/server"
+	"github.com/bbuck/dragon-mud/server/output"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+)
+
+// Events emitted as sessions change state. Each carries the session id,
+// character, transport and address of the session.
+const (
+	// OpenEvent is emitted when a client connects.
+	OpenEvent = "session:open"
+	// PlayEvent is emitted when a session starts playing a character.
+	PlayEvent = "session:play"
+	// ReconnectEvent is emitted when a new connection takes over the session
+	// of a character already in the game, it includes the previous state of
+	// the session.
+	ReconnectEvent = "session:reconnect"
+	// LinkDeadEvent is emitted when a playing session loses its connection.
+	LinkDeadEvent = "session:linkdead"
+	// IdleEvent is emitted when a session is closed for not sending input
+	// within the idle timeout.
+	IdleEvent = "session:idle"
+	// CloseEvent is emitted when a session ends, it includes the reason.
+	CloseEvent = "session:close"
+	// DataEvent is emitted when the client sends structured data, such as a
+	// GMCP package. It includes the package name and the decoded data.
+	DataEvent = "session:data"
+	// ResumeEvent is emitted when a session handed over by another process,
+	// like the server before a copyover, continues playing its character.
+	ResumeEvent = "session:resume"
+)
+
+// message written to connections closed for being idle
+const idleMessage = "You have been idle too long, disconnecting."
+
+// message written to a connection replaced by a reconnect
+const replacedMessage = "Your character was reconnected from elsewhere."
+
+// Options configure a Manager, zero timeouts never expire and a zero output
+// delay writes messages as they're sent.
+type Options struct {
+	// IdleTimeout is how long a connected session can go without input before
+	// it's closed.
+	IdleTimeout time.Duration
+	// LinkDeadTimeout is how long a link-dead session waits for its player to
+	// reconnect before it's closed.
+	LinkDeadTimeout time.Duration
+	// OutputDelay is how long output is held so messages sent close together
+	// are written together.
+	OutputDelay time.Duration
+	// PageLength is the number of lines shown at a time when paging long
+	// responses, until the player chooses otherwise. Zero doesn't page them.
+	PageLength int
+}
+
+// Manager owns every open session, expiring them as they time out and
+// matching reconnecting players to the session of their character.
+type Manager struct {
+	options    Options
+	emitter    *events.Emitter
+	sessions   map[string]*Session
+	characters map[string]*Session
+	mutex      *sync.RWMutex
+	now        func() time.Time
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+var (
+	globalManager *Manager
+	globalOnce    sync.Once
+)
+
+// Global returns the manager for the server's sessions, timing them out
+// according to the session.idle_timeout and session.link_dead_timeout
+// settings, batching output with session.output_delay and paging it with
+// session.page_length. Events aren't emitted until an emitter is set.
+func Global() *Manager {
+	globalOnce.Do(func() {
+		globalManager = NewManager(nil, Options{
+			IdleTimeout:     viper.GetDuration("session.idle_timeout"),
+			LinkDeadTimeout: viper.GetDuration("session.link_dead_timeout"),
+			OutputDelay:     viper.GetDuration("session.output_delay"),
+			PageLength:      viper.GetInt("session.page_length"),
+		})
+	})
+
+	return globalManager
+}
+
+// NewManager creates a manager emitting session events to em, which may be
+// nil if nothing is listening.
+func NewManager(em *events.Emitter, opts Options) *Manager {
+	return &Manager{
+		options:    opts,
+		emitter:    em,
+		sessions:   make(map[string]*Session),
+		characters: make(map[string]*Session),
+		mutex:      new(sync.RWMutex),
+		now:        time.Now,
+	}
+}
+
+// SetClock replaces the function used to get the current time, it's intended
+// for tests.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
+
+// SetEmitter sets the emitter session events are emitted to.
+func (m *Manager) SetEmitter(em *events.Emitter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.emitter = em
+}
+
+// Open starts a session for a new connection.
+func (m *Manager) Open(c server.Conn) *Session {
+	m.mutex.Lock()
+	now := m.now()
+	s := &Session{
+		id:        uuid.NewV1().String(),
+		manager:   m,
+		conn:      c,
+		state:     Connected,
+		opened:    now,
+		lastInput: now,
+		terminal:  server.Terminal{Color: console.ColorBasic},
+	}
+	s.out = output.NewWriter(s, m.options.OutputDelay)
+	s.out.SetSettings(outputSettings(s.terminal, false))
+	s.out.SetPageLength(m.options.PageLength)
+	m.sessions[s.id] = s
+	data := s.data()
+	m.mutex.Unlock()
+
+	s.listen(c)
+	m.emit(OpenEvent, data)
+
+	return s
+}
+
+// Resume continues a session handed over by another process on its
+// connection. The session keeps its id and plays its character right away,
+// only ResumeEvent is emitted. If the character is already playing the
+// connection takes over its session, as with Play.
+func (m *Manager) Resume(c server.Conn, h Handover) *Session {
+	m.mutex.Lock()
+	_, taken := m.sessions[h.ID]
+	_, playing := m.characters[strings.ToLower(h.Character)]
+	if taken || playing || h.ID == "" {
+		m.mutex.Unlock()
+
+		return m.Play(m.Open(c), h.Character)
+	}
+
+	now := m.now()
+	s := &Session{
+		id:        h.ID,
+		manager:   m,
+		conn:      c,
+		character: h.Character,
+		state:     Playing,
+		opened:    h.Opened,
+		lastInput: now,
+		terminal:  h.Terminal,
+		noColor:   h.NoColor,
+	}
+	s.out = output.NewWriter(s, m.options.OutputDelay)
+	s.out.SetSettings(outputSettings(s.terminal, s.noColor))
+	s.out.SetPageLength(m.options.PageLength)
+	m.sessions[s.id] = s
+	m.characters[strings.ToLower(s.character)] = s
+	data := s.data()
+	m.mutex.Unlock()
+
+	s.listen(c)
+	m.emit(ResumeEvent, data)
+
+	return s
+}
+
+// Get returns the open session with the id, or nil.
+func (m *Manager) Get(id string) *Session {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.sessions[id]
+}
+
+// ForCharacter returns the session playing the character, or nil. Character
+// names are matched without regard to case.
+func (m *Manager) ForCharacter(name string) *Session {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.characters[strings.ToLower(name)]
+}
+
+// Sessions returns every open session.
+func (m *Manager) Sessions() []*Session {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+
+	return sessions
+}
+
+// Play starts the session playing the character, returning the session the
+// player should continue with. If the character already has a session, link
+// dead or still connected elsewhere, the connection of s takes it over and
+// that session is returned instead, s is discarded without a close event.
+// The connection being replaced, if any, is closed.
+func (m *Manager) Play(s *Session, character string) *Session {
+	key := strings.ToLower(character)
+
+	m.mutex.Lock()
+	if s.state != Connected {
+		m.mutex.Unlock()
+
+		return s
+	}
+
+	existing, ok := m.characters[key]
+	if !ok {
+		s.character = character
+		s.state = Playing
+		m.characters[key] = s
+		data := s.data()
+		m.mutex.Unlock()
+
+		m.emit(PlayEvent, data)
+
+		return s
+	}
+
+	previous, replaced := existing.state, existing.conn
+	existing.conn = s.conn
+	existing.state = Playing
+	existing.lastInput = m.now()
+	existing.linkDead = time.Time{}
+	existing.terminal = s.terminal
+	noColor := existing.noColor
+	delete(m.sessions, s.id)
+	s.state = Closed
+	data := existing.data()
+	data["previous"] = previous.String()
+	data["replaced"] = s.id
+	m.mutex.Unlock()
+
+	existing.listen(s.conn)
+	existing.out.SetSettings(outputSettings(s.terminal, noColor))
+	existing.out.ClearPrompt()
+	if previous == Playing {
+		output.NewWriter(replaced, 0).Send(replacedMessage)
+		replaced.Close()
+	}
+	m.emit(ReconnectEvent, data)
+
+	return existing
+}
+
+// Drop handles the loss of the session's connection. Playing sessions go
+// link-dead, others are closed.
+func (m *Manager) Drop(s *Session) {
+	m.lost(s, s.Conn())
+}
+
+// Close ends the session for the reason given, closing its connection once
+// waiting output is written.
+func (m *Manager) Close(s *Session, reason string) {
+	s.out.Flush()
+
+	m.mutex.Lock()
+	if s.state == Closed {
+		m.mutex.Unlock()
+
+		return
+	}
+	previous := s.state
+	m.remove(s)
+	data := s.data()
+	data["reason"] = reason
+	m.mutex.Unlock()
+
+	if previous != LinkDead {
+		s.conn.Close()
+	}
+	m.emit(CloseEvent, data)
+}
+
+// Sweep closes sessions that have been idle or link-dead too long.
+func (m *Manager) Sweep() {
+	m.mutex.RLock()
+	now := m.now()
+	var idle, expired []*Session
+	for _, s := range m.sessions {
+		switch s.state {
+		case Connected, Playing:
+			if m.options.IdleTimeout > 0 && now.Sub(s.lastInput) >= m.options.IdleTimeout {
+				idle = append(idle, s)
+			}
+		case LinkDead:
+			if m.options.LinkDeadTimeout > 0 && now.Sub(s.linkDead) >= m.options.LinkDeadTimeout {
+				expired = append(expired, s)
+			}
+		}
+	}
+	m.mutex.RUnlock()
+
+	for _, s := range idle {
+		m.emit(IdleEvent, m.dataFor(s))
+		s.out.Send(idleMessage)
+		m.Close(s, "idle")
+	}
+	for _, s := range expired {
+		m.Close(s, "linkdead")
+	}
+}
+
+// Start sweeps for expired sessions from the background at the interval
+// given, until Stop is called.
+func (m *Manager) Start(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(interval, m.stop, m.done)
+}
+
+// Stop stops sweeping for expired sessions, open sessions are left alone.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (m *Manager) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.Sweep()
+		}
+	}
+}
+
+// touch records input from the player
+func (m *Manager) touch(s *Session) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s.lastInput = m.now()
+}
+
+// lost handles the failure of connection c, unless the session has moved on
+// to another connection
+func (m *Manager) lost(s *Session, c server.Conn) {
+	m.mutex.Lock()
+	if s.conn != c {
+		m.mutex.Unlock()
+
+		return
+	}
+
+	switch s.state {
+	case Playing:
+		s.state = LinkDead
+		s.linkDead = m.now()
+		data := s.data()
+		m.mutex.Unlock()
+
+		c.Close()
+		m.emit(LinkDeadEvent, data)
+	case Connected:
+		m.mutex.Unlock()
+
+		m.Close(s, "disconnected")
+	default:
+		m.mutex.Unlock()
+	}
+}
+
+// remove stops tracking the session, the mutex must be held
+func (m *Manager) remove(s *Session) {
+	delete(m.sessions, s.id)
+	if s.character != "" {
+		key := strings.ToLower(s.character)
+		if m.characters[key] == s {
+			delete(m.characters, key)
+		}
+	}
+	s.state = Closed
+}
+
+func (m *Manager) dataFor(s *Session) events.Data {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return s.data()
+}
+
+func (m *Manager) emit(evt string, data events.Data) {
+	m.mutex.RLock()
+	em := m.emitter
+	m.mutex.RUnlock()
+
+	if em != nil {
+		em.Emit(evt, data)
+	}
+}
+
+// listen receives the data and terminal changes reported by the connection
+func (s *Session) listen(c server.Conn) {
+	c.OnData(s.received)
+	if tr, ok := c.(server.TerminalReporter); ok {
+		tr.OnTerminal(s.terminalChanged)
+	}
+}
+
+// terminalChanged records the client's display as it's reported, the
+// manager's mutex is released before the output settings change since
+// writing output takes it
+func (s *Session) terminalChanged(t server.Terminal) {
+	s.manager.mutex.Lock()
+	s.terminal = t
+	noColor := s.noColor
+	s.manager.mutex.Unlock()
+
+	s.out.SetSettings(outputSettings(t, noColor))
+}
+
+// outputSettings renders output for the terminal, wrapping to DefaultWidth
+// for clients that don't report their width and leaving out color when the
+// player turned it off
+func outputSettings(t server.Terminal, noColor bool) output.Settings {
+	settings := output.Settings{
+		Width: t.Width,
+		Color: t.Color,
+	}
+	if settings.Width == 0 {
+		settings.Width = DefaultWidth
+	}
+	if noColor {
+		settings.Color = console.ColorMono
+	}
+
+	return settings
+}
+
+// received emits data sent by the client, data that isn't valid JSON is
+// passed on as a string
+func (s *Session) received(kind string, raw json.RawMessage) {
+	var value interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+	}
+
+	s.manager.mutex.RLock()
+	data := s.data()
+	fn := s.onData
+	s.manager.mutex.RUnlock()
+
+	if fn != nil {
+		fn(kind, raw)
+	}
+	data["package"] = kind
+	data["data"] = value
+	s.manager.emit(DataEvent, data)
+}
+
+// data describes the session for events, the mutex must be held
+func (s *Session) data() events.Data {
+	return events.Data{
+		"session":   s.id,
+		"character": s.character,
+		"state":     s.state.String(),
+		"transport": s.conn.Transport(),
+		"address":   s.conn.RemoteAddr().String(),
+	}
+}
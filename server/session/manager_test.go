@@ -0,0 +1,419 @@
+package session_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/output"
+	"github.com/bbuck/dragon-mud/server"
+	. "github.com/bbuck/dragon-mud/server/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeConn reads the input given and records what's written to it
+type fakeConn struct {
+	input      *bytes.Buffer
+	output     *bytes.Buffer
+	closed     bool
+	onData     func(string, json.RawMessage)
+	onTerminal func(server.Terminal)
+	mutex      *sync.Mutex
+}
+
+func newFakeConn(input string) *fakeConn {
+	return &fakeConn{
+		input:  bytes.NewBufferString(input),
+		output: new(bytes.Buffer),
+		mutex:  new(sync.Mutex),
+	}
+}
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return 0, io.EOF
+	}
+
+	return c.input.Read(p)
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.output.Write(p)
+}
+
+func (c *fakeConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+func (c *fakeConn) written() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.output.String()
+}
+
+func (c *fakeConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}
+}
+
+func (c *fakeConn) Transport() string {
+	return server.Telnet
+}
+
+func (c *fakeConn) SendData(string, interface{}) error {
+	return nil
+}
+
+func (c *fakeConn) OnData(fn func(string, json.RawMessage)) {
+	c.onData = fn
+}
+
+func (c *fakeConn) OnTerminal(fn func(server.Terminal)) {
+	c.onTerminal = fn
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		em       *events.Emitter
+		received chan events.Data
+		manager  *Manager
+		now      time.Time
+		conn     *fakeConn
+		s        *Session
+	)
+
+	listen := func(evts ...string) {
+		for _, evt := range evts {
+			evt := evt
+			em.On(evt, events.HandlerFunc(func(d events.Data) error {
+				d["event"] = evt
+				received <- d
+
+				return nil
+			}))
+		}
+	}
+
+	next := func() events.Data {
+		var d events.Data
+		Eventually(received).Should(Receive(&d))
+
+		return d
+	}
+
+	BeforeEach(func() {
+		em = events.NewEmitter(nil)
+		received = make(chan events.Data, 10)
+		now = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		manager = NewManager(em, Options{
+			IdleTimeout:     time.Minute,
+			LinkDeadTimeout: 5 * time.Minute,
+		})
+		manager.SetClock(func() time.Time {
+			return now
+		})
+		conn = newFakeConn("look\n")
+	})
+
+	Describe("Open", func() {
+		It("gives each session a unique id", func() {
+			listen(OpenEvent)
+			s = manager.Open(conn)
+			other := manager.Open(newFakeConn(""))
+
+			Ω(s.ID()).ShouldNot(BeEmpty())
+			Ω(s.ID()).ShouldNot(Equal(other.ID()))
+			Ω(s.State()).Should(Equal(Connected))
+			Ω(manager.Get(s.ID())).Should(Equal(s))
+			Ω(manager.Sessions()).Should(HaveLen(2))
+
+			d := next()
+			Ω(d["event"]).Should(Equal(OpenEvent))
+			Ω(d["transport"]).Should(Equal(server.Telnet))
+			Ω(d["address"]).Should(Equal("127.0.0.1:4000"))
+		})
+	})
+
+	Describe("Play", func() {
+		BeforeEach(func() {
+			s = manager.Open(conn)
+		})
+
+		It("starts playing the character", func() {
+			listen(PlayEvent)
+
+			Ω(manager.Play(s, "Izuna")).Should(Equal(s))
+			Ω(s.State()).Should(Equal(Playing))
+			Ω(s.Character()).Should(Equal("Izuna"))
+			Ω(manager.ForCharacter("izuna")).Should(Equal(s))
+
+			d := next()
+			Ω(d["session"]).Should(Equal(s.ID()))
+			Ω(d["character"]).Should(Equal("Izuna"))
+		})
+
+		It("reconnects to a link-dead character", func() {
+			listen(LinkDeadEvent, ReconnectEvent)
+			manager.Play(s, "Izuna")
+			manager.Drop(s)
+
+			Ω(s.State()).Should(Equal(LinkDead))
+			Ω(conn.isClosed()).Should(BeTrue())
+			Ω(next()["event"]).Should(Equal(LinkDeadEvent))
+
+			newConn := newFakeConn("")
+			fresh := manager.Open(newConn)
+			Ω(manager.Play(fresh, "izuna")).Should(Equal(s))
+			Ω(s.State()).Should(Equal(Playing))
+			Ω(s.Conn()).Should(Equal(newConn))
+			Ω(manager.Get(fresh.ID())).Should(BeNil())
+			Ω(manager.Sessions()).Should(HaveLen(1))
+
+			d := next()
+			Ω(d["event"]).Should(Equal(ReconnectEvent))
+			Ω(d["previous"]).Should(Equal("linkdead"))
+			Ω(d["replaced"]).Should(Equal(fresh.ID()))
+		})
+
+		It("receives data from the new connection after reconnecting", func() {
+			listen(DataEvent)
+			manager.Play(s, "Izuna")
+			manager.Drop(s)
+			newConn := newFakeConn("")
+			manager.Play(manager.Open(newConn), "Izuna")
+
+			newConn.onData("Core.Ping", nil)
+			Ω(next()["session"]).Should(Equal(s.ID()))
+		})
+
+		It("takes over a character connected elsewhere", func() {
+			manager.Play(s, "Izuna")
+			newConn := newFakeConn("say hi\n")
+
+			Ω(manager.Play(manager.Open(newConn), "Izuna")).Should(Equal(s))
+			Ω(conn.isClosed()).Should(BeTrue())
+			Ω(conn.written()).Should(ContainSubstring("reconnected from elsewhere"))
+
+			// the replaced connection failing doesn't affect the session
+			_, err := s.Read(make([]byte, 10))
+			Ω(err).Should(BeNil())
+			_, err = conn.Read(make([]byte, 10))
+			Ω(err).Should(Equal(io.EOF))
+			Ω(s.State()).Should(Equal(Playing))
+		})
+	})
+
+	Describe("Resume", func() {
+		It("continues a session handed over by another process", func() {
+			listen(OpenEvent, PlayEvent, ResumeEvent)
+			opened := now.Add(-time.Hour)
+			s = manager.Resume(conn, Handover{
+				ID:        "handed-over",
+				Character: "Izuna",
+				Opened:    opened,
+				Terminal:  server.Terminal{Width: 100, Color: output.Color256},
+			})
+
+			Ω(s.ID()).Should(Equal("handed-over"))
+			Ω(s.State()).Should(Equal(Playing))
+			Ω(s.Opened()).Should(Equal(opened))
+			Ω(s.Terminal().Width).Should(Equal(100))
+			Ω(manager.ForCharacter("izuna")).Should(Equal(s))
+			Ω(s.Handover().Character).Should(Equal("Izuna"))
+
+			d := next()
+			Ω(d["event"]).Should(Equal(ResumeEvent))
+			Ω(d["character"]).Should(Equal("Izuna"))
+			Consistently(received).ShouldNot(Receive())
+		})
+
+		It("takes over the session of a character already playing", func() {
+			s = manager.Open(conn)
+			manager.Play(s, "Izuna")
+
+			other := newFakeConn("")
+			Ω(manager.Resume(other, Handover{ID: "handed-over", Character: "Izuna"})).Should(Equal(s))
+			Ω(s.Conn()).Should(Equal(other))
+			Ω(manager.Sessions()).Should(HaveLen(1))
+		})
+	})
+
+	Describe("Session", func() {
+		BeforeEach(func() {
+			s = manager.Open(conn)
+		})
+
+		It("records input", func() {
+			now = now.Add(time.Second)
+			buf := make([]byte, 10)
+			n, err := s.Read(buf)
+
+			Ω(err).Should(BeNil())
+			Ω(string(buf[:n])).Should(Equal("look\n"))
+			Ω(s.LastInput()).Should(Equal(now))
+		})
+
+		It("goes link-dead when a playing connection fails", func() {
+			manager.Play(s, "Izuna")
+			s.Read(make([]byte, 10))
+			_, err := s.Read(make([]byte, 10))
+
+			Ω(err).Should(Equal(io.EOF))
+			Ω(s.State()).Should(Equal(LinkDead))
+		})
+
+		It("closes when a connection fails before playing", func() {
+			listen(CloseEvent)
+			s.Read(make([]byte, 10))
+			s.Read(make([]byte, 10))
+
+			Ω(s.State()).Should(Equal(Closed))
+			Ω(next()["reason"]).Should(Equal("disconnected"))
+		})
+
+		It("emits data sent by the client", func() {
+			listen(DataEvent)
+			var kinds []string
+			s.OnData(func(kind string, _ json.RawMessage) {
+				kinds = append(kinds, kind)
+			})
+			conn.onData("Char.Login", json.RawMessage(`{"name": "Izuna"}`))
+
+			d := next()
+			Ω(d["session"]).Should(Equal(s.ID()))
+			Ω(d["package"]).Should(Equal("Char.Login"))
+			Ω(d["data"]).Should(HaveKeyWithValue("name", "Izuna"))
+			Ω(kinds).Should(Equal([]string{"Char.Login"}))
+		})
+
+		It("uses the terminal reported by the client", func() {
+			Ω(s.Terminal().Color).Should(Equal(output.ColorBasic))
+			Ω(s.Output().Settings().Width).Should(Equal(DefaultWidth))
+
+			conn.onTerminal(server.Terminal{Width: 12, Type: "DUMB", Color: output.ColorMono})
+			s.Output().Send("[r]the red dragon[x] sleeps")
+
+			Ω(s.Terminal().Type).Should(Equal("DUMB"))
+			Ω(conn.written()).Should(Equal("the red\r\ndragon\r\nsleeps\r\n"))
+		})
+
+		It("leaves out color when the player turns it off", func() {
+			Ω(s.ColorEnabled()).Should(BeTrue())
+			s.SetColorEnabled(false)
+			conn.onTerminal(server.Terminal{Width: 100, Color: output.Color256})
+			s.Output().Send("[r]the red dragon[x] sleeps")
+
+			Ω(s.ColorEnabled()).Should(BeFalse())
+			Ω(s.Handover().NoColor).Should(BeTrue())
+			Ω(conn.written()).Should(Equal("the red dragon sleeps\r\n"))
+		})
+
+		It("redraws the prompt after the player's input", func() {
+			s.Output().SetPrompt("> ")
+			s.Output().Send("Welcome.")
+			conn.input.WriteString("look\n")
+			s.Read(make([]byte, 16))
+			s.Output().Send("A dark cave.")
+			s.Output().Send("A bat flies past.")
+
+			Ω(conn.written()).Should(Equal("Welcome.\r\n> A dark cave.\r\n> \r\nA bat flies past.\r\n> "))
+		})
+
+		It("keeps the terminal of the new connection after reconnecting", func() {
+			manager.Play(s, "Izuna")
+			manager.Drop(s)
+			newConn := newFakeConn("")
+			fresh := manager.Open(newConn)
+			newConn.onTerminal(server.Terminal{Width: 100, Color: output.Color256})
+			manager.Play(fresh, "Izuna")
+
+			Ω(s.Terminal().Width).Should(Equal(100))
+			newConn.onTerminal(server.Terminal{Width: 90})
+			Ω(s.Terminal().Width).Should(Equal(90))
+		})
+
+		It("discards output while link-dead", func() {
+			manager.Play(s, "Izuna")
+			manager.Drop(s)
+			n, err := s.Write([]byte("hello"))
+
+			Ω(n).Should(Equal(5))
+			Ω(err).Should(BeNil())
+			Ω(conn.written()).Should(BeEmpty())
+		})
+	})
+
+	Describe("Sweep", func() {
+		BeforeEach(func() {
+			s = manager.Open(conn)
+		})
+
+		It("closes idle sessions", func() {
+			listen(IdleEvent, CloseEvent)
+			now = now.Add(59 * time.Second)
+			manager.Sweep()
+			Ω(s.State()).Should(Equal(Connected))
+
+			now = now.Add(time.Second)
+			manager.Sweep()
+			Ω(s.State()).Should(Equal(Closed))
+			Ω(conn.isClosed()).Should(BeTrue())
+			Ω(conn.written()).Should(ContainSubstring("idle too long"))
+
+			first, second := next(), next()
+			Ω([]interface{}{first["event"], second["event"]}).Should(ConsistOf(IdleEvent, CloseEvent))
+		})
+
+		It("closes sessions link-dead too long", func() {
+			listen(CloseEvent)
+			manager.Play(s, "Izuna")
+			manager.Drop(s)
+
+			now = now.Add(4 * time.Minute)
+			manager.Sweep()
+			Ω(s.State()).Should(Equal(LinkDead))
+
+			now = now.Add(time.Minute)
+			manager.Sweep()
+			Ω(s.State()).Should(Equal(Closed))
+			Ω(manager.ForCharacter("Izuna")).Should(BeNil())
+			Ω(next()["reason"]).Should(Equal("linkdead"))
+		})
+
+		It("leaves sessions alone without timeouts", func() {
+			manager = NewManager(nil, Options{})
+			manager.SetClock(func() time.Time {
+				return now
+			})
+			s = manager.Open(conn)
+			now = now.Add(24 * time.Hour)
+			manager.Sweep()
+
+			Ω(s.State()).Should(Equal(Connected))
+		})
+	})
+})
@@ -0,0 +1,254 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package session tracks players from the moment their client connects until
+// they leave, regardless of the transport they connected with. A session
+// outlives the connection it started with: when a playing character's
+// connection drops the session goes link-dead and waits for the player to
+// reconnect, keeping their character in the game until it's reclaimed or the
+// link-dead timeout passes.
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/bbuck/dragon-mud/server"
+	"github.com/bbuck/dragon-mud/server/output"
+)
+
+// State is where a session is in its lifecycle.
+type State int
+
+// Session states
+const (
+	// Connected sessions have a connection but no character yet, such as
+	// players still logging in.
+	Connected State = iota
+	// Playing sessions have a connection and a character.
+	Playing
+	// LinkDead sessions lost their connection while playing, the character
+	// stays in the game until the player reconnects.
+	LinkDead
+	// Closed sessions have ended and are no longer tracked.
+	Closed
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Playing:
+		return "playing"
+	case LinkDead:
+		return "linkdead"
+	case Closed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Session is a player's time in the game. It's a server.Conn itself, writing
+// to and reading from whichever connection the player is using now. Output
+// written while the session is link-dead is discarded.
+type Session struct {
+	id        string
+	manager   *Manager
+	conn      server.Conn
+	character string
+	state     State
+	opened    time.Time
+	lastInput time.Time
+	linkDead  time.Time
+	onData    func(kind string, data json.RawMessage)
+	terminal  server.Terminal
+	noColor   bool
+	out       *output.Writer
+}
+
+// Handover is what another process needs to continue a playing session, it
+// can be encoded as JSON. See Manager.Resume.
+type Handover struct {
+	ID        string          `json:"id"`
+	Character string          `json:"character"`
+	Opened    time.Time       `json:"opened"`
+	Terminal  server.Terminal `json:"terminal"`
+	NoColor   bool            `json:"no_color,omitempty"`
+}
+
+// DefaultWidth is the width output is wrapped to for clients that don't
+// report the size of their window.
+const DefaultWidth = 80
+
+// ID uniquely identifies the session.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Character is the name of the character being played, empty until the
+// session is playing.
+func (s *Session) Character() string {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return s.character
+}
+
+// State returns where the session is in its lifecycle.
+func (s *Session) State() State {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return s.state
+}
+
+// Opened is when the session started.
+func (s *Session) Opened() time.Time {
+	return s.opened
+}
+
+// LastInput is when the player last sent anything, or when they connected if
+// they haven't.
+func (s *Session) LastInput() time.Time {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return s.lastInput
+}
+
+// Terminal returns what the client reported about its display, clients that
+// report nothing are assumed to support the basic colors.
+func (s *Session) Terminal() server.Terminal {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return s.terminal
+}
+
+// ColorEnabled returns whether the player wants color, it's on unless they
+// turn it off.
+func (s *Session) ColorEnabled() bool {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return !s.noColor
+}
+
+// SetColorEnabled turns color on or off for the player. While it's off output
+// is sent without color whatever the client can display.
+func (s *Session) SetColorEnabled(enabled bool) {
+	s.manager.mutex.Lock()
+	s.noColor = !enabled
+	t := s.terminal
+	s.manager.mutex.Unlock()
+
+	s.out.SetSettings(outputSettings(t, !enabled))
+}
+
+// Handover describes the session for another process to continue it.
+func (s *Session) Handover() Handover {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return Handover{
+		ID:        s.id,
+		Character: s.character,
+		Opened:    s.opened,
+		Terminal:  s.terminal,
+		NoColor:   s.noColor,
+	}
+}
+
+// Output returns the writer game text should be sent to the player with,
+// it's wrapped to the width of the client's window and colored for what it
+// can display.
+func (s *Session) Output() *output.Writer {
+	return s.out
+}
+
+// Conn returns the connection the session is currently using.
+func (s *Session) Conn() server.Conn {
+	s.manager.mutex.RLock()
+	defer s.manager.mutex.RUnlock()
+
+	return s.conn
+}
+
+// Read reads from the current connection. If the connection fails while the
+// session still uses it, the session is dropped, becoming link-dead if a
+// character is being played. A connection replaced by a reconnect fails
+// without affecting the session.
+func (s *Session) Read(p []byte) (int, error) {
+	c := s.Conn()
+	n, err := c.Read(p)
+	if n > 0 {
+		s.manager.touch(s)
+	}
+	if bytes.IndexByte(p[:n], '\n') >= 0 {
+		s.out.ClearPrompt()
+	}
+	if err != nil {
+		s.manager.lost(s, c)
+	}
+
+	return n, err
+}
+
+// Write writes to the current connection as it is, output is discarded while
+// the session is link-dead. Game text should be sent through Output instead.
+func (s *Session) Write(p []byte) (int, error) {
+	s.manager.mutex.RLock()
+	c, state := s.conn, s.state
+	s.manager.mutex.RUnlock()
+
+	if state == LinkDead || state == Closed {
+		return len(p), nil
+	}
+
+	return c.Write(p)
+}
+
+// Close ends the session as though the player quit, output waiting to be
+// sent is written first.
+func (s *Session) Close() error {
+	s.manager.Close(s, "quit")
+
+	return nil
+}
+
+// RemoteAddr is the address of the current, or last, connection.
+func (s *Session) RemoteAddr() net.Addr {
+	return s.Conn().RemoteAddr()
+}
+
+// Transport is the transport of the current, or last, connection.
+func (s *Session) Transport() string {
+	return s.Conn().Transport()
+}
+
+// OnData sets a function called with data sent by the client, it follows
+// the session across reconnects. The data is emitted as a DataEvent whether
+// or not a function is set.
+func (s *Session) OnData(fn func(kind string, data json.RawMessage)) {
+	s.manager.mutex.Lock()
+	defer s.manager.mutex.Unlock()
+
+	s.onData = fn
+}
+
+// SendData sends data over the current connection, it's discarded while the
+// session is link-dead.
+func (s *Session) SendData(kind string, data interface{}) error {
+	s.manager.mutex.RLock()
+	c, state := s.conn, s.state
+	s.manager.mutex.RUnlock()
+
+	if state == LinkDead || state == Closed {
+		return nil
+	}
+
+	return c.SendData(kind, data)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package websocket lets browser clients connect to the game over WebSockets.
+// Every frame is a text frame holding a JSON envelope, {"type": "...",
+// "data": ...}. Game text is sent to the client with the type "text", and
+// the client sends what the player types with the type "input" (plain,
+// non-JSON frames are also accepted as input). Any other type is out-of-band
+// data, like the player's vitals, for the client to handle itself.
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/server"
+	"golang.org/x/net/websocket"
+)
+
+// Envelope types used for game text.
+const (
+	TextType  = "text"
+	InputType = "input"
+)
+
+// Envelope wraps every message sent over the WebSocket.
+type Envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Conn adapts a WebSocket to a server.Conn.
+type Conn struct {
+	ws      *websocket.Conn
+	remote  net.Addr
+	input   bytes.Buffer
+	onData  func(kind string, data json.RawMessage)
+	readMu  *sync.Mutex
+	writeMu *sync.Mutex
+}
+
+// NewConn wraps the WebSocket, remote is the address of the client.
+func NewConn(ws *websocket.Conn, remote net.Addr) *Conn {
+	return &Conn{
+		ws:      ws,
+		remote:  remote,
+		readMu:  new(sync.Mutex),
+		writeMu: new(sync.Mutex),
+	}
+}
+
+// OnData sets the function called with out-of-band data sent by the client,
+// it's called from Read. Data is discarded if no function is set.
+func (c *Conn) OnData(fn func(kind string, data json.RawMessage)) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	c.onData = fn
+}
+
+// Read reads what the player typed, each input message is a line.
+func (c *Conn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for c.input.Len() == 0 {
+		var frame string
+		if err := websocket.Message.Receive(c.ws, &frame); err != nil {
+			return 0, err
+		}
+
+		var env Envelope
+		if err := json.Unmarshal([]byte(frame), &env); err != nil || env.Type == "" {
+			c.addInput(frame)
+
+			continue
+		}
+
+		if env.Type == InputType {
+			var line string
+			if err := json.Unmarshal(env.Data, &line); err != nil {
+				continue
+			}
+			c.addInput(line)
+		} else if c.onData != nil {
+			c.onData(env.Type, env.Data)
+		}
+	}
+
+	return c.input.Read(p)
+}
+
+// buffer a line of input, making sure it ends in a newline
+func (c *Conn) addInput(line string) {
+	c.input.WriteString(strings.TrimRight(line, "\r\n"))
+	c.input.WriteByte('\n')
+}
+
+// Write sends game text to the client.
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.send(TextType, string(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// SendData sends out-of-band data to the client, the kind is the envelope's
+// type so it can't be "text".
+func (c *Conn) SendData(kind string, data interface{}) error {
+	if kind == TextType || kind == "" {
+		return fmt.Errorf("invalid data type %q", kind)
+	}
+
+	return c.send(kind, data)
+}
+
+func (c *Conn) send(kind string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return websocket.JSON.Send(c.ws, Envelope{Type: kind, Data: raw})
+}
+
+// Close closes the WebSocket.
+func (c *Conn) Close() error {
+	return c.ws.Close()
+}
+
+// RemoteAddr is the address of the client, the WebSocket only knows the
+// origin of the page.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Transport is always server.WebSocket.
+func (c *Conn) Transport() string {
+	return server.WebSocket
+}
+
+// Handler accepts WebSocket connections, passing each to handle. Connections
+// are only accepted from pages served by one of the origins, or any page if
+// no origins are given.
+func Handler(origins []string, handle func(server.Conn)) http.Handler {
+	return websocket.Server{
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			return checkOrigin(origins, r)
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.TextFrame
+			handle(NewConn(ws, remoteAddr(ws.Request())))
+		},
+	}
+}
+
+func checkOrigin(origins []string, r *http.Request) error {
+	if len(origins) == 0 {
+		return nil
+	}
+
+	origin := r.Header.Get("Origin")
+	for _, o := range origins {
+		if strings.EqualFold(o, origin) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// wsAddr is the address of a WebSocket client
+type wsAddr string
+
+func (a wsAddr) Network() string { return "websocket" }
+func (a wsAddr) String() string  { return string(a) }
+
+func remoteAddr(r *http.Request) net.Addr {
+	return wsAddr(r.RemoteAddr)
+}
@@ -0,0 +1,137 @@
+package websocket_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/server"
+	. "github.com/bbuck/dragon-mud/server/websocket"
+	"golang.org/x/net/websocket"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebSocket", func() {
+	var (
+		srv   *httptest.Server
+		conns chan server.Conn
+		url   string
+	)
+
+	start := func(origins ...string) {
+		conns = make(chan server.Conn, 1)
+		srv = httptest.NewServer(Handler(origins, func(c server.Conn) {
+			conns <- c
+			// the connection closes when the handler returns
+			io.Copy(ioutil.Discard, c)
+		}))
+		url = "ws" + strings.TrimPrefix(srv.URL, "http")
+	}
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	dial := func(origin string) *websocket.Conn {
+		ws, err := websocket.Dial(url, "", origin)
+		Ω(err).Should(BeNil())
+
+		return ws
+	}
+
+	receive := func(ws *websocket.Conn) Envelope {
+		var env Envelope
+		Ω(websocket.JSON.Receive(ws, &env)).Should(Succeed())
+
+		return env
+	}
+
+	It("sends game text in envelopes", func() {
+		start()
+		ws := dial("http://localhost/")
+		defer ws.Close()
+		c := <-conns
+
+		Ω(c.Transport()).Should(Equal(server.WebSocket))
+		c.Write([]byte("You see a dragon.\r\n"))
+
+		env := receive(ws)
+		Ω(env.Type).Should(Equal(TextType))
+		Ω(string(env.Data)).Should(Equal(`"You see a dragon.\r\n"`))
+	})
+
+	It("sends out-of-band data", func() {
+		start()
+		ws := dial("http://localhost/")
+		defer ws.Close()
+		c := <-conns
+
+		Ω(c.SendData("vitals", map[string]int{"hp": 10})).Should(Succeed())
+		env := receive(ws)
+		Ω(env.Type).Should(Equal("vitals"))
+		Ω(string(env.Data)).Should(Equal(`{"hp":10}`))
+
+		Ω(c.SendData(TextType, nil)).ShouldNot(Succeed())
+	})
+
+	It("rejects pages from other origins", func() {
+		start("https://mud.example.com")
+
+		_, err := websocket.Dial(url, "", "https://evil.example.com")
+		Ω(err).ShouldNot(BeNil())
+
+		ws := dial("https://mud.example.com")
+		ws.Close()
+	})
+
+	Describe("reading input", func() {
+		var (
+			ws   *websocket.Conn
+			conn *Conn
+		)
+
+		BeforeEach(func() {
+			conn = nil
+			srvConns := make(chan *Conn, 1)
+			srv = httptest.NewServer(Handler(nil, func(c server.Conn) {
+				srvConns <- c.(*Conn)
+				<-make(chan struct{})
+			}))
+			url = "ws" + strings.TrimPrefix(srv.URL, "http")
+			ws = dial("http://localhost/")
+			conn = <-srvConns
+		})
+
+		AfterEach(func() {
+			ws.Close()
+		})
+
+		It("reads input envelopes and plain frames as lines", func() {
+			websocket.JSON.Send(ws, map[string]string{"type": InputType, "data": "look"})
+			websocket.Message.Send(ws, "north\r\n")
+
+			lines := bufio.NewReader(conn)
+			Ω(lines.ReadString('\n')).Should(Equal("look\n"))
+			Ω(lines.ReadString('\n')).Should(Equal("north\n"))
+		})
+
+		It("passes out-of-band data to the handler", func() {
+			received := make(chan string, 1)
+			conn.OnData(func(kind string, data json.RawMessage) {
+				received <- kind + " " + string(data)
+			})
+
+			websocket.JSON.Send(ws, map[string]interface{}{"type": "resize", "data": map[string]int{"width": 80}})
+			websocket.JSON.Send(ws, map[string]string{"type": InputType, "data": "look"})
+
+			lines := bufio.NewReader(conn)
+			Ω(lines.ReadString('\n')).Should(Equal("look\n"))
+			Ω(received).Should(Receive(Equal(`resize {"width":80}`)))
+		})
+	})
+})
@@ -310,6 +310,37 @@ var _ = Describe("LiveDB", func() {
 				})
 			})
 
+			Context("within a transaction", func() {
+				It("runs one statement after another", func() {
+					tx, err := db.Begin()
+					Ω(err).ShouldNot(HaveOccurred())
+
+					By("creating nodes with two statements")
+
+					result, err := tx.Cypher(`CREATE (:TalonTxTest {n: 1})`).Exec()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(result.Stats.NodesCreated).Should(BeEquivalentTo(1))
+
+					result, err = tx.Cypher(`CREATE (:TalonTxTest {n: 2})`).Exec()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(result.Stats.NodesCreated).Should(BeEquivalentTo(1))
+
+					By("querying after the rows of another query are closed")
+
+					rows, err := tx.Cypher(`MATCH (n:TalonTxTest) RETURN n`).Query()
+					Ω(err).ShouldNot(HaveOccurred())
+					all, err := rows.All()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(all).Should(HaveLen(2))
+
+					result, err = tx.Cypher(`MATCH (n:TalonTxTest) DELETE n`).Exec()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(result.Stats.NodesDeleted).Should(BeEquivalentTo(2))
+
+					Ω(tx.Commit()).Should(Succeed())
+				})
+			})
+
 			Context("returning other data types", func() {
 				It("handling string value returns", func() {
 					By("adding a test node to the database")
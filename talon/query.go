@@ -11,8 +11,10 @@ var noProperties = make(Properties)
 // Query reprsents a Talon query before it's been converted in Cypher
 type Query struct {
 	db         *DB
+	conn       bolt.Conn
 	rawCypher  string
 	properties Properties
+	err        error
 }
 
 // ToCypher converts a query object into a Cypher query string.
@@ -35,22 +37,27 @@ func (q *Query) Query() (*Rows, error) {
 
 	rows, err := stmt.QueryNeo(q.propsForQuery())
 	if err != nil {
-		conn.Close()
+		stmt.Close()
+		q.release(conn)
 
 		return nil, err
 	}
 
 	r := wrapBoltRows(rows)
+	r.stmt = stmt
 
 	return r, nil
 }
 
-// Exec runs a query that doesn't expect rows to be returned.
+// Exec runs a query that doesn't expect rows to be returned. The statement is
+// closed once it's run, a connection only has one open statement at a time so
+// the next query of a transaction couldn't be prepared otherwise.
 func (q *Query) Exec() (*Result, error) {
 	_, stmt, err := q.getStatement()
 	if err != nil {
 		return nil, err
 	}
+	defer stmt.Close()
 
 	result, err := stmt.ExecNeo(q.propsForQuery())
 	if err != nil {
@@ -61,14 +68,22 @@ func (q *Query) Exec() (*Result, error) {
 }
 
 func (q *Query) getStatement() (bolt.Conn, bolt.Stmt, error) {
-	conn, err := q.db.conn()
-	if err != nil {
-		return nil, nil, err
+	if q.err != nil {
+		return nil, nil, q.err
+	}
+
+	conn := q.conn
+	if conn == nil {
+		var err error
+		conn, err = q.db.conn()
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	stmt, err := conn.PrepareNeo(q.ToCypher())
 	if err != nil {
-		conn.Close()
+		q.release(conn)
 
 		return nil, nil, err
 	}
@@ -76,6 +91,14 @@ func (q *Query) getStatement() (bolt.Conn, bolt.Stmt, error) {
 	return conn, stmt, nil
 }
 
+// close the connection unless it's owned by a transaction, in which case the
+// transaction is responsible for it.
+func (q *Query) release(conn bolt.Conn) {
+	if q.conn == nil {
+		conn.Close()
+	}
+}
+
 func (q *Query) propsForQuery() map[string]interface{} {
 	if len(q.properties) == 0 {
 		return nil
@@ -81,6 +81,9 @@ type Rows struct {
 
 	closed   bool
 	boltRows bolt.Rows
+	// the statement the rows were queried with, closing the rows doesn't
+	// close it
+	stmt bolt.Stmt
 }
 
 // create a talon.Rows object from a bolt.Rows object.
@@ -91,11 +94,15 @@ func wrapBoltRows(rs bolt.Rows) *Rows {
 	}
 }
 
-// Close will close the incoming stream of graph entities.
+// Close will close the incoming stream of graph entities, and the statement
+// they came from so the connection can run another.
 func (r *Rows) Close() {
 	if !r.closed {
 		r.closed = true
 		r.boltRows.Close()
+		if r.stmt != nil {
+			r.stmt.Close()
+		}
 	}
 }
 
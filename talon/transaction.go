@@ -0,0 +1,112 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package talon
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+
+	bolt "github.com/johnnadratowski/golang-neo4j-bolt-driver"
+)
+
+// ErrTxClosed is returned when an already committed or rolled back transaction
+// is used again.
+var ErrTxClosed = errors.New("transaction has already been committed or rolled back")
+
+// Tx represents an open transaction on the database. All queries built from a
+// transaction share the same underlying connection, which is released once the
+// transaction is committed or rolled back.
+type Tx struct {
+	conn   bolt.Conn
+	tx     sqldriver.Tx
+	closed bool
+}
+
+// Begin opens a new connection to the database and starts a transaction on
+// it.
+func (d *DB) Begin() (*Tx, error) {
+	conn, err := d.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return &Tx{
+		conn: conn,
+		tx:   tx,
+	}, nil
+}
+
+// CypherP builds a query with properties that will execute within the
+// transaction.
+func (t *Tx) CypherP(cypher string, p Properties) (*Query, error) {
+	if t.closed {
+		return nil, ErrTxClosed
+	}
+
+	props, err := p.MarshaledProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{
+		conn:       t.conn,
+		rawCypher:  cypher,
+		properties: props,
+	}
+
+	return q, nil
+}
+
+// Cypher builds a query from a raw cypher string that will execute within the
+// transaction.
+func (t *Tx) Cypher(cypher string) *Query {
+	q, err := t.CypherP(cypher, noProperties)
+	if err != nil {
+		return &Query{
+			conn:      t.conn,
+			rawCypher: cypher,
+			err:       err,
+		}
+	}
+
+	return q
+}
+
+// Commit will commit all changes made within the transaction and release the
+// connection.
+func (t *Tx) Commit() error {
+	if t.closed {
+		return ErrTxClosed
+	}
+
+	return t.finish(t.tx.Commit())
+}
+
+// Rollback discards all changes made within the transaction and releases the
+// connection.
+func (t *Tx) Rollback() error {
+	if t.closed {
+		return ErrTxClosed
+	}
+
+	return t.finish(t.tx.Rollback())
+}
+
+// mark the transaction closed and release it's connection, preferring the
+// error from the commit/rollback over the error from closing.
+func (t *Tx) finish(err error) error {
+	t.closed = true
+	cerr := t.conn.Close()
+	if err != nil {
+		return err
+	}
+
+	return cerr
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CHARSET subnegotiation commands
+const (
+	charsetRequest  byte = 1
+	charsetAccepted byte = 2
+	charsetRejected byte = 3
+)
+
+// UTF8 is the character set used by the server, text is only converted for
+// clients using another.
+const UTF8 = "UTF-8"
+
+// a single byte character set text can be converted to, runes it can't
+// represent are sent as '?'
+type charset struct {
+	name string
+	// nil for US-ASCII, which only keeps the first 128 runes
+	charmap *charmap.Charmap
+}
+
+// charsets the server can convert text to, in the order they're offered to
+// clients, UTF-8 needs no conversion
+var charsets = []charset{
+	{name: "ISO-8859-1", charmap: charmap.ISO8859_1},
+	{name: "CP437", charmap: charmap.CodePage437},
+	{name: "US-ASCII"},
+}
+
+// other names clients use for the supported character sets
+var charsetAliases = map[string]string{
+	"UTF8":       UTF8,
+	"LATIN1":     "ISO-8859-1",
+	"ISO8859-1":  "ISO-8859-1",
+	"ISO_8859-1": "ISO-8859-1",
+	"IBM437":     "CP437",
+	"ASCII":      "US-ASCII",
+}
+
+// findCharset returns the supported character set with the name, nil is
+// returned for UTF-8
+func findCharset(name string) (*charset, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if alias, ok := charsetAliases[name]; ok {
+		name = alias
+	}
+	if name == UTF8 {
+		return nil, true
+	}
+	for i := range charsets {
+		if charsets[i].name == name {
+			return &charsets[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// encode converts UTF-8 text to the character set
+func (cs *charset) encode(text []byte) []byte {
+	encoded := make([]byte, 0, len(text))
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+
+		switch {
+		case r < utf8.RuneSelf:
+			encoded = append(encoded, byte(r))
+		case cs.charmap != nil:
+			b, ok := cs.charmap.EncodeRune(r)
+			if !ok {
+				b = '?'
+			}
+			encoded = append(encoded, b)
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+
+	return encoded
+}
+
+// decode converts text in the character set to UTF-8
+func (cs *charset) decode(text []byte) []byte {
+	decoded := make([]byte, 0, len(text))
+	for _, b := range text {
+		r := rune(b)
+		switch {
+		case b < utf8.RuneSelf:
+		case cs.charmap != nil:
+			r = cs.charmap.DecodeByte(b)
+		default:
+			r = utf8.RuneError
+		}
+		decoded = append(decoded, string(r)...)
+	}
+
+	return decoded
+}
+
+// RequestCharset offers to negotiate the character set with the client, it
+// will be asked to choose from UTF-8 or one of the fallbacks the server can
+// convert text to.
+func (c *Conn) RequestCharset() error {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.terminal.askedCharset = true
+
+	return c.sendCommand(WILL, CHARSET)
+}
+
+// SetCharset sets the character set text is converted to and from, like
+// "UTF-8", "ISO-8859-1" or "CP437". It's used until the client negotiates
+// another.
+func (c *Conn) SetCharset(name string) error {
+	cs, ok := findCharset(name)
+	if !ok {
+		return fmt.Errorf("unsupported character set %q", name)
+	}
+
+	c.wmutex.Lock()
+	c.setCharset(cs)
+	c.wmutex.Unlock()
+
+	c.terminalChanged()
+
+	return nil
+}
+
+// setCharset must be called with the write mutex held
+func (c *Conn) setCharset(cs *charset) {
+	c.charset = cs
+	c.terminal.Charset = UTF8
+	if cs != nil {
+		c.terminal.Charset = cs.name
+	}
+}
+
+// charsetAccepted handles DO CHARSET by asking the client to pick a character
+// set, the write mutex must be held
+func (c *Conn) charsetAccepted() {
+	if !c.terminal.askedCharset {
+		c.terminal.askedCharset = true
+		c.sendCommand(WILL, CHARSET)
+	}
+
+	names := []string{UTF8}
+	for _, cs := range charsets {
+		names = append(names, cs.name)
+	}
+	msg := append([]byte{charsetRequest}, ";"+strings.Join(names, ";")...)
+	c.sendSubnegotiation(CHARSET, msg)
+}
+
+// charsetReceived handles the client choosing a character set, or asking the
+// server to choose one
+func (c *Conn) charsetReceived(data []byte) {
+	if len(data) < 1 {
+		return
+	}
+
+	switch data[0] {
+	case charsetAccepted:
+		if cs, ok := findCharset(string(data[1:])); ok {
+			c.wmutex.Lock()
+			c.setCharset(cs)
+			c.wmutex.Unlock()
+
+			c.terminalChanged()
+		}
+	case charsetRequest:
+		c.charsetRequested(data[1:])
+	}
+}
+
+// charsetRequested accepts the first character set offered by the client that
+// the server supports
+func (c *Conn) charsetRequested(data []byte) {
+	// the first byte separates the names, an optional "[TTABLE]" version may
+	// come before it
+	if bytes.HasPrefix(data, []byte("[TTABLE]")) && len(data) > 9 {
+		data = data[9:]
+	}
+	if len(data) < 2 {
+		return
+	}
+
+	c.wmutex.Lock()
+	for _, name := range bytes.Split(data[1:], data[:1]) {
+		if cs, ok := findCharset(string(name)); ok {
+			c.setCharset(cs)
+			c.sendSubnegotiation(CHARSET, append([]byte{charsetAccepted}, name...))
+			c.wmutex.Unlock()
+
+			c.terminalChanged()
+
+			return
+		}
+	}
+	c.sendSubnegotiation(CHARSET, []byte{charsetRejected})
+	c.wmutex.Unlock()
+}
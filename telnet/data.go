@@ -0,0 +1,44 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import "encoding/json"
+
+// Structured data is exchanged as named values, GMCP packages and MSDP
+// variables are both converted to and from a name with a JSON value so the
+// rest of the server doesn't need to know which protocol a client speaks.
+
+// SendData sends structured data named kind, as a GMCP package if the client
+// agreed to GMCP or otherwise as an MSDP variable. It's dropped for clients
+// that support neither.
+func (c *Conn) SendData(kind string, data interface{}) error {
+	c.wmutex.Lock()
+	gmcp := c.gmcp.enabled
+	c.wmutex.Unlock()
+
+	if gmcp {
+		return c.SendGMCP(kind, data)
+	}
+
+	return c.SendMSDP(kind, data)
+}
+
+// OnData sets the function called with structured data sent by the client
+// over either protocol, it's called from Read.
+func (c *Conn) OnData(fn func(kind string, data json.RawMessage)) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.onData = fn
+}
+
+// dataReceived passes data from the client to the OnData function
+func (c *Conn) dataReceived(kind string, data json.RawMessage) {
+	c.wmutex.Lock()
+	fn := c.onData
+	c.wmutex.Unlock()
+
+	if fn != nil {
+		fn(kind, data)
+	}
+}
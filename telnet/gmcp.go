@@ -0,0 +1,109 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// gmcp is the GMCP state of a connection, it's guarded by the write mutex
+type gmcp struct {
+	// wanted is true while the server wants to send GMCP
+	wanted bool
+	// offered is true once WILL GMCP was sent
+	offered bool
+	// enabled is true while the client agrees to GMCP
+	enabled bool
+}
+
+// SetGMCP offers the client GMCP, or withdraws it. Packages are only sent
+// once the client agrees to receive them.
+func (c *Conn) SetGMCP(enabled bool) error {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.gmcp.wanted = enabled
+	switch {
+	case enabled && !c.gmcp.offered:
+		c.gmcp.offered = true
+
+		return c.sendCommand(WILL, GMCP)
+	case !enabled && c.gmcp.offered:
+		c.gmcp.offered = false
+		c.gmcp.enabled = false
+
+		return c.sendCommand(WONT, GMCP)
+	}
+
+	return nil
+}
+
+// GMCP is true while the client accepts GMCP packages.
+func (c *Conn) GMCP() bool {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	return c.gmcp.enabled
+}
+
+// SendGMCP sends a package, such as "Char.Vitals", with data encoded as JSON.
+// Nil data sends only the package name. Packages are silently dropped when
+// the client hasn't agreed to GMCP.
+func (c *Conn) SendGMCP(pkg string, data interface{}) error {
+	msg := []byte(pkg)
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		msg = append(append(msg, ' '), encoded...)
+	}
+	msg = bytes.Replace(msg, []byte{IAC}, []byte{IAC, IAC}, -1)
+
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	if !c.gmcp.enabled {
+		return nil
+	}
+
+	return c.sendSubnegotiation(GMCP, msg)
+}
+
+// gmcpAccepted handles DO GMCP, the write mutex must be held
+func (c *Conn) gmcpAccepted() {
+	if !c.gmcp.wanted {
+		c.sendCommand(WONT, GMCP)
+
+		return
+	}
+	if !c.gmcp.offered {
+		c.gmcp.offered = true
+		c.sendCommand(WILL, GMCP)
+	}
+	c.gmcp.enabled = true
+}
+
+// gmcpRefused handles DONT GMCP, the write mutex must be held
+func (c *Conn) gmcpRefused() {
+	if c.gmcp.enabled {
+		c.sendCommand(WONT, GMCP)
+	}
+	c.gmcp.enabled = false
+	c.gmcp.offered = false
+}
+
+// gmcpReceived passes a package from the client to the OnData function, the
+// data is the JSON following the package name
+func (c *Conn) gmcpReceived(data []byte) {
+	pkg, payload := data, []byte(nil)
+	if i := bytes.IndexByte(data, ' '); i >= 0 {
+		pkg, payload = data[:i], bytes.TrimSpace(data[i+1:])
+	}
+	if len(payload) > 0 {
+		payload = append([]byte(nil), payload...)
+	}
+
+	c.dataReceived(string(pkg), json.RawMessage(payload))
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/metrics"
+)
+
+var (
+	mccpRawBytes  *metrics.Counter
+	mccpSentBytes *metrics.Counter
+	mccpMetrics   sync.Once
+)
+
+// mccp is the compression state of a connection, it's guarded by the write
+// mutex
+type mccp struct {
+	// wanted is true while the server wants to compress
+	wanted bool
+	// offered is true once WILL MCCP2 was sent
+	offered bool
+	// accepted is true while the client agrees to compression
+	accepted bool
+	writer   *zlib.Writer
+	raw      uint64
+	sent     uint64
+}
+
+// write compresses p, flushing so the client can show it right away
+func (m *mccp) write(p []byte) (int, error) {
+	sent := m.sent
+	n, err := m.writer.Write(p)
+	if err == nil {
+		err = m.writer.Flush()
+	}
+	m.raw += uint64(n)
+
+	mccpMetrics.Do(func() {
+		mccpRawBytes, _ = metrics.Global().Counter("telnet.mccp.raw_bytes")
+		mccpSentBytes, _ = metrics.Global().Counter("telnet.mccp.sent_bytes")
+	})
+	mccpRawBytes.Add(uint64(n))
+	mccpSentBytes.Add(m.sent - sent)
+
+	return n, err
+}
+
+// SetCompression turns MCCP2 compression of everything sent to the client on
+// or off. Turning it on offers compression to the client, it starts once the
+// client accepts. Turning it off ends the compressed stream, the client can
+// be offered compression again later.
+func (c *Conn) SetCompression(enabled bool) error {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.compression.wanted = enabled
+	switch {
+	case enabled && c.compression.accepted:
+		return c.startCompression()
+	case enabled && !c.compression.offered:
+		c.compression.offered = true
+
+		return c.sendCommand(WILL, MCCP2)
+	case !enabled:
+		return c.stopCompression()
+	}
+
+	return nil
+}
+
+// Compressing is true while output to the client is compressed.
+func (c *Conn) Compressing() bool {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	return c.compression.writer != nil
+}
+
+// CompressionStats returns the number of bytes written while compressing and
+// the number of bytes they were compressed to, across every time compression
+// was on.
+func (c *Conn) CompressionStats() (raw, sent uint64) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	return c.compression.raw, c.compression.sent
+}
+
+// compressionAccepted handles DO MCCP2, the write mutex must be held
+func (c *Conn) compressionAccepted() {
+	if !c.compression.wanted {
+		c.sendCommand(WONT, MCCP2)
+
+		return
+	}
+	if !c.compression.offered {
+		c.compression.offered = true
+		c.sendCommand(WILL, MCCP2)
+	}
+	c.compression.accepted = true
+	c.startCompression()
+}
+
+// compressionRefused handles DONT MCCP2, the write mutex must be held
+func (c *Conn) compressionRefused() {
+	wasOn := c.compression.writer != nil
+	c.stopCompression()
+	c.compression.accepted = false
+	c.compression.offered = false
+	if wasOn {
+		c.sendCommand(WONT, MCCP2)
+	}
+}
+
+// startCompression tells the client compression begins and compresses
+// everything after, the write mutex must be held
+func (c *Conn) startCompression() error {
+	if c.compression.writer != nil {
+		return nil
+	}
+	if err := c.sendSubnegotiation(MCCP2, nil); err != nil {
+		return err
+	}
+	c.compression.writer = zlib.NewWriter(countingWriter{w: c.Conn, n: &c.compression.sent})
+
+	return nil
+}
+
+// stopCompression ends the compressed stream, the client reads what follows
+// uncompressed. The write mutex must be held.
+func (c *Conn) stopCompression() error {
+	if c.compression.writer == nil {
+		return nil
+	}
+	w := c.compression.writer
+	c.compression.writer = nil
+
+	return w.Close()
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n *uint64
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += uint64(n)
+
+	return n, err
+}
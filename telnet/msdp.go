@@ -0,0 +1,391 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// bytes marking the parts of MSDP data
+const (
+	msdpVar        byte = 1
+	msdpVal        byte = 2
+	msdpTableOpen  byte = 3
+	msdpTableClose byte = 4
+	msdpArrayOpen  byte = 5
+	msdpArrayClose byte = 6
+)
+
+// commands clients can send to ask for variables
+var msdpCommands = []string{"LIST", "REPORT", "RESET", "SEND", "UNREPORT"}
+
+// msdp is the MSDP state of a connection, it's guarded by the write mutex
+type msdp struct {
+	// wanted is true while the server wants to send MSDP
+	wanted bool
+	// offered is true once WILL MSDP was sent
+	offered bool
+	// enabled is true while the client agrees to MSDP
+	enabled bool
+	// values holds the last value sent for each variable, so they can be
+	// listed and sent when asked for
+	values map[string]interface{}
+	// reported holds the variables the client wants sent as they change
+	reported map[string]bool
+}
+
+// SetMSDP offers the client MSDP, or withdraws it.
+func (c *Conn) SetMSDP(enabled bool) error {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.msdp.wanted = enabled
+	switch {
+	case enabled && !c.msdp.offered:
+		c.msdp.offered = true
+
+		return c.sendCommand(WILL, MSDP)
+	case !enabled && c.msdp.offered:
+		c.msdp.offered = false
+		c.msdp.enabled = false
+
+		return c.sendCommand(WONT, MSDP)
+	}
+
+	return nil
+}
+
+// MSDP is true while the client accepts MSDP variables.
+func (c *Conn) MSDP() bool {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	return c.msdp.enabled
+}
+
+// SendMSDP updates the variable, sending it if the client asked for it to be
+// reported. Maps are sent as MSDP tables and slices as arrays.
+func (c *Conn) SendMSDP(name string, data interface{}) error {
+	value, err := msdpValue(data)
+	if err != nil {
+		return err
+	}
+
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	if c.msdp.values == nil {
+		c.msdp.values = make(map[string]interface{})
+	}
+	c.msdp.values[name] = value
+	if !c.msdp.enabled || !c.msdp.reported[name] {
+		return nil
+	}
+
+	return c.sendMSDPVariable(name, value)
+}
+
+// msdpAccepted handles DO MSDP, the write mutex must be held
+func (c *Conn) msdpAccepted() {
+	if !c.msdp.wanted {
+		c.sendCommand(WONT, MSDP)
+
+		return
+	}
+	if !c.msdp.offered {
+		c.msdp.offered = true
+		c.sendCommand(WILL, MSDP)
+	}
+	c.msdp.enabled = true
+}
+
+// msdpRefused handles DONT MSDP, the write mutex must be held
+func (c *Conn) msdpRefused() {
+	if c.msdp.enabled {
+		c.sendCommand(WONT, MSDP)
+	}
+	c.msdp.enabled = false
+	c.msdp.offered = false
+	c.msdp.reported = nil
+}
+
+// msdpReceived handles variables sent by the client. Commands asking for
+// variables are answered, anything else is passed to the OnData function.
+func (c *Conn) msdpReceived(data []byte) {
+	for _, v := range parseMSDP(data) {
+		switch strings.ToUpper(v.name) {
+		case "LIST":
+			c.msdpList(v.value)
+		case "REPORT":
+			c.msdpReport(v.value, true)
+		case "UNREPORT":
+			c.msdpReport(v.value, false)
+		case "SEND":
+			c.msdpSend(v.value)
+		case "RESET":
+			c.wmutex.Lock()
+			c.msdp.reported = nil
+			c.wmutex.Unlock()
+		default:
+			encoded, err := json.Marshal(v.value)
+			if err == nil {
+				c.dataReceived(v.name, json.RawMessage(encoded))
+			}
+		}
+	}
+}
+
+// msdpList answers a LIST command
+func (c *Conn) msdpList(value interface{}) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	for _, list := range msdpNames(value) {
+		var names []string
+		switch strings.ToUpper(list) {
+		case "COMMANDS":
+			names = msdpCommands
+		case "LISTS":
+			names = []string{"COMMANDS", "LISTS", "REPORTABLE_VARIABLES", "REPORTED_VARIABLES"}
+		case "REPORTABLE_VARIABLES":
+			for name := range c.msdp.values {
+				names = append(names, name)
+			}
+		case "REPORTED_VARIABLES":
+			for name := range c.msdp.reported {
+				names = append(names, name)
+			}
+		default:
+			continue
+		}
+		sort.Strings(names)
+
+		items := make([]interface{}, len(names))
+		for i, name := range names {
+			items[i] = name
+		}
+		c.sendMSDPVariable(list, items)
+	}
+}
+
+// msdpReport starts or stops reporting the variables named, their current
+// values are sent as reporting starts
+func (c *Conn) msdpReport(value interface{}, report bool) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	if c.msdp.reported == nil {
+		c.msdp.reported = make(map[string]bool)
+	}
+	for _, name := range msdpNames(value) {
+		if !report {
+			delete(c.msdp.reported, name)
+
+			continue
+		}
+		c.msdp.reported[name] = true
+		if v, ok := c.msdp.values[name]; ok {
+			c.sendMSDPVariable(name, v)
+		}
+	}
+}
+
+// msdpSend sends the current values of the variables named
+func (c *Conn) msdpSend(value interface{}) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	for _, name := range msdpNames(value) {
+		if v, ok := c.msdp.values[name]; ok {
+			c.sendMSDPVariable(name, v)
+		}
+	}
+}
+
+// sendMSDPVariable sends a single variable, the write mutex must be held
+func (c *Conn) sendMSDPVariable(name string, value interface{}) error {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(msdpVar)
+	buf.WriteString(name)
+	buf.WriteByte(msdpVal)
+	encodeMSDP(buf, value)
+
+	msg := bytes.Replace(buf.Bytes(), []byte{IAC}, []byte{IAC, IAC}, -1)
+
+	return c.sendSubnegotiation(MSDP, msg)
+}
+
+// msdpValue converts data to the values MSDP can represent by way of JSON:
+// maps, slices, strings, bools, numbers (as json.Number) and nil
+func msdpValue(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// encodeMSDP writes the value following a VAL
+func encodeMSDP(buf *bytes.Buffer, value interface{}) {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte(msdpTableOpen)
+		for _, k := range keys {
+			buf.WriteByte(msdpVar)
+			buf.WriteString(k)
+			buf.WriteByte(msdpVal)
+			encodeMSDP(buf, t[k])
+		}
+		buf.WriteByte(msdpTableClose)
+	case []interface{}:
+		buf.WriteByte(msdpArrayOpen)
+		for _, item := range t {
+			buf.WriteByte(msdpVal)
+			encodeMSDP(buf, item)
+		}
+		buf.WriteByte(msdpArrayClose)
+	case string:
+		buf.WriteString(t)
+	case json.Number:
+		buf.WriteString(t.String())
+	case bool:
+		if t {
+			buf.WriteByte('1')
+		} else {
+			buf.WriteByte('0')
+		}
+	}
+}
+
+// a variable sent by the client
+type msdpVariable struct {
+	name  string
+	value interface{}
+}
+
+// parseMSDP decodes the variables in a subnegotiation. Values are strings,
+// or maps and slices for tables and arrays. A variable given more than one
+// value gets a slice of them.
+func parseMSDP(data []byte) []msdpVariable {
+	p := &msdpParser{data: data}
+
+	return p.variables(0)
+}
+
+type msdpParser struct {
+	data []byte
+	pos  int
+}
+
+// variables reads VAR/VAL pairs until the end of the data or the closing
+// byte given
+func (p *msdpParser) variables(end byte) []msdpVariable {
+	var vars []msdpVariable
+	for p.pos < len(p.data) {
+		b := p.data[p.pos]
+		p.pos++
+		switch b {
+		case end:
+			return vars
+		case msdpVar:
+			v := msdpVariable{name: p.text()}
+			for p.pos < len(p.data) && p.data[p.pos] == msdpVal {
+				p.pos++
+				val := p.value()
+				switch existing := v.value.(type) {
+				case nil:
+					v.value = val
+				case []interface{}:
+					v.value = append(existing, val)
+				default:
+					v.value = []interface{}{existing, val}
+				}
+			}
+			vars = append(vars, v)
+		}
+	}
+
+	return vars
+}
+
+// value reads what follows a VAL
+func (p *msdpParser) value() interface{} {
+	if p.pos >= len(p.data) {
+		return ""
+	}
+
+	switch p.data[p.pos] {
+	case msdpTableOpen:
+		p.pos++
+		table := make(map[string]interface{})
+		for _, v := range p.variables(msdpTableClose) {
+			table[v.name] = v.value
+		}
+
+		return table
+	case msdpArrayOpen:
+		p.pos++
+		var items []interface{}
+		for p.pos < len(p.data) {
+			b := p.data[p.pos]
+			p.pos++
+			if b == msdpArrayClose {
+				break
+			}
+			if b == msdpVal {
+				items = append(items, p.value())
+			}
+		}
+
+		return items
+	default:
+		return p.text()
+	}
+}
+
+// text reads up to the next MSDP byte
+func (p *msdpParser) text() string {
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] > msdpArrayClose {
+		p.pos++
+	}
+
+	return string(p.data[start:p.pos])
+}
+
+// msdpNames returns the names given as the value of a command, either one
+// name or an array of them
+func msdpNames(value interface{}) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var names []string
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+
+		return names
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/checkpoint"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/mob"
+	players "github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/scripting"
+	"github.com/bbuck/dragon-mud/server/session"
+)
+
+// message written to players once the game is rolled back to a checkpoint
+const rollbackMessage = "Time folds back on itself, the world is as it was a while ago."
+
+// registerCheckpoints registers the parts of the game checkpoints capture,
+// the players, the items lying in rooms, the mobs and the doors, and tells
+// players in the game when it's rolled back
+func registerCheckpoints() {
+	checkpoint.Global().Register("players", checkpoint.Part{
+		Capture: func() (interface{}, error) {
+			return players.Global().Records()
+		},
+		Restore: func(state json.RawMessage) error {
+			var records []players.Record
+			if err := json.Unmarshal(state, &records); err != nil {
+				return err
+			}
+			for _, r := range records {
+				if c := combat.Global().Lookup(strings.ToLower(r.Name)); c != nil {
+					combat.Global().Remove(c)
+				}
+			}
+
+			return players.Global().Restore(records)
+		},
+	})
+	checkpoint.Global().Register("floor", checkpoint.Part{
+		Capture: func() (interface{}, error) {
+			return item.GlobalFloor().Snapshot(), nil
+		},
+		Restore: func(state json.RawMessage) error {
+			var rooms map[string]item.List
+			if err := json.Unmarshal(state, &rooms); err != nil {
+				return err
+			}
+			item.GlobalFloor().Restore(rooms)
+
+			return nil
+		},
+	})
+	checkpoint.Global().Register("mobs", checkpoint.Part{
+		Capture: func() (interface{}, error) {
+			return mob.Global().Snapshot(), nil
+		},
+		Restore: func(state json.RawMessage) error {
+			var states []mob.State
+			if err := json.Unmarshal(state, &states); err != nil {
+				return err
+			}
+			for _, m := range mob.Global().Restore(states) {
+				if c := combat.Global().Lookup(m.ID()); c != nil {
+					combat.Global().Remove(c)
+				}
+				effect.Global().Forget(m.ID())
+			}
+
+			return nil
+		},
+	})
+	checkpoint.Global().Register("doors", checkpoint.Part{
+		Capture: func() (interface{}, error) {
+			return world.Global().Doors(), nil
+		},
+		Restore: func(state json.RawMessage) error {
+			var doors []world.DoorState
+			if err := json.Unmarshal(state, &doors); err != nil {
+				return err
+			}
+			world.Global().RestoreDoors(doors)
+
+			return nil
+		},
+	})
+
+	scripting.ServerEmitter.On(checkpoint.RollbackEvent, events.HandlerFunc(func(d events.Data) error {
+		log.WithFields(logger.Fields{
+			"checkpoint": d["name"],
+			"by":         d["by"],
+		}).Warn("Rolled the game back to a checkpoint.")
+		for _, s := range session.Global().Sessions() {
+			if s.State() == session.Playing {
+				s.Output().Send(rollbackMessage)
+			}
+		}
+
+		return nil
+	}))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package server
+
+import (
+	"crypto/tls"
+	"strings"
+
+	core "github.com/bbuck/dragon-mud/server"
+	"github.com/bbuck/dragon-mud/telnet"
+)
+
+// telnetConn adapts a telnet connection to a core.Conn, it's also a
+// core.Compressor and core.TerminalReporter. Structured data is sent over
+// GMCP or MSDP, whichever the client supports.
+type telnetConn struct {
+	*telnet.Conn
+}
+
+// Transport is core.TelnetTLS for connections to the TLS port and core.Telnet
+// for the rest.
+func (c telnetConn) Transport() string {
+	if _, ok := c.Conn.Conn.(*tls.Conn); ok {
+		return core.TelnetTLS
+	}
+
+	return core.Telnet
+}
+
+// OnTerminal reports the window size and terminal type the client gives, the
+// type is the last one given since MTTS clients give their name first.
+func (c telnetConn) OnTerminal(fn func(core.Terminal)) {
+	c.Conn.OnTerminal(func(t telnet.Terminal) {
+		term := core.Terminal{
+			Width:   t.Width,
+			Height:  t.Height,
+			Color:   t.ColorSupport(),
+			Charset: t.Charset,
+		}
+		for _, typ := range t.Types {
+			if !strings.HasPrefix(strings.ToUpper(typ), "MTTS ") {
+				term.Type = typ
+			}
+		}
+		fn(term)
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package server
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/server/copyover"
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/bbuck/dragon-mud/telnet"
+)
+
+// message written to players whose connections can't be kept through a
+// copyover, like those over TLS or WebSockets
+const copyoverClosedMessage = "Your connection can't be kept through the upgrade, reconnect in a moment."
+
+// message written to players once their session is resumed after a copyover
+const copyoverDoneMessage = "The world settles around you once more."
+
+// the listening sockets of the server by name, handed over by a copyover
+var (
+	listeners      = make(map[string]net.Listener)
+	listenersMutex = new(sync.Mutex)
+)
+
+// listen returns the listening socket with the name handed over by a
+// copyover, listening on the address if there's none
+func listen(handed *copyover.State, name, addr string) (net.Listener, error) {
+	var (
+		l   net.Listener
+		err error
+	)
+	if handed != nil {
+		l, err = handed.Listener(name)
+		if err != nil {
+			log.WithError(err).WithField("listener", name).Warn("Failed to take over the listener, listening again.")
+		}
+	}
+	if l == nil {
+		l, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	listenersMutex.Lock()
+	listeners[name] = l
+	listenersMutex.Unlock()
+
+	return l, nil
+}
+
+// handOff collects the listeners and the connections of every playing telnet
+// session for a copyover, returning the ids of the sessions handed over.
+// Compression of their connections ends, the new process starts it again.
+func handOff() (*copyover.Handoff, map[string]bool) {
+	h := copyover.NewHandoff()
+	listenersMutex.Lock()
+	for name, l := range listeners {
+		if err := h.Listener(name, l); err != nil {
+			log.WithError(err).WithField("listener", name).Warn("Failed to hand over the listener.")
+		}
+	}
+	listenersMutex.Unlock()
+
+	handed := make(map[string]bool)
+	for _, s := range session.Global().Sessions() {
+		tc, ok := s.Conn().(telnetConn)
+		if !ok || s.State() != session.Playing {
+			continue
+		}
+		s.Output().Flush()
+		st, err := tc.Suspend()
+		if err != nil {
+			log.WithError(err).WithField("character", s.Character()).Warn("Failed to end compression before the copyover.")
+
+			continue
+		}
+		if err := h.Connection(tc.Conn.Conn, s.Handover(), st); err != nil {
+			if err != copyover.ErrNoFile {
+				log.WithError(err).WithField("character", s.Character()).Warn("Failed to hand over the connection.")
+			}
+
+			continue
+		}
+		handed[s.ID()] = true
+	}
+
+	return h, handed
+}
+
+// copyoverServer starts the server's binary in place of this process, handing
+// it the sessions handed off. It only returns if the binary couldn't be
+// started.
+func copyoverServer(h *copyover.Handoff) error {
+	log.WithField("sessions", h.Len()).Warn("Copying over to a new server.")
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return h.Exec(exe, os.Args, os.Environ())
+}
+
+// resumeSessions resumes the sessions handed over by the copyover that
+// started this process
+func resumeSessions(handed *copyover.State) {
+	resumed := 0
+	for _, c := range handed.Connections {
+		l := log.WithField("character", c.Session.Character)
+		nc, err := c.Conn()
+		if err != nil {
+			l.WithError(err).Error("Failed to take over the connection of a session.")
+
+			continue
+		}
+		tc, err := telnet.Resume(nc, c.Telnet)
+		if err != nil {
+			l.WithError(err).Warn("Failed to compress output again.")
+		}
+		go resume(session.Global().Resume(telnetConn{tc}, c.Session))
+		resumed++
+	}
+	log.WithFields(logger.Fields{
+		"sessions": resumed,
+		"duration": time.Since(handed.Created).String(),
+	}).Info("Resumed the sessions handed over by the copyover.")
+}
+
+// resume continues a session handed over by a copyover, running the commands
+// the player types without logging them in again
+func resume(s *session.Session) {
+	p := newPlayer(s)
+	s.Output().Send(copyoverDoneMessage)
+	command.GlobalPacer().Queue(p).Push("look")
+	readCommands(p)
+}
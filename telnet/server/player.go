@@ -0,0 +1,803 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbuck/dragon-mud/game/admin"
+	"github.com/bbuck/dragon-mud/game/advance"
+	"github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/equipment"
+	"github.com/bbuck/dragon-mud/game/group"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/language"
+	"github.com/bbuck/dragon-mud/game/lock"
+	"github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/game/minimap"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/game/perm"
+	players "github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/presence"
+	"github.com/bbuck/dragon-mud/game/pvp"
+	"github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/game/social"
+	"github.com/bbuck/dragon-mud/game/trigger"
+	"github.com/bbuck/dragon-mud/game/vision"
+	"github.com/bbuck/dragon-mud/game/weather"
+	"github.com/bbuck/dragon-mud/game/world"
+	"github.com/bbuck/dragon-mud/random"
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/spf13/viper"
+)
+
+// player runs commands for the session read from, following the player to
+// the session of their character when they reconnect to it
+type player struct {
+	s     *session.Session
+	mutex *sync.Mutex
+}
+
+func newPlayer(s *session.Session) *player {
+	return &player{
+		s:     s,
+		mutex: new(sync.Mutex),
+	}
+}
+
+func (p *player) session() *session.Session {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.s
+}
+
+func (p *player) setSession(s *session.Session) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.s = s
+}
+
+func (p *player) ID() string {
+	return p.session().ID()
+}
+
+// Level is the level of the role of the character being played
+func (p *player) Level() command.Level {
+	return perm.Global().Level(p.session().Character())
+}
+
+// Can is true if the role of the character being played has the capability
+func (p *player) Can(capability string) bool {
+	return perm.Global().Can(p.session().Character(), capability)
+}
+
+func (p *player) Send(text string) error {
+	return p.session().Output().Send(text)
+}
+
+func (p *player) Read(b []byte) (int, error) {
+	return p.session().Read(b)
+}
+
+// mover is a player in the game as the systems moving them and their items
+// see them, telling them what happens through the session playing them
+type mover struct {
+	*players.Player
+}
+
+// ID returns the player's lowercased name, as they're kept in the game
+func (m mover) ID() string {
+	return strings.ToLower(m.Name())
+}
+
+func (m mover) Send(text string) error {
+	if s := session.Global().ForCharacter(m.Name()); s != nil {
+		return s.Output().Send(text)
+	}
+
+	return nil
+}
+
+func (m mover) SendData(pkg string, data interface{}) error {
+	if s := session.Global().ForCharacter(m.Name()); s != nil {
+		return s.SendData(pkg, data)
+	}
+
+	return nil
+}
+
+func (m mover) Size() int {
+	return m.Stat("size")
+}
+
+func (m mover) CarryLimit() int {
+	limit := viper.GetInt("player.carry_weight")
+	if limit <= 0 {
+		return 0
+	}
+
+	return limit + m.Stat("carry")
+}
+
+// resolveMover returns the player the caller is playing
+func resolveMover(caller command.Caller) movement.Mover {
+	s := session.Global().Get(caller.ID())
+	if s == nil || s.Character() == "" {
+		return nil
+	}
+	p := players.Global().Get(s.Character())
+	if p == nil {
+		return nil
+	}
+
+	return mover{p}
+}
+
+// resolveCarrier returns the player the caller is playing
+func resolveCarrier(caller command.Caller) item.Carrier {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveOpener returns the player the caller is playing
+func resolveOpener(caller command.Caller) lock.Opener {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveWearer returns the player the caller is playing
+func resolveWearer(caller command.Caller) equipment.Wearer {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveCombatant returns the player the caller is playing
+func resolveCombatant(caller command.Caller) combat.Combatant {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveLearner returns the player the caller is playing
+func resolveLearner(caller command.Caller) skill.Learner {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveBearer returns the player the caller is playing
+func resolveBearer(caller command.Caller) effect.Bearer {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveQuestor returns the player the caller is playing
+func resolveQuestor(caller command.Caller) quest.Questor {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveTalker returns the player the caller is playing
+func resolveTalker(caller command.Caller) dialogue.Talker {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveAdvancer returns the player the caller is playing
+func resolveAdvancer(caller command.Caller) advance.Advancer {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveCrafter returns the player the caller is playing
+func resolveCrafter(caller command.Caller) craft.Crafter {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveCustomer returns the player the caller is playing
+func resolveCustomer(caller command.Caller) shop.Customer {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveMember returns the player the caller is playing
+func resolveMember(caller command.Caller) channels.Member {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveActor returns the player the caller is playing
+func resolveActor(caller command.Caller) social.Actor {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveCorrespondent returns the player the caller is playing
+func resolveCorrespondent(caller command.Caller) mail.Correspondent {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// reader is a player with the permission level of the caller playing them,
+// for using boards, building and staff commands
+type reader struct {
+	mover
+	level command.Level
+}
+
+func (r reader) Level() command.Level {
+	return r.level
+}
+
+// resolveReader returns the player the caller is playing
+func resolveReader(caller command.Caller) board.Reader {
+	if m := resolveMover(caller); m != nil {
+		return reader{m.(mover), caller.Level()}
+	}
+
+	return nil
+}
+
+// resolveBuilder returns the player the caller is playing, at the caller's
+// level
+func resolveBuilder(caller command.Caller) olc.Builder {
+	if m := resolveMover(caller); m != nil {
+		return reader{m.(mover), caller.Level()}
+	}
+
+	return nil
+}
+
+// resolveStaffer returns the player the caller is playing, at the caller's
+// level
+func resolveStaffer(caller command.Caller) admin.Staffer {
+	if m := resolveMover(caller); m != nil {
+		return reader{m.(mover), caller.Level()}
+	}
+
+	return nil
+}
+
+// characterOf returns the name of the character the caller is playing, or
+// the id of their session if they aren't playing one
+func characterOf(caller command.Caller) string {
+	if s := session.Global().Get(caller.ID()); s != nil && s.Character() != "" {
+		return s.Character()
+	}
+
+	return caller.ID()
+}
+
+// online is a player in the game along with the caller typing for them, so
+// staff can make them type commands
+type online struct {
+	mover
+	caller *player
+}
+
+// ID returns the id of the session playing them, as their input is queued
+func (o online) ID() string {
+	return o.caller.ID()
+}
+
+func (o online) Level() command.Level {
+	return o.caller.Level()
+}
+
+// onlinePlayers returns the players in the game with a session playing them
+func onlinePlayers() []admin.Player {
+	var playing []admin.Player
+	for _, p := range players.Global().Players() {
+		if s := session.Global().ForCharacter(p.Name()); s != nil {
+			playing = append(playing, online{mover{p}, newPlayer(s)})
+		}
+	}
+
+	return playing
+}
+
+// notifyMail tells the recipient of the letter they have mail, if they're in
+// the game
+func notifyMail(to string, l mail.Letter) {
+	if p := players.Global().Get(to); p != nil {
+		mover{p}.Send(fmt.Sprintf("You have new mail from %s.", l.From))
+	}
+}
+
+// resolvePerson returns the player the caller is playing
+func resolvePerson(caller command.Caller) presence.Person {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// onlineProfiles returns the profiles of the players in the game
+func onlineProfiles() []presence.Profile {
+	var profiles []presence.Profile
+	for _, p := range players.Global().Players() {
+		pr := profileOf(p.Record())
+		pr.Online = true
+		if s := session.Global().ForCharacter(p.Name()); s != nil {
+			pr.Idle = time.Since(s.LastInput())
+		}
+		profiles = append(profiles, pr)
+	}
+
+	return profiles
+}
+
+// lookupProfile returns the profile of the player, in the game or not
+func lookupProfile(name string) (presence.Profile, bool) {
+	if p := players.Global().Get(name); p != nil {
+		pr := profileOf(p.Record())
+		pr.Online = true
+		if s := session.Global().ForCharacter(p.Name()); s != nil {
+			pr.Idle = time.Since(s.LastInput())
+		}
+
+		return pr, true
+	}
+	r, err := players.Global().Lookup(name)
+	if err != nil {
+		return presence.Profile{}, false
+	}
+
+	return profileOf(r), true
+}
+
+// profileOf is what's shown about the player saved in the record
+func profileOf(r players.Record) presence.Profile {
+	pr := presence.Profile{
+		Name:  r.Name,
+		Race:  r.Race,
+		Class: r.Class,
+	}
+	pr.Title, _ = r.Vars[presence.TitleVar].(string)
+	pr.Plan, _ = r.Vars[presence.PlanVar].(string)
+	for flag, on := range r.Flags {
+		if on {
+			pr.Flags = append(pr.Flags, flag)
+		}
+	}
+	sort.Strings(pr.Flags)
+
+	return pr
+}
+
+// onlineMembers returns the players in the game
+func onlineMembers() []channels.Member {
+	var members []channels.Member
+	for _, p := range players.Global().Players() {
+		members = append(members, mover{p})
+	}
+
+	return members
+}
+
+// clanOf returns the id of the clan the player is in
+func clanOf(m channels.Member) string {
+	c, _ := clan.Global().Of(m.(mover).Name())
+
+	return c.ID
+}
+
+// lookupClanMember returns the player with the name, nil if they aren't
+// playing
+func lookupClanMember(name string) clan.Member {
+	if p := players.Global().Get(name); p != nil {
+		return mover{p}
+	}
+
+	return nil
+}
+
+// resolveClanMember returns the player the caller is playing
+func resolveClanMember(caller command.Caller) clan.Member {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// groupOf returns the id of the channel member's group, if they're in one
+func groupOf(m channels.Member) string {
+	g, _ := group.Global().Of(m.(mover).Name())
+
+	return g.ID
+}
+
+// groupID returns the id of the player's group, empty if they aren't in one
+func groupID(name string) string {
+	g, _ := group.Global().Of(name)
+
+	return g.ID
+}
+
+// lookupMember returns the player with the name, nil if they aren't playing
+func lookupMember(name string) group.Member {
+	if p := players.Global().Get(name); p != nil {
+		return mover{p}
+	}
+
+	return nil
+}
+
+// resolveGroupMember returns the player the caller is playing
+func resolveGroupMember(caller command.Caller) group.Member {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// lookupFighter returns the player with the name, nil if they aren't
+// playing
+func lookupFighter(name string) pvp.Fighter {
+	if p := players.Global().Get(name); p != nil {
+		return mover{p}
+	}
+
+	return nil
+}
+
+// resolveFighter returns the player the caller is playing
+func resolveFighter(caller command.Caller) pvp.Fighter {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveWatcher returns the player the caller is playing
+func resolveWatcher(caller command.Caller) weather.Watcher {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveViewer returns the player the caller is playing
+func resolveViewer(caller command.Caller) minimap.Viewer {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveLooker returns the player the caller is playing
+func resolveLooker(caller command.Caller) vision.Looker {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// resolveSpeaker returns the player the caller is playing
+func resolveSpeaker(caller command.Caller) language.Speaker {
+	if m := resolveMover(caller); m != nil {
+		return m.(mover)
+	}
+
+	return nil
+}
+
+// roomHearers returns the players in the room, for hearing what's said
+func roomHearers(room string) []language.Listener {
+	var listeners []language.Listener
+	for _, p := range players.Global().Players() {
+		if p.Location() == room {
+			listeners = append(listeners, mover{p})
+		}
+	}
+
+	return listeners
+}
+
+// roomListeners returns the players in the room
+func roomListeners(room string) []weather.Listener {
+	var listeners []weather.Listener
+	for _, p := range players.Global().Players() {
+		if p.Location() == room {
+			listeners = append(listeners, mover{p})
+		}
+	}
+
+	return listeners
+}
+
+// lootMembers returns the killer and the members of their group in the
+// same room, for sharing loot
+func lootMembers(killer string) []item.Carrier {
+	var carriers []item.Carrier
+	for _, member := range group.Global().Nearby(killer) {
+		carriers = append(carriers, member.(mover))
+	}
+
+	return carriers
+}
+
+// roomPlayers returns the players in the room
+func roomPlayers(room string) []movement.Mover {
+	var movers []movement.Mover
+	for _, p := range players.Global().Players() {
+		if p.Location() == room {
+			movers = append(movers, mover{p})
+		}
+	}
+
+	return movers
+}
+
+// clearRoom takes the mobs and items out of the room
+func clearRoom(room string) {
+	for _, m := range mob.Global().In(room) {
+		if c := combat.Global().Lookup(m.ID()); c != nil {
+			combat.Global().Remove(c)
+		}
+		effect.Global().Forget(m.ID())
+		mob.Global().Remove(m.ID())
+	}
+	item.GlobalFloor().Clear(room)
+}
+
+// locatePlayer returns the room the online player is in, for mobs pursuing
+// or fleeing from them
+func locatePlayer(name string) (string, bool) {
+	if p := players.Global().Get(name); p != nil {
+		return p.Location(), true
+	}
+
+	return "", false
+}
+
+// roomEntities returns the mobs and items in the room, for their triggers
+func roomEntities(room string) []trigger.Entity {
+	var entities []trigger.Entity
+	for _, m := range mob.Global().In(room) {
+		entities = append(entities, trigger.Entity{Kind: trigger.NPCKind, ID: m.ID(), Proto: m.Proto(), Room: room})
+	}
+	for _, it := range item.GlobalFloor().In(room) {
+		entities = append(entities, trigger.Entity{Kind: trigger.ItemKind, ID: it.ID, Proto: it.Proto, Room: room})
+	}
+
+	return entities
+}
+
+// gameEntities returns every mob and the items on the floor of every room,
+// for their timers
+func gameEntities() []trigger.Entity {
+	var entities []trigger.Entity
+	for _, m := range mob.Global().All() {
+		entities = append(entities, trigger.Entity{Kind: trigger.NPCKind, ID: m.ID(), Proto: m.Proto(), Room: m.Location()})
+	}
+	for _, room := range item.GlobalFloor().Rooms() {
+		for _, it := range item.GlobalFloor().In(room) {
+			entities = append(entities, trigger.Entity{Kind: trigger.ItemKind, ID: it.ID, Proto: it.Proto, Room: room})
+		}
+	}
+
+	return entities
+}
+
+// roomCarriers returns the players and mobs in the room
+func roomCarriers(room string) []item.Carrier {
+	var carriers []item.Carrier
+	for _, p := range players.Global().Players() {
+		if p.Location() == room {
+			carriers = append(carriers, mover{p})
+		}
+	}
+	for _, m := range mob.Global().In(room) {
+		carriers = append(carriers, m)
+	}
+
+	return carriers
+}
+
+// roomOpeners returns the players and mobs in the room
+func roomOpeners(room string) []lock.Opener {
+	var openers []lock.Opener
+	for _, c := range roomCarriers(room) {
+		openers = append(openers, c.(lock.Opener))
+	}
+
+	return openers
+}
+
+// roomMovers returns the players and mobs in the room
+func roomMovers(room string) []movement.Mover {
+	var movers []movement.Mover
+	for _, c := range roomCarriers(room) {
+		movers = append(movers, c.(movement.Mover))
+	}
+
+	return movers
+}
+
+// roomViewers returns the players and mobs in the room
+func roomViewers(room string) []vision.Viewer {
+	var viewers []vision.Viewer
+	for _, c := range roomCarriers(room) {
+		viewers = append(viewers, c)
+	}
+
+	return viewers
+}
+
+// describeRoom returns what the mover sees of the room they enter
+func describeRoom(m movement.Mover, r world.Room) string {
+	return vision.Global().Describe(m, r)
+}
+
+// seesCombatant is true if the combatant can see the other to attack them
+func seesCombatant(c, other combat.Combatant) bool {
+	return vision.Global().SeesCharacter(c, other)
+}
+
+// seesItem is true if the carrier can see the item on the floor
+func seesItem(c item.Carrier, it item.Item) bool {
+	return vision.Global().SeesItem(c, it)
+}
+
+// roomActors returns the players and mobs in the room
+func roomActors(room string) []social.Actor {
+	var actors []social.Actor
+	for _, c := range roomCarriers(room) {
+		actors = append(actors, c.(social.Actor))
+	}
+
+	return actors
+}
+
+// roomCombatants returns the players and mobs in the room
+func roomCombatants(room string) []combat.Combatant {
+	var combatants []combat.Combatant
+	for _, c := range roomCarriers(room) {
+		combatants = append(combatants, c.(combat.Combatant))
+	}
+
+	return combatants
+}
+
+// lookupCombatant returns the player with the lowercased name or the mob
+// with the id
+func lookupCombatant(id string) combat.Combatant {
+	if p := players.Global().Get(id); p != nil {
+		return mover{p}
+	}
+	if m := mob.Global().Get(id); m != nil {
+		return m
+	}
+
+	return nil
+}
+
+// roomGivers returns the NPC definitions of the mobs in the room
+func roomGivers(room string) []string {
+	var protos []string
+	for _, m := range mob.Global().In(room) {
+		protos = append(protos, m.Proto())
+	}
+
+	return protos
+}
+
+// roomSpeakers returns the mobs in the room
+func roomSpeakers(room string) []dialogue.Speaker {
+	var speakers []dialogue.Speaker
+	for _, m := range mob.Global().In(room) {
+		speakers = append(speakers, m)
+	}
+
+	return speakers
+}
+
+// questProgress counts what the named character did towards their quests
+func questProgress(name, kind, target string, n int) {
+	if q, ok := lookupCombatant(strings.ToLower(name)).(quest.Questor); ok {
+		quest.Global().Progress(q, kind, target, n)
+	}
+}
+
+// escape moves the fleeing combatant out a random exit they can take
+func escape(c combat.Combatant) error {
+	room, ok := world.Global().Room(c.Location())
+	if !ok {
+		return &item.Refused{Message: "There's nowhere to run!"}
+	}
+	exits := room.SortedExits(false)
+	if len(exits) > 0 {
+		start := random.Intn(len(exits))
+		for i := range exits {
+			exit := exits[(start+i)%len(exits)]
+			if err := movement.Global().Move(c.(movement.Mover), exit.Direction); err == nil {
+				return nil
+			}
+		}
+	}
+
+	return &item.Refused{Message: "There's nowhere to run!"}
+}
+
+// killed removes mobs killed in a fight from the game, players die and
+// respawn
+func killed(victim, killer combat.Combatant) {
+	switch v := victim.(type) {
+	case *mob.Mob:
+		mob.Global().Kill(v.ID(), killer.Name())
+	case mover:
+		if pvp.Global().Defeat(v.Name(), killer.Name()) {
+			return
+		}
+		if err := death.Global().Die(v, killer.Name()); err != nil {
+			log.WithError(err).WithField("player", v.Name()).Error("Failed to respawn a player.")
+		}
+	}
+}
@@ -3,14 +3,78 @@
 package server
 
 import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"os"
 	"strings"
-
+	"syscall"
 	"time"
 
+	"github.com/bbuck/dragon-mud/audit"
+	"github.com/bbuck/dragon-mud/backup"
+	"github.com/bbuck/dragon-mud/data"
+	"github.com/bbuck/dragon-mud/data/migrate"
+	"github.com/bbuck/dragon-mud/events"
+	"github.com/bbuck/dragon-mud/game/account"
+	"github.com/bbuck/dragon-mud/game/admin"
+	"github.com/bbuck/dragon-mud/game/advance"
+	"github.com/bbuck/dragon-mud/game/ai"
+	"github.com/bbuck/dragon-mud/game/ban"
+	"github.com/bbuck/dragon-mud/game/board"
+	"github.com/bbuck/dragon-mud/game/channels"
+	"github.com/bbuck/dragon-mud/game/character"
+	"github.com/bbuck/dragon-mud/game/checkpoint"
+	"github.com/bbuck/dragon-mud/game/clan"
+	"github.com/bbuck/dragon-mud/game/clock"
+	"github.com/bbuck/dragon-mud/game/combat"
+	"github.com/bbuck/dragon-mud/game/command"
+	"github.com/bbuck/dragon-mud/game/craft"
+	"github.com/bbuck/dragon-mud/game/death"
+	"github.com/bbuck/dragon-mud/game/dialogue"
+	"github.com/bbuck/dragon-mud/game/effect"
+	"github.com/bbuck/dragon-mud/game/equipment"
+	"github.com/bbuck/dragon-mud/game/group"
+	"github.com/bbuck/dragon-mud/game/help"
+	"github.com/bbuck/dragon-mud/game/instance"
+	"github.com/bbuck/dragon-mud/game/item"
+	"github.com/bbuck/dragon-mud/game/language"
+	"github.com/bbuck/dragon-mud/game/lock"
+	"github.com/bbuck/dragon-mud/game/loot"
+	"github.com/bbuck/dragon-mud/game/mail"
+	"github.com/bbuck/dragon-mud/game/minimap"
+	"github.com/bbuck/dragon-mud/game/mob"
+	"github.com/bbuck/dragon-mud/game/movement"
+	"github.com/bbuck/dragon-mud/game/olc"
+	"github.com/bbuck/dragon-mud/game/perm"
+	players "github.com/bbuck/dragon-mud/game/player"
+	"github.com/bbuck/dragon-mud/game/presence"
+	"github.com/bbuck/dragon-mud/game/procgen"
+	"github.com/bbuck/dragon-mud/game/prompt"
+	"github.com/bbuck/dragon-mud/game/pvp"
+	"github.com/bbuck/dragon-mud/game/quest"
+	"github.com/bbuck/dragon-mud/game/save"
+	"github.com/bbuck/dragon-mud/game/shop"
+	"github.com/bbuck/dragon-mud/game/skill"
+	"github.com/bbuck/dragon-mud/game/social"
+	"github.com/bbuck/dragon-mud/game/trigger"
+	"github.com/bbuck/dragon-mud/game/vision"
+	"github.com/bbuck/dragon-mud/game/weather"
+	"github.com/bbuck/dragon-mud/game/world"
 	"github.com/bbuck/dragon-mud/logger"
+	"github.com/bbuck/dragon-mud/metrics"
 	"github.com/bbuck/dragon-mud/plugins"
+	"github.com/bbuck/dragon-mud/sched"
 	"github.com/bbuck/dragon-mud/scripting"
+	core "github.com/bbuck/dragon-mud/server"
+	"github.com/bbuck/dragon-mud/server/copyover"
+	"github.com/bbuck/dragon-mud/server/output"
+	"github.com/bbuck/dragon-mud/server/session"
+	"github.com/bbuck/dragon-mud/server/websocket"
+	"github.com/bbuck/dragon-mud/telnet"
 	"github.com/spf13/viper"
 )
 
@@ -29,6 +93,144 @@ func Run() {
 	if err := plugins.LoadViews(); err != nil {
 		log.WithError(err).Error("Failed to load views")
 	}
+	if err := plugins.LoadLocales(); err != nil {
+		log.WithError(err).Error("Failed to load locales")
+	}
+	if err := plugins.LoadNames(); err != nil {
+		log.WithError(err).Error("Failed to load name corpora")
+	}
+	if err := plugins.LoadMigrations(); err != nil {
+		log.WithError(err).Fatal("Failed to load the migrations")
+	}
+	if viper.GetBool("migrate.check") {
+		if err := migrate.Global().Check(data.Storage()); err != nil {
+			log.WithError(err).Fatal("The storage doesn't match the migrations, run [W]dragon migrate[x] to bring it up to date.")
+		}
+	}
+	if token := viper.GetString("metrics.admin_token"); token != "" {
+		metrics.SetAdminToken(token)
+		metrics.Handle("/admin/bans", metrics.Admin(ban.Handler(ban.Global())))
+	}
+	if addr := viper.GetString("metrics.address"); addr != "" {
+		go func() {
+			if err := metrics.Serve(addr); err != nil {
+				log.WithError(err).Error("Metrics server stopped.")
+			}
+		}()
+	}
+	if err := world.Global().LoadDir(viper.GetString("world.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the world")
+	}
+	if err := equipment.Global().Bodies().LoadDir(viper.GetString("equipment.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the bodies")
+	}
+	if err := skill.Global().Defs().LoadDir(viper.GetString("skill.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the skills")
+	}
+	skill.Global().SetAdept(viper.GetInt("skill.adept"))
+	if err := quest.Global().Defs().LoadDir(viper.GetString("quest.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the quests")
+	}
+	if err := world.Global().LinkGraph(data.GlobalGraph()); err != nil {
+		log.WithError(err).Error("Failed to link the rooms in the graph")
+	}
+	if err := quest.Global().Defs().LinkGraph(data.GlobalGraph()); err != nil {
+		log.WithError(err).Error("Failed to link the quests in the graph")
+	}
+	if err := dialogue.Global().Trees().LoadDir(viper.GetString("dialogue.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the dialogues")
+	}
+	if err := shop.Global().Defs().LoadDir(viper.GetString("shop.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the shops")
+	}
+	if err := craft.Global().Recipes().LoadDir(viper.GetString("craft.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the recipes")
+	}
+	for _, def := range channels.Defaults() {
+		channels.Global().Defs().Add(def)
+	}
+	if err := channels.Global().Defs().LoadDir(viper.GetString("channels.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the channels")
+	}
+	channels.Global().SetMembers(onlineMembers)
+	channels.Global().Scope("clan", clanOf)
+	channels.Global().Scope("group", groupOf)
+	group.Global().SetLookup(lookupMember)
+	group.Global().SetMax(viper.GetInt("group.max_size"))
+	group.Global().SetStat(viper.GetString("group.experience"))
+	if err := social.Global().Defs().LoadYAML([]byte(social.Defaults)); err != nil {
+		log.WithError(err).Error("Failed to load the default socials")
+	}
+	if err := social.Global().Defs().LoadDir(viper.GetString("social.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the socials")
+	}
+	if err := social.Global().SetFile(viper.GetString("social.file")); err != nil {
+		log.WithError(err).Error("Failed to load the socials builders made")
+	}
+	social.Global().SetOccupants(roomActors)
+	if err := board.Global().Defs().LoadDir(viper.GetString("board.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the boards")
+	}
+	mail.Global().SetExists(players.Global().Exists)
+	mail.Global().SetNotify(notifyMail)
+	presence.Global().SetHistory(viper.GetInt("presence.history"))
+	presence.Global().SetProfiles(onlineProfiles, lookupProfile)
+	for _, name := range []string{presence.WhoTemplate, presence.WhoLineTemplate, presence.FingerTemplate} {
+		if err := presence.Global().SetTemplate(name, viper.GetString("presence."+name)); err != nil {
+			log.WithError(err).WithField("template", name).Error("Failed to set a presence template.")
+		}
+	}
+	if err := help.Global().Index().LoadDir(viper.GetString("help.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the help articles")
+	}
+	if err := help.Global().SetFile(viper.GetString("help.file")); err != nil {
+		log.WithError(err).Error("Failed to load the help articles builders wrote")
+	}
+	help.Global().SetRegistry(command.Global())
+	if err := perm.Global().SetFile(viper.GetString("perm.file")); err != nil {
+		log.WithError(err).Error("Failed to load the staff roles")
+	}
+	if err := ban.Global().SetFile(viper.GetString("ban.file")); err != nil {
+		log.WithError(err).Error("Failed to load the bans")
+	}
+	ban.Global().SetLedger(audit.Global())
+	if err := clan.Global().SetFile(viper.GetString("clan.file")); err != nil {
+		log.WithError(err).Error("Failed to load the clans")
+	}
+	clan.Global().SetLookup(lookupClanMember)
+	if err := pvp.Global().Defs().LoadDir(viper.GetString("pvp.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the pvp rules and arenas")
+	}
+	if err := pvp.Global().SetDefault(viper.GetString("pvp.default")); err != nil {
+		log.WithError(err).Error("Failed to set the default pvp rule.")
+	}
+	pvp.Global().SetCooldown(viper.GetDuration("pvp.cooldown"))
+	pvp.Global().SetLookup(lookupFighter)
+	if err := weather.Global().Defs().LoadDir(viper.GetString("weather.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the climates")
+	}
+	if err := weather.Global().SetDefault(viper.GetString("weather.climate")); err != nil {
+		log.WithError(err).Error("Failed to set the default climate.")
+	}
+	weather.Global().SetOccupants(roomListeners)
+	admin.Global().SetPlayers(onlinePlayers)
+	admin.Global().SetReset(mob.Global().ResetZone)
+	admin.Global().SetForce(func(p admin.Player, line string) {
+		command.GlobalPacer().Queue(p).Push(line)
+	})
+	admin.Global().SetStop(func(stop admin.Stop) {
+		// the command asking is still running in the pacer, which stopping
+		// waits for
+		go stopServer(stop)
+	})
+	if currency, err := shop.ParseCurrency(viper.GetStringSlice("shop.denominations")); err != nil {
+		log.WithError(err).Error("Failed to read the currency, using the default.")
+		shop.Global().SetCurrency(viper.GetString("shop.stat"), shop.DefaultCurrency)
+	} else {
+		shop.Global().SetCurrency(viper.GetString("shop.stat"), currency)
+	}
+	mail.Global().SetMoney(viper.GetString("shop.stat"), shop.Global().Currency())
+	clan.Global().SetMoney(viper.GetString("shop.stat"), shop.Global().Currency())
 	serverRunning = true
 	host := viper.GetString("telnet.interface")
 	port := viper.GetString("telnet.port")
@@ -36,8 +238,476 @@ func Run() {
 	scripting.Initialize()
 	done := scripting.ServerEmitter.EmitOnce("server:init", nil)
 	<-done
+	if err := instance.Global().Defs().LoadDir(viper.GetString("instance.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the instance templates")
+	}
+	if err := instance.Global().TakeResets(); err != nil {
+		log.WithError(err).Error("Failed to take the resets of the instance templates")
+	}
+	instance.Global().SetGroup(groupID)
+	instance.Global().SetOccupants(roomPlayers)
+	instance.Global().SetReset(mob.Global().ResetZone)
+	instance.Global().SetClear(clearRoom)
+	if err := procgen.Global().Defs().LoadDir(viper.GetString("procgen.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the dungeons")
+	}
+	instance.Global().SetGenerate(procgen.Global().Generate)
+	movement.Global().SetRouter(instance.Global().Route)
+	if err := mob.Global().ResetAll(); err != nil {
+		log.WithError(err).Warn("Some zone resets failed.")
+	}
+	if err := mob.Global().Schedule(sched.Global(), viper.GetString("mob.reset")); err != nil {
+		log.WithError(err).Error("Failed to schedule zone resets.")
+	}
+	if err := ai.Global().Trees().LoadDir(viper.GetString("ai.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the behavior trees")
+	}
+	ai.Global().Walker().SetLocate(locatePlayer)
+	ai.Global().Start(viper.GetDuration("ai.pulse"))
+	combat.Global().SetOccupants(roomCombatants)
+	combat.Global().SetLookup(lookupCombatant)
+	combat.Global().SetVisible(seesCombatant)
+	combat.Global().SetEscape(escape)
+	combat.Global().OnDeath(killed)
+	combat.Global().SetUnarmed(viper.GetString("combat.unarmed"))
+	combat.Global().StartRounds(viper.GetDuration("combat.pulse"))
+	effect.Global().Start(viper.GetDuration("effect.pulse"))
+	clock.Game().Start(viper.GetDuration("clock.pulse"))
+	instance.Global().Start(viper.GetDuration("instance.pulse"))
+	trigger.Global().SetDir(viper.GetString("trigger.dir"))
+	trigger.Global().SetRunner(scripting.RunTrigger)
+	trigger.Global().SetOccupants(roomEntities)
+	trigger.Global().SetEntities(gameEntities)
+	trigger.Global().Start(viper.GetDuration("trigger.pulse"))
+	scripting.ServerEmitter.On(olc.CommitEvent, events.HandlerFunc(func(events.Data) error {
+		return world.Global().LinkGraph(data.GlobalGraph())
+	}))
+	scripting.ServerEmitter.On(movement.EnterEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["mover"].(string)
+		if to, ok := d["to"].(string); ok {
+			trigger.Global().Enter(to, name)
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(movement.ExitEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["mover"].(string)
+		if from, ok := d["from"].(string); ok {
+			trigger.Global().Leave(from, name)
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(item.GiveEvent, events.HandlerFunc(func(d events.Data) error {
+		actor, _ := d["actor"].(string)
+		room, _ := d["room"].(string)
+		id, _ := d["item"].(string)
+		proto, _ := d["proto"].(string)
+		target, _ := d["target_id"].(string)
+		to := trigger.Entity{ID: target, Room: room}
+		if m := mob.Global().Get(target); m != nil {
+			to.Kind, to.Proto = trigger.NPCKind, m.Proto()
+		}
+		trigger.Global().Give(actor, trigger.Entity{Kind: trigger.ItemKind, ID: id, Proto: proto, Room: room}, to)
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(language.SayEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["name"].(string)
+		room, _ := d["room"].(string)
+		message, _ := d["message"].(string)
+		trigger.Global().Speech(room, name, message)
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(mob.DeathEvent, events.HandlerFunc(func(d events.Data) error {
+		id, _ := d["mob"].(string)
+		proto, _ := d["proto"].(string)
+		room, _ := d["room"].(string)
+		killer, _ := d["killer"].(string)
+		trigger.Global().Death(trigger.Entity{Kind: trigger.NPCKind, ID: id, Proto: proto, Room: room}, killer)
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(clock.HourEvent, events.HandlerFunc(func(events.Data) error {
+		weather.Global().Update()
+
+		return nil
+	}))
+	craft.Global().Start(viper.GetDuration("craft.pulse"))
+	if err := loot.Global().Tables().LoadDir(viper.GetString("loot.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the loot tables")
+	}
+	if err := loot.Global().SetRule(viper.GetString("loot.rule")); err != nil {
+		log.WithError(err).Error("Failed to set the loot rule, using the default.")
+	}
+	loot.Global().SetMembers(lootMembers)
+	scripting.ServerEmitter.On(mob.DeathEvent, events.HandlerFunc(loot.Global().Died))
+	scripting.ServerEmitter.On(mob.DeathEvent, events.HandlerFunc(func(d events.Data) error {
+		if id, ok := d["mob"].(string); ok {
+			if c := combat.Global().Lookup(id); c != nil {
+				combat.Global().Remove(c)
+			}
+			effect.Global().Forget(id)
+		}
+		killer, _ := d["killer"].(string)
+		if proto, ok := d["proto"].(string); ok && killer != "" {
+			for _, member := range group.Global().Nearby(killer) {
+				questProgress(member.Name(), quest.Kill, proto, 1)
+			}
+			if def, ok := world.Global().NPC(proto); ok {
+				group.Global().Share(killer, def.Stats[viper.GetString("group.experience")])
+			}
+		}
+
+		return nil
+	}))
+
+	session.Global().Start(time.Second)
+	command.GlobalPacer().Start(viper.GetDuration("input.pulse"))
+	scripting.ServerEmitter.On(session.CloseEvent, events.HandlerFunc(func(d events.Data) error {
+		if id, ok := d["session"].(string); ok {
+			command.GlobalPacer().Remove(id)
+			prompt.GlobalManager().Remove(id)
+		}
+		if character, ok := d["character"].(string); ok && character != "" {
+			if c := combat.Global().Lookup(strings.ToLower(character)); c != nil {
+				combat.Global().Remove(c)
+			}
+			effect.Global().Forget(strings.ToLower(character))
+			craft.Global().Cancel(strings.ToLower(character))
+			group.Global().Leave(character)
+			pvp.Global().Leave(character)
+			if err := presence.Global().Logout(character); err != nil {
+				log.WithError(err).WithField("character", character).Error("Failed to record the logout.")
+			}
+			// what's attached to unsent letters is only kept in memory
+			if p := players.Global().Get(character); p != nil {
+				mail.Global().Detach(mover{p})
+			}
+			if err := players.Global().Unload(character); err != nil {
+				log.WithError(err).WithField("character", character).Error("Failed to save the player.")
+			}
+		}
+
+		return nil
+	}))
+	if err := command.Global().Register(prompt.NewCommand(prompt.GlobalManager())); err != nil {
+		log.WithError(err).Error("Failed to register the prompt command.")
+	}
+	movement.Global().SetOccupants(roomMovers)
+	movement.Global().SetDescriber(describeRoom)
+	for _, c := range movement.NewCommands(movement.Global(), resolveMover) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a movement command.")
+		}
+	}
+	item.Global().SetOccupants(roomCarriers)
+	item.Global().SetVisible(seesItem)
+	for _, c := range item.NewCommands(item.Global(), resolveCarrier) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register an item command.")
+		}
+	}
+	lock.Global().SetOccupants(roomOpeners)
+	for _, c := range lock.NewCommands(lock.Global(), resolveOpener) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a door command.")
+		}
+	}
+	for _, c := range equipment.NewCommands(equipment.Global(), resolveWearer) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register an equipment command.")
+		}
+	}
+	for _, c := range combat.NewCommands(combat.Global(), resolveCombatant) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a combat command.")
+		}
+	}
+	scripting.ServerEmitter.On("before:"+combat.StartEvent, events.HandlerFunc(func(d events.Data) error {
+		attacker, _ := d["attacker"].(string)
+		defender, _ := d["defender"].(string)
+		if room, _ := d["room"].(string); pvp.Global().Safe(room) {
+			return errors.New(pvp.SafeRoomMessage)
+		}
+		if group.Global().Allies(attacker, defender) {
+			return errors.New("You can't attack a member of your group.")
+		}
+		a, b := players.Global().Get(attacker), players.Global().Get(defender)
+		if a != nil && b != nil {
+			return pvp.Global().Attack(mover{a}, mover{b})
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(pvp.EndEvent, events.HandlerFunc(func(d events.Data) error {
+		names, _ := d["players"].([]string)
+		for _, name := range names {
+			if c := combat.Global().Lookup(strings.ToLower(name)); c != nil {
+				combat.Global().Remove(c)
+			}
+		}
+
+		return nil
+	}))
+	for _, c := range pvp.NewCommands(pvp.Global(), resolveFighter) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a pvp command.")
+		}
+	}
+	if err := command.Global().Register(weather.NewCommand(weather.Global(), resolveWatcher)); err != nil {
+		log.WithError(err).Error("Failed to register the weather command.")
+	}
+	vision.Global().SetOccupants(roomViewers)
+	vision.Global().SetDaylight(func() bool {
+		return clock.Game().Now().IsDay()
+	})
+	vision.Global().SetModifiers(viper.GetInt("vision.day"), viper.GetInt("vision.night"))
+	for _, c := range vision.NewCommands(vision.Global(), resolveLooker) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a vision command.")
+		}
+	}
+	for _, def := range language.Defaults() {
+		language.Global().Defs().Add(def)
+	}
+	if err := language.Global().Defs().LoadDir(viper.GetString("language.dir")); err != nil {
+		log.WithError(err).Error("Failed to load the languages")
+	}
+	language.Global().SetDefault(viper.GetString("language.default"))
+	language.Global().SetGain(viper.GetInt("language.gain"))
+	language.Global().SetOccupants(roomHearers)
+	for _, c := range language.NewCommands(language.Global(), resolveSpeaker) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a language command.")
+		}
+	}
+	minimap.Global().SetRadius(viper.GetInt("map.radius"))
+	if terrain, err := minimap.ParseTerrain(viper.GetStringSlice("map.terrain")); err != nil {
+		log.WithError(err).Error("Failed to read the map terrain, using the default.")
+	} else {
+		minimap.Global().SetTerrain(terrain)
+	}
+	if err := command.Global().Register(minimap.NewCommand(minimap.Global(), resolveViewer)); err != nil {
+		log.WithError(err).Error("Failed to register the map command.")
+	}
+	for _, c := range clan.NewCommands(clan.Global(), resolveClanMember) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a clan command.")
+		}
+	}
+	for _, c := range group.NewCommands(group.Global(), resolveGroupMember) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a group command.")
+		}
+	}
+	for _, c := range skill.NewCommands(skill.Global(), resolveLearner) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a skill command.")
+		}
+	}
+	for _, c := range effect.NewCommands(effect.Global(), resolveBearer) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register an effect command.")
+		}
+	}
+	quest.Global().SetNearby(roomGivers)
+	scripting.ServerEmitter.On(item.GetEvent, events.HandlerFunc(func(d events.Data) error {
+		actor, _ := d["actor"].(string)
+		proto, _ := d["proto"].(string)
+		if count, ok := d["count"].(int); ok && proto != "" {
+			questProgress(actor, quest.Collect, proto, count)
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On("before:"+movement.EnterEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["mover"].(string)
+		to, _ := d["to"].(string)
+		if !clan.Global().Allowed(name, to) {
+			owner, _ := clan.Global().Owner(to)
+
+			return fmt.Errorf("That room belongs to %s.", owner.Name)
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(movement.EnterEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["mover"].(string)
+		if to, ok := d["to"].(string); ok {
+			questProgress(name, quest.Visit, to, 1)
+		}
+		craft.Global().Cancel(strings.ToLower(name))
 
-	listener, err := net.Listen("tcp", host+":"+port)
+		return nil
+	}))
+	for _, c := range quest.NewCommands(quest.Global(), resolveQuestor) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a quest command.")
+		}
+	}
+	dialogue.Global().SetNearby(roomSpeakers)
+	scripting.ServerEmitter.On(dialogue.StartEvent, events.HandlerFunc(func(d events.Data) error {
+		name, _ := d["player_name"].(string)
+		if npc, ok := d["npc"].(string); ok {
+			questProgress(name, quest.Talk, npc, 1)
+		}
+
+		return nil
+	}))
+	scripting.ServerEmitter.On(session.DataEvent, events.HandlerFunc(func(d events.Data) error {
+		if d["package"] != dialogue.ChoosePackage {
+			return nil
+		}
+		character, _ := d["character"].(string)
+		if p := players.Global().Get(character); p != nil {
+			if err := dialogue.Global().Reply(mover{p}, fmt.Sprint(d["data"])); err != nil {
+				mover{p}.Send(err.Error())
+			}
+		}
+
+		return nil
+	}))
+	for _, c := range dialogue.NewCommands(dialogue.Global(), resolveTalker) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a dialogue command.")
+		}
+	}
+	shop.Global().SetNearby(roomGivers)
+	shop.Global().SetLedger(audit.Global())
+	for _, c := range shop.NewCommands(shop.Global(), resolveCustomer) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a shop command.")
+		}
+	}
+	craft.Global().SetImprove(func(c craft.Crafter, id string) {
+		if l, ok := c.(skill.Learner); ok {
+			skill.Global().Improve(l, id)
+		}
+	})
+	if c, err := advance.LoadConfig(viper.GetString("advance.file")); err != nil {
+		log.WithError(err).Error("Failed to load the advancement file, using the defaults.")
+	} else if err := advance.Global().SetConfig(c); err != nil {
+		log.WithError(err).Error("Failed to set up advancement, using the defaults.")
+	}
+	advance.Global().SetStat(viper.GetString("group.experience"))
+	if err := death.Global().SetPolicy(viper.GetString("death.policy")); err != nil {
+		log.WithError(err).Error("Failed to set the respawn policy, using the default.")
+	}
+	recall := viper.GetString("death.recall_room")
+	if recall == "" {
+		recall = viper.GetString("world.start_room")
+	}
+	death.Global().SetRecall(recall)
+	death.Global().SetDecay(viper.GetDuration("death.corpse_decay"), viper.GetDuration("death.player_corpse_decay"))
+	death.Global().SetExperienceLoss(viper.GetString("group.experience"), viper.GetInt("death.xp_loss"))
+	death.Global().SetHealth(viper.GetInt("death.respawn_hp"))
+	death.Global().Start(viper.GetDuration("death.pulse"))
+	mob.Global().SetCorpse(death.Global().MobCorpse)
+	for _, c := range advance.NewCommands(advance.Global(), resolveAdvancer) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register an advancement command.")
+		}
+	}
+	for _, c := range craft.NewCommands(craft.Global(), resolveCrafter) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a crafting command.")
+		}
+	}
+	for _, c := range channels.NewCommands(channels.Global(), resolveMember) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a channel command.")
+		}
+	}
+	if err := channels.Global().SetRegistry(command.Global(), resolveMember); err != nil {
+		log.WithError(err).Error("Failed to register the commands of the channels.")
+	}
+	for _, c := range social.NewCommands(social.Global()) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a social command.")
+		}
+	}
+	if err := social.Global().SetRegistry(command.Global(), resolveActor); err != nil {
+		log.WithError(err).Error("Failed to register the commands of some socials.")
+	}
+	for _, c := range mail.NewCommands(mail.Global(), resolveCorrespondent) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a mail command.")
+		}
+	}
+	for _, c := range board.NewCommands(board.Global(), resolveReader) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a board command.")
+		}
+	}
+	for _, c := range presence.NewCommands(presence.Global(), resolvePerson) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a presence command.")
+		}
+	}
+	for _, c := range olc.NewCommands(olc.Global(), resolveBuilder) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a building command.")
+		}
+	}
+	for _, c := range perm.NewCommands(perm.Global(), players.Global().Exists) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a role command.")
+		}
+	}
+	for _, c := range admin.NewCommands(admin.Global(), resolveStaffer) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register an admin command.")
+		}
+	}
+	for _, c := range ban.NewCommands(ban.Global(), characterOf) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a ban command.")
+		}
+	}
+	for _, c := range checkpoint.NewCommands(checkpoint.Global(), characterOf) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a checkpoint command.")
+		}
+	}
+	registerCheckpoints()
+	scripting.ServerEmitter.On(ban.AddEvent, events.HandlerFunc(func(events.Data) error {
+		kickBanned()
+
+		return nil
+	}))
+	command.Global().Unregister("help")
+	for _, c := range help.NewCommands(help.Global()) {
+		if err := command.Global().Register(c); err != nil {
+			log.WithError(err).WithField("command", c.Name).Error("Failed to register a help command.")
+		}
+	}
+	save.Global().Register("players", players.Global().SaveAll)
+	save.Global().Register("zones", func() (int, error) {
+		saved, err := olc.Global().SaveAll()
+
+		return len(saved), err
+	})
+	save.Global().Start(viper.GetDuration("save.interval"))
+	if spec := viper.GetString("backup.schedule"); spec != "" {
+		if _, err := sched.Global().Add("backup", spec, runBackup); err != nil {
+			log.WithError(err).Error("Failed to schedule backups.")
+		}
+	}
+	scripting.ServerEmitter.On(session.PlayEvent, events.HandlerFunc(func(d events.Data) error {
+		return enterGame(d, false)
+	}))
+	scripting.ServerEmitter.On(session.ResumeEvent, events.HandlerFunc(func(d events.Data) error {
+		return enterGame(d, true)
+	}))
+
+	handed, resuming, err := copyover.Load()
+	if err != nil {
+		log.WithError(err).Error("Failed to load what the copyover handed over.")
+		handed, resuming = nil, false
+	}
+	listener, err := listen(handed, "telnet", host+":"+port)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to start TCP server.")
 	}
@@ -47,12 +717,65 @@ func Run() {
 		"port": port,
 	}).Info("TCP server started")
 
+	if tlsPort := viper.GetString("telnet.tls.port"); tlsPort != "" {
+		cfg, err := tlsConfig()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to configure TLS.")
+		}
+
+		inner, err := listen(handed, "tls", host+":"+tlsPort)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to start TLS server.")
+		}
+		tlsListener := tls.NewListener(inner, cfg)
+
+		log.WithFields(logger.Fields{
+			"host": host,
+			"port": tlsPort,
+		}).Info("TLS server started")
+
+		go runServer(tlsListener)
+	}
+
+	if addr := viper.GetString("websocket.address"); addr != "" {
+		go runWebSocketServer(addr)
+	}
+
+	if resuming {
+		resumeSessions(handed)
+	}
+
+	go runServerTicks()
 	runServer(listener)
 }
 
+// runWebSocketServer accepts browser clients, handling them the same as
+// telnet clients
+func runWebSocketServer(addr string) {
+	connections, _ := metrics.Global().Counter("websocket.connections")
+	path := viper.GetString("websocket.path")
+	handler := websocket.Handler(viper.GetStringSlice("websocket.origins"), func(c core.Conn) {
+		log.WithField("addr", c.RemoteAddr().String()).Debug("Accepted incoming WebSocket connection.")
+		connections.Inc()
+		handle(c)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+
+	log.WithFields(logger.Fields{
+		"address": addr,
+		"path":    path,
+	}).Info("WebSocket server started")
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.WithError(err).Error("WebSocket server stopped.")
+	}
+}
+
 func runServer(listener net.Listener) {
 	defer listener.Close()
-	go runServerTicks()
+	connections, _ := metrics.Global().Counter("telnet.connections")
 	for serverRunning {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -61,15 +784,248 @@ func runServer(listener net.Listener) {
 			continue
 		}
 
+		_, secure := conn.(*tls.Conn)
 		addrInfo := strings.Split(conn.RemoteAddr().String(), ":")
 		log.WithFields(logger.Fields{
 			"ip":   addrInfo[0],
 			"port": addrInfo[1],
+			"tls":  secure,
 		}).Debug("Accepted incoming connection.")
-		go handleConnection(conn)
+		connections.Inc()
+		tc := telnet.NewConn(conn)
+		if err := tc.RequestTerminal(); err != nil {
+			log.WithError(err).Warn("Failed to ask for the terminal type.")
+		}
+		if err := tc.SetCharset(viper.GetString("telnet.charset")); err != nil {
+			log.WithError(err).Warn("Failed to set the default character set.")
+		}
+		if err := tc.RequestCharset(); err != nil {
+			log.WithError(err).Warn("Failed to negotiate the character set.")
+		}
+		if viper.GetBool("telnet.mccp") {
+			if err := tc.SetCompression(true); err != nil {
+				log.WithError(err).Warn("Failed to offer compression.")
+			}
+		}
+		if viper.GetBool("telnet.gmcp") {
+			if err := tc.SetGMCP(true); err != nil {
+				log.WithError(err).Warn("Failed to offer GMCP.")
+			}
+		}
+		if viper.GetBool("telnet.msdp") {
+			if err := tc.SetMSDP(true); err != nil {
+				log.WithError(err).Warn("Failed to offer MSDP.")
+			}
+		}
+		go handle(telnetConn{tc})
 	}
 }
 
+// runBackup saves what changed and then backs up the game
+func runBackup() {
+	if _, err := save.Global().Save(save.Manual); err != nil {
+		log.WithError(err).Warn("Failed to save everything before the backup.")
+	}
+	archive, removed, err := backup.Run()
+	if err != nil {
+		log.WithError(err).Error("Failed to back up the game.")
+
+		return
+	}
+	log.WithFields(logger.Fields{
+		"archive": archive,
+		"removed": len(removed),
+	}).Info("Backed up the game.")
+}
+
+// stopServer saves every player and closes their sessions before exiting,
+// or before starting the server again in place of this process to reboot. A
+// copyover hands the sessions it can to the new process instead of closing
+// them.
+func stopServer(stop admin.Stop) {
+	log.WithFields(logger.Fields{
+		"by":       stop.By,
+		"reason":   stop.Reason,
+		"reboot":   stop.Reboot,
+		"copyover": stop.Copyover,
+	}).Warn("Stopping the server.")
+	serverRunning = false
+	command.GlobalPacer().Stop()
+	mail.Global().ReturnDrafts(func(id string) mail.Correspondent {
+		if p := players.Global().Get(id); p != nil {
+			return mover{p}
+		}
+
+		return nil
+	})
+	if stats, err := save.Global().Stop(); err != nil {
+		log.WithError(err).WithField("failed", len(stats.Failed)).Error("Failed to save everything that changed.")
+	}
+	var (
+		handoff *copyover.Handoff
+		handed  map[string]bool
+	)
+	if stop.Copyover {
+		handoff, handed = handOff()
+	}
+	for _, s := range session.Global().Sessions() {
+		if handed[s.ID()] {
+			continue
+		}
+		if stop.Copyover {
+			s.Output().Send(copyoverClosedMessage)
+		}
+		session.Global().Close(s, "shutdown")
+	}
+	if handoff != nil {
+		err := copyoverServer(handoff)
+		log.WithError(err).Error("Failed to copy over, rebooting instead.")
+		handoff.Close()
+		for _, s := range session.Global().Sessions() {
+			session.Global().Close(s, "shutdown")
+		}
+	}
+	if stop.Reboot {
+		exe, err := os.Executable()
+		if err == nil {
+			err = syscall.Exec(exe, os.Args, os.Environ())
+		}
+		log.WithError(err).Error("Failed to reboot, shutting down instead.")
+	}
+	os.Exit(0)
+}
+
+// handle opens a session for the connection, logs the player in and then
+// runs the commands they type until the connection is lost
+func handle(c core.Conn) {
+	if b, ok := ban.Global().Find(ban.Address, addressOf(c.RemoteAddr())); ok {
+		log.WithField("addr", c.RemoteAddr().String()).Info("Refused a banned connection.")
+		output.NewWriter(c, 0).Send(b.Message())
+		c.Close()
+
+		return
+	}
+
+	p := newPlayer(session.Global().Open(c))
+	pacer := command.GlobalPacer()
+	login := account.NewLogin(account.Global(), scripting.ServerEmitter, func(_ *account.Account, character string) {
+		s := p.session()
+		if playing := session.Global().Play(s, character); playing != s {
+			pacer.Remove(s.ID())
+			prompt.GlobalManager().Remove(s.ID())
+			p.setSession(playing)
+		}
+	}, func() {
+		session.Global().Close(p.session(), "quit")
+	})
+	login.SetCheck(func(owner, name string) error {
+		if b, ok := ban.Global().Check(addressOf(p.session().RemoteAddr()), owner, name); ok {
+			return errors.New(b.Message())
+		}
+
+		return nil
+	})
+	login.SetCreator(character.NewCreator(character.Global(), account.Global(), character.GlobalStore(), scripting.ServerEmitter))
+	pacer.Dispatcher().Enter(p, login)
+	login.Start(p)
+
+	readCommands(p)
+}
+
+// readCommands queues the commands the player types until the connection is
+// lost
+func readCommands(p *player) {
+	lines := bufio.NewScanner(p)
+	for lines.Scan() {
+		command.GlobalPacer().Queue(p).Push(lines.Text())
+	}
+}
+
+// enterGame loads the player of a session that started playing, or resumed
+// playing after a copyover, and puts them in the game
+func enterGame(d events.Data, resumed bool) error {
+	id, _ := d["session"].(string)
+	s := session.Global().Get(id)
+	if s == nil {
+		return nil
+	}
+	pr, err := prompt.GlobalManager().Attach(id, s.Output())
+	if err != nil {
+		log.WithError(err).Warn("Failed to create the player's prompt.")
+	}
+	p, err := players.Global().Load(s.Character())
+	if err != nil {
+		log.WithError(err).WithField("character", s.Character()).Error("Failed to load the player.")
+
+		return nil
+	}
+	// the login of resumed sessions was recorded before the copyover
+	if !resumed {
+		address, _ := d["address"].(string)
+		if err := presence.Global().Login(p.Name(), address); err != nil {
+			log.WithError(err).WithField("character", p.Name()).Error("Failed to record the login.")
+		}
+	}
+	if _, ok := world.Global().Room(p.Location()); !ok {
+		if start := viper.GetString("world.start_room"); start != "" {
+			p.SetLocation(start)
+		}
+	}
+	// saved effects are already in the player's stats, they only need
+	// to keep ticking
+	effect.Global().Track(mover{p})
+	dialogue.Global().Show(mover{p})
+	if n := mail.Global().Unread(p.Name()); n > 0 && !resumed {
+		mover{p}.Send(fmt.Sprintf("You have %d unread letter(s), type \"mail\" to read them.", n))
+	}
+	if pr != nil {
+		stats := make(prompt.Stats)
+		for stat, value := range p.Stats() {
+			stats[stat] = value
+		}
+		pr.SetStats(stats)
+	}
+	p.OnStat(func(stat string, value int) {
+		if pr != nil {
+			pr.Set(stat, value)
+		}
+		// experience from anywhere, kills, quests or scripts, levels
+		// players up as it's earned
+		if stat == advance.Global().Stat() {
+			advance.Global().Check(mover{p})
+		}
+	})
+
+	return nil
+}
+
+// kickBanned closes the sessions of players a ban now keeps out, telling
+// them why
+func kickBanned() {
+	for _, s := range session.Global().Sessions() {
+		var owner string
+		if s.Character() != "" {
+			if a, err := account.Global().Owner(s.Character()); err == nil {
+				owner = a.Name
+			}
+		}
+		if b, ok := ban.Global().Check(addressOf(s.RemoteAddr()), owner, s.Character()); ok {
+			s.Output().Send(b.Message())
+			session.Global().Close(s, "banned")
+		}
+	}
+}
+
+// addressOf returns the host of the address, without its port
+func addressOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
 func runServerTicks() {
 	go runTicker(time.Tick(1*time.Second), "tick:1s")
 	go runTicker(time.Tick(5*time.Second), "tick:5s")
@@ -86,8 +1042,3 @@ func runTicker(tick <-chan time.Time, evt string) {
 		scripting.GlobalEmit(evt, nil)
 	}
 }
-
-func handleConnection(conn net.Conn) {
-	conn.Write([]byte("You were connected successfully, closing connection.\r\n"))
-	conn.Close()
-}
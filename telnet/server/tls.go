@@ -0,0 +1,50 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// errNoCertificate is returned when TLS is enabled without a way to get a
+// certificate
+var errNoCertificate = errors.New("telnet TLS requires cert and key files or autocert domains")
+
+// tlsConfig builds the TLS configuration for the telnet TLS listener from the
+// telnet.tls settings. Certificates come from the cert and key files, or from
+// Let's Encrypt for the autocert domains when no files are given.
+func tlsConfig() (*tls.Config, error) {
+	cert := viper.GetString("telnet.tls.cert")
+	key := viper.GetString("telnet.tls.key")
+	if cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tls.Config{
+			Certificates: []tls.Certificate{pair},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	}
+
+	domains := viper.GetStringSlice("telnet.tls.autocert.domains")
+	if len(domains) == 0 {
+		return nil, errNoCertificate
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(viper.GetString("telnet.tls.autocert.cache_dir")),
+		Email:      viper.GetString("telnet.tls.autocert.email"),
+	}
+	cfg := manager.TLSConfig()
+	cfg.MinVersion = tls.VersionTLS12
+
+	return cfg, nil
+}
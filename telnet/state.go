@@ -0,0 +1,127 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"net"
+	"sort"
+)
+
+// Option is the negotiated state of an option offered to the client.
+type Option struct {
+	// Wanted is true while the server wants the option.
+	Wanted bool `json:"wanted,omitempty"`
+	// Offered is true once the server offered the option.
+	Offered bool `json:"offered,omitempty"`
+	// Enabled is true while the client agrees to the option.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// State is everything negotiated with the client, so another process can
+// carry on with the connection without negotiating again. It can be encoded
+// as JSON.
+type State struct {
+	MCCP     Option   `json:"mccp"`
+	GMCP     Option   `json:"gmcp"`
+	MSDP     Option   `json:"msdp"`
+	Terminal Terminal `json:"terminal"`
+	// Reported are the MSDP variables the client wants sent as they change.
+	Reported []string `json:"reported,omitempty"`
+	// Values are the last values sent for MSDP variables.
+	Values       map[string]interface{} `json:"values,omitempty"`
+	AskedNAWS    bool                   `json:"asked_naws,omitempty"`
+	AskedTTYPE   bool                   `json:"asked_ttype,omitempty"`
+	AskedCharset bool                   `json:"asked_charset,omitempty"`
+}
+
+// Suspend ends compression, so the client reads what follows uncompressed,
+// and returns what was negotiated. The connection is left open, it's meant
+// to be handed on and continued with Resume.
+func (c *Conn) Suspend() (State, error) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	st := State{
+		MCCP: Option{
+			Wanted:  c.compression.wanted,
+			Offered: c.compression.offered,
+			Enabled: c.compression.accepted,
+		},
+		GMCP: Option{
+			Wanted:  c.gmcp.wanted,
+			Offered: c.gmcp.offered,
+			Enabled: c.gmcp.enabled,
+		},
+		MSDP: Option{
+			Wanted:  c.msdp.wanted,
+			Offered: c.msdp.offered,
+			Enabled: c.msdp.enabled,
+		},
+		Terminal:     c.terminal.Terminal,
+		AskedNAWS:    c.terminal.askedNAWS,
+		AskedTTYPE:   c.terminal.askedTTYPE,
+		AskedCharset: c.terminal.askedCharset,
+	}
+	st.Terminal.Types = append([]string(nil), st.Terminal.Types...)
+	for name := range c.msdp.reported {
+		st.Reported = append(st.Reported, name)
+	}
+	sort.Strings(st.Reported)
+	if len(c.msdp.values) > 0 {
+		st.Values = make(map[string]interface{}, len(c.msdp.values))
+		for name, value := range c.msdp.values {
+			st.Values[name] = value
+		}
+	}
+
+	return st, c.stopCompression()
+}
+
+// Resume wraps a connection another Conn was suspended from, continuing with
+// what was negotiated. Compression starts again if it was on.
+func Resume(c net.Conn, st State) (*Conn, error) {
+	conn := NewConn(c)
+	cs, ok := findCharset(st.Terminal.Charset)
+	if !ok {
+		cs = nil
+	}
+
+	conn.wmutex.Lock()
+	defer conn.wmutex.Unlock()
+
+	conn.compression.wanted = st.MCCP.Wanted
+	conn.compression.offered = st.MCCP.Offered
+	conn.compression.accepted = st.MCCP.Enabled
+	conn.gmcp.wanted = st.GMCP.Wanted
+	conn.gmcp.offered = st.GMCP.Offered
+	conn.gmcp.enabled = st.GMCP.Enabled
+	conn.msdp.wanted = st.MSDP.Wanted
+	conn.msdp.offered = st.MSDP.Offered
+	conn.msdp.enabled = st.MSDP.Enabled
+	for _, name := range st.Reported {
+		if conn.msdp.reported == nil {
+			conn.msdp.reported = make(map[string]bool)
+		}
+		conn.msdp.reported[name] = true
+	}
+	for name, value := range st.Values {
+		if conn.msdp.values == nil {
+			conn.msdp.values = make(map[string]interface{})
+		}
+		conn.msdp.values[name] = value
+	}
+	conn.terminal.Terminal = st.Terminal
+	conn.terminal.Types = append([]string(nil), st.Terminal.Types...)
+	conn.terminal.askedNAWS = st.AskedNAWS
+	conn.terminal.askedTTYPE = st.AskedTTYPE
+	conn.terminal.askedCharset = st.AskedCharset
+	conn.setCharset(cs)
+
+	if conn.compression.wanted && conn.compression.accepted {
+		if err := conn.startCompression(); err != nil {
+			return conn, err
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package telnet speaks the telnet protocol over a network connection. Reads
+// return only the text sent by the client, negotiations and other commands
+// are handled as they arrive, and writes escape the text so it can't be
+// mistaken for commands.
+package telnet
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+)
+
+// Telnet commands
+const (
+	SE   byte = 240
+	SB   byte = 250
+	WILL byte = 251
+	WONT byte = 252
+	DO   byte = 253
+	DONT byte = 254
+	IAC  byte = 255
+)
+
+// Telnet options supported by the server
+const (
+	// TTYPE is the terminal type option, the client reports the terminals it
+	// can emulate.
+	TTYPE byte = 24
+	// NAWS is Negotiate About Window Size, the client reports the size of its
+	// window as it changes.
+	NAWS byte = 31
+	// CHARSET negotiates the character set text is sent in.
+	CHARSET byte = 42
+	// MCCP2 is the MUD Client Compression Protocol, version 2.
+	MCCP2 byte = 86
+	// MSDP is the Mud Server Data Protocol, an older way to carry structured
+	// data.
+	MSDP byte = 69
+	// GMCP is the Generic MUD Communication Protocol, which carries
+	// structured data as JSON.
+	GMCP byte = 201
+)
+
+// the longest subnegotiation kept, longer ones are truncated
+const maxSubnegotiation = 8192
+
+// where the reader is within a command
+type readState int
+
+const (
+	stateData readState = iota
+	stateIAC
+	stateOption
+	stateSB
+	stateSBIAC
+)
+
+// Conn is a telnet connection. It's safe to read from one goroutine while
+// writing from others.
+type Conn struct {
+	net.Conn
+
+	// guarded by the reading goroutine
+	state   readState
+	command byte
+	sb      []byte
+	pending []byte
+
+	// guarded by wmutex, which covers everything written to the connection
+	wmutex      *sync.Mutex
+	compression mccp
+	gmcp        gmcp
+	msdp        msdp
+	terminal    terminal
+	charset     *charset
+	onData      func(kind string, data json.RawMessage)
+}
+
+// NewConn wraps the connection, no options are offered until they're
+// enabled.
+func NewConn(c net.Conn) *Conn {
+	conn := &Conn{
+		Conn:   c,
+		wmutex: new(sync.Mutex),
+	}
+	conn.terminal.Charset = UTF8
+
+	return conn
+}
+
+// Read reads text sent by the client, handling any commands mixed in with
+// it. Text is converted to UTF-8 from the client's character set. Read waits
+// for text even if everything received so far was commands.
+func (c *Conn) Read(p []byte) (int, error) {
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+
+		return n, nil
+	}
+
+	for {
+		n, err := c.Conn.Read(p)
+		n = c.filter(p[:n])
+
+		c.wmutex.Lock()
+		cs := c.charset
+		c.wmutex.Unlock()
+		if cs != nil && n > 0 {
+			// decoded text can be longer, what doesn't fit is kept for the
+			// next read
+			decoded := cs.decode(p[:n])
+			n = copy(p, decoded)
+			c.pending = decoded[n:]
+		}
+
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// Write sends the text to the client, converted to the client's character
+// set and compressed if the client agreed to it.
+func (c *Conn) Write(p []byte) (int, error) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	text := p
+	if c.charset != nil {
+		text = c.charset.encode(p)
+	}
+	escaped := make([]byte, 0, len(text))
+	for _, b := range text {
+		if b == IAC {
+			escaped = append(escaped, IAC)
+		}
+		escaped = append(escaped, b)
+	}
+
+	if _, err := c.write(escaped); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close ends compression, if it was on, and closes the connection.
+func (c *Conn) Close() error {
+	c.wmutex.Lock()
+	c.stopCompression()
+	c.wmutex.Unlock()
+
+	return c.Conn.Close()
+}
+
+// filter removes commands from the data read, handling them, and returns the
+// length of the text left at the start of data.
+func (c *Conn) filter(data []byte) int {
+	n := 0
+	for _, b := range data {
+		switch c.state {
+		case stateData:
+			if b == IAC {
+				c.state = stateIAC
+			} else {
+				data[n] = b
+				n++
+			}
+		case stateIAC:
+			switch b {
+			case IAC:
+				data[n] = b
+				n++
+				c.state = stateData
+			case WILL, WONT, DO, DONT:
+				c.command = b
+				c.state = stateOption
+			case SB:
+				c.sb = c.sb[:0]
+				c.state = stateSB
+			default:
+				// other commands, like go ahead, mean nothing to the server
+				c.state = stateData
+			}
+		case stateOption:
+			c.negotiate(c.command, b)
+			c.state = stateData
+		case stateSB:
+			if b == IAC {
+				c.state = stateSBIAC
+			} else if len(c.sb) < maxSubnegotiation {
+				c.sb = append(c.sb, b)
+			}
+		case stateSBIAC:
+			switch b {
+			case SE:
+				if len(c.sb) > 0 {
+					c.subnegotiate(c.sb[0], c.sb[1:])
+				}
+				c.state = stateData
+			case IAC:
+				if len(c.sb) < maxSubnegotiation {
+					c.sb = append(c.sb, IAC)
+				}
+				c.state = stateSB
+			default:
+				c.state = stateSB
+			}
+		}
+	}
+
+	return n
+}
+
+// negotiate responds to the client asking for, or offering, an option.
+// Options the server doesn't support are refused.
+func (c *Conn) negotiate(command, option byte) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	switch {
+	case option == MCCP2 && command == DO:
+		c.compressionAccepted()
+	case option == MCCP2 && command == DONT:
+		c.compressionRefused()
+	case option == GMCP && command == DO:
+		c.gmcpAccepted()
+	case option == GMCP && command == DONT:
+		c.gmcpRefused()
+	case option == MSDP && command == DO:
+		c.msdpAccepted()
+	case option == MSDP && command == DONT:
+		c.msdpRefused()
+	case option == NAWS && command == WILL:
+		c.nawsOffered()
+	case option == TTYPE && command == WILL:
+		c.ttypeOffered()
+	case option == CHARSET && command == DO:
+		c.charsetAccepted()
+	case command == DO:
+		c.sendCommand(WONT, option)
+	case command == WILL:
+		c.sendCommand(DONT, option)
+	}
+}
+
+// subnegotiate handles the parameters the client sent for an option
+func (c *Conn) subnegotiate(option byte, data []byte) {
+	switch option {
+	case GMCP:
+		c.gmcpReceived(data)
+	case MSDP:
+		c.msdpReceived(data)
+	case NAWS:
+		c.nawsReceived(data)
+	case TTYPE:
+		c.ttypeReceived(data)
+	case CHARSET:
+		c.charsetReceived(data)
+	}
+}
+
+// sendCommand sends a negotiation, the write mutex must be held
+func (c *Conn) sendCommand(command, option byte) error {
+	_, err := c.write([]byte{IAC, command, option})
+
+	return err
+}
+
+// sendSubnegotiation sends the parameters of an option, data must already be
+// escaped. The write mutex must be held.
+func (c *Conn) sendSubnegotiation(option byte, data []byte) error {
+	msg := make([]byte, 0, len(data)+5)
+	msg = append(msg, IAC, SB, option)
+	msg = append(msg, data...)
+	msg = append(msg, IAC, SE)
+	_, err := c.write(msg)
+
+	return err
+}
+
+// write sends bytes as they are, through the compressor when it's on. The
+// write mutex must be held.
+func (c *Conn) write(p []byte) (int, error) {
+	if c.compression.writer != nil {
+		return c.compression.write(p)
+	}
+
+	return c.Conn.Write(p)
+}
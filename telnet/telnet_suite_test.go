@@ -0,0 +1,13 @@
+package telnet_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTelnet(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Telnet Suite")
+}
@@ -0,0 +1,467 @@
+package telnet_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/bbuck/dragon-mud/output"
+	. "github.com/bbuck/dragon-mud/telnet"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// bufferConn is a connection reading the input given and recording what's
+// written to it
+type bufferConn struct {
+	net.Conn
+	input  *bytes.Buffer
+	output *bytes.Buffer
+	mutex  *sync.Mutex
+}
+
+func newBufferConn(input ...byte) *bufferConn {
+	return &bufferConn{
+		input:  bytes.NewBuffer(input),
+		output: new(bytes.Buffer),
+		mutex:  new(sync.Mutex),
+	}
+}
+
+func (c *bufferConn) Read(p []byte) (int, error) {
+	return c.input.Read(p)
+}
+
+func (c *bufferConn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.output.Write(p)
+}
+
+func (c *bufferConn) Close() error {
+	return nil
+}
+
+// sent returns what was written since the last call
+func (c *bufferConn) sent() []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sent := append([]byte(nil), c.output.Bytes()...)
+	c.output.Reset()
+
+	return sent
+}
+
+var _ = Describe("Conn", func() {
+	var (
+		raw  *bufferConn
+		conn *Conn
+	)
+
+	readAll := func() string {
+		b, err := ioutil.ReadAll(conn)
+		Ω(err).Should(BeNil())
+
+		return string(b)
+	}
+
+	Describe("Read", func() {
+		It("removes commands from the text", func() {
+			raw = newBufferConn('h', IAC, 249, 'i', IAC, SB, 24, 0, 'x', IAC, IAC, IAC, SE, '\n')
+			conn = NewConn(raw)
+
+			Ω(readAll()).Should(Equal("hi\n"))
+		})
+
+		It("unescapes doubled IAC", func() {
+			raw = newBufferConn('a', IAC, IAC, 'b')
+			conn = NewConn(raw)
+
+			Ω(readAll()).Should(Equal("a\xffb"))
+		})
+
+		It("refuses unsupported options", func() {
+			raw = newBufferConn(IAC, DO, 1, IAC, WILL, 34, IAC, WONT, 3, 'x')
+			conn = NewConn(raw)
+
+			Ω(readAll()).Should(Equal("x"))
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WONT, 1, IAC, DONT, 34}))
+		})
+	})
+
+	Describe("Write", func() {
+		It("escapes IAC", func() {
+			raw = newBufferConn()
+			conn = NewConn(raw)
+			conn.Write([]byte("a\xffb"))
+
+			Ω(raw.sent()).Should(Equal([]byte{'a', IAC, IAC, 'b'}))
+		})
+	})
+
+	Describe("compression", func() {
+		BeforeEach(func() {
+			raw = newBufferConn(IAC, DO, MCCP2, 'x')
+			conn = NewConn(raw)
+		})
+
+		It("offers compression", func() {
+			Ω(conn.SetCompression(true)).Should(Succeed())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WILL, MCCP2}))
+			Ω(conn.Compressing()).Should(BeFalse())
+		})
+
+		It("compresses once the client accepts", func() {
+			conn.SetCompression(true)
+			raw.sent()
+			readAll()
+
+			Ω(conn.Compressing()).Should(BeTrue())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, SB, MCCP2, IAC, SE}))
+
+			text := bytes.Repeat([]byte("The dragon roars. "), 50)
+			conn.Write(text)
+
+			z, err := zlib.NewReader(bytes.NewReader(raw.sent()))
+			Ω(err).Should(BeNil())
+			received := make([]byte, len(text))
+			_, err = io.ReadFull(z, received)
+			Ω(err).Should(BeNil())
+			Ω(received).Should(Equal(text))
+
+			rawBytes, sent := conn.CompressionStats()
+			Ω(rawBytes).Should(Equal(uint64(len(text))))
+			Ω(sent).Should(BeNumerically(">", 0))
+			Ω(sent).Should(BeNumerically("<", rawBytes))
+		})
+
+		It("ends the stream when turned off", func() {
+			conn.SetCompression(true)
+			readAll()
+			raw.sent()
+
+			conn.Write([]byte("compressed"))
+			Ω(conn.SetCompression(false)).Should(Succeed())
+			Ω(conn.Compressing()).Should(BeFalse())
+			conn.Write([]byte("plain"))
+
+			stream := bytes.NewReader(raw.sent())
+			z, err := zlib.NewReader(stream)
+			Ω(err).Should(BeNil())
+			compressed, err := ioutil.ReadAll(z)
+			Ω(err).Should(BeNil())
+			Ω(string(compressed)).Should(Equal("compressed"))
+
+			rest, _ := ioutil.ReadAll(stream)
+			Ω(string(rest)).Should(Equal("plain"))
+		})
+
+		It("refuses compression it doesn't want", func() {
+			readAll()
+
+			Ω(conn.Compressing()).Should(BeFalse())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WONT, MCCP2}))
+		})
+	})
+
+	Describe("GMCP", func() {
+		BeforeEach(func() {
+			raw = newBufferConn(IAC, DO, GMCP, 'x')
+			conn = NewConn(raw)
+		})
+
+		It("sends packages once the client agrees", func() {
+			Ω(conn.SetGMCP(true)).Should(Succeed())
+			Ω(conn.SendGMCP("Char.Vitals", map[string]int{"hp": 10})).Should(Succeed())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WILL, GMCP}))
+
+			readAll()
+			Ω(conn.GMCP()).Should(BeTrue())
+			Ω(conn.SendGMCP("Char.Vitals", map[string]int{"hp": 10})).Should(Succeed())
+
+			expected := append([]byte{IAC, SB, GMCP}, `Char.Vitals {"hp":10}`...)
+			Ω(raw.sent()).Should(Equal(append(expected, IAC, SE)))
+		})
+
+		It("receives packages from the client", func() {
+			raw = newBufferConn('a', IAC, SB, GMCP)
+			raw.input.WriteString(`Core.Hello {"client": "Mudlet"}`)
+			raw.input.Write([]byte{IAC, SE, 'b'})
+			conn = NewConn(raw)
+
+			var (
+				pkg  string
+				data json.RawMessage
+			)
+			conn.OnData(func(p string, d json.RawMessage) {
+				pkg, data = p, d
+			})
+
+			Ω(readAll()).Should(Equal("ab"))
+			Ω(pkg).Should(Equal("Core.Hello"))
+			Ω(string(data)).Should(MatchJSON(`{"client": "Mudlet"}`))
+		})
+
+		It("refuses GMCP it doesn't want", func() {
+			readAll()
+
+			Ω(conn.GMCP()).Should(BeFalse())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WONT, GMCP}))
+		})
+	})
+
+	Describe("MSDP", func() {
+		var received map[string]string
+
+		msdp := func(parts ...interface{}) []byte {
+			msg := []byte{IAC, SB, MSDP}
+			for _, part := range parts {
+				switch t := part.(type) {
+				case string:
+					msg = append(msg, t...)
+				case int:
+					msg = append(msg, byte(t))
+				}
+			}
+
+			return append(msg, IAC, SE)
+		}
+
+		BeforeEach(func() {
+			received = make(map[string]string)
+			raw = newBufferConn(IAC, DO, MSDP)
+			conn = NewConn(raw)
+			conn.SetMSDP(true)
+			conn.OnData(func(kind string, data json.RawMessage) {
+				received[kind] = string(data)
+			})
+		})
+
+		It("sends reported variables", func() {
+			raw.input.Write(msdp(1, "REPORT", 2, "Char.Vitals"))
+			readAll()
+			raw.sent()
+
+			Ω(conn.MSDP()).Should(BeTrue())
+			Ω(conn.SendMSDP("Room.Info", "ignored")).Should(Succeed())
+			Ω(raw.sent()).Should(BeEmpty())
+
+			Ω(conn.SendData("Char.Vitals", map[string]interface{}{"hp": 10, "name": "Izuna"})).Should(Succeed())
+			Ω(raw.sent()).Should(Equal(msdp(1, "Char.Vitals", 2, 3, 1, "hp", 2, "10", 1, "name", 2, "Izuna", 4)))
+		})
+
+		It("sends values as reporting starts", func() {
+			conn.SendMSDP("ROOM_NAME", "Cave")
+			raw.input.Write(msdp(1, "REPORT", 2, 5, 2, "ROOM_NAME", 2, "HEALTH", 6))
+			readAll()
+
+			Ω(raw.sent()).Should(Equal(append([]byte{IAC, WILL, MSDP}, msdp(1, "ROOM_NAME", 2, "Cave")...)))
+		})
+
+		It("lists reportable variables", func() {
+			conn.SendMSDP("HEALTH", 10)
+			conn.SendMSDP("AREA", []string{"a", "b"})
+			raw.sent()
+			raw.input.Write(msdp(1, "LIST", 2, "REPORTABLE_VARIABLES"))
+			readAll()
+
+			Ω(raw.sent()).Should(Equal(msdp(1, "REPORTABLE_VARIABLES", 2, 5, 2, "AREA", 2, "HEALTH", 6)))
+		})
+
+		It("passes other variables on as data", func() {
+			raw.input.Write(msdp(1, "CLIENT_ID", 2, "tintin", 1, "Char.Size", 2, 3, 1, "width", 2, "80", 4))
+			readAll()
+
+			Ω(received).Should(HaveKeyWithValue("CLIENT_ID", `"tintin"`))
+			Ω(received["Char.Size"]).Should(MatchJSON(`{"width": "80"}`))
+		})
+
+		It("prefers GMCP for data", func() {
+			raw.input.Write([]byte{IAC, DO, GMCP})
+			conn.SetGMCP(true)
+			readAll()
+			raw.sent()
+			conn.SendData("Core.Ping", nil)
+
+			Ω(raw.sent()).Should(Equal(append([]byte{IAC, SB, GMCP}, append([]byte("Core.Ping"), IAC, SE)...)))
+		})
+	})
+
+	Describe("terminal", func() {
+		var reported []Terminal
+
+		BeforeEach(func() {
+			reported = nil
+			raw = newBufferConn()
+			conn = NewConn(raw)
+			conn.OnTerminal(func(t Terminal) {
+				reported = append(reported, t)
+			})
+		})
+
+		ttype := func(name string) []byte {
+			return append(append([]byte{IAC, SB, TTYPE, 0}, name...), IAC, SE)
+		}
+
+		It("asks for the window size and terminal type", func() {
+			Ω(conn.RequestTerminal()).Should(Succeed())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, DO, NAWS, IAC, DO, TTYPE}))
+		})
+
+		It("records the window size", func() {
+			conn.RequestTerminal()
+			raw.input.Write([]byte{IAC, WILL, NAWS, IAC, SB, NAWS, 0, 120, 0, 40, IAC, SE, 'x'})
+			readAll()
+
+			Ω(conn.Terminal().Width).Should(Equal(120))
+			Ω(conn.Terminal().Height).Should(Equal(40))
+			Ω(reported).Should(HaveLen(1))
+		})
+
+		It("asks for terminal types until they repeat", func() {
+			conn.RequestTerminal()
+			raw.sent()
+			raw.input.Write([]byte{IAC, WILL, TTYPE})
+			raw.input.Write(ttype("MUDLET"))
+			raw.input.Write(ttype("XTERM-256COLOR"))
+			raw.input.Write(ttype("XTERM-256COLOR"))
+			readAll()
+
+			send := []byte{IAC, SB, TTYPE, 1, IAC, SE}
+			Ω(raw.sent()).Should(Equal(append(append(send, send...), send...)))
+			Ω(conn.Terminal().Types).Should(Equal([]string{"MUDLET", "XTERM-256COLOR"}))
+			Ω(conn.Terminal().ColorSupport()).Should(Equal(output.Color256))
+		})
+	})
+
+	Describe("charset", func() {
+		BeforeEach(func() {
+			raw = newBufferConn()
+			conn = NewConn(raw)
+		})
+
+		charset := func(command byte, name string) []byte {
+			return append(append([]byte{IAC, SB, CHARSET, command}, name...), IAC, SE)
+		}
+
+		It("offers the supported character sets", func() {
+			Ω(conn.RequestCharset()).Should(Succeed())
+			Ω(raw.sent()).Should(Equal([]byte{IAC, WILL, CHARSET}))
+
+			raw.input.Write([]byte{IAC, DO, CHARSET, 'x'})
+			readAll()
+
+			Ω(raw.sent()).Should(Equal(charset(1, ";UTF-8;ISO-8859-1;CP437;US-ASCII")))
+		})
+
+		It("converts text to the character set the client accepts", func() {
+			conn.RequestCharset()
+			raw.input.Write(charset(2, "ISO-8859-1"))
+			raw.input.Write([]byte{'x'})
+			readAll()
+			raw.sent()
+
+			conn.Write([]byte("café ☃"))
+			Ω(raw.sent()).Should(Equal([]byte("caf\xe9 ?")))
+			Ω(conn.Terminal().Charset).Should(Equal("ISO-8859-1"))
+		})
+
+		It("converts text from the client to UTF-8", func() {
+			Ω(conn.SetCharset("cp437")).Should(Succeed())
+			raw.input.Write([]byte{0x82, 0xdb, 'a'})
+
+			buf := make([]byte, 2)
+			var text []byte
+			for {
+				n, err := conn.Read(buf)
+				text = append(text, buf[:n]...)
+				if err != nil {
+					break
+				}
+			}
+
+			Ω(string(text)).Should(Equal("é█a"))
+		})
+
+		It("answers the client's request", func() {
+			raw.input.Write(charset(1, ";KOI8-R;latin1"))
+			raw.input.Write(charset(1, ";KOI8-R"))
+			raw.input.Write([]byte{'x'})
+			readAll()
+
+			expected := append(charset(2, "latin1"), charset(3, "")...)
+			Ω(raw.sent()).Should(Equal(expected))
+			Ω(conn.Terminal().Charset).Should(Equal("ISO-8859-1"))
+		})
+
+		It("refuses unknown character sets", func() {
+			Ω(conn.SetCharset("KOI8-R")).ShouldNot(Succeed())
+			Ω(conn.Terminal().Charset).Should(Equal(UTF8))
+		})
+	})
+
+	Describe("Suspend and Resume", func() {
+		BeforeEach(func() {
+			raw = newBufferConn(IAC, DO, MCCP2, IAC, DO, GMCP, IAC, WILL, NAWS, IAC, SB, NAWS, 0, 100, 0, 30, IAC, SE, 'x')
+			conn = NewConn(raw)
+			conn.SetCompression(true)
+			conn.SetGMCP(true)
+			conn.RequestTerminal()
+			conn.SetCharset("latin1")
+			readAll()
+			raw.sent()
+		})
+
+		It("carries on with what was negotiated", func() {
+			st, err := conn.Suspend()
+			Ω(err).Should(BeNil())
+			Ω(conn.Compressing()).Should(BeFalse())
+
+			encoded, err := json.Marshal(st)
+			Ω(err).Should(BeNil())
+			var decoded State
+			Ω(json.Unmarshal(encoded, &decoded)).Should(Succeed())
+
+			next := newBufferConn()
+			resumed, err := Resume(next, decoded)
+			Ω(err).Should(BeNil())
+			Ω(resumed.Compressing()).Should(BeTrue())
+			Ω(resumed.GMCP()).Should(BeTrue())
+			Ω(resumed.Terminal().Width).Should(Equal(100))
+			Ω(resumed.Terminal().Charset).Should(Equal("ISO-8859-1"))
+
+			resumed.Write([]byte("é"))
+			resumed.Close()
+			sent := next.sent()
+			Ω(sent[:5]).Should(Equal([]byte{IAC, SB, MCCP2, IAC, SE}))
+			z, err := zlib.NewReader(bytes.NewReader(sent[5:]))
+			Ω(err).Should(BeNil())
+			text, err := ioutil.ReadAll(z)
+			Ω(err).Should(BeNil())
+			Ω(text).Should(Equal([]byte{0xe9}))
+		})
+
+		It("doesn't compress when the client hadn't agreed", func() {
+			resumed, err := Resume(newBufferConn(), State{MCCP: Option{Wanted: true, Offered: true}})
+			Ω(err).Should(BeNil())
+			Ω(resumed.Compressing()).Should(BeFalse())
+			Ω(resumed.Terminal().Charset).Should(Equal(UTF8))
+		})
+	})
+
+	Describe("Terminal", func() {
+		It("guesses color support from the types", func() {
+			Ω(Terminal{}.ColorSupport()).Should(Equal(output.ColorBasic))
+			Ω(Terminal{Types: []string{"dumb"}}.ColorSupport()).Should(Equal(output.ColorMono))
+			Ω(Terminal{Types: []string{"TINTIN", "ANSI", "MTTS 9"}}.ColorSupport()).Should(Equal(output.Color256))
+			Ω(Terminal{Types: []string{"MTTS 0"}}.ColorSupport()).Should(Equal(output.ColorMono))
+		})
+	})
+})
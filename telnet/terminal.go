@@ -0,0 +1,183 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package telnet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bbuck/dragon-mud/output"
+)
+
+// TTYPE subnegotiation commands
+const (
+	ttypeIs   byte = 0
+	ttypeSend byte = 1
+)
+
+// clients are asked for their terminal type until they repeat themselves or
+// this many types were given, MTTS clients send their name, their terminal
+// type and then their MTTS capabilities
+const maxTerminalTypes = 3
+
+// MTTS capability bits
+const (
+	mttsANSI     = 1
+	mtts256Color = 8
+)
+
+// Terminal describes the client's display as reported through NAWS, TTYPE
+// and CHARSET, zero values weren't reported.
+type Terminal struct {
+	// Width and Height are the size of the window in characters.
+	Width  int
+	Height int
+	// Types are the terminal types the client gave, in order, usually the
+	// client's name followed by the terminal it emulates.
+	Types []string
+	// Charset is the character set text is sent in.
+	Charset string
+}
+
+// ColorSupport guesses the colors the terminal can display from its types.
+// Terminals that don't say are assumed to support the basic colors.
+func (t Terminal) ColorSupport() output.ColorSupport {
+	color := output.ColorBasic
+	for _, typ := range t.Types {
+		upper := strings.ToUpper(typ)
+		switch {
+		case strings.HasPrefix(upper, "MTTS "):
+			bits, err := strconv.Atoi(strings.TrimSpace(upper[5:]))
+			if err != nil {
+				continue
+			}
+			switch {
+			case bits&mtts256Color != 0:
+				return output.Color256
+			case bits&mttsANSI != 0:
+				color = output.ColorBasic
+			default:
+				return output.ColorMono
+			}
+		case strings.Contains(upper, "256COLOR"):
+			color = output.Color256
+		case upper == "DUMB":
+			color = output.ColorMono
+		}
+	}
+
+	return color
+}
+
+// terminal is the NAWS, TTYPE and CHARSET state of a connection, it's guarded
+// by the write mutex
+type terminal struct {
+	Terminal
+	askedNAWS    bool
+	askedTTYPE   bool
+	askedCharset bool
+	onChange     func(Terminal)
+}
+
+// RequestTerminal asks the client to report its window size and terminal
+// type.
+func (c *Conn) RequestTerminal() error {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.terminal.askedNAWS = true
+	c.terminal.askedTTYPE = true
+	if err := c.sendCommand(DO, NAWS); err != nil {
+		return err
+	}
+
+	return c.sendCommand(DO, TTYPE)
+}
+
+// Terminal returns what the client has reported about its display so far.
+func (c *Conn) Terminal() Terminal {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	t := c.terminal.Terminal
+	t.Types = append([]string(nil), t.Types...)
+
+	return t
+}
+
+// OnTerminal sets the function called whenever the client reports a change
+// to its display, it's called from Read.
+func (c *Conn) OnTerminal(fn func(Terminal)) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	c.terminal.onChange = fn
+}
+
+// nawsOffered handles WILL NAWS, the write mutex must be held
+func (c *Conn) nawsOffered() {
+	if !c.terminal.askedNAWS {
+		c.terminal.askedNAWS = true
+		c.sendCommand(DO, NAWS)
+	}
+}
+
+// ttypeOffered handles WILL TTYPE by asking for the first type, the write
+// mutex must be held
+func (c *Conn) ttypeOffered() {
+	if !c.terminal.askedTTYPE {
+		c.terminal.askedTTYPE = true
+		c.sendCommand(DO, TTYPE)
+	}
+	c.terminal.Types = nil
+	c.sendSubnegotiation(TTYPE, []byte{ttypeSend})
+}
+
+// nawsReceived records the window size, sent as two 16 bit numbers
+func (c *Conn) nawsReceived(data []byte) {
+	if len(data) != 4 {
+		return
+	}
+
+	c.wmutex.Lock()
+	c.terminal.Width = int(data[0])<<8 | int(data[1])
+	c.terminal.Height = int(data[2])<<8 | int(data[3])
+	c.wmutex.Unlock()
+
+	c.terminalChanged()
+}
+
+// ttypeReceived records a terminal type, asking for the next one until the
+// client repeats itself
+func (c *Conn) ttypeReceived(data []byte) {
+	if len(data) < 1 || data[0] != ttypeIs {
+		return
+	}
+	typ := string(data[1:])
+
+	c.wmutex.Lock()
+	types := c.terminal.Types
+	if len(types) > 0 && types[len(types)-1] == typ {
+		c.wmutex.Unlock()
+
+		return
+	}
+	c.terminal.Types = append(types, typ)
+	if len(c.terminal.Types) < maxTerminalTypes {
+		c.sendSubnegotiation(TTYPE, []byte{ttypeSend})
+	}
+	c.wmutex.Unlock()
+
+	c.terminalChanged()
+}
+
+// terminalChanged passes the terminal to the OnTerminal function
+func (c *Conn) terminalChanged() {
+	c.wmutex.Lock()
+	fn := c.terminal.onChange
+	c.wmutex.Unlock()
+
+	if fn != nil {
+		fn(c.Terminal())
+	}
+}
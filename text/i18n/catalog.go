@@ -0,0 +1,279 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package i18n
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// message is either a plain string or a set of plural forms keyed by plural
+// category.
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// Catalog holds translated messages for any number of locales. Messages are
+// identified by dotted keys, like "combat.miss", and may reference variables
+// with {name} which are replaced when translating.
+type Catalog struct {
+	DefaultLocale string
+
+	messages map[string]map[string]message
+	mutex    *sync.RWMutex
+}
+
+// NewCatalog creates an empty catalog that falls back to the given locale
+// when a message is missing in the requested one.
+func NewCatalog(defaultLocale string) *Catalog {
+	return &Catalog{
+		DefaultLocale: normalize(defaultLocale),
+		messages:      make(map[string]map[string]message),
+		mutex:         new(sync.RWMutex),
+	}
+}
+
+// Locales returns the sorted list of locales with messages in the catalog.
+func (c *Catalog) Locales() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// LoadDir loads every .yml and .yaml file in the directory, using the name of
+// the file as the locale ("en.yml", "pt-BR.yaml"). Missing directories are
+// ignored.
+func (c *Catalog) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := c.LoadYAML(strings.TrimSuffix(fi.Name(), ext), contents); err != nil {
+			return fmt.Errorf("%s: %s", fi.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadYAML adds the messages in the YAML document to the locale. Nested maps
+// build dotted keys, while a map containing only plural categories (and at
+// least "other") defines the plural forms of a single message:
+//   combat:
+//     miss: "{attacker} misses {target}."
+//   coins:
+//     one: "{count} coin"
+//     other: "{count} coins"
+func (c *Catalog) LoadYAML(locale string, contents []byte) error {
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return err
+	}
+
+	msgs := make(map[string]message)
+	flatten("", raw, msgs)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	locale = normalize(locale)
+	existing, ok := c.messages[locale]
+	if !ok {
+		existing = make(map[string]message)
+		c.messages[locale] = existing
+	}
+	for key, msg := range msgs {
+		existing[key] = msg
+	}
+
+	return nil
+}
+
+// Set adds a single plain message to the locale.
+func (c *Catalog) Set(locale, key, text string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	locale = normalize(locale)
+	if _, ok := c.messages[locale]; !ok {
+		c.messages[locale] = make(map[string]message)
+	}
+	c.messages[locale][key] = message{text: text}
+}
+
+// Has returns whether the key can be translated for the locale, including
+// through fallback locales.
+func (c *Catalog) Has(locale, key string) bool {
+	_, ok := c.lookup(locale, key)
+
+	return ok
+}
+
+// T translates the key into the given locale, replacing {name} references
+// with values from vars. If vars contains a "count" the plural form for that
+// count is chosen. Lookups fall back from "en-US" to "en" and then to the
+// default locale, if no message is found the key itself is returned.
+func (c *Catalog) T(locale, key string, vars map[string]interface{}) string {
+	msg, ok := c.lookup(locale, key)
+	if !ok {
+		return key
+	}
+
+	text := msg.text
+	if msg.plural != nil {
+		count, _ := countFrom(vars)
+		text, ok = msg.plural[PluralCategory(locale, count)]
+		if count == 0 {
+			if zero, zok := msg.plural[Zero]; zok {
+				text, ok = zero, true
+			}
+		}
+		if !ok {
+			text = msg.plural[Other]
+		}
+	}
+
+	return interpolate(text, vars)
+}
+
+func (c *Catalog) lookup(locale, key string) (message, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	locale = normalize(locale)
+	for _, loc := range []string{locale, language(locale), c.DefaultLocale} {
+		if msgs, ok := c.messages[loc]; ok {
+			if msg, ok := msgs[key]; ok {
+				return msg, true
+			}
+		}
+	}
+
+	return message{}, false
+}
+
+var pluralKeys = map[string]bool{
+	Zero:  true,
+	One:   true,
+	Two:   true,
+	Few:   true,
+	Many:  true,
+	Other: true,
+}
+
+// walk the parsed YAML building dotted keys for each message
+func flatten(prefix string, raw map[interface{}]interface{}, out map[string]message) {
+	if prefix != "" && isPluralMap(raw) {
+		forms := make(map[string]string)
+		for k, v := range raw {
+			forms[fmt.Sprint(k)] = fmt.Sprint(v)
+		}
+		out[prefix] = message{plural: forms}
+
+		return
+	}
+
+	for k, v := range raw {
+		key := fmt.Sprint(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch val := v.(type) {
+		case map[interface{}]interface{}:
+			flatten(key, val, out)
+		case nil:
+			continue
+		default:
+			out[key] = message{text: fmt.Sprint(val)}
+		}
+	}
+}
+
+func isPluralMap(raw map[interface{}]interface{}) bool {
+	if _, ok := raw[Other]; !ok {
+		return false
+	}
+	for k, v := range raw {
+		if !pluralKeys[fmt.Sprint(k)] {
+			return false
+		}
+		if _, isMap := v.(map[interface{}]interface{}); isMap {
+			return false
+		}
+	}
+
+	return true
+}
+
+var varRx = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// replace {name} with the value from vars, unknown names are left untouched
+func interpolate(text string, vars map[string]interface{}) string {
+	if len(vars) == 0 {
+		return text
+	}
+
+	return varRx.ReplaceAllStringFunc(text, func(match string) string {
+		if val, ok := vars[match[1:len(match)-1]]; ok {
+			if f, isFloat := val.(float64); isFloat && f == float64(int64(f)) {
+				return strconv.FormatInt(int64(f), 10)
+			}
+
+			return fmt.Sprint(val)
+		}
+
+		return match
+	})
+}
+
+// fetch the count from the vars, numbers from Lua are floats
+func countFrom(vars map[string]interface{}) (int, bool) {
+	switch n := vars["count"].(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
+
+// locales are stored in lower case with dashes, "pt_BR" becomes "pt-br"
+func normalize(locale string) string {
+	return strings.Replace(strings.ToLower(locale), "_", "-", -1)
+}
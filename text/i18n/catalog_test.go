@@ -0,0 +1,83 @@
+package i18n_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/bbuck/dragon-mud/text/i18n"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var englishYAML = []byte(`
+greeting: "Welcome, {name}!"
+combat:
+  miss: "{attacker} misses {target}."
+coins:
+  zero: "no coins"
+  one: "{count} coin"
+  other: "{count} coins"
+`)
+
+var russianYAML = []byte(`
+greeting: "Добро пожаловать, {name}!"
+coins:
+  one: "{count} монета"
+  few: "{count} монеты"
+  many: "{count} монет"
+  other: "{count} монеты"
+`)
+
+var _ = Describe("Catalog", func() {
+	var c *Catalog
+
+	BeforeEach(func() {
+		c = NewCatalog("en")
+		Ω(c.LoadYAML("en", englishYAML)).Should(Succeed())
+		Ω(c.LoadYAML("ru", russianYAML)).Should(Succeed())
+	})
+
+	It("translates nested keys with variables", func() {
+		vars := map[string]interface{}{"attacker": "The orc", "target": "you"}
+		Ω(c.T("en", "combat.miss", vars)).Should(Equal("The orc misses you."))
+	})
+
+	It("falls back from regional locales", func() {
+		Ω(c.T("ru_RU", "greeting", map[string]interface{}{"name": "Иван"})).Should(Equal("Добро пожаловать, Иван!"))
+	})
+
+	It("falls back to the default locale", func() {
+		Ω(c.T("ru", "combat.miss", nil)).Should(Equal("{attacker} misses {target}."))
+	})
+
+	It("returns the key for missing messages", func() {
+		Ω(c.T("en", "missing.key", nil)).Should(Equal("missing.key"))
+		Ω(c.Has("en", "missing.key")).Should(BeFalse())
+	})
+
+	DescribeTable("plural forms",
+		func(locale string, count float64, expected string) {
+			Ω(c.T(locale, "coins", map[string]interface{}{"count": count})).Should(Equal(expected))
+		},
+		Entry("english zero", "en", 0.0, "no coins"),
+		Entry("english one", "en", 1.0, "1 coin"),
+		Entry("english other", "en", 5.0, "5 coins"),
+		Entry("russian one", "ru", 21.0, "21 монета"),
+		Entry("russian few", "ru", 3.0, "3 монеты"),
+		Entry("russian many", "ru", 11.0, "11 монет"),
+	)
+
+	It("loads locale files from a directory", func() {
+		dir, err := ioutil.TempDir("", "locales")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "fr.yml"), []byte(`greeting: "Bienvenue, {name} !"`), 0644)
+
+		Ω(c.LoadDir(dir)).Should(Succeed())
+		Ω(c.Locales()).Should(Equal([]string{"en", "fr", "ru"}))
+		Ω(c.T("fr", "greeting", map[string]interface{}{"name": "Jean"})).Should(Equal("Bienvenue, Jean !"))
+	})
+})
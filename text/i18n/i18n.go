@@ -0,0 +1,28 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package i18n
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	defaultCatalog *Catalog
+	catalogOnce    sync.Once
+)
+
+// Global returns the catalog shared by the game, falling back to the locale
+// configured as "i18n.default_locale".
+func Global() *Catalog {
+	catalogOnce.Do(func() {
+		locale := viper.GetString("i18n.default_locale")
+		if locale == "" {
+			locale = "en"
+		}
+		defaultCatalog = NewCatalog(locale)
+	})
+
+	return defaultCatalog
+}
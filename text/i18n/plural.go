@@ -0,0 +1,103 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package i18n
+
+import "strings"
+
+// Plural categories, as defined by the Unicode CLDR plural rules.
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralRule maps a count onto the plural category used to select a message.
+type PluralRule func(n int) string
+
+var pluralRules = map[string]PluralRule{
+	"en": oneOther,
+	"de": oneOther,
+	"es": oneOther,
+	"it": oneOther,
+	"nl": oneOther,
+	"pt": oneOther,
+	"sv": oneOther,
+	"fr": func(n int) string {
+		if n == 0 || n == 1 {
+			return One
+		}
+
+		return Other
+	},
+	"ru": slavic,
+	"uk": slavic,
+	"pl": func(n int) string {
+		switch {
+		case n == 1:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	},
+	"ja": otherOnly,
+	"ko": otherOnly,
+	"zh": otherOnly,
+}
+
+// RegisterPluralRule sets the rule used for the given language, replacing any
+// existing rule.
+func RegisterPluralRule(lang string, rule PluralRule) {
+	pluralRules[strings.ToLower(lang)] = rule
+}
+
+// PluralCategory returns the plural category for the count in the given
+// locale. Locales without a known rule use the English rule.
+func PluralCategory(locale string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+
+	if rule, ok := pluralRules[language(locale)]; ok {
+		return rule(n)
+	}
+
+	return oneOther(n)
+}
+
+func oneOther(n int) string {
+	if n == 1 {
+		return One
+	}
+
+	return Other
+}
+
+func otherOnly(int) string {
+	return Other
+}
+
+func slavic(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return One
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+// the language portion of a locale, "en-US" and "en_US" become "en"
+func language(locale string) string {
+	locale = strings.ToLower(locale)
+	if idx := strings.IndexAny(locale, "-_"); idx >= 0 {
+		return locale[:idx]
+	}
+
+	return locale
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package inflect
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// leadingColorRx matches any color codes (like [R] or [c123]) at the start of
+// a string so they can be skipped when capitalizing.
+var leadingColorRx = regexp.MustCompile(`^(\[\[?-?[a-zA-Z0-9~]{1,4}?\]?\])*`)
+
+var (
+	irregularPlurals = map[string]string{
+		"child":  "children",
+		"dwarf":  "dwarves",
+		"elf":    "elves",
+		"foot":   "feet",
+		"goose":  "geese",
+		"half":   "halves",
+		"knife":  "knives",
+		"leaf":   "leaves",
+		"life":   "lives",
+		"loaf":   "loaves",
+		"louse":  "lice",
+		"man":    "men",
+		"mouse":  "mice",
+		"ox":     "oxen",
+		"person": "people",
+		"staff":  "staves",
+		"thief":  "thieves",
+		"tooth":  "teeth",
+		"wife":   "wives",
+		"wolf":   "wolves",
+		"woman":  "women",
+	}
+	uncountables = map[string]struct{}{
+		"armor":     {},
+		"deer":      {},
+		"equipment": {},
+		"fish":      {},
+		"gold":      {},
+		"money":     {},
+		"moose":     {},
+		"sheep":     {},
+		"silver":    {},
+	}
+	oesWords = map[string]struct{}{
+		"echo":    {},
+		"hero":    {},
+		"potato":  {},
+		"tomato":  {},
+		"torpedo": {},
+		"volcano": {},
+	}
+	// words starting with a vowel that sound like a consonant, or starting
+	// with a consonant that sound like a vowel
+	consonantSounds = []string{"eu", "ewe", "one", "once", "uni", "use", "usu", "uti", "ura", "ure", "uro"}
+	vowelSounds     = []string{"heir", "herb", "honest", "honor", "honour", "hour"}
+	smallWords      = map[string]struct{}{
+		"a":   {},
+		"an":  {},
+		"and": {},
+		"at":  {},
+		"but": {},
+		"by":  {},
+		"for": {},
+		"in":  {},
+		"of":  {},
+		"on":  {},
+		"or":  {},
+		"the": {},
+		"to":  {},
+	}
+	possessivePronouns = map[string]string{
+		"i":    "my",
+		"you":  "your",
+		"he":   "his",
+		"she":  "her",
+		"it":   "its",
+		"we":   "our",
+		"they": "their",
+	}
+)
+
+// Plural returns the plural form of the given word. The last word of a phrase
+// is pluralized, so "long sword" becomes "long swords" and the casing of the
+// original word's first letter is preserved.
+func Plural(word string) string {
+	if word == "" {
+		return word
+	}
+
+	idx := strings.LastIndex(word, " ")
+	prefix, last := word[:idx+1], word[idx+1:]
+	lower := strings.ToLower(last)
+
+	if _, ok := uncountables[lower]; ok {
+		return word
+	}
+
+	if plural, ok := irregularPlurals[lower]; ok {
+		return prefix + matchCase(last, plural)
+	}
+
+	var suffixed string
+	switch {
+	case hasAnySuffix(lower, "s", "x", "z", "ch", "sh"):
+		suffixed = last + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !isVowel(lower[len(lower)-2]):
+		suffixed = last[:len(last)-1] + "ies"
+	case strings.HasSuffix(lower, "o"):
+		if _, ok := oesWords[lower]; ok {
+			suffixed = last + "es"
+		} else {
+			suffixed = last + "s"
+		}
+	default:
+		suffixed = last + "s"
+	}
+
+	return prefix + suffixed
+}
+
+// Pluralize returns the word as is when count is exactly one, otherwise it
+// returns the plural form of the word.
+func Pluralize(word string, count int) string {
+	if count == 1 || count == -1 {
+		return word
+	}
+
+	return Plural(word)
+}
+
+// Possessive returns the possessive form of the given name or pronoun, such
+// as "Bob's", "James'" or "your".
+func Possessive(name string) string {
+	if name == "" {
+		return name
+	}
+
+	if pronoun, ok := possessivePronouns[strings.ToLower(name)]; ok {
+		return matchCase(name, pronoun)
+	}
+
+	if strings.HasSuffix(strings.ToLower(name), "s") {
+		return name + "'"
+	}
+
+	return name + "'s"
+}
+
+// Capitalize upper cases the first letter in the string, skipping over any
+// leading color codes so that "[R]the orc" becomes "[R]The orc".
+func Capitalize(s string) string {
+	codes := leadingColorRx.FindString(s)
+	rest := s[len(codes):]
+	r, size := utf8.DecodeRuneInString(rest)
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return codes + string(unicode.ToUpper(r)) + rest[size:]
+}
+
+// Article returns the indefinite article, "a" or "an", that should be used
+// before the given word based on how the word is likely to sound.
+func Article(word string) string {
+	lower := strings.ToLower(leadingColorRx.ReplaceAllString(word, ""))
+	if lower == "" {
+		return "a"
+	}
+
+	for _, prefix := range vowelSounds {
+		if strings.HasPrefix(lower, prefix) {
+			return "an"
+		}
+	}
+	for _, prefix := range consonantSounds {
+		if strings.HasPrefix(lower, prefix) {
+			return "a"
+		}
+	}
+
+	if isVowel(lower[0]) {
+		return "an"
+	}
+
+	return "a"
+}
+
+// WithArticle prefixes the word with it's indefinite article, like
+// "an apple" or "a unicorn".
+func WithArticle(word string) string {
+	return Article(word) + " " + word
+}
+
+// Titlecase capitalizes each word in the text except for short words like
+// "of" and "the" that aren't at the start, so "sword of the north" becomes
+// "Sword of the North".
+func Titlecase(text string) string {
+	words := strings.Split(text, " ")
+	for i, word := range words {
+		plain := strings.ToLower(leadingColorRx.ReplaceAllString(word, ""))
+		if _, small := smallWords[plain]; small && i > 0 {
+			continue
+		}
+		words[i] = Capitalize(word)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// copy the case of the first letter of src onto the start of dest
+func matchCase(src, dest string) string {
+	r, _ := utf8.DecodeRuneInString(src)
+	if unicode.IsUpper(r) {
+		return Capitalize(dest)
+	}
+
+	return dest
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
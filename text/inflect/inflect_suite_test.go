@@ -0,0 +1,13 @@
+package inflect_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestInflect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Inflect Suite")
+}
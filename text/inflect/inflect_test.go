@@ -0,0 +1,75 @@
+package inflect_test
+
+import (
+	. "github.com/bbuck/dragon-mud/text/inflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inflect", func() {
+	DescribeTable("Plural",
+		func(input, expected string) {
+			Ω(Plural(input)).Should(Equal(expected))
+		},
+		Entry("regular words", "sword", "swords"),
+		Entry("words ending in s", "glass", "glasses"),
+		Entry("words ending in ch", "torch", "torches"),
+		Entry("words ending in consonant y", "ruby", "rubies"),
+		Entry("words ending in vowel y", "key", "keys"),
+		Entry("words ending in o", "potato", "potatoes"),
+		Entry("irregular words", "wolf", "wolves"),
+		Entry("capitalized irregular words", "Dwarf", "Dwarves"),
+		Entry("uncountable words", "gold", "gold"),
+		Entry("phrases", "long sword", "long swords"),
+		Entry("empty strings", "", ""),
+	)
+
+	DescribeTable("Pluralize",
+		func(input string, count int, expected string) {
+			Ω(Pluralize(input, count)).Should(Equal(expected))
+		},
+		Entry("a count of one", "coin", 1, "coin"),
+		Entry("a count of zero", "coin", 0, "coins"),
+		Entry("a count greater than one", "coin", 5, "coins"),
+	)
+
+	DescribeTable("Possessive",
+		func(input, expected string) {
+			Ω(Possessive(input)).Should(Equal(expected))
+		},
+		Entry("names", "Bob", "Bob's"),
+		Entry("names ending in s", "James", "James'"),
+		Entry("pronouns", "you", "your"),
+		Entry("capitalized pronouns", "It", "Its"),
+	)
+
+	DescribeTable("Capitalize",
+		func(input, expected string) {
+			Ω(Capitalize(input)).Should(Equal(expected))
+		},
+		Entry("simple strings", "the orc", "The orc"),
+		Entry("strings with leading colors", "[R][-b]the orc", "[R][-b]The orc"),
+		Entry("empty strings", "", ""),
+	)
+
+	DescribeTable("WithArticle",
+		func(input, expected string) {
+			Ω(WithArticle(input)).Should(Equal(expected))
+		},
+		Entry("consonants", "sword", "a sword"),
+		Entry("vowels", "apple", "an apple"),
+		Entry("vowels with consonant sounds", "unicorn", "a unicorn"),
+		Entry("consonants with vowel sounds", "hour glass", "an hour glass"),
+		Entry("leading color codes", "[R]orc", "an [R]orc"),
+	)
+
+	DescribeTable("Titlecase",
+		func(input, expected string) {
+			Ω(Titlecase(input)).Should(Equal(expected))
+		},
+		Entry("simple phrases", "sword of the north", "Sword of the North"),
+		Entry("leading small words", "the rusty dagger", "The Rusty Dagger"),
+	)
+})
@@ -0,0 +1,150 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package namegen
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/bbuck/dragon-mud/random"
+)
+
+// DefaultOrder is the number of previous letters used to choose the next one,
+// higher orders produce names closer to the corpus.
+const DefaultOrder = 3
+
+// maxAttempts is the number of names generated looking for one that fits the
+// requested length before giving up.
+const maxAttempts = 200
+
+const (
+	startRune = '^'
+	endRune   = '$'
+)
+
+// ErrEmptyModel is returned when generating names from a model with no
+// training data.
+var ErrEmptyModel = errors.New("the name model has no training data")
+
+// ErrNoName is returned when no name matching the constraints could be
+// generated.
+var ErrNoName = errors.New("unable to generate a name within the given length")
+
+// Model is a Markov chain of letters trained on a list of names. Generated
+// names follow the same letter patterns as the training names without
+// (usually) repeating them.
+type Model struct {
+	Order int
+
+	chains map[string][]rune
+	known  map[string]bool
+}
+
+// NewModel creates an empty model using the given order, orders less than 1
+// use DefaultOrder.
+func NewModel(order int) *Model {
+	if order < 1 {
+		order = DefaultOrder
+	}
+
+	return &Model{
+		Order:  order,
+		chains: make(map[string][]rune),
+		known:  make(map[string]bool),
+	}
+}
+
+// Train adds the names to the model, blank names are ignored. Names are
+// trained in lower case.
+func (m *Model) Train(names ...string) {
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		m.known[name] = true
+
+		runes := []rune(strings.Repeat(string(startRune), m.Order) + name + string(endRune))
+		for i := m.Order; i < len(runes); i++ {
+			key := string(runes[i-m.Order : i])
+			m.chains[key] = append(m.chains[key], runes[i])
+		}
+	}
+}
+
+// Size returns the number of unique names the model was trained with.
+func (m *Model) Size() int {
+	return len(m.known)
+}
+
+// Generate builds a new name between minLen and maxLen letters long (a maxLen
+// of 0 or less has no limit). Names that exactly match a training name are
+// rejected unless nothing else can be produced.
+func (m *Model) Generate(minLen, maxLen int) (string, error) {
+	if len(m.chains) == 0 {
+		return "", ErrEmptyModel
+	}
+
+	var fallback string
+	for i := 0; i < maxAttempts; i++ {
+		name := m.walk(maxLen)
+		length := len([]rune(name))
+		if length < minLen || (maxLen > 0 && length > maxLen) {
+			continue
+		}
+
+		if m.known[name] {
+			fallback = name
+
+			continue
+		}
+
+		return capitalize(name), nil
+	}
+
+	if fallback != "" {
+		return capitalize(fallback), nil
+	}
+
+	return "", ErrNoName
+}
+
+// walk the chain from the start until reaching the end or exceeding maxLen
+func (m *Model) walk(maxLen int) string {
+	key := []rune(strings.Repeat(string(startRune), m.Order))
+	var name []rune
+	for {
+		options := m.chains[string(key)]
+		if len(options) == 0 {
+			break
+		}
+
+		next := options[random.Intn(len(options))]
+		if next == endRune {
+			break
+		}
+
+		name = append(name, next)
+		if maxLen > 0 && len(name) > maxLen {
+			break
+		}
+		key = append(key[1:], next)
+	}
+
+	return string(name)
+}
+
+// capitalize the first letter of each word in the name
+func capitalize(name string) string {
+	runes := []rune(name)
+	upper := true
+	for i, r := range runes {
+		if upper {
+			runes[i] = unicode.ToUpper(r)
+		}
+		upper = r == ' ' || r == '-'
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+// Package namegen generates pronounceable names from Markov chains trained on
+// lists of example names. Each list defines a style, such as a culture or a
+// kind of item, allowing generated NPCs and items to fit in with the world.
+package namegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Generator holds a model for each named style.
+type Generator struct {
+	models map[string]*Model
+	mutex  *sync.RWMutex
+}
+
+// NewGenerator creates a generator without any styles.
+func NewGenerator() *Generator {
+	return &Generator{
+		models: make(map[string]*Model),
+		mutex:  new(sync.RWMutex),
+	}
+}
+
+var (
+	globalGenerator *Generator
+	globalOnce      sync.Once
+)
+
+// Global returns the generator shared by the game.
+func Global() *Generator {
+	globalOnce.Do(func() {
+		globalGenerator = NewGenerator()
+	})
+
+	return globalGenerator
+}
+
+// Train adds names to the style, creating it if it doesn't exist yet.
+func (g *Generator) Train(style string, names ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	model, ok := g.models[style]
+	if !ok {
+		model = NewModel(DefaultOrder)
+		g.models[style] = model
+	}
+	model.Train(names...)
+}
+
+// Has determines if the style has been defined.
+func (g *Generator) Has(style string) bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	_, ok := g.models[style]
+
+	return ok
+}
+
+// Styles returns the sorted names of all defined styles.
+func (g *Generator) Styles() []string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	styles := make([]string, 0, len(g.models))
+	for style := range g.models {
+		styles = append(styles, style)
+	}
+	sort.Strings(styles)
+
+	return styles
+}
+
+// Generate creates a name in the given style, see Model.Generate.
+func (g *Generator) Generate(style string, minLen, maxLen int) (string, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	model, ok := g.models[style]
+	if !ok {
+		return "", fmt.Errorf("unknown name style %q", style)
+	}
+
+	return model.Generate(minLen, maxLen)
+}
+
+// LoadDir trains a style from every .txt file in the directory, using the name
+// of the file as the style ("elven.txt" is "elven"). Files list one name per
+// line, blank lines and lines starting with # are ignored. Missing directories
+// are ignored.
+func (g *Generator) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".txt" {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		g.Train(strings.TrimSuffix(fi.Name(), ".txt"), parseCorpus(contents)...)
+	}
+
+	return nil
+}
+
+// read the names from a corpus file
+func parseCorpus(contents []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+
+	return names
+}
@@ -0,0 +1,13 @@
+package namegen_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestNamegen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Namegen Suite")
+}
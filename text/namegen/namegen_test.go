@@ -0,0 +1,102 @@
+package namegen_test
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"unicode"
+
+	"github.com/bbuck/dragon-mud/random"
+	. "github.com/bbuck/dragon-mud/text/namegen"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var elven = []string{
+	"Aerendil", "Aelindra", "Caladrel", "Elandor", "Erevan", "Faelar",
+	"Galadrel", "Ilyndra", "Lorandil", "Miriel", "Naerendil", "Thalion",
+}
+
+var _ = Describe("Namegen", func() {
+	BeforeEach(func() {
+		random.SetSource(rand.NewSource(1))
+	})
+
+	Describe("Model", func() {
+		It("generates capitalized names within the length limits", func() {
+			m := NewModel(2)
+			m.Train(elven...)
+
+			for i := 0; i < 20; i++ {
+				name, err := m.Generate(4, 9)
+				Ω(err).Should(BeNil())
+				Ω(len(name)).Should(BeNumerically(">=", 4))
+				Ω(len(name)).Should(BeNumerically("<=", 9))
+				Ω(unicode.IsUpper([]rune(name)[0])).Should(BeTrue())
+			}
+		})
+
+		It("prefers names that aren't in the corpus", func() {
+			m := NewModel(2)
+			m.Train(elven...)
+
+			name, err := m.Generate(3, 0)
+			Ω(err).Should(BeNil())
+			Ω(elven).ShouldNot(ContainElement(name))
+		})
+
+		It("falls back to corpus names when nothing new can be made", func() {
+			m := NewModel(3)
+			m.Train("Bob")
+
+			name, err := m.Generate(0, 0)
+			Ω(err).Should(BeNil())
+			Ω(name).Should(Equal("Bob"))
+		})
+
+		It("fails without training data", func() {
+			_, err := NewModel(3).Generate(0, 0)
+
+			Ω(err).Should(Equal(ErrEmptyModel))
+		})
+
+		It("fails when the length can't be met", func() {
+			m := NewModel(3)
+			m.Train("Bob")
+
+			_, err := m.Generate(10, 20)
+			Ω(err).Should(Equal(ErrNoName))
+		})
+	})
+
+	Describe("Generator", func() {
+		It("loads styles from corpus files", func() {
+			dir, err := ioutil.TempDir("", "namegen")
+			Ω(err).Should(BeNil())
+			defer os.RemoveAll(dir)
+
+			ioutil.WriteFile(filepath.Join(dir, "dwarven.txt"), []byte("# dwarves\nThorin\n\nBalin\nDurin\n"), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "notes.md"), []byte("ignored"), 0644)
+
+			g := NewGenerator()
+			Ω(g.LoadDir(dir)).Should(Succeed())
+			Ω(g.Styles()).Should(Equal([]string{"dwarven"}))
+
+			name, err := g.Generate("dwarven", 0, 0)
+			Ω(err).Should(BeNil())
+			Ω(name).ShouldNot(BeEmpty())
+		})
+
+		It("ignores missing directories", func() {
+			Ω(NewGenerator().LoadDir("/does/not/exist")).Should(Succeed())
+		})
+
+		It("fails for unknown styles", func() {
+			_, err := NewGenerator().Generate("orcish", 0, 0)
+
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,146 @@
+// Copyright (c) 2016-2017 Brandon Buck
+
+package strutil
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bbuck/dragon-mud/ansi"
+)
+
+// Alignment determines which side of the text padding is added to.
+type Alignment uint8
+
+// Alignment values
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+// Wrap breaks the text into lines no wider than width visible characters.
+// Color codes do not count towards the width of a line and existing line
+// breaks are kept. Words longer than width are placed on their own line rather
+// than being split.
+func Wrap(text string, width int) string {
+	if width < 1 {
+		return text
+	}
+
+	paragraphs := strings.Split(text, "\n")
+	for i, para := range paragraphs {
+		var (
+			lines   []string
+			line    string
+			lineLen int
+		)
+		for _, word := range strings.Fields(para) {
+			wordLen := ansi.VisibleLength(word)
+			switch {
+			case lineLen == 0:
+				line, lineLen = word, wordLen
+			case lineLen+1+wordLen <= width:
+				line += " " + word
+				lineLen += 1 + wordLen
+			default:
+				lines = append(lines, line)
+				line, lineLen = word, wordLen
+			}
+		}
+		lines = append(lines, line)
+		paragraphs[i] = strings.Join(lines, "\n")
+	}
+
+	return strings.Join(paragraphs, "\n")
+}
+
+// Pad fills the text with the fill string until it's visible length is width.
+// Text already at or beyond width is returned as is.
+func Pad(text string, width int, align Alignment, fill string) string {
+	if fill == "" {
+		fill = " "
+	}
+
+	missing := width - ansi.VisibleLength(text)
+	if missing <= 0 {
+		return text
+	}
+
+	switch align {
+	case AlignRight:
+		return repeat(fill, missing) + text
+	case AlignCenter:
+		left := missing / 2
+
+		return repeat(fill, left) + text + repeat(fill, missing-left)
+	default:
+		return text + repeat(fill, missing)
+	}
+}
+
+// Levenshtein returns the number of single character edits (insertions,
+// deletions or substitutions) needed to turn a into b.
+func Levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(br)]
+}
+
+// Closest finds the candidate with the smallest edit distance from input,
+// ignoring case. Candidates further than maxDistance are ignored, if none
+// are close enough ok is false.
+func Closest(input string, candidates []string, maxDistance int) (match string, ok bool) {
+	best := maxDistance + 1
+	input = strings.ToLower(input)
+	for _, candidate := range candidates {
+		if d := Levenshtein(input, strings.ToLower(candidate)); d < best {
+			best, match, ok = d, candidate, true
+		}
+	}
+
+	return match, ok
+}
+
+// EscapeTemplate makes text safe to embed in a template, escaping template
+// tags and color codes so they're displayed rather than processed. This
+// should be used on any player provided text, like names or says.
+func EscapeTemplate(text string) string {
+	return strings.Replace(ansi.EscapeCodes(text), "{{", "\\{{", -1)
+}
+
+// repeat fill until it covers n visible characters
+func repeat(fill string, n int) string {
+	fillLen := utf8.RuneCountInString(fill)
+	s := strings.Repeat(fill, n/fillLen+1)
+
+	return string([]rune(s)[:n])
+}
+
+func minInt(nums ...int) int {
+	m := nums[0]
+	for _, n := range nums[1:] {
+		if n < m {
+			m = n
+		}
+	}
+
+	return m
+}
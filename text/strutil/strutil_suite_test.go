@@ -0,0 +1,13 @@
+package strutil_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestStrutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Strutil Suite")
+}
@@ -0,0 +1,57 @@
+package strutil_test
+
+import (
+	. "github.com/bbuck/dragon-mud/text/strutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strutil", func() {
+	DescribeTable("Wrap",
+		func(input string, width int, expected string) {
+			Ω(Wrap(input, width)).Should(Equal(expected))
+		},
+		Entry("short text", "a short line", 20, "a short line"),
+		Entry("long text", "the quick brown fox jumps over the lazy dog", 15, "the quick brown\nfox jumps over\nthe lazy dog"),
+		Entry("colored text", "[R]the[x] quick brown", 9, "[R]the[x] quick\nbrown"),
+		Entry("existing line breaks", "one two\nthree four", 7, "one two\nthree\nfour"),
+		Entry("long words", "a supercalifragilistic word", 5, "a\nsupercalifragilistic\nword"),
+	)
+
+	DescribeTable("Pad",
+		func(input string, width int, align Alignment, fill, expected string) {
+			Ω(Pad(input, width, align, fill)).Should(Equal(expected))
+		},
+		Entry("left aligned", "hp", 5, AlignLeft, "", "hp   "),
+		Entry("right aligned", "hp", 5, AlignRight, "", "   hp"),
+		Entry("centered", "hp", 6, AlignCenter, "-", "--hp--"),
+		Entry("colored", "[R]hp[x]", 4, AlignLeft, ".", "[R]hp[x].."),
+		Entry("too long", "health", 3, AlignLeft, "", "health"),
+	)
+
+	DescribeTable("Levenshtein",
+		func(a, b string, expected int) {
+			Ω(Levenshtein(a, b)).Should(Equal(expected))
+		},
+		Entry("equal strings", "look", "look", 0),
+		Entry("substitutions", "look", "book", 1),
+		Entry("insertions", "lok", "look", 1),
+		Entry("mixed edits", "kitten", "sitting", 3),
+		Entry("empty strings", "", "abc", 3),
+	)
+
+	It("finds the closest candidate", func() {
+		match, ok := Closest("inventroy", []string{"look", "inventory", "invite"}, 2)
+		Ω(ok).Should(BeTrue())
+		Ω(match).Should(Equal("inventory"))
+
+		_, ok = Closest("xyzzy", []string{"look"}, 2)
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("escapes template and color syntax", func() {
+		Ω(EscapeTemplate("{{name}} [r]")).Should(Equal("\\{{name}} [[r]]"))
+	})
+})
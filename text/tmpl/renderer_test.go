@@ -123,5 +123,37 @@ var _ = Describe("Renderer", func() {
 				Ω(result).Should(Equal("red text!"))
 			})
 		})
+
+		Context("calling the text helpers", func() {
+			var data = map[string]interface{}{
+				"name":  "James",
+				"item":  "wolf",
+				"count": float64(3),
+				"mob":   "the orc",
+			}
+
+			render := func(contents string) string {
+				result, err := RenderOnce(contents, data)
+				Ω(err).Should(BeNil())
+
+				return result
+			}
+
+			It("pluralizes words based on count", func() {
+				Ω(render("{{count}} {{pluralize item count}}")).Should(Equal("3 wolves"))
+			})
+
+			It("builds possessive names", func() {
+				Ω(render("{{possessive name}} sword")).Should(Equal("James' sword"))
+			})
+
+			It("capitalizes text", func() {
+				Ω(render("{{capitalize mob}} attacks!")).Should(Equal("The orc attacks!"))
+			})
+
+			It("wraps text in color codes", func() {
+				Ω(render(`{{color "R" mob}}`)).Should(Equal("[R]the orc[x]"))
+			})
+		})
 	})
 })
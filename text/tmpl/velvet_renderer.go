@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/bbuck/dragon-mud/ansi"
+	"github.com/bbuck/dragon-mud/text/inflect"
 	"github.com/fatih/structs"
 	"github.com/gobuffalo/velvet"
 )
@@ -17,6 +18,35 @@ func init() {
 
 		return template.HTML(s)
 	})
+	velvet.Helpers.Add("pluralize", func(word string, count interface{}) template.HTML {
+		return template.HTML(inflect.Pluralize(word, helperInt(count)))
+	})
+	velvet.Helpers.Add("possessive", func(name string) template.HTML {
+		return template.HTML(inflect.Possessive(name))
+	})
+	velvet.Helpers.Add("capitalize", func(s string) template.HTML {
+		return template.HTML(inflect.Capitalize(s))
+	})
+	velvet.Helpers.Add("color", func(code, s string) template.HTML {
+		return template.HTML(fmt.Sprintf("[%s]%s[x]", code, s))
+	})
+}
+
+// numbers provided from Lua are always floats, so helpers taking counts need
+// to accept any numeric type.
+func helperInt(i interface{}) int {
+	switch n := i.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case float32:
+		return int(n)
+	default:
+		return 0
+	}
 }
 
 // InvalidDataError represents that a data value was provided of an unexpected